@@ -5,6 +5,7 @@ import (
 	"os"
 	"runtime"
 
+	"github.com/depscloud/api/v1alpha/extractor"
 	"github.com/depscloud/api/v1alpha/tracker"
 
 	"github.com/sirupsen/logrus"
@@ -21,16 +22,51 @@ const (
 	VariableCertPath      = "DEPSCLOUD_CERT_PATH"
 	VariableKeyPath       = "DEPSCLOUD_KEY_PATH"
 
+	VariableConfig  = "DEPSCLOUD_CONFIG"
+	VariableContext = "DEPSCLOUD_CONTEXT"
+	VariableToken   = "DEPSCLOUD_TOKEN"
+
 	DefaultProtocol = "grpc"
 	DefaultBaseURL  = "https://api.deps.cloud"
 )
 
-var (
-	protocol = or(os.Getenv(VariableProtocol), DefaultProtocol)
-	baseURL  = or(os.Getenv(VariableBaseURL), DefaultBaseURL)
-)
+var current = resolve()
+
+// resolve determines the deployment to talk to. Values come from the current
+// context of the config file, which DEPSCLOUD_CONTEXT overrides, and
+// environment variables take precedence over both.
+func resolve() *Context {
+	resolved := &Context{}
+
+	cfg, err := LoadConfig(ConfigPath())
+	if err != nil {
+		logrus.Warnf("failed to load config: %v", err)
+		cfg = &Config{}
+	}
+
+	selected := cfg.Current()
+	if name := os.Getenv(VariableContext); name != "" {
+		if selected = cfg.Get(name); selected == nil {
+			logrus.Warnf("context %s does not exist", name)
+		}
+	}
+
+	if selected != nil {
+		*resolved = *selected
+	}
+
+	resolved.Protocol = or(os.Getenv(VariableProtocol), or(resolved.Protocol, DefaultProtocol))
+	resolved.BaseURL = or(os.Getenv(VariableBaseURL), or(resolved.BaseURL, DefaultBaseURL))
+	resolved.CAPath = or(os.Getenv(VariableCAPath), resolved.CAPath)
+	resolved.CertPath = or(os.Getenv(VariableCertPath), resolved.CertPath)
+	resolved.KeyPath = or(os.Getenv(VariableKeyPath), resolved.KeyPath)
+	resolved.Token = or(os.Getenv(VariableToken), resolved.Token)
+
+	return resolved
+}
 
 type SystemInfo struct {
+	Context  string
 	Protocol string
 	BaseURL  string
 	Os       string
@@ -38,7 +74,7 @@ type SystemInfo struct {
 }
 
 func (s SystemInfo) String() string {
-	return fmt.Sprintf("{protocol: %v, baseURL: %v, os: %v, arch: %v}", s.Protocol, s.BaseURL, s.Os, s.Arch)
+	return fmt.Sprintf("{context: %v, protocol: %v, baseURL: %v, os: %v, arch: %v}", s.Context, s.Protocol, s.BaseURL, s.Os, s.Arch)
 }
 
 func or(read, def string) string {
@@ -49,16 +85,24 @@ func or(read, def string) string {
 }
 
 func DefaultClient() Client {
-	if protocol == "grpc" {
-		return grpcDefaultClient(baseURL)
+	authenticate()
+
+	if current.Protocol == "grpc" {
+		return grpcDefaultClient(current)
 	}
 
 	logrus.Warnf("the HTTP api is deprecated, please migrate to gRPC")
-	return httpDefaltClient(baseURL)
+	return httpDefaltClient(current.BaseURL)
 }
 
 func GetSystemInfo() SystemInfo {
-	return SystemInfo{Protocol: protocol, BaseURL: baseURL, Os: runtime.GOOS, Arch: runtime.GOARCH}
+	return SystemInfo{
+		Context:  current.Name,
+		Protocol: current.Protocol,
+		BaseURL:  current.BaseURL,
+		Os:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+	}
 }
 
 type Client interface {
@@ -66,4 +110,7 @@ type Client interface {
 	Modules() tracker.ModuleServiceClient
 	Sources() tracker.SourceServiceClient
 	Search() tracker.SearchServiceClient
+	Extractor() extractor.DependencyExtractorClient
+	TextSearch() TextSearchClient
+	Labels() LabelClient
 }
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+)
+
+// Context is a named deployment of deps.cloud along with how to connect to
+// it, allowing users to switch between deployments (such as staging and
+// production) without juggling environment variables.
+type Context struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol,omitempty"`
+	BaseURL  string `json:"base-url,omitempty"`
+	CAPath   string `json:"ca-path,omitempty"`
+	CertPath string `json:"cert-path,omitempty"`
+	KeyPath  string `json:"key-path,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// Config holds the contexts known to the command line along with the one
+// that's currently in use.
+type Config struct {
+	CurrentContext string     `json:"current-context,omitempty"`
+	Contexts       []*Context `json:"contexts,omitempty"`
+}
+
+// ConfigPath returns the location of the config file, which can be moved using
+// the DEPSCLOUD_CONFIG environment variable.
+func ConfigPath() string {
+	if path := os.Getenv(VariableConfig); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".deps", "config.yaml")
+}
+
+// LoadConfig reads the config file at the path. A missing file is treated as
+// an empty config.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Save writes the config file. Since contexts can hold tokens, the file is
+// only readable by the current user.
+func (c *Config) Save(path string) error {
+	if path == "" {
+		return fmt.Errorf("unable to determine the location of the config file, set %s", VariableConfig)
+	}
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Get returns the named context or nil when it doesn't exist.
+func (c *Config) Get(name string) *Context {
+	for _, context := range c.Contexts {
+		if context.Name == name {
+			return context
+		}
+	}
+	return nil
+}
+
+// Current returns the context in use, or nil when there isn't one.
+func (c *Config) Current() *Context {
+	if c.CurrentContext == "" {
+		return nil
+	}
+	return c.Get(c.CurrentContext)
+}
+
+// Set adds the context, replacing any existing context with the same name.
+func (c *Config) Set(context *Context) {
+	for i, existing := range c.Contexts {
+		if existing.Name == context.Name {
+			c.Contexts[i] = context
+			return
+		}
+	}
+	c.Contexts = append(c.Contexts, context)
+}
+
+// Delete removes the named context, returning false when it doesn't exist. The
+// current context is unset when it's removed.
+func (c *Config) Delete(name string) bool {
+	for i, existing := range c.Contexts {
+		if existing.Name == name {
+			c.Contexts = append(c.Contexts[:i], c.Contexts[i+1:]...)
+			if c.CurrentContext == name {
+				c.CurrentContext = ""
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deps")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "nested", "config.yaml")
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Nil(t, cfg.Current())
+
+	cfg.Set(&Context{Name: "production", BaseURL: "https://api.deps.cloud"})
+	cfg.Set(&Context{Name: "staging", BaseURL: "https://staging.deps.cloud", Token: "token"})
+	cfg.Set(&Context{Name: "staging", BaseURL: "http://localhost:8080"})
+	cfg.CurrentContext = "staging"
+	require.NoError(t, cfg.Save(path))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	cfg, err = LoadConfig(path)
+	require.NoError(t, err)
+	require.Len(t, cfg.Contexts, 2)
+	require.Equal(t, &Context{Name: "staging", BaseURL: "http://localhost:8080"}, cfg.Current())
+
+	require.True(t, cfg.Delete("staging"))
+	require.False(t, cfg.Delete("staging"))
+	require.Equal(t, "", cfg.CurrentContext)
+}
+
+func TestResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deps")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+
+	cfg := &Config{CurrentContext: "production"}
+	cfg.Set(&Context{Name: "production", BaseURL: "https://api.deps.cloud", Token: "production"})
+	cfg.Set(&Context{Name: "staging", Protocol: "http", BaseURL: "https://staging.deps.cloud", Token: "staging"})
+	require.NoError(t, cfg.Save(path))
+
+	for _, variable := range []string{VariableConfig, VariableContext, VariableBaseURL, VariableProtocol, VariableToken} {
+		defer os.Setenv(variable, os.Getenv(variable))
+		os.Unsetenv(variable)
+	}
+
+	os.Setenv(VariableConfig, path)
+	resolved := resolve()
+	require.Equal(t, "production", resolved.Name)
+	require.Equal(t, DefaultProtocol, resolved.Protocol)
+	require.Equal(t, "production", resolved.Token)
+
+	os.Setenv(VariableContext, "staging")
+	resolved = resolve()
+	require.Equal(t, "http", resolved.Protocol)
+	require.Equal(t, "https://staging.deps.cloud", resolved.BaseURL)
+
+	// environment variables take precedence over the context
+	os.Setenv(VariableBaseURL, "http://localhost:8080")
+	resolved = resolve()
+	require.Equal(t, "http://localhost:8080", resolved.BaseURL)
+	require.Equal(t, "staging", resolved.Token)
+}
+
+func TestHTTPClientToken(t *testing.T) {
+	authorization := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	require.Equal(t, http.DefaultClient, newHTTPClient(&Context{}))
+
+	r, err := newHTTPClient(&Context{Token: "secret"}).Get(server.URL)
+	require.NoError(t, err)
+	r.Body.Close()
+	require.Equal(t, "Bearer secret", authorization)
+}
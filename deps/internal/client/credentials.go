@@ -0,0 +1,250 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// DefaultContextName is used to store credentials when no context is in use.
+const DefaultContextName = "default"
+
+// keychainService names the entries deps adds to the system keychain.
+const keychainService = "deps.cloud"
+
+// Credentials authenticate the command line with a deployment. API tokens
+// only have an access token. Tokens from an OIDC provider also carry what's
+// needed to refresh them once they expire.
+type Credentials struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+	TokenURL     string    `json:"token_url,omitempty"`
+	ClientID     string    `json:"client_id,omitempty"`
+}
+
+// Expired returns whether the access token is expired, or about to be.
+func (c *Credentials) Expired() bool {
+	return !c.Expiry.IsZero() && time.Now().Add(30*time.Second).After(c.Expiry)
+}
+
+// CredentialStore holds credentials for each context.
+type CredentialStore interface {
+	// Name describes where credentials are kept.
+	Name() string
+	// Get returns the credentials of the context, or nil when there are none.
+	Get(context string) (*Credentials, error)
+	Set(context string, credentials *Credentials) error
+	Delete(context string) error
+}
+
+// DefaultCredentialStore keeps credentials in the system keychain where one
+// is available, and otherwise in a file beside the config file that only the
+// current user can read.
+func DefaultCredentialStore() CredentialStore {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err == nil {
+			return &keychainStore{}
+		}
+	case "linux":
+		if _, err := exec.LookPath("secret-tool"); err == nil && os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+			return &secretServiceStore{}
+		}
+	}
+
+	path := ConfigPath()
+	if path != "" {
+		path = filepath.Join(filepath.Dir(path), "credentials.yaml")
+	}
+	return &FileCredentialStore{Path: path}
+}
+
+// FileCredentialStore keeps credentials for every context in a single file.
+type FileCredentialStore struct {
+	Path string
+}
+
+func (s *FileCredentialStore) Name() string {
+	return s.Path
+}
+
+func (s *FileCredentialStore) load() (map[string]*Credentials, error) {
+	all := make(map[string]*Credentials)
+
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return all, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", s.Path, err)
+	}
+
+	return all, nil
+}
+
+func (s *FileCredentialStore) save(all map[string]*Credentials) error {
+	if s.Path == "" {
+		return fmt.Errorf("unable to determine where to store credentials, set %s", VariableConfig)
+	}
+
+	data, err := yaml.Marshal(all)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.Path, data, 0600)
+}
+
+func (s *FileCredentialStore) Get(context string) (*Credentials, error) {
+	if s.Path == "" {
+		return nil, nil
+	}
+
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return all[context], nil
+}
+
+func (s *FileCredentialStore) Set(context string, credentials *Credentials) error {
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	all[context] = credentials
+	return s.save(all)
+}
+
+func (s *FileCredentialStore) Delete(context string) error {
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := all[context]; !ok {
+		return nil
+	}
+
+	delete(all, context)
+	return s.save(all)
+}
+
+// runTool executes a keychain tool, returning its trimmed output.
+func runTool(stdin string, name string, args ...string) (string, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s: %s", name, message)
+		}
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func decodeCredentials(data string) (*Credentials, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	credentials := &Credentials{}
+	if err := json.Unmarshal([]byte(data), credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// keychainStore uses the macOS keychain.
+type keychainStore struct{}
+
+func (s *keychainStore) Name() string {
+	return "macOS keychain"
+}
+
+func (s *keychainStore) Get(context string) (*Credentials, error) {
+	data, err := runTool("", "security", "find-generic-password", "-s", keychainService, "-a", context, "-w")
+	if err != nil {
+		// the item not existing is indistinguishable from other failures
+		return nil, nil
+	}
+	return decodeCredentials(data)
+}
+
+func (s *keychainStore) Set(context string, credentials *Credentials) error {
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	_, err = runTool("", "security", "add-generic-password", "-U", "-s", keychainService, "-a", context, "-w", string(data))
+	return err
+}
+
+func (s *keychainStore) Delete(context string) error {
+	if existing, _ := s.Get(context); existing == nil {
+		return nil
+	}
+
+	_, err := runTool("", "security", "delete-generic-password", "-s", keychainService, "-a", context)
+	return err
+}
+
+// secretServiceStore uses the keyring of the desktop session, such as the
+// GNOME keyring, through libsecret.
+type secretServiceStore struct{}
+
+func (s *secretServiceStore) Name() string {
+	return "secret service keyring"
+}
+
+func (s *secretServiceStore) Get(context string) (*Credentials, error) {
+	data, err := runTool("", "secret-tool", "lookup", "service", keychainService, "context", context)
+	if err != nil {
+		return nil, nil
+	}
+	return decodeCredentials(data)
+}
+
+func (s *secretServiceStore) Set(context string, credentials *Credentials) error {
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	// the secret is read from stdin so it never appears in the process list
+	_, err = runTool(string(data), "secret-tool", "store",
+		"--label", fmt.Sprintf("deps.cloud (%s)", context),
+		"service", keychainService, "context", context)
+	return err
+}
+
+func (s *secretServiceStore) Delete(context string) error {
+	_, err := runTool("", "secret-tool", "clear", "service", keychainService, "context", context)
+	return err
+}
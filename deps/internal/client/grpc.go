@@ -4,8 +4,11 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/depscloud/api/v1alpha/extractor"
 	"github.com/depscloud/api/v1alpha/tracker"
 	"github.com/depscloud/depscloud/internal/client"
+
+	"google.golang.org/grpc"
 )
 
 func translateBaseURL(baseURL string) (bool, string) {
@@ -28,16 +31,25 @@ func translateBaseURL(baseURL string) (bool, string) {
 	return tls, host
 }
 
-func grpcDefaultClient(baseURL string) Client {
-	isSecure, hostPort := translateBaseURL(baseURL)
+func grpcDefaultClient(ctx *Context) Client {
+	isSecure, hostPort := translateBaseURL(ctx.BaseURL)
+
+	options := make([]grpc.DialOption, 0, 1)
+	if ctx.Token != "" {
+		options = append(options, grpc.WithPerRPCCredentials(tokenCredentials(ctx.Token)))
+	}
 
 	conn, err := client.Connect(&client.Config{
 		Address:       hostPort,
 		ServiceConfig: client.DefaultServiceConfig,
 		LoadBalancer:  client.DefaultLoadBalancer,
 		TLS:           isSecure,
-		TLSConfig:     &client.TLSConfig{},
-	})
+		TLSConfig: &client.TLSConfig{
+			CAPath:   ctx.CAPath,
+			CertPath: ctx.CertPath,
+			KeyPath:  ctx.KeyPath,
+		},
+	}, options...)
 	if err != nil {
 		panic(err)
 	}
@@ -47,5 +59,8 @@ func grpcDefaultClient(baseURL string) Client {
 		modules:      tracker.NewModuleServiceClient(conn),
 		sources:      tracker.NewSourceServiceClient(conn),
 		search:       tracker.NewSearchServiceClient(conn),
+		extractor:    extractor.NewDependencyExtractorClient(conn),
+		textSearch:   &httpTextSearchClient{newHTTPClient(ctx), ctx.BaseURL},
+		labels:       &httpLabelClient{newHTTPClient(ctx), ctx.BaseURL},
 	}
 }
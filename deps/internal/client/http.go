@@ -1,14 +1,15 @@
 package client
 
-import "net/http"
-
 func httpDefaltClient(baseURL string) Client {
-	client := http.DefaultClient
+	client := HTTPClient()
 
 	return &httpClient{
 		dependencies: &httpDependencyService{client, baseURL},
 		modules:      &httpModuleClient{client, baseURL},
 		sources:      &httpSourceClient{client, baseURL},
 		search:       nil,
+		extractor:    &httpExtractorClient{client, baseURL},
+		textSearch:   &httpTextSearchClient{client, baseURL},
+		labels:       &httpLabelClient{client, baseURL},
 	}
 }
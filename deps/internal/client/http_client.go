@@ -1,12 +1,18 @@
 package client
 
-import "github.com/depscloud/api/v1alpha/tracker"
+import (
+	"github.com/depscloud/api/v1alpha/extractor"
+	"github.com/depscloud/api/v1alpha/tracker"
+)
 
 type httpClient struct {
 	dependencies tracker.DependencyServiceClient
 	modules      tracker.ModuleServiceClient
 	sources      tracker.SourceServiceClient
 	search       tracker.SearchServiceClient
+	extractor    extractor.DependencyExtractorClient
+	textSearch   TextSearchClient
+	labels       LabelClient
 }
 
 func (c *httpClient) Dependencies() tracker.DependencyServiceClient {
@@ -25,4 +31,16 @@ func (c *httpClient) Search() tracker.SearchServiceClient {
 	return c.search
 }
 
+func (c *httpClient) Extractor() extractor.DependencyExtractorClient {
+	return c.extractor
+}
+
+func (c *httpClient) TextSearch() TextSearchClient {
+	return c.textSearch
+}
+
+func (c *httpClient) Labels() LabelClient {
+	return c.labels
+}
+
 var _ Client = &httpClient{}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/depscloud/api/v1alpha/extractor"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+
+	"google.golang.org/grpc"
+)
+
+type httpExtractorClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (e *httpExtractorClient) post(action string, in interface{}, out proto.Message) error {
+	uri := fmt.Sprintf("%s/v1alpha/dependencies/%s",
+		e.baseURL,
+		action)
+
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	r, err := e.client.Post(uri, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	return jsonpb.Unmarshal(r.Body, out)
+}
+
+func (e *httpExtractorClient) Match(ctx context.Context, in *extractor.MatchRequest, opts ...grpc.CallOption) (*extractor.MatchResponse, error) {
+	resp := &extractor.MatchResponse{}
+	if err := e.post("match", in, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (e *httpExtractorClient) Extract(ctx context.Context, in *extractor.ExtractRequest, opts ...grpc.CallOption) (*extractor.ExtractResponse, error) {
+	resp := &extractor.ExtractResponse{}
+	if err := e.post("extract", in, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+var _ extractor.DependencyExtractorClient = &httpExtractorClient{}
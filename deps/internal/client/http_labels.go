@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/depscloud/api/v1alpha/schema"
+)
+
+// LabelClient reads the labels of modules, such as the team that owns them or
+// their license. Labels are only available over http, whichever protocol is
+// used for the rest of the api.
+type LabelClient interface {
+	ModuleLabels(ctx context.Context, module *schema.Module) (map[string]string, error)
+}
+
+type httpLabelClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (l *httpLabelClient) ModuleLabels(ctx context.Context, module *schema.Module) (map[string]string, error) {
+	uri := fmt.Sprintf("%s/v1alpha/labels/modules?language=%s&organization=%s&module=%s",
+		l.baseURL,
+		url.QueryEscape(module.Language),
+		url.QueryEscape(module.Organization),
+		url.QueryEscape(module.Module))
+
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := l.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s reading the labels of %s", r.Status, module.Module)
+	}
+
+	response := struct {
+		Labels map[string]string `json:"labels"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	return response.Labels, nil
+}
+
+var _ LabelClient = &httpLabelClient{}
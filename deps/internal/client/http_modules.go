@@ -12,6 +12,7 @@ import (
 	"github.com/gogo/protobuf/jsonpb"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type httpModuleClient struct {
@@ -25,7 +26,20 @@ func (m *httpModuleClient) List(ctx context.Context, in *tracker.ListRequest, op
 		in.Page,
 		in.Count)
 
-	r, err := m.client.Get(uri)
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// filters are passed as metadata, which the gateway reads from headers
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			req.Header.Add("Grpc-Metadata-"+key, value)
+		}
+	}
+
+	r, err := m.client.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+)
+
+// Text search modes, from strictest to loosest.
+const (
+	MatchPrefix    = "prefix"
+	MatchSubstring = "substring"
+	MatchFuzzy     = "fuzzy"
+)
+
+// TextSearchRequest searches the names of modules, or the urls of sources,
+// along with their labels.
+type TextSearchRequest struct {
+	Query string
+	// Type is module, the default, or source.
+	Type string
+	// Mode is one of MatchPrefix, MatchSubstring, the default, or MatchFuzzy.
+	Mode  string
+	Limit int
+}
+
+// TextSearchResult is a module or source matching a text search. Field names
+// what matched and Score ranks the match, lower is better.
+type TextSearchResult struct {
+	Type         string
+	Module       *schema.Module
+	Source       *schema.Source
+	Labels       map[string]string
+	Field        string
+	Score        int
+	Dependents   int64
+	LastObserved *time.Time
+}
+
+// TextSearchClient finds modules and sources by name. Text search is only
+// available over http, whichever protocol is used for the rest of the api.
+type TextSearchClient interface {
+	Search(ctx context.Context, req *TextSearchRequest) ([]*TextSearchResult, error)
+}
+
+type httpTextSearchClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (s *httpTextSearchClient) Search(ctx context.Context, in *TextSearchRequest) ([]*TextSearchResult, error) {
+	query := url.Values{}
+	query.Set("q", in.Query)
+	if in.Type != "" {
+		query.Set("type", in.Type)
+	}
+	if in.Mode != "" {
+		query.Set("mode", in.Mode)
+	}
+	if in.Limit > 0 {
+		query.Set("limit", fmt.Sprintf("%d", in.Limit))
+	}
+
+	uri := fmt.Sprintf("%s/v1alpha/queries/search?%s", s.baseURL, query.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	response := struct {
+		Error   string `json:"error"`
+		Results []struct {
+			Type         string            `json:"type"`
+			Data         json.RawMessage   `json:"data"`
+			Labels       map[string]string `json:"labels"`
+			Field        string            `json:"field"`
+			Score        int               `json:"score"`
+			Dependents   int64             `json:"dependents"`
+			LastObserved *time.Time        `json:"last_observed"`
+		} `json:"results"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("unexpected status %s", r.Status)
+	}
+
+	if r.StatusCode != http.StatusOK {
+		if response.Error != "" {
+			return nil, fmt.Errorf("%s", response.Error)
+		}
+		return nil, fmt.Errorf("unexpected status %s", r.Status)
+	}
+
+	results := make([]*TextSearchResult, 0, len(response.Results))
+	for _, item := range response.Results {
+		result := &TextSearchResult{
+			Type:         item.Type,
+			Labels:       item.Labels,
+			Field:        item.Field,
+			Score:        item.Score,
+			Dependents:   item.Dependents,
+			LastObserved: item.LastObserved,
+		}
+
+		var data interface{}
+		if item.Type == "source" {
+			result.Source = &schema.Source{}
+			data = result.Source
+		} else {
+			result.Module = &schema.Module{}
+			data = result.Module
+		}
+
+		if err := json.Unmarshal(item.Data, data); err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+var _ TextSearchClient = &httpTextSearchClient{}
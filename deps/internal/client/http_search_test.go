@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"q is required"}`))
+			return
+		}
+
+		require.Equal(t, "/v1alpha/queries/search", r.URL.Path)
+		require.Equal(t, "fuzzy", r.URL.Query().Get("mode"))
+
+		_, _ = w.Write([]byte(`{"results":[
+			{"type":"module","data":{"language":"go","name":"github.com/depscloud/api"},"field":"name","score":1,"dependents":3},
+			{"type":"source","data":{"url":"https://github.com/depscloud/api.git"},"field":"url","score":2}
+		]}`))
+	}))
+	defer server.Close()
+
+	search := &httpTextSearchClient{client: server.Client(), baseURL: server.URL}
+
+	results, err := search.Search(context.Background(), &TextSearchRequest{Query: "api", Mode: MatchFuzzy})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "github.com/depscloud/api", results[0].Module.Name)
+	require.Equal(t, int64(3), results[0].Dependents)
+	require.Equal(t, "https://github.com/depscloud/api.git", results[1].Source.Url)
+
+	_, err = search.Search(context.Background(), &TextSearchRequest{})
+	require.EqualError(t, err, "q is required")
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const grantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+// DeviceAuthorization is what the user needs to approve the login from a
+// browser, on this or another device.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceFlow logs in with an OIDC provider using the device authorization
+// grant (RFC 8628), which doesn't need a browser on the machine running deps.
+type DeviceFlow struct {
+	Issuer   string
+	ClientID string
+	Scopes   []string
+	Client   *http.Client
+
+	tokenURL string
+	wait     func(time.Duration)
+}
+
+type discovery struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (f *DeviceFlow) client() *http.Client {
+	if f.Client == nil {
+		return http.DefaultClient
+	}
+	return f.Client
+}
+
+func (f *DeviceFlow) postForm(ctx context.Context, endpoint string, form url.Values, into interface{}) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		return resp.StatusCode, fmt.Errorf("unexpected response from %s: %v", endpoint, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// Start discovers the endpoints of the issuer and requests a code for the
+// user to approve.
+func (f *DeviceFlow) Start(ctx context.Context) (*DeviceAuthorization, error) {
+	endpoint := strings.TrimSuffix(f.Issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to discover %s: %s", f.Issuer, resp.Status)
+	}
+
+	endpoints := &discovery{}
+	if err := json.NewDecoder(resp.Body).Decode(endpoints); err != nil {
+		return nil, fmt.Errorf("failed to discover %s: %v", f.Issuer, err)
+	}
+
+	if endpoints.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("%s does not support the device authorization grant", f.Issuer)
+	}
+	f.tokenURL = endpoints.TokenEndpoint
+
+	authorization := &DeviceAuthorization{}
+	status, err := f.postForm(ctx, endpoints.DeviceAuthorizationEndpoint, url.Values{
+		"client_id": {f.ClientID},
+		"scope":     {strings.Join(f.Scopes, " ")},
+	}, authorization)
+	if err != nil {
+		return nil, err
+	} else if status != http.StatusOK || authorization.DeviceCode == "" {
+		return nil, fmt.Errorf("failed to start device authorization: %d", status)
+	}
+
+	return authorization, nil
+}
+
+// Wait polls the issuer until the user approves or denies the login, or the
+// code expires.
+func (f *DeviceFlow) Wait(ctx context.Context, authorization *DeviceAuthorization) (*Credentials, error) {
+	wait := f.wait
+	if wait == nil {
+		wait = time.Sleep
+	}
+
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	deadline := time.Now().Add(time.Duration(authorization.ExpiresIn) * time.Second)
+
+	for authorization.ExpiresIn <= 0 || time.Now().Before(deadline) {
+		wait(interval)
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		token := &tokenResponse{}
+		_, err := f.postForm(ctx, f.tokenURL, url.Values{
+			"grant_type":  {grantTypeDeviceCode},
+			"device_code": {authorization.DeviceCode},
+			"client_id":   {f.ClientID},
+		}, token)
+		if err != nil {
+			return nil, err
+		}
+
+		switch token.Error {
+		case "":
+			credentials := &Credentials{
+				AccessToken:  token.AccessToken,
+				RefreshToken: token.RefreshToken,
+				TokenURL:     f.tokenURL,
+				ClientID:     f.ClientID,
+			}
+			if token.ExpiresIn > 0 {
+				credentials.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+			}
+			return credentials, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return nil, fmt.Errorf("login was denied")
+		case "expired_token":
+			return nil, fmt.Errorf("login code expired, please try again")
+		default:
+			if token.ErrorDescription != "" {
+				return nil, fmt.Errorf("%s: %s", token.Error, token.ErrorDescription)
+			}
+			return nil, fmt.Errorf("%s", token.Error)
+		}
+	}
+
+	return nil, fmt.Errorf("login code expired, please try again")
+}
+
+// Refresh exchanges the refresh token for a new access token.
+func Refresh(ctx context.Context, credentials *Credentials) (*Credentials, error) {
+	if credentials.RefreshToken == "" || credentials.TokenURL == "" {
+		return nil, fmt.Errorf("token expired, please run deps login")
+	}
+
+	config := &oauth2.Config{
+		ClientID: credentials.ClientID,
+		Endpoint: oauth2.Endpoint{
+			TokenURL:  credentials.TokenURL,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+
+	token, err := config.TokenSource(ctx, &oauth2.Token{
+		RefreshToken: credentials.RefreshToken,
+		Expiry:       time.Unix(1, 0),
+	}).Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token, please run deps login: %v", err)
+	}
+
+	refreshed := *credentials
+	refreshed.AccessToken = token.AccessToken
+	refreshed.Expiry = token.Expiry
+	if token.RefreshToken != "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+
+	return &refreshed, nil
+}
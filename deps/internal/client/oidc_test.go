@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func oidcServer(t *testing.T, pending int) *httptest.Server {
+	var server *httptest.Server
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"device_authorization_endpoint": server.URL + "/device",
+			"token_endpoint":                server.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "deps-cli", r.FormValue("client_id"))
+		require.Equal(t, "openid offline_access", r.FormValue("scope"))
+
+		_, _ = w.Write([]byte(`{"device_code":"device","user_code":"ABCD-EFGH","verification_uri":"https://example.com/device","expires_in":600,"interval":1}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("grant_type") {
+		case grantTypeDeviceCode:
+			require.Equal(t, "device", r.FormValue("device_code"))
+
+			if pending > 0 {
+				pending--
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"access_token":"access","refresh_token":"refresh","expires_in":3600,"token_type":"Bearer"}`))
+		case "refresh_token":
+			require.Equal(t, "refresh", r.FormValue("refresh_token"))
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"refreshed","expires_in":3600,"token_type":"Bearer"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"unsupported_grant_type"}`))
+		}
+	})
+
+	server = httptest.NewServer(mux)
+	return server
+}
+
+func TestDeviceFlow(t *testing.T) {
+	server := oidcServer(t, 2)
+	defer server.Close()
+
+	waited := make([]time.Duration, 0)
+	flow := &DeviceFlow{
+		Issuer:   server.URL,
+		ClientID: "deps-cli",
+		Scopes:   []string{"openid", "offline_access"},
+		wait: func(interval time.Duration) {
+			waited = append(waited, interval)
+		},
+	}
+
+	ctx := context.Background()
+
+	authorization, err := flow.Start(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "ABCD-EFGH", authorization.UserCode)
+
+	credentials, err := flow.Wait(ctx, authorization)
+	require.NoError(t, err)
+	require.Len(t, waited, 3)
+	require.Equal(t, time.Second, waited[0])
+	require.Equal(t, "access", credentials.AccessToken)
+	require.Equal(t, "refresh", credentials.RefreshToken)
+	require.Equal(t, server.URL+"/token", credentials.TokenURL)
+	require.False(t, credentials.Expired())
+
+	credentials.Expiry = time.Now().Add(-time.Minute)
+	require.True(t, credentials.Expired())
+
+	refreshed, err := Refresh(ctx, credentials)
+	require.NoError(t, err)
+	require.Equal(t, "refreshed", refreshed.AccessToken)
+	require.Equal(t, "refresh", refreshed.RefreshToken)
+	require.False(t, refreshed.Expired())
+
+	_, err = Refresh(ctx, &Credentials{AccessToken: "token"})
+	require.EqualError(t, err, "token expired, please run deps login")
+}
+
+func TestFileCredentialStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deps")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &FileCredentialStore{Path: filepath.Join(dir, "credentials.yaml")}
+
+	credentials, err := store.Get("staging")
+	require.NoError(t, err)
+	require.Nil(t, credentials)
+
+	require.NoError(t, store.Set("staging", &Credentials{AccessToken: "token"}))
+	require.NoError(t, store.Set("production", &Credentials{AccessToken: "other"}))
+
+	info, err := os.Stat(store.Path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	credentials, err = store.Get("staging")
+	require.NoError(t, err)
+	require.Equal(t, &Credentials{AccessToken: "token"}, credentials)
+
+	require.NoError(t, store.Delete("staging"))
+	require.NoError(t, store.Delete("staging"))
+
+	credentials, err = store.Get("staging")
+	require.NoError(t, err)
+	require.Nil(t, credentials)
+
+	credentials, err = store.Get("production")
+	require.NoError(t, err)
+	require.Equal(t, "other", credentials.AccessToken)
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/depscloud/depscloud/internal/client"
+
+	"github.com/sirupsen/logrus"
+)
+
+// tokenCredentials passes the token of the current context on every call.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	// deployments commonly terminate tls in front of the api
+	return false
+}
+
+// tokenTransport adds the token of the current context to http requests.
+type tokenTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+var authenticateOnce sync.Once
+
+// authenticate fills in the token of the current context from the credentials
+// stored by deps login, refreshing them when they've expired. A token from the
+// environment or config file takes precedence.
+func authenticate() {
+	authenticateOnce.Do(func() {
+		if current.Token != "" {
+			return
+		}
+
+		store := DefaultCredentialStore()
+		name := or(current.Name, DefaultContextName)
+
+		credentials, err := store.Get(name)
+		if err != nil {
+			logrus.Warnf("failed to load credentials: %v", err)
+			return
+		} else if credentials == nil {
+			return
+		}
+
+		if credentials.Expired() {
+			refreshed, err := Refresh(context.Background(), credentials)
+			if err != nil {
+				logrus.Warn(err)
+				return
+			}
+
+			if err := store.Set(name, refreshed); err != nil {
+				logrus.Warnf("failed to store credentials: %v", err)
+			}
+			credentials = refreshed
+		}
+
+		current.Token = credentials.AccessToken
+	})
+}
+
+// HTTPClient returns a client for calling the http api of the current
+// context, passing its token and trusting its certificates.
+func HTTPClient() *http.Client {
+	authenticate()
+	return newHTTPClient(current)
+}
+
+func newHTTPClient(ctx *Context) *http.Client {
+	if ctx.Token == "" && ctx.CAPath == "" && ctx.CertPath == "" {
+		return http.DefaultClient
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if ctx.CAPath != "" || ctx.CertPath != "" {
+		tlsConfig, err := client.LoadTLSConfig(&client.TLSConfig{
+			CAPath:   ctx.CAPath,
+			CertPath: ctx.CertPath,
+			KeyPath:  ctx.KeyPath,
+		})
+		if err != nil {
+			logrus.Warnf("failed to load tls config: %v", err)
+		} else {
+			transport.TLSClientConfig = tlsConfig
+		}
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if ctx.Token != "" {
+		roundTripper = &tokenTransport{token: ctx.Token, next: transport}
+	}
+
+	return &http.Client{Transport: roundTripper}
+}
@@ -0,0 +1,190 @@
+package browse
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/client"
+	"github.com/depscloud/depscloud/deps/internal/cmds/get"
+
+	"github.com/spf13/cobra"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// listModules pages through every module known to the graph.
+func listModules(modulesClient tracker.ModuleServiceClient) func(ctx context.Context) ([]*schema.Module, error) {
+	return func(ctx context.Context) ([]*schema.Module, error) {
+		pageSize := 100
+		modules := make([]*schema.Module, 0)
+
+		for i := 1; true; i++ {
+			response, err := modulesClient.List(ctx, &tracker.ListRequest{
+				Page:  int32(i),
+				Count: int32(pageSize),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			modules = append(modules, response.Modules...)
+
+			if len(response.Modules) < pageSize {
+				break
+			}
+		}
+
+		return modules, nil
+	}
+}
+
+// readKey reads a single key press from a terminal in raw mode, translating
+// the escape sequences of the arrow keys.
+func readKey(reader *bufio.Reader) (key, rune, error) {
+	r, _, err := reader.ReadRune()
+	if err != nil {
+		return keyQuit, 0, err
+	}
+
+	switch r {
+	case 3, 4: // ctrl+c, ctrl+d
+		return keyQuit, r, nil
+	case '\r', '\n':
+		return keyEnter, r, nil
+	case '\t':
+		return keyTab, r, nil
+	case 127, 8: // backspace
+		return keyBack, r, nil
+	case 27:
+		// a lone escape has nothing buffered after it
+		if reader.Buffered() == 0 {
+			return keyEscape, r, nil
+		}
+
+		if next, _, err := reader.ReadRune(); err != nil || next != '[' {
+			return keyEscape, r, err
+		}
+
+		code, _, err := reader.ReadRune()
+		if err != nil {
+			return keyEscape, r, err
+		}
+
+		switch code {
+		case 'A':
+			return keyUp, r, nil
+		case 'B':
+			return keyDown, r, nil
+		case 'C':
+			return keyEnter, r, nil
+		case 'D':
+			return keyBack, r, nil
+		}
+		return keyEscape, r, nil
+	}
+
+	return keyRune, r, nil
+}
+
+// draw replaces the screen with the view. Raw mode doesn't translate newlines,
+// so each line returns the carriage itself.
+func draw(out io.Writer, b *browser) error {
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	view := strings.ReplaceAll(b.view(width, height), "\n", "\r\n")
+	_, err = fmt.Fprint(out, "\x1b[H\x1b[2J"+view)
+	return err
+}
+
+func Command(
+	client client.Client,
+) *cobra.Command {
+	module := &schema.Module{}
+	dependents := false
+
+	cmd := &cobra.Command{
+		Use:   "browse",
+		Short: "Interactively browse the dependents and dependencies of modules",
+		Example: strings.Join([]string{
+			"deps browse",
+			"deps browse -l go -n github.com/depscloud/api",
+			"deps browse -l go -n github.com/depscloud/api --dependents",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stdin := int(os.Stdin.Fd())
+			if !terminal.IsTerminal(stdin) {
+				return fmt.Errorf("browse requires an interactive terminal")
+			}
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			b := &browser{
+				dependencies: client.Dependencies(),
+				listModules:  listModules(client.Modules()),
+				dependents:   dependents,
+			}
+
+			// without a module to start from, browsing starts with a search
+			if module.Language != "" && (module.Name != "" || module.Module != "") {
+				b.open(ctx, get.SetModuleFields(module))
+			} else {
+				b.search(ctx)
+			}
+
+			if b.err != nil {
+				return b.err
+			}
+
+			state, err := terminal.MakeRaw(stdin)
+			if err != nil {
+				return err
+			}
+			defer terminal.Restore(stdin, state)
+
+			out := cmd.OutOrStdout()
+
+			// switch to the alternate screen, restoring the original on exit
+			fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+			defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+			reader := bufio.NewReader(os.Stdin)
+			for {
+				if err := draw(out, b); err != nil {
+					return err
+				}
+
+				k, r, err := readKey(reader)
+				if err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+
+				if b.update(ctx, k, r) {
+					return nil
+				}
+			}
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&(module.Language), "language", "l", module.Language, "The language of the module to start from")
+	flags.StringVarP(&(module.Organization), "organization", "o", module.Organization, "The organization of the module to start from")
+	flags.StringVarP(&(module.Module), "module", "m", module.Module, "The name of the module to start from")
+	flags.StringVarP(&(module.Name), "name", "n", module.Name, "The name of the module to start from")
+	flags.BoolVar(&dependents, "dependents", dependents, "Start by browsing dependents instead of dependencies")
+
+	return cmd
+}
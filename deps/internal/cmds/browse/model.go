@@ -0,0 +1,317 @@
+package browse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+)
+
+// key is a key press the browser responds to.
+type key int
+
+const (
+	keyRune key = iota
+	keyUp
+	keyDown
+	keyEnter
+	keyBack
+	keyTab
+	keyEscape
+	keyQuit
+)
+
+// frame is a module the user browsed into, remembering where the cursor was
+// so that going back returns to the same place.
+type frame struct {
+	module     *schema.Module
+	dependents bool
+	items      []*tracker.Dependency
+	cursor     int
+}
+
+// browser holds the state of the interactive browser. Key presses update it
+// and view renders it, keeping it independent of the terminal.
+type browser struct {
+	dependencies tracker.DependencyServiceClient
+	listModules  func(ctx context.Context) ([]*schema.Module, error)
+
+	dependents bool
+	stack      []*frame
+
+	searching bool
+	query     string
+	modules   []*schema.Module
+	matches   []*schema.Module
+	cursor    int
+
+	err error
+}
+
+func (b *browser) current() *frame {
+	if len(b.stack) == 0 {
+		return nil
+	}
+	return b.stack[len(b.stack)-1]
+}
+
+func (b *browser) load(ctx context.Context, f *frame) error {
+	req := &tracker.DependencyRequest{
+		Language:     f.module.Language,
+		Organization: f.module.Organization,
+		Module:       f.module.Module,
+		Name:         f.module.Name,
+	}
+
+	if b.dependents {
+		response, err := b.dependencies.ListDependents(ctx, req)
+		if err != nil {
+			return err
+		}
+		f.items = response.GetDependents()
+	} else {
+		response, err := b.dependencies.ListDependencies(ctx, req)
+		if err != nil {
+			return err
+		}
+		f.items = response.GetDependencies()
+	}
+
+	if f.dependents != b.dependents {
+		f.dependents = b.dependents
+		f.cursor = 0
+	}
+	return nil
+}
+
+// open browses into the module.
+func (b *browser) open(ctx context.Context, module *schema.Module) {
+	f := &frame{module: module, dependents: b.dependents}
+	if b.err = b.load(ctx, f); b.err == nil {
+		b.stack = append(b.stack, f)
+		b.searching = false
+	}
+}
+
+// search starts searching for a module, loading the modules to search
+// through the first time.
+func (b *browser) search(ctx context.Context) {
+	if b.modules == nil {
+		modules, err := b.listModules(ctx)
+		if err != nil {
+			b.err = err
+			return
+		}
+		b.modules = modules
+	}
+
+	b.searching = true
+	b.query = ""
+	b.filter()
+}
+
+func (b *browser) filter() {
+	query := strings.ToLower(b.query)
+
+	b.matches = make([]*schema.Module, 0)
+	for _, module := range b.modules {
+		if strings.Contains(strings.ToLower(diagram.Name(module)), query) {
+			b.matches = append(b.matches, module)
+		}
+	}
+	b.cursor = 0
+}
+
+func move(cursor, delta, length int) int {
+	cursor += delta
+	if cursor >= length {
+		cursor = length - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	return cursor
+}
+
+// update applies the key press, returning whether the browser should quit.
+func (b *browser) update(ctx context.Context, k key, r rune) bool {
+	b.err = nil
+
+	if b.searching {
+		switch k {
+		case keyQuit:
+			return true
+		case keyRune:
+			b.query += string(r)
+			b.filter()
+		case keyBack:
+			if len(b.query) > 0 {
+				runes := []rune(b.query)
+				b.query = string(runes[:len(runes)-1])
+				b.filter()
+			}
+		case keyUp:
+			b.cursor = move(b.cursor, -1, len(b.matches))
+		case keyDown:
+			b.cursor = move(b.cursor, 1, len(b.matches))
+		case keyEnter:
+			if len(b.matches) > 0 {
+				b.open(ctx, b.matches[b.cursor])
+			}
+		case keyEscape:
+			// there's nothing to go back to before the first module
+			if len(b.stack) > 0 {
+				b.searching = false
+			}
+		}
+		return false
+	}
+
+	f := b.current()
+
+	switch k {
+	case keyQuit:
+		return true
+	case keyRune:
+		switch r {
+		case 'q':
+			return true
+		case '/':
+			b.search(ctx)
+		case 'k':
+			f.cursor = move(f.cursor, -1, len(f.items))
+		case 'j':
+			f.cursor = move(f.cursor, 1, len(f.items))
+		}
+	case keyUp:
+		f.cursor = move(f.cursor, -1, len(f.items))
+	case keyDown:
+		f.cursor = move(f.cursor, 1, len(f.items))
+	case keyEnter:
+		if len(f.items) > 0 {
+			b.open(ctx, f.items[f.cursor].GetModule())
+		}
+	case keyBack, keyEscape:
+		if len(b.stack) > 1 {
+			b.stack = b.stack[:len(b.stack)-1]
+
+			// modules are reloaded when the direction changed since they
+			// were browsed
+			if f := b.current(); f.dependents != b.dependents {
+				b.err = b.load(ctx, f)
+			}
+		}
+	case keyTab:
+		b.dependents = !b.dependents
+		if b.err = b.load(ctx, f); b.err != nil {
+			b.dependents = !b.dependents
+		}
+	}
+
+	return false
+}
+
+// window returns the range of items to show so the cursor stays visible.
+func window(cursor, length, height int) (int, int) {
+	if height < 1 {
+		height = 1
+	}
+
+	start := 0
+	if cursor >= height {
+		start = cursor - height + 1
+	}
+
+	end := start + height
+	if end > length {
+		end = length
+	}
+	return start, end
+}
+
+func (b *browser) view(width, height int) string {
+	lines := make([]string, 0, height)
+
+	var items []string
+	cursor := 0
+
+	if b.searching {
+		lines = append(lines, "Search modules: "+b.query+"_", "")
+		for _, module := range b.matches {
+			items = append(items, module.Language+"  "+diagram.Name(module))
+		}
+		cursor = b.cursor
+
+		if len(items) == 0 {
+			items = append(items, "(no matching modules)")
+		}
+	} else if f := b.current(); f != nil {
+		direction, other := "dependencies", "dependents"
+		if b.dependents {
+			direction, other = other, direction
+		}
+
+		path := make([]string, 0, len(b.stack))
+		for _, f := range b.stack {
+			path = append(path, diagram.Name(f.module))
+		}
+
+		lines = append(lines,
+			fmt.Sprintf("%s of %s:%s (%d)", direction, f.module.Language, diagram.Name(f.module), len(f.items)),
+			strings.Join(path, " > "))
+
+		for _, item := range f.items {
+			line := diagram.Name(item.GetModule())
+			if constraint := item.GetDepends().GetVersionConstraint(); constraint != "" {
+				line += "@" + constraint
+			}
+			if scopes := item.GetDepends().GetScopes(); len(scopes) > 0 {
+				line += "  [" + strings.Join(scopes, ",") + "]"
+			}
+			items = append(items, line)
+		}
+		cursor = f.cursor
+
+		if len(items) == 0 {
+			items = append(items, "(no "+direction+")")
+		}
+
+		lines[0] += "  tab: " + other
+	}
+
+	start, end := window(cursor, len(items), height-4)
+	for i := start; i < end; i++ {
+		prefix := "  "
+		if i == cursor {
+			prefix = "> "
+		}
+		lines = append(lines, prefix+items[i])
+	}
+
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+
+	if b.err != nil {
+		lines = append(lines, "error: "+b.err.Error())
+	} else {
+		lines = append(lines, "")
+	}
+
+	if b.searching {
+		lines = append(lines, "type to search  ↑/↓ move  enter open  esc cancel  ctrl+c quit")
+	} else {
+		lines = append(lines, "↑/↓ move  enter open  ← back  tab switch  / search  q quit")
+	}
+
+	for i, line := range lines {
+		if width > 0 && len([]rune(line)) > width {
+			lines[i] = string([]rune(line)[:width])
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,90 @@
+package browse
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/fakes"
+
+	"github.com/stretchr/testify/require"
+)
+
+func module(name string) *schema.Module {
+	return &schema.Module{Language: "go", Name: name}
+}
+
+func TestBrowser(t *testing.T) {
+	ctx := context.Background()
+
+	b := &browser{
+		dependencies: &fakes.Dependencies{
+			Dependencies: map[string][]*tracker.Dependency{
+				"github.com/a/app": {fakes.Edge("go", "github.com/a/lib", "v1.0.0"), fakes.Edge("go", "github.com/a/util", "v0.1.0")},
+				"github.com/a/lib": {fakes.Edge("go", "github.com/a/util", "v0.2.0")},
+			},
+			Dependents: map[string][]*tracker.Dependency{
+				"github.com/a/lib": {fakes.Edge("go", "github.com/a/app", "v1.0.0"), fakes.Edge("go", "github.com/b/app", "v1.1.0")},
+			},
+		},
+		listModules: func(ctx context.Context) ([]*schema.Module, error) {
+			return []*schema.Module{module("github.com/a/app"), module("github.com/a/lib"), module("github.com/b/app")}, nil
+		},
+	}
+
+	// browsing starts by searching for a module
+	b.search(ctx)
+	for _, r := range "a/app" {
+		require.False(t, b.update(ctx, keyRune, r))
+	}
+	require.Len(t, b.matches, 1)
+	require.Contains(t, b.view(80, 10), "> go  github.com/a/app")
+
+	b.update(ctx, keyBack, 0)
+	b.update(ctx, keyBack, 0)
+	b.update(ctx, keyBack, 0)
+	require.Equal(t, "a/", b.query)
+	require.Len(t, b.matches, 2)
+
+	b.update(ctx, keyEnter, 0)
+	require.False(t, b.searching)
+	require.Equal(t, "github.com/a/app", b.current().module.Name)
+
+	// drill into the second dependency and back out again
+	b.update(ctx, keyDown, 0)
+	b.update(ctx, keyDown, 0)
+	require.Equal(t, 1, b.current().cursor)
+	b.update(ctx, keyUp, 0)
+	b.update(ctx, keyEnter, 0)
+	require.Equal(t, "github.com/a/lib", b.current().module.Name)
+
+	view := b.view(80, 10)
+	require.True(t, strings.HasPrefix(view, "dependencies of go:github.com/a/lib (1)"))
+	require.Contains(t, view, "github.com/a/app > github.com/a/lib")
+	require.Contains(t, view, "> github.com/a/util@v0.2.0")
+
+	// switching to dependents reloads the module
+	b.update(ctx, keyTab, 0)
+	require.Len(t, b.current().items, 2)
+	require.Contains(t, b.view(80, 10), "dependents of go:github.com/a/lib (2)")
+
+	b.update(ctx, keyBack, 0)
+	require.Len(t, b.stack, 1)
+	require.True(t, b.current().dependents)
+	require.Empty(t, b.current().items)
+	require.Contains(t, b.view(80, 10), "(no dependents)")
+
+	require.True(t, b.update(ctx, keyRune, 'q'))
+}
+
+func TestWindow(t *testing.T) {
+	start, end := window(0, 3, 5)
+	require.Equal(t, 0, start)
+	require.Equal(t, 3, end)
+
+	start, end = window(7, 10, 5)
+	require.Equal(t, 3, start)
+	require.Equal(t, 8, end)
+}
@@ -0,0 +1,257 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/client"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+
+	"github.com/spf13/cobra"
+)
+
+// ExitViolations is the exit code used when the source violates the policy,
+// distinguishing it from failing to check the source at all.
+const ExitViolations = 2
+
+// The rules a dependency can violate.
+const (
+	RuleBanned   = "banned"
+	RuleMaxDepth = "max_depth"
+	RuleLicense  = "license"
+)
+
+// Violation is a dependency breaking a rule of the policy. Path leads from a
+// module managed by the source to the dependency.
+type Violation struct {
+	Rule    string   `json:"rule"`
+	Module  string   `json:"module"`
+	Path    []string `json:"path"`
+	Message string   `json:"message"`
+}
+
+// Report is the outcome of checking a source against a policy.
+type Report struct {
+	Source     string       `json:"source"`
+	Passed     bool         `json:"passed"`
+	Checked    int          `json:"checked"`
+	Violations []*Violation `json:"violations"`
+}
+
+// ViolationsError is returned when the report contains violations.
+type ViolationsError struct {
+	Count int
+}
+
+func (e *ViolationsError) Error() string {
+	return fmt.Sprintf("found %d policy violations", e.Count)
+}
+
+// node is a module reached while walking the dependencies of the source.
+type node struct {
+	module            *schema.Module
+	versionConstraint string
+	depth             int
+	parent            *node
+}
+
+func (n *node) id() string {
+	return n.module.GetLanguage() + ":" + diagram.Name(n.module)
+}
+
+func (n *node) path() []string {
+	path := make([]string, 0, n.depth+1)
+	for current := n; current != nil; current = current.parent {
+		path = append([]string{current.id()}, path...)
+	}
+	return path
+}
+
+// walk visits the dependencies of the modules managed by the source breadth
+// first, so each module is reached along its shortest path.
+func walk(
+	ctx context.Context,
+	modulesClient tracker.ModuleServiceClient,
+	dependencyClient tracker.DependencyServiceClient,
+	sourceURL string,
+) ([]*node, error) {
+	managed, err := modulesClient.ListManaged(ctx, &schema.Source{Url: sourceURL})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(managed.GetModules()) == 0 {
+		return nil, fmt.Errorf("no modules are known for source %s", sourceURL)
+	}
+
+	seen := make(map[string]bool)
+	queue := make([]*node, 0, len(managed.GetModules()))
+
+	for _, managedModule := range managed.GetModules() {
+		n := &node{module: managedModule.GetModule()}
+		if !seen[n.id()] {
+			seen[n.id()] = true
+			queue = append(queue, n)
+		}
+	}
+
+	for i := 0; i < len(queue); i++ {
+		current := queue[i]
+
+		response, err := dependencyClient.ListDependencies(ctx, &tracker.DependencyRequest{
+			Language:     current.module.Language,
+			Organization: current.module.Organization,
+			Module:       current.module.Module,
+			Name:         current.module.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dependency := range response.GetDependencies() {
+			n := &node{
+				module:            dependency.GetModule(),
+				versionConstraint: dependency.GetDepends().GetVersionConstraint(),
+				depth:             current.depth + 1,
+				parent:            current,
+			}
+
+			if !seen[n.id()] {
+				seen[n.id()] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	return queue, nil
+}
+
+// evaluate checks the dependencies reached from the source against the
+// policy. The modules managed by the source are only checked for licenses.
+func evaluate(ctx context.Context, policy *Policy, labels client.LabelClient, sourceURL string, nodes []*node) (*Report, error) {
+	report := &Report{
+		Source:     sourceURL,
+		Checked:    len(nodes),
+		Violations: make([]*Violation, 0),
+	}
+
+	deny := make(map[string]bool)
+	if policy.Licenses != nil {
+		for _, license := range policy.Licenses.Deny {
+			deny[strings.ToLower(license)] = true
+		}
+	}
+
+	for _, n := range nodes {
+		violation := func(rule, message string) {
+			report.Violations = append(report.Violations, &Violation{
+				Rule:    rule,
+				Module:  n.id(),
+				Path:    n.path(),
+				Message: message,
+			})
+		}
+
+		if n.depth > 0 {
+			if ban := policy.banned(n.module, n.versionConstraint); ban != nil {
+				message := fmt.Sprintf("%s is banned", n.id())
+				if ban.Reason != "" {
+					message += ": " + ban.Reason
+				}
+				violation(RuleBanned, message)
+			}
+
+			if policy.MaxDepth > 0 && n.depth > policy.MaxDepth {
+				violation(RuleMaxDepth, fmt.Sprintf("%s is %d dependencies deep, more than the %d allowed", n.id(), n.depth, policy.MaxDepth))
+			}
+		}
+
+		if len(deny) > 0 {
+			moduleLabels, err := labels.ModuleLabels(ctx, n.module)
+			if err != nil {
+				return nil, err
+			}
+
+			if license := strings.TrimSpace(moduleLabels[policy.Licenses.Label]); deny[strings.ToLower(license)] {
+				violation(RuleLicense, fmt.Sprintf("%s uses the denied license %s", n.id(), license))
+			}
+		}
+	}
+
+	sort.SliceStable(report.Violations, func(i, j int) bool {
+		if report.Violations[i].Rule != report.Violations[j].Rule {
+			return report.Violations[i].Rule < report.Violations[j].Rule
+		}
+		return report.Violations[i].Module < report.Violations[j].Module
+	})
+
+	report.Passed = len(report.Violations) == 0
+	return report, nil
+}
+
+func Command(
+	modulesClient tracker.ModuleServiceClient,
+	dependencyClient tracker.DependencyServiceClient,
+	labelClient client.LabelClient,
+	writer writer.Writer,
+) *cobra.Command {
+	policyFile := ""
+	sourceURL := ""
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check the dependencies of a source against a policy",
+		Long: strings.TrimSpace(`
+Check the dependencies of a source, direct and transitive, against a policy that
+bans modules, limits how deep dependencies go, and denies licenses. A report of
+any violations is written either way. The command exits with 2 when the policy
+is violated and 1 when the source couldn't be checked, so merges can be gated
+on the result.`),
+		Example: strings.Join([]string{
+			"deps check --policy policy.yaml --source https://github.com/depscloud/depscloud.git",
+		}, "\n"),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if policyFile == "" || sourceURL == "" {
+				return fmt.Errorf("policy and source must be provided")
+			}
+
+			policy, err := LoadPolicy(policyFile)
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+
+			nodes, err := walk(ctx, modulesClient, dependencyClient, sourceURL)
+			if err != nil {
+				return err
+			}
+
+			report, err := evaluate(ctx, policy, labelClient, sourceURL, nodes)
+			if err != nil {
+				return err
+			}
+
+			if err := writer.Write(report); err != nil {
+				return err
+			}
+
+			if !report.Passed {
+				return &ViolationsError{Count: len(report.Violations)}
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&policyFile, "policy", policyFile, "The policy file to check against")
+	flags.StringVar(&sourceURL, "source", sourceURL, "The url of the source to check")
+
+	return cmd
+}
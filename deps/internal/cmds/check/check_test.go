@@ -0,0 +1,119 @@
+package check
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/fakes"
+
+	"github.com/stretchr/testify/require"
+)
+
+const policyYAML = `
+banned:
+- language: node
+  name: left-pad
+  reason: unpublished in the past
+- name: "@evil/*"
+- name: lodash
+  versions: "<4.17.21"
+max_depth: 2
+licenses:
+  deny: [GPL-3.0]
+`
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "check")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	policyFile := filepath.Join(dir, "policy.yaml")
+	require.NoError(t, ioutil.WriteFile(policyFile, []byte(policyYAML), 0644))
+
+	policy, err := LoadPolicy(policyFile)
+	require.NoError(t, err)
+	require.Equal(t, "license", policy.Licenses.Label)
+
+	modules := &fakes.Modules{
+		Managed: []*tracker.ManagedModule{
+			{Module: &schema.Module{Language: "node", Name: "web"}},
+		},
+	}
+
+	dependencies := &fakes.Dependencies{
+		Dependencies: map[string][]*tracker.Dependency{
+			"web":         {fakes.Edge("node", "express", "^4.17.0"), fakes.Edge("node", "lodash", "^4.17.21"), fakes.Edge("node", "@evil/pkg", "1.0.0")},
+			"express":     {fakes.Edge("node", "body-parser", "1.19.0"), fakes.Edge("node", "lodash", "4.17.0")},
+			"body-parser": {fakes.Edge("node", "left-pad", "1.3.0")},
+		},
+	}
+
+	nodes, err := walk(ctx, modules, dependencies, "https://github.com/depscloud/web.git")
+	require.NoError(t, err)
+	require.Len(t, nodes, 6)
+
+	labels := fakes.Licenses{"body-parser": "GPL-3.0", "express": "MIT"}
+
+	report, err := evaluate(ctx, policy, labels, "https://github.com/depscloud/web.git", nodes)
+	require.NoError(t, err)
+	require.False(t, report.Passed)
+	require.Equal(t, 6, report.Checked)
+	require.Equal(t, []*Violation{
+		{
+			Rule:    RuleBanned,
+			Module:  "node:@evil/pkg",
+			Path:    []string{"node:web", "node:@evil/pkg"},
+			Message: "node:@evil/pkg is banned",
+		},
+		{
+			Rule:    RuleBanned,
+			Module:  "node:left-pad",
+			Path:    []string{"node:web", "node:express", "node:body-parser", "node:left-pad"},
+			Message: "node:left-pad is banned: unpublished in the past",
+		},
+		{
+			Rule:    RuleLicense,
+			Module:  "node:body-parser",
+			Path:    []string{"node:web", "node:express", "node:body-parser"},
+			Message: "node:body-parser uses the denied license GPL-3.0",
+		},
+		{
+			Rule:    RuleMaxDepth,
+			Module:  "node:left-pad",
+			Path:    []string{"node:web", "node:express", "node:body-parser", "node:left-pad"},
+			Message: "node:left-pad is 3 dependencies deep, more than the 2 allowed",
+		},
+	}, report.Violations)
+
+	// lodash is reached directly with a version that isn't banned first
+	policy.MaxDepth = 0
+	policy.Banned = policy.Banned[2:]
+	policy.Licenses = nil
+
+	report, err = evaluate(ctx, policy, labels, "https://github.com/depscloud/web.git", nodes)
+	require.NoError(t, err)
+	require.True(t, report.Passed)
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "check")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	policyFile := filepath.Join(dir, "policy.yaml")
+
+	require.NoError(t, ioutil.WriteFile(policyFile, []byte("banned:\n- language: go\n"), 0644))
+	_, err = LoadPolicy(policyFile)
+	require.Error(t, err)
+
+	require.NoError(t, ioutil.WriteFile(policyFile, []byte("max_depth: -1\n"), 0644))
+	_, err = LoadPolicy(policyFile)
+	require.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+	"github.com/depscloud/depscloud/internal/constraints"
+
+	"github.com/ghodss/yaml"
+)
+
+// Ban forbids depending on modules, directly or transitively. Name is a glob
+// matched against the name of the module. When versions is provided, only
+// dependencies whose version satisfies the constraint are banned.
+type Ban struct {
+	Language string `json:"language,omitempty"`
+	Name     string `json:"name"`
+	Versions string `json:"versions,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// LicensePolicy denies the licenses found in a label on each module.
+type LicensePolicy struct {
+	Label string   `json:"label,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// Policy describes the dependencies a source is allowed to have.
+//
+//	banned:
+//	- language: node
+//	  name: left-pad
+//	  reason: unpublished in the past
+//	- language: go
+//	  name: github.com/dgrijalva/jwt-go
+//	max_depth: 6
+//	licenses:
+//	  deny: [AGPL-3.0, GPL-3.0]
+type Policy struct {
+	Banned   []*Ban         `json:"banned,omitempty"`
+	MaxDepth int            `json:"max_depth,omitempty"`
+	Licenses *LicensePolicy `json:"licenses,omitempty"`
+}
+
+// LoadPolicy reads and validates the policy file.
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %v", file, err)
+	}
+
+	return policy, nil
+}
+
+func (p *Policy) validate() error {
+	for i, ban := range p.Banned {
+		if ban.Name == "" {
+			return fmt.Errorf("banned[%d]: name is required", i)
+		}
+
+		if _, err := path.Match(ban.Name, ""); err != nil {
+			return fmt.Errorf("banned[%d]: %v", i, err)
+		}
+
+		if ban.Versions != "" {
+			if _, err := constraints.Parse("", ban.Versions); err != nil {
+				return fmt.Errorf("banned[%d]: %v", i, err)
+			}
+		}
+	}
+
+	if p.MaxDepth < 0 {
+		return fmt.Errorf("max_depth must not be negative")
+	}
+
+	if p.Licenses != nil && p.Licenses.Label == "" {
+		p.Licenses.Label = "license"
+	}
+
+	return nil
+}
+
+// banned returns the ban matching the dependency, if any. Dependencies on a
+// range of versions are banned when the lowest version they allow is.
+func (p *Policy) banned(module *schema.Module, versionConstraint string) *Ban {
+	name := diagram.Name(module)
+
+	for _, ban := range p.Banned {
+		if ban.Language != "" && ban.Language != module.Language {
+			continue
+		}
+
+		if matched, _ := path.Match(ban.Name, name); !matched {
+			continue
+		}
+
+		if ban.Versions == "" {
+			return ban
+		}
+
+		version := versionConstraint
+		if !constraints.IsVersion(version) {
+			parsed, err := constraints.Parse("", version)
+			if err != nil {
+				continue
+			}
+			version = parsed.Floor()
+		}
+
+		banned, _ := constraints.Parse("", ban.Versions)
+		if version != "" && banned.Satisfies(version) {
+			return ban
+		}
+	}
+
+	return nil
+}
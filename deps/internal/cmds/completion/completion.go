@@ -2,31 +2,60 @@ package completion
 
 import (
 	"fmt"
-	"os"
+	"io"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+// zshPreamble loads bash completion into zsh. cobra's zsh completion can't
+// call back into deps, which module names and languages are completed with.
+const zshPreamble = `#compdef deps
+
+autoload -U +X bashcompinit && bashcompinit
+
+`
+
 func Command() *cobra.Command {
 	return &cobra.Command{
-		Use:   "completion <bash|powershell|zsh>",
+		Use:   "completion <bash|fish|powershell|zsh>",
 		Short: "Generate command completion for different shells",
+		Long: strings.TrimSpace(`
+Generate command completion for different shells. Under bash, fish, and zsh,
+languages are completed along with the names of modules and the urls of
+sources, which are looked up as they're typed.`),
+		Example: strings.Join([]string{
+			"source <(deps completion bash)",
+			"deps completion fish > ~/.config/fish/completions/deps.fish",
+			"deps completion zsh > \"${fpath[1]}/_deps\"",
+		}, "\n"),
+		ValidArgs: []string{"bash", "fish", "powershell", "zsh"},
+		Args:      cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			sh := "bash"
 			if len(args) > 0 {
 				sh = args[0]
 			}
 
-			switch sh {
-			case "zsh":
-				return cmd.GenZshCompletion(os.Stdout)
-			case "powershell":
-				return cmd.GenPowerShellCompletion(os.Stdout)
-			case "bash":
-				return cmd.GenBashCompletion(os.Stdout)
-			}
-
-			return fmt.Errorf("unrecognized shell: %s", sh)
+			return generate(cmd.Root(), sh, cmd.OutOrStdout())
 		},
 	}
 }
+
+func generate(root *cobra.Command, sh string, out io.Writer) error {
+	switch sh {
+	case "zsh":
+		if _, err := io.WriteString(out, zshPreamble); err != nil {
+			return err
+		}
+		return root.GenBashCompletion(out)
+	case "fish":
+		return root.GenFishCompletion(out, true)
+	case "powershell":
+		return root.GenPowerShellCompletion(out)
+	case "bash":
+		return root.GenBashCompletion(out)
+	}
+
+	return fmt.Errorf("unrecognized shell: %s", sh)
+}
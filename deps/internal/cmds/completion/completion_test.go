@@ -0,0 +1,100 @@
+package completion
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/deps/internal/client"
+
+	"github.com/spf13/cobra"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSearch []*client.TextSearchResult
+
+func (f fakeSearch) Search(ctx context.Context, req *client.TextSearchRequest) ([]*client.TextSearchResult, error) {
+	results := make([]*client.TextSearchResult, 0)
+	for _, result := range f {
+		if result.Type != req.Type {
+			continue
+		}
+
+		name := ""
+		if result.Module != nil {
+			name = result.Module.Name
+		} else {
+			name = result.Source.Url
+		}
+
+		if strings.HasPrefix(name, req.Query) {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func module(language, name string) *client.TextSearchResult {
+	return &client.TextSearchResult{Type: "module", Module: &schema.Module{Language: language, Name: name}}
+}
+
+func complete(t *testing.T, root *cobra.Command, args ...string) []string {
+	out := bytes.NewBuffer(nil)
+	root.SetOut(out)
+	root.SetArgs(append([]string{"__complete"}, args...))
+	require.NoError(t, root.Execute())
+
+	// the last line holds the directive
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	return lines[:len(lines)-1]
+}
+
+func TestFlagCompletion(t *testing.T) {
+	root := &cobra.Command{Use: "deps"}
+	dependents := &cobra.Command{Use: "dependents", Run: func(*cobra.Command, []string) {}}
+	dependents.Flags().StringP("language", "l", "", "")
+	dependents.Flags().StringP("name", "n", "", "")
+	sbom := &cobra.Command{Use: "sbom", Run: func(*cobra.Command, []string) {}}
+	sbom.Flags().String("source", "", "")
+	root.AddCommand(dependents, sbom)
+
+	register(root, completions(fakeSearch{
+		module("go", "github.com/depscloud/api"),
+		module("go", "github.com/depscloud/depscloud"),
+		module("node", "github.com/depscloud/web"),
+		{Type: "source", Source: &schema.Source{Url: "https://github.com/depscloud/depscloud.git"}},
+	}))
+
+	require.Equal(t, Languages, complete(t, root, "dependents", "-l", ""))
+
+	require.Equal(t, []string{
+		"github.com/depscloud/api",
+		"github.com/depscloud/depscloud",
+		"github.com/depscloud/web",
+	}, complete(t, root, "dependents", "--name", "github.com/depscloud/"))
+
+	require.Equal(t, []string{
+		"github.com/depscloud/api",
+		"github.com/depscloud/depscloud",
+	}, complete(t, root, "dependents", "-l", "go", "-n", "github.com/depscloud/"))
+
+	require.Equal(t, []string{
+		"https://github.com/depscloud/depscloud.git",
+	}, complete(t, root, "sbom", "--source", "https://"))
+}
+
+func TestGenerate(t *testing.T) {
+	root := &cobra.Command{Use: "deps"}
+	root.AddCommand(Command())
+
+	for _, sh := range []string{"bash", "fish", "powershell", "zsh"} {
+		out := bytes.NewBuffer(nil)
+		require.NoError(t, generate(root, sh, out), sh)
+		require.Contains(t, out.String(), "deps", sh)
+	}
+
+	require.Error(t, generate(root, "tcsh", bytes.NewBuffer(nil)))
+}
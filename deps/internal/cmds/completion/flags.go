@@ -0,0 +1,137 @@
+package completion
+
+import (
+	"context"
+	"sort"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/deps/internal/client"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Languages are the languages the extractor produces modules for.
+var Languages = []string{"actions", "cpp", "go", "java", "js", "julia", "node", "php", "r", "rust"}
+
+// maxResults bounds the number of completions suggested at once.
+const maxResults = 50
+
+type completionFunc = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// completions chooses how each flag is completed, by its name.
+func completions(search client.TextSearchClient) map[string]completionFunc {
+	moduleField := func(field func(*schema.Module) string) completionFunc {
+		return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			language, _ := cmd.Flags().GetString("language")
+
+			matches, err := lookup(contextOf(cmd), search, "module", toComplete)
+			if err != nil {
+				cobra.CompErrorln(err.Error())
+				return nil, cobra.ShellCompDirectiveError
+			}
+
+			values := make([]string, 0, len(matches))
+			for _, m := range matches {
+				if m.Module != nil && (language == "" || m.Module.Language == language) {
+					values = append(values, field(m.Module))
+				}
+			}
+
+			return unique(values), cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+
+	name := moduleField(func(m *schema.Module) string {
+		if m.Name != "" {
+			return m.Name
+		}
+		return m.Module
+	})
+
+	sources := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		matches, err := lookup(contextOf(cmd), search, "source", toComplete)
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		values := make([]string, 0, len(matches))
+		for _, m := range matches {
+			if m.Source != nil {
+				values = append(values, m.Source.Url)
+			}
+		}
+
+		return unique(values), cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return map[string]completionFunc{
+		"language": func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return Languages, cobra.ShellCompDirectiveNoFileComp
+		},
+		"organization": moduleField(func(m *schema.Module) string { return m.Organization }),
+		"module":       moduleField(func(m *schema.Module) string { return m.Module }),
+		"name":         name,
+		"from":         name,
+		"to":           name,
+		"source":       sources,
+		"url":          sources,
+	}
+}
+
+// lookup finds the modules or sources starting with the text being completed.
+func lookup(ctx context.Context, search client.TextSearchClient, graphItemType, prefix string) ([]*client.TextSearchResult, error) {
+	if prefix == "" {
+		return nil, nil
+	}
+
+	return search.Search(ctx, &client.TextSearchRequest{
+		Query: prefix,
+		Type:  graphItemType,
+		Mode:  client.MatchPrefix,
+		Limit: maxResults,
+	})
+}
+
+// contextOf returns the context of the command, which isn't set on the command
+// being completed.
+func contextOf(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+// RegisterFlags completes the languages, modules, and sources passed to the
+// commands beneath root.
+func RegisterFlags(root *cobra.Command, search client.TextSearchClient) {
+	register(root, completions(search))
+}
+
+func register(cmd *cobra.Command, funcs map[string]completionFunc) {
+	cmd.LocalNonPersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if f, ok := funcs[flag.Name]; ok {
+			_ = cmd.RegisterFlagCompletionFunc(flag.Name, f)
+		}
+	})
+
+	for _, child := range cmd.Commands() {
+		register(child, funcs)
+	}
+}
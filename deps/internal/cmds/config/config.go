@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/depscloud/depscloud/deps/internal/client"
+
+	"github.com/spf13/cobra"
+)
+
+func Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the deployments deps talks to",
+		Long: strings.TrimSpace(`
+Manage the contexts in the config file, each naming a deployment of deps.cloud
+along with how to connect to it. The current context is used unless another is
+chosen with DEPSCLOUD_CONTEXT. Environment variables, such as
+DEPSCLOUD_BASE_URL, take precedence over the values of a context.`),
+		Example: strings.Join([]string{
+			"deps config set-context staging --base-url https://staging.deps.cloud --token $TOKEN",
+			"deps config use-context staging",
+			"DEPSCLOUD_CONTEXT=production deps get sources",
+		}, "\n"),
+	}
+
+	cmd.AddCommand(currentContextCommand())
+	cmd.AddCommand(getContextsCommand())
+	cmd.AddCommand(useContextCommand())
+	cmd.AddCommand(setContextCommand())
+	cmd.AddCommand(deleteContextCommand())
+
+	return cmd
+}
+
+// contextNames completes the names of the contexts in the config file.
+func contextNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	cfg, err := client.LoadConfig(client.ConfigPath())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	names := make([]string, 0, len(cfg.Contexts))
+	for _, context := range cfg.Contexts {
+		names = append(names, context.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func currentContextCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "current-context",
+		Short: "Output the name of the current context",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := client.LoadConfig(client.ConfigPath())
+			if err != nil {
+				return err
+			}
+
+			if cfg.CurrentContext == "" {
+				return fmt.Errorf("current context is not set")
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), cfg.CurrentContext)
+			return nil
+		},
+	}
+}
+
+func getContextsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "get-contexts",
+		Aliases: []string{"get-context"},
+		Short:   "List the contexts in the config file",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := client.LoadConfig(client.ConfigPath())
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 3, ' ', 0)
+			fmt.Fprintln(w, "CURRENT\tNAME\tPROTOCOL\tBASE URL")
+
+			for _, context := range cfg.Contexts {
+				marker := ""
+				if context.Name == cfg.CurrentContext {
+					marker = "*"
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, context.Name, context.Protocol, context.BaseURL)
+			}
+
+			return w.Flush()
+		},
+	}
+}
+
+func useContextCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "use-context <name>",
+		Short:             "Change the current context",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: contextNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := client.ConfigPath()
+
+			cfg, err := client.LoadConfig(path)
+			if err != nil {
+				return err
+			}
+
+			if cfg.Get(args[0]) == nil {
+				return fmt.Errorf("context %s does not exist", args[0])
+			}
+
+			cfg.CurrentContext = args[0]
+			if err := cfg.Save(path); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "switched to context %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+func setContextCommand() *cobra.Command {
+	updated := &client.Context{}
+
+	cmd := &cobra.Command{
+		Use:   "set-context <name>",
+		Short: "Create or update a context",
+		Long: strings.TrimSpace(`
+Create or update a context. Only the provided flags are changed on an existing
+context, and the first context created becomes the current one.`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := client.ConfigPath()
+
+			cfg, err := client.LoadConfig(path)
+			if err != nil {
+				return err
+			}
+
+			context := cfg.Get(args[0])
+			if context == nil {
+				context = &client.Context{Name: args[0]}
+			}
+
+			flags := cmd.Flags()
+			set := func(flag, value string, field *string) {
+				if flags.Changed(flag) {
+					*field = value
+				}
+			}
+
+			set("protocol", updated.Protocol, &context.Protocol)
+			set("base-url", updated.BaseURL, &context.BaseURL)
+			set("ca-path", updated.CAPath, &context.CAPath)
+			set("cert-path", updated.CertPath, &context.CertPath)
+			set("key-path", updated.KeyPath, &context.KeyPath)
+			set("token", updated.Token, &context.Token)
+
+			cfg.Set(context)
+			if cfg.CurrentContext == "" {
+				cfg.CurrentContext = context.Name
+			}
+
+			if err := cfg.Save(path); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "context %s saved to %s\n", context.Name, path)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&updated.Protocol, "protocol", "", "The protocol to use, grpc or http")
+	flags.StringVar(&updated.BaseURL, "base-url", "", "The url of the deployment, such as https://api.deps.cloud")
+	flags.StringVar(&updated.CAPath, "ca-path", "", "The certificate authority used to verify the deployment")
+	flags.StringVar(&updated.CertPath, "cert-path", "", "The client certificate to present")
+	flags.StringVar(&updated.KeyPath, "key-path", "", "The key of the client certificate")
+	flags.StringVar(&updated.Token, "token", "", "The bearer token to authenticate with")
+
+	return cmd
+}
+
+func deleteContextCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "delete-context <name>",
+		Short:             "Remove a context from the config file",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: contextNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := client.ConfigPath()
+
+			cfg, err := client.LoadConfig(path)
+			if err != nil {
+				return err
+			}
+
+			if !cfg.Delete(args[0]) {
+				return fmt.Errorf("context %s does not exist", args[0])
+			}
+
+			if err := cfg.Save(path); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "deleted context %s\n", args[0])
+			return nil
+		},
+	}
+}
@@ -19,7 +19,7 @@ func Command(version mux.Version) *cobra.Command {
 		RunE: func(_ *cobra.Command, args []string) error {
 
 			systemInfo := client.GetSystemInfo()
-			debugClient := httpDebugClient{client: http.DefaultClient, baseURL: systemInfo.BaseURL}
+			debugClient := httpDebugClient{client: client.HTTPClient(), baseURL: systemInfo.BaseURL}
 			serverVersion, versionErr := debugClient.GetServerVersion()
 			healthString, healthErr := debugClient.GetHealth()
 
@@ -0,0 +1,163 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/cmds/get"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+
+	"github.com/spf13/cobra"
+)
+
+// The kinds of change between the edges of two modules.
+const (
+	added   = "+"
+	removed = "-"
+	changed = "~"
+)
+
+// change is a module that was added to or removed from the edges of a module,
+// or whose version constraint changed.
+type change struct {
+	kind   string
+	module *schema.Module
+	from   string
+	to     string
+}
+
+func (c change) String() string {
+	name := c.module.Language + ":" + diagram.Name(c.module)
+
+	switch c.kind {
+	case added:
+		return fmt.Sprintf("%s %s %s", c.kind, name, c.to)
+	case removed:
+		return fmt.Sprintf("%s %s %s", c.kind, name, c.from)
+	}
+	return fmt.Sprintf("%s %s %s -> %s", c.kind, name, c.from, c.to)
+}
+
+func edgeKey(module *schema.Module) string {
+	return module.Language + "|" + diagram.Name(module)
+}
+
+// compare returns the changes between the edges of two modules, sorted by the
+// name of the module that changed.
+func compare(from, to []*tracker.Dependency) []change {
+	before := make(map[string]*tracker.Dependency, len(from))
+	for _, edge := range from {
+		before[edgeKey(edge.GetModule())] = edge
+	}
+
+	changes := make([]change, 0)
+	after := make(map[string]bool, len(to))
+
+	for _, edge := range to {
+		key := edgeKey(edge.GetModule())
+		after[key] = true
+
+		constraint := edge.GetDepends().GetVersionConstraint()
+		if previous, ok := before[key]; !ok {
+			changes = append(changes, change{kind: added, module: edge.GetModule(), to: constraint})
+		} else if previousConstraint := previous.GetDepends().GetVersionConstraint(); previousConstraint != constraint {
+			changes = append(changes, change{kind: changed, module: edge.GetModule(), from: previousConstraint, to: constraint})
+		}
+	}
+
+	for _, edge := range from {
+		if !after[edgeKey(edge.GetModule())] {
+			changes = append(changes, change{
+				kind:   removed,
+				module: edge.GetModule(),
+				from:   edge.GetDepends().GetVersionConstraint(),
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return edgeKey(changes[i].module) < edgeKey(changes[j].module)
+	})
+
+	return changes
+}
+
+func write(out io.Writer, changes []change) error {
+	for _, c := range changes {
+		if _, err := fmt.Fprintln(out, c.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Command(
+	dependencyClient tracker.DependencyServiceClient,
+) *cobra.Command {
+	language := ""
+	fromName := ""
+	toName := ""
+	dependents := false
+
+	list := func(ctx context.Context, module *schema.Module) ([]*tracker.Dependency, error) {
+		req := &tracker.DependencyRequest{
+			Language:     module.Language,
+			Organization: module.Organization,
+			Module:       module.Module,
+			Name:         module.Name,
+		}
+
+		if dependents {
+			response, err := dependencyClient.ListDependents(ctx, req)
+			return response.GetDependents(), err
+		}
+
+		response, err := dependencyClient.ListDependencies(ctx, req)
+		return response.GetDependencies(), err
+	}
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare the dependencies (or dependents) of two modules",
+		Long: strings.TrimSpace(`
+Compare the dependencies (or dependents) of two modules, such as a library
+before and after it was renamed. Each line is a module that was added (+),
+removed (-), or whose version constraint changed (~).`),
+		Example: strings.Join([]string{
+			"deps diff -l go --from github.com/depscloud/api --to github.com/depscloud/depscloud",
+			"deps diff -l go --from github.com/depscloud/api --to github.com/depscloud/depscloud --dependents",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if language == "" || fromName == "" || toName == "" {
+				return fmt.Errorf("language, from, and to must be provided")
+			}
+
+			ctx := cmd.Context()
+
+			from, err := list(ctx, get.SetModuleFields(&schema.Module{Language: language, Name: fromName}))
+			if err != nil {
+				return err
+			}
+
+			to, err := list(ctx, get.SetModuleFields(&schema.Module{Language: language, Name: toName}))
+			if err != nil {
+				return err
+			}
+
+			return write(cmd.OutOrStdout(), compare(from, to))
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&language, "language", "l", language, "The language of the modules")
+	flags.StringVar(&fromName, "from", fromName, "The name of the module to compare from")
+	flags.StringVar(&toName, "to", toName, "The name of the module to compare to")
+	flags.BoolVar(&dependents, "dependents", dependents, "Compare the modules that depend on each module instead")
+
+	return cmd
+}
@@ -0,0 +1,41 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+
+	"github.com/stretchr/testify/require"
+)
+
+func edge(name, constraint string) *tracker.Dependency {
+	return &tracker.Dependency{
+		Module:  &schema.Module{Language: "go", Name: name},
+		Depends: &schema.Depends{VersionConstraint: constraint},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	from := []*tracker.Dependency{
+		edge("github.com/a/lib", "v1.0.0"),
+		edge("github.com/a/old", "v0.1.0"),
+		edge("github.com/a/same", "v2.0.0"),
+	}
+
+	to := []*tracker.Dependency{
+		edge("github.com/a/same", "v2.0.0"),
+		edge("github.com/a/new", "v0.2.0"),
+		edge("github.com/a/lib", "v1.1.0"),
+	}
+
+	out := &bytes.Buffer{}
+	require.NoError(t, write(out, compare(from, to)))
+	require.Equal(t, `~ go:github.com/a/lib v1.0.0 -> v1.1.0
++ go:github.com/a/new v0.2.0
+- go:github.com/a/old v0.1.0
+`, out.String())
+
+	require.Empty(t, compare(from, from))
+}
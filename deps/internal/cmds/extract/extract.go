@@ -0,0 +1,168 @@
+package extract
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/extractor"
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+
+	"github.com/spf13/cobra"
+
+	"gopkg.in/src-d/go-git.v4"
+)
+
+// listPaths walks the directory, returning the paths of its files relative to
+// it. Version control metadata is skipped.
+func listPaths(dir string) ([]string, error) {
+	paths := make([]string, 0)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relative, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		paths = append(paths, relative)
+		return nil
+	})
+
+	return paths, err
+}
+
+// checkout describes the git checkout containing the directory, returning the
+// url of its origin and the ref that's checked out. Either is empty when it
+// can't be determined.
+func checkout(dir string) (string, string) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", ""
+	}
+
+	url := ""
+	if remote, err := repo.Remote(git.DefaultRemoteName); err == nil && len(remote.Config().URLs) > 0 {
+		url = remote.Config().URLs[0]
+	}
+
+	ref := ""
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		ref = head.Name().String()
+	}
+
+	return url, ref
+}
+
+func Command(
+	extractorClient extractor.DependencyExtractorClient,
+	sourceClient tracker.SourceServiceClient,
+	writer writer.Writer,
+) *cobra.Command {
+	url := ""
+	store := false
+
+	cmd := &cobra.Command{
+		Use:   "extract [path]",
+		Short: "Extract the dependencies of a local directory",
+		Long: strings.TrimSpace(`
+Extract the dependencies of a local directory, such as a checkout of a branch
+that hasn't been merged yet, writing each dependency management file that was
+found. With --store, the dependencies are stored in the graph under the url of
+the source, which defaults to the origin of the git checkout.`),
+		Example: strings.Join([]string{
+			"deps extract",
+			"deps extract ./path/to/checkout",
+			"deps extract ./path/to/checkout --store --url https://github.com/depscloud/depscloud.git",
+		}, "\n"),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			ctx := cmd.Context()
+
+			paths, err := listPaths(dir)
+			if err != nil {
+				return err
+			}
+
+			matched, err := extractorClient.Match(ctx, &extractor.MatchRequest{
+				Separator: string(filepath.Separator),
+				Paths:     paths,
+			})
+			if err != nil {
+				return err
+			}
+
+			fileContents := make(map[string]string, len(matched.GetMatchedPaths()))
+			for _, path := range matched.GetMatchedPaths() {
+				data, err := ioutil.ReadFile(filepath.Join(dir, path))
+				if err != nil {
+					return err
+				}
+				fileContents[path] = string(data)
+			}
+
+			originURL, ref := checkout(dir)
+			if url == "" {
+				url = originURL
+			}
+
+			extracted, err := extractorClient.Extract(ctx, &extractor.ExtractRequest{
+				Separator:    string(filepath.Separator),
+				FileContents: fileContents,
+				Url:          url,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, managementFile := range extracted.GetManagementFiles() {
+				if err := writer.Write(managementFile); err != nil {
+					return err
+				}
+			}
+
+			if !store {
+				return nil
+			}
+
+			if url == "" {
+				return fmt.Errorf("the url of the source couldn't be determined, it must be provided to store dependencies")
+			}
+
+			_, err = sourceClient.Track(ctx, &tracker.SourceRequest{
+				Source: &schema.Source{
+					Url:  url,
+					Kind: "repository",
+					Ref:  ref,
+				},
+				ManagementFiles: extracted.GetManagementFiles(),
+			})
+			return err
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&url, "url", "u", url, "The url of the source, defaults to the origin of the git checkout")
+	flags.BoolVar(&store, "store", store, "Store the dependencies in the graph")
+
+	return cmd
+}
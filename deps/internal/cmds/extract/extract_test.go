@@ -0,0 +1,44 @@
+package extract
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+)
+
+func TestCheckout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extract")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	url, ref := checkout(dir)
+	require.Equal(t, "", url)
+	require.Equal(t, "", ref)
+
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{"https://github.com/depscloud/depscloud.git"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "web"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module a\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "web", "package.json"), []byte("{}"), 0644))
+
+	paths, err := listPaths(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"go.mod", filepath.Join("web", "package.json")}, paths)
+
+	// the checkout is found from within a subdirectory
+	url, _ = checkout(filepath.Join(dir, "web"))
+	require.Equal(t, "https://github.com/depscloud/depscloud.git", url)
+}
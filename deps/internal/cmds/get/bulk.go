@@ -0,0 +1,277 @@
+package get
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+
+	"github.com/sirupsen/logrus"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// bulkParallelism is how many modules are queried at once when the search
+// stream isn't available.
+const bulkParallelism = 8
+
+// BulkResult is a dependent (or dependency) of one of the modules read from
+// stdin, which Of identifies using the key it was given as.
+type BulkResult struct {
+	Of      string          `json:"of"`
+	Depends *schema.Depends `json:"depends"`
+	Module  *schema.Module  `json:"module"`
+}
+
+// isBulk returns whether the modules to query are read from stdin.
+func isBulk(args []string) bool {
+	return len(args) == 1 && args[0] == "-"
+}
+
+// parseKey reads a module key, either "language:name" or "language name".
+// When a language is provided on the command line, the whole line is the name
+// unless it contains a space, since java names contain a colon.
+func parseKey(line, language string) (*tracker.DependencyRequest, error) {
+	var name string
+
+	if fields := strings.Fields(line); len(fields) == 2 {
+		language, name = fields[0], fields[1]
+	} else if len(fields) > 2 {
+		return nil, fmt.Errorf("invalid module key %q", line)
+	} else if language != "" {
+		name = line
+	} else if i := strings.Index(line, ":"); i > 0 {
+		language, name = line[:i], line[i+1:]
+	}
+
+	if language == "" || name == "" {
+		return nil, fmt.Errorf("invalid module key %q, expected language:name", line)
+	}
+
+	return setRequestFields(&tracker.DependencyRequest{Language: language, Name: name}), nil
+}
+
+// readKeys reads newline delimited module keys, skipping blank lines,
+// comments, and duplicates.
+func readKeys(in io.Reader, language string) ([]string, []*tracker.DependencyRequest, error) {
+	keys := make([]string, 0)
+	requests := make([]*tracker.DependencyRequest, 0)
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		req, err := parseKey(line, language)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if seen[keyForRequest(req)] {
+			continue
+		}
+		seen[keyForRequest(req)] = true
+
+		keys = append(keys, line)
+		requests = append(requests, req)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(requests) == 0 {
+		return nil, nil, fmt.Errorf("no module keys were read from stdin")
+	}
+
+	return keys, requests, nil
+}
+
+// bulkQuery looks up the dependents (or dependencies) of many modules. The
+// requests are sent down a single search stream when the client supports it,
+// otherwise each module is requested on its own. Results are keyed using
+// keyForRequest.
+type bulkQuery struct {
+	dependencyClient tracker.DependencyServiceClient
+	searchClient     tracker.SearchServiceClient
+	dependents       bool
+}
+
+func (q *bulkQuery) run(ctx context.Context, requests []*tracker.DependencyRequest) (map[string][]*tracker.Dependency, map[string]error) {
+	if q.searchClient != nil {
+		results, err := q.stream(ctx, requests)
+		if err == nil {
+			return results, nil
+		} else if status.Code(err) != codes.Unimplemented {
+			logrus.Warnf("falling back to individual requests: %v", err)
+		}
+	}
+
+	return q.individually(ctx, requests)
+}
+
+func (q *bulkQuery) stream(ctx context.Context, requests []*tracker.DependencyRequest) (map[string][]*tracker.Dependency, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	call, err := q.searchClient.Search(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sendErr := make(chan error, 1)
+	go func() {
+		for _, req := range requests {
+			request := &tracker.SearchRequest{DependenciesOf: req}
+			if q.dependents {
+				request = &tracker.SearchRequest{DependentsOf: req}
+			}
+
+			if err := call.Send(request); err != nil {
+				sendErr <- err
+				return
+			}
+		}
+		sendErr <- call.CloseSend()
+	}()
+
+	results := make(map[string][]*tracker.Dependency, len(requests))
+
+	for len(results) < len(requests) {
+		resp, err := call.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if q.dependents {
+			results[keyForRequest(resp.GetRequest().GetDependentsOf())] = resp.GetDependents()
+		} else {
+			results[keyForRequest(resp.GetRequest().GetDependenciesOf())] = resp.GetDependencies()
+		}
+	}
+
+	if err := <-sendErr; err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(results) < len(requests) {
+		return nil, fmt.Errorf("search stream ended after %d of %d modules", len(results), len(requests))
+	}
+
+	return results, nil
+}
+
+func (q *bulkQuery) individually(ctx context.Context, requests []*tracker.DependencyRequest) (map[string][]*tracker.Dependency, map[string]error) {
+	results := make(map[string][]*tracker.Dependency, len(requests))
+	errs := make(map[string]error)
+	lock := &sync.Mutex{}
+
+	work := make(chan *tracker.DependencyRequest)
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < bulkParallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for req := range work {
+				var items []*tracker.Dependency
+				var err error
+
+				if q.dependents {
+					var response *tracker.ListDependentsResponse
+					if response, err = q.dependencyClient.ListDependents(ctx, req); err == nil {
+						items = response.GetDependents()
+					}
+				} else {
+					var response *tracker.ListDependenciesResponse
+					if response, err = q.dependencyClient.ListDependencies(ctx, req); err == nil {
+						items = response.GetDependencies()
+					}
+				}
+
+				lock.Lock()
+				if err != nil {
+					errs[keyForRequest(req)] = err
+				} else {
+					results[keyForRequest(req)] = items
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, req := range requests {
+		work <- req
+	}
+	close(work)
+	wg.Wait()
+
+	return results, errs
+}
+
+// runBulk reads module keys from stdin and writes the consolidated results in
+// the order the keys were read. Modules that fail are logged and reported
+// once the rest have been written.
+func runBulk(
+	ctx context.Context,
+	in io.Reader,
+	language string,
+	query *bulkQuery,
+	excludedScopes []string,
+	output *writer.Output,
+) error {
+	keys, requests, err := readKeys(in, language)
+	if err != nil {
+		return err
+	}
+
+	results, errs := query.run(ctx, requests)
+
+	edges := make([]diagram.Edge, 0)
+	for i, req := range requests {
+		if err, ok := errs[keyForRequest(req)]; ok {
+			logrus.Errorf("failed to query %s: %v", keys[i], err)
+			continue
+		}
+
+		items := filterScopes(results[keyForRequest(req)], excludedScopes)
+
+		if rendersDiagram(output) {
+			edges = append(edges, edgesOf(requestToModule(req), items, query.dependents)...)
+			continue
+		}
+
+		for _, item := range items {
+			_ = output.Write(&BulkResult{
+				Of:      keys[i],
+				Depends: item.GetDepends(),
+				Module:  item.GetModule(),
+			})
+		}
+	}
+
+	if rendersDiagram(output) {
+		if err := diagram.Write(output.Out(), output.Format, edges); err != nil {
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to query %d of %d modules", len(errs), len(requests))
+	}
+	return nil
+}
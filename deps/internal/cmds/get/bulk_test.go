@@ -0,0 +1,129 @@
+package get
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+)
+
+var bulkDependents = map[string][]*tracker.Dependency{
+	"go|github.com|depscloud/api": {
+		{Module: &schema.Module{Language: "go", Name: "github.com/depscloud/depscloud"}},
+		{Module: &schema.Module{Language: "go", Name: "github.com/depscloud/hacktoberfest"}, Depends: &schema.Depends{Scopes: []string{"test"}}},
+	},
+	"java|com.google.guava|guava": {
+		{Module: &schema.Module{Language: "java", Name: "com.example:app"}},
+	},
+}
+
+type fakeDependencyClient struct {
+	tracker.DependencyServiceClient
+}
+
+func (f *fakeDependencyClient) ListDependents(ctx context.Context, in *tracker.DependencyRequest, opts ...grpc.CallOption) (*tracker.ListDependentsResponse, error) {
+	return &tracker.ListDependentsResponse{Dependents: bulkDependents[keyForRequest(in)]}, nil
+}
+
+type fakeSearchStream struct {
+	grpc.ClientStream
+	responses chan *tracker.SearchResponse
+}
+
+func (f *fakeSearchStream) Send(request *tracker.SearchRequest) error {
+	f.responses <- &tracker.SearchResponse{
+		Request:    request,
+		Dependents: bulkDependents[keyForRequest(request.GetDependentsOf())],
+	}
+	return nil
+}
+
+func (f *fakeSearchStream) CloseSend() error {
+	close(f.responses)
+	return nil
+}
+
+func (f *fakeSearchStream) Recv() (*tracker.SearchResponse, error) {
+	response, ok := <-f.responses
+	if !ok {
+		return nil, io.EOF
+	}
+	return response, nil
+}
+
+type fakeSearchClient struct {
+	tracker.SearchServiceClient
+	streams int
+}
+
+func (f *fakeSearchClient) Search(ctx context.Context, opts ...grpc.CallOption) (tracker.SearchService_SearchClient, error) {
+	f.streams++
+	return &fakeSearchStream{responses: make(chan *tracker.SearchResponse, 10)}, nil
+}
+
+func Test_parseKey(t *testing.T) {
+	req, err := parseKey("go:github.com/depscloud/api", "")
+	require.NoError(t, err)
+	require.Equal(t, "go|github.com|depscloud/api", keyForRequest(req))
+
+	req, err = parseKey("java com.google.guava:guava", "")
+	require.NoError(t, err)
+	require.Equal(t, "java|com.google.guava|guava", keyForRequest(req))
+
+	req, err = parseKey("com.google.guava:guava", "java")
+	require.NoError(t, err)
+	require.Equal(t, "java|com.google.guava|guava", keyForRequest(req))
+
+	_, err = parseKey("github.com/depscloud/api", "")
+	require.Error(t, err)
+}
+
+func Test_runBulk(t *testing.T) {
+	stdin := strings.Join([]string{
+		"# modules to audit",
+		"go:github.com/depscloud/api",
+		"",
+		"java com.google.guava:guava",
+		"go github.com/depscloud/api",
+		"rust:bytes",
+	}, "\n")
+
+	search := &fakeSearchClient{}
+
+	for _, searchClient := range []tracker.SearchServiceClient{search, nil} {
+		out := &bytes.Buffer{}
+		output := writer.NewOutput(out)
+		output.Format = writer.FormatCSV
+
+		query := &bulkQuery{
+			dependencyClient: &fakeDependencyClient{},
+			searchClient:     searchClient,
+			dependents:       true,
+		}
+
+		err := runBulk(context.Background(), strings.NewReader(stdin), "", query, []string{"test"}, output)
+		require.NoError(t, err)
+		require.NoError(t, output.Flush())
+
+		require.Equal(t, ""+
+			"of,depends.language,depends.version_constraint,depends.scopes,depends.ref,module.language,module.organization,module.module,module.name\n"+
+			"go:github.com/depscloud/api,,,,,go,,,github.com/depscloud/depscloud\n"+
+			"java com.google.guava:guava,,,,,java,,,com.example:app\n",
+			out.String())
+	}
+
+	require.Equal(t, 1, search.streams)
+
+	query := &bulkQuery{dependencyClient: &fakeDependencyClient{}, dependents: true}
+	err := runBulk(context.Background(), strings.NewReader("# nothing\n"), "", query, nil, writer.NewOutput(&bytes.Buffer{}))
+	require.EqualError(t, err, "no module keys were read from stdin")
+}
@@ -5,6 +5,9 @@ import (
 
 	"github.com/depscloud/api/v1alpha/schema"
 	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+	"github.com/depscloud/depscloud/internal/scopes"
 
 	"github.com/spf13/cobra"
 )
@@ -18,6 +21,60 @@ func addDependencyRequestFlags(cmd *cobra.Command, req *tracker.DependencyReques
 	flags.StringVarP(&(req.Name), "name", "n", req.Name, "The name of the module")
 }
 
+func addScopeFlags(cmd *cobra.Command, excluded *[]string) {
+	flags := cmd.Flags()
+
+	flags.StringSliceVar(excluded, "exclude-scopes", *excluded,
+		"Exclude edges by scope ("+strings.Join(scopes.All, ", ")+")")
+}
+
+func filterScopes(dependencies []*tracker.Dependency, excluded []string) []*tracker.Dependency {
+	if len(excluded) == 0 {
+		return dependencies
+	}
+
+	filtered := make([]*tracker.Dependency, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		if scopes.Matches(dependency.GetDepends().GetScopes(), excluded) {
+			filtered = append(filtered, dependency)
+		}
+	}
+	return filtered
+}
+
+// validateFormat checks the format before any requests are made. Only the
+// commands that query the graph can render it as a diagram.
+func validateFormat(format string) error {
+	if format == "" || diagram.Validate(format) == nil {
+		return nil
+	}
+	return writer.Validate(format)
+}
+
+// rendersDiagram returns whether the output was asked for as a diagram of the
+// graph, rather than as a list of values.
+func rendersDiagram(output *writer.Output) bool {
+	return diagram.Validate(output.Format) == nil
+}
+
+// edgesOf converts the dependencies (or dependents) of a module into edges
+// that point from each module to the module it depends on.
+func edgesOf(module *schema.Module, items []*tracker.Dependency, dependents bool) []diagram.Edge {
+	edges := make([]diagram.Edge, 0, len(items))
+	for _, item := range items {
+		edge := diagram.Edge{
+			From:              module,
+			To:                item.GetModule(),
+			VersionConstraint: item.GetDepends().GetVersionConstraint(),
+		}
+		if dependents {
+			edge.From, edge.To = edge.To, edge.From
+		}
+		edges = append(edges, edge)
+	}
+	return edges
+}
+
 func addSourceFlags(cmd *cobra.Command, source *schema.Source) {
 	flags := cmd.Flags()
 
@@ -33,7 +90,9 @@ func addModuleFlags(cmd *cobra.Command, module *schema.Module) {
 	flags.StringVarP(&(module.Name), "name", "n", module.Name, "The name of the module")
 }
 
-func setModuleFields(module *schema.Module) *schema.Module {
+// SetModuleFields fills in the organization and module from the name of the
+// module, when it's given.
+func SetModuleFields(module *schema.Module) *schema.Module {
 	if module.Name == "" {
 		return module
 	}
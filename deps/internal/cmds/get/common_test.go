@@ -3,6 +3,9 @@ package get
 import (
 	"testing"
 
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,3 +30,15 @@ func Test_parseName(t *testing.T) {
 	}
 
 }
+
+func Test_filterScopes(t *testing.T) {
+	dependencies := []*tracker.Dependency{
+		{Depends: &schema.Depends{Scopes: []string{"compile"}}},
+		{Depends: &schema.Depends{Scopes: []string{"test"}}},
+		{Depends: &schema.Depends{Scopes: []string{"dev"}}},
+	}
+
+	require.Len(t, filterScopes(dependencies, nil), 3)
+	require.Len(t, filterScopes(dependencies, []string{"test"}), 2)
+	require.Equal(t, dependencies[:1], filterScopes(dependencies, []string{"test", "dev"}))
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
 	"github.com/depscloud/depscloud/deps/internal/writer"
 
 	"github.com/spf13/cobra"
@@ -13,19 +14,30 @@ import (
 func DependenciesCommand(
 	dependencyClient tracker.DependencyServiceClient,
 	searchClient tracker.SearchServiceClient,
-	writer writer.Writer,
+	output *writer.Output,
 ) *cobra.Command {
 	req := &tracker.DependencyRequest{}
+	excludedScopes := make([]string, 0)
 
 	cmd := &cobra.Command{
-		Use:     "dependencies",
+		Use:     "dependencies [-]",
 		Aliases: []string{"dependency"},
 		Short:   "Get the list of modules the given module depends on",
 		Example: strings.Join([]string{
 			"deps get dependencies -l go -o github.com -m depscloud/api",
 			"deps get dependencies -l go -n github.com/depscloud/api",
+			"deps get dependencies -l go -n github.com/depscloud/api --exclude-scopes test,dev",
+			"deps get dependencies -l go -n github.com/depscloud/api --format dot | dot -Tsvg > dependencies.svg",
+			"deps get dependencies --format csv - < modules.txt",
 		}, "\n"),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if isBulk(args) {
+				query := &bulkQuery{dependencyClient: dependencyClient, searchClient: searchClient, dependents: false}
+				return runBulk(cmd.Context(), cmd.InOrStdin(), req.Language, query, excludedScopes, output)
+			} else if len(args) > 0 {
+				return fmt.Errorf("unexpected arguments, use - to read module keys from stdin")
+			}
+
 			if req.Language == "" && ((req.Organization == "" || req.Module == "") || req.Name == "") {
 				return fmt.Errorf("language + name or language + organization + module must be provided")
 			}
@@ -36,15 +48,21 @@ func DependenciesCommand(
 				return err
 			}
 
-			for _, dependency := range response.Dependencies {
-				_ = writer.Write(dependency)
+			dependencies := filterScopes(response.Dependencies, excludedScopes)
+			if rendersDiagram(output) {
+				edges := edgesOf(requestToModule(req), dependencies, false)
+				return diagram.Write(output.Out(), output.Format, edges)
+			}
+
+			for _, dependency := range dependencies {
+				_ = output.Write(dependency)
 			}
 
 			return nil
 		},
 	}
 
-	topologyCmd := topologyCommand(writer, searchClient, func(depRequest *tracker.DependencyRequest) *tracker.SearchRequest {
+	topologyCmd := topologyCommand(output, searchClient, func(depRequest *tracker.DependencyRequest) *tracker.SearchRequest {
 		return &tracker.SearchRequest{
 			DependenciesOf: depRequest,
 		}
@@ -52,6 +70,7 @@ func DependenciesCommand(
 
 	cmd.AddCommand(topologyCmd)
 	addDependencyRequestFlags(cmd, req)
+	addScopeFlags(cmd, &excludedScopes)
 
 	return cmd
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
 	"github.com/depscloud/depscloud/deps/internal/writer"
 
 	"github.com/spf13/cobra"
@@ -13,19 +14,30 @@ import (
 func DependentsCommand(
 	dependencyClient tracker.DependencyServiceClient,
 	searchClient tracker.SearchServiceClient,
-	writer writer.Writer,
+	output *writer.Output,
 ) *cobra.Command {
 	req := &tracker.DependencyRequest{}
+	excludedScopes := make([]string, 0)
 
 	cmd := &cobra.Command{
-		Use:     "dependents",
+		Use:     "dependents [-]",
 		Aliases: []string{"dependent"},
 		Short:   "Get the list of modules that depend on the given module",
 		Example: strings.Join([]string{
 			"deps get dependents -l go -o github.com -m depscloud/api",
 			"deps get dependents -l go -n github.com/depscloud/api",
+			"deps get dependents -l go -n github.com/depscloud/api --exclude-scopes test,dev",
+			"deps get dependents -l go -n github.com/depscloud/api --format dot | dot -Tsvg > dependents.svg",
+			"deps get dependents --format csv - < modules.txt",
 		}, "\n"),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if isBulk(args) {
+				query := &bulkQuery{dependencyClient: dependencyClient, searchClient: searchClient, dependents: true}
+				return runBulk(cmd.Context(), cmd.InOrStdin(), req.Language, query, excludedScopes, output)
+			} else if len(args) > 0 {
+				return fmt.Errorf("unexpected arguments, use - to read module keys from stdin")
+			}
+
 			if req.Language == "" && ((req.Organization == "" || req.Module == "") || req.Name == "") {
 				return fmt.Errorf("language + name or language + organization + module must be provided")
 			}
@@ -37,15 +49,21 @@ func DependentsCommand(
 				return err
 			}
 
-			for _, dependent := range response.Dependents {
-				_ = writer.Write(dependent)
+			dependents := filterScopes(response.Dependents, excludedScopes)
+			if rendersDiagram(output) {
+				edges := edgesOf(requestToModule(req), dependents, true)
+				return diagram.Write(output.Out(), output.Format, edges)
+			}
+
+			for _, dependent := range dependents {
+				_ = output.Write(dependent)
 			}
 
 			return nil
 		},
 	}
 
-	topologyCmd := topologyCommand(writer, searchClient, func(depRequest *tracker.DependencyRequest) *tracker.SearchRequest {
+	topologyCmd := topologyCommand(output, searchClient, func(depRequest *tracker.DependencyRequest) *tracker.SearchRequest {
 		return &tracker.SearchRequest{
 			DependentsOf: depRequest,
 		}
@@ -53,6 +71,7 @@ func DependentsCommand(
 
 	cmd.AddCommand(topologyCmd)
 	addDependencyRequestFlags(cmd, req)
+	addScopeFlags(cmd, &excludedScopes)
 
 	return cmd
 }
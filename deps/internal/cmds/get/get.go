@@ -1,7 +1,10 @@
 package get
 
 import (
+	"strings"
+
 	"github.com/depscloud/depscloud/deps/internal/client"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
 	"github.com/depscloud/depscloud/deps/internal/writer"
 
 	"github.com/spf13/cobra"
@@ -9,17 +12,28 @@ import (
 
 func Command(
 	client client.Client,
-	writer writer.Writer,
+	output *writer.Output,
 ) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get <resource>",
 		Short: "Retrieve information from the graph",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateFormat(output.Format)
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			return output.Flush()
+		},
 	}
 
-	cmd.AddCommand(DependenciesCommand(client.Dependencies(), client.Search(), writer))
-	cmd.AddCommand(DependentsCommand(client.Dependencies(), client.Search(), writer))
-	cmd.AddCommand(ModulesCommand(client.Modules(), writer))
-	cmd.AddCommand(SourcesCommand(client.Sources(), client.Modules(), writer))
+	formats := append(append([]string{}, writer.Formats...), diagram.Formats...)
+	cmd.PersistentFlags().StringVar(&(output.Format), "format", output.Format,
+		"The format to write results in ("+strings.Join(formats, ", ")+"), where dot and mermaid render a graph")
+
+	cmd.AddCommand(DependenciesCommand(client.Dependencies(), client.Search(), output))
+	cmd.AddCommand(DependentsCommand(client.Dependencies(), client.Search(), output))
+	cmd.AddCommand(ModulesCommand(client.Modules(), output))
+	cmd.AddCommand(SourcesCommand(client.Sources(), client.Modules(), output))
+	cmd.AddCommand(TreeCommand(client.Dependencies(), output))
 
 	return cmd
 }
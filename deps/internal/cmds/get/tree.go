@@ -0,0 +1,213 @@
+package get
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+
+	"github.com/spf13/cobra"
+)
+
+// treeNode is a module within a dependency tree. Modules that appear more than
+// once are only expanded the first time, while a module that depends on one of
+// its ancestors is marked as a cycle.
+type treeNode struct {
+	module     *schema.Module
+	constraint string
+	children   []*treeNode
+	repeated   bool
+	cycle      bool
+}
+
+type listEdges func(ctx context.Context, req *tracker.DependencyRequest) ([]*tracker.Dependency, error)
+
+func moduleToRequest(module *schema.Module) *tracker.DependencyRequest {
+	return &tracker.DependencyRequest{
+		Language:     module.Language,
+		Organization: module.Organization,
+		Module:       module.Module,
+		Name:         module.Name,
+	}
+}
+
+// buildTree walks the edges from the root, up to the given depth. A depth of
+// zero walks the entire graph.
+func buildTree(ctx context.Context, list listEdges, root *schema.Module, depth int, excludedScopes []string) (*treeNode, error) {
+	expanded := make(map[string]bool)
+	ancestors := make(map[string]bool)
+
+	var walk func(node *treeNode, level int) error
+	walk = func(node *treeNode, level int) error {
+		nodeKey := key(node.module)
+		expanded[nodeKey] = true
+
+		if depth > 0 && level >= depth {
+			return nil
+		}
+
+		edges, err := list(ctx, moduleToRequest(node.module))
+		if err != nil {
+			return err
+		}
+
+		ancestors[nodeKey] = true
+		defer delete(ancestors, nodeKey)
+
+		for _, edge := range filterScopes(edges, excludedScopes) {
+			child := &treeNode{
+				module:     edge.GetModule(),
+				constraint: edge.GetDepends().GetVersionConstraint(),
+			}
+			node.children = append(node.children, child)
+
+			childKey := key(child.module)
+			if ancestors[childKey] {
+				child.cycle = true
+			} else if expanded[childKey] {
+				child.repeated = true
+			} else if err := walk(child, level+1); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	tree := &treeNode{module: root}
+	if err := walk(tree, 0); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// treeEdges flattens the tree into the edges between its modules, pointing
+// from each module to the module it depends on.
+func treeEdges(tree *treeNode, dependents bool) []diagram.Edge {
+	edges := make([]diagram.Edge, 0)
+
+	var walk func(node *treeNode)
+	walk = func(node *treeNode) {
+		for _, child := range node.children {
+			edge := diagram.Edge{From: node.module, To: child.module, VersionConstraint: child.constraint}
+			if dependents {
+				edge.From, edge.To = edge.To, edge.From
+			}
+			edges = append(edges, edge)
+			walk(child)
+		}
+	}
+
+	walk(tree)
+	return edges
+}
+
+// writeTree renders the tree as ASCII art, one module per line.
+func writeTree(out io.Writer, tree *treeNode) error {
+	var write func(node *treeNode, prefix, branch, indent string) error
+	write = func(node *treeNode, prefix, branch, indent string) error {
+		line := diagram.Name(node.module)
+		if node.constraint != "" {
+			line += "@" + node.constraint
+		}
+		if node.cycle {
+			line += " (cycle)"
+		} else if node.repeated {
+			line += " (*)"
+		}
+
+		if _, err := fmt.Fprintln(out, prefix+branch+line); err != nil {
+			return err
+		}
+
+		for i, child := range node.children {
+			childBranch, childIndent := "├── ", "│   "
+			if i == len(node.children)-1 {
+				childBranch, childIndent = "└── ", "    "
+			}
+
+			if err := write(child, prefix+indent, childBranch, childIndent); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return write(tree, "", "", "")
+}
+
+func TreeCommand(
+	dependencyClient tracker.DependencyServiceClient,
+	output *writer.Output,
+) *cobra.Command {
+	req := &tracker.DependencyRequest{}
+	excludedScopes := make([]string, 0)
+	depth := 3
+	dependents := false
+
+	cmd := &cobra.Command{
+		Use:   "tree",
+		Short: "Render the transitive dependencies (or dependents) of the given module as a tree",
+		Example: strings.Join([]string{
+			"deps get tree -l go -n github.com/depscloud/api",
+			"deps get tree -l go -o github.com -m depscloud/api --depth 5",
+			"deps get tree -l go -n github.com/depscloud/api --dependents --exclude-scopes test",
+			"deps get tree -l go -n github.com/depscloud/api --format mermaid",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if req.Language == "" && ((req.Organization == "" || req.Module == "") || req.Name == "") {
+				return fmt.Errorf("language + name or language + organization + module must be provided")
+			}
+
+			list := func(ctx context.Context, req *tracker.DependencyRequest) ([]*tracker.Dependency, error) {
+				response, err := dependencyClient.ListDependencies(ctx, req)
+				return response.GetDependencies(), err
+			}
+
+			if dependents {
+				list = func(ctx context.Context, req *tracker.DependencyRequest) ([]*tracker.Dependency, error) {
+					response, err := dependencyClient.ListDependents(ctx, req)
+					return response.GetDependents(), err
+				}
+			}
+
+			root := requestToModule(setRequestFields(req))
+
+			tree, err := buildTree(cmd.Context(), list, root, depth, excludedScopes)
+			if err != nil {
+				return err
+			}
+
+			if output.Format == "" {
+				return writeTree(output.Out(), tree)
+			}
+
+			edges := treeEdges(tree, dependents)
+			if rendersDiagram(output) {
+				return diagram.Write(output.Out(), output.Format, edges)
+			}
+
+			for _, edge := range edges {
+				if err := output.Write(edge); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	addDependencyRequestFlags(cmd, req)
+	addScopeFlags(cmd, &excludedScopes)
+
+	flags := cmd.Flags()
+	flags.IntVar(&depth, "depth", depth, "How many levels of the tree to render, 0 for all of them")
+	flags.BoolVar(&dependents, "dependents", dependents, "Render the modules that depend on the module instead")
+
+	return cmd
+}
@@ -0,0 +1,65 @@
+package get
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_buildTree(t *testing.T) {
+	module := func(name string) *schema.Module {
+		return &schema.Module{Language: "go", Organization: "github.com", Module: name, Name: "github.com/" + name}
+	}
+
+	edge := func(name, constraint string, scopes ...string) *tracker.Dependency {
+		return &tracker.Dependency{
+			Module:  module(name),
+			Depends: &schema.Depends{VersionConstraint: constraint, Scopes: scopes},
+		}
+	}
+
+	graph := map[string][]*tracker.Dependency{
+		"a/app":  {edge("a/lib", "v1.0.0"), edge("a/util", "v0.2.0"), edge("a/testing", "v1.1.0", "test")},
+		"a/lib":  {edge("a/util", "v0.2.0"), edge("a/app", "v0.1.0")},
+		"a/util": {edge("a/deep", "v2.0.0")},
+	}
+
+	list := func(ctx context.Context, req *tracker.DependencyRequest) ([]*tracker.Dependency, error) {
+		return graph[req.Module], nil
+	}
+
+	tree, err := buildTree(context.Background(), list, module("a/app"), 0, []string{"test"})
+	require.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, writeTree(out, tree))
+	require.Equal(t, `github.com/a/app
+├── github.com/a/lib@v1.0.0
+│   ├── github.com/a/util@v0.2.0
+│   │   └── github.com/a/deep@v2.0.0
+│   └── github.com/a/app@v0.1.0 (cycle)
+└── github.com/a/util@v0.2.0 (*)
+`, out.String())
+
+	tree, err = buildTree(context.Background(), list, module("a/app"), 1, nil)
+	require.NoError(t, err)
+
+	out = &bytes.Buffer{}
+	require.NoError(t, writeTree(out, tree))
+	require.Equal(t, `github.com/a/app
+├── github.com/a/lib@v1.0.0
+├── github.com/a/util@v0.2.0
+└── github.com/a/testing@v1.1.0
+`, out.String())
+
+	edges := treeEdges(tree, true)
+	require.Len(t, edges, 3)
+	require.Equal(t, "a/lib", edges[0].From.Module)
+	require.Equal(t, "a/app", edges[0].To.Module)
+	require.Equal(t, "v1.0.0", edges[0].VersionConstraint)
+}
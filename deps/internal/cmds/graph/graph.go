@@ -0,0 +1,156 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/depscloud/depscloud/deps/internal/client"
+
+	"github.com/spf13/cobra"
+)
+
+func Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Export or import the entire graph",
+	}
+
+	cmd.AddCommand(exportCommand())
+	cmd.AddCommand(importCommand())
+
+	return cmd
+}
+
+func exportCommand() *cobra.Command {
+	format := ""
+	output := ""
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the entire graph to a file or stdout",
+		Example: `  deps graph export > graph.jsonl
+  deps graph export --format protobuf --output graph.pb`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var out io.Writer = cmd.OutOrStdout()
+			if output != "" && output != "-" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+
+			graphClient := newHTTPGraphClient()
+			return graphClient.Export(format, out)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "jsonl", "the format to export, jsonl or protobuf")
+	cmd.Flags().StringVar(&output, "output", "", "the file to write to, defaults to stdout")
+
+	return cmd
+}
+
+func importCommand() *cobra.Command {
+	format := ""
+	input := ""
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Read a graph written by export into the graph",
+		Example: `  deps graph import < graph.jsonl
+  deps graph import --format protobuf --input graph.pb`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var in io.Reader = cmd.InOrStdin()
+			if input != "" && input != "-" {
+				file, err := os.Open(input)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				in = file
+			}
+
+			graphClient := newHTTPGraphClient()
+			imported, err := graphClient.Import(format, in)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "imported %d items\n", imported)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "jsonl", "the format to import, jsonl or protobuf")
+	cmd.Flags().StringVar(&input, "input", "", "the file to read from, defaults to stdin")
+
+	return cmd
+}
+
+func newHTTPGraphClient() *httpGraphClient {
+	return &httpGraphClient{client: client.HTTPClient(), baseURL: client.GetSystemInfo().BaseURL}
+}
+
+type httpGraphClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (s *httpGraphClient) uri(action, format string) string {
+	return fmt.Sprintf("%s/v1alpha/graph/%s?format=%s", s.baseURL, action, url.QueryEscape(format))
+}
+
+func (s *httpGraphClient) Export(format string, out io.Writer) error {
+	r, err := s.client.Get(s.uri("export", format))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return readError(r)
+	}
+
+	_, err = io.Copy(out, r.Body)
+	return err
+}
+
+func (s *httpGraphClient) Import(format string, in io.Reader) (int, error) {
+	r, err := s.client.Post(s.uri("import", format), "application/octet-stream", in)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return 0, readError(r)
+	}
+
+	response := struct {
+		Imported int `json:"imported"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+		return 0, err
+	}
+
+	return response.Imported, nil
+}
+
+func readError(r *http.Response) error {
+	response := struct {
+		Error string `json:"error"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil || response.Error == "" {
+		return fmt.Errorf("unexpected status %s", r.Status)
+	}
+
+	return fmt.Errorf("%s", response.Error)
+}
@@ -0,0 +1,217 @@
+package licenses
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/client"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+	"github.com/depscloud/depscloud/internal/filters"
+
+	"github.com/spf13/cobra"
+)
+
+// Unknown groups the modules without a license.
+const Unknown = "UNKNOWN"
+
+// License is a license used by the dependencies of an organization, along with
+// the modules using it.
+type License struct {
+	License string   `json:"license"`
+	Count   int      `json:"count"`
+	Denied  bool     `json:"denied"`
+	Modules []string `json:"modules"`
+}
+
+// listDependencies returns the distinct modules the organization depends on,
+// leaving out the organization's own modules.
+func listDependencies(
+	ctx context.Context,
+	modulesClient tracker.ModuleServiceClient,
+	dependencyClient tracker.DependencyServiceClient,
+	language, organization string,
+) ([]*schema.Module, error) {
+	ctx = (&filters.Filter{Language: language, Organization: organization}).AppendToOutgoingContext(ctx)
+
+	pageSize := 100
+	owned := make([]*schema.Module, 0)
+
+	for i := 1; true; i++ {
+		response, err := modulesClient.List(ctx, &tracker.ListRequest{
+			Page:  int32(i),
+			Count: int32(pageSize),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, module := range response.GetModules() {
+			// older deployments don't support filters
+			if module.Organization == organization && (language == "" || module.Language == language) {
+				owned = append(owned, module)
+			}
+		}
+
+		if len(response.GetModules()) < pageSize {
+			break
+		}
+	}
+
+	seen := make(map[string]bool)
+	dependencies := make([]*schema.Module, 0)
+
+	for _, module := range owned {
+		response, err := dependencyClient.ListDependencies(ctx, &tracker.DependencyRequest{
+			Language:     module.Language,
+			Organization: module.Organization,
+			Module:       module.Module,
+			Name:         module.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dependency := range response.GetDependencies() {
+			dependencyModule := dependency.GetModule()
+			if dependencyModule.GetOrganization() == organization {
+				continue
+			}
+
+			key := dependencyModule.GetLanguage() + "|" + diagram.Name(dependencyModule)
+			if !seen[key] {
+				seen[key] = true
+				dependencies = append(dependencies, dependencyModule)
+			}
+		}
+	}
+
+	return dependencies, nil
+}
+
+// inventory groups the modules by the license found in their labels, most
+// used first. Licenses matching one of denied are flagged, ignoring case.
+func inventory(
+	ctx context.Context,
+	labels client.LabelClient,
+	label string,
+	modules []*schema.Module,
+	denied []string,
+) ([]*License, error) {
+	deny := make(map[string]bool, len(denied))
+	for _, license := range denied {
+		deny[strings.ToLower(license)] = true
+	}
+
+	byLicense := make(map[string]*License)
+
+	for _, module := range modules {
+		moduleLabels, err := labels.ModuleLabels(ctx, module)
+		if err != nil {
+			return nil, err
+		}
+
+		license := strings.TrimSpace(moduleLabels[label])
+		if license == "" {
+			license = Unknown
+		}
+
+		entry, ok := byLicense[license]
+		if !ok {
+			entry = &License{
+				License: license,
+				Denied:  deny[strings.ToLower(license)],
+			}
+			byLicense[license] = entry
+		}
+
+		entry.Count++
+		entry.Modules = append(entry.Modules, module.Language+":"+diagram.Name(module))
+	}
+
+	result := make([]*License, 0, len(byLicense))
+	for _, entry := range byLicense {
+		sort.Strings(entry.Modules)
+		result = append(result, entry)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].License < result[j].License
+	})
+
+	return result, nil
+}
+
+func Command(
+	modulesClient tracker.ModuleServiceClient,
+	dependencyClient tracker.DependencyServiceClient,
+	labelClient client.LabelClient,
+	writer writer.Writer,
+) *cobra.Command {
+	language := ""
+	organization := ""
+	label := "license"
+	denied := make([]string, 0)
+
+	cmd := &cobra.Command{
+		Use:     "licenses",
+		Aliases: []string{"license"},
+		Short:   "Report the licenses of an organization's dependencies",
+		Long: strings.TrimSpace(`
+Report the licenses used by the dependencies of an organization's modules,
+grouped by license with the number of modules using each. Licenses are read
+from a label on each module. Modules without one are reported as UNKNOWN. When
+any dependency uses a denied license, the command fails after writing the
+report.`),
+		Example: strings.Join([]string{
+			"deps licenses -o github.com",
+			"deps licenses -l go -o github.com --deny GPL-3.0,AGPL-3.0",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if organization == "" {
+				return fmt.Errorf("organization must be provided")
+			}
+
+			ctx := cmd.Context()
+
+			modules, err := listDependencies(ctx, modulesClient, dependencyClient, language, organization)
+			if err != nil {
+				return err
+			}
+
+			licenses, err := inventory(ctx, labelClient, label, modules, denied)
+			if err != nil {
+				return err
+			}
+
+			offending := 0
+			for _, license := range licenses {
+				_ = writer.Write(license)
+
+				if license.Denied {
+					offending += license.Count
+				}
+			}
+
+			if offending > 0 {
+				return fmt.Errorf("%d dependencies use a denied license", offending)
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&language, "language", "l", language, "Only report on the organization's modules for the language")
+	flags.StringVarP(&organization, "organization", "o", organization, "The organization whose dependencies are reported on")
+	flags.StringVar(&label, "label", label, "The module label holding the license")
+	flags.StringSliceVar(&denied, "deny", denied, "Licenses that aren't allowed, failing the command when used")
+
+	return cmd
+}
@@ -0,0 +1,53 @@
+package licenses
+
+import (
+	"context"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/fakes"
+
+	"github.com/stretchr/testify/require"
+)
+
+func module(organization, name string) *schema.Module {
+	return &schema.Module{Language: "node", Organization: organization, Module: name}
+}
+
+func TestInventory(t *testing.T) {
+	ctx := context.Background()
+
+	modules := &fakes.Modules{
+		Modules: []*schema.Module{
+			module("acme", "app"),
+			module("acme", "lib"),
+			module("other", "app"),
+		},
+	}
+
+	dependencies := &fakes.Dependencies{
+		Dependencies: map[string][]*tracker.Dependency{
+			"app": {fakes.ModuleEdge(module("acme", "lib"), ""), fakes.ModuleEdge(module("", "express"), ""), fakes.ModuleEdge(module("", "left-pad"), "")},
+			"lib": {fakes.ModuleEdge(module("", "express"), ""), fakes.ModuleEdge(module("", "gpl-thing"), ""), fakes.ModuleEdge(module("", "mystery"), "")},
+		},
+	}
+
+	listed, err := listDependencies(ctx, modules, dependencies, "", "acme")
+	require.NoError(t, err)
+	require.Len(t, listed, 4)
+
+	labels := fakes.Licenses{
+		"express":   "MIT",
+		"left-pad":  "MIT",
+		"gpl-thing": "GPL-3.0",
+	}
+
+	licenses, err := inventory(ctx, labels, "license", listed, []string{"gpl-3.0"})
+	require.NoError(t, err)
+	require.Equal(t, []*License{
+		{License: "MIT", Count: 2, Modules: []string{"node:express", "node:left-pad"}},
+		{License: "GPL-3.0", Count: 1, Denied: true, Modules: []string{"node:gpl-thing"}},
+		{License: Unknown, Count: 1, Modules: []string{"node:mystery"}},
+	}, licenses)
+}
@@ -0,0 +1,147 @@
+package login
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/depscloud/depscloud/deps/internal/client"
+
+	"github.com/spf13/cobra"
+)
+
+// contextName returns the context credentials are stored for, defaulting to
+// the one in use.
+func contextName(name string) string {
+	if name != "" {
+		return name
+	}
+	if current := client.GetSystemInfo().Context; current != "" {
+		return current
+	}
+	return client.DefaultContextName
+}
+
+func Command(store client.CredentialStore) *cobra.Command {
+	name := ""
+	token := ""
+	tokenStdin := false
+	flow := &client.DeviceFlow{
+		Scopes: []string{"openid", "offline_access"},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Authenticate with a deployment",
+		Long: strings.TrimSpace(`
+Authenticate with a deployment using an API token or an OIDC provider. With an
+issuer, a code is shown to approve from a browser on any device, using the
+device authorization grant. Credentials are stored in the system keychain where
+one is available, otherwise in a file only readable by the current user, and
+are sent with each request to the context they were stored for. Tokens from an
+OIDC provider are refreshed as they expire.`),
+		Example: strings.Join([]string{
+			"deps login --issuer https://accounts.example.com --client-id deps-cli",
+			"deps login --token-stdin < token.txt",
+			"deps login --context staging --token $TOKEN",
+		}, "\n"),
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context := contextName(name)
+			out := cmd.OutOrStdout()
+
+			if tokenStdin {
+				line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				if err != nil && line == "" {
+					return fmt.Errorf("failed to read token from stdin: %v", err)
+				}
+				token = strings.TrimSpace(line)
+			}
+
+			var credentials *client.Credentials
+
+			switch {
+			case token != "" && flow.Issuer != "":
+				return fmt.Errorf("only one of a token or an issuer can be provided")
+
+			case token != "":
+				credentials = &client.Credentials{AccessToken: token}
+
+			case flow.Issuer != "":
+				if flow.ClientID == "" {
+					return fmt.Errorf("client-id must be provided with an issuer")
+				}
+
+				ctx := cmd.Context()
+
+				authorization, err := flow.Start(ctx)
+				if err != nil {
+					return err
+				}
+
+				if authorization.VerificationURIComplete != "" {
+					fmt.Fprintf(out, "To log in, visit %s and confirm the code %s\n",
+						authorization.VerificationURIComplete, authorization.UserCode)
+				} else {
+					fmt.Fprintf(out, "To log in, visit %s and enter the code %s\n",
+						authorization.VerificationURI, authorization.UserCode)
+				}
+
+				credentials, err = flow.Wait(ctx, authorization)
+				if err != nil {
+					return err
+				}
+
+			default:
+				return fmt.Errorf("a token or an issuer must be provided")
+			}
+
+			if err := store.Set(context, credentials); err != nil {
+				return fmt.Errorf("failed to store credentials: %v", err)
+			}
+
+			fmt.Fprintf(out, "Logged in to context %s, credentials stored in %s\n", context, store.Name())
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&name, "context", name, "The context to log in to, defaulting to the current context")
+	flags.StringVar(&token, "token", token, "An API token to authenticate with")
+	flags.BoolVar(&tokenStdin, "token-stdin", tokenStdin, "Read the API token from stdin")
+	flags.StringVar(&flow.Issuer, "issuer", flow.Issuer, "The url of the OIDC provider to log in with")
+	flags.StringVar(&flow.ClientID, "client-id", flow.ClientID, "The client id registered with the OIDC provider")
+	flags.StringSliceVar(&flow.Scopes, "scopes", flow.Scopes, "The scopes to request from the OIDC provider")
+
+	return cmd
+}
+
+func LogoutCommand(store client.CredentialStore) *cobra.Command {
+	name := ""
+
+	cmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the stored credentials of a deployment",
+		Example: strings.Join([]string{
+			"deps logout",
+			"deps logout --context staging",
+		}, "\n"),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context := contextName(name)
+
+			if err := store.Delete(context); err != nil {
+				return fmt.Errorf("failed to remove credentials: %v", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Logged out of context %s\n", context)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&name, "context", name, "The context to log out of, defaulting to the current context")
+
+	return cmd
+}
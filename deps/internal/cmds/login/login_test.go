@@ -0,0 +1,49 @@
+package login
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/depscloud/depscloud/deps/internal/client"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "login")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &client.FileCredentialStore{Path: filepath.Join(dir, "credentials.yaml")}
+	out := &bytes.Buffer{}
+
+	cmd := Command(store)
+	cmd.SetIn(strings.NewReader("secret\n"))
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"--context", "staging", "--token-stdin"})
+	require.NoError(t, cmd.Execute())
+	require.Contains(t, out.String(), "Logged in to context staging")
+
+	credentials, err := store.Get("staging")
+	require.NoError(t, err)
+	require.Equal(t, &client.Credentials{AccessToken: "secret"}, credentials)
+
+	cmd = Command(store)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{"--token", "secret", "--issuer", "https://accounts.example.com"})
+	require.EqualError(t, cmd.Execute(), "only one of a token or an issuer can be provided")
+
+	cmd = LogoutCommand(store)
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"--context", "staging"})
+	require.NoError(t, cmd.Execute())
+
+	credentials, err = store.Get("staging")
+	require.NoError(t, err)
+	require.Nil(t, credentials)
+}
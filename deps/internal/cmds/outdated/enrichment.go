@@ -0,0 +1,67 @@
+package outdated
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+	"github.com/depscloud/depscloud/internal/registries"
+)
+
+// enrichment reads the registry metadata the tracker recorded for the
+// dependencies of a module, keyed by language:name.
+type enrichment interface {
+	Dependencies(ctx context.Context, module *schema.Module) (map[string]*registries.Metadata, error)
+}
+
+// httpEnrichment reads the enriched dependencies of a module using the
+// registry api of the tracker.
+type httpEnrichment struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (e *httpEnrichment) Dependencies(ctx context.Context, module *schema.Module) (map[string]*registries.Metadata, error) {
+	query := url.Values{}
+	query.Set("language", module.GetLanguage())
+	query.Set("organization", module.GetOrganization())
+	query.Set("module", module.GetModule())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.baseURL+"/v1alpha/registry/dependencies?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	response := struct {
+		Dependencies []struct {
+			Module   *schema.Module       `json:"module"`
+			Registry *registries.Metadata `json:"registry"`
+		} `json:"dependencies"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]*registries.Metadata, len(response.Dependencies))
+	for _, dependency := range response.Dependencies {
+		if dependency.Registry != nil && dependency.Registry.Latest != "" {
+			metadata[dependency.Module.GetLanguage()+":"+diagram.Name(dependency.Module)] = dependency.Registry
+		}
+	}
+	return metadata, nil
+}
@@ -0,0 +1,228 @@
+package outdated
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/client"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+	"github.com/depscloud/depscloud/internal/constraints"
+	"github.com/depscloud/depscloud/internal/registries"
+
+	"github.com/spf13/cobra"
+)
+
+// systems maps languages to the dialect their version constraints are written
+// in.
+var systems = map[string]string{
+	"go":   "vgo",
+	"node": "npm",
+	"rust": "cargo",
+	"php":  "composer",
+}
+
+// Dependency describes how far a dependency of the source trails the latest
+// published version.
+type Dependency struct {
+	Module            string `json:"module"`
+	Dependent         string `json:"dependent"`
+	VersionConstraint string `json:"version_constraint"`
+	Current           string `json:"current"`
+	Latest            string `json:"latest"`
+	Behind            string `json:"behind,omitempty"`
+	Distance          int    `json:"distance,omitempty"`
+	Satisfied         bool   `json:"satisfied"`
+	Error             string `json:"error,omitempty"`
+}
+
+// checker looks up the latest versions of modules, remembering them since the
+// same module is often depended on by several modules of a source. Versions
+// already recorded by the tracker's registry enrichment are preferred, and
+// registries are only asked for modules it hasn't enriched.
+type checker struct {
+	registries *registries.Client
+	enrichment enrichment
+	latest     map[string]string
+	errors     map[string]error
+}
+
+// enrich remembers the latest versions the tracker recorded for the
+// dependencies of the module. Failures are ignored since the registries are
+// asked instead.
+func (c *checker) enrich(ctx context.Context, module *schema.Module) {
+	if c.enrichment == nil {
+		return
+	}
+
+	metadata, err := c.enrichment.Dependencies(ctx, module)
+	if err != nil {
+		return
+	}
+
+	for key, m := range metadata {
+		if _, ok := c.latest[key]; !ok {
+			c.latest[key] = m.Latest
+		}
+	}
+}
+
+func (c *checker) check(ctx context.Context, dependent *schema.Module, dependency *tracker.Dependency) *Dependency {
+	module := dependency.GetModule()
+	constraint := dependency.GetDepends().GetVersionConstraint()
+
+	result := &Dependency{
+		Module:            module.GetLanguage() + ":" + diagram.Name(module),
+		Dependent:         dependent.GetLanguage() + ":" + diagram.Name(dependent),
+		VersionConstraint: constraint,
+	}
+
+	parsed, err := constraints.Parse(systems[module.GetLanguage()], constraint)
+	if err != nil {
+		result.Error = fmt.Sprintf("unable to read version constraint: %s", err.Error())
+		return result
+	}
+
+	result.Current = constraint
+	if !constraints.IsVersion(constraint) {
+		result.Current = parsed.Floor()
+	}
+
+	if !registries.Supported(module.GetLanguage()) {
+		result.Error = fmt.Sprintf("no registry is known for %s", module.GetLanguage())
+		return result
+	}
+
+	key := result.Module
+	if _, ok := c.latest[key]; !ok && c.errors[key] == nil {
+		metadata, err := c.registries.Lookup(ctx, module)
+		switch {
+		case err != nil:
+			c.errors[key] = err
+		case metadata == nil:
+			c.errors[key] = fmt.Errorf("no release is published")
+		default:
+			c.latest[key] = metadata.Latest
+		}
+	}
+
+	if err := c.errors[key]; err != nil {
+		result.Error = fmt.Sprintf("unable to find the latest version: %s", err.Error())
+		return result
+	}
+
+	result.Latest = c.latest[key]
+	result.Satisfied = parsed.Satisfies(result.Latest)
+	result.Behind, result.Distance = constraints.Behind(result.Current, result.Latest)
+
+	return result
+}
+
+// outdated checks the dependencies of each module managed by the source. The
+// results are ordered by module and then dependency.
+func outdated(
+	ctx context.Context,
+	modulesClient tracker.ModuleServiceClient,
+	dependencyClient tracker.DependencyServiceClient,
+	c *checker,
+	sourceURL string,
+) ([]*Dependency, error) {
+	managed, err := modulesClient.ListManaged(ctx, &schema.Source{Url: sourceURL})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(managed.GetModules()) == 0 {
+		return nil, fmt.Errorf("no modules are known for source %s", sourceURL)
+	}
+
+	results := make([]*Dependency, 0)
+
+	for _, managedModule := range managed.GetModules() {
+		module := managedModule.GetModule()
+		c.enrich(ctx, module)
+
+		response, err := dependencyClient.ListDependencies(ctx, &tracker.DependencyRequest{
+			Language:     module.Language,
+			Organization: module.Organization,
+			Module:       module.Module,
+			Name:         module.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dependency := range response.GetDependencies() {
+			results = append(results, c.check(ctx, module, dependency))
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Dependent != results[j].Dependent {
+			return results[i].Dependent < results[j].Dependent
+		}
+		return results[i].Module < results[j].Module
+	})
+
+	return results, nil
+}
+
+func Command(
+	modulesClient tracker.ModuleServiceClient,
+	dependencyClient tracker.DependencyServiceClient,
+	writer writer.Writer,
+) *cobra.Command {
+	sourceURL := ""
+	all := false
+
+	cmd := &cobra.Command{
+		Use:   "outdated",
+		Short: "Report the dependencies of a source that trail their latest version",
+		Long: strings.TrimSpace(`
+Report the dependencies of a source that trail the latest version published to
+their registry, along with how far behind each one is. The current version is
+the pinned version, or the lowest version its constraint allows. The latest
+versions recorded by the tracker's registry enrichment are used when present,
+and the registries are asked otherwise. Go, node, python, java, rust, php, and
+r dependencies are supported.`),
+		Example: strings.Join([]string{
+			"deps outdated --source https://github.com/depscloud/depscloud.git",
+			"deps outdated --source https://github.com/depscloud/depscloud.git --all",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if sourceURL == "" {
+				return fmt.Errorf("source must be provided")
+			}
+
+			c := &checker{
+				registries: registries.NewClient("deps", nil),
+				enrichment: &httpEnrichment{client: client.HTTPClient(), baseURL: client.GetSystemInfo().BaseURL},
+				latest:     make(map[string]string),
+				errors:     make(map[string]error),
+			}
+
+			results, err := outdated(cmd.Context(), modulesClient, dependencyClient, c, sourceURL)
+			if err != nil {
+				return err
+			}
+
+			for _, result := range results {
+				if all || result.Behind != "" || result.Error != "" {
+					_ = writer.Write(result)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&sourceURL, "source", sourceURL, "The url of the source to check")
+	flags.BoolVar(&all, "all", all, "Include dependencies that are up to date")
+
+	return cmd
+}
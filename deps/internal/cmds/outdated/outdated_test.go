@@ -0,0 +1,109 @@
+package outdated
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/fakes"
+	"github.com/depscloud/depscloud/internal/registries"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutdated(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1alpha/registry/dependencies", func(w http.ResponseWriter, r *http.Request) {
+		// only the go module has been enriched by the tracker
+		if r.URL.Query().Get("language") != "go" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"dependencies":[{"module":{"language":"go","name":"github.com/spf13/cobra"},"registry":{"latest":"v1.1.1"}}]}`))
+	})
+	mux.HandleFunc("/go/github.com/!sirupsen/logrus/@latest", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Version":"v1.7.0"}`))
+	})
+	mux.HandleFunc("/node/@types/node", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"dist-tags":{"latest":"14.14.0","next":"15.0.0-rc.1"}}`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := &checker{
+		registries: registries.NewClient("deps", map[string]string{
+			"go":   server.URL + "/go",
+			"node": server.URL + "/node",
+		}),
+		enrichment: &httpEnrichment{client: server.Client(), baseURL: server.URL},
+		latest:     make(map[string]string),
+		errors:     make(map[string]error),
+	}
+
+	modules := &fakes.Modules{
+		Managed: []*tracker.ManagedModule{
+			{Module: &schema.Module{Language: "go", Name: "github.com/depscloud/depscloud"}},
+			{Module: &schema.Module{Language: "node", Name: "web"}},
+		},
+	}
+
+	dependencies := &fakes.Dependencies{
+		Dependencies: map[string][]*tracker.Dependency{
+			"github.com/depscloud/depscloud": {
+				fakes.Edge("go", "github.com/spf13/cobra", "v1.0.0"),
+				fakes.Edge("go", "github.com/Sirupsen/logrus", "v1.7.0"),
+				fakes.Edge("go", "github.com/missing/module", "v0.1.0"),
+			},
+			"web": {
+				fakes.Edge("node", "@types/node", "^12.0.0"),
+				fakes.Edge("ruby", "rails", "6.0.3"),
+			},
+		},
+	}
+
+	results, err := outdated(ctx, modules, dependencies, c, "https://github.com/depscloud/depscloud.git")
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	require.Equal(t, &Dependency{
+		Module:            "go:github.com/Sirupsen/logrus",
+		Dependent:         "go:github.com/depscloud/depscloud",
+		VersionConstraint: "v1.7.0",
+		Current:           "v1.7.0",
+		Latest:            "v1.7.0",
+		Satisfied:         true,
+	}, results[0])
+
+	require.Equal(t, "go:github.com/missing/module", results[1].Module)
+	require.Equal(t, "unable to find the latest version: no release is published", results[1].Error)
+
+	require.Equal(t, &Dependency{
+		Module:            "go:github.com/spf13/cobra",
+		Dependent:         "go:github.com/depscloud/depscloud",
+		VersionConstraint: "v1.0.0",
+		Current:           "v1.0.0",
+		Latest:            "v1.1.1",
+		Behind:            "minor",
+		Distance:          1,
+		Satisfied:         true,
+	}, results[2])
+
+	require.Equal(t, &Dependency{
+		Module:            "node:@types/node",
+		Dependent:         "node:web",
+		VersionConstraint: "^12.0.0",
+		Current:           "12.0.0",
+		Latest:            "14.14.0",
+		Behind:            "major",
+		Distance:          2,
+		Satisfied:         false,
+	}, results[3])
+
+	require.Equal(t, "no registry is known for ruby", results[4].Error)
+}
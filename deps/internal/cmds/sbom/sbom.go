@@ -0,0 +1,115 @@
+package sbom
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/sbom"
+
+	"github.com/spf13/cobra"
+)
+
+// collect assembles the modules managed by the source, along with their
+// dependencies.
+func collect(ctx context.Context, modulesClient tracker.ModuleServiceClient, dependencyClient tracker.DependencyServiceClient, sourceURL string) (*sbom.BOM, error) {
+	managed, err := modulesClient.ListManaged(ctx, &schema.Source{Url: sourceURL})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(managed.GetModules()) == 0 {
+		return nil, fmt.Errorf("no modules are known for source %s", sourceURL)
+	}
+
+	b := sbom.New(sourceURL)
+
+	for _, managedModule := range managed.GetModules() {
+		module := managedModule.GetModule()
+
+		response, err := dependencyClient.ListDependencies(ctx, &tracker.DependencyRequest{
+			Language:     module.Language,
+			Organization: module.Organization,
+			Module:       module.Module,
+			Name:         module.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		dependencies := make([]*sbom.Component, 0, len(response.GetDependencies()))
+		for _, dependency := range response.GetDependencies() {
+			dependencies = append(dependencies, sbom.NewComponent(dependency.GetModule(), dependency.GetDepends().GetVersionConstraint()))
+		}
+
+		b.DependsOn(sbom.NewComponent(module, ""), dependencies)
+	}
+
+	b.Sort()
+	return b, nil
+}
+
+func Command(
+	modulesClient tracker.ModuleServiceClient,
+	dependencyClient tracker.DependencyServiceClient,
+	version string,
+) *cobra.Command {
+	sourceURL := ""
+	format := sbom.FormatCycloneDX
+	output := ""
+
+	cmd := &cobra.Command{
+		Use:   "sbom",
+		Short: "Write a software bill of materials for a source",
+		Long: strings.TrimSpace(`
+Write a software bill of materials for a source, listing the modules it
+manages and the modules they depend on. Versions are only included when a
+dependency pins one exactly, otherwise its version constraint is kept.`),
+		Example: strings.Join([]string{
+			"deps sbom --source https://github.com/depscloud/depscloud.git",
+			"deps sbom --source https://github.com/depscloud/depscloud.git --format spdx --output sbom.spdx.json",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if sourceURL == "" {
+				return fmt.Errorf("source must be provided")
+			}
+
+			if err := sbom.Validate(format); err != nil {
+				return err
+			}
+
+			b, err := collect(cmd.Context(), modulesClient, dependencyClient, sourceURL)
+			if err != nil {
+				return err
+			}
+
+			doc, err := sbom.NewDocument("deps", version)
+			if err != nil {
+				return err
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			if output != "" && output != "-" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+
+			return sbom.Write(out, format, b, doc)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&sourceURL, "source", sourceURL, "The url of the source to describe")
+	flags.StringVar(&format, "format", format, "The format of the SBOM, "+sbom.FormatCycloneDX+" or "+sbom.FormatSPDX)
+	flags.StringVar(&output, "output", output, "The file to write to, defaults to stdout")
+
+	return cmd
+}
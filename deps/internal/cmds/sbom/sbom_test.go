@@ -0,0 +1,39 @@
+package sbom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/deps/internal/fakes"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	ctx := context.Background()
+
+	modules := &fakes.Modules{
+		Managed: []*tracker.ManagedModule{
+			{Module: &schema.Module{Language: "go", Name: "github.com/depscloud/depscloud"}},
+		},
+	}
+
+	dependencies := &fakes.Dependencies{
+		Dependencies: map[string][]*tracker.Dependency{
+			"github.com/depscloud/depscloud": {
+				fakes.Edge("go", "github.com/depscloud/api", "v0.1.0"),
+				fakes.Edge("go", "github.com/spf13/cobra", "^1.0.0"),
+			},
+		},
+	}
+
+	b, err := collect(ctx, modules, dependencies, "https://github.com/depscloud/depscloud.git")
+	require.NoError(t, err)
+	require.Len(t, b.Components, 3)
+	require.Equal(t, []string{
+		"pkg:golang/github.com/depscloud/api@v0.1.0",
+		"pkg:golang/github.com/spf13/cobra?constraint=%5E1.0.0",
+	}, b.Dependencies["pkg:golang/github.com/depscloud/depscloud"])
+}
@@ -0,0 +1,145 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/depscloud/depscloud/deps/internal/client"
+	"github.com/depscloud/depscloud/deps/internal/diagram"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+
+	"github.com/spf13/cobra"
+)
+
+// maxLimit is the most results the api returns for a search.
+const maxLimit = 100
+
+// Result is a module or source matching the search, best match first. Modules
+// set their language and name while sources set their url.
+type Result struct {
+	Rank         int               `json:"rank"`
+	Type         string            `json:"type"`
+	Language     string            `json:"language,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	URL          string            `json:"url,omitempty"`
+	Matched      string            `json:"matched"`
+	Dependents   int64             `json:"dependents"`
+	LastObserved *time.Time        `json:"last_observed,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// rank numbers the results in the order the api ranked them. Modules of other
+// languages are dropped, after which at most limit results are kept.
+func rank(results []*client.TextSearchResult, language string, limit int) []*Result {
+	ranked := make([]*Result, 0, len(results))
+
+	for _, result := range results {
+		if len(ranked) == limit {
+			break
+		}
+
+		r := &Result{
+			Type:         result.Type,
+			Matched:      result.Field,
+			Dependents:   result.Dependents,
+			LastObserved: result.LastObserved,
+			Labels:       result.Labels,
+		}
+
+		if module := result.Module; module != nil {
+			if language != "" && module.Language != language {
+				continue
+			}
+
+			r.Language = module.Language
+			r.Name = diagram.Name(module)
+		} else if source := result.Source; source != nil {
+			r.URL = source.Url
+		}
+
+		r.Rank = len(ranked) + 1
+		ranked = append(ranked, r)
+	}
+
+	return ranked
+}
+
+func Command(
+	searchClient client.TextSearchClient,
+	writer writer.Writer,
+) *cobra.Command {
+	language := ""
+	fuzzy := false
+	prefix := false
+	sources := false
+	limit := 20
+
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search for modules or sources by name",
+		Long: strings.TrimSpace(`
+Search for modules by name, or sources by url, along with their labels. Results
+are ranked by how closely they match, then by their number of dependents. By
+default, the query can appear anywhere in the name. With --fuzzy, the characters
+of the query only need to appear in order.`),
+		Example: strings.Join([]string{
+			"deps search depscloud",
+			"deps search --language go --prefix github.com/depscloud",
+			"deps search --fuzzy dpsapi",
+			"deps search --sources depscloud",
+		}, "\n"),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if fuzzy && prefix {
+				return fmt.Errorf("only one of --fuzzy or --prefix can be provided")
+			}
+
+			if limit < 1 || limit > maxLimit {
+				return fmt.Errorf("limit must be between 1 and %d", maxLimit)
+			}
+
+			req := &client.TextSearchRequest{
+				Query: strings.Join(args, " "),
+				Type:  "module",
+				Mode:  client.MatchSubstring,
+				Limit: limit,
+			}
+
+			if sources {
+				req.Type = "source"
+			}
+
+			if fuzzy {
+				req.Mode = client.MatchFuzzy
+			} else if prefix {
+				req.Mode = client.MatchPrefix
+			}
+
+			// languages are filtered here, so ask for as many results as possible
+			if language != "" {
+				req.Limit = maxLimit
+			}
+
+			results, err := searchClient.Search(cmd.Context(), req)
+			if err != nil {
+				return err
+			}
+
+			for _, result := range rank(results, language, limit) {
+				_ = writer.Write(result)
+			}
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&language, "language", "l", language, "Only include modules of the language")
+	flags.BoolVar(&fuzzy, "fuzzy", fuzzy, "Match names containing the characters of the query in order")
+	flags.BoolVar(&prefix, "prefix", prefix, "Match names starting with the query")
+	flags.BoolVar(&sources, "sources", sources, "Search the urls of sources instead of modules")
+	flags.IntVar(&limit, "limit", limit, "The most results to return")
+
+	return cmd
+}
@@ -0,0 +1,32 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/deps/internal/client"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRank(t *testing.T) {
+	results := []*client.TextSearchResult{
+		{Type: "module", Module: &schema.Module{Language: "go", Name: "github.com/depscloud/api"}, Field: "name", Dependents: 4},
+		{Type: "module", Module: &schema.Module{Language: "node", Organization: "depscloud", Module: "api"}, Field: "name", Dependents: 2},
+		{Type: "module", Module: &schema.Module{Language: "go", Name: "github.com/depscloud/depscloud"}, Field: "labels.team"},
+		{Type: "source", Source: &schema.Source{Url: "https://github.com/depscloud/api.git"}, Field: "url"},
+	}
+
+	ranked := rank(results, "", 3)
+	require.Len(t, ranked, 3)
+	require.Equal(t, &Result{Rank: 2, Type: "module", Language: "node", Name: "depscloud/api", Matched: "name", Dependents: 2}, ranked[1])
+
+	ranked = rank(results[:3], "go", 20)
+	require.Len(t, ranked, 2)
+	require.Equal(t, 2, ranked[1].Rank)
+	require.Equal(t, "github.com/depscloud/depscloud", ranked[1].Name)
+	require.Equal(t, "labels.team", ranked[1].Matched)
+
+	ranked = rank(results[3:], "", 20)
+	require.Equal(t, &Result{Rank: 1, Type: "source", URL: "https://github.com/depscloud/api.git", Matched: "url"}, ranked[0])
+}
@@ -0,0 +1,284 @@
+package serviceaccounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/depscloud/depscloud/deps/internal/client"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/serviceaccounts"
+
+	"github.com/spf13/cobra"
+)
+
+// Client manages service accounts and their tokens using the http api.
+type Client interface {
+	ListAccounts() ([]*serviceaccounts.Account, error)
+	PutAccount(name, description string) ([]*serviceaccounts.Account, error)
+	DeleteAccount(name string) ([]*serviceaccounts.Account, error)
+	ListTokens(account string) ([]*serviceaccounts.Token, error)
+	IssueToken(account string, req *serviceaccounts.TokenRequest) (*serviceaccounts.IssuedResponse, error)
+	RotateToken(id string) (*serviceaccounts.IssuedResponse, error)
+	RevokeToken(id string) ([]*serviceaccounts.Token, error)
+}
+
+// Command returns the commands used to manage service accounts. A nil client
+// talks to the http api of the current context.
+func Command(serviceAccountClient Client, output *writer.Output) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "serviceaccounts",
+		Aliases: []string{"sa"},
+		Short:   "Manage service accounts and their tokens",
+		Example: `  deps serviceaccounts create indexer --description "indexes github.com"
+  deps serviceaccounts issue indexer --role editor --ttl 720h > indexer.token
+  deps serviceaccounts tokens indexer
+  deps serviceaccounts rotate 3f2a9c1d5e7b8a60 > indexer.token
+  deps serviceaccounts revoke 3f2a9c1d5e7b8a60`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if serviceAccountClient == nil {
+				serviceAccountClient = NewHTTPClient(client.HTTPClient(), client.GetSystemInfo().BaseURL)
+			}
+
+			if output.Format == "" {
+				return nil
+			}
+			return writer.Validate(output.Format)
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			return output.Flush()
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&(output.Format), "format", output.Format,
+		"The format to write results in ("+strings.Join(writer.Formats, ", ")+")")
+
+	writeAccounts := func(accounts []*serviceaccounts.Account, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, account := range accounts {
+			if err := output.Write(account); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	writeTokens := func(tokens []*serviceaccounts.Token, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			if err := output.Write(token); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// the secret is written alone so it can be redirected into a file
+	writeIssued := func(cmd *cobra.Command, issued *serviceaccounts.IssuedResponse, err error) error {
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.ErrOrStderr(), "issued token %s for %s, expiring %s. It can't be retrieved again.\n",
+			issued.Details.ID, issued.Details.Account, issued.Details.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"))
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), issued.Token)
+		return err
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the service accounts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeAccounts(serviceAccountClient.ListAccounts())
+		},
+	})
+
+	description := ""
+	createCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create or update a service account",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeAccounts(serviceAccountClient.PutAccount(args[0], description))
+		},
+	}
+	createCmd.Flags().StringVar(&description, "description", "", "what the service account is used for")
+	cmd.AddCommand(createCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a service account, revoking its tokens",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeAccounts(serviceAccountClient.DeleteAccount(args[0]))
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "tokens <name>",
+		Short: "List the tokens issued to a service account",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeTokens(serviceAccountClient.ListTokens(args[0]))
+		},
+	})
+
+	tokenRequest := &serviceaccounts.TokenRequest{}
+	role := string(rbac.RoleViewer)
+	issueCmd := &cobra.Command{
+		Use:   "issue <name>",
+		Short: "Issue a token to a service account, writing it to stdout",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parsed, err := rbac.ParseRole(role)
+			if err != nil {
+				return err
+			}
+			tokenRequest.Role = parsed
+
+			issued, err := serviceAccountClient.IssueToken(args[0], tokenRequest)
+			return writeIssued(cmd, issued, err)
+		},
+	}
+	issueCmd.Flags().StringVar(&role, "role", role, "the role granted by the token; viewer, editor, or admin")
+	issueCmd.Flags().StringVar(&(tokenRequest.Organization), "organization", "", "narrow the token to the modules of an organization")
+	issueCmd.Flags().StringVar(&(tokenRequest.TTL), "ttl", "", "how long the token is valid for, like 720h (default 2160h)")
+	cmd.AddCommand(issueCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rotate <token-id>",
+		Short: "Revoke a token, replacing it with one of the same scope",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			issued, err := serviceAccountClient.RotateToken(args[0])
+			return writeIssued(cmd, issued, err)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "revoke <token-id>",
+		Short: "Revoke a token",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeTokens(serviceAccountClient.RevokeToken(args[0]))
+		},
+	})
+
+	return cmd
+}
+
+// NewHTTPClient returns a client for the http api at the base url.
+func NewHTTPClient(client *http.Client, baseURL string) Client {
+	return &httpClient{client: client, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+type httpClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (c *httpClient) do(method, route string, params url.Values, body, response interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	uri := c.baseURL + serviceaccounts.RoutePrefix + route
+	if len(params) > 0 {
+		uri += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequest(method, uri, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	r, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return readError(r)
+	}
+
+	return json.NewDecoder(r.Body).Decode(response)
+}
+
+func (c *httpClient) accounts(method string, params url.Values, body interface{}) ([]*serviceaccounts.Account, error) {
+	response := &serviceaccounts.AccountsResponse{}
+	if err := c.do(method, "accounts", params, body, response); err != nil {
+		return nil, err
+	}
+	return response.Accounts, nil
+}
+
+func (c *httpClient) tokens(method string, params url.Values) ([]*serviceaccounts.Token, error) {
+	response := &serviceaccounts.TokensResponse{}
+	if err := c.do(method, "tokens", params, nil, response); err != nil {
+		return nil, err
+	}
+	return response.Tokens, nil
+}
+
+func (c *httpClient) ListAccounts() ([]*serviceaccounts.Account, error) {
+	return c.accounts(http.MethodGet, nil, nil)
+}
+
+func (c *httpClient) PutAccount(name, description string) ([]*serviceaccounts.Account, error) {
+	return c.accounts(http.MethodPut, url.Values{"name": {name}}, &serviceaccounts.AccountRequest{Description: description})
+}
+
+func (c *httpClient) DeleteAccount(name string) ([]*serviceaccounts.Account, error) {
+	return c.accounts(http.MethodDelete, url.Values{"name": {name}}, nil)
+}
+
+func (c *httpClient) ListTokens(account string) ([]*serviceaccounts.Token, error) {
+	return c.tokens(http.MethodGet, url.Values{"account": {account}})
+}
+
+func (c *httpClient) IssueToken(account string, req *serviceaccounts.TokenRequest) (*serviceaccounts.IssuedResponse, error) {
+	response := &serviceaccounts.IssuedResponse{}
+	if err := c.do(http.MethodPost, "tokens", url.Values{"account": {account}}, req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *httpClient) RotateToken(id string) (*serviceaccounts.IssuedResponse, error) {
+	response := &serviceaccounts.IssuedResponse{}
+	if err := c.do(http.MethodPost, "tokens/rotate", url.Values{"id": {id}}, nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *httpClient) RevokeToken(id string) ([]*serviceaccounts.Token, error) {
+	return c.tokens(http.MethodDelete, url.Values{"id": {id}})
+}
+
+func readError(r *http.Response) error {
+	response := struct {
+		Error string `json:"error"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil || response.Error == "" {
+		return fmt.Errorf("unexpected status %s", r.Status)
+	}
+
+	return fmt.Errorf("%s", response.Error)
+}
@@ -0,0 +1,95 @@
+package serviceaccounts_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/deps/internal/cmds/serviceaccounts"
+	"github.com/depscloud/depscloud/deps/internal/writer"
+	"github.com/depscloud/depscloud/internal/rbac"
+	api "github.com/depscloud/depscloud/internal/serviceaccounts"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	issuedAt := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	requests := make([]string, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+
+		switch r.URL.Path {
+		case "/v1alpha/serviceaccounts/accounts":
+			_ = json.NewEncoder(w).Encode(&api.AccountsResponse{Accounts: []*api.Account{
+				{Name: "indexer", CreatedAt: issuedAt},
+			}})
+		case "/v1alpha/serviceaccounts/tokens":
+			if r.Method == http.MethodGet {
+				_ = json.NewEncoder(w).Encode(&api.TokensResponse{Tokens: []*api.Token{
+					{ID: "3f2a9c1d5e7b8a60", Account: "indexer", Role: rbac.RoleEditor, CreatedAt: issuedAt, ExpiresAt: issuedAt.Add(time.Hour)},
+				}})
+				return
+			}
+
+			req := &api.TokenRequest{}
+			_ = json.NewDecoder(r.Body).Decode(req)
+			if req.Role != rbac.RoleEditor || req.TTL != "720h" {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "unexpected request"})
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(&api.IssuedResponse{
+				Token:   "dcsa_3f2a9c1d5e7b8a60_secret",
+				Details: &api.Token{ID: "3f2a9c1d5e7b8a60", Account: "indexer", Role: rbac.RoleEditor, ExpiresAt: issuedAt},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "token \"missing\" not found"})
+		}
+	}))
+	defer server.Close()
+
+	run := func(args ...string) (string, error) {
+		stdout := &bytes.Buffer{}
+		cmd := serviceaccounts.Command(serviceaccounts.NewHTTPClient(server.Client(), server.URL), writer.NewOutput(stdout))
+		cmd.SetOut(stdout)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+
+		err := cmd.Execute()
+		return stdout.String(), err
+	}
+
+	out, err := run("create", "indexer", "--description", "indexes github.com")
+	require.Nil(t, err)
+	require.Contains(t, out, `"name":"indexer"`)
+
+	// only the secret is written to stdout
+	out, err = run("issue", "indexer", "--role", "editor", "--ttl", "720h")
+	require.Nil(t, err)
+	require.Equal(t, "dcsa_3f2a9c1d5e7b8a60_secret\n", out)
+
+	out, err = run("tokens", "indexer", "--format", "csv")
+	require.Nil(t, err)
+	require.True(t, strings.HasPrefix(out, "id,account,role"))
+
+	_, err = run("issue", "indexer", "--role", "owner")
+	require.NotNil(t, err)
+
+	_, err = run("rotate", "missing")
+	require.EqualError(t, err, `token "missing" not found`)
+
+	require.Equal(t, []string{
+		"PUT /v1alpha/serviceaccounts/accounts?name=indexer",
+		"POST /v1alpha/serviceaccounts/tokens?account=indexer",
+		"GET /v1alpha/serviceaccounts/tokens?account=indexer",
+		"POST /v1alpha/serviceaccounts/tokens/rotate?id=missing",
+	}, requests)
+}
@@ -0,0 +1,130 @@
+package diagram
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+)
+
+// The formats a graph can be rendered in.
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{FormatDOT, FormatMermaid}
+
+// Edge is a dependency of one module on another, labeled with its version
+// constraint.
+type Edge struct {
+	From              *schema.Module `json:"from"`
+	To                *schema.Module `json:"to"`
+	VersionConstraint string         `json:"version_constraint"`
+}
+
+// Validate returns an error when the graph can't be rendered in the format.
+func Validate(format string) error {
+	if format != FormatDOT && format != FormatMermaid {
+		return fmt.Errorf("unsupported format %q, must be one of %s", format, strings.Join(Formats, ", "))
+	}
+	return nil
+}
+
+// Write renders the edges in the format.
+func Write(out io.Writer, format string, edges []Edge) error {
+	switch format {
+	case FormatDOT:
+		return WriteDOT(out, edges)
+	case FormatMermaid:
+		return WriteMermaid(out, edges)
+	}
+	return Validate(format)
+}
+
+// Name returns the name a module is displayed with.
+func Name(module *schema.Module) string {
+	if module.Name != "" {
+		return module.Name
+	}
+	if module.Organization == "" || module.Organization == "_" {
+		return module.Module
+	}
+	return module.Organization + "/" + module.Module
+}
+
+func id(module *schema.Module) string {
+	return module.Language + ":" + Name(module)
+}
+
+// nodes returns the distinct modules of the edges in the order they appear.
+func nodes(edges []Edge) []*schema.Module {
+	seen := make(map[string]bool)
+	modules := make([]*schema.Module, 0)
+
+	for _, edge := range edges {
+		for _, module := range []*schema.Module{edge.From, edge.To} {
+			if !seen[id(module)] {
+				seen[id(module)] = true
+				modules = append(modules, module)
+			}
+		}
+	}
+
+	return modules
+}
+
+func quoteDOT(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// WriteDOT renders the edges as a Graphviz digraph.
+func WriteDOT(out io.Writer, edges []Edge) error {
+	lines := []string{"digraph deps {"}
+
+	for _, module := range nodes(edges) {
+		lines = append(lines, fmt.Sprintf("  %s [label=%s];", quoteDOT(id(module)), quoteDOT(Name(module))))
+	}
+
+	for _, edge := range edges {
+		line := fmt.Sprintf("  %s -> %s", quoteDOT(id(edge.From)), quoteDOT(id(edge.To)))
+		if edge.VersionConstraint != "" {
+			line += " [label=" + quoteDOT(edge.VersionConstraint) + "]"
+		}
+		lines = append(lines, line+";")
+	}
+
+	lines = append(lines, "}")
+
+	_, err := fmt.Fprintln(out, strings.Join(lines, "\n"))
+	return err
+}
+
+func quoteMermaid(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, "#quot;") + `"`
+}
+
+// WriteMermaid renders the edges as a Mermaid flowchart. Modules are given
+// generated ids, since their names contain characters Mermaid doesn't allow.
+func WriteMermaid(out io.Writer, edges []Edge) error {
+	lines := []string{"graph LR"}
+	ids := make(map[string]string)
+
+	for i, module := range nodes(edges) {
+		ids[id(module)] = fmt.Sprintf("m%d", i)
+		lines = append(lines, fmt.Sprintf("  m%d[%s]", i, quoteMermaid(Name(module))))
+	}
+
+	for _, edge := range edges {
+		arrow := "-->"
+		if edge.VersionConstraint != "" {
+			arrow += "|" + quoteMermaid(edge.VersionConstraint) + "|"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %s", ids[id(edge.From)], arrow, ids[id(edge.To)]))
+	}
+
+	_, err := fmt.Fprintln(out, strings.Join(lines, "\n"))
+	return err
+}
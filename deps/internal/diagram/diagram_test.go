@@ -0,0 +1,48 @@
+package diagram
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	app := &schema.Module{Language: "go", Name: "github.com/a/app"}
+	lib := &schema.Module{Language: "go", Organization: "github.com", Module: "a/lib"}
+	util := &schema.Module{Language: "node", Name: `@a/"util"`}
+
+	edges := []Edge{
+		{From: app, To: lib, VersionConstraint: "v1.0.0"},
+		{From: lib, To: util},
+		{From: app, To: util, VersionConstraint: "^1.0.0 || ^2.0.0"},
+	}
+
+	out := &bytes.Buffer{}
+	require.NoError(t, Write(out, FormatDOT, edges))
+	require.Equal(t, `digraph deps {
+  "go:github.com/a/app" [label="github.com/a/app"];
+  "go:github.com/a/lib" [label="github.com/a/lib"];
+  "node:@a/\"util\"" [label="@a/\"util\""];
+  "go:github.com/a/app" -> "go:github.com/a/lib" [label="v1.0.0"];
+  "go:github.com/a/lib" -> "node:@a/\"util\"";
+  "go:github.com/a/app" -> "node:@a/\"util\"" [label="^1.0.0 || ^2.0.0"];
+}
+`, out.String())
+
+	out = &bytes.Buffer{}
+	require.NoError(t, Write(out, FormatMermaid, edges))
+	require.Equal(t, `graph LR
+  m0["github.com/a/app"]
+  m1["github.com/a/lib"]
+  m2["@a/#quot;util#quot;"]
+  m0 -->|"v1.0.0"| m1
+  m1 --> m2
+  m0 -->|"^1.0.0 || ^2.0.0"| m2
+`, out.String())
+
+	require.Error(t, Write(out, "svg", edges))
+	require.Error(t, Validate(""))
+}
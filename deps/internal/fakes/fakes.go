@@ -0,0 +1,83 @@
+// Package fakes provides in memory tracker clients and fixtures for testing
+// the deps commands without a running tracker.
+package fakes
+
+import (
+	"context"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+
+	"google.golang.org/grpc"
+)
+
+// key returns the name modules are recorded under, which is the name of the
+// module when it has one and the module otherwise.
+func key(name, module string) string {
+	if name != "" {
+		return name
+	}
+	return module
+}
+
+// Modules serves the modules listed by the tracker and those managed by a
+// source.
+type Modules struct {
+	tracker.ModuleServiceClient
+	Modules []*schema.Module
+	Managed []*tracker.ManagedModule
+}
+
+// List returns all of the modules.
+func (f *Modules) List(ctx context.Context, in *tracker.ListRequest, opts ...grpc.CallOption) (*tracker.ListModuleResponse, error) {
+	return &tracker.ListModuleResponse{Modules: f.Modules}, nil
+}
+
+// ListManaged returns the managed modules, regardless of the source.
+func (f *Modules) ListManaged(ctx context.Context, in *schema.Source, opts ...grpc.CallOption) (*tracker.ListManagedResponse, error) {
+	return &tracker.ListManagedResponse{Modules: f.Managed}, nil
+}
+
+// Dependencies serves the edges of the dependency graph, keyed by the module
+// they're listed for.
+type Dependencies struct {
+	tracker.DependencyServiceClient
+	Dependencies map[string][]*tracker.Dependency
+	Dependents   map[string][]*tracker.Dependency
+}
+
+// ListDependents returns the dependents of the requested module.
+func (f *Dependencies) ListDependents(ctx context.Context, in *tracker.DependencyRequest, opts ...grpc.CallOption) (*tracker.ListDependentsResponse, error) {
+	return &tracker.ListDependentsResponse{Dependents: f.Dependents[key(in.Name, in.Module)]}, nil
+}
+
+// ListDependencies returns the dependencies of the requested module.
+func (f *Dependencies) ListDependencies(ctx context.Context, in *tracker.DependencyRequest, opts ...grpc.CallOption) (*tracker.ListDependenciesResponse, error) {
+	return &tracker.ListDependenciesResponse{Dependencies: f.Dependencies[key(in.Name, in.Module)]}, nil
+}
+
+// Licenses labels modules with their license, keyed like Dependencies.
+// Modules without a license aren't labeled.
+type Licenses map[string]string
+
+// ModuleLabels returns the license label of the module.
+func (f Licenses) ModuleLabels(ctx context.Context, module *schema.Module) (map[string]string, error) {
+	if license, ok := f[key(module.Name, module.Module)]; ok {
+		return map[string]string{"license": license}, nil
+	}
+	return nil, nil
+}
+
+// Edge returns a dependency on the named module.
+func Edge(language, name, constraint string) *tracker.Dependency {
+	return ModuleEdge(&schema.Module{Language: language, Name: name}, constraint)
+}
+
+// ModuleEdge returns a dependency on the module.
+func ModuleEdge(module *schema.Module, constraint string) *tracker.Dependency {
+	dependency := &tracker.Dependency{Module: module}
+	if constraint != "" {
+		dependency.Depends = &schema.Depends{VersionConstraint: constraint}
+	}
+	return dependency
+}
@@ -0,0 +1,78 @@
+package watch
+
+import (
+	"strings"
+)
+
+// The ways a line can change between runs.
+const (
+	Unchanged = ' '
+	Added     = '+'
+	Removed   = '-'
+)
+
+// Line is a line of output, marked with how it changed since the last run.
+type Line struct {
+	Op   byte
+	Text string
+}
+
+// lines splits output into lines, dropping the trailing newline.
+func lines(output string) []string {
+	output = strings.TrimSuffix(output, "\n")
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+// diff compares the output of two runs line by line, keeping the lines they
+// share in order using their longest common subsequence.
+func diff(previous, current []string) []Line {
+	// common[i][j] is the length of the longest common subsequence of
+	// previous[i:] and current[j:]
+	common := make([][]int, len(previous)+1)
+	for i := range common {
+		common[i] = make([]int, len(current)+1)
+	}
+
+	for i := len(previous) - 1; i >= 0; i-- {
+		for j := len(current) - 1; j >= 0; j-- {
+			if previous[i] == current[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	result := make([]Line, 0, len(current))
+	i, j := 0, 0
+
+	for i < len(previous) && j < len(current) {
+		switch {
+		case previous[i] == current[j]:
+			result = append(result, Line{Op: Unchanged, Text: current[j]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			result = append(result, Line{Op: Removed, Text: previous[i]})
+			i++
+		default:
+			result = append(result, Line{Op: Added, Text: current[j]})
+			j++
+		}
+	}
+
+	for ; i < len(previous); i++ {
+		result = append(result, Line{Op: Removed, Text: previous[i]})
+	}
+
+	for ; j < len(current); j++ {
+		result = append(result, Line{Op: Added, Text: current[j]})
+	}
+
+	return result
+}
@@ -0,0 +1,224 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/depscloud/depscloud/deps/internal/writer"
+
+	"github.com/spf13/cobra"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// DefaultInterval is how often commands are re-run when watching.
+const DefaultInterval = 10 * time.Second
+
+// watcher re-runs a command and renders how its output changed. On a
+// terminal, the screen is redrawn each run with the changes highlighted.
+// Otherwise, only the lines that changed are appended, so the output can be
+// followed in a log.
+type watcher struct {
+	out         io.Writer
+	command     string
+	interval    time.Duration
+	interactive bool
+	now         func() time.Time
+
+	runs     int
+	previous []string
+}
+
+func (w *watcher) header(added, removed int) string {
+	return fmt.Sprintf("Every %s: %s  (%d added, %d removed)  %s",
+		w.interval, w.command, added, removed, w.now().Format("2006-01-02 15:04:05"))
+}
+
+// render writes the output of the latest run, or the error it failed with.
+// Failed runs leave the previous output in place, since the service may only
+// be briefly unavailable.
+func (w *watcher) render(output string, err error) error {
+	if err != nil {
+		message := fmt.Sprintf("%s\nerror: %v\n", w.header(0, 0), err)
+		if w.interactive {
+			message = "\x1b[H\x1b[2J" + message + "\n" + strings.Join(w.previous, "\n") + "\n"
+		}
+
+		_, err = fmt.Fprint(w.out, message)
+		return err
+	}
+
+	current := lines(output)
+
+	changes := make([]Line, 0, len(current))
+	if w.runs == 0 {
+		for _, line := range current {
+			changes = append(changes, Line{Op: Unchanged, Text: line})
+		}
+	} else {
+		changes = diff(w.previous, current)
+	}
+
+	w.runs++
+	w.previous = current
+
+	added, removed := 0, 0
+	for _, change := range changes {
+		switch change.Op {
+		case Added:
+			added++
+		case Removed:
+			removed++
+		}
+	}
+
+	buf := &bytes.Buffer{}
+
+	switch {
+	case w.interactive:
+		buf.WriteString("\x1b[H\x1b[2J" + w.header(added, removed) + "\n\n")
+
+		for _, change := range changes {
+			switch change.Op {
+			case Added:
+				buf.WriteString("\x1b[32m+ " + change.Text + "\x1b[0m\n")
+			case Removed:
+				buf.WriteString("\x1b[31m- " + change.Text + "\x1b[0m\n")
+			default:
+				buf.WriteString("  " + change.Text + "\n")
+			}
+		}
+
+	case w.runs == 1:
+		buf.WriteString(w.header(added, removed) + "\n")
+		for _, line := range current {
+			buf.WriteString(line + "\n")
+		}
+
+	case added+removed > 0:
+		buf.WriteString(w.header(added, removed) + "\n")
+		for _, change := range changes {
+			if change.Op != Unchanged {
+				buf.WriteString(string(change.Op) + " " + change.Text + "\n")
+			}
+		}
+	}
+
+	_, err = w.out.Write(buf.Bytes())
+	return err
+}
+
+// loop runs the command on each interval until the context is done. Only the
+// first run failing stops the loop, as it's most likely a mistake in how the
+// command was called.
+func (w *watcher) loop(ctx context.Context, run func(out io.Writer) error) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		buf := &bytes.Buffer{}
+		err := run(buf)
+		if err != nil && w.runs == 0 {
+			return err
+		}
+
+		if err := w.render(buf.String(), err); err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func isTerminal(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	return ok && terminal.IsTerminal(int(file.Fd()))
+}
+
+// Enable adds the --watch and --interval flags to the command, which re-run
+// it and the commands beneath it until interrupted, highlighting what changed
+// in their output between runs.
+func Enable(cmd *cobra.Command, output *writer.Output) *cobra.Command {
+	enabled := false
+	interval := DefaultInterval
+
+	flags := cmd.PersistentFlags()
+	flags.BoolVar(&enabled, "watch", enabled, "Re-run the query on an interval, highlighting what changed")
+	flags.DurationVar(&interval, "interval", interval, "How often to re-run the query when watching")
+
+	var wrap func(cmd *cobra.Command)
+	wrap = func(cmd *cobra.Command) {
+		for _, child := range cmd.Commands() {
+			wrap(child)
+		}
+
+		runE := cmd.RunE
+		if runE == nil {
+			return
+		}
+
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			if !enabled {
+				return runE(cmd, args)
+			}
+
+			if interval < time.Second {
+				return fmt.Errorf("interval must be at least 1s")
+			}
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(signals)
+
+			go func() {
+				select {
+				case <-signals:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			out := output.Out()
+			defer output.Reset(out)
+
+			w := &watcher{
+				out:         out,
+				command:     strings.Join(append([]string{cmd.CommandPath()}, args...), " "),
+				interval:    interval,
+				interactive: isTerminal(out),
+				now:         time.Now,
+			}
+
+			return w.loop(ctx, func(buf io.Writer) error {
+				output.Reset(buf)
+
+				if err := runE(cmd, args); err != nil {
+					return err
+				}
+				return output.Flush()
+			})
+		}
+	}
+
+	wrap(cmd)
+
+	return cmd
+}
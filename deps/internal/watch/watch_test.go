@@ -0,0 +1,84 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	require.Equal(t, []Line{
+		{Op: Unchanged, Text: "a"},
+		{Op: Removed, Text: "b"},
+		{Op: Unchanged, Text: "c"},
+		{Op: Added, Text: "d"},
+	}, diff([]string{"a", "b", "c"}, []string{"a", "c", "d"}))
+
+	require.Equal(t, []Line{{Op: Added, Text: "a"}}, diff(nil, lines("a\n")))
+	require.Equal(t, []Line{{Op: Removed, Text: "a"}}, diff(lines("a\n"), lines("")))
+}
+
+func TestLoop(t *testing.T) {
+	// an empty run stands in for the service being unavailable
+	runs := []string{
+		"NAME\na\nb\n",
+		"",
+		"NAME\na\nb\n",
+		"NAME\na\nc\n",
+	}
+
+	out := &bytes.Buffer{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &watcher{
+		out:      out,
+		command:  "deps get sources",
+		interval: time.Millisecond,
+		now: func() time.Time {
+			return time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+		},
+	}
+
+	i := 0
+	err := w.loop(ctx, func(buf io.Writer) error {
+		run := runs[i]
+		i++
+
+		if i == len(runs) {
+			cancel()
+		}
+
+		if run == "" {
+			return fmt.Errorf("unavailable")
+		}
+
+		_, err := buf.Write([]byte(run))
+		return err
+	})
+	require.NoError(t, err)
+
+	// unchanged runs write nothing, so changes can be followed in a log
+	require.Equal(t, ""+
+		"Every 1ms: deps get sources  (0 added, 0 removed)  2020-10-01 12:00:00\n"+
+		"NAME\n"+
+		"a\n"+
+		"b\n"+
+		"Every 1ms: deps get sources  (0 added, 0 removed)  2020-10-01 12:00:00\n"+
+		"error: unavailable\n"+
+		"Every 1ms: deps get sources  (1 added, 1 removed)  2020-10-01 12:00:00\n"+
+		"- b\n"+
+		"+ c\n",
+		out.String())
+
+	// the first run failing stops the loop
+	w = &watcher{out: out, interval: time.Millisecond, now: time.Now}
+	err = w.loop(context.Background(), func(buf io.Writer) error {
+		return fmt.Errorf("language must be provided")
+	})
+	require.EqualError(t, err, "language must be provided")
+}
@@ -0,0 +1,167 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// fieldName returns the name of a struct field, as given by its json tag, and
+// whether it's written at all. Unexported fields and the bookkeeping fields of
+// generated protobuf messages are skipped.
+func fieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" || strings.HasPrefix(field.Name, "XXX_") {
+		return "", false
+	}
+
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return "", false
+	} else if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, true
+}
+
+// indirect dereferences pointers and interfaces. Nil pointers to structs become
+// empty structs, so a value always has the same fields whether or not they're
+// set.
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			if value.Kind() == reflect.Ptr && value.Type().Elem().Kind() == reflect.Struct {
+				return reflect.Zero(value.Type().Elem())
+			}
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// structure converts the value into maps, slices, and scalars, keeping every
+// field of a struct so that, unlike JSON with omitted empty fields, values of
+// the same type always have the same fields.
+func structure(data interface{}) interface{} {
+	return structureValue(reflect.ValueOf(data))
+}
+
+func structureValue(value reflect.Value) interface{} {
+	value = indirect(value)
+
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		for i := 0; i < value.NumField(); i++ {
+			if name, ok := fieldName(value.Type().Field(i)); ok {
+				fields[name] = structureValue(value.Field(i))
+			}
+		}
+		return fields
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface()
+		}
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			items[i] = structureValue(value.Index(i))
+		}
+		return items
+	case reflect.Map:
+		entries := make(map[string]interface{}, value.Len())
+		for _, key := range value.MapKeys() {
+			entries[fmt.Sprint(key.Interface())] = structureValue(value.MapIndex(key))
+		}
+		return entries
+	}
+
+	return value.Interface()
+}
+
+// field is a single column of a record, named by the path to it within the
+// value it was read from.
+type field struct {
+	name  string
+	value string
+}
+
+// records flattens the value into rows. Slices produce a row for each of their
+// items, while nested structs produce columns named by their path, such as
+// depends.version_constraint.
+func records(data interface{}) [][]field {
+	value := indirect(reflect.ValueOf(data))
+
+	if (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) && value.Type().Elem().Kind() != reflect.Uint8 {
+		rows := make([][]field, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			rows = append(rows, records(value.Index(i).Interface())...)
+		}
+		return rows
+	}
+
+	record := make([]field, 0)
+	flatten("", value, &record)
+	return [][]field{record}
+}
+
+func flatten(name string, value reflect.Value, record *[]field) {
+	value = indirect(value)
+
+	if value.Kind() == reflect.Struct {
+		for i := 0; i < value.NumField(); i++ {
+			fieldName, ok := fieldName(value.Type().Field(i))
+			if !ok {
+				continue
+			}
+			if name != "" {
+				fieldName = name + "." + fieldName
+			}
+			flatten(fieldName, value.Field(i), record)
+		}
+		return
+	}
+
+	if name == "" {
+		name = "value"
+	}
+	*record = append(*record, field{name: name, value: format(value)})
+}
+
+// format renders a value within a single cell. Lists of scalars are separated
+// by commas, while anything more complex is written as JSON.
+func format(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return ""
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+
+		items := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			item := indirect(value.Index(i))
+			switch item.Kind() {
+			case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+				return formatJSON(value)
+			}
+			items = append(items, format(item))
+		}
+		return strings.Join(items, ",")
+	case reflect.Map:
+		return formatJSON(value)
+	}
+
+	return fmt.Sprint(value.Interface())
+}
+
+func formatJSON(value reflect.Value) string {
+	contents, err := json.Marshal(structureValue(value))
+	if err != nil {
+		return fmt.Sprint(value.Interface())
+	}
+	return string(contents)
+}
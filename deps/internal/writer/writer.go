@@ -1,11 +1,28 @@
 package writer
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ghodss/yaml"
+)
+
+// The formats values can be written in.
+const (
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatTable = "table"
+	FormatCSV   = "csv"
 )
 
+// Formats lists the supported formats.
+var Formats = []string{FormatJSON, FormatYAML, FormatTable, FormatCSV}
+
 type Writer interface {
 	Write(data interface{}) error
 }
@@ -24,4 +41,189 @@ func JSONWriter(writer io.Writer) Writer {
 	}
 }
 
-var Default = JSONWriter(os.Stdout)
+type yamlWriter struct {
+	writer io.Writer
+}
+
+// Write appends the value to a YAML list, so the output as a whole can be
+// read as a single document.
+func (w *yamlWriter) Write(data interface{}) error {
+	contents, err := yaml.Marshal([]interface{}{structure(data)})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.writer.Write(contents)
+	return err
+}
+
+func YAMLWriter(writer io.Writer) Writer {
+	return &yamlWriter{
+		writer: writer,
+	}
+}
+
+// recordWriter writes values as rows, with the columns of the first value.
+type recordWriter struct {
+	columns []string
+	header  func(columns []string) error
+	row     func(values []string) error
+	flush   func() error
+}
+
+func (w *recordWriter) Write(data interface{}) error {
+	for _, record := range records(data) {
+		if w.columns == nil {
+			w.columns = make([]string, 0, len(record))
+			for _, field := range record {
+				w.columns = append(w.columns, field.name)
+			}
+
+			if err := w.header(w.columns); err != nil {
+				return err
+			}
+		}
+
+		values := make(map[string]string, len(record))
+		for _, field := range record {
+			values[field.name] = field.value
+		}
+
+		row := make([]string, len(w.columns))
+		for i, column := range w.columns {
+			row[i] = values[column]
+		}
+
+		if err := w.row(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *recordWriter) Flush() error {
+	return w.flush()
+}
+
+// TableWriter aligns values into columns, which are only written once the
+// writer is flushed.
+func TableWriter(writer io.Writer) Writer {
+	tw := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+
+	line := func(values []string) error {
+		_, err := fmt.Fprintln(tw, strings.Join(values, "\t"))
+		return err
+	}
+
+	return &recordWriter{
+		header: func(columns []string) error {
+			header := make([]string, len(columns))
+			for i, column := range columns {
+				header[i] = strings.ToUpper(column)
+			}
+			return line(header)
+		},
+		row:   line,
+		flush: tw.Flush,
+	}
+}
+
+// CSVWriter writes values as comma separated rows, beneath a header naming
+// each column.
+func CSVWriter(writer io.Writer) Writer {
+	cw := csv.NewWriter(writer)
+
+	return &recordWriter{
+		header: cw.Write,
+		row:    cw.Write,
+		flush: func() error {
+			cw.Flush()
+			return cw.Error()
+		},
+	}
+}
+
+// Validate returns an error when values can't be written in the format.
+func Validate(format string) error {
+	for _, supported := range Formats {
+		if format == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported format %q, must be one of %s", format, strings.Join(Formats, ", "))
+}
+
+// New constructs a writer for the format.
+func New(format string, writer io.Writer) (Writer, error) {
+	switch format {
+	case FormatJSON:
+		return JSONWriter(writer), nil
+	case FormatYAML:
+		return YAMLWriter(writer), nil
+	case FormatTable:
+		return TableWriter(writer), nil
+	case FormatCSV:
+		return CSVWriter(writer), nil
+	}
+	return nil, Validate(format)
+}
+
+// Output writes values in the format chosen on the command line, which is
+// only known once flags are parsed. Commands with an output of their own, such
+// as a tree, use it when no format is chosen. Otherwise values are written as
+// JSON.
+type Output struct {
+	Format string
+
+	out    io.Writer
+	writer Writer
+}
+
+// NewOutput constructs an output that writes to out.
+func NewOutput(out io.Writer) *Output {
+	return &Output{
+		out: out,
+	}
+}
+
+// Out returns the destination of the output, for commands that render values
+// themselves.
+func (o *Output) Out() io.Writer {
+	return o.out
+}
+
+// Reset writes any further values to out, starting over with a new writer so
+// formats with a header write it again.
+func (o *Output) Reset(out io.Writer) {
+	o.out = out
+	o.writer = nil
+}
+
+func (o *Output) Write(data interface{}) error {
+	if o.writer == nil {
+		format := o.Format
+		if format == "" {
+			format = FormatJSON
+		}
+
+		writer, err := New(format, o.out)
+		if err != nil {
+			return err
+		}
+		o.writer = writer
+	}
+
+	return o.writer.Write(data)
+}
+
+// Flush writes any buffered values, which formats that align their output
+// hold on to until every value is known.
+func (o *Output) Flush() error {
+	if flusher, ok := o.writer.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+var Default = NewOutput(os.Stdout)
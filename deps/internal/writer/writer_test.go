@@ -0,0 +1,75 @@
+package writer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+
+	"github.com/stretchr/testify/require"
+)
+
+var dependencies = []*tracker.Dependency{
+	{
+		Depends: &schema.Depends{Language: "go", VersionConstraint: "v1.0.0", Scopes: []string{"direct", "test"}},
+		Module:  &schema.Module{Language: "go", Name: "github.com/a/b"},
+	},
+	{
+		Module: &schema.Module{Language: "go", Name: "github.com/a/c"},
+	},
+}
+
+func write(t *testing.T, format string, values ...interface{}) string {
+	out := &bytes.Buffer{}
+
+	output := NewOutput(out)
+	output.Format = format
+
+	for _, value := range values {
+		require.NoError(t, output.Write(value))
+	}
+	require.NoError(t, output.Flush())
+
+	return out.String()
+}
+
+func TestTable(t *testing.T) {
+	require.Equal(t, ""+
+		"DEPENDS.LANGUAGE  DEPENDS.VERSION_CONSTRAINT  DEPENDS.SCOPES  DEPENDS.REF  MODULE.LANGUAGE  MODULE.ORGANIZATION  MODULE.MODULE  MODULE.NAME\n"+
+		"go                v1.0.0                      direct,test                  go                                                   github.com/a/b\n"+
+		"                                                                           go                                                   github.com/a/c\n",
+		write(t, FormatTable, dependencies[0], dependencies[1]))
+}
+
+func TestCSV(t *testing.T) {
+	// slices are written a row per item
+	require.Equal(t, ""+
+		"depends.language,depends.version_constraint,depends.scopes,depends.ref,module.language,module.organization,module.module,module.name\n"+
+		"go,v1.0.0,\"direct,test\",,go,,,github.com/a/b\n"+
+		",,,,go,,,github.com/a/c\n",
+		write(t, FormatCSV, dependencies))
+
+	require.Equal(t, "value\nabc\n", write(t, FormatCSV, "abc"))
+}
+
+func TestYAML(t *testing.T) {
+	require.Equal(t, `- language: go
+  module: ""
+  name: github.com/a/b
+  organization: ""
+- language: go
+  module: ""
+  name: github.com/a/c
+  organization: ""
+`, write(t, FormatYAML, dependencies[0].Module, dependencies[1].Module))
+}
+
+func TestJSON(t *testing.T) {
+	require.Equal(t, `{"language":"go","name":"github.com/a/b"}
+`, write(t, "", dependencies[0].Module))
+
+	output := NewOutput(&bytes.Buffer{})
+	output.Format = "xml"
+	require.Error(t, output.Write(dependencies[0]))
+}
@@ -1,13 +1,29 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/depscloud/depscloud/deps/internal/client"
+	"github.com/depscloud/depscloud/deps/internal/cmds/browse"
+	"github.com/depscloud/depscloud/deps/internal/cmds/check"
 	"github.com/depscloud/depscloud/deps/internal/cmds/completion"
+	"github.com/depscloud/depscloud/deps/internal/cmds/config"
 	"github.com/depscloud/depscloud/deps/internal/cmds/debug"
+	"github.com/depscloud/depscloud/deps/internal/cmds/diff"
+	"github.com/depscloud/depscloud/deps/internal/cmds/extract"
 	"github.com/depscloud/depscloud/deps/internal/cmds/get"
+	"github.com/depscloud/depscloud/deps/internal/cmds/graph"
+	"github.com/depscloud/depscloud/deps/internal/cmds/licenses"
+	"github.com/depscloud/depscloud/deps/internal/cmds/login"
+	"github.com/depscloud/depscloud/deps/internal/cmds/outdated"
+	"github.com/depscloud/depscloud/deps/internal/cmds/sbom"
+	"github.com/depscloud/depscloud/deps/internal/cmds/search"
+	"github.com/depscloud/depscloud/deps/internal/cmds/serviceaccounts"
+	"github.com/depscloud/depscloud/deps/internal/watch"
 	"github.com/depscloud/depscloud/deps/internal/writer"
+	"github.com/depscloud/depscloud/internal/logging"
 	"github.com/depscloud/depscloud/internal/mux"
 
 	"github.com/sirupsen/logrus"
@@ -21,6 +37,21 @@ deps provides command line access to information stored in a deps.cloud API.
   # configure for private deployments
   export DEPSCLOUD_BASE_URL="https://api.deps.cloud"
 
+  # or switch between named deployments
+  deps config set-context staging --base-url https://staging.deps.cloud --token $TOKEN
+  deps config use-context staging
+
+  # authenticate with a deployment using an OIDC provider or an API token
+  deps login --issuer https://accounts.example.com --client-id deps-cli
+  deps login --token-stdin < token.txt
+
+  # complete commands, languages, and module names as they're typed
+  source <(deps completion bash)
+
+  # find modules without knowing their exact name
+  deps search depscloud
+  deps search --language go --fuzzy dpsapi
+
   # list available sources
   deps get sources
   deps get sources -l go -o github.com -m depscloud/api
@@ -36,6 +67,53 @@ deps provides command line access to information stored in a deps.cloud API.
   # list dependencies of a module
   deps get dependencies -l go -o github.com -m depscloud/api
   deps get dependencies -l go -n github.com/depscloud/api
+
+  # list the dependents of many modules at once, one language:name per line
+  deps get dependents --format csv - < modules.txt
+
+  # render the transitive dependencies of a module as a tree
+  deps get tree -l go -n github.com/depscloud/api --depth 3
+
+  # choose how results are written
+  deps get sources --format table
+  deps get dependents -l go -n github.com/depscloud/api --format csv > dependents.csv
+
+  # render dependents as a graphviz or mermaid diagram
+  deps get dependents -l go -n github.com/depscloud/api --format dot | dot -Tsvg > dependents.svg
+  deps get tree -l go -n github.com/depscloud/api --format mermaid
+
+  # re-run a query every 30s, highlighting changes, while waiting on indexing
+  deps get dependents -l go -n github.com/depscloud/api --watch --interval 30s
+
+  # compare the dependents of a library before and after a rename
+  deps diff -l go --from github.com/depscloud/api --to github.com/depscloud/depscloud --dependents
+
+  # interactively browse dependents and dependencies
+  deps browse -l go -n github.com/depscloud/api
+
+  # extract the dependencies of a local checkout, optionally storing them
+  deps extract ./path/to/checkout
+  deps extract ./path/to/checkout --store
+
+  # write a software bill of materials for a source
+  deps sbom --source https://github.com/depscloud/depscloud.git --format spdx
+
+  # report the licenses of an organization's dependencies, failing on denied ones
+  deps licenses -o github.com --deny GPL-3.0,AGPL-3.0
+
+  # report the dependencies of a source that trail their latest version
+  deps outdated --source https://github.com/depscloud/depscloud.git
+
+  # fail a build when a source violates a dependency policy
+  deps check --policy policy.yaml --source https://github.com/depscloud/depscloud.git
+
+  # issue a scoped, expiring token for the indexer or a CI job
+  deps serviceaccounts create indexer --description "indexes github.com"
+  deps serviceaccounts issue indexer --role editor --ttl 720h > indexer.token
+
+  # copy the graph to another deployment
+  deps graph export > graph.jsonl
+  DEPSCLOUD_BASE_URL="https://staging.deps.cloud" deps graph import < graph.jsonl
 `
 
 // variables set by build using -X ldflag
@@ -45,16 +123,44 @@ var date string
 
 func main() {
 	version := mux.Version{Version: version, Commit: commit, Date: date}
+	credentials := client.DefaultCredentialStore()
 	client := client.DefaultClient()
-	writer := writer.Default
+	output := writer.Default
 
 	cmd := &cobra.Command{
 		Use:  "deps",
 		Long: long,
 	}
 
+	// subcommands define their own pre-run hooks, so logging is configured
+	// once the flags are parsed instead
+	logConfig := logging.DefaultConfig()
+	cmd.PersistentFlags().StringVar(&(logConfig.Level), "log-level", logConfig.Level,
+		"the minimum level logged; trace, debug, info, warn, error, or fatal")
+	cmd.PersistentFlags().StringVar(&(logConfig.Format), "log-format", logConfig.Format,
+		"the format logs are written in; text or json")
+
+	cobra.OnInitialize(func() {
+		if err := logging.Setup("deps", logConfig); err != nil {
+			logrus.Fatal(err)
+		}
+	})
+
+	cmd.AddCommand(browse.Command(client))
+	cmd.AddCommand(check.Command(client.Modules(), client.Dependencies(), client.Labels(), output))
 	cmd.AddCommand(completion.Command())
-	cmd.AddCommand(get.Command(client, writer))
+	cmd.AddCommand(config.Command())
+	cmd.AddCommand(diff.Command(client.Dependencies()))
+	cmd.AddCommand(extract.Command(client.Extractor(), client.Sources(), output))
+	cmd.AddCommand(watch.Enable(get.Command(client, output), output))
+	cmd.AddCommand(graph.Command())
+	cmd.AddCommand(licenses.Command(client.Modules(), client.Dependencies(), client.Labels(), output))
+	cmd.AddCommand(login.Command(credentials))
+	cmd.AddCommand(login.LogoutCommand(credentials))
+	cmd.AddCommand(outdated.Command(client.Modules(), client.Dependencies(), output))
+	cmd.AddCommand(sbom.Command(client.Modules(), client.Dependencies(), version.Version))
+	cmd.AddCommand(watch.Enable(search.Command(client.TextSearch(), output), output))
+	cmd.AddCommand(serviceaccounts.Command(nil, output))
 
 	cmd.AddCommand(&cobra.Command{
 		Use:   "version",
@@ -68,7 +174,15 @@ func main() {
 
 	cmd.AddCommand(debug.Command(version))
 
+	completion.RegisterFlags(cmd, client.TextSearch())
+
 	if err := cmd.Execute(); err != nil {
+		violations := &check.ViolationsError{}
+		if errors.As(err, &violations) {
+			logrus.Error(err)
+			os.Exit(check.ExitViolations)
+		}
+
 		logrus.Fatal(err)
 	}
 }
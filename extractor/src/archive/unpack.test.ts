@@ -0,0 +1,103 @@
+import unpack from "./unpack";
+
+import zlib = require("zlib");
+
+function tarEntry(name: string, content: string, type: string = "0"): Buffer {
+    const body = Buffer.from(content);
+    const header = Buffer.alloc(512);
+    header.write(name, 0, 100);
+    header.write(body.length.toString(8).padStart(11, "0"), 124, 12);
+    header.write(type, 156, 1);
+    header.write("ustar", 257, 6);
+
+    const padding = Buffer.alloc((512 - (body.length % 512)) % 512);
+    return Buffer.concat([ header, body, padding ]);
+}
+
+function tar(entries: Buffer[]): Buffer {
+    return Buffer.concat(entries.concat([ Buffer.alloc(1024) ]));
+}
+
+function zip(entries: { name: string, content: string }[]): Buffer {
+    const locals: Buffer[] = [];
+    const centrals: Buffer[] = [];
+    let offset = 0;
+
+    entries.forEach(({ name, content }) => {
+        const nameBuf = Buffer.from(name);
+        const body = Buffer.from(content);
+        const compressed = zlib.deflateRawSync(body);
+
+        const local = Buffer.alloc(30);
+        local.writeUInt32LE(0x04034b50, 0);
+        local.writeUInt16LE(8, 8);
+        local.writeUInt32LE(compressed.length, 18);
+        local.writeUInt32LE(body.length, 22);
+        local.writeUInt16LE(nameBuf.length, 26);
+
+        const central = Buffer.alloc(46);
+        central.writeUInt32LE(0x02014b50, 0);
+        central.writeUInt16LE(8, 10);
+        central.writeUInt32LE(compressed.length, 20);
+        central.writeUInt32LE(body.length, 24);
+        central.writeUInt16LE(nameBuf.length, 28);
+        central.writeUInt32LE(offset, 42);
+
+        locals.push(local, nameBuf, compressed);
+        centrals.push(central, nameBuf);
+        offset += local.length + nameBuf.length + compressed.length;
+    });
+
+    const directory = Buffer.concat(centrals);
+    const eocd = Buffer.alloc(22);
+    eocd.writeUInt32LE(0x06054b50, 0);
+    eocd.writeUInt16LE(entries.length, 8);
+    eocd.writeUInt16LE(entries.length, 10);
+    eocd.writeUInt32LE(directory.length, 12);
+    eocd.writeUInt32LE(offset, 16);
+
+    return Buffer.concat(locals.concat([ directory, eocd ]));
+}
+
+const limits = { maxBytes: 1024 * 1024, maxEntries: 100 };
+
+describe("unpack", () => {
+    test("tar.gz", async () => {
+        const archive = zlib.gzipSync(tar([
+            tarEntry("repo/go.mod", "module github.com/depscloud/depscloud"),
+            tarEntry("repo/link", "", "2"),
+            tarEntry("repo/../../etc/passwd", "root"),
+            tarEntry("repo/web/package.json", "{}"),
+        ]));
+
+        expect(await unpack(archive, { ...limits, strip: 1 })).toEqual({
+            "go.mod": "module github.com/depscloud/depscloud",
+            "web/package.json": "{}",
+        });
+    });
+
+    test("zip", async () => {
+        const archive = zip([
+            { name: "go.mod", content: "module github.com/depscloud/depscloud" },
+            { name: "README.md", content: "# depscloud" },
+            { name: "/etc/passwd", content: "root" },
+        ]);
+
+        const actual = await unpack(archive, { ...limits, filter: (p) => !p.endsWith(".md") });
+
+        expect(actual).toEqual({
+            "go.mod": "module github.com/depscloud/depscloud",
+        });
+    });
+
+    test("limits", async () => {
+        const archive = tar([
+            tarEntry("a", "aaaa"),
+            tarEntry("b", "bbbb"),
+        ]);
+
+        await expect(unpack(archive, { maxBytes: 6, maxEntries: 100 })).rejects.toThrow(/bytes/);
+        await expect(unpack(archive, { maxBytes: 100, maxEntries: 1 })).rejects.toThrow(/entries/);
+        await expect(unpack(zlib.gzipSync(archive), { maxBytes: 512, maxEntries: 100 })).rejects.toThrow(/bytes/);
+    });
+});
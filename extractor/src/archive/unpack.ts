@@ -0,0 +1,267 @@
+import path = require("path");
+import zlib = require("zlib");
+
+export interface UnpackOptions {
+    // the maximum number of bytes that may be read out of the archive
+    maxBytes: number;
+    // the maximum number of entries the archive may contain
+    maxEntries: number;
+    // the number of leading path components to remove from each entry
+    strip?: number;
+    // entries that do not pass the filter are skipped
+    filter?: (entryPath: string) => boolean;
+}
+
+const blockSize = 512;
+
+const zipLocalHeader = 0x04034b50;
+const zipCentralHeader = 0x02014b50;
+const zipEndOfCentralDirectory = 0x06054b50;
+
+// cleanPath normalizes an entry path, returning null for entries that would
+// escape the directory they are unpacked into.
+function cleanPath(entryPath: string, strip: number): string | null {
+    const normalized = path.posix.normalize(entryPath.replace(/\\/g, "/"));
+    if (normalized.startsWith("/") || normalized === ".." || normalized.startsWith("../")) {
+        return null;
+    }
+
+    const parts = normalized.split("/").filter((part) => part.length > 0 && part !== ".");
+    if (parts.length <= strip) {
+        return null;
+    }
+
+    return parts.slice(strip).join("/");
+}
+
+class Limiter {
+    private readonly options: UnpackOptions;
+    private bytes: number;
+    private entries: number;
+
+    constructor(options: UnpackOptions) {
+        this.options = options;
+        this.bytes = 0;
+        this.entries = 0;
+    }
+
+    public entry(size: number) {
+        this.entries++;
+        if (this.entries > this.options.maxEntries) {
+            throw new Error(`archive exceeds the limit of ${this.options.maxEntries} entries`);
+        }
+
+        this.bytes += size;
+        if (this.bytes > this.options.maxBytes) {
+            throw new Error(`archive exceeds the limit of ${this.options.maxBytes} bytes`);
+        }
+    }
+}
+
+// decompress inflates the data, failing once more than limit bytes have been
+// produced. This guards against archives that expand far beyond their size.
+function decompress(stream: zlib.Gunzip | zlib.InflateRaw, data: Buffer, limit: number): Promise<Buffer> {
+    return new Promise((resolve, reject) => {
+        const chunks: Buffer[] = [];
+        let length = 0;
+
+        stream.on("data", (chunk: Buffer) => {
+            length += chunk.length;
+            if (length > limit) {
+                stream.destroy();
+                reject(new Error(`archive exceeds the limit of ${limit} bytes`));
+                return;
+            }
+            chunks.push(chunk);
+        });
+        stream.on("end", () => resolve(Buffer.concat(chunks)));
+        stream.on("error", (err) => reject(new Error(`failed to decompress archive: ${err.message}`)));
+
+        stream.end(data);
+    });
+}
+
+function readString(buf: Buffer, start: number, length: number): string {
+    const raw = buf.slice(start, start + length);
+    const end = raw.indexOf(0);
+    return raw.slice(0, end > -1 ? end : raw.length).toString("utf8");
+}
+
+function readOctal(buf: Buffer, start: number, length: number): number {
+    const value = readString(buf, start, length).trim();
+    return value.length > 0 ? parseInt(value, 8) : 0;
+}
+
+// parsePax extracts the path record from a pax extended header, if present.
+function parsePax(data: Buffer): string | null {
+    let remaining = data.toString("utf8");
+
+    while (remaining.length > 0) {
+        const space = remaining.indexOf(" ");
+        const length = parseInt(remaining.slice(0, space), 10);
+        if (space < 0 || isNaN(length) || length <= 0) {
+            break;
+        }
+
+        const record = remaining.slice(space + 1, length - 1);
+        remaining = remaining.slice(length);
+
+        if (record.startsWith("path=")) {
+            return record.slice("path=".length);
+        }
+    }
+
+    return null;
+}
+
+function untar(data: Buffer, options: UnpackOptions, files: { [path: string]: string }) {
+    const limiter = new Limiter(options);
+
+    let offset = 0;
+    let longName: string = null;
+
+    while (offset + blockSize <= data.length) {
+        const header = data.slice(offset, offset + blockSize);
+        if (header.every((b) => b === 0)) {
+            break;
+        }
+
+        const size = readOctal(header, 124, 12);
+        const type = String.fromCharCode(header[156] || 48);
+        const start = offset + blockSize;
+        const body = data.slice(start, start + size);
+
+        offset = start + Math.ceil(size / blockSize) * blockSize;
+
+        limiter.entry(size);
+
+        if (type === "x" || type === "L") {
+            // pax and gnu headers carry the name of the next entry
+            longName = type === "x" ? parsePax(body) : readString(body, 0, body.length);
+            continue;
+        } else if (type === "g") {
+            continue;
+        }
+
+        let name = longName;
+        longName = null;
+
+        if (!name) {
+            const prefix = readString(header, 345, 155);
+            name = readString(header, 0, 100);
+            name = prefix ? `${prefix}/${name}` : name;
+        }
+
+        // only regular files are unpacked, links are never followed
+        if (type !== "0" && type !== "7") {
+            continue;
+        }
+
+        const entryPath = cleanPath(name, options.strip || 0);
+        if (entryPath && (!options.filter || options.filter(entryPath))) {
+            files[entryPath] = body.toString("utf8");
+        }
+    }
+}
+
+async function unzip(data: Buffer, options: UnpackOptions, files: { [path: string]: string }) {
+    const limiter = new Limiter(options);
+
+    // the end of central directory record is at least 22 bytes and may be
+    // followed by a comment of up to 65535 bytes.
+    let eocd = -1;
+    for (let i = data.length - 22; i >= 0 && i >= data.length - 22 - 65535; i--) {
+        if (data.readUInt32LE(i) === zipEndOfCentralDirectory) {
+            eocd = i;
+            break;
+        }
+    }
+
+    if (eocd < 0) {
+        throw new Error("zip archive is missing its central directory");
+    }
+
+    const count = data.readUInt16LE(eocd + 10);
+    let offset = data.readUInt32LE(eocd + 16);
+
+    for (let i = 0; i < count; i++) {
+        if (data.readUInt32LE(offset) !== zipCentralHeader) {
+            throw new Error("zip archive has a malformed central directory");
+        }
+
+        const method = data.readUInt16LE(offset + 10);
+        const compressedSize = data.readUInt32LE(offset + 20);
+        const size = data.readUInt32LE(offset + 24);
+        const nameLength = data.readUInt16LE(offset + 28);
+        const extraLength = data.readUInt16LE(offset + 30);
+        const commentLength = data.readUInt16LE(offset + 32);
+        const externalAttributes = data.readUInt32LE(offset + 38);
+        const localOffset = data.readUInt32LE(offset + 42);
+        const name = data.slice(offset + 46, offset + 46 + nameLength).toString("utf8");
+
+        offset += 46 + nameLength + extraLength + commentLength;
+
+        limiter.entry(size);
+
+        // directories end with a slash and symlinks are flagged in the upper
+        // bits of the external attributes (S_IFLNK).
+        const isSymlink = ((externalAttributes >>> 16) & 0o170000) === 0o120000;
+        if (name.endsWith("/") || isSymlink) {
+            continue;
+        }
+
+        const entryPath = cleanPath(name, options.strip || 0);
+        if (!entryPath || (options.filter && !options.filter(entryPath))) {
+            continue;
+        }
+
+        if (data.readUInt32LE(localOffset) !== zipLocalHeader) {
+            throw new Error(`zip archive has a malformed entry: ${name}`);
+        }
+
+        const localNameLength = data.readUInt16LE(localOffset + 26);
+        const localExtraLength = data.readUInt16LE(localOffset + 28);
+        const start = localOffset + 30 + localNameLength + localExtraLength;
+        const compressed = data.slice(start, start + compressedSize);
+
+        let content: Buffer;
+        if (method === 0) {
+            content = compressed;
+        } else if (method === 8) {
+            // the declared size can't be trusted, so inflation is bounded
+            content = await decompress(zlib.createInflateRaw(), compressed, size);
+        } else {
+            throw new Error(`zip archive uses an unsupported compression method: ${method}`);
+        }
+
+        files[entryPath] = content.toString("utf8");
+    }
+}
+
+function isGzip(data: Buffer): boolean {
+    return data.length > 2 && data[0] === 0x1f && data[1] === 0x8b;
+}
+
+function isZip(data: Buffer): boolean {
+    return data.length > 4 && data.readUInt32LE(0) === zipLocalHeader;
+}
+
+// unpack reads the files out of a tar, tar.gz, or zip archive. Only regular
+// files are returned. Entries that would escape the root of the archive are
+// skipped and archives that exceed the configured limits are rejected.
+export default async function unpack(data: Buffer, options: UnpackOptions): Promise<{ [path: string]: string }> {
+    const files: { [path: string]: string } = {};
+
+    if (isZip(data)) {
+        await unzip(data, options, files);
+        return files;
+    }
+
+    let tar = data;
+    if (isGzip(data)) {
+        tar = await decompress(zlib.createGunzip(), data, options.maxBytes);
+    }
+
+    untar(tar, options, files);
+    return files;
+}
@@ -0,0 +1,7 @@
+// Cache stores serialized extraction results. Implementations may evict
+// entries at any time, so a missing entry only means the files need to be
+// extracted again.
+export default interface Cache {
+    get(key: string): Promise<string | null>;
+    set(key: string, value: string): Promise<void>;
+}
@@ -0,0 +1,49 @@
+import ExtractionCache from "./ExtractionCache";
+import MemoryCache from "./MemoryCache";
+import ExtractorFile from "../extractors/ExtractorFile";
+
+describe("ExtractionCache", () => {
+    test("keys on the path and content of files", () => {
+        const cache = new ExtractionCache(new MemoryCache(10));
+        const url = "https://github.com/depscloud/depscloud.git";
+
+        const key = (path: string, body: string) => cache.key("PackageJsonExtractor", url, {
+            "package.json": new ExtractorFile(body, path),
+        }, null);
+
+        expect(key("package.json", "{}")).toBe(key("package.json", "{}"));
+        expect(key("package.json", "{}") === key("web/package.json", "{}")).toBe(false);
+        expect(key("package.json", "{}") === key("package.json", "{ }")).toBe(false);
+    });
+
+    test("reuses cached results", async () => {
+        const cache = new ExtractionCache(new MemoryCache(10));
+
+        let calls = 0;
+        const extract = async () => {
+            calls++;
+            return { language: "node", system: "npm", name: "test", dependencies: [] } as any;
+        };
+
+        const first = await cache.cached("key", extract);
+        const second = await cache.cached("key", extract);
+
+        expect(second).toEqual(first);
+        expect(calls).toBe(1);
+    });
+
+    test("does not cache abandoned extractions", async () => {
+        const cache = new ExtractionCache(new MemoryCache(10));
+
+        let calls = 0;
+        const extract = async () => {
+            calls++;
+            return null;
+        };
+
+        await cache.cached("key", extract);
+        await cache.cached("key", extract);
+
+        expect(calls).toBe(2);
+    });
+});
@@ -0,0 +1,100 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import {getLogger} from "log4js";
+import Cache from "./Cache";
+import ExtractorFile from "../extractors/ExtractorFile";
+
+import crypto = require("crypto");
+import promClient = require("prom-client");
+
+const logger = getLogger();
+
+// bump when the format of the cached results changes
+const VERSION = "v1";
+
+const cacheRequests = new promClient.Counter({
+    name: "extractor_cache_requests_total",
+    help: "The number of extraction results looked up in the cache.",
+    labelNames: [ "result" ],
+});
+
+type Result = DependencyManagementFile | DependencyManagementFile[];
+
+function sha256(data: string): string {
+    return crypto.createHash("sha256").update(data).digest("hex");
+}
+
+// digest identifies a set of files by their paths and contents.
+function digest(files: { [key: string]: ExtractorFile }): string {
+    const hash = crypto.createHash("sha256");
+
+    Object.keys(files).sort().forEach((key) => {
+        hash.update(key).update("\0")
+            .update(files[key].path()).update("\0")
+            .update(sha256(files[key].raw())).update("\n");
+    });
+
+    return hash.digest("hex");
+}
+
+// ExtractionCache stores the results of an extractor keyed by the path and
+// content hash of the files it was given. Extractors that read from the
+// workspace (parent poms, workspace members) also key on the workspace, so
+// their results are reused only when the source is unchanged. Failures to
+// reach the cache are logged and treated as a miss.
+export default class ExtractionCache {
+    private readonly cache: Cache;
+
+    constructor(cache: Cache) {
+        this.cache = cache;
+    }
+
+    public key(
+        name: string,
+        url: string,
+        files: { [key: string]: ExtractorFile },
+        workspaceDigest: () => string,
+    ): string {
+        const parts = [ VERSION, name, url, digest(files) ];
+        if (workspaceDigest) {
+            parts.push(workspaceDigest());
+        }
+        return `extractor:${name}:${sha256(parts.join("\n"))}`;
+    }
+
+    // cached returns the result stored under the key, or extracts and stores
+    // it. Results that aren't cacheable, such as those that produced warnings,
+    // are extracted again the next time.
+    public async cached(
+        key: string,
+        extract: () => Promise<Result>,
+        cacheable: (result: Result) => boolean = () => true,
+    ): Promise<Result> {
+        let value: string = null;
+        try {
+            value = await this.cache.get(key);
+        } catch (e) {
+            logger.warn(`[cache] failed to get ${key}: ${e.message}`);
+        }
+
+        if (value !== null) {
+            cacheRequests.inc({ result: "hit" });
+            return JSON.parse(value);
+        }
+
+        cacheRequests.inc({ result: "miss" });
+        const result = await extract();
+
+        // timed out extractions resolve to null and shouldn't be remembered
+        if (result && cacheable(result)) {
+            try {
+                await this.cache.set(key, JSON.stringify(result));
+            } catch (e) {
+                logger.warn(`[cache] failed to set ${key}: ${e.message}`);
+            }
+        }
+
+        return result;
+    }
+}
+
+export {digest};
@@ -0,0 +1,17 @@
+import MemoryCache from "./MemoryCache";
+
+describe("MemoryCache", () => {
+    test("evicts the least recently used entry", async () => {
+        const cache = new MemoryCache(2);
+
+        await cache.set("a", "1");
+        await cache.set("b", "2");
+        expect(await cache.get("a")).toBe("1");
+
+        await cache.set("c", "3");
+
+        expect(await cache.get("a")).toBe("1");
+        expect(await cache.get("b")).toBeNull();
+        expect(await cache.get("c")).toBe("3");
+    });
+});
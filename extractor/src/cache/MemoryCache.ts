@@ -0,0 +1,36 @@
+import Cache from "./Cache";
+
+// MemoryCache keeps the most recently used entries in process. Once the cache
+// holds the maximum number of entries, the least recently used entry is
+// evicted.
+export default class MemoryCache implements Cache {
+    private readonly maxEntries: number;
+    private readonly entries: Map<string, string>;
+
+    constructor(maxEntries: number) {
+        this.maxEntries = maxEntries;
+        this.entries = new Map();
+    }
+
+    public async get(key: string): Promise<string | null> {
+        if (!this.entries.has(key)) {
+            return null;
+        }
+
+        // maps iterate in insertion order, re-inserting marks the entry as
+        // the most recently used
+        const value = this.entries.get(key);
+        this.entries.delete(key);
+        this.entries.set(key, value);
+        return value;
+    }
+
+    public async set(key: string, value: string): Promise<void> {
+        this.entries.delete(key);
+        this.entries.set(key, value);
+
+        while (this.entries.size > this.maxEntries) {
+            this.entries.delete(this.entries.keys().next().value);
+        }
+    }
+}
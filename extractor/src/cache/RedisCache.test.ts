@@ -0,0 +1,55 @@
+import RedisCache from "./RedisCache";
+
+import net = require("net");
+
+// startServer runs a minimal redis server that understands GET and SET.
+function startServer(): Promise<net.Server> {
+    const data: { [key: string]: string } = {};
+
+    const server = net.createServer((socket) => {
+        let buffer = "";
+        socket.on("data", (chunk) => {
+            buffer += chunk.toString();
+
+            let match = /^\*(\d+)\r\n((?:\$\d+\r\n[^\r]*\r\n)*)/.exec(buffer);
+            while (match) {
+                const args = match[2].split("\r\n").filter((_, i) => i % 2 === 1);
+                if (args.length < parseInt(match[1], 10)) {
+                    break;
+                }
+                buffer = buffer.substring(match[0].length);
+
+                if (args[0] === "SET") {
+                    data[args[1]] = args[2];
+                    socket.write("+OK\r\n");
+                } else if (args[0] === "GET" && data[args[1]] !== undefined) {
+                    socket.write(`$${Buffer.byteLength(data[args[1]])}\r\n${data[args[1]]}\r\n`);
+                } else if (args[0] === "GET") {
+                    socket.write("$-1\r\n");
+                } else {
+                    socket.write("-ERR unknown command\r\n");
+                }
+
+                match = /^\*(\d+)\r\n((?:\$\d+\r\n[^\r]*\r\n)*)/.exec(buffer);
+            }
+        });
+    });
+
+    return new Promise((resolve) => server.listen(0, "127.0.0.1", () => resolve(server)));
+}
+
+describe("RedisCache", () => {
+    test("stores and retrieves entries", async () => {
+        const server = await startServer();
+        const address = server.address() as net.AddressInfo;
+
+        const cache = new RedisCache(`redis://127.0.0.1:${address.port}`, 0);
+
+        expect(await cache.get("missing")).toBeNull();
+
+        await cache.set("key", "välue");
+        expect(await cache.get("key")).toBe("välue");
+
+        server.close();
+    });
+});
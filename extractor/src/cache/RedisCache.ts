@@ -0,0 +1,185 @@
+import Cache from "./Cache";
+
+import net = require("net");
+import url = require("url");
+
+const COMMAND_TIMEOUT = 5000;
+
+interface Reply {
+    value: any;
+    offset: number;
+}
+
+// parseReply reads a single reply from the buffer starting at offset. Null is
+// returned when the buffer does not yet contain the complete reply.
+function parseReply(buffer: Buffer, offset: number): Reply | null {
+    const end = buffer.indexOf("\r\n", offset);
+    if (end < 0) {
+        return null;
+    }
+
+    const type = String.fromCharCode(buffer[offset]);
+    const line = buffer.toString("utf8", offset + 1, end);
+
+    switch (type) {
+    case "+":
+        return { value: line, offset: end + 2 };
+    case "-":
+        return { value: new Error(line), offset: end + 2 };
+    case ":":
+        return { value: parseInt(line, 10), offset: end + 2 };
+    case "$": {
+        const length = parseInt(line, 10);
+        if (length < 0) {
+            return { value: null, offset: end + 2 };
+        }
+
+        const start = end + 2;
+        if (buffer.length < start + length + 2) {
+            return null;
+        }
+        return { value: buffer.toString("utf8", start, start + length), offset: start + length + 2 };
+    }
+    }
+
+    throw new Error(`unsupported reply type: ${type}`);
+}
+
+function encodeCommand(args: string[]): string {
+    return args.reduce(
+        (command, arg) => `${command}$${Buffer.byteLength(arg)}\r\n${arg}\r\n`,
+        `*${args.length}\r\n`,
+    );
+}
+
+interface Pending {
+    resolve: (value: any) => void;
+    reject: (err: Error) => void;
+}
+
+// Connection pipelines commands over a single socket. Replies are returned in
+// the order commands were sent.
+class Connection {
+    private readonly socket: net.Socket;
+    private readonly pending: Pending[];
+    private buffer: Buffer;
+
+    public closed: boolean;
+
+    constructor(host: string, port: number) {
+        this.pending = [];
+        this.buffer = Buffer.alloc(0);
+        this.closed = false;
+
+        this.socket = net.connect(port, host);
+        this.socket.setNoDelay(true);
+        this.socket.setTimeout(COMMAND_TIMEOUT);
+
+        this.socket.on("data", (chunk: Buffer) => this.receive(chunk));
+        this.socket.on("timeout", () => {
+            if (this.pending.length > 0) {
+                this.socket.destroy(new Error("redis command timed out"));
+            }
+        });
+        this.socket.on("error", (err) => this.close(err));
+        this.socket.on("close", () => this.close(new Error("redis connection closed")));
+    }
+
+    public send(args: string[]): Promise<any> {
+        if (this.closed) {
+            return Promise.reject(new Error("redis connection closed"));
+        }
+
+        return new Promise((resolve, reject) => {
+            this.pending.push({ resolve, reject });
+            this.socket.write(encodeCommand(args));
+        });
+    }
+
+    private receive(chunk: Buffer) {
+        this.buffer = Buffer.concat([ this.buffer, chunk ]);
+
+        let offset = 0;
+        try {
+            for (let reply = parseReply(this.buffer, offset); reply !== null; reply = parseReply(this.buffer, offset)) {
+                offset = reply.offset;
+
+                const next = this.pending.shift();
+                if (!next) {
+                    continue;
+                } else if (reply.value instanceof Error) {
+                    next.reject(reply.value);
+                } else {
+                    next.resolve(reply.value);
+                }
+            }
+        } catch (e) {
+            this.socket.destroy(e);
+            return;
+        }
+
+        this.buffer = this.buffer.slice(offset);
+    }
+
+    private close(err: Error) {
+        this.closed = true;
+        this.pending.splice(0).forEach((p) => p.reject(err));
+    }
+}
+
+// RedisCache shares extraction results between extractor replicas using a
+// redis server addressed as redis://[:password@]host[:port][/db]. Entries
+// expire after ttl seconds, or never when the ttl is 0.
+export default class RedisCache implements Cache {
+    private readonly host: string;
+    private readonly port: number;
+    private readonly password: string;
+    private readonly db: string;
+    private readonly ttl: number;
+
+    private connection: Connection;
+
+    constructor(address: string, ttl: number) {
+        const parsed = url.parse(address);
+
+        this.host = parsed.hostname || "localhost";
+        this.port = parseInt(parsed.port || "6379", 10);
+        this.password = parsed.auth ? parsed.auth.substring(parsed.auth.indexOf(":") + 1) : "";
+        this.db = (parsed.pathname || "").replace(/^\//, "");
+        this.ttl = ttl;
+
+        this.connection = null;
+    }
+
+    private connect(): Connection {
+        if (this.connection && !this.connection.closed) {
+            return this.connection;
+        }
+
+        const connection = new Connection(this.host, this.port);
+
+        // failures surface on the commands that follow
+        if (this.password) {
+            connection.send([ "AUTH", this.password ]).catch(() => null);
+        }
+        if (this.db) {
+            connection.send([ "SELECT", this.db ]).catch(() => null);
+        }
+
+        this.connection = connection;
+        return connection;
+    }
+
+    public get(key: string): Promise<string | null> {
+        return this.connect().send([ "GET", key ]);
+    }
+
+    public async set(key: string, value: string): Promise<void> {
+        const args = [ "SET", key, value ];
+        if (this.ttl > 0) {
+            args.push("EX", `${this.ttl}`);
+        }
+
+        await this.connect().send(args);
+    }
+}
@@ -0,0 +1,134 @@
+import fs = require("fs");
+import YAML = require("js-yaml");
+
+const asyncFs = fs.promises;
+
+export type OptionType = "string" | "int" | "float" | "bool" | "list";
+
+// OptionSpec describes a command line option. Options backed by an environment
+// variable name it, so the variable takes precedence over the config file.
+export interface OptionSpec {
+    flag: string;
+    description: string;
+    type: OptionType;
+    env?: string;
+}
+
+// The options added to every binary, matching the other services.
+export const CONFIG_FILE_FLAG = "config-file";
+export const PRINT_CONFIG_FLAG = "print-config";
+
+const REDACTED = "<redacted>";
+
+// the values of options containing these words are never printed
+const SENSITIVE = [ "secret", "token", "password", "header" ];
+
+// flagName returns the name of the flag, without the dashes or argument.
+export function flagName(spec: OptionSpec): string {
+    return spec.flag.split(" ")[0].replace(/^--/, "");
+}
+
+// optionKey returns the key caporal stores the value of the flag under.
+export function optionKey(name: string): string {
+    return name.replace(/-([a-z])/g, (_, c) => c.toUpperCase());
+}
+
+function validate(name: string, type: OptionType, value: any): any {
+    switch (type) {
+        case "int":
+            if (!Number.isInteger(value)) {
+                throw new Error(`invalid value for ${name}: expected an integer`);
+            }
+            return value;
+        case "float":
+            if (typeof value !== "number") {
+                throw new Error(`invalid value for ${name}: expected a number`);
+            }
+            return value;
+        case "bool":
+            if (typeof value !== "boolean") {
+                throw new Error(`invalid value for ${name}: expected true or false`);
+            }
+            return value;
+        case "list":
+            return (Array.isArray(value) ? value : [ value ]).map((v) => `${v}`);
+        default:
+            if (typeof value === "object") {
+                throw new Error(`invalid value for ${name}: expected a string`);
+            }
+            return `${value}`;
+    }
+}
+
+// loadConfig applies the options in the yaml or json file to the options that
+// weren't set on the command line or through their environment variable. The
+// file is keyed by flag name. Options that don't match a flag, or have the
+// wrong type, are rejected so mistakes aren't silently ignored.
+export async function loadConfig(
+    path: string,
+    specs: OptionSpec[],
+    options: any,
+    env: { [key: string]: string } = process.env,
+): Promise<any> {
+    if (!path) {
+        return options;
+    }
+
+    const content = await asyncFs.readFile(path);
+    const file = YAML.safeLoad(content.toString()) || {};
+
+    const resolved = { ...options };
+    Object.keys(file).forEach((name) => {
+        const spec = specs.find((s) => flagName(s) === name);
+        if (!spec || name === CONFIG_FILE_FLAG || name === PRINT_CONFIG_FLAG) {
+            throw new Error(`unknown option ${name} in ${path}`);
+        }
+
+        const key = optionKey(name);
+        if (resolved[key] !== undefined || (spec.env && env[spec.env])) {
+            return;
+        }
+        resolved[key] = validate(name, spec.type, file[name]);
+    });
+
+    return resolved;
+}
+
+function redact(name: string, value: any): any {
+    if (SENSITIVE.some((word) => name.indexOf(word) >= 0)) {
+        return REDACTED;
+    }
+
+    if (typeof value === "string") {
+        // credentials in addresses are removed
+        return value.replace(/^([a-z][a-z0-9+.-]*:\/\/[^:@\/]*):[^@\/]*@/i, "$1:xxxxx@");
+    }
+    return value;
+}
+
+// printConfig returns the resolved options as yaml, which can be used as a
+// config file. Options that aren't set are omitted, leaving their defaults.
+export function printConfig(
+    specs: OptionSpec[],
+    options: any,
+    env: { [key: string]: string } = process.env,
+): string {
+    const resolved = {};
+    specs.map(flagName)
+        .filter((name) => name !== CONFIG_FILE_FLAG && name !== PRINT_CONFIG_FLAG)
+        .sort()
+        .forEach((name) => {
+            const spec = specs.find((s) => flagName(s) === name);
+
+            let value = options[optionKey(name)];
+            if (value === undefined && spec.env) {
+                value = env[spec.env];
+            }
+
+            if (value !== undefined) {
+                resolved[name] = redact(name, value);
+            }
+        });
+
+    return YAML.safeDump(resolved);
+}
@@ -1,24 +1,9 @@
 import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
 import Extractor from "./Extractor";
 import ExtractorFile from "./ExtractorFile";
-import Globals from "./Globals";
 import Languages from "./Languages";
 import MatchConfig from "../matcher/MatchConfig";
-
-interface ID {
-    organization: string;
-    module: string;
-}
-
-function parseName(module: string): ID {
-    let organization = Globals.ORGANIZATION;
-    if (module.charAt(0) === "@") {
-        const index = module.indexOf("/");
-        organization = module.substr(1,  index - 1);
-        module = module.substr(index + 1);
-    }
-    return { organization, module };
-}
+import parseName from "./nodeutils/parseName";
 
 function extract(dependencyHash: any, scope: string): Dependency[] {
     return Object.keys(dependencyHash)
@@ -53,6 +38,10 @@ export default class BowerJsonExtractor implements Extractor {
         return [ "bower.json" ];
     }
 
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
     public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
         const {
             name,
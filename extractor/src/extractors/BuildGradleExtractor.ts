@@ -29,6 +29,10 @@ export default class BuildGradleExtractor implements Extractor {
         return [ "build.gradle", "settings.gradle" ];
     }
 
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
     public async extract(url: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
         const promises = this.requires()
             .map((req) => files[req].raw())
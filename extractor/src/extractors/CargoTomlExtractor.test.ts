@@ -18,4 +18,19 @@ describe("CargoTomlExtractor", () => {
         expect(actual).toMatchSnapshot();
         expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
     });
+
+    test("workspace", async () => {
+        const workspace = {};
+        for (const filePath of [ "Cargo.toml", "crates/cli/Cargo.toml", "crates/core/Cargo.toml" ]) {
+            const buffer = await readFileAsync(require.resolve(`./testdata/workspaces/cargo/${filePath}`));
+            workspace[filePath] = new ExtractorFile(buffer.toString(), filePath);
+        }
+
+        const parser = new CargoTomlExtractor();
+
+        const actual = await parser.extract("git@github.com:example/monorepo.git", { "Cargo.toml": workspace["Cargo.toml"] }, workspace);
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
 });
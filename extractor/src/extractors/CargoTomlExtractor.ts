@@ -4,6 +4,10 @@ import ExtractorFile from "./ExtractorFile";
 import Globals from "./Globals";
 import Languages from "./Languages";
 import MatchConfig from "../matcher/MatchConfig";
+import inferRepository from "./urlutils/inferRepository";
+import resolveMembers from "./workspaceutils/resolveMembers";
+import declaredLicenses from "./licenseutils/declaredLicenses";
+import withLicenses from "./licenseutils/withLicenses";
 
 const organization = Globals.ORGANIZATION;
 const scopes = [ "direct" ];
@@ -25,10 +29,24 @@ export default class CargoTomlExtractor implements Extractor {
         return [ "Cargo.toml" ];
     }
 
-    public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+    public usesWorkspace(): boolean {
+        return true;
+    }
+
+    public async extract(
+        url: string,
+        files: { [p: string]: ExtractorFile },
+        workspace?: { [path: string]: ExtractorFile },
+    ): Promise<DependencyManagementFile> {
         const toml = files["Cargo.toml"].toml();
 
-        const dependencies: Dependency[] = Object.keys(toml.dependencies)
+        // virtual manifests only describe a workspace and have no package
+        const pkg = toml.package || { name: inferRepository(url).module, version: "" };
+        if (!pkg.name) {
+            return null;
+        }
+
+        const dependencies: Dependency[] = Object.keys(toml.dependencies || {})
             .map((name) => {
                 const val = toml.dependencies[name];
 
@@ -51,15 +69,32 @@ export default class CargoTomlExtractor implements Extractor {
                 }
             });
 
-        return {
+        if (toml.workspace) {
+            const { members, exclude } = toml.workspace;
+
+            resolveMembers(files["Cargo.toml"], "Cargo.toml", members || [], workspace, exclude || [])
+                .map((member) => member.toml().package)
+                .filter((member) => member && member.name)
+                .forEach((member) => {
+                    dependencies.push({
+                        organization,
+                        module: member.name,
+                        versionConstraint: typeof member.version === "string" ? member.version : "",
+                        scopes: [ "workspace" ],
+                        name: member.name,
+                    });
+                });
+        }
+
+        return withLicenses({
             language: Languages.RUST,
             system: "cargo",
             sourceUrl: "",
             organization,
-            module: toml.package.name,
-            version: toml.package.version,
+            module: pkg.name,
+            version: pkg.version,
             dependencies,
-            name: toml.package.name,
-        };
+            name: pkg.name,
+        }, declaredLicenses(pkg.license));
     }
 }
@@ -52,6 +52,10 @@ export default class ComposerJsonExtractor implements Extractor {
         return [ "composer.json" ];
     }
 
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
     public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
         const {
             name,
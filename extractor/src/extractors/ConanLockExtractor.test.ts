@@ -0,0 +1,35 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ConanLockExtractor from "./ConanLockExtractor";
+import ExtractorFile from "./ExtractorFile";
+
+const readFileAsync = promisify(readFile);
+
+async function readTestData(name: string): Promise<ExtractorFile> {
+    const buffer = await readFileAsync(require.resolve(`./testdata/${name}`));
+    return new ExtractorFile(buffer.toString());
+}
+
+describe("ConanLockExtractor", () => {
+    test("lockfile", async () => {
+        const parser = new ConanLockExtractor();
+
+        const actual = await parser.extract("git@github.com:organization/module.git", {
+            "conan.lock": await readTestData("conan.lock"),
+        });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("graphLock", async () => {
+        const parser = new ConanLockExtractor();
+
+        const actual = await parser.extract("git@github.com:organization/module.git", {
+            "conan.lock": await readTestData("conan-graph.lock"),
+        });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+});
@@ -0,0 +1,92 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Globals from "./Globals";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+import {asLockfile} from "./lockutils/withResolvedVersions";
+import parseReference from "./conanutils/parseReference";
+import toDependencies from "./conanutils/toDependencies";
+import inferRepository from "./urlutils/inferRepository";
+
+// extractGraphLock reads the node graph produced by conan 1.x. Every node
+// other than the root is a locked dependency. Nodes that are only reachable
+// as build requirements are scoped as such.
+function extractGraphLock(nodes: any): { root: string; dependencies: Dependency[] } {
+    const buildNodes = {};
+    Object.keys(nodes).forEach((id) => {
+        (nodes[id].build_requires || []).forEach((child) => buildNodes[child] = true);
+    });
+
+    let dependencies: Dependency[] = [];
+    Object.keys(nodes)
+        .filter((id) => id !== "0")
+        .forEach((id) => {
+            const scope = buildNodes[id] ? "build" : "";
+            dependencies = dependencies.concat(toDependencies([ nodes[id].ref ], scope));
+        });
+
+    return {
+        root: (nodes["0"] || {}).ref || "",
+        dependencies,
+    };
+}
+
+export default class ConanLockExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/conan.lock",
+            ],
+            excludes: [
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "conan.lock" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(url: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+        const lock = files["conan.lock"].json();
+
+        let root = "";
+        let dependencies: Dependency[] = [];
+
+        if (lock.graph_lock) {
+            ({ root, dependencies } = extractGraphLock(lock.graph_lock.nodes || {}));
+        } else {
+            dependencies = dependencies.concat(toDependencies(lock.requires || [], ""));
+            dependencies = dependencies.concat(toDependencies(lock.build_requires || [], "build"));
+            dependencies = dependencies.concat(toDependencies(lock.python_requires || [], "python"));
+        }
+
+        // the root reference is only present when the lock was created from a
+        // conanfile.py declaring a name. otherwise fall back to the repository.
+        const reference = parseReference(root);
+
+        let organization = Globals.ORGANIZATION;
+        let module = reference.module;
+        let version = reference.version;
+        if (!module || module.startsWith("conanfile")) {
+            ({ organization, module } = inferRepository(url));
+            version = "";
+        }
+
+        return asLockfile({
+            language: Languages.CPP,
+            system: "conan",
+            sourceUrl: "",
+            organization,
+            module,
+            version,
+            dependencies,
+            name: module,
+        });
+    }
+}
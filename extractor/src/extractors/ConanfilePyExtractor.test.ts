@@ -0,0 +1,21 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ConanfilePyExtractor from "./ConanfilePyExtractor";
+import ExtractorFile from "./ExtractorFile";
+
+const readFileAsync = promisify(readFile);
+
+describe("ConanfilePyExtractor", () => {
+    test("fullParse", async () => {
+        const conanfilePath = require.resolve("./testdata/conanfile.py");
+        const buffer = await readFileAsync(conanfilePath);
+        const content = buffer.toString();
+
+        const parser = new ConanfilePyExtractor();
+
+        const actual = await parser.extract("git@github.com:organization/module.git", { "conanfile.py": new ExtractorFile(content) });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+});
@@ -0,0 +1,129 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Globals from "./Globals";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+import toDependencies from "./conanutils/toDependencies";
+import inferRepository from "./urlutils/inferRepository";
+
+const attributeScopes = {
+    "requires": "",
+    "build_requires": "build",
+    "tool_requires": "build",
+    "test_requires": "test",
+};
+
+const attributePattern = /^\s*(requires|build_requires|tool_requires|test_requires)\s*=\s*/;
+const callPattern = /self\.(requires|build_requires|tool_requires|test_requires)\(\s*["']([^"']+)["']/g;
+const referencePattern = /["']([^"'\s]+\/[^"'\s]+)["']/g;
+
+function readString(content: string, attribute: string): string {
+    const match = new RegExp(`^\\s*${attribute}\\s*=\\s*["']([^"']+)["']`, "m").exec(content);
+    return match ? match[1] : "";
+}
+
+// readValue returns the right hand side of an assignment, following brackets
+// across lines so that multi-line lists and tuples are read in full.
+function readValue(lines: string[], i: number, start: number): string {
+    let value = "";
+    let depth = 0;
+
+    for (let j = i; j < lines.length; j++) {
+        const line = j === i ? lines[j].substr(start) : lines[j];
+        value += line + "\n";
+
+        for (const c of line) {
+            if (c === "[" || c === "(") {
+                depth++;
+            } else if (c === "]" || c === ")") {
+                depth--;
+            }
+        }
+
+        if (depth <= 0) {
+            break;
+        }
+    }
+
+    return value;
+}
+
+function matchAll(pattern: RegExp, content: string): RegExpExecArray[] {
+    const matches = [];
+    const re = new RegExp(pattern.source, "g");
+
+    let match = re.exec(content);
+    while (match !== null) {
+        matches.push(match);
+        match = re.exec(content);
+    }
+
+    return matches;
+}
+
+export default class ConanfilePyExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/conanfile.py",
+            ],
+            excludes: [
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "conanfile.py" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(url: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+        const content = files["conanfile.py"].raw();
+        const lines = content.split("\n");
+
+        let dependencies: Dependency[] = [];
+
+        // class attributes: requires = "zlib/1.2.11", "boost/1.72.0"
+        lines.forEach((line, i) => {
+            const match = attributePattern.exec(line);
+            if (!match) {
+                return;
+            }
+
+            const value = readValue(lines, i, match[0].length);
+            const references = matchAll(referencePattern, value).map((m) => m[1]);
+
+            dependencies = dependencies.concat(toDependencies(references, attributeScopes[match[1]]));
+        });
+
+        // method calls: self.requires("zlib/1.2.11")
+        matchAll(callPattern, content).forEach((match) => {
+            dependencies = dependencies.concat(toDependencies([ match[2] ], attributeScopes[match[1]]));
+        });
+
+        const name = readString(content, "name");
+        const version = readString(content, "version");
+
+        let organization = Globals.ORGANIZATION;
+        let module = name;
+        if (!module) {
+            ({ organization, module } = inferRepository(url));
+        }
+
+        return {
+            language: Languages.CPP,
+            system: "conan",
+            sourceUrl: readString(content, "url"),
+            organization,
+            module,
+            version,
+            dependencies,
+            name: module,
+        };
+    }
+}
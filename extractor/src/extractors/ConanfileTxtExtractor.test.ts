@@ -0,0 +1,21 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ConanfileTxtExtractor from "./ConanfileTxtExtractor";
+import ExtractorFile from "./ExtractorFile";
+
+const readFileAsync = promisify(readFile);
+
+describe("ConanfileTxtExtractor", () => {
+    test("fullParse", async () => {
+        const conanfilePath = require.resolve("./testdata/conanfile.txt");
+        const buffer = await readFileAsync(conanfilePath);
+        const content = buffer.toString();
+
+        const parser = new ConanfileTxtExtractor();
+
+        const actual = await parser.extract("git@github.com:organization/module.git", { "conanfile.txt": new ExtractorFile(content) });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+});
@@ -0,0 +1,75 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+import toDependencies from "./conanutils/toDependencies";
+import inferRepository from "./urlutils/inferRepository";
+
+const sectionScopes = {
+    "requires": "",
+    "build_requires": "build",
+    "tool_requires": "build",
+    "test_requires": "test",
+};
+
+export default class ConanfileTxtExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/conanfile.txt",
+            ],
+            excludes: [
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "conanfile.txt" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(url: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+        const lines = files["conanfile.txt"].raw().split(/\n+/g).map((line) => line.trim());
+
+        let section = null;
+        let dependencies: Dependency[] = [];
+
+        lines.forEach((line) => {
+            if (line.length === 0 || line.startsWith("#")) {
+                return;
+            }
+
+            if (line.startsWith("[") && line.endsWith("]")) {
+                section = line.substr(1, line.length - 2).trim();
+                return;
+            }
+
+            const scope = sectionScopes[section];
+            if (scope === undefined) {
+                return;
+            }
+
+            dependencies = dependencies.concat(toDependencies([ line.split("#")[0] ], scope));
+        });
+
+        // conanfile.txt only describes the consumer, so the module is named
+        // after the repository containing it.
+        const { organization, module } = inferRepository(url);
+
+        return {
+            language: Languages.CPP,
+            system: "conan",
+            sourceUrl: "",
+            organization,
+            module,
+            version: "",
+            dependencies,
+            name: module,
+        };
+    }
+}
@@ -0,0 +1,39 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import CycloneDxExtractor from "./CycloneDxExtractor";
+import ExtractorFile from "./ExtractorFile";
+
+const readFileAsync = promisify(readFile);
+
+async function readTestData(name: string): Promise<ExtractorFile> {
+    const buffer = await readFileAsync(require.resolve(`./testdata/${name}`));
+    return new ExtractorFile(buffer.toString());
+}
+
+describe("CycloneDxExtractor", () => {
+    test("json", async () => {
+        const parser = new CycloneDxExtractor();
+
+        const actual = await parser.extract("", { "*.{json,xml}": await readTestData("bom.json") });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("xml", async () => {
+        const parser = new CycloneDxExtractor();
+
+        const expected = await parser.extract("", { "*.{json,xml}": await readTestData("bom.json") });
+        const actual = await parser.extract("", { "*.{json,xml}": await readTestData("bom.xml") });
+
+        expect(actual).toEqual(expected);
+    });
+
+    test("notCycloneDx", async () => {
+        const parser = new CycloneDxExtractor();
+
+        const actual = await parser.extract("", { "*.{json,xml}": await readTestData("composer.json") });
+
+        expect(actual).toEqual([]);
+    });
+});
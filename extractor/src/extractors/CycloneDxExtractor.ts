@@ -0,0 +1,147 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import cheerio = require("cheerio");
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import MatchConfig from "../matcher/MatchConfig";
+import toManagementFiles, {Component, Edge} from "./sbomutils/toManagementFiles";
+
+const bomFile = "*.{json,xml}";
+
+const scopes = {
+    "required": "",
+    "optional": "optional",
+    "excluded": "excluded",
+};
+
+interface Bom {
+    root: string;
+    components: { [ref: string]: Component };
+    graph: { [ref: string]: Edge[] };
+}
+
+function refOf(component: any): string {
+    return component["bom-ref"] || component.purl || [ component.group, component.name, component.version ].join(":");
+}
+
+function fromJson(bom: any): Bom {
+    const components = {};
+    const collect = (component: any) => {
+        components[refOf(component)] = {
+            purl: component.purl || "",
+            group: component.group || "",
+            name: component.name || "",
+            version: component.version || "",
+            scope: scopes[component.scope] || "",
+        };
+        (component.components || []).forEach(collect);
+    };
+
+    const metadata = bom.metadata || {};
+    if (metadata.component) {
+        collect(metadata.component);
+    }
+    (bom.components || []).forEach(collect);
+
+    const graph = {};
+    (bom.dependencies || []).forEach((dependency) => {
+        graph[dependency.ref] = (dependency.dependsOn || []).map((ref) => ({ ref }));
+    });
+
+    return {
+        root: metadata.component ? refOf(metadata.component) : "",
+        components,
+        graph,
+    };
+}
+
+function fromXml(raw: string): Bom {
+    const $ = cheerio.load(raw, { xmlMode: true });
+
+    const read = (element: CheerioElement) => {
+        const node = $(element);
+        const component = {
+            "bom-ref": node.attr("bom-ref"),
+            purl: node.children("purl").text(),
+            group: node.children("group").text(),
+            name: node.children("name").text(),
+            version: node.children("version").text(),
+        };
+        return {
+            ref: refOf(component),
+            component: {
+                purl: component.purl,
+                group: component.group,
+                name: component.name,
+                version: component.version,
+                scope: scopes[node.children("scope").text()] || "",
+            },
+        };
+    };
+
+    const components = {};
+    $("bom > metadata > component, bom > components component").each((i, element) => {
+        const { ref, component } = read(element);
+        components[ref] = component;
+    });
+
+    const graph = {};
+    $("bom > dependencies > dependency").each((i, element) => {
+        const node = $(element);
+        graph[node.attr("ref")] = node.children("dependency")
+            .map((j, child) => ({ ref: $(child).attr("ref") }))
+            .get();
+    });
+
+    const root = $("bom > metadata > component").first();
+
+    return {
+        root: root.length > 0 ? read(root.get(0)).ref : "",
+        components,
+        graph,
+    };
+}
+
+// CycloneDxExtractor converts CycloneDX software bill of materials (JSON or
+// XML) into management files. This allows artifacts that are built outside
+// of source control (container images, binaries) to be added to the graph.
+export default class CycloneDxExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/bom.json",
+                "**/bom.xml",
+                "**/*.cdx.json",
+                "**/*.cdx.xml",
+            ],
+            excludes: [
+                "**/node_modules/**",
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ bomFile ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile[]> {
+        const raw = files[bomFile].raw().trim();
+
+        let bom: Bom;
+        if (raw.startsWith("<")) {
+            bom = fromXml(raw);
+        } else {
+            const json = files[bomFile].json();
+            if (json.bomFormat !== "CycloneDX") {
+                return [];
+            }
+            bom = fromJson(json);
+        }
+
+        return toManagementFiles("cyclonedx", bom.root, bom.components, bom.graph);
+    }
+}
@@ -0,0 +1,39 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import DescriptionExtractor from "./DescriptionExtractor";
+import ExtractorFile from "./ExtractorFile";
+
+const readFileAsync = promisify(readFile);
+
+describe("DescriptionExtractor", () => {
+    test("fullParse", async () => {
+        const filePath = require.resolve("./testdata/DESCRIPTION");
+        const buffer = await readFileAsync(filePath);
+        const content = buffer.toString();
+
+        const parser = new DescriptionExtractor();
+
+        const actual = await parser.extract("git@github.com:organization/module.git", { "DESCRIPTION": new ExtractorFile(content) });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("malformedPackages", async () => {
+        const file = new ExtractorFile([
+            "Package: module",
+            "Imports: (>= 1.0.0), rlang (>= 0.4.0),",
+            "    (broken",
+        ].join("\n"));
+
+        const parser = new DescriptionExtractor();
+
+        const actual = await parser.extract("", { "DESCRIPTION": file });
+
+        expect(actual.dependencies.map((dependency) => dependency.name)).toEqual([ "rlang" ]);
+        expect(file.takeWarnings()).toEqual([
+            "unsupported package: (>= 1.0.0)",
+            "unsupported package: (broken",
+        ]);
+    });
+});
@@ -0,0 +1,117 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Globals from "./Globals";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+
+const fieldScopes = {
+    "Depends": "",
+    "Imports": "",
+    "LinkingTo": "linking",
+    "Suggests": "suggests",
+    "Enhances": "enhances",
+};
+
+// parseFields reads a debian control formatted file. Lines starting with
+// whitespace continue the value of the previous field.
+function parseFields(content: string): { [field: string]: string } {
+    const fields = {};
+
+    let field = null;
+    content.split(/\r?\n/g).forEach((line) => {
+        if (line.trim().length === 0) {
+            return;
+        }
+
+        if (/^\s/.test(line)) {
+            if (field) {
+                fields[field] += " " + line.trim();
+            }
+            return;
+        }
+
+        const pos = line.indexOf(":");
+        if (pos === -1) {
+            return;
+        }
+
+        field = line.substr(0, pos).trim();
+        fields[field] = line.substr(pos + 1).trim();
+    });
+
+    return fields;
+}
+
+// parsePackages parses a list of packages such as "dplyr (>= 1.0.0), rlang".
+// The R runtime itself is not a package and is skipped. Entries without a
+// package name are reported as warnings on the file and skipped.
+function parsePackages(file: ExtractorFile, value: string, scope: string): Dependency[] {
+    return value.split(",")
+        .map((entry) => entry.trim())
+        .filter((entry) => entry.length > 0)
+        .map((entry) => {
+            const match = /^([^\s(]+)\s*(?:\(([^)]*)\))?/.exec(entry);
+            if (!match) {
+                file.warn(`unsupported package: ${entry}`);
+                return null;
+            }
+
+            return {
+                organization: Globals.ORGANIZATION,
+                module: match[1],
+                versionConstraint: (match[2] || "").replace(/\s+/g, " ").trim(),
+                scopes: [ scope ],
+                name: match[1],
+            };
+        })
+        .filter((dependency) => !!dependency && dependency.module !== "R");
+}
+
+export default class DescriptionExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/DESCRIPTION",
+            ],
+            excludes: [
+                "**/renv/**",
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "DESCRIPTION" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+        const file = files["DESCRIPTION"];
+        const fields = parseFields(file.raw());
+
+        let dependencies: Dependency[] = [];
+        Object.keys(fieldScopes)
+            .filter((field) => !!fields[field])
+            .forEach((field) => {
+                dependencies = dependencies.concat(parsePackages(file, fields[field], fieldScopes[field]));
+            });
+
+        const name = fields["Package"] || "";
+        const sourceUrl = (fields["URL"] || "").split(/[\s,]+/)[0];
+
+        return {
+            language: Languages.R,
+            system: "cran",
+            sourceUrl,
+            organization: Globals.ORGANIZATION,
+            module: name,
+            version: fields["Version"] || "",
+            dependencies,
+            name,
+        };
+    }
+}
@@ -5,5 +5,17 @@ import MatchConfig from "../matcher/MatchConfig";
 export default interface Extractor {
     matchConfig(): MatchConfig;
     requires(): string[];
-    extract(url: string, files: { [key: string]: ExtractorFile }): Promise<DependencyManagementFile>;
+
+    // usesWorkspace returns true when extract reads the workspace, in which
+    // case its results may depend on files other than the ones it requires.
+    usesWorkspace(): boolean;
+
+    // extract returns the management file described by the required files.
+    // Extractors for aggregate formats (such as an sbom) may return several.
+    // The workspace contains every matched file in the source keyed by path.
+    extract(
+        url: string,
+        files: { [key: string]: ExtractorFile },
+        workspace?: { [path: string]: ExtractorFile },
+    ): Promise<DependencyManagementFile | DependencyManagementFile[]>;
 }
@@ -4,9 +4,17 @@ import TOML = require("toml");
 
 export default class ExtractorFile {
     private readonly body: string;
+    private readonly filePath: string;
+    private readonly warnings: string[];
 
-    constructor(body: string) {
+    constructor(body: string, filePath = "") {
         this.body = body;
+        this.filePath = filePath;
+        this.warnings = [];
+    }
+
+    public path(): string {
+        return this.filePath;
     }
 
     public raw(): string {
@@ -28,4 +36,16 @@ export default class ExtractorFile {
     public xml(): Cheerio {
         return cheerio.load(this.body).root();
     }
+
+    // warn records a problem that didn't prevent the file from being
+    // extracted, such as syntax the extractor doesn't support. Warnings are
+    // reported along with the results of the extraction.
+    public warn(message: string) {
+        this.warnings.push(message);
+    }
+
+    // takeWarnings returns the warnings recorded since it was last called.
+    public takeWarnings(): string[] {
+        return this.warnings.splice(0, this.warnings.length);
+    }
 }
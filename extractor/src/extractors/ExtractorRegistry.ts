@@ -3,14 +3,29 @@ import BowerJsonExtractor from "./BowerJsonExtractor";
 import BuildGradleExtractor from "./BuildGradleExtractor";
 import CargoTomlExtractor from "./CargoTomlExtractor";
 import ComposerJsonExtractor from "./ComposerJsonExtractor";
+import ConanfilePyExtractor from "./ConanfilePyExtractor";
+import ConanfileTxtExtractor from "./ConanfileTxtExtractor";
+import ConanLockExtractor from "./ConanLockExtractor";
+import CycloneDxExtractor from "./CycloneDxExtractor";
+import DescriptionExtractor from "./DescriptionExtractor";
 import Extractor from "./Extractor";
+import GithubWorkflowExtractor from "./GithubWorkflowExtractor";
 import GodepsJsonExtractor from "./GodepsJsonExtractor";
 import GoModExtractor from "./GoModExtractor";
+import GoWorkExtractor from "./GoWorkExtractor";
 import GopkgTomlExtractor from "./GopkgTomlExtractor";
 import IvyXmlExtractor from "./IvyXmlExtractor";
+import ManifestTomlExtractor from "./ManifestTomlExtractor";
 import PackageJsonExtractor from "./PackageJsonExtractor";
+import PackageLockJsonExtractor from "./PackageLockJsonExtractor";
+import PnpmLockExtractor from "./PnpmLockExtractor";
 import PomXmlExtractor from "./PomXmlExtractor";
+import ProjectTomlExtractor from "./ProjectTomlExtractor";
+import RenvLockExtractor from "./RenvLockExtractor";
+import SpdxExtractor from "./SpdxExtractor";
+import VcpkgJsonExtractor from "./VcpkgJsonExtractor";
 import VendorConfExtractor from "./VendorConfExtractor";
+import YarnLockExtractor from "./YarnLockExtractor";
 
 const ExtractorRegistry = new Registry<Extractor>("Extractor");
 
@@ -18,14 +33,30 @@ ExtractorRegistry.registerAll({
     "build.gradle": async () => new BuildGradleExtractor(),
     "Cargo.toml": async () => new CargoTomlExtractor(),
     "composer.json": async () => new ComposerJsonExtractor(),
+    "conanfile.py": async () => new ConanfilePyExtractor(),
+    "conanfile.txt": async () => new ConanfileTxtExtractor(),
+    "conan.lock": async () => new ConanLockExtractor(),
+    "DESCRIPTION": async () => new DescriptionExtractor(),
     "Godeps.json": async () => new GodepsJsonExtractor(),
     "go.mod": async () => new GoModExtractor(),
+    "go.work": async () => new GoWorkExtractor(),
     "Gopkg.toml": async () => new GopkgTomlExtractor(),
     "ivy.xml": async () => new IvyXmlExtractor(),
     "package.json": async () => new PackageJsonExtractor(),
-    "pom.xml": async () => new PomXmlExtractor(),
+    "package-lock.json": async () => new PackageLockJsonExtractor(),
+    "pnpm-lock.yaml": async () => new PnpmLockExtractor(),
+    "pom.xml": async (params) => new PomXmlExtractor(params && params.mavenRepository),
+    "Project.toml": async () => new ProjectTomlExtractor(),
+    "Manifest.toml": async () => new ManifestTomlExtractor(),
+    "renv.lock": async () => new RenvLockExtractor(),
     "bower.json": async () => new BowerJsonExtractor(),
+    "bom.json": async () => new CycloneDxExtractor(),
+    "spdx": async () => new SpdxExtractor(),
+    "vcpkg.json": async () => new VcpkgJsonExtractor(),
+    "vcpkg-lock.json": async () => new VcpkgJsonExtractor(true),
     "vendor.conf": async () => new VendorConfExtractor(),
+    "yarn.lock": async () => new YarnLockExtractor(),
+    ".github/workflows": async () => new GithubWorkflowExtractor(),
 });
 
 export default ExtractorRegistry;
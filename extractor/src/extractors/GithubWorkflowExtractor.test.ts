@@ -0,0 +1,23 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ExtractorFile from "./ExtractorFile";
+import GithubWorkflowExtractor from "./GithubWorkflowExtractor";
+
+const readFileAsync = promisify(readFile);
+
+describe("GithubWorkflowExtractor", () => {
+    test("fullParse", async () => {
+        const workflowPath = require.resolve("./testdata/workflow.yaml");
+        const buffer = await readFileAsync(workflowPath);
+        const content = buffer.toString();
+
+        const parser = new GithubWorkflowExtractor();
+
+        const actual = await parser.extract("git@github.com:depscloud/depscloud.git", {
+            "*.{yml,yaml}": new ExtractorFile(content, ".github/workflows/branch.yaml"),
+        });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+});
@@ -0,0 +1,113 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+import inferRepository from "./urlutils/inferRepository";
+
+const workflowFile = "*.{yml,yaml}";
+
+// parseUses converts a `uses:` reference into a dependency. Local actions
+// (./path) and docker images (docker://image) are not tracked since they do
+// not reference another repository.
+function parseUses(uses: string, scope: string): Dependency {
+    if (!uses || uses.startsWith("./") || uses.startsWith("docker://")) {
+        return null;
+    }
+
+    const pos = uses.lastIndexOf("@");
+    if (pos === -1) {
+        return null;
+    }
+
+    const name = uses.substr(0, pos);
+    const versionConstraint = uses.substr(pos + 1);
+
+    const slash = name.indexOf("/");
+    if (slash === -1) {
+        return null;
+    }
+
+    return {
+        organization: name.substr(0, slash),
+        module: name.substr(slash + 1),
+        versionConstraint,
+        scopes: [ scope ],
+        name,
+    } as Dependency;
+}
+
+export default class GithubWorkflowExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/.github/workflows/*.yml",
+                "**/.github/workflows/*.yaml",
+            ],
+            excludes: [
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ workflowFile ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(url: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+        const file = files[workflowFile];
+        const workflow = file.yaml() || {};
+        const jobs = workflow.jobs || {};
+
+        const uses: Dependency[] = [];
+        Object.keys(jobs).forEach((id) => {
+            const job = jobs[id] || {};
+
+            // reusable workflows are referenced at the job level
+            uses.push(parseUses(job.uses, "workflow"));
+
+            (job.steps || []).forEach((step) => {
+                uses.push(parseUses((step || {}).uses, "action"));
+            });
+        });
+
+        const index: { [key: string]: Dependency } = {};
+        const dependencies: Dependency[] = [];
+        uses.filter((dependency) => !!dependency)
+            .forEach((dependency) => {
+                const key = `${dependency.name}@${dependency.versionConstraint}`;
+
+                const existing = index[key];
+                if (!existing) {
+                    index[key] = dependency;
+                    dependencies.push(dependency);
+                } else if (existing.scopes.indexOf(dependency.scopes[0]) === -1) {
+                    existing.scopes.push(dependency.scopes[0]);
+                }
+            });
+
+        // workflows are named the same way other repositories reference
+        // them: {owner}/{repo}/.github/workflows/{file}
+        const repository = inferRepository(url);
+
+        const organization = repository.organization;
+        const module = [ repository.module, file.path() ]
+            .filter((part) => !!part)
+            .join("/");
+
+        return {
+            language: Languages.ACTIONS,
+            system: "github",
+            sourceUrl: url,
+            organization,
+            module,
+            version: "",
+            dependencies,
+            name: `${organization}/${module}`,
+        };
+    }
+}
@@ -25,8 +25,13 @@ export default class GoModExtractor implements Extractor {
         return [ "go.mod" ];
     }
 
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
     public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
-        const content = files["go.mod"].raw();
+        const file = files["go.mod"];
+        const content = file.raw();
 
         const lines = content.split(/\n+/g).map(i => i.trim());
 
@@ -104,6 +109,7 @@ export default class GoModExtractor implements Extractor {
 
                 default:
                     logger.debug(`parse error: unsupported directive: ${directive}`);
+                    file.warn(`unsupported directive: ${directive}`);
             }
         }
 
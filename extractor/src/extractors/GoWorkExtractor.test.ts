@@ -0,0 +1,23 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ExtractorFile from "./ExtractorFile";
+import GoWorkExtractor from "./GoWorkExtractor";
+
+const readFileAsync = promisify(readFile);
+
+describe("GoWorkExtractor", () => {
+    test("fullParse", async () => {
+        const workspace = {};
+        for (const filePath of [ "go.work", "api/go.mod", "server/go.mod" ]) {
+            const buffer = await readFileAsync(require.resolve(`./testdata/workspaces/go/${filePath}`));
+            workspace[filePath] = new ExtractorFile(buffer.toString(), filePath);
+        }
+
+        const parser = new GoWorkExtractor();
+
+        const actual = await parser.extract("git@github.com:example/monorepo.git", { "go.work": workspace["go.work"] }, workspace);
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+});
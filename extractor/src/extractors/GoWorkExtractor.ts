@@ -0,0 +1,110 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import inferImportPath from "./goutils/inferImportPath";
+import parseImportPath from "./goutils/parseImportPath";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+
+import path = require("path");
+
+function parseUses(content: string): string[] {
+    const lines = content.split(/\n+/g)
+        .map((line) => line.replace(/\/\/.*$/, "").trim());
+
+    const uses = [];
+    for (let i = 0; i < lines.length; i++) {
+        const parts = lines[i].split(/\s+/);
+        if (parts[0] !== "use") {
+            continue;
+        }
+
+        if (parts[1] === "(") {
+            i++;    // uses on subsequent lines
+            for (; i < lines.length && lines[i] !== ")"; i++) {
+                if (lines[i].length > 0) {
+                    uses.push(lines[i]);
+                }
+            }
+        } else if (parts.length > 1) {
+            uses.push(parts[1]);
+        }
+    }
+    return uses;
+}
+
+// GoWorkExtractor describes a go workspace (go.work) as a module of the
+// repository that depends on each of the modules it uses.
+export default class GoWorkExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/go.work",
+            ],
+            excludes: [
+                "**/vendor/**",
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "go.work" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return true;
+    }
+
+    public async extract(
+        url: string,
+        files: { [p: string]: ExtractorFile },
+        workspace?: { [path: string]: ExtractorFile },
+    ): Promise<DependencyManagementFile> {
+        const file = files["go.work"];
+        const dir = path.posix.dirname(file.path());
+
+        let name = inferImportPath(url);
+        if (!name) {
+            return null;
+        } else if (file.path() && dir !== ".") {
+            name = [ name, dir ].join("/");
+        }
+
+        const dependencies: Dependency[] = [];
+        parseUses(file.raw()).forEach((use) => {
+            const goMod = workspace && workspace[path.posix.join(dir, use, "go.mod")];
+            if (!goMod) {
+                return;
+            }
+
+            const matched = /^module\s+(\S+)/m.exec(goMod.raw());
+            if (!matched) {
+                return;
+            }
+
+            const modulePath = matched[1].replace(/"/g, "");
+            const { organization, module } = parseImportPath(modulePath);
+            dependencies.push({
+                organization,
+                module,
+                versionConstraint: "",
+                scopes: [ "workspace" ],
+                name: modulePath,
+            });
+        });
+
+        const { organization, module } = parseImportPath(name);
+
+        return {
+            language: Languages.GO,
+            system: "vgo",
+            sourceUrl: "",
+            organization,
+            module,
+            version: "",
+            dependencies,
+            name,
+        };
+    }
+}
@@ -22,6 +22,10 @@ export default class GodepsJsonExtractor implements Extractor {
         return [ "Godeps.json" ];
     }
 
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
     public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
         const {
             ImportPath,
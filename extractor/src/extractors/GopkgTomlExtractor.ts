@@ -65,6 +65,10 @@ export default class GopkgTomlExtractor implements Extractor {
         return [ "Gopkg.toml" ];
     }
 
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
     public async extract(url: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
         const name = inferImportPath(url);
         const { organization, module } = parseImportPath(name);
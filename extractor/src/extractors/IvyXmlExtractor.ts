@@ -22,6 +22,10 @@ export default class IvyXmlExtractor implements Extractor {
         return [ "ivy.xml" ];
     }
 
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
     public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
         const xml = files["ivy.xml"].xml();
 
@@ -21,7 +21,23 @@ const Languages = {
 
     get JAVASCRIPT() : string {
         return "js";
-    }
+    },
+
+    get ACTIONS() : string {
+        return "actions";
+    },
+
+    get CPP() : string {
+        return "cpp";
+    },
+
+    get R() : string {
+        return "r";
+    },
+
+    get JULIA() : string {
+        return "julia";
+    },
 };
 
 export default Languages;
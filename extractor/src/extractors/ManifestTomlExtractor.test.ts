@@ -0,0 +1,37 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ExtractorFile from "./ExtractorFile";
+import ManifestTomlExtractor from "./ManifestTomlExtractor";
+
+const readFileAsync = promisify(readFile);
+
+async function readTestData(name: string): Promise<ExtractorFile> {
+    const buffer = await readFileAsync(require.resolve(`./testdata/${name}`));
+    return new ExtractorFile(buffer.toString());
+}
+
+describe("ManifestTomlExtractor", () => {
+    test("v1", async () => {
+        const parser = new ManifestTomlExtractor();
+
+        const actual = await parser.extract("", {
+            "Project.toml": await readTestData("Project.toml"),
+            "Manifest.toml": await readTestData("Manifest-v1.toml"),
+        });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("v2", async () => {
+        const parser = new ManifestTomlExtractor();
+
+        const actual = await parser.extract("", {
+            "Project.toml": await readTestData("Project.toml"),
+            "Manifest.toml": await readTestData("Manifest.toml"),
+        });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+});
@@ -0,0 +1,70 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+import {asLockfile} from "./lockutils/withResolvedVersions";
+
+// Manifest.toml records the resolved version of every package in the
+// environment. Packages are grouped by name under [[deps.Name]] in format 2.0
+// and under [[Name]] in older manifests. Multiple packages may share a name,
+// so entries are matched to the project by UUID.
+export default class ManifestTomlExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/Project.toml",
+                "**/Manifest.toml",
+            ],
+            excludes: [
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "Project.toml", "Manifest.toml" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+        const project = files["Project.toml"].toml();
+        const manifest = files["Manifest.toml"].toml();
+
+        const entries = manifest.manifest_format ? (manifest.deps || {}) : manifest;
+
+        const direct = {};
+        Object.keys(project.deps || {})
+            .forEach((name) => direct[project.deps[name]] = true);
+
+        const dependencies: Dependency[] = [];
+        Object.keys(entries)
+            .filter((name) => Array.isArray(entries[name]))
+            .forEach((name) => {
+                entries[name].forEach((entry) => {
+                    dependencies.push({
+                        organization: entry.uuid || "",
+                        module: name,
+                        // standard libraries ship with julia and are not versioned
+                        versionConstraint: entry.version || "",
+                        scopes: [ direct[entry.uuid] ? "" : "transitive" ],
+                        name,
+                    });
+                });
+            });
+
+        return asLockfile({
+            language: Languages.JULIA,
+            system: "pkg",
+            sourceUrl: "",
+            organization: project.uuid || "",
+            module: project.name || "",
+            version: project.version || "",
+            dependencies,
+            name: project.name || "",
+        });
+    }
+}
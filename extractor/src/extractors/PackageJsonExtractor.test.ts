@@ -18,4 +18,34 @@ describe("PackageJsonExtractor", () => {
         expect(actual).toMatchSnapshot();
         expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
     });
+
+    test("npmWorkspace", async () => {
+        const workspace = {};
+        for (const filePath of [ "package.json", "packages/a/package.json", "packages/b/package.json" ]) {
+            const buffer = await readFileAsync(require.resolve(`./testdata/workspaces/npm/${filePath}`));
+            workspace[filePath] = new ExtractorFile(buffer.toString(), filePath);
+        }
+
+        const parser = new PackageJsonExtractor();
+
+        const actual = await parser.extract("git@github.com:example/monorepo.git", { "package.json": workspace["package.json"] }, workspace);
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("pnpmWorkspace", async () => {
+        const workspace = {};
+        for (const filePath of [ "package.json", "pnpm-workspace.yaml", "packages/c/package.json", "packages/private/package.json" ]) {
+            const buffer = await readFileAsync(require.resolve(`./testdata/workspaces/pnpm/${filePath}`));
+            workspace[filePath] = new ExtractorFile(buffer.toString(), filePath);
+        }
+
+        const parser = new PackageJsonExtractor();
+
+        const actual = await parser.extract("git@github.com:example/monorepo.git", { "package.json": workspace["package.json"] }, workspace);
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
 });
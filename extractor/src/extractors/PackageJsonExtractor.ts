@@ -1,24 +1,15 @@
 import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
 import Extractor from "./Extractor";
 import ExtractorFile from "./ExtractorFile";
-import Globals from "./Globals";
 import Languages from "./Languages";
 import MatchConfig from "../matcher/MatchConfig";
+import parseName from "./nodeutils/parseName";
+import inferRepository from "./urlutils/inferRepository";
+import resolveMembers from "./workspaceutils/resolveMembers";
+import declaredLicenses from "./licenseutils/declaredLicenses";
+import withLicenses from "./licenseutils/withLicenses";
 
-interface ID {
-    organization: string;
-    module: string;
-}
-
-function parseName(module: string): ID {
-    let organization = Globals.ORGANIZATION;
-    if (module.charAt(0) === "@") {
-        const index = module.indexOf("/");
-        organization = module.substr(1,  index - 1);
-        module = module.substr(index + 1);
-    }
-    return { organization, module };
-}
+import path = require("path");
 
 function extract(dependencyHash: any, scope: string): Dependency[] {
     return Object.keys(dependencyHash)
@@ -36,11 +27,33 @@ function extract(dependencyHash: any, scope: string): Dependency[] {
         });
 }
 
+// workspacePatterns returns the member patterns declared by npm and yarn
+// (package.json workspaces) or pnpm (pnpm-workspace.yaml).
+function workspacePatterns(
+    file: ExtractorFile,
+    workspaces: any,
+    workspace: { [path: string]: ExtractorFile },
+): string[] {
+    if (Array.isArray(workspaces)) {
+        return workspaces;
+    } else if (workspaces && Array.isArray(workspaces.packages)) {
+        return workspaces.packages;
+    }
+
+    const pnpmWorkspace = workspace && workspace[path.posix.join(path.posix.dirname(file.path()), "pnpm-workspace.yaml")];
+    if (pnpmWorkspace) {
+        return (pnpmWorkspace.yaml() || {}).packages || [];
+    }
+
+    return [];
+}
+
 export default class PackageJsonExtractor implements Extractor {
     public matchConfig(): MatchConfig {
         return {
             includes: [
                 "**/package.json",
+                "**/pnpm-workspace.yaml",
             ],
             excludes: [
                 "**/node_modules/**",
@@ -53,9 +66,17 @@ export default class PackageJsonExtractor implements Extractor {
         return [ "package.json" ];
     }
 
-    public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+    public usesWorkspace(): boolean {
+        return true;
+    }
+
+    public async extract(
+        url: string,
+        files: { [p: string]: ExtractorFile },
+        workspace?: { [path: string]: ExtractorFile },
+    ): Promise<DependencyManagementFile> {
+        const pkg = files["package.json"].json();
         const {
-            name,
             version,
             repository,
             dependencies,
@@ -63,9 +84,20 @@ export default class PackageJsonExtractor implements Extractor {
             peerDependencies,
             // bundledDependencies,
             optionalDependencies,
-        } = files["package.json"].json();
+            workspaces,
+            license,
+            licenses,
+        } = pkg;
 
-        const { organization, module } = parseName((name || ""));
+        let name = pkg.name;
+
+        const patterns = workspacePatterns(files["package.json"], workspaces, workspace);
+        if (!name && patterns.length > 0) {
+            // workspace roots are frequently left unnamed
+            name = inferRepository(url).module;
+        }
+
+        const { organization, module } = parseName(name);
 
         let allDependencies = extract((dependencies || {}), "");
         allDependencies = allDependencies.concat(extract((devDependencies || {}), "dev"));
@@ -73,18 +105,32 @@ export default class PackageJsonExtractor implements Extractor {
         // deps = deps.concat(extract((bundledDependencies || {}), "bundled"));
         allDependencies = allDependencies.concat(extract((optionalDependencies || {}), "optional"));
 
+        resolveMembers(files["package.json"], "package.json", patterns, workspace)
+            .map((member) => member.json())
+            .filter((member) => !!member.name)
+            .forEach((member) => {
+                const id = parseName(member.name);
+                allDependencies.push({
+                    organization: id.organization,
+                    module: id.module,
+                    versionConstraint: member.version || "",
+                    scopes: [ "workspace" ],
+                    name: member.name,
+                });
+            });
+
         let sourceUrl = repository;
         if (typeof repository === "object") {
             sourceUrl = repository.url;
         }
 
-        return {
+        return withLicenses({
             language: Languages.NODE,
             system: "npm",
             sourceUrl,
             organization, module, version,
             dependencies: allDependencies,
             name,
-        };
+        }, declaredLicenses(license, licenses));
     }
 }
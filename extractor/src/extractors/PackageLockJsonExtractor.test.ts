@@ -0,0 +1,44 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ExtractorFile from "./ExtractorFile";
+import PackageLockJsonExtractor from "./PackageLockJsonExtractor";
+
+const readFileAsync = promisify(readFile);
+
+describe("PackageLockJsonExtractor", () => {
+    test("fullParse", async () => {
+        const lockPath = require.resolve("./testdata/package-lock.json");
+        const buffer = await readFileAsync(lockPath);
+        const content = buffer.toString();
+
+        const parser = new PackageLockJsonExtractor();
+
+        const actual = await parser.extract("", { "package-lock.json": new ExtractorFile(content) });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("unversioned", async () => {
+        const lockPath = require.resolve("./testdata/package-lock-unversioned.json");
+        const buffer = await readFileAsync(lockPath);
+        const content = buffer.toString();
+
+        const parser = new PackageLockJsonExtractor();
+
+        const actual = await parser.extract("", { "package-lock.json": new ExtractorFile(content) });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("lockfileVersion1", async () => {
+        const parser = new PackageLockJsonExtractor();
+
+        const actual = await parser.extract("", {
+            "package-lock.json": new ExtractorFile("{\"name\":\"module\",\"lockfileVersion\":1,\"dependencies\":{}}"),
+        });
+
+        expect(actual).toBeNull();
+    });
+});
@@ -0,0 +1,127 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+import {asLockfile} from "./lockutils/withResolvedVersions";
+import parseName from "./nodeutils/parseName";
+
+const nodeModules = "node_modules/";
+
+// installedAs returns the name the package was installed under at the
+// provided path (node_modules/a/node_modules/@b/c => @b/c).
+function installedAs(path: string): string {
+    return path.substr(path.lastIndexOf(nodeModules) + nodeModules.length);
+}
+
+function scopesFor(entry: any, scope: string): string[] {
+    const scopes = [ scope ];
+    if (entry.dev && scope !== "dev") {
+        scopes.push("dev");
+    }
+    if (entry.optional && scope !== "optional") {
+        scopes.push("optional");
+    }
+    return scopes;
+}
+
+export default class PackageLockJsonExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/package-lock.json",
+            ],
+            excludes: [
+                "**/node_modules/**",
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "package-lock.json" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+        const lock = files["package-lock.json"].json();
+
+        // lockfileVersion 1 does not contain the flattened packages section
+        const packages = lock.packages;
+        if (!packages) {
+            return null;
+        }
+
+        const root = packages[""] || {};
+        const name = root.name || lock.name;
+        const version = root.version || lock.version;
+
+        const direct: { [name: string]: string } = {};
+        [
+            { hash: root.dependencies, scope: "" },
+            { hash: root.devDependencies, scope: "dev" },
+            { hash: root.peerDependencies, scope: "peer" },
+            { hash: root.optionalDependencies, scope: "optional" },
+        ].forEach(({ hash, scope }) => {
+            Object.keys(hash || {}).forEach((dependency) => {
+                direct[dependency] = scope;
+            });
+        });
+
+        const seen = {};
+        const dependencies: Dependency[] = [];
+
+        Object.keys(packages)
+            .filter((path) => path.startsWith(nodeModules) || path.indexOf(`/${nodeModules}`) > -1)
+            .forEach((path) => {
+                const entry = packages[path] || {};
+
+                // links point to workspace packages, not installed dependencies
+                if (entry.link) {
+                    return;
+                }
+
+                // aliased packages record their real name on the entry
+                const alias = installedAs(path);
+                const dependency = entry.name || alias;
+                // entries may lack a version, such as git dependencies that
+                // npm couldn't resolve, so they're kept without a constraint
+                const versionConstraint = entry.version ? `${entry.version}` : "";
+
+                const key = `${dependency}@${versionConstraint}`;
+                if (seen[key]) {
+                    return;
+                }
+                seen[key] = true;
+
+                // top level installs of packages declared by the root package
+                // are direct dependencies. everything else is transitive.
+                const isDirect = path === `${nodeModules}${alias}` && direct[alias] !== undefined;
+                const scope = isDirect ? direct[alias] : "transitive";
+
+                const { organization, module } = parseName(dependency);
+
+                dependencies.push({
+                    organization,
+                    module,
+                    versionConstraint,
+                    scopes: scopesFor(entry, scope),
+                    name: dependency,
+                });
+            });
+
+        const { organization, module } = parseName(name);
+
+        return asLockfile({
+            language: Languages.NODE,
+            system: "npm",
+            sourceUrl: "",
+            organization, module, version,
+            dependencies,
+            name,
+        });
+    }
+}
@@ -0,0 +1,55 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ExtractorFile from "./ExtractorFile";
+import PnpmLockExtractor from "./PnpmLockExtractor";
+
+const readFileAsync = promisify(readFile);
+
+async function readTestData(name: string): Promise<ExtractorFile> {
+    const buffer = await readFileAsync(require.resolve(`./testdata/${name}`));
+    return new ExtractorFile(buffer.toString());
+}
+
+describe("PnpmLockExtractor", () => {
+    test("v6", async () => {
+        const parser = new PnpmLockExtractor();
+
+        const actual = await parser.extract("", {
+            "package.json": await readTestData("package.json"),
+            "pnpm-lock.yaml": await readTestData("pnpm-lock-v6.yaml"),
+        });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("v9", async () => {
+        const parser = new PnpmLockExtractor();
+
+        const actual = await parser.extract("", {
+            "package.json": await readTestData("package.json"),
+            "pnpm-lock.yaml": await readTestData("pnpm-lock.yaml"),
+        });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("workspace", async () => {
+        const workspace = {};
+        for (const filePath of [ "package.json", "pnpm-lock.yaml", "packages/c/package.json", "packages/private/package.json" ]) {
+            const buffer = await readFileAsync(require.resolve(`./testdata/workspaces/pnpm/${filePath}`));
+            workspace[filePath] = new ExtractorFile(buffer.toString(), filePath);
+        }
+
+        const parser = new PnpmLockExtractor();
+
+        const actual = await parser.extract("", {
+            "package.json": workspace["package.json"],
+            "pnpm-lock.yaml": workspace["pnpm-lock.yaml"],
+        }, workspace);
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+});
@@ -0,0 +1,130 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+import {asLockfile} from "./lockutils/withResolvedVersions";
+import parseName from "./nodeutils/parseName";
+
+import path = require("path");
+
+// resolveVersion normalizes the version recorded in the lockfile. Peer
+// dependency suffixes (1.0.0(react@17.0.0) in v6+ and 1.0.0_react@17.0.0 in
+// v5) are dropped and workspace links fall back to the declared specifier.
+function resolveVersion(version: string, specifier: string): string {
+    if (!version) {
+        return specifier || "";
+    }
+
+    if (version.startsWith("link:")) {
+        return specifier || version;
+    }
+
+    let resolved = version;
+    const paren = resolved.indexOf("(");
+    if (paren > -1) {
+        resolved = resolved.substr(0, paren);
+    }
+
+    const underscore = resolved.indexOf("_");
+    if (underscore > -1) {
+        resolved = resolved.substr(0, underscore);
+    }
+
+    return resolved;
+}
+
+function extract(dependencyHash: any, specifiers: any, scope: string): Dependency[] {
+    return Object.keys(dependencyHash)
+        .map((dependency) => {
+            const { organization, module } = parseName(dependency);
+
+            // v6+ lockfiles use { specifier, version } while v5 lockfiles
+            // store the version directly and keep specifiers separately.
+            let entry = dependencyHash[dependency];
+            if (typeof entry !== "object") {
+                entry = { specifier: specifiers[dependency], version: entry };
+            }
+
+            return {
+                organization,
+                module,
+                versionConstraint: resolveVersion(`${entry.version || ""}`, entry.specifier),
+                scopes: [ scope ],
+                name: dependency,
+            };
+        });
+}
+
+// managementFile describes the project of an importer in the lockfile, which
+// is named by its package.json.
+function managementFile(packageJson: ExtractorFile, importer: any): DependencyManagementFile {
+    const { name, version } = packageJson.json();
+    const specifiers = importer.specifiers || {};
+
+    const { organization, module } = parseName(name);
+
+    let allDependencies = extract((importer.dependencies || {}), specifiers, "");
+    allDependencies = allDependencies.concat(extract((importer.devDependencies || {}), specifiers, "dev"));
+    allDependencies = allDependencies.concat(extract((importer.optionalDependencies || {}), specifiers, "optional"));
+
+    return asLockfile({
+        language: Languages.NODE,
+        system: "pnpm",
+        sourceUrl: "",
+        organization, module, version,
+        dependencies: allDependencies,
+        name,
+    });
+}
+
+export default class PnpmLockExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/package.json",
+                "**/pnpm-lock.yaml",
+            ],
+            excludes: [
+                "**/node_modules/**",
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "package.json", "pnpm-lock.yaml" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return true;
+    }
+
+    public async extract(
+        _: string,
+        files: { [p: string]: ExtractorFile },
+        workspace?: { [path: string]: ExtractorFile },
+    ): Promise<DependencyManagementFile | DependencyManagementFile[]> {
+        const lock = files["pnpm-lock.yaml"].yaml() || {};
+        const importers = lock.importers || {};
+
+        // workspaces (and all v9 lockfiles) nest the root project under
+        // importers while older single project lockfiles keep it top level.
+        const root = managementFile(files["package.json"], importers["."] || lock);
+
+        // a workspace shares the lockfile at its root, where the other
+        // importers are keyed by the path of their package relative to it.
+        const lockPath = files["pnpm-lock.yaml"].path();
+        if (!workspace || !lockPath) {
+            return root;
+        }
+
+        const dir = path.posix.dirname(lockPath);
+        const members = Object.keys(importers)
+            .filter((importer) => importer !== ".")
+            .filter((importer) => workspace[path.posix.join(dir, importer, "package.json")])
+            .map((importer) => managementFile(workspace[path.posix.join(dir, importer, "package.json")], importers[importer]));
+
+        return members.length > 0 ? [ root ].concat(members) : root;
+    }
+}
@@ -18,4 +18,39 @@ describe("PomXmlExtractor", () => {
         expect(actual).toMatchSnapshot();
         expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
     });
+
+    test("parentResolution", async () => {
+        const read = async (name: string) => {
+            const buffer = await readFileAsync(require.resolve(`./testdata/${name}`));
+            return buffer.toString();
+        };
+
+        const workspace = {
+            "pom.xml": new ExtractorFile(await read("pom-parent.xml"), "pom.xml"),
+            "bom/pom.xml": new ExtractorFile(await read("pom-bom.xml"), "bom/pom.xml"),
+            "service/pom.xml": new ExtractorFile(await read("pom-child.xml"), "service/pom.xml"),
+        };
+
+        const parser = new PomXmlExtractor();
+
+        const actual = await parser.extract("", { "pom.xml": workspace["service/pom.xml"] }, workspace);
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("multiModule", async () => {
+        const workspace = {};
+        for (const filePath of [ "pom.xml", "core/pom.xml", "service/pom.xml" ]) {
+            const buffer = await readFileAsync(require.resolve(`./testdata/workspaces/maven/${filePath}`));
+            workspace[filePath] = new ExtractorFile(buffer.toString(), filePath);
+        }
+
+        const parser = new PomXmlExtractor();
+
+        const actual = await parser.extract("git@github.com:example/monorepo.git", { "pom.xml": workspace["pom.xml"] }, workspace);
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
 });
@@ -1,11 +1,24 @@
 import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
-import cheerio = require("cheerio");
 import Extractor from "./Extractor";
 import ExtractorFile from "./ExtractorFile";
 import Languages from "./Languages";
 import MatchConfig from "../matcher/MatchConfig";
+import MavenRepository from "./mavenutils/MavenRepository";
+import PomResolver, {managementKey} from "./mavenutils/PomResolver";
+import interpolate from "./mavenutils/interpolate";
+import withLicenses from "./licenseutils/withLicenses";
+
+import path = require("path");
 
 export default class PomXmlExtractor implements Extractor {
+    private readonly repository: MavenRepository;
+
+    // when a maven repository is provided, parent poms and boms that are not
+    // part of the source are fetched from it.
+    constructor(mavenRepository?: string) {
+        this.repository = mavenRepository ? new MavenRepository(mavenRepository) : null;
+    }
+
     public matchConfig(): MatchConfig {
         return {
             includes: [
@@ -22,43 +35,78 @@ export default class PomXmlExtractor implements Extractor {
         return [ "pom.xml" ];
     }
 
-    public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
-        const xml = files["pom.xml"].xml();
+    public usesWorkspace(): boolean {
+        return true;
+    }
 
-        const parentGroupId = xml.find("project > parent > groupId").text();
-        const parentArtifactId = xml.find("project > parent > artifactId").text();
-        const parentVersion = xml.find("project > parent > version").text();
+    public async extract(
+        _: string,
+        files: { [p: string]: ExtractorFile },
+        workspace?: { [path: string]: ExtractorFile },
+    ): Promise<DependencyManagementFile> {
+        const resolver = new PomResolver(workspace, this.repository);
+        const { pom, effective } = await resolver.resolve(files["pom.xml"]);
+        const { properties } = effective;
 
-        const groupId = xml.find("project > groupId").text() || parentGroupId;
-        const artifactId = xml.find("project > artifactId").text();
-        const version = xml.find("project > version").text();
+        const groupId = effective.groupId;
+        const artifactId = effective.artifactId;
+        const version = effective.version;
 
-        const sourceUrl = xml.find("project > scm > url").text();
+        const sourceUrl = interpolate(pom.scmUrl, properties);
 
         const dependencies: Dependency[] = [];
-        if (parentGroupId && parentArtifactId && parentVersion) {
-            dependencies.push({
-                organization: parentGroupId,
-                module: parentArtifactId,
-                versionConstraint: parentVersion,
-                scopes: [ "parent" ],
-                name: [parentGroupId, parentArtifactId].join(":"),
-            });
+        if (pom.parent) {
+            const parentGroupId = interpolate(pom.parent.groupId, properties);
+            const parentArtifactId = interpolate(pom.parent.artifactId, properties);
+            const parentVersion = interpolate(pom.parent.version, properties);
+
+            if (parentGroupId && parentArtifactId && parentVersion) {
+                dependencies.push({
+                    organization: parentGroupId,
+                    module: parentArtifactId,
+                    versionConstraint: parentVersion,
+                    scopes: [ "parent" ],
+                    name: [parentGroupId, parentArtifactId].join(":"),
+                });
+            }
         }
 
-        const matched: Cheerio = xml.find("project > dependencies > dependency");
-        matched.map((i, match: CheerioElement) => {
-            const organization = cheerio(match).find("> groupId").text();
-            const module = cheerio(match).find("> artifactId").text();
-            const versionConstraint = cheerio(match).find("> version").text();
-            const scope = cheerio(match).find("> scope").text();
+        pom.dependencies.forEach((dep) => {
+            const organization = interpolate(dep.groupId, properties);
+            const module = interpolate(dep.artifactId, properties);
+            const managed = effective.managed[managementKey({ groupId: organization, artifactId: module })];
+
+            const versionConstraint = interpolate(dep.version, properties) || (managed && managed.version) || "";
+            const scope = interpolate(dep.scope, properties) || (managed && managed.scope) || "";
 
             const scopes = [scope || "compile"];
 
-            dependencies.push({organization, module, versionConstraint, scopes, name: [groupId, artifactId].join(":")});
+            dependencies.push({organization, module, versionConstraint, scopes, name: [organization, module].join(":")});
         });
 
-        return {
+        // modules of a multi-module build are resolved from the workspace
+        const dir = path.posix.dirname(files["pom.xml"].path());
+        for (const modulePath of pom.modules) {
+            let candidate = path.posix.join(dir, interpolate(modulePath, properties));
+            if (!candidate.endsWith(".xml") && !candidate.endsWith(".pom")) {
+                candidate = path.posix.join(candidate, "pom.xml");
+            }
+
+            if (!files["pom.xml"].path() || !workspace || !workspace[candidate]) {
+                continue;
+            }
+
+            const member = (await resolver.resolve(workspace[candidate])).effective;
+            dependencies.push({
+                organization: member.groupId,
+                module: member.artifactId,
+                versionConstraint: member.version,
+                scopes: [ "workspace" ],
+                name: [member.groupId, member.artifactId].join(":"),
+            });
+        }
+
+        return withLicenses({
             language: Languages.JAVA,
             system: "maven",
             sourceUrl,
@@ -67,6 +115,6 @@ export default class PomXmlExtractor implements Extractor {
             version,
             dependencies,
             name: [groupId, artifactId].join(":"),
-        };
+        }, effective.licenses);
     }
 }
@@ -0,0 +1,50 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ExtractorFile from "./ExtractorFile";
+import ProjectTomlExtractor from "./ProjectTomlExtractor";
+
+const readFileAsync = promisify(readFile);
+
+describe("ProjectTomlExtractor", () => {
+    test("fullParse", async () => {
+        const projectToml = require.resolve("./testdata/Project.toml");
+        const buffer = await readFileAsync(projectToml);
+        const content = buffer.toString();
+
+        const parser = new ProjectTomlExtractor();
+
+        const actual = await parser.extract("", { "*Project.toml": new ExtractorFile(content) });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("juliaProject", async () => {
+        const juliaProjectToml = require.resolve("./testdata/JuliaProject.toml");
+        const buffer = await readFileAsync(juliaProjectToml);
+        const content = buffer.toString();
+
+        const parser = new ProjectTomlExtractor();
+
+        const actual = await parser.extract("", { "*Project.toml": new ExtractorFile(content, "JuliaProject.toml") });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("prefersJuliaProject", async () => {
+        const workspace = {};
+        for (const filePath of [ "Project.toml", "JuliaProject.toml" ]) {
+            const buffer = await readFileAsync(require.resolve(`./testdata/${filePath}`));
+            workspace[filePath] = new ExtractorFile(buffer.toString(), filePath);
+        }
+
+        const parser = new ProjectTomlExtractor();
+
+        const project = await parser.extract("", { "*Project.toml": workspace["Project.toml"] }, workspace);
+        expect(project).toBeNull();
+
+        const juliaProject = await parser.extract("", { "*Project.toml": workspace["JuliaProject.toml"] }, workspace);
+        expect(juliaProject.module).toEqual("Example");
+    });
+});
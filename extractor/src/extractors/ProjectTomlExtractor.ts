@@ -0,0 +1,90 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+
+import path = require("path");
+
+// Julia packages are identified by their UUID rather than their name, so the
+// UUID is used as the organization of every module.
+//
+// Projects may be described by either a Project.toml or a JuliaProject.toml.
+// The pattern is limited to those names by the matcher. When a directory has
+// both, Pkg reads JuliaProject.toml, so the Project.toml is skipped.
+const projectFile = "*Project.toml";
+
+export default class ProjectTomlExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/Project.toml",
+                "**/JuliaProject.toml",
+            ],
+            excludes: [
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ projectFile ];
+    }
+
+    public usesWorkspace(): boolean {
+        return true;
+    }
+
+    public async extract(
+        _: string,
+        files: { [p: string]: ExtractorFile },
+        workspace?: { [path: string]: ExtractorFile },
+    ): Promise<DependencyManagementFile> {
+        const file = files[projectFile];
+        const filePath = file.path();
+
+        if (workspace && path.posix.basename(filePath) === "Project.toml"
+            && workspace[path.posix.join(path.posix.dirname(filePath), "JuliaProject.toml")]) {
+            return null;
+        }
+
+        const project = file.toml();
+
+        const compat = project.compat || {};
+        const testTargets = (project.targets || {}).test || [];
+
+        const toDependencies = (hash: any, scope: string): Dependency[] => Object.keys(hash || {})
+            .map((name) => ({
+                organization: hash[name],
+                module: name,
+                versionConstraint: compat[name] || "",
+                scopes: [ scope ],
+                name,
+            }));
+
+        let dependencies = toDependencies(project.deps, "");
+        dependencies = dependencies.concat(toDependencies(project.weakdeps, "weak"));
+
+        const extras = project.extras || {};
+        dependencies = dependencies.concat(toDependencies(
+            testTargets.reduce((agg, name) => {
+                if (extras[name]) {
+                    agg[name] = extras[name];
+                }
+                return agg;
+            }, {}),
+            "test",
+        ));
+
+        return {
+            language: Languages.JULIA,
+            system: "pkg",
+            sourceUrl: "",
+            organization: project.uuid || "",
+            module: project.name || "",
+            version: project.version || "",
+            dependencies,
+            name: project.name || "",
+        };
+    }
+}
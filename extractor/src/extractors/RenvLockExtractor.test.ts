@@ -0,0 +1,21 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ExtractorFile from "./ExtractorFile";
+import RenvLockExtractor from "./RenvLockExtractor";
+
+const readFileAsync = promisify(readFile);
+
+describe("RenvLockExtractor", () => {
+    test("fullParse", async () => {
+        const filePath = require.resolve("./testdata/renv.lock");
+        const buffer = await readFileAsync(filePath);
+        const content = buffer.toString();
+
+        const parser = new RenvLockExtractor();
+
+        const actual = await parser.extract("git@github.com:organization/module.git", { "renv.lock": new ExtractorFile(content) });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+});
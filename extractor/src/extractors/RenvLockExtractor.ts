@@ -0,0 +1,64 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Globals from "./Globals";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+import {asLockfile} from "./lockutils/withResolvedVersions";
+import inferRepository from "./urlutils/inferRepository";
+
+export default class RenvLockExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/renv.lock",
+            ],
+            excludes: [
+                "**/renv/**",
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "renv.lock" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(url: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+        const { Packages: packages } = files["renv.lock"].json();
+
+        // renv.lock snapshots the entire library, so dependencies are not
+        // separated into direct and transitive ones.
+        const dependencies: Dependency[] = Object.keys(packages || {})
+            .map((key) => {
+                const { Package: name, Version: version } = packages[key];
+
+                return {
+                    organization: Globals.ORGANIZATION,
+                    module: name || key,
+                    versionConstraint: version || "",
+                    scopes: [ "" ],
+                    name: name || key,
+                };
+            });
+
+        // renv projects are typically analyses rather than packages, so the
+        // module is named after the repository.
+        const { organization, module } = inferRepository(url);
+
+        return asLockfile({
+            language: Languages.R,
+            system: "renv",
+            sourceUrl: "",
+            organization,
+            module,
+            version: "",
+            dependencies,
+            name: module,
+        });
+    }
+}
@@ -0,0 +1,27 @@
+// Scopes are the canonical classification of a dependency. Extractors report
+// ecosystem specific scopes (compile, devDependencies, build_requires, ...)
+// which are mapped onto one of these so edges can be compared across
+// languages.
+const Scopes = {
+    get RUNTIME() : string {
+        return "runtime";
+    },
+
+    get DEV() : string {
+        return "dev";
+    },
+
+    get TEST() : string {
+        return "test";
+    },
+
+    get BUILD() : string {
+        return "build";
+    },
+
+    get OPTIONAL() : string {
+        return "optional";
+    },
+};
+
+export default Scopes;
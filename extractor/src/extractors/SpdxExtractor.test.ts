@@ -0,0 +1,31 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ExtractorFile from "./ExtractorFile";
+import SpdxExtractor from "./SpdxExtractor";
+
+const readFileAsync = promisify(readFile);
+
+async function readTestData(name: string): Promise<ExtractorFile> {
+    const buffer = await readFileAsync(require.resolve(`./testdata/${name}`));
+    return new ExtractorFile(buffer.toString());
+}
+
+describe("SpdxExtractor", () => {
+    test("json", async () => {
+        const parser = new SpdxExtractor();
+
+        const actual = await parser.extract("", { "*.{spdx,json}": await readTestData("module.spdx.json") });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("tagValue", async () => {
+        const parser = new SpdxExtractor();
+
+        const expected = await parser.extract("", { "*.{spdx,json}": await readTestData("module.spdx.json") });
+        const actual = await parser.extract("", { "*.{spdx,json}": await readTestData("module.spdx") });
+
+        expect(actual).toEqual(expected);
+    });
+});
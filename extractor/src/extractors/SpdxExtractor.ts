@@ -0,0 +1,209 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import MatchConfig from "../matcher/MatchConfig";
+import toManagementFiles, {Component, Edge} from "./sbomutils/toManagementFiles";
+
+const spdxFile = "*.{spdx,json}";
+const documentId = "SPDXRef-DOCUMENT";
+
+// relationships from the dependent to the dependency
+const dependsOn = {
+    "DEPENDS_ON": "",
+    "CONTAINS": "",
+};
+
+// relationships from the dependency to the dependent
+const dependencyOf = {
+    "DEPENDENCY_OF": "",
+    "RUNTIME_DEPENDENCY_OF": "",
+    "DEV_DEPENDENCY_OF": "dev",
+    "TEST_DEPENDENCY_OF": "test",
+    "BUILD_DEPENDENCY_OF": "build",
+    "OPTIONAL_DEPENDENCY_OF": "optional",
+    "PROVIDED_DEPENDENCY_OF": "provided",
+    "CONTAINED_BY": "",
+};
+
+interface Package {
+    id: string;
+    name: string;
+    version: string;
+    purl: string;
+}
+
+interface Relationship {
+    from: string;
+    type: string;
+    to: string;
+}
+
+interface Document {
+    describes: string[];
+    packages: Package[];
+    relationships: Relationship[];
+}
+
+function fromJson(json: any): Document {
+    const packages = (json.packages || []).map((pkg) => {
+        const purlRef = (pkg.externalRefs || [])
+            .find((ref) => ref.referenceType === "purl");
+
+        return {
+            id: pkg.SPDXID,
+            name: pkg.name || "",
+            version: pkg.versionInfo || "",
+            purl: purlRef ? purlRef.referenceLocator : "",
+        };
+    });
+
+    const relationships = (json.relationships || []).map((relationship) => ({
+        from: relationship.spdxElementId,
+        type: relationship.relationshipType,
+        to: relationship.relatedSpdxElement,
+    }));
+
+    return {
+        describes: json.documentDescribes || [],
+        packages,
+        relationships,
+    };
+}
+
+// fromTagValue parses the tag-value format. Every package starts with a
+// PackageName tag and the tags following it describe that package. Values
+// wrapped in <text></text> may span multiple lines and are skipped.
+function fromTagValue(raw: string): Document {
+    const document: Document = { describes: [], packages: [], relationships: [] };
+
+    let current: Package = null;
+    let inText = false;
+
+    raw.split(/\r?\n/g).forEach((line) => {
+        if (inText) {
+            inText = line.indexOf("</text>") === -1;
+            return;
+        }
+
+        const pos = line.indexOf(":");
+        if (pos === -1 || line.startsWith("#")) {
+            return;
+        }
+
+        const tag = line.substr(0, pos).trim();
+        const value = line.substr(pos + 1).trim();
+
+        if (value.startsWith("<text>")) {
+            inText = value.indexOf("</text>") === -1;
+            return;
+        }
+
+        switch (tag) {
+        case "PackageName":
+            current = { id: "", name: value, version: "", purl: "" };
+            document.packages.push(current);
+            break;
+        case "SPDXID":
+            if (current) {
+                current.id = value;
+            }
+            break;
+        case "PackageVersion":
+            if (current) {
+                current.version = value;
+            }
+            break;
+        case "ExternalRef": {
+            const [ , type, locator ] = value.split(/\s+/);
+            if (current && type === "purl") {
+                current.purl = locator;
+            }
+            break;
+        }
+        case "FileName":
+        case "SnippetSPDXID":
+            // files and snippets follow the packages they belong to
+            current = null;
+            break;
+        case "Relationship": {
+            const [ from, type, to ] = value.split(/\s+/);
+            document.relationships.push({ from, type, to });
+            break;
+        }
+        }
+    });
+
+    return document;
+}
+
+// SpdxExtractor converts SPDX 2.x documents (JSON or tag-value) into
+// management files using the dependency relationships between packages.
+export default class SpdxExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/*.spdx",
+                "**/*.spdx.json",
+            ],
+            excludes: [
+                "**/node_modules/**",
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ spdxFile ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile[]> {
+        const raw = files[spdxFile].raw().trim();
+
+        let document: Document;
+        if (raw.startsWith("{")) {
+            const json = files[spdxFile].json();
+            if (!`${json.spdxVersion || ""}`.startsWith("SPDX-")) {
+                return [];
+            }
+            document = fromJson(json);
+        } else if (raw.indexOf("SPDXVersion:") > -1) {
+            document = fromTagValue(raw);
+        } else {
+            return [];
+        }
+
+        const components: { [ref: string]: Component } = {};
+        document.packages.forEach((pkg) => {
+            components[pkg.id] = {
+                purl: pkg.purl,
+                group: "",
+                name: pkg.name,
+                version: pkg.version,
+                scope: "",
+            };
+        });
+
+        const graph: { [ref: string]: Edge[] } = {};
+        const addEdge = (from: string, to: string, scope: string) => {
+            graph[from] = graph[from] || [];
+            graph[from].push({ ref: to, scope });
+        };
+
+        const describes = document.describes.slice();
+        document.relationships.forEach(({ from, type, to }) => {
+            if (from === documentId && type === "DESCRIBES") {
+                describes.push(to);
+            } else if (dependsOn[type] !== undefined) {
+                addEdge(from, to, dependsOn[type]);
+            } else if (dependencyOf[type] !== undefined) {
+                addEdge(to, from, dependencyOf[type]);
+            }
+        });
+
+        return toManagementFiles("spdx", describes[0] || "", components, graph);
+    }
+}
@@ -0,0 +1,36 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ExtractorFile from "./ExtractorFile";
+import VcpkgJsonExtractor from "./VcpkgJsonExtractor";
+
+const readFileAsync = promisify(readFile);
+
+async function readTestData(name: string): Promise<ExtractorFile> {
+    const buffer = await readFileAsync(require.resolve(`./testdata/${name}`));
+    return new ExtractorFile(buffer.toString());
+}
+
+describe("VcpkgJsonExtractor", () => {
+    test("fullParse", async () => {
+        const parser = new VcpkgJsonExtractor();
+
+        const actual = await parser.extract("", {
+            "vcpkg.json": await readTestData("vcpkg.json"),
+        });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("locked", async () => {
+        const parser = new VcpkgJsonExtractor(true);
+
+        const actual = await parser.extract("", {
+            "vcpkg.json": await readTestData("vcpkg.json"),
+            "vcpkg-lock.json": await readTestData("vcpkg-lock.json"),
+        });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+});
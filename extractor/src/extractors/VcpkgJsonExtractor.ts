@@ -0,0 +1,123 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Globals from "./Globals";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+import inferRepository from "./urlutils/inferRepository";
+
+const manifestFile = "vcpkg.json";
+const lockFile = "vcpkg-lock.json";
+const defaultRegistry = "https://github.com/microsoft/vcpkg";
+
+function versionOf(manifest: any): string {
+    return manifest["version"] ||
+        manifest["version-semver"] ||
+        manifest["version-date"] ||
+        manifest["version-string"] ||
+        "";
+}
+
+// versionConstraint determines the constraint placed on a dependency. Overrides
+// pin an exact version, "version>=" sets a minimum and everything else floats
+// with the registry baseline.
+function versionConstraint(dependency: any, overrides: { [name: string]: string }, baseline: string): string {
+    if (overrides[dependency.name]) {
+        return overrides[dependency.name];
+    }
+
+    if (dependency["version>="]) {
+        return `>=${dependency["version>="]}`;
+    }
+
+    if (baseline) {
+        return `baseline:${baseline}`;
+    }
+
+    return "";
+}
+
+export default class VcpkgJsonExtractor implements Extractor {
+    private readonly locked: boolean;
+
+    constructor(locked = false) {
+        this.locked = locked;
+    }
+
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                `**/${manifestFile}`,
+                `**/${lockFile}`,
+            ],
+            excludes: [
+                "**/vcpkg_installed/**",
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        if (this.locked) {
+            return [ manifestFile, lockFile ];
+        }
+        return [ manifestFile ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(url: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+        const manifest = files[manifestFile].json();
+
+        // the lockfile pins the commit of each git registry. when the manifest
+        // does not declare a builtin-baseline, the default registry pin is used.
+        let baseline = manifest["builtin-baseline"] || "";
+        if (this.locked && !baseline) {
+            const registry = files[lockFile].json()[defaultRegistry] || {};
+            baseline = registry["HEAD"] || "";
+        }
+
+        const overrides = (manifest.overrides || [])
+            .reduce((agg, override) => {
+                agg[override.name] = versionOf(override);
+                return agg;
+            }, {});
+
+        const dependencies: Dependency[] = (manifest.dependencies || [])
+            .map((dependency) => typeof dependency === "string" ? { name: dependency } : dependency)
+            .map((dependency) => {
+                const scopes = [ dependency.host ? "host" : "" ];
+                (dependency.features || []).forEach((feature) => {
+                    scopes.push(`feature:${typeof feature === "string" ? feature : feature.name}`);
+                });
+
+                return {
+                    organization: Globals.ORGANIZATION,
+                    module: dependency.name,
+                    versionConstraint: versionConstraint(dependency, overrides, baseline),
+                    scopes,
+                    name: dependency.name,
+                };
+            });
+
+        // manifests used by applications are not required to declare a name
+        let organization = Globals.ORGANIZATION;
+        let module = manifest.name;
+        if (!module) {
+            ({ organization, module } = inferRepository(url));
+        }
+
+        return {
+            language: Languages.CPP,
+            system: "vcpkg",
+            sourceUrl: manifest.homepage || "",
+            organization,
+            module,
+            version: versionOf(manifest),
+            dependencies,
+            name: module,
+        };
+    }
+}
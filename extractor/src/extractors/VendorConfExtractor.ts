@@ -26,6 +26,10 @@ export default class VendorConfExtractor implements Extractor {
         return [ fileName ];
     }
 
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
     public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
         const content = files[fileName].raw();
 
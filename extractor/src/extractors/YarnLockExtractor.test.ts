@@ -0,0 +1,36 @@
+import {readFile} from "fs";
+import {promisify} from "util";
+import ExtractorFile from "./ExtractorFile";
+import YarnLockExtractor from "./YarnLockExtractor";
+
+const readFileAsync = promisify(readFile);
+
+async function readTestData(name: string): Promise<ExtractorFile> {
+    const buffer = await readFileAsync(require.resolve(`./testdata/${name}`));
+    return new ExtractorFile(buffer.toString());
+}
+
+describe("YarnLockExtractor", () => {
+    test("fullParse", async () => {
+        const parser = new YarnLockExtractor();
+
+        const actual = await parser.extract("", {
+            "package.json": await readTestData("yarn.package.json"),
+            "yarn.lock": await readTestData("yarn.lock"),
+        });
+
+        expect(actual).toMatchSnapshot();
+        expect(JSON.stringify(actual, null, 2)).toMatchSnapshot();
+    });
+
+    test("classic", async () => {
+        const parser = new YarnLockExtractor();
+
+        const actual = await parser.extract("", {
+            "package.json": await readTestData("package.json"),
+            "yarn.lock": new ExtractorFile("# yarn lockfile v1\n\n\ndevDependency@1.0.0:\n  version \"1.0.0\"\n"),
+        });
+
+        expect(actual).toBeNull();
+    });
+});
@@ -0,0 +1,144 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Extractor from "./Extractor";
+import ExtractorFile from "./ExtractorFile";
+import Languages from "./Languages";
+import MatchConfig from "../matcher/MatchConfig";
+import {asLockfile} from "./lockutils/withResolvedVersions";
+import parseName from "./nodeutils/parseName";
+
+const localVersion = "0.0.0-use.local";
+
+interface Descriptor {
+    name: string;
+    range: string;
+}
+
+// parseDescriptor splits a descriptor (name@range) into its parts, taking care
+// of scoped package names.
+function parseDescriptor(descriptor: string): Descriptor {
+    const pos = descriptor.indexOf("@", 1);
+    if (pos === -1) {
+        return { name: descriptor, range: "" };
+    }
+    return {
+        name: descriptor.substr(0, pos),
+        range: descriptor.substr(pos + 1),
+    };
+}
+
+// indexLock maps every descriptor in the lockfile to the version it resolved
+// to. Ranges using the default npm: protocol are indexed with and without it.
+function indexLock(lock: any): { [descriptor: string]: string } {
+    const index = {};
+
+    Object.keys(lock)
+        .filter((key) => key !== "__metadata")
+        .forEach((key) => {
+            const { version } = lock[key] || {};
+
+            key.split(",")
+                .map((descriptor) => parseDescriptor(descriptor.trim()))
+                .forEach(({ name, range }) => {
+                    index[`${name}@${range}`] = `${version}`;
+                    if (range.startsWith("npm:")) {
+                        index[`${name}@${range.substr(4)}`] = `${version}`;
+                    }
+                });
+        });
+
+    return index;
+}
+
+// resolveVersion returns the version a package.json range resolved to. Local
+// protocols (workspace:, portal:, link:, file:) do not resolve to a published
+// version, so their range is kept as is. Patched packages resolve to the
+// version of the package they patch.
+function resolveVersion(index: { [descriptor: string]: string }, name: string, range: string): string {
+    if (range.startsWith("workspace:") || range.startsWith("portal:") ||
+        range.startsWith("link:") || range.startsWith("file:")) {
+        return range;
+    }
+
+    if (range.startsWith("patch:")) {
+        const end = range.indexOf("#");
+        const source = decodeURIComponent(range.substring("patch:".length, end === -1 ? range.length : end));
+        return resolveVersion(index, name, parseDescriptor(source).range);
+    }
+
+    const version = index[`${name}@${range}`] || index[`${name}@npm:${range}`];
+    if (!version || version === localVersion) {
+        return range;
+    }
+    return version;
+}
+
+function extract(index: { [descriptor: string]: string }, dependencyHash: any, scope: string): Dependency[] {
+    return Object.keys(dependencyHash)
+        .map((dependency) => {
+            const { organization, module } = parseName(dependency);
+
+            return {
+                organization,
+                module,
+                versionConstraint: resolveVersion(index, dependency, `${dependencyHash[dependency]}`),
+                scopes: [ scope ],
+                name: dependency,
+            };
+        });
+}
+
+export default class YarnLockExtractor implements Extractor {
+    public matchConfig(): MatchConfig {
+        return {
+            includes: [
+                "**/package.json",
+                "**/yarn.lock",
+            ],
+            excludes: [
+                "**/node_modules/**",
+                "**/testdata/**",
+            ],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "package.json", "yarn.lock" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(_: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile> {
+        // only yarn v2+ lockfiles are valid yaml. these can be identified by
+        // their __metadata block.
+        if (files["yarn.lock"].raw().indexOf("__metadata:") === -1) {
+            return null;
+        }
+
+        const {
+            name,
+            version,
+            dependencies,
+            devDependencies,
+            optionalDependencies,
+        } = files["package.json"].json();
+
+        const index = indexLock(files["yarn.lock"].yaml() || {});
+
+        const { organization, module } = parseName(name);
+
+        let allDependencies = extract(index, (dependencies || {}), "");
+        allDependencies = allDependencies.concat(extract(index, (devDependencies || {}), "dev"));
+        allDependencies = allDependencies.concat(extract(index, (optionalDependencies || {}), "optional"));
+
+        return asLockfile({
+            language: Languages.NODE,
+            system: "yarn",
+            sourceUrl: "",
+            organization, module, version,
+            dependencies: allDependencies,
+            name,
+        });
+    }
+}
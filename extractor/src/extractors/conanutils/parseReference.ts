@@ -0,0 +1,43 @@
+import Globals from "../Globals";
+
+export interface Reference {
+    organization: string;
+    module: string;
+    version: string;
+    name: string;
+}
+
+// parseReference parses a conan reference of the form
+// name/version[@user/channel][#revision]. References published under a user
+// are organized by that user.
+export default function parseReference(reference: string): Reference {
+    let ref = (reference || "").trim();
+
+    const revision = ref.indexOf("#");
+    if (revision > -1) {
+        ref = ref.substr(0, revision);
+    }
+
+    let organization = Globals.ORGANIZATION;
+    const at = ref.indexOf("@");
+    if (at > -1) {
+        const user = ref.substr(at + 1).split("/")[0];
+        if (user && user !== "_") {
+            organization = user;
+        }
+        ref = ref.substr(0, at);
+    }
+
+    const slash = ref.indexOf("/");
+    if (slash === -1) {
+        return { organization, module: ref, version: "", name: ref };
+    }
+
+    const name = ref.substr(0, slash);
+    return {
+        organization,
+        module: name,
+        version: ref.substr(slash + 1),
+        name,
+    };
+}
@@ -0,0 +1,16 @@
+import {Dependency} from "@depscloud/api/v1alpha/deps";
+import parseReference from "./parseReference";
+
+// toDependencies converts a list of conan references into dependencies
+export default function toDependencies(references: string[], scope: string): Dependency[] {
+    return references
+        .map((reference) => parseReference(reference))
+        .filter(({ module }) => !!module)
+        .map(({ organization, module, version, name }) => ({
+            organization,
+            module,
+            versionConstraint: version,
+            scopes: [ scope ],
+            name,
+        }));
+}
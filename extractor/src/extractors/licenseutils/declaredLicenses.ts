@@ -0,0 +1,26 @@
+// declaredLicenses normalizes the various ways a manifest can declare its
+// license into a list of license expressions. Manifests commonly accept a
+// string, a list of strings, or objects with a type or name (such as the
+// legacy npm "licenses" field or maven <license> entries).
+export default function declaredLicenses(...declarations: any[]): string[] {
+    const licenses: string[] = [];
+
+    const add = (declaration: any) => {
+        if (!declaration) {
+            return;
+        } else if (Array.isArray(declaration)) {
+            declaration.forEach(add);
+        } else if (typeof declaration === "string") {
+            const license = declaration.trim();
+            if (license.length > 0 && licenses.indexOf(license) === -1) {
+                licenses.push(license);
+            }
+        } else if (typeof declaration === "object") {
+            add(declaration.type || declaration.name);
+        }
+    };
+
+    declarations.forEach(add);
+
+    return licenses;
+}
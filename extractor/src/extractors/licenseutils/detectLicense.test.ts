@@ -0,0 +1,33 @@
+import declaredLicenses from "./declaredLicenses";
+import detectLicense from "./detectLicense";
+
+describe("detectLicense", () => {
+    test("detect", () => {
+        expect(detectLicense(`MIT License
+
+Copyright (c) 2020 depscloud
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal`)).toEqual("MIT");
+
+        expect(detectLicense(`
+                                 Apache License
+                           Version 2.0, January 2004
+                        http://www.apache.org/licenses/`)).toEqual("Apache-2.0");
+
+        expect(detectLicense(`                    GNU GENERAL PUBLIC LICENSE
+                       Version 3, 29 June 2007
+
+  ...use the GNU Lesser General Public License instead of this License.`)).toEqual("GPL-3.0");
+
+        expect(detectLicense("All rights reserved.")).toBeNull();
+    });
+
+    test("declared", () => {
+        expect(declaredLicenses("MIT")).toEqual([ "MIT" ]);
+        expect(declaredLicenses({ type: "ISC" })).toEqual([ "ISC" ]);
+        expect(declaredLicenses(undefined, [ { type: "MIT" }, { type: "Apache-2.0" } ])).toEqual([ "MIT", "Apache-2.0" ]);
+        expect(declaredLicenses("MIT OR Apache-2.0", [ "MIT OR Apache-2.0" ])).toEqual([ "MIT OR Apache-2.0" ]);
+        expect(declaredLicenses(null, "")).toEqual([]);
+    });
+});
@@ -0,0 +1,42 @@
+interface Signature {
+    id: string;
+    patterns: RegExp[];
+}
+
+// signatures are ordered so that the more specific variants of a license
+// family are considered before the general ones. The gnu titles are matched
+// case sensitively since each of their texts mentions the others by name.
+const signatures: Signature[] = [
+    { id: "AGPL-3.0", patterns: [ /GNU AFFERO GENERAL PUBLIC LICENSE/ ] },
+    { id: "LGPL-3.0", patterns: [ /GNU LESSER GENERAL PUBLIC LICENSE/, /Version 3/i ] },
+    { id: "LGPL-2.1", patterns: [ /GNU LESSER GENERAL PUBLIC LICENSE/, /Version 2\.1/i ] },
+    { id: "GPL-3.0", patterns: [ /GNU GENERAL PUBLIC LICENSE/, /Version 3/i ] },
+    { id: "GPL-2.0", patterns: [ /GNU GENERAL PUBLIC LICENSE/, /Version 2/i ] },
+    { id: "Apache-2.0", patterns: [ /Apache License/i, /Version 2\.0/i ] },
+    { id: "MPL-2.0", patterns: [ /Mozilla Public License,? (Version|v\.?) ?2\.0/i ] },
+    { id: "EPL-2.0", patterns: [ /Eclipse Public License - v 2\.0/i ] },
+    { id: "EPL-1.0", patterns: [ /Eclipse Public License - v 1\.0/i ] },
+    { id: "BSL-1.0", patterns: [ /Boost Software License - Version 1\.0/i ] },
+    { id: "Unlicense", patterns: [ /This is free and unencumbered software released into the public domain/i ] },
+    { id: "ISC", patterns: [ /Permission to use, copy, modify, and\/or distribute this software for any purpose/i ] },
+    { id: "MIT", patterns: [ /Permission is hereby granted, free of charge, to any person obtaining a copy/i ] },
+    {
+        id: "BSD-3-Clause",
+        patterns: [
+            /Redistribution and use in source and binary forms/i,
+            /Neither the name of/i,
+        ],
+    },
+    { id: "BSD-2-Clause", patterns: [ /Redistribution and use in source and binary forms/i ] },
+];
+
+// detectLicense identifies the license of a LICENSE (or COPYING) file using
+// well known phrases from the license texts. The SPDX identifier of the
+// license is returned, or null when the text isn't recognized.
+export default function detectLicense(text: string): string | null {
+    const content = (text || "").replace(/\s+/g, " ");
+
+    const match = signatures.find((signature) => signature.patterns.every((pattern) => pattern.test(content)));
+
+    return match ? match.id : null;
+}
@@ -0,0 +1,66 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import ExtractorFile from "../ExtractorFile";
+import MatchConfig from "../../matcher/MatchConfig";
+import detectLicense from "./detectLicense";
+import withLicenses, {licensesOf} from "./withLicenses";
+
+import path = require("path");
+
+// licenseFiles describes the files used to detect the license of a module
+// when its manifest doesn't declare one.
+export const licenseFiles: MatchConfig = {
+    includes: [
+        "**/LICENSE",
+        "**/LICENSE.*",
+        "**/LICENCE",
+        "**/LICENCE.*",
+        "**/COPYING",
+        "**/COPYING.*",
+    ],
+    excludes: [
+        "**/node_modules/**",
+        "**/vendor/**",
+        "**/testdata/**",
+    ],
+};
+
+// withLicenseFiles fills in the licenses of management files that don't
+// declare any. The closest license file to the manifest is used, searching
+// from the directory of the manifest up to the root of the repository.
+export default function withLicenseFiles(
+    managementFiles: DependencyManagementFile[],
+    manifestPath: string,
+    licenses: { [path: string]: ExtractorFile },
+): DependencyManagementFile[] {
+    const undeclared = managementFiles.filter((file) => licensesOf(file).length === 0);
+    if (undeclared.length === 0) {
+        return managementFiles;
+    }
+
+    const byDir: { [dir: string]: ExtractorFile[] } = {};
+    Object.keys(licenses).sort().forEach((key) => {
+        const dir = path.posix.dirname(key);
+        byDir[dir] = (byDir[dir] || []).concat(licenses[key]);
+    });
+
+    let dir = path.posix.dirname(manifestPath);
+    let detected: string = null;
+
+    for (;;) {
+        detected = (byDir[dir] || [])
+            .map((file) => detectLicense(file.raw()))
+            .find((license) => !!license);
+
+        const parent = path.posix.dirname(dir);
+        if (detected || parent === dir) {
+            break;
+        }
+        dir = parent;
+    }
+
+    if (detected) {
+        undeclared.forEach((file) => withLicenses(file, [ detected ]));
+    }
+
+    return managementFiles;
+}
@@ -0,0 +1,20 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+
+// Licensed describes a management file that carries the licenses declared by
+// its module. The field isn't part of the v1alpha api, so it's only present
+// on management files that declare a license.
+export interface Licensed {
+    licenses?: string[];
+}
+
+export function licensesOf(file: DependencyManagementFile): string[] {
+    return (file as Licensed).licenses || [];
+}
+
+// withLicenses attaches the licenses to the management file.
+export default function withLicenses(file: DependencyManagementFile, licenses: string[]): DependencyManagementFile {
+    if (licenses && licenses.length > 0) {
+        (file as Licensed).licenses = licenses;
+    }
+    return file;
+}
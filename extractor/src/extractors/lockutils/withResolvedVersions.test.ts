@@ -0,0 +1,59 @@
+import withResolvedVersions, {asLockfile} from "./withResolvedVersions";
+
+describe("withResolvedVersions", () => {
+    test("mergesLockfile", () => {
+        const managementFiles = withResolvedVersions([
+            {
+                language: "node",
+                system: "npm",
+                sourceUrl: "",
+                organization: "example",
+                module: "a",
+                version: "1.0.0",
+                dependencies: [
+                    { organization: "_", module: "lodash", versionConstraint: "^4.17.0", scopes: [ "" ], name: "lodash" },
+                    { organization: "_", module: "left-pad", versionConstraint: "^1.0.0", scopes: [ "" ], name: "left-pad" },
+                ],
+                name: "@example/a",
+            },
+            asLockfile({
+                language: "node",
+                system: "npm",
+                sourceUrl: "",
+                organization: "example",
+                module: "a",
+                version: "1.0.0",
+                dependencies: [
+                    { organization: "_", module: "lodash", versionConstraint: "3.10.1", scopes: [ "transitive" ], name: "lodash" },
+                    { organization: "_", module: "lodash", versionConstraint: "4.17.20", scopes: [ "" ], name: "lodash" },
+                    { organization: "_", module: "debug", versionConstraint: "4.3.1", scopes: [ "transitive" ], name: "debug" },
+                ],
+                name: "@example/a",
+            }),
+            asLockfile({
+                language: "R",
+                system: "renv",
+                sourceUrl: "",
+                organization: "_",
+                module: "b",
+                version: "",
+                dependencies: [
+                    { organization: "_", module: "dplyr", versionConstraint: "1.0.2", scopes: [ "" ], name: "dplyr" },
+                ],
+                name: "b",
+            }),
+        ]);
+
+        expect(managementFiles.map((file) => file.name)).toEqual([ "@example/a", "b" ]);
+
+        expect(managementFiles[0].dependencies.map((d) => [ d.name, d.versionConstraint, d.scopes ])).toEqual([
+            [ "lodash", "^4.17.0", [ "", "resolved:4.17.20" ] ],
+            [ "left-pad", "^1.0.0", [ "" ] ],
+            [ "debug", "4.3.1", [ "transitive", "resolved:4.3.1" ] ],
+        ]);
+
+        expect(managementFiles[1].dependencies.map((d) => d.scopes)).toEqual([
+            [ "", "resolved:1.0.2" ],
+        ]);
+    });
+});
@@ -0,0 +1,87 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+
+// RESOLVED_PREFIX qualifies the version a lockfile resolved a dependency to.
+// It's carried as a scope so it survives the v1alpha api, leaving the
+// versionConstraint free to hold the range declared by the manifest.
+export const RESOLVED_PREFIX = "resolved:";
+
+const lockfiles = new WeakSet<DependencyManagementFile>();
+
+// asLockfile marks the management file as produced from a lockfile. The
+// versions of its dependencies are those that were resolved.
+export function asLockfile(file: DependencyManagementFile): DependencyManagementFile {
+    if (file) {
+        lockfiles.add(file);
+    }
+    return file;
+}
+
+export function resolvedVersion(dependency: Dependency): string | null {
+    const scope = (dependency.scopes || []).find((s) => s.startsWith(RESOLVED_PREFIX));
+    return scope ? scope.substr(RESOLVED_PREFIX.length) : null;
+}
+
+function withResolved(dependency: Dependency, version: string): Dependency {
+    if (!version || resolvedVersion(dependency) !== null) {
+        return dependency;
+    }
+    return { ...dependency, scopes: (dependency.scopes || []).concat(RESOLVED_PREFIX + version) };
+}
+
+function isTransitive(dependency: Dependency): boolean {
+    return (dependency.scopes || []).indexOf("transitive") > -1;
+}
+
+// withResolvedVersions merges the results of lockfiles into the manifests of
+// the same module. Declared dependencies keep their range and gain the
+// version the lockfile resolved them to. Dependencies only found in the
+// lockfile (transitive dependencies) are added as resolved. Lockfiles without
+// a matching manifest are kept with all of their dependencies resolved.
+export default function withResolvedVersions(managementFiles: DependencyManagementFile[]): DependencyManagementFile[] {
+    const keyOf = (file: DependencyManagementFile) => [ file.language, file.name ].join("|");
+
+    const manifests: { [key: string]: DependencyManagementFile } = {};
+    managementFiles
+        .filter((file) => !lockfiles.has(file))
+        .forEach((file) => manifests[keyOf(file)] = file);
+
+    const result: DependencyManagementFile[] = [];
+    managementFiles.forEach((file) => {
+        if (!lockfiles.has(file)) {
+            result.push(file);
+            return;
+        }
+
+        const locked = (file.dependencies || [])
+            .map((dependency) => withResolved(dependency, dependency.versionConstraint));
+
+        const manifest = manifests[keyOf(file)];
+        if (!manifest) {
+            result.push({ ...file, dependencies: locked });
+            return;
+        }
+
+        // direct entries take precedence over transitive installs of the same
+        // package at another version.
+        const resolved: { [name: string]: string } = {};
+        locked
+            .filter((dependency) => !isTransitive(dependency))
+            .concat(locked.filter(isTransitive))
+            .forEach((dependency) => {
+                if (resolved[dependency.name] === undefined) {
+                    resolved[dependency.name] = dependency.versionConstraint;
+                }
+            });
+
+        const declared: { [name: string]: boolean } = {};
+        manifest.dependencies = (manifest.dependencies || []).map((dependency) => {
+            declared[dependency.name] = true;
+            return withResolved(dependency, resolved[dependency.name]);
+        });
+
+        manifest.dependencies = manifest.dependencies
+            .concat(locked.filter((dependency) => !declared[dependency.name]));
+    });
+
+    return result;
+}
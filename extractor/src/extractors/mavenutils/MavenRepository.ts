@@ -0,0 +1,65 @@
+import {getLogger} from "log4js";
+import {Coordinates} from "./parsePom";
+
+import http = require("http");
+import https = require("https");
+
+const logger = getLogger();
+
+// MavenRepository fetches poms from a remote repository (such as
+// https://repo.maven.apache.org/maven2). Parent poms and boms are shared
+// between many modules, so lookups are cached for the life of the process.
+export default class MavenRepository {
+    private readonly url: string;
+    private readonly cache: Map<string, Promise<string | null>>;
+
+    constructor(url: string) {
+        this.url = url.replace(/\/+$/, "");
+        this.cache = new Map();
+    }
+
+    public fetch(coordinates: Coordinates): Promise<string | null> {
+        const { groupId, artifactId, version } = coordinates;
+        if (!groupId || !artifactId || !version || version.indexOf("${") > -1) {
+            return Promise.resolve(null);
+        }
+
+        const url = [
+            this.url,
+            groupId.replace(/\./g, "/"),
+            artifactId,
+            version,
+            `${artifactId}-${version}.pom`,
+        ].join("/");
+
+        if (!this.cache.has(url)) {
+            this.cache.set(url, this.get(url));
+        }
+        return this.cache.get(url);
+    }
+
+    private get(url: string): Promise<string | null> {
+        const client = url.startsWith("http://") ? http : https;
+
+        return new Promise((resolve) => {
+            const req = client.get(url, (resp) => {
+                if (resp.statusCode !== 200) {
+                    logger.warn(`[maven] failed to fetch ${url}: ${resp.statusCode}`);
+                    resp.resume();
+                    resolve(null);
+                    return;
+                }
+
+                const chunks = [];
+                resp.on("data", (chunk) => chunks.push(chunk));
+                resp.on("end", () => resolve(Buffer.concat(chunks).toString()));
+                resp.on("error", () => resolve(null));
+            });
+
+            req.on("error", (err) => {
+                logger.warn(`[maven] failed to fetch ${url}: ${err.message}`);
+                resolve(null);
+            });
+        });
+    }
+}
@@ -0,0 +1,202 @@
+import ExtractorFile from "../ExtractorFile";
+import MavenRepository from "./MavenRepository";
+import interpolate from "./interpolate";
+import parsePom, {Coordinates, Pom, PomDependency} from "./parsePom";
+
+import path = require("path");
+
+// parent and bom chains are rarely more than a few levels deep. The bound
+// guards against cycles between poms.
+const maxDepth = 10;
+
+interface Located {
+    pom: Pom;
+    path: string;
+}
+
+interface Model extends Coordinates {
+    properties: { [key: string]: string };
+    managed: PomDependency[];
+    licenses: string[];
+}
+
+export interface EffectivePom extends Coordinates {
+    properties: { [key: string]: string };
+    managed: { [key: string]: PomDependency };
+    licenses: string[];
+}
+
+export function managementKey(dep: { groupId: string, artifactId: string }): string {
+    return [ dep.groupId, dep.artifactId ].join(":");
+}
+
+function isPomFile(filePath: string): boolean {
+    return filePath.endsWith("pom.xml") || filePath.endsWith(".pom");
+}
+
+// PomResolver computes the effective model of a pom by walking its parents
+// and imported boms. Poms are located within the workspace first and fall
+// back to the remote repository when one is configured.
+export default class PomResolver {
+    private readonly workspace: { [path: string]: ExtractorFile };
+    private readonly repository: MavenRepository;
+    private readonly parsed: { [path: string]: Pom };
+
+    constructor(workspace: { [path: string]: ExtractorFile }, repository: MavenRepository = null) {
+        this.workspace = workspace || {};
+        this.repository = repository;
+        this.parsed = {};
+    }
+
+    public async resolve(file: ExtractorFile): Promise<{ pom: Pom, effective: EffectivePom }> {
+        const pom = parsePom(file.raw());
+        const effective = await this.effective(pom, file.path(), 0);
+        return { pom, effective };
+    }
+
+    private parse(filePath: string): Pom {
+        if (!this.parsed[filePath]) {
+            this.parsed[filePath] = parsePom(this.workspace[filePath].raw());
+        }
+        return this.parsed[filePath];
+    }
+
+    private async effective(pom: Pom, filePath: string, depth: number): Promise<EffectivePom> {
+        const model = await this.inherit(pom, filePath, depth);
+
+        const properties = Object.assign({}, model.properties, {
+            "project.groupId": model.groupId,
+            "project.artifactId": model.artifactId,
+            "project.version": model.version,
+        });
+
+        if (pom.parent) {
+            properties["project.parent.groupId"] = pom.parent.groupId;
+            properties["project.parent.artifactId"] = pom.parent.artifactId;
+            properties["project.parent.version"] = pom.parent.version;
+        }
+
+        const managed: { [key: string]: PomDependency } = {};
+        const imports: { [key: string]: PomDependency } = {};
+
+        model.managed.forEach((entry) => {
+            const dep: PomDependency = {
+                groupId: interpolate(entry.groupId, properties),
+                artifactId: interpolate(entry.artifactId, properties),
+                version: interpolate(entry.version, properties),
+                scope: interpolate(entry.scope, properties),
+                type: interpolate(entry.type, properties),
+            };
+
+            if (dep.scope === "import" && dep.type === "pom") {
+                imports[managementKey(dep)] = dep;
+            } else {
+                managed[managementKey(dep)] = dep;
+            }
+        });
+
+        // entries declared directly take precedence over those from a bom
+        for (const bomKey of Object.keys(imports)) {
+            if (depth >= maxDepth) {
+                break;
+            }
+
+            const located = await this.locate(imports[bomKey]);
+            if (!located) {
+                continue;
+            }
+
+            const bom = await this.effective(located.pom, located.path, depth + 1);
+            Object.keys(bom.managed)
+                .filter((key) => !managed[key])
+                .forEach((key) => managed[key] = bom.managed[key]);
+        }
+
+        return {
+            groupId: interpolate(model.groupId, properties),
+            artifactId: interpolate(model.artifactId, properties),
+            version: interpolate(model.version, properties),
+            properties,
+            managed,
+            licenses: model.licenses.map((license) => interpolate(license, properties)),
+        };
+    }
+
+    // inherit merges the raw model of the pom with that of its parents.
+    // Interpolation is deferred so inherited values pick up the properties of
+    // the child, as maven does.
+    private async inherit(pom: Pom, filePath: string, depth: number): Promise<Model> {
+        let parent: Model = {
+            groupId: "",
+            artifactId: "",
+            version: "",
+            properties: {},
+            managed: [],
+            licenses: [],
+        };
+
+        if (pom.parent && depth < maxDepth) {
+            const located = await this.locateParent(pom, filePath);
+            if (located) {
+                parent = await this.inherit(located.pom, located.path, depth + 1);
+            }
+        }
+
+        return {
+            groupId: pom.groupId || (pom.parent && pom.parent.groupId) || "",
+            artifactId: pom.artifactId,
+            version: pom.version || (pom.parent && pom.parent.version) || "",
+            properties: Object.assign({}, parent.properties, pom.properties),
+            managed: parent.managed.concat(pom.dependencyManagement),
+            // licenses are inherited unless the child declares its own
+            licenses: pom.licenses.length > 0 ? pom.licenses : parent.licenses,
+        };
+    }
+
+    private async locateParent(pom: Pom, filePath: string): Promise<Located | null> {
+        const { relativePath } = pom.parent;
+
+        if (relativePath && filePath) {
+            let candidate = path.posix.join(path.posix.dirname(filePath), relativePath);
+            if (!isPomFile(candidate)) {
+                candidate = path.posix.join(candidate, "pom.xml");
+            }
+
+            if (this.workspace[candidate]) {
+                const parent = this.parse(candidate);
+                if (parent.artifactId === pom.parent.artifactId) {
+                    return { pom: parent, path: candidate };
+                }
+            }
+        }
+
+        return this.locate(pom.parent);
+    }
+
+    private async locate(coordinates: Coordinates): Promise<Located | null> {
+        const match = Object.keys(this.workspace)
+            .filter(isPomFile)
+            .find((candidate) => {
+                const pom = this.parse(candidate);
+                const groupId = pom.groupId || (pom.parent && pom.parent.groupId);
+                return groupId === coordinates.groupId && pom.artifactId === coordinates.artifactId;
+            });
+
+        if (match) {
+            return { pom: this.parse(match), path: match };
+        }
+
+        if (!this.repository) {
+            return null;
+        }
+
+        const raw = await this.repository.fetch(coordinates);
+        if (!raw) {
+            return null;
+        }
+
+        // remote poms have no location within the workspace, so their parents
+        // are always located by coordinates.
+        return { pom: parsePom(raw), path: "" };
+    }
+}
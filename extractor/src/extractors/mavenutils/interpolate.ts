@@ -0,0 +1,25 @@
+const pattern = /\$\{([^}]+)\}/g;
+
+// properties may refer to other properties, so substitution is repeated until
+// the value settles. The bound guards against self-referencing properties.
+const maxPasses = 10;
+
+// interpolate replaces ${name} references within the value using the provided
+// properties. References without a definition are left in place.
+export default function interpolate(value: string, properties: { [key: string]: string }): string {
+    let current = value || "";
+
+    for (let i = 0; i < maxPasses && current.indexOf("${") > -1; i++) {
+        const next = current.replace(pattern, (match, name) => {
+            const replacement = properties[name];
+            return replacement === undefined ? match : replacement;
+        });
+
+        if (next === current) {
+            break;
+        }
+        current = next;
+    }
+
+    return current;
+}
@@ -0,0 +1,89 @@
+import cheerio = require("cheerio");
+
+export interface Coordinates {
+    groupId: string;
+    artifactId: string;
+    version: string;
+}
+
+export interface PomDependency extends Coordinates {
+    scope: string;
+    type: string;
+}
+
+export interface PomParent extends Coordinates {
+    relativePath: string;
+}
+
+export interface Pom extends Coordinates {
+    parent: PomParent | null;
+    properties: { [key: string]: string };
+    dependencyManagement: PomDependency[];
+    dependencies: PomDependency[];
+    modules: string[];
+    licenses: string[];
+    scmUrl: string;
+}
+
+function parseDependencies($: CheerioStatic, selector: string): PomDependency[] {
+    const dependencies: PomDependency[] = [];
+    $(selector).each((i, element) => {
+        const dep = $(element);
+        dependencies.push({
+            groupId: dep.children("groupId").text().trim(),
+            artifactId: dep.children("artifactId").text().trim(),
+            version: dep.children("version").text().trim(),
+            scope: dep.children("scope").text().trim(),
+            type: dep.children("type").text().trim(),
+        });
+    });
+    return dependencies;
+}
+
+// parsePom reads the parts of a project object model needed to compute the
+// coordinates and dependencies of a module. Values are returned as written,
+// without any property interpolation.
+export default function parsePom(raw: string): Pom {
+    const $ = cheerio.load(raw, { xmlMode: true });
+
+    let parent: PomParent = null;
+    const parentElement = $("project > parent");
+    if (parentElement.length > 0) {
+        const relativePath = parentElement.children("relativePath");
+
+        parent = {
+            groupId: parentElement.children("groupId").text().trim(),
+            artifactId: parentElement.children("artifactId").text().trim(),
+            version: parentElement.children("version").text().trim(),
+            relativePath: relativePath.length > 0 ? relativePath.text().trim() : "../pom.xml",
+        };
+    }
+
+    const properties = {};
+    $("project > properties").children().each((i, element) => {
+        properties[element.tagName] = $(element).text().trim();
+    });
+
+    const modules = [];
+    $("project > modules > module").each((i, element) => {
+        modules.push($(element).text().trim());
+    });
+
+    const licenses = [];
+    $("project > licenses > license > name").each((i, element) => {
+        licenses.push($(element).text().trim());
+    });
+
+    return {
+        groupId: $("project > groupId").text().trim(),
+        artifactId: $("project > artifactId").text().trim(),
+        version: $("project > version").text().trim(),
+        parent,
+        properties,
+        dependencyManagement: parseDependencies($, "project > dependencyManagement > dependencies > dependency"),
+        dependencies: parseDependencies($, "project > dependencies > dependency"),
+        modules,
+        licenses,
+        scmUrl: $("project > scm > url").text().trim(),
+    };
+}
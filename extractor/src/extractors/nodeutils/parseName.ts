@@ -0,0 +1,17 @@
+import Globals from "../Globals";
+
+export interface Parsed {
+    organization: string;
+    module: string;
+}
+
+export default function parseName(name: string): Parsed {
+    let organization = Globals.ORGANIZATION;
+    let module = name || "";
+    if (module.charAt(0) === "@") {
+        const index = module.indexOf("/");
+        organization = module.substr(1, index - 1);
+        module = module.substr(index + 1);
+    }
+    return { organization, module };
+}
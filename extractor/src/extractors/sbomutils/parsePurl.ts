@@ -0,0 +1,91 @@
+import Globals from "../Globals";
+import Languages from "../Languages";
+import parseImportPath from "../goutils/parseImportPath";
+
+export interface Purl {
+    type: string;
+    namespace: string;
+    name: string;
+    version: string;
+}
+
+export interface ID {
+    language: string;
+    organization: string;
+    module: string;
+    name: string;
+}
+
+const languages = {
+    "cargo": Languages.RUST,
+    "composer": Languages.PHP,
+    "conan": Languages.CPP,
+    "cran": Languages.R,
+    "github": Languages.ACTIONS,
+    "golang": Languages.GO,
+    "julia": Languages.JULIA,
+    "maven": Languages.JAVA,
+    "npm": Languages.NODE,
+};
+
+// parsePurl parses a package url of the form
+// pkg:type/namespace/name@version?qualifiers#subpath
+export default function parsePurl(purl: string): Purl {
+    if (!purl || !purl.startsWith("pkg:")) {
+        return null;
+    }
+
+    let remainder = purl.substr("pkg:".length);
+    remainder = remainder.split("#")[0];
+    remainder = remainder.split("?")[0];
+
+    let version = "";
+    const at = remainder.lastIndexOf("@");
+    if (at > -1) {
+        version = decodeURIComponent(remainder.substr(at + 1));
+        remainder = remainder.substr(0, at);
+    }
+
+    const parts = remainder.split("/").filter((part) => part.length > 0);
+    if (parts.length < 2) {
+        return null;
+    }
+
+    return {
+        type: parts[0].toLowerCase(),
+        namespace: parts.slice(1, parts.length - 1).map(decodeURIComponent).join("/"),
+        name: decodeURIComponent(parts[parts.length - 1]),
+        version,
+    };
+}
+
+// identify maps a package url onto the identifiers used by the extractor for
+// the same ecosystem, so modules reported by an sbom line up with modules
+// extracted from source.
+export function identify(purl: Purl): ID {
+    const { type, namespace, name } = purl;
+    const language = languages[type] || type;
+
+    switch (type) {
+    case "npm":
+        return {
+            language,
+            organization: namespace ? namespace.replace(/^@/, "") : Globals.ORGANIZATION,
+            module: name,
+            name: namespace ? `${namespace}/${name}` : name,
+        };
+    case "maven":
+        return { language, organization: namespace, module: name, name: `${namespace}:${name}` };
+    case "golang": {
+        const importPath = [ namespace, name ].filter(Boolean).join("/");
+        return { language, ...parseImportPath(importPath), name: importPath };
+    }
+    default:
+        return {
+            language,
+            organization: namespace || Globals.ORGANIZATION,
+            module: name,
+            name: namespace ? `${namespace}/${name}` : name,
+        };
+    }
+}
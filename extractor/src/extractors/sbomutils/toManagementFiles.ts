@@ -0,0 +1,95 @@
+import {Dependency, DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import Globals from "../Globals";
+import parsePurl, {ID, identify} from "./parsePurl";
+
+export interface Component {
+    purl: string;
+    group: string;
+    name: string;
+    version: string;
+    scope: string;
+}
+
+// Edge references a dependency of a component. Formats that scope the
+// relationship rather than the component (such as spdx) set the scope here.
+export interface Edge {
+    ref: string;
+    scope?: string;
+}
+
+function identifyComponent(component: Component): ID & { version: string } {
+    const purl = parsePurl(component.purl);
+    if (purl) {
+        return { ...identify(purl), version: component.version || purl.version };
+    }
+
+    const organization = component.group || Globals.ORGANIZATION;
+    return {
+        language: "",
+        organization,
+        module: component.name,
+        name: component.group ? `${component.group}/${component.name}` : component.name,
+        version: component.version || "",
+    };
+}
+
+// toManagementFiles converts the dependency graph described by a software
+// bill of materials into management files. A management file only describes
+// a single language, so the dependencies of each component are grouped by
+// the language of the dependency. When the graph is missing, every component
+// is treated as a dependency of the root component.
+export default function toManagementFiles(
+    system: string,
+    root: string,
+    components: { [ref: string]: Component },
+    graph: { [ref: string]: Edge[] },
+): DependencyManagementFile[] {
+    if (Object.keys(graph).length === 0 && root) {
+        graph = {
+            [root]: Object.keys(components)
+                .filter((ref) => ref !== root)
+                .map((ref) => ({ ref })),
+        };
+    }
+
+    const managementFiles: DependencyManagementFile[] = [];
+
+    Object.keys(graph)
+        .filter((ref) => !!components[ref])
+        .forEach((ref) => {
+            const parent = identifyComponent(components[ref]);
+
+            const byLanguage: { [language: string]: Dependency[] } = {};
+            (graph[ref] || [])
+                .filter((edge) => !!components[edge.ref])
+                .forEach((edge) => {
+                    const child = components[edge.ref];
+                    const id = identifyComponent(child);
+                    const language = id.language || parent.language;
+
+                    byLanguage[language] = byLanguage[language] || [];
+                    byLanguage[language].push({
+                        organization: id.organization,
+                        module: id.module,
+                        versionConstraint: id.version,
+                        scopes: [ (edge.scope !== undefined ? edge.scope : child.scope) || "" ],
+                        name: id.name,
+                    });
+                });
+
+            Object.keys(byLanguage).forEach((language) => {
+                managementFiles.push({
+                    language,
+                    system,
+                    sourceUrl: "",
+                    organization: parent.organization,
+                    module: parent.module,
+                    version: parent.version,
+                    dependencies: byLanguage[language],
+                    name: parent.name,
+                });
+            });
+        });
+
+    return managementFiles;
+}
@@ -0,0 +1,38 @@
+import classifyScopes from "./classifyScopes";
+
+describe("classifyScopes", () => {
+    test("runtime", () => {
+        expect(classifyScopes([])).toBe("runtime");
+        expect(classifyScopes([ "" ])).toBe("runtime");
+        expect(classifyScopes([ "compile" ])).toBe("runtime");
+        expect(classifyScopes([ "indirect" ])).toBe("runtime");
+        expect(classifyScopes([ "", "feature:ssl" ])).toBe("runtime");
+        expect(classifyScopes([ "compile", "annotationProcessor" ])).toBe("runtime");
+    });
+
+    test("dev", () => {
+        expect(classifyScopes([ "dev" ])).toBe("dev");
+        expect(classifyScopes([ "transitive", "dev" ])).toBe("dev");
+        expect(classifyScopes([ "test", "dev" ])).toBe("dev");
+    });
+
+    test("test", () => {
+        expect(classifyScopes([ "test" ])).toBe("test");
+        expect(classifyScopes([ "testCompile" ])).toBe("test");
+        expect(classifyScopes([ "test->default" ])).toBe("test");
+    });
+
+    test("build", () => {
+        expect(classifyScopes([ "build" ])).toBe("build");
+        expect(classifyScopes([ "host", "feature:ssl" ])).toBe("build");
+        expect(classifyScopes([ "dev", "resolved:1.0.0-test" ])).toBe("dev");
+        expect(classifyScopes([ "parent" ])).toBe("build");
+        expect(classifyScopes([ "action" ])).toBe("build");
+    });
+
+    test("optional", () => {
+        expect(classifyScopes([ "optional" ])).toBe("optional");
+        expect(classifyScopes([ "suggests" ])).toBe("optional");
+        expect(classifyScopes([ "transitive", "optional" ])).toBe("optional");
+    });
+});
@@ -0,0 +1,69 @@
+import Scopes from "../Scopes";
+
+// qualifiers describe how a dependency was reached rather than what it is
+// needed for, so they don't influence the classification.
+const qualifiers = {
+    "direct": true,
+    "indirect": true,
+    "internal": true,
+    "transitive": true,
+    "transitive=true": true,
+};
+
+const known = {
+    "action": Scopes.BUILD,
+    "annotationprocessor": Scopes.BUILD,
+    "compileonly": Scopes.BUILD,
+    "enhances": Scopes.OPTIONAL,
+    "host": Scopes.BUILD,
+    "kapt": Scopes.BUILD,
+    "linking": Scopes.BUILD,
+    "parent": Scopes.BUILD,
+    "suggests": Scopes.OPTIONAL,
+    "tool_requires": Scopes.BUILD,
+    "weak": Scopes.OPTIONAL,
+    "workflow": Scopes.BUILD,
+    "workspace": Scopes.BUILD,
+};
+
+// when no runtime scope is present, the least restrictive classification wins.
+const precedence = [ Scopes.OPTIONAL, Scopes.BUILD, Scopes.DEV, Scopes.TEST ];
+
+export function classifyScope(scope: string): string | null {
+    // ivy configurations are written as conf->dependencyConf
+    let normalized = (scope || "").toLowerCase();
+    const arrow = normalized.indexOf("->");
+    if (arrow > -1) {
+        normalized = normalized.substr(0, arrow);
+    }
+
+    if (qualifiers[normalized] || normalized.startsWith("feature:") || normalized.startsWith("resolved:")) {
+        return null;
+    } else if (known[normalized]) {
+        return known[normalized];
+    } else if (normalized.indexOf("test") > -1) {
+        return Scopes.TEST;
+    } else if (normalized.startsWith("dev")) {
+        return Scopes.DEV;
+    } else if (normalized.indexOf("build") > -1) {
+        return Scopes.BUILD;
+    } else if (normalized.indexOf("optional") > -1) {
+        return Scopes.OPTIONAL;
+    }
+
+    return Scopes.RUNTIME;
+}
+
+// classifyScopes maps the ecosystem specific scopes of a dependency onto one
+// of the canonical Scopes. Dependencies without a scope are runtime.
+export default function classifyScopes(scopes: string[]): string {
+    const classified = (scopes || [])
+        .map(classifyScope)
+        .filter((scope) => scope !== null);
+
+    if (classified.length === 0 || classified.indexOf(Scopes.RUNTIME) > -1) {
+        return Scopes.RUNTIME;
+    }
+
+    return precedence.find((scope) => classified.indexOf(scope) > -1);
+}
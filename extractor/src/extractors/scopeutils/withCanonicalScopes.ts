@@ -0,0 +1,17 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import classifyScopes from "./classifyScopes";
+
+// withCanonicalScopes ensures every dependency carries one of the canonical
+// scopes in addition to the ecosystem specific ones reported by extractors.
+export default function withCanonicalScopes(managementFiles: DependencyManagementFile[]): DependencyManagementFile[] {
+    managementFiles.forEach((managementFile) => {
+        (managementFile.dependencies || []).forEach((dependency) => {
+            const scopes = dependency.scopes || [];
+            const scope = classifyScopes(scopes);
+            if (scopes.indexOf(scope) === -1) {
+                dependency.scopes = scopes.concat(scope);
+            }
+        });
+    });
+    return managementFiles;
+}
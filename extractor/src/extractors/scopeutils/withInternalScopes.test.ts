@@ -0,0 +1,34 @@
+import withInternalScopes from "./withInternalScopes";
+
+describe("withInternalScopes", () => {
+    test("marksInternal", () => {
+        const managementFiles = withInternalScopes([
+            {
+                language: "node",
+                system: "npm",
+                sourceUrl: "",
+                organization: "example",
+                module: "a",
+                version: "1.0.0",
+                dependencies: [
+                    { organization: "example", module: "b", versionConstraint: "^1.0.0", scopes: [ "" ], name: "@example/b" },
+                    { organization: "_", module: "lodash", versionConstraint: "^4.0.0", scopes: [ "" ], name: "lodash" },
+                ],
+                name: "@example/a",
+            },
+            {
+                language: "node",
+                system: "npm",
+                sourceUrl: "",
+                organization: "example",
+                module: "b",
+                version: "1.0.0",
+                dependencies: [],
+                name: "@example/b",
+            },
+        ]);
+
+        expect(managementFiles[0].dependencies[0].scopes).toEqual([ "", "internal" ]);
+        expect(managementFiles[0].dependencies[1].scopes).toEqual([ "" ]);
+    });
+});
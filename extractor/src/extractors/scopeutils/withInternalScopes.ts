@@ -0,0 +1,23 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+
+// withInternalScopes marks dependencies on modules that are managed by the
+// same source as internal. This distinguishes the relationships between the
+// modules of a monorepo from those on third party modules.
+export default function withInternalScopes(managementFiles: DependencyManagementFile[]): DependencyManagementFile[] {
+    const internal = {};
+    managementFiles.forEach((managementFile) => {
+        internal[[ managementFile.language, managementFile.name ].join("|")] = true;
+    });
+
+    managementFiles.forEach((managementFile) => {
+        (managementFile.dependencies || []).forEach((dependency) => {
+            const key = [ managementFile.language, dependency.name ].join("|");
+            const scopes = dependency.scopes || [];
+
+            if (internal[key] && scopes.indexOf("internal") === -1) {
+                dependency.scopes = scopes.concat("internal");
+            }
+        });
+    });
+    return managementFiles;
+}
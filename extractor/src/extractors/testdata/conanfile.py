@@ -0,0 +1,27 @@
+from conans import ConanFile, CMake
+
+
+class ModuleConan(ConanFile):
+    name = "module"
+    version = "9.9.9"
+    url = "https://github.com/organization/module"
+    license = "MIT"
+    settings = "os", "compiler", "build_type", "arch"
+    requires = "zlib/1.2.11", "poco/1.9.4@pocoproject/stable"
+    build_requires = [
+        "cmake/3.16.3",
+        ("ninja/1.10.2", "private"),
+    ]
+    generators = "cmake"
+
+    def requirements(self):
+        if self.settings.os == "Windows":
+            self.requires("openssl/1.1.1g")
+
+    def build_requirements(self):
+        self.test_requires("gtest/1.10.0")
+
+    def build(self):
+        cmake = CMake(self)
+        cmake.configure()
+        cmake.build()
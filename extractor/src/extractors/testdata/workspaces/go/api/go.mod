@@ -0,0 +1,3 @@
+module github.com/example/monorepo/api
+
+go 1.18
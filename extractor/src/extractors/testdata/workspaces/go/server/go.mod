@@ -0,0 +1,5 @@
+module github.com/example/monorepo/server
+
+go 1.18
+
+require github.com/example/monorepo/api v0.0.0
@@ -0,0 +1,27 @@
+import inferImportPath from "../goutils/inferImportPath";
+
+export interface Repository {
+    organization: string;
+    module: string;
+}
+
+// inferRepository derives the owner and name of a repository from its clone
+// url. git@github.com:depscloud/depscloud.git => { depscloud, depscloud }
+export default function inferRepository(url: string): Repository {
+    const importPath = inferImportPath(url);
+    if (!importPath) {
+        return { organization: "", module: "" };
+    }
+
+    const path = importPath.substr(importPath.indexOf("/") + 1);
+
+    const pos = path.indexOf("/");
+    if (pos === -1) {
+        return { organization: "", module: path };
+    }
+
+    return {
+        organization: path.substr(0, pos),
+        module: path.substr(pos + 1),
+    };
+}
@@ -0,0 +1,54 @@
+import {Minimatch} from "minimatch";
+import ExtractorFile from "../ExtractorFile";
+
+import path = require("path");
+
+function normalizePattern(pattern: string): string {
+    return pattern.trim()
+        .replace(/^\.\//, "")
+        .replace(/\/+$/, "");
+}
+
+// resolveMembers returns the manifests of the workspace members described by
+// the patterns. Patterns are relative to the directory containing the root
+// manifest and may contain globs (packages/*). Patterns prefixed with ! as
+// well as the provided excludes remove members from the set.
+export default function resolveMembers(
+    root: ExtractorFile,
+    manifest: string,
+    patterns: string[],
+    workspace: { [path: string]: ExtractorFile },
+    excludes: string[] = [],
+): ExtractorFile[] {
+    if (!workspace || !root.path()) {
+        return [];
+    }
+
+    const includeMatchers = patterns
+        .filter((pattern) => pattern.charAt(0) !== "!")
+        .map((pattern) => new Minimatch(normalizePattern(pattern)));
+
+    const excludeMatchers = patterns
+        .filter((pattern) => pattern.charAt(0) === "!")
+        .map((pattern) => pattern.substr(1))
+        .concat(excludes)
+        .map((pattern) => new Minimatch(normalizePattern(pattern)));
+
+    const rootDir = path.posix.dirname(root.path());
+
+    return Object.keys(workspace)
+        .filter((filePath) => path.posix.basename(filePath) === manifest)
+        .filter((filePath) => {
+            const dir = path.posix.dirname(filePath);
+            const relative = rootDir === "." ? dir : path.posix.relative(rootDir, dir);
+
+            if (relative === "." || relative === "" || relative.startsWith("..")) {
+                return false;
+            }
+
+            return includeMatchers.some((m) => m.match(relative)) &&
+                !excludeMatchers.some((m) => m.match(relative));
+        })
+        .sort()
+        .map((filePath) => workspace[filePath]);
+}
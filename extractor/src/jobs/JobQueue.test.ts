@@ -0,0 +1,54 @@
+import JobQueue, {Job} from "./JobQueue";
+
+function finished(jobs: JobQueue, id: string): Promise<Job> {
+    return new Promise((resolve) => {
+        const statuses = [];
+        jobs.watch(id, (job) => {
+            statuses.push(job.status);
+            if (job.status === "complete" || job.status === "failed") {
+                resolve({ ...job, statuses } as any);
+            }
+        });
+    });
+}
+
+describe("JobQueue", () => {
+    test("completes jobs in the background", async () => {
+        const jobs = new JobQueue({ maxJobs: 1, retention: 1000 });
+
+        const job = jobs.submit(async () => [
+            { language: "go", system: "vgo", name: "github.com/depscloud/depscloud", dependencies: [] } as any,
+        ]);
+
+        expect(job.status).toBe("pending");
+
+        const result: any = await finished(jobs, job.id);
+        expect(result.statuses).toEqual([ "pending", "running", "complete" ]);
+        expect(jobs.get(job.id).managementFiles.length).toBe(1);
+    });
+
+    test("records failures", async () => {
+        const jobs = new JobQueue({ maxJobs: 1, retention: 1000 });
+
+        const job = jobs.submit(() => Promise.reject(new Error("unsupported archive")));
+
+        const result = await finished(jobs, job.id);
+        expect(result.status).toBe("failed");
+        expect(result.error).toBe("unsupported archive");
+    });
+
+    test("bounds the number of jobs in progress", async () => {
+        const jobs = new JobQueue({ maxJobs: 1, retention: 1000 });
+
+        const first = jobs.submit(async () => []);
+        expect(jobs.submit(async () => [])).toBeNull();
+
+        await finished(jobs, first.id);
+        expect(jobs.submit(async () => []) === null).toBe(false);
+    });
+
+    test("forgets unknown jobs", () => {
+        const jobs = new JobQueue({ maxJobs: 1, retention: 1000 });
+        expect(jobs.get("missing")).toBeNull();
+    });
+});
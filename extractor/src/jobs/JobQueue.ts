@@ -0,0 +1,131 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import {getLogger} from "log4js";
+
+import crypto = require("crypto");
+import events = require("events");
+import promClient = require("prom-client");
+
+const logger = getLogger();
+
+const jobsTotal = new promClient.Counter({
+    name: "extractor_jobs_total",
+    help: "The number of extraction jobs that have finished.",
+    labelNames: [ "status" ],
+});
+
+export type JobStatus = "pending" | "running" | "complete" | "failed";
+
+export interface Job {
+    id: string;
+    status: JobStatus;
+    createdAt: Date;
+    updatedAt: Date;
+    error?: string;
+    managementFiles?: DependencyManagementFile[];
+}
+
+export interface JobQueueOptions {
+    // maxJobs bounds the number of jobs that are pending or running.
+    maxJobs: number;
+    // retention is the number of milliseconds finished jobs are kept for.
+    retention: number;
+}
+
+function done(job: Job): boolean {
+    return job.status === "complete" || job.status === "failed";
+}
+
+// JobQueue runs extractions in the background so callers don't need to hold a
+// connection open while a large source is extracted. Jobs are kept in memory,
+// so they must be polled from the replica that accepted them. Finished jobs
+// are forgotten once the retention period has passed.
+export default class JobQueue {
+    private readonly options: JobQueueOptions;
+    private readonly jobs: Map<string, Job>;
+    private readonly emitter: events.EventEmitter;
+
+    private active: number;
+
+    constructor(options: JobQueueOptions) {
+        this.options = options;
+        this.jobs = new Map();
+        this.emitter = new events.EventEmitter();
+        this.emitter.setMaxListeners(0);
+
+        this.active = 0;
+    }
+
+    // submit starts the work in the background and returns the pending job.
+    // Null is returned when too many jobs are already in progress.
+    public submit(work: () => Promise<DependencyManagementFile[]>): Job | null {
+        if (this.active >= this.options.maxJobs) {
+            return null;
+        }
+
+        const now = new Date();
+        const job: Job = {
+            id: crypto.randomBytes(16).toString("hex"),
+            status: "pending",
+            createdAt: now,
+            updatedAt: now,
+        };
+
+        this.jobs.set(job.id, job);
+        this.active++;
+
+        setImmediate(async () => {
+            this.update(job, { status: "running" });
+
+            try {
+                const managementFiles = await work();
+                this.update(job, { status: "complete", managementFiles });
+            } catch (e) {
+                logger.error(`[jobs] ${job.id} failed: ${e.message}`);
+                this.update(job, { status: "failed", error: e.message });
+            }
+
+            this.active--;
+            jobsTotal.inc({ status: job.status });
+
+            setTimeout(() => this.jobs.delete(job.id), this.options.retention).unref();
+        });
+
+        return job;
+    }
+
+    public get(id: string): Job | null {
+        return this.jobs.get(id) || null;
+    }
+
+    // watch invokes the listener with the current state of the job and again
+    // every time it changes, until the job is done. The returned function stops
+    // watching the job.
+    public watch(id: string, listener: (job: Job) => void): () => void {
+        const job = this.get(id);
+        if (!job) {
+            return () => null;
+        }
+
+        listener(job);
+        if (done(job)) {
+            return () => null;
+        }
+
+        const onUpdate = (updated: Job) => {
+            listener(updated);
+            if (done(updated)) {
+                this.emitter.removeListener(id, onUpdate);
+            }
+        };
+
+        this.emitter.on(id, onUpdate);
+        return () => this.emitter.removeListener(id, onUpdate);
+    }
+
+    private update(job: Job, changes: Partial<Job>) {
+        Object.assign(job, changes, { updatedAt: new Date() });
+        this.emitter.emit(job.id, job);
+    }
+}
+
+export {done};
@@ -1,17 +1,34 @@
 import {DependencyExtractor} from "@depscloud/api/v1alpha/extractor";
 
 import {Server, ServerCredentials} from "@grpc/grpc-js";
-import {configure, getLogger} from "log4js";
+import {addLayout, configure, getLogger} from "log4js";
+import ExtractionCache from "./cache/ExtractionCache";
+import MemoryCache from "./cache/MemoryCache";
+import RedisCache from "./cache/RedisCache";
+import {CONFIG_FILE_FLAG, OptionSpec, PRINT_CONFIG_FLAG, loadConfig, printConfig} from "./config/Config";
+import JobQueue from "./jobs/JobQueue";
+import CycloneDxExtractor from "./extractors/CycloneDxExtractor";
+import Extractor from "./extractors/Extractor";
 import ExtractorRegistry from "./extractors/ExtractorRegistry";
-import AsyncDependencyExtractor from "./service/AsyncDependencyExtractor";
+import SpdxExtractor from "./extractors/SpdxExtractor";
+import loadPlugins from "./plugins/loadPlugins";
 import DependencyExtractorImpl from "./service/DependencyExtractorImpl";
+import DiagnosticsLog from "./service/DiagnosticsLog";
+import WorkerPool from "./service/WorkerPool";
+import archiveHandler from "./service/archiveHandler";
+import {jobEventsHandler, jobResultsHandler, jobStatusHandler, submitJobHandler} from "./service/jobHandlers";
+import extractHandler from "./service/extractHandler";
+import HealthStatus from "./service/healthStatus";
+import {cpuProfileHandler, gcHandler, gcStatsHandler, heapSnapshotHandler, parseAddress} from "./service/diagnostics";
+import diagnosticsService, {diagnosticsHandler, loadDiagnosticsService} from "./service/diagnosticsService";
+import streamingService, {loadStreamingService} from "./service/streamingService";
 import unasyncify from "./service/unasyncify";
+import Tracer, {parseHeaders, setTracer} from "./telemetry/Tracer";
 
 import express = require("express");
 import program = require("caporal");
 import fs = require("fs");
 import health = require("grpc-health-check/health");
-import healthv1 = require("grpc-health-check/v1/health_pb");
 import Matcher from "./matcher/Matcher";
 import promMiddleware = require('express-prometheus-middleware');
 
@@ -21,28 +38,113 @@ const asyncFs = fs.promises;
 
 const logger = getLogger();
 
-program.name("extractor")
-    .option("--bind-address <bindAddress>", "The ip address to bind to.", program.STRING)
-    .option("--http-port <port>", "The port to run http on.", program.INT)
-    .option("--grpc-port <port>", "The port to bind to.", program.INT)
-    .option("--port <port>", "The port to bind to.", program.INT)
-    .option("--tls-key <key>", "The path to the private key used for TLS", program.STRING)
-    .option("--tls-cert <cert>", "The path to the certificate used for TLS", program.STRING)
-    .option("--tls-ca <ca>", "The path to the certificate authority used for TLS", program.STRING)
-    .option("--disable-manifests <manifest>", "The manifests to disable support for", program.ARRAY)
-    .action(async (args: any, options: any) => {
+// the options are shared with the config file, so both accept the same names
+const optionSpecs: OptionSpec[] = [
+    { flag: "--bind-address <bindAddress>", description: "The ip address to bind to.", type: "string" },
+    { flag: "--http-port <port>", description: "The port to run http on.", type: "int" },
+    { flag: "--grpc-port <port>", description: "The port to bind to.", type: "int" },
+    { flag: "--port <port>", description: "The port to bind to.", type: "int" },
+    { flag: "--tls-key <key>", description: "The path to the private key used for TLS", type: "string" },
+    { flag: "--tls-cert <cert>", description: "The path to the certificate used for TLS", type: "string" },
+    { flag: "--tls-ca <ca>", description: "The path to the certificate authority used for TLS", type: "string" },
+    { flag: "--disable-manifests <manifest>", description: "The manifests to disable support for", type: "list" },
+    { flag: "--maven-repository <url>", description: "The maven repository used to resolve parent poms and boms", type: "string" },
+    { flag: "--archive-max-size <bytes>", description: "The maximum number of bytes unpacked from an uploaded archive", type: "int" },
+    { flag: "--archive-max-entries <entries>", description: "The maximum number of entries in an uploaded archive", type: "int" },
+    { flag: "--plugins <path>", description: "The path to the configuration file for external extractor plugins", type: "string" },
+    { flag: "--workers <workers>", description: "The number of files extracted concurrently", type: "int" },
+    { flag: "--extract-timeout <millis>", description: "The number of milliseconds a file may be extracted for, 0 to disable", type: "int" },
+    { flag: "--max-file-size <bytes>", description: "The number of bytes a file may contain before it's skipped, 0 to disable", type: "int" },
+    { flag: "--diagnostics-sources <sources>", description: "The number of recently extracted sources whose diagnostics are kept", type: "int" },
+    { flag: "--cache-size <entries>", description: "The number of extraction results cached in memory, 0 to disable", type: "int" },
+    { flag: "--cache-redis <url>", description: "The redis server used to cache extraction results (redis://host:port/db)", type: "string" },
+    { flag: "--cache-ttl <seconds>", description: "The number of seconds extraction results are cached in redis", type: "int" },
+    { flag: "--max-jobs <jobs>", description: "The maximum number of extraction jobs in progress", type: "int" },
+    { flag: "--job-retention <seconds>", description: "The number of seconds the results of a finished job are kept", type: "int" },
+    { flag: "--log-level <level>", description: "The minimum level logged; trace, debug, info, warn, error, or fatal", type: "string", env: "LOG_LEVEL" },
+    { flag: "--log-format <format>", description: "The format logs are written in; text or json", type: "string", env: "LOG_FORMAT" },
+    { flag: "--otlp-endpoint <url>", description: "The OTLP/HTTP collector spans are exported to, tracing is disabled when empty", type: "string", env: "OTEL_EXPORTER_OTLP_ENDPOINT" },
+    { flag: "--otlp-header <header>", description: "The key=value headers sent to the collector", type: "list", env: "OTEL_EXPORTER_OTLP_HEADERS" },
+    { flag: "--trace-sample-ratio <ratio>", description: "The fraction of new traces that are recorded", type: "float", env: "OTEL_TRACES_SAMPLER_ARG" },
+    { flag: "--otlp-buffer-size <spans>", description: "The number of spans held between exports", type: "int" },
+    { flag: "--otlp-flush-interval <millis>", description: "The number of milliseconds between exports", type: "int" },
+    { flag: "--debug-address <address>", description: "The address to serve heap snapshots, cpu profiles, and gc stats on, such as localhost:6060; disabled when empty", type: "string", env: "DEBUG_ADDRESS" },
+    { flag: "--grpc-keepalive-time <millis>", description: "The number of milliseconds a grpc connection is idle before it's pinged", type: "int" },
+    { flag: "--grpc-keepalive-timeout <millis>", description: "The number of milliseconds to wait for a ping to be acknowledged", type: "int" },
+    { flag: "--grpc-max-connection-age <millis>", description: "The number of milliseconds a grpc connection may be open, 0 to leave it open", type: "int" },
+    { flag: "--grpc-max-connection-age-grace <millis>", description: "The number of milliseconds calls are given to finish once a connection reaches its max age", type: "int" },
+    { flag: `--${CONFIG_FILE_FLAG} <path>`, description: "The yaml or json file of options, keyed by flag name", type: "string", env: "CONFIG_FILE" },
+    { flag: `--${PRINT_CONFIG_FLAG}`, description: "Print the resolved options and exit", type: "bool" },
+];
+
+const optionTypes = {
+    string: program.STRING,
+    int: program.INT,
+    float: program.FLOAT,
+    bool: program.BOOL,
+    list: program.ARRAY,
+};
+
+program.name("extractor");
+optionSpecs.forEach((spec) => program.option(spec.flag, spec.description, optionTypes[spec.type]));
+
+program
+    .action(async (args: any, cliOptions: any) => {
+        // options are resolved from flags, environment variables, and then
+        // the config file, as they are by the other services
+        const options = await loadConfig(cliOptions.configFile || process.env.CONFIG_FILE, optionSpecs, cliOptions);
+        if (options.printConfig) {
+            process.stdout.write(printConfig(optionSpecs, options));
+            return;
+        }
+
+        // json logs carry the same fields as the logs of the other services
+        // so they can be aggregated together
+        addLayout("json", () => (event) => JSON.stringify({
+            time: event.startTime.toISOString(),
+            level: event.level.levelStr.toLowerCase(),
+            msg: event.data.join(" "),
+            service: "extractor",
+        }));
+
+        const logFormat = options.logFormat || process.env.LOG_FORMAT || "text";
+        if (logFormat !== "text" && logFormat !== "json") {
+            throw new Error(`unsupported log format ${logFormat}, specify one of text/json`);
+        }
+
         configure({
             appenders: {
-                console: { type: "console" },
+                console: logFormat === "json" ?
+                    { type: "console", layout: { type: "json" } } :
+                    { type: "console" },
             },
             categories: {
                 default: {
                     appenders: [ "console" ],
-                    level: "debug",
+                    level: options.logLevel || process.env.LOG_LEVEL || "debug",
                 },
             },
         });
 
+        // the standard environment variables are honored, as they are by the
+        // other services
+        const tracer = new Tracer({
+            service: "extractor",
+            version: packageMeta.version,
+            endpoint: options.otlpEndpoint || process.env.OTEL_EXPORTER_OTLP_ENDPOINT,
+            headers: parseHeaders(options.otlpHeader || [ process.env.OTEL_EXPORTER_OTLP_HEADERS ]),
+            sampleRatio: options.traceSampleRatio === undefined ?
+                parseFloat(process.env.OTEL_TRACES_SAMPLER_ARG || "1") :
+                options.traceSampleRatio,
+            bufferSize: options.otlpBufferSize,
+            flushInterval: options.otlpFlushInterval,
+        });
+        if (tracer.enabled()) {
+            logger.info("[main] exporting traces");
+            setTracer(tracer);
+            tracer.start();
+        }
+
         const disabledManifests = (options.disableManifests || [])
             .reduce((agg, item) => {
                 agg[item] = true;
@@ -51,9 +153,15 @@ program.name("extractor")
 
         const extractorReqs = ExtractorRegistry.known()
             .filter((e) => !disabledManifests[e])
-            .map((extractor) => ExtractorRegistry.resolve(extractor, null));
+            .map((extractor) => ExtractorRegistry.resolve(extractor, {
+                mavenRepository: options.mavenRepository,
+            }));
 
-        const extractors = await Promise.all(extractorReqs);
+        const extractors: Extractor[] = await Promise.all(extractorReqs);
+        if (options.plugins) {
+            // plugins are consulted after the built-in extractors
+            extractors.push(...await loadPlugins(options.plugins));
+        }
 
         const matchersAndExtractors = extractors.map((extractor) => {
             return {
@@ -62,17 +170,44 @@ program.name("extractor")
             }
         });
 
-        const impl: AsyncDependencyExtractor = new DependencyExtractorImpl(matchersAndExtractors);
-
-        const healthcheck = new health.Implementation({
-            "": healthv1.HealthCheckResponse.ServingStatus.SERVING,
+        const pool = new WorkerPool({
+            workers: options.workers,
+            timeout: options.extractTimeout,
         });
-        // toggle the service health as such
-        // healthcheck.setStatus("", healthv1.HealthCheckResponse.ServingStatus.NOT_SERVING);
 
-        const server = new Server();
+        let cache: ExtractionCache = null;
+        if (options.cacheRedis) {
+            logger.info("[main] caching extraction results in redis");
+            cache = new ExtractionCache(new RedisCache(options.cacheRedis, options.cacheTtl || 7 * 24 * 60 * 60));
+        } else if (options.cacheSize !== 0) {
+            cache = new ExtractionCache(new MemoryCache(options.cacheSize || 10000));
+        }
+
+        const diagnostics = new DiagnosticsLog(options.diagnosticsSources);
+
+        const impl = new DependencyExtractorImpl(matchersAndExtractors, pool, cache, diagnostics, options.maxFileSize);
+
+        const healthStatus = new HealthStatus();
+
+        // idle connections are pinged so intermediaries don't drop them,
+        // matching the defaults of the other services
+        const serverOptions: { [key: string]: number } = {
+            "grpc.keepalive_time_ms": options.grpcKeepaliveTime || 30000,
+            "grpc.keepalive_timeout_ms": options.grpcKeepaliveTimeout || 10000,
+            "grpc.keepalive_permit_without_calls": 1,
+        };
+        if (options.grpcMaxConnectionAge) {
+            serverOptions["grpc.max_connection_age_ms"] = options.grpcMaxConnectionAge;
+        }
+        if (options.grpcMaxConnectionAgeGrace) {
+            serverOptions["grpc.max_connection_age_grace_ms"] = options.grpcMaxConnectionAgeGrace;
+        }
+
+        const server = new Server(serverOptions);
         server.addService(DependencyExtractor.service, unasyncify(impl));
-        server.addService(health.service, healthcheck);
+        server.addService(loadStreamingService(), streamingService(impl));
+        server.addService(loadDiagnosticsService(), diagnosticsService(diagnostics));
+        server.addService(health.service, healthStatus.implementation);
 
         let credentials = ServerCredentials.createInsecure();
         if (options.tlsKey && options.tlsCert && options.tlsCa) {
@@ -111,23 +246,76 @@ program.name("extractor")
             collectDefaultMetrics: true,
         }))
 
-        const healthHandle = (req, resp) => {
-            resp.json({
-                state: "ok",
-                timestamp: new Date(),
-                results: {},
-            });
+        app.get("/healthz", healthStatus.healthHandler());
+        app.get("/health", healthStatus.healthHandler());
+        app.get("/readyz", healthStatus.readyHandler());
+
+        // sboms produced outside of a repository (container scans, build
+        // pipelines) can be converted without going through match/extract.
+        const sbomBody = express.text({ type: "*/*", limit: "32mb" });
+        app.post("/v1alpha/sbom/cyclonedx", sbomBody, extractHandler(new CycloneDxExtractor()));
+        app.post("/v1alpha/sbom/spdx", sbomBody, extractHandler(new SpdxExtractor()));
+
+        // source trees can be uploaded as a single archive
+        const archiveLimits = {
+            maxBytes: options.archiveMaxSize || 256 * 1024 * 1024,
+            maxEntries: options.archiveMaxEntries || 100000,
         };
+        const archiveBody = express.raw({ type: "*/*", limit: "64mb" });
+        app.post("/v1alpha/extract/archive", archiveBody, archiveHandler(impl, archiveLimits));
+
+        // large extractions can be submitted as a job and followed until they
+        // complete, rather than waiting on a single request
+        const jobs = new JobQueue({
+            maxJobs: options.maxJobs || 16,
+            retention: (options.jobRetention || 60 * 60) * 1000,
+        });
+        const jobBody = express.json({ limit: "64mb" });
+        app.post("/v1alpha/jobs", jobBody, submitJobHandler(impl, jobs));
+        app.get("/v1alpha/jobs/:id", jobStatusHandler(jobs));
+        app.get("/v1alpha/jobs/:id/events", jobEventsHandler(jobs));
+        app.get("/v1alpha/jobs/:id/results", jobResultsHandler(jobs));
 
-        app.get("/healthz", healthHandle);
-        app.get("/health", healthHandle);
+        // files that were skipped or only partially extracted can be found
+        // after the fact, rather than showing up as missing edges
+        app.get("/v1alpha/diagnostics", diagnosticsHandler(diagnostics));
 
         app.get("/version", (req, resp) => {
 		resp.json(packageMeta.meta);
         });
 
-        app.listen(httpPort, () => {
+        const httpServer = app.listen(httpPort, () => {
             logger.info(`[main] starting http on ${bindAddress}:${httpPort}`)
         });
+
+        // profiles and dumps are served separately from the api, so they're
+        // only reachable by operators with access to the address
+        let debugServer = null;
+        if (options.debugAddress) {
+            const debugApp = express();
+            debugApp.get("/debug/gcstats", gcStatsHandler());
+            debugApp.post("/debug/gc", gcHandler());
+            debugApp.get("/debug/heap", heapSnapshotHandler());
+            debugApp.get("/debug/profile", cpuProfileHandler());
+
+            const {host, port} = parseAddress(options.debugAddress);
+            debugServer = debugApp.listen(port, host, () => {
+                logger.info(`[main] starting debug server on ${host}:${port}`);
+            });
+        }
+
+        // stop serving before draining, so clients and probes move away while
+        // in flight extractions finish
+        process.once("SIGTERM", () => {
+            logger.info("[main] received shutdown signal, gracefully shutting down");
+            healthStatus.shutdown();
+
+            server.tryShutdown(() => {
+                if (debugServer) {
+                    debugServer.close();
+                }
+                httpServer.close(() => process.exit(0));
+            });
+        });
     })
     .parse(process.argv);
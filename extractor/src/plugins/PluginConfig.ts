@@ -0,0 +1,11 @@
+// PluginConfig describes an external extractor. Plugins run as sidecars that
+// implement the DependencyExtractor service and are delegated the files that
+// match their patterns.
+export default interface PluginConfig {
+    name: string;
+    address: string;
+    includes: string[];
+    excludes?: string[];
+    // the number of milliseconds to wait for the plugin to respond
+    timeout?: number;
+}
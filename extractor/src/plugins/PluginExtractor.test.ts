@@ -0,0 +1,63 @@
+import ExtractorFile from "../extractors/ExtractorFile";
+import PluginExtractor from "./PluginExtractor";
+
+describe("PluginExtractor", () => {
+    test("delegates", async () => {
+        const requests = [];
+
+        const extractor = new PluginExtractor({
+            name: "test",
+            address: "localhost:8091",
+            includes: [ "**/*.deps" ],
+        }, {
+            extract(request, options, callback) {
+                requests.push(request);
+                callback(null, {
+                    managementFiles: [
+                        {
+                            language: "go",
+                            system: "deps",
+                            sourceUrl: "",
+                            organization: "github.com",
+                            module: "depscloud/depscloud",
+                            version: "",
+                            dependencies: [],
+                            name: "github.com/depscloud/depscloud",
+                        },
+                    ],
+                });
+            },
+        });
+
+        expect(extractor.matchConfig()).toEqual({ includes: [ "**/*.deps" ], excludes: [] });
+
+        const actual = await extractor.extract("git@github.com:depscloud/depscloud.git", {
+            "*": new ExtractorFile("content", "a/b.deps"),
+        });
+
+        expect(requests).toEqual([
+            {
+                url: "git@github.com:depscloud/depscloud.git",
+                separator: "/",
+                fileContents: { "a/b.deps": "content" },
+            },
+        ]);
+        expect(actual.map((f) => f.name)).toEqual([ "github.com/depscloud/depscloud" ]);
+    });
+
+    test("failure", async () => {
+        const extractor = new PluginExtractor({
+            name: "test",
+            address: "localhost:8091",
+            includes: [ "**/*.deps" ],
+        }, {
+            extract(request, options, callback) {
+                callback(new Error("unavailable"), null);
+            },
+        });
+
+        const actual = await extractor.extract("", { "*": new ExtractorFile("content", "a/b.deps") });
+
+        expect(actual).toEqual([]);
+    });
+});
@@ -0,0 +1,77 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import {DependencyExtractor, ExtractRequest, ExtractResponse} from "@depscloud/api/v1alpha/extractor";
+import {credentials, makeGenericClientConstructor} from "@grpc/grpc-js";
+import {getLogger} from "log4js";
+import Extractor from "../extractors/Extractor";
+import ExtractorFile from "../extractors/ExtractorFile";
+import MatchConfig from "../matcher/MatchConfig";
+import PluginConfig from "./PluginConfig";
+
+const logger = getLogger();
+
+const defaultTimeout = 30000;
+
+export interface PluginClient {
+    extract(
+        request: ExtractRequest,
+        options: { deadline: Date },
+        callback: (err: Error | null, response: ExtractResponse) => void,
+    ): void;
+}
+
+const DependencyExtractorClient = makeGenericClientConstructor(DependencyExtractor.service, "DependencyExtractor");
+
+// PluginExtractor delegates extraction to an external extractor. This allows
+// proprietary manifest formats to be supported without changing the extractor.
+export default class PluginExtractor implements Extractor {
+    private readonly config: PluginConfig;
+    private readonly client: PluginClient;
+
+    constructor(config: PluginConfig, client?: PluginClient) {
+        this.config = config;
+        this.client = client || (new DependencyExtractorClient(
+            config.address, credentials.createInsecure()) as any);
+    }
+
+    public matchConfig(): MatchConfig {
+        return {
+            includes: this.config.includes || [],
+            excludes: this.config.excludes || [],
+        };
+    }
+
+    public requires(): string[] {
+        return [ "*" ];
+    }
+
+    public usesWorkspace(): boolean {
+        return false;
+    }
+
+    public async extract(url: string, files: { [p: string]: ExtractorFile }): Promise<DependencyManagementFile[]> {
+        const file = files["*"];
+
+        const request: ExtractRequest = {
+            url,
+            separator: "/",
+            fileContents: {
+                [file.path()]: file.raw(),
+            },
+        };
+
+        const deadline = new Date(Date.now() + (this.config.timeout || defaultTimeout));
+
+        return new Promise((resolve) => {
+            this.client.extract(request, { deadline }, (err, response) => {
+                if (err) {
+                    // a misbehaving plugin shouldn't fail the entire extraction
+                    logger.error(`[plugin] ${this.config.name} failed to extract ${file.path()}: ${err.message}`);
+                    resolve([]);
+                    return;
+                }
+
+                resolve((response && response.managementFiles) || []);
+            });
+        });
+    }
+}
@@ -0,0 +1,29 @@
+import PluginConfig from "./PluginConfig";
+import PluginExtractor from "./PluginExtractor";
+
+import fs = require("fs");
+import YAML = require("js-yaml");
+
+const asyncFs = fs.promises;
+
+// loadPlugins reads the plugin configuration file and constructs an extractor
+// for each of the configured plugins.
+//
+// plugins:
+//   - name: proprietary
+//     address: localhost:8091
+//     includes:
+//       - "**/*.deps"
+export default async function loadPlugins(path: string): Promise<PluginExtractor[]> {
+    const content = await asyncFs.readFile(path);
+    const config = YAML.safeLoad(content.toString()) || {};
+
+    const plugins: PluginConfig[] = config.plugins || [];
+
+    return plugins.map((plugin) => {
+        if (!plugin.name || !plugin.address || !plugin.includes || plugin.includes.length === 0) {
+            throw new Error(`plugin ${plugin.name || "<unnamed>"} requires a name, address, and includes`);
+        }
+        return new PluginExtractor(plugin);
+    });
+}
@@ -2,6 +2,8 @@ import fs = require("fs");
 import path = require("path");
 import ExtractorRegistry from "../extractors/ExtractorRegistry";
 import DependencyExtractorImpl from "./DependencyExtractorImpl";
+import DiagnosticsLog, {PARSE_ERROR, TOO_LARGE, UNSUPPORTED_SYNTAX} from "./DiagnosticsLog";
+import WorkerPool from "./WorkerPool";
 import Matcher from "../matcher/Matcher";
 
 const fsp = fs.promises;
@@ -76,4 +78,69 @@ describe("DependencyExtractorImpl", () => {
 
         expect(dependencyManagementFiles).toMatchSnapshot();
     });
+
+    test("extractEach", async () => {
+        const extractors = await Promise.all([
+            ExtractorRegistry.resolve("go.mod", null),
+            ExtractorRegistry.resolve("package.json", null),
+        ]);
+
+        const extractorImpl = new DependencyExtractorImpl(extractors.map((extractor) => ({
+            matcher: new Matcher(extractor.matchConfig()),
+            extractor,
+        })));
+
+        const extractorsDir = path.resolve(__dirname, "../extractors/testdata");
+
+        const fileContents = {
+            "go.mod": (await fsp.readFile(path.join(extractorsDir, "go.mod"))).toString(),
+            "web/package.json": (await fsp.readFile(path.join(extractorsDir, "package.json"))).toString(),
+        };
+
+        const results = [];
+        await extractorImpl.extractEach(
+            "git@github.com:depscloud/extractor.git", "/", fileContents,
+            (paths, managementFiles) => results.push({ paths, names: managementFiles.map((f) => f.name) }));
+
+        expect(results.sort((a, b) => a.paths[0].localeCompare(b.paths[0]))).toEqual([
+            { paths: [ "go.mod" ], names: [ "github.com/depscloud/finch" ] },
+            { paths: [ "web/package.json" ], names: [ "@organization/module" ] },
+        ]);
+    });
+
+    test("extractWithDiagnostics", async () => {
+        const extractors = await Promise.all([
+            ExtractorRegistry.resolve("go.mod", null),
+            ExtractorRegistry.resolve("package.json", null),
+            ExtractorRegistry.resolve("Cargo.toml", null),
+        ]);
+
+        const log = new DiagnosticsLog();
+        const extractorImpl = new DependencyExtractorImpl(extractors.map((extractor) => ({
+            matcher: new Matcher(extractor.matchConfig()),
+            extractor,
+        })), new WorkerPool(), null, log, 64);
+
+        const url = "git@github.com:depscloud/extractor.git";
+        const { managementFiles, diagnostics } = await extractorImpl.extractWithDiagnostics(url, "/", {
+            "go.mod": "module github.com/depscloud/depscloud\ntoolchain go1.21.0\n",
+            "web/package.json": "{ not json",
+            "Cargo.toml": `[package]\nname = "${"a".repeat(64)}"\n`,
+        });
+
+        // the other files are still extracted
+        expect(managementFiles.map((f) => f.name)).toEqual([ "github.com/depscloud/depscloud" ]);
+
+        const summary = diagnostics
+            .map((d) => [ d.path, d.severity, d.code ])
+            .sort((a, b) => a[0].localeCompare(b[0]));
+
+        expect(summary).toEqual([
+            [ "Cargo.toml", "warning", TOO_LARGE ],
+            [ "go.mod", "warning", UNSUPPORTED_SYNTAX ],
+            [ "web/package.json", "error", PARSE_ERROR ],
+        ]);
+
+        expect(log.get(url).diagnostics).toEqual(diagnostics);
+    });
 });
@@ -3,12 +3,32 @@ import {
     ExtractRequest, ExtractResponse, MatchRequest, MatchResponse,
 } from "@depscloud/api/v1alpha/extractor";
 import {ServerUnaryCall} from "@grpc/grpc-js";
+import {getLogger} from "log4js";
+import ExtractionCache, {digest} from "../cache/ExtractionCache";
 import ExtractorFile from "../extractors/ExtractorFile";
+import withLicenseFiles, {licenseFiles} from "../extractors/licenseutils/withLicenseFiles";
+import withResolvedVersions from "../extractors/lockutils/withResolvedVersions";
+import withCanonicalScopes from "../extractors/scopeutils/withCanonicalScopes";
+import withInternalScopes from "../extractors/scopeutils/withInternalScopes";
 import AsyncDependencyExtractor from "./AsyncDependencyExtractor";
+import DiagnosticsLog, {
+    Diagnostic, PARSE_ERROR, Severity, TIMEOUT, TOO_LARGE, UNSUPPORTED_SYNTAX, toMetadata,
+} from "./DiagnosticsLog";
 import MatcherAndExtractor from "./MatcherAndExtractor";
+import WorkerPool from "./WorkerPool";
+import Matcher from "../matcher/Matcher";
+import PluginExtractor from "../plugins/PluginExtractor";
+import {SpanContext, fromMetadata, getTracer} from "../telemetry/Tracer";
+import {PathFilter, filterFromMetadata} from "./requestFilter";
+
+import { Minimatch } from "minimatch";
 
 import path = require("path")
 
+const logger = getLogger();
+
+const licenseFileMatcher = new Matcher(licenseFiles);
+
 function constructTree(separator: string, paths: string[]): any {
     const root: any = {};
 
@@ -39,20 +59,111 @@ function normalizePaths(separator: string, paths: string[]): string[] {
     })
 }
 
+function isPattern(requirement: string): boolean {
+    return requirement.indexOf("*") > -1;
+}
+
+// resolveRequirements returns every set of files within the directory that
+// satisfies the requirements of the extractor. Literal requirements must be
+// present in the directory. Pattern requirements (such as "*.yml") produce a
+// separate set for each file that matches both the pattern and the matcher.
+function resolveRequirements(
+    separator: string,
+    dir: any,
+    me: MatcherAndExtractor,
+): { [req: string]: string }[] {
+    let candidates: { [req: string]: string }[] = [ {} ];
+
+    me.extractor.requires().forEach((req) => {
+        let keys: string[] = [];
+
+        if (isPattern(req)) {
+            const pattern = new Minimatch(req);
+
+            keys = Object.keys(dir)
+                .filter((name) => typeof dir[name] === "string" && pattern.match(name))
+                .map((name) => dir[name])
+                .filter((key) => me.matcher.match(normalizePaths(separator, [ key ])[0]));
+        } else if (dir[req] && typeof dir[req] === "string") {
+            keys = [ dir[req] ];
+        }
+
+        candidates = candidates
+            .map((candidate) => keys.map((key) => ({ ...candidate, [req]: key })))
+            .reduce((all, next) => all.concat(next), []);
+    });
+
+    return candidates;
+}
+
+// traced records a span for the extraction, including the time spent waiting
+// on a worker, so slow manifests stand out within the trace of a source.
+function traced<T>(
+    parent: SpanContext,
+    extractor: string,
+    manifestPath: string,
+    run: () => Promise<T>,
+): Promise<T> {
+    const span = getTracer().startSpan("extractor.extract_file", parent);
+    span.setAttribute("extractor.name", extractor);
+    span.setAttribute("extractor.manifest", manifestPath);
+
+    return run().then((result) => {
+        span.finish();
+        return result;
+    }, (error) => {
+        span.finish(error);
+        throw error;
+    });
+}
+
+// ExtractionResult holds the management files produced by extracting a set of
+// files, along with any problems found along the way.
+export interface ExtractionResult {
+    managementFiles: DependencyManagementFile[];
+    diagnostics: Diagnostic[];
+}
+
+interface Extraction {
+    paths: string[];
+    result: Promise<ExtractionResult>;
+}
+
 export default class DependencyExtractorImpl implements AsyncDependencyExtractor {
     private readonly matcherAndExtractors: MatcherAndExtractor[];
+    private readonly pool: WorkerPool;
+    private readonly cache: ExtractionCache;
+    private readonly diagnostics: DiagnosticsLog;
+    private readonly maxFileSize: number;
 
-    constructor(matcherAndExtractors: MatcherAndExtractor[]) {
+    // files larger than maxFileSize bytes are skipped, unless it's 0
+    constructor(
+        matcherAndExtractors: MatcherAndExtractor[],
+        pool: WorkerPool = new WorkerPool(),
+        cache: ExtractionCache = null,
+        diagnostics: DiagnosticsLog = null,
+        maxFileSize: number = 0,
+    ) {
         this.matcherAndExtractors = matcherAndExtractors;
+        this.pool = pool;
+        this.cache = cache;
+        this.diagnostics = diagnostics;
+        this.maxFileSize = Math.max(0, maxFileSize || 0);
     }
 
-    public matchInternal(separator: string, paths: string[]): string[] {
+    // matchInternal returns the paths supported by an extractor. When a filter
+    // is provided, paths must also satisfy the filter.
+    public matchInternal(separator: string, paths: string[], filter: PathFilter = null): string[] {
         const matchedPaths = [];
         const normPaths = normalizePaths(separator, paths);
 
         normPaths.forEach((p, i) => {
+            if (filter && !filter.match(p)) {
+                return;
+            }
+
             const found = this.matcherAndExtractors.find((me) => me.matcher.match(p))
-            if (found) {
+            if (found || licenseFileMatcher.match(p)) {
                 matchedPaths.push(paths[i]);
             }
         })
@@ -64,22 +175,60 @@ export default class DependencyExtractorImpl implements AsyncDependencyExtractor
         const { separator, paths } = call.request;
 
         return {
-            matchedPaths: this.matchInternal(separator, paths),
+            matchedPaths: this.matchInternal(separator, paths, filterFromMetadata(call.metadata)),
         };
     }
 
-    public async extractInternal(
+    // oversized returns the first path whose contents exceed the max file
+    // size, if any.
+    private oversized(paths: string[], fileContents: { [key: string]: string }): string | null {
+        if (this.maxFileSize === 0) {
+            return null;
+        }
+
+        return paths.find((p) => Buffer.byteLength(fileContents[p], "utf8") > this.maxFileSize) || null;
+    }
+
+    // extractions returns a pending extraction for every set of files that
+    // satisfies the requirements of an extractor. Each extraction is traced
+    // as a child of the parent span, when provided. Files that fail to
+    // extract, or are skipped, are reported as diagnostics rather than
+    // failing the other extractions.
+    private extractions(
         url: string,
         separator: string,
         fileContents: { [key: string]: string },
-    ): Promise<DependencyManagementFile[]> {
+        filter: PathFilter,
+        parent: SpanContext,
+    ): Extraction[] {
         const paths = Object.keys(fileContents);
-        const matchedPaths = this.matchInternal(separator, paths);
+        const matchedPaths = this.matchInternal(separator, paths, filter);
 
         const root = constructTree(separator, matchedPaths);
 
+        // every matched file is made available to extractors that need to
+        // resolve information from elsewhere in the source (parent poms).
+        const workspace: { [path: string]: ExtractorFile } = {};
+        matchedPaths.forEach((key) => {
+            const normalized = normalizePaths(separator, [ key ])[0];
+            workspace[normalized] = new ExtractorFile(fileContents[key], normalized);
+        });
+
+        const licenses: { [path: string]: ExtractorFile } = {};
+        Object.keys(workspace)
+            .filter((key) => licenseFileMatcher.match(key))
+            .forEach((key) => licenses[key] = workspace[key]);
+
+        let workspaceDigest: string = null;
+        const digestWorkspace = () => {
+            if (workspaceDigest === null) {
+                workspaceDigest = digest(workspace);
+            }
+            return workspaceDigest;
+        };
+
         let level = [ root ];
-        let managementFilePromises: Promise<DependencyManagementFile>[] = [];
+        let extractions: Extraction[] = [];
 
         while (level.length > 0) {
             const size = level.length;
@@ -87,21 +236,92 @@ export default class DependencyExtractorImpl implements AsyncDependencyExtractor
             for (let i = 0; i < size; i++) {
                 const dir = level.shift();
 
-                const nextManagementFilePromises = this.matcherAndExtractors
-                    .filter((me) =>
-                        me.extractor.requires()
-                            .map((req) => dir[req] && typeof dir[req] === "string")
-                            .reduce((last, current) => last && current))
-                    .map((me) => {
-                        const files = {};
-                        me.extractor.requires().forEach((req) => {
-                            const key = dir[req];
-                            files[req] = new ExtractorFile(fileContents[key]);
-                        });
-                        return me.extractor.extract(url, files);
-                    });
-
-                managementFilePromises = managementFilePromises.concat(nextManagementFilePromises);
+                const nextExtractions = this.matcherAndExtractors
+                    .map((me) => resolveRequirements(separator, dir, me)
+                        .map((candidate) => {
+                            const files = {};
+                            Object.keys(candidate).forEach((req) => {
+                                const key = candidate[req];
+                                files[req] = workspace[normalizePaths(separator, [ key ])[0]];
+                            });
+                            const paths = Object.keys(candidate).map((req) => candidate[req]);
+                            const manifestPath = normalizePaths(separator, [ paths[0] ])[0];
+
+                            const name = me.extractor.constructor.name;
+                            const diagnostic = (severity: Severity, code: string, message: string): Diagnostic => ({
+                                path: manifestPath,
+                                extractor: name,
+                                severity,
+                                code,
+                                message,
+                            });
+
+                            const tooLarge = this.oversized(paths, fileContents);
+                            if (tooLarge) {
+                                return {
+                                    paths,
+                                    result: Promise.resolve({
+                                        managementFiles: [],
+                                        diagnostics: [ diagnostic("warning", TOO_LARGE,
+                                            `${tooLarge} is larger than ${this.maxFileSize} bytes and was skipped`) ],
+                                    }),
+                                };
+                            }
+
+                            const warnings: string[] = [];
+                            let timedOut = false;
+
+                            const extract = () => traced(parent, name, manifestPath,
+                                () => this.pool.run(name, async () => {
+                                    try {
+                                        return await me.extractor.extract(url, files, workspace);
+                                    } finally {
+                                        Object.keys(files).forEach((req) => warnings.push(...files[req].takeWarnings()));
+                                    }
+                                }, () => timedOut = true));
+
+                            // plugins are external and may change without the
+                            // extractor knowing, so their results aren't cached.
+                            // results with warnings are extracted again so the
+                            // warnings are reported every time.
+                            let pending: Promise<DependencyManagementFile | DependencyManagementFile[]>;
+                            if (this.cache && !(me.extractor instanceof PluginExtractor)) {
+                                const key = this.cache.key(name, url, files,
+                                    me.extractor.usesWorkspace() ? digestWorkspace : null);
+                                pending = this.cache.cached(key, extract, () => warnings.length === 0);
+                            } else {
+                                pending = extract();
+                            }
+
+                            return {
+                                paths,
+                                result: pending.then((result) => {
+                                    const diagnostics = warnings
+                                        .map((warning) => diagnostic("warning", UNSUPPORTED_SYNTAX, warning));
+                                    if (timedOut) {
+                                        diagnostics.push(diagnostic("error", TIMEOUT, "extraction timed out and was skipped"));
+                                    }
+
+                                    return {
+                                        managementFiles: withLicenseFiles(
+                                            ([] as DependencyManagementFile[]).concat(result).filter((f) => !!f),
+                                            manifestPath,
+                                            licenses,
+                                        ),
+                                        diagnostics,
+                                    };
+                                }, (error) => {
+                                    logger.warn(`[${name}] failed to extract ${manifestPath}: ${error.message}`);
+                                    return {
+                                        managementFiles: [],
+                                        diagnostics: [ diagnostic("error", PARSE_ERROR, error.message) ],
+                                    };
+                                }),
+                            };
+                        }))
+                    .reduce((all, next) => all.concat(next), []);
+
+                extractions = extractions.concat(nextExtractions);
 
                 const nextLevel = Object.keys(dir)
                     .map((name) => dir[name])
@@ -111,21 +331,113 @@ export default class DependencyExtractorImpl implements AsyncDependencyExtractor
             }
         }
 
-        let managementFiles = await Promise.all(managementFilePromises);
-        managementFiles = managementFiles
+        return extractions;
+    }
+
+    // record remembers the diagnostics of the source, so they can be fetched
+    // once the extraction is done.
+    private record(url: string, diagnostics: Diagnostic[]) {
+        if (this.diagnostics) {
+            this.diagnostics.record(url, diagnostics);
+        }
+    }
+
+    // extractWithDiagnostics extracts the files, returning the management
+    // files along with the problems found while extracting them.
+    public async extractWithDiagnostics(
+        url: string,
+        separator: string,
+        fileContents: { [key: string]: string },
+        filter: PathFilter = null,
+        parent: SpanContext = null,
+    ): Promise<ExtractionResult> {
+        const results = await Promise.all(
+            this.extractions(url, separator, fileContents, filter, parent).map((e) => e.result));
+
+        const managementFiles = results
+            .reduce<DependencyManagementFile[]>((all, result) => all.concat(result.managementFiles), [])
             .filter((f) => !!f)         // ensure no nulls returned
             .filter((f) => !!f.module); // ensure a module is returned
 
+        const diagnostics = results
+            .reduce<Diagnostic[]>((all, result) => all.concat(result.diagnostics), []);
+
+        this.record(url, diagnostics);
+
+        return {
+            managementFiles: withCanonicalScopes(withInternalScopes(withResolvedVersions(managementFiles))),
+            diagnostics,
+        };
+    }
+
+    public async extractInternal(
+        url: string,
+        separator: string,
+        fileContents: { [key: string]: string },
+        filter: PathFilter = null,
+        parent: SpanContext = null,
+    ): Promise<DependencyManagementFile[]> {
+        const { managementFiles } = await this.extractWithDiagnostics(url, separator, fileContents, filter, parent);
         return managementFiles;
     }
 
+    // extractEach invokes the callback with the results of each extraction as
+    // it completes. Internal scopes and the merging of lockfiles into their
+    // manifests depend on the complete set of management files, so streamed
+    // results only carry canonical scopes and resolved versions.
+    public async extractEach(
+        url: string,
+        separator: string,
+        fileContents: { [key: string]: string },
+        callback: (paths: string[], managementFiles: DependencyManagementFile[], diagnostics: Diagnostic[]) => void,
+        filter: PathFilter = null,
+        parent: SpanContext = null,
+    ): Promise<void> {
+        const diagnostics: Diagnostic[] = [];
+
+        const pending = this.extractions(url, separator, fileContents, filter, parent)
+            .map(async ({ paths, result }) => {
+                const extracted = await result;
+                const managementFiles = extracted.managementFiles
+                    .filter((f) => !!f)
+                    .filter((f) => !!f.module);
+
+                diagnostics.push(...extracted.diagnostics);
+
+                if (managementFiles.length > 0 || extracted.diagnostics.length > 0) {
+                    callback(paths, withCanonicalScopes(withResolvedVersions(managementFiles)), extracted.diagnostics);
+                }
+            });
+
+        await Promise.all(pending);
+        this.record(url, diagnostics);
+    }
+
     public async extract(call: ServerUnaryCall<ExtractRequest, ExtractResponse>): Promise<ExtractResponse> {
         const { url, separator, fileContents } = call.request;
 
-        const managementFiles = await this.extractInternal(url, separator, fileContents);
+        // the extraction continues the trace of the caller, such as the
+        // indexer run that cloned the source
+        const span = getTracer().startSpan("extractor.extract", fromMetadata(call.metadata));
+        span.setAttribute("source.url", url);
 
-        return {
-            managementFiles,
-        };
+        try {
+            const { managementFiles, diagnostics } = await this.extractWithDiagnostics(
+                url, separator, fileContents, filterFromMetadata(call.metadata), span.context);
+
+            // the response of the api can't carry diagnostics, so they're
+            // returned in the metadata of the call
+            if (diagnostics.length > 0) {
+                call.sendMetadata(toMetadata(diagnostics));
+            }
+
+            span.finish();
+            return {
+                managementFiles,
+            };
+        } catch (e) {
+            span.finish(e);
+            throw e;
+        }
     }
 }
@@ -0,0 +1,31 @@
+import DiagnosticsLog, {METADATA_KEY, PARSE_ERROR, toMetadata} from "./DiagnosticsLog";
+
+const diagnostic = {
+    path: "package.json",
+    extractor: "PackageJsonExtractor",
+    severity: "error" as const,
+    code: PARSE_ERROR,
+    message: "Unexpected token n in JSON at position 2",
+};
+
+describe("DiagnosticsLog", () => {
+    test("keeps the last extraction of recent sources", () => {
+        const log = new DiagnosticsLog(2);
+
+        log.record("a", [ diagnostic ]);
+        log.record("b", []);
+        log.record("a", [ diagnostic, diagnostic ]);
+        log.record("c", [ diagnostic ]);
+
+        // b was the least recently extracted
+        expect(log.get("b")).toBeNull();
+        expect(log.get("a").diagnostics.length).toBe(2);
+
+        expect(log.list().map((source) => source.url)).toEqual([ "c", "a" ]);
+    });
+
+    test("toMetadata", () => {
+        const [ value ] = toMetadata([ diagnostic ]).get(METADATA_KEY);
+        expect(JSON.parse(value.toString())).toEqual([ diagnostic ]);
+    });
+});
@@ -0,0 +1,91 @@
+import {Metadata} from "@grpc/grpc-js";
+
+import promClient = require("prom-client");
+
+const diagnosticsTotal = new promClient.Counter({
+    name: "extractor_diagnostics_total",
+    help: "The number of problems reported while extracting files, by severity and code.",
+    labelNames: [ "severity", "code" ],
+});
+
+export type Severity = "warning" | "error";
+
+// the codes of the problems reported by the extractor
+export const PARSE_ERROR = "parse_error";
+export const UNSUPPORTED_SYNTAX = "unsupported_syntax";
+export const TOO_LARGE = "too_large";
+export const TIMEOUT = "timeout";
+
+// Diagnostic describes a problem with a file that caused it to be skipped, or
+// that may have left its results incomplete.
+export interface Diagnostic {
+    path: string;
+    extractor: string;
+    severity: Severity;
+    code: string;
+    message: string;
+}
+
+// SourceDiagnostics are the problems reported by the last extraction of a
+// source.
+export interface SourceDiagnostics {
+    url: string;
+    extractedAt: Date;
+    diagnostics: Diagnostic[];
+}
+
+// the metadata key diagnostics are returned under by Extract, whose response
+// can't carry them
+export const METADATA_KEY = "extraction-diagnostics-bin";
+
+// at most this many diagnostics are returned in metadata, which is limited in
+// size
+const MAX_METADATA_DIAGNOSTICS = 100;
+
+// toMetadata encodes the diagnostics as json under METADATA_KEY.
+export function toMetadata(diagnostics: Diagnostic[]): Metadata {
+    const metadata = new Metadata();
+    metadata.set(METADATA_KEY, Buffer.from(JSON.stringify(diagnostics.slice(0, MAX_METADATA_DIAGNOSTICS))));
+    return metadata;
+}
+
+export const DEFAULT_MAX_SOURCES = 1000;
+
+// DiagnosticsLog keeps the diagnostics of recently extracted sources so they
+// can be fetched after the fact, such as when edges are missing from the
+// graph. Only the last extraction of each source is kept, and the least
+// recently extracted sources are forgotten once maxSources is reached.
+export default class DiagnosticsLog {
+    private readonly maxSources: number;
+    private readonly sources: Map<string, SourceDiagnostics>;
+
+    constructor(maxSources: number = DEFAULT_MAX_SOURCES) {
+        this.maxSources = Math.max(1, maxSources);
+        this.sources = new Map();
+    }
+
+    public record(url: string, diagnostics: Diagnostic[]) {
+        diagnostics.forEach((d) => diagnosticsTotal.inc({ severity: d.severity, code: d.code }));
+
+        // maps iterate in insertion order, so the first key is the least
+        // recently extracted source
+        this.sources.delete(url);
+        this.sources.set(url, { url, extractedAt: new Date(), diagnostics });
+
+        if (this.sources.size > this.maxSources) {
+            this.sources.delete(this.sources.keys().next().value);
+        }
+    }
+
+    public get(url: string): SourceDiagnostics | null {
+        return this.sources.get(url) || null;
+    }
+
+    // list returns the sources whose last extraction reported a problem, most
+    // recently extracted first.
+    public list(): SourceDiagnostics[] {
+        return Array.from(this.sources.values())
+            .filter((source) => source.diagnostics.length > 0)
+            .reverse();
+    }
+}
@@ -0,0 +1,49 @@
+import WorkerPool from "./WorkerPool";
+
+function delay<T>(millis: number, value: T): Promise<T> {
+    return new Promise((resolve) => setTimeout(() => resolve(value), millis));
+}
+
+describe("WorkerPool", () => {
+    test("limits concurrent extractions", async () => {
+        const pool = new WorkerPool({ workers: 2, timeout: 0 });
+
+        let active = 0;
+        let maxActive = 0;
+
+        const task = async (value: number) => {
+            active++;
+            maxActive = Math.max(maxActive, active);
+            await delay(10, null);
+            active--;
+            return value;
+        };
+
+        const results = await Promise.all([ 1, 2, 3, 4, 5 ]
+            .map((value) => pool.run("test", () => task(value))));
+
+        expect(results).toEqual([ 1, 2, 3, 4, 5 ]);
+        expect(maxActive).toBe(2);
+    });
+
+    test("abandons extractions that exceed the timeout", async () => {
+        const pool = new WorkerPool({ workers: 1, timeout: 10 });
+
+        const slow = pool.run("test", () => delay(1000, "slow"));
+        const fast = pool.run("test", () => delay(1, "fast"));
+
+        expect(await slow).toBeNull();
+        expect(await fast).toBe("fast");
+    });
+
+    test("propagates errors", async () => {
+        const pool = new WorkerPool({ workers: 1 });
+
+        const failed = await pool.run("test", () => Promise.reject(new Error("failed")))
+            .then(() => "resolved", (err) => err.message);
+        const next = await pool.run("test", () => Promise.resolve("next"));
+
+        expect(failed).toBe("failed");
+        expect(next).toBe("next");
+    });
+});
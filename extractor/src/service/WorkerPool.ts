@@ -0,0 +1,128 @@
+import {getLogger} from "log4js";
+
+import promClient = require("prom-client");
+
+const logger = getLogger();
+
+const activeExtractions = new promClient.Gauge({
+    name: "extractor_active_extractions",
+    help: "The number of extractions currently being processed.",
+});
+
+const queuedExtractions = new promClient.Gauge({
+    name: "extractor_queued_extractions",
+    help: "The number of extractions waiting for an available worker.",
+});
+
+const extractionDuration = new promClient.Histogram({
+    name: "extractor_extraction_duration_seconds",
+    help: "The time taken to extract a set of files.",
+    labelNames: [ "extractor", "outcome" ],
+    buckets: [ 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60 ],
+});
+
+export interface WorkerPoolOptions {
+    // workers is the number of extractions processed at the same time.
+    workers?: number;
+    // timeout is the number of milliseconds an extraction may run for before
+    // it is abandoned. A timeout of 0 disables the timeout.
+    timeout?: number;
+}
+
+export const DEFAULT_WORKERS = 8;
+export const DEFAULT_TIMEOUT = 60000;
+
+// WorkerPool bounds the number of extractions that are processed at once.
+// Extractions are started in the order they are submitted as workers become
+// available. Extractions that exceed the timeout resolve to null and free
+// their worker so a single pathological file can't stall a repository.
+export default class WorkerPool {
+    private readonly workers: number;
+    private readonly timeout: number;
+
+    private active: number;
+    private readonly queue: (() => void)[];
+
+    constructor(options: WorkerPoolOptions = {}) {
+        this.workers = Math.max(1, options.workers || DEFAULT_WORKERS);
+        this.timeout = Math.max(0, options.timeout === undefined ? DEFAULT_TIMEOUT : options.timeout);
+
+        this.active = 0;
+        this.queue = [];
+    }
+
+    // run resolves to the result of the task once a worker is available. When
+    // the task is abandoned, onTimeout is called and the result is null.
+    public run<T>(name: string, task: () => Promise<T>, onTimeout: () => void = null): Promise<T | null> {
+        return new Promise<T | null>((resolve, reject) => {
+            const start = () => {
+                this.active++;
+                activeExtractions.inc();
+
+                const end = extractionDuration.startTimer({ extractor: name });
+                let settled = false;
+                let timer = null;
+
+                const release = (outcome: string) => {
+                    if (settled) {
+                        return false;
+                    }
+                    settled = true;
+
+                    if (timer) {
+                        clearTimeout(timer);
+                    }
+
+                    end({ outcome });
+                    activeExtractions.dec();
+                    this.active--;
+                    this.next();
+                    return true;
+                };
+
+                if (this.timeout > 0) {
+                    timer = setTimeout(() => {
+                        if (release("timeout")) {
+                            logger.warn(`[${name}] extraction exceeded ${this.timeout}ms, skipping`);
+                            if (onTimeout) {
+                                onTimeout();
+                            }
+                            resolve(null);
+                        }
+                    }, this.timeout);
+                }
+
+                // yield before running the task so that long running requests
+                // don't starve the other requests being served
+                setImmediate(() => {
+                    let pending: Promise<T>;
+                    try {
+                        pending = task();
+                    } catch (e) {
+                        pending = Promise.reject(e);
+                    }
+
+                    pending.then(
+                        (result) => release("success") && resolve(result),
+                        (err) => release("error") && reject(err),
+                    );
+                });
+            };
+
+            if (this.active < this.workers) {
+                start();
+            } else {
+                queuedExtractions.inc();
+                this.queue.push(start);
+            }
+        });
+    }
+
+    private next() {
+        const start = this.queue.shift();
+        if (start) {
+            queuedExtractions.dec();
+            start();
+        }
+    }
+}
@@ -0,0 +1,50 @@
+import {getLogger} from "log4js";
+import unpack, {UnpackOptions} from "../archive/unpack";
+import DependencyExtractorImpl from "./DependencyExtractorImpl";
+import {filterFromQuery} from "./requestFilter";
+
+const logger = getLogger();
+
+// archiveHandler runs match and extract over an uploaded source tree. This
+// allows systems that already have a checkout (such as ci) to send a single
+// tar, tar.gz, or zip instead of every path and its contents. The source url
+// and the number of leading path components to strip can be provided using
+// the url and strip query parameters. Paths can be filtered using the include
+// and exclude query parameters.
+export default function archiveHandler(
+    impl: DependencyExtractorImpl,
+    limits: UnpackOptions,
+): (req: any, resp: any) => Promise<void> {
+    return async (req, resp) => {
+        if (!Buffer.isBuffer(req.body) || req.body.length === 0) {
+            resp.status(400).json({ error: "request body is required" });
+            return;
+        }
+
+        const url = `${req.query.url || ""}`;
+        const strip = parseInt(`${req.query.strip || 0}`, 10);
+
+        if (isNaN(strip) || strip < 0) {
+            resp.status(400).json({ error: "strip must be a non-negative integer" });
+            return;
+        }
+
+        const filter = filterFromQuery(req.query);
+
+        try {
+            // only the contents of matched files are retained in memory
+            const fileContents = await unpack(req.body, {
+                ...limits,
+                strip,
+                filter: (p) => impl.matchInternal("/", [ p ], filter).length > 0,
+            });
+
+            const { managementFiles, diagnostics } = await impl.extractWithDiagnostics(url, "/", fileContents, filter);
+
+            resp.json({ managementFiles, diagnostics });
+        } catch (e) {
+            logger.error(`[archive] ${e.message}`);
+            resp.status(400).json({ error: e.message });
+        }
+    };
+}
@@ -0,0 +1,50 @@
+import {gcHandler, gcStatsHandler, parseAddress} from "./diagnostics";
+
+function response() {
+    const resp: any = {};
+    resp.status = jest.fn(() => resp);
+    resp.json = jest.fn(() => resp);
+    return resp;
+}
+
+describe("diagnostics", () => {
+    test("gcStatsHandler", () => {
+        const resp = response();
+        gcStatsHandler()({}, resp);
+
+        expect(resp.status).toHaveBeenCalledWith(200);
+
+        const stats = resp.json.mock.calls[0][0];
+        expect(stats.memory.heapUsed).toBeGreaterThan(0);
+        expect(stats.heap.total_heap_size).toBeGreaterThan(0);
+        expect(stats.spaces.length).toBeGreaterThan(0);
+    });
+
+    test("gcHandler", () => {
+        const previous = (global as any).gc;
+
+        try {
+            (global as any).gc = undefined;
+
+            let resp = response();
+            gcHandler()({}, resp);
+            expect(resp.status).toHaveBeenCalledWith(501);
+
+            const gc = jest.fn();
+            (global as any).gc = gc;
+
+            resp = response();
+            gcHandler()({}, resp);
+            expect(gc).toHaveBeenCalled();
+            expect(resp.status).toHaveBeenCalledWith(200);
+        } finally {
+            (global as any).gc = previous;
+        }
+    });
+
+    test("parseAddress", () => {
+        expect(parseAddress("localhost:6060")).toEqual({ host: "localhost", port: 6060 });
+        expect(parseAddress(":6060")).toEqual({ host: "0.0.0.0", port: 6060 });
+        expect(parseAddress("0.0.0.0:6060")).toEqual({ host: "0.0.0.0", port: 6060 });
+    });
+});
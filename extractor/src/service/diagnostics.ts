@@ -0,0 +1,102 @@
+import inspector = require("inspector");
+import v8 = require("v8");
+
+// gcStatsHandler reports the memory held by the process, matching the
+// gcstats endpoint of the other services.
+export function gcStatsHandler(): (req: any, resp: any) => void {
+    return (req, resp) => {
+        resp.status(200).json({
+            uptime: process.uptime(),
+            memory: process.memoryUsage(),
+            heap: v8.getHeapStatistics(),
+            spaces: v8.getHeapSpaceStatistics(),
+        });
+    };
+}
+
+// gcHandler forces a garbage collection. It's only available when node is
+// started using --expose-gc.
+export function gcHandler(): (req: any, resp: any) => void {
+    const handleStats = gcStatsHandler();
+
+    return (req, resp) => {
+        const gc = (global as any).gc;
+        if (typeof gc !== "function") {
+            resp.status(501).json({ error: "garbage collection is only exposed when node is started using --expose-gc" });
+            return;
+        }
+
+        gc();
+        handleStats(req, resp);
+    };
+}
+
+// heapSnapshotHandler streams a snapshot of the heap, which can be loaded
+// into the memory tab of the chrome devtools.
+export function heapSnapshotHandler(): (req: any, resp: any) => void {
+    return (req, resp) => {
+        const session = new inspector.Session();
+        session.connect();
+
+        resp.status(200);
+        resp.set("Content-Type", "application/json");
+        resp.set("Content-Disposition", `attachment; filename="extractor-${Date.now()}.heapsnapshot"`);
+
+        session.on("HeapProfiler.addHeapSnapshotChunk", (message: any) => resp.write(message.params.chunk));
+        session.post("HeapProfiler.takeHeapSnapshot", {}, () => {
+            session.disconnect();
+            resp.end();
+        });
+    };
+}
+
+// cpuProfileHandler records a cpu profile for the number of seconds in the
+// seconds parameter, 30 by default.
+export function cpuProfileHandler(): (req: any, resp: any) => void {
+    return (req, resp) => {
+        const seconds = Math.min(parseInt(`${req.query.seconds || 30}`, 10) || 30, 300);
+
+        const session = new inspector.Session();
+        session.connect();
+
+        const fail = (err: Error) => {
+            session.disconnect();
+            resp.status(500).json({ error: err.message });
+        };
+
+        session.post("Profiler.enable", (err) => {
+            if (err) {
+                return fail(err);
+            }
+
+            session.post("Profiler.start", (err) => {
+                if (err) {
+                    return fail(err);
+                }
+
+                setTimeout(() => {
+                    session.post("Profiler.stop", (err, result: any) => {
+                        if (err) {
+                            return fail(err);
+                        }
+
+                        session.disconnect();
+                        resp.set("Content-Disposition", `attachment; filename="extractor-${Date.now()}.cpuprofile"`);
+                        resp.status(200).json(result.profile);
+                    });
+                }, seconds * 1000);
+            });
+        });
+    };
+}
+
+// parseAddress splits a host:port address. As with the other services, an
+// empty host listens on every interface.
+export function parseAddress(address: string): { host: string, port: number } {
+    const index = address.lastIndexOf(":");
+
+    return {
+        host: address.substring(0, Math.max(index, 0)) || "0.0.0.0",
+        port: parseInt(address.substring(index + 1), 10),
+    };
+}
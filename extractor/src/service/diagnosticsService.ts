@@ -0,0 +1,56 @@
+import {
+    ServerUnaryCall, ServiceDefinition, UntypedServiceImplementation, loadPackageDefinition, sendUnaryData,
+} from "@grpc/grpc-js";
+import DiagnosticsLog, {SourceDiagnostics} from "./DiagnosticsLog";
+
+import protoLoader = require("@grpc/proto-loader");
+import path = require("path");
+
+// the proto lives outside of src so it's available to both src and lib
+const protoDir = path.join(__dirname, "..", "..", "proto");
+
+export function loadDiagnosticsService(): ServiceDefinition {
+    const packageDefinition = protoLoader.loadSync(path.join(protoDir, "diagnostics.proto"), {
+        defaults: true,
+        includeDirs: [ protoDir ],
+    });
+
+    const proto: any = loadPackageDefinition(packageDefinition);
+    return proto.cloud.deps.extractor.v1alpha.ExtractionDiagnostics.service;
+}
+
+// listSources returns the diagnostics of the source, or of every source with
+// diagnostics when no url is provided.
+function listSources(log: DiagnosticsLog, url: string): SourceDiagnostics[] {
+    if (!url) {
+        return log.list();
+    }
+
+    const source = log.get(url);
+    return source ? [ source ] : [];
+}
+
+// diagnosticsService exposes the diagnostics of recently extracted sources,
+// so files that were skipped can be found after the fact.
+export default function diagnosticsService(log: DiagnosticsLog): UntypedServiceImplementation {
+    return {
+        list: (call: ServerUnaryCall<{ url: string }, any>, callback: sendUnaryData<any>) => {
+            const sources = listSources(log, call.request.url).map((source) => ({
+                ...source,
+                extractedAt: source.extractedAt.getTime(),
+            }));
+
+            callback(null, { sources });
+        },
+    };
+}
+
+// diagnosticsHandler serves the same diagnostics over http. The source can be
+// selected using the url query parameter.
+export function diagnosticsHandler(log: DiagnosticsLog): (req: any, resp: any) => void {
+    return (req, resp) => {
+        resp.status(200).json({
+            sources: listSources(log, `${req.query.url || ""}`),
+        });
+    };
+}
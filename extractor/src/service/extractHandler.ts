@@ -0,0 +1,40 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import {getLogger} from "log4js";
+import Extractor from "../extractors/Extractor";
+import ExtractorFile from "../extractors/ExtractorFile";
+import withCanonicalScopes from "../extractors/scopeutils/withCanonicalScopes";
+
+const logger = getLogger();
+
+// extractHandler exposes an extractor over http. The request body is handed
+// to the extractor as its required file, allowing documents that are produced
+// outside of a repository (such as an sbom) to be converted into management
+// files. The source url can be provided using the url query parameter.
+export default function extractHandler(extractor: Extractor): (req: any, resp: any) => Promise<void> {
+    const [ requirement ] = extractor.requires();
+
+    return async (req, resp) => {
+        if (typeof req.body !== "string" || req.body.length === 0) {
+            resp.status(400).json({ error: "request body is required" });
+            return;
+        }
+
+        const url = `${req.query.url || ""}`;
+
+        try {
+            const result = await extractor.extract(url, {
+                [requirement]: new ExtractorFile(req.body),
+            });
+
+            const managementFiles = ([] as DependencyManagementFile[])
+                .concat(result)
+                .filter((f) => !!f)
+                .filter((f) => !!f.module);
+
+            resp.json({ managementFiles: withCanonicalScopes(managementFiles) });
+        } catch (e) {
+            logger.error(`[extract] ${e.message}`);
+            resp.status(400).json({ error: e.message });
+        }
+    };
+}
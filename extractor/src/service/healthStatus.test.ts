@@ -0,0 +1,46 @@
+import HealthStatus, {SERVICES} from "./healthStatus";
+
+function response() {
+    const resp: any = {};
+    resp.status = jest.fn(() => resp);
+    resp.json = jest.fn(() => resp);
+    resp.end = jest.fn(() => resp);
+    return resp;
+}
+
+describe("HealthStatus", () => {
+    test("serves every service until shutdown", () => {
+        const status = new HealthStatus();
+        const ready = status.readyHandler();
+
+        let resp = response();
+        ready({ query: {} }, resp);
+        expect(resp.status).toHaveBeenCalledWith(200);
+        expect(resp.json).toHaveBeenCalledWith({
+            status: "SERVING",
+            services: {
+                [SERVICES[0]]: "SERVING",
+                [SERVICES[1]]: "SERVING",
+            },
+        });
+
+        status.setServing(SERVICES[1], false);
+
+        resp = response();
+        ready({ query: { service: SERVICES[1] } }, resp);
+        expect(resp.status).toHaveBeenCalledWith(503);
+        expect(resp.json).toHaveBeenCalledWith({ status: "NOT_SERVING" });
+
+        resp = response();
+        ready({ query: { service: "unknown" } }, resp);
+        expect(resp.status).toHaveBeenCalledWith(404);
+
+        status.shutdown();
+        expect(status.serving()).toBe(false);
+        expect(status.serving(SERVICES[0])).toBe(false);
+
+        resp = response();
+        status.healthHandler()({}, resp);
+        expect(resp.status).toHaveBeenCalledWith(500);
+    });
+});
@@ -0,0 +1,85 @@
+import health = require("grpc-health-check/health");
+import healthv1 = require("grpc-health-check/v1/health_pb");
+
+const ServingStatus = healthv1.HealthCheckResponse.ServingStatus;
+
+// the grpc services served by the extractor, matching the names the other
+// services report their health under
+export const SERVICES = [
+    "cloud.deps.api.v1alpha.extractor.DependencyExtractor",
+    "cloud.deps.extractor.v1alpha.StreamingDependencyExtractor",
+];
+
+const statusNames = Object.keys(ServingStatus).reduce((names, name) => {
+    names[ServingStatus[name]] = name;
+    return names;
+}, {});
+
+// HealthStatus tracks the serving status of each grpc service, along with the
+// overall status under the empty service name. The same statuses are served
+// by the grpc health service and the readiness endpoint, so the gateway and
+// kubernetes probes see the same signal.
+export default class HealthStatus {
+    public readonly implementation: any;
+    private readonly statuses: { [service: string]: number };
+
+    constructor(services: string[] = SERVICES) {
+        this.statuses = { "": ServingStatus.SERVING };
+        services.forEach((service) => this.statuses[service] = ServingStatus.SERVING);
+
+        this.implementation = new health.Implementation({ ...this.statuses });
+    }
+
+    public setServing(service: string, serving: boolean) {
+        const status = serving ? ServingStatus.SERVING : ServingStatus.NOT_SERVING;
+        this.statuses[service] = status;
+        this.implementation.setStatus(service, status);
+    }
+
+    // shutdown stops serving every service, so clients and probes move away
+    // from the process while it drains.
+    public shutdown() {
+        Object.keys(this.statuses).forEach((service) => this.setServing(service, false));
+    }
+
+    public serving(service: string = ""): boolean {
+        return this.statuses[service] === ServingStatus.SERVING;
+    }
+
+    // readyHandler reports the status of every service, or of the service
+    // named by the service query parameter.
+    public readyHandler(): (req: any, resp: any) => void {
+        return (req, resp) => {
+            const service = `${req.query.service || ""}`;
+            if (this.statuses[service] === undefined) {
+                resp.status(404).end();
+                return;
+            }
+
+            const body: any = { status: statusNames[this.statuses[service]] };
+            if (service === "") {
+                body.services = Object.keys(this.statuses)
+                    .filter((name) => name !== "")
+                    .reduce((services, name) => {
+                        services[name] = statusNames[this.statuses[name]];
+                        return services;
+                    }, {});
+            }
+
+            resp.status(this.serving(service) ? 200 : 503).json(body);
+        };
+    }
+
+    // healthHandler reports the health of the process in the same form as the
+    // other services.
+    public healthHandler(): (req: any, resp: any) => void {
+        return (req, resp) => {
+            const serving = this.serving();
+            resp.status(serving ? 200 : 500).json({
+                state: serving ? "ok" : "outage",
+                timestamp: new Date(),
+                results: {},
+            });
+        };
+    }
+}
@@ -0,0 +1,106 @@
+import JobQueue, {Job, done} from "../jobs/JobQueue";
+import DependencyExtractorImpl from "./DependencyExtractorImpl";
+import requestFilter from "./requestFilter";
+
+// status omits the results of the job, which can be large.
+function status(job: Job): any {
+    return {
+        id: job.id,
+        status: job.status,
+        createdAt: job.createdAt,
+        updatedAt: job.updatedAt,
+        error: job.error,
+    };
+}
+
+// submitJobHandler accepts a batch of files to extract in the background. The
+// body mirrors an extract request ({ url, separator, fileContents }) and may
+// also provide includes and excludes globs to filter the paths. The response
+// contains the id used to follow the job.
+export function submitJobHandler(impl: DependencyExtractorImpl, jobs: JobQueue): (req: any, resp: any) => void {
+    return (req, resp) => {
+        const body = req.body || {};
+        const { url, separator, fileContents } = body;
+
+        if (!fileContents || typeof fileContents !== "object") {
+            resp.status(400).json({ error: "fileContents is required" });
+            return;
+        }
+
+        const filter = requestFilter(body.includes, body.excludes);
+
+        const job = jobs.submit(() => impl.extractInternal(
+            `${url || ""}`, `${separator || "/"}`, fileContents, filter));
+
+        if (!job) {
+            resp.status(503).json({ error: "too many jobs in progress, try again later" });
+            return;
+        }
+
+        resp.status(202)
+            .location(`${req.baseUrl}${req.path}/${job.id}`)
+            .json(status(job));
+    };
+}
+
+// jobStatusHandler reports the current status of a job.
+export function jobStatusHandler(jobs: JobQueue): (req: any, resp: any) => void {
+    return (req, resp) => {
+        const job = jobs.get(req.params.id);
+        if (!job) {
+            resp.status(404).json({ error: "job not found" });
+            return;
+        }
+
+        resp.json(status(job));
+    };
+}
+
+// jobEventsHandler streams the status of a job as server-sent events until
+// the job is done.
+export function jobEventsHandler(jobs: JobQueue): (req: any, resp: any) => void {
+    return (req, resp) => {
+        if (!jobs.get(req.params.id)) {
+            resp.status(404).json({ error: "job not found" });
+            return;
+        }
+
+        resp.status(200).set({
+            "Content-Type": "text/event-stream",
+            "Cache-Control": "no-cache",
+            "Connection": "keep-alive",
+        });
+        resp.flushHeaders();
+
+        const stop = jobs.watch(req.params.id, (job) => {
+            resp.write(`event: status\ndata: ${JSON.stringify(status(job))}\n\n`);
+            if (done(job)) {
+                resp.end();
+            }
+        });
+
+        req.on("close", stop);
+    };
+}
+
+// jobResultsHandler returns the management files extracted by a completed
+// job. Jobs that haven't completed respond with a conflict.
+export function jobResultsHandler(jobs: JobQueue): (req: any, resp: any) => void {
+    return (req, resp) => {
+        const job = jobs.get(req.params.id);
+        if (!job) {
+            resp.status(404).json({ error: "job not found" });
+            return;
+        }
+
+        if (job.status === "failed") {
+            resp.status(500).json({ error: job.error });
+            return;
+        } else if (job.status !== "complete") {
+            resp.status(409).json({ error: `job is ${job.status}` });
+            return;
+        }
+
+        resp.json({ managementFiles: job.managementFiles });
+    };
+}
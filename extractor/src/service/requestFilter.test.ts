@@ -0,0 +1,26 @@
+import requestFilter, {filterFromQuery} from "./requestFilter";
+
+describe("requestFilter", () => {
+    test("unfiltered", () => {
+        expect(requestFilter([], [])).toBeNull();
+        expect(filterFromQuery({})).toBeNull();
+    });
+
+    test("globs", () => {
+        const filter = requestFilter([ "services/**,libs/**" ], [ "**/vendor/**", "**/testdata/**" ]);
+
+        expect(filter.match("services/a/go.mod")).toBe(true);
+        expect(filter.match("libs/b/package.json")).toBe(true);
+        expect(filter.match("tools/go.mod")).toBe(false);
+        expect(filter.match("services/a/vendor/github.com/x/go.mod")).toBe(false);
+        expect(filter.match("libs/b/testdata/package.json")).toBe(false);
+    });
+
+    test("query", () => {
+        const filter = filterFromQuery({ exclude: "**/node_modules/**" });
+
+        expect(filter.match("package.json")).toBe(true);
+        expect(filter.match(".github/workflows/ci.yml")).toBe(true);
+        expect(filter.match("node_modules/a/package.json")).toBe(false);
+    });
+});
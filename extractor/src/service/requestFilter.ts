@@ -0,0 +1,50 @@
+import {Metadata} from "@grpc/grpc-js";
+
+import { Minimatch } from "minimatch";
+
+// The match and extract messages are owned by the api, so callers provide the
+// globs used to filter their paths using request metadata. Each value may
+// hold several comma separated globs.
+export const INCLUDE_METADATA_KEY = "x-depscloud-include";
+export const EXCLUDE_METADATA_KEY = "x-depscloud-exclude";
+
+export interface PathFilter {
+    match(path: string): boolean;
+}
+
+function split(values: any[]): string[] {
+    return values
+        .map((value) => `${value}`.split(","))
+        .reduce((all, next) => all.concat(next), [])
+        .map((glob) => glob.trim())
+        .filter((glob) => glob.length > 0);
+}
+
+// requestFilter constructs a filter from the globs of a request. Paths must
+// match one of the includes (when any are provided) and none of the excludes.
+// Requests without any globs aren't filtered.
+export default function requestFilter(includes: any[], excludes: any[]): PathFilter | null {
+    const include = split(includes || []).map((glob) => new Minimatch(glob, { dot: true }));
+    const exclude = split(excludes || []).map((glob) => new Minimatch(glob, { dot: true }));
+
+    if (include.length === 0 && exclude.length === 0) {
+        return null;
+    }
+
+    return {
+        match: (path) => (include.length === 0 || include.some((glob) => glob.match(path))) &&
+            !exclude.some((glob) => glob.match(path)),
+    };
+}
+
+export function filterFromMetadata(metadata: Metadata): PathFilter | null {
+    if (!metadata) {
+        return null;
+    }
+    return requestFilter(metadata.get(INCLUDE_METADATA_KEY), metadata.get(EXCLUDE_METADATA_KEY));
+}
+
+export function filterFromQuery(query: any): PathFilter | null {
+    const asArray = (value: any) => value === undefined ? [] : [].concat(value);
+    return requestFilter(asArray(query.include), asArray(query.exclude));
+}
@@ -0,0 +1,92 @@
+import {DependencyManagementFile} from "@depscloud/api/v1alpha/deps";
+import {
+    ServerDuplexStream, ServiceDefinition, UntypedServiceImplementation, loadPackageDefinition, status,
+} from "@grpc/grpc-js";
+import {getLogger} from "log4js";
+import DependencyExtractorImpl from "./DependencyExtractorImpl";
+import {Diagnostic} from "./DiagnosticsLog";
+import requestFilter from "./requestFilter";
+import {fromMetadata} from "../telemetry/Tracer";
+
+import protoLoader = require("@grpc/proto-loader");
+import path = require("path");
+
+const logger = getLogger();
+
+// the proto lives outside of src so it's available to both src and lib
+const protoDir = path.join(__dirname, "..", "..", "proto");
+
+interface ExtractChunk {
+    url: string;
+    separator: string;
+    path: string;
+    content: Buffer;
+    includes: string[];
+    excludes: string[];
+}
+
+interface ExtractResult {
+    paths: string[];
+    managementFiles: DependencyManagementFile[];
+    diagnostics: Diagnostic[];
+}
+
+export function loadStreamingService(): ServiceDefinition {
+    const packageDefinition = protoLoader.loadSync(path.join(protoDir, "stream.proto"), {
+        defaults: true,
+        includeDirs: [ protoDir ],
+    });
+
+    const proto: any = loadPackageDefinition(packageDefinition);
+    return proto.cloud.deps.extractor.v1alpha.StreamingDependencyExtractor.service;
+}
+
+// streamingService exposes the extractor as a bidirectional stream. Callers
+// stream the contents of their files in chunks, avoiding the message size
+// limits hit when a large repository is sent in a single request. Once the
+// caller finishes sending, results are streamed back as each extractor
+// completes.
+export default function streamingService(impl: DependencyExtractorImpl): UntypedServiceImplementation {
+    return {
+        extract: (call: ServerDuplexStream<ExtractChunk, ExtractResult>) => {
+            let url = "";
+            let separator = "";
+            let includes: string[] = [];
+            let excludes: string[] = [];
+            const chunks: { [path: string]: Buffer[] } = {};
+
+            call.on("data", (chunk: ExtractChunk) => {
+                url = url || chunk.url;
+                separator = separator || chunk.separator;
+                includes = includes.length > 0 ? includes : (chunk.includes || []);
+                excludes = excludes.length > 0 ? excludes : (chunk.excludes || []);
+
+                if (!chunk.path) {
+                    return;
+                }
+
+                if (!chunks[chunk.path]) {
+                    chunks[chunk.path] = [];
+                }
+                chunks[chunk.path].push(chunk.content);
+            });
+
+            call.on("end", async () => {
+                const fileContents: { [path: string]: string } = {};
+                Object.keys(chunks).forEach((key) => {
+                    fileContents[key] = Buffer.concat(chunks[key]).toString("utf8");
+                });
+
+                try {
+                    await impl.extractEach(url, separator || "/", fileContents, (paths, managementFiles, diagnostics) => {
+                        call.write({ paths, managementFiles, diagnostics });
+                    }, requestFilter(includes, excludes), fromMetadata(call.metadata));
+                    call.end();
+                } catch (e) {
+                    logger.error(`[stream] ${e.message}`);
+                    call.emit("error", { code: status.INTERNAL, details: e.message });
+                }
+            });
+        },
+    };
+}
@@ -0,0 +1,85 @@
+import {Metadata} from "@grpc/grpc-js";
+import Tracer, {TRACEPARENT_KEY, formatTraceparent, fromMetadata, parseHeaders, parseTraceparent} from "./Tracer";
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01";
+
+describe("Tracer", () => {
+    test("parses traceparents", () => {
+        const context = parseTraceparent(traceparent);
+        expect(context).toEqual({
+            traceId: "4bf92f3577b34da6a3ce929d0e0e4736",
+            spanId: "00f067aa0ba902b7",
+            sampled: true,
+        });
+        expect(formatTraceparent(context)).toBe(traceparent);
+
+        [
+            "",
+            "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+            "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+            "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+            "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+        ].forEach((value) => expect(parseTraceparent(value)).toBeNull());
+
+        const metadata = new Metadata();
+        metadata.set(TRACEPARENT_KEY, traceparent);
+        expect(fromMetadata(metadata)).toEqual(context);
+        expect(fromMetadata(new Metadata())).toBeNull();
+    });
+
+    test("parses headers", () => {
+        expect(parseHeaders([ "x-api-key=secret, x-tenant=depscloud" ])).toEqual({
+            "x-api-key": "secret",
+            "x-tenant": "depscloud",
+        });
+        expect(parseHeaders([ undefined ])).toEqual({});
+        expect(() => parseHeaders([ "invalid" ])).toThrow();
+    });
+
+    test("exports spans continuing the trace of the caller", async () => {
+        const bodies: any[] = [];
+        const tracer = new Tracer({ service: "extractor", version: "0.2.20" }, async (body) => {
+            bodies.push(JSON.parse(body));
+        });
+
+        const parent = parseTraceparent(traceparent);
+        const extract = tracer.startSpan("extractor.extract", parent);
+        const file = tracer.startSpan("extractor.extract_file", extract.context);
+        file.setAttribute("extractor.name", "GoModExtractor");
+        file.finish(new Error("timed out"));
+        extract.finish();
+
+        await tracer.flush();
+        expect(bodies.length).toBe(1);
+
+        const spans = bodies[0].resourceSpans[0].scopeSpans[0].spans;
+        expect(spans.map((span) => span.name)).toEqual([ "extractor.extract_file", "extractor.extract" ]);
+        expect(spans[1].traceId).toBe(parent.traceId);
+        expect(spans[1].parentSpanId).toBe(parent.spanId);
+        expect(spans[0].parentSpanId).toBe(spans[1].spanId);
+        expect(spans[0].status).toEqual({ code: 2, message: "timed out" });
+        expect(spans[0].attributes).toEqual([ { key: "extractor.name", value: { stringValue: "GoModExtractor" } } ]);
+
+        // nothing is exported until more spans are recorded
+        await tracer.flush();
+        expect(bodies.length).toBe(1);
+    });
+
+    test("doesn't record unsampled or disabled spans", async () => {
+        const bodies: any[] = [];
+        const tracer = new Tracer({ service: "extractor" }, async (body) => {
+            bodies.push(body);
+        });
+
+        tracer.startSpan("extractor.extract", parseTraceparent(traceparent.replace(/01$/, "00"))).finish();
+        await tracer.flush();
+        expect(bodies.length).toBe(0);
+
+        const disabled = new Tracer({ service: "extractor" });
+        expect(disabled.enabled()).toBe(false);
+
+        const span = disabled.startSpan("extractor.extract", parseTraceparent(traceparent));
+        expect(span.context.traceId).toBe("4bf92f3577b34da6a3ce929d0e0e4736");
+        span.finish();
+    });
+});
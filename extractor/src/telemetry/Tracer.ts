@@ -0,0 +1,360 @@
+import {Metadata} from "@grpc/grpc-js";
+import {getLogger} from "log4js";
+
+import crypto = require("crypto");
+import http = require("http");
+import https = require("https");
+import promClient = require("prom-client");
+import url = require("url");
+
+const logger = getLogger();
+
+// TRACEPARENT_KEY is the W3C trace context header, and grpc metadata key, used
+// to pass the current span between services.
+export const TRACEPARENT_KEY = "traceparent";
+
+const TRACES_PATH = "/v1/traces";
+const INSTRUMENTATION_SCOPE = "@depscloud/extractor/telemetry";
+
+// The kinds of spans and status codes, numbered as they are by OTLP.
+export const SPAN_KIND_INTERNAL = 1;
+export const SPAN_KIND_SERVER = 2;
+
+const STATUS_UNSET = 0;
+const STATUS_ERROR = 2;
+
+const spanDuration = new promClient.Histogram({
+    name: "telemetry_span_duration_seconds",
+    help: "The time taken by the operations traced within the service.",
+    labelNames: [ "span", "result" ],
+    buckets: [ 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60 ],
+});
+
+// SpanContext identifies a span and the trace it belongs to.
+export interface SpanContext {
+    traceId: string;
+    spanId: string;
+    sampled: boolean;
+}
+
+const TRACEPARENT = /^00-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$/;
+const ZEROS = /^0+$/;
+
+// parseTraceparent parses a W3C traceparent, returning null when it's
+// malformed.
+export function parseTraceparent(value: string): SpanContext | null {
+    const match = TRACEPARENT.exec(`${value || ""}`.trim());
+    if (!match || ZEROS.test(match[1]) || ZEROS.test(match[2])) {
+        return null;
+    }
+
+    return {
+        traceId: match[1],
+        spanId: match[2],
+        sampled: (parseInt(match[3], 16) & 1) === 1,
+    };
+}
+
+// formatTraceparent formats the span context as a W3C traceparent.
+export function formatTraceparent(context: SpanContext): string {
+    return `00-${context.traceId}-${context.spanId}-${context.sampled ? "01" : "00"}`;
+}
+
+// fromMetadata returns the span passed by the caller of a grpc call, if any.
+export function fromMetadata(metadata: Metadata): SpanContext | null {
+    if (!metadata) {
+        return null;
+    }
+
+    const values = metadata.get(TRACEPARENT_KEY);
+    return values.length > 0 ? parseTraceparent(`${values[0]}`) : null;
+}
+
+// parseHeaders parses the key=value headers sent to the collector. Each value
+// may hold several comma separated headers, as OTEL_EXPORTER_OTLP_HEADERS does.
+export function parseHeaders(values: string[]): { [key: string]: string } {
+    const headers = {};
+
+    (values || [])
+        .map((value) => `${value}`.split(","))
+        .reduce((all, next) => all.concat(next), [])
+        .map((header) => header.trim())
+        .filter((header) => header.length > 0)
+        .forEach((header) => {
+            const i = header.indexOf("=");
+            if (i <= 0) {
+                throw new Error(`otlp headers must be formatted as key=value, got "${header}"`);
+            }
+            headers[header.substring(0, i).trim()] = header.substring(i + 1).trim();
+        });
+
+    return headers;
+}
+
+function randomId(bytes: number): string {
+    return crypto.randomBytes(bytes).toString("hex");
+}
+
+function otlpAttributes(attributes: { [key: string]: string }): any[] {
+    return Object.keys(attributes).map((key) => ({
+        key,
+        value: { stringValue: attributes[key] },
+    }));
+}
+
+// Span is an operation being traced. Spans of a disabled tracer aren't
+// recorded, but still carry the trace of the caller.
+export class Span {
+    public readonly context: SpanContext;
+
+    private readonly tracer: Tracer;
+    private readonly name: string;
+    private readonly kind: number;
+    private readonly parent: SpanContext | null;
+    private readonly start: number;
+    private readonly attributes: { [key: string]: string };
+
+    private end: number;
+    private error: string;
+
+    constructor(tracer: Tracer, name: string, kind: number, parent: SpanContext | null, context: SpanContext) {
+        this.tracer = tracer;
+        this.name = name;
+        this.kind = kind;
+        this.parent = parent;
+        this.context = context;
+        this.start = Date.now();
+        this.attributes = {};
+        this.end = null;
+        this.error = null;
+    }
+
+    // setAttribute describes the span, such as the url of the source it acts on.
+    public setAttribute(key: string, value: string): void {
+        this.attributes[key] = `${value}`;
+    }
+
+    // finish ends the span, marking it failed when there's an error.
+    public finish(error: Error = null): void {
+        if (this.end !== null) {
+            return;
+        }
+
+        this.end = Date.now();
+        if (error) {
+            this.error = error.message || `${error}`;
+        }
+
+        if (this.kind === SPAN_KIND_INTERNAL) {
+            spanDuration.observe({
+                span: this.name,
+                result: error ? "error" : "ok",
+            }, (this.end - this.start) / 1000);
+        }
+
+        this.tracer.record(this);
+    }
+
+    // encode returns the json encoding of the span used by OTLP.
+    public encode(): any {
+        const encoded: any = {
+            traceId: this.context.traceId,
+            spanId: this.context.spanId,
+            name: this.name,
+            kind: this.kind,
+            startTimeUnixNano: `${this.start}000000`,
+            endTimeUnixNano: `${this.end}000000`,
+            attributes: otlpAttributes(this.attributes),
+            status: { code: STATUS_UNSET },
+        };
+
+        if (this.parent) {
+            encoded.parentSpanId = this.parent.spanId;
+        }
+        if (this.error !== null) {
+            encoded.status = { code: STATUS_ERROR, message: this.error };
+        }
+        return encoded;
+    }
+}
+
+export interface TracerOptions {
+    // service and version identify the spans of this process.
+    service: string;
+    version?: string;
+    // endpoint is the OTLP/HTTP collector spans are exported to. Spans aren't
+    // exported when it's empty.
+    endpoint?: string;
+    headers?: { [key: string]: string };
+    // sampleRatio is the fraction of new traces that are recorded. Traces
+    // started by callers follow the decision of the caller.
+    sampleRatio?: number;
+    // bufferSize is the number of spans held between exports. Spans are
+    // dropped once it's full.
+    bufferSize?: number;
+    // flushInterval is the number of milliseconds between exports.
+    flushInterval?: number;
+}
+
+// Exporter sends an encoded batch of spans to the collector.
+export type Exporter = (body: string) => Promise<void>;
+
+function httpExporter(endpoint: string, headers: { [key: string]: string }): Exporter {
+    const target = url.parse(endpoint.replace(/\/+$/, "") + TRACES_PATH);
+
+    return (body) => new Promise<void>((resolve, reject) => {
+        const options: http.RequestOptions = {
+            protocol: target.protocol,
+            hostname: target.hostname,
+            port: target.port,
+            path: target.path,
+            method: "POST",
+            headers: {
+                ...headers,
+                "Content-Type": "application/json",
+                "Content-Length": Buffer.byteLength(body),
+            },
+        };
+
+        const onResponse = (resp: http.IncomingMessage) => {
+            resp.resume();
+            if (resp.statusCode < 200 || resp.statusCode >= 300) {
+                reject(new Error(`collector responded with ${resp.statusCode}`));
+                return;
+            }
+            resolve();
+        };
+
+        const req = target.protocol === "https:" ?
+            https.request(options, onResponse) :
+            http.request(options, onResponse);
+
+        req.on("error", reject);
+        req.end(body);
+    });
+}
+
+// Tracer buffers the spans of the service and exports them to an OTLP/HTTP
+// collector in batches.
+export default class Tracer {
+    private readonly options: TracerOptions;
+    private readonly exporter: Exporter;
+
+    private spans: Span[];
+    private timer: NodeJS.Timeout;
+
+    constructor(options: TracerOptions, exporter: Exporter = null) {
+        this.options = {
+            ...options,
+            sampleRatio: options.sampleRatio === undefined ? 1 : options.sampleRatio,
+            bufferSize: options.bufferSize || 2048,
+            flushInterval: options.flushInterval || 5000,
+        };
+
+        this.exporter = exporter;
+        if (!this.exporter && this.options.endpoint) {
+            this.exporter = httpExporter(this.options.endpoint, this.options.headers || {});
+        }
+
+        this.spans = [];
+        this.timer = null;
+    }
+
+    // enabled returns true when spans are exported.
+    public enabled(): boolean {
+        return !!this.exporter;
+    }
+
+    private sampled(): boolean {
+        return this.enabled() && Math.random() < this.options.sampleRatio;
+    }
+
+    // startSpan begins a span that's a child of the parent, or the root of a
+    // new trace when there isn't one. The span must be finished by the caller.
+    public startSpan(name: string, parent: SpanContext | null = null, kind: number = SPAN_KIND_INTERNAL): Span {
+        const context = {
+            traceId: parent ? parent.traceId : randomId(16),
+            spanId: randomId(8),
+            sampled: parent ? parent.sampled : this.sampled(),
+        };
+
+        return new Span(this, name, kind, parent, context);
+    }
+
+    // record buffers the span when it's sampled.
+    public record(span: Span): void {
+        if (!this.enabled() || !span.context.sampled) {
+            return;
+        }
+
+        if (this.spans.length >= this.options.bufferSize) {
+            logger.error("[telemetry] buffer is full, dropped span");
+            return;
+        }
+
+        this.spans.push(span);
+    }
+
+    // flush exports the buffered spans.
+    public async flush(): Promise<void> {
+        if (this.spans.length === 0) {
+            return;
+        }
+
+        const batch = this.spans;
+        this.spans = [];
+
+        const body = JSON.stringify({
+            resourceSpans: [ {
+                resource: {
+                    attributes: otlpAttributes({
+                        "service.name": this.options.service,
+                        "service.version": this.options.version || "",
+                    }),
+                },
+                scopeSpans: [ {
+                    scope: { name: INSTRUMENTATION_SCOPE },
+                    spans: batch.map((span) => span.encode()),
+                } ],
+            } ],
+        });
+
+        try {
+            await this.exporter(body);
+        } catch (e) {
+            logger.error(`[telemetry] failed to export ${batch.length} spans: ${e.message}`);
+        }
+    }
+
+    // start periodically exports spans until the tracer is stopped.
+    public start(): void {
+        if (!this.enabled() || this.timer !== null) {
+            return;
+        }
+
+        this.timer = setInterval(() => this.flush(), this.options.flushInterval);
+        this.timer.unref();
+    }
+
+    // stop ends the periodic exports, flushing whatever remains.
+    public async stop(): Promise<void> {
+        if (this.timer !== null) {
+            clearInterval(this.timer);
+            this.timer = null;
+        }
+        await this.flush();
+    }
+}
+
+let current = new Tracer({ service: "extractor" });
+
+// setTracer makes the tracer record the spans started by this process.
+export function setTracer(tracer: Tracer): void {
+    current = tracer;
+}
+
+// getTracer returns the tracer recording the spans of this process. Until one
+// is set, spans aren't exported but the trace of callers is passed along.
+export function getTracer(): Tracer {
+    return current;
+}
@@ -64,3 +64,18 @@ func Checks(
 		},
 	}
 }
+
+// Services returns the checks each grpc service proxied by the gateway
+// depends on, so an outage of the extractor doesn't take down the tracker
+// services and vice versa.
+func Services() map[string][]string {
+	tracker := []string{"modules", "sources"}
+
+	return map[string][]string{
+		"cloud.deps.api.v1alpha.tracker.SourceService":         tracker,
+		"cloud.deps.api.v1alpha.tracker.ModuleService":         tracker,
+		"cloud.deps.api.v1alpha.tracker.DependencyService":     tracker,
+		"cloud.deps.api.v1alpha.tracker.SearchService":         tracker,
+		"cloud.deps.api.v1alpha.extractor.DependencyExtractor": {"extraction"},
+	}
+}
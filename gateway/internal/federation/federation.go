@@ -0,0 +1,636 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/paging"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultListCount and maxListCount match the page sizes of the tracker.
+	defaultListCount = 10
+	maxListCount     = 1000
+)
+
+// Config lists the trackers whose graphs are federated with the graph of the
+// primary tracker.
+type Config struct {
+	Name         string
+	Members      *cli.StringSlice
+	AllowPartial bool
+}
+
+// WithFlags returns the flags used to configure federation.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	if cfg.Members == nil {
+		cfg.Members = cli.NewStringSlice()
+	}
+
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "federation-name",
+			Usage:       "the name of the primary tracker within the federation",
+			Value:       cfg.Name,
+			Destination: &(cfg.Name),
+			EnvVars:     []string{"FEDERATION_NAME"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "federation-member",
+			Usage:       "a tracker whose graph is queried along with the primary tracker, as name=address",
+			Destination: cfg.Members,
+			EnvVars:     []string{"FEDERATION_MEMBERS"},
+		},
+		&cli.BoolFlag{
+			Name:        "federation-allow-partial",
+			Usage:       "respond with the results of the available trackers when others fail",
+			Value:       cfg.AllowPartial,
+			Destination: &(cfg.AllowPartial),
+			EnvVars:     []string{"FEDERATION_ALLOW_PARTIAL"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// Enabled returns true when other trackers are federated.
+func (c *Config) Enabled() bool {
+	return c != nil && c.Members != nil && len(c.Members.Value()) > 0
+}
+
+// Endpoint is the address of a federated tracker.
+type Endpoint struct {
+	Name    string
+	Address string
+}
+
+// Endpoints returns the endpoint of each member, in the order they're listed.
+func (c *Config) Endpoints() ([]*Endpoint, error) {
+	endpoints := make([]*Endpoint, 0)
+	if !c.Enabled() {
+		return endpoints, nil
+	}
+
+	seen := map[string]bool{c.Name: true}
+	for _, member := range c.Members.Value() {
+		parts := strings.SplitN(member, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("federation members must be provided as name=address, got %q", member)
+		} else if seen[parts[0]] {
+			return nil, fmt.Errorf("federation member %q is named more than once", parts[0])
+		}
+
+		seen[parts[0]] = true
+		endpoints = append(endpoints, &Endpoint{Name: parts[0], Address: parts[1]})
+	}
+
+	return endpoints, nil
+}
+
+// Member is a tracker within the federation.
+type Member struct {
+	Name         string
+	Sources      tracker.SourceServiceClient
+	Modules      tracker.ModuleServiceClient
+	Dependencies tracker.DependencyServiceClient
+}
+
+// NewMember returns a member that's called using the connection.
+func NewMember(name string, conn *grpc.ClientConn) *Member {
+	return &Member{
+		Name:         name,
+		Sources:      tracker.NewSourceServiceClient(conn),
+		Modules:      tracker.NewModuleServiceClient(conn),
+		Dependencies: tracker.NewDependencyServiceClient(conn),
+	}
+}
+
+// Federation fans read queries out to every member, merging their results.
+// Writes, such as tracking a source, only go to the primary member, which is
+// listed first. Listed sources and modules are merged in the order of the
+// members and paged again, so a page holds the requested count across the
+// federation. Other lists aren't paged, since the tokens of the members can't
+// be combined.
+type Federation struct {
+	members      []*Member
+	allowPartial bool
+}
+
+// New returns a federation of the primary and other members.
+func New(cfg *Config, primary *Member, others ...*Member) *Federation {
+	return &Federation{
+		members:      append([]*Member{primary}, others...),
+		allowPartial: cfg.AllowPartial,
+	}
+}
+
+// fanOut calls every member concurrently, passing the results of each member
+// to merge in the order the members are listed. Failed members are skipped
+// when partial results are allowed and at least one member responds.
+func (f *Federation) fanOut(ctx context.Context, call func(ctx context.Context, member *Member) (interface{}, error), merge func(result interface{})) error {
+	results := make([]interface{}, len(f.members))
+	errs := make([]error, len(f.members))
+
+	wg := sync.WaitGroup{}
+	for i, member := range f.members {
+		wg.Add(1)
+		go func(i int, member *Member) {
+			defer wg.Done()
+			results[i], errs[i] = call(ctx, member)
+		}(i, member)
+	}
+	wg.Wait()
+
+	failed := make([]string, 0)
+	var lastErr error
+	for i, err := range errs {
+		if err != nil {
+			logging.FromContext(ctx).Warnf("[federation] %s failed: %s", f.members[i].Name, err.Error())
+			failed = append(failed, f.members[i].Name)
+			lastErr = err
+		}
+	}
+
+	if len(failed) == len(f.members) {
+		return lastErr
+	} else if len(failed) > 0 && !f.allowPartial {
+		return status.Errorf(codes.Unavailable, "federated trackers failed: %s", strings.Join(failed, ", "))
+	}
+
+	for i, result := range results {
+		if errs[i] == nil {
+			merge(result)
+		}
+	}
+	return nil
+}
+
+// memberOptions returns the call options passed to each member. Headers and
+// trailers are left out since they're written once for the federated call.
+func memberOptions(opts []grpc.CallOption) []grpc.CallOption {
+	forwarded := make([]grpc.CallOption, 0, len(opts))
+	for _, opt := range opts {
+		switch opt.(type) {
+		case grpc.HeaderCallOption, *grpc.HeaderCallOption, grpc.TrailerCallOption, *grpc.TrailerCallOption:
+			continue
+		}
+		forwarded = append(forwarded, opt)
+	}
+	return forwarded
+}
+
+// setHeader writes the header of the federated call to the header options of
+// the caller.
+func setHeader(header metadata.MD, opts []grpc.CallOption) {
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case grpc.HeaderCallOption:
+			*opt.HeaderAddr = header
+		case *grpc.HeaderCallOption:
+			*opt.HeaderAddr = header
+		}
+	}
+}
+
+// moduleKey identifies a module the same way the tracker keys it.
+func moduleKey(module *schema.Module) string {
+	return strings.Join([]string{module.GetLanguage(), module.GetOrganization(), module.GetModule()}, "|")
+}
+
+// listPage determines the page and count of a federated list, the same way
+// the tracker does, along with the cursor wrapped by the page token. The
+// paging metadata of the caller is removed from the returned context, since
+// each member is paged by the federation.
+func listPage(ctx context.Context, in *tracker.ListRequest) (context.Context, int32, int32, string, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+
+	first := func(key string) string {
+		values := md.Get(key)
+		delete(md, key)
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+
+	size, token := first(paging.SizeMetadataKey), first(paging.TokenMetadataKey)
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	count := int(in.GetCount())
+	if count <= 0 && size != "" {
+		parsed, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, 0, 0, "", status.Errorf(codes.InvalidArgument, "invalid %s: %q", paging.SizeMetadataKey, size)
+		}
+		count = parsed
+	}
+	if count <= 0 {
+		count = defaultListCount
+	} else if count > maxListCount {
+		count = maxListCount
+	}
+
+	page := int(in.GetPage())
+	if page <= 0 {
+		page = 1
+	}
+
+	position := ""
+	if token != "" {
+		var err error
+		if position, err = paging.DecodeToken(token); err != nil {
+			return nil, 0, 0, "", err
+		}
+	}
+
+	return ctx, int32(page), int32(count), position, nil
+}
+
+// cursor is the position of a federated list within the list of each member.
+// An offset counts the items of a member that were listed, or is -1 once the
+// member has listed all of its items.
+type cursor struct {
+	page    int32
+	offsets []int
+}
+
+func (c *cursor) String() string {
+	parts := make([]string, 0, len(c.offsets)+1)
+	parts = append(parts, strconv.Itoa(int(c.page)))
+	for _, offset := range c.offsets {
+		parts = append(parts, strconv.Itoa(offset))
+	}
+	return strings.Join(parts, ",")
+}
+
+// more returns true when a member may still have items to list.
+func (c *cursor) more() bool {
+	for _, offset := range c.offsets {
+		if offset >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCursor reads the cursor of a page token issued by the federation.
+func (f *Federation) parseCursor(position string) (*cursor, error) {
+	invalid := status.Error(codes.InvalidArgument, "invalid page token")
+
+	parts := strings.Split(position, ",")
+	if len(parts) != len(f.members)+1 {
+		return nil, invalid
+	}
+
+	page, err := strconv.Atoi(parts[0])
+	if err != nil || page <= 0 {
+		return nil, invalid
+	}
+
+	c := &cursor{page: int32(page), offsets: make([]int, len(f.members))}
+	for i, part := range parts[1:] {
+		if c.offsets[i], err = strconv.Atoi(part); err != nil || c.offsets[i] < -1 {
+			return nil, invalid
+		}
+	}
+	return c, nil
+}
+
+// listed holds the items a member listed from its offset.
+type listed struct {
+	member int
+	items  []interface{}
+	more   bool
+}
+
+// listFrom reads up to count items of the member, starting at the offset.
+// Since members are paged by count, the items may span two of its pages.
+func listFrom(
+	ctx context.Context,
+	member *Member,
+	offset int,
+	count int32,
+	opts []grpc.CallOption,
+	call func(ctx context.Context, member *Member, in *tracker.ListRequest, opts ...grpc.CallOption) ([]interface{}, error),
+) ([]interface{}, bool, error) {
+	page, skip := int32(offset/int(count))+1, offset%int(count)
+
+	items, err := call(ctx, member, &tracker.ListRequest{Page: page, Count: count}, opts...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	more := len(items) == int(count)
+	if skip > len(items) {
+		skip = len(items)
+	}
+	items = items[skip:]
+
+	if skip > 0 && more {
+		rest, err := call(ctx, member, &tracker.ListRequest{Page: page + 1, Count: count}, opts...)
+		if err != nil {
+			return nil, false, err
+		}
+
+		items = append(items, rest...)
+		more = len(rest) == int(count)
+	}
+
+	if len(items) > int(count) {
+		items, more = items[:count], true
+	}
+	return items, more, nil
+}
+
+// listAt reads the page of items at the cursor, merged in the order of the
+// members and without the items whose key was already listed on the page.
+// Each member is read from its own offset, so a page costs at most two pages
+// of every member, and the cursor of the next page is returned.
+func (f *Federation) listAt(
+	ctx context.Context,
+	at *cursor,
+	count int32,
+	opts []grpc.CallOption,
+	key func(item interface{}) string,
+	call func(ctx context.Context, member *Member, in *tracker.ListRequest, opts ...grpc.CallOption) ([]interface{}, error),
+) ([]interface{}, *cursor, error) {
+	index := make(map[*Member]int, len(f.members))
+	for i, member := range f.members {
+		index[member] = i
+	}
+
+	next := &cursor{page: at.page + 1, offsets: append([]int{}, at.offsets...)}
+	merged := make([]interface{}, 0, count)
+	seen := make(map[string]bool)
+
+	err := f.fanOut(ctx, func(ctx context.Context, member *Member) (interface{}, error) {
+		result := &listed{member: index[member]}
+		if offset := at.offsets[result.member]; offset >= 0 {
+			items, more, err := listFrom(ctx, member, offset, count, opts, call)
+			if err != nil {
+				return nil, err
+			}
+			result.items, result.more = items, more
+		}
+		return result, nil
+	}, func(result interface{}) {
+		r := result.(*listed)
+
+		consumed := 0
+		for _, item := range r.items {
+			if len(merged) == int(count) {
+				break
+			}
+
+			consumed++
+			if k := key(item); !seen[k] {
+				seen[k] = true
+				merged = append(merged, item)
+			}
+		}
+
+		if consumed == len(r.items) && !r.more {
+			next.offsets[r.member] = -1
+		} else {
+			next.offsets[r.member] += consumed
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return merged, next, nil
+}
+
+// list reads a page of the items listed by every member. Items listed by
+// earlier members come first, and the position within each member is carried
+// by the page token, so following tokens reads every member once per page.
+// Items are only deduplicated within a page, so an item listed by several
+// members may appear on more than one page. A page requested by number is
+// found by walking the pages before it. The token of the next page is written
+// to the header options of the caller.
+func (f *Federation) list(
+	ctx context.Context,
+	in *tracker.ListRequest,
+	opts []grpc.CallOption,
+	key func(item interface{}) string,
+	call func(ctx context.Context, member *Member, in *tracker.ListRequest, opts ...grpc.CallOption) ([]interface{}, error),
+) ([]interface{}, int32, int32, error) {
+	ctx, page, count, position, err := listPage(ctx, in)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	forwarded := memberOptions(opts)
+
+	at := &cursor{page: 1, offsets: make([]int, len(f.members))}
+	if position != "" {
+		if at, err = f.parseCursor(position); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	for at.page < page && at.more() {
+		if _, at, err = f.listAt(ctx, at, count, forwarded, key, call); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	merged := make([]interface{}, 0)
+	next := &cursor{page: page + 1, offsets: at.offsets}
+	if at.page == page || position != "" {
+		if merged, next, err = f.listAt(ctx, at, count, forwarded, key, call); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	if next.more() {
+		setHeader(metadata.Pairs(paging.NextTokenMetadataKey, paging.EncodeToken(next.String())), opts)
+	}
+	return merged, next.page - 1, count, nil
+}
+
+// Sources returns a client that lists the sources of every member.
+func (f *Federation) Sources() tracker.SourceServiceClient {
+	return &sourceService{f}
+}
+
+type sourceService struct {
+	*Federation
+}
+
+func (s *sourceService) List(ctx context.Context, in *tracker.ListRequest, opts ...grpc.CallOption) (*tracker.ListSourceResponse, error) {
+	items, page, count, err := s.list(ctx, in, opts, func(item interface{}) string {
+		return item.(*schema.Source).GetUrl()
+	}, func(ctx context.Context, member *Member, in *tracker.ListRequest, opts ...grpc.CallOption) ([]interface{}, error) {
+		response, err := member.Sources.List(ctx, in, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]interface{}, 0, len(response.GetSources()))
+		for _, source := range response.GetSources() {
+			items = append(items, source)
+		}
+		return items, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &tracker.ListSourceResponse{Page: page, Count: count, Sources: make([]*schema.Source, 0, len(items))}
+	for _, item := range items {
+		response.Sources = append(response.Sources, item.(*schema.Source))
+	}
+	return response, nil
+}
+
+func (s *sourceService) Track(ctx context.Context, in *tracker.SourceRequest, opts ...grpc.CallOption) (*tracker.TrackResponse, error) {
+	return s.members[0].Sources.Track(ctx, in, opts...)
+}
+
+// Modules returns a client that lists the modules of every member.
+func (f *Federation) Modules() tracker.ModuleServiceClient {
+	return &moduleService{f}
+}
+
+type moduleService struct {
+	*Federation
+}
+
+func (m *moduleService) List(ctx context.Context, in *tracker.ListRequest, opts ...grpc.CallOption) (*tracker.ListModuleResponse, error) {
+	items, page, count, err := m.list(ctx, in, opts, func(item interface{}) string {
+		return moduleKey(item.(*schema.Module))
+	}, func(ctx context.Context, member *Member, in *tracker.ListRequest, opts ...grpc.CallOption) ([]interface{}, error) {
+		response, err := member.Modules.List(ctx, in, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]interface{}, 0, len(response.GetModules()))
+		for _, module := range response.GetModules() {
+			items = append(items, module)
+		}
+		return items, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &tracker.ListModuleResponse{Page: page, Count: count, Modules: make([]*schema.Module, 0, len(items))}
+	for _, item := range items {
+		response.Modules = append(response.Modules, item.(*schema.Module))
+	}
+	return response, nil
+}
+
+func (m *moduleService) ListSources(ctx context.Context, in *schema.Module, opts ...grpc.CallOption) (*tracker.ListSourcesResponse, error) {
+	response := &tracker.ListSourcesResponse{}
+	seen := make(map[string]bool)
+
+	err := m.fanOut(ctx, func(ctx context.Context, member *Member) (interface{}, error) {
+		return member.Modules.ListSources(ctx, in, memberOptions(opts)...)
+	}, func(result interface{}) {
+		for _, source := range result.(*tracker.ListSourcesResponse).GetSources() {
+			if url := source.GetSource().GetUrl(); !seen[url] {
+				seen[url] = true
+				response.Sources = append(response.Sources, source)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (m *moduleService) ListManaged(ctx context.Context, in *schema.Source, opts ...grpc.CallOption) (*tracker.ListManagedResponse, error) {
+	response := &tracker.ListManagedResponse{}
+	seen := make(map[string]bool)
+
+	err := m.fanOut(ctx, func(ctx context.Context, member *Member) (interface{}, error) {
+		return member.Modules.ListManaged(ctx, in, memberOptions(opts)...)
+	}, func(result interface{}) {
+		for _, module := range result.(*tracker.ListManagedResponse).GetModules() {
+			if key := moduleKey(module.GetModule()); !seen[key] {
+				seen[key] = true
+				response.Modules = append(response.Modules, module)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Dependencies returns a client that lists the dependents and dependencies of
+// a module across every member. A library published by one member is often
+// depended on by the modules of the others.
+func (f *Federation) Dependencies() tracker.DependencyServiceClient {
+	return &dependencyService{f}
+}
+
+type dependencyService struct {
+	*Federation
+}
+
+// mergeDependencies appends the dependencies of the modules that haven't been
+// seen yet.
+func mergeDependencies(merged []*tracker.Dependency, seen map[string]bool, dependencies []*tracker.Dependency) []*tracker.Dependency {
+	for _, dependency := range dependencies {
+		if key := moduleKey(dependency.GetModule()); !seen[key] {
+			seen[key] = true
+			merged = append(merged, dependency)
+		}
+	}
+	return merged
+}
+
+func (d *dependencyService) ListDependents(ctx context.Context, in *tracker.DependencyRequest, opts ...grpc.CallOption) (*tracker.ListDependentsResponse, error) {
+	response := &tracker.ListDependentsResponse{}
+	seen := make(map[string]bool)
+
+	err := d.fanOut(ctx, func(ctx context.Context, member *Member) (interface{}, error) {
+		return member.Dependencies.ListDependents(ctx, in, memberOptions(opts)...)
+	}, func(result interface{}) {
+		response.Dependents = mergeDependencies(response.Dependents, seen, result.(*tracker.ListDependentsResponse).GetDependents())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (d *dependencyService) ListDependencies(ctx context.Context, in *tracker.DependencyRequest, opts ...grpc.CallOption) (*tracker.ListDependenciesResponse, error) {
+	response := &tracker.ListDependenciesResponse{}
+	seen := make(map[string]bool)
+
+	err := d.fanOut(ctx, func(ctx context.Context, member *Member) (interface{}, error) {
+		return member.Dependencies.ListDependencies(ctx, in, memberOptions(opts)...)
+	}, func(result interface{}) {
+		response.Dependencies = mergeDependencies(response.Dependencies, seen, result.(*tracker.ListDependenciesResponse).GetDependencies())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+var _ tracker.SourceServiceClient = &sourceService{}
+var _ tracker.ModuleServiceClient = &moduleService{}
+var _ tracker.DependencyServiceClient = &dependencyService{}
@@ -0,0 +1,405 @@
+package federation_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/gateway/internal/federation"
+	"github.com/depscloud/depscloud/internal/paging"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeSources struct {
+	tracker.SourceServiceClient
+	tracked []string
+	sources []string
+	opts    []grpc.CallOption
+	pages   []int32
+}
+
+func (f *fakeSources) List(ctx context.Context, in *tracker.ListRequest, opts ...grpc.CallOption) (*tracker.ListSourceResponse, error) {
+	f.opts = opts
+	f.pages = append(f.pages, in.GetPage())
+
+	response := &tracker.ListSourceResponse{Page: in.GetPage(), Count: in.GetCount()}
+	for i := int((in.GetPage() - 1) * in.GetCount()); i < len(f.sources) && len(response.Sources) < int(in.GetCount()); i++ {
+		response.Sources = append(response.Sources, &schema.Source{Url: f.sources[i]})
+	}
+	return response, nil
+}
+
+func (f *fakeSources) Track(ctx context.Context, in *tracker.SourceRequest, opts ...grpc.CallOption) (*tracker.TrackResponse, error) {
+	f.tracked = append(f.tracked, in.GetSource().GetUrl())
+	return &tracker.TrackResponse{Tracking: true}, nil
+}
+
+type fakeModules struct {
+	tracker.ModuleServiceClient
+	modules []*schema.Module
+}
+
+func (f *fakeModules) List(ctx context.Context, in *tracker.ListRequest, opts ...grpc.CallOption) (*tracker.ListModuleResponse, error) {
+	response := &tracker.ListModuleResponse{Page: in.GetPage(), Count: in.GetCount()}
+	for i := int((in.GetPage() - 1) * in.GetCount()); i < len(f.modules) && len(response.Modules) < int(in.GetCount()); i++ {
+		response.Modules = append(response.Modules, f.modules[i])
+	}
+	return response, nil
+}
+
+// goModule returns a go module, split into its organization and module like
+// the tracker.
+func goModule(name string) *schema.Module {
+	parts := strings.SplitN(name, "/", 2)
+	return &schema.Module{Language: "go", Organization: parts[0], Module: parts[1], Name: name}
+}
+
+type fakeDependencies struct {
+	tracker.DependencyServiceClient
+	dependents []string
+	graph      map[string][]string
+	err        error
+}
+
+func (f *fakeDependencies) ListDependents(ctx context.Context, in *tracker.DependencyRequest, opts ...grpc.CallOption) (*tracker.ListDependentsResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	dependents := f.dependents
+	if f.graph != nil {
+		dependents = f.graph[in.GetName()]
+	}
+
+	response := &tracker.ListDependentsResponse{}
+	for _, name := range dependents {
+		response.Dependents = append(response.Dependents, &tracker.Dependency{
+			Module: goModule(name),
+		})
+	}
+	return response, nil
+}
+
+func member(name string, dependents ...string) *federation.Member {
+	return &federation.Member{
+		Name:         name,
+		Sources:      &fakeSources{},
+		Modules:      &fakeModules{},
+		Dependencies: &fakeDependencies{dependents: dependents},
+	}
+}
+
+func names(response *tracker.ListDependentsResponse) []string {
+	results := make([]string, 0)
+	for _, dependent := range response.GetDependents() {
+		results = append(results, dependent.GetModule().GetName())
+	}
+	return results
+}
+
+func TestEndpoints(t *testing.T) {
+	cfg := &federation.Config{Name: "us", Members: cli.NewStringSlice("eu=tracker.eu:8090", "apac=tracker.apac:8090")}
+	require.True(t, cfg.Enabled())
+
+	endpoints, err := cfg.Endpoints()
+	require.Nil(t, err)
+	require.Equal(t, []*federation.Endpoint{
+		{Name: "eu", Address: "tracker.eu:8090"},
+		{Name: "apac", Address: "tracker.apac:8090"},
+	}, endpoints)
+
+	for _, members := range [][]string{
+		{"tracker.eu:8090"},
+		{"eu="},
+		{"us=tracker.eu:8090"},
+		{"eu=tracker.eu:8090", "eu=tracker.apac:8090"},
+	} {
+		cfg.Members = cli.NewStringSlice(members...)
+		_, err := cfg.Endpoints()
+		require.NotNil(t, err, members)
+	}
+
+	require.False(t, (&federation.Config{Name: "us", Members: cli.NewStringSlice()}).Enabled())
+}
+
+func TestDependencies(t *testing.T) {
+	ctx := context.Background()
+	request := &tracker.DependencyRequest{Language: "go", Name: "github.com/depscloud/api"}
+
+	us := member("us", "github.com/depscloud/depscloud", "github.com/depscloud/hacktoberfest")
+	eu := member("eu", "github.com/depscloud/depscloud", "gitlab.eu/payments/ledger")
+	apac := member("apac", "gitlab.apac/search/indexer")
+
+	deps := federation.New(&federation.Config{}, us, eu, apac).Dependencies()
+	response, err := deps.ListDependents(ctx, request)
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"github.com/depscloud/depscloud",
+		"github.com/depscloud/hacktoberfest",
+		"gitlab.eu/payments/ledger",
+		"gitlab.apac/search/indexer",
+	}, names(response))
+
+	// a failed member is skipped when partial results are allowed
+	apac.Dependencies.(*fakeDependencies).err = status.Error(codes.Unavailable, "connection refused")
+
+	response, err = federation.New(&federation.Config{AllowPartial: true}, us, eu, apac).Dependencies().ListDependents(ctx, request)
+	require.Nil(t, err)
+	require.Len(t, response.Dependents, 3)
+
+	_, err = federation.New(&federation.Config{}, us, eu, apac).Dependencies().ListDependents(ctx, request)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Contains(t, err.Error(), "apac")
+
+	// when every member fails, the error of a member is returned
+	us.Dependencies.(*fakeDependencies).err = fmt.Errorf("not found")
+	eu.Dependencies.(*fakeDependencies).err = fmt.Errorf("not found")
+
+	_, err = federation.New(&federation.Config{AllowPartial: true}, us, eu, apac).Dependencies().ListDependents(ctx, request)
+	require.NotNil(t, err)
+}
+
+func TestTrack(t *testing.T) {
+	us := member("us")
+	eu := member("eu")
+
+	sources := federation.New(&federation.Config{}, us, eu).Sources()
+	_, err := sources.Track(context.Background(), &tracker.SourceRequest{
+		Source: &schema.Source{Url: "https://github.com/depscloud/depscloud.git"},
+	})
+	require.Nil(t, err)
+
+	require.Equal(t, []string{"https://github.com/depscloud/depscloud.git"}, us.Sources.(*fakeSources).tracked)
+	require.Empty(t, eu.Sources.(*fakeSources).tracked)
+}
+
+func TestListSources(t *testing.T) {
+	us := member("us")
+	us.Sources.(*fakeSources).sources = []string{"a", "b", "c"}
+	eu := member("eu")
+	eu.Sources.(*fakeSources).sources = []string{"b", "d", "e", "f"}
+
+	sources := federation.New(&federation.Config{}, us, eu).Sources()
+
+	list := func(ctx context.Context, in *tracker.ListRequest) ([]string, string) {
+		var header metadata.MD
+		response, err := sources.List(ctx, in, grpc.WaitForReady(true), grpc.Header(&header))
+		require.Nil(t, err)
+
+		urls := make([]string, 0)
+		for _, source := range response.GetSources() {
+			urls = append(urls, source.GetUrl())
+		}
+
+		token := ""
+		if values := header.Get(paging.NextTokenMetadataKey); len(values) > 0 {
+			token = values[0]
+		}
+		return urls, token
+	}
+
+	ctx := context.Background()
+
+	// pages span members and hold the requested count. items are only
+	// deduplicated within a page, so b is listed by both members.
+	urls, token := list(ctx, &tracker.ListRequest{Page: 1, Count: 2})
+	require.Equal(t, []string{"a", "b"}, urls)
+	require.NotEmpty(t, token)
+
+	urls, _ = list(ctx, &tracker.ListRequest{Page: 2, Count: 2})
+	require.Equal(t, []string{"c", "b"}, urls)
+
+	urls, token = list(ctx, &tracker.ListRequest{Page: 3, Count: 2})
+	require.Equal(t, []string{"d", "e"}, urls)
+	require.NotEmpty(t, token)
+
+	// the last page has no token
+	urls, token = list(ctx, &tracker.ListRequest{Page: 4, Count: 2})
+	require.Equal(t, []string{"f"}, urls)
+	require.Empty(t, token)
+
+	urls, token = list(ctx, &tracker.ListRequest{Page: 5, Count: 2})
+	require.Empty(t, urls)
+	require.Empty(t, token)
+
+	// page tokens are issued by the federation and carry the position within
+	// each member, so following them reads each member from where it left off
+	us.Sources.(*fakeSources).pages = nil
+	eu.Sources.(*fakeSources).pages = nil
+
+	ctx = metadata.AppendToOutgoingContext(ctx, paging.SizeMetadataKey, "4")
+	urls, token = list(ctx, &tracker.ListRequest{})
+	require.Equal(t, []string{"a", "b", "c", "d"}, urls)
+
+	urls, token = list(metadata.AppendToOutgoingContext(ctx, paging.TokenMetadataKey, token), &tracker.ListRequest{})
+	require.Equal(t, []string{"e", "f"}, urls)
+	require.Empty(t, token)
+
+	require.Equal(t, []int32{1}, us.Sources.(*fakeSources).pages)
+	require.Equal(t, []int32{1, 1, 2}, eu.Sources.(*fakeSources).pages)
+
+	// the page size may change between pages
+	urls, token = list(ctx, &tracker.ListRequest{Count: 1})
+	require.Equal(t, []string{"a"}, urls)
+
+	urls, _ = list(metadata.AppendToOutgoingContext(ctx, paging.TokenMetadataKey, token), &tracker.ListRequest{Count: 3})
+	require.Equal(t, []string{"b", "c", "d"}, urls)
+
+	_, err := sources.List(metadata.AppendToOutgoingContext(ctx, paging.TokenMetadataKey, paging.EncodeToken("1,0")), &tracker.ListRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// options are forwarded to the members, except for the header
+	require.Len(t, eu.Sources.(*fakeSources).opts, 1)
+}
+
+func TestListModules(t *testing.T) {
+	us := member("us")
+	us.Modules.(*fakeModules).modules = []*schema.Module{
+		goModule("github.com/depscloud/api"),
+		goModule("github.com/depscloud/depscloud"),
+	}
+	eu := member("eu")
+	eu.Modules.(*fakeModules).modules = []*schema.Module{
+		goModule("github.com/depscloud/api"),
+		goModule("gitlab.eu/depscloud/api"),
+	}
+
+	response, err := federation.New(&federation.Config{}, us, eu).Modules().List(context.Background(), &tracker.ListRequest{Page: 1, Count: 10})
+	require.Nil(t, err)
+
+	// modules are the same when their language, organization, and module are
+	names := make([]string, 0)
+	for _, module := range response.GetModules() {
+		names = append(names, module.GetName())
+	}
+	require.Equal(t, []string{
+		"github.com/depscloud/api",
+		"github.com/depscloud/depscloud",
+		"gitlab.eu/depscloud/api",
+	}, names)
+}
+
+// graphMember returns a member whose dependents are keyed by the module they're
+// listed for.
+func graphMember(name string, graph map[string][]string) *federation.Member {
+	m := member(name)
+	m.Dependencies.(*fakeDependencies).graph = graph
+	return m
+}
+
+func dependentsOf(name string) *tracker.SearchRequest {
+	module := goModule(name)
+	return &tracker.SearchRequest{
+		DependentsOf: &tracker.DependencyRequest{
+			Language:     module.GetLanguage(),
+			Organization: module.GetOrganization(),
+			Module:       module.GetModule(),
+			Name:         module.GetName(),
+		},
+	}
+}
+
+type searchStream interface {
+	Send(*tracker.SearchRequest) error
+	Recv() (*tracker.SearchResponse, error)
+	CloseSend() error
+}
+
+// searched returns the module of each response on the stream, along with its
+// dependents.
+func searched(t *testing.T, stream searchStream) []string {
+	results := make([]string, 0)
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			return results
+		}
+		require.Nil(t, err)
+
+		dependents := make([]string, 0)
+		for _, dependent := range response.GetDependents() {
+			dependents = append(dependents, dependent.GetModule().GetName())
+		}
+		results = append(results, response.GetRequest().GetDependentsOf().GetName()+" <- "+strings.Join(dependents, ","))
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+
+	// the dependents of the library cross from one member to the next, and
+	// back again
+	us := graphMember("us", map[string][]string{
+		"github.com/depscloud/api": {"github.com/depscloud/depscloud"},
+	})
+	eu := graphMember("eu", map[string][]string{
+		"github.com/depscloud/depscloud": {"gitlab.eu/payments/ledger"},
+	})
+	apac := graphMember("apac", map[string][]string{
+		"gitlab.eu/payments/ledger": {"gitlab.apac/search/indexer", "github.com/depscloud/api"},
+	})
+
+	search := federation.New(&federation.Config{}, us, eu, apac).Search()
+
+	bfs, err := search.BreadthFirstSearch(ctx)
+	require.Nil(t, err)
+	require.Nil(t, bfs.Send(dependentsOf("github.com/depscloud/api")))
+	require.Equal(t, []string{
+		"github.com/depscloud/api <- github.com/depscloud/depscloud",
+		"github.com/depscloud/depscloud <- gitlab.eu/payments/ledger",
+		"gitlab.eu/payments/ledger <- gitlab.apac/search/indexer,github.com/depscloud/api",
+		"gitlab.apac/search/indexer <- ",
+	}, searched(t, bfs))
+
+	dfs, err := search.DepthFirstSearch(ctx)
+	require.Nil(t, err)
+	require.Nil(t, dfs.Send(dependentsOf("github.com/depscloud/depscloud")))
+	require.Equal(t, []string{
+		"github.com/depscloud/depscloud <- gitlab.eu/payments/ledger",
+		"gitlab.eu/payments/ledger <- gitlab.apac/search/indexer,github.com/depscloud/api",
+		"github.com/depscloud/api <- github.com/depscloud/depscloud",
+		"gitlab.apac/search/indexer <- ",
+	}, searched(t, dfs))
+
+	// each request of a search is answered across the federation
+	stream, err := search.Search(ctx)
+	require.Nil(t, err)
+	require.Nil(t, stream.Send(dependentsOf("github.com/depscloud/api")))
+	require.Nil(t, stream.Send(dependentsOf("gitlab.eu/payments/ledger")))
+	require.Nil(t, stream.CloseSend())
+	require.Equal(t, []string{
+		"github.com/depscloud/api <- github.com/depscloud/depscloud",
+		"gitlab.eu/payments/ledger <- gitlab.apac/search/indexer,github.com/depscloud/api",
+	}, searched(t, stream))
+
+	// a failed member fails the search unless partial results are allowed
+	apac.Dependencies.(*fakeDependencies).err = status.Error(codes.Unavailable, "connection refused")
+
+	bfs, err = search.BreadthFirstSearch(ctx)
+	require.Nil(t, err)
+	require.Nil(t, bfs.Send(dependentsOf("github.com/depscloud/api")))
+
+	_, err = bfs.Recv()
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	bfs, err = federation.New(&federation.Config{AllowPartial: true}, us, eu, apac).Search().BreadthFirstSearch(ctx)
+	require.Nil(t, err)
+	require.Nil(t, bfs.Send(dependentsOf("github.com/depscloud/api")))
+	require.Equal(t, []string{
+		"github.com/depscloud/api <- github.com/depscloud/depscloud",
+		"github.com/depscloud/depscloud <- gitlab.eu/payments/ledger",
+		"gitlab.eu/payments/ledger <- ",
+	}, searched(t, bfs))
+}
@@ -0,0 +1,319 @@
+package federation
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Search returns a client that searches the graphs of every member. Each step
+// of a search is answered by the federated module and dependency clients, so
+// traversals follow the edges between the graphs of different members, such
+// as the dependents of a library in one member that live in another. The
+// streams are served within the gateway rather than by a single member.
+func (f *Federation) Search() tracker.SearchServiceClient {
+	return &searchService{
+		modules:      f.Modules(),
+		dependencies: f.Dependencies(),
+	}
+}
+
+type searchService struct {
+	modules      tracker.ModuleServiceClient
+	dependencies tracker.DependencyServiceClient
+}
+
+func (s *searchService) process(ctx context.Context, request *tracker.SearchRequest) (*tracker.SearchResponse, error) {
+	response := &tracker.SearchResponse{
+		Request: request,
+	}
+
+	if dependenciesOf := request.GetDependenciesOf(); dependenciesOf != nil {
+		result, err := s.dependencies.ListDependencies(ctx, dependenciesOf)
+		if err != nil {
+			return nil, err
+		}
+		response.Dependencies = result.GetDependencies()
+
+	} else if dependentsOf := request.GetDependentsOf(); dependentsOf != nil {
+		result, err := s.dependencies.ListDependents(ctx, dependentsOf)
+		if err != nil {
+			return nil, err
+		}
+		response.Dependents = result.GetDependents()
+
+	} else if sourcesFor := request.GetSourcesFor(); sourcesFor != nil {
+		result, err := s.modules.ListSources(ctx, sourcesFor)
+		if err != nil {
+			return nil, err
+		}
+		response.Sources = result.GetSources()
+
+	} else if modulesFor := request.GetModulesFor(); modulesFor != nil {
+		result, err := s.modules.ListManaged(ctx, modulesFor)
+		if err != nil {
+			return nil, err
+		}
+		response.Modules = result.GetModules()
+
+	} else {
+		return nil, api.ErrUnimplemented
+	}
+
+	return response, nil
+}
+
+// adjacent returns the requests that continue a traversal from the response,
+// in the same direction as the request it answers.
+func adjacent(response *tracker.SearchResponse) ([]*tracker.SearchRequest, error) {
+	var dependencies []*tracker.Dependency
+	var next func(in *tracker.DependencyRequest) *tracker.SearchRequest
+
+	if response.GetRequest().GetDependenciesOf() != nil {
+		dependencies = response.GetDependencies()
+		next = func(in *tracker.DependencyRequest) *tracker.SearchRequest {
+			return &tracker.SearchRequest{DependenciesOf: in}
+		}
+	} else if response.GetRequest().GetDependentsOf() != nil {
+		dependencies = response.GetDependents()
+		next = func(in *tracker.DependencyRequest) *tracker.SearchRequest {
+			return &tracker.SearchRequest{DependentsOf: in}
+		}
+	} else {
+		return nil, api.ErrUnimplemented
+	}
+
+	requests := make([]*tracker.SearchRequest, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		requests = append(requests, next(&tracker.DependencyRequest{
+			Language:     dependency.GetModule().GetLanguage(),
+			Organization: dependency.GetModule().GetOrganization(),
+			Module:       dependency.GetModule().GetModule(),
+			Name:         dependency.GetModule().GetName(),
+		}))
+	}
+	return requests, nil
+}
+
+// requestKey identifies the module a traversal request is for, the same way
+// the tracker keys its seen set.
+func requestKey(request *tracker.SearchRequest) string {
+	in := request.GetDependenciesOf()
+	if in == nil {
+		in = request.GetDependentsOf()
+	}
+
+	return moduleKey(&schema.Module{
+		Language:     in.GetLanguage(),
+		Organization: in.GetOrganization(),
+		Module:       in.GetModule(),
+	})
+}
+
+// Search answers each request sent on the stream until the client stops
+// sending or sends a cancel request.
+func (s *searchService) Search(ctx context.Context, opts ...grpc.CallOption) (tracker.SearchService_SearchClient, error) {
+	return newSearchStream(ctx, func(stream *searchStream) error {
+		for {
+			request, ok := stream.next()
+			if !ok || request.GetCancel() {
+				return nil
+			}
+
+			response, err := s.process(stream.ctx, request)
+			if err != nil {
+				return err
+			}
+
+			if err := stream.send(response); err != nil {
+				return err
+			}
+		}
+	}), nil
+}
+
+func (s *searchService) BreadthFirstSearch(ctx context.Context, opts ...grpc.CallOption) (tracker.SearchService_BreadthFirstSearchClient, error) {
+	return newSearchStream(ctx, func(stream *searchStream) error {
+		return s.traverse(stream, false)
+	}), nil
+}
+
+func (s *searchService) DepthFirstSearch(ctx context.Context, opts ...grpc.CallOption) (tracker.SearchService_DepthFirstSearchClient, error) {
+	return newSearchStream(ctx, func(stream *searchStream) error {
+		return s.traverse(stream, true)
+	}), nil
+}
+
+// traverse walks the graph from the first request on the stream, visiting
+// each module once. The traversal stops early when the client sends a cancel
+// request.
+func (s *searchService) traverse(stream *searchStream, depthFirst bool) error {
+	root, ok := stream.next()
+	if !ok {
+		return nil
+	}
+
+	requests := stream.requests
+	pending := []*tracker.SearchRequest{root}
+	seen := map[string]bool{requestKey(root): true}
+
+	for len(pending) > 0 {
+		var request *tracker.SearchRequest
+		if depthFirst {
+			request, pending = pending[len(pending)-1], pending[:len(pending)-1]
+		} else {
+			request, pending = pending[0], pending[1:]
+		}
+
+		response, err := s.process(stream.ctx, request)
+		if err != nil {
+			return err
+		}
+
+		adjacentRequests, err := adjacent(response)
+		if err != nil {
+			return err
+		}
+
+		for _, next := range adjacentRequests {
+			if key := requestKey(next); !seen[key] {
+				seen[key] = true
+				pending = append(pending, next)
+			}
+		}
+
+		if err := stream.send(response); err != nil {
+			return err
+		}
+
+		select {
+		case request, ok := <-requests:
+			if !ok {
+				requests = nil
+			} else if request.GetCancel() {
+				return nil
+			} else {
+				return status.Error(codes.InvalidArgument, "unexpected request body")
+			}
+		default:
+		}
+	}
+
+	return nil
+}
+
+// searchStream is a client stream served within the gateway. Requests are
+// handed to the search as they're sent and responses are buffered until the
+// client receives them.
+type searchStream struct {
+	ctx context.Context
+
+	requests  chan *tracker.SearchRequest
+	responses chan *tracker.SearchResponse
+	closeSend sync.Once
+	err       error
+}
+
+// newSearchStream starts the search on a new stream. The stream ends when the
+// search returns or the context is canceled.
+func newSearchStream(ctx context.Context, search func(stream *searchStream) error) *searchStream {
+	ctx, cancel := context.WithCancel(ctx)
+
+	stream := &searchStream{
+		ctx:       ctx,
+		requests:  make(chan *tracker.SearchRequest),
+		responses: make(chan *tracker.SearchResponse, 16),
+	}
+
+	go func() {
+		defer close(stream.responses)
+		defer cancel()
+		stream.err = search(stream)
+	}()
+
+	return stream
+}
+
+// next returns the next request sent by the client, or false once the client
+// stops sending.
+func (s *searchStream) next() (*tracker.SearchRequest, bool) {
+	select {
+	case <-s.ctx.Done():
+		return nil, false
+	case request, ok := <-s.requests:
+		return request, ok
+	}
+}
+
+func (s *searchStream) send(response *tracker.SearchResponse) error {
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case s.responses <- response:
+		return nil
+	}
+}
+
+func (s *searchStream) Send(request *tracker.SearchRequest) error {
+	select {
+	case <-s.ctx.Done():
+		return io.EOF
+	case s.requests <- request:
+		return nil
+	}
+}
+
+func (s *searchStream) Recv() (*tracker.SearchResponse, error) {
+	response, ok := <-s.responses
+	if !ok {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	return response, nil
+}
+
+func (s *searchStream) CloseSend() error {
+	s.closeSend.Do(func() {
+		close(s.requests)
+	})
+	return nil
+}
+
+func (s *searchStream) Header() (metadata.MD, error) {
+	return metadata.MD{}, nil
+}
+
+func (s *searchStream) Trailer() metadata.MD {
+	return metadata.MD{}
+}
+
+func (s *searchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *searchStream) SendMsg(m interface{}) error {
+	return s.Send(m.(*tracker.SearchRequest))
+}
+
+func (s *searchStream) RecvMsg(m interface{}) error {
+	response, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	*(m.(*tracker.SearchResponse)) = *response
+	return nil
+}
+
+var _ tracker.SearchServiceClient = &searchService{}
+var _ tracker.SearchService_SearchClient = &searchStream{}
@@ -0,0 +1,285 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/tenants"
+)
+
+// Route is where the combined document is served.
+const Route = "/openapi.json"
+
+// Version is the version of the OpenAPI specification documents are written
+// in.
+const Version = "3.0.3"
+
+// The security schemes documented for the gateway.
+const (
+	BearerScheme  = "bearerAuth"
+	SubjectScheme = "subjectHeader"
+	TenantScheme  = "tenantHeader"
+)
+
+// Options describe the document and how callers authenticate with the
+// gateway.
+type Options struct {
+	Title       string
+	Version     string
+	RBACMode    string
+	TenancyMode string
+}
+
+type object = map[string]interface{}
+
+// rewriteRefs points references to swagger definitions at the components of
+// the document.
+func rewriteRefs(value interface{}) interface{} {
+	switch v := value.(type) {
+	case object:
+		for key, child := range v {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				v[key] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+			} else {
+				v[key] = rewriteRefs(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = rewriteRefs(child)
+		}
+	}
+	return value
+}
+
+// convertParameter moves the fields describing the type of the parameter into
+// its schema.
+func convertParameter(parameter object) object {
+	converted := object{}
+	schema := object{}
+
+	for key, value := range parameter {
+		switch key {
+		case "collectionFormat":
+			// arrays are passed as repeated query parameters by the gateway
+		case "type", "format", "items", "enum", "default":
+			schema[key] = value
+		default:
+			converted[key] = value
+		}
+	}
+
+	if len(schema) > 0 {
+		converted["schema"] = schema
+	}
+	return converted
+}
+
+func convertResponse(response object) object {
+	converted := object{"description": response["description"]}
+	if schema, ok := response["schema"]; ok {
+		converted["content"] = object{
+			"application/json": object{"schema": schema},
+		}
+	}
+	return converted
+}
+
+func convertOperation(operation object) object {
+	converted := object{}
+
+	for key, value := range operation {
+		switch key {
+		case "parameters":
+			parameters := make([]interface{}, 0)
+			for _, p := range value.([]interface{}) {
+				parameter := p.(object)
+				if parameter["in"] == "body" {
+					converted["requestBody"] = object{
+						"required": parameter["required"] == true,
+						"content": object{
+							"application/json": object{"schema": parameter["schema"]},
+						},
+					}
+					continue
+				}
+				parameters = append(parameters, convertParameter(parameter))
+			}
+			if len(parameters) > 0 {
+				converted["parameters"] = parameters
+			}
+		case "responses":
+			responses := object{}
+			for code, response := range value.(object) {
+				responses[code] = convertResponse(response.(object))
+			}
+			converted["responses"] = responses
+		case "consumes", "produces", "schemes":
+			// described by the content of requests and responses
+		default:
+			converted[key] = value
+		}
+	}
+
+	return converted
+}
+
+// security returns the schemes callers authenticate with, along with the
+// combinations of them that are accepted.
+func security(opts *Options) (object, []interface{}) {
+	schemes := object{}
+	var alternatives []object
+
+	switch opts.RBACMode {
+	case rbac.ModeCertificate:
+		// subjects are read from client certificates, so only service
+		// account tokens are sent with requests
+		alternatives = append(alternatives, object{}, object{BearerScheme: []string{}})
+	case rbac.ModeMetadata:
+		schemes[SubjectScheme] = object{
+			"type":        "apiKey",
+			"in":          "header",
+			"name":        rbac.HeaderKey,
+			"description": "the subject the call is made on behalf of, set by a trusted proxy",
+		}
+		alternatives = append(alternatives, object{SubjectScheme: []string{}}, object{BearerScheme: []string{}})
+	}
+
+	if len(alternatives) > 0 {
+		schemes[BearerScheme] = object{
+			"type":        "http",
+			"scheme":      "bearer",
+			"description": "a service account token",
+		}
+	}
+
+	if opts.TenancyMode == tenants.ModeMetadata {
+		schemes[TenantScheme] = object{
+			"type":        "apiKey",
+			"in":          "header",
+			"name":        tenants.HeaderKey,
+			"description": "the tenant the call is made for",
+		}
+
+		if len(alternatives) == 0 {
+			alternatives = append(alternatives, object{})
+		}
+		for _, alternative := range alternatives {
+			alternative[TenantScheme] = []string{}
+		}
+	}
+
+	requirements := make([]interface{}, 0, len(alternatives))
+	for _, alternative := range alternatives {
+		requirements = append(requirements, alternative)
+	}
+	return schemes, requirements
+}
+
+// Merge converts the swagger documents into a single OpenAPI document,
+// annotated with the security schemes of the gateway. Operations on the same
+// path are combined, and definitions shared between documents are kept once.
+func Merge(opts *Options, documents ...[]byte) ([]byte, error) {
+	paths := object{}
+	schemas := object{}
+	tags := make(map[string]bool)
+
+	for _, document := range documents {
+		swagger := object{}
+		if err := json.Unmarshal(document, &swagger); err != nil {
+			return nil, err
+		}
+
+		if version := swagger["swagger"]; version != "2.0" {
+			return nil, fmt.Errorf("unsupported swagger version %v", version)
+		}
+
+		rewriteRefs(swagger)
+
+		if definitions, ok := swagger["definitions"].(object); ok {
+			for name, schema := range definitions {
+				schemas[name] = schema
+			}
+		}
+
+		documentPaths, _ := swagger["paths"].(object)
+		for path, item := range documentPaths {
+			operations, ok := paths[path].(object)
+			if !ok {
+				operations = object{}
+				paths[path] = operations
+			}
+
+			for method, operation := range item.(object) {
+				if _, ok := operations[method]; ok {
+					return nil, fmt.Errorf("%s %s is described by more than one document", strings.ToUpper(method), path)
+				}
+
+				converted := convertOperation(operation.(object))
+				operations[method] = converted
+
+				if operationTags, ok := converted["tags"].([]interface{}); ok {
+					for _, tag := range operationTags {
+						tags[fmt.Sprint(tag)] = true
+					}
+				}
+			}
+		}
+	}
+
+	tagNames := make([]string, 0, len(tags))
+	for tag := range tags {
+		tagNames = append(tagNames, tag)
+	}
+	sort.Strings(tagNames)
+
+	tagList := make([]object, 0, len(tagNames))
+	for _, tag := range tagNames {
+		tagList = append(tagList, object{"name": tag})
+	}
+
+	securitySchemes, requirements := security(opts)
+
+	components := object{"schemas": schemas}
+	if len(securitySchemes) > 0 {
+		components["securitySchemes"] = securitySchemes
+	}
+
+	info := object{
+		"title":   opts.Title,
+		"version": opts.Version,
+	}
+	if opts.Version == "" {
+		info["version"] = "version not set"
+	}
+	if opts.RBACMode == rbac.ModeCertificate || opts.TenancyMode == tenants.ModeCertificate {
+		// OpenAPI 3.0 has no scheme for client certificates
+		info["description"] = "Callers authenticate using client certificates."
+	}
+
+	doc := object{
+		"openapi":    Version,
+		"info":       info,
+		"tags":       tagList,
+		"paths":      paths,
+		"components": components,
+	}
+	if len(requirements) > 0 {
+		doc["security"] = requirements
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Handler serves the document.
+func Handler(document []byte) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write(document)
+	}
+}
@@ -0,0 +1,103 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/depscloud/api/swagger"
+
+	"github.com/depscloud/depscloud/gateway/internal/openapi"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/stretchr/testify/require"
+)
+
+type document struct {
+	OpenAPI    string                                       `json:"openapi"`
+	Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+	Components struct {
+		Schemas         map[string]interface{} `json:"schemas"`
+		SecuritySchemes map[string]interface{} `json:"securitySchemes"`
+	} `json:"components"`
+	Security []map[string][]string `json:"security"`
+}
+
+func merge(t *testing.T, opts *openapi.Options) *document {
+	data, err := openapi.Merge(opts,
+		swagger.MustAsset("v1alpha/tracker/tracker.swagger.json"),
+		swagger.MustAsset("v1alpha/extractor/extractor.swagger.json"))
+	require.Nil(t, err)
+
+	// every reference points at the components of the document
+	require.NotContains(t, string(data), "#/definitions/")
+
+	doc := &document{}
+	require.Nil(t, json.Unmarshal(data, doc))
+	return doc
+}
+
+func TestMerge(t *testing.T) {
+	doc := merge(t, &openapi.Options{Title: "deps.cloud", Version: "v0.3.0"})
+	require.Equal(t, openapi.Version, doc.OpenAPI)
+	require.Empty(t, doc.Components.SecuritySchemes)
+	require.Empty(t, doc.Security)
+
+	// both documents are combined
+	require.Contains(t, doc.Paths, "/v1alpha/sources/track")
+	require.Contains(t, doc.Paths, "/v1alpha/dependencies/extract")
+	require.Contains(t, doc.Components.Schemas, "trackerSourceRequest")
+	require.Contains(t, doc.Components.Schemas, "extractorExtractRequest")
+
+	// bodies are described as request bodies, and parameters by schemas
+	track := doc.Paths["/v1alpha/sources/track"]["post"]
+	require.NotNil(t, track["requestBody"])
+	require.Nil(t, track["parameters"])
+
+	modules := doc.Paths["/v1alpha/modules"]["get"]
+	parameters := modules["parameters"].([]interface{})
+	require.NotEmpty(t, parameters)
+	for _, parameter := range parameters {
+		require.Contains(t, parameter, "schema")
+		require.NotContains(t, parameter, "type")
+	}
+
+	responses := modules["responses"].(map[string]interface{})
+	require.Contains(t, responses["200"], "content")
+}
+
+func TestMerge_security(t *testing.T) {
+	doc := merge(t, &openapi.Options{RBACMode: rbac.ModeMetadata, TenancyMode: tenants.ModeMetadata})
+	require.Contains(t, doc.Components.SecuritySchemes, openapi.BearerScheme)
+	require.Contains(t, doc.Components.SecuritySchemes, openapi.SubjectScheme)
+	require.Contains(t, doc.Components.SecuritySchemes, openapi.TenantScheme)
+	require.Equal(t, []map[string][]string{
+		{openapi.SubjectScheme: {}, openapi.TenantScheme: {}},
+		{openapi.BearerScheme: {}, openapi.TenantScheme: {}},
+	}, doc.Security)
+
+	doc = merge(t, &openapi.Options{RBACMode: rbac.ModeCertificate})
+	require.Contains(t, doc.Components.SecuritySchemes, openapi.BearerScheme)
+	require.Equal(t, []map[string][]string{{}, {openapi.BearerScheme: {}}}, doc.Security)
+}
+
+func TestMerge_conflicts(t *testing.T) {
+	tracker := swagger.MustAsset("v1alpha/tracker/tracker.swagger.json")
+
+	_, err := openapi.Merge(&openapi.Options{}, tracker, tracker)
+	require.NotNil(t, err)
+
+	_, err = openapi.Merge(&openapi.Options{}, []byte(`{"openapi": "3.0.0"}`))
+	require.NotNil(t, err)
+}
+
+func TestHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	openapi.Handler([]byte(`{"openapi":"3.0.3"}`))(recorder, httptest.NewRequest("GET", openapi.Route, nil))
+
+	require.Equal(t, 200, recorder.Code)
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	require.True(t, strings.HasPrefix(recorder.Body.String(), `{"openapi"`))
+}
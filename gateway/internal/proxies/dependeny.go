@@ -4,6 +4,10 @@ import (
 	"context"
 
 	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/paging"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func NewDependencyServiceProxy(client tracker.DependencyServiceClient) tracker.DependencyServiceServer {
@@ -17,11 +21,29 @@ type dependencyService struct {
 }
 
 func (d *dependencyService) ListDependents(ctx context.Context, request *tracker.DependencyRequest) (*tracker.ListDependentsResponse, error) {
-	return d.client.ListDependents(ctx, request)
+	var header metadata.MD
+	response, err := d.client.ListDependents(forwardContext(ctx), request, grpc.Header(&header))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := paging.RelayNextToken(ctx, header); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
 func (d *dependencyService) ListDependencies(ctx context.Context, request *tracker.DependencyRequest) (*tracker.ListDependenciesResponse, error) {
-	return d.client.ListDependencies(ctx, request)
+	var header metadata.MD
+	response, err := d.client.ListDependencies(forwardContext(ctx), request, grpc.Header(&header))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := paging.RelayNextToken(ctx, header); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
 var _ tracker.DependencyServiceServer = &dependencyService{}
@@ -0,0 +1,21 @@
+package proxies
+
+import (
+	"context"
+
+	"github.com/depscloud/depscloud/internal/asof"
+	"github.com/depscloud/depscloud/internal/delta"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/idempotency"
+	"github.com/depscloud/depscloud/internal/paging"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/tenants"
+)
+
+// forwardContext passes the paging, filter, as of, idempotency, delta,
+// tenant, and subject metadata of a request along to the backend.
+func forwardContext(ctx context.Context) context.Context {
+	ctx = delta.ForwardContext(idempotency.ForwardContext(paging.ForwardContext(ctx)))
+	ctx = tenants.ForwardContext(asof.ForwardContext(filters.ForwardContext(ctx)))
+	return rbac.ForwardContext(ctx)
+}
@@ -5,6 +5,10 @@ import (
 
 	"github.com/depscloud/api/v1alpha/schema"
 	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/paging"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func NewModuleServiceProxy(client tracker.ModuleServiceClient) tracker.ModuleServiceServer {
@@ -18,15 +22,42 @@ type moduleService struct {
 }
 
 func (m *moduleService) List(ctx context.Context, request *tracker.ListRequest) (*tracker.ListModuleResponse, error) {
-	return m.client.List(ctx, request)
+	var header metadata.MD
+	response, err := m.client.List(forwardContext(ctx), request, grpc.Header(&header))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := paging.RelayNextToken(ctx, header); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
 func (m *moduleService) ListSources(ctx context.Context, module *schema.Module) (*tracker.ListSourcesResponse, error) {
-	return m.client.ListSources(ctx, module)
+	var header metadata.MD
+	response, err := m.client.ListSources(forwardContext(ctx), module, grpc.Header(&header))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := paging.RelayNextToken(ctx, header); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
 func (m *moduleService) ListManaged(ctx context.Context, source *schema.Source) (*tracker.ListManagedResponse, error) {
-	return m.client.ListManaged(ctx, source)
+	var header metadata.MD
+	response, err := m.client.ListManaged(forwardContext(ctx), source, grpc.Header(&header))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := paging.RelayNextToken(ctx, header); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
 var _ tracker.ModuleServiceServer = &moduleService{}
@@ -0,0 +1,34 @@
+package proxies
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/depscloud/depscloud/internal/telemetry"
+)
+
+// NewQueryProxy forwards requests for graph queries, dashboards, tombstones,
+// labels, role bindings, audit records, service accounts, and graph transfers
+// to the http api of the tracker since they aren't part of the grpc api. The
+// trace of each request is passed along.
+func NewQueryProxy(address string, tlsConfig *tls.Config) (http.Handler, error) {
+	target, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var transport http.RoundTripper
+	if tlsConfig != nil {
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		}
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = telemetry.Transport(transport)
+
+	return proxy, nil
+}
@@ -16,6 +16,7 @@ func NewSearchServiceProxy(client tracker.SearchServiceClient) tracker.SearchSer
 type searchClientStream interface {
 	Send(*tracker.SearchRequest) error
 	Recv() (*tracker.SearchResponse, error)
+	CloseSend() error
 }
 
 type searchServerStream interface {
@@ -35,7 +36,11 @@ func process(parent context.Context, client searchClientStream, server searchSer
 				return
 			default:
 				req, err := server.Recv()
-				if err != nil {
+				if err == io.EOF {
+					// let the tracker know no more requests are coming
+					_ = client.CloseSend()
+					return
+				} else if err != nil {
 					return
 				}
 
@@ -73,7 +78,7 @@ func (s *searchService) Search(server tracker.SearchService_SearchServer) error
 	ctx, cancel := context.WithCancel(server.Context())
 	defer cancel()
 
-	call, err := s.client.Search(ctx)
+	call, err := s.client.Search(forwardContext(ctx))
 	if err != nil {
 		return err
 	}
@@ -85,7 +90,7 @@ func (s *searchService) BreadthFirstSearch(server tracker.SearchService_BreadthF
 	ctx, cancel := context.WithCancel(server.Context())
 	defer cancel()
 
-	call, err := s.client.BreadthFirstSearch(ctx)
+	call, err := s.client.BreadthFirstSearch(forwardContext(ctx))
 	if err != nil {
 		return err
 	}
@@ -97,7 +102,7 @@ func (s *searchService) DepthFirstSearch(server tracker.SearchService_DepthFirst
 	ctx, cancel := context.WithCancel(server.Context())
 	defer cancel()
 
-	call, err := s.client.DepthFirstSearch(ctx)
+	call, err := s.client.DepthFirstSearch(forwardContext(ctx))
 	if err != nil {
 		return err
 	}
@@ -4,6 +4,11 @@ import (
 	"context"
 
 	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/delta"
+	"github.com/depscloud/depscloud/internal/paging"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func NewSourceServiceProxy(client tracker.SourceServiceClient) tracker.SourceServiceServer {
@@ -17,11 +22,29 @@ type sourceService struct {
 }
 
 func (s *sourceService) List(ctx context.Context, request *tracker.ListRequest) (*tracker.ListSourceResponse, error) {
-	return s.client.List(ctx, request)
+	var header metadata.MD
+	response, err := s.client.List(forwardContext(ctx), request, grpc.Header(&header))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := paging.RelayNextToken(ctx, header); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
 func (s *sourceService) Track(ctx context.Context, request *tracker.SourceRequest) (*tracker.TrackResponse, error) {
-	return s.client.Track(ctx, request)
+	var header metadata.MD
+	response, err := s.client.Track(forwardContext(ctx), request, grpc.Header(&header))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := delta.RelayState(ctx, header); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
 var _ tracker.SourceServiceServer = &sourceService{}
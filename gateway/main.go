@@ -1,19 +1,33 @@
 package main
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/depscloud/api/swagger"
 	"github.com/depscloud/api/v1alpha/extractor"
 	"github.com/depscloud/api/v1alpha/tracker"
 	"github.com/depscloud/depscloud/gateway/internal/checks"
+	"github.com/depscloud/depscloud/gateway/internal/federation"
+	"github.com/depscloud/depscloud/gateway/internal/openapi"
 	"github.com/depscloud/depscloud/gateway/internal/proxies"
+	"github.com/depscloud/depscloud/internal/audit"
 	"github.com/depscloud/depscloud/internal/client"
+	"github.com/depscloud/depscloud/internal/config"
+	"github.com/depscloud/depscloud/internal/diagnostics"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/middleware"
 	"github.com/depscloud/depscloud/internal/mux"
+	"github.com/depscloud/depscloud/internal/quotas"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/serviceaccounts"
+	"github.com/depscloud/depscloud/internal/telemetry"
+	"github.com/depscloud/depscloud/internal/tenants"
 
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 
@@ -32,22 +46,49 @@ var commit string
 var date string
 
 type gatewayConfig struct {
-	httpPort int
-	grpcPort int
+	server             *config.Server
+	trackerHTTPAddress string
 }
 
 func main() {
 	version := mux.Version{Version: version, Commit: commit, Date: date}
 	cfg := &gatewayConfig{
-		httpPort: 8080,
-		grpcPort: 8090,
+		server:             &config.Server{HTTPPort: 8080, GRPCPort: 8090, PortAlias: config.PortHTTP},
+		trackerHTTPAddress: "http://tracker:8080",
 	}
 
-	tlsConfig := &mux.TLSConfig{}
+	var serverFlags []cli.Flag
+	cfg.server, serverFlags = config.WithServerFlags(cfg.server)
+
+	tenancy, tenancyFlags := tenants.WithFlags(&tenants.Config{Mode: tenants.ModeNone})
+
+	rbacConfig, rbacFlags := rbac.WithFlags(&rbac.Config{
+		Mode:            rbac.ModeNone,
+		DefaultRole:     string(rbac.RoleViewer),
+		RefreshInterval: 30 * time.Second,
+	})
+
+	auditConfig, auditFlags := audit.WithFlags(&audit.Config{
+		Sink:          audit.SinkNone,
+		BufferSize:    1000,
+		FlushInterval: time.Second,
+	})
+
+	quotaConfig, quotaFlags := quotas.WithFlags(&quotas.Config{})
+
+	telemetryConfig, telemetryFlags := telemetry.WithFlags(telemetry.DefaultConfig())
+	loggingConfig, loggingFlags := logging.WithFlags(logging.DefaultConfig())
+	middlewareConfig, middlewareFlags := middleware.WithFlags(middleware.DefaultConfig())
+	diagnosticsConfig, diagnosticsFlags := diagnostics.WithFlags(&diagnostics.Config{})
+
+	federationConfig, federationFlags := federation.WithFlags(&federation.Config{
+		Name:         "primary",
+		AllowPartial: true,
+	})
 
 	extractorConfig, extractorFlags := client.WithFlags("extractor", &client.Config{
 		Address:       "extractor:8090",
-		ServiceConfig: client.DefaultServiceConfig,
+		ServiceConfig: client.ServiceConfig("cloud.deps.api.v1alpha.extractor.DependencyExtractor"),
 		LoadBalancer:  client.DefaultLoadBalancer,
 		TLS:           false,
 		TLSConfig:     &client.TLSConfig{},
@@ -61,47 +102,24 @@ func main() {
 		TLSConfig:     &client.TLSConfig{},
 	})
 
-	flags := []cli.Flag{
-		&cli.IntFlag{
-			Name:        "http-port",
-			Aliases:     []string{"port"},
-			Usage:       "the port to run http on",
-			Value:       cfg.httpPort,
-			Destination: &cfg.httpPort,
-			EnvVars:     []string{"HTTP_PORT"},
-		},
-		&cli.IntFlag{
-			Name:        "grpc-port",
-			Usage:       "the port to run grpc on",
-			Value:       cfg.grpcPort,
-			Destination: &cfg.grpcPort,
-			EnvVars:     []string{"GRPC_PORT"},
-		},
-		&cli.StringFlag{
-			Name:        "tls-key",
-			Usage:       "path to the file containing the TLS private key",
-			Value:       tlsConfig.KeyPath,
-			Destination: &tlsConfig.KeyPath,
-			EnvVars:     []string{"TLS_KEY_PATH"},
-		},
-		&cli.StringFlag{
-			Name:        "tls-cert",
-			Usage:       "path to the file containing the TLS certificate",
-			Value:       tlsConfig.CertPath,
-			Destination: &tlsConfig.CertPath,
-			EnvVars:     []string{"TLS_CERT_PATH"},
-		},
-		&cli.StringFlag{
-			Name:        "tls-ca",
-			Usage:       "path to the file containing the TLS certificate authority",
-			Value:       tlsConfig.CAPath,
-			Destination: &tlsConfig.CAPath,
-			EnvVars:     []string{"TLS_CA_PATH"},
-		},
-	}
-
+	flags := append(serverFlags, tenancyFlags...)
+	flags = append(flags, rbacFlags...)
+	flags = append(flags, auditFlags...)
+	flags = append(flags, quotaFlags...)
+	flags = append(flags, telemetryFlags...)
+	flags = append(flags, loggingFlags...)
+	flags = append(flags, middlewareFlags...)
+	flags = append(flags, diagnosticsFlags...)
 	flags = append(flags, extractorFlags...)
 	flags = append(flags, trackerFlags...)
+	flags = append(flags, federationFlags...)
+	flags = append(flags, &cli.StringFlag{
+		Name:        "tracker-http-address",
+		Usage:       "http address of the tracker, used to proxy graph queries, tombstone management, and graph export and import",
+		Value:       cfg.trackerHTTPAddress,
+		Destination: &cfg.trackerHTTPAddress,
+		EnvVars:     []string{"TRACKER_HTTP_ADDRESS"},
+	})
 
 	app := &cli.App{
 		Name:  "gateway",
@@ -118,7 +136,102 @@ func main() {
 		},
 		Flags: flags,
 		Action: func(c *cli.Context) error {
-			grpcServer, httpServer := mux.DefaultServers()
+			if err := logging.Setup("gateway", loggingConfig); err != nil {
+				return err
+			}
+			middleware.Setup(middlewareConfig)
+
+			if err := diagnostics.Serve(c.Context, diagnosticsConfig); err != nil {
+				return err
+			}
+
+			if err := tenancy.Validate(); err != nil {
+				return err
+			}
+
+			if err := rbacConfig.Validate(); err != nil {
+				return err
+			}
+
+			tracer, err := telemetry.NewTracer("gateway", version.Version, telemetryConfig, nil)
+			if err != nil {
+				return err
+			} else if tracer != nil {
+				telemetry.SetTracer(tracer)
+				go tracer.Run(c.Context)
+			}
+
+			metricExporter, err := telemetry.NewMetricExporter("gateway", version.Version, telemetryConfig, nil, nil)
+			if err != nil {
+				return err
+			}
+			go metricExporter.Run(c.Context)
+
+			serverTLSConfig, err := mux.LoadTLSConfig(cfg.server.TLS)
+			if err != nil {
+				return err
+			}
+
+			// the resolved tenant is passed along to the tracker, which must
+			// trust the gateway to act on behalf of other tenants
+			serverOptions, err := tenancy.ServerOptions(serverTLSConfig)
+			if err != nil {
+				return err
+			}
+
+			var trackerTLSConfig *tls.Config
+			trackerHTTPClient := &http.Client{Transport: telemetry.Transport(nil)}
+			if trackerConfig.TLS || trackerConfig.TLSConfig.CertPath != "" {
+				trackerTLSConfig, err = client.LoadTLSConfig(trackerConfig.TLSConfig)
+				if err != nil {
+					return err
+				}
+
+				trackerHTTPClient = &http.Client{
+					Transport: telemetry.Transport(&http.Transport{
+						Proxy:           http.ProxyFromEnvironment,
+						TLSClientConfig: trackerTLSConfig,
+					}),
+				}
+			}
+
+			// roles bound using the api are read from the tracker, which
+			// enforces them as well
+			authorizer, err := rbac.NewAuthorizer(rbacConfig, rbac.HTTPSource(cfg.trackerHTTPAddress, trackerHTTPClient))
+			if err != nil {
+				return err
+			}
+
+			// service account tokens are verified by the tracker and cached
+			// as long as role bindings
+			authorizer.WithVerifier(serviceaccounts.HTTPVerifier(cfg.trackerHTTPAddress, trackerHTTPClient, rbacConfig.RefreshInterval))
+
+			rbacOptions, err := authorizer.ServerOptions(serverTLSConfig)
+			if err != nil {
+				return err
+			}
+			// extractions and writes proxied to the tracker are audited once
+			// the caller is authorized
+			auditSink, err := auditConfig.NewSink(trackerHTTPClient)
+			if err != nil {
+				return err
+			}
+
+			auditLogger := audit.NewLogger("gateway", auditSink, auditConfig)
+			go auditLogger.Run(c.Context)
+
+			// quotas are counted per service account token, once the token
+			// has been verified
+			tokenQuotas, err := quotas.New(quotaConfig)
+			if err != nil {
+				return err
+			}
+
+			serverOptions = append(serverOptions, rbacOptions...)
+			serverOptions = append(serverOptions, tokenQuotas.ServerOptions()...)
+			serverOptions = append(serverOptions, auditLogger.ServerOptions()...)
+
+			grpcServer, httpServer := mux.DefaultServers(serverOptions...)
 			gatewayMux := runtime.NewServeMux()
 
 			ctx := context.Background()
@@ -136,23 +249,70 @@ func main() {
 			defer trackerConn.Close()
 
 			sourceService := tracker.NewSourceServiceClient(trackerConn)
-			tracker.RegisterSourceServiceServer(grpcServer, proxies.NewSourceServiceProxy(sourceService))
-			_ = tracker.RegisterSourceServiceHandlerClient(ctx, gatewayMux, sourceService)
-
 			moduleService := tracker.NewModuleServiceClient(trackerConn)
-			tracker.RegisterModuleServiceServer(grpcServer, proxies.NewModuleServiceProxy(moduleService))
-			_ = tracker.RegisterModuleServiceHandlerClient(ctx, gatewayMux, moduleService)
-
 			dependencyService := tracker.NewDependencyServiceClient(trackerConn)
-			tracker.RegisterDependencyServiceServer(grpcServer, proxies.NewDependencyServiceProxy(dependencyService))
-			_ = tracker.RegisterDependencyServiceHandlerClient(ctx, gatewayMux, dependencyService)
+			searchService := tracker.NewSearchServiceClient(trackerConn)
+
+			// read queries and searches span every federated tracker, while
+			// writes only go to the primary tracker
+			sourceClient, moduleClient, dependencyClient, searchClient := sourceService, moduleService, dependencyService, searchService
+
+			if federationConfig.Enabled() {
+				endpoints, err := federationConfig.Endpoints()
+				if err != nil {
+					return err
+				}
+
+				members := make([]*federation.Member, 0, len(endpoints))
+				for _, endpoint := range endpoints {
+					memberConfig := *trackerConfig
+					memberConfig.Address = endpoint.Address
+
+					memberConn, err := client.Connect(&memberConfig)
+					if err != nil {
+						return err
+					}
+					defer memberConn.Close()
+
+					members = append(members, federation.NewMember(endpoint.Name, memberConn))
+				}
+
+				federated := federation.New(federationConfig, federation.NewMember(federationConfig.Name, trackerConn), members...)
+				sourceClient = federated.Sources()
+				moduleClient = federated.Modules()
+				dependencyClient = federated.Dependencies()
+				searchClient = federated.Search()
+			}
+
+			tracker.RegisterSourceServiceServer(grpcServer, proxies.NewSourceServiceProxy(sourceClient))
+			_ = tracker.RegisterSourceServiceHandlerClient(ctx, gatewayMux, sourceClient)
+
+			tracker.RegisterModuleServiceServer(grpcServer, proxies.NewModuleServiceProxy(moduleClient))
+			_ = tracker.RegisterModuleServiceHandlerClient(ctx, gatewayMux, moduleClient)
+
+			tracker.RegisterDependencyServiceServer(grpcServer, proxies.NewDependencyServiceProxy(dependencyClient))
+			_ = tracker.RegisterDependencyServiceHandlerClient(ctx, gatewayMux, dependencyClient)
 
 			extractorService := extractor.NewDependencyExtractorClient(extractorConn)
 			extractor.RegisterDependencyExtractorServer(grpcServer, proxies.NewExtractorServiceProxy(extractorService))
 			_ = extractor.RegisterDependencyExtractorHandlerClient(ctx, gatewayMux, extractorService)
 
-			searchService := tracker.NewSearchServiceClient(trackerConn)
-			tracker.RegisterSearchServiceServer(grpcServer, proxies.NewSearchServiceProxy(searchService))
+			tracker.RegisterSearchServiceServer(grpcServer, proxies.NewSearchServiceProxy(searchClient))
+
+			queryProxy, err := proxies.NewQueryProxy(cfg.trackerHTTPAddress, trackerTLSConfig)
+			if err != nil {
+				return err
+			}
+			httpServer.Handle("/v1alpha/queries/", queryProxy)
+			httpServer.Handle("/v1alpha/tombstones/", queryProxy)
+			httpServer.Handle("/v1alpha/graph/", queryProxy)
+			httpServer.Handle("/v1alpha/labels/", queryProxy)
+			httpServer.Handle("/v1alpha/registry/", queryProxy)
+			httpServer.Handle("/v1alpha/sbom/", queryProxy)
+			httpServer.Handle("/v1alpha/dashboard/", queryProxy)
+			httpServer.Handle(rbac.RoutePrefix, queryProxy)
+			httpServer.Handle(audit.RoutePrefix, queryProxy)
+			httpServer.Handle(serviceaccounts.RoutePrefix, queryProxy)
 
 			httpServer.HandleFunc("/swagger/", func(writer http.ResponseWriter, request *http.Request) {
 				assetPath := strings.TrimPrefix(request.URL.Path, "/swagger/")
@@ -177,19 +337,37 @@ func main() {
 				_, _ = writer.Write(asset)
 			})
 
+			// the apis served by the gateway are described by a single
+			// document for client generators and api portals
+			openapiDocument, err := openapi.Merge(&openapi.Options{
+				Title:       "deps.cloud",
+				Version:     version.Version,
+				RBACMode:    rbacConfig.Mode,
+				TenancyMode: tenancy.Mode,
+			}, swagger.MustAsset("v1alpha/tracker/tracker.swagger.json"), swagger.MustAsset("v1alpha/extractor/extractor.swagger.json"))
+			if err != nil {
+				return err
+			}
+			httpServer.Handle(quotas.Route, tokenQuotas)
+			httpServer.Handle(openapi.Route, openapi.Handler(openapiDocument))
+
 			httpServer.Handle("/", gatewayMux)
 
-			return mux.Serve(grpcServer, httpServer, &mux.Config{
+			return mux.Serve(grpcServer, tenancy.Middleware(authorizer.Middleware(tokenQuotas.Middleware(auditLogger.Middleware(httpServer)))), &mux.Config{
 				Context:         c.Context,
-				BindAddressHTTP: fmt.Sprintf("0.0.0.0:%d", cfg.httpPort),
-				BindAddressGRPC: fmt.Sprintf("0.0.0.0:%d", cfg.grpcPort),
+				BindAddressHTTP: fmt.Sprintf("0.0.0.0:%d", cfg.server.HTTPPort),
+				BindAddressGRPC: fmt.Sprintf("0.0.0.0:%d", cfg.server.GRPCPort),
 				Checks:          checks.Checks(extractorService, sourceService, moduleService),
+				ServiceChecks:   checks.Services(),
 				Version:         &version,
-				TLSConfig:       tlsConfig,
+				TLSConfig:       cfg.server.TLS,
+				GRPCCredentials: tenancy.Mode == tenants.ModeCertificate || rbacConfig.Mode == rbac.ModeCertificate,
 			})
 		},
 	}
 
+	config.Apply(app)
+
 	if err := app.Run(os.Args); err != nil {
 		logrus.Fatal(err)
 	}
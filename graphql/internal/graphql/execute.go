@@ -0,0 +1,689 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Request is a query along with the operation to run and its variables.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Error is an error within a response. Errors raised while resolving a field
+// include the path to the field.
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Response is the result of a request. Data is only set when the request was
+// valid and could be executed.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// orderedMap is an object within the response, which keeps its keys in the
+// order they were selected.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: make(map[string]interface{})}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// execution holds the state of a single request.
+type execution struct {
+	schema    *Schema
+	document  *Document
+	variables map[string]interface{}
+	defined   map[string]bool
+	args      map[*Field]map[string]interface{}
+	errors    []*Error
+}
+
+// task is an object whose fields are yet to be resolved.
+type task struct {
+	object     *Object
+	source     interface{}
+	selections []Selection
+	result     *orderedMap
+	path       []interface{}
+}
+
+// resolution is a field that was resolved, but not yet completed.
+type resolution struct {
+	task       *task
+	key        string
+	fields     []*Field
+	definition *FieldDefinition
+	value      interface{}
+	err        error
+}
+
+// Execute runs the query of the request. Queries are validated before any of
+// their fields are resolved, and only query operations are supported.
+//
+// Fields are resolved one depth at a time. Every field of a depth is resolved
+// before any of the thunks they returned are called, so that loaders can
+// batch the keys requested across the whole depth. Errors leave the field
+// they were raised by null, rather than its parent, so output types are best
+// left nullable.
+func (s *Schema) Execute(ctx context.Context, req *Request) *Response {
+	document, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{asError(err)}}
+	}
+
+	operation, err := document.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{asError(err)}}
+	}
+
+	if operation.Type != "query" {
+		return &Response{Errors: []*Error{{
+			Message:   fmt.Sprintf("%s operations are not supported", operation.Type),
+			Locations: []Location{operation.Location},
+		}}}
+	}
+
+	e := &execution{
+		schema:    s,
+		document:  document,
+		variables: make(map[string]interface{}),
+		defined:   make(map[string]bool),
+		args:      make(map[*Field]map[string]interface{}),
+	}
+
+	e.coerceVariables(operation, req.Variables)
+	if len(e.errors) == 0 {
+		e.validate(s.query, operation.SelectionSet, make(map[string]bool))
+		e.validateResponseKeys(operation.SelectionSet)
+	}
+
+	if len(e.errors) > 0 {
+		return &Response{Errors: e.errors}
+	}
+
+	data := e.execute(ctx, operation)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func asError(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Message: err.Error()}
+}
+
+func (d *Document) operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document contains multiple operations")
+		}
+		return d.Operations[0], nil
+	}
+
+	for _, operation := range d.Operations {
+		if operation.Name == name {
+			return operation, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+func (e *execution) errorf(location Location, format string, args ...interface{}) {
+	e.errors = append(e.errors, &Error{
+		Message:   fmt.Sprintf(format, args...),
+		Locations: []Location{location},
+	})
+}
+
+func (e *execution) coerceVariables(operation *Operation, values map[string]interface{}) {
+	for _, definition := range operation.Variables {
+		name := definition.Name
+		if e.defined[name] {
+			e.errorf(definition.Location, "variable $%s is defined more than once", name)
+			continue
+		}
+		e.defined[name] = true
+
+		t, err := e.schema.inputType(definition.Type)
+		if err != nil {
+			e.errorf(definition.Location, "variable $%s: %s", name, err.Error())
+			continue
+		}
+
+		value, provided := values[name]
+		if !provided {
+			if definition.Default == nil {
+				if _, ok := t.(*NonNull); ok {
+					e.errorf(definition.Location, "variable $%s of type %s is required", name, t)
+				}
+				continue
+			}
+
+			value, err = e.literal(definition.Default)
+			if err != nil {
+				e.errorf(definition.Location, "variable $%s: %s", name, err.Error())
+				continue
+			}
+		}
+
+		coerced, err := coerceInput(t, value)
+		if err != nil {
+			e.errorf(definition.Location, "variable $%s: %s", name, err.Error())
+			continue
+		}
+		e.variables[name] = coerced
+	}
+}
+
+// literal converts a value within the query into the form variables are
+// given in, substituting the variables it refers to.
+func (e *execution) literal(value Value) (interface{}, error) {
+	switch v := value.(type) {
+	case Variable:
+		if !e.defined[string(v)] {
+			return nil, fmt.Errorf("variable $%s is not defined", v)
+		}
+		return e.variables[string(v)], nil
+
+	case EnumValue:
+		return nil, fmt.Errorf("enum value %s is not supported", v)
+
+	case ListValue:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if items[i], err = e.literal(item); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+
+	case ObjectValue:
+		return nil, fmt.Errorf("input objects are not supported")
+	}
+
+	return value, nil
+}
+
+// coerceInput converts a value into the type of an argument or variable.
+// Single values are accepted where a list is expected.
+func coerceInput(t Type, value interface{}) (interface{}, error) {
+	if nonNull, ok := t.(*NonNull); ok {
+		if value == nil {
+			return nil, fmt.Errorf("expected a non-null %s", nonNull.Of)
+		}
+		return coerceInput(nonNull.Of, value)
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	switch v := t.(type) {
+	case *List:
+		items, ok := value.([]interface{})
+		if !ok {
+			item, err := coerceInput(v.Of, value)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+
+		coerced := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if coerced[i], err = coerceInput(v.Of, item); err != nil {
+				return nil, err
+			}
+		}
+		return coerced, nil
+
+	case *Scalar:
+		return v.Parse(value)
+	}
+
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// included evaluates the @skip and @include directives.
+func (e *execution) included(directives []*Directive) bool {
+	for _, directive := range directives {
+		for _, arg := range directive.Arguments {
+			value, _ := e.literal(arg.Value)
+
+			if (directive.Name == "skip" && value == true) || (directive.Name == "include" && value == false) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (e *execution) validateDirectives(directives []*Directive) {
+	for _, directive := range directives {
+		if directive.Name != "skip" && directive.Name != "include" {
+			e.errorf(directive.Location, "unknown directive @%s", directive.Name)
+			continue
+		}
+
+		if len(directive.Arguments) != 1 || directive.Arguments[0].Name != "if" {
+			e.errorf(directive.Location, "directive @%s requires a single if argument", directive.Name)
+			continue
+		}
+
+		value, err := e.literal(directive.Arguments[0].Value)
+		if err == nil {
+			_, err = coerceInput(&NonNull{Of: Boolean}, value)
+		}
+
+		if err != nil {
+			e.errorf(directive.Location, "directive @%s: %s", directive.Name, err.Error())
+		}
+	}
+}
+
+// validate checks the selections against the object and coerces the
+// arguments of the fields they select.
+func (e *execution) validate(object *Object, selections []Selection, visiting map[string]bool) {
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *Field:
+			e.validateDirectives(s.Directives)
+			e.validateField(object, s, visiting)
+
+		case *FragmentSpread:
+			e.validateDirectives(s.Directives)
+
+			fragment, ok := e.document.Fragments[s.Name]
+			if !ok {
+				e.errorf(s.Location, "unknown fragment %s", s.Name)
+				continue
+			} else if visiting[s.Name] {
+				e.errorf(s.Location, "fragment %s spreads itself", s.Name)
+				continue
+			}
+
+			if !e.validateTypeCondition(object, fragment.TypeCondition, s.Location) {
+				continue
+			}
+
+			visiting[s.Name] = true
+			e.validate(object, fragment.SelectionSet, visiting)
+			delete(visiting, s.Name)
+
+		case *InlineFragment:
+			e.validateDirectives(s.Directives)
+
+			if s.TypeCondition == "" || e.validateTypeCondition(object, s.TypeCondition, s.Location) {
+				e.validate(object, s.SelectionSet, visiting)
+			}
+		}
+	}
+}
+
+func (e *execution) validateTypeCondition(object *Object, typeCondition string, location Location) bool {
+	if _, ok := e.schema.types[typeCondition]; !ok {
+		e.errorf(location, "unknown type %s", typeCondition)
+		return false
+	} else if typeCondition != object.Name {
+		e.errorf(location, "fragment on %s cannot be spread within %s", typeCondition, object.Name)
+		return false
+	}
+	return true
+}
+
+func (e *execution) validateField(object *Object, field *Field, visiting map[string]bool) {
+	if field.Name == "__typename" {
+		if len(field.Arguments) > 0 || len(field.SelectionSet) > 0 {
+			e.errorf(field.Location, "__typename does not accept arguments or selections")
+		}
+		return
+	}
+
+	definition := object.Field(field.Name)
+	if definition == nil {
+		e.errorf(field.Location, "cannot query field %s on type %s", field.Name, object.Name)
+		return
+	}
+
+	args := make(map[string]interface{}, len(definition.Args))
+	provided := make(map[string]bool, len(field.Arguments))
+	for _, arg := range field.Arguments {
+		var argDefinition *ArgumentDefinition
+		for _, candidate := range definition.Args {
+			if candidate.Name == arg.Name {
+				argDefinition = candidate
+			}
+		}
+
+		if argDefinition == nil {
+			e.errorf(arg.Location, "unknown argument %s on field %s.%s", arg.Name, object.Name, field.Name)
+			continue
+		} else if provided[arg.Name] {
+			e.errorf(arg.Location, "argument %s is provided more than once", arg.Name)
+			continue
+		}
+		provided[arg.Name] = true
+
+		value, err := e.literal(arg.Value)
+		if err == nil {
+			value, err = coerceInput(argDefinition.Type, value)
+		}
+
+		if err != nil {
+			e.errorf(arg.Location, "argument %s: %s", arg.Name, err.Error())
+			continue
+		}
+
+		if value != nil {
+			args[arg.Name] = value
+		}
+	}
+
+	for _, argDefinition := range definition.Args {
+		if _, ok := args[argDefinition.Name]; ok || provided[argDefinition.Name] {
+			continue
+		}
+
+		if argDefinition.DefaultValue != nil {
+			args[argDefinition.Name] = argDefinition.DefaultValue
+		} else if _, ok := argDefinition.Type.(*NonNull); ok {
+			e.errorf(field.Location, "argument %s of type %s is required", argDefinition.Name, argDefinition.Type)
+		}
+	}
+
+	e.args[field] = args
+
+	switch t := named(definition.Type).(type) {
+	case *Scalar:
+		if len(field.SelectionSet) > 0 {
+			e.errorf(field.Location, "field %s of type %s must not have a selection", field.Name, definition.Type)
+		}
+
+	case *Object:
+		if len(field.SelectionSet) == 0 {
+			e.errorf(field.Location, "field %s of type %s must have a selection", field.Name, definition.Type)
+			return
+		}
+
+		e.validate(t, field.SelectionSet, visiting)
+		e.validateResponseKeys(field.SelectionSet)
+	}
+}
+
+// validateResponseKeys ensures the same response key isn't used for different
+// fields. It's only called once the selections are known to be valid.
+func (e *execution) validateResponseKeys(selections []Selection) {
+	if len(e.errors) > 0 {
+		return
+	}
+
+	keys, grouped := e.collectFields(selections)
+	for _, key := range keys {
+		for _, other := range grouped[key][1:] {
+			if other.Name != grouped[key][0].Name {
+				e.errorf(other.Location, "%s selects both %s and %s", key, grouped[key][0].Name, other.Name)
+			}
+		}
+	}
+}
+
+// collectFields groups the fields that are selected by their response key,
+// expanding fragments and evaluating directives.
+func (e *execution) collectFields(selections []Selection) ([]string, map[string][]*Field) {
+	keys := make([]string, 0)
+	grouped := make(map[string][]*Field)
+	visited := make(map[string]bool)
+
+	var collect func(selections []Selection)
+	collect = func(selections []Selection) {
+		for _, selection := range selections {
+			switch s := selection.(type) {
+			case *Field:
+				if !e.included(s.Directives) {
+					continue
+				}
+
+				key := s.ResponseKey()
+				if _, ok := grouped[key]; !ok {
+					keys = append(keys, key)
+				}
+				grouped[key] = append(grouped[key], s)
+
+			case *FragmentSpread:
+				if !e.included(s.Directives) || visited[s.Name] {
+					continue
+				}
+				visited[s.Name] = true
+				collect(e.document.Fragments[s.Name].SelectionSet)
+
+			case *InlineFragment:
+				if e.included(s.Directives) {
+					collect(s.SelectionSet)
+				}
+			}
+		}
+	}
+
+	collect(selections)
+	return keys, grouped
+}
+
+func (e *execution) execute(ctx context.Context, operation *Operation) *orderedMap {
+	root := &task{
+		object:     e.schema.query,
+		selections: operation.SelectionSet,
+		result:     newOrderedMap(),
+	}
+
+	for level := []*task{root}; len(level) > 0; {
+		resolutions := make([]*resolution, 0)
+		for _, t := range level {
+			keys, grouped := e.collectFields(t.selections)
+
+			for _, key := range keys {
+				field := grouped[key][0]
+				if field.Name == "__typename" {
+					t.result.set(key, t.object.Name)
+					continue
+				}
+
+				r := &resolution{
+					task:       t,
+					key:        key,
+					fields:     grouped[key],
+					definition: t.object.Field(field.Name),
+				}
+
+				r.value, r.err = e.resolve(ResolveParams{Context: ctx, Source: t.source, Args: e.args[field]}, r.definition)
+				t.result.set(key, nil)
+				resolutions = append(resolutions, r)
+			}
+		}
+
+		next := make([]*task, 0)
+		for _, r := range resolutions {
+			value, err := r.value, r.err
+			for err == nil {
+				thunk, ok := value.(Thunk)
+				if !ok {
+					break
+				}
+				value, err = e.call(thunk)
+			}
+
+			path := append(append([]interface{}{}, r.task.path...), r.key)
+			if err != nil {
+				e.fieldError(err, r.fields, path)
+				continue
+			}
+
+			r.task.result.set(r.key, e.complete(r.definition.Type, r.fields, value, path, &next))
+		}
+
+		level = next
+	}
+
+	return root.result
+}
+
+// resolve calls the resolver of the field, recovering from panics so that one
+// field can't fail the whole request. Fields without a resolver are read from
+// their source when it's a map.
+func (e *execution) resolve(params ResolveParams, definition *FieldDefinition) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while resolving %s: %v", definition.Name, r)
+		}
+	}()
+
+	if definition.Resolve == nil {
+		if source, ok := params.Source.(map[string]interface{}); ok {
+			return source[definition.Name], nil
+		}
+		return nil, nil
+	}
+
+	return definition.Resolve(params)
+}
+
+func (e *execution) call(thunk Thunk) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while resolving: %v", r)
+		}
+	}()
+
+	return thunk()
+}
+
+func (e *execution) fieldError(err error, fields []*Field, path []interface{}) {
+	e.errors = append(e.errors, &Error{
+		Message:   err.Error(),
+		Locations: []Location{fields[0].Location},
+		Path:      path,
+	})
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Func:
+		return v.IsNil()
+	}
+	return false
+}
+
+// complete converts the value of a field into its output. Objects are added
+// to next to have their fields resolved with the following depth.
+func (e *execution) complete(t Type, fields []*Field, value interface{}, path []interface{}, next *[]*task) interface{} {
+	if nonNull, ok := t.(*NonNull); ok {
+		if isNil(value) {
+			e.fieldError(fmt.Errorf("%s cannot be null", fields[0].Name), fields, path)
+			return nil
+		}
+		return e.complete(nonNull.Of, fields, value, path, next)
+	}
+
+	if isNil(value) {
+		return nil
+	}
+
+	switch v := t.(type) {
+	case *List:
+		items := reflect.ValueOf(value)
+		if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+			e.fieldError(fmt.Errorf("%s is not a list", fields[0].Name), fields, path)
+			return nil
+		}
+
+		completed := make([]interface{}, items.Len())
+		for i := range completed {
+			itemPath := append(append([]interface{}{}, path...), i)
+			completed[i] = e.complete(v.Of, fields, items.Index(i).Interface(), itemPath, next)
+		}
+		return completed
+
+	case *Scalar:
+		serialized, err := v.Serialize(value)
+		if err != nil {
+			e.fieldError(err, fields, path)
+			return nil
+		}
+		return serialized
+
+	case *Object:
+		selections := make([]Selection, 0)
+		for _, field := range fields {
+			selections = append(selections, field.SelectionSet...)
+		}
+
+		result := newOrderedMap()
+		*next = append(*next, &task{
+			object:     v,
+			source:     value,
+			selections: selections,
+			result:     result,
+			path:       path,
+		})
+		return result
+	}
+
+	return nil
+}
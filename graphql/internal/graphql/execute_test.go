@@ -0,0 +1,271 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type person struct {
+	name    string
+	friends []string
+}
+
+func testSchema(t *testing.T, batches *[][]interface{}) (*Schema, ContextFunc) {
+	people := map[string]*person{
+		"ada":   {name: "ada", friends: []string{"grace", "linus"}},
+		"grace": {name: "grace", friends: []string{"ada"}},
+		"linus": {name: "linus", friends: []string{"ada", "grace"}},
+	}
+
+	newLoader := func() *Loader {
+		return NewLoader(func(ctx context.Context, keys []interface{}) []*Result {
+			*batches = append(*batches, keys)
+
+			results := make([]*Result, len(keys))
+			for i, key := range keys {
+				p, ok := people[key.(string)]
+				if !ok {
+					results[i] = &Result{Err: fmt.Errorf("unknown person %s", key)}
+					continue
+				}
+				results[i] = &Result{Value: p}
+			}
+			return results
+		})
+	}
+
+	type loaderKey struct{}
+	loader := func(ctx context.Context) *Loader {
+		return ctx.Value(loaderKey{}).(*Loader)
+	}
+
+	object := &Object{Name: "Person"}
+	object.Fields = []*FieldDefinition{
+		{
+			Name: "name",
+			Type: &NonNull{Of: String},
+			Resolve: func(params ResolveParams) (interface{}, error) {
+				return params.Source.(*person).name, nil
+			},
+		},
+		{
+			Name: "friends",
+			Type: &List{Of: object},
+			Resolve: func(params ResolveParams) (interface{}, error) {
+				friends := params.Source.(*person).friends
+				thunks := make([]Thunk, len(friends))
+				for i, friend := range friends {
+					thunks[i] = loader(params.Context).Load(params.Context, friend)
+				}
+
+				return Thunk(func() (interface{}, error) {
+					result := make([]interface{}, len(thunks))
+					for i, thunk := range thunks {
+						var err error
+						if result[i], err = thunk(); err != nil {
+							return nil, err
+						}
+					}
+					return result, nil
+				}), nil
+			},
+		},
+		{
+			Name: "fail",
+			Type: String,
+			Resolve: func(params ResolveParams) (interface{}, error) {
+				return nil, fmt.Errorf("failed")
+			},
+		},
+	}
+
+	query := &Object{
+		Name: "Query",
+		Fields: []*FieldDefinition{
+			{
+				Name: "person",
+				Type: object,
+				Args: []*ArgumentDefinition{
+					{Name: "name", Type: &NonNull{Of: String}},
+				},
+				Resolve: func(params ResolveParams) (interface{}, error) {
+					return loader(params.Context).Load(params.Context, params.Args["name"]), nil
+				},
+			},
+			{
+				Name: "echo",
+				Type: &List{Of: Int},
+				Args: []*ArgumentDefinition{
+					{Name: "values", Type: &List{Of: Int}, DefaultValue: []interface{}{1}},
+				},
+				Resolve: func(params ResolveParams) (interface{}, error) {
+					return params.Args["values"], nil
+				},
+			},
+		},
+	}
+
+	schema, err := NewSchema(query)
+	require.Nil(t, err)
+
+	// a loader per request
+	contextFunc := func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, loaderKey{}, newLoader())
+	}
+
+	return schema, contextFunc
+}
+
+func execute(t *testing.T, schema *Schema, contextFunc ContextFunc, query string, variables map[string]interface{}) (string, []*Error) {
+	response := schema.Execute(contextFunc(context.Background()), &Request{Query: query, Variables: variables})
+	if response.Data == nil {
+		return "", response.Errors
+	}
+
+	data, err := json.Marshal(response.Data)
+	require.Nil(t, err)
+	return string(data), response.Errors
+}
+
+func TestExecute(t *testing.T) {
+	batches := make([][]interface{}, 0)
+	schema, contextFunc := testSchema(t, &batches)
+
+	data, errs := execute(t, schema, contextFunc, `
+		query People($name: String!) {
+			person(name: $name) {
+				__typename
+				name
+				... on Person { friends { ...names } }
+			}
+		}
+
+		fragment names on Person {
+			name
+			friends { who: name }
+		}
+	`, map[string]interface{}{"name": "ada"})
+	require.Empty(t, errs)
+	require.Equal(t, `{"person":{"__typename":"Person","name":"ada","friends":[`+
+		`{"name":"grace","friends":[{"who":"ada"}]},`+
+		`{"name":"linus","friends":[{"who":"ada"},{"who":"grace"}]}]}}`, data)
+
+	// each depth is loaded with a single batch, skipping cached keys
+	require.Equal(t, [][]interface{}{{"ada"}, {"grace", "linus"}}, batches)
+
+	data, errs = execute(t, schema, contextFunc, `{ a: person(name: "ada") { name fail } b: person(name: "bob") { name } }`, nil)
+	require.Equal(t, `{"a":{"name":"ada","fail":null},"b":null}`, data)
+	require.Len(t, errs, 2)
+	require.Equal(t, "unknown person bob", errs[0].Message)
+	require.Equal(t, []interface{}{"b"}, errs[0].Path)
+	require.Equal(t, "failed", errs[1].Message)
+	require.Equal(t, []interface{}{"a", "fail"}, errs[1].Path)
+
+	data, errs = execute(t, schema, contextFunc, `query ($skip: Boolean = true) {
+		echo
+		list: echo(values: [1, 2])
+		single: echo(values: 3)
+		skipped: echo @skip(if: $skip)
+		included: echo @include(if: true)
+	}`, nil)
+	require.Empty(t, errs)
+	require.Equal(t, `{"echo":[1],"list":[1,2],"single":[3],"included":[1]}`, data)
+}
+
+func TestExecuteErrors(t *testing.T) {
+	batches := make([][]interface{}, 0)
+	schema, contextFunc := testSchema(t, &batches)
+
+	invalid := func(query string, variables map[string]interface{}, message string) {
+		_, errs := execute(t, schema, contextFunc, query, variables)
+		require.NotEmpty(t, errs, query)
+		require.Contains(t, errs[0].Message, message, query)
+	}
+
+	invalid(`{ person(name: "ada") { name `, nil, "syntax error: unexpected end of document")
+	invalid(`{ person(name: "ada") { age } }`, nil, "cannot query field age on type Person")
+	invalid(`{ person(name: "ada") }`, nil, "must have a selection")
+	invalid(`{ person(name: "ada") { name { first } } }`, nil, "must not have a selection")
+	invalid(`{ person { name } }`, nil, "argument name of type String! is required")
+	invalid(`{ person(name: 1) { name } }`, nil, "String cannot represent 1")
+	invalid(`{ person(name: "ada", age: 1) { name } }`, nil, "unknown argument age")
+	invalid(`{ person(name: $name) { name } }`, nil, "variable $name is not defined")
+	invalid(`query ($name: String!) { person(name: $name) { name } }`, nil, "variable $name of type String! is required")
+	invalid(`{ person(name: "ada") { ...missing } }`, nil, "unknown fragment missing")
+	invalid(`{ person(name: "ada") { ...a } } fragment a on Person { ...a }`, nil, "fragment a spreads itself")
+	invalid(`{ person(name: "ada") { ... on Query { echo } } }`, nil, "fragment on Query cannot be spread within Person")
+	invalid(`{ person(name: "ada") { name name: fail } }`, nil, "name selects both name and fail")
+	invalid(`{ echo echo: person(name: "ada") { name } }`, nil, "echo selects both echo and person")
+	invalid(`{ echo @defer }`, nil, "unknown directive @defer")
+	invalid(`mutation { echo }`, nil, "mutation operations are not supported")
+	invalid(`query a { echo } query b { echo }`, nil, "operationName is required")
+}
+
+func TestHandler(t *testing.T) {
+	batches := make([][]interface{}, 0)
+	schema, contextFunc := testSchema(t, &batches)
+	handler := NewHandler(schema, contextFunc)
+
+	serve := func(r *http.Request) (int, map[string]interface{}) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		body := make(map[string]interface{})
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(&body))
+		return recorder.Code, body
+	}
+
+	status, body := serve(httptest.NewRequest(http.MethodPost, "/graphql",
+		strings.NewReader(`{"query":"query ($n: String!) { person(name: $n) { name } }","variables":{"n":"ada"}}`)))
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, map[string]interface{}{"person": map[string]interface{}{"name": "ada"}}, body["data"])
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{ echo }`))
+	r.Header.Set("Content-Type", "application/graphql")
+	status, body = serve(r)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, map[string]interface{}{"echo": []interface{}{float64(1)}}, body["data"])
+
+	params := url.Values{"query": {"query ($v: [Int]) { echo(values: $v) }"}, "variables": {`{"v":[4,5]}`}}
+	status, body = serve(httptest.NewRequest(http.MethodGet, "/graphql?"+params.Encode(), nil))
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, map[string]interface{}{"echo": []interface{}{float64(4), float64(5)}}, body["data"])
+
+	status, body = serve(httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("{ nope }"), nil))
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Nil(t, body["data"])
+	require.NotEmpty(t, body["errors"])
+
+	status, _ = serve(httptest.NewRequest(http.MethodDelete, "/graphql", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, status)
+}
+
+func TestSDL(t *testing.T) {
+	batches := make([][]interface{}, 0)
+	schema, _ := testSchema(t, &batches)
+
+	require.Equal(t, `schema {
+  query: Query
+}
+
+type Query {
+  person(name: String!): Person
+  echo(values: [Int] = [1]): [Int]
+}
+
+type Person {
+  name: String!
+  friends: [Person]
+  fail: String
+}
+`, schema.SDL())
+}
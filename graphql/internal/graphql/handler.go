@@ -0,0 +1,94 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+)
+
+// ContextFunc prepares the context of a request, such as by adding the
+// loaders the resolvers of a request share.
+type ContextFunc func(ctx context.Context) context.Context
+
+// NewHandler serves the schema over http. Queries are accepted as a json
+// encoded Request in the body of a POST, as the body of a POST with the
+// application/graphql content type, or as the query, operationName, and
+// variables parameters of a GET.
+func NewHandler(schema *Schema, contextFunc ContextFunc) http.Handler {
+	return &handler{schema: schema, contextFunc: contextFunc}
+}
+
+type handler struct {
+	schema      *Schema
+	contextFunc ContextFunc
+}
+
+func writeResponse(w http.ResponseWriter, status int, response *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func requestError(w http.ResponseWriter, status int, err error) {
+	writeResponse(w, status, &Response{Errors: []*Error{{Message: err.Error()}}})
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := &Request{}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				requestError(w, http.StatusBadRequest, fmt.Errorf("failed to parse variables"))
+				return
+			}
+		}
+
+	case http.MethodPost:
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		if mediaType == "application/graphql" {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				requestError(w, http.StatusBadRequest, fmt.Errorf("failed to read query"))
+				return
+			}
+			req.Query = string(body)
+		} else if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			requestError(w, http.StatusBadRequest, fmt.Errorf("failed to parse request"))
+			return
+		}
+
+	default:
+		requestError(w, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	if req.Query == "" {
+		requestError(w, http.StatusBadRequest, fmt.Errorf("query is required"))
+		return
+	}
+
+	ctx := r.Context()
+	if h.contextFunc != nil {
+		ctx = h.contextFunc(ctx)
+	}
+
+	response := h.schema.Execute(ctx, req)
+
+	// requests that couldn't be executed are the caller's fault
+	status := http.StatusOK
+	if response.Data == nil {
+		status = http.StatusBadRequest
+	}
+
+	writeResponse(w, status, response)
+}
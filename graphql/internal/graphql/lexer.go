@@ -0,0 +1,298 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+// Location is the line and column of a token in a query, starting from 1.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type token struct {
+	kind     tokenKind
+	value    string
+	location Location
+}
+
+// lexer splits a query into tokens, skipping whitespace, commas, and
+// comments, which are insignificant in GraphQL.
+type lexer struct {
+	input  string
+	pos    int
+	line   int
+	column int
+}
+
+func newLexer(input string) *lexer {
+	// a leading byte order mark is ignored
+	return &lexer{input: strings.TrimPrefix(input, "\ufeff"), line: 1, column: 1}
+}
+
+func (l *lexer) errorf(location Location, format string, args ...interface{}) error {
+	return &Error{
+		Message:   "syntax error: " + fmt.Sprintf(format, args...),
+		Locations: []Location{location},
+	}
+}
+
+func (l *lexer) advance(n int) {
+	for _, r := range l.input[l.pos : l.pos+n] {
+		if r == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+	}
+	l.pos += n
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.input) {
+		switch c := l.input[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.advance(1)
+		case c == '#':
+			end := strings.IndexAny(l.input[l.pos:], "\r\n")
+			if end < 0 {
+				end = len(l.input) - l.pos
+			}
+			l.advance(end)
+		default:
+			return
+		}
+	}
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// next returns the next token in the input.
+func (l *lexer) next() (*token, error) {
+	l.skipIgnored()
+
+	location := Location{Line: l.line, Column: l.column}
+	if l.pos >= len(l.input) {
+		return &token{kind: tokenEOF, location: location}, nil
+	}
+
+	c := l.input[l.pos]
+	switch {
+	case strings.IndexByte("!$()=:@[]{}|&", c) >= 0:
+		l.advance(1)
+		return &token{kind: tokenPunctuator, value: string(c), location: location}, nil
+
+	case c == '.':
+		if !strings.HasPrefix(l.input[l.pos:], "...") {
+			return nil, l.errorf(location, "unexpected %q", ".")
+		}
+		l.advance(3)
+		return &token{kind: tokenPunctuator, value: "...", location: location}, nil
+
+	case isNameStart(c):
+		end := l.pos + 1
+		for end < len(l.input) && (isNameStart(l.input[end]) || isDigit(l.input[end])) {
+			end++
+		}
+
+		value := l.input[l.pos:end]
+		l.advance(end - l.pos)
+		return &token{kind: tokenName, value: value, location: location}, nil
+
+	case c == '-' || isDigit(c):
+		return l.number(location)
+
+	case c == '"':
+		if strings.HasPrefix(l.input[l.pos:], `"""`) {
+			return l.blockString(location)
+		}
+		return l.string(location)
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.input[l.pos:])
+	return nil, l.errorf(location, "unexpected %q", r)
+}
+
+func (l *lexer) number(location Location) (*token, error) {
+	end := l.pos
+	if l.input[end] == '-' {
+		end++
+	}
+
+	digits := func() int {
+		start := end
+		for end < len(l.input) && isDigit(l.input[end]) {
+			end++
+		}
+		return end - start
+	}
+
+	if digits() == 0 {
+		return nil, l.errorf(location, "invalid number")
+	}
+
+	kind := tokenInt
+	if end < len(l.input) && l.input[end] == '.' {
+		end++
+		kind = tokenFloat
+		if digits() == 0 {
+			return nil, l.errorf(location, "invalid number")
+		}
+	}
+
+	if end < len(l.input) && (l.input[end] == 'e' || l.input[end] == 'E') {
+		end++
+		kind = tokenFloat
+		if end < len(l.input) && (l.input[end] == '+' || l.input[end] == '-') {
+			end++
+		}
+		if digits() == 0 {
+			return nil, l.errorf(location, "invalid number")
+		}
+	}
+
+	if end < len(l.input) && (isNameStart(l.input[end]) || l.input[end] == '.') {
+		return nil, l.errorf(location, "invalid number")
+	}
+
+	value := l.input[l.pos:end]
+	l.advance(end - l.pos)
+	return &token{kind: kind, value: value, location: location}, nil
+}
+
+func (l *lexer) string(location Location) (*token, error) {
+	value := &strings.Builder{}
+
+	end := l.pos + 1
+	for end < len(l.input) {
+		c := l.input[end]
+		switch {
+		case c == '"':
+			l.advance(end + 1 - l.pos)
+			return &token{kind: tokenString, value: value.String(), location: location}, nil
+
+		case c == '\n' || c == '\r':
+			return nil, l.errorf(location, "unterminated string")
+
+		case c == '\\':
+			if end+1 >= len(l.input) {
+				return nil, l.errorf(location, "unterminated string")
+			}
+
+			escaped := l.input[end+1]
+			switch escaped {
+			case '"', '\\', '/':
+				value.WriteByte(escaped)
+			case 'b':
+				value.WriteByte('\b')
+			case 'f':
+				value.WriteByte('\f')
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			case 'u':
+				if end+6 > len(l.input) {
+					return nil, l.errorf(location, "invalid unicode escape")
+				}
+
+				code, err := strconv.ParseUint(l.input[end+2:end+6], 16, 32)
+				if err != nil {
+					return nil, l.errorf(location, "invalid unicode escape")
+				}
+				value.WriteRune(rune(code))
+				end += 4
+			default:
+				return nil, l.errorf(location, "invalid escape \\%c", escaped)
+			}
+			end += 2
+
+		default:
+			value.WriteByte(c)
+			end++
+		}
+	}
+
+	return nil, l.errorf(location, "unterminated string")
+}
+
+// blockString reads a """ delimited string, removing the indentation shared
+// by its lines along with its leading and trailing blank lines.
+func (l *lexer) blockString(location Location) (*token, error) {
+	raw := &strings.Builder{}
+
+	end := l.pos + 3
+	for end < len(l.input) {
+		switch {
+		case strings.HasPrefix(l.input[end:], `\"""`):
+			raw.WriteString(`"""`)
+			end += 4
+		case strings.HasPrefix(l.input[end:], `"""`):
+			l.advance(end + 3 - l.pos)
+			return &token{kind: tokenString, value: blockStringValue(raw.String()), location: location}, nil
+		default:
+			raw.WriteByte(l.input[end])
+			end++
+		}
+	}
+
+	return nil, l.errorf(location, "unterminated string")
+}
+
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(raw), "\n")
+
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,87 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Result is the value loaded for a key.
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// BatchFunc loads the values of many keys at once, returning a result for
+// each key in the same order.
+type BatchFunc func(ctx context.Context, keys []interface{}) []*Result
+
+// Loader batches and caches the loading of keys. Keys requested through Load
+// are collected until one of the returned thunks is called, which loads all of
+// the collected keys with a single call to the BatchFunc. Since results are
+// cached for the life of the loader, loaders should be created per request.
+type Loader struct {
+	batch BatchFunc
+
+	mu      sync.Mutex
+	cache   map[interface{}]*Result
+	pending []interface{}
+	queued  map[interface{}]bool
+}
+
+// NewLoader returns a loader that loads keys using the batch function. Keys
+// must be comparable.
+func NewLoader(batch BatchFunc) *Loader {
+	return &Loader{
+		batch:  batch,
+		cache:  make(map[interface{}]*Result),
+		queued: make(map[interface{}]bool),
+	}
+}
+
+// Load queues the key to be loaded, returning a thunk of its value.
+func (l *Loader) Load(ctx context.Context, key interface{}) Thunk {
+	l.mu.Lock()
+	if _, ok := l.cache[key]; !ok && !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		result, ok := l.cache[key]
+		if !ok {
+			l.dispatch(ctx)
+			result = l.cache[key]
+		}
+
+		return result.Value, result.Err
+	}
+}
+
+// dispatch loads the pending keys. It's called with the lock held.
+func (l *Loader) dispatch(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+	l.queued = make(map[interface{}]bool)
+
+	results := l.batch(ctx, keys)
+	if len(results) != len(keys) {
+		err := fmt.Errorf("loaded %d results for %d keys", len(results), len(keys))
+
+		results = make([]*Result, len(keys))
+		for i := range results {
+			results[i] = &Result{Err: err}
+		}
+	}
+
+	for i, key := range keys {
+		if results[i] == nil {
+			results[i] = &Result{}
+		}
+		l.cache[key] = results[i]
+	}
+}
@@ -0,0 +1,611 @@
+package graphql
+
+import (
+	"strconv"
+)
+
+// Document is a parsed query, holding its operations and fragments.
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query, mutation, or subscription. Queries written without
+// the query keyword have an empty name.
+type Operation struct {
+	Type         string
+	Name         string
+	Variables    []*VariableDefinition
+	Directives   []*Directive
+	SelectionSet []Selection
+	Location     Location
+}
+
+// VariableDefinition declares a variable of an operation.
+type VariableDefinition struct {
+	Name     string
+	Type     *TypeRef
+	Default  Value
+	Location Location
+}
+
+// TypeRef refers to a type by name. Lists have an Elem instead of a name.
+type TypeRef struct {
+	Name    string
+	Elem    *TypeRef
+	NonNull bool
+}
+
+func (t *TypeRef) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	}
+
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+// Selection is a Field, FragmentSpread, or InlineFragment.
+type Selection interface {
+	location() Location
+}
+
+// Field selects a field of an object, optionally under an alias.
+type Field struct {
+	Alias        string
+	Name         string
+	Arguments    []*Argument
+	Directives   []*Directive
+	SelectionSet []Selection
+	Location     Location
+}
+
+// ResponseKey is the key the field is returned under.
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+func (f *Field) location() Location { return f.Location }
+
+// FragmentSpread includes the selections of a named fragment.
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Location   Location
+}
+
+func (f *FragmentSpread) location() Location { return f.Location }
+
+// InlineFragment includes its selections when the object matches its type
+// condition, or always when it doesn't have one.
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+	Location      Location
+}
+
+func (f *InlineFragment) location() Location { return f.Location }
+
+// Fragment is a named set of selections on a type.
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+	Location      Location
+}
+
+// Argument is a value passed to a field or directive.
+type Argument struct {
+	Name     string
+	Value    Value
+	Location Location
+}
+
+// Directive annotates a selection, such as @include(if: $flag).
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+	Location  Location
+}
+
+// Value is a literal within a query. Scalars are held as int, float64,
+// string, bool, or nil, and the remaining kinds use the types below.
+type Value interface{}
+
+// Variable refers to the value of a variable.
+type Variable string
+
+// EnumValue is the name of an enum value.
+type EnumValue string
+
+// ListValue is a list of values.
+type ListValue []Value
+
+// ObjectValue is an input object, keyed by field name.
+type ObjectValue map[string]Value
+
+type parser struct {
+	lexer *lexer
+	token *token
+}
+
+// Parse parses a query into a document.
+func Parse(query string) (*Document, error) {
+	p := &parser{lexer: newLexer(query)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Fragments: make(map[string]*Fragment)}
+	for p.token.kind != tokenEOF {
+		location := p.token.location
+
+		switch {
+		case p.peek(tokenPunctuator, "{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Type: "query", SelectionSet: selections, Location: location})
+
+		case p.peek(tokenName, "query"), p.peek(tokenName, "mutation"), p.peek(tokenName, "subscription"):
+			operation, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, operation)
+
+		case p.peek(tokenName, "fragment"):
+			fragment, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := doc.Fragments[fragment.Name]; ok {
+				return nil, &Error{Message: "fragment " + fragment.Name + " is defined more than once", Locations: []Location{location}}
+			}
+			doc.Fragments[fragment.Name] = fragment
+
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, &Error{Message: "the document doesn't contain an operation"}
+	}
+
+	return doc, nil
+}
+
+func (p *parser) advance() (err error) {
+	p.token, err = p.lexer.next()
+	return err
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.token.kind == kind && p.token.value == value
+}
+
+func (p *parser) unexpected() error {
+	if p.token.kind == tokenEOF {
+		return p.lexer.errorf(p.token.location, "unexpected end of document")
+	}
+	return p.lexer.errorf(p.token.location, "unexpected %q", p.token.value)
+}
+
+// skip advances past the punctuator when it's next, returning whether it was.
+func (p *parser) skip(value string) (bool, error) {
+	if !p.peek(tokenPunctuator, value) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(value string) error {
+	if !p.peek(tokenPunctuator, value) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.unexpected()
+	}
+
+	value := p.token.value
+	return value, p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	operation := &Operation{Type: p.token.value, Location: p.token.location}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if p.token.kind == tokenName {
+		if operation.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if operation.Variables, err = p.variableDefinitions(); err != nil {
+		return nil, err
+	}
+
+	if operation.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+
+	if operation.SelectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+func (p *parser) variableDefinitions() ([]*VariableDefinition, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+
+	definitions := make([]*VariableDefinition, 0)
+	for {
+		if ok, err := p.skip(")"); ok || err != nil {
+			return definitions, err
+		}
+
+		definition := &VariableDefinition{Location: p.token.location}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if definition.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		if definition.Type, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if definition.Default, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+
+		definitions = append(definitions, definition)
+	}
+}
+
+func (p *parser) typeRef() (*TypeRef, error) {
+	ref := &TypeRef{}
+
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if ref.Elem, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else {
+		if ref.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	ok, err := p.skip("!")
+	ref.NonNull = ok
+	return ref, err
+}
+
+func (p *parser) directives(constant bool) ([]*Directive, error) {
+	directives := make([]*Directive, 0)
+	for p.peek(tokenPunctuator, "@") {
+		directive := &Directive{Location: p.token.location}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if directive.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+
+		if directive.Arguments, err = p.arguments(constant); err != nil {
+			return nil, err
+		}
+
+		directives = append(directives, directive)
+	}
+	return directives, nil
+}
+
+func (p *parser) arguments(constant bool) ([]*Argument, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+
+	arguments := make([]*Argument, 0)
+	for {
+		if ok, err := p.skip(")"); ok || err != nil {
+			if len(arguments) == 0 && err == nil {
+				return nil, p.lexer.errorf(p.token.location, "expected an argument")
+			}
+			return arguments, err
+		}
+
+		argument := &Argument{Location: p.token.location}
+
+		var err error
+		if argument.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		if argument.Value, err = p.value(constant); err != nil {
+			return nil, err
+		}
+
+		arguments = append(arguments, argument)
+	}
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	selections := make([]Selection, 0)
+	for {
+		if ok, err := p.skip("}"); ok || err != nil {
+			if len(selections) == 0 && err == nil {
+				return nil, p.lexer.errorf(p.token.location, "expected a selection")
+			}
+			return selections, err
+		}
+
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+}
+
+func (p *parser) selection() (Selection, error) {
+	location := p.token.location
+
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		return p.fragmentSelection(location)
+	}
+
+	field := &Field{Location: location}
+
+	var err error
+	if field.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		field.Alias = field.Name
+		if field.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if field.Arguments, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+
+	if field.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+
+	if p.peek(tokenPunctuator, "{") {
+		if field.SelectionSet, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+
+	return field, nil
+}
+
+// fragmentSelection reads what follows a ..., which is either the name of a
+// fragment or an inline fragment.
+func (p *parser) fragmentSelection(location Location) (Selection, error) {
+	if p.token.kind == tokenName && p.token.value != "on" {
+		spread := &FragmentSpread{Name: p.token.value, Location: location}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		var err error
+		spread.Directives, err = p.directives(false)
+		return spread, err
+	}
+
+	fragment := &InlineFragment{Location: location}
+	if p.peek(tokenName, "on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if fragment.TypeCondition, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if fragment.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+
+	if fragment.SelectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+
+	return fragment, nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	fragment := &Fragment{Location: p.token.location}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if fragment.Name, err = p.name(); err != nil {
+		return nil, err
+	} else if fragment.Name == "on" {
+		return nil, p.lexer.errorf(fragment.Location, "fragments can't be named on")
+	}
+
+	if !p.peek(tokenName, "on") {
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if fragment.TypeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+
+	if fragment.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+
+	if fragment.SelectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+
+	return fragment, nil
+}
+
+// value reads a literal. Constant values, such as the defaults of variables,
+// can't refer to variables.
+func (p *parser) value(constant bool) (Value, error) {
+	t := p.token
+
+	switch t.kind {
+	case tokenPunctuator:
+		switch t.value {
+		case "$":
+			if constant {
+				return nil, p.unexpected()
+			}
+
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			name, err := p.name()
+			return Variable(name), err
+
+		case "[":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			list := make(ListValue, 0)
+			for {
+				if ok, err := p.skip("]"); ok || err != nil {
+					return list, err
+				}
+
+				value, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+
+		case "{":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			object := make(ObjectValue)
+			for {
+				if ok, err := p.skip("}"); ok || err != nil {
+					return object, err
+				}
+
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+
+				if object[name], err = p.value(constant); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+	case tokenInt:
+		value, err := strconv.Atoi(t.value)
+		if err != nil {
+			return nil, p.lexer.errorf(t.location, "%s is out of range", t.value)
+		}
+		return value, p.advance()
+
+	case tokenFloat:
+		value, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, p.lexer.errorf(t.location, "%s is out of range", t.value)
+		}
+		return value, p.advance()
+
+	case tokenString:
+		return t.value, p.advance()
+
+	case tokenName:
+		var value Value
+		switch t.value {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			value = EnumValue(t.value)
+		}
+		return value, p.advance()
+	}
+
+	return nil, p.unexpected()
+}
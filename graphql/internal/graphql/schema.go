@@ -0,0 +1,363 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Type is a Scalar, Object, List, or NonNull.
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type. Serialize converts the value a resolver returned
+// into its output, and Parse converts an input into the value resolvers are
+// given as an argument.
+type Scalar struct {
+	Name        string
+	Description string
+	Serialize   func(value interface{}) (interface{}, error)
+	Parse       func(value interface{}) (interface{}, error)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// Object is a type with fields.
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*FieldDefinition
+}
+
+func (o *Object) String() string { return o.Name }
+
+// Field returns the definition of the field, or nil when there isn't one.
+func (o *Object) Field(name string) *FieldDefinition {
+	for _, field := range o.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// List is a list of another type.
+type List struct {
+	Of Type
+}
+
+func (l *List) String() string { return "[" + l.Of.String() + "]" }
+
+// NonNull is another type that is never null.
+type NonNull struct {
+	Of Type
+}
+
+func (n *NonNull) String() string { return n.Of.String() + "!" }
+
+// ResolveParams are given to a resolver. Source is the value that was
+// resolved for the object the field belongs to, and is nil for the fields of
+// the query.
+type ResolveParams struct {
+	Context context.Context
+	Source  interface{}
+	Args    map[string]interface{}
+}
+
+// ResolveFunc resolves the value of a field. Resolvers can return a Thunk to
+// defer their work until the other fields of the same depth are resolved,
+// which lets a Loader batch their requests.
+type ResolveFunc func(params ResolveParams) (interface{}, error)
+
+// Thunk is a value that is computed later.
+type Thunk func() (interface{}, error)
+
+// FieldDefinition is a field of an object.
+type FieldDefinition struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*ArgumentDefinition
+	Resolve     ResolveFunc
+}
+
+// ArgumentDefinition is an argument a field accepts.
+type ArgumentDefinition struct {
+	Name         string
+	Description  string
+	Type         Type
+	DefaultValue interface{}
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case uint32:
+		return int(v), true
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v), true
+		}
+	case json.Number:
+		i, err := v.Int64()
+		return int(i), err == nil
+	}
+	return 0, false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+
+	i, ok := toInt(value)
+	return float64(i), ok
+}
+
+func scalarError(name string, value interface{}) error {
+	return fmt.Errorf("%s cannot represent %v", name, value)
+}
+
+// The built-in scalars.
+var (
+	Int = &Scalar{
+		Name:        "Int",
+		Description: "A signed 32 bit integer.",
+		Serialize: func(value interface{}) (interface{}, error) {
+			if i, ok := toInt(value); ok && i >= math.MinInt32 && i <= math.MaxInt32 {
+				return i, nil
+			}
+			return nil, scalarError("Int", value)
+		},
+	}
+
+	Float = &Scalar{
+		Name:        "Float",
+		Description: "A double precision floating point number.",
+		Serialize: func(value interface{}) (interface{}, error) {
+			if f, ok := toFloat(value); ok {
+				return f, nil
+			}
+			return nil, scalarError("Float", value)
+		},
+	}
+
+	String = &Scalar{
+		Name:        "String",
+		Description: "A UTF-8 string.",
+		Serialize: func(value interface{}) (interface{}, error) {
+			if s, ok := value.(string); ok {
+				return s, nil
+			}
+			return nil, scalarError("String", value)
+		},
+	}
+
+	Boolean = &Scalar{
+		Name:        "Boolean",
+		Description: "true or false.",
+		Serialize: func(value interface{}) (interface{}, error) {
+			if b, ok := value.(bool); ok {
+				return b, nil
+			}
+			return nil, scalarError("Boolean", value)
+		},
+	}
+
+	ID = &Scalar{
+		Name:        "ID",
+		Description: "A unique identifier, serialized as a string.",
+		Serialize: func(value interface{}) (interface{}, error) {
+			if s, ok := value.(string); ok {
+				return s, nil
+			} else if i, ok := toInt(value); ok {
+				return fmt.Sprintf("%d", i), nil
+			}
+			return nil, scalarError("ID", value)
+		},
+	}
+)
+
+func init() {
+	// the built-in scalars accept the same inputs they can serialize
+	for _, scalar := range []*Scalar{Int, Float, String, Boolean, ID} {
+		scalar.Parse = scalar.Serialize
+	}
+}
+
+// Schema describes the types that can be queried, starting from the query.
+type Schema struct {
+	query *Object
+	types map[string]Type
+}
+
+func named(t Type) Type {
+	for {
+		switch v := t.(type) {
+		case *List:
+			t = v.Of
+		case *NonNull:
+			t = v.Of
+		default:
+			return t
+		}
+	}
+}
+
+// NewSchema returns the schema of the types reachable from the query.
+func NewSchema(query *Object) (*Schema, error) {
+	s := &Schema{
+		query: query,
+		types: map[string]Type{
+			Int.Name:     Int,
+			Float.Name:   Float,
+			String.Name:  String,
+			Boolean.Name: Boolean,
+			ID.Name:      ID,
+		},
+	}
+
+	if err := s.add(query); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Schema) add(t Type) error {
+	t = named(t)
+
+	var name string
+	switch v := t.(type) {
+	case *Scalar:
+		name = v.Name
+	case *Object:
+		name = v.Name
+	default:
+		return fmt.Errorf("unsupported type %T", t)
+	}
+
+	if existing, ok := s.types[name]; ok {
+		if existing != t {
+			return fmt.Errorf("type %s is defined more than once", name)
+		}
+		return nil
+	}
+	s.types[name] = t
+
+	object, ok := t.(*Object)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(object.Fields))
+	for _, field := range object.Fields {
+		if seen[field.Name] {
+			return fmt.Errorf("field %s.%s is defined more than once", object.Name, field.Name)
+		}
+		seen[field.Name] = true
+
+		for _, arg := range field.Args {
+			if _, ok := named(arg.Type).(*Scalar); !ok {
+				return fmt.Errorf("argument %s of %s.%s must be a scalar or a list of scalars", arg.Name, object.Name, field.Name)
+			}
+		}
+
+		if err := s.add(field.Type); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inputType resolves the type of a variable.
+func (s *Schema) inputType(ref *TypeRef) (Type, error) {
+	var t Type
+	if ref.Elem != nil {
+		elem, err := s.inputType(ref.Elem)
+		if err != nil {
+			return nil, err
+		}
+		t = &List{Of: elem}
+	} else if scalar, ok := s.types[ref.Name].(*Scalar); ok {
+		t = scalar
+	} else {
+		return nil, fmt.Errorf("%s is not an input type", ref.Name)
+	}
+
+	if ref.NonNull {
+		t = &NonNull{Of: t}
+	}
+	return t, nil
+}
+
+func writeDescription(sb *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+
+	quoted, _ := json.Marshal(description)
+	sb.WriteString(indent + string(quoted) + "\n")
+}
+
+// SDL prints the schema in the schema definition language. The query type is
+// printed first, followed by the other types in order of their name.
+func (s *Schema) SDL() string {
+	names := make([]string, 0, len(s.types))
+	for name, t := range s.types {
+		if _, ok := t.(*Object); ok && name != s.query.Name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{s.query.Name}, names...)
+
+	sb := &strings.Builder{}
+	sb.WriteString("schema {\n  query: " + s.query.Name + "\n}\n")
+
+	for _, name := range names {
+		object := s.types[name].(*Object)
+
+		sb.WriteString("\n")
+		writeDescription(sb, "", object.Description)
+		sb.WriteString("type " + object.Name + " {\n")
+
+		for _, field := range object.Fields {
+			writeDescription(sb, "  ", field.Description)
+			sb.WriteString("  " + field.Name)
+
+			if len(field.Args) > 0 {
+				args := make([]string, len(field.Args))
+				for i, arg := range field.Args {
+					args[i] = arg.Name + ": " + arg.Type.String()
+					if arg.DefaultValue != nil {
+						value, _ := json.Marshal(arg.DefaultValue)
+						args[i] += " = " + string(value)
+					}
+				}
+				sb.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+
+			sb.WriteString(": " + field.Type.String() + "\n")
+		}
+
+		sb.WriteString("}\n")
+	}
+
+	return sb.String()
+}
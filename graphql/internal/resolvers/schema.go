@@ -0,0 +1,277 @@
+package resolvers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/graphql/internal/graphql"
+	"github.com/depscloud/depscloud/internal/tenants"
+)
+
+// Label is a key and value set on a source or module.
+type Label struct {
+	Key   string
+	Value string
+}
+
+func sortedLabels(value interface{}, err error) (interface{}, error) {
+	labels, _ := value.(map[string]string)
+	if err != nil || labels == nil {
+		return nil, err
+	}
+
+	result := make([]*Label, 0, len(labels))
+	for key, value := range labels {
+		result = append(result, &Label{Key: key, Value: value})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+// optional resolves empty strings as null.
+func optional(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func stringField(name, description string, resolve func(source interface{}) string) *graphql.FieldDefinition {
+	return &graphql.FieldDefinition{
+		Name:        name,
+		Description: description,
+		Type:        graphql.String,
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+			return optional(resolve(params.Source)), nil
+		},
+	}
+}
+
+// then applies fn to the value of the thunk once it's loaded.
+func then(thunk graphql.Thunk, fn func(value interface{}, err error) (interface{}, error)) graphql.Thunk {
+	return func() (interface{}, error) {
+		return fn(thunk())
+	}
+}
+
+func pagingArgs() []*graphql.ArgumentDefinition {
+	return []*graphql.ArgumentDefinition{
+		{Name: "page", Description: "The page to list, starting from 1.", Type: graphql.Int, DefaultValue: 1},
+		{Name: "count", Description: "The number of items in a page. The tracker's default is used when omitted.", Type: graphql.Int},
+	}
+}
+
+func listRequest(args map[string]interface{}) *tracker.ListRequest {
+	page, _ := args["page"].(int)
+	count, _ := args["count"].(int)
+	return &tracker.ListRequest{Page: int32(page), Count: int32(count)}
+}
+
+// NewSchema returns the schema resolving sources, modules, and the edges
+// between them using the tracker.
+func NewSchema(t *Tracker) (*graphql.Schema, error) {
+	source := &graphql.Object{Name: "Source", Description: "A repository that is indexed by the graph."}
+	module := &graphql.Object{Name: "Module", Description: "A library or application that is managed by a source."}
+
+	managedModule := &graphql.Object{Name: "ManagedModule", Description: "A module managed by a source."}
+	managingSource := &graphql.Object{Name: "ManagingSource", Description: "A source managing a module."}
+	dependency := &graphql.Object{Name: "Dependency", Description: "An edge between a module and a module it depends on."}
+	label := &graphql.Object{Name: "Label", Description: "A label set on a source or module."}
+	vulnerability := &graphql.Object{Name: "Vulnerability", Description: "An advisory affecting a version of a module."}
+
+	source.Fields = []*graphql.FieldDefinition{
+		stringField("url", "The url of the repository.", func(s interface{}) string { return s.(*schema.Source).GetUrl() }),
+		stringField("kind", "The kind of repository. Only known for listed sources.", func(s interface{}) string { return s.(*schema.Source).GetKind() }),
+		stringField("ref", "The ref the source was indexed at. Only known for listed sources.", func(s interface{}) string { return s.(*schema.Source).GetRef() }),
+		{
+			Name:        "modules",
+			Description: "The modules the source manages.",
+			Type:        &graphql.List{Of: managedModule},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return t.loaders(params.Context).managed.Load(params.Context, params.Source.(*schema.Source).GetUrl()), nil
+			},
+		},
+		{
+			Name:        "labels",
+			Description: "The labels of the source, or null when the tracker doesn't store labels.",
+			Type:        &graphql.List{Of: label},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				thunk := t.loaders(params.Context).sourceLabels.Load(params.Context, params.Source.(*schema.Source).GetUrl())
+				return then(thunk, sortedLabels), nil
+			},
+		},
+	}
+
+	moduleEdges := func(name, description string, loader func(l *loaders) *graphql.Loader, of *graphql.Object) *graphql.FieldDefinition {
+		return &graphql.FieldDefinition{
+			Name:        name,
+			Description: description,
+			Type:        &graphql.List{Of: of},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				key := keyForModule(params.Source.(*schema.Module))
+				return loader(t.loaders(params.Context)).Load(params.Context, key), nil
+			},
+		}
+	}
+
+	module.Fields = []*graphql.FieldDefinition{
+		stringField("language", "The language of the module.", func(s interface{}) string { return s.(*schema.Module).GetLanguage() }),
+		stringField("organization", "The organization of the module.", func(s interface{}) string { return s.(*schema.Module).GetOrganization() }),
+		stringField("module", "The name of the module within its organization.", func(s interface{}) string { return s.(*schema.Module).GetModule() }),
+		stringField("name", "The name the module is published under.", func(s interface{}) string { return s.(*schema.Module).GetName() }),
+		moduleEdges("sources", "The sources managing the module.", func(l *loaders) *graphql.Loader { return l.sources }, managingSource),
+		moduleEdges("dependencies", "The modules this module depends on.", func(l *loaders) *graphql.Loader { return l.dependencies }, dependency),
+		moduleEdges("dependents", "The modules depending on this module.", func(l *loaders) *graphql.Loader { return l.dependents }, dependency),
+		{
+			Name:        "labels",
+			Description: "The labels of the module, or null when the tracker doesn't store labels.",
+			Type:        &graphql.List{Of: label},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				thunk := t.loaders(params.Context).moduleLabels.Load(params.Context, keyForModule(params.Source.(*schema.Module)))
+				return then(thunk, sortedLabels), nil
+			},
+		},
+		moduleEdges("vulnerabilities", "The advisories affecting versions of the module, or null when the tracker doesn't scan for them.",
+			func(l *loaders) *graphql.Loader { return l.vulnerabilities }, vulnerability),
+	}
+
+	managedModule.Fields = []*graphql.FieldDefinition{
+		stringField("system", "The dependency management system.", func(s interface{}) string { return s.(*tracker.ManagedModule).GetManages().GetSystem() }),
+		stringField("version", "The version of the module.", func(s interface{}) string { return s.(*tracker.ManagedModule).GetManages().GetVersion() }),
+		{
+			Name: "module",
+			Type: module,
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return params.Source.(*tracker.ManagedModule).GetModule(), nil
+			},
+		},
+	}
+
+	managingSource.Fields = []*graphql.FieldDefinition{
+		stringField("system", "The dependency management system.", func(s interface{}) string { return s.(*tracker.ManagedSource).GetManages().GetSystem() }),
+		stringField("version", "The version of the module.", func(s interface{}) string { return s.(*tracker.ManagedSource).GetManages().GetVersion() }),
+		{
+			Name: "source",
+			Type: source,
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return params.Source.(*tracker.ManagedSource).GetSource(), nil
+			},
+		},
+	}
+
+	dependency.Fields = []*graphql.FieldDefinition{
+		stringField("versionConstraint", "The version constraint of the dependency.", func(s interface{}) string {
+			return s.(*tracker.Dependency).GetDepends().GetVersionConstraint()
+		}),
+		{
+			Name:        "scopes",
+			Description: "The scopes of the dependency, such as test or dev.",
+			Type:        &graphql.List{Of: graphql.String},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return params.Source.(*tracker.Dependency).GetDepends().GetScopes(), nil
+			},
+		},
+		{
+			Name:        "module",
+			Description: "The module on the other end of the edge.",
+			Type:        module,
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return params.Source.(*tracker.Dependency).GetModule(), nil
+			},
+		},
+	}
+
+	label.Fields = []*graphql.FieldDefinition{
+		stringField("key", "", func(s interface{}) string { return s.(*Label).Key }),
+		stringField("value", "", func(s interface{}) string { return s.(*Label).Value }),
+	}
+
+	vulnerability.Fields = []*graphql.FieldDefinition{
+		stringField("id", "The id of the advisory.", func(s interface{}) string { return s.(*Vulnerability).Advisory.ID }),
+		stringField("summary", "", func(s interface{}) string { return s.(*Vulnerability).Advisory.Summary }),
+		stringField("severity", "", func(s interface{}) string { return s.(*Vulnerability).Advisory.Severity }),
+		stringField("version", "The version of the module the advisory affects.", func(s interface{}) string { return s.(*Vulnerability).Version }),
+		{
+			Name:        "aliases",
+			Description: "Other ids of the advisory, such as CVEs.",
+			Type:        &graphql.List{Of: graphql.String},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return params.Source.(*Vulnerability).Advisory.Aliases, nil
+			},
+		},
+		stringField("modified", "When the advisory was last modified, in RFC 3339 format.", func(s interface{}) string {
+			modified := s.(*Vulnerability).Advisory.Modified
+			if modified.IsZero() {
+				return ""
+			}
+			return modified.UTC().Format(time.RFC3339)
+		}),
+	}
+
+	query := &graphql.Object{
+		Name: "Query",
+		Fields: []*graphql.FieldDefinition{
+			{
+				Name:        "sources",
+				Description: "Lists the sources known to the graph.",
+				Type:        &graphql.List{Of: source},
+				Args:        pagingArgs(),
+				Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+					resp, err := t.Sources.List(tenants.ForwardContext(params.Context), listRequest(params.Args))
+					return resp.GetSources(), err
+				},
+			},
+			{
+				Name:        "source",
+				Description: "Returns the source with the url.",
+				Type:        source,
+				Args: []*graphql.ArgumentDefinition{
+					{Name: "url", Type: &graphql.NonNull{Of: graphql.String}},
+				},
+				Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+					return &schema.Source{Url: params.Args["url"].(string)}, nil
+				},
+			},
+			{
+				Name:        "modules",
+				Description: "Lists the modules known to the graph.",
+				Type:        &graphql.List{Of: module},
+				Args:        pagingArgs(),
+				Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+					resp, err := t.Modules.List(tenants.ForwardContext(params.Context), listRequest(params.Args))
+					return resp.GetModules(), err
+				},
+			},
+			{
+				Name:        "module",
+				Description: "Returns the module identified by its language and either its organization and module, or its name.",
+				Type:        module,
+				Args: []*graphql.ArgumentDefinition{
+					{Name: "language", Type: &graphql.NonNull{Of: graphql.String}},
+					{Name: "organization", Type: graphql.String},
+					{Name: "module", Type: graphql.String},
+					{Name: "name", Type: graphql.String},
+				},
+				Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+					m := &schema.Module{Language: params.Args["language"].(string)}
+					m.Organization, _ = params.Args["organization"].(string)
+					m.Module, _ = params.Args["module"].(string)
+					m.Name, _ = params.Args["name"].(string)
+
+					if m.Module == "" && m.Name == "" {
+						return nil, fmt.Errorf("module or name is required")
+					}
+					return m, nil
+				},
+			},
+		},
+	}
+
+	return graphql.NewSchema(query)
+}
@@ -0,0 +1,186 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/graphql/internal/graphql"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func module(name string) *schema.Module {
+	return &schema.Module{Language: "go", Organization: "depscloud", Module: name}
+}
+
+// fakeTracker serves a graph where the depscloud source manages a, which
+// depends on b and c, which both depend on d.
+type fakeTracker struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	tenants map[string]bool
+}
+
+func (f *fakeTracker) call(ctx context.Context, method string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls[method]++
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		for _, tenant := range md.Get(tenants.MetadataKey) {
+			f.tenants[tenant] = true
+		}
+	}
+}
+
+var edges = map[string][]string{
+	"a": {"b", "c"},
+	"b": {"d"},
+	"c": {"d"},
+}
+
+func (f *fakeTracker) List(ctx context.Context, in *tracker.ListRequest, opts ...grpc.CallOption) (*tracker.ListSourceResponse, error) {
+	f.call(ctx, "sources.List")
+	return &tracker.ListSourceResponse{Sources: []*schema.Source{{Url: "https://github.com/depscloud/depscloud.git", Kind: "repository"}}}, nil
+}
+
+func (f *fakeTracker) Track(ctx context.Context, in *tracker.SourceRequest, opts ...grpc.CallOption) (*tracker.TrackResponse, error) {
+	panic("unexpected call to Track")
+}
+
+type fakeModules struct{ *fakeTracker }
+
+func (f fakeModules) List(ctx context.Context, in *tracker.ListRequest, opts ...grpc.CallOption) (*tracker.ListModuleResponse, error) {
+	f.call(ctx, "modules.List")
+	return &tracker.ListModuleResponse{Modules: []*schema.Module{module("a"), module("b")}}, nil
+}
+
+func (f fakeModules) ListSources(ctx context.Context, in *schema.Module, opts ...grpc.CallOption) (*tracker.ListSourcesResponse, error) {
+	f.call(ctx, "modules.ListSources")
+	return &tracker.ListSourcesResponse{}, nil
+}
+
+func (f fakeModules) ListManaged(ctx context.Context, in *schema.Source, opts ...grpc.CallOption) (*tracker.ListManagedResponse, error) {
+	f.call(ctx, "modules.ListManaged")
+	return &tracker.ListManagedResponse{Modules: []*tracker.ManagedModule{
+		{Manages: &schema.Manages{System: "vgo", Version: "v1.0.0"}, Module: module("a")},
+	}}, nil
+}
+
+type fakeDependencies struct{ *fakeTracker }
+
+func (f fakeDependencies) ListDependents(ctx context.Context, in *tracker.DependencyRequest, opts ...grpc.CallOption) (*tracker.ListDependentsResponse, error) {
+	f.call(ctx, "dependencies.ListDependents")
+	return &tracker.ListDependentsResponse{}, nil
+}
+
+func (f fakeDependencies) ListDependencies(ctx context.Context, in *tracker.DependencyRequest, opts ...grpc.CallOption) (*tracker.ListDependenciesResponse, error) {
+	f.call(ctx, "dependencies.ListDependencies")
+
+	dependencies := make([]*tracker.Dependency, 0)
+	for _, name := range edges[in.GetModule()] {
+		dependencies = append(dependencies, &tracker.Dependency{
+			Depends: &schema.Depends{VersionConstraint: "v1.0.0", Scopes: []string{"direct"}},
+			Module:  module(name),
+		})
+	}
+	return &tracker.ListDependenciesResponse{Dependencies: dependencies}, nil
+}
+
+func TestSchema(t *testing.T) {
+	fake := &fakeTracker{calls: make(map[string]int), tenants: make(map[string]bool)}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "acme", r.Header.Get(tenants.HeaderKey))
+
+		switch r.URL.Path {
+		case "/v1alpha/labels/modules":
+			fake.call(r.Context(), "labels.modules")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"labels": map[string]string{"team": "platform", "module": r.URL.Query().Get("module")},
+			})
+		case "/v1alpha/vulnerabilities/modules":
+			fake.call(r.Context(), "vulnerabilities.modules")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"vulnerabilities": []map[string]interface{}{
+					{"version": "v1.0.0", "advisory": map[string]interface{}{"id": "GHSA-1", "aliases": []string{"CVE-1"}, "modified": "2020-01-02T00:00:00Z"}},
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tr := &Tracker{
+		Sources:      fake,
+		Modules:      fakeModules{fake},
+		Dependencies: fakeDependencies{fake},
+		HTTPAddress:  server.URL,
+		Concurrency:  2,
+	}
+
+	s, err := NewSchema(tr)
+	require.Nil(t, err)
+	require.Contains(t, s.SDL(), "module(language: String!, organization: String, module: String, name: String): Module")
+
+	ctx := tr.NewContext(tenants.NewContext(context.Background(), "acme"))
+	response := s.Execute(ctx, &graphql.Request{Query: `{
+		sources {
+			url
+			kind
+			labels { key }
+			modules {
+				system
+				module {
+					module
+					dependencies {
+						versionConstraint
+						module {
+							module
+							labels { key value }
+							dependencies {
+								module { module vulnerabilities { id aliases modified } }
+							}
+						}
+					}
+				}
+			}
+		}
+	}`})
+	require.Empty(t, response.Errors)
+
+	data, err := json.Marshal(response.Data)
+	require.Nil(t, err)
+
+	d := `{"module":"d","vulnerabilities":[{"id":"GHSA-1","aliases":["CVE-1"],"modified":"2020-01-02T00:00:00Z"}]}`
+	require.Equal(t, `{"sources":[{"url":"https://github.com/depscloud/depscloud.git","kind":"repository","labels":null,"modules":[`+
+		`{"system":"vgo","module":{"module":"a","dependencies":[`+
+		`{"versionConstraint":"v1.0.0","module":{"module":"b","labels":[{"key":"module","value":"b"},{"key":"team","value":"platform"}],"dependencies":[{"module":`+d+`}]}},`+
+		`{"versionConstraint":"v1.0.0","module":{"module":"c","labels":[{"key":"module","value":"c"},{"key":"team","value":"platform"}],"dependencies":[{"module":`+d+`}]}}`+
+		`]}}]}]}`, string(data))
+
+	// d is reached through both b and c, but only loaded once
+	require.Equal(t, map[string]int{
+		"sources.List":                  1,
+		"modules.ListManaged":           1,
+		"dependencies.ListDependencies": 3,
+		"labels.modules":                2,
+		"vulnerabilities.modules":       1,
+	}, fake.calls)
+	require.Equal(t, map[string]bool{"acme": true}, fake.tenants)
+
+	response = s.Execute(ctx, &graphql.Request{Query: `{ module(language: "go") { name } }`})
+	require.Len(t, response.Errors, 1)
+	require.Equal(t, "module or name is required", response.Errors[0].Message)
+}
@@ -0,0 +1,240 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/graphql/internal/graphql"
+	"github.com/depscloud/depscloud/internal/tenants"
+)
+
+// DefaultConcurrency is the number of calls a loader makes to the tracker at
+// once when its concurrency isn't configured.
+const DefaultConcurrency = 8
+
+// Tracker holds the clients used to resolve queries. Edges are read using the
+// grpc api, while labels and vulnerabilities are read using the http api.
+type Tracker struct {
+	Sources      tracker.SourceServiceClient
+	Modules      tracker.ModuleServiceClient
+	Dependencies tracker.DependencyServiceClient
+
+	HTTPClient  *http.Client
+	HTTPAddress string
+
+	Concurrency int
+}
+
+// moduleKey identifies a module within a loader. Modules are compared by
+// value, so the same module reached through different edges is loaded once.
+type moduleKey struct {
+	Language     string
+	Organization string
+	Module       string
+	Name         string
+}
+
+func keyForModule(module *schema.Module) moduleKey {
+	return moduleKey{
+		Language:     module.GetLanguage(),
+		Organization: module.GetOrganization(),
+		Module:       module.GetModule(),
+		Name:         module.GetName(),
+	}
+}
+
+func (k moduleKey) request() *tracker.DependencyRequest {
+	return &tracker.DependencyRequest{
+		Language:     k.Language,
+		Organization: k.Organization,
+		Module:       k.Module,
+		Name:         k.Name,
+	}
+}
+
+// Vulnerability is an advisory affecting a version of a module.
+type Vulnerability struct {
+	Version  string `json:"version"`
+	Advisory struct {
+		ID       string    `json:"id"`
+		Aliases  []string  `json:"aliases"`
+		Summary  string    `json:"summary"`
+		Severity string    `json:"severity"`
+		Modified time.Time `json:"modified"`
+	} `json:"advisory"`
+}
+
+// loaders are shared by the resolvers of a single request, so each edge is
+// requested from the tracker at most once per request.
+type loaders struct {
+	managed         *graphql.Loader
+	sources         *graphql.Loader
+	dependencies    *graphql.Loader
+	dependents      *graphql.Loader
+	sourceLabels    *graphql.Loader
+	moduleLabels    *graphql.Loader
+	vulnerabilities *graphql.Loader
+}
+
+type contextKey struct{}
+
+// NewContext returns a context holding the loaders of a request.
+func (t *Tracker) NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, t.newLoaders())
+}
+
+// loaders returns the loaders of the request. Requests made without a
+// context from NewContext are still resolved, but aren't batched.
+func (t *Tracker) loaders(ctx context.Context) *loaders {
+	if l, ok := ctx.Value(contextKey{}).(*loaders); ok {
+		return l
+	}
+	return t.newLoaders()
+}
+
+func (t *Tracker) newLoaders() *loaders {
+	return &loaders{
+		managed: graphql.NewLoader(t.batch(func(ctx context.Context, key interface{}) (interface{}, error) {
+			resp, err := t.Modules.ListManaged(ctx, &schema.Source{Url: key.(string)})
+			return resp.GetModules(), err
+		})),
+
+		sources: graphql.NewLoader(t.batch(func(ctx context.Context, key interface{}) (interface{}, error) {
+			k := key.(moduleKey)
+			resp, err := t.Modules.ListSources(ctx, &schema.Module{
+				Language:     k.Language,
+				Organization: k.Organization,
+				Module:       k.Module,
+				Name:         k.Name,
+			})
+			return resp.GetSources(), err
+		})),
+
+		dependencies: graphql.NewLoader(t.batch(func(ctx context.Context, key interface{}) (interface{}, error) {
+			resp, err := t.Dependencies.ListDependencies(ctx, key.(moduleKey).request())
+			return resp.GetDependencies(), err
+		})),
+
+		dependents: graphql.NewLoader(t.batch(func(ctx context.Context, key interface{}) (interface{}, error) {
+			resp, err := t.Dependencies.ListDependents(ctx, key.(moduleKey).request())
+			return resp.GetDependents(), err
+		})),
+
+		sourceLabels: graphql.NewLoader(t.batch(func(ctx context.Context, key interface{}) (interface{}, error) {
+			return t.labels(ctx, "sources", url.Values{"url": {key.(string)}})
+		})),
+
+		moduleLabels: graphql.NewLoader(t.batch(func(ctx context.Context, key interface{}) (interface{}, error) {
+			return t.labels(ctx, "modules", moduleParams(key.(moduleKey)))
+		})),
+
+		vulnerabilities: graphql.NewLoader(t.batch(func(ctx context.Context, key interface{}) (interface{}, error) {
+			resp := &struct {
+				Vulnerabilities []*Vulnerability `json:"vulnerabilities"`
+			}{}
+
+			if ok, err := t.get(ctx, "/v1alpha/vulnerabilities/modules", moduleParams(key.(moduleKey)), resp); !ok || err != nil {
+				return nil, err
+			}
+			return resp.Vulnerabilities, nil
+		})),
+	}
+}
+
+// batch loads the keys of a batch concurrently. The tracker has no api to read
+// the edges of many nodes at once, so a batch is the set of distinct keys a
+// depth of the query needs, requested together instead of one at a time.
+func (t *Tracker) batch(load func(ctx context.Context, key interface{}) (interface{}, error)) graphql.BatchFunc {
+	return func(ctx context.Context, keys []interface{}) []*graphql.Result {
+		ctx = tenants.ForwardContext(ctx)
+
+		concurrency := t.Concurrency
+		if concurrency <= 0 {
+			concurrency = DefaultConcurrency
+		}
+
+		results := make([]*graphql.Result, len(keys))
+		semaphore := make(chan struct{}, concurrency)
+		wg := sync.WaitGroup{}
+
+		for i, key := range keys {
+			wg.Add(1)
+			semaphore <- struct{}{}
+
+			go func(i int, key interface{}) {
+				defer func() {
+					<-semaphore
+					wg.Done()
+				}()
+
+				value, err := load(ctx, key)
+				results[i] = &graphql.Result{Value: value, Err: err}
+			}(i, key)
+		}
+
+		wg.Wait()
+		return results
+	}
+}
+
+func moduleParams(key moduleKey) url.Values {
+	return url.Values{
+		"language":     {key.Language},
+		"organization": {key.Organization},
+		"module":       {key.Module},
+	}
+}
+
+func (t *Tracker) labels(ctx context.Context, kind string, params url.Values) (interface{}, error) {
+	resp := &struct {
+		Labels map[string]string `json:"labels"`
+	}{}
+
+	if ok, err := t.get(ctx, "/v1alpha/labels/"+kind, params, resp); !ok || err != nil {
+		return nil, err
+	}
+	return resp.Labels, nil
+}
+
+// get reads a route of the tracker's http api into the response. Routes
+// that aren't found belong to features the tracker wasn't started with, so
+// false is returned rather than an error.
+func (t *Tracker) get(ctx context.Context, path string, params url.Values, response interface{}) (bool, error) {
+	address := strings.TrimSuffix(t.HTTPAddress, "/") + path + "?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return false, err
+	}
+
+	if tenant := tenants.FromContext(ctx); tenant != "" {
+		req.Header.Set(tenants.HeaderKey, tenant)
+	}
+
+	client := t.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	} else if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status from tracker: %s", resp.Status)
+	}
+
+	return true, json.NewDecoder(resp.Body).Decode(response)
+}
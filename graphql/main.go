@@ -0,0 +1,214 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/graphql/internal/graphql"
+	"github.com/depscloud/depscloud/graphql/internal/resolvers"
+	"github.com/depscloud/depscloud/internal/client"
+	"github.com/depscloud/depscloud/internal/config"
+	"github.com/depscloud/depscloud/internal/diagnostics"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/middleware"
+	"github.com/depscloud/depscloud/internal/mux"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/mjpitz/go-gracefully/check"
+	"github.com/mjpitz/go-gracefully/state"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/urfave/cli/v2"
+
+	"golang.org/x/net/context"
+
+	_ "google.golang.org/grpc/health"
+)
+
+// variables set during build using -X ldflag
+var version string
+var commit string
+var date string
+
+type graphqlConfig struct {
+	server             *config.Server
+	trackerHTTPAddress string
+	concurrency        int
+}
+
+func main() {
+	version := mux.Version{Version: version, Commit: commit, Date: date}
+	cfg := &graphqlConfig{
+		server:             &config.Server{HTTPPort: 8080, GRPCPort: 8090, PortAlias: config.PortHTTP},
+		trackerHTTPAddress: "http://tracker:8080",
+		concurrency:        resolvers.DefaultConcurrency,
+	}
+
+	var serverFlags []cli.Flag
+	cfg.server, serverFlags = config.WithServerFlags(cfg.server)
+
+	tenancy, tenancyFlags := tenants.WithFlags(&tenants.Config{Mode: tenants.ModeNone})
+
+	trackerConfig, trackerFlags := client.WithFlags("tracker", &client.Config{
+		Address:       "tracker:8090",
+		ServiceConfig: client.DefaultServiceConfig,
+		LoadBalancer:  client.DefaultLoadBalancer,
+		TLS:           false,
+		TLSConfig:     &client.TLSConfig{},
+	})
+
+	flags := append(serverFlags,
+		&cli.IntFlag{
+			Name:        "concurrency",
+			Usage:       "the number of calls each batch of a query makes to the tracker at once",
+			Value:       cfg.concurrency,
+			Destination: &cfg.concurrency,
+			EnvVars:     []string{"CONCURRENCY"},
+		},
+	)
+
+	loggingConfig, loggingFlags := logging.WithFlags(logging.DefaultConfig())
+	middlewareConfig, middlewareFlags := middleware.WithFlags(middleware.DefaultConfig())
+	diagnosticsConfig, diagnosticsFlags := diagnostics.WithFlags(&diagnostics.Config{})
+
+	flags = append(flags, tenancyFlags...)
+	flags = append(flags, trackerFlags...)
+	flags = append(flags, loggingFlags...)
+	flags = append(flags, middlewareFlags...)
+	flags = append(flags, diagnosticsFlags...)
+	flags = append(flags, &cli.StringFlag{
+		Name:        "tracker-http-address",
+		Usage:       "http address of the tracker, used to read labels and vulnerabilities",
+		Value:       cfg.trackerHTTPAddress,
+		Destination: &cfg.trackerHTTPAddress,
+		EnvVars:     []string{"TRACKER_HTTP_ADDRESS"},
+	})
+
+	app := &cli.App{
+		Name:  "graphql",
+		Usage: "a GraphQL api over the dependency graph",
+		Commands: []*cli.Command{
+			{
+				Name:  "version",
+				Usage: "Output version information",
+				Action: func(c *cli.Context) error {
+					fmt.Println(fmt.Sprintf("%s %s", c.Command.Name, version))
+					return nil
+				},
+			},
+		},
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			if err := logging.Setup("graphql", loggingConfig); err != nil {
+				return err
+			}
+			middleware.Setup(middlewareConfig)
+
+			if err := diagnostics.Serve(c.Context, diagnosticsConfig); err != nil {
+				return err
+			}
+
+			if err := tenancy.Validate(); err != nil {
+				return err
+			}
+
+			serverTLSConfig, err := mux.LoadTLSConfig(cfg.server.TLS)
+			if err != nil {
+				return err
+			}
+
+			serverOptions, err := tenancy.ServerOptions(serverTLSConfig)
+			if err != nil {
+				return err
+			}
+
+			grpcServer, httpServer := mux.DefaultServers(serverOptions...)
+
+			trackerConn, err := client.Connect(trackerConfig)
+			if err != nil {
+				return err
+			}
+			defer trackerConn.Close()
+
+			httpClient := http.DefaultClient
+			if trackerConfig.TLS || trackerConfig.TLSConfig.CertPath != "" {
+				var trackerTLSConfig *tls.Config
+				trackerTLSConfig, err = client.LoadTLSConfig(trackerConfig.TLSConfig)
+				if err != nil {
+					return err
+				}
+
+				httpClient = &http.Client{
+					Transport: &http.Transport{
+						Proxy:           http.ProxyFromEnvironment,
+						TLSClientConfig: trackerTLSConfig,
+					},
+				}
+			}
+
+			sourceService := tracker.NewSourceServiceClient(trackerConn)
+
+			t := &resolvers.Tracker{
+				Sources:      sourceService,
+				Modules:      tracker.NewModuleServiceClient(trackerConn),
+				Dependencies: tracker.NewDependencyServiceClient(trackerConn),
+				HTTPClient:   httpClient,
+				HTTPAddress:  cfg.trackerHTTPAddress,
+				Concurrency:  cfg.concurrency,
+			}
+
+			schema, err := resolvers.NewSchema(t)
+			if err != nil {
+				return err
+			}
+
+			sdl := schema.SDL()
+
+			httpServer.Handle("/graphql", graphql.NewHandler(schema, t.NewContext))
+			httpServer.HandleFunc("/graphql/schema", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(sdl))
+			})
+
+			checks := []check.Check{
+				&check.Periodic{
+					Metadata: check.Metadata{
+						Name:   "sources",
+						Weight: 10,
+					},
+					Interval: time.Second * 5,
+					Timeout:  time.Second * 5,
+					RunFunc: func(ctx context.Context) (state.State, error) {
+						_, err := sourceService.List(ctx, &tracker.ListRequest{})
+						if err != nil {
+							return state.Outage, err
+						}
+						return state.OK, nil
+					},
+				},
+			}
+
+			return mux.Serve(grpcServer, tenancy.Middleware(httpServer), &mux.Config{
+				Context:         c.Context,
+				BindAddressHTTP: fmt.Sprintf("0.0.0.0:%d", cfg.server.HTTPPort),
+				BindAddressGRPC: fmt.Sprintf("0.0.0.0:%d", cfg.server.GRPCPort),
+				Checks:          checks,
+				Version:         &version,
+				TLSConfig:       cfg.server.TLS,
+				GRPCCredentials: tenancy.Mode == tenants.ModeCertificate,
+			})
+		},
+	}
+
+	config.Apply(app)
+
+	if err := app.Run(os.Args); err != nil {
+		logrus.Fatal(err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/kubernetes"
+	"github.com/depscloud/depscloud/indexer/internal/remotes"
+	"github.com/depscloud/depscloud/internal/logging"
+)
+
+// runController indexes the IndexerSources defined in the cluster on every
+// interval, reporting the outcome for each source on its status.
+func runController(ctx context.Context, client *kubernetes.Client, interval time.Duration, index func(string, []*remotes.Repository)) error {
+	for {
+		sources, err := client.ListSources(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[controller] failed to list sources: %v", err)
+		} else {
+			syncSources(ctx, client, sources, index)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func discover(ctx context.Context, source *kubernetes.Source) ([]*remotes.Repository, error) {
+	account, err := source.Account()
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := remotes.ParseAccount(account)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := remote.FetchRepositories(ctx, &remotes.FetchRepositoriesRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Repositories, nil
+}
+
+func updateStatus(ctx context.Context, client *kubernetes.Client, source *kubernetes.Source, status *kubernetes.SourceStatus) {
+	status.ObservedGeneration = source.Metadata.Generation
+	status.LastRunTime = time.Now().UTC().Format(time.RFC3339)
+
+	if err := client.UpdateStatus(ctx, source, status); err != nil {
+		logging.FromContext(ctx).Errorf("[controller] failed to update status of %s/%s: %v",
+			source.Metadata.Namespace, source.Metadata.Name, err)
+	}
+}
+
+// syncSources discovers the repositories of every source before indexing them
+// together, so a repository belonging to more than one source is only indexed
+// once.
+func syncSources(ctx context.Context, client *kubernetes.Client, sources []*kubernetes.Source, index func(string, []*remotes.Repository)) {
+	repositories := make([]*remotes.Repository, 0)
+	seen := make(map[string]bool)
+
+	statuses := make(map[*kubernetes.Source]*kubernetes.SourceStatus, len(sources))
+
+	for _, source := range sources {
+		logging.FromContext(ctx).Infof("[controller] discovering repositories for %s/%s", source.Metadata.Namespace, source.Metadata.Name)
+
+		repos, err := discover(ctx, source)
+		if err != nil {
+			updateStatus(ctx, client, source, &kubernetes.SourceStatus{
+				Phase:   kubernetes.PhaseFailed,
+				Message: err.Error(),
+			})
+			continue
+		}
+
+		status := &kubernetes.SourceStatus{
+			Phase:        kubernetes.PhaseDiscovered,
+			Repositories: len(repos),
+		}
+		statuses[source] = status
+		updateStatus(ctx, client, source, status)
+
+		for _, repo := range repos {
+			key := remotes.CanonicalURL(repo.RepositoryURL)
+			if !seen[key] {
+				seen[key] = true
+				repositories = append(repositories, repo)
+			}
+		}
+	}
+
+	index(controllerRun, repositories)
+
+	for source, status := range statuses {
+		status.Phase = kubernetes.PhaseIndexed
+		updateStatus(ctx, client, source, status)
+	}
+}
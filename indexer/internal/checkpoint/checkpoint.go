@@ -0,0 +1,212 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// state is the content of the checkpoint file.
+type state struct {
+	Runs map[string]*runState `json:"runs"`
+}
+
+type runState struct {
+	Started   time.Time `json:"started"`
+	Completed []string  `json:"completed"`
+}
+
+// Store persists the repositories completed by each run to a local file, so a
+// run that's interrupted (by a crash, a preempted pod, or the run deadline)
+// resumes where it left off instead of starting over.
+type Store struct {
+	path   string
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	state *state
+}
+
+// Open reads the checkpoint file at the path, which doesn't need to exist yet.
+// Runs that started more than maxAge ago aren't resumed, when it's positive.
+func Open(path string, maxAge time.Duration) (*Store, error) {
+	s := &Store{
+		path:   path,
+		maxAge: maxAge,
+		now:    time.Now,
+	}
+
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Reload reads the checkpoint file again, picking up the progress recorded by
+// another indexer sharing the file (such as a previous leader).
+func (s *Store) Reload() error {
+	current := &state{}
+
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	} else if err == nil {
+		if err := json.Unmarshal(data, current); err != nil {
+			return errors.Wrapf(err, "failed to read checkpoint %s", s.path)
+		}
+	}
+
+	if current.Runs == nil {
+		current.Runs = make(map[string]*runState)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = current
+	return nil
+}
+
+// Interrupted returns the names of the runs that were started but never
+// finished, and can still be resumed.
+func (s *Store) Interrupted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.state.Runs))
+	for name, run := range s.state.Runs {
+		if s.maxAge <= 0 || s.now().Sub(run.Started) <= s.maxAge {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Start resumes the named run when it was interrupted, or begins a new one.
+func (s *Store) Start(name string) (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.state.Runs[name]
+	if ok && (s.maxAge <= 0 || s.now().Sub(previous.Started) <= s.maxAge) {
+		completed := make(map[string]bool, len(previous.Completed))
+		for _, url := range previous.Completed {
+			completed[url] = true
+		}
+
+		return &Run{store: s, name: name, completed: completed, resumed: len(completed)}, nil
+	}
+
+	s.state.Runs[name] = &runState{
+		Started:   s.now(),
+		Completed: make([]string, 0),
+	}
+
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+
+	return &Run{store: s, name: name, completed: make(map[string]bool)}, nil
+}
+
+// flush atomically replaces the checkpoint file with the current state.
+func (s *Store) flush() error {
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Run tracks the repositories completed by a single run. A nil run doesn't
+// record anything, so callers don't need to check whether checkpointing is
+// enabled.
+type Run struct {
+	store     *Store
+	name      string
+	completed map[string]bool
+	resumed   int
+}
+
+// Resumed returns the number of repositories completed before the run was
+// resumed.
+func (r *Run) Resumed() int {
+	if r == nil {
+		return 0
+	}
+	return r.resumed
+}
+
+// Completed returns whether the repository was completed by an earlier
+// attempt of the run.
+func (r *Run) Completed(url string) bool {
+	if r == nil {
+		return false
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.completed[url]
+}
+
+// Complete records the repository as completed.
+func (r *Run) Complete(url string) error {
+	if r == nil {
+		return nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	run, ok := r.store.state.Runs[r.name]
+	if !ok || r.completed[url] {
+		return nil
+	}
+
+	r.completed[url] = true
+	run.Completed = append(run.Completed, url)
+
+	return r.store.flush()
+}
+
+// Finish removes the run from the checkpoint once all of its repositories
+// have been completed, so the next run starts from the beginning.
+func (r *Run) Finish() error {
+	if r == nil {
+		return nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.state.Runs, r.name)
+	return r.store.flush()
+}
@@ -0,0 +1,68 @@
+package checkpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoint.json")
+
+	store, err := Open(path, 0)
+	require.NoError(t, err)
+
+	run, err := store.Start("github")
+	require.NoError(t, err)
+	require.Equal(t, 0, run.Resumed())
+	require.NoError(t, run.Complete("https://github.com/a/b.git"))
+	require.NoError(t, run.Complete("https://github.com/a/b.git"))
+
+	other, err := store.Start("gitlab")
+	require.NoError(t, err)
+	require.NoError(t, other.Complete("https://gitlab.com/a/b.git"))
+	require.NoError(t, other.Finish())
+
+	// an interrupted run resumes from the file
+	store, err = Open(path, time.Hour)
+	require.NoError(t, err)
+
+	run, err = store.Start("github")
+	require.NoError(t, err)
+	require.Equal(t, 1, run.Resumed())
+	require.True(t, run.Completed("https://github.com/a/b.git"))
+	require.False(t, run.Completed("https://github.com/a/c.git"))
+
+	require.Equal(t, []string{"github"}, store.Interrupted())
+
+	other, err = store.Start("gitlab")
+	require.NoError(t, err)
+	require.Equal(t, 0, other.Resumed())
+
+	// progress recorded by another store is picked up on reload
+	shared, err := Open(path, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, other.Finish())
+	require.NoError(t, shared.Reload())
+	require.Equal(t, []string{"github"}, shared.Interrupted())
+
+	// runs older than the max age start over
+	store.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	run, err = store.Start("github")
+	require.NoError(t, err)
+	require.Equal(t, 0, run.Resumed())
+
+	// a nil run records nothing
+	var none *Run
+	require.False(t, none.Completed("https://github.com/a/b.git"))
+	require.NoError(t, none.Complete("https://github.com/a/b.git"))
+	require.NoError(t, none.Finish())
+}
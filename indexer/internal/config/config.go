@@ -1,9 +1,15 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"path"
+	"reflect"
+	"strings"
+
+	"github.com/depscloud/depscloud/internal/secrets"
 
 	"github.com/ghodss/yaml"
 
@@ -63,9 +69,44 @@ func defaultParserIndex() map[string]Parser {
 	return parserIndex
 }
 
+// resolveSecrets replaces every string in the configuration that refers to a
+// secret in Vault or a cloud secret manager with the secret, so tokens and
+// passwords needn't be mounted as files or environment variables.
+func resolveSecrets(ctx context.Context, value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			return resolveSecrets(ctx, value.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if err := resolveSecrets(ctx, value.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			if err := resolveSecrets(ctx, value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		if !value.CanSet() || !secrets.IsReference(value.String()) {
+			return nil
+		}
+
+		secret, err := secrets.Resolve(ctx, value.String())
+		if err != nil {
+			return err
+		}
+		value.SetString(secret)
+	}
+	return nil
+}
+
 // Load accepts a url that points to a configuration file. The file is then
 // loaded into memory and parsed into a Configuration object. Parser lookup is
-// performed based on the url's extension.
+// performed based on the url's extension. References to secrets are resolved.
 func Load(url string) (*Configuration, error) {
 	idx := defaultParserIndex()
 
@@ -85,5 +126,52 @@ func Load(url string) (*Configuration, error) {
 		return nil, errors.Wrap(err, "failed to parse body of file")
 	}
 
+	if err := resolveSecrets(context.Background(), reflect.ValueOf(config)); err != nil {
+		return nil, errors.Wrap(err, "failed to resolve secrets")
+	}
+
 	return config, nil
 }
+
+// LoadRepositories accepts a path or an http(s) url that points to a list of
+// repositories. The list is parsed as YAML, which covers JSON as well.
+func LoadRepositories(location string) (*StaticRepositoryList, error) {
+	var body []byte
+	var err error
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		body, err = fetch(location)
+	} else {
+		body, err = ioutil.ReadFile(location)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to read repositories: %s", location))
+	}
+
+	body, err = yaml.YAMLToJSON(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse repositories")
+	}
+
+	list := &StaticRepositoryList{}
+	if err := jsonpb.UnmarshalString(string(body), list); err != nil {
+		return nil, errors.Wrap(err, "failed to parse repositories")
+	}
+
+	return list, nil
+}
+
+func fetch(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
@@ -50,6 +50,16 @@ type Clone struct {
 	Strategy             CloneStrategy `protobuf:"varint,1,opt,name=strategy,proto3,enum=cloud.deps.indexer.config.CloneStrategy" json:"strategy,omitempty"`
 	Basic                *Basic        `protobuf:"bytes,2,opt,name=basic,proto3" json:"basic,omitempty"`
 	PublicKey            *PublicKey    `protobuf:"bytes,3,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Sparse               bool          `protobuf:"varint,4,opt,name=sparse,proto3" json:"sparse,omitempty"`
+	Submodules           bool          `protobuf:"varint,5,opt,name=submodules,proto3" json:"submodules,omitempty"`
+	SubmoduleDepth       int32         `protobuf:"varint,6,opt,name=submodule_depth,json=submoduleDepth,proto3" json:"submodule_depth,omitempty"`
+	Token                *Token        `protobuf:"bytes,7,opt,name=token,proto3" json:"token,omitempty"`
+	NetrcPath            string        `protobuf:"bytes,8,opt,name=netrc_path,json=netrcPath,proto3" json:"netrc_path,omitempty"`
+	Branches             []string      `protobuf:"bytes,9,rep,name=branches,proto3" json:"branches,omitempty"`
+	BranchPattern        string        `protobuf:"bytes,10,opt,name=branch_pattern,json=branchPattern,proto3" json:"branch_pattern,omitempty"`
+	Tags                 bool          `protobuf:"varint,11,opt,name=tags,proto3" json:"tags,omitempty"`
+	LatestTags           int32         `protobuf:"varint,12,opt,name=latest_tags,json=latestTags,proto3" json:"latest_tags,omitempty"`
+	TagConstraint        string        `protobuf:"bytes,13,opt,name=tag_constraint,json=tagConstraint,proto3" json:"tag_constraint,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
 	XXX_unrecognized     []byte        `json:"-"`
 	XXX_sizecache        int32         `json:"-"`
@@ -100,11 +110,83 @@ func (m *Clone) GetPublicKey() *PublicKey {
 	return nil
 }
 
+func (m *Clone) GetSparse() bool {
+	if m != nil {
+		return m.Sparse
+	}
+	return false
+}
+
+func (m *Clone) GetSubmodules() bool {
+	if m != nil {
+		return m.Submodules
+	}
+	return false
+}
+
+func (m *Clone) GetSubmoduleDepth() int32 {
+	if m != nil {
+		return m.SubmoduleDepth
+	}
+	return 0
+}
+
+func (m *Clone) GetToken() *Token {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+func (m *Clone) GetNetrcPath() string {
+	if m != nil {
+		return m.NetrcPath
+	}
+	return ""
+}
+
+func (m *Clone) GetBranches() []string {
+	if m != nil {
+		return m.Branches
+	}
+	return nil
+}
+
+func (m *Clone) GetBranchPattern() string {
+	if m != nil {
+		return m.BranchPattern
+	}
+	return ""
+}
+
+func (m *Clone) GetTags() bool {
+	if m != nil {
+		return m.Tags
+	}
+	return false
+}
+
+func (m *Clone) GetLatestTags() int32 {
+	if m != nil {
+		return m.LatestTags
+	}
+	return 0
+}
+
+func (m *Clone) GetTagConstraint() string {
+	if m != nil {
+		return m.TagConstraint
+	}
+	return ""
+}
+
 type PublicKey struct {
 	User                 string   `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
 	PrivateKeyPath       string   `protobuf:"bytes,2,opt,name=private_key_path,json=privateKeyPath,proto3" json:"private_key_path,omitempty"`
 	PrivateKey           string   `protobuf:"bytes,3,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"`
 	Password             string   `protobuf:"bytes,4,opt,name=password,proto3" json:"password,omitempty"`
+	PrivateKeyEnv        string   `protobuf:"bytes,5,opt,name=private_key_env,json=privateKeyEnv,proto3" json:"private_key_env,omitempty"`
+	PasswordEnv          string   `protobuf:"bytes,6,opt,name=password_env,json=passwordEnv,proto3" json:"password_env,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -162,9 +244,25 @@ func (m *PublicKey) GetPassword() string {
 	return ""
 }
 
+func (m *PublicKey) GetPrivateKeyEnv() string {
+	if m != nil {
+		return m.PrivateKeyEnv
+	}
+	return ""
+}
+
+func (m *PublicKey) GetPasswordEnv() string {
+	if m != nil {
+		return m.PasswordEnv
+	}
+	return ""
+}
+
 type Basic struct {
 	Username             string   `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
 	Password             string   `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	PasswordFile         string   `protobuf:"bytes,3,opt,name=password_file,json=passwordFile,proto3" json:"password_file,omitempty"`
+	PasswordEnv          string   `protobuf:"bytes,4,opt,name=password_env,json=passwordEnv,proto3" json:"password_env,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -208,6 +306,82 @@ func (m *Basic) GetPassword() string {
 	return ""
 }
 
+func (m *Basic) GetPasswordFile() string {
+	if m != nil {
+		return m.PasswordFile
+	}
+	return ""
+}
+
+func (m *Basic) GetPasswordEnv() string {
+	if m != nil {
+		return m.PasswordEnv
+	}
+	return ""
+}
+
+type Token struct {
+	Username             string   `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Token                string   `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	TokenFile            string   `protobuf:"bytes,3,opt,name=token_file,json=tokenFile,proto3" json:"token_file,omitempty"`
+	TokenEnv             string   `protobuf:"bytes,4,opt,name=token_env,json=tokenEnv,proto3" json:"token_env,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Token) Reset()         { *m = Token{} }
+func (m *Token) String() string { return proto.CompactTextString(m) }
+func (*Token) ProtoMessage()    {}
+func (*Token) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{3}
+}
+func (m *Token) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Token.Unmarshal(m, b)
+}
+func (m *Token) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Token.Marshal(b, m, deterministic)
+}
+func (m *Token) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Token.Merge(m, src)
+}
+func (m *Token) XXX_Size() int {
+	return xxx_messageInfo_Token.Size(m)
+}
+func (m *Token) XXX_DiscardUnknown() {
+	xxx_messageInfo_Token.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Token proto.InternalMessageInfo
+
+func (m *Token) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *Token) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *Token) GetTokenFile() string {
+	if m != nil {
+		return m.TokenFile
+	}
+	return ""
+}
+
+func (m *Token) GetTokenEnv() string {
+	if m != nil {
+		return m.TokenEnv
+	}
+	return ""
+}
+
 type OAuthToken struct {
 	Token                string   `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
 	ApplicationId        string   `protobuf:"bytes,2,opt,name=application_id,json=applicationId,proto3" json:"application_id,omitempty"`
@@ -220,7 +394,7 @@ func (m *OAuthToken) Reset()         { *m = OAuthToken{} }
 func (m *OAuthToken) String() string { return proto.CompactTextString(m) }
 func (*OAuthToken) ProtoMessage()    {}
 func (*OAuthToken) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3eaf2c85e69e9ea4, []int{3}
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{4}
 }
 func (m *OAuthToken) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_OAuthToken.Unmarshal(m, b)
@@ -268,7 +442,7 @@ func (m *OAuth2Token) Reset()         { *m = OAuth2Token{} }
 func (m *OAuth2Token) String() string { return proto.CompactTextString(m) }
 func (*OAuth2Token) ProtoMessage()    {}
 func (*OAuth2Token) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3eaf2c85e69e9ea4, []int{4}
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{5}
 }
 func (m *OAuth2Token) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_OAuth2Token.Unmarshal(m, b)
@@ -316,6 +490,68 @@ func (m *OAuth2Token) GetExpiry() string {
 	return ""
 }
 
+type GithubApp struct {
+	AppId                int64    `protobuf:"varint,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	InstallationIds      []int64  `protobuf:"varint,2,rep,packed,name=installation_ids,json=installationIds,proto3" json:"installation_ids,omitempty"`
+	PrivateKeyPath       string   `protobuf:"bytes,3,opt,name=private_key_path,json=privateKeyPath,proto3" json:"private_key_path,omitempty"`
+	PrivateKey           string   `protobuf:"bytes,4,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GithubApp) Reset()         { *m = GithubApp{} }
+func (m *GithubApp) String() string { return proto.CompactTextString(m) }
+func (*GithubApp) ProtoMessage()    {}
+func (*GithubApp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{6}
+}
+func (m *GithubApp) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GithubApp.Unmarshal(m, b)
+}
+func (m *GithubApp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GithubApp.Marshal(b, m, deterministic)
+}
+func (m *GithubApp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GithubApp.Merge(m, src)
+}
+func (m *GithubApp) XXX_Size() int {
+	return xxx_messageInfo_GithubApp.Size(m)
+}
+func (m *GithubApp) XXX_DiscardUnknown() {
+	xxx_messageInfo_GithubApp.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GithubApp proto.InternalMessageInfo
+
+func (m *GithubApp) GetAppId() int64 {
+	if m != nil {
+		return m.AppId
+	}
+	return 0
+}
+
+func (m *GithubApp) GetInstallationIds() []int64 {
+	if m != nil {
+		return m.InstallationIds
+	}
+	return nil
+}
+
+func (m *GithubApp) GetPrivateKeyPath() string {
+	if m != nil {
+		return m.PrivateKeyPath
+	}
+	return ""
+}
+
+func (m *GithubApp) GetPrivateKey() string {
+	if m != nil {
+		return m.PrivateKey
+	}
+	return ""
+}
+
 type Github struct {
 	BaseUrl              string        `protobuf:"bytes,1,opt,name=base_url,json=baseUrl,proto3" json:"base_url,omitempty"`
 	UploadUrl            string        `protobuf:"bytes,2,opt,name=upload_url,json=uploadUrl,proto3" json:"upload_url,omitempty"`
@@ -325,6 +561,7 @@ type Github struct {
 	Clone                *Clone        `protobuf:"bytes,6,opt,name=clone,proto3" json:"clone,omitempty"`
 	SkipOrganizations    []string      `protobuf:"bytes,7,rep,name=skip_organizations,json=skipOrganizations,proto3" json:"skip_organizations,omitempty"`
 	Oauth2               *OAuth2Token  `protobuf:"bytes,10,opt,name=oauth2,proto3" json:"oauth2,omitempty"`
+	App                  *GithubApp    `protobuf:"bytes,11,opt,name=app,proto3" json:"app,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
 	XXX_unrecognized     []byte        `json:"-"`
 	XXX_sizecache        int32         `json:"-"`
@@ -334,7 +571,7 @@ func (m *Github) Reset()         { *m = Github{} }
 func (m *Github) String() string { return proto.CompactTextString(m) }
 func (*Github) ProtoMessage()    {}
 func (*Github) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3eaf2c85e69e9ea4, []int{5}
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{7}
 }
 func (m *Github) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Github.Unmarshal(m, b)
@@ -411,6 +648,13 @@ func (m *Github) GetOauth2() *OAuth2Token {
 	return nil
 }
 
+func (m *Github) GetApp() *GithubApp {
+	if m != nil {
+		return m.App
+	}
+	return nil
+}
+
 type Gitlab struct {
 	BaseUrl              string        `protobuf:"bytes,1,opt,name=base_url,json=baseUrl,proto3" json:"base_url,omitempty"`
 	Users                []string      `protobuf:"bytes,3,rep,name=users,proto3" json:"users,omitempty"`
@@ -418,8 +662,11 @@ type Gitlab struct {
 	Strategy             CloneStrategy `protobuf:"varint,5,opt,name=strategy,proto3,enum=cloud.deps.indexer.config.CloneStrategy" json:"strategy,omitempty"` // Deprecated: Do not use.
 	Clone                *Clone        `protobuf:"bytes,6,opt,name=clone,proto3" json:"clone,omitempty"`
 	SkipGroups           []string      `protobuf:"bytes,7,rep,name=skip_groups,json=skipGroups,proto3" json:"skip_groups,omitempty"`
+	IncludeSubgroups     bool          `protobuf:"varint,8,opt,name=include_subgroups,json=includeSubgroups,proto3" json:"include_subgroups,omitempty"`
+	IncludeArchived      bool          `protobuf:"varint,9,opt,name=include_archived,json=includeArchived,proto3" json:"include_archived,omitempty"`
 	Private              *OAuthToken   `protobuf:"bytes,10,opt,name=private,proto3" json:"private,omitempty"`
 	Oauth                *OAuthToken   `protobuf:"bytes,11,opt,name=oauth,proto3" json:"oauth,omitempty"`
+	Visibilities         []string      `protobuf:"bytes,12,rep,name=visibilities,proto3" json:"visibilities,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
 	XXX_unrecognized     []byte        `json:"-"`
 	XXX_sizecache        int32         `json:"-"`
@@ -429,7 +676,7 @@ func (m *Gitlab) Reset()         { *m = Gitlab{} }
 func (m *Gitlab) String() string { return proto.CompactTextString(m) }
 func (*Gitlab) ProtoMessage()    {}
 func (*Gitlab) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3eaf2c85e69e9ea4, []int{6}
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{8}
 }
 func (m *Gitlab) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Gitlab.Unmarshal(m, b)
@@ -492,6 +739,20 @@ func (m *Gitlab) GetSkipGroups() []string {
 	return nil
 }
 
+func (m *Gitlab) GetIncludeSubgroups() bool {
+	if m != nil {
+		return m.IncludeSubgroups
+	}
+	return false
+}
+
+func (m *Gitlab) GetIncludeArchived() bool {
+	if m != nil {
+		return m.IncludeArchived
+	}
+	return false
+}
+
 func (m *Gitlab) GetPrivate() *OAuthToken {
 	if m != nil {
 		return m.Private
@@ -506,12 +767,22 @@ func (m *Gitlab) GetOauth() *OAuthToken {
 	return nil
 }
 
+func (m *Gitlab) GetVisibilities() []string {
+	if m != nil {
+		return m.Visibilities
+	}
+	return nil
+}
+
 type Bitbucket struct {
+	BaseUrl              string        `protobuf:"bytes,1,opt,name=base_url,json=baseUrl,proto3" json:"base_url,omitempty"`
+	Projects             []string      `protobuf:"bytes,2,rep,name=projects,proto3" json:"projects,omitempty"`
 	Users                []string      `protobuf:"bytes,3,rep,name=users,proto3" json:"users,omitempty"`
 	Teams                []string      `protobuf:"bytes,4,rep,name=teams,proto3" json:"teams,omitempty"`
 	Strategy             CloneStrategy `protobuf:"varint,5,opt,name=strategy,proto3,enum=cloud.deps.indexer.config.CloneStrategy" json:"strategy,omitempty"` // Deprecated: Do not use.
 	Clone                *Clone        `protobuf:"bytes,6,opt,name=clone,proto3" json:"clone,omitempty"`
 	SkipTeams            []string      `protobuf:"bytes,7,rep,name=skip_teams,json=skipTeams,proto3" json:"skip_teams,omitempty"`
+	SkipProjects         []string      `protobuf:"bytes,8,rep,name=skip_projects,json=skipProjects,proto3" json:"skip_projects,omitempty"`
 	Basic                *Basic        `protobuf:"bytes,10,opt,name=basic,proto3" json:"basic,omitempty"`
 	Oauth                *OAuthToken   `protobuf:"bytes,11,opt,name=oauth,proto3" json:"oauth,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
@@ -523,7 +794,7 @@ func (m *Bitbucket) Reset()         { *m = Bitbucket{} }
 func (m *Bitbucket) String() string { return proto.CompactTextString(m) }
 func (*Bitbucket) ProtoMessage()    {}
 func (*Bitbucket) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3eaf2c85e69e9ea4, []int{7}
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{9}
 }
 func (m *Bitbucket) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Bitbucket.Unmarshal(m, b)
@@ -543,6 +814,20 @@ func (m *Bitbucket) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_Bitbucket proto.InternalMessageInfo
 
+func (m *Bitbucket) GetBaseUrl() string {
+	if m != nil {
+		return m.BaseUrl
+	}
+	return ""
+}
+
+func (m *Bitbucket) GetProjects() []string {
+	if m != nil {
+		return m.Projects
+	}
+	return nil
+}
+
 func (m *Bitbucket) GetUsers() []string {
 	if m != nil {
 		return m.Users
@@ -579,6 +864,13 @@ func (m *Bitbucket) GetSkipTeams() []string {
 	return nil
 }
 
+func (m *Bitbucket) GetSkipProjects() []string {
+	if m != nil {
+		return m.SkipProjects
+	}
+	return nil
+}
+
 func (m *Bitbucket) GetBasic() *Basic {
 	if m != nil {
 		return m.Basic
@@ -611,7 +903,7 @@ func (m *Generic) Reset()         { *m = Generic{} }
 func (m *Generic) String() string { return proto.CompactTextString(m) }
 func (*Generic) ProtoMessage()    {}
 func (*Generic) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3eaf2c85e69e9ea4, []int{8}
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{10}
 }
 func (m *Generic) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Generic.Unmarshal(m, b)
@@ -687,19 +979,105 @@ func (m *Generic) GetBasic() *Basic {
 	return nil
 }
 
-type Static struct {
-	RepositoryUrls       []string `protobuf:"bytes,1,rep,name=repository_urls,json=repositoryUrls,proto3" json:"repository_urls,omitempty"`
+type StaticRepository struct {
+	RepositoryUrl        string   `protobuf:"bytes,1,opt,name=repository_url,json=repositoryUrl,proto3" json:"repository_url,omitempty"`
 	Clone                *Clone   `protobuf:"bytes,2,opt,name=clone,proto3" json:"clone,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
+func (m *StaticRepository) Reset()         { *m = StaticRepository{} }
+func (m *StaticRepository) String() string { return proto.CompactTextString(m) }
+func (*StaticRepository) ProtoMessage()    {}
+func (*StaticRepository) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{11}
+}
+func (m *StaticRepository) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StaticRepository.Unmarshal(m, b)
+}
+func (m *StaticRepository) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StaticRepository.Marshal(b, m, deterministic)
+}
+func (m *StaticRepository) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StaticRepository.Merge(m, src)
+}
+func (m *StaticRepository) XXX_Size() int {
+	return xxx_messageInfo_StaticRepository.Size(m)
+}
+func (m *StaticRepository) XXX_DiscardUnknown() {
+	xxx_messageInfo_StaticRepository.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StaticRepository proto.InternalMessageInfo
+
+func (m *StaticRepository) GetRepositoryUrl() string {
+	if m != nil {
+		return m.RepositoryUrl
+	}
+	return ""
+}
+
+func (m *StaticRepository) GetClone() *Clone {
+	if m != nil {
+		return m.Clone
+	}
+	return nil
+}
+
+type StaticRepositoryList struct {
+	Repositories         []*StaticRepository `protobuf:"bytes,1,rep,name=repositories,proto3" json:"repositories,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *StaticRepositoryList) Reset()         { *m = StaticRepositoryList{} }
+func (m *StaticRepositoryList) String() string { return proto.CompactTextString(m) }
+func (*StaticRepositoryList) ProtoMessage()    {}
+func (*StaticRepositoryList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{12}
+}
+func (m *StaticRepositoryList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StaticRepositoryList.Unmarshal(m, b)
+}
+func (m *StaticRepositoryList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StaticRepositoryList.Marshal(b, m, deterministic)
+}
+func (m *StaticRepositoryList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StaticRepositoryList.Merge(m, src)
+}
+func (m *StaticRepositoryList) XXX_Size() int {
+	return xxx_messageInfo_StaticRepositoryList.Size(m)
+}
+func (m *StaticRepositoryList) XXX_DiscardUnknown() {
+	xxx_messageInfo_StaticRepositoryList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StaticRepositoryList proto.InternalMessageInfo
+
+func (m *StaticRepositoryList) GetRepositories() []*StaticRepository {
+	if m != nil {
+		return m.Repositories
+	}
+	return nil
+}
+
+type Static struct {
+	RepositoryUrls       []string            `protobuf:"bytes,1,rep,name=repository_urls,json=repositoryUrls,proto3" json:"repository_urls,omitempty"`
+	Clone                *Clone              `protobuf:"bytes,2,opt,name=clone,proto3" json:"clone,omitempty"`
+	Repositories         []*StaticRepository `protobuf:"bytes,3,rep,name=repositories,proto3" json:"repositories,omitempty"`
+	RepositoriesLocation string              `protobuf:"bytes,4,opt,name=repositories_location,json=repositoriesLocation,proto3" json:"repositories_location,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
 func (m *Static) Reset()         { *m = Static{} }
 func (m *Static) String() string { return proto.CompactTextString(m) }
 func (*Static) ProtoMessage()    {}
 func (*Static) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3eaf2c85e69e9ea4, []int{9}
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{13}
 }
 func (m *Static) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Static.Unmarshal(m, b)
@@ -733,32 +1111,342 @@ func (m *Static) GetClone() *Clone {
 	return nil
 }
 
-type Rds struct {
-	Target               string   `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *Static) GetRepositories() []*StaticRepository {
+	if m != nil {
+		return m.Repositories
+	}
+	return nil
 }
 
-func (m *Rds) Reset()         { *m = Rds{} }
-func (m *Rds) String() string { return proto.CompactTextString(m) }
-func (*Rds) ProtoMessage()    {}
-func (*Rds) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3eaf2c85e69e9ea4, []int{10}
+func (m *Static) GetRepositoriesLocation() string {
+	if m != nil {
+		return m.RepositoriesLocation
+	}
+	return ""
 }
-func (m *Rds) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Rds.Unmarshal(m, b)
+
+type Gitea struct {
+	BaseUrl              string      `protobuf:"bytes,1,opt,name=base_url,json=baseUrl,proto3" json:"base_url,omitempty"`
+	Users                []string    `protobuf:"bytes,2,rep,name=users,proto3" json:"users,omitempty"`
+	Organizations        []string    `protobuf:"bytes,3,rep,name=organizations,proto3" json:"organizations,omitempty"`
+	Clone                *Clone      `protobuf:"bytes,4,opt,name=clone,proto3" json:"clone,omitempty"`
+	SkipOrganizations    []string    `protobuf:"bytes,5,rep,name=skip_organizations,json=skipOrganizations,proto3" json:"skip_organizations,omitempty"`
+	Basic                *Basic      `protobuf:"bytes,10,opt,name=basic,proto3" json:"basic,omitempty"`
+	Token                *OAuthToken `protobuf:"bytes,11,opt,name=token,proto3" json:"token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
-func (m *Rds) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Rds.Marshal(b, m, deterministic)
+
+func (m *Gitea) Reset()         { *m = Gitea{} }
+func (m *Gitea) String() string { return proto.CompactTextString(m) }
+func (*Gitea) ProtoMessage()    {}
+func (*Gitea) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{14}
 }
-func (m *Rds) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Rds.Merge(m, src)
+func (m *Gitea) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Gitea.Unmarshal(m, b)
 }
-func (m *Rds) XXX_Size() int {
-	return xxx_messageInfo_Rds.Size(m)
+func (m *Gitea) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Gitea.Marshal(b, m, deterministic)
 }
-func (m *Rds) XXX_DiscardUnknown() {
+func (m *Gitea) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Gitea.Merge(m, src)
+}
+func (m *Gitea) XXX_Size() int {
+	return xxx_messageInfo_Gitea.Size(m)
+}
+func (m *Gitea) XXX_DiscardUnknown() {
+	xxx_messageInfo_Gitea.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Gitea proto.InternalMessageInfo
+
+func (m *Gitea) GetBaseUrl() string {
+	if m != nil {
+		return m.BaseUrl
+	}
+	return ""
+}
+
+func (m *Gitea) GetUsers() []string {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
+func (m *Gitea) GetOrganizations() []string {
+	if m != nil {
+		return m.Organizations
+	}
+	return nil
+}
+
+func (m *Gitea) GetClone() *Clone {
+	if m != nil {
+		return m.Clone
+	}
+	return nil
+}
+
+func (m *Gitea) GetSkipOrganizations() []string {
+	if m != nil {
+		return m.SkipOrganizations
+	}
+	return nil
+}
+
+func (m *Gitea) GetBasic() *Basic {
+	if m != nil {
+		return m.Basic
+	}
+	return nil
+}
+
+func (m *Gitea) GetToken() *OAuthToken {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+type AzureDevops struct {
+	BaseUrl              string      `protobuf:"bytes,1,opt,name=base_url,json=baseUrl,proto3" json:"base_url,omitempty"`
+	Organization         string      `protobuf:"bytes,2,opt,name=organization,proto3" json:"organization,omitempty"`
+	Projects             []string    `protobuf:"bytes,3,rep,name=projects,proto3" json:"projects,omitempty"`
+	Clone                *Clone      `protobuf:"bytes,4,opt,name=clone,proto3" json:"clone,omitempty"`
+	SkipProjects         []string    `protobuf:"bytes,5,rep,name=skip_projects,json=skipProjects,proto3" json:"skip_projects,omitempty"`
+	PersonalAccessToken  *OAuthToken `protobuf:"bytes,10,opt,name=personal_access_token,json=personalAccessToken,proto3" json:"personal_access_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *AzureDevops) Reset()         { *m = AzureDevops{} }
+func (m *AzureDevops) String() string { return proto.CompactTextString(m) }
+func (*AzureDevops) ProtoMessage()    {}
+func (*AzureDevops) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{15}
+}
+func (m *AzureDevops) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AzureDevops.Unmarshal(m, b)
+}
+func (m *AzureDevops) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AzureDevops.Marshal(b, m, deterministic)
+}
+func (m *AzureDevops) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AzureDevops.Merge(m, src)
+}
+func (m *AzureDevops) XXX_Size() int {
+	return xxx_messageInfo_AzureDevops.Size(m)
+}
+func (m *AzureDevops) XXX_DiscardUnknown() {
+	xxx_messageInfo_AzureDevops.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AzureDevops proto.InternalMessageInfo
+
+func (m *AzureDevops) GetBaseUrl() string {
+	if m != nil {
+		return m.BaseUrl
+	}
+	return ""
+}
+
+func (m *AzureDevops) GetOrganization() string {
+	if m != nil {
+		return m.Organization
+	}
+	return ""
+}
+
+func (m *AzureDevops) GetProjects() []string {
+	if m != nil {
+		return m.Projects
+	}
+	return nil
+}
+
+func (m *AzureDevops) GetClone() *Clone {
+	if m != nil {
+		return m.Clone
+	}
+	return nil
+}
+
+func (m *AzureDevops) GetSkipProjects() []string {
+	if m != nil {
+		return m.SkipProjects
+	}
+	return nil
+}
+
+func (m *AzureDevops) GetPersonalAccessToken() *OAuthToken {
+	if m != nil {
+		return m.PersonalAccessToken
+	}
+	return nil
+}
+
+type AwsCredentials struct {
+	AccessKeyId          string   `protobuf:"bytes,1,opt,name=access_key_id,json=accessKeyId,proto3" json:"access_key_id,omitempty"`
+	SecretAccessKey      string   `protobuf:"bytes,2,opt,name=secret_access_key,json=secretAccessKey,proto3" json:"secret_access_key,omitempty"`
+	SessionToken         string   `protobuf:"bytes,3,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AwsCredentials) Reset()         { *m = AwsCredentials{} }
+func (m *AwsCredentials) String() string { return proto.CompactTextString(m) }
+func (*AwsCredentials) ProtoMessage()    {}
+func (*AwsCredentials) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{16}
+}
+func (m *AwsCredentials) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AwsCredentials.Unmarshal(m, b)
+}
+func (m *AwsCredentials) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AwsCredentials.Marshal(b, m, deterministic)
+}
+func (m *AwsCredentials) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AwsCredentials.Merge(m, src)
+}
+func (m *AwsCredentials) XXX_Size() int {
+	return xxx_messageInfo_AwsCredentials.Size(m)
+}
+func (m *AwsCredentials) XXX_DiscardUnknown() {
+	xxx_messageInfo_AwsCredentials.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AwsCredentials proto.InternalMessageInfo
+
+func (m *AwsCredentials) GetAccessKeyId() string {
+	if m != nil {
+		return m.AccessKeyId
+	}
+	return ""
+}
+
+func (m *AwsCredentials) GetSecretAccessKey() string {
+	if m != nil {
+		return m.SecretAccessKey
+	}
+	return ""
+}
+
+func (m *AwsCredentials) GetSessionToken() string {
+	if m != nil {
+		return m.SessionToken
+	}
+	return ""
+}
+
+type CodeCommit struct {
+	Regions              []string        `protobuf:"bytes,1,rep,name=regions,proto3" json:"regions,omitempty"`
+	RoleArn              string          `protobuf:"bytes,2,opt,name=role_arn,json=roleArn,proto3" json:"role_arn,omitempty"`
+	ExternalId           string          `protobuf:"bytes,3,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	Clone                *Clone          `protobuf:"bytes,4,opt,name=clone,proto3" json:"clone,omitempty"`
+	SkipRepositories     []string        `protobuf:"bytes,5,rep,name=skip_repositories,json=skipRepositories,proto3" json:"skip_repositories,omitempty"`
+	Credentials          *AwsCredentials `protobuf:"bytes,10,opt,name=credentials,proto3" json:"credentials,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *CodeCommit) Reset()         { *m = CodeCommit{} }
+func (m *CodeCommit) String() string { return proto.CompactTextString(m) }
+func (*CodeCommit) ProtoMessage()    {}
+func (*CodeCommit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{17}
+}
+func (m *CodeCommit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CodeCommit.Unmarshal(m, b)
+}
+func (m *CodeCommit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CodeCommit.Marshal(b, m, deterministic)
+}
+func (m *CodeCommit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CodeCommit.Merge(m, src)
+}
+func (m *CodeCommit) XXX_Size() int {
+	return xxx_messageInfo_CodeCommit.Size(m)
+}
+func (m *CodeCommit) XXX_DiscardUnknown() {
+	xxx_messageInfo_CodeCommit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CodeCommit proto.InternalMessageInfo
+
+func (m *CodeCommit) GetRegions() []string {
+	if m != nil {
+		return m.Regions
+	}
+	return nil
+}
+
+func (m *CodeCommit) GetRoleArn() string {
+	if m != nil {
+		return m.RoleArn
+	}
+	return ""
+}
+
+func (m *CodeCommit) GetExternalId() string {
+	if m != nil {
+		return m.ExternalId
+	}
+	return ""
+}
+
+func (m *CodeCommit) GetClone() *Clone {
+	if m != nil {
+		return m.Clone
+	}
+	return nil
+}
+
+func (m *CodeCommit) GetSkipRepositories() []string {
+	if m != nil {
+		return m.SkipRepositories
+	}
+	return nil
+}
+
+func (m *CodeCommit) GetCredentials() *AwsCredentials {
+	if m != nil {
+		return m.Credentials
+	}
+	return nil
+}
+
+type Rds struct {
+	Target               string   `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Rds) Reset()         { *m = Rds{} }
+func (m *Rds) String() string { return proto.CompactTextString(m) }
+func (*Rds) ProtoMessage()    {}
+func (*Rds) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{18}
+}
+func (m *Rds) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Rds.Unmarshal(m, b)
+}
+func (m *Rds) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Rds.Marshal(b, m, deterministic)
+}
+func (m *Rds) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Rds.Merge(m, src)
+}
+func (m *Rds) XXX_Size() int {
+	return xxx_messageInfo_Rds.Size(m)
+}
+func (m *Rds) XXX_DiscardUnknown() {
 	xxx_messageInfo_Rds.DiscardUnknown(m)
 }
 
@@ -771,23 +1459,179 @@ func (m *Rds) GetTarget() string {
 	return ""
 }
 
+type RepositoryFilter struct {
+	SkipArchived         bool     `protobuf:"varint,1,opt,name=skip_archived,json=skipArchived,proto3" json:"skip_archived,omitempty"`
+	SkipForks            bool     `protobuf:"varint,2,opt,name=skip_forks,json=skipForks,proto3" json:"skip_forks,omitempty"`
+	Topics               []string `protobuf:"bytes,3,rep,name=topics,proto3" json:"topics,omitempty"`
+	SkipTopics           []string `protobuf:"bytes,4,rep,name=skip_topics,json=skipTopics,proto3" json:"skip_topics,omitempty"`
+	NamePattern          string   `protobuf:"bytes,5,opt,name=name_pattern,json=namePattern,proto3" json:"name_pattern,omitempty"`
+	SkipNamePattern      string   `protobuf:"bytes,6,opt,name=skip_name_pattern,json=skipNamePattern,proto3" json:"skip_name_pattern,omitempty"`
+	MaxSizeKb            int64    `protobuf:"varint,7,opt,name=max_size_kb,json=maxSizeKb,proto3" json:"max_size_kb,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RepositoryFilter) Reset()         { *m = RepositoryFilter{} }
+func (m *RepositoryFilter) String() string { return proto.CompactTextString(m) }
+func (*RepositoryFilter) ProtoMessage()    {}
+func (*RepositoryFilter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{19}
+}
+func (m *RepositoryFilter) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RepositoryFilter.Unmarshal(m, b)
+}
+func (m *RepositoryFilter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RepositoryFilter.Marshal(b, m, deterministic)
+}
+func (m *RepositoryFilter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RepositoryFilter.Merge(m, src)
+}
+func (m *RepositoryFilter) XXX_Size() int {
+	return xxx_messageInfo_RepositoryFilter.Size(m)
+}
+func (m *RepositoryFilter) XXX_DiscardUnknown() {
+	xxx_messageInfo_RepositoryFilter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RepositoryFilter proto.InternalMessageInfo
+
+func (m *RepositoryFilter) GetSkipArchived() bool {
+	if m != nil {
+		return m.SkipArchived
+	}
+	return false
+}
+
+func (m *RepositoryFilter) GetSkipForks() bool {
+	if m != nil {
+		return m.SkipForks
+	}
+	return false
+}
+
+func (m *RepositoryFilter) GetTopics() []string {
+	if m != nil {
+		return m.Topics
+	}
+	return nil
+}
+
+func (m *RepositoryFilter) GetSkipTopics() []string {
+	if m != nil {
+		return m.SkipTopics
+	}
+	return nil
+}
+
+func (m *RepositoryFilter) GetNamePattern() string {
+	if m != nil {
+		return m.NamePattern
+	}
+	return ""
+}
+
+func (m *RepositoryFilter) GetSkipNamePattern() string {
+	if m != nil {
+		return m.SkipNamePattern
+	}
+	return ""
+}
+
+func (m *RepositoryFilter) GetMaxSizeKb() int64 {
+	if m != nil {
+		return m.MaxSizeKb
+	}
+	return 0
+}
+
+type PathRule struct {
+	RepositoryPattern    string   `protobuf:"bytes,1,opt,name=repository_pattern,json=repositoryPattern,proto3" json:"repository_pattern,omitempty"`
+	Includes             []string `protobuf:"bytes,2,rep,name=includes,proto3" json:"includes,omitempty"`
+	Excludes             []string `protobuf:"bytes,3,rep,name=excludes,proto3" json:"excludes,omitempty"`
+	Sources              []string `protobuf:"bytes,4,rep,name=sources,proto3" json:"sources,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PathRule) Reset()         { *m = PathRule{} }
+func (m *PathRule) String() string { return proto.CompactTextString(m) }
+func (*PathRule) ProtoMessage()    {}
+func (*PathRule) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{20}
+}
+func (m *PathRule) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PathRule.Unmarshal(m, b)
+}
+func (m *PathRule) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PathRule.Marshal(b, m, deterministic)
+}
+func (m *PathRule) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PathRule.Merge(m, src)
+}
+func (m *PathRule) XXX_Size() int {
+	return xxx_messageInfo_PathRule.Size(m)
+}
+func (m *PathRule) XXX_DiscardUnknown() {
+	xxx_messageInfo_PathRule.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PathRule proto.InternalMessageInfo
+
+func (m *PathRule) GetRepositoryPattern() string {
+	if m != nil {
+		return m.RepositoryPattern
+	}
+	return ""
+}
+
+func (m *PathRule) GetIncludes() []string {
+	if m != nil {
+		return m.Includes
+	}
+	return nil
+}
+
+func (m *PathRule) GetExcludes() []string {
+	if m != nil {
+		return m.Excludes
+	}
+	return nil
+}
+
+func (m *PathRule) GetSources() []string {
+	if m != nil {
+		return m.Sources
+	}
+	return nil
+}
+
 type Account struct {
-	Github               *Github    `protobuf:"bytes,1,opt,name=github,proto3" json:"github,omitempty"`
-	Gitlab               *Gitlab    `protobuf:"bytes,2,opt,name=gitlab,proto3" json:"gitlab,omitempty"`
-	Bitbucket            *Bitbucket `protobuf:"bytes,3,opt,name=bitbucket,proto3" json:"bitbucket,omitempty"`
-	Generic              *Generic   `protobuf:"bytes,4,opt,name=generic,proto3" json:"generic,omitempty"`
-	Static               *Static    `protobuf:"bytes,5,opt,name=static,proto3" json:"static,omitempty"`
-	Rds                  *Rds       `protobuf:"bytes,6,opt,name=rds,proto3" json:"rds,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
-	XXX_unrecognized     []byte     `json:"-"`
-	XXX_sizecache        int32      `json:"-"`
+	Github               *Github           `protobuf:"bytes,1,opt,name=github,proto3" json:"github,omitempty"`
+	Gitlab               *Gitlab           `protobuf:"bytes,2,opt,name=gitlab,proto3" json:"gitlab,omitempty"`
+	Bitbucket            *Bitbucket        `protobuf:"bytes,3,opt,name=bitbucket,proto3" json:"bitbucket,omitempty"`
+	Generic              *Generic          `protobuf:"bytes,4,opt,name=generic,proto3" json:"generic,omitempty"`
+	Static               *Static           `protobuf:"bytes,5,opt,name=static,proto3" json:"static,omitempty"`
+	Rds                  *Rds              `protobuf:"bytes,6,opt,name=rds,proto3" json:"rds,omitempty"`
+	Gitea                *Gitea            `protobuf:"bytes,7,opt,name=gitea,proto3" json:"gitea,omitempty"`
+	AzureDevops          *AzureDevops      `protobuf:"bytes,8,opt,name=azure_devops,json=azureDevops,proto3" json:"azure_devops,omitempty"`
+	CodeCommit           *CodeCommit       `protobuf:"bytes,9,opt,name=code_commit,json=codeCommit,proto3" json:"code_commit,omitempty"`
+	Name                 string            `protobuf:"bytes,20,opt,name=name,proto3" json:"name,omitempty"`
+	Schedule             string            `protobuf:"bytes,21,opt,name=schedule,proto3" json:"schedule,omitempty"`
+	Jitter               string            `protobuf:"bytes,22,opt,name=jitter,proto3" json:"jitter,omitempty"`
+	Filter               *RepositoryFilter `protobuf:"bytes,23,opt,name=filter,proto3" json:"filter,omitempty"`
+	Paths                []*PathRule       `protobuf:"bytes,24,rep,name=paths,proto3" json:"paths,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *Account) Reset()         { *m = Account{} }
 func (m *Account) String() string { return proto.CompactTextString(m) }
 func (*Account) ProtoMessage()    {}
 func (*Account) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3eaf2c85e69e9ea4, []int{11}
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{21}
 }
 func (m *Account) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Account.Unmarshal(m, b)
@@ -849,6 +1693,62 @@ func (m *Account) GetRds() *Rds {
 	return nil
 }
 
+func (m *Account) GetGitea() *Gitea {
+	if m != nil {
+		return m.Gitea
+	}
+	return nil
+}
+
+func (m *Account) GetAzureDevops() *AzureDevops {
+	if m != nil {
+		return m.AzureDevops
+	}
+	return nil
+}
+
+func (m *Account) GetCodeCommit() *CodeCommit {
+	if m != nil {
+		return m.CodeCommit
+	}
+	return nil
+}
+
+func (m *Account) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Account) GetSchedule() string {
+	if m != nil {
+		return m.Schedule
+	}
+	return ""
+}
+
+func (m *Account) GetJitter() string {
+	if m != nil {
+		return m.Jitter
+	}
+	return ""
+}
+
+func (m *Account) GetFilter() *RepositoryFilter {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
+func (m *Account) GetPaths() []*PathRule {
+	if m != nil {
+		return m.Paths
+	}
+	return nil
+}
+
 type Configuration struct {
 	Accounts             []*Account `protobuf:"bytes,1,rep,name=accounts,proto3" json:"accounts,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
@@ -860,7 +1760,7 @@ func (m *Configuration) Reset()         { *m = Configuration{} }
 func (m *Configuration) String() string { return proto.CompactTextString(m) }
 func (*Configuration) ProtoMessage()    {}
 func (*Configuration) Descriptor() ([]byte, []int) {
-	return fileDescriptor_3eaf2c85e69e9ea4, []int{12}
+	return fileDescriptor_3eaf2c85e69e9ea4, []int{22}
 }
 func (m *Configuration) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Configuration.Unmarshal(m, b)
@@ -892,14 +1792,24 @@ func init() {
 	proto.RegisterType((*Clone)(nil), "cloud.deps.indexer.config.Clone")
 	proto.RegisterType((*PublicKey)(nil), "cloud.deps.indexer.config.PublicKey")
 	proto.RegisterType((*Basic)(nil), "cloud.deps.indexer.config.Basic")
+	proto.RegisterType((*Token)(nil), "cloud.deps.indexer.config.Token")
 	proto.RegisterType((*OAuthToken)(nil), "cloud.deps.indexer.config.OAuthToken")
 	proto.RegisterType((*OAuth2Token)(nil), "cloud.deps.indexer.config.OAuth2Token")
+	proto.RegisterType((*GithubApp)(nil), "cloud.deps.indexer.config.GithubApp")
 	proto.RegisterType((*Github)(nil), "cloud.deps.indexer.config.Github")
 	proto.RegisterType((*Gitlab)(nil), "cloud.deps.indexer.config.Gitlab")
 	proto.RegisterType((*Bitbucket)(nil), "cloud.deps.indexer.config.Bitbucket")
 	proto.RegisterType((*Generic)(nil), "cloud.deps.indexer.config.Generic")
+	proto.RegisterType((*StaticRepository)(nil), "cloud.deps.indexer.config.StaticRepository")
+	proto.RegisterType((*StaticRepositoryList)(nil), "cloud.deps.indexer.config.StaticRepositoryList")
 	proto.RegisterType((*Static)(nil), "cloud.deps.indexer.config.Static")
+	proto.RegisterType((*Gitea)(nil), "cloud.deps.indexer.config.Gitea")
+	proto.RegisterType((*AzureDevops)(nil), "cloud.deps.indexer.config.AzureDevops")
+	proto.RegisterType((*AwsCredentials)(nil), "cloud.deps.indexer.config.AwsCredentials")
+	proto.RegisterType((*CodeCommit)(nil), "cloud.deps.indexer.config.CodeCommit")
 	proto.RegisterType((*Rds)(nil), "cloud.deps.indexer.config.Rds")
+	proto.RegisterType((*RepositoryFilter)(nil), "cloud.deps.indexer.config.RepositoryFilter")
+	proto.RegisterType((*PathRule)(nil), "cloud.deps.indexer.config.PathRule")
 	proto.RegisterType((*Account)(nil), "cloud.deps.indexer.config.Account")
 	proto.RegisterType((*Configuration)(nil), "cloud.deps.indexer.config.Configuration")
 }
@@ -907,62 +1817,125 @@ func init() {
 func init() { proto.RegisterFile("config.proto", fileDescriptor_3eaf2c85e69e9ea4) }
 
 var fileDescriptor_3eaf2c85e69e9ea4 = []byte{
-	// 908 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x56, 0x5f, 0x8f, 0xdb, 0x44,
-	0x10, 0x27, 0xff, 0x9c, 0x78, 0xd2, 0x1c, 0xc7, 0xaa, 0xaa, 0x5c, 0xd0, 0xd1, 0xc3, 0xb4, 0x70,
-	0x42, 0x10, 0xa1, 0x20, 0x21, 0x21, 0x50, 0xab, 0xe6, 0x90, 0xee, 0xaa, 0x3e, 0x5c, 0xe4, 0xa4,
-	0x2f, 0xbc, 0x58, 0x1b, 0x7b, 0xeb, 0xac, 0xe2, 0xb3, 0x57, 0xbb, 0x6b, 0x68, 0xee, 0x85, 0x37,
-	0x3e, 0x12, 0x1f, 0x02, 0x89, 0xaf, 0x02, 0x12, 0x9f, 0x00, 0xed, 0xec, 0xe6, 0x5f, 0x45, 0x7d,
-	0x04, 0x5e, 0xfa, 0xb6, 0x33, 0x3b, 0xbf, 0xd9, 0x9f, 0x7f, 0x33, 0x3b, 0x6b, 0xb8, 0x93, 0x94,
-	0xc5, 0x4b, 0x9e, 0x0d, 0x85, 0x2c, 0x75, 0x49, 0xee, 0x27, 0x79, 0x59, 0xa5, 0xc3, 0x94, 0x09,
-	0x35, 0xe4, 0x45, 0xca, 0x5e, 0x31, 0x39, 0xb4, 0x01, 0xe1, 0x6f, 0x0d, 0xe8, 0x9c, 0xe7, 0x65,
-	0xc1, 0xc8, 0xf7, 0xd0, 0x53, 0x5a, 0x52, 0xcd, 0xb2, 0x55, 0xd0, 0x38, 0x6d, 0x9c, 0x1d, 0x8d,
-	0xce, 0x86, 0x6f, 0xc4, 0x0d, 0x11, 0x33, 0x75, 0xf1, 0xd1, 0x06, 0x49, 0xbe, 0x86, 0xce, 0x9c,
-	0x2a, 0x9e, 0x04, 0xcd, 0xd3, 0xc6, 0x59, 0x7f, 0x74, 0x5a, 0x93, 0x62, 0x6c, 0xe2, 0x22, 0x1b,
-	0x4e, 0xce, 0x01, 0x44, 0x35, 0xcf, 0x79, 0x12, 0x2f, 0xd9, 0x2a, 0x68, 0x21, 0xf8, 0x61, 0x0d,
-	0x78, 0x82, 0xc1, 0xcf, 0xd9, 0x2a, 0xf2, 0xc5, 0x7a, 0x19, 0xfe, 0xd2, 0x00, 0x7f, 0xb3, 0x41,
-	0x08, 0xb4, 0x2b, 0xc5, 0x24, 0x7e, 0x8c, 0x1f, 0xe1, 0x9a, 0x9c, 0xc1, 0xb1, 0x90, 0xfc, 0x47,
-	0xaa, 0x99, 0x39, 0x27, 0x16, 0x54, 0x2f, 0x90, 0xa9, 0x1f, 0x1d, 0x39, 0xff, 0x73, 0xb6, 0x9a,
-	0x50, 0xbd, 0x20, 0x0f, 0xa0, 0xbf, 0x13, 0x89, 0x8c, 0xfc, 0x08, 0xb6, 0x41, 0xe4, 0x7d, 0xe8,
-	0x09, 0xaa, 0xd4, 0x4f, 0xa5, 0x4c, 0x83, 0x36, 0xee, 0x6e, 0xec, 0xf0, 0x09, 0x74, 0xf0, 0xeb,
-	0x4c, 0x90, 0x39, 0xb7, 0xa0, 0xd7, 0xcc, 0xf1, 0xd8, 0xd8, 0x7b, 0x09, 0x9a, 0xaf, 0x25, 0x78,
-	0x06, 0x70, 0xf5, 0xb4, 0xd2, 0x8b, 0x59, 0xb9, 0x64, 0x05, 0xb9, 0x0b, 0x1d, 0x6d, 0x16, 0x2e,
-	0x85, 0x35, 0xc8, 0x23, 0x38, 0xa2, 0x42, 0xe4, 0x3c, 0xa1, 0x9a, 0x97, 0x45, 0xcc, 0xd7, 0x59,
-	0x06, 0x3b, 0xde, 0x67, 0x69, 0xf8, 0x33, 0xf4, 0x31, 0xd5, 0xa8, 0x2e, 0xd7, 0x09, 0x00, 0x2e,
-	0x62, 0xbd, 0x12, 0xcc, 0xe5, 0xf1, 0xd1, 0x33, 0x5b, 0x09, 0x46, 0x3e, 0x86, 0x81, 0x64, 0x2f,
-	0x25, 0x53, 0x8b, 0xd8, 0x82, 0xad, 0x1c, 0x77, 0x9c, 0xd3, 0x66, 0xbe, 0x07, 0x1e, 0x7b, 0x25,
-	0xb8, 0x5c, 0x39, 0x39, 0x9c, 0x15, 0xfe, 0xd9, 0x04, 0xef, 0x82, 0xeb, 0x45, 0x35, 0x27, 0xf7,
-	0xa1, 0x37, 0xa7, 0x8a, 0xc5, 0x95, 0xcc, 0xdd, 0xf9, 0x5d, 0x63, 0xbf, 0x90, 0xb9, 0x61, 0x50,
-	0x89, 0xbc, 0xa4, 0x29, 0x6e, 0x3a, 0x06, 0xd6, 0x63, 0xb6, 0xef, 0x42, 0xc7, 0x08, 0xa7, 0x82,
-	0xd6, 0x69, 0xcb, 0xd0, 0x46, 0x83, 0x3c, 0x84, 0x41, 0x29, 0x33, 0x5a, 0xf0, 0x1b, 0xfc, 0x5a,
-	0x15, 0xb4, 0x71, 0x77, 0xdf, 0x49, 0x2e, 0x77, 0x3a, 0xbb, 0x73, 0x58, 0x67, 0x8f, 0x9b, 0x41,
-	0x63, 0xbf, 0xbb, 0x13, 0xb3, 0x1d, 0x78, 0xb7, 0x76, 0x37, 0xa6, 0x89, 0x6c, 0x38, 0xf9, 0x02,
-	0x88, 0x5a, 0x72, 0x11, 0xef, 0x93, 0xed, 0x22, 0xd9, 0xf7, 0xcc, 0xce, 0xd5, 0x1e, 0xe1, 0xc7,
-	0xe0, 0x95, 0xd4, 0x94, 0x2c, 0x00, 0x3c, 0xe7, 0x93, 0x9a, 0x73, 0x76, 0x6a, 0x1b, 0x39, 0x54,
-	0xf8, 0x97, 0x55, 0x3c, 0xa7, 0xb5, 0x8a, 0xff, 0xb3, 0xa4, 0xf7, 0xc0, 0xcb, 0x64, 0x59, 0x89,
-	0xb5, 0x96, 0xce, 0x7a, 0x0b, 0x44, 0x7c, 0x00, 0x7d, 0x14, 0xd1, 0xd1, 0xb3, 0xea, 0x81, 0x71,
-	0x5d, 0x58, 0x8a, 0x4f, 0xa0, 0xeb, 0xee, 0xa7, 0xd3, 0xed, 0xd1, 0x6d, 0xba, 0x59, 0xd9, 0xd6,
-	0x28, 0xf2, 0x2d, 0x74, 0x50, 0xc1, 0xa0, 0x7f, 0x08, 0xdc, 0x62, 0xc2, 0xdf, 0x9b, 0xe0, 0x8f,
-	0xb9, 0x9e, 0x57, 0xc9, 0x92, 0xe9, 0x37, 0x88, 0x6b, 0x2e, 0x1f, 0xa3, 0xd7, 0x6b, 0x6d, 0xad,
-	0xf1, 0x16, 0x48, 0x7b, 0x02, 0xa8, 0x63, 0x6c, 0xc9, 0x59, 0x65, 0x7d, 0xe3, 0x99, 0x21, 0xc1,
-	0xcd, 0x50, 0x87, 0xc3, 0x86, 0xfa, 0xff, 0xd2, 0xf3, 0xd7, 0x26, 0x74, 0x2f, 0x58, 0xc1, 0x24,
-	0x4f, 0xea, 0xba, 0x98, 0x40, 0x7b, 0x67, 0x8a, 0xe3, 0x9a, 0x7c, 0x0e, 0x44, 0x30, 0x19, 0x0b,
-	0x9a, 0xb1, 0x58, 0x50, 0x49, 0xaf, 0x99, 0x66, 0xd2, 0xcd, 0xac, 0x63, 0xc1, 0xe4, 0x84, 0x66,
-	0x6c, 0xb2, 0xf6, 0x9b, 0x39, 0xfa, 0x5a, 0xa4, 0x9d, 0x5f, 0x03, 0xb1, 0x17, 0xf6, 0x01, 0xf8,
-	0x18, 0xa6, 0xf8, 0x0d, 0xc3, 0x32, 0x75, 0xcc, 0xbc, 0xce, 0xd8, 0x94, 0xdf, 0xe0, 0x2c, 0x57,
-	0x2c, 0x67, 0x89, 0x2e, 0x25, 0x6a, 0xef, 0x47, 0x1b, 0x7b, 0x5b, 0x94, 0xee, 0x61, 0x45, 0xf9,
-	0x8f, 0xaa, 0x87, 0x1c, 0xbc, 0xa9, 0xa6, 0x9a, 0x27, 0xe4, 0x53, 0x78, 0x57, 0x32, 0x51, 0x2a,
-	0xae, 0x4b, 0xb9, 0x32, 0xe2, 0xa9, 0xa0, 0x81, 0xb5, 0x3d, 0xda, 0xba, 0x5f, 0xc8, 0x5c, 0x6d,
-	0x29, 0x36, 0x0f, 0xa2, 0x18, 0x9e, 0x40, 0x2b, 0x4a, 0x71, 0x66, 0x68, 0x2a, 0x33, 0xa6, 0x5d,
-	0x71, 0x9c, 0x15, 0xfe, 0xd1, 0x84, 0xee, 0xd3, 0x24, 0x29, 0xab, 0x42, 0x93, 0x6f, 0xc0, 0xcb,
-	0xf0, 0x11, 0xc0, 0x98, 0xfe, 0xe8, 0xa3, 0x9a, 0x33, 0xec, 0x6b, 0x11, 0x39, 0x80, 0x83, 0xe6,
-	0x74, 0xee, 0xe8, 0xdd, 0x02, 0xcd, 0xa9, 0x85, 0x9a, 0xf1, 0x37, 0x06, 0x7f, 0xbe, 0xbe, 0x93,
-	0xff, 0xe2, 0xaf, 0x62, 0x73, 0x7f, 0xa3, 0x2d, 0x8c, 0x7c, 0x07, 0xdd, 0xcc, 0xf6, 0x21, 0x36,
-	0x46, 0x7f, 0x14, 0xd6, 0x9d, 0x6f, 0x23, 0xa3, 0x35, 0xc4, 0x90, 0x57, 0x58, 0x0d, 0xec, 0x99,
-	0x7a, 0xf2, 0xb6, 0x6c, 0x91, 0x03, 0x90, 0x2f, 0xa1, 0x25, 0x53, 0xe5, 0xee, 0xf2, 0x87, 0x35,
-	0xb8, 0x28, 0x55, 0x91, 0x09, 0x0d, 0xaf, 0x60, 0x70, 0x8e, 0xae, 0x4a, 0xe2, 0x53, 0x42, 0x1e,
-	0x43, 0x8f, 0xda, 0x02, 0xd8, 0xd2, 0xd7, 0x93, 0x77, 0xb5, 0x8a, 0x36, 0x98, 0xcf, 0x42, 0x18,
-	0xec, 0xcd, 0x1b, 0xd2, 0x85, 0xd6, 0x74, 0x7a, 0x79, 0xfc, 0x0e, 0xe9, 0x41, 0xfb, 0x72, 0x36,
-	0x9b, 0x1c, 0x37, 0xc6, 0xbd, 0x1f, 0x3c, 0x8b, 0x9f, 0x7b, 0xf8, 0xbb, 0xf9, 0xd5, 0xdf, 0x01,
-	0x00, 0x00, 0xff, 0xff, 0x28, 0x2f, 0x57, 0xe7, 0x7e, 0x0a, 0x00, 0x00,
+	// 1920 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x58, 0xcd, 0x6e, 0xdc, 0xc8,
+	0x11, 0x0e, 0x87, 0xf3, 0xc7, 0xa2, 0x46, 0x92, 0x3b, 0xb2, 0xc3, 0xdd, 0x85, 0xed, 0x31, 0xbd,
+	0xde, 0xcc, 0xda, 0x89, 0x10, 0x68, 0x81, 0x05, 0x16, 0x09, 0x36, 0x90, 0xe4, 0xb5, 0x2d, 0x68,
+	0x11, 0x0b, 0x2d, 0xe5, 0x90, 0x5c, 0x88, 0x1e, 0xb2, 0x35, 0xd3, 0x2b, 0x8a, 0x64, 0xba, 0x9b,
+	0x5a, 0x8d, 0x2f, 0x41, 0x0e, 0x41, 0x0e, 0xb9, 0xe6, 0x90, 0x63, 0x72, 0x0a, 0xf2, 0x02, 0x39,
+	0xe4, 0x2d, 0xf2, 0x22, 0xc9, 0x1b, 0x04, 0x41, 0xff, 0x90, 0xc3, 0x91, 0xed, 0x19, 0xc9, 0xce,
+	0x61, 0x6f, 0xac, 0xea, 0xaa, 0xee, 0xfa, 0xeb, 0xaa, 0xaf, 0x09, 0x6b, 0x71, 0x9e, 0x9d, 0xb2,
+	0xc9, 0x76, 0xc1, 0x73, 0x99, 0xa3, 0x0f, 0xe2, 0x34, 0x2f, 0x93, 0xed, 0x84, 0x16, 0x62, 0x9b,
+	0x65, 0x09, 0xbd, 0xa4, 0x7c, 0xdb, 0x08, 0x84, 0x7f, 0x6d, 0x43, 0x67, 0x3f, 0xcd, 0x33, 0x8a,
+	0x9e, 0x42, 0x5f, 0x48, 0x4e, 0x24, 0x9d, 0xcc, 0x02, 0x67, 0xe8, 0x8c, 0xd6, 0x77, 0x46, 0xdb,
+	0x6f, 0xd5, 0xdb, 0xd6, 0x3a, 0xc7, 0x56, 0x1e, 0xd7, 0x9a, 0xe8, 0x73, 0xe8, 0x8c, 0x89, 0x60,
+	0x71, 0xd0, 0x1a, 0x3a, 0x23, 0x7f, 0x67, 0xb8, 0x64, 0x8b, 0x3d, 0x25, 0x87, 0x8d, 0x38, 0xda,
+	0x07, 0x28, 0xca, 0x71, 0xca, 0xe2, 0xe8, 0x8c, 0xce, 0x02, 0x57, 0x2b, 0x7f, 0xbc, 0x44, 0xf9,
+	0x48, 0x0b, 0x1f, 0xd2, 0x19, 0xf6, 0x8a, 0xea, 0x13, 0xdd, 0x81, 0xae, 0x28, 0x08, 0x17, 0x34,
+	0x68, 0x0f, 0x9d, 0x51, 0x1f, 0x5b, 0x0a, 0xdd, 0x03, 0x10, 0xe5, 0xf8, 0x3c, 0x4f, 0xca, 0x94,
+	0x8a, 0xa0, 0xa3, 0xd7, 0x1a, 0x1c, 0xf4, 0x43, 0xd8, 0xa8, 0xa9, 0x28, 0xa1, 0x85, 0x9c, 0x06,
+	0xdd, 0xa1, 0x33, 0xea, 0xe0, 0xf5, 0x9a, 0xfd, 0x54, 0x71, 0x95, 0x77, 0x32, 0x3f, 0xa3, 0x59,
+	0xd0, 0x5b, 0xe9, 0xdd, 0x89, 0x92, 0xc3, 0x46, 0x1c, 0xdd, 0x05, 0xc8, 0xa8, 0xe4, 0x71, 0x54,
+	0x10, 0x39, 0x0d, 0xfa, 0x43, 0x67, 0xe4, 0x61, 0x4f, 0x73, 0x8e, 0x88, 0x9c, 0xa2, 0x0f, 0xa1,
+	0x3f, 0xe6, 0x24, 0x8b, 0xa7, 0x54, 0x04, 0xde, 0xd0, 0x1d, 0x79, 0xb8, 0xa6, 0xd1, 0x23, 0x58,
+	0x37, 0xdf, 0x4a, 0x57, 0x52, 0x9e, 0x05, 0xa0, 0xd5, 0x07, 0x86, 0x7b, 0x64, 0x98, 0x08, 0x41,
+	0x5b, 0x92, 0x89, 0x08, 0x7c, 0xed, 0x9c, 0xfe, 0x46, 0xf7, 0xc1, 0x4f, 0x89, 0xa4, 0x42, 0x46,
+	0x7a, 0x69, 0x4d, 0xbb, 0x04, 0x86, 0x75, 0xa2, 0x04, 0x1e, 0xc1, 0xba, 0x24, 0x93, 0x28, 0xce,
+	0x33, 0x95, 0x3f, 0x96, 0xc9, 0x60, 0x60, 0xf6, 0x96, 0x64, 0xb2, 0x5f, 0x33, 0xc3, 0x7f, 0x39,
+	0xe0, 0xd5, 0xf1, 0x56, 0x27, 0x95, 0x82, 0x72, 0x5d, 0x23, 0x1e, 0xd6, 0xdf, 0x68, 0x04, 0x9b,
+	0x05, 0x67, 0x17, 0x44, 0x52, 0x95, 0x3e, 0xe3, 0x65, 0x4b, 0xaf, 0xaf, 0x5b, 0xfe, 0x21, 0x9d,
+	0x69, 0x57, 0xef, 0x83, 0xdf, 0x90, 0xd4, 0x89, 0xf6, 0x30, 0xcc, 0x85, 0x54, 0x2c, 0x0a, 0x22,
+	0xc4, 0xb7, 0x39, 0x4f, 0x74, 0x16, 0x3d, 0x5c, 0xd3, 0xe8, 0x13, 0xd8, 0x68, 0x1e, 0x43, 0xb3,
+	0x0b, 0x9d, 0x4c, 0x0f, 0x0f, 0xe6, 0x1b, 0x7c, 0x95, 0x5d, 0xa0, 0x07, 0xb0, 0x56, 0xe9, 0x68,
+	0xa1, 0xae, 0x16, 0xf2, 0x2b, 0xde, 0x57, 0xd9, 0x45, 0xf8, 0x07, 0x07, 0x3a, 0xba, 0x00, 0xd5,
+	0x81, 0xca, 0x87, 0x8c, 0x9c, 0x53, 0xeb, 0x53, 0x4d, 0x2f, 0x18, 0xd3, 0xba, 0x62, 0xcc, 0x43,
+	0x18, 0xd4, 0x87, 0x9c, 0xb2, 0x94, 0x5a, 0x5f, 0xea, 0x93, 0x9f, 0xb1, 0x94, 0xbe, 0x66, 0x49,
+	0xfb, 0x75, 0x4b, 0x4a, 0xe8, 0xe8, 0x5a, 0x59, 0x6a, 0xc8, 0x56, 0x55, 0x78, 0xc6, 0x8a, 0x79,
+	0x59, 0xe9, 0x8f, 0xe6, 0xf9, 0x9e, 0xe6, 0xe8, 0xc3, 0x3f, 0x02, 0x43, 0x34, 0x4e, 0xee, 0x6b,
+	0x86, 0x3a, 0xf6, 0x00, 0xe0, 0xe5, 0x6e, 0x29, 0xa7, 0xe6, 0xec, 0x7a, 0x7f, 0xa7, 0xb9, 0xff,
+	0x23, 0x58, 0x27, 0x45, 0x91, 0xb2, 0x98, 0x48, 0x96, 0x67, 0x11, 0xab, 0x82, 0x30, 0x68, 0x70,
+	0x0f, 0x92, 0xf0, 0xb7, 0xe0, 0xeb, 0xad, 0x76, 0x96, 0xed, 0x55, 0xdb, 0x2a, 0x67, 0x05, 0xb5,
+	0xfb, 0x18, 0xf3, 0x4e, 0x66, 0x05, 0x55, 0xd1, 0xe4, 0xf4, 0x94, 0x53, 0x31, 0x8d, 0x8c, 0xb2,
+	0x8d, 0xa6, 0x65, 0x9a, 0x9d, 0xef, 0x40, 0x97, 0x5e, 0x16, 0x8c, 0xcf, 0xac, 0x37, 0x96, 0x0a,
+	0xff, 0xec, 0x80, 0xf7, 0x9c, 0xc9, 0x69, 0x39, 0xde, 0x2d, 0x0a, 0x74, 0x1b, 0xba, 0xa4, 0x28,
+	0x94, 0xb5, 0xca, 0x00, 0x17, 0x77, 0x48, 0x51, 0x1c, 0x24, 0xe8, 0x53, 0xd8, 0x64, 0x99, 0x90,
+	0x24, 0x4d, 0x2b, 0x6f, 0x44, 0xd0, 0x1a, 0xba, 0x23, 0x17, 0x6f, 0x34, 0xf9, 0x07, 0x89, 0x78,
+	0x63, 0x39, 0xbb, 0xd7, 0x29, 0xe7, 0xf6, 0xd5, 0x72, 0x0e, 0xff, 0xe6, 0x42, 0xd7, 0x98, 0x86,
+	0x3e, 0x80, 0xfe, 0x98, 0x08, 0x1a, 0x95, 0x3c, 0xb5, 0xa1, 0xe9, 0x29, 0xfa, 0x97, 0x3c, 0x55,
+	0xc1, 0x29, 0x8b, 0x34, 0x27, 0x89, 0x5e, 0xb4, 0xc1, 0x31, 0x1c, 0xb5, 0xbc, 0x05, 0x1d, 0x55,
+	0x09, 0x22, 0x70, 0x75, 0x73, 0x30, 0x04, 0xfa, 0x18, 0x06, 0x39, 0x9f, 0x90, 0x8c, 0xbd, 0xd2,
+	0x86, 0x8b, 0xa0, 0xad, 0x57, 0x17, 0x99, 0xe8, 0x45, 0xa3, 0xad, 0x77, 0x6e, 0xd6, 0xd6, 0xf7,
+	0x5a, 0x81, 0xb3, 0xd8, 0xda, 0x63, 0xb5, 0x1c, 0x74, 0x57, 0x36, 0x3f, 0xbd, 0x0d, 0x36, 0xe2,
+	0xe8, 0xc7, 0x80, 0xc4, 0x19, 0x2b, 0xa2, 0x45, 0x63, 0x7b, 0xda, 0xd8, 0x5b, 0x6a, 0xe5, 0xe5,
+	0x82, 0xc1, 0x5f, 0x42, 0x37, 0x27, 0xaa, 0x9a, 0x74, 0xa3, 0xf3, 0x77, 0x3e, 0x59, 0x72, 0x4e,
+	0xa3, 0xec, 0xb0, 0xd5, 0x42, 0x9f, 0x83, 0x4b, 0x8a, 0x22, 0xf0, 0x57, 0x8e, 0x90, 0xba, 0x62,
+	0xb0, 0x52, 0x08, 0xff, 0x6d, 0x32, 0x95, 0x92, 0xa5, 0x99, 0x7a, 0x73, 0x2a, 0xee, 0x40, 0x77,
+	0xc2, 0xf3, 0xb2, 0xa8, 0x72, 0x60, 0xa9, 0xef, 0x40, 0xf0, 0xef, 0x83, 0xaf, 0x83, 0x6f, 0xcd,
+	0x33, 0x51, 0x07, 0xc5, 0x7a, 0x6e, 0x4c, 0x7c, 0x02, 0xb7, 0x58, 0x16, 0xa7, 0x65, 0x42, 0x23,
+	0x51, 0x8e, 0xad, 0x58, 0x5f, 0x4f, 0x91, 0x4d, 0xbb, 0x70, 0x5c, 0xf1, 0xcd, 0x1d, 0x32, 0xc2,
+	0x84, 0xc7, 0x53, 0x76, 0x41, 0x93, 0xc0, 0xd3, 0xb2, 0x1b, 0x96, 0xbf, 0x6b, 0xd9, 0xe8, 0xe7,
+	0xd0, 0xb3, 0xd7, 0xc0, 0xe6, 0xf1, 0xd1, 0xaa, 0x3c, 0x9a, 0x34, 0x56, 0x5a, 0xe8, 0xa7, 0xd0,
+	0xd1, 0x19, 0x0d, 0xfc, 0x9b, 0xa8, 0x1b, 0x1d, 0x14, 0xc2, 0xda, 0x05, 0x13, 0x6c, 0xcc, 0x52,
+	0x26, 0x19, 0x55, 0xb3, 0x4f, 0xf9, 0xbd, 0xc0, 0x0b, 0xff, 0xee, 0x82, 0xb7, 0xc7, 0xe4, 0xb8,
+	0x8c, 0xcf, 0xa8, 0x5c, 0x96, 0x73, 0x35, 0x05, 0x78, 0xfe, 0x0d, 0x8d, 0xa5, 0xe9, 0x18, 0x1e,
+	0xae, 0xe9, 0xb7, 0xd4, 0x83, 0x6a, 0x81, 0x94, 0x9c, 0x57, 0xe5, 0x60, 0x88, 0xef, 0x40, 0x35,
+	0xdc, 0x05, 0x9d, 0xfa, 0xc8, 0x18, 0x67, 0x8a, 0xc1, 0x53, 0x9c, 0x13, 0x6d, 0xe0, 0x43, 0x18,
+	0xe8, 0xe5, 0xda, 0xdb, 0xbe, 0x09, 0x9b, 0x62, 0x1e, 0x55, 0x1e, 0xd7, 0x08, 0x0f, 0x6e, 0x86,
+	0xf0, 0xde, 0x27, 0x9f, 0xe1, 0x3f, 0x5a, 0xd0, 0x7b, 0x4e, 0x33, 0xca, 0x59, 0xbc, 0x2c, 0x53,
+	0x08, 0xda, 0x0d, 0xec, 0xa1, 0xbf, 0xd1, 0x8f, 0x00, 0x15, 0x94, 0x47, 0x05, 0x99, 0xd0, 0xa8,
+	0x20, 0x9c, 0x9c, 0x53, 0x49, 0xb9, 0x6d, 0xe7, 0x9b, 0x05, 0xe5, 0x47, 0x64, 0x42, 0x8f, 0x2a,
+	0xbe, 0x1a, 0x79, 0x57, 0x24, 0xdb, 0x16, 0x61, 0x2c, 0x88, 0x7d, 0x04, 0x9e, 0x16, 0x13, 0xec,
+	0x15, 0xd5, 0xb9, 0xec, 0x28, 0x64, 0x30, 0xa1, 0xc7, 0xec, 0x95, 0x46, 0x0d, 0x82, 0xa6, 0x34,
+	0x96, 0x39, 0xb7, 0xd0, 0xa3, 0xa6, 0xe7, 0x99, 0xeb, 0xdd, 0x2c, 0x73, 0xef, 0x18, 0xf5, 0xf0,
+	0x37, 0xb0, 0x79, 0x2c, 0x89, 0x64, 0x31, 0xa6, 0x45, 0x2e, 0x98, 0xcc, 0xf9, 0x4c, 0xf9, 0xc8,
+	0x6b, 0xaa, 0x11, 0xc6, 0xc1, 0x9c, 0xab, 0x82, 0x59, 0x9b, 0xda, 0xba, 0x91, 0xa9, 0xe1, 0x04,
+	0xb6, 0xae, 0x1e, 0xf9, 0x35, 0x13, 0x12, 0xbd, 0x84, 0xb5, 0xfa, 0x00, 0x75, 0x27, 0x9d, 0xa1,
+	0x3b, 0xf2, 0x77, 0x9e, 0x2c, 0xd9, 0xf6, 0xea, 0x36, 0x78, 0x61, 0x83, 0xf0, 0x3f, 0x0e, 0x74,
+	0x8d, 0x88, 0x42, 0xf0, 0x8b, 0x2e, 0x99, 0xed, 0x3d, 0xbc, 0xbe, 0xe0, 0x93, 0x78, 0x57, 0xa7,
+	0x5e, 0x33, 0xde, 0x7d, 0x4f, 0xe3, 0xd1, 0x67, 0x70, 0xbb, 0x49, 0x47, 0x69, 0x6e, 0xf0, 0x94,
+	0xad, 0xb7, 0xad, 0xe6, 0xe2, 0xd7, 0x76, 0x2d, 0xfc, 0x67, 0x0b, 0x3a, 0xcf, 0x99, 0xa4, 0xe4,
+	0x5a, 0x23, 0xaa, 0xb5, 0x14, 0x2d, 0xb8, 0x6f, 0x42, 0x0b, 0x75, 0x78, 0xda, 0xff, 0x8f, 0x19,
+	0xdf, 0x79, 0xdb, 0x8c, 0x7f, 0x8f, 0x1e, 0x62, 0xd0, 0xe1, 0xcd, 0x7a, 0x88, 0xd6, 0x09, 0xff,
+	0xd2, 0x02, 0x7f, 0xf7, 0x55, 0xc9, 0xe9, 0x53, 0x7a, 0x91, 0x17, 0x62, 0x59, 0x08, 0x43, 0x58,
+	0x6b, 0x7a, 0x62, 0xfb, 0xc9, 0x02, 0x6f, 0x61, 0x2a, 0xb8, 0x57, 0xa6, 0xc2, 0xbb, 0x86, 0xf1,
+	0xb5, 0x06, 0xdc, 0x79, 0x43, 0x03, 0xfe, 0x15, 0xdc, 0x2e, 0x28, 0x17, 0x79, 0x46, 0xd2, 0x88,
+	0xc4, 0x31, 0x15, 0xc2, 0x42, 0xe6, 0x1b, 0xcd, 0xd9, 0xef, 0x57, 0x7b, 0xec, 0xea, 0x2d, 0x34,
+	0x33, 0xfc, 0xbd, 0x03, 0xeb, 0xbb, 0xdf, 0x8a, 0x7d, 0x4e, 0x13, 0x9a, 0x49, 0x46, 0x52, 0x81,
+	0x42, 0x18, 0xd8, 0x43, 0x14, 0x14, 0xb6, 0xa0, 0xda, 0xc3, 0xbe, 0x61, 0x1e, 0xd2, 0xd9, 0x41,
+	0x82, 0x1e, 0xc3, 0x2d, 0x41, 0x63, 0x4e, 0x65, 0x34, 0x17, 0xb5, 0x31, 0xdb, 0x30, 0x0b, 0xbb,
+	0x95, 0xb4, 0x76, 0x91, 0x0a, 0xa1, 0x10, 0xf8, 0x02, 0xd0, 0xb7, 0x4c, 0x63, 0xc7, 0x9f, 0x5a,
+	0x00, 0xfb, 0x79, 0x42, 0xf7, 0xf3, 0xf3, 0x73, 0x26, 0x51, 0x00, 0x3d, 0x4e, 0x27, 0xba, 0xa4,
+	0xcc, 0xad, 0xae, 0x48, 0x95, 0x43, 0x9e, 0xa7, 0x0a, 0x8d, 0x54, 0x49, 0xea, 0x29, 0x7a, 0x97,
+	0x67, 0x0a, 0xf9, 0xd0, 0x4b, 0xf5, 0x36, 0x26, 0xa9, 0x32, 0xdb, 0x1c, 0x03, 0x15, 0xeb, 0x20,
+	0x79, 0xe7, 0x24, 0x3d, 0x01, 0x5d, 0xd1, 0xd1, 0x42, 0x3f, 0x30, 0x89, 0xda, 0x54, 0x0b, 0xb8,
+	0xc1, 0x47, 0x87, 0xe0, 0xc7, 0xf3, 0x68, 0xda, 0x14, 0x7d, 0xba, 0xe4, 0xa8, 0xc5, 0xf0, 0xe3,
+	0xa6, 0x76, 0x78, 0x17, 0x5c, 0x9c, 0x68, 0xb4, 0x29, 0x09, 0x9f, 0x50, 0x69, 0x73, 0x61, 0xa9,
+	0xf0, 0xbf, 0x0e, 0x6c, 0xce, 0xfb, 0xcd, 0x33, 0x96, 0xaa, 0x49, 0x55, 0x95, 0x54, 0x8d, 0xd7,
+	0x1c, 0x8d, 0xd7, 0x74, 0x49, 0xd5, 0x60, 0xad, 0xc2, 0x05, 0xa7, 0x39, 0x3f, 0x13, 0x3a, 0x90,
+	0x7d, 0x83, 0x0b, 0x9e, 0x29, 0x86, 0x3e, 0x30, 0x2f, 0x58, 0x5c, 0x15, 0xba, 0xa5, 0x6a, 0x70,
+	0x69, 0x17, 0xdb, 0x73, 0x70, 0x79, 0x62, 0x04, 0x1e, 0xc0, 0x9a, 0x7a, 0xbe, 0xd6, 0xbf, 0x2e,
+	0xcc, 0x6b, 0xdd, 0x57, 0xbc, 0xea, 0xc7, 0xc5, 0x63, 0x1b, 0xcd, 0x05, 0xb9, 0xae, 0xad, 0x9d,
+	0x33, 0x56, 0xfc, 0xa2, 0x21, 0x7b, 0x0f, 0xfc, 0x73, 0x72, 0xa9, 0x87, 0x6e, 0x74, 0x36, 0xd6,
+	0x23, 0xd4, 0xc5, 0xde, 0x39, 0xb9, 0x54, 0x63, 0xf7, 0x70, 0x1c, 0xfe, 0xd1, 0x81, 0xbe, 0x7a,
+	0x96, 0xe1, 0x32, 0xd5, 0x2d, 0xa9, 0x31, 0x12, 0xaa, 0x9d, 0x4d, 0xc4, 0x6e, 0xcd, 0x57, 0xaa,
+	0xbd, 0x3f, 0x84, 0xbe, 0x85, 0xb0, 0x35, 0xc8, 0xab, 0x68, 0xb5, 0x46, 0x2f, 0xed, 0x9a, 0xbd,
+	0xea, 0x15, 0xad, 0x6a, 0x53, 0xe4, 0x25, 0x8f, 0x69, 0xe5, 0x7f, 0x45, 0x86, 0xbf, 0xeb, 0x42,
+	0x6f, 0x37, 0x8e, 0xf3, 0x32, 0x93, 0xe8, 0x0b, 0xe8, 0x4e, 0xf4, 0x73, 0x43, 0x1b, 0xe0, 0xef,
+	0x3c, 0x58, 0xf9, 0x2e, 0xc1, 0x56, 0xc1, 0xaa, 0xa6, 0x64, 0x1c, 0xb4, 0xae, 0xa3, 0x9a, 0x12,
+	0xa3, 0xaa, 0xde, 0x31, 0x7b, 0xe0, 0x8d, 0x2b, 0x80, 0x7b, 0x8d, 0x7f, 0x6a, 0x35, 0x18, 0xc6,
+	0x73, 0x35, 0xf4, 0x33, 0xe8, 0x4d, 0x0c, 0xf0, 0xb2, 0xf7, 0x24, 0x5c, 0x76, 0xbe, 0x91, 0xc4,
+	0x95, 0x8a, 0x32, 0x5e, 0xe8, 0x39, 0x18, 0x74, 0x56, 0x1a, 0x6f, 0x07, 0xa6, 0x55, 0x40, 0x3f,
+	0x01, 0x97, 0x27, 0xc2, 0x22, 0xdc, 0x7b, 0x4b, 0xf4, 0x70, 0x22, 0xb0, 0x12, 0x55, 0x17, 0x7a,
+	0xa2, 0x86, 0xe3, 0x35, 0xb0, 0x95, 0x1e, 0xa2, 0xd8, 0x88, 0xa3, 0x03, 0x58, 0x23, 0x6a, 0x2e,
+	0x44, 0x89, 0x1e, 0x0c, 0x41, 0x7f, 0xe5, 0xbb, 0xb3, 0x31, 0x46, 0xb0, 0x4f, 0xe6, 0x04, 0x7a,
+	0x06, 0x7e, 0x9c, 0x27, 0x34, 0x8a, 0x75, 0xe3, 0x0a, 0xbc, 0x95, 0x1d, 0x79, 0xde, 0xe5, 0x30,
+	0xc4, 0xf5, 0xb7, 0x02, 0xb2, 0xfa, 0x3f, 0xd0, 0x96, 0x01, 0xb2, 0xd5, 0xcf, 0x28, 0x11, 0x4f,
+	0xa9, 0xfa, 0x1b, 0x19, 0xdc, 0xb6, 0xb0, 0xd2, 0xd2, 0xea, 0x86, 0x7e, 0xc3, 0x54, 0x21, 0x07,
+	0x77, 0x4c, 0x4b, 0x30, 0x14, 0xda, 0x87, 0xee, 0xa9, 0xee, 0x03, 0xc1, 0x0f, 0x86, 0xce, 0x0a,
+	0xc0, 0x72, 0xb5, 0x75, 0x60, 0xab, 0x8a, 0xbe, 0x80, 0x8e, 0x42, 0xd2, 0x22, 0x08, 0x34, 0xe8,
+	0x79, 0xb8, 0xec, 0xb7, 0xac, 0xbd, 0x7d, 0xd8, 0x68, 0x84, 0x2f, 0x61, 0xb0, 0xaf, 0x57, 0x4a,
+	0x6e, 0xa6, 0xe6, 0x97, 0xd0, 0x27, 0xe6, 0x4e, 0x54, 0x00, 0x70, 0x59, 0x3d, 0xd9, 0xeb, 0x83,
+	0x6b, 0x9d, 0xc7, 0x21, 0x0c, 0x16, 0x1e, 0x46, 0xa8, 0x07, 0xee, 0xf1, 0xf1, 0x8b, 0xcd, 0xef,
+	0xa1, 0x3e, 0xb4, 0x5f, 0x9c, 0x9c, 0x1c, 0x6d, 0x3a, 0x7b, 0xfd, 0x5f, 0x77, 0x8d, 0xfe, 0xb8,
+	0xab, 0xff, 0x7f, 0x7f, 0xf6, 0xbf, 0x01, 0x00, 0x73, 0xff, 0x1c, 0x2d, 0x0f, 0x17, 0x00, 0x00,
 }
@@ -1,6 +1,11 @@
 package config_test
 
 import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/depscloud/depscloud/indexer/internal/config"
@@ -53,6 +58,9 @@ func testGitlab(t *testing.T, gitlab *config.Gitlab) {
 
 	require.Equal(t, "base_url", gitlab.BaseUrl)
 	require.Equal(t, config.CloneStrategy_HTTP, gitlab.Strategy)
+	require.True(t, gitlab.IncludeSubgroups)
+	require.True(t, gitlab.IncludeArchived)
+	require.Equal(t, []string{"public"}, gitlab.Visibilities)
 	testClone(t, gitlab.Clone)
 }
 
@@ -73,6 +81,13 @@ func testGithub(t *testing.T, github *config.Github) {
 	testClone(t, github.Clone)
 }
 
+func testGithubApp(t *testing.T, app *config.GithubApp) {
+	require.NotNil(t, app)
+	require.Equal(t, int64(1), app.AppId)
+	require.Equal(t, []int64{2}, app.InstallationIds)
+	require.Equal(t, "private_key_path", app.PrivateKeyPath)
+}
+
 func testBitbucket(t *testing.T, bitbucket *config.Bitbucket) {
 	require.NotNil(t, bitbucket)
 
@@ -95,10 +110,74 @@ func testStatic(t *testing.T, static *config.Static) {
 	require.Contains(t, static.RepositoryUrls, "repository_urls")
 
 	testClone(t, static.Clone)
+
+	require.NotNil(t, static.Clone.Token)
+	require.Equal(t, "username", static.Clone.Token.Username)
+	require.Equal(t, "token_env", static.Clone.Token.TokenEnv)
+	require.Equal(t, "netrc_path", static.Clone.NetrcPath)
+}
+
+func testGitea(t *testing.T, gitea *config.Gitea) {
+	require.NotNil(t, gitea)
+	require.Equal(t, "base_url", gitea.BaseUrl)
+	require.Equal(t, []string{"user1"}, gitea.Users)
+	require.Equal(t, []string{"org1"}, gitea.Organizations)
+	require.Equal(t, []string{"org2"}, gitea.SkipOrganizations)
+	testClone(t, gitea.Clone)
+	testOauth(t, gitea.Token)
+}
+
+func testAzureDevops(t *testing.T, azureDevops *config.AzureDevops) {
+	require.NotNil(t, azureDevops)
+	require.Equal(t, "base_url", azureDevops.BaseUrl)
+	require.Equal(t, "organization", azureDevops.Organization)
+	require.Equal(t, []string{"project1"}, azureDevops.Projects)
+	require.Equal(t, []string{"project2"}, azureDevops.SkipProjects)
+	testClone(t, azureDevops.Clone)
+	testOauth(t, azureDevops.PersonalAccessToken)
+}
+
+func testCodeCommit(t *testing.T, codeCommit *config.CodeCommit) {
+	require.NotNil(t, codeCommit)
+	require.Equal(t, []string{"us-east-1"}, codeCommit.Regions)
+	require.Equal(t, "role_arn", codeCommit.RoleArn)
+	require.Equal(t, "external_id", codeCommit.ExternalId)
+	require.Equal(t, []string{"repository1"}, codeCommit.SkipRepositories)
+	testClone(t, codeCommit.Clone)
+	require.True(t, codeCommit.Clone.Sparse)
+	require.True(t, codeCommit.Clone.Submodules)
+	require.Equal(t, int32(2), codeCommit.Clone.SubmoduleDepth)
+	require.Equal(t, []string{"release"}, codeCommit.Clone.Branches)
+	require.Equal(t, "release/*", codeCommit.Clone.BranchPattern)
+	require.Equal(t, int32(3), codeCommit.Clone.LatestTags)
+	require.Equal(t, ">=1.0.0", codeCommit.Clone.TagConstraint)
+
+	require.NotNil(t, codeCommit.Credentials)
+	require.Equal(t, "access_key_id", codeCommit.Credentials.AccessKeyId)
+	require.Equal(t, "secret_access_key", codeCommit.Credentials.SecretAccessKey)
+}
+
+func testFilter(t *testing.T, filter *config.RepositoryFilter) {
+	require.NotNil(t, filter)
+	require.True(t, filter.SkipArchived)
+	require.True(t, filter.SkipForks)
+	require.Equal(t, []string{"topic1"}, filter.Topics)
+	require.Equal(t, []string{"topic2"}, filter.SkipTopics)
+	require.Equal(t, "name_pattern", filter.NamePattern)
+	require.Equal(t, "skip_name_pattern", filter.SkipNamePattern)
+	require.Equal(t, int64(1024), filter.MaxSizeKb)
+}
+
+func testPaths(t *testing.T, paths []*config.PathRule) {
+	require.Len(t, paths, 1)
+	require.Equal(t, "repository_pattern", paths[0].RepositoryPattern)
+	require.Equal(t, []string{"include"}, paths[0].Includes)
+	require.Equal(t, []string{"exclude"}, paths[0].Excludes)
+	require.Equal(t, []string{"services/*"}, paths[0].Sources)
 }
 
 func testCommon(t *testing.T, cfg *config.Configuration) {
-	require.Len(t, cfg.Accounts, 9)
+	require.Len(t, cfg.Accounts, 14)
 
 	{
 		generic := cfg.Accounts[0].GetGeneric()
@@ -126,6 +205,8 @@ func testCommon(t *testing.T, cfg *config.Configuration) {
 	{
 		github := cfg.Accounts[4].GetGithub()
 		testGithub(t, github)
+		testFilter(t, cfg.Accounts[4].Filter)
+		testPaths(t, cfg.Accounts[4].Paths)
 	}
 
 	{
@@ -150,6 +231,41 @@ func testCommon(t *testing.T, cfg *config.Configuration) {
 		static := cfg.Accounts[8].GetStatic()
 		testStatic(t, static)
 	}
+
+	{
+		github := cfg.Accounts[9].GetGithub()
+		require.NotNil(t, github)
+		require.Equal(t, []string{"org1"}, github.Organizations)
+		testGithubApp(t, github.App)
+	}
+
+	{
+		bitbucket := cfg.Accounts[10].GetBitbucket()
+		require.NotNil(t, bitbucket)
+		require.Equal(t, "base_url", bitbucket.BaseUrl)
+		require.Equal(t, []string{"project1"}, bitbucket.Projects)
+		require.Equal(t, []string{"project2"}, bitbucket.SkipProjects)
+		testOauth(t, bitbucket.Oauth)
+	}
+
+	{
+		gitea := cfg.Accounts[11].GetGitea()
+		testGitea(t, gitea)
+	}
+
+	{
+		azureDevops := cfg.Accounts[12].GetAzureDevops()
+		testAzureDevops(t, azureDevops)
+	}
+
+	{
+		codeCommit := cfg.Accounts[13].GetCodeCommit()
+		testCodeCommit(t, codeCommit)
+
+		require.Equal(t, "codecommit", cfg.Accounts[13].Name)
+		require.Equal(t, "0 */6 * * *", cfg.Accounts[13].Schedule)
+		require.Equal(t, "10m", cfg.Accounts[13].Jitter)
+	}
 }
 
 func Test_proto(t *testing.T) {
@@ -169,3 +285,51 @@ func Test_json(t *testing.T) {
 	require.NoError(t, err)
 	testCommon(t, cfg)
 }
+
+func Test_repositories(t *testing.T) {
+	list, err := config.LoadRepositories("../../hack/config/repositories.yaml")
+	require.NoError(t, err)
+	require.Len(t, list.Repositories, 2)
+
+	require.Equal(t, "repository_url", list.Repositories[0].RepositoryUrl)
+	testClone(t, list.Repositories[0].Clone)
+
+	require.Equal(t, "repository_url_without_clone", list.Repositories[1].RepositoryUrl)
+	require.Nil(t, list.Repositories[1].Clone)
+}
+
+func Test_secrets(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"data":{"token":"ghp_token","password":"hunter2"},"metadata":{}}}`))
+	}))
+	defer vault.Close()
+
+	require.NoError(t, os.Setenv("VAULT_ADDR", vault.URL))
+	defer os.Unsetenv("VAULT_ADDR")
+
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+accounts:
+- github:
+    organizations:
+    - depscloud
+    oauth2:
+      token: "vault://secret/data/depscloud/github#token"
+    clone:
+      strategy: "HTTP"
+      basic:
+        username: "depscloud"
+        password: "vault://secret/data/depscloud/github#password"
+`), 0644))
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+
+	github := cfg.Accounts[0].GetGithub()
+	require.Equal(t, "ghp_token", github.GetOauth2().GetToken())
+	require.Equal(t, "hunter2", github.GetClone().GetBasic().GetPassword())
+}
@@ -0,0 +1,200 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/internal/secrets"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+// defaultTokenUser is used for tokens configured without a username. Forges
+// accepting tokens over basic auth ignore the username.
+const defaultTokenUser = "git"
+
+// secret returns the first of the value, the contents of the file, or the
+// environment variable that's been configured. The environment variable may
+// refer to a secret in Vault or a cloud secret manager.
+func secret(value, file, env string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+
+	if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	if env != "" {
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", env)
+		}
+		return secrets.Resolve(context.Background(), value)
+	}
+
+	return "", nil
+}
+
+// netrcEntry holds the credentials for a machine in a netrc file.
+type netrcEntry struct {
+	login    string
+	password string
+}
+
+// parseNetrc parses the machine and default entries of a netrc file. Macros
+// are skipped.
+func parseNetrc(data string) map[string]*netrcEntry {
+	entries := make(map[string]*netrcEntry)
+
+	var entry *netrcEntry
+	tokens := strings.Fields(data)
+
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			if i+1 < len(tokens) {
+				i++
+				entry = &netrcEntry{}
+				entries[tokens[i]] = entry
+			}
+		case "default":
+			entry = &netrcEntry{}
+			entries[""] = entry
+		case "login", "password", "account":
+			if i+1 >= len(tokens) {
+				break
+			}
+
+			key, value := tokens[i], tokens[i+1]
+			i++
+
+			if entry == nil {
+				continue
+			} else if key == "login" {
+				entry.login = value
+			} else if key == "password" {
+				entry.password = value
+			}
+		case "macdef":
+			// macro bodies run until a blank line, which fields can't see, so
+			// anything after a macro is ignored
+			return entries
+		}
+	}
+
+	return entries
+}
+
+// netrcAuth looks up the credentials for the host of the repository in the
+// netrc file, falling back to its default entry.
+func netrcAuth(repositoryURL, path string) (transport.AuthMethod, error) {
+	parsed, err := url.Parse(repositoryURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := parseNetrc(string(data))
+
+	entry, ok := entries[parsed.Hostname()]
+	if !ok {
+		if entry, ok = entries[""]; !ok {
+			return nil, nil
+		}
+	}
+
+	return &http.BasicAuth{
+		Username: entry.login,
+		Password: entry.password,
+	}, nil
+}
+
+func publicKeys(publicKey *config.PublicKey) (*ssh.PublicKeys, error) {
+	user := publicKey.GetUser()
+	if user == "" {
+		user = "git"
+	}
+
+	password, err := secret(publicKey.GetPassword(), "", publicKey.GetPasswordEnv())
+	if err != nil {
+		return nil, err
+	}
+
+	if privateKeyPath := publicKey.GetPrivateKeyPath(); privateKeyPath != "" {
+		return ssh.NewPublicKeysFromFile(user, privateKeyPath, password)
+	}
+
+	privateKey, err := secret(publicKey.GetPrivateKey(), "", publicKey.GetPrivateKeyEnv())
+	if err != nil {
+		return nil, err
+	} else if privateKey == "" {
+		return nil, fmt.Errorf("no private key provided")
+	}
+
+	return ssh.NewPublicKeys(user, []byte(privateKey), password)
+}
+
+// cloneAuth resolves the credentials used to clone the repository from its
+// clone configuration. Basic auth is preferred, followed by public keys,
+// tokens, and the netrc file. A nil auth method is returned when none of them
+// are configured.
+func cloneAuth(repositoryURL string, clone *config.Clone) (transport.AuthMethod, error) {
+	if basic := clone.GetBasic(); basic != nil {
+		password, err := secret(basic.GetPassword(), basic.GetPasswordFile(), basic.GetPasswordEnv())
+		if err != nil {
+			return nil, err
+		}
+
+		return &http.BasicAuth{
+			Username: basic.GetUsername(),
+			Password: password,
+		}, nil
+	}
+
+	if publicKey := clone.GetPublicKey(); publicKey != nil {
+		keys, err := publicKeys(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return keys, nil
+	}
+
+	if token := clone.GetToken(); token != nil {
+		value, err := secret(token.GetToken(), token.GetTokenFile(), token.GetTokenEnv())
+		if err != nil {
+			return nil, err
+		}
+
+		username := token.GetUsername()
+		if username == "" {
+			username = defaultTokenUser
+		}
+
+		return &http.BasicAuth{
+			Username: username,
+			Password: value,
+		}, nil
+	}
+
+	if netrcPath := clone.GetNetrcPath(); netrcPath != "" {
+		return netrcAuth(repositoryURL, netrcPath)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,76 @@
+package consumer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+func TestCloneAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+
+	netrcFile := filepath.Join(dir, "netrc")
+	require.NoError(t, ioutil.WriteFile(netrcFile, []byte(`
+machine github.com
+  login user1
+  password github-token
+default login user2 password default-token
+`), 0600))
+
+	require.NoError(t, os.Setenv("DEPSCLOUD_TEST_PASSWORD", "env-password"))
+	defer os.Unsetenv("DEPSCLOUD_TEST_PASSWORD")
+
+	testCases := []struct {
+		url      string
+		clone    *config.Clone
+		expected *http.BasicAuth
+		err      bool
+	}{
+		{"https://github.com/a/b.git", nil, nil, false},
+		{"https://github.com/a/b.git", &config.Clone{
+			Basic: &config.Basic{Username: "user", PasswordEnv: "DEPSCLOUD_TEST_PASSWORD"},
+		}, &http.BasicAuth{Username: "user", Password: "env-password"}, false},
+		{"https://github.com/a/b.git", &config.Clone{
+			Basic: &config.Basic{Username: "user", PasswordEnv: "DEPSCLOUD_TEST_MISSING"},
+		}, nil, true},
+		{"https://github.com/a/b.git", &config.Clone{
+			Token: &config.Token{TokenFile: tokenFile},
+		}, &http.BasicAuth{Username: "git", Password: "file-token"}, false},
+		{"https://github.com/a/b.git", &config.Clone{
+			NetrcPath: netrcFile,
+		}, &http.BasicAuth{Username: "user1", Password: "github-token"}, false},
+		{"https://gitlab.com/a/b.git", &config.Clone{
+			NetrcPath: netrcFile,
+		}, &http.BasicAuth{Username: "user2", Password: "default-token"}, false},
+		{"git@github.com:a/b.git", &config.Clone{
+			NetrcPath: netrcFile,
+		}, nil, false},
+	}
+
+	for _, testCase := range testCases {
+		auth, err := cloneAuth(testCase.url, testCase.clone)
+		if testCase.err {
+			require.Error(t, err)
+			continue
+		}
+
+		require.NoError(t, err)
+		if testCase.expected == nil {
+			require.Nil(t, auth)
+		} else {
+			require.Equal(t, testCase.expected, auth)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package consumer
+
+import (
+	"path"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/indexer/internal/set"
+
+	"gopkg.in/src-d/go-git.v4"
+	gitconfig "gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// refSourceURL returns the url a branch or tag is tracked under. The default
+// branch is tracked under the repository url, while other branches and tags
+// add their name as the fragment, following the url#ref convention of other
+// tools.
+func refSourceURL(repositoryURL string, ref plumbing.ReferenceName) string {
+	if ref == "" {
+		return repositoryURL
+	}
+	return repositoryURL + "#" + ref.Short()
+}
+
+// selectBranches returns the names of the branches that are listed in, or
+// match the pattern of, the clone configuration. The default branch is left
+// out as it's always indexed.
+func selectBranches(refs []*plumbing.Reference, clone *config.Clone) ([]plumbing.ReferenceName, error) {
+	listed := set.FromSlice(clone.GetBranches())
+	pattern := clone.GetBranchPattern()
+
+	defaultBranch := plumbing.ReferenceName("")
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			defaultBranch = ref.Target()
+		}
+	}
+
+	branches := make([]plumbing.ReferenceName, 0)
+	for _, ref := range refs {
+		name := ref.Name()
+		if !name.IsBranch() || name == defaultBranch {
+			continue
+		}
+
+		selected := listed.Contains(name.Short())
+		if !selected && pattern != "" {
+			matched, err := path.Match(pattern, name.Short())
+			if err != nil {
+				return nil, err
+			}
+			selected = matched
+		}
+
+		if selected {
+			branches = append(branches, name)
+		}
+	}
+
+	return branches, nil
+}
+
+// selectsRefs reports whether the clone configuration selects any branches or
+// tags beyond the default branch.
+func selectsRefs(clone *config.Clone) bool {
+	return len(clone.GetBranches()) > 0 || clone.GetBranchPattern() != "" ||
+		clone.GetTags() || clone.GetLatestTags() > 0 || clone.GetTagConstraint() != ""
+}
+
+// listRefs lists the references of the remote repository and selects the
+// branches, followed by the tags, to index from them.
+func listRefs(repositoryURL string, clone *config.Clone, auth transport.AuthMethod) ([]plumbing.ReferenceName, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{repositoryURL},
+	})
+
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return nil, err
+	}
+
+	branches, err := selectBranches(refs, clone)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := selectTags(refs, clone)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(branches, tags...), nil
+}
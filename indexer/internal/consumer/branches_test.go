@@ -0,0 +1,42 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestSelectBranches(t *testing.T) {
+	hash := plumbing.NewHash("31c68b5bfd7a7fd8c8be52385c04bc41463bc6c9")
+	refs := []*plumbing.Reference{
+		plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main"),
+		plumbing.NewHashReference("refs/heads/main", hash),
+		plumbing.NewHashReference("refs/heads/develop", hash),
+		plumbing.NewHashReference("refs/heads/release/1.0", hash),
+		plumbing.NewHashReference("refs/heads/release/2.0", hash),
+		plumbing.NewHashReference("refs/tags/release/3.0", hash),
+	}
+
+	testCases := []struct {
+		clone    *config.Clone
+		expected []plumbing.ReferenceName
+	}{
+		{&config.Clone{}, []plumbing.ReferenceName{}},
+		{&config.Clone{Branches: []string{"develop", "main"}}, []plumbing.ReferenceName{"refs/heads/develop"}},
+		{&config.Clone{BranchPattern: "release/*"}, []plumbing.ReferenceName{"refs/heads/release/1.0", "refs/heads/release/2.0"}},
+		{&config.Clone{Branches: []string{"develop"}, BranchPattern: "*"}, []plumbing.ReferenceName{"refs/heads/develop"}},
+	}
+
+	for _, testCase := range testCases {
+		branches, err := selectBranches(refs, testCase.clone)
+		require.NoError(t, err)
+		require.Equal(t, testCase.expected, branches)
+	}
+
+	require.Equal(t, "https://github.com/a/b.git", refSourceURL("https://github.com/a/b.git", ""))
+	require.Equal(t, "https://github.com/a/b.git#release/1.0", refSourceURL("https://github.com/a/b.git", "refs/heads/release/1.0"))
+}
@@ -0,0 +1,125 @@
+package consumer
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// checkout provides the paths and contents of the files in a repository.
+type checkout interface {
+	Paths(ctx context.Context) ([]string, error)
+	Read(path string) (string, error)
+}
+
+// worktreeCheckout reads files from a fully checked out work tree.
+type worktreeCheckout struct {
+	fs billy.Filesystem
+}
+
+var _ checkout = &worktreeCheckout{}
+
+func (w *worktreeCheckout) Paths(ctx context.Context) ([]string, error) {
+	queue := []string{""}
+	paths := make([]string, 0)
+
+	for len(queue) > 0 {
+		newQueue := make([]string, 0)
+		size := len(queue)
+
+		for i := 0; i < size; i++ {
+			path := queue[i]
+
+			finfos, err := w.fs.ReadDir(path)
+			if err != nil {
+				logging.FromContext(ctx).Errorf("[consumer] failed to stat path: %v", err)
+			}
+
+			for _, finfo := range finfos {
+				fpath := w.fs.Join(path, finfo.Name())
+				if finfo.IsDir() {
+					newQueue = append(newQueue, fpath)
+				} else {
+					paths = append(paths, fpath)
+				}
+			}
+		}
+
+		queue = newQueue
+	}
+
+	return paths, nil
+}
+
+func (w *worktreeCheckout) Read(path string) (string, error) {
+	file, err := w.fs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// treeCheckout reads files straight from the tree of the HEAD commit, so only
+// the files that are read ever leave the object store.
+type treeCheckout struct {
+	tree *object.Tree
+}
+
+var _ checkout = &treeCheckout{}
+
+// headTree returns the tree of the commit the repository's HEAD points to.
+func headTree(repo *git.Repository) (*object.Tree, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	return commit.Tree()
+}
+
+func newTreeCheckout(repo *git.Repository) (*treeCheckout, error) {
+	tree, err := headTree(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &treeCheckout{tree: tree}, nil
+}
+
+func (t *treeCheckout) Paths(ctx context.Context) ([]string, error) {
+	paths := make([]string, 0)
+
+	err := t.tree.Files().ForEach(func(file *object.File) error {
+		paths = append(paths, filepath.FromSlash(file.Name))
+		return nil
+	})
+
+	return paths, err
+}
+
+func (t *treeCheckout) Read(path string) (string, error) {
+	file, err := t.tree.File(filepath.ToSlash(path))
+	if err != nil {
+		return "", err
+	}
+
+	return file.Contents()
+}
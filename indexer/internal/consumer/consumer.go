@@ -2,29 +2,97 @@ package consumer
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/depscloud/api/v1alpha/deps"
 	"github.com/depscloud/api/v1alpha/extractor"
 	"github.com/depscloud/api/v1alpha/schema"
 	"github.com/depscloud/api/v1alpha/tracker"
 	"github.com/depscloud/depscloud/indexer/internal/remotes"
-
-	"github.com/sirupsen/logrus"
+	"github.com/depscloud/depscloud/indexer/internal/state"
+	"github.com/depscloud/depscloud/internal/idempotency"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/telemetry"
 
 	"gopkg.in/src-d/go-billy.v4/osfs"
 	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/cache"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
-	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
-	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
 	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The extractor reads the globs used to filter the paths of a repository from
+// the request metadata.
+const (
+	IncludeMetadataKey = "x-depscloud-include"
+	ExcludeMetadataKey = "x-depscloud-exclude"
 )
 
+// Filter describes the paths considered by the extractor. Paths must match one
+// of the includes (when provided) and none of the excludes.
+type Filter struct {
+	Includes []string
+	Excludes []string
+}
+
+func (f *Filter) context(ctx context.Context) context.Context {
+	if f == nil {
+		return ctx
+	}
+
+	pairs := make([]string, 0, 4)
+	if len(f.Includes) > 0 {
+		pairs = append(pairs, IncludeMetadataKey, strings.Join(f.Includes, ","))
+	}
+	if len(f.Excludes) > 0 {
+		pairs = append(pairs, ExcludeMetadataKey, strings.Join(f.Excludes, ","))
+	}
+
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
 // RepositoryConsumer represent the contract for consuming repositories
 type RepositoryConsumer interface {
-	Consume(repository *remotes.Repository)
+	// Consume indexes the repository, returning an error when any part of it
+	// couldn't be indexed.
+	Consume(ctx context.Context, repository *remotes.Repository) error
+}
+
+// Options tune how repositories are consumed.
+type Options struct {
+	// Sparse skips checking out the work tree of every repository.
+	Sparse bool
+	// Timeout bounds how long a single repository can take, when positive.
+	Timeout time.Duration
+	// ExtractConcurrency bounds how many repositories are extracted at once,
+	// when positive, independent of how many are being cloned.
+	ExtractConcurrency int
+	// DryRun records the files matched in each source to the report, when
+	// set, instead of extracting and storing their dependencies.
+	DryRun *Report
+	// State records what was indexed, when set, so sources whose manifests
+	// haven't changed skip extraction.
+	State *state.Store
+	// Refresh is how long an unchanged source can go without being extracted
+	// again. Sources are extracted every time when it isn't positive.
+	Refresh time.Duration
 }
 
 // NewConsumer creates a consumer process that is agnostic to the ingress channel.
@@ -32,11 +100,25 @@ func NewConsumer(
 	authMethod transport.AuthMethod,
 	desClient extractor.DependencyExtractorClient,
 	sourceService tracker.SourceServiceClient,
+	filter *Filter,
+	options *Options,
 ) RepositoryConsumer {
+	if options == nil {
+		options = &Options{}
+	}
+
+	var extractSlots chan struct{}
+	if options.ExtractConcurrency > 0 {
+		extractSlots = make(chan struct{}, options.ExtractConcurrency)
+	}
+
 	return &consumer{
 		authMethod:    authMethod,
 		desClient:     desClient,
 		sourceService: sourceService,
+		filter:        filter,
+		options:       options,
+		extractSlots:  extractSlots,
 	}
 }
 
@@ -44,165 +126,387 @@ type consumer struct {
 	authMethod    transport.AuthMethod
 	desClient     extractor.DependencyExtractorClient
 	sourceService tracker.SourceServiceClient
+	filter        *Filter
+	options       *Options
+	extractSlots  chan struct{}
 }
 
 var _ RepositoryConsumer = &consumer{}
 
-func (c *consumer) Consume(repository *remotes.Repository) {
+func (c *consumer) Consume(ctx context.Context, repository *remotes.Repository) (err error) {
 	repourl := repository.RepositoryURL
 
+	ctx, span := telemetry.Start(ctx, "indexer.repository")
+	span.SetAttribute("repository.url", repourl)
+	defer func() { span.End(err) }()
+
+	start := time.Now()
+	defer func() {
+		repositoryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	if c.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.options.Timeout)
+		defer cancel()
+	}
+
+	// per-source credentials take precedence over the global ones
+	auth, err := cloneAuth(repourl, repository.Clone)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[%s] failed to get credentials for repository: %v", repourl, err)
+		return err
+	}
+
+	if auth == nil {
+		auth = c.authMethod
+	}
+
+	// failing to list the refs doesn't stop the default branch from being
+	// indexed, but the repository is still reported as failed
+	var failed error
+
+	refs := make([]plumbing.ReferenceName, 0)
+	if selectsRefs(repository.Clone) {
+		if refs, err = listRefs(repourl, repository.Clone, auth); err != nil {
+			logging.FromContext(ctx).Errorf("[%s] failed to list branches and tags: %v", repourl, err)
+			failed = err
+		}
+	}
+
+	// the default branch is always indexed, followed by any selected branches
+	// and tags
+	if err := c.consumeRef(ctx, repository, auth, ""); err != nil {
+		failed = err
+	}
+	for _, ref := range refs {
+		if err := c.consumeRef(ctx, repository, auth, ref); err != nil {
+			failed = err
+		}
+	}
+
+	return failed
+}
+
+// consumeRef indexes a single branch or tag of the repository, where an empty
+// ref is the default branch. The ref is stored alongside the source, so the
+// dependencies of a tag are recorded against the version it released.
+func (c *consumer) consumeRef(ctx context.Context, repository *remotes.Repository, auth transport.AuthMethod, ref plumbing.ReferenceName) error {
+	sourceURL := refSourceURL(repository.RepositoryURL, ref)
+	defaultBranch := ref == ""
+
 	dir, err := ioutil.TempDir(os.TempDir(), "dis")
 	if err != nil {
-		logrus.Errorf("failed to create tempdir")
-		return
+		logging.FromContext(ctx).Errorf("failed to create tempdir")
+		return err
 	}
 
 	// ensure proper cleanup
 	defer func() {
-		logrus.Infof("[%s] cleaning up file system", repourl)
+		logging.FromContext(ctx).Infof("[%s] cleaning up file system", sourceURL)
 		if err := os.RemoveAll(dir); err != nil {
-			logrus.Errorf("failed to cleanup scratch directory: %s", err.Error())
+			logging.FromContext(ctx).Errorf("failed to cleanup scratch directory: %s", err.Error())
 		}
 	}()
 
 	fs := osfs.New(dir)
 	gitfs, err := fs.Chroot(git.GitDirName)
 	if err != nil {
-		logrus.Errorf("failed to chroot for .git: %v", err)
-		return
+		logging.FromContext(ctx).Errorf("failed to chroot for .git: %v", err)
+		return err
 	}
 
+	// sparse checkouts skip writing the work tree, reading only the files
+	// matching the manifest patterns from the object store
+	sparse := c.options.Sparse || repository.Clone.GetSparse()
+
 	storage := filesystem.NewStorage(gitfs, cache.NewObjectLRUDefault())
 	options := &git.CloneOptions{
-		URL:   repourl,
-		Depth: 1,
+		URL:           repository.RepositoryURL,
+		Auth:          auth,
+		ReferenceName: ref,
+		Depth:         1,
+		SingleBranch:  true,
+		Tags:          git.NoTags,
+		NoCheckout:    sparse,
 	}
 
-	if repository.Clone != nil {
-		if basic := repository.Clone.GetBasic(); basic != nil {
-			options.Auth = &http.BasicAuth{
-				Username: basic.GetUsername(),
-				Password: basic.GetPassword(),
-			}
-		} else if publicKey := repository.Clone.GetPublicKey(); publicKey != nil {
-			user := publicKey.GetUser()
-			if user == "" {
-				user = "git"
-			}
+	logging.FromContext(ctx).Infof("[%s] cloning repository", sourceURL)
+	cloneCtx, cloneSpan := telemetry.Start(ctx, "indexer.clone")
+	cloneSpan.SetAttribute("repository.url", repository.RepositoryURL)
+	cloneSpan.SetAttribute("repository.ref", ref.String())
 
-			password := publicKey.GetPassword()
+	cloneStart := time.Now()
+	repo, err := git.CloneContext(cloneCtx, storage, fs, options)
+	observeStage(stageClone, cloneStart, err)
+	cloneSpan.End(err)
 
-			var keys *ssh.PublicKeys
-			if privateKeyPath := publicKey.GetPrivateKeyPath(); privateKeyPath != "" {
-				keys, err = ssh.NewPublicKeysFromFile(user, privateKeyPath, password)
-			} else if privateKey := publicKey.GetPrivateKey(); privateKey != "" {
-				keys, err = ssh.NewPublicKeys(user, []byte(privateKey), password)
-			}
+	if err != nil {
+		logging.FromContext(ctx).Errorf("failed to clone: %v", err)
+		return err
+	}
 
-			if keys == nil || err != nil {
-				logrus.Errorf("[%s] failed to get public keys for repository", repourl)
-				return
-			}
+	// submodules can only be initialized into a work tree, but they're
+	// recorded either way
+	submodules := repository.Clone.GetSubmodules()
+	if submodules && !sparse {
+		depth := int(repository.Clone.GetSubmoduleDepth())
+		if depth <= 0 {
+			depth = 1
+		}
 
-			options.Auth = keys
+		if err := updateSubmodules(ctx, sourceURL, repo, depth, options.Auth); err != nil {
+			logging.FromContext(ctx).Warnf("[%s] failed to update submodules: %v", sourceURL, err)
 		}
-	} else if c.authMethod != nil {
-		options.Auth = c.authMethod
 	}
 
-	logrus.Infof("[%s] cloning repository", repourl)
-	repo, err := git.Clone(storage, fs, options)
+	var files checkout = &worktreeCheckout{fs: fs}
+	if sparse {
+		if files, err = newTreeCheckout(repo); err != nil {
+			logging.FromContext(ctx).Errorf("[%s] failed to read head tree: %v", sourceURL, err)
+			return err
+		}
+	}
 
+	logging.FromContext(ctx).Infof("[%s] walking file system", sourceURL)
+	paths, err := files.Paths(ctx)
 	if err != nil {
-		logrus.Errorf("failed to clone: %v", err)
-		return
+		logging.FromContext(ctx).Errorf("[%s] failed to list files: %v", sourceURL, err)
+		return err
 	}
 
-	logrus.Infof("[%s] walking file system", repourl)
-	queue := []string{""}
-	paths := make([]string, 0)
+	// cloning a tag detaches the head, so the requested ref is used when set
+	commit := ""
+	if head, err := repo.Head(); err == nil {
+		commit = head.Hash().String()
+		if ref == "" {
+			ref = head.Name()
+		}
+	}
 
-	for len(queue) > 0 {
-		newQueue := make([]string, 0)
-		size := len(queue)
+	sources, err := splitSources(paths, repository.Paths.GetSources())
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[%s] failed to split sources: %v", sourceURL, err)
+		return err
+	}
 
-		for i := 0; i < size; i++ {
-			path := queue[i]
+	// submodules are recorded against the repository itself
+	var submoduleFile *deps.DependencyManagementFile
+	if submodules {
+		if submoduleFile, err = submoduleManagementFile(ctx, repository.RepositoryURL, repo); err != nil {
+			logging.FromContext(ctx).Warnf("[%s] failed to read submodules: %v", sourceURL, err)
+		}
+	}
 
-			finfos, err := fs.ReadDir(path)
-			if err != nil {
-				logrus.Errorf("failed to stat path: %v", err)
-			}
+	var failed error
 
-			for _, finfo := range finfos {
-				fpath := fs.Join(path, finfo.Name())
-				if finfo.IsDir() {
-					newQueue = append(newQueue, fpath)
-				} else {
-					paths = append(paths, fpath)
-				}
-			}
+	filter := c.filter.withRule(repository.Paths)
+	for _, source := range sources {
+		var extra []*deps.DependencyManagementFile
+		if source.dir == "" && submoduleFile != nil {
+			extra = append(extra, submoduleFile)
 		}
 
-		queue = newQueue
+		url := refSourceURL(dirSourceURL(repository.RepositoryURL, source.dir), ref)
+		if err := c.consumeSource(ctx, url, ref, commit, files, source.paths, filter, extra); err != nil {
+			failed = err
+		}
 	}
 
-	logrus.Infof("[%s] matching dependency files", repourl)
-	matchedResponse, err := c.desClient.Match(context.Background(), &extractor.MatchRequest{
+	if defaultBranch && failed == nil && c.options.State != nil && c.options.DryRun == nil {
+		if err := c.options.State.Indexed(repository.RepositoryURL, commit); err != nil {
+			logging.FromContext(ctx).Warnf("[%s] failed to record indexed commit: %v", sourceURL, err)
+		}
+	}
+
+	return failed
+}
+
+// consumeSource extracts and stores the dependencies of the paths of a
+// source, along with any management files derived outside of the extractor.
+func (c *consumer) consumeSource(
+	ctx context.Context,
+	sourceURL string,
+	ref plumbing.ReferenceName,
+	commit string,
+	files checkout,
+	paths []string,
+	filter *Filter,
+	extra []*deps.DependencyManagementFile,
+) (err error) {
+	ctx, span := telemetry.Start(ctx, "indexer.source")
+	span.SetAttribute("source.url", sourceURL)
+	defer func() { span.End(err) }()
+
+	extractCtx := filter.context(ctx)
+
+	logging.FromContext(ctx).Infof("[%s] matching dependency files", sourceURL)
+	matchStart := time.Now()
+	matchedResponse, err := c.desClient.Match(extractCtx, &extractor.MatchRequest{
 		Separator: string(filepath.Separator),
 		Paths:     paths,
 	})
+	observeStage(stageMatch, matchStart, err)
 
 	if err != nil {
-		logrus.Errorf("[%s] failed to match patchs for repository", repourl)
-		return
+		logging.FromContext(ctx).Errorf("[%s] failed to match patchs for repository", sourceURL)
+		return err
+	}
+
+	if c.options.DryRun != nil {
+		c.options.DryRun.Add(&ReportEntry{
+			URL:     sourceURL,
+			Ref:     ref.String(),
+			Matched: matchedResponse.MatchedPaths,
+		})
+		return nil
 	}
 
 	fileContents := make(map[string]string)
 	for _, matched := range matchedResponse.MatchedPaths {
-		file, err := fs.Open(matched)
+		data, err := files.Read(matched)
 		if err != nil {
-			logrus.Warnf("failed to open file %s: %v", matched, err)
+			logging.FromContext(ctx).Warnf("failed to read file %s: %v", matched, err)
 			continue
 		}
 
-		data, err := ioutil.ReadAll(file)
-		if err != nil {
-			logrus.Warnf("failed to read file %s: %v", matched, err)
-			continue
-		}
+		fileContents[matched] = data
+	}
 
-		fileContents[matched] = string(data)
+	digest, err := manifestDigest(ref, fileContents, extra)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[%s] failed to derive manifest digest: %v", sourceURL, err)
+		return err
 	}
 
-	logrus.Infof("[%s] extracting dependencies", repourl)
-	extractResponse, err := c.desClient.Extract(context.Background(), &extractor.ExtractRequest{
-		Url:          repourl,
+	if c.options.State != nil && c.options.State.Unchanged(sourceURL, digest, c.options.Refresh) {
+		logging.FromContext(ctx).Infof("[%s] manifests unchanged, skipping extraction", sourceURL)
+		return nil
+	}
+
+	if c.extractSlots != nil {
+		select {
+		case c.extractSlots <- struct{}{}:
+		case <-ctx.Done():
+			logging.FromContext(ctx).Errorf("[%s] timed out waiting to extract dependencies", sourceURL)
+			return ctx.Err()
+		}
+	}
+
+	logging.FromContext(ctx).Infof("[%s] extracting dependencies", sourceURL)
+	extractStart := time.Now()
+	extractHeader := metadata.MD{}
+	extractResponse, err := c.desClient.Extract(extractCtx, &extractor.ExtractRequest{
+		Url:          sourceURL,
 		Separator:    string(filepath.Separator),
 		FileContents: fileContents,
-	})
+	}, grpc.Header(&extractHeader))
 
-	if err != nil {
-		logrus.Errorf("failed to extract deps from repo: %s", repourl)
-		return
+	observeStage(stageExtract, extractStart, err)
+
+	if c.extractSlots != nil {
+		<-c.extractSlots
 	}
 
-	ref := ""
-	if head, err := repo.Head(); err == nil {
-		ref = head.Name().String()
+	if err != nil {
+		logging.FromContext(ctx).Errorf("failed to extract deps from repo: %s", sourceURL)
+		return err
 	}
 
-	logrus.Infof("[%s] storing dependencies", repourl)
-	_, err = c.sourceService.Track(context.Background(), &tracker.SourceRequest{
+	reportDiagnostics(ctx, sourceURL, extractHeader)
+
+	request := &tracker.SourceRequest{
 		Source: &schema.Source{
-			Url:  repourl,
+			Url:  sourceURL,
 			Kind: "repository",
-			Ref:  ref,
+			Ref:  ref.String(),
 		},
-		ManagementFiles: extractResponse.GetManagementFiles(),
+		ManagementFiles: append(extractResponse.GetManagementFiles(), extra...),
+	}
+
+	logging.FromContext(ctx).Infof("[%s] storing dependencies", sourceURL)
+	storeStart := time.Now()
+	err = c.track(ctx, commit, digest, request)
+	observeStage(stageStore, storeStart, err)
+
+	if err != nil {
+		logging.FromContext(ctx).Errorf("failed to update deps for repo: %s, %v", sourceURL, err)
+		return err
+	}
+
+	if c.options.State != nil {
+		if err := c.options.State.Extracted(sourceURL, digest); err != nil {
+			logging.FromContext(ctx).Warnf("[%s] failed to record manifest digest: %v", sourceURL, err)
+		}
+	}
+	return nil
+}
+
+// manifestDigest identifies the manifests of a source at a ref, so a source
+// whose manifests haven't changed can skip extraction.
+func manifestDigest(ref plumbing.ReferenceName, fileContents map[string]string, extra []*deps.DependencyManagementFile) (string, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"ref":   ref.String(),
+		"files": fileContents,
+		"extra": extra,
 	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// maxTrackAttempts bounds how often storing a source is attempted while the
+// tracker is unavailable.
+const maxTrackAttempts = 3
 
+// trackBackoff is how long the first retry waits, growing with each attempt.
+var trackBackoff = 250 * time.Millisecond
+
+// track stores the dependencies of a source. Transient failures are retried
+// using the same idempotency key, so a write the tracker applied before its
+// response was lost isn't applied twice.
+func (c *consumer) track(ctx context.Context, commit, digest string, request *tracker.SourceRequest) error {
+	key, err := deliveryKey(commit, digest)
 	if err != nil {
-		logrus.Errorf("failed to update deps for repo: %s, %v", repourl, err)
+		return err
 	}
+
+	ctx = idempotency.AppendToOutgoingContext(ctx, key)
+	for attempt := 1; ; attempt++ {
+		_, err = c.sourceService.Track(ctx, request)
+		if err == nil || attempt == maxTrackAttempts || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt) * trackBackoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// deliveryKey identifies a single attempt at storing the dependencies of a
+// source. It's derived from the commit and manifests being stored along with
+// a random nonce, so returning to earlier contents, such as after a revert,
+// is stored again rather than matching the key of the earlier write.
+func deliveryKey(commit, digest string) (string, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(commit + "\x00" + digest))
+	return hex.EncodeToString(sum[:]) + "." + hex.EncodeToString(nonce), nil
 }
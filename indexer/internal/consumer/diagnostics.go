@@ -0,0 +1,70 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// DiagnosticsMetadataKey is the response metadata the extractor returns the
+// problems it found with the files of a source under, since the response of
+// the api can't carry them.
+const DiagnosticsMetadataKey = "extraction-diagnostics-bin"
+
+var extractionDiagnostics = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "indexer_extraction_diagnostics_total",
+	Help: "The number of files the extractor reported problems with, by severity and code.",
+}, []string{"severity", "code"})
+
+// Diagnostic describes a file the extractor skipped or may have only
+// partially extracted, such as an unparseable manifest.
+type Diagnostic struct {
+	Path      string `json:"path"`
+	Extractor string `json:"extractor"`
+	Severity  string `json:"severity"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+}
+
+// diagnosticsFromMetadata decodes the diagnostics returned by the extractor.
+// Extractors that predate diagnostics don't return any.
+func diagnosticsFromMetadata(md metadata.MD) ([]*Diagnostic, error) {
+	diagnostics := make([]*Diagnostic, 0)
+
+	for _, value := range md.Get(DiagnosticsMetadataKey) {
+		decoded := make([]*Diagnostic, 0)
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			return nil, err
+		}
+
+		diagnostics = append(diagnostics, decoded...)
+	}
+
+	return diagnostics, nil
+}
+
+// reportDiagnostics logs the problems the extractor found with the files of
+// the source, so missing dependencies can be traced back to their manifest.
+func reportDiagnostics(ctx context.Context, sourceURL string, md metadata.MD) {
+	diagnostics, err := diagnosticsFromMetadata(md)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("[%s] failed to decode extraction diagnostics: %v", sourceURL, err)
+		return
+	}
+
+	for _, d := range diagnostics {
+		extractionDiagnostics.WithLabelValues(d.Severity, d.Code).Inc()
+
+		if d.Severity == "error" {
+			logging.FromContext(ctx).Errorf("[%s] failed to extract %s: %s (%s)", sourceURL, d.Path, d.Message, d.Code)
+		} else {
+			logging.FromContext(ctx).Warnf("[%s] problem extracting %s: %s (%s)", sourceURL, d.Path, d.Message, d.Code)
+		}
+	}
+}
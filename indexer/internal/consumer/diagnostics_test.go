@@ -0,0 +1,33 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestDiagnosticsFromMetadata(t *testing.T) {
+	diagnostics, err := diagnosticsFromMetadata(metadata.MD{})
+	require.Nil(t, err)
+	require.Empty(t, diagnostics)
+
+	md := metadata.Pairs(DiagnosticsMetadataKey,
+		`[{"path":"go.mod","extractor":"GoModExtractor","severity":"warning","code":"unsupported_syntax","message":"unsupported directive: toolchain"}]`)
+
+	diagnostics, err = diagnosticsFromMetadata(md)
+	require.Nil(t, err)
+	require.Equal(t, []*Diagnostic{
+		{
+			Path:      "go.mod",
+			Extractor: "GoModExtractor",
+			Severity:  "warning",
+			Code:      "unsupported_syntax",
+			Message:   "unsupported directive: toolchain",
+		},
+	}, diagnostics)
+
+	_, err = diagnosticsFromMetadata(metadata.Pairs(DiagnosticsMetadataKey, "{"))
+	require.NotNil(t, err)
+}
@@ -0,0 +1,51 @@
+package consumer
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The stages each source goes through while being indexed.
+const (
+	stageClone   = "clone"
+	stageMatch   = "match"
+	stageExtract = "extract"
+	stageStore   = "store"
+)
+
+var (
+	stageCompleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_stage_completed_total",
+		Help: "The number of sources that completed each stage of indexing (clone, match, extract, store).",
+	}, []string{"stage"})
+
+	stageFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_stage_failed_total",
+		Help: "The number of sources that failed each stage of indexing (clone, match, extract, store).",
+	}, []string{"stage"})
+
+	stageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "indexer_stage_duration_seconds",
+		Help:    "How long each stage of indexing a source took.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 16),
+	}, []string{"stage"})
+
+	repositoryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "indexer_repository_duration_seconds",
+		Help:    "How long indexing a repository took, across all of its branches and tags.",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 16),
+	})
+)
+
+// observeStage records how a stage that began at start went.
+func observeStage(stage string, start time.Time, err error) {
+	stageDuration.WithLabelValues(stage).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		stageFailed.WithLabelValues(stage).Inc()
+	} else {
+		stageCompleted.WithLabelValues(stage).Inc()
+	}
+}
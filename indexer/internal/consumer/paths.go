@@ -0,0 +1,113 @@
+package consumer
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+
+	"github.com/pkg/errors"
+)
+
+// withRule returns the filter of a repository with the path rule. The includes
+// of the rule replace the global ones, narrowing the repository down to the
+// manifests relevant to it, while its excludes add to the global ones.
+func (f *Filter) withRule(rule *config.PathRule) *Filter {
+	if rule == nil {
+		return f
+	}
+
+	filter := &Filter{}
+	if f != nil {
+		filter.Includes = f.Includes
+		filter.Excludes = append(filter.Excludes, f.Excludes...)
+	}
+
+	if len(rule.GetIncludes()) > 0 {
+		filter.Includes = rule.GetIncludes()
+	}
+	filter.Excludes = append(filter.Excludes, rule.GetExcludes()...)
+
+	return filter
+}
+
+// dirSourceURL returns the url a directory of a repository is tracked under,
+// following the url//dir convention used by terraform and go-getter.
+func dirSourceURL(repositoryURL, dir string) string {
+	if dir == "" {
+		return repositoryURL
+	}
+	return repositoryURL + "//" + dir
+}
+
+// pathSource is a directory of a repository that's indexed as its own source,
+// where an empty directory is the repository itself.
+type pathSource struct {
+	dir   string
+	paths []string
+}
+
+// splitSources groups the paths of a repository by the source directory they
+// belong to. Directories are selected by globs (such as services/*), with the
+// first matching glob winning. The repository itself comes first, holding the
+// paths outside of every source directory, followed by the directories in
+// order.
+func splitSources(paths []string, globs []string) ([]*pathSource, error) {
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid source glob %s", glob)
+		}
+	}
+
+	root := &pathSource{paths: make([]string, 0, len(paths))}
+	sources := make(map[string]*pathSource)
+
+	for _, p := range paths {
+		dir := sourceDir(filepath.ToSlash(p), globs)
+		if dir == "" {
+			root.paths = append(root.paths, p)
+			continue
+		}
+
+		if _, ok := sources[dir]; !ok {
+			sources[dir] = &pathSource{dir: dir}
+		}
+		sources[dir].paths = append(sources[dir].paths, p)
+	}
+
+	dirs := make([]string, 0, len(sources))
+	for dir := range sources {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	result := []*pathSource{root}
+	for _, dir := range dirs {
+		result = append(result, sources[dir])
+	}
+	return result, nil
+}
+
+// sourceDir returns the source directory containing the path, or an empty
+// string when it's outside of all of them.
+func sourceDir(p string, globs []string) string {
+	segments := strings.Split(p, "/")
+
+	for _, glob := range globs {
+		depth := len(strings.Split(strings.Trim(glob, "/"), "/"))
+
+		// the path must be a file within the directory
+		if len(segments) <= depth {
+			continue
+		}
+
+		dir := strings.Join(segments[:depth], "/")
+		if matched, _ := path.Match(strings.Trim(glob, "/"), dir); matched {
+			return dir
+		}
+	}
+
+	return ""
+}
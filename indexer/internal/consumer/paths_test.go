@@ -0,0 +1,64 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSources(t *testing.T) {
+	paths := []string{
+		"package.json",
+		"services/api/go.mod",
+		"services/api/internal/go.mod",
+		"services/web/package.json",
+		"services/README.md",
+		"libs/shared/pom.xml",
+	}
+
+	sources, err := splitSources(paths, nil)
+	require.NoError(t, err)
+	require.Equal(t, []*pathSource{{paths: paths}}, sources)
+
+	sources, err = splitSources(paths, []string{"services/*", "libs/*/"})
+	require.NoError(t, err)
+	require.Equal(t, []*pathSource{
+		{paths: []string{"package.json", "services/README.md"}},
+		{dir: "libs/shared", paths: []string{"libs/shared/pom.xml"}},
+		{dir: "services/api", paths: []string{"services/api/go.mod", "services/api/internal/go.mod"}},
+		{dir: "services/web", paths: []string{"services/web/package.json"}},
+	}, sources)
+
+	_, err = splitSources(paths, []string{"services/["})
+	require.Error(t, err)
+
+	require.Equal(t, "https://github.com/a/b.git", dirSourceURL("https://github.com/a/b.git", ""))
+	require.Equal(t, "https://github.com/a/b.git//services/api#v1.0.0",
+		refSourceURL(dirSourceURL("https://github.com/a/b.git", "services/api"), "refs/tags/v1.0.0"))
+}
+
+func TestFilterWithRule(t *testing.T) {
+	global := &Filter{
+		Includes: []string{"**/package.json"},
+		Excludes: []string{"**/node_modules/**"},
+	}
+
+	require.Equal(t, global, global.withRule(nil))
+
+	require.Equal(t, &Filter{
+		Includes: []string{"**/package.json"},
+		Excludes: []string{"**/node_modules/**", "**/testdata/**"},
+	}, global.withRule(&config.PathRule{Excludes: []string{"**/testdata/**"}}))
+
+	require.Equal(t, &Filter{
+		Includes: []string{"services/**"},
+		Excludes: []string{"**/node_modules/**"},
+	}, global.withRule(&config.PathRule{Includes: []string{"services/**"}}))
+
+	var none *Filter
+	require.Equal(t, &Filter{
+		Includes: []string{"services/**"},
+	}, none.withRule(&config.PathRule{Includes: []string{"services/**"}}))
+}
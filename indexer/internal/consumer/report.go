@@ -0,0 +1,72 @@
+package consumer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ReportEntry describes a source that would have been indexed.
+type ReportEntry struct {
+	URL     string
+	Ref     string
+	Matched []string
+}
+
+// Report collects the sources matched during a dry run.
+type Report struct {
+	mu      sync.Mutex
+	entries []*ReportEntry
+}
+
+// Add records the source and the paths matched within it.
+func (r *Report) Add(entry *ReportEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, entry)
+}
+
+// Entries returns the recorded sources, ordered by their url.
+func (r *Report) Entries() []*ReportEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]*ReportEntry, len(r.entries))
+	copy(entries, r.entries)
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].URL < entries[j].URL
+	})
+
+	return entries
+}
+
+// Write renders the report, listing each source followed by its matched
+// paths.
+func (r *Report) Write(w io.Writer) error {
+	entries := r.Entries()
+
+	matched := 0
+	for _, entry := range entries {
+		if _, err := fmt.Fprintf(w, "%s (%s)\n", entry.URL, entry.Ref); err != nil {
+			return err
+		}
+
+		paths := make([]string, len(entry.Matched))
+		copy(paths, entry.Matched)
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			if _, err := fmt.Fprintf(w, "  %s\n", path); err != nil {
+				return err
+			}
+		}
+
+		matched += len(paths)
+	}
+
+	_, err := fmt.Fprintf(w, "%d sources would be indexed, matching %d files\n", len(entries), matched)
+	return err
+}
@@ -0,0 +1,32 @@
+package consumer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	report := &Report{}
+	report.Add(&ReportEntry{
+		URL:     "https://github.com/depscloud/depscloud.git//services/api",
+		Ref:     "refs/heads/main",
+		Matched: []string{"services/api/go.mod"},
+	})
+	report.Add(&ReportEntry{
+		URL:     "https://github.com/depscloud/depscloud.git",
+		Ref:     "refs/heads/main",
+		Matched: []string{"package.json", "go.mod"},
+	})
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, report.Write(buffer))
+	require.Equal(t, `https://github.com/depscloud/depscloud.git (refs/heads/main)
+  go.mod
+  package.json
+https://github.com/depscloud/depscloud.git//services/api (refs/heads/main)
+  services/api/go.mod
+2 sources would be indexed, matching 3 files
+`, buffer.String())
+}
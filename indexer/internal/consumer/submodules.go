@@ -0,0 +1,115 @@
+package consumer
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/deps"
+	"github.com/depscloud/depscloud/indexer/internal/remotes"
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"github.com/golang/protobuf/proto"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+// Submodules are reported as a management file of their own, where the
+// repository depends on the repository of each submodule at its commit.
+const (
+	SubmoduleLanguage = "git"
+	SubmoduleSystem   = "submodule"
+)
+
+// repositoryName normalizes the url of a repository to its host and path, so
+// the http and ssh urls of a repository share a name. Relative urls, as
+// allowed in .gitmodules, are resolved against the parent's name.
+func repositoryName(parent, repositoryURL string) string {
+	if strings.HasPrefix(repositoryURL, "./") || strings.HasPrefix(repositoryURL, "../") {
+		return strings.TrimSuffix(strings.TrimSuffix(path.Join(parent, repositoryURL), "/"), ".git")
+	}
+	return remotes.RepositoryName(repositoryURL)
+}
+
+// submoduleManagementFile describes the submodules of the repository's HEAD,
+// returning nil when it has none.
+func submoduleManagementFile(ctx context.Context, repositoryURL string, repo *git.Repository) (*deps.DependencyManagementFile, error) {
+	tree, err := headTree(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := tree.File(".gitmodules")
+	if err == object.ErrFileNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	modules := config.NewModules()
+	if err := modules.Unmarshal([]byte(contents)); err != nil {
+		return nil, err
+	}
+
+	name := repositoryName("", repositoryURL)
+	managementFile := &deps.DependencyManagementFile{
+		Language: proto.String(SubmoduleLanguage),
+		System:   proto.String(SubmoduleSystem),
+		Name:     proto.String(name),
+	}
+
+	for _, submodule := range modules.Submodules {
+		// the commit of the submodule is recorded in the tree of its parent
+		entry, err := tree.FindEntry(submodule.Path)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("[%s] failed to find submodule %s: %v", repositoryURL, submodule.Path, err)
+			continue
+		}
+
+		managementFile.Dependencies = append(managementFile.Dependencies, &deps.Dependency{
+			Name:              proto.String(repositoryName(name, submodule.URL)),
+			VersionConstraint: proto.String(entry.Hash.String()),
+		})
+	}
+
+	return managementFile, nil
+}
+
+// updateSubmodules initializes the submodules of the repository, along with
+// their nested submodules up to the given depth. Submodules that fail to
+// update are logged and skipped.
+func updateSubmodules(ctx context.Context, repositoryURL string, repo *git.Repository, depth int, auth transport.AuthMethod) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return err
+	}
+
+	for _, submodule := range submodules {
+		logging.FromContext(ctx).Infof("[%s] updating submodule %s", repositoryURL, submodule.Config().Path)
+
+		err := submodule.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+			Init:              true,
+			RecurseSubmodules: git.SubmoduleRescursivity(depth - 1),
+			Auth:              auth,
+		})
+
+		if err != nil {
+			logging.FromContext(ctx).Warnf("[%s] failed to update submodule %s: %v", repositoryURL, submodule.Config().Path, err)
+		}
+	}
+
+	return nil
+}
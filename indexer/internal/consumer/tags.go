@@ -0,0 +1,66 @@
+package consumer
+
+import (
+	"sort"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/internal/constraints"
+
+	"github.com/pkg/errors"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// tagConstraintSystem is the system whose semantics tag constraints are
+// interpreted with, allowing ranges such as ^1.2.0 or >=2.0.0 <3.0.0.
+const tagConstraintSystem = "npm"
+
+// selectTags returns the names of the tags to index, ordered from the newest
+// version to the oldest. Tags are only ordered and filtered by version when a
+// constraint or a number of the latest tags is configured, in which case tags
+// that aren't versions are left out.
+func selectTags(refs []*plumbing.Reference, clone *config.Clone) ([]plumbing.ReferenceName, error) {
+	tags := make([]plumbing.ReferenceName, 0)
+
+	latest := int(clone.GetLatestTags())
+	constraint := clone.GetTagConstraint()
+	if !clone.GetTags() && latest <= 0 && constraint == "" {
+		return tags, nil
+	}
+
+	versioned := latest > 0 || constraint != ""
+
+	parsed := constraints.Any
+	if constraint != "" {
+		var err error
+		if parsed, err = constraints.Parse(tagConstraintSystem, constraint); err != nil {
+			return nil, errors.Wrap(err, "invalid tag constraint")
+		}
+	}
+
+	for _, ref := range refs {
+		name := ref.Name()
+		if !name.IsTag() {
+			continue
+		}
+
+		version := name.Short()
+		if versioned && (!constraints.IsVersion(version) || !parsed.Satisfies(version)) {
+			continue
+		}
+
+		tags = append(tags, name)
+	}
+
+	if versioned {
+		sort.SliceStable(tags, func(i, j int) bool {
+			return constraints.Compare(tags[i].Short(), tags[j].Short()) > 0
+		})
+	}
+
+	if latest > 0 && len(tags) > latest {
+		tags = tags[:latest]
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,53 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestSelectTags(t *testing.T) {
+	hash := plumbing.NewHash("31c68b5bfd7a7fd8c8be52385c04bc41463bc6c9")
+	refs := []*plumbing.Reference{
+		plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main"),
+		plumbing.NewHashReference("refs/heads/main", hash),
+		plumbing.NewHashReference("refs/heads/v3.0.0", hash),
+		plumbing.NewHashReference("refs/tags/nightly", hash),
+		plumbing.NewHashReference("refs/tags/v1.2.0", hash),
+		plumbing.NewHashReference("refs/tags/v1.10.0", hash),
+		plumbing.NewHashReference("refs/tags/v2.0.0-rc.1", hash),
+		plumbing.NewHashReference("refs/tags/v2.0.0", hash),
+	}
+
+	testCases := []struct {
+		clone    *config.Clone
+		expected []plumbing.ReferenceName
+		err      bool
+	}{
+		{&config.Clone{}, []plumbing.ReferenceName{}, false},
+		{&config.Clone{Tags: true}, []plumbing.ReferenceName{
+			"refs/tags/nightly", "refs/tags/v1.2.0", "refs/tags/v1.10.0", "refs/tags/v2.0.0-rc.1", "refs/tags/v2.0.0",
+		}, false},
+		{&config.Clone{LatestTags: 2}, []plumbing.ReferenceName{"refs/tags/v2.0.0", "refs/tags/v2.0.0-rc.1"}, false},
+		{&config.Clone{TagConstraint: "^1.0.0"}, []plumbing.ReferenceName{"refs/tags/v1.10.0", "refs/tags/v1.2.0"}, false},
+		{&config.Clone{TagConstraint: ">=1.5.0", LatestTags: 1}, []plumbing.ReferenceName{"refs/tags/v2.0.0"}, false},
+		{&config.Clone{TagConstraint: "[1.0"}, nil, true},
+	}
+
+	for _, testCase := range testCases {
+		tags, err := selectTags(refs, testCase.clone)
+		if testCase.err {
+			require.Error(t, err)
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Equal(t, testCase.expected, tags)
+	}
+
+	require.Equal(t, "https://github.com/a/b.git#v1.2.0", refSourceURL("https://github.com/a/b.git", "refs/tags/v1.2.0"))
+}
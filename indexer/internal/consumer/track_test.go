@@ -0,0 +1,94 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/deps"
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/idempotency"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeSourceService applies each key once, like the tracker, and can lose the
+// response of the write it applies.
+type fakeSourceService struct {
+	tracker.SourceServiceClient
+
+	keys     map[string]bool
+	applied  int
+	current  *tracker.SourceRequest
+	failures int
+}
+
+func (f *fakeSourceService) Track(ctx context.Context, in *tracker.SourceRequest, opts ...grpc.CallOption) (*tracker.TrackResponse, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	key := md.Get(idempotency.MetadataKey)[0]
+
+	if !f.keys[key] {
+		f.keys[key] = true
+		f.applied++
+		f.current = in
+	}
+
+	if f.failures > 0 {
+		f.failures--
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}
+	return &tracker.TrackResponse{Tracking: true}, nil
+}
+
+func sourceRequest(version string) *tracker.SourceRequest {
+	return &tracker.SourceRequest{
+		Source: &schema.Source{Url: "https://github.com/depscloud/depscloud.git", Kind: "repository", Ref: "refs/heads/main"},
+		ManagementFiles: []*deps.DependencyManagementFile{
+			{Language: proto.String("go"), Name: proto.String("github.com/depscloud/depscloud"), Dependencies: []*deps.Dependency{
+				{Name: proto.String("github.com/depscloud/api"), VersionConstraint: proto.String(version)},
+			}},
+		},
+	}
+}
+
+func TestTrack_Revert(t *testing.T) {
+	service := &fakeSourceService{keys: make(map[string]bool)}
+	c := &consumer{sourceService: service}
+	ctx := context.Background()
+
+	a, b := sourceRequest("v0.1.0"), sourceRequest("v0.2.0")
+
+	require.Nil(t, c.track(ctx, "c1", "a", a))
+	require.Nil(t, c.track(ctx, "c2", "b", b))
+
+	// reverting to the same commit and manifests is still stored
+	require.Nil(t, c.track(ctx, "c1", "a", a))
+
+	require.Equal(t, 3, service.applied)
+	require.Equal(t, a, service.current)
+}
+
+func TestTrack_Retry(t *testing.T) {
+	trackBackoff = time.Millisecond
+	defer func() { trackBackoff = 250 * time.Millisecond }()
+
+	service := &fakeSourceService{keys: make(map[string]bool), failures: 1}
+	c := &consumer{sourceService: service}
+
+	// the retry reuses the key of the write whose response was lost
+	require.Nil(t, c.track(context.Background(), "c1", "a", sourceRequest("v0.1.0")))
+	require.Equal(t, 1, service.applied)
+
+	service.failures = maxTrackAttempts
+	err := c.track(context.Background(), "c2", "b", sourceRequest("v0.2.0"))
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, 2, service.applied)
+}
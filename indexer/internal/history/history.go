@@ -0,0 +1,205 @@
+package history
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/remotes"
+)
+
+// Failure is a repository that a run failed to index.
+type Failure struct {
+	RepositoryURL string `json:"repositoryUrl"`
+	Reason        string `json:"reason"`
+}
+
+// Run summarizes a single run of a source.
+type Run struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Total      int        `json:"total"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	Skipped    int        `json:"skipped"`
+	Failures   []Failure  `json:"failures"`
+}
+
+// Attempt is the last time a repository was indexed.
+type Attempt struct {
+	RepositoryURL string    `json:"repositoryUrl"`
+	RunID         int       `json:"runId"`
+	Run           string    `json:"run"`
+	At            time.Time `json:"at"`
+	Succeeded     bool      `json:"succeeded"`
+	Reason        string    `json:"reason,omitempty"`
+}
+
+// History keeps the most recent runs, along with the last attempt at indexing
+// each repository, so operators can find out why a repository is missing from
+// the graph.
+type History struct {
+	size int
+	now  func() time.Time
+
+	mu       sync.Mutex
+	nextID   int
+	runs     []*Run
+	attempts map[string]*Attempt
+}
+
+// New constructs a history that keeps the given number of runs.
+func New(size int) *History {
+	if size < 1 {
+		size = 1
+	}
+
+	return &History{
+		size:     size,
+		now:      time.Now,
+		nextID:   1,
+		attempts: make(map[string]*Attempt),
+	}
+}
+
+// Start records the start of a run with the name, which indexes the given
+// number of repositories.
+func (h *History) Start(name string, total int) *Recorder {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	run := &Run{
+		ID:        h.nextID,
+		Name:      name,
+		StartedAt: h.now(),
+		Total:     total,
+		Failures:  make([]Failure, 0),
+	}
+	h.nextID++
+
+	h.runs = append(h.runs, run)
+	if len(h.runs) > h.size {
+		h.runs = h.runs[len(h.runs)-h.size:]
+	}
+
+	return &Recorder{history: h, run: run}
+}
+
+// Runs returns the recorded runs, most recent first.
+func (h *History) Runs() []Run {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	runs := make([]Run, 0, len(h.runs))
+	for i := len(h.runs) - 1; i >= 0; i-- {
+		run := *h.runs[i]
+		run.Failures = append([]Failure{}, run.Failures...)
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// LastAttempt returns the last attempt at indexing the repository, or nil when
+// it hasn't been attempted.
+func (h *History) LastAttempt(repositoryURL string) *Attempt {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if attempt, ok := h.attempts[remotes.CanonicalURL(repositoryURL)]; ok {
+		copied := *attempt
+		return &copied
+	}
+	return nil
+}
+
+// ServeHTTP lists the recorded runs, or the last attempt at indexing a single
+// repository when it's given by the repository query parameter.
+func (h *History) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body interface{}
+	if repositoryURL := request.URL.Query().Get("repository"); repositoryURL != "" {
+		attempt := h.LastAttempt(repositoryURL)
+		if attempt == nil {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body = attempt
+	} else {
+		body = map[string]interface{}{
+			"runs": h.Runs(),
+		}
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(writer).Encode(body)
+}
+
+// Recorder records the outcome of a single run. A nil recorder records
+// nothing.
+type Recorder struct {
+	history *History
+	run     *Run
+}
+
+// Indexed records the outcome of indexing the repository.
+func (r *Recorder) Indexed(repositoryURL string, err error) {
+	if r == nil {
+		return
+	}
+
+	r.history.mu.Lock()
+	defer r.history.mu.Unlock()
+
+	attempt := &Attempt{
+		RepositoryURL: repositoryURL,
+		RunID:         r.run.ID,
+		Run:           r.run.Name,
+		At:            r.history.now(),
+		Succeeded:     err == nil,
+	}
+
+	if err != nil {
+		attempt.Reason = err.Error()
+		r.run.Failed++
+		r.run.Failures = append(r.run.Failures, Failure{
+			RepositoryURL: repositoryURL,
+			Reason:        err.Error(),
+		})
+	} else {
+		r.run.Succeeded++
+	}
+
+	r.history.attempts[remotes.CanonicalURL(repositoryURL)] = attempt
+}
+
+// Skipped records repositories the run skipped.
+func (r *Recorder) Skipped(count int) {
+	if r == nil {
+		return
+	}
+
+	r.history.mu.Lock()
+	defer r.history.mu.Unlock()
+
+	r.run.Skipped += count
+}
+
+// Finish records the end of the run.
+func (r *Recorder) Finish() {
+	if r == nil {
+		return
+	}
+
+	r.history.mu.Lock()
+	defer r.history.mu.Unlock()
+
+	finishedAt := r.history.now()
+	r.run.FinishedAt = &finishedAt
+}
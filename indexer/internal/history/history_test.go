@@ -0,0 +1,94 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	runs := New(2)
+
+	first := runs.Start("github", 3)
+	first.Indexed("https://github.com/a/b.git", nil)
+	first.Indexed("https://github.com/a/c.git", fmt.Errorf("failed to clone"))
+	first.Skipped(1)
+	first.Finish()
+
+	second := runs.Start("gitlab", 1)
+	second.Indexed("https://gitlab.com/a/b.git", nil)
+
+	// only the most recent runs are kept
+	third := runs.Start("github", 2)
+	third.Indexed("https://github.com/A/b.git", fmt.Errorf("failed to extract"))
+
+	recorded := runs.Runs()
+	require.Len(t, recorded, 2)
+	require.Equal(t, 3, recorded[0].ID)
+	require.Nil(t, recorded[0].FinishedAt)
+	require.Equal(t, 1, recorded[0].Failed)
+	require.Equal(t, []Failure{{RepositoryURL: "https://github.com/A/b.git", Reason: "failed to extract"}}, recorded[0].Failures)
+	require.Equal(t, 2, recorded[1].ID)
+	require.Equal(t, 1, recorded[1].Succeeded)
+
+	// attempts outlive the runs they were made in
+	attempt := runs.LastAttempt("https://github.com/a/c.git")
+	require.NotNil(t, attempt)
+	require.Equal(t, 1, attempt.RunID)
+	require.False(t, attempt.Succeeded)
+	require.Equal(t, "failed to clone", attempt.Reason)
+
+	attempt = runs.LastAttempt("https://github.com/a/b.git")
+	require.NotNil(t, attempt)
+	require.Equal(t, 3, attempt.RunID)
+	require.Equal(t, "failed to extract", attempt.Reason)
+
+	require.Nil(t, runs.LastAttempt("https://github.com/a/d.git"))
+
+	// a nil recorder records nothing
+	var recorder *Recorder
+	recorder.Indexed("https://github.com/a/d.git", nil)
+	recorder.Skipped(1)
+	recorder.Finish()
+}
+
+func TestServeHTTP(t *testing.T) {
+	runs := New(10)
+
+	run := runs.Start("github", 1)
+	run.Indexed("https://github.com/a/b.git", fmt.Errorf("failed to clone"))
+	run.Finish()
+
+	testCases := []struct {
+		method string
+		target string
+		status int
+	}{
+		{http.MethodGet, "/runs", http.StatusOK},
+		{http.MethodGet, "/runs?repository=https://github.com/a/b.git", http.StatusOK},
+		{http.MethodGet, "/runs?repository=https://github.com/a/c.git", http.StatusNotFound},
+		{http.MethodPost, "/runs", http.StatusMethodNotAllowed},
+	}
+
+	for _, testCase := range testCases {
+		recorder := httptest.NewRecorder()
+		runs.ServeHTTP(recorder, httptest.NewRequest(testCase.method, testCase.target, nil))
+		require.Equal(t, testCase.status, recorder.Code, testCase.target)
+	}
+
+	recorder := httptest.NewRecorder()
+	runs.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/runs", nil))
+
+	body := struct {
+		Runs []Run `json:"runs"`
+	}{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Len(t, body.Runs, 1)
+	require.Equal(t, "github", body.Runs[0].Name)
+	require.NotNil(t, body.Runs[0].FinishedAt)
+	require.Equal(t, "failed to clone", body.Runs[0].Failures[0].Reason)
+}
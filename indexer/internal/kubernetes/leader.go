@@ -0,0 +1,172 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+)
+
+// LeaderElector elects a single leader among the replicas sharing a lease.
+// The leader renews the lease while it's running; when it stops renewing (by
+// dying or losing contact with the API server), another replica takes over
+// once the lease expires.
+type LeaderElector struct {
+	client        *Client
+	name          string
+	identity      string
+	leaseDuration time.Duration
+	retryPeriod   time.Duration
+	now           func() time.Time
+
+	// the lease is considered expired once it hasn't changed for a lease
+	// duration, measured with the local clock so replicas don't need to
+	// agree on the time
+	observed     LeaseSpec
+	observedTime time.Time
+}
+
+// NewLeaderElector constructs an elector for the lease with the name, where
+// the identity distinguishes this replica from the others (such as its pod
+// name). Replicas try to acquire or renew the lease several times within each
+// lease duration.
+func NewLeaderElector(client *Client, name, identity string, leaseDuration time.Duration) *LeaderElector {
+	return &LeaderElector{
+		client:        client,
+		name:          name,
+		identity:      identity,
+		leaseDuration: leaseDuration,
+		retryPeriod:   leaseDuration / 3,
+		now:           time.Now,
+	}
+}
+
+// tryAcquire acquires or renews the lease, returning whether this replica
+// holds it.
+func (e *LeaderElector) tryAcquire(ctx context.Context) (bool, error) {
+	now := e.now()
+	timestamp := now.UTC().Format(microTimeFormat)
+
+	lease, err := e.client.GetLease(ctx, e.name)
+	if err != nil {
+		return false, err
+	}
+
+	if lease == nil {
+		lease = &Lease{}
+		lease.Metadata.Name = e.name
+		lease.Metadata.Namespace = e.client.namespace
+		lease.Spec = LeaseSpec{
+			HolderIdentity:       e.identity,
+			LeaseDurationSeconds: int(e.leaseDuration.Seconds()),
+			AcquireTime:          timestamp,
+			RenewTime:            timestamp,
+		}
+
+		_, err = e.client.CreateLease(ctx, lease)
+		if err == ErrConflict {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	if lease.Spec.HolderIdentity != e.observed.HolderIdentity || lease.Spec.RenewTime != e.observed.RenewTime {
+		e.observed = lease.Spec
+		e.observedTime = now
+	}
+
+	held := lease.Spec.HolderIdentity == e.identity
+	expired := lease.Spec.HolderIdentity == "" || now.After(e.observedTime.Add(e.leaseDuration))
+	if !held && !expired {
+		return false, nil
+	}
+
+	if !held {
+		lease.Spec.HolderIdentity = e.identity
+		lease.Spec.AcquireTime = timestamp
+		lease.Spec.LeaseTransitions++
+	}
+	lease.Spec.LeaseDurationSeconds = int(e.leaseDuration.Seconds())
+	lease.Spec.RenewTime = timestamp
+
+	_, err = e.client.UpdateLease(ctx, lease)
+	if err == ErrConflict {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// release gives up the lease so another replica can take over without waiting
+// for it to expire.
+func (e *LeaderElector) release(ctx context.Context) {
+	lease, err := e.client.GetLease(ctx, e.name)
+	if err != nil || lease == nil || lease.Spec.HolderIdentity != e.identity {
+		return
+	}
+
+	lease.Spec.HolderIdentity = ""
+	if _, err := e.client.UpdateLease(ctx, lease); err != nil {
+		logging.FromContext(ctx).Warnf("[kubernetes.leader] failed to release lease %s: %v", e.name, err)
+	}
+}
+
+// Run calls lead once this replica becomes the leader, cancelling the context
+// given to it when leadership is lost. Leadership is sought again after it's
+// lost, so lead may be called more than once. Run returns once the context is
+// done, releasing the lease if it's held.
+func (e *LeaderElector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(e.retryPeriod)
+	defer ticker.Stop()
+
+	var cancel context.CancelFunc
+	var done chan struct{}
+	var renewed time.Time
+
+	stop := func() {
+		if cancel != nil {
+			cancel()
+			<-done
+			cancel = nil
+		}
+	}
+
+	for {
+		acquired, err := e.tryAcquire(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("[kubernetes.leader] failed to acquire lease %s: %v", e.name, err)
+		}
+
+		if acquired {
+			renewed = e.now()
+
+			if cancel == nil {
+				logging.FromContext(ctx).Infof("[kubernetes.leader] %s became the leader", e.identity)
+
+				leaderCtx, cancelLeader := context.WithCancel(ctx)
+				cancel = cancelLeader
+				done = make(chan struct{})
+
+				go func() {
+					defer close(done)
+					lead(leaderCtx)
+				}()
+			}
+		} else if cancel != nil && (err == nil || e.now().Sub(renewed) > e.leaseDuration) {
+			// another replica took over, or the lease may have expired
+			// without being renewed
+			logging.FromContext(ctx).Warnf("[kubernetes.leader] %s lost leadership", e.identity)
+			stop()
+		}
+
+		select {
+		case <-ctx.Done():
+			stop()
+
+			releaseCtx, cancelRelease := context.WithTimeout(context.Background(), e.retryPeriod)
+			e.release(releaseCtx)
+			cancelRelease()
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package kubernetes_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/kubernetes"
+
+	"github.com/stretchr/testify/require"
+)
+
+// leaseServer is a minimal API server storing a single lease, rejecting writes
+// based on a stale resource version like the real one.
+type leaseServer struct {
+	mu      sync.Mutex
+	lease   *kubernetes.Lease
+	version int
+}
+
+func (s *leaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	const path = "/apis/coordination.k8s.io/v1/namespaces/default/leases"
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == path+"/indexer":
+		if s.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.lease)
+
+	case r.Method == http.MethodPost && r.URL.Path == path:
+		if s.lease != nil {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.write(w, r, http.StatusCreated)
+
+	case r.Method == http.MethodPut && r.URL.Path == path+"/indexer":
+		lease := &kubernetes.Lease{}
+		_ = json.NewDecoder(r.Body).Decode(lease)
+		if s.lease == nil || lease.Metadata.ResourceVersion != s.lease.Metadata.ResourceVersion {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.store(w, lease, http.StatusOK)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *leaseServer) write(w http.ResponseWriter, r *http.Request, status int) {
+	lease := &kubernetes.Lease{}
+	_ = json.NewDecoder(r.Body).Decode(lease)
+	s.store(w, lease, status)
+}
+
+func (s *leaseServer) store(w http.ResponseWriter, lease *kubernetes.Lease, status int) {
+	s.version++
+	lease.Metadata.ResourceVersion = strconv.Itoa(s.version)
+	s.lease = lease
+
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(lease)
+}
+
+func (s *leaseServer) holder() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lease == nil {
+		return ""
+	}
+	return s.lease.Spec.HolderIdentity
+}
+
+func TestLeaderElector(t *testing.T) {
+	leases := &leaseServer{}
+	server := httptest.NewServer(leases)
+	defer server.Close()
+
+	client := kubernetes.NewClient(server.URL, "default", "token", server.Client())
+
+	leading := make(chan string, 2)
+	run := func(identity string) (context.CancelFunc, chan struct{}) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+
+		elector := kubernetes.NewLeaderElector(client, "indexer", identity, 300*time.Millisecond)
+		go func() {
+			defer close(done)
+			elector.Run(ctx, func(ctx context.Context) {
+				leading <- identity
+				<-ctx.Done()
+			})
+		}()
+
+		return cancel, done
+	}
+
+	stopFirst, firstDone := run("first")
+	require.Equal(t, "first", <-leading)
+
+	stopSecond, secondDone := run("second")
+	defer func() {
+		stopSecond()
+		<-secondDone
+	}()
+
+	// the second replica waits while the first renews the lease
+	select {
+	case identity := <-leading:
+		t.Fatalf("%s became the leader while the lease was held", identity)
+	case <-time.After(500 * time.Millisecond):
+	}
+	require.Equal(t, "first", leases.holder())
+
+	// stopping the leader hands the lease over
+	stopFirst()
+	<-firstDone
+
+	select {
+	case identity := <-leading:
+		require.Equal(t, "second", identity)
+	case <-time.After(2 * time.Second):
+		t.Fatal("second replica never became the leader")
+	}
+	require.Equal(t, "second", leases.holder())
+}
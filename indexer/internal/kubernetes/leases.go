@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// microTimeFormat is the format of the timestamps within a Lease.
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// Lease is a coordination.k8s.io/v1 Lease, used to elect a leader among the
+// indexer's replicas.
+type Lease struct {
+	Metadata struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+	} `json:"metadata"`
+	Spec LeaseSpec `json:"spec"`
+}
+
+// LeaseSpec describes who holds a lease and until when.
+type LeaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+func (c *Client) leasePath() string {
+	return "/apis/coordination.k8s.io/v1/namespaces/" + c.namespace + "/leases"
+}
+
+// GetLease returns the lease with the name, or nil when it doesn't exist.
+func (c *Client) GetLease(ctx context.Context, name string) (*Lease, error) {
+	lease := &Lease{}
+
+	err := c.do(ctx, http.MethodGet, c.leasePath()+"/"+name, "", nil, lease)
+	if hasStatus(err, http.StatusNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return lease, nil
+}
+
+// CreateLease creates the lease, returning ErrConflict when another replica
+// created it first.
+func (c *Client) CreateLease(ctx context.Context, lease *Lease) (*Lease, error) {
+	return c.writeLease(ctx, http.MethodPost, c.leasePath(), lease)
+}
+
+// UpdateLease replaces the lease, returning ErrConflict when it changed since
+// it was read.
+func (c *Client) UpdateLease(ctx context.Context, lease *Lease) (*Lease, error) {
+	return c.writeLease(ctx, http.MethodPut, c.leasePath()+"/"+lease.Metadata.Name, lease)
+}
+
+// ErrConflict is returned when a lease was changed by another replica.
+var ErrConflict = fmt.Errorf("lease was changed by another replica")
+
+func (c *Client) writeLease(ctx context.Context, method, path string, lease *Lease) (*Lease, error) {
+	if c.namespace == "" {
+		return nil, fmt.Errorf("leases require a namespace")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "coordination.k8s.io/v1",
+		"kind":       "Lease",
+		"metadata":   lease.Metadata,
+		"spec":       lease.Spec,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	written := &Lease{}
+	err = c.do(ctx, method, path, "application/json", body, written)
+	if hasStatus(err, http.StatusConflict) {
+		return nil, ErrConflict
+	} else if err != nil {
+		return nil, err
+	}
+
+	return written, nil
+}
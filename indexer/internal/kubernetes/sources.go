@@ -0,0 +1,211 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+
+	"github.com/gogo/protobuf/jsonpb"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Group is the API group of the IndexerSource custom resource.
+	Group = "deps.cloud"
+
+	// Version is the version of the IndexerSource custom resource.
+	Version = "v1alpha"
+
+	// Resource is the plural name of the IndexerSource custom resource.
+	Resource = "indexersources"
+
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// Phases of an IndexerSource.
+const (
+	PhaseDiscovered = "Discovered"
+	PhaseIndexed    = "Indexed"
+	PhaseFailed     = "Failed"
+)
+
+// Source is an IndexerSource, whose spec holds the same account configuration
+// as the indexer's config file.
+type Source struct {
+	Metadata struct {
+		Name       string `json:"name"`
+		Namespace  string `json:"namespace"`
+		Generation int64  `json:"generation"`
+	} `json:"metadata"`
+	Spec json.RawMessage `json:"spec"`
+}
+
+// Account parses the spec of the source.
+func (s *Source) Account() (*config.Account, error) {
+	account := &config.Account{}
+	if err := jsonpb.UnmarshalString(string(s.Spec), account); err != nil {
+		return nil, errors.Wrap(err, "failed to parse spec")
+	}
+	return account, nil
+}
+
+// SourceStatus reports the outcome of the latest run for a source.
+type SourceStatus struct {
+	Phase              string `json:"phase"`
+	Message            string `json:"message"`
+	Repositories       int    `json:"repositories"`
+	ObservedGeneration int64  `json:"observedGeneration"`
+	LastRunTime        string `json:"lastRunTime"`
+}
+
+// StatusError is returned when the API server responds with an unsuccessful
+// status.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s failed with status %d", e.Method, e.Path, e.StatusCode)
+}
+
+func hasStatus(err error, code int) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.StatusCode == code
+}
+
+// Client reads IndexerSources and writes their status using the Kubernetes
+// API.
+type Client struct {
+	baseURL   string
+	namespace string
+	token     string
+	client    *http.Client
+}
+
+// NewClient constructs a client for the API server at baseURL. An empty
+// namespace reads sources from all namespaces.
+func NewClient(baseURL, namespace, token string, client *http.Client) *Client {
+	return &Client{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		namespace: namespace,
+		token:     token,
+		client:    client,
+	}
+}
+
+// InClusterClient constructs a client using the service account mounted into
+// the pod. Sources are read from the pod's namespace unless another one is
+// given.
+func InClusterClient(namespace string) (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running within kubernetes")
+	}
+
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("failed to load service account certificate authority")
+	}
+
+	if namespace == "" {
+		current, err := ioutil.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, err
+		}
+		namespace = strings.TrimSpace(string(current))
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+
+	baseURL := "https://" + net.JoinHostPort(host, port)
+	return NewClient(baseURL, namespace, strings.TrimSpace(string(token)), client), nil
+}
+
+func (c *Client) path(namespace string) string {
+	path := "/apis/" + Group + "/" + Version
+	if namespace != "" {
+		path += "/namespaces/" + namespace
+	}
+	return path + "/" + Resource
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, body []byte, value interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{Method: method, Path: path, StatusCode: resp.StatusCode}
+	}
+
+	if value == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
+
+// ListSources returns the IndexerSources in the client's namespace.
+func (c *Client) ListSources(ctx context.Context) ([]*Source, error) {
+	list := &struct {
+		Items []*Source `json:"items"`
+	}{}
+
+	if err := c.do(ctx, http.MethodGet, c.path(c.namespace), "", nil, list); err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// UpdateStatus replaces the status of the source.
+func (c *Client) UpdateStatus(ctx context.Context, source *Source, status *SourceStatus) error {
+	body, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return err
+	}
+
+	path := c.path(source.Metadata.Namespace) + "/" + source.Metadata.Name + "/status"
+	return c.do(ctx, http.MethodPatch, path, "application/merge-patch+json", body, nil)
+}
@@ -0,0 +1,67 @@
+package kubernetes_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/depscloud/indexer/internal/kubernetes"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	var patched map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/deps.cloud/v1alpha/namespaces/default/indexersources":
+			_, _ = w.Write([]byte(`{"items":[{
+				"metadata":{"name":"example","namespace":"default","generation":2},
+				"spec":{"github":{"organizations":["depscloud"]}}
+			}]}`))
+
+		case r.Method == http.MethodPatch && r.URL.Path == "/apis/deps.cloud/v1alpha/namespaces/default/indexersources/example/status":
+			require.Equal(t, "application/merge-patch+json", r.Header.Get("Content-Type"))
+
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(body, &patched))
+			_, _ = w.Write([]byte(`{}`))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := kubernetes.NewClient(server.URL, "default", "token", server.Client())
+	ctx := context.Background()
+
+	sources, err := client.ListSources(ctx)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	require.Equal(t, "example", sources[0].Metadata.Name)
+	require.Equal(t, int64(2), sources[0].Metadata.Generation)
+
+	account, err := sources[0].Account()
+	require.NoError(t, err)
+	require.Equal(t, []string{"depscloud"}, account.GetGithub().GetOrganizations())
+
+	err = client.UpdateStatus(ctx, sources[0], &kubernetes.SourceStatus{
+		Phase:              kubernetes.PhaseIndexed,
+		Repositories:       3,
+		ObservedGeneration: 2,
+	})
+	require.NoError(t, err)
+
+	status := patched["status"].(map[string]interface{})
+	require.Equal(t, "Indexed", status["phase"])
+	require.Equal(t, float64(3), status["repositories"])
+	require.Equal(t, float64(2), status["observedGeneration"])
+}
@@ -0,0 +1,93 @@
+package remotes
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/internal/sigv4"
+
+	"github.com/pkg/errors"
+)
+
+// stsEndpoint is the global endpoint of the security token service, which is
+// signed for us-east-1.
+const stsEndpoint = "https://sts.amazonaws.com/"
+
+// awsCredentials returns the configured credentials, falling back to the
+// standard environment variables when none are configured.
+func awsCredentials(cfg *config.AwsCredentials) (*config.AwsCredentials, error) {
+	if cfg.GetAccessKeyId() != "" {
+		return cfg, nil
+	}
+
+	credentials, err := sigv4.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return &config.AwsCredentials{
+		AccessKeyId:     credentials.AccessKeyID,
+		SecretAccessKey: credentials.SecretAccessKey,
+		SessionToken:    credentials.SessionToken,
+	}, nil
+}
+
+type assumeRoleResponse struct {
+	Credentials struct {
+		AccessKeyID     string `xml:"AccessKeyId"`
+		SecretAccessKey string `xml:"SecretAccessKey"`
+		SessionToken    string `xml:"SessionToken"`
+	} `xml:"AssumeRoleResult>Credentials"`
+}
+
+// assumeRole exchanges the credentials for temporary credentials of the role.
+func assumeRole(client *http.Client, credentials *config.AwsCredentials, roleARN, externalID string) (*config.AwsCredentials, error) {
+	form := url.Values{}
+	form.Set("Action", "AssumeRole")
+	form.Set("Version", "2011-06-15")
+	form.Set("RoleArn", roleARN)
+	form.Set("RoleSessionName", "depscloud-indexer")
+	if externalID != "" {
+		form.Set("ExternalId", externalID)
+	}
+	body := []byte(form.Encode())
+
+	req, err := http.NewRequest(http.MethodPost, stsEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	sigv4.Sign(req, body, credentials, "us-east-1", "sts", time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to assume role")
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read body")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to assume role %s, status %d", roleARN, resp.StatusCode)
+	}
+
+	parsed := &assumeRoleResponse{}
+	if err := xml.Unmarshal(data, parsed); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal XML")
+	}
+
+	return &config.AwsCredentials{
+		AccessKeyId:     parsed.Credentials.AccessKeyID,
+		SecretAccessKey: parsed.Credentials.SecretAccessKey,
+		SessionToken:    parsed.Credentials.SessionToken,
+	}, nil
+}
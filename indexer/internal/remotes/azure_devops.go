@@ -0,0 +1,198 @@
+package remotes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/indexer/internal/set"
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// azureDevopsBaseURL is used when no base url is configured.
+	azureDevopsBaseURL = "https://dev.azure.com"
+
+	// azureDevopsAPIVersion is the version of the REST API that's requested.
+	azureDevopsAPIVersion = "6.0"
+
+	// azureDevopsCloneUser is sent alongside the personal access token when
+	// cloning over HTTP. Azure DevOps ignores the username.
+	azureDevopsCloneUser = "pat"
+)
+
+// NewAzureDevopsRemote constructs a new remote implementation that speaks with
+// Azure DevOps for repository related information. The base url can point at
+// an Azure DevOps Server collection instead of the hosted service.
+func NewAzureDevopsRemote(cfg *config.AzureDevops) (Remote, error) {
+	if cfg.GetOrganization() == "" {
+		return nil, fmt.Errorf("organization is required")
+	}
+
+	pat := cfg.GetPersonalAccessToken()
+	if pat == nil {
+		return nil, fmt.Errorf("auth format not supported")
+	}
+
+	baseURL := strings.TrimSuffix(cfg.GetBaseUrl(), "/")
+	if baseURL == "" {
+		baseURL = azureDevopsBaseURL
+	}
+
+	return &azureDevopsRemote{
+		config:        cfg,
+		client:        rateLimited(&http.Client{}),
+		baseURL:       baseURL + "/" + url.PathEscape(cfg.GetOrganization()),
+		authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte(":"+pat.GetToken())),
+	}, nil
+}
+
+var _ Remote = &azureDevopsRemote{}
+
+type azureDevopsRemote struct {
+	config        *config.AzureDevops
+	client        *http.Client
+	baseURL       string
+	authorization string
+}
+
+type azureDevopsProjects struct {
+	Value []struct {
+		Name string `json:"name"`
+	} `json:"value"`
+}
+
+type azureDevopsRepositories struct {
+	Value []struct {
+		Name       string `json:"name"`
+		RemoteURL  string `json:"remoteUrl"`
+		SSHURL     string `json:"sshUrl"`
+		IsDisabled bool   `json:"isDisabled"`
+		Size       int64  `json:"size"`
+	} `json:"value"`
+}
+
+// get decodes the resource at path into value, returning the continuation
+// token for the next page when there is one.
+func (r *azureDevopsRemote) get(path string, query url.Values, value interface{}) (string, error) {
+	query.Set("api-version", azureDevopsAPIVersion)
+	fullURL := r.baseURL + path + "?" + query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, fullURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", r.authorization)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("failed to get url: %s", fullURL))
+	}
+	defer resp.Body.Close()
+
+	// invalid tokens are redirected to a sign in page rather than rejected
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return "", fmt.Errorf("failed to get url: %s, status %d", fullURL, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(value); err != nil {
+		return "", errors.Wrapf(err, "failed to unmarshal JSON")
+	}
+
+	return resp.Header.Get("X-Ms-Continuationtoken"), nil
+}
+
+func (r *azureDevopsRemote) projects(ctx context.Context) ([]string, error) {
+	if len(r.config.Projects) > 0 {
+		return r.config.Projects, nil
+	}
+
+	logging.FromContext(ctx).Infof("[remotes.azuredevops] fetching projects")
+
+	projects := make([]string, 0)
+	for continuation := ""; true; {
+		query := url.Values{}
+		query.Set("$top", "100")
+		if continuation != "" {
+			query.Set("continuationToken", continuation)
+		}
+
+		page := &azureDevopsProjects{}
+		next, err := r.get("/_apis/projects", query, page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, project := range page.Value {
+			projects = append(projects, project.Name)
+		}
+
+		if next == "" {
+			break
+		}
+		continuation = next
+	}
+
+	return projects, nil
+}
+
+func (r *azureDevopsRemote) FetchRepositories(ctx context.Context, _ *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+	cloneConfig := r.config.GetClone()
+	if cloneConfig == nil {
+		cloneConfig = &config.Clone{}
+	}
+	cloneConfig = cloneWithToken(cloneConfig, azureDevopsCloneUser, r.config.GetPersonalAccessToken().GetToken())
+
+	projects, err := r.projects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories := make([]*Repository, 0)
+
+	skipProjects := set.FromSlice(r.config.SkipProjects)
+	for _, project := range projects {
+		if skipProjects.Contains(project) {
+			logging.FromContext(ctx).Infof("[remotes.azuredevops] skipping project %q", project)
+			continue
+		}
+		logging.FromContext(ctx).Infof("[remotes.azuredevops] fetching repositories for project: %s", project)
+
+		repos := &azureDevopsRepositories{}
+		if _, err := r.get("/"+url.PathEscape(project)+"/_apis/git/repositories", url.Values{}, repos); err != nil {
+			logging.FromContext(ctx).Errorf("[remotes.azuredevops] encountered err while fetching repositories for project %s, %v", project, err)
+			continue
+		}
+
+		for _, repo := range repos.Value {
+			// disabled repositories can't be cloned
+			if repo.IsDisabled {
+				continue
+			}
+
+			repositoryURL := repo.SSHURL
+			if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
+				repositoryURL = repo.RemoteURL
+			}
+
+			repositories = append(repositories, &Repository{
+				RepositoryURL: repositoryURL,
+				Clone:         cloneConfig,
+				Name:          project + "/" + repo.Name,
+				Size:          repo.Size / 1024,
+			})
+		}
+	}
+
+	return &FetchRepositoriesResponse{
+		Repositories: repositories,
+	}, nil
+}
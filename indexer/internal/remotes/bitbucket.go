@@ -1,19 +1,25 @@
 package remotes
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/davidji99/bitbucket-go/bitbucket"
 
 	"github.com/depscloud/depscloud/indexer/internal/config"
 	"github.com/depscloud/depscloud/indexer/internal/set"
-
-	"github.com/sirupsen/logrus"
+	"github.com/depscloud/depscloud/internal/logging"
 )
 
 // NewBitbucketRemote constructs a new remote implementation that speaks with Bitbucket
-// for repository related information.
+// for repository related information. Bitbucket Cloud is used unless a base url
+// is configured, in which case the Bitbucket Server or Data Center instance at
+// that url is used.
 func NewBitbucketRemote(cfg *config.Bitbucket) (Remote, error) {
+	if cfg.GetBaseUrl() != "" {
+		return NewBitbucketServerRemote(cfg)
+	}
+
 	var client *bitbucket.Client
 	var err error
 
@@ -22,6 +28,11 @@ func NewBitbucketRemote(cfg *config.Bitbucket) (Remote, error) {
 		password := basic.GetPassword()
 
 		client, err = bitbucket.New(username, password)
+	} else if oauth := cfg.GetOauth(); oauth != nil {
+		// the client always sends basic auth, so the header is overridden
+		client, err = bitbucket.New("", "", bitbucket.CustomHTTPHeaders(map[string]string{
+			"Authorization": "Bearer " + oauth.GetToken(),
+		}))
 	} else {
 		err = fmt.Errorf("auth format not supported")
 	}
@@ -38,32 +49,27 @@ func NewBitbucketRemote(cfg *config.Bitbucket) (Remote, error) {
 
 var _ Remote = &bitbucketRemote{}
 
-func convertRepositoriesResponse(response interface{}, cloneConfig *config.Clone) []*Repository {
-	rdata := response.(map[string]interface{})
-	values := rdata["values"].([]interface{})
-
-	strategy := "ssh"
-	if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
-		strategy = "https"
-	}
-
-	repos := make([]*Repository, 0, len(values))
-	for _, value := range values {
-		val := value.(map[string]interface{})
-
-		links := val["links"].(map[string]interface{})
-
-		cloneURLs := links["clone"].([]interface{})
+func convertRepositoriesResponse(response *bitbucket.Repositories, cloneConfig *config.Clone) []*Repository {
+	repos := make([]*Repository, 0, len(response.Values))
+	for _, value := range response.Values {
+		links := value.GetLinks()
+		if links == nil {
+			continue
+		}
 
-		for _, cloneURL := range cloneURLs {
-			curl := cloneURL.(map[string]interface{})
+		repositoryURL := links.GetSSHCloneURL()
+		if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
+			repositoryURL = links.GetHTTPSCloneURL()
+		}
 
-			if curl["name"].(string) == strategy {
-				repos = append(repos, &Repository{
-					RepositoryURL: curl["href"].(string),
-					Clone:         cloneConfig,
-				})
-			}
+		if repositoryURL != "" {
+			repos = append(repos, &Repository{
+				RepositoryURL: repositoryURL,
+				Clone:         cloneConfig,
+				Name:          value.GetFullName(),
+				Fork:          value.Parent != nil,
+				Size:          value.GetSize() / 1024,
+			})
 		}
 	}
 
@@ -75,7 +81,7 @@ type bitbucketRemote struct {
 	config *config.Bitbucket
 }
 
-func (r *bitbucketRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+func (r *bitbucketRemote) FetchRepositories(ctx context.Context, _ *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
 	pageLen := uint64(10)
 	allRepos := make([]*Repository, 0)
 	cloneConfig := r.config.GetClone()
@@ -88,7 +94,7 @@ func (r *bitbucketRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRe
 	}
 
 	for _, user := range r.config.Users {
-		logrus.Infof("[remotes.bitbucket] fetching projects for user: %s", user)
+		logging.FromContext(ctx).Infof("[remotes.bitbucket] fetching projects for user: %s", user)
 
 		for page := uint64(1); true; page++ {
 			repos, _, err := r.client.Repositories.List(user, &bitbucket.ListOpts{
@@ -97,14 +103,13 @@ func (r *bitbucketRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRe
 			})
 
 			if err != nil {
-				logrus.Errorf("[remotes.bitbucket] encountered err while fetching projects for user %s, %v", user, err)
-				continue
+				logging.FromContext(ctx).Errorf("[remotes.bitbucket] encountered err while fetching projects for user %s, %v", user, err)
+				break
 			}
 
-			rr := convertRepositoriesResponse(repos, cloneConfig)
-			allRepos = append(allRepos, rr...)
+			allRepos = append(allRepos, convertRepositoriesResponse(repos, cloneConfig)...)
 
-			if uint64(len(rr)) < pageLen {
+			if repos.GetNext() == "" {
 				break
 			}
 		}
@@ -113,10 +118,10 @@ func (r *bitbucketRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRe
 	skipTeams := set.FromSlice(r.config.SkipTeams)
 	for _, team := range r.config.Teams {
 		if skipTeams.Contains(team) {
-			logrus.Infof("[remotes.bitbucket] skipping team %q", team)
+			logging.FromContext(ctx).Infof("[remotes.bitbucket] skipping team %q", team)
 			continue
 		}
-		logrus.Infof("[remotes.bitbucket] fetching projects for team: %s", team)
+		logging.FromContext(ctx).Infof("[remotes.bitbucket] fetching projects for team: %s", team)
 
 		for page := uint64(1); true; page++ {
 			repos, _, err := r.client.Repositories.List(team, &bitbucket.ListOpts{
@@ -125,14 +130,13 @@ func (r *bitbucketRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRe
 			})
 
 			if err != nil {
-				logrus.Errorf("[remotes.bitbucket] encountered err while fetching projects for team %s, %v", team, err)
-				continue
+				logging.FromContext(ctx).Errorf("[remotes.bitbucket] encountered err while fetching projects for team %s, %v", team, err)
+				break
 			}
 
-			rr := convertRepositoriesResponse(repos, cloneConfig)
-			allRepos = append(allRepos, rr...)
+			allRepos = append(allRepos, convertRepositoriesResponse(repos, cloneConfig)...)
 
-			if uint64(len(rr)) < pageLen {
+			if repos.GetNext() == "" {
 				break
 			}
 		}
@@ -0,0 +1,223 @@
+package remotes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/indexer/internal/set"
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"github.com/pkg/errors"
+)
+
+// bitbucketServerPageSize is the number of items requested per page.
+const bitbucketServerPageSize = 100
+
+// NewBitbucketServerRemote constructs a new remote implementation that speaks
+// with a Bitbucket Server or Data Center instance for repository related
+// information. Projects are discovered when none are configured.
+func NewBitbucketServerRemote(cfg *config.Bitbucket) (Remote, error) {
+	authorization := ""
+	if basic := cfg.GetBasic(); basic != nil {
+		credentials := basic.GetUsername() + ":" + basic.GetPassword()
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	} else if oauth := cfg.GetOauth(); oauth != nil {
+		// personal and project access tokens are sent as bearer tokens
+		authorization = "Bearer " + oauth.GetToken()
+	} else {
+		return nil, fmt.Errorf("auth format not supported")
+	}
+
+	return &bitbucketServerRemote{
+		config:        cfg,
+		client:        rateLimited(&http.Client{}),
+		baseURL:       strings.TrimSuffix(cfg.GetBaseUrl(), "/"),
+		authorization: authorization,
+	}, nil
+}
+
+var _ Remote = &bitbucketServerRemote{}
+
+type bitbucketServerRemote struct {
+	config        *config.Bitbucket
+	client        *http.Client
+	baseURL       string
+	authorization string
+}
+
+type bitbucketServerPage struct {
+	Values        []json.RawMessage `json:"values"`
+	IsLastPage    bool              `json:"isLastPage"`
+	NextPageStart int               `json:"nextPageStart"`
+}
+
+type bitbucketServerProject struct {
+	Key string `json:"key"`
+}
+
+type bitbucketServerRepository struct {
+	Slug    string `json:"slug"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+	Origin *struct{} `json:"origin"`
+	Links  struct {
+		Clone []struct {
+			Href string `json:"href"`
+			Name string `json:"name"`
+		} `json:"clone"`
+	} `json:"links"`
+}
+
+// list calls fn with each value of the paged resource at path.
+func (r *bitbucketServerRemote) list(path string, fn func(value json.RawMessage) error) error {
+	for start := 0; true; {
+		query := url.Values{}
+		query.Set("start", fmt.Sprintf("%d", start))
+		query.Set("limit", fmt.Sprintf("%d", bitbucketServerPageSize))
+
+		fullURL := r.baseURL + "/rest/api/1.0" + path + "?" + query.Encode()
+
+		req, err := http.NewRequest(http.MethodGet, fullURL, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", r.authorization)
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to get url: %s", fullURL))
+		}
+
+		page := &bitbucketServerPage{}
+		err = json.NewDecoder(resp.Body).Decode(page)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to get url: %s, status %d", fullURL, resp.StatusCode)
+		} else if err != nil {
+			return errors.Wrapf(err, "failed to unmarshal JSON")
+		}
+
+		for _, value := range page.Values {
+			if err := fn(value); err != nil {
+				return err
+			}
+		}
+
+		if page.IsLastPage || len(page.Values) == 0 {
+			break
+		}
+		start = page.NextPageStart
+	}
+
+	return nil
+}
+
+func (r *bitbucketServerRemote) projects(ctx context.Context) ([]string, error) {
+	if len(r.config.Projects) > 0 {
+		return r.config.Projects, nil
+	}
+
+	logging.FromContext(ctx).Infof("[remotes.bitbucket] fetching projects")
+
+	projects := make([]string, 0)
+	err := r.list("/projects", func(value json.RawMessage) error {
+		project := &bitbucketServerProject{}
+		if err := json.Unmarshal(value, project); err != nil {
+			return err
+		}
+
+		projects = append(projects, project.Key)
+		return nil
+	})
+
+	return projects, err
+}
+
+func (r *bitbucketServerRemote) repositories(path string, cloneConfig *config.Clone) ([]*Repository, error) {
+	name := "ssh"
+	if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
+		name = "http"
+	}
+
+	repositories := make([]*Repository, 0)
+	err := r.list(path, func(value json.RawMessage) error {
+		repository := &bitbucketServerRepository{}
+		if err := json.Unmarshal(value, repository); err != nil {
+			return err
+		}
+
+		for _, link := range repository.Links.Clone {
+			if link.Name == name {
+				repositories = append(repositories, &Repository{
+					RepositoryURL: link.Href,
+					Clone:         cloneConfig,
+					Name:          repository.Project.Key + "/" + repository.Slug,
+					Fork:          repository.Origin != nil,
+				})
+			}
+		}
+		return nil
+	})
+
+	return repositories, err
+}
+
+func (r *bitbucketServerRemote) FetchRepositories(ctx context.Context, _ *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+	cloneConfig := r.config.GetClone()
+
+	// if clone config is nil, fall back
+	if cloneConfig == nil {
+		cloneConfig = &config.Clone{
+			Strategy: r.config.GetStrategy(),
+		}
+	}
+
+	allRepos := make([]*Repository, 0)
+
+	for _, user := range r.config.Users {
+		logging.FromContext(ctx).Infof("[remotes.bitbucket] fetching repositories for user: %s", user)
+
+		repos, err := r.repositories("/users/"+url.PathEscape(user)+"/repos", cloneConfig)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[remotes.bitbucket] encountered err while fetching repositories for user %s, %v", user, err)
+			continue
+		}
+
+		allRepos = append(allRepos, repos...)
+	}
+
+	projects, err := r.projects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	skipProjects := set.FromSlice(r.config.SkipProjects)
+	for _, project := range projects {
+		if skipProjects.Contains(project) {
+			logging.FromContext(ctx).Infof("[remotes.bitbucket] skipping project %q", project)
+			continue
+		}
+		logging.FromContext(ctx).Infof("[remotes.bitbucket] fetching repositories for project: %s", project)
+
+		repos, err := r.repositories("/projects/"+url.PathEscape(project)+"/repos", cloneConfig)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[remotes.bitbucket] encountered err while fetching repositories for project %s, %v", project, err)
+			continue
+		}
+
+		allRepos = append(allRepos, repos...)
+	}
+
+	return &FetchRepositoriesResponse{
+		Repositories: allRepos,
+	}, nil
+}
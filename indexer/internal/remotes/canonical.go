@@ -0,0 +1,37 @@
+package remotes
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RepositoryName returns the host and path of the repository behind a clone
+// url (such as github.com/depscloud/depscloud), dropping the scheme, any
+// credentials or port, and the .git suffix. Both urls and the scp-like syntax
+// used by ssh (git@github.com:depscloud/depscloud.git) are understood.
+func RepositoryName(repositoryURL string) string {
+	name := repositoryURL
+
+	if parsed, err := url.Parse(repositoryURL); err == nil && parsed.Host != "" {
+		name = parsed.Hostname() + parsed.Path
+	} else if err == nil && parsed.Scheme == "file" {
+		name = parsed.Path
+	} else if i := strings.Index(repositoryURL, ":"); i > 0 {
+		// scp-like syntax, such as git@github.com:depscloud/depscloud.git
+		host := repositoryURL[:i]
+		if j := strings.LastIndex(host, "@"); j >= 0 {
+			host = host[j+1:]
+		}
+		name = host + "/" + strings.TrimPrefix(repositoryURL[i+1:], "/")
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(name, "/"), ".git")
+}
+
+// CanonicalURL identifies the repository behind a clone url, so the same
+// repository discovered over ssh and https, or by more than one source, is
+// only indexed once. Forges treat owners and names case insensitively, so the
+// canonical url is lowercase.
+func CanonicalURL(repositoryURL string) string {
+	return strings.ToLower(RepositoryName(repositoryURL))
+}
@@ -0,0 +1,152 @@
+package remotes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/indexer/internal/set"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/sigv4"
+
+	"github.com/pkg/errors"
+)
+
+// NewCodeCommitRemote constructs a new remote implementation that speaks with
+// AWS CodeCommit for repository related information. Requests are signed with
+// the configured credentials, or those in the environment, and the role is
+// assumed when one is configured.
+func NewCodeCommitRemote(cfg *config.CodeCommit) (Remote, error) {
+	if len(cfg.GetRegions()) == 0 {
+		return nil, fmt.Errorf("at least one region is required")
+	}
+
+	credentials, err := awsCredentials(cfg.GetCredentials())
+	if err != nil {
+		return nil, err
+	}
+
+	return &codeCommitRemote{
+		config:      cfg,
+		client:      http.DefaultClient,
+		credentials: credentials,
+	}, nil
+}
+
+var _ Remote = &codeCommitRemote{}
+
+type codeCommitRemote struct {
+	config      *config.CodeCommit
+	client      *http.Client
+	credentials *config.AwsCredentials
+}
+
+type listRepositoriesRequest struct {
+	NextToken string `json:"nextToken,omitempty"`
+}
+
+type listRepositoriesResponse struct {
+	Repositories []struct {
+		RepositoryName string `json:"repositoryName"`
+	} `json:"repositories"`
+	NextToken string `json:"nextToken"`
+}
+
+func (r *codeCommitRemote) listRepositories(credentials *config.AwsCredentials, region, nextToken string) (*listRepositoriesResponse, error) {
+	body, err := json.Marshal(&listRepositoriesRequest{NextToken: nextToken})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("https://codecommit.%s.amazonaws.com/", region)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "CodeCommit_20150413.ListRepositories")
+	sigv4.Sign(req, body, credentials, region, "codecommit", time.Now())
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to list repositories in %s", region))
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read body")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list repositories in %s, status %d", region, resp.StatusCode)
+	}
+
+	response := &listRepositoriesResponse{}
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal JSON")
+	}
+
+	return response, nil
+}
+
+func (r *codeCommitRemote) FetchRepositories(ctx context.Context, _ *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+	cloneConfig := r.config.GetClone()
+	if cloneConfig == nil {
+		cloneConfig = &config.Clone{}
+	}
+
+	// assumed roles expire, so they're assumed for each fetch
+	credentials := r.credentials
+	if roleARN := r.config.GetRoleArn(); roleARN != "" {
+		var err error
+		if credentials, err = assumeRole(r.client, r.credentials, roleARN, r.config.GetExternalId()); err != nil {
+			return nil, err
+		}
+	}
+
+	skipRepositories := set.FromSlice(r.config.SkipRepositories)
+	repositories := make([]*Repository, 0)
+
+	for _, region := range r.config.Regions {
+		logging.FromContext(ctx).Infof("[remotes.codecommit] fetching repositories for region: %s", region)
+
+		for nextToken, more := "", true; more; {
+			response, err := r.listRepositories(credentials, region, nextToken)
+			if err != nil {
+				logging.FromContext(ctx).Errorf("[remotes.codecommit] encountered err while fetching repositories for region %s, %v", region, err)
+				break
+			}
+
+			for _, repository := range response.Repositories {
+				if skipRepositories.Contains(repository.RepositoryName) {
+					logging.FromContext(ctx).Infof("[remotes.codecommit] skipping repository %q", repository.RepositoryName)
+					continue
+				}
+
+				scheme := "ssh"
+				if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
+					scheme = "https"
+				}
+
+				repositories = append(repositories, &Repository{
+					RepositoryURL: fmt.Sprintf("%s://git-codecommit.%s.amazonaws.com/v1/repos/%s", scheme, region, repository.RepositoryName),
+					Clone:         cloneConfig,
+					Name:          repository.RepositoryName,
+				})
+			}
+
+			nextToken, more = response.NextToken, response.NextToken != ""
+		}
+	}
+
+	return &FetchRepositoriesResponse{
+		Repositories: repositories,
+	}, nil
+}
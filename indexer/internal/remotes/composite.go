@@ -1,9 +1,15 @@
 package remotes
 
-import "github.com/sirupsen/logrus"
+import (
+	"context"
+
+	"github.com/depscloud/depscloud/internal/logging"
+)
 
 // NewCompositeRemote wraps the supplied remotes in a composite wrapper
-// which logs errors and continues processing remote endpoints.
+// which logs errors and continues processing remote endpoints. Repositories
+// found by more than one remote, even under different clone urls, are only
+// returned for the first of them.
 func NewCompositeRemote(remotes ...Remote) Remote {
 	return &compositeRemote{
 		remotes: remotes,
@@ -16,15 +22,27 @@ type compositeRemote struct {
 	remotes []Remote
 }
 
-func (r *compositeRemote) FetchRepositories(request *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+func (r *compositeRemote) FetchRepositories(ctx context.Context, request *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
 	repositories := make([]*Repository, 0)
+	seen := make(map[string]bool)
+
 	for _, remote := range r.remotes {
-		repos, err := remote.FetchRepositories(request)
+		repos, err := remote.FetchRepositories(ctx, request)
 
 		if err != nil {
-			logrus.Errorf("[remotes.composite] failed to list repositories from remote: %v", err)
-		} else {
-			repositories = append(repositories, repos.Repositories...)
+			logging.FromContext(ctx).Errorf("[remotes.composite] failed to list repositories from remote: %v", err)
+			continue
+		}
+
+		for _, repo := range repos.Repositories {
+			key := CanonicalURL(repo.RepositoryURL)
+			if seen[key] {
+				logging.FromContext(ctx).Infof("[remotes.composite] skipping duplicate repository %s", repo.RepositoryURL)
+				continue
+			}
+
+			seen[key] = true
+			repositories = append(repositories, repo)
 		}
 	}
 	return &FetchRepositoriesResponse{
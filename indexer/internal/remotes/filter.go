@@ -0,0 +1,108 @@
+package remotes
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/indexer/internal/set"
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"github.com/pkg/errors"
+)
+
+// NewFilteredRemote wraps the remote so only the repositories passing the
+// filter are returned. Names are matched against the repository's name, or
+// its url when the remote doesn't report one. Repositories must have one of
+// the topics, when any are given.
+func NewFilteredRemote(remote Remote, filter *config.RepositoryFilter) (Remote, error) {
+	r := &filteredRemote{
+		remote:     remote,
+		filter:     filter,
+		topics:     set.FromSlice(filter.GetTopics()),
+		skipTopics: set.FromSlice(filter.GetSkipTopics()),
+	}
+
+	var err error
+	if pattern := filter.GetNamePattern(); pattern != "" {
+		if r.name, err = regexp.Compile(pattern); err != nil {
+			return nil, errors.Wrap(err, "invalid name pattern")
+		}
+	}
+
+	if pattern := filter.GetSkipNamePattern(); pattern != "" {
+		if r.skipName, err = regexp.Compile(pattern); err != nil {
+			return nil, errors.Wrap(err, "invalid skip name pattern")
+		}
+	}
+
+	return r, nil
+}
+
+var _ Remote = &filteredRemote{}
+
+type filteredRemote struct {
+	remote     Remote
+	filter     *config.RepositoryFilter
+	topics     set.StringSet
+	skipTopics set.StringSet
+	name       *regexp.Regexp
+	skipName   *regexp.Regexp
+}
+
+// skipped returns the reason the repository is filtered out, or an empty
+// string when it isn't.
+func (r *filteredRemote) skipped(repository *Repository) string {
+	name := repository.Name
+	if name == "" {
+		name = repository.RepositoryURL
+	}
+
+	switch {
+	case r.filter.GetSkipArchived() && repository.Archived:
+		return "archived"
+	case r.filter.GetSkipForks() && repository.Fork:
+		return "fork"
+	case r.filter.GetMaxSizeKb() > 0 && repository.Size > r.filter.GetMaxSizeKb():
+		return "too large"
+	case r.name != nil && !r.name.MatchString(name):
+		return "name does not match"
+	case r.skipName != nil && r.skipName.MatchString(name):
+		return "name matches skip pattern"
+	}
+
+	matched := len(r.topics) == 0
+	for _, topic := range repository.Topics {
+		if r.skipTopics.Contains(topic) {
+			return "skipped topic " + topic
+		}
+		matched = matched || r.topics.Contains(topic)
+	}
+
+	if !matched {
+		return "missing topics"
+	}
+
+	return ""
+}
+
+func (r *filteredRemote) FetchRepositories(ctx context.Context, request *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+	response, err := r.remote.FetchRepositories(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories := make([]*Repository, 0, len(response.Repositories))
+	for _, repository := range response.Repositories {
+		if reason := r.skipped(repository); reason != "" {
+			logging.FromContext(ctx).Infof("[remotes.filter] skipping repository %s, %s", repository.RepositoryURL, reason)
+			continue
+		}
+
+		repositories = append(repositories, repository)
+	}
+
+	return &FetchRepositoriesResponse{
+		Repositories: repositories,
+	}, nil
+}
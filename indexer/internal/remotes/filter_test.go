@@ -0,0 +1,60 @@
+package remotes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRemote struct {
+	repositories []*Repository
+}
+
+func (r *fakeRemote) FetchRepositories(ctx context.Context, _ *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+	return &FetchRepositoriesResponse{Repositories: r.repositories}, nil
+}
+
+func TestFilteredRemote(t *testing.T) {
+	remote := &fakeRemote{
+		repositories: []*Repository{
+			{RepositoryURL: "https://github.com/depscloud/depscloud.git", Name: "depscloud/depscloud", Topics: []string{"dependencies"}, Size: 100},
+			{RepositoryURL: "https://github.com/depscloud/archived.git", Name: "depscloud/archived", Archived: true, Topics: []string{"dependencies"}},
+			{RepositoryURL: "https://github.com/depscloud/fork.git", Name: "depscloud/fork", Fork: true, Topics: []string{"dependencies"}},
+			{RepositoryURL: "https://github.com/depscloud/large.git", Name: "depscloud/large", Size: 2000, Topics: []string{"dependencies"}},
+			{RepositoryURL: "https://github.com/depscloud/untagged.git", Name: "depscloud/untagged"},
+			{RepositoryURL: "https://github.com/depscloud/deprecated.git", Name: "depscloud/deprecated", Topics: []string{"dependencies", "deprecated"}},
+			{RepositoryURL: "https://github.com/depscloud/sandbox-api.git", Name: "depscloud/sandbox-api", Topics: []string{"dependencies"}},
+			{RepositoryURL: "https://github.com/other/other.git", Name: "other/other", Topics: []string{"dependencies"}},
+		},
+	}
+
+	filtered, err := NewFilteredRemote(remote, &config.RepositoryFilter{
+		SkipArchived:    true,
+		SkipForks:       true,
+		Topics:          []string{"dependencies"},
+		SkipTopics:      []string{"deprecated"},
+		NamePattern:     "^depscloud/",
+		SkipNamePattern: "sandbox",
+		MaxSizeKb:       1000,
+	})
+	require.NoError(t, err)
+
+	response, err := filtered.FetchRepositories(context.Background(), &FetchRepositoriesRequest{})
+	require.NoError(t, err)
+	require.Len(t, response.Repositories, 1)
+	require.Equal(t, "depscloud/depscloud", response.Repositories[0].Name)
+
+	// without a filter, nothing is skipped
+	unfiltered, err := NewFilteredRemote(remote, &config.RepositoryFilter{})
+	require.NoError(t, err)
+
+	response, err = unfiltered.FetchRepositories(context.Background(), &FetchRepositoriesRequest{})
+	require.NoError(t, err)
+	require.Len(t, response.Repositories, len(remote.repositories))
+
+	_, err = NewFilteredRemote(remote, &config.RepositoryFilter{NamePattern: "("})
+	require.Error(t, err)
+}
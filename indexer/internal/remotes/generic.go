@@ -1,18 +1,18 @@
 package remotes
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 
 	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/internal/logging"
 
 	"github.com/nytlabs/gojee"
 
 	"github.com/pkg/errors"
-
-	"github.com/sirupsen/logrus"
 )
 
 // NewGenericRemote constructs a new remote endpoint that
@@ -28,7 +28,7 @@ type genericRemote struct {
 	config *config.Generic
 }
 
-func (r *genericRemote) FetchRepositories(request *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+func (r *genericRemote) FetchRepositories(ctx context.Context, request *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
 	cloneConfig := r.config.GetClone()
 
 	tokens, err := jee.Lexer(r.config.Selector)
@@ -41,7 +41,7 @@ func (r *genericRemote) FetchRepositories(request *FetchRepositoriesRequest) (*F
 		return nil, err
 	}
 
-	logrus.Infof("[remotes.generic] requesting data from generic endpoint: %s", r.config.BaseUrl)
+	logging.FromContext(ctx).Infof("[remotes.generic] requesting data from generic endpoint: %s", r.config.BaseUrl)
 
 	repositories := make([]*Repository, 0)
 	for page := 1; true; page++ {
@@ -62,7 +62,7 @@ func (r *genericRemote) FetchRepositories(request *FetchRepositoriesRequest) (*F
 		}
 
 		if resp.StatusCode == http.StatusNotFound {
-			logrus.Infof("[remotes.generic] encountered a 404. assuming end of data")
+			logging.FromContext(ctx).Infof("[remotes.generic] encountered a 404. assuming end of data")
 			break
 		}
 
@@ -92,7 +92,7 @@ func (r *genericRemote) FetchRepositories(request *FetchRepositoriesRequest) (*F
 		}
 
 		if int32(len(resultArray)) < r.config.PageSize {
-			logrus.Infof("[remotes.generic] encountered an incomplete page. assuming end of data")
+			logging.FromContext(ctx).Infof("[remotes.generic] encountered an incomplete page. assuming end of data")
 			break
 		}
 	}
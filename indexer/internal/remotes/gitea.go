@@ -0,0 +1,212 @@
+package remotes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/indexer/internal/set"
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"github.com/pkg/errors"
+)
+
+// giteaPageSize is the number of items requested per page. Gitea caps pages
+// at 50 items by default.
+const giteaPageSize = 50
+
+// NewGiteaRemote constructs a new remote implementation that speaks with a
+// Gitea or Forgejo instance for repository related information.
+func NewGiteaRemote(cfg *config.Gitea) (Remote, error) {
+	if cfg.GetBaseUrl() == "" {
+		return nil, fmt.Errorf("base url is required")
+	}
+
+	authorization := ""
+	if basic := cfg.GetBasic(); basic != nil {
+		credentials := basic.GetUsername() + ":" + basic.GetPassword()
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	} else if token := cfg.GetToken(); token != nil {
+		authorization = "token " + token.GetToken()
+	}
+
+	return &giteaRemote{
+		config:        cfg,
+		client:        rateLimited(&http.Client{}),
+		baseURL:       strings.TrimSuffix(cfg.GetBaseUrl(), "/"),
+		authorization: authorization,
+	}, nil
+}
+
+var _ Remote = &giteaRemote{}
+
+type giteaRemote struct {
+	config        *config.Gitea
+	client        *http.Client
+	baseURL       string
+	authorization string
+}
+
+type giteaOrganization struct {
+	UserName string `json:"username"`
+}
+
+type giteaRepository struct {
+	CloneURL string `json:"clone_url"`
+	SSHURL   string `json:"ssh_url"`
+	FullName string `json:"full_name"`
+	Archived bool   `json:"archived"`
+	Fork     bool   `json:"fork"`
+	Size     int64  `json:"size"`
+	// Updated changes with every push to the repository.
+	Updated time.Time `json:"updated_at"`
+}
+
+// list calls fn with each value of the paged resource at path.
+func (r *giteaRemote) list(path string, fn func(value json.RawMessage) error) error {
+	for page := 1; true; page++ {
+		query := url.Values{}
+		query.Set("page", fmt.Sprintf("%d", page))
+		query.Set("limit", fmt.Sprintf("%d", giteaPageSize))
+
+		fullURL := r.baseURL + "/api/v1" + path + "?" + query.Encode()
+
+		req, err := http.NewRequest(http.MethodGet, fullURL, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		if r.authorization != "" {
+			req.Header.Set("Authorization", r.authorization)
+		}
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to get url: %s", fullURL))
+		}
+
+		values := make([]json.RawMessage, 0)
+		err = json.NewDecoder(resp.Body).Decode(&values)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to get url: %s, status %d", fullURL, resp.StatusCode)
+		} else if err != nil {
+			return errors.Wrapf(err, "failed to unmarshal JSON")
+		}
+
+		for _, value := range values {
+			if err := fn(value); err != nil {
+				return err
+			}
+		}
+
+		if len(values) < giteaPageSize {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (r *giteaRemote) organizations(user string) ([]string, error) {
+	organizations := make([]string, 0)
+	err := r.list("/users/"+url.PathEscape(user)+"/orgs", func(value json.RawMessage) error {
+		organization := &giteaOrganization{}
+		if err := json.Unmarshal(value, organization); err != nil {
+			return err
+		}
+
+		organizations = append(organizations, organization.UserName)
+		return nil
+	})
+
+	return organizations, err
+}
+
+func (r *giteaRemote) repositories(path string, cloneConfig *config.Clone) ([]*Repository, error) {
+	repositories := make([]*Repository, 0)
+	err := r.list(path, func(value json.RawMessage) error {
+		repository := &giteaRepository{}
+		if err := json.Unmarshal(value, repository); err != nil {
+			return err
+		}
+
+		repositoryURL := repository.SSHURL
+		if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
+			repositoryURL = repository.CloneURL
+		}
+
+		repositories = append(repositories, &Repository{
+			RepositoryURL: repositoryURL,
+			Clone:         cloneConfig,
+			Name:          repository.FullName,
+			Archived:      repository.Archived,
+			Fork:          repository.Fork,
+			Size:          repository.Size,
+			PushedAt:      repository.Updated,
+		})
+		return nil
+	})
+
+	return repositories, err
+}
+
+func (r *giteaRemote) FetchRepositories(ctx context.Context, _ *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+	cloneConfig := r.config.GetClone()
+	if cloneConfig == nil {
+		cloneConfig = &config.Clone{}
+	}
+
+	organizations := make([]string, 0)
+	repositories := make([]*Repository, 0)
+
+	if r.config.Organizations != nil {
+		// init with configured orgs
+		organizations = append(organizations, r.config.Organizations...)
+	}
+
+	// discover more from users
+	for _, user := range r.config.Users {
+		logging.FromContext(ctx).Infof("[remotes.gitea] processing organizations for user: %s", user)
+
+		orgs, err := r.organizations(user)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[remotes.gitea] encountered err while fetching organizations for user %s, %v", user, err)
+		}
+		organizations = append(organizations, orgs...)
+
+		logging.FromContext(ctx).Infof("[remotes.gitea] processing repositories for user: %s", user)
+
+		repos, err := r.repositories("/users/"+url.PathEscape(user)+"/repos", cloneConfig)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[remotes.gitea] encountered err while fetching repositories for user %s, %v", user, err)
+		}
+		repositories = append(repositories, repos...)
+	}
+
+	skipOrganizations := set.FromSlice(r.config.SkipOrganizations)
+	for _, organization := range organizations {
+		if skipOrganizations.Contains(organization) {
+			logging.FromContext(ctx).Infof("[remotes.gitea] skipping org %q", organization)
+			continue
+		}
+		logging.FromContext(ctx).Infof("[remotes.gitea] processing repositories for organization: %s", organization)
+
+		repos, err := r.repositories("/orgs/"+url.PathEscape(organization)+"/repos", cloneConfig)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[remotes.gitea] encountered err while fetching repositories for organization %s, %v", organization, err)
+		}
+		repositories = append(repositories, repos...)
+	}
+
+	return &FetchRepositoriesResponse{
+		Repositories: repositories,
+	}, nil
+}
@@ -3,14 +3,14 @@ package remotes
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/depscloud/depscloud/indexer/internal/config"
 	"github.com/depscloud/depscloud/indexer/internal/set"
+	"github.com/depscloud/depscloud/internal/logging"
 
 	"github.com/google/go-github/v20/github"
 
-	"github.com/sirupsen/logrus"
-
 	"golang.org/x/oauth2"
 )
 
@@ -30,7 +30,20 @@ func NewGithubRemote(cfg *config.Github) (Remote, error) {
 	}
 
 	httpClient := &http.Client{}
-	if o2 := cfg.GetOauth2(); o2 != nil {
+	if app := cfg.GetApp(); app != nil {
+		key, err := parseGithubAppKey(app)
+		if err != nil {
+			return nil, err
+		}
+
+		ts := oauth2.ReuseTokenSource(nil, &githubAppTokenSource{
+			appID: app.GetAppId(),
+			key:   key,
+			now:   time.Now,
+		})
+
+		httpClient = oauth2.NewClient(context.Background(), ts)
+	} else if o2 := cfg.GetOauth2(); o2 != nil {
 		ts := oauth2.StaticTokenSource(&oauth2.Token{
 			AccessToken: o2.Token,
 		})
@@ -38,25 +51,45 @@ func NewGithubRemote(cfg *config.Github) (Remote, error) {
 		httpClient = oauth2.NewClient(context.Background(), ts)
 	}
 
-	client, err := fn(httpClient)
+	client, err := fn(rateLimited(httpClient))
 	if err != nil {
 		return nil, err
 	}
 
 	return &githubRemote{
-		config: cfg,
-		client: client,
+		config:    cfg,
+		client:    client,
+		newClient: fn,
 	}, nil
 }
 
 var _ Remote = &githubRemote{}
 
 type githubRemote struct {
-	config *config.Github
-	client *github.Client
+	config    *config.Github
+	client    *github.Client
+	newClient func(client *http.Client) (*github.Client, error)
 }
 
-func (r *githubRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+func (r *githubRemote) repository(repo *github.Repository, cloneConfig *config.Clone) *Repository {
+	repositoryURL := repo.GetSSHURL()
+	if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
+		repositoryURL = repo.GetCloneURL()
+	}
+
+	return &Repository{
+		RepositoryURL: repositoryURL,
+		Clone:         cloneConfig,
+		Name:          repo.GetFullName(),
+		Archived:      repo.GetArchived(),
+		Fork:          repo.GetFork(),
+		Topics:        repo.Topics,
+		Size:          int64(repo.GetSize()),
+		PushedAt:      repo.GetPushedAt().Time,
+	}
+}
+
+func (r *githubRemote) FetchRepositories(ctx context.Context, _ *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
 	cloneConfig := r.config.GetClone()
 
 	// if clone config is nil, fall back
@@ -66,6 +99,11 @@ func (r *githubRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepos
 		}
 	}
 
+	// apps discover repositories through their installations
+	if r.config.GetApp() != nil {
+		return r.fetchInstallationRepositories(ctx, cloneConfig)
+	}
+
 	organizations := make([]string, 0)
 	repositories := make([]*Repository, 0)
 
@@ -76,15 +114,15 @@ func (r *githubRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepos
 
 	// discover more from users
 	for _, user := range r.config.Users {
-		logrus.Infof("[remotes.github] processing organizations for user: %s", user)
+		logging.FromContext(ctx).Infof("[remotes.github] processing organizations for user: %s", user)
 
 		for orgPage := 1; orgPage != 0; {
-			orgs, response, err := r.client.Organizations.List(context.Background(), user, &github.ListOptions{
+			orgs, response, err := r.client.Organizations.List(ctx, user, &github.ListOptions{
 				Page: orgPage,
 			})
 
 			if err != nil {
-				logrus.Errorf("[remotes.github] encountered err on orgPage %d, %v", orgPage, err)
+				logging.FromContext(ctx).Errorf("[remotes.github] encountered err on orgPage %d, %v", orgPage, err)
 				break
 			}
 
@@ -98,34 +136,24 @@ func (r *githubRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepos
 			orgPage = response.NextPage
 		}
 
-		logrus.Infof("[remotes.github] processing repositories for user: %s", user)
+		logging.FromContext(ctx).Infof("[remotes.github] processing repositories for user: %s", user)
 
 		for repoPage := 1; repoPage != 0; {
-			repos, response, err := r.client.Repositories.List(context.Background(), user, &github.RepositoryListOptions{
+			repos, response, err := r.client.Repositories.List(ctx, user, &github.RepositoryListOptions{
 				ListOptions: github.ListOptions{
 					Page: repoPage,
 				},
 			})
 
 			if err != nil {
-				logrus.Errorf("[remotes.github] encountered err on repoPage %d, %v", repoPage, err)
+				logging.FromContext(ctx).Errorf("[remotes.github] encountered err on repoPage %d, %v", repoPage, err)
 				break
 			}
 
 			urls := make([]*Repository, len(repos))
 
 			for i, repo := range repos {
-				if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
-					urls[i] = &Repository{
-						RepositoryURL: repo.GetCloneURL(),
-						Clone:         cloneConfig,
-					}
-				} else {
-					urls[i] = &Repository{
-						RepositoryURL: repo.GetSSHURL(),
-						Clone:         cloneConfig,
-					}
-				}
+				urls[i] = r.repository(repo, cloneConfig)
 			}
 
 			repositories = append(repositories, urls...)
@@ -137,37 +165,27 @@ func (r *githubRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepos
 	skipOrganizations := set.FromSlice(r.config.SkipOrganizations)
 	for _, organization := range organizations {
 		if skipOrganizations.Contains(organization) {
-			logrus.Infof("[remotes.github] skipping org %q", organization)
+			logging.FromContext(ctx).Infof("[remotes.github] skipping org %q", organization)
 			continue
 		}
-		logrus.Infof("[remotes.github] processing repositories for organization: %s", organization)
+		logging.FromContext(ctx).Infof("[remotes.github] processing repositories for organization: %s", organization)
 
 		for orgRepoPage := 1; orgRepoPage != 0; {
-			orgRepos, response, err := r.client.Repositories.ListByOrg(context.Background(), organization, &github.RepositoryListByOrgOptions{
+			orgRepos, response, err := r.client.Repositories.ListByOrg(ctx, organization, &github.RepositoryListByOrgOptions{
 				ListOptions: github.ListOptions{
 					Page: orgRepoPage,
 				},
 			})
 
 			if err != nil {
-				logrus.Errorf("[remotes.github] encountered err on orgRepoPage %d, %v", orgRepoPage, err)
+				logging.FromContext(ctx).Errorf("[remotes.github] encountered err on orgRepoPage %d, %v", orgRepoPage, err)
 				break
 			}
 
 			urls := make([]*Repository, len(orgRepos))
 
 			for i, repo := range orgRepos {
-				if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
-					urls[i] = &Repository{
-						RepositoryURL: repo.GetCloneURL(),
-						Clone:         cloneConfig,
-					}
-				} else {
-					urls[i] = &Repository{
-						RepositoryURL: repo.GetSSHURL(),
-						Clone:         cloneConfig,
-					}
-				}
+				urls[i] = r.repository(repo, cloneConfig)
 			}
 
 			repositories = append(repositories, urls...)
@@ -0,0 +1,222 @@
+package remotes
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/indexer/internal/set"
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"github.com/google/go-github/v20/github"
+
+	"golang.org/x/oauth2"
+)
+
+// githubAppTokenLifetime is how long the tokens identifying the app are valid.
+// GitHub rejects anything valid for more than 10 minutes.
+const githubAppTokenLifetime = 9 * time.Minute
+
+// githubAppCloneUser is the username GitHub expects alongside an installation
+// token when cloning over HTTP.
+const githubAppCloneUser = "x-access-token"
+
+func parseGithubAppKey(cfg *config.GithubApp) (*rsa.PrivateKey, error) {
+	body := []byte(cfg.GetPrivateKey())
+	if path := cfg.GetPrivateKeyPath(); path != "" {
+		var err error
+		if body, err = ioutil.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, fmt.Errorf("github app private key must be PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("github app private key must be an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// githubAppTokenSource issues the JSON web tokens that identify the app. They
+// can only be used to list the app's installations and to create tokens for
+// them.
+type githubAppTokenSource struct {
+	appID int64
+	key   *rsa.PrivateKey
+	now   func() time.Time
+}
+
+func (s *githubAppTokenSource) Token() (*oauth2.Token, error) {
+	// backdate the token to allow for clock drift between us and GitHub
+	issuedAt := s.now().Add(-time.Minute)
+	expiry := issuedAt.Add(githubAppTokenLifetime)
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": issuedAt.Unix(),
+		"exp": expiry.Unix(),
+		"iss": strconv.FormatInt(s.appID, 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Token{
+		AccessToken: unsigned + "." + base64.RawURLEncoding.EncodeToString(signature),
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+	}, nil
+}
+
+// installationTokenSource exchanges the app's identity for a token scoped to
+// one of its installations. Installation tokens expire after an hour, so this
+// is wrapped in a ReuseTokenSource to create a new one as they run out.
+type installationTokenSource struct {
+	apps           *github.AppsService
+	installationID int64
+}
+
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	token, _, err := s.apps.CreateInstallationToken(context.Background(), s.installationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		TokenType:   "token",
+		Expiry:      token.GetExpiresAt(),
+	}, nil
+}
+
+// installations returns the ids of the installations to index. These are the
+// configured installations, or every installation of the app when none are
+// configured. When users or organizations are configured, only installations
+// on those accounts are indexed.
+func (r *githubRemote) installations(ctx context.Context) ([]int64, error) {
+	if ids := r.config.GetApp().GetInstallationIds(); len(ids) > 0 {
+		return ids, nil
+	}
+
+	accounts := set.FromSlice(append(append([]string{}, r.config.Users...), r.config.Organizations...))
+	skipOrganizations := set.FromSlice(r.config.SkipOrganizations)
+
+	ids := make([]int64, 0)
+	for page := 1; page != 0; {
+		installations, response, err := r.client.Apps.ListInstallations(ctx, &github.ListOptions{
+			Page: page,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, installation := range installations {
+			login := installation.GetAccount().GetLogin()
+
+			if skipOrganizations.Contains(login) || (len(accounts) > 0 && !accounts.Contains(login)) {
+				logging.FromContext(ctx).Infof("[remotes.github] skipping installation on %q", login)
+				continue
+			}
+
+			ids = append(ids, installation.GetID())
+		}
+
+		page = response.NextPage
+	}
+
+	return ids, nil
+}
+
+func (r *githubRemote) fetchInstallationRepositories(ctx context.Context, cloneConfig *config.Clone) (*FetchRepositoriesResponse, error) {
+	installations, err := r.installations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	skipOrganizations := set.FromSlice(r.config.SkipOrganizations)
+	repositories := make([]*Repository, 0)
+
+	for _, installationID := range installations {
+		logging.FromContext(ctx).Infof("[remotes.github] processing repositories for installation: %d", installationID)
+
+		ts := oauth2.ReuseTokenSource(nil, &installationTokenSource{
+			apps:           r.client.Apps,
+			installationID: installationID,
+		})
+
+		token, err := ts.Token()
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[remotes.github] failed to create token for installation %d, %v", installationID, err)
+			continue
+		}
+
+		client, err := r.newClient(rateLimited(oauth2.NewClient(context.Background(), ts)))
+		if err != nil {
+			return nil, err
+		}
+
+		installationCloneConfig := cloneWithToken(cloneConfig, githubAppCloneUser, token.AccessToken)
+
+		for repoPage := 1; repoPage != 0; {
+			repos, response, err := client.Apps.ListRepos(ctx, &github.ListOptions{
+				Page: repoPage,
+			})
+
+			if err != nil {
+				logging.FromContext(ctx).Errorf("[remotes.github] encountered err on repoPage %d, %v", repoPage, err)
+				break
+			}
+
+			for _, repo := range repos {
+				if skipOrganizations.Contains(repo.GetOwner().GetLogin()) {
+					continue
+				}
+				repositories = append(repositories, r.repository(repo, installationCloneConfig))
+			}
+
+			repoPage = response.NextPage
+		}
+	}
+
+	return &FetchRepositoriesResponse{
+		Repositories: repositories,
+	}, nil
+}
@@ -1,12 +1,15 @@
 package remotes
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/depscloud/depscloud/indexer/internal/config"
 	"github.com/depscloud/depscloud/indexer/internal/set"
-
-	"github.com/sirupsen/logrus"
+	"github.com/depscloud/depscloud/internal/logging"
 
 	"github.com/xanzy/go-gitlab"
 )
@@ -14,7 +17,9 @@ import (
 // NewGitlabRemote constructs a new remote implementation that speaks with Gitlab
 // for repository related information.
 func NewGitlabRemote(cfg *config.Gitlab) (Remote, error) {
-	options := make([]gitlab.ClientOptionFunc, 0)
+	options := []gitlab.ClientOptionFunc{
+		gitlab.WithHTTPClient(rateLimited(&http.Client{})),
+	}
 	if baseURL := cfg.GetBaseUrl(); baseURL != "" {
 		options = append(options, gitlab.WithBaseURL(baseURL))
 	}
@@ -24,6 +29,8 @@ func NewGitlabRemote(cfg *config.Gitlab) (Remote, error) {
 
 	if private := cfg.GetPrivate(); private != nil {
 		client, err = gitlab.NewClient(private.GetToken(), options...)
+	} else if oauth := cfg.GetOauth(); oauth != nil {
+		client, err = gitlab.NewOAuthClient(oauth.GetToken(), options...)
 	} else {
 		err = fmt.Errorf("no auth method provided")
 	}
@@ -45,7 +52,54 @@ type gitlabRemote struct {
 	client *gitlab.Client
 }
 
-func (r *gitlabRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+// skipped returns true when the group, or a group containing it, should be
+// skipped.
+func (r *gitlabRemote) skipped(group string) bool {
+	for _, skip := range r.config.SkipGroups {
+		if group == skip || strings.HasPrefix(group, skip+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// included returns true when the project passes the archived and visibility
+// filters.
+func (r *gitlabRemote) included(project *gitlab.Project) bool {
+	if project.Archived && !r.config.GetIncludeArchived() {
+		return false
+	}
+
+	if visibilities := r.config.GetVisibilities(); len(visibilities) > 0 {
+		return set.FromSlice(visibilities).Contains(string(project.Visibility))
+	}
+
+	return true
+}
+
+func (r *gitlabRemote) repository(project *gitlab.Project, cloneConfig *config.Clone) *Repository {
+	repositoryURL := project.SSHURLToRepo
+	if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
+		repositoryURL = project.HTTPURLToRepo
+	}
+
+	var pushedAt time.Time
+	if project.LastActivityAt != nil {
+		pushedAt = *project.LastActivityAt
+	}
+
+	return &Repository{
+		RepositoryURL: repositoryURL,
+		Clone:         cloneConfig,
+		Name:          project.PathWithNamespace,
+		Archived:      project.Archived,
+		Fork:          project.ForkedFromProject != nil,
+		Topics:        project.TagList,
+		PushedAt:      pushedAt,
+	}
+}
+
+func (r *gitlabRemote) FetchRepositories(ctx context.Context, _ *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
 	cloneConfig := r.config.GetClone()
 
 	// if clone config is nil, fall back
@@ -56,12 +110,35 @@ func (r *gitlabRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepos
 	}
 
 	repositories := make([]*Repository, 0)
+	seen := make(map[int]bool)
+
+	// with subgroups included, a project can be listed for every group above
+	// it, so each is only added once
+	add := func(project *gitlab.Project) {
+		if seen[project.ID] || !r.included(project) {
+			return
+		}
+
+		if project.Namespace != nil && r.skipped(project.Namespace.FullPath) {
+			return
+		}
+
+		seen[project.ID] = true
+		repositories = append(repositories, r.repository(project, cloneConfig))
+	}
+
 	groups := make(map[string]bool, 0)
 	for _, group := range r.config.GetGroups() {
 		groups[group] = true
 	}
 
-	logrus.Infof("[remotes.gitlab] fetching groups")
+	// archived projects are only filtered by the api when they're excluded
+	var archived *bool
+	if !r.config.GetIncludeArchived() {
+		archived = gitlab.Bool(false)
+	}
+
+	logging.FromContext(ctx).Infof("[remotes.gitlab] fetching groups")
 	page := 1
 	for page > 0 {
 		grps, resp, err := r.client.Groups.ListGroups(&gitlab.ListGroupsOptions{
@@ -72,19 +149,19 @@ func (r *gitlabRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepos
 		})
 
 		if err != nil {
-			logrus.Errorf("[remotes.gitlab] encountered err while fetching groups %v", err)
+			logging.FromContext(ctx).Errorf("[remotes.gitlab] encountered err while fetching groups %v", err)
 			break
 		}
 
 		for _, group := range grps {
-			groups[group.Path] = true
+			groups[group.FullPath] = true
 		}
 
 		page = resp.NextPage
 	}
 
 	for _, user := range r.config.Users {
-		logrus.Infof("[remotes.gitlab] fetching projects for user: %s", user)
+		logging.FromContext(ctx).Infof("[remotes.gitlab] fetching projects for user: %s", user)
 
 		page = 1
 		for page > 0 {
@@ -93,42 +170,28 @@ func (r *gitlabRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepos
 					Page:    page,
 					PerPage: 100,
 				},
+				Archived: archived,
 			})
 
 			if err != nil {
-				logrus.Errorf("[remotes.gitlab] encountered err while fetching projects for user %s, %v", user, err)
+				logging.FromContext(ctx).Errorf("[remotes.gitlab] encountered err while fetching projects for user %s, %v", user, err)
 				break
 			}
 
-			urls := make([]*Repository, len(projects))
-
-			for i, project := range projects {
-				if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
-					urls[i] = &Repository{
-						RepositoryURL: project.HTTPURLToRepo,
-						Clone:         cloneConfig,
-					}
-				} else {
-					urls[i] = &Repository{
-						RepositoryURL: project.SSHURLToRepo,
-						Clone:         cloneConfig,
-					}
-				}
+			for _, project := range projects {
+				add(project)
 			}
 
-			repositories = append(repositories, urls...)
-
 			page = resp.NextPage
 		}
 	}
 
-	skipGroups := set.FromSlice(r.config.SkipGroups)
 	for group := range groups {
-		if skipGroups.Contains(group) {
-			logrus.Infof("[remotes.gitlab] skipping group %q", group)
+		if r.skipped(group) {
+			logging.FromContext(ctx).Infof("[remotes.gitlab] skipping group %q", group)
 			continue
 		}
-		logrus.Infof("[remotes.gitlab] fetching projects for group: %s", group)
+		logging.FromContext(ctx).Infof("[remotes.gitlab] fetching projects for group: %s", group)
 
 		page = 1
 		for page > 0 {
@@ -137,31 +200,19 @@ func (r *gitlabRemote) FetchRepositories(*FetchRepositoriesRequest) (*FetchRepos
 					Page:    page,
 					PerPage: 100,
 				},
+				Archived:         archived,
+				IncludeSubgroups: gitlab.Bool(r.config.GetIncludeSubgroups()),
 			})
 
 			if err != nil {
-				logrus.Errorf("[remotes.gitlab] encountered err while fetching projects for group %s, %v", group, err)
+				logging.FromContext(ctx).Errorf("[remotes.gitlab] encountered err while fetching projects for group %s, %v", group, err)
 				break
 			}
 
-			urls := make([]*Repository, len(projects))
-
-			for i, project := range projects {
-				if cloneConfig.GetStrategy() == config.CloneStrategy_HTTP {
-					urls[i] = &Repository{
-						RepositoryURL: project.HTTPURLToRepo,
-						Clone:         cloneConfig,
-					}
-				} else {
-					urls[i] = &Repository{
-						RepositoryURL: project.SSHURLToRepo,
-						Clone:         cloneConfig,
-					}
-				}
+			for _, project := range projects {
+				add(project)
 			}
 
-			repositories = append(repositories, urls...)
-
 			page = resp.NextPage
 		}
 	}
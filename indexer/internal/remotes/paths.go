@@ -0,0 +1,71 @@
+package remotes
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+
+	"github.com/pkg/errors"
+)
+
+// NewPathRemote wraps the remote so each repository carries the first of the
+// path rules whose pattern matches it. Patterns are matched against the
+// repository's name, or its url when the remote doesn't report one, and rules
+// without a pattern match every repository.
+func NewPathRemote(remote Remote, rules []*config.PathRule) (Remote, error) {
+	r := &pathRemote{
+		remote:   remote,
+		rules:    rules,
+		patterns: make([]*regexp.Regexp, len(rules)),
+	}
+
+	for i, rule := range rules {
+		if rule.GetRepositoryPattern() == "" {
+			continue
+		}
+
+		pattern, err := regexp.Compile(rule.GetRepositoryPattern())
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid repository pattern")
+		}
+		r.patterns[i] = pattern
+	}
+
+	return r, nil
+}
+
+var _ Remote = &pathRemote{}
+
+type pathRemote struct {
+	remote   Remote
+	rules    []*config.PathRule
+	patterns []*regexp.Regexp
+}
+
+func (r *pathRemote) rule(repository *Repository) *config.PathRule {
+	name := repository.Name
+	if name == "" {
+		name = repository.RepositoryURL
+	}
+
+	for i, rule := range r.rules {
+		if r.patterns[i] == nil || r.patterns[i].MatchString(name) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (r *pathRemote) FetchRepositories(ctx context.Context, request *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+	response, err := r.remote.FetchRepositories(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repository := range response.Repositories {
+		repository.Paths = r.rule(repository)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,41 @@
+package remotes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathRemote(t *testing.T) {
+	remote := &fakeRemote{
+		repositories: []*Repository{
+			{RepositoryURL: "https://github.com/depscloud/monorepo.git", Name: "depscloud/monorepo"},
+			{RepositoryURL: "https://github.com/depscloud/api.git", Name: "depscloud/api"},
+			{RepositoryURL: "https://example.com/other.git"},
+		},
+	}
+
+	monorepo := &config.PathRule{
+		RepositoryPattern: "monorepo$",
+		Sources:           []string{"services/*"},
+	}
+	fallback := &config.PathRule{
+		Excludes: []string{"**/testdata/**"},
+	}
+
+	withPaths, err := NewPathRemote(remote, []*config.PathRule{monorepo, fallback})
+	require.NoError(t, err)
+
+	response, err := withPaths.FetchRepositories(context.Background(), &FetchRepositoriesRequest{})
+	require.NoError(t, err)
+	require.Len(t, response.Repositories, 3)
+	require.Equal(t, monorepo, response.Repositories[0].Paths)
+	require.Equal(t, fallback, response.Repositories[1].Paths)
+	require.Equal(t, fallback, response.Repositories[2].Paths)
+
+	_, err = NewPathRemote(remote, []*config.PathRule{{RepositoryPattern: "("}})
+	require.Error(t, err)
+}
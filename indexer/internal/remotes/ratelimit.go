@@ -0,0 +1,166 @@
+package remotes
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+)
+
+const (
+	// rateLimitRetries bounds how many times a rate limited request is retried.
+	rateLimitRetries = 3
+
+	// secondaryRateLimitWait is how long to wait when a secondary rate limit
+	// is hit without saying when to retry.
+	secondaryRateLimitWait = time.Minute
+
+	// maxRateLimitWait bounds how long a single wait can be, protecting against
+	// bad reset headers.
+	maxRateLimitWait = time.Hour
+)
+
+// rateLimited wraps the transport of the client so requests wait out exhausted
+// rate limits, and retries those that are rejected by one.
+func rateLimited(client *http.Client) *http.Client {
+	client.Transport = &rateLimitTransport{
+		base:  client.Transport,
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+	return client
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// rateLimitHeader returns the first of the headers set on the response. GitHub
+// and Gitea prefix their rate limit headers with X-, while GitLab doesn't.
+func rateLimitHeader(resp *http.Response, names ...string) string {
+	for _, name := range names {
+		if value := resp.Header.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// rateLimitTransport waits out the rate limit reported by the forge once it's
+// exhausted, before handing back the response that exhausted it. Clients that
+// refuse to send requests while they know the limit is exhausted, like
+// go-github, only ever see it reset.
+type rateLimitTransport struct {
+	base  http.RoundTripper
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+var _ http.RoundTripper = &rateLimitTransport{}
+
+func (t *rateLimitTransport) transport() http.RoundTripper {
+	if t.base == nil {
+		return http.DefaultTransport
+	}
+	return t.base
+}
+
+// reset returns when the rate limit resets, or the zero time when it's not
+// exhausted.
+func (t *rateLimitTransport) reset(resp *http.Response) time.Time {
+	if rateLimitHeader(resp, "X-RateLimit-Remaining", "RateLimit-Remaining") != "0" {
+		return time.Time{}
+	}
+
+	seconds, err := strconv.ParseInt(rateLimitHeader(resp, "X-RateLimit-Reset", "RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(seconds, 0)
+}
+
+// retryAfter returns how long to wait before retrying a rejected request, and
+// false when the request wasn't rejected by a rate limit.
+func (t *rateLimitTransport) retryAfter(resp *http.Response, resetAt time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if !resetAt.IsZero() {
+		return resetAt.Sub(t.now()), true
+	}
+
+	// a forbidden response without any rate limit headers is a permission
+	// problem, which retrying won't solve
+	if resp.StatusCode == http.StatusForbidden {
+		return 0, false
+	}
+
+	return secondaryRateLimitWait, true
+}
+
+func (t *rateLimitTransport) wait(req *http.Request, wait time.Duration) error {
+	if wait <= 0 {
+		return nil
+	} else if wait > maxRateLimitWait {
+		wait = maxRateLimitWait
+	}
+
+	logging.FromContext(req.Context()).Warnf("[remotes.ratelimit] rate limited by %s, waiting %s", req.URL.Host, wait)
+	return t.sleep(req.Context(), wait)
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.transport().RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		resetAt := t.reset(resp)
+		wait, retry := t.retryAfter(resp, resetAt)
+
+		// requests with a body can only be retried when it can be replayed
+		if !retry || attempt >= rateLimitRetries || (req.Body != nil && req.GetBody == nil) {
+			if !resetAt.IsZero() {
+				if err := t.wait(req, resetAt.Sub(t.now())); err != nil {
+					_ = resp.Body.Close()
+					return nil, err
+				}
+			}
+
+			return resp, nil
+		}
+
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		if err := t.wait(req, wait); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package remotes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitTransport(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	reset := now.Add(10 * time.Minute)
+
+	testCases := []struct {
+		name     string
+		headers  []map[string]string
+		statuses []int
+		status   int
+		requests int
+		waits    []time.Duration
+	}{
+		{
+			name:     "not limited",
+			headers:  []map[string]string{{"X-RateLimit-Remaining": "10"}},
+			statuses: []int{http.StatusOK},
+			status:   http.StatusOK,
+			requests: 1,
+		},
+		{
+			name:     "exhausted",
+			headers:  []map[string]string{{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset.Unix(), 10)}},
+			statuses: []int{http.StatusOK},
+			status:   http.StatusOK,
+			requests: 1,
+			waits:    []time.Duration{10 * time.Minute},
+		},
+		{
+			name:     "secondary",
+			headers:  []map[string]string{{"Retry-After": "30"}, {}},
+			statuses: []int{http.StatusForbidden, http.StatusOK},
+			status:   http.StatusOK,
+			requests: 2,
+			waits:    []time.Duration{30 * time.Second},
+		},
+		{
+			name:     "gitlab",
+			headers:  []map[string]string{{"RateLimit-Remaining": "0", "RateLimit-Reset": strconv.FormatInt(reset.Unix(), 10)}, {}},
+			statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+			status:   http.StatusOK,
+			requests: 2,
+			waits:    []time.Duration{10 * time.Minute},
+		},
+		{
+			name:     "forbidden",
+			headers:  []map[string]string{{}},
+			statuses: []int{http.StatusForbidden},
+			status:   http.StatusForbidden,
+			requests: 1,
+		},
+		{
+			name:     "retries exhausted",
+			headers:  []map[string]string{{}, {}, {}, {}},
+			statuses: []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests},
+			status:   http.StatusTooManyRequests,
+			requests: 4,
+			waits:    []time.Duration{time.Minute, time.Minute, time.Minute},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for name, value := range testCase.headers[requests] {
+					w.Header().Set(name, value)
+				}
+				w.WriteHeader(testCase.statuses[requests])
+				requests++
+			}))
+			defer server.Close()
+
+			var waits []time.Duration
+			client := &http.Client{
+				Transport: &rateLimitTransport{
+					now: func() time.Time { return now },
+					sleep: func(ctx context.Context, d time.Duration) error {
+						waits = append(waits, d)
+						return nil
+					},
+				},
+			}
+
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, testCase.status, resp.StatusCode)
+			require.Equal(t, testCase.requests, requests)
+			require.Equal(t, testCase.waits, waits)
+		})
+	}
+}
@@ -1,11 +1,30 @@
 package remotes
 
-import "github.com/depscloud/depscloud/indexer/internal/config"
+import (
+	"context"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+)
 
 // Repository represents the combination of a URL and it's corresponding clone credentials.
+// Remotes also report the attributes they know about, which are used to filter
+// repositories, leaving the rest unset.
 type Repository struct {
 	RepositoryURL string
 	Clone         *config.Clone
+	// Paths narrows the files considered within the repository, when set.
+	Paths *config.PathRule
+
+	Name     string
+	Archived bool
+	Fork     bool
+	Topics   []string
+	// Size is in kilobytes.
+	Size int64
+	// PushedAt is when the repository last changed, as reported by the forge.
+	// It's the zero time when the forge doesn't report it.
+	PushedAt time.Time
 }
 
 // FetchRepositoriesRequest is a request wrapper that encapsulates request data.
@@ -20,5 +39,22 @@ type FetchRepositoriesResponse struct {
 
 // Remote defines an abstraction for interacting with upstream source control providers.
 type Remote interface {
-	FetchRepositories(request *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error)
+	FetchRepositories(ctx context.Context, request *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error)
+}
+
+// cloneWithToken adds the token to HTTP clones that weren't given credentials
+// of their own, so private repositories can be cloned.
+func cloneWithToken(cloneConfig *config.Clone, username, token string) *config.Clone {
+	if cloneConfig.GetStrategy() != config.CloneStrategy_HTTP ||
+		cloneConfig.GetBasic() != nil || cloneConfig.GetPublicKey() != nil ||
+		cloneConfig.GetToken() != nil || cloneConfig.GetNetrcPath() != "" {
+		return cloneConfig
+	}
+
+	withToken := *cloneConfig
+	withToken.Basic = &config.Basic{
+		Username: username,
+		Password: token,
+	}
+	return &withToken
 }
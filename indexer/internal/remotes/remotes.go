@@ -6,29 +6,56 @@ import (
 	"github.com/depscloud/depscloud/indexer/internal/config"
 )
 
+// ParseAccount constructs the remote endpoint described by a single account,
+// filtering its repositories when the account has a filter.
+func ParseAccount(account *config.Account) (Remote, error) {
+	remote, err := parseRemote(account)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter := account.GetFilter(); filter != nil {
+		if remote, err = NewFilteredRemote(remote, filter); err != nil {
+			return nil, err
+		}
+	}
+
+	if paths := account.GetPaths(); len(paths) > 0 {
+		return NewPathRemote(remote, paths)
+	}
+
+	return remote, nil
+}
+
+func parseRemote(account *config.Account) (Remote, error) {
+	if generic := account.GetGeneric(); generic != nil {
+		return NewGenericRemote(generic), nil
+	} else if bitbucket := account.GetBitbucket(); bitbucket != nil {
+		return NewBitbucketRemote(bitbucket)
+	} else if github := account.GetGithub(); github != nil {
+		return NewGithubRemote(github)
+	} else if gitlab := account.GetGitlab(); gitlab != nil {
+		return NewGitlabRemote(gitlab)
+	} else if gitea := account.GetGitea(); gitea != nil {
+		return NewGiteaRemote(gitea)
+	} else if azureDevops := account.GetAzureDevops(); azureDevops != nil {
+		return NewAzureDevopsRemote(azureDevops)
+	} else if codeCommit := account.GetCodeCommit(); codeCommit != nil {
+		return NewCodeCommitRemote(codeCommit)
+	} else if static := account.GetStatic(); static != nil {
+		return NewStaticRemote(static), nil
+	}
+
+	return nil, fmt.Errorf("unrecognized account")
+}
+
 // ParseConfig is used to parse the account configuration and construct the
 // necessary remote endpoint based on the configuration object.
 func ParseConfig(configuration *config.Configuration) (Remote, error) {
 	remotes := make([]Remote, len(configuration.Accounts))
 
 	for i, account := range configuration.Accounts {
-		var remote Remote
-		var err error
-
-		if generic := account.GetGeneric(); generic != nil {
-			remote = NewGenericRemote(generic)
-		} else if bitbucket := account.GetBitbucket(); bitbucket != nil {
-			remote, err = NewBitbucketRemote(bitbucket)
-		} else if github := account.GetGithub(); github != nil {
-			remote, err = NewGithubRemote(github)
-		} else if gitlab := account.GetGitlab(); gitlab != nil {
-			remote, err = NewGitlabRemote(gitlab)
-		} else if static := account.GetStatic(); static != nil {
-			remote = NewStaticRemote(static)
-		} else {
-			err = fmt.Errorf("unrecognized account")
-		}
-
+		remote, err := ParseAccount(account)
 		if err != nil {
 			return nil, err
 		}
@@ -1,6 +1,9 @@
 package remotes
 
-import "github.com/depscloud/depscloud/indexer/internal/config"
+import (
+	"context"
+	"github.com/depscloud/depscloud/indexer/internal/config"
+)
 
 var _ Remote = &staticRemote{}
 
@@ -15,16 +18,39 @@ type staticRemote struct {
 	config *config.Static
 }
 
-func (s *staticRemote) FetchRepositories(request *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
+func (s *staticRemote) FetchRepositories(ctx context.Context, request *FetchRepositoriesRequest) (*FetchRepositoriesResponse, error) {
 	cloneConfig := s.config.GetClone()
 
-	repositories := make([]*Repository, len(s.config.RepositoryUrls))
-	for i, repositoryURL := range s.config.RepositoryUrls {
-		repositories[i] = &Repository{
+	repositories := make([]*Repository, 0, len(s.config.RepositoryUrls))
+	for _, repositoryURL := range s.config.RepositoryUrls {
+		repositories = append(repositories, &Repository{
 			RepositoryURL: repositoryURL,
 			Clone:         cloneConfig,
+		})
+	}
+
+	listed := s.config.Repositories
+	if location := s.config.GetRepositoriesLocation(); location != "" {
+		list, err := config.LoadRepositories(location)
+		if err != nil {
+			return nil, err
 		}
+		listed = append(append([]*config.StaticRepository{}, listed...), list.GetRepositories()...)
 	}
+
+	// repositories without their own clone configuration use the shared one
+	for _, repository := range listed {
+		repositoryClone := repository.GetClone()
+		if repositoryClone == nil {
+			repositoryClone = cloneConfig
+		}
+
+		repositories = append(repositories, &Repository{
+			RepositoryURL: repository.GetRepositoryUrl(),
+			Clone:         repositoryClone,
+		})
+	}
+
 	return &FetchRepositoriesResponse{
 		Repositories: repositories,
 	}, nil
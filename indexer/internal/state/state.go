@@ -0,0 +1,192 @@
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/remotes"
+
+	"github.com/pkg/errors"
+)
+
+// Repository is what was last indexed from a repository's default branch.
+type Repository struct {
+	Commit    string    `json:"commit"`
+	IndexedAt time.Time `json:"indexedAt"`
+}
+
+// Source is the digest of the manifests last extracted from a source.
+type Source struct {
+	Digest      string    `json:"digest"`
+	ExtractedAt time.Time `json:"extractedAt"`
+}
+
+type data struct {
+	Repositories map[string]*Repository `json:"repositories"`
+	Sources      map[string]*Source     `json:"sources"`
+}
+
+// Store remembers what was last indexed, so repositories that changed are
+// indexed first and sources whose manifests haven't changed aren't extracted
+// again. Repositories are keyed by their canonical url, while sources are
+// keyed by the url they're tracked under.
+type Store struct {
+	path string
+	now  func() time.Time
+
+	mu   sync.Mutex
+	data *data
+}
+
+// Open reads the state file at the path, which doesn't need to exist yet. An
+// empty path keeps the state in memory, for the lifetime of the process.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		now:  time.Now,
+		data: &data{},
+	}
+
+	if path != "" {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		} else if err == nil {
+			if err := json.Unmarshal(contents, s.data); err != nil {
+				return nil, errors.Wrapf(err, "failed to read state %s", path)
+			}
+		}
+	}
+
+	if s.data.Repositories == nil {
+		s.data.Repositories = make(map[string]*Repository)
+	}
+	if s.data.Sources == nil {
+		s.data.Sources = make(map[string]*Source)
+	}
+
+	return s, nil
+}
+
+// flush atomically replaces the state file with the current state. The
+// caller must hold the lock.
+func (s *Store) flush() error {
+	if s.path == "" {
+		return nil
+	}
+
+	contents, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(contents); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Repository returns what was last indexed from the repository, or nil when
+// it hasn't been indexed yet.
+func (s *Store) Repository(repositoryURL string) *Repository {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if repository, ok := s.data.Repositories[remotes.CanonicalURL(repositoryURL)]; ok {
+		copied := *repository
+		return &copied
+	}
+	return nil
+}
+
+// Indexed records that the commit of the repository's default branch was
+// indexed.
+func (s *Store) Indexed(repositoryURL, commit string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Repositories[remotes.CanonicalURL(repositoryURL)] = &Repository{
+		Commit:    commit,
+		IndexedAt: s.now(),
+	}
+	return s.flush()
+}
+
+// Unchanged returns whether the manifests of the source match those it was
+// last extracted with, less than refresh ago. Sources are extracted again once
+// refresh passes, picking up changes to the extractor itself, and every time
+// when it isn't positive.
+func (s *Store) Unchanged(sourceURL, digest string, refresh time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source, ok := s.data.Sources[sourceURL]
+	if !ok || source.Digest != digest {
+		return false
+	}
+
+	return refresh > 0 && s.now().Sub(source.ExtractedAt) < refresh
+}
+
+// Extracted records the digest of the manifests the source was extracted
+// with.
+func (s *Store) Extracted(sourceURL, digest string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Sources[sourceURL] = &Source{
+		Digest:      digest,
+		ExtractedAt: s.now(),
+	}
+	return s.flush()
+}
+
+// changed returns whether the repository may have changed since it was last
+// indexed. Repositories that were never indexed, or whose forge doesn't
+// report when they were pushed, are assumed to have changed.
+func (s *Store) changed(repository *remotes.Repository) bool {
+	indexed, ok := s.data.Repositories[remotes.CanonicalURL(repository.RepositoryURL)]
+	if !ok || repository.PushedAt.IsZero() {
+		return true
+	}
+	return repository.PushedAt.After(indexed.IndexedAt)
+}
+
+// Prioritize orders the repositories so those that may have changed since
+// they were last indexed come first, keeping the order within each group.
+func (s *Store) Prioritize(repositories []*remotes.Repository) []*remotes.Repository {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := make(map[*remotes.Repository]bool, len(repositories))
+	for _, repository := range repositories {
+		changed[repository] = s.changed(repository)
+	}
+
+	prioritized := make([]*remotes.Repository, len(repositories))
+	copy(prioritized, repositories)
+
+	sort.SliceStable(prioritized, func(i, j int) bool {
+		return changed[prioritized[i]] && !changed[prioritized[j]]
+	})
+
+	return prioritized
+}
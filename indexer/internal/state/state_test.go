@@ -0,0 +1,63 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/remotes"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrioritize(t *testing.T) {
+	store, err := Open("")
+	require.NoError(t, err)
+
+	indexedAt := time.Now()
+	store.now = func() time.Time { return indexedAt }
+
+	require.NoError(t, store.Indexed("https://github.com/a/pushed.git", "abc"))
+	require.NoError(t, store.Indexed("https://github.com/a/idle.git", "def"))
+	require.NoError(t, store.Indexed("https://github.com/a/unknown.git", "ghi"))
+
+	idle := &remotes.Repository{RepositoryURL: "https://github.com/a/idle.git", PushedAt: indexedAt.Add(-time.Hour)}
+	pushed := &remotes.Repository{RepositoryURL: "https://github.com/A/pushed.git", PushedAt: indexedAt.Add(time.Hour)}
+	unknown := &remotes.Repository{RepositoryURL: "https://github.com/a/unknown.git"}
+	added := &remotes.Repository{RepositoryURL: "https://github.com/a/added.git", PushedAt: indexedAt.Add(-time.Hour)}
+
+	prioritized := store.Prioritize([]*remotes.Repository{idle, pushed, unknown, added})
+	require.Equal(t, []*remotes.Repository{pushed, unknown, added, idle}, prioritized)
+
+	require.Equal(t, "abc", store.Repository("https://github.com/a/pushed.git").Commit)
+	require.Nil(t, store.Repository("https://github.com/a/added.git"))
+}
+
+func TestUnchanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state.json")
+
+	store, err := Open(path)
+	require.NoError(t, err)
+
+	sourceURL := "https://github.com/a/b.git"
+	require.False(t, store.Unchanged(sourceURL, "digest", time.Hour))
+	require.NoError(t, store.Extracted(sourceURL, "digest"))
+
+	// the state lasts between runs
+	store, err = Open(path)
+	require.NoError(t, err)
+
+	require.True(t, store.Unchanged(sourceURL, "digest", time.Hour))
+	require.False(t, store.Unchanged(sourceURL, "changed", time.Hour))
+	require.False(t, store.Unchanged(sourceURL, "digest", 0))
+
+	// unchanged sources are extracted again once the refresh passes
+	store.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	require.False(t, store.Unchanged(sourceURL, "digest", time.Hour))
+}
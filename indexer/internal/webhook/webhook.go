@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/depscloud/depscloud/indexer/internal/remotes"
+	"github.com/depscloud/depscloud/internal/logging"
+)
+
+// maxPayloadSize bounds the size of the push payloads that are read.
+const maxPayloadSize = 25 << 20
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		repositories: make(map[string]*remotes.Repository),
+	}
+}
+
+// Registry tracks the repositories discovered by the indexer. Only pushes to
+// repositories in the registry are indexed, using the clone configuration they
+// were discovered with.
+type Registry struct {
+	mu           sync.RWMutex
+	repositories map[string]*remotes.Repository
+}
+
+// Add records the repositories, replacing any previously discovered
+// repository with the same canonical url.
+func (r *Registry) Add(repositories []*remotes.Repository) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, repository := range repositories {
+		r.repositories[remotes.CanonicalURL(repository.RepositoryURL)] = repository
+	}
+}
+
+// Lookup returns the first repository known by one of the urls, or nil when
+// none of them are known.
+func (r *Registry) Lookup(urls ...string) *remotes.Repository {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+
+		if repository, ok := r.repositories[remotes.CanonicalURL(url)]; ok {
+			return repository
+		}
+	}
+	return nil
+}
+
+// Config contains the secrets used to verify deliveries. Deliveries from a
+// provider without a secret are rejected.
+type Config struct {
+	GithubSecret string
+	GitlabToken  string
+}
+
+// NewHandler returns a handler that accepts GitHub push events on
+// /webhooks/github and GitLab push events on /webhooks/gitlab. Pushes to the
+// default branch of a known repository are indexed in the background.
+func NewHandler(cfg *Config, registry *Registry, index func(*remotes.Repository)) http.Handler {
+	h := &handler{
+		config:   cfg,
+		registry: registry,
+		index:    index,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhooks/github", h.github)
+	mux.HandleFunc("/webhooks/gitlab", h.gitlab)
+	return mux
+}
+
+type handler struct {
+	config   *Config
+	registry *Registry
+	index    func(*remotes.Repository)
+}
+
+// push is the part of a push event used to find the repository to index.
+type push struct {
+	ref           string
+	defaultBranch string
+	urls          []string
+}
+
+func (h *handler) read(writer http.ResponseWriter, request *http.Request) ([]byte, bool) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(writer, request.Body, maxPayloadSize))
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+
+	return body, true
+}
+
+func (h *handler) handle(ctx context.Context, writer http.ResponseWriter, provider string, event *push) {
+	if event.ref != "refs/heads/"+event.defaultBranch {
+		logging.FromContext(ctx).Infof("[webhook.%s] ignoring push to %s", provider, event.ref)
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	repository := h.registry.Lookup(event.urls...)
+	if repository == nil {
+		logging.FromContext(ctx).Infof("[webhook.%s] ignoring push to unknown repository %v", provider, event.urls)
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	logging.FromContext(ctx).Infof("[webhook.%s] indexing %s", provider, repository.RepositoryURL)
+	go h.index(repository)
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// verifyGithubSignature checks the X-Hub-Signature-256 header, which is the
+// hex encoded HMAC-SHA256 of the body prefixed with "sha256=".
+func verifyGithubSignature(secret string, body []byte, signature string) bool {
+	if secret == "" || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+
+	actual, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(actual, mac.Sum(nil))
+}
+
+type githubPush struct {
+	Ref        string `json:"ref"`
+	Repository struct {
+		CloneURL      string `json:"clone_url"`
+		SSHURL        string `json:"ssh_url"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+}
+
+func (h *handler) github(writer http.ResponseWriter, request *http.Request) {
+	body, ok := h.read(writer, request)
+	if !ok {
+		return
+	}
+
+	if !verifyGithubSignature(h.config.GithubSecret, body, request.Header.Get("X-Hub-Signature-256")) {
+		writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// ping and other events are acknowledged without doing anything
+	if request.Header.Get("X-GitHub-Event") != "push" {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	event := &githubPush{}
+	if err := json.Unmarshal(body, event); err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.handle(request.Context(), writer, "github", &push{
+		ref:           event.Ref,
+		defaultBranch: event.Repository.DefaultBranch,
+		urls:          []string{event.Repository.CloneURL, event.Repository.SSHURL},
+	})
+}
+
+// verifyGitlabToken checks the X-Gitlab-Token header, which GitLab sets to the
+// secret token of the webhook.
+func verifyGitlabToken(secret, token string) bool {
+	return secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1
+}
+
+type gitlabPush struct {
+	Ref     string `json:"ref"`
+	Project struct {
+		GitHTTPURL    string `json:"git_http_url"`
+		GitSSHURL     string `json:"git_ssh_url"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"project"`
+}
+
+func (h *handler) gitlab(writer http.ResponseWriter, request *http.Request) {
+	body, ok := h.read(writer, request)
+	if !ok {
+		return
+	}
+
+	if !verifyGitlabToken(h.config.GitlabToken, request.Header.Get("X-Gitlab-Token")) {
+		writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if request.Header.Get("X-Gitlab-Event") != "Push Hook" {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	event := &gitlabPush{}
+	if err := json.Unmarshal(body, event); err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.handle(request.Context(), writer, "gitlab", &push{
+		ref:           event.Ref,
+		defaultBranch: event.Project.DefaultBranch,
+		urls:          []string{event.Project.GitHTTPURL, event.Project.GitSSHURL},
+	})
+}
@@ -0,0 +1,119 @@
+package webhook_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/depscloud/indexer/internal/remotes"
+	"github.com/depscloud/depscloud/indexer/internal/webhook"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newHandler() (http.Handler, chan *remotes.Repository) {
+	registry := webhook.NewRegistry()
+	registry.Add([]*remotes.Repository{
+		{RepositoryURL: "https://github.com/depscloud/depscloud.git"},
+		{RepositoryURL: "git@gitlab.com:depscloud/depscloud.git"},
+	})
+
+	indexed := make(chan *remotes.Repository, 1)
+	handler := webhook.NewHandler(&webhook.Config{
+		GithubSecret: "secret",
+		GitlabToken:  "token",
+	}, registry, func(repository *remotes.Repository) {
+		indexed <- repository
+	})
+
+	return handler, indexed
+}
+
+func TestGithub(t *testing.T) {
+	handler, indexed := newHandler()
+
+	body := []byte(`{
+		"ref": "refs/heads/main",
+		"repository": {
+			"clone_url": "https://github.com/depscloud/depscloud.git",
+			"ssh_url": "git@github.com:depscloud/depscloud.git",
+			"default_branch": "main"
+		}
+	}`)
+	feature := []byte(`{"ref":"refs/heads/feature","repository":{"default_branch":"main"}}`)
+	unknown := []byte(`{"ref":"refs/heads/main","repository":{"clone_url":"https://github.com/a/b.git","default_branch":"main"}}`)
+
+	testCases := []struct {
+		event     string
+		signature string
+		body      []byte
+		status    int
+	}{
+		{"push", "", body, http.StatusUnauthorized},
+		{"push", sign("other", body), body, http.StatusUnauthorized},
+		{"ping", sign("secret", body), body, http.StatusNoContent},
+		{"push", sign("secret", feature), feature, http.StatusNoContent},
+		{"push", sign("secret", unknown), unknown, http.StatusNotFound},
+		{"push", sign("secret", body), body, http.StatusAccepted},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(http.MethodPost, "/webhooks/github", bytes.NewReader(testCase.body))
+		request.Header.Set("X-GitHub-Event", testCase.event)
+		request.Header.Set("X-Hub-Signature-256", testCase.signature)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		require.Equal(t, testCase.status, recorder.Code)
+	}
+
+	repository := <-indexed
+	require.Equal(t, "https://github.com/depscloud/depscloud.git", repository.RepositoryURL)
+}
+
+func TestGitlab(t *testing.T) {
+	handler, indexed := newHandler()
+
+	body := []byte(`{
+		"ref": "refs/heads/master",
+		"project": {
+			"git_http_url": "https://gitlab.com/depscloud/depscloud.git",
+			"git_ssh_url": "git@gitlab.com:depscloud/depscloud.git",
+			"default_branch": "master"
+		}
+	}`)
+
+	testCases := []struct {
+		event  string
+		token  string
+		status int
+	}{
+		{"Push Hook", "", http.StatusUnauthorized},
+		{"Push Hook", "other", http.StatusUnauthorized},
+		{"Tag Push Hook", "token", http.StatusNoContent},
+		{"Push Hook", "token", http.StatusAccepted},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(http.MethodPost, "/webhooks/gitlab", bytes.NewReader(body))
+		request.Header.Set("X-Gitlab-Event", testCase.event)
+		request.Header.Set("X-Gitlab-Token", testCase.token)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		require.Equal(t, testCase.status, recorder.Code)
+	}
+
+	repository := <-indexed
+	require.Equal(t, "git@gitlab.com:depscloud/depscloud.git", repository.RepositoryURL)
+}
@@ -1,23 +1,39 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/depscloud/api/v1alpha/extractor"
 	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/indexer/internal/checkpoint"
 	"github.com/depscloud/depscloud/indexer/internal/config"
 	"github.com/depscloud/depscloud/indexer/internal/consumer"
+	"github.com/depscloud/depscloud/indexer/internal/history"
+	"github.com/depscloud/depscloud/indexer/internal/kubernetes"
 	"github.com/depscloud/depscloud/indexer/internal/remotes"
+	"github.com/depscloud/depscloud/indexer/internal/state"
+	"github.com/depscloud/depscloud/indexer/internal/webhook"
 	"github.com/depscloud/depscloud/internal/client"
+	sharedconfig "github.com/depscloud/depscloud/internal/config"
+	"github.com/depscloud/depscloud/internal/diagnostics"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/middleware"
 	"github.com/depscloud/depscloud/internal/mux"
+	"github.com/depscloud/depscloud/internal/telemetry"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/urfave/cli/v2"
 
+	"google.golang.org/grpc"
 	_ "google.golang.org/grpc/health"
 
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
@@ -29,19 +45,135 @@ var version string
 var commit string
 var date string
 
+// The run names used to checkpoint the controller and a single run of the
+// config file. Scheduled runs are named after their source.
+const (
+	controllerRun = "controller"
+	defaultRun    = "default"
+)
+
 // NewWorker encapsulates logic for pulling information off a channel and invoking the consumer
-func NewWorker(repositories chan *remotes.Repository, wg *sync.WaitGroup, rc consumer.RepositoryConsumer) {
+func NewWorker(ctx context.Context, repositories chan *remotes.Repository, wg *sync.WaitGroup, rc consumer.RepositoryConsumer, run *checkpoint.Run, p *progress) {
 	for repository := range repositories {
-		rc.Consume(repository)
+		p.started()
+		p.finished(repository.RepositoryURL, rc.Consume(ctx, repository))
+
+		// repositories cut off by the run deadline are picked up on resume
+		if ctx.Err() == nil {
+			if err := run.Complete(repository.RepositoryURL); err != nil {
+				logging.FromContext(ctx).Errorf("[main] failed to checkpoint %s: %v", repository.RepositoryURL, err)
+			}
+		}
+
 		wg.Done()
 	}
 }
 
+// indexRepositories consumes the repositories using the given number of
+// workers, returning whether all of them have been consumed. Repositories
+// completed by an earlier attempt of the run are skipped, as are those that
+// haven't been started by the time the context is done.
+func indexRepositories(ctx context.Context, workers int, rc consumer.RepositoryConsumer, run *checkpoint.Run, p *progress, repositories []*remotes.Repository) bool {
+	if resumed := run.Resumed(); resumed > 0 {
+		logging.FromContext(ctx).Infof("[main] resuming run, %d repositories were already indexed", resumed)
+	}
+
+	// start a wait group to track remaining work
+	wg := &sync.WaitGroup{}
+
+	queue := make(chan *remotes.Repository, workers)
+	defer close(queue)
+
+	for i := 0; i < workers; i++ {
+		go NewWorker(ctx, queue, wg, rc, run, p)
+	}
+
+	// feed until there are no more left
+	for i, repository := range repositories {
+		if run.Completed(repository.RepositoryURL) {
+			p.skip(1)
+			continue
+		}
+
+		wg.Add(1)
+
+		select {
+		case queue <- repository:
+		case <-ctx.Done():
+			wg.Done()
+			logging.FromContext(ctx).Errorf("[main] run deadline exceeded, skipping %d repositories", len(repositories)-i)
+			p.skip(len(repositories) - i)
+			wg.Wait()
+			return false
+		}
+	}
+
+	// wait for all work to be done
+	wg.Wait()
+	return ctx.Err() == nil
+}
+
+// serveHTTP serves metrics, health, and run history for the long running
+// modes, along with webhooks when they're enabled.
+func serveHTTP(ctx context.Context, port int, runs, webhooks http.Handler) {
+	httpMux := http.NewServeMux()
+	httpMux.Handle("/metrics", promhttp.Handler())
+	httpMux.Handle("/runs", runs)
+	httpMux.HandleFunc("/healthz", func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	if webhooks != nil {
+		httpMux.Handle("/webhooks/", webhooks)
+	}
+
+	if err := http.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", port), httpMux); err != nil {
+		logging.FromContext(ctx).Errorf("[main] failed to serve http: %v", err)
+	}
+}
+
 type indexerConfig struct {
 	workers    int
 	configPath string
+
+	extractConcurrency int
+	repositoryTimeout  time.Duration
+	runDeadline        time.Duration
+
+	controller          bool
+	controllerNamespace string
+	controllerInterval  time.Duration
+
+	scheduler       bool
+	defaultSchedule string
+	httpPort        int
+
+	leaderElect              bool
+	leaderElectLease         string
+	leaderElectNamespace     string
+	leaderElectLeaseDuration time.Duration
+
+	sparseCheckout bool
+	dryRun         bool
+
+	progressInterval time.Duration
+	historySize      int
+
+	statePath        string
+	unchangedRefresh time.Duration
+
+	checkpointPath   string
+	checkpointMaxAge time.Duration
+
+	githubWebhookSecret string
+	gitlabWebhookToken  string
+
+	tokenPath string
+
 	sshUser    string
 	sshKeyPath string
+	includes   *cli.StringSlice
+	excludes   *cli.StringSlice
 }
 
 var description = strings.TrimSpace(`
@@ -53,8 +185,37 @@ func main() {
 	cfg := &indexerConfig{
 		workers:    5,
 		configPath: "",
+
+		extractConcurrency: 0,
+		repositoryTimeout:  30 * time.Minute,
+		runDeadline:        0,
+
+		checkpointPath:   "",
+		checkpointMaxAge: 24 * time.Hour,
+
+		progressInterval: time.Minute,
+		historySize:      20,
+
+		statePath:        "",
+		unchangedRefresh: 7 * 24 * time.Hour,
+
 		sshUser:    "git",
 		sshKeyPath: "",
+		includes:   cli.NewStringSlice(),
+		excludes:   cli.NewStringSlice(),
+
+		controller:          false,
+		controllerNamespace: "",
+		controllerInterval:  time.Hour,
+
+		scheduler:       false,
+		defaultSchedule: "@daily",
+		httpPort:        8080,
+
+		leaderElect:              false,
+		leaderElectLease:         "depscloud-indexer",
+		leaderElectNamespace:     "",
+		leaderElectLeaseDuration: 15 * time.Second,
 	}
 
 	extractorConfig, extractorFlags := client.WithFlags("extractor", &client.Config{
@@ -81,6 +242,69 @@ func main() {
 			Destination: &cfg.workers,
 			EnvVars:     []string{"WORKERS"},
 		},
+		&cli.IntFlag{
+			Name:        "extract-concurrency",
+			Usage:       "number of repositories extracted at once, defaulting to one per worker",
+			Value:       cfg.extractConcurrency,
+			Destination: &cfg.extractConcurrency,
+			EnvVars:     []string{"EXTRACT_CONCURRENCY"},
+		},
+		&cli.DurationFlag{
+			Name:        "repository-timeout",
+			Usage:       "how long a single repository can take to process, 0 for no limit",
+			Value:       cfg.repositoryTimeout,
+			Destination: &cfg.repositoryTimeout,
+			EnvVars:     []string{"REPOSITORY_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:        "run-deadline",
+			Usage:       "how long a run can take before the remaining repositories are skipped, 0 for no limit",
+			Value:       cfg.runDeadline,
+			Destination: &cfg.runDeadline,
+			EnvVars:     []string{"RUN_DEADLINE"},
+		},
+		&cli.StringFlag{
+			Name:        "checkpoint-path",
+			Usage:       "file to record the progress of runs in, so interrupted runs resume where they left off",
+			Value:       cfg.checkpointPath,
+			Destination: &cfg.checkpointPath,
+			EnvVars:     []string{"CHECKPOINT_PATH"},
+		},
+		&cli.DurationFlag{
+			Name:        "checkpoint-max-age",
+			Usage:       "how long after starting an interrupted run can be resumed, 0 for no limit",
+			Value:       cfg.checkpointMaxAge,
+			Destination: &cfg.checkpointMaxAge,
+			EnvVars:     []string{"CHECKPOINT_MAX_AGE"},
+		},
+		&cli.DurationFlag{
+			Name:        "progress-interval",
+			Usage:       "how often to log the progress of a run, 0 to disable",
+			Value:       cfg.progressInterval,
+			Destination: &cfg.progressInterval,
+			EnvVars:     []string{"PROGRESS_INTERVAL"},
+		},
+		&cli.IntFlag{
+			Name:        "history-size",
+			Usage:       "number of runs to keep the history of, served on /runs",
+			Value:       cfg.historySize,
+			Destination: &cfg.historySize,
+			EnvVars:     []string{"HISTORY_SIZE"},
+		},
+		&cli.StringFlag{
+			Name:        "state-path",
+			Usage:       "file to remember what was indexed in, so it lasts between runs of the indexer",
+			Value:       cfg.statePath,
+			Destination: &cfg.statePath,
+			EnvVars:     []string{"STATE_PATH"},
+		},
+		&cli.DurationFlag{
+			Name:        "unchanged-refresh",
+			Usage:       "how long sources whose manifests haven't changed skip extraction, 0 to always extract",
+			Value:       cfg.unchangedRefresh,
+			Destination: &cfg.unchangedRefresh,
+			EnvVars:     []string{"UNCHANGED_REFRESH"},
+		},
 		&cli.StringFlag{
 			Name:        "config",
 			Usage:       "path to the config file",
@@ -102,10 +326,139 @@ func main() {
 			Destination: &cfg.sshKeyPath,
 			EnvVars:     []string{"SSH_KEYPATH"},
 		},
+		&cli.StringSliceFlag{
+			Name:        "include",
+			Usage:       "globs a path must match for it to be considered during extraction",
+			Destination: cfg.includes,
+			EnvVars:     []string{"INCLUDE"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "exclude",
+			Usage:       "globs that remove paths from consideration during extraction (e.g. **/vendor/**)",
+			Destination: cfg.excludes,
+			EnvVars:     []string{"EXCLUDE"},
+		},
+		&cli.BoolFlag{
+			Name:        "controller",
+			Usage:       "continuously index the IndexerSource resources in the cluster instead of a config file",
+			Value:       cfg.controller,
+			Destination: &cfg.controller,
+			EnvVars:     []string{"CONTROLLER"},
+		},
+		&cli.StringFlag{
+			Name:        "controller-namespace",
+			Usage:       "the namespace to read IndexerSource resources from, defaulting to the pod's namespace",
+			Value:       cfg.controllerNamespace,
+			Destination: &cfg.controllerNamespace,
+			EnvVars:     []string{"CONTROLLER_NAMESPACE"},
+		},
+		&cli.DurationFlag{
+			Name:        "controller-interval",
+			Usage:       "how often the controller indexes its sources",
+			Value:       cfg.controllerInterval,
+			Destination: &cfg.controllerInterval,
+			EnvVars:     []string{"CONTROLLER_INTERVAL"},
+		},
+		&cli.BoolFlag{
+			Name:        "scheduler",
+			Usage:       "keep running, indexing each account in the config file on its own schedule",
+			Value:       cfg.scheduler,
+			Destination: &cfg.scheduler,
+			EnvVars:     []string{"SCHEDULER"},
+		},
+		&cli.StringFlag{
+			Name:        "default-schedule",
+			Usage:       "the cron schedule of accounts that don't have their own",
+			Value:       cfg.defaultSchedule,
+			Destination: &cfg.defaultSchedule,
+			EnvVars:     []string{"DEFAULT_SCHEDULE"},
+		},
+		&cli.IntFlag{
+			Name:        "http-port",
+			Usage:       "the port to serve metrics and health on when running as a controller or scheduler",
+			Value:       cfg.httpPort,
+			Destination: &cfg.httpPort,
+			EnvVars:     []string{"HTTP_PORT"},
+		},
+		&cli.BoolFlag{
+			Name:        "leader-elect",
+			Usage:       "only index from the replica holding a kubernetes lease, so several replicas can run for availability",
+			Value:       cfg.leaderElect,
+			Destination: &cfg.leaderElect,
+			EnvVars:     []string{"LEADER_ELECT"},
+		},
+		&cli.StringFlag{
+			Name:        "leader-elect-lease",
+			Usage:       "the name of the lease used to elect the leader",
+			Value:       cfg.leaderElectLease,
+			Destination: &cfg.leaderElectLease,
+			EnvVars:     []string{"LEADER_ELECT_LEASE"},
+		},
+		&cli.StringFlag{
+			Name:        "leader-elect-namespace",
+			Usage:       "the namespace of the lease, defaulting to the pod's namespace",
+			Value:       cfg.leaderElectNamespace,
+			Destination: &cfg.leaderElectNamespace,
+			EnvVars:     []string{"LEADER_ELECT_NAMESPACE"},
+		},
+		&cli.DurationFlag{
+			Name:        "leader-elect-lease-duration",
+			Usage:       "how long the leader can go without renewing the lease before another replica takes over",
+			Value:       cfg.leaderElectLeaseDuration,
+			Destination: &cfg.leaderElectLeaseDuration,
+			EnvVars:     []string{"LEADER_ELECT_LEASE_DURATION"},
+		},
+		&cli.BoolFlag{
+			Name:        "sparse-checkout",
+			Usage:       "only check out the files matching the extractor's manifest patterns",
+			Value:       cfg.sparseCheckout,
+			Destination: &cfg.sparseCheckout,
+			EnvVars:     []string{"SPARSE_CHECKOUT"},
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "discover repositories and match their files, printing a report instead of extracting and storing dependencies",
+			Value:       cfg.dryRun,
+			Destination: &cfg.dryRun,
+			EnvVars:     []string{"DRY_RUN"},
+		},
+		&cli.StringFlag{
+			Name:        "github-webhook-secret",
+			Usage:       "the secret used to verify github push webhooks, enabling /webhooks/github",
+			Value:       cfg.githubWebhookSecret,
+			Destination: &cfg.githubWebhookSecret,
+			EnvVars:     []string{"GITHUB_WEBHOOK_SECRET"},
+		},
+		&cli.StringFlag{
+			Name:        "gitlab-webhook-token",
+			Usage:       "the secret token used to verify gitlab push webhooks, enabling /webhooks/gitlab",
+			Value:       cfg.gitlabWebhookToken,
+			Destination: &cfg.gitlabWebhookToken,
+			EnvVars:     []string{"GITLAB_WEBHOOK_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:        "token-path",
+			Usage:       "path to a service account token used to authenticate with the tracker",
+			Value:       cfg.tokenPath,
+			Destination: &cfg.tokenPath,
+			EnvVars:     []string{"TOKEN_PATH"},
+		},
 	}
 	flags = append(flags, extractorFlags...)
 	flags = append(flags, trackerFlags...)
 
+	telemetryConfig, telemetryFlags := telemetry.WithFlags(telemetry.DefaultConfig())
+	flags = append(flags, telemetryFlags...)
+
+	loggingConfig, loggingFlags := logging.WithFlags(logging.DefaultConfig())
+	flags = append(flags, loggingFlags...)
+
+	middlewareConfig, middlewareFlags := middleware.WithFlags(middleware.DefaultConfig())
+	flags = append(flags, middlewareFlags...)
+
+	diagnosticsConfig, diagnosticsFlags := diagnostics.WithFlags(&diagnostics.Config{})
+	flags = append(flags, diagnosticsFlags...)
+
 	app := &cli.App{
 		Name:        "indexer",
 		Usage:       "crawl sources and store extracted content",
@@ -123,14 +476,42 @@ func main() {
 			},
 		},
 		Flags: flags,
-		Action: func(context *cli.Context) error {
+		Action: func(c *cli.Context) error {
+			if err := logging.Setup("indexer", loggingConfig); err != nil {
+				return err
+			}
+			middleware.Setup(middlewareConfig)
+
+			if err := diagnostics.Serve(c.Context, diagnosticsConfig); err != nil {
+				return err
+			}
+
+			tracer, err := telemetry.NewTracer("indexer", version, telemetryConfig, nil)
+			if err != nil {
+				return err
+			} else if tracer != nil {
+				telemetry.SetTracer(tracer)
+				go tracer.Run(c.Context)
+			}
+
+			metricExporter, err := telemetry.NewMetricExporter("indexer", version, telemetryConfig, nil, nil)
+			if err != nil {
+				return err
+			}
+			go metricExporter.Run(c.Context)
+
 			extractorConn, err := client.Connect(extractorConfig)
 			if err != nil {
 				return err
 			}
 			defer extractorConn.Close()
 
-			trackerConn, err := client.Connect(trackerConfig)
+			var trackerOptions []grpc.DialOption
+			if cfg.tokenPath != "" {
+				trackerOptions = append(trackerOptions, grpc.WithPerRPCCredentials(client.TokenFileCredentials(cfg.tokenPath)))
+			}
+
+			trackerConn, err := client.Connect(trackerConfig, trackerOptions...)
 			if err != nil {
 				return err
 			}
@@ -139,60 +520,215 @@ func main() {
 			extractorClient := extractor.NewDependencyExtractorClient(extractorConn)
 			sourceService := tracker.NewSourceServiceClient(trackerConn)
 
-			var remoteConfig *config.Configuration
+			var authMethod transport.AuthMethod
 
-			if len(cfg.configPath) > 0 {
-				remoteConfig, err = config.Load(cfg.configPath)
+			if len(cfg.sshKeyPath) > 0 {
+				logging.FromContext(c.Context).Infof("[main] loading ssh key")
+				authMethod, err = ssh.NewPublicKeysFromFile(cfg.sshUser, cfg.sshKeyPath, "")
 				if err != nil {
 					return err
 				}
-			} else {
-				return fmt.Errorf("--config must be provided")
 			}
 
-			remote, err := remotes.ParseConfig(remoteConfig)
+			var report *consumer.Report
+			if cfg.dryRun {
+				if cfg.controller || cfg.scheduler {
+					return fmt.Errorf("--dry-run can't be used with --controller or --scheduler")
+				}
+				report = &consumer.Report{}
+			}
+
+			indexState, err := state.Open(cfg.statePath)
 			if err != nil {
 				return err
 			}
 
-			var authMethod transport.AuthMethod
+			rc := consumer.NewConsumer(authMethod, extractorClient, sourceService, &consumer.Filter{
+				Includes: cfg.includes.Value(),
+				Excludes: cfg.excludes.Value(),
+			}, &consumer.Options{
+				Sparse:             cfg.sparseCheckout,
+				Timeout:            cfg.repositoryTimeout,
+				ExtractConcurrency: cfg.extractConcurrency,
+				DryRun:             report,
+				State:              indexState,
+				Refresh:            cfg.unchangedRefresh,
+			})
 
-			if len(cfg.sshKeyPath) > 0 {
-				logrus.Infof("[main] loading ssh key")
-				authMethod, err = ssh.NewPublicKeysFromFile(cfg.sshUser, cfg.sshKeyPath, "")
+			// dry runs don't index anything, so there's no progress to keep
+			var checkpoints *checkpoint.Store
+			if len(cfg.checkpointPath) > 0 && !cfg.dryRun {
+				if checkpoints, err = checkpoint.Open(cfg.checkpointPath, cfg.checkpointMaxAge); err != nil {
+					return err
+				}
+			}
+
+			// pushes are only indexed for repositories that have been discovered
+			registry := webhook.NewRegistry()
+			runHistory := history.New(cfg.historySize)
+
+			index := func(name string, repositories []*remotes.Repository) {
+				registry.Add(repositories)
+
+				// repositories that changed since they were last indexed go first
+				repositories = indexState.Prioritize(repositories)
+
+				// every repository of the run is traced as part of it
+				ctx, span := telemetry.Start(context.Background(), "indexer.run")
+				span.SetAttribute("run.name", name)
+				defer span.End(nil)
+
+				if cfg.runDeadline > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, cfg.runDeadline)
+					defer cancel()
+				}
+
+				var run *checkpoint.Run
+				if checkpoints != nil {
+					var err error
+					if run, err = checkpoints.Start(name); err != nil {
+						logging.FromContext(ctx).Errorf("[main] failed to start checkpoint for %s: %v", name, err)
+					}
+				}
+
+				record := runHistory.Start(name, len(repositories))
+				defer record.Finish()
+
+				p := newProgress(name, len(repositories), record)
+				reportCtx, stopReport := context.WithCancel(ctx)
+				go p.report(reportCtx, cfg.progressInterval)
+
+				finished := indexRepositories(ctx, cfg.workers, rc, run, p, repositories)
+				stopReport()
+				p.log(ctx, "[main] run finished")
+
+				if finished {
+					if err := run.Finish(); err != nil {
+						logging.FromContext(ctx).Errorf("[main] failed to finish checkpoint for %s: %v", name, err)
+					}
+				}
+			}
+
+			var webhooks http.Handler
+			if cfg.githubWebhookSecret != "" || cfg.gitlabWebhookToken != "" {
+				if !cfg.controller && !cfg.scheduler {
+					return fmt.Errorf("webhooks require --controller or --scheduler")
+				}
+
+				webhooks = webhook.NewHandler(&webhook.Config{
+					GithubSecret: cfg.githubWebhookSecret,
+					GitlabToken:  cfg.gitlabWebhookToken,
+				}, registry, func(repository *remotes.Repository) {
+					rc.Consume(context.Background(), repository)
+				})
+			}
+
+			var elector *kubernetes.LeaderElector
+			if cfg.leaderElect {
+				if !cfg.controller && !cfg.scheduler {
+					return fmt.Errorf("--leader-elect requires --controller or --scheduler")
+				}
+
+				leaseClient, err := kubernetes.InClusterClient(cfg.leaderElectNamespace)
+				if err != nil {
+					return err
+				}
+
+				identity, err := os.Hostname()
 				if err != nil {
 					return err
 				}
+
+				elector = kubernetes.NewLeaderElector(leaseClient, cfg.leaderElectLease, identity, cfg.leaderElectLeaseDuration)
 			}
 
-			resp, err := remote.FetchRepositories(&remotes.FetchRepositoriesRequest{})
-			if err != nil {
-				return err
+			// with leader election, sources are only run by the leader, which
+			// picks up any runs a previous leader left unfinished
+			lead := func(run func(ctx context.Context, interrupted []string) error) error {
+				start := func(ctx context.Context) error {
+					var interrupted []string
+					if checkpoints != nil {
+						if err := checkpoints.Reload(); err != nil {
+							logging.FromContext(ctx).Errorf("[main] failed to reload checkpoint: %v", err)
+						}
+						interrupted = checkpoints.Interrupted()
+					}
+
+					return run(ctx, interrupted)
+				}
+
+				if elector == nil {
+					return start(context.Background())
+				}
+
+				elector.Run(context.Background(), func(ctx context.Context) {
+					if err := start(ctx); err != nil {
+						logging.FromContext(ctx).Errorf("[main] %v", err)
+					}
+				})
+				return nil
+			}
+
+			if cfg.controller || cfg.scheduler {
+				go serveHTTP(c.Context, cfg.httpPort, runHistory, webhooks)
+			}
+
+			if cfg.controller {
+				kubernetesClient, err := kubernetes.InClusterClient(cfg.controllerNamespace)
+				if err != nil {
+					return err
+				}
+
+				// the controller runs right away, resuming any interrupted run
+				return lead(func(ctx context.Context, _ []string) error {
+					return runController(ctx, kubernetesClient, cfg.controllerInterval, index)
+				})
+			}
+
+			var remoteConfig *config.Configuration
+
+			if len(cfg.configPath) > 0 {
+				remoteConfig, err = config.Load(cfg.configPath)
+				if err != nil {
+					return err
+				}
+			} else {
+				return fmt.Errorf("--config must be provided")
 			}
 
-			// start a wait group to track remaining work
-			wg := &sync.WaitGroup{}
-			wg.Add(len(resp.Repositories))
+			if cfg.scheduler {
+				sources, err := scheduleSources(remoteConfig.GetAccounts(), cfg.defaultSchedule)
+				if err != nil {
+					return err
+				}
 
-			repositories := make(chan *remotes.Repository, cfg.workers)
-			defer close(repositories)
+				return lead(func(ctx context.Context, interrupted []string) error {
+					return runScheduler(ctx, sources, interrupted, index)
+				})
+			}
 
-			rc := consumer.NewConsumer(authMethod, extractorClient, sourceService)
-			for i := 0; i < cfg.workers; i++ {
-				go NewWorker(repositories, wg, rc)
+			remote, err := remotes.ParseConfig(remoteConfig)
+			if err != nil {
+				return err
 			}
 
-			// feed until there are no more left
-			for _, repository := range resp.Repositories {
-				repositories <- repository
+			resp, err := remote.FetchRepositories(c.Context, &remotes.FetchRepositoriesRequest{})
+			if err != nil {
+				return err
 			}
 
-			// wait for all work to be done
-			wg.Wait()
+			index(defaultRun, resp.Repositories)
+
+			if report != nil {
+				return report.Write(os.Stdout)
+			}
 			return nil
 		},
 	}
 
+	sharedconfig.Apply(app)
+
 	if err := app.Run(os.Args); err != nil {
 		logrus.Fatal(err)
 	}
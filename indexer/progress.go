@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/history"
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	repositoriesDiscovered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "indexer_repositories_discovered_total",
+		Help: "The number of repositories handed to runs for indexing.",
+	})
+
+	repositoriesIndexed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_repositories_indexed_total",
+		Help: "The number of repositories indexed, by result (success, failure, skipped).",
+	}, []string{"result"})
+
+	repositoriesQueued = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "indexer_queue_depth",
+		Help: "The number of repositories waiting to be indexed.",
+	})
+
+	repositoriesInProgress = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "indexer_repositories_in_progress",
+		Help: "The number of repositories being indexed.",
+	})
+)
+
+// progress tracks how far along a run is, keeping the metrics and run history
+// up to date and periodically logging it.
+type progress struct {
+	run    string
+	start  time.Time
+	total  int64
+	record *history.Recorder
+
+	queued     int64
+	inProgress int64
+	succeeded  int64
+	failed     int64
+	skipped    int64
+}
+
+func newProgress(run string, total int, record *history.Recorder) *progress {
+	repositoriesDiscovered.Add(float64(total))
+	repositoriesQueued.Add(float64(total))
+
+	return &progress{
+		run:    run,
+		start:  time.Now(),
+		total:  int64(total),
+		queued: int64(total),
+		record: record,
+	}
+}
+
+// started marks a queued repository as being indexed.
+func (p *progress) started() {
+	atomic.AddInt64(&p.queued, -1)
+	atomic.AddInt64(&p.inProgress, 1)
+	repositoriesQueued.Dec()
+	repositoriesInProgress.Inc()
+}
+
+// finished marks a repository being indexed as done.
+func (p *progress) finished(repositoryURL string, err error) {
+	atomic.AddInt64(&p.inProgress, -1)
+	repositoriesInProgress.Dec()
+	p.record.Indexed(repositoryURL, err)
+
+	if err != nil {
+		atomic.AddInt64(&p.failed, 1)
+		repositoriesIndexed.WithLabelValues("failure").Inc()
+	} else {
+		atomic.AddInt64(&p.succeeded, 1)
+		repositoriesIndexed.WithLabelValues("success").Inc()
+	}
+}
+
+// skip marks queued repositories as skipped, either because an earlier
+// attempt of the run indexed them or because the run ran out of time.
+func (p *progress) skip(count int) {
+	atomic.AddInt64(&p.queued, -int64(count))
+	atomic.AddInt64(&p.skipped, int64(count))
+	repositoriesQueued.Sub(float64(count))
+	repositoriesIndexed.WithLabelValues("skipped").Add(float64(count))
+	p.record.Skipped(count)
+}
+
+func (p *progress) log(ctx context.Context, message string) {
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"run":         p.run,
+		"total":       p.total,
+		"queued":      atomic.LoadInt64(&p.queued),
+		"in_progress": atomic.LoadInt64(&p.inProgress),
+		"succeeded":   atomic.LoadInt64(&p.succeeded),
+		"failed":      atomic.LoadInt64(&p.failed),
+		"skipped":     atomic.LoadInt64(&p.skipped),
+		"elapsed":     time.Since(p.start).Round(time.Second).String(),
+	}).Info(message)
+}
+
+// report logs the progress on every interval until the context is done.
+func (p *progress) report(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.log(ctx, "[main] run progress")
+		}
+	}
+}
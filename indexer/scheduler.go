@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/depscloud/depscloud/indexer/internal/config"
+	"github.com/depscloud/depscloud/indexer/internal/remotes"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/schedule"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	sourceLastRun = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "indexer_source_last_run_timestamp_seconds",
+		Help: "When the latest run of each source finished.",
+	}, []string{"source"})
+
+	sourceLastRunDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "indexer_source_last_run_duration_seconds",
+		Help: "How long the latest run of each source took.",
+	}, []string{"source"})
+
+	sourceLastRunRepositories = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "indexer_source_last_run_repositories",
+		Help: "The number of repositories found by the latest run of each source.",
+	}, []string{"source"})
+
+	sourceRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_source_runs_total",
+		Help: "The number of runs of each source, by result.",
+	}, []string{"source", "result"})
+)
+
+// scheduledSource is an account that runs on its own schedule.
+type scheduledSource struct {
+	name     string
+	remote   remotes.Remote
+	schedule schedule.Schedule
+	jitter   time.Duration
+}
+
+// scheduleSources prepares each account to run on its schedule, falling back
+// to the default schedule for accounts without one. Accounts without a name
+// are named after their position in the config.
+func scheduleSources(accounts []*config.Account, defaultSchedule string) ([]*scheduledSource, error) {
+	sources := make([]*scheduledSource, 0, len(accounts))
+
+	for i, account := range accounts {
+		source := &scheduledSource{name: account.GetName()}
+		if source.name == "" {
+			source.name = fmt.Sprintf("account-%d", i)
+		}
+
+		expression := account.GetSchedule()
+		if expression == "" {
+			expression = defaultSchedule
+		}
+
+		var err error
+		if source.schedule, err = schedule.Parse(expression); err != nil {
+			return nil, fmt.Errorf("invalid schedule for %s: %v", source.name, err)
+		}
+
+		if jitter := account.GetJitter(); jitter != "" {
+			if source.jitter, err = time.ParseDuration(jitter); err != nil {
+				return nil, fmt.Errorf("invalid jitter for %s: %v", source.name, err)
+			}
+		}
+
+		if source.remote, err = remotes.ParseAccount(account); err != nil {
+			return nil, fmt.Errorf("invalid account %s: %v", source.name, err)
+		}
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// repositoryOwners assigns each repository to the first source to discover
+// it, so a repository found by more than one source is only indexed by one of
+// them, under one url.
+type repositoryOwners struct {
+	mu     sync.Mutex
+	owners map[string]string
+}
+
+func newRepositoryOwners() *repositoryOwners {
+	return &repositoryOwners{owners: make(map[string]string)}
+}
+
+// claim returns the repositories owned by the source. Repositories the source
+// no longer discovers are released, so another source can pick them up.
+func (o *repositoryOwners) claim(ctx context.Context, source string, repositories []*remotes.Repository) []*remotes.Repository {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	discovered := make(map[string]bool, len(repositories))
+	claimed := make([]*remotes.Repository, 0, len(repositories))
+
+	for _, repository := range repositories {
+		key := remotes.CanonicalURL(repository.RepositoryURL)
+		if discovered[key] {
+			continue
+		}
+		discovered[key] = true
+
+		if owner, ok := o.owners[key]; ok && owner != source {
+			logging.FromContext(ctx).Infof("[scheduler] skipping repository %s for source %s, it's indexed by %s", repository.RepositoryURL, source, owner)
+			continue
+		}
+
+		o.owners[key] = source
+		claimed = append(claimed, repository)
+	}
+
+	for key, owner := range o.owners {
+		if owner == source && !discovered[key] {
+			delete(o.owners, key)
+		}
+	}
+
+	return claimed
+}
+
+// run indexes the source, recording how the run went.
+func (s *scheduledSource) run(ctx context.Context, owners *repositoryOwners, index func(string, []*remotes.Repository)) {
+	start := time.Now()
+	logging.FromContext(ctx).Infof("[scheduler] running source %s", s.name)
+
+	resp, err := s.remote.FetchRepositories(ctx, &remotes.FetchRepositoriesRequest{})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[scheduler] failed to fetch repositories for source %s: %v", s.name, err)
+		sourceRuns.WithLabelValues(s.name, "error").Inc()
+		return
+	}
+
+	index(s.name, owners.claim(ctx, s.name, resp.Repositories))
+
+	finish := time.Now()
+	sourceLastRun.WithLabelValues(s.name).Set(float64(finish.Unix()))
+	sourceLastRunDuration.WithLabelValues(s.name).Set(finish.Sub(start).Seconds())
+	sourceLastRunRepositories.WithLabelValues(s.name).Set(float64(len(resp.Repositories)))
+	sourceRuns.WithLabelValues(s.name, "success").Inc()
+
+	logging.FromContext(ctx).Infof("[scheduler] finished source %s in %s", s.name, finish.Sub(start))
+}
+
+// runScheduler runs each source on its schedule until the context is done. A
+// random delay up to the source's jitter is added to each run, so sources
+// sharing a schedule don't all start at once. Runs of a source never overlap;
+// a run that's still going when the next one is due delays it. Sources with
+// an interrupted run are run right away, so the run resumes without waiting
+// for the next one to be due.
+func runScheduler(ctx context.Context, sources []*scheduledSource, interrupted []string, index func(string, []*remotes.Repository)) error {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(sources))
+
+	owners := newRepositoryOwners()
+
+	resume := make(map[string]bool, len(interrupted))
+	for _, name := range interrupted {
+		resume[name] = true
+	}
+
+	for _, source := range sources {
+		go func(source *scheduledSource, resume bool) {
+			defer wg.Done()
+
+			if resume {
+				logging.FromContext(ctx).Infof("[scheduler] resuming interrupted run of source %s", source.name)
+				source.run(ctx, owners, index)
+			}
+
+			for {
+				next := source.schedule.Next(time.Now())
+				if next.IsZero() {
+					logging.FromContext(ctx).Errorf("[scheduler] source %s will never run again", source.name)
+					return
+				}
+
+				if source.jitter > 0 {
+					next = next.Add(time.Duration(rand.Int63n(int64(source.jitter))))
+				}
+
+				logging.FromContext(ctx).Infof("[scheduler] source %s next runs at %s", source.name, next.Format(time.RFC3339))
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Until(next)):
+					source.run(ctx, owners, index)
+				}
+			}
+		}(source, resume[source.name])
+	}
+
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,45 @@
+// Package asof reads the graph as it was at an earlier time.
+package asof
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/requestmeta"
+)
+
+// MetadataKey holds the RFC 3339 timestamp a read should be answered as of.
+const MetadataKey = "x-depscloud-as-of"
+
+// FromIncomingContext returns the time the client asked to read the graph as
+// of. The zero time is returned when the client wants the current graph.
+func FromIncomingContext(ctx context.Context) (time.Time, error) {
+	value := requestmeta.First(ctx, MetadataKey)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", MetadataKey)
+	}
+
+	return asOf, nil
+}
+
+// AppendToOutgoingContext attaches the time to requests made with the returned
+// context. The zero time leaves the context untouched.
+func AppendToOutgoingContext(ctx context.Context, asOf time.Time) context.Context {
+	if asOf.IsZero() {
+		return ctx
+	}
+
+	return requestmeta.Append(ctx, MetadataKey, asOf.Format(time.RFC3339Nano))
+}
+
+// ForwardContext copies the as of time of an incoming request onto the
+// outgoing context so it's passed along to the backend.
+func ForwardContext(ctx context.Context) context.Context {
+	return requestmeta.Forward(ctx, MetadataKey)
+}
@@ -0,0 +1,54 @@
+package asof_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/asof"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFromIncomingContext(t *testing.T) {
+	asOf, err := asof.FromIncomingContext(context.Background())
+	require.Nil(t, err)
+	require.True(t, asOf.IsZero())
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(asof.MetadataKey, "2020-04-01T12:00:00Z"))
+	asOf, err = asof.FromIncomingContext(ctx)
+	require.Nil(t, err)
+	require.Equal(t, time.Date(2020, time.April, 1, 12, 0, 0, 0, time.UTC), asOf)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(asof.MetadataKey, "last quarter"))
+	_, err = asof.FromIncomingContext(ctx)
+	require.NotNil(t, err)
+}
+
+func TestForwardContext(t *testing.T) {
+	ctx := asof.ForwardContext(context.Background())
+	_, ok := metadata.FromOutgoingContext(ctx)
+	require.False(t, ok)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		asof.MetadataKey, "2020-04-01T12:00:00Z",
+		"authorization", "secret",
+	))
+
+	md, ok := metadata.FromOutgoingContext(asof.ForwardContext(ctx))
+	require.True(t, ok)
+	require.Equal(t, metadata.Pairs(asof.MetadataKey, "2020-04-01T12:00:00Z"), md)
+}
+
+func TestAppendToOutgoingContext(t *testing.T) {
+	ctx := asof.AppendToOutgoingContext(context.Background(), time.Time{})
+	_, ok := metadata.FromOutgoingContext(ctx)
+	require.False(t, ok)
+
+	ctx = asof.AppendToOutgoingContext(context.Background(), time.Date(2020, time.April, 1, 12, 0, 0, 0, time.UTC))
+	md, ok := metadata.FromOutgoingContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, []string{"2020-04-01T12:00:00Z"}, md.Get(asof.MetadataKey))
+}
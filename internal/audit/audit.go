@@ -0,0 +1,454 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// RoutePrefix prefixes the HTTP routes used to write and query audit records.
+const RoutePrefix = "/v1alpha/audit/"
+
+// Sinks determine where audit records are written.
+const (
+	// SinkNone disables auditing.
+	SinkNone = "none"
+	// SinkFile appends records to a file as json lines.
+	SinkFile = "file"
+	// SinkHTTP posts batches of records to a url, such as the audit api of
+	// the tracker.
+	SinkHTTP = "http"
+	// SinkDatabase stores records in the database of the tracker. Only the
+	// tracker supports it.
+	SinkDatabase = "database"
+)
+
+// Record describes an action taken by a caller.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Service string    `json:"service"`
+	Tenant  string    `json:"tenant,omitempty"`
+	Subject string    `json:"subject,omitempty"`
+	// Action is the full grpc method, or the http method and path.
+	Action string `json:"action"`
+	// Resource identifies what was acted on, such as the url of a source or
+	// the parameters of an http request.
+	Resource string `json:"resource,omitempty"`
+	// Status is the http status of the outcome. grpc codes are mapped onto
+	// their http equivalent.
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RecordsRequest writes records using the audit api.
+type RecordsRequest struct {
+	Records []*Record `json:"records"`
+}
+
+// RecordsResponse contains the records matching a query, newest first.
+type RecordsResponse struct {
+	Records []*Record `json:"records"`
+}
+
+// Sink writes batches of records.
+type Sink interface {
+	Write(ctx context.Context, records []*Record) error
+}
+
+// FileSink appends records to a file as json lines.
+type FileSink struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (f *FileSink) Write(ctx context.Context, records []*Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+
+	return file.Close()
+}
+
+var _ Sink = &FileSink{}
+
+// HTTPSink posts batches of records to a url as a RecordsRequest. Records are
+// posted separately for each tenant, which is passed using the tenant header.
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (h *HTTPSink) Write(ctx context.Context, records []*Record) error {
+	order := make([]string, 0)
+	byTenant := make(map[string][]*Record)
+	for _, record := range records {
+		if _, ok := byTenant[record.Tenant]; !ok {
+			order = append(order, record.Tenant)
+		}
+		byTenant[record.Tenant] = append(byTenant[record.Tenant], record)
+	}
+
+	for _, tenant := range order {
+		if err := h.post(ctx, tenant, byTenant[tenant]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HTTPSink) post(ctx context.Context, tenant string, records []*Record) error {
+	body, err := json.Marshal(&RecordsRequest{Records: records})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if tenant != "" {
+		req.Header.Set(tenants.HeaderKey, tenant)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit sink responded with %s", resp.Status)
+	}
+	return nil
+}
+
+var _ Sink = &HTTPSink{}
+
+// Config controls where the audit records of a service are written.
+type Config struct {
+	Sink          string
+	Path          string
+	URL           string
+	BufferSize    int
+	FlushInterval time.Duration
+}
+
+// WithFlags returns the flags used to configure auditing.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "audit-sink",
+			Usage:       "where audit records are written; none, file, http, or database (tracker only)",
+			Value:       cfg.Sink,
+			Destination: &(cfg.Sink),
+			EnvVars:     []string{"AUDIT_SINK"},
+		},
+		&cli.StringFlag{
+			Name:        "audit-file",
+			Usage:       "path to the file audit records are appended to by the file sink",
+			Value:       cfg.Path,
+			Destination: &(cfg.Path),
+			EnvVars:     []string{"AUDIT_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "audit-url",
+			Usage:       "the url audit records are posted to by the http sink, such as http://tracker:8080/v1alpha/audit/records",
+			Value:       cfg.URL,
+			Destination: &(cfg.URL),
+			EnvVars:     []string{"AUDIT_URL"},
+		},
+		&cli.IntFlag{
+			Name:        "audit-buffer-size",
+			Usage:       "the number of audit records held before they're written, records are dropped when it's full",
+			Value:       cfg.BufferSize,
+			Destination: &(cfg.BufferSize),
+			EnvVars:     []string{"AUDIT_BUFFER_SIZE"},
+		},
+		&cli.DurationFlag{
+			Name:        "audit-flush-interval",
+			Usage:       "how often buffered audit records are written",
+			Value:       cfg.FlushInterval,
+			Destination: &(cfg.FlushInterval),
+			EnvVars:     []string{"AUDIT_FLUSH_INTERVAL"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// Enabled returns true when audit records are written.
+func (c *Config) Enabled() bool {
+	return c != nil && c.Sink != "" && c.Sink != SinkNone
+}
+
+// NewSink returns the file or http sink described by the config. Disabled
+// configs have no sink. The database sink is provided by the tracker.
+func (c *Config) NewSink(client *http.Client) (Sink, error) {
+	switch c.Sink {
+	case "", SinkNone:
+		return nil, nil
+	case SinkFile:
+		if c.Path == "" {
+			return nil, fmt.Errorf("the file sink requires a path")
+		}
+		return &FileSink{Path: c.Path}, nil
+	case SinkHTTP:
+		if c.URL == "" {
+			return nil, fmt.Errorf("the http sink requires a url")
+		}
+		return &HTTPSink{URL: c.URL, Client: client}, nil
+	case SinkDatabase:
+		return nil, fmt.Errorf("the database sink is only supported by the tracker")
+	}
+	return nil, fmt.Errorf("unsupported audit sink %s, specify one of none/file/http/database", c.Sink)
+}
+
+// Logger buffers the records of a service and writes them to a sink in
+// batches. A nil logger discards records.
+type Logger struct {
+	service       string
+	sink          Sink
+	records       chan *Record
+	flushInterval time.Duration
+}
+
+// NewLogger returns a logger writing the records of the service to the sink.
+// No logger is returned without a sink.
+func NewLogger(service string, sink Sink, cfg *Config) *Logger {
+	if sink == nil {
+		return nil
+	}
+
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+
+	flushInterval := cfg.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	return &Logger{
+		service:       service,
+		sink:          sink,
+		records:       make(chan *Record, bufferSize),
+		flushInterval: flushInterval,
+	}
+}
+
+// Log buffers the record. Records are dropped rather than holding up the
+// caller when the buffer is full.
+func (l *Logger) Log(record *Record) {
+	if l == nil {
+		return
+	}
+
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+	if record.Service == "" {
+		record.Service = l.service
+	}
+
+	select {
+	case l.records <- record:
+	default:
+		logrus.Errorf("[audit] buffer is full, dropped record of %s by %q", record.Action, record.Subject)
+	}
+}
+
+// Run writes buffered records until the context is done, flushing whatever
+// remains before returning.
+func (l *Logger) Run(ctx context.Context) {
+	if l == nil {
+		return
+	}
+
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Record, 0, cap(l.records))
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := l.sink.Write(ctx, batch); err != nil {
+			logrus.Errorf("[audit] failed to write %d records: %s", len(batch), err.Error())
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case record := <-l.records:
+					batch = append(batch, record)
+				default:
+					flush(context.Background())
+					return
+				}
+			}
+		case record := <-l.records:
+			batch = append(batch, record)
+			if len(batch) == cap(batch) {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// extractionMethods submit manifests for extraction. They don't write to the
+// graph, but are audited along with the methods that do.
+var extractionMethods = map[string]bool{
+	"/cloud.deps.api.v1alpha.extractor.DependencyExtractor/Extract": true,
+}
+
+// Audited returns true when calls to the grpc method are recorded. Methods
+// requiring the editor role or more are recorded, as are extractions.
+func Audited(method string) bool {
+	return extractionMethods[method] || rbac.RequiredForMethod(method).Includes(rbac.RoleEditor)
+}
+
+// AuditedRequest returns true when the http request is recorded. Requests
+// requiring the editor role or more are recorded, except for those made to
+// the audit api itself.
+func AuditedRequest(r *http.Request) bool {
+	return !strings.HasPrefix(r.URL.Path, RoutePrefix) && rbac.RequiredForRequest(r).Includes(rbac.RoleEditor)
+}
+
+// resourceOf identifies what a grpc request acts on.
+func resourceOf(req interface{}) string {
+	switch req := req.(type) {
+	case *tracker.SourceRequest:
+		return req.GetSource().GetUrl()
+	case interface{ GetUrl() string }:
+		return req.GetUrl()
+	case interface{ GetOrganization() string }:
+		return req.GetOrganization()
+	}
+	return ""
+}
+
+// UnaryServerInterceptor records the outcome of audited calls. It must run
+// after the tenant and subject of the call are resolved.
+func (l *Logger) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if Audited(info.FullMethod) {
+			record := &Record{
+				Tenant:   tenants.FromContext(ctx),
+				Subject:  rbac.FromContext(ctx),
+				Action:   info.FullMethod,
+				Resource: resourceOf(req),
+				Status:   runtime.HTTPStatusFromCode(status.Code(err)),
+			}
+			if err != nil {
+				record.Error = status.Convert(err).Message()
+			}
+			l.Log(record)
+		}
+		return resp, err
+	}
+}
+
+// ServerOptions returns the interceptors that record audited calls. No
+// options are returned when auditing is disabled.
+func (l *Logger) ServerOptions() []grpc.ServerOption {
+	if l == nil {
+		return nil
+	}
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(l.UnaryServerInterceptor())}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Middleware records the outcome of audited http requests. It must run after
+// the tenant and subject of the request are resolved.
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !AuditedRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		l.Log(&Record{
+			Tenant:   tenants.FromContext(r.Context()),
+			Subject:  rbac.FromContext(r.Context()),
+			Action:   strings.Join([]string{r.Method, r.URL.Path}, " "),
+			Resource: r.URL.RawQuery,
+			Status:   recorder.status,
+		})
+	})
+}
@@ -0,0 +1,197 @@
+package audit_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/audit"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	batches [][]*audit.Record
+}
+
+func (m *memorySink) Write(ctx context.Context, records []*audit.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.batches = append(m.batches, append([]*audit.Record{}, records...))
+	return nil
+}
+
+func (m *memorySink) records() []*audit.Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	records := make([]*audit.Record, 0)
+	for _, batch := range m.batches {
+		records = append(records, batch...)
+	}
+	return records
+}
+
+// run logs the records made by fn, returning them once they're flushed.
+func run(t *testing.T, fn func(logger *audit.Logger)) []*audit.Record {
+	sink := &memorySink{}
+	logger := audit.NewLogger("tracker", sink, &audit.Config{BufferSize: 10, FlushInterval: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		logger.Run(ctx)
+		close(done)
+	}()
+
+	fn(logger)
+
+	cancel()
+	<-done
+	return sink.records()
+}
+
+func TestInterceptor(t *testing.T) {
+	records := run(t, func(logger *audit.Logger) {
+		interceptor := logger.UnaryServerInterceptor()
+		ctx := rbac.NewContext(tenants.NewContext(context.Background(), "payments"), "indexer")
+
+		call := func(method string, req interface{}, err error) {
+			_, _ = interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, err
+			})
+		}
+
+		call("/cloud.deps.api.v1alpha.tracker.SourceService/Track",
+			&tracker.SourceRequest{Source: &schema.Source{Url: "https://github.com/depscloud/depscloud.git"}}, nil)
+		call("/cloud.deps.api.v1alpha.tracker.SourceService/List", &tracker.ListRequest{}, nil)
+		call("/cloud.deps.api.v1alpha.extractor.DependencyExtractor/Extract", &tracker.SourceRequest{},
+			status.Error(codes.InvalidArgument, "no manifests"))
+	})
+
+	require.Len(t, records, 2)
+
+	require.Equal(t, "tracker", records[0].Service)
+	require.Equal(t, "payments", records[0].Tenant)
+	require.Equal(t, "indexer", records[0].Subject)
+	require.Equal(t, "/cloud.deps.api.v1alpha.tracker.SourceService/Track", records[0].Action)
+	require.Equal(t, "https://github.com/depscloud/depscloud.git", records[0].Resource)
+	require.Equal(t, http.StatusOK, records[0].Status)
+	require.False(t, records[0].Time.IsZero())
+
+	require.Equal(t, "/cloud.deps.api.v1alpha.extractor.DependencyExtractor/Extract", records[1].Action)
+	require.Equal(t, http.StatusBadRequest, records[1].Status)
+	require.Equal(t, "no manifests", records[1].Error)
+}
+
+func TestMiddleware(t *testing.T) {
+	records := run(t, func(logger *audit.Logger) {
+		handler := logger.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v1alpha/graph/import" {
+				http.Error(w, "invalid", http.StatusBadRequest)
+			}
+		}))
+
+		serve := func(method, path string) {
+			r := httptest.NewRequest(method, path, nil)
+			r = r.WithContext(rbac.NewContext(r.Context(), "alice"))
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+		}
+
+		serve(http.MethodGet, "/v1alpha/labels/modules?language=go")
+		serve(http.MethodPut, "/v1alpha/labels/modules?language=go")
+		serve(http.MethodPost, "/v1alpha/queries/evaluate")
+		serve(http.MethodPost, "/v1alpha/graph/import")
+		serve(http.MethodPost, "/v1alpha/audit/records")
+	})
+
+	require.Len(t, records, 2)
+	require.Equal(t, "PUT /v1alpha/labels/modules", records[0].Action)
+	require.Equal(t, "language=go", records[0].Resource)
+	require.Equal(t, "alice", records[0].Subject)
+	require.Equal(t, http.StatusOK, records[0].Status)
+	require.Equal(t, "POST /v1alpha/graph/import", records[1].Action)
+	require.Equal(t, http.StatusBadRequest, records[1].Status)
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sink, err := (&audit.Config{Sink: audit.SinkFile, Path: filepath.Join(dir, "audit.log")}).NewSink(nil)
+	require.Nil(t, err)
+
+	require.Nil(t, sink.Write(context.Background(), []*audit.Record{{Action: "a"}, {Action: "b"}}))
+	require.Nil(t, sink.Write(context.Background(), []*audit.Record{{Action: "c"}}))
+
+	file, err := os.Open(filepath.Join(dir, "audit.log"))
+	require.Nil(t, err)
+	defer file.Close()
+
+	actions := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		record := &audit.Record{}
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), record))
+		actions = append(actions, record.Action)
+	}
+	require.Equal(t, []string{"a", "b", "c"}, actions)
+}
+
+func TestHTTPSink(t *testing.T) {
+	received := make(map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &audit.RecordsRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tenant := r.Header.Get(tenants.HeaderKey)
+		for _, record := range req.Records {
+			received[tenant] = append(received[tenant], record.Action)
+		}
+	}))
+	defer server.Close()
+
+	sink, err := (&audit.Config{Sink: audit.SinkHTTP, URL: server.URL}).NewSink(nil)
+	require.Nil(t, err)
+
+	// records are posted under their tenant
+	require.Nil(t, sink.Write(context.Background(), []*audit.Record{
+		{Action: "a"},
+		{Action: "b", Tenant: "payments"},
+		{Action: "c"},
+	}))
+	require.Equal(t, map[string][]string{"": {"a", "c"}, "payments": {"b"}}, received)
+
+	_, err = (&audit.Config{Sink: audit.SinkHTTP}).NewSink(nil)
+	require.NotNil(t, err)
+
+	_, err = (&audit.Config{Sink: audit.SinkDatabase}).NewSink(nil)
+	require.NotNil(t, err)
+
+	sink, err = (&audit.Config{Sink: audit.SinkNone}).NewSink(nil)
+	require.Nil(t, err)
+	require.Nil(t, sink)
+	require.Nil(t, audit.NewLogger("gateway", sink, &audit.Config{}))
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/urfave/cli/v2"
@@ -9,12 +10,24 @@ import (
 // https://github.com/grpc/grpc/blob/master/doc/service_config.md
 const DefaultServiceConfig = `{"loadBalancingPolicy":"round_robin","healthCheckConfig":{"serviceName":""}}`
 
+// ServiceConfig returns the default service config with connections health
+// checked using the status of the named service, rather than the overall
+// status of the backend.
+func ServiceConfig(serviceName string) string {
+	return fmt.Sprintf(`{"loadBalancingPolicy":"round_robin","healthCheckConfig":{"serviceName":%q}}`, serviceName)
+}
+
 const DefaultLoadBalancer = "round_robin"
 
+// CompressionGzip compresses the messages sent to the backend using gzip.
+// Servers reply using the same compression.
+const CompressionGzip = "gzip"
+
 type Config struct {
 	Address       string
 	ServiceConfig string
 	LoadBalancer  string
+	Compression   string
 	TLS           bool
 	TLSConfig     *TLSConfig
 }
@@ -38,6 +51,13 @@ func WithFlags(prefix string, cfg *Config) (*Config, []cli.Flag) {
 			Destination: &(cfg.ServiceConfig),
 			EnvVars:     []string{upper + "_SERVICE_CONFIG"},
 		},
+		&cli.StringFlag{
+			Name:        lower + "-compression",
+			Usage:       "compression used for calls to the " + lower + "; gzip, or empty to disable",
+			Value:       cfg.Compression,
+			Destination: &(cfg.Compression),
+			EnvVars:     []string{upper + "_COMPRESSION"},
+		},
 		&cli.BoolFlag{
 			Name:        lower + "-tls",
 			Usage:       "enable TLS for the " + lower,
@@ -1,22 +1,27 @@
 package client
 
 import (
-	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
-	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"fmt"
+
+	"github.com/depscloud/depscloud/internal/middleware"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
 )
 
-func Connect(cfg *Config) (*grpc.ClientConn, error) {
-	options := []grpc.DialOption{
-		grpc.WithDefaultServiceConfig(cfg.ServiceConfig),
-		grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(
-			grpc_prometheus.StreamClientInterceptor,
-		)),
-		grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(
-			grpc_prometheus.UnaryClientInterceptor,
-		)),
+// Connect dials the configured address using the shared client interceptors.
+// Additional options, such as per call credentials, are applied after the
+// defaults.
+func Connect(cfg *Config, extra ...grpc.DialOption) (*grpc.ClientConn, error) {
+	options := middleware.DialOptions(grpc.WithDefaultServiceConfig(cfg.ServiceConfig))
+
+	switch cfg.Compression {
+	case "", "none":
+	case CompressionGzip:
+		options = append(options, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	default:
+		return nil, fmt.Errorf("unsupported compression %s, specify gzip or leave it empty", cfg.Compression)
 	}
 
 	if cfg.TLS || cfg.TLSConfig.CertPath != "" {
@@ -31,5 +36,7 @@ func Connect(cfg *Config) (*grpc.ClientConn, error) {
 		options = append(options, grpc.WithInsecure())
 	}
 
+	options = append(options, extra...)
+
 	return grpc.Dial(cfg.Address, options...)
 }
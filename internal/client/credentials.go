@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// TokenFileCredentials passes the bearer token in the file on every call. The
+// file is read for each call so rotated tokens, such as those mounted from a
+// secret, are used without a restart.
+func TokenFileCredentials(path string) credentials.PerRPCCredentials {
+	return tokenFileCredentials(path)
+}
+
+type tokenFileCredentials string
+
+func (t tokenFileCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	// calls made on behalf of another caller keep their token
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		return nil, nil
+	}
+
+	token, err := ioutil.ReadFile(string(t))
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"authorization": "Bearer " + strings.TrimSpace(string(token))}, nil
+}
+
+func (t tokenFileCredentials) RequireTransportSecurity() bool {
+	// like the tenant and subject metadata, tokens may be sent to services
+	// within the cluster without tls
+	return false
+}
@@ -0,0 +1,352 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/depscloud/depscloud/internal/mux"
+	"github.com/depscloud/depscloud/internal/secrets"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/urfave/cli/v2"
+)
+
+// The flags added to every binary. The indexer already uses --config for the
+// sources it crawls, so the file holding the options is named separately.
+const (
+	FileFlag        = "config-file"
+	PrintConfigFlag = "print-config"
+)
+
+const redacted = "<redacted>"
+
+// secretsMetadata holds the references of the options resolved from a secret
+// manager in the metadata of the app, so the reference is printed rather than
+// the secret.
+const secretsMetadata = "config.secrets"
+
+// sensitive names the options whose values are never printed.
+var sensitive = []string{"secret", "token", "password", "header"}
+
+// Apply adds the config file and print config flags to the app. Options are
+// resolved in order of precedence from flags, environment variables, the
+// config file, and finally the defaults. Options referring to a secret in
+// Vault or a cloud secret manager are then replaced by the secret. When
+// --print-config is set, the resolved options are printed instead of running
+// the app.
+func Apply(app *cli.App) {
+	app.Flags = append(app.Flags,
+		&cli.StringFlag{
+			Name:    FileFlag,
+			Usage:   "path to a yaml or json file of options, keyed by flag name",
+			EnvVars: []string{"CONFIG_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:  PrintConfigFlag,
+			Usage: "print the resolved options and exit",
+		},
+	)
+
+	before := app.Before
+	app.Before = func(c *cli.Context) error {
+		if err := Load(c, c.String(FileFlag)); err != nil {
+			return err
+		}
+		if err := ResolveSecrets(c); err != nil {
+			return err
+		}
+		if before != nil {
+			return before(c)
+		}
+		return nil
+	}
+
+	action := app.Action
+	app.Action = func(c *cli.Context) error {
+		if c.Bool(PrintConfigFlag) {
+			return Print(c, c.App.Writer)
+		}
+		if action != nil {
+			return action(c)
+		}
+		return nil
+	}
+}
+
+// read parses a yaml or json file of options.
+func read(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	options := make(map[string]interface{})
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return options, nil
+}
+
+// format converts a value read from the file to the form used on the command
+// line.
+func format(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// lookup finds the flag of the app with the name, including its aliases.
+func lookup(c *cli.Context, name string) cli.Flag {
+	for _, flag := range c.App.Flags {
+		for _, n := range flag.Names() {
+			if n == name {
+				return flag
+			}
+		}
+	}
+	return nil
+}
+
+// Load applies the options in the file to the flags that weren't set using
+// the command line or environment. Options that don't match a flag, or can't
+// be parsed by it, are rejected so mistakes aren't silently ignored.
+func Load(c *cli.Context, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	options, err := read(path)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range options {
+		flag := lookup(c, name)
+		if flag == nil || name == FileFlag || name == PrintConfigFlag {
+			return fmt.Errorf("unknown option %s in %s", name, path)
+		}
+
+		primary := flag.Names()[0]
+		if c.IsSet(primary) {
+			continue
+		}
+
+		values := []interface{}{value}
+		if list, ok := value.([]interface{}); ok {
+			values = list
+		}
+
+		for _, v := range values {
+			if err := c.Set(primary, format(v)); err != nil {
+				return fmt.Errorf("invalid value for %s in %s: %v", name, path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResolveSecrets replaces the value of string options that refer to a secret
+// with the secret. See secrets.Reference for the form references take.
+func ResolveSecrets(c *cli.Context) error {
+	references := make(map[string]string)
+
+	for _, flag := range c.App.Flags {
+		if _, ok := flag.(*cli.StringFlag); !ok {
+			continue
+		}
+
+		name := flag.Names()[0]
+		value := c.String(name)
+		if !secrets.IsReference(value) {
+			continue
+		}
+
+		secret, err := secrets.Resolve(c.Context, value)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %v", name, err)
+		}
+
+		if err := c.Set(name, secret); err != nil {
+			return err
+		}
+		references[name] = value
+	}
+
+	if c.App.Metadata == nil {
+		c.App.Metadata = make(map[string]interface{})
+	}
+	c.App.Metadata[secretsMetadata] = references
+	return nil
+}
+
+// isSensitive returns true when the value of the option shouldn't be printed.
+func isSensitive(name string) bool {
+	for _, word := range sensitive {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// printable returns the value of the flag as it would be read from a config
+// file. Credentials in addresses are redacted.
+func printable(c *cli.Context, flag cli.Flag) interface{} {
+	name := flag.Names()[0]
+
+	switch flag.(type) {
+	case *cli.BoolFlag:
+		return c.Bool(name)
+	case *cli.IntFlag:
+		return c.Int(name)
+	case *cli.Int64Flag:
+		return c.Int64(name)
+	case *cli.UintFlag:
+		return c.Uint(name)
+	case *cli.Float64Flag:
+		return c.Float64(name)
+	case *cli.DurationFlag:
+		return c.Duration(name).String()
+	case *cli.StringSliceFlag:
+		return c.StringSlice(name)
+	case *cli.IntSliceFlag:
+		return c.IntSlice(name)
+	}
+
+	value := c.String(name)
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+	}
+	return value
+}
+
+// Print writes the resolved options as yaml, which can be used as a config
+// file. The values of secrets and tokens are redacted, and options resolved
+// from a secret manager are printed as their reference.
+func Print(c *cli.Context, w io.Writer) error {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	references, _ := c.App.Metadata[secretsMetadata].(map[string]string)
+
+	options := make(map[string]interface{})
+	for _, flag := range c.App.Flags {
+		name := flag.Names()[0]
+		switch name {
+		case FileFlag, PrintConfigFlag, "help", "version":
+			continue
+		}
+
+		value := printable(c, flag)
+		if reference, ok := references[name]; ok {
+			value = reference
+		} else if isSensitive(name) && c.IsSet(name) {
+			value = redacted
+		}
+		options[name] = value
+	}
+
+	data, err := yaml.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// Server holds the options shared by the services that serve http and grpc.
+type Server struct {
+	HTTPPort int
+	GRPCPort int
+	// PortAlias names the port set using the legacy --port flag, either http
+	// or grpc. Services have historically differed on which one it sets.
+	PortAlias string
+	TLS       *mux.TLSConfig
+}
+
+// The ports the legacy --port flag may alias.
+const (
+	PortHTTP = "http"
+	PortGRPC = "grpc"
+)
+
+// WithServerFlags returns the flags used to configure the ports and TLS
+// certificates a service serves with.
+func WithServerFlags(cfg *Server) (*Server, []cli.Flag) {
+	if cfg.TLS == nil {
+		cfg.TLS = &mux.TLSConfig{}
+	}
+
+	httpPort := &cli.IntFlag{
+		Name:        "http-port",
+		Usage:       "the port to run http on",
+		Value:       cfg.HTTPPort,
+		Destination: &(cfg.HTTPPort),
+		EnvVars:     []string{"HTTP_PORT"},
+	}
+
+	grpcPort := &cli.IntFlag{
+		Name:        "grpc-port",
+		Usage:       "the port to run grpc on",
+		Value:       cfg.GRPCPort,
+		Destination: &(cfg.GRPCPort),
+		EnvVars:     []string{"GRPC_PORT"},
+	}
+
+	switch cfg.PortAlias {
+	case PortHTTP:
+		httpPort.Aliases = []string{"port"}
+	case PortGRPC:
+		grpcPort.Aliases = []string{"port"}
+	}
+
+	flags := []cli.Flag{
+		httpPort,
+		grpcPort,
+		&cli.StringFlag{
+			Name:        "tls-key",
+			Usage:       "path to the file containing the TLS private key",
+			Value:       cfg.TLS.KeyPath,
+			Destination: &(cfg.TLS.KeyPath),
+			EnvVars:     []string{"TLS_KEY_PATH"},
+		},
+		&cli.StringFlag{
+			Name:        "tls-cert",
+			Usage:       "path to the file containing the TLS certificate",
+			Value:       cfg.TLS.CertPath,
+			Destination: &(cfg.TLS.CertPath),
+			EnvVars:     []string{"TLS_CERT_PATH"},
+		},
+		&cli.StringFlag{
+			Name:        "tls-ca",
+			Usage:       "path to the file containing the TLS certificate authority",
+			Value:       cfg.TLS.CAPath,
+			Destination: &(cfg.TLS.CAPath),
+			EnvVars:     []string{"TLS_CA_PATH"},
+		},
+	}
+
+	return cfg, flags
+}
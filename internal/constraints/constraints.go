@@ -0,0 +1,656 @@
+package constraints
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operator compares a version against the version of a Comparator.
+type Operator = string
+
+const (
+	Equal              Operator = "="
+	NotEqual           Operator = "!="
+	GreaterThan        Operator = ">"
+	GreaterThanOrEqual Operator = ">="
+	LessThan           Operator = "<"
+	LessThanOrEqual    Operator = "<="
+)
+
+// Comparator is a single bound on a version (such as >=1.2.0).
+type Comparator struct {
+	Operator Operator
+	Version  string
+}
+
+func (c Comparator) String() string {
+	return c.Operator + c.Version
+}
+
+// Range is satisfied by versions that satisfy all of its comparators.
+type Range []Comparator
+
+func (r Range) String() string {
+	parts := make([]string, 0, len(r))
+	for _, comparator := range r {
+		parts = append(parts, comparator.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// Constraint is the canonical form of a version constraint. It's satisfied by
+// versions that satisfy any of its ranges. A constraint without any ranges
+// allows every version.
+type Constraint []Range
+
+// Any is the constraint satisfied by every version.
+var Any = Constraint{}
+
+// String renders the constraint using the canonical syntax. Comparators within
+// a range are separated by a space and ranges are separated by " || ", as in
+// ">=1.2.0 <2.0.0 || >=3.0.0".
+func (c Constraint) String() string {
+	if len(c) == 0 {
+		return "*"
+	}
+
+	parts := make([]string, 0, len(c))
+	for _, r := range c {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, " || ")
+}
+
+type bareSemantics int
+
+const (
+	bareExact bareSemantics = iota
+	bareCaret
+	bareMinimum
+)
+
+// dialect captures how an ecosystem interprets the parts of a constraint that
+// are ambiguous between ecosystems.
+type dialect struct {
+	// bare describes how a version without an operator is interpreted.
+	bare bareSemantics
+	// partialRanges treats versions missing components (1.2) as x-ranges.
+	partialRanges bool
+	// pessimisticTilde treats ~ as allowing the last component to increase,
+	// rather than only the patch.
+	pessimisticTilde bool
+}
+
+var dialects = map[string]dialect{
+	"bower":    {bare: bareExact, partialRanges: true},
+	"cargo":    {bare: bareCaret},
+	"composer": {bare: bareExact, pessimisticTilde: true},
+	"gopkg":    {bare: bareCaret},
+	"npm":      {bare: bareExact, partialRanges: true},
+	"pnpm":     {bare: bareExact, partialRanges: true},
+	"vgo":      {bare: bareMinimum},
+	"yarn":     {bare: bareExact, partialRanges: true},
+}
+
+// keywords that select the newest available version.
+var anyVersion = map[string]bool{
+	"":                   true,
+	"*":                  true,
+	"x":                  true,
+	"latest":             true,
+	"release":            true,
+	"latest.release":     true,
+	"latest.integration": true,
+	"+":                  true,
+}
+
+// operators are ordered so that longer operators are matched first.
+var operators = []string{"===", "==", "!=", ">=", "<=", "~=", "~>", ">", "<", "=", "^", "~"}
+
+var hyphenRange = regexp.MustCompile(`^(\S+)\s+-\s+(\S+)$`)
+
+// Normalize converts an ecosystem specific version constraint into its
+// canonical form. Constraints that can't be interpreted (such as git
+// references) produce an empty string.
+func Normalize(system, constraint string) string {
+	c, err := Parse(system, constraint)
+	if err != nil {
+		return ""
+	}
+	return c.String()
+}
+
+// Parse interprets the version constraint using the semantics of the provided
+// system. Caret and tilde ranges (npm, cargo, composer), x-ranges and hyphen
+// ranges, maven and ivy intervals, gradle dynamic versions, and pep 440
+// operators are supported.
+func Parse(system, constraint string) (Constraint, error) {
+	d := dialects[strings.ToLower(system)]
+	constraint = strings.TrimSpace(constraint)
+
+	if anyVersion[strings.ToLower(constraint)] {
+		return Any, nil
+	}
+
+	if strings.ContainsAny(constraint[:1], "[(]") {
+		return parseIntervals(constraint)
+	}
+
+	result := make(Constraint, 0)
+	for _, alternative := range splitAlternatives(constraint) {
+		r, err := parseRange(d, alternative)
+		if err != nil {
+			return nil, err
+		}
+
+		// any alternative allowing every version allows every version
+		if len(r) == 0 {
+			return Any, nil
+		}
+		result = append(result, r)
+	}
+
+	return result, nil
+}
+
+func splitAlternatives(constraint string) []string {
+	alternatives := strings.Split(constraint, "||")
+	if len(alternatives) == 1 && strings.Contains(constraint, "|") {
+		// composer accepts a single pipe as well
+		alternatives = strings.Split(constraint, "|")
+	}
+
+	for i := range alternatives {
+		alternatives[i] = strings.TrimSpace(alternatives[i])
+	}
+	return alternatives
+}
+
+func parseRange(d dialect, constraint string) (Range, error) {
+	if anyVersion[strings.ToLower(constraint)] {
+		return Range{}, nil
+	}
+
+	if match := hyphenRange.FindStringSubmatch(constraint); match != nil {
+		lower, err := parseVersion(match[1])
+		if err != nil {
+			return nil, err
+		}
+		upper, err := parseVersion(match[2])
+		if err != nil {
+			return nil, err
+		}
+
+		return Range{
+			{GreaterThanOrEqual, lower.floor()},
+			{LessThanOrEqual, upper.floor()},
+		}, nil
+	}
+
+	tokens := strings.FieldsFunc(constraint, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	result := make(Range, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		// allow whitespace between the operator and the version (>= 1.0)
+		if isOperator(token) && i+1 < len(tokens) {
+			i++
+			token += tokens[i]
+		}
+
+		comparators, err := parseComparator(d, token)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, comparators...)
+	}
+
+	return result, nil
+}
+
+func isOperator(token string) bool {
+	for _, operator := range operators {
+		if token == operator {
+			return true
+		}
+	}
+	return false
+}
+
+func parseComparator(d dialect, token string) (Range, error) {
+	operator := ""
+	for _, op := range operators {
+		if strings.HasPrefix(token, op) {
+			operator = op
+			break
+		}
+	}
+
+	v, err := parseVersion(strings.TrimSpace(token[len(operator):]))
+	if err != nil {
+		return nil, err
+	}
+
+	switch operator {
+	case "^":
+		return v.caret(), nil
+	case "~":
+		if d.pessimisticTilde {
+			return v.pessimistic(), nil
+		}
+		return v.tilde(), nil
+	case "~>", "~=":
+		return v.pessimistic(), nil
+	case "=", "==", "===":
+		if v.wildcard {
+			return v.xrange(), nil
+		}
+		return Range{{Equal, v.raw}}, nil
+	case "!=":
+		return Range{{NotEqual, v.raw}}, nil
+	case ">", ">=", "<", "<=":
+		return Range{{operator, v.floor()}}, nil
+	}
+
+	if v.wildcard || (d.partialRanges && v.partial) {
+		return v.xrange(), nil
+	}
+
+	switch d.bare {
+	case bareCaret:
+		return v.caret(), nil
+	case bareMinimum:
+		return Range{{GreaterThanOrEqual, v.raw}}, nil
+	}
+	return Range{{Equal, v.raw}}, nil
+}
+
+// parseIntervals reads maven and ivy version ranges such as [1.0,2.0) or
+// (,1.0],[1.2,). Ivy also allows ]1.0,2.0[ for exclusive bounds.
+func parseIntervals(constraint string) (Constraint, error) {
+	result := make(Constraint, 0)
+	remaining := constraint
+
+	for len(remaining) > 0 {
+		remaining = strings.TrimLeft(remaining, ", ")
+		if len(remaining) == 0 {
+			break
+		}
+
+		open := remaining[0]
+		end := strings.IndexAny(remaining[1:], "[]()")
+		if (open != '[' && open != '(' && open != ']') || end < 0 {
+			return nil, fmt.Errorf("malformed interval: %s", constraint)
+		}
+		end++
+
+		closing := remaining[end]
+		body := remaining[1:end]
+		remaining = remaining[end+1:]
+
+		bounds := strings.SplitN(body, ",", 2)
+		lower := strings.TrimSpace(bounds[0])
+
+		if len(bounds) == 1 {
+			if open != '[' || closing != ']' || lower == "" {
+				return nil, fmt.Errorf("malformed interval: %s", constraint)
+			}
+			result = append(result, Range{{Equal, lower}})
+			continue
+		}
+
+		upper := strings.TrimSpace(bounds[1])
+
+		r := make(Range, 0, 2)
+		if lower != "" {
+			op := GreaterThanOrEqual
+			if open != '[' {
+				op = GreaterThan
+			}
+			r = append(r, Comparator{op, lower})
+		}
+		if upper != "" {
+			op := LessThanOrEqual
+			if closing != ']' {
+				op = LessThan
+			}
+			r = append(r, Comparator{op, upper})
+		}
+
+		if len(r) == 0 {
+			return Any, nil
+		}
+		result = append(result, r)
+	}
+
+	return result, nil
+}
+
+type version struct {
+	raw string
+	// components holds the numeric components preceding any wildcard
+	components []int
+	// length is the number of components written, including wildcards
+	length   int
+	wildcard bool
+	partial  bool
+}
+
+func isWildcard(component string) bool {
+	return component == "x" || component == "X" || component == "*" || component == "+"
+}
+
+func parseVersion(raw string) (*version, error) {
+	// go modules and git tags are commonly prefixed with a v
+	if len(raw) > 1 && (raw[0] == 'v' || raw[0] == 'V') && raw[1] >= '0' && raw[1] <= '9' {
+		raw = raw[1:]
+	}
+
+	if raw == "" || raw[0] < '0' || raw[0] > '9' {
+		return nil, fmt.Errorf("not a version: %q", raw)
+	}
+
+	v := &version{raw: raw}
+
+	// gradle and ivy dynamic versions (1.+ and 1.0+)
+	core := raw
+	if strings.HasSuffix(core, "+") && !strings.HasSuffix(core, ".+") {
+		core = core[:len(core)-1] + ".+"
+	}
+
+	// everything after a pre-release or build separator is ignored
+	if idx := strings.IndexAny(core, "-+"); idx > -1 && !strings.HasSuffix(core, ".+") {
+		core = core[:idx]
+	}
+
+	components := strings.Split(core, ".")
+	v.length = len(components)
+
+	for _, component := range components {
+		if isWildcard(component) {
+			v.wildcard = true
+			break
+		}
+
+		digits := component
+		for i, r := range component {
+			if r < '0' || r > '9' {
+				digits = component[:i]
+				break
+			}
+		}
+
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			return nil, fmt.Errorf("not a version: %q", raw)
+		}
+		v.components = append(v.components, n)
+	}
+
+	v.partial = v.length < 3
+
+	return v, nil
+}
+
+func (v *version) format(components []int, length int) string {
+	parts := make([]string, 0, length)
+	for i := 0; i < length; i++ {
+		n := 0
+		if i < len(components) {
+			n = components[i]
+		}
+		parts = append(parts, strconv.Itoa(n))
+	}
+	return strings.Join(parts, ".")
+}
+
+// floor returns the lowest version matching the written version.
+func (v *version) floor() string {
+	if v.wildcard {
+		return v.format(v.components, v.length)
+	}
+	return v.raw
+}
+
+// bump returns the version following the component at idx.
+func (v *version) bump(idx int) string {
+	components := make([]int, idx+1)
+	copy(components, v.components)
+	components[idx]++
+
+	length := v.length
+	if length < idx+1 {
+		length = idx + 1
+	}
+	return v.format(components, length)
+}
+
+func (v *version) between(upper int) Range {
+	return Range{
+		{GreaterThanOrEqual, v.floor()},
+		{LessThan, v.bump(upper)},
+	}
+}
+
+// caret allows changes that do not modify the left most non-zero component.
+func (v *version) caret() Range {
+	if len(v.components) == 0 {
+		return Range{}
+	}
+
+	idx := len(v.components) - 1
+	for i, n := range v.components {
+		if n != 0 {
+			idx = i
+			break
+		}
+	}
+	return v.between(idx)
+}
+
+// tilde allows patch level changes when a minor version is provided and minor
+// level changes when it is not.
+func (v *version) tilde() Range {
+	if len(v.components) == 0 {
+		return Range{}
+	} else if len(v.components) == 1 {
+		return v.between(0)
+	}
+	return v.between(1)
+}
+
+// pessimistic allows the last written component to increase (~> and ~=).
+func (v *version) pessimistic() Range {
+	if len(v.components) <= 1 {
+		return v.caret()
+	}
+	return v.between(len(v.components) - 2)
+}
+
+// xrange allows any version matching the written components.
+func (v *version) xrange() Range {
+	if len(v.components) == 0 {
+		return Range{}
+	}
+	return v.between(len(v.components) - 1)
+}
+
+// Satisfies reports whether the version satisfies any of the ranges of the
+// constraint.
+func (c Constraint) Satisfies(version string) bool {
+	if len(c) == 0 {
+		return true
+	}
+
+	for _, r := range c {
+		if r.Satisfies(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// Satisfies reports whether the version satisfies all of the comparators of
+// the range. As with npm, a pre-release only satisfies a range when one of its
+// comparators names a pre-release of the same release, so ^1.0.0 doesn't
+// allow 2.0.0-rc.1.
+func (r Range) Satisfies(version string) bool {
+	v, err := parseVersion(version)
+	prerelease := err == nil && v.prerelease() != ""
+
+	for _, comparator := range r {
+		if !comparator.Satisfies(version) {
+			return false
+		}
+
+		if prerelease {
+			bound, err := parseVersion(comparator.Version)
+			if err == nil && bound.prerelease() != "" && Compare(bound.release(), v.release()) == 0 {
+				prerelease = false
+			}
+		}
+	}
+	return !prerelease
+}
+
+// Satisfies reports whether the version is within the bound of the
+// comparator.
+func (c Comparator) Satisfies(version string) bool {
+	cmp := Compare(version, c.Version)
+
+	switch c.Operator {
+	case Equal:
+		return cmp == 0
+	case NotEqual:
+		return cmp != 0
+	case GreaterThan:
+		return cmp > 0
+	case GreaterThanOrEqual:
+		return cmp >= 0
+	case LessThan:
+		return cmp < 0
+	case LessThanOrEqual:
+		return cmp <= 0
+	}
+	return false
+}
+
+// IsVersion reports whether the string can be read as a version (such as
+// 1.2.3 or v1.2.3-rc.1).
+func IsVersion(raw string) bool {
+	v, err := parseVersion(raw)
+	return err == nil && !v.wildcard
+}
+
+// Floor returns the lowest version the constraint allows, or an empty string
+// when it has no lower bound.
+func (c Constraint) Floor() string {
+	floor := ""
+	for _, r := range c {
+		lower := ""
+		for _, comparator := range r {
+			if comparator.Operator == GreaterThanOrEqual || comparator.Operator == Equal {
+				lower = comparator.Version
+			}
+		}
+
+		if lower == "" {
+			return ""
+		} else if floor == "" || Compare(lower, floor) < 0 {
+			floor = lower
+		}
+	}
+	return floor
+}
+
+// Behind reports how far the version trails latest, returning the most
+// significant component that's lower (major, minor, or patch) along with the
+// difference between them. Versions that aren't behind return an empty string.
+func Behind(version, latest string) (string, int) {
+	v, err := parseVersion(version)
+	if err != nil || v.wildcard || Compare(version, latest) >= 0 {
+		return "", 0
+	}
+
+	l, err := parseVersion(latest)
+	if err != nil {
+		return "", 0
+	}
+
+	names := []string{"major", "minor", "patch"}
+	for i, name := range names {
+		if x, y := v.component(i), l.component(i); x < y {
+			return name, y - x
+		}
+	}
+
+	// the remaining components or pre-release differ
+	return names[len(names)-1], 0
+}
+
+// Compare orders two versions by their numeric components, returning -1, 0,
+// or +1. Missing components count as zero and a pre-release orders before the
+// release it precedes. Strings that aren't versions order before versions.
+func Compare(a, b string) int {
+	va, errA := parseVersion(a)
+	vb, errB := parseVersion(b)
+
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+
+	for i := 0; i < len(va.components) || i < len(vb.components); i++ {
+		x, y := va.component(i), vb.component(i)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+
+	pa, pb := va.prerelease(), vb.prerelease()
+	switch {
+	case pa == pb:
+		return 0
+	case pa == "":
+		return 1
+	case pb == "":
+		return -1
+	}
+	return strings.Compare(pa, pb)
+}
+
+// release returns the version without its pre-release and build metadata.
+func (v *version) release() string {
+	return v.format(v.components, len(v.components))
+}
+
+func (v *version) component(idx int) int {
+	if idx < len(v.components) {
+		return v.components[idx]
+	}
+	return 0
+}
+
+// prerelease returns the pre-release identifiers of the version, ignoring any
+// build metadata.
+func (v *version) prerelease() string {
+	raw := v.raw
+	if idx := strings.Index(raw, "+"); idx > -1 {
+		raw = raw[:idx]
+	}
+	if idx := strings.Index(raw, "-"); idx > -1 {
+		return raw[idx+1:]
+	}
+	return ""
+}
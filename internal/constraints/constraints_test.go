@@ -0,0 +1,159 @@
+package constraints_test
+
+import (
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/constraints"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		system     string
+		constraint string
+		expected   string
+	}{
+		// npm
+		{"npm", "", "*"},
+		{"npm", "latest", "*"},
+		{"npm", "^1.2.3", ">=1.2.3 <2.0.0"},
+		{"npm", "^0.2.3", ">=0.2.3 <0.3.0"},
+		{"npm", "^0.0.3", ">=0.0.3 <0.0.4"},
+		{"npm", "~1.2.3", ">=1.2.3 <1.3.0"},
+		{"npm", "~1", ">=1 <2"},
+		{"npm", "1.2.x", ">=1.2.0 <1.3.0"},
+		{"npm", "1.2", ">=1.2 <1.3"},
+		{"npm", "1.2.3", "=1.2.3"},
+		{"npm", ">= 1.2.0 < 2", ">=1.2.0 <2"},
+		{"npm", "1.2.3 - 2.3.4", ">=1.2.3 <=2.3.4"},
+		{"npm", "^1.0.0 || ^2.0.0", ">=1.0.0 <2.0.0 || >=2.0.0 <3.0.0"},
+		{"npm", "1.x || *", "*"},
+		{"npm", "github:depscloud/api", ""},
+
+		// cargo and composer
+		{"cargo", "1.2.3", ">=1.2.3 <2.0.0"},
+		{"cargo", ">=1.2, <1.5", ">=1.2 <1.5"},
+		{"composer", "~1.2", ">=1.2 <2.0"},
+		{"composer", "^7.1|^8.0", ">=7.1 <8.0 || >=8.0 <9.0"},
+
+		// maven, gradle, and ivy
+		{"maven", "1.0", "=1.0"},
+		{"maven", "[1.0,2.0)", ">=1.0 <2.0"},
+		{"maven", "[1.5]", "=1.5"},
+		{"maven", "(,1.0],[1.2,)", "<=1.0 || >=1.2"},
+		{"maven", "${project.version}", ""},
+		{"gradle", "1.+", ">=1.0 <2.0"},
+		{"ivy", "]1.0,2.0[", ">1.0 <2.0"},
+		{"ivy", "1.0+", ">=1.0.0 <1.1.0"},
+		{"ivy", "latest.integration", "*"},
+
+		// go
+		{"vgo", "v1.2.3", ">=1.2.3"},
+		{"vgo", "v0.0.0-20200101000000-abcdef012345", ">=0.0.0-20200101000000-abcdef012345"},
+
+		// pep 440
+		{"pip", "~=1.4.2", ">=1.4.2 <1.5.0"},
+		{"pip", "~=2.2", ">=2.2 <3.0"},
+		{"pip", "==1.2.*", ">=1.2.0 <1.3.0"},
+		{"pip", ">=1.0,!=1.3.4", ">=1.0 !=1.3.4"},
+	}
+
+	for _, test := range tests {
+		actual := constraints.Normalize(test.system, test.constraint)
+		require.Equal(t, test.expected, actual, "%s %q", test.system, test.constraint)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.10.0", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+		{"1.0.0+build.1", "1.0.0", 0},
+		{"main", "0.0.1", -1},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, constraints.Compare(test.a, test.b), "%s %s", test.a, test.b)
+	}
+}
+
+func TestBehind(t *testing.T) {
+	tests := []struct {
+		version, latest string
+		expected        string
+		distance        int
+	}{
+		{"1.2.3", "1.2.3", "", 0},
+		{"1.2.3", "1.2.5", "patch", 2},
+		{"v1.2.3", "v1.4.0", "minor", 2},
+		{"1.9.0", "3.0.0", "major", 2},
+		{"1.0.0-rc.1", "1.0.0", "patch", 0},
+		{"2.0.0", "1.0.0", "", 0},
+		{"main", "1.0.0", "", 0},
+	}
+
+	for _, test := range tests {
+		behind, distance := constraints.Behind(test.version, test.latest)
+		require.Equal(t, test.expected, behind, "%s %s", test.version, test.latest)
+		require.Equal(t, test.distance, distance, "%s %s", test.version, test.latest)
+	}
+}
+
+func TestFloor(t *testing.T) {
+	tests := []struct {
+		system     string
+		constraint string
+		expected   string
+	}{
+		{"npm", "^1.2.3", "1.2.3"},
+		{"npm", "1.2.3", "1.2.3"},
+		{"npm", "<2.0.0", ""},
+		{"npm", "^2.0.0 || ^1.4.0", "1.4.0"},
+		{"npm", "*", ""},
+	}
+
+	for _, test := range tests {
+		c, err := constraints.Parse(test.system, test.constraint)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, c.Floor(), test.constraint)
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		system     string
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"npm", "*", "0.1.0", true},
+		{"npm", "^1.2.0", "v1.4.0", true},
+		{"npm", "^1.2.0", "2.0.0", false},
+		{"npm", "1.x || >=3", "3.1.0", true},
+		{"npm", "1.x || >=3", "2.1.0", false},
+		{"npm", "^1.0.0", "2.0.0-rc.1", false},
+		{"npm", ">=2.0.0-rc.0", "2.0.0-rc.1", true},
+		{"npm", ">=2.0.0-rc.0", "2.1.0-rc.1", false},
+		{"maven", "[1.0,2.0)", "2.0", false},
+		{"pip", ">=1.0,!=1.3.4", "1.3.4", false},
+	}
+
+	for _, test := range tests {
+		constraint, err := constraints.Parse(test.system, test.constraint)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, constraint.Satisfies(test.version), "%s %q %s", test.system, test.constraint, test.version)
+	}
+
+	require.True(t, constraints.IsVersion("v1.2.3"))
+	require.False(t, constraints.IsVersion("release-1"))
+	require.False(t, constraints.IsVersion("1.x"))
+}
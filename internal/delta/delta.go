@@ -0,0 +1,127 @@
+// Package delta describes partial updates of a source.
+package delta
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/internal/requestmeta"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Partial updates of a source are described through request metadata. In
+// delta mode, the management files of a track request replace only the
+// modules they manage. Modules the source no longer manages are listed in the
+// removed metadata, and every other module is left as is. The state of the
+// source after a track is returned in a response header. Passing it back as a
+// precondition rejects the next update if the source changed in between.
+const (
+	ModeMetadataKey    = "x-depscloud-track-mode"
+	RemovedMetadataKey = "x-depscloud-removed-module"
+	IfStateMetadataKey = "x-depscloud-if-source-state"
+	StateMetadataKey   = "x-depscloud-source-state"
+)
+
+// Mode is the value of the mode metadata that enables delta updates.
+const Mode = "delta"
+
+// Update describes a partial update of a source.
+type Update struct {
+	// Delta is true when only the modules of the request are replaced.
+	Delta bool
+
+	// Removed lists the modules the source no longer manages.
+	Removed []*schema.Module
+
+	// IfState is the state the source must be in for the update to apply.
+	IfState string
+}
+
+// FromIncomingContext returns the update requested by the client. Full
+// updates without a precondition are returned when no metadata is provided.
+func FromIncomingContext(ctx context.Context) (*Update, error) {
+	update := &Update{}
+
+	switch mode := requestmeta.First(ctx, ModeMetadataKey); mode {
+	case "", "full":
+	case Mode:
+		update.Delta = true
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s: %q", ModeMetadataKey, mode)
+	}
+
+	for _, value := range requestmeta.Values(ctx, RemovedMetadataKey) {
+		query, err := url.ParseQuery(value)
+		if err != nil || query.Get("language") == "" || query.Get("module") == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid %s: %q", RemovedMetadataKey, value)
+		}
+
+		update.Removed = append(update.Removed, &schema.Module{
+			Language:     query.Get("language"),
+			Organization: query.Get("organization"),
+			Module:       query.Get("module"),
+		})
+	}
+
+	if len(update.Removed) > 0 && !update.Delta {
+		return nil, status.Errorf(codes.InvalidArgument, "%s requires %s to be %s", RemovedMetadataKey, ModeMetadataKey, Mode)
+	}
+
+	update.IfState = requestmeta.First(ctx, IfStateMetadataKey)
+	return update, nil
+}
+
+// AppendToOutgoingContext attaches the update to requests made with the
+// returned context.
+func AppendToOutgoingContext(ctx context.Context, update *Update) context.Context {
+	if update == nil {
+		return ctx
+	}
+
+	pairs := make([]string, 0)
+	if update.Delta {
+		pairs = append(pairs, ModeMetadataKey, Mode)
+	}
+
+	for _, module := range update.Removed {
+		pairs = append(pairs, RemovedMetadataKey, url.Values{
+			"language":     []string{module.GetLanguage()},
+			"organization": []string{module.GetOrganization()},
+			"module":       []string{module.GetModule()},
+		}.Encode())
+	}
+
+	if update.IfState != "" {
+		pairs = append(pairs, IfStateMetadataKey, update.IfState)
+	}
+
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+// ForwardContext copies the update metadata of an incoming request onto the
+// outgoing context so proxies pass it along to the backend.
+func ForwardContext(ctx context.Context) context.Context {
+	return requestmeta.Forward(ctx, ModeMetadataKey, RemovedMetadataKey, IfStateMetadataKey)
+}
+
+// SetState returns the state of the source to the client. Nothing is sent
+// when the state is empty.
+func SetState(ctx context.Context, state string) error {
+	if state == "" {
+		return nil
+	}
+	return grpc.SetHeader(ctx, metadata.Pairs(StateMetadataKey, state))
+}
+
+// RelayState returns the state from a backend response header to the client.
+func RelayState(ctx context.Context, header metadata.MD) error {
+	return SetState(ctx, requestmeta.Get(header, StateMetadataKey))
+}
@@ -0,0 +1,101 @@
+package delta_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/internal/delta"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestFromIncomingContext(t *testing.T) {
+	update, err := delta.FromIncomingContext(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, &delta.Update{}, update)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		delta.ModeMetadataKey, delta.Mode,
+		delta.RemovedMetadataKey, "language=go&organization=depscloud&module=a",
+		delta.RemovedMetadataKey, "language=node&module=%40depscloud%2Fb",
+		delta.IfStateMetadataKey, "abc",
+	))
+
+	update, err = delta.FromIncomingContext(ctx)
+	require.Nil(t, err)
+	require.Equal(t, &delta.Update{
+		Delta: true,
+		Removed: []*schema.Module{
+			{Language: "go", Organization: "depscloud", Module: "a"},
+			{Language: "node", Module: "@depscloud/b"},
+		},
+		IfState: "abc",
+	}, update)
+
+	for _, md := range []metadata.MD{
+		metadata.Pairs(delta.ModeMetadataKey, "partial"),
+		metadata.Pairs(delta.ModeMetadataKey, delta.Mode, delta.RemovedMetadataKey, "module=a"),
+		metadata.Pairs(delta.RemovedMetadataKey, "language=go&module=a"),
+	} {
+		_, err = delta.FromIncomingContext(metadata.NewIncomingContext(context.Background(), md))
+		require.NotNil(t, err, md)
+	}
+}
+
+func TestAppendToOutgoingContext(t *testing.T) {
+	ctx := delta.AppendToOutgoingContext(context.Background(), &delta.Update{})
+	_, ok := metadata.FromOutgoingContext(ctx)
+	require.False(t, ok)
+
+	update := &delta.Update{
+		Delta:   true,
+		Removed: []*schema.Module{{Language: "node", Module: "@depscloud/b"}},
+		IfState: "abc",
+	}
+
+	md, ok := metadata.FromOutgoingContext(delta.AppendToOutgoingContext(context.Background(), update))
+	require.True(t, ok)
+
+	// the update survives the round trip
+	parsed, err := delta.FromIncomingContext(metadata.NewIncomingContext(context.Background(), md))
+	require.Nil(t, err)
+	require.Equal(t, update, parsed)
+}
+
+func TestForward(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		delta.ModeMetadataKey, delta.Mode,
+		delta.RemovedMetadataKey, "language=go&module=a",
+		delta.RemovedMetadataKey, "language=go&module=b",
+		"authorization", "secret",
+	))
+
+	md, ok := metadata.FromOutgoingContext(delta.ForwardContext(ctx))
+	require.True(t, ok)
+	require.Equal(t, []string{delta.Mode}, md.Get(delta.ModeMetadataKey))
+	require.Len(t, md.Get(delta.RemovedMetadataKey), 2)
+	require.Len(t, md.Get("authorization"), 0)
+
+	stream := &headerStream{}
+	ctx = grpc.NewContextWithServerTransportStream(context.Background(), stream)
+
+	require.Nil(t, delta.RelayState(ctx, metadata.MD{}))
+	require.Len(t, stream.header, 0)
+
+	require.Nil(t, delta.RelayState(ctx, metadata.Pairs(delta.StateMetadataKey, "abc")))
+	require.Equal(t, []string{"abc"}, stream.header.Get(delta.StateMetadataKey))
+}
@@ -0,0 +1,156 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Config controls the debug server. It's served separately from the api, so
+// profiles and dumps are only reachable by operators with access to the
+// address.
+type Config struct {
+	Address string
+}
+
+// WithFlags returns the flags used to configure the debug server.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "debug-address",
+			Usage:       "address to serve pprof profiles, goroutine dumps, and gc stats on, such as localhost:6060; disabled when empty",
+			Value:       cfg.Address,
+			Destination: &(cfg.Address),
+			EnvVars:     []string{"DEBUG_ADDRESS"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// GCStats summarizes the memory held by the process and the work done by the
+// garbage collector.
+type GCStats struct {
+	Goroutines   int             `json:"goroutines"`
+	HeapAlloc    uint64          `json:"heap_alloc"`
+	HeapInuse    uint64          `json:"heap_inuse"`
+	HeapIdle     uint64          `json:"heap_idle"`
+	HeapReleased uint64          `json:"heap_released"`
+	HeapObjects  uint64          `json:"heap_objects"`
+	Sys          uint64          `json:"sys"`
+	NumGC        int64           `json:"num_gc"`
+	LastGC       time.Time       `json:"last_gc"`
+	PauseTotal   time.Duration   `json:"pause_total"`
+	Pauses       []time.Duration `json:"pauses"`
+}
+
+// ReadGCStats returns the current stats of the process, along with the most
+// recent garbage collection pauses.
+func ReadGCStats() *GCStats {
+	memStats := &runtime.MemStats{}
+	runtime.ReadMemStats(memStats)
+
+	gcStats := &debug.GCStats{}
+	debug.ReadGCStats(gcStats)
+
+	pauses := gcStats.Pause
+	if len(pauses) > 10 {
+		pauses = pauses[:10]
+	}
+
+	return &GCStats{
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    memStats.HeapAlloc,
+		HeapInuse:    memStats.HeapInuse,
+		HeapIdle:     memStats.HeapIdle,
+		HeapReleased: memStats.HeapReleased,
+		HeapObjects:  memStats.HeapObjects,
+		Sys:          memStats.Sys,
+		NumGC:        gcStats.NumGC,
+		LastGC:       gcStats.LastGC,
+		PauseTotal:   gcStats.PauseTotal,
+		Pauses:       pauses,
+	}
+}
+
+func writeGCStats(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ReadGCStats())
+}
+
+// Handler returns the debug endpoints.
+//
+//	/debug/pprof/      the profiles of net/http/pprof
+//	/debug/goroutines  the stack of every goroutine
+//	/debug/gcstats     memory and garbage collection stats
+//	/debug/gc          forces a garbage collection when posted to, returning
+//	                   memory to the os
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	mux.HandleFunc("/debug/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_ = rpprof.Lookup("goroutine").WriteTo(w, 2)
+	})
+
+	mux.HandleFunc("/debug/gcstats", func(w http.ResponseWriter, r *http.Request) {
+		writeGCStats(w)
+	})
+
+	mux.HandleFunc("/debug/gc", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		debug.FreeOSMemory()
+		writeGCStats(w)
+	})
+
+	return mux
+}
+
+// Serve starts the debug server in the background when an address is
+// configured. It's stopped once the context is done.
+func Serve(ctx context.Context, cfg *Config) error {
+	if cfg.Address == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: Handler()}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	go func() {
+		logrus.Infof("[diagnostics] starting debug server on %s", cfg.Address)
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logrus.Errorf("[diagnostics] failed to serve: %v", err)
+		}
+	}()
+
+	return nil
+}
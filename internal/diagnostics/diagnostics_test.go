@@ -0,0 +1,62 @@
+package diagnostics_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/diagnostics"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(diagnostics.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/debug/pprof/")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/debug/goroutines")
+	require.Nil(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.Nil(t, err)
+	require.Contains(t, string(body), "goroutine ")
+
+	resp, err = http.Get(server.URL + "/debug/gcstats")
+	require.Nil(t, err)
+	stats := &diagnostics.GCStats{}
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(stats))
+	_ = resp.Body.Close()
+	require.Greater(t, stats.Goroutines, 0)
+	require.Greater(t, stats.Sys, uint64(0))
+
+	// collections are only forced when posted to
+	resp, err = http.Get(server.URL + "/debug/gc")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	resp, err = http.Post(server.URL+"/debug/gc", "", nil)
+	require.Nil(t, err)
+	collected := &diagnostics.GCStats{}
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(collected))
+	_ = resp.Body.Close()
+	require.Greater(t, collected.NumGC, stats.NumGC)
+}
+
+func TestServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// nothing is served without an address
+	require.Nil(t, diagnostics.Serve(ctx, &diagnostics.Config{}))
+
+	require.NotNil(t, diagnostics.Serve(ctx, &diagnostics.Config{Address: "localhost:-1"}))
+}
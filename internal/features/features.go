@@ -0,0 +1,242 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Route is the admin endpoint used to read and change flags at runtime.
+const Route = "/v1alpha/admin/features"
+
+// Flag is a behavior that can be turned on or off per deployment, or rolled
+// out to a percentage of tenants.
+type Flag struct {
+	Name    string `json:"name"`
+	Usage   string `json:"usage"`
+	Default bool   `json:"default"`
+	// Percent of tenants the flag is enabled for, between 0 and 100.
+	Percent int `json:"percent"`
+}
+
+var registered = make(map[string]*Flag)
+
+// Register declares a flag and its default. Flags are declared up front so
+// mistyped names are rejected rather than silently ignored.
+func Register(name, usage string, enabled bool) string {
+	percent := 0
+	if enabled {
+		percent = 100
+	}
+
+	registered[name] = &Flag{Name: name, Usage: usage, Default: enabled, Percent: percent}
+	return name
+}
+
+// The flags known to the services.
+var (
+	// FindCache serves dependents and dependencies lookups from the in-memory
+	// cache of the tracker, when the cache is configured.
+	FindCache = Register("find-cache", "serve dependents and dependencies lookups from the in-memory cache", true)
+	// DeltaUpdates accepts track requests that only replace the modules they
+	// name.
+	DeltaUpdates = Register("delta-updates", "accept track requests that only replace the modules they name", true)
+)
+
+// Config holds the flags set for the deployment.
+type Config struct {
+	Features *cli.StringSlice
+}
+
+// WithFlags returns the flags used to configure features.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	if cfg.Features == nil {
+		cfg.Features = cli.NewStringSlice()
+	}
+
+	flags := []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:        "feature",
+			Usage:       "enable a feature by name, or set it using name=on, name=off, or name=25% to roll it out to a percentage of tenants",
+			Destination: cfg.Features,
+			EnvVars:     []string{"FEATURES"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// parsePercent parses the value of a flag into the percent of tenants it's
+// enabled for.
+func parsePercent(value string) (int, error) {
+	switch strings.ToLower(value) {
+	case "", "on", "true":
+		return 100, nil
+	case "off", "false":
+		return 0, nil
+	}
+
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err == nil && percent >= 0 && percent <= 100 {
+			return percent, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unsupported value %s, specify one of on/off or a percentage", value)
+}
+
+// Set holds the state of every registered flag.
+type Set struct {
+	mu    sync.RWMutex
+	flags map[string]*Flag
+}
+
+// New returns the flags with the values in the config applied over their
+// defaults.
+func New(cfg *Config) (*Set, error) {
+	s := &Set{flags: make(map[string]*Flag, len(registered))}
+	for name, flag := range registered {
+		copied := *flag
+		s.flags[name] = &copied
+	}
+
+	if cfg == nil || cfg.Features == nil {
+		return s, nil
+	}
+
+	for _, feature := range cfg.Features.Value() {
+		parts := strings.SplitN(feature, "=", 2)
+		value := ""
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+
+		if err := s.Set(strings.TrimSpace(parts[0]), strings.TrimSpace(value)); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Set changes the value of the flag.
+func (s *Set) Set(name, value string) error {
+	percent, err := parsePercent(value)
+	if err != nil {
+		return fmt.Errorf("feature %s: %v", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flag, ok := s.flags[name]
+	if !ok {
+		return fmt.Errorf("unknown feature %s", name)
+	}
+
+	flag.Percent = percent
+	return nil
+}
+
+// bucket places the tenant into one of 100 buckets, so a tenant stays enabled
+// as the percentage of a flag is raised.
+func bucket(name, tenant string) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name + "/" + tenant))
+	return int(hash.Sum32() % 100)
+}
+
+// Enabled returns true when the flag is enabled for the tenant of the
+// request.
+func (s *Set) Enabled(ctx context.Context, name string) bool {
+	s.mu.RLock()
+	flag, ok := s.flags[name]
+	percent := 0
+	if ok {
+		percent = flag.Percent
+	}
+	s.mu.RUnlock()
+
+	switch {
+	case percent >= 100:
+		return true
+	case percent <= 0:
+		return false
+	}
+
+	return bucket(name, tenants.FromContext(ctx)) < percent
+}
+
+// List returns the flags sorted by name.
+func (s *Set) List() []*Flag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flags := make([]*Flag, 0, len(s.flags))
+	for _, flag := range s.flags {
+		copied := *flag
+		flags = append(flags, &copied)
+	}
+
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+	return flags
+}
+
+// ServeHTTP handles GET and PUT /v1alpha/admin/features. PUT changes the flag
+// named by the name parameter to the value parameter until the process
+// restarts. Both respond with every flag.
+func (s *Set) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		query := r.URL.Query()
+		if err := s.Set(query.Get("name"), query.Get("value")); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"features": s.List()})
+}
+
+var defaultSet *Set
+
+func init() {
+	// the defaults are read once every flag has been registered
+	defaultSet, _ = New(nil)
+}
+
+// SetDefault replaces the flags read by Enabled.
+func SetDefault(s *Set) {
+	defaultSet = s
+}
+
+// Default returns the flags read by Enabled.
+func Default() *Set {
+	return defaultSet
+}
+
+// Enabled returns true when the flag is enabled for the tenant of the request
+// in the flags of the process.
+func Enabled(ctx context.Context, name string) bool {
+	return defaultSet.Enabled(ctx, name)
+}
@@ -0,0 +1,81 @@
+package features_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/features"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+
+	set, err := features.New(&features.Config{})
+	require.Nil(t, err)
+	require.True(t, set.Enabled(ctx, features.FindCache))
+	require.False(t, set.Enabled(ctx, "unknown"))
+
+	set, err = features.New(&features.Config{Features: cli.NewStringSlice("find-cache=off", "delta-updates")})
+	require.Nil(t, err)
+	require.False(t, set.Enabled(ctx, features.FindCache))
+	require.True(t, set.Enabled(ctx, features.DeltaUpdates))
+
+	for _, invalid := range []string{"find-cache=maybe", "find-cache=150%", "unknown=on"} {
+		_, err := features.New(&features.Config{Features: cli.NewStringSlice(invalid)})
+		require.NotNil(t, err, invalid)
+	}
+}
+
+func TestRollout(t *testing.T) {
+	set, err := features.New(&features.Config{Features: cli.NewStringSlice("find-cache=50%")})
+	require.Nil(t, err)
+
+	enabled := 0
+	for _, tenant := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"} {
+		ctx := tenants.NewContext(context.Background(), tenant)
+		if set.Enabled(ctx, features.FindCache) {
+			enabled++
+		}
+
+		// tenants remain enabled as the rollout grows
+		before := set.Enabled(ctx, features.FindCache)
+		require.Nil(t, set.Set(features.FindCache, "75%"))
+		require.True(t, !before || set.Enabled(ctx, features.FindCache))
+		require.Nil(t, set.Set(features.FindCache, "50%"))
+	}
+
+	require.True(t, enabled > 0 && enabled < 12)
+}
+
+func TestServeHTTP(t *testing.T) {
+	set, err := features.New(nil)
+	require.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	set.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, features.Route+"?name=delta-updates&value=off", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.False(t, set.Enabled(context.Background(), features.DeltaUpdates))
+
+	resp := struct {
+		Features []*features.Flag `json:"features"`
+	}{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(&resp))
+	require.Equal(t, &features.Flag{
+		Name:    "delta-updates",
+		Usage:   "accept track requests that only replace the modules they name",
+		Default: true,
+		Percent: 0,
+	}, resp.Features[0])
+
+	recorder = httptest.NewRecorder()
+	set.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, features.Route+"?name=unknown&value=on", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,74 @@
+// Package filters reads the filters of list and search requests.
+package filters
+
+import (
+	"context"
+
+	"github.com/depscloud/depscloud/internal/requestmeta"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// List and search endpoints accept filters through request metadata.
+const (
+	LanguageMetadataKey     = "x-depscloud-filter-language"
+	OrganizationMetadataKey = "x-depscloud-filter-organization"
+	NamePrefixMetadataKey   = "x-depscloud-filter-name-prefix"
+	LabelsMetadataKey       = "x-depscloud-filter-labels"
+)
+
+// Filter narrows the results of list and search endpoints. Language and
+// organization must match exactly. The name prefix matches the name of a
+// module or the url of a source. Labels is a comma separated list of label
+// selectors, either key=value or key, that must all match.
+type Filter struct {
+	Language     string
+	Organization string
+	NamePrefix   string
+	Labels       string
+}
+
+// Empty returns true when the filter matches everything.
+func (f *Filter) Empty() bool {
+	return f == nil || (f.Language == "" && f.Organization == "" && f.NamePrefix == "" && f.Labels == "")
+}
+
+// FromIncomingContext returns the filter requested by the client.
+func FromIncomingContext(ctx context.Context) *Filter {
+	return &Filter{
+		Language:     requestmeta.First(ctx, LanguageMetadataKey),
+		Organization: requestmeta.First(ctx, OrganizationMetadataKey),
+		NamePrefix:   requestmeta.First(ctx, NamePrefixMetadataKey),
+		Labels:       requestmeta.First(ctx, LabelsMetadataKey),
+	}
+}
+
+// AppendToOutgoingContext attaches the filter to requests made with the
+// returned context.
+func (f *Filter) AppendToOutgoingContext(ctx context.Context) context.Context {
+	if f.Empty() {
+		return ctx
+	}
+
+	kv := make([]string, 0, 8)
+	if f.Language != "" {
+		kv = append(kv, LanguageMetadataKey, f.Language)
+	}
+	if f.Organization != "" {
+		kv = append(kv, OrganizationMetadataKey, f.Organization)
+	}
+	if f.NamePrefix != "" {
+		kv = append(kv, NamePrefixMetadataKey, f.NamePrefix)
+	}
+	if f.Labels != "" {
+		kv = append(kv, LabelsMetadataKey, f.Labels)
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// ForwardContext copies the filter of an incoming request onto the outgoing
+// context so it's passed along to the backend.
+func ForwardContext(ctx context.Context) context.Context {
+	return requestmeta.Forward(ctx, LanguageMetadataKey, OrganizationMetadataKey, NamePrefixMetadataKey, LabelsMetadataKey)
+}
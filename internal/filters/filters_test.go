@@ -0,0 +1,45 @@
+package filters_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/filters"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFromIncomingContext(t *testing.T) {
+	require.True(t, filters.FromIncomingContext(context.Background()).Empty())
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		filters.LanguageMetadataKey, "go",
+		filters.NamePrefixMetadataKey, "github.com/depscloud/",
+		filters.LabelsMetadataKey, "team=core",
+	))
+
+	filter := filters.FromIncomingContext(ctx)
+	require.False(t, filter.Empty())
+	require.Equal(t, &filters.Filter{
+		Language:   "go",
+		NamePrefix: "github.com/depscloud/",
+		Labels:     "team=core",
+	}, filter)
+}
+
+func TestForwardContext(t *testing.T) {
+	ctx := filters.ForwardContext(context.Background())
+	_, ok := metadata.FromOutgoingContext(ctx)
+	require.False(t, ok)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		filters.OrganizationMetadataKey, "depscloud",
+		"authorization", "secret",
+	))
+
+	md, ok := metadata.FromOutgoingContext(filters.ForwardContext(ctx))
+	require.True(t, ok)
+	require.Equal(t, metadata.Pairs(filters.OrganizationMetadataKey, "depscloud"), md)
+}
@@ -0,0 +1,41 @@
+// Package idempotency reads the keys that identify retried writes.
+package idempotency
+
+import (
+	"context"
+
+	"github.com/depscloud/depscloud/internal/requestmeta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataKey holds the key identifying a write. Retries of a write reuse its
+// key so the tracker can tell they were already applied.
+const MetadataKey = "x-depscloud-idempotency-key"
+
+// MaxLength is the longest key the tracker stores.
+const MaxLength = 128
+
+// FromIncomingContext returns the idempotency key of a write. An empty key is
+// returned when the client didn't provide one.
+func FromIncomingContext(ctx context.Context) (string, error) {
+	key := requestmeta.First(ctx, MetadataKey)
+	if len(key) > MaxLength {
+		return "", status.Errorf(codes.InvalidArgument, "%s must be at most %d characters", MetadataKey, MaxLength)
+	}
+
+	return key, nil
+}
+
+// AppendToOutgoingContext attaches the key to requests made with the returned
+// context. An empty key leaves the context untouched.
+func AppendToOutgoingContext(ctx context.Context, key string) context.Context {
+	return requestmeta.Append(ctx, MetadataKey, key)
+}
+
+// ForwardContext copies the idempotency key of an incoming request onto the
+// outgoing context so it's passed along to the backend.
+func ForwardContext(ctx context.Context) context.Context {
+	return requestmeta.Forward(ctx, MetadataKey)
+}
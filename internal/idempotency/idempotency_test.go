@@ -0,0 +1,54 @@
+package idempotency_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/idempotency"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFromIncomingContext(t *testing.T) {
+	key, err := idempotency.FromIncomingContext(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, "", key)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotency.MetadataKey, "abc123"))
+	key, err = idempotency.FromIncomingContext(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "abc123", key)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotency.MetadataKey, strings.Repeat("a", idempotency.MaxLength+1)))
+	_, err = idempotency.FromIncomingContext(ctx)
+	require.NotNil(t, err)
+}
+
+func TestForwardContext(t *testing.T) {
+	ctx := idempotency.ForwardContext(context.Background())
+	_, ok := metadata.FromOutgoingContext(ctx)
+	require.False(t, ok)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		idempotency.MetadataKey, "abc123",
+		"authorization", "secret",
+	))
+
+	md, ok := metadata.FromOutgoingContext(idempotency.ForwardContext(ctx))
+	require.True(t, ok)
+	require.Equal(t, metadata.Pairs(idempotency.MetadataKey, "abc123"), md)
+}
+
+func TestAppendToOutgoingContext(t *testing.T) {
+	ctx := idempotency.AppendToOutgoingContext(context.Background(), "")
+	_, ok := metadata.FromOutgoingContext(ctx)
+	require.False(t, ok)
+
+	ctx = idempotency.AppendToOutgoingContext(context.Background(), "abc123")
+	md, ok := metadata.FromOutgoingContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, metadata.Pairs(idempotency.MetadataKey, "abc123"), md)
+}
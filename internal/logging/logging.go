@@ -0,0 +1,258 @@
+// Package logging configures the logs written by each service and carries the
+// request id, tenant, and trace id of a request to the entries logged for it.
+//
+// Logs are written using logrus, which the services already depend on and
+// which provides the levels, fields, and json output needed here. Anything
+// logged while handling a request or running a background loop should use the
+// entry returned by FromContext, leaving the standard logger configured by
+// Setup for messages logged while a process starts up or exits.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/telemetry"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// The request id is passed between services through request metadata, and
+// returned to callers over HTTP using the header, so the logs of a request
+// can be found across services.
+const (
+	MetadataKey = "x-request-id"
+	HeaderKey   = "X-Request-Id"
+)
+
+// The formats logs are written in.
+const (
+	// FormatText writes human readable lines, as logrus does by default.
+	FormatText = "text"
+	// FormatJSON writes a json object per line for log aggregators.
+	FormatJSON = "json"
+)
+
+// The fields attached to log entries.
+const (
+	ServiceField   = "service"
+	RequestIDField = "request_id"
+	TenantField    = "tenant"
+	TraceIDField   = "trace_id"
+)
+
+// Config controls the level and format of logs.
+type Config struct {
+	Level  string
+	Format string
+}
+
+// DefaultConfig returns the defaults used by every service.
+func DefaultConfig() *Config {
+	return &Config{
+		Level:  logrus.InfoLevel.String(),
+		Format: FormatText,
+	}
+}
+
+// WithFlags returns the flags used to configure logging.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "the minimum level logged; trace, debug, info, warn, error, or fatal",
+			Value:       cfg.Level,
+			Destination: &(cfg.Level),
+			EnvVars:     []string{"LOG_LEVEL"},
+		},
+		&cli.StringFlag{
+			Name:        "log-format",
+			Usage:       "the format logs are written in; text or json",
+			Value:       cfg.Format,
+			Destination: &(cfg.Format),
+			EnvVars:     []string{"LOG_FORMAT"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// serviceFormatter names the service in every entry. The fields are copied,
+// since entries derived from one another share them.
+type serviceFormatter struct {
+	logrus.Formatter
+	service string
+}
+
+func (f *serviceFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(logrus.Fields, len(entry.Data)+1)
+	for key, value := range entry.Data {
+		data[key] = value
+	}
+	data[ServiceField] = f.service
+
+	named := *entry
+	named.Data = data
+	return f.Formatter.Format(&named)
+}
+
+// Setup configures the standard logger used throughout the service, so
+// existing log lines pick up the level, format, and service name.
+func Setup(service string, cfg *Config) error {
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		return fmt.Errorf("unsupported log level %s", cfg.Level)
+	}
+
+	var formatter logrus.Formatter
+	switch strings.ToLower(cfg.Format) {
+	case "", FormatText:
+		formatter = &logrus.TextFormatter{}
+	case FormatJSON:
+		formatter = &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+	default:
+		return fmt.Errorf("unsupported log format %s, specify one of text/json", cfg.Format)
+	}
+
+	logrus.SetLevel(level)
+	logrus.SetFormatter(&serviceFormatter{Formatter: formatter, service: service})
+	return nil
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+type contextKey struct{}
+
+// NewContext returns a context for the request id.
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestID)
+}
+
+// RequestID returns the id of the request. The id assigned by this service is
+// preferred, otherwise the id passed by the calling service is used.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if requestID, ok := ctx.Value(contextKey{}).(string); ok {
+		return requestID
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(MetadataKey); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// FromContext returns a logger describing the request, including its id,
+// tenant, and trace when they're known. Fields are read when it's called, so
+// it picks up a tenant resolved after the request id was assigned.
+func FromContext(ctx context.Context) *logrus.Entry {
+	fields := logrus.Fields{}
+
+	if requestID := RequestID(ctx); requestID != "" {
+		fields[RequestIDField] = requestID
+	}
+
+	if tenant := tenants.FromContext(ctx); tenant != "" {
+		fields[TenantField] = tenant
+	}
+
+	if sc := telemetry.FromContext(ctx); sc.Valid() {
+		fields[TraceIDField] = hex.EncodeToString(sc.TraceID[:])
+	}
+
+	return logrus.WithFields(fields)
+}
+
+// incomingContext adopts the request id passed by the caller of a grpc call,
+// assigning one when there isn't one.
+func incomingContext(ctx context.Context) context.Context {
+	requestID := RequestID(ctx)
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	return NewContext(ctx, requestID)
+}
+
+// UnaryServerInterceptor assigns an id to each call, continuing the id of the
+// caller.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = incomingContext(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, RequestID(ctx)))
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor assigns an id to each stream, continuing the id of
+// the caller.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = incomingContext(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(MetadataKey, RequestID(wrapped.WrappedContext)))
+		return handler(srv, wrapped)
+	}
+}
+
+// outgoingContext passes the request id along to the service being called.
+func outgoingContext(ctx context.Context) context.Context {
+	requestID := RequestID(ctx)
+	if requestID == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, requestID)
+}
+
+// UnaryClientInterceptor passes the request id along with every call made to
+// the backend.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor passes the request id along with every stream
+// opened to the backend.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+// Middleware assigns an id to each http request, continuing the id of the
+// caller, and returns it in the response headers. The id is set on the
+// request headers so it's passed along when the request is proxied.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(HeaderKey)
+		if requestID == "" {
+			requestID = newRequestID()
+			r.Header.Set(HeaderKey, requestID)
+		}
+
+		w.Header().Set(HeaderKey, requestID)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), requestID)))
+	})
+}
@@ -0,0 +1,92 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestSetup(t *testing.T) {
+	defer logrus.SetOutput(os.Stderr)
+	defer logrus.SetFormatter(&logrus.TextFormatter{})
+	defer logrus.SetLevel(logrus.InfoLevel)
+
+	require.NotNil(t, logging.Setup("tracker", &logging.Config{Level: "loud", Format: logging.FormatJSON}))
+	require.NotNil(t, logging.Setup("tracker", &logging.Config{Level: "info", Format: "xml"}))
+
+	require.Nil(t, logging.Setup("tracker", &logging.Config{Level: "warn", Format: logging.FormatJSON}))
+
+	output := &bytes.Buffer{}
+	logrus.SetOutput(output)
+
+	ctx := tenants.NewContext(logging.NewContext(context.Background(), "abc123"), "payments")
+	logging.FromContext(ctx).Infof("[service.source] skipped")
+	logging.FromContext(ctx).Warnf("[service.source] %s", "failed")
+
+	entry := make(map[string]interface{})
+	require.Nil(t, json.Unmarshal(output.Bytes(), &entry))
+	require.Equal(t, "warning", entry["level"])
+	require.Equal(t, "[service.source] failed", entry["msg"])
+	require.Equal(t, "tracker", entry[logging.ServiceField])
+	require.Equal(t, "abc123", entry[logging.RequestIDField])
+	require.Equal(t, "payments", entry[logging.TenantField])
+}
+
+func TestMiddleware(t *testing.T) {
+	requestID := ""
+	handler := logging.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = logging.RequestID(r.Context())
+		require.Equal(t, requestID, r.Header.Get(logging.HeaderKey))
+	}))
+
+	// an id is assigned when the caller doesn't provide one
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/queries/search", nil))
+	require.Len(t, requestID, 32)
+	require.Equal(t, requestID, recorder.Header().Get(logging.HeaderKey))
+
+	request := httptest.NewRequest(http.MethodGet, "/v1alpha/queries/search", nil)
+	request.Header.Set(logging.HeaderKey, "abc123")
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, "abc123", requestID)
+	require.Equal(t, "abc123", recorder.Header().Get(logging.HeaderKey))
+}
+
+func TestInterceptors(t *testing.T) {
+	forwarded := ""
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		forwarded = md.Get(logging.MetadataKey)[0]
+		return nil
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, logging.UnaryClientInterceptor()(ctx, "/tracker/Track", nil, nil, nil, invoker)
+	}
+
+	// the id of the caller is passed along to the backend
+	incoming := metadata.NewIncomingContext(context.Background(), metadata.Pairs(logging.MetadataKey, "abc123"))
+	_, err := logging.UnaryServerInterceptor()(incoming, nil, &grpc.UnaryServerInfo{}, handler)
+	require.Nil(t, err)
+	require.Equal(t, "abc123", forwarded)
+
+	_, err = logging.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	require.Nil(t, err)
+	require.Len(t, forwarded, 32)
+}
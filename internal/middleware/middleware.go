@@ -0,0 +1,280 @@
+package middleware
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/telemetry"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/grpc-ecosystem/go-grpc-prometheus"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	// servers accept calls compressed using gzip, replying in kind
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// Config controls the behavior shared by every grpc server and client.
+type Config struct {
+	// DefaultTimeout is the deadline given to unary calls that are made, or
+	// received, without one. Zero leaves those calls without a deadline.
+	DefaultTimeout time.Duration
+
+	// KeepaliveTime is how long a connection is idle before it's pinged, so
+	// proxies and load balancers don't drop it. KeepaliveTimeout is how long
+	// to wait for the ping to be acknowledged before closing the connection.
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+	// KeepaliveMinTime is the shortest interval servers allow clients to ping
+	// at. Clients pinging more often are disconnected.
+	KeepaliveMinTime time.Duration
+
+	// MaxConnectionIdle closes server connections without calls for the
+	// duration. MaxConnectionAge closes server connections after the duration,
+	// giving calls in progress MaxConnectionAgeGrace to finish, so clients
+	// rebalance across replicas. Zero leaves connections open.
+	MaxConnectionIdle     time.Duration
+	MaxConnectionAge      time.Duration
+	MaxConnectionAgeGrace time.Duration
+}
+
+// DefaultConfig returns the defaults used by every service.
+func DefaultConfig() *Config {
+	return &Config{
+		DefaultTimeout:   time.Minute,
+		KeepaliveTime:    30 * time.Second,
+		KeepaliveTimeout: 10 * time.Second,
+		KeepaliveMinTime: 10 * time.Second,
+	}
+}
+
+// WithFlags returns the flags used to configure the interceptors.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	flags := []cli.Flag{
+		&cli.DurationFlag{
+			Name:        "grpc-default-timeout",
+			Usage:       "the deadline given to grpc calls made or received without one, 0 to leave them without a deadline",
+			Value:       cfg.DefaultTimeout,
+			Destination: &(cfg.DefaultTimeout),
+			EnvVars:     []string{"GRPC_DEFAULT_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:        "grpc-keepalive-time",
+			Usage:       "how long a grpc connection is idle before it's pinged, 0 to stop clients from pinging",
+			Value:       cfg.KeepaliveTime,
+			Destination: &(cfg.KeepaliveTime),
+			EnvVars:     []string{"GRPC_KEEPALIVE_TIME"},
+		},
+		&cli.DurationFlag{
+			Name:        "grpc-keepalive-timeout",
+			Usage:       "how long to wait for a ping to be acknowledged before closing the grpc connection",
+			Value:       cfg.KeepaliveTimeout,
+			Destination: &(cfg.KeepaliveTimeout),
+			EnvVars:     []string{"GRPC_KEEPALIVE_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:        "grpc-keepalive-min-time",
+			Usage:       "the shortest interval clients may ping the grpc server at",
+			Value:       cfg.KeepaliveMinTime,
+			Destination: &(cfg.KeepaliveMinTime),
+			EnvVars:     []string{"GRPC_KEEPALIVE_MIN_TIME"},
+		},
+		&cli.DurationFlag{
+			Name:        "grpc-max-connection-idle",
+			Usage:       "how long a grpc server connection may go without calls before it's closed, 0 to leave it open",
+			Value:       cfg.MaxConnectionIdle,
+			Destination: &(cfg.MaxConnectionIdle),
+			EnvVars:     []string{"GRPC_MAX_CONNECTION_IDLE"},
+		},
+		&cli.DurationFlag{
+			Name:        "grpc-max-connection-age",
+			Usage:       "how long a grpc server connection may be open before it's closed, 0 to leave it open",
+			Value:       cfg.MaxConnectionAge,
+			Destination: &(cfg.MaxConnectionAge),
+			EnvVars:     []string{"GRPC_MAX_CONNECTION_AGE"},
+		},
+		&cli.DurationFlag{
+			Name:        "grpc-max-connection-age-grace",
+			Usage:       "how long calls in progress are given to finish once a grpc connection reaches its max age",
+			Value:       cfg.MaxConnectionAgeGrace,
+			Destination: &(cfg.MaxConnectionAgeGrace),
+			EnvVars:     []string{"GRPC_MAX_CONNECTION_AGE_GRACE"},
+		},
+	}
+
+	return cfg, flags
+}
+
+var defaults = DefaultConfig()
+
+// Setup configures the interceptors used throughout the service.
+func Setup(cfg *Config) {
+	copied := *cfg
+	defaults = &copied
+}
+
+// withDefaultTimeout gives the call the default deadline when it doesn't
+// have one.
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || defaults.DefaultTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, defaults.DefaultTimeout)
+}
+
+// recoverPanic logs the panic along with the request it occurred in,
+// returning an error that doesn't leak the details to the caller.
+func recoverPanic(ctx context.Context, p interface{}) error {
+	logging.FromContext(ctx).Errorf("[middleware] recovered from panic: %v\n%s", p, debug.Stack())
+	return status.Error(codes.Internal, "internal error")
+}
+
+// logged returns true for codes that are worth logging at a higher level,
+// since they're the fault of the service rather than the caller.
+func logged(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	entry := logging.FromContext(ctx).WithField("grpc_method", method).
+		WithField("grpc_code", code.String()).
+		WithField("grpc_duration", time.Since(start).String())
+
+	if logged(code) {
+		entry.Warnf("[middleware] %s failed: %s", method, err.Error())
+	} else {
+		entry.Debugf("[middleware] %s", method)
+	}
+}
+
+func unaryLogging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func streamLogging() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func unaryServerTimeout() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+func unaryClientTimeout() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := withDefaultTimeout(ctx)
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// ServerOptions returns the interceptors and connection settings installed on
+// every grpc server. Each call is given a request id, traced, logged,
+// monitored, given the default deadline, and recovered from panics.
+// Additional options, such as those used to authenticate callers, are applied
+// after the defaults so they see the request id and trace of the call.
+func ServerOptions(opts ...grpc.ServerOption) []grpc.ServerOption {
+	recovery := grpc_recovery.WithRecoveryHandlerContext(recoverPanic)
+
+	serverOptions := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:                  defaults.KeepaliveTime,
+			Timeout:               defaults.KeepaliveTimeout,
+			MaxConnectionIdle:     defaults.MaxConnectionIdle,
+			MaxConnectionAge:      defaults.MaxConnectionAge,
+			MaxConnectionAgeGrace: defaults.MaxConnectionAgeGrace,
+		}),
+		// clients ping idle connections, so they aren't dropped between calls
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             defaults.KeepaliveMinTime,
+			PermitWithoutStream: true,
+		}),
+		grpc.ChainStreamInterceptor(grpc_middleware.ChainStreamServer(
+			logging.StreamServerInterceptor(),
+			telemetry.StreamServerInterceptor(),
+			streamLogging(),
+			grpc_prometheus.StreamServerInterceptor,
+			grpc_recovery.StreamServerInterceptor(recovery),
+		)),
+		grpc.ChainUnaryInterceptor(grpc_middleware.ChainUnaryServer(
+			logging.UnaryServerInterceptor(),
+			telemetry.UnaryServerInterceptor(),
+			unaryLogging(),
+			grpc_prometheus.UnaryServerInterceptor,
+			unaryServerTimeout(),
+			grpc_recovery.UnaryServerInterceptor(recovery),
+		)),
+	}
+
+	return append(serverOptions, opts...)
+}
+
+// NewServer returns a grpc server with the default interceptors installed.
+func NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	grpc_prometheus.EnableHandlingTimeHistogram()
+
+	return grpc.NewServer(ServerOptions(opts...)...)
+}
+
+// DialOptions returns the interceptors and connection settings installed on
+// every grpc client. The tenant, request id, and trace of the request are
+// passed along with each call, and unary calls are given the default
+// deadline. Idle connections are pinged so intermediaries don't drop them.
+// Additional options, such as per call credentials or retries, are applied
+// after the defaults.
+func DialOptions(opts ...grpc.DialOption) []grpc.DialOption {
+	dialOptions := []grpc.DialOption{
+		grpc.WithChainStreamInterceptor(
+			tenants.StreamClientInterceptor(),
+			logging.StreamClientInterceptor(),
+			telemetry.StreamClientInterceptor(),
+			grpc_prometheus.StreamClientInterceptor,
+		),
+		grpc.WithChainUnaryInterceptor(
+			tenants.UnaryClientInterceptor(),
+			logging.UnaryClientInterceptor(),
+			telemetry.UnaryClientInterceptor(),
+			unaryClientTimeout(),
+			grpc_prometheus.UnaryClientInterceptor,
+		),
+	}
+
+	// grpc raises intervals shorter than 10s, so zero is left unset to stop
+	// pinging altogether
+	if defaults.KeepaliveTime > 0 {
+		dialOptions = append(dialOptions, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                defaults.KeepaliveTime,
+			Timeout:             defaults.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
+	}
+
+	return append(dialOptions, opts...)
+}
@@ -0,0 +1,143 @@
+package middleware_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/middleware"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+
+	check func(ctx context.Context) error
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if err := h.check(ctx); err != nil {
+		return nil, err
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func serve(t *testing.T, check func(ctx context.Context) error, opts ...grpc.DialOption) (healthpb.HealthClient, *int32) {
+	listener := bufconn.Listen(1024 * 1024)
+	dials := new(int32)
+
+	server := middleware.NewServer()
+	healthpb.RegisterHealthServer(server, &healthServer{check: check})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		atomic.AddInt32(dials, 1)
+		return listener.Dial()
+	}
+
+	opts = append(opts, grpc.WithInsecure(), grpc.WithContextDialer(dialer))
+
+	cc, err := grpc.Dial("bufnet", middleware.DialOptions(opts...)...)
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+
+	return healthpb.NewHealthClient(cc), dials
+}
+
+func TestServerOptions(t *testing.T) {
+	middleware.Setup(&middleware.Config{DefaultTimeout: time.Minute})
+	defer middleware.Setup(middleware.DefaultConfig())
+
+	var requestID string
+	var deadline time.Time
+	var hasDeadline bool
+
+	client, _ := serve(t, func(ctx context.Context) error {
+		requestID = logging.RequestID(ctx)
+		deadline, hasDeadline = ctx.Deadline()
+		return nil
+	})
+
+	// calls made without a deadline are given the default
+	var header metadata.MD
+	ctx := logging.NewContext(context.Background(), "request-1")
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+	require.Nil(t, err)
+	require.Equal(t, "request-1", requestID)
+	require.Equal(t, []string{"request-1"}, header.Get(logging.MetadataKey))
+	require.True(t, hasDeadline)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+
+	// the deadline of the caller is kept
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.Nil(t, err)
+	require.NotEmpty(t, requestID)
+	require.WithinDuration(t, time.Now().Add(10*time.Second), deadline, 5*time.Second)
+
+	// no deadline is given when the default is turned off
+	middleware.Setup(&middleware.Config{})
+
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.Nil(t, err)
+	require.False(t, hasDeadline)
+}
+
+func TestServerOptions_recovery(t *testing.T) {
+	client, _ := serve(t, func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.NotContains(t, err.Error(), "boom")
+}
+
+func TestServerOptions_maxConnectionAge(t *testing.T) {
+	middleware.Setup(&middleware.Config{
+		MaxConnectionAge:      100 * time.Millisecond,
+		MaxConnectionAgeGrace: 100 * time.Millisecond,
+	})
+	defer middleware.Setup(middleware.DefaultConfig())
+
+	client, dials := serve(t, func(ctx context.Context) error {
+		return nil
+	})
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.Nil(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(dials))
+
+	// the connection is closed once it's too old, and calls made after are
+	// sent over a new one
+	time.Sleep(500 * time.Millisecond)
+
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.Nil(t, err)
+	require.Greater(t, atomic.LoadInt32(dials), int32(1))
+}
+
+func TestServerOptions_gzip(t *testing.T) {
+	client, _ := serve(t, func(ctx context.Context) error {
+		return nil
+	}, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.Nil(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
@@ -0,0 +1,183 @@
+package mux
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/mjpitz/go-gracefully/check"
+	"github.com/mjpitz/go-gracefully/health"
+	"github.com/mjpitz/go-gracefully/state"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthStatus derives the serving status of each grpc service from the
+// state of the checks it depends on. The overall status, reported under the
+// empty service name, follows the state of the system.
+type healthStatus struct {
+	mu       sync.Mutex
+	server   *grpchealth.Server
+	services map[string][]string
+	checks   map[string]state.State
+	system   state.State
+	stopped  bool
+}
+
+func newHealthStatus(server *grpchealth.Server, services map[string][]string) *healthStatus {
+	h := &healthStatus{
+		server:   server,
+		services: services,
+		checks:   make(map[string]state.State),
+		system:   state.Unknown,
+	}
+
+	for service := range services {
+		server.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	return h
+}
+
+func servingStatus(serving bool) healthpb.HealthCheckResponse_ServingStatus {
+	if serving {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+// update applies the report of a check, or of the system when the report has
+// no check, to the statuses of the services.
+func (h *healthStatus) update(report check.Report) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stopped {
+		return
+	}
+
+	if report.Check == nil {
+		h.system = report.Result.State
+	} else {
+		h.checks[report.Check.GetMetadata().Name] = report.Result.State
+	}
+
+	systemServing := h.system != state.Outage
+	h.server.SetServingStatus("", servingStatus(systemServing))
+
+	for service, checks := range h.services {
+		serving := systemServing
+		if len(checks) > 0 {
+			serving = true
+			for _, name := range checks {
+				if h.checks[name] == state.Outage {
+					serving = false
+				}
+			}
+		}
+		h.server.SetServingStatus(service, servingStatus(serving))
+	}
+}
+
+// shutdown stops serving every service, so clients and probes move away from
+// the process while it drains.
+func (h *healthStatus) shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stopped = true
+	h.server.Shutdown()
+}
+
+func (h *healthStatus) status(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	resp, err := h.server.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, false
+	}
+	return resp.GetStatus(), true
+}
+
+type readiness struct {
+	Status   string            `json:"status"`
+	Services map[string]string `json:"services,omitempty"`
+}
+
+// readyHandler reports the same statuses as the grpc health service. The
+// service parameter selects the status of a single service.
+func (h *healthStatus) readyHandler(writer http.ResponseWriter, request *http.Request) {
+	service := request.URL.Query().Get("service")
+
+	status, ok := h.status(request.Context(), service)
+	if !ok {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body := readiness{Status: status.String()}
+	if service == "" {
+		body.Services = make(map[string]string, len(h.services))
+		for name := range h.services {
+			status, _ := h.status(request.Context(), name)
+			body.Services[name] = status.String()
+		}
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if status == healthpb.HealthCheckResponse_SERVING {
+		writer.WriteHeader(http.StatusOK)
+	} else {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(writer).Encode(body)
+}
+
+// grpcServices returns the checks of every service registered with the
+// server. The health and reflection services are left out.
+func grpcServices(grpcServer *grpc.Server, serviceChecks map[string][]string) map[string][]string {
+	services := make(map[string][]string)
+	for name := range grpcServer.GetServiceInfo() {
+		if strings.HasPrefix(name, "grpc.") {
+			continue
+		}
+
+		services[name] = serviceChecks[name]
+	}
+	return services
+}
+
+func registerHealth(grpcServer *grpc.Server, httpServer *http.ServeMux, config *Config) *healthStatus {
+	monitor := health.NewMonitor(config.Checks...)
+	reports, unsubscribe := monitor.Subscribe()
+	stopCh := config.Context.Done()
+
+	healthCheck := grpchealth.NewServer()
+	status := newHealthStatus(healthCheck, grpcServices(grpcServer, config.ServiceChecks))
+
+	go func() {
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-stopCh:
+				status.shutdown()
+				return
+			case report := <-reports:
+				status.update(report)
+			}
+		}
+	}()
+
+	handler := health.HandlerFunc(monitor)
+	httpServer.HandleFunc("/healthz", handler)
+	httpServer.HandleFunc("/health", handler)
+	httpServer.HandleFunc("/readyz", status.readyHandler)
+
+	healthpb.RegisterHealthServer(grpcServer, healthCheck)
+	_ = monitor.Start(config.Context)
+
+	return status
+}
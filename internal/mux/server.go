@@ -12,13 +12,13 @@ import (
 	"strings"
 	"syscall"
 
-	"github.com/grpc-ecosystem/go-grpc-middleware"
-	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	"github.com/grpc-ecosystem/go-grpc-prometheus"
 
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/middleware"
+	"github.com/depscloud/depscloud/internal/telemetry"
+
 	"github.com/mjpitz/go-gracefully/check"
-	"github.com/mjpitz/go-gracefully/health"
-	"github.com/mjpitz/go-gracefully/state"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
@@ -27,15 +27,13 @@ import (
 	"github.com/sirupsen/logrus"
 
 	metrics "github.com/slok/go-http-metrics/metrics/prometheus"
-	"github.com/slok/go-http-metrics/middleware"
+	httpmetrics "github.com/slok/go-http-metrics/middleware"
 	std "github.com/slok/go-http-metrics/middleware/std"
 
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 
 	"google.golang.org/grpc"
-	grpchealth "google.golang.org/grpc/health"
-	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -56,61 +54,25 @@ type Config struct {
 	BindAddressGRPC string
 
 	Checks []check.Check
+	// ServiceChecks names the checks each grpc service depends on. A service
+	// stops serving while any of its checks are in an outage. Services without
+	// checks follow the health of the system.
+	ServiceChecks map[string][]string
 
 	TLSConfig *TLSConfig
+	// GRPCCredentials is set when the grpc server was given credentials for
+	// the TLSConfig, so it can inspect client certificates. The grpc port is
+	// then served without wrapping the listener in TLS.
+	GRPCCredentials bool
 
 	Version *Version
 }
 
-func DefaultServers() (*grpc.Server, *http.ServeMux) {
-	grpcOpts := []grpc.ServerOption{
-		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
-			grpc_prometheus.StreamServerInterceptor,
-			grpc_recovery.StreamServerInterceptor(),
-		)),
-		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
-			grpc_prometheus.UnaryServerInterceptor,
-			grpc_recovery.UnaryServerInterceptor(),
-		)),
-	}
-
-	grpc_prometheus.EnableHandlingTimeHistogram()
-
-	return grpc.NewServer(grpcOpts...), http.NewServeMux()
-}
-
-func registerHealth(grpcServer *grpc.Server, httpServer *http.ServeMux, config *Config) {
-	monitor := health.NewMonitor(config.Checks...)
-	reports, unsubscribe := monitor.Subscribe()
-	stopCh := config.Context.Done()
-
-	healthCheck := grpchealth.NewServer()
-
-	go func() {
-		defer unsubscribe()
-
-		for {
-			select {
-			case <-stopCh:
-				return
-			case report := <-reports:
-				if report.Check == nil {
-					if report.Result.State == state.Outage {
-						healthCheck.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
-					} else {
-						healthCheck.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
-					}
-				}
-			}
-		}
-	}()
-
-	handler := health.HandlerFunc(monitor)
-	httpServer.HandleFunc("/healthz", handler)
-	httpServer.HandleFunc("/health", handler)
-
-	healthpb.RegisterHealthServer(grpcServer, healthCheck)
-	_ = monitor.Start(config.Context)
+// DefaultServers returns the grpc and http servers. Additional options, such
+// as interceptors, are applied after the shared request id, tracing, logging,
+// monitoring, deadline, and recovery interceptors.
+func DefaultServers(opts ...grpc.ServerOption) (*grpc.Server, *http.ServeMux) {
+	return middleware.NewServer(opts...), http.NewServeMux()
 }
 
 func registerMetrics(httpServer *http.ServeMux) {
@@ -131,30 +93,18 @@ func registerVersion(httpServer *http.ServeMux, config *Config) {
 }
 
 func monitorHandler(httpServer http.Handler) http.Handler {
-	mdlw := middleware.New(middleware.Config{
+	mdlw := httpmetrics.New(httpmetrics.Config{
 		Recorder: metrics.NewRecorder(metrics.Config{}),
 	})
 	return std.Handler("", mdlw, httpServer)
 }
 
 func Serve(grpcServer *grpc.Server, httpServer http.Handler, config *Config) error {
-	stop := make(chan os.Signal)
+	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 
-	go func() {
-		defer signal.Stop(stop)
-
-		<-stop
-		logrus.Infof("[runtime] received shutdown signal, gracefully shutting down")
-		go grpcServer.GracefulStop()
-
-		<-stop
-		logrus.Infof("[runtime] shutdown re-notified, forcing termination")
-		grpcServer.Stop()
-	}()
-
 	// don't double report gRPC metrics, it has it's own
-	monitoredServer := monitorHandler(httpServer)
+	monitoredServer := logging.Middleware(telemetry.Middleware(monitorHandler(httpServer)))
 
 	httpMux := http.NewServeMux()
 	httpMux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
@@ -167,10 +117,23 @@ func Serve(grpcServer *grpc.Server, httpServer http.Handler, config *Config) err
 	})
 
 	reflection.Register(grpcServer)
-	registerHealth(grpcServer, httpMux, config)
+	healthStatus := registerHealth(grpcServer, httpMux, config)
 	registerMetrics(httpMux)
 	registerVersion(httpMux, config)
 
+	go func() {
+		defer signal.Stop(stop)
+
+		<-stop
+		logrus.Infof("[runtime] received shutdown signal, gracefully shutting down")
+		healthStatus.shutdown()
+		go grpcServer.GracefulStop()
+
+		<-stop
+		logrus.Infof("[runtime] shutdown re-notified, forcing termination")
+		grpcServer.Stop()
+	}()
+
 	grpc_prometheus.Register(grpcServer)
 
 	corsMux := cors.Default().Handler(httpMux)
@@ -189,7 +152,11 @@ func Serve(grpcServer *grpc.Server, httpServer http.Handler, config *Config) err
 
 	if tlsConfig != nil {
 		httpListener, httpErr = tls.Listen("tcp", config.BindAddressHTTP, tlsConfig)
-		grpcListener, grpcErr = tls.Listen("tcp", config.BindAddressGRPC, tlsConfig)
+		if config.GRPCCredentials {
+			grpcListener, grpcErr = net.Listen("tcp", config.BindAddressGRPC)
+		} else {
+			grpcListener, grpcErr = tls.Listen("tcp", config.BindAddressGRPC, tlsConfig)
+		}
 	} else {
 		httpListener, httpErr = net.Listen("tcp", config.BindAddressHTTP)
 		grpcListener, grpcErr = net.Listen("tcp", config.BindAddressGRPC)
@@ -0,0 +1,77 @@
+// Package paging pages list endpoints using the metadata of a request.
+package paging
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+
+	"github.com/depscloud/depscloud/internal/requestmeta"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// List endpoints without paging fields accept a page size and token through
+// request metadata, and return the token of the next page in a header.
+const (
+	SizeMetadataKey      = "x-depscloud-page-size"
+	TokenMetadataKey     = "x-depscloud-page-token"
+	NextTokenMetadataKey = "x-depscloud-next-page-token"
+)
+
+// FromIncomingContext returns the page size and token requested by the
+// client. The size is 0 when the client didn't ask for one.
+func FromIncomingContext(ctx context.Context) (int, string, error) {
+	size := 0
+	if value := requestmeta.First(ctx, SizeMetadataKey); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, "", status.Errorf(codes.InvalidArgument, "invalid %s: %q", SizeMetadataKey, value)
+		}
+		size = parsed
+	}
+
+	return size, requestmeta.First(ctx, TokenMetadataKey), nil
+}
+
+// SetNextToken returns the token for the next page to the client. Nothing is
+// sent when the token is empty.
+func SetNextToken(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return grpc.SetHeader(ctx, metadata.Pairs(NextTokenMetadataKey, token))
+}
+
+// ForwardContext copies the paging metadata of an incoming request onto the
+// outgoing context so proxies pass it along to the backend.
+func ForwardContext(ctx context.Context) context.Context {
+	return requestmeta.Forward(ctx, SizeMetadataKey, TokenMetadataKey)
+}
+
+// RelayNextToken returns the next page token from a backend response header
+// to the client.
+func RelayNextToken(ctx context.Context, header metadata.MD) error {
+	return SetNextToken(ctx, requestmeta.Get(header, NextTokenMetadataKey))
+}
+
+// EncodeToken wraps a cursor in an opaque token. Clients should not rely on
+// the contents of a token.
+func EncodeToken(cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+}
+
+// DecodeToken returns the cursor wrapped by the token.
+func DecodeToken(token string) (string, error) {
+	cursor, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, "invalid page token")
+	}
+	return string(cursor), nil
+}
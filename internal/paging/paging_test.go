@@ -0,0 +1,77 @@
+package paging_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/paging"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestFromIncomingContext(t *testing.T) {
+	size, token, err := paging.FromIncomingContext(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, 0, size)
+	require.Equal(t, "", token)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		paging.SizeMetadataKey, "25",
+		paging.TokenMetadataKey, "abc",
+	))
+
+	size, token, err = paging.FromIncomingContext(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 25, size)
+	require.Equal(t, "abc", token)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(paging.SizeMetadataKey, "-1"))
+	_, _, err = paging.FromIncomingContext(ctx)
+	require.NotNil(t, err)
+}
+
+func TestToken(t *testing.T) {
+	require.Equal(t, "", paging.EncodeToken(""))
+
+	token := paging.EncodeToken("module---a---b---")
+	cursor, err := paging.DecodeToken(token)
+	require.Nil(t, err)
+	require.Equal(t, "module---a---b---", cursor)
+
+	_, err = paging.DecodeToken("not a token!")
+	require.NotNil(t, err)
+}
+
+func TestForward(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		paging.SizeMetadataKey, "25",
+		"authorization", "secret",
+	))
+
+	md, ok := metadata.FromOutgoingContext(paging.ForwardContext(ctx))
+	require.True(t, ok)
+	require.Equal(t, []string{"25"}, md.Get(paging.SizeMetadataKey))
+	require.Len(t, md.Get("authorization"), 0)
+
+	stream := &headerStream{}
+	ctx = grpc.NewContextWithServerTransportStream(context.Background(), stream)
+
+	require.Nil(t, paging.RelayNextToken(ctx, metadata.MD{}))
+	require.Len(t, stream.header, 0)
+
+	require.Nil(t, paging.RelayNextToken(ctx, metadata.Pairs(paging.NextTokenMetadataKey, "next")))
+	require.Equal(t, []string{"next"}, stream.header.Get(paging.NextTokenMetadataKey))
+}
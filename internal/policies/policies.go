@@ -0,0 +1,298 @@
+package policies
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Default paths of the rules that are evaluated. Each rule is expected to be
+// a set of deny messages, but a boolean allow rule or an object with allow
+// and deny fields are also understood.
+const (
+	DefaultTrackPolicy = "depscloud/track/deny"
+	DefaultQueryPolicy = "depscloud/query/deny"
+)
+
+// health checks and metrics are never subject to policies
+var exempt = map[string]bool{
+	"/grpc.health.v1.Health/Check": true,
+	"/grpc.health.v1.Health/Watch": true,
+	"/health":                      true,
+	"/healthz":                     true,
+	"/metrics":                     true,
+	"/version":                     true,
+}
+
+// Config points at an Open Policy Agent whose policies decide which sources
+// may be tracked and which queries may be made. Policies are disabled when no
+// url is configured.
+type Config struct {
+	URL         string
+	TrackPolicy string
+	QueryPolicy string
+	FailOpen    bool
+	Timeout     time.Duration
+}
+
+// WithFlags returns the flags used to configure policies.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "opa-url",
+			Usage:       "the address of the open policy agent evaluating policies, disabled when empty",
+			Value:       cfg.URL,
+			Destination: &(cfg.URL),
+			EnvVars:     []string{"OPA_URL"},
+		},
+		&cli.StringFlag{
+			Name:        "opa-track-policy",
+			Usage:       "the path of the rule evaluated before the edges of a source are stored, disabled when empty",
+			Value:       cfg.TrackPolicy,
+			Destination: &(cfg.TrackPolicy),
+			EnvVars:     []string{"OPA_TRACK_POLICY"},
+		},
+		&cli.StringFlag{
+			Name:        "opa-query-policy",
+			Usage:       "the path of the rule evaluated for each request, disabled when empty",
+			Value:       cfg.QueryPolicy,
+			Destination: &(cfg.QueryPolicy),
+			EnvVars:     []string{"OPA_QUERY_POLICY"},
+		},
+		&cli.BoolFlag{
+			Name:        "opa-fail-open",
+			Usage:       "allow requests when the open policy agent can't be reached",
+			Value:       cfg.FailOpen,
+			Destination: &(cfg.FailOpen),
+			EnvVars:     []string{"OPA_FAIL_OPEN"},
+		},
+		&cli.DurationFlag{
+			Name:        "opa-timeout",
+			Usage:       "how long to wait on the open policy agent for a decision",
+			Value:       cfg.Timeout,
+			Destination: &(cfg.Timeout),
+			EnvVars:     []string{"OPA_TIMEOUT"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// Enabled returns true when an open policy agent is configured.
+func (c *Config) Enabled() bool {
+	return c != nil && c.URL != ""
+}
+
+// Decision is the outcome of evaluating a policy.
+type Decision struct {
+	Allow   bool
+	Reasons []string
+}
+
+// decide interprets the result of a rule. Undefined rules allow everything.
+func decide(result interface{}) (*Decision, error) {
+	switch result := result.(type) {
+	case nil:
+		return &Decision{Allow: true}, nil
+	case bool:
+		return &Decision{Allow: result}, nil
+	case string:
+		return &Decision{Allow: result == "", Reasons: reasons(result)}, nil
+	case []interface{}:
+		messages := reasons(result...)
+		return &Decision{Allow: len(messages) == 0, Reasons: messages}, nil
+	case map[string]interface{}:
+		decision := &Decision{Allow: true}
+		if allow, ok := result["allow"]; ok {
+			allowed, ok := allow.(bool)
+			if !ok {
+				return nil, fmt.Errorf("allow must be a boolean")
+			}
+			decision.Allow = allowed
+		}
+
+		if deny, ok := result["deny"]; ok {
+			denied, err := decide(deny)
+			if err != nil {
+				return nil, err
+			}
+			decision.Allow = decision.Allow && denied.Allow
+			decision.Reasons = append(decision.Reasons, denied.Reasons...)
+		}
+
+		if list, ok := result["reasons"].([]interface{}); ok {
+			decision.Reasons = append(decision.Reasons, reasons(list...)...)
+		}
+		return decision, nil
+	}
+
+	return nil, fmt.Errorf("unsupported policy result %v", result)
+}
+
+func reasons(values ...interface{}) []string {
+	messages := make([]string, 0, len(values))
+	for _, value := range values {
+		switch value := value.(type) {
+		case string:
+			if value != "" {
+				messages = append(messages, value)
+			}
+		case nil:
+		default:
+			messages = append(messages, fmt.Sprintf("%v", value))
+		}
+	}
+	return messages
+}
+
+// Evaluate evaluates the rule at the path using the data api of the open
+// policy agent.
+func (c *Config) Evaluate(ctx context.Context, path string, input interface{}) (*Decision, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	url := strings.TrimSuffix(c.URL, "/") + "/v1/data/" + strings.Trim(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("open policy agent responded with %s for %s", resp.Status, path)
+	}
+
+	response := struct {
+		Result interface{} `json:"result"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	return decide(response.Result)
+}
+
+// Check evaluates the rule at the path, returning a PermissionDenied error
+// listing the reasons when the input is denied. When the open policy agent
+// fails, the input is allowed only if the config fails open. Disabled configs
+// and empty paths allow everything.
+func (c *Config) Check(ctx context.Context, path string, input interface{}) error {
+	if !c.Enabled() || path == "" {
+		return nil
+	}
+
+	decision, err := c.Evaluate(ctx, path, input)
+	if err != nil {
+		logrus.Errorf("[policies] failed to evaluate %s: %s", path, err.Error())
+		if c.FailOpen {
+			return nil
+		}
+		return status.Error(codes.Unavailable, "failed to evaluate policy")
+	}
+
+	if decision.Allow {
+		return nil
+	}
+
+	if len(decision.Reasons) == 0 {
+		return status.Errorf(codes.PermissionDenied, "denied by policy %s", path)
+	}
+	return status.Errorf(codes.PermissionDenied, "denied by policy %s: %s", path, strings.Join(decision.Reasons, "; "))
+}
+
+// QueryInput is the input of the query policy.
+type QueryInput struct {
+	Tenant string `json:"tenant"`
+	// Method is the full grpc method or the http method of the request.
+	Method string `json:"method"`
+	// Path is the path of http requests.
+	Path  string              `json:"path,omitempty"`
+	Query map[string][]string `json:"query,omitempty"`
+	// Request is the message of grpc requests.
+	Request interface{} `json:"request,omitempty"`
+}
+
+// UnaryServerInterceptor checks each call against the query policy. It must
+// run after the tenant of the call is resolved.
+func (c *Config) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !exempt[info.FullMethod] {
+			err := c.Check(ctx, c.QueryPolicy, &QueryInput{
+				Tenant:  tenants.FromContext(ctx),
+				Method:  info.FullMethod,
+				Request: req,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ServerOptions returns the interceptors that check calls against the query
+// policy. No options are returned when policies are disabled.
+func (c *Config) ServerOptions() []grpc.ServerOption {
+	if !c.Enabled() || c.QueryPolicy == "" {
+		return nil
+	}
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(c.UnaryServerInterceptor())}
+}
+
+// Middleware checks each http request against the query policy. It must run
+// after the tenant of the request is resolved.
+func (c *Config) Middleware(next http.Handler) http.Handler {
+	if !c.Enabled() || c.QueryPolicy == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !exempt[r.URL.Path] {
+			err := c.Check(r.Context(), c.QueryPolicy, &QueryInput{
+				Tenant: tenants.FromContext(r.Context()),
+				Method: r.Method,
+				Path:   r.URL.Path,
+				Query:  r.URL.Query(),
+			})
+
+			if err != nil {
+				code := http.StatusForbidden
+				if status.Code(err) == codes.Unavailable {
+					code = http.StatusServiceUnavailable
+				}
+				http.Error(w, status.Convert(err).Message(), code)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
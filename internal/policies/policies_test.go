@@ -0,0 +1,148 @@
+package policies_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/policies"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// opa serves the results of rules by path, recording the input of each
+// evaluation.
+func opa(t *testing.T, results map[string]string, inputs *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+
+		request := struct {
+			Input map[string]interface{} `json:"input"`
+		}{}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		*inputs = append(*inputs, request.Input)
+
+		result, ok := results[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(result))
+	}))
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+
+	inputs := make([]map[string]interface{}, 0)
+	server := opa(t, map[string]string{
+		"/v1/data/deny/none":      `{"result": []}`,
+		"/v1/data/deny/some":      `{"result": ["banned registry", "unpinned"]}`,
+		"/v1/data/allow/true":     `{"result": true}`,
+		"/v1/data/allow/false":    `{"result": false}`,
+		"/v1/data/decision":       `{"result": {"allow": true, "deny": ["banned registry"]}}`,
+		"/v1/data/undefined":      `{}`,
+		"/v1/data/allow/reasoned": `{"result": {"allow": false, "reasons": ["not yet"]}}`,
+	}, &inputs)
+	defer server.Close()
+
+	config := &policies.Config{URL: server.URL}
+
+	require.Nil(t, config.Check(ctx, "deny/none", map[string]string{"a": "b"}))
+	require.Equal(t, map[string]interface{}{"a": "b"}, inputs[0])
+
+	err := config.Check(ctx, "deny/some", nil)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Equal(t, "denied by policy deny/some: banned registry; unpinned", status.Convert(err).Message())
+
+	require.Nil(t, config.Check(ctx, "allow/true", nil))
+	require.Equal(t, codes.PermissionDenied, status.Code(config.Check(ctx, "allow/false", nil)))
+	require.Equal(t, codes.PermissionDenied, status.Code(config.Check(ctx, "decision", nil)))
+	require.Nil(t, config.Check(ctx, "undefined", nil))
+
+	err = config.Check(ctx, "allow/reasoned", nil)
+	require.Equal(t, "denied by policy allow/reasoned: not yet", status.Convert(err).Message())
+
+	// failures deny requests unless the config fails open
+	require.Equal(t, codes.Unavailable, status.Code(config.Check(ctx, "missing", nil)))
+	config.FailOpen = true
+	require.Nil(t, config.Check(ctx, "missing", nil))
+
+	// disabled configs and empty paths allow everything
+	evaluated := len(inputs)
+	require.Nil(t, config.Check(ctx, "", nil))
+	require.Nil(t, (&policies.Config{}).Check(ctx, "deny/some", nil))
+	require.Nil(t, (*policies.Config)(nil).Check(ctx, "deny/some", nil))
+	require.Len(t, inputs, evaluated)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	inputs := make([]map[string]interface{}, 0)
+	server := opa(t, map[string]string{
+		"/v1/data/depscloud/query/deny": `{"result": ["searching is disabled"]}`,
+	}, &inputs)
+	defer server.Close()
+
+	config := &policies.Config{URL: server.URL, QueryPolicy: policies.DefaultQueryPolicy}
+	interceptor := config.UnaryServerInterceptor()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	ctx := tenants.NewContext(context.Background(), "payments")
+	_, err := interceptor(ctx, map[string]string{"like": "depscloud"},
+		&grpc.UnaryServerInfo{FullMethod: "/v1alpha.tracker.SearchService/Search"}, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	require.Len(t, inputs, 1)
+	require.Equal(t, "payments", inputs[0]["tenant"])
+	require.Equal(t, "/v1alpha.tracker.SearchService/Search", inputs[0]["method"])
+	require.Equal(t, map[string]interface{}{"like": "depscloud"}, inputs[0]["request"])
+
+	// health checks are never evaluated
+	resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+	require.Nil(t, err)
+	require.Equal(t, "ok", resp)
+	require.Len(t, inputs, 1)
+
+	require.Empty(t, (&policies.Config{}).ServerOptions())
+	require.Len(t, config.ServerOptions(), 1)
+}
+
+func TestMiddleware(t *testing.T) {
+	inputs := make([]map[string]interface{}, 0)
+	server := opa(t, map[string]string{
+		"/v1/data/depscloud/query/deny": `{"result": []}`,
+	}, &inputs)
+	defer server.Close()
+
+	config := &policies.Config{URL: server.URL, QueryPolicy: policies.DefaultQueryPolicy}
+	handler := config.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(path string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	require.Equal(t, http.StatusOK, serve("/v1alpha/graph/export?language=go"))
+	require.Len(t, inputs, 1)
+	require.Equal(t, "GET", inputs[0]["method"])
+	require.Equal(t, "/v1alpha/graph/export", inputs[0]["path"])
+	require.Equal(t, map[string]interface{}{"language": []interface{}{"go"}}, inputs[0]["query"])
+
+	require.Equal(t, http.StatusOK, serve("/health"))
+	require.Len(t, inputs, 1)
+
+	config.QueryPolicy = "depscloud/missing"
+	handler = config.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	require.Equal(t, http.StatusServiceUnavailable, serve("/v1alpha/graph/export"))
+}
@@ -0,0 +1,413 @@
+package quotas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Route is the admin endpoint used to read the usage of tokens on the replica
+// that serves the request.
+const Route = "/v1alpha/admin/quotas"
+
+// health checks and metrics are never counted
+var exempt = map[string]bool{
+	"/grpc.health.v1.Health/Check": true,
+	"/grpc.health.v1.Health/Watch": true,
+	"/health":                      true,
+	"/healthz":                     true,
+	"/metrics":                     true,
+	"/version":                     true,
+}
+
+// Limits are the number of requests a token may make each day and month, in
+// UTC. A limit of 0 is unlimited.
+type Limits struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// Config sets the quotas of service account tokens. Every token shares the
+// default limits unless they're overridden for the token.
+type Config struct {
+	Daily     int64
+	Monthly   int64
+	Overrides *cli.StringSlice
+}
+
+// WithFlags returns the flags used to configure quotas.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	if cfg.Overrides == nil {
+		cfg.Overrides = cli.NewStringSlice()
+	}
+
+	flags := []cli.Flag{
+		&cli.Int64Flag{
+			Name:        "token-daily-quota",
+			Usage:       "the number of requests each service account token may make per day on each replica, 0 is unlimited. counts are kept in memory, so limits are best-effort",
+			Value:       cfg.Daily,
+			Destination: &(cfg.Daily),
+			EnvVars:     []string{"TOKEN_DAILY_QUOTA"},
+		},
+		&cli.Int64Flag{
+			Name:        "token-monthly-quota",
+			Usage:       "the number of requests each service account token may make per month on each replica, 0 is unlimited. counts are kept in memory, so limits are best-effort",
+			Value:       cfg.Monthly,
+			Destination: &(cfg.Monthly),
+			EnvVars:     []string{"TOKEN_MONTHLY_QUOTA"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "token-quota",
+			Usage:       "override the per replica quotas of a token using id=daily/monthly, where 0 is unlimited",
+			Destination: cfg.Overrides,
+			EnvVars:     []string{"TOKEN_QUOTAS"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// parseOverride parses an override of the form id=daily/monthly.
+func parseOverride(value string) (string, Limits, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return "", Limits{}, fmt.Errorf("token quota %s must be of the form id=daily/monthly", value)
+	}
+
+	limits := strings.SplitN(parts[1], "/", 2)
+	if len(limits) != 2 {
+		return "", Limits{}, fmt.Errorf("token quota %s must be of the form id=daily/monthly", value)
+	}
+
+	daily, err := strconv.ParseInt(strings.TrimSpace(limits[0]), 10, 64)
+	if err != nil || daily < 0 {
+		return "", Limits{}, fmt.Errorf("token quota %s has an invalid daily limit", value)
+	}
+
+	monthly, err := strconv.ParseInt(strings.TrimSpace(limits[1]), 10, 64)
+	if err != nil || monthly < 0 {
+		return "", Limits{}, fmt.Errorf("token quota %s has an invalid monthly limit", value)
+	}
+
+	return strings.TrimSpace(parts[0]), Limits{Daily: daily, Monthly: monthly}, nil
+}
+
+// Usage is the number of requests a token made during the current day and
+// month.
+type Usage struct {
+	Tenant  string `json:"tenant,omitempty"`
+	TokenID string `json:"tokenId"`
+	Subject string `json:"subject"`
+	Day     string `json:"day"`
+	Daily   int64  `json:"daily"`
+	Month   string `json:"month"`
+	Monthly int64  `json:"monthly"`
+	Limits  Limits `json:"limits"`
+}
+
+// reset starts new periods once the day or month of the usage has passed.
+func (u *Usage) reset(now time.Time) {
+	if day := now.Format("2006-01-02"); u.Day != day {
+		u.Day, u.Daily = day, 0
+	}
+	if month := now.Format("2006-01"); u.Month != month {
+		u.Month, u.Monthly = month, 0
+	}
+}
+
+// nextDay and nextMonth return when the periods containing now end.
+func nextDay(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+func nextMonth(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Remaining returns the limit closest to being exceeded, the number of
+// requests left within it, and when it resets. Unlimited usage returns a
+// limit of 0.
+func (u *Usage) Remaining(now time.Time) (limit, remaining int64, reset time.Time) {
+	now = now.UTC()
+	remaining = -1
+
+	if u.Limits.Daily > 0 {
+		limit, remaining, reset = u.Limits.Daily, u.Limits.Daily-u.Daily, nextDay(now)
+	}
+
+	if left := u.Limits.Monthly - u.Monthly; u.Limits.Monthly > 0 && (remaining < 0 || left < remaining) {
+		limit, remaining, reset = u.Limits.Monthly, left, nextMonth(now)
+	}
+
+	if remaining < 0 {
+		remaining = 0
+	}
+	return limit, remaining, reset
+}
+
+// ExceededError is returned once a token has used its quota for a period.
+type ExceededError struct {
+	Period string
+	Limit  int64
+	Reset  time.Time
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s quota of %d requests exceeded, resets at %s", e.Period, e.Limit, e.Reset.Format(time.RFC3339))
+}
+
+// GRPCStatus allows the error to be returned from grpc calls.
+func (e *ExceededError) GRPCStatus() *status.Status {
+	return status.New(codes.ResourceExhausted, e.Error())
+}
+
+// retryAfter returns the number of seconds until the quota resets.
+func (e *ExceededError) retryAfter(now time.Time) string {
+	seconds := int64(e.Reset.Sub(now).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// Quotas counts the requests made by each service account token and rejects
+// those beyond its limits. Counts are kept in the memory of each replica of
+// the gateway, so limits apply per replica and restart with the process. They
+// are a best-effort guard against runaway clients rather than a billing limit.
+type Quotas struct {
+	defaults  Limits
+	overrides map[string]Limits
+
+	mu    sync.Mutex
+	usage map[string]*Usage
+}
+
+// New returns the quotas described by the config.
+func New(cfg *Config) (*Quotas, error) {
+	q := &Quotas{
+		defaults:  Limits{Daily: cfg.Daily, Monthly: cfg.Monthly},
+		overrides: make(map[string]Limits),
+		usage:     make(map[string]*Usage),
+	}
+
+	if q.defaults.Daily < 0 || q.defaults.Monthly < 0 {
+		return nil, fmt.Errorf("token quotas must not be negative")
+	}
+
+	if cfg.Overrides != nil {
+		for _, override := range cfg.Overrides.Value() {
+			id, limits, err := parseOverride(override)
+			if err != nil {
+				return nil, err
+			}
+			q.overrides[id] = limits
+		}
+	}
+
+	return q, nil
+}
+
+// Enabled returns true when any token is limited.
+func (q *Quotas) Enabled() bool {
+	return q != nil && (q.defaults.Daily > 0 || q.defaults.Monthly > 0 || len(q.overrides) > 0)
+}
+
+func (q *Quotas) limitsFor(tokenID string) Limits {
+	if limits, ok := q.overrides[tokenID]; ok {
+		return limits
+	}
+	return q.defaults
+}
+
+// Take counts a request made using the credential within the tenant,
+// returning the usage of its token. An ExceededError is returned, and the
+// request isn't counted, once the token has used its quota. Requests made
+// without a token aren't counted.
+func (q *Quotas) Take(tenant string, credential *rbac.Credential, now time.Time) (*Usage, error) {
+	if credential == nil || credential.TokenID == "" {
+		return nil, nil
+	}
+
+	now = now.UTC()
+	key := tenant + "/" + credential.TokenID
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	usage, ok := q.usage[key]
+	if !ok {
+		usage = &Usage{Tenant: tenant, TokenID: credential.TokenID, Subject: credential.Subject}
+		q.usage[key] = usage
+	}
+
+	usage.reset(now)
+	usage.Limits = q.limitsFor(credential.TokenID)
+
+	var err error
+	if usage.Limits.Daily > 0 && usage.Daily >= usage.Limits.Daily {
+		err = &ExceededError{Period: "daily", Limit: usage.Limits.Daily, Reset: nextDay(now)}
+	} else if usage.Limits.Monthly > 0 && usage.Monthly >= usage.Limits.Monthly {
+		err = &ExceededError{Period: "monthly", Limit: usage.Limits.Monthly, Reset: nextMonth(now)}
+	} else {
+		usage.Daily++
+		usage.Monthly++
+	}
+
+	copied := *usage
+	return &copied, err
+}
+
+// List returns the usage of the tokens of the tenant as of now, sorted by
+// token id.
+func (q *Quotas) List(tenant string, now time.Time) []*Usage {
+	now = now.UTC()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	usage := make([]*Usage, 0)
+	for _, u := range q.usage {
+		if u.Tenant != tenant {
+			continue
+		}
+
+		copied := *u
+		copied.reset(now)
+		copied.Limits = q.limitsFor(u.TokenID)
+		usage = append(usage, &copied)
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].TokenID < usage[j].TokenID
+	})
+	return usage
+}
+
+func (q *Quotas) take(ctx context.Context) (*Usage, error) {
+	return q.Take(tenants.FromContext(ctx), rbac.CredentialFromContext(ctx), time.Now())
+}
+
+// UnaryServerInterceptor counts each call made using a token, rejecting those
+// beyond its quota with ResourceExhausted. It must run after the credential
+// of the call is resolved.
+func (q *Quotas) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !exempt[info.FullMethod] {
+			if _, err := q.take(ctx); err != nil {
+				if exceeded, ok := err.(*ExceededError); ok {
+					_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", exceeded.retryAfter(time.Now())))
+				}
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor counts each stream opened using a token, the same
+// way as calls.
+func (q *Quotas) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !exempt[info.FullMethod] {
+			if _, err := q.take(ss.Context()); err != nil {
+				if exceeded, ok := err.(*ExceededError); ok {
+					_ = ss.SetHeader(metadata.Pairs("retry-after", exceeded.retryAfter(time.Now())))
+				}
+				return err
+			}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// ServerOptions returns the interceptors that enforce quotas. No options are
+// returned when no token is limited.
+func (q *Quotas) ServerOptions() []grpc.ServerOption {
+	if !q.Enabled() {
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(q.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(q.StreamServerInterceptor()),
+	}
+}
+
+// Middleware counts each http request made using a token. The remaining quota
+// is reported using the RateLimit headers, and requests beyond the quota are
+// rejected with 429 Too Many Requests and a Retry-After header. It must run
+// after the credential of the request is resolved.
+func (q *Quotas) Middleware(next http.Handler) http.Handler {
+	if !q.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		usage, err := q.take(r.Context())
+
+		if usage != nil {
+			if limit, remaining, reset := usage.Remaining(now); limit > 0 {
+				w.Header().Set("RateLimit-Limit", strconv.FormatInt(limit, 10))
+				w.Header().Set("RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+				w.Header().Set("RateLimit-Reset", strconv.FormatInt(int64(reset.Sub(now).Seconds()), 10))
+			}
+		}
+
+		if exceeded, ok := err.(*ExceededError); ok {
+			w.Header().Set("Retry-After", exceeded.retryAfter(now))
+			http.Error(w, exceeded.Error(), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ServeHTTP handles GET /v1alpha/admin/quotas, responding with the default
+// limits and the usage of the tokens of the tenant. The token parameter
+// narrows the usage to a single token.
+func (q *Quotas) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	usage := q.List(tenants.FromContext(r.Context()), time.Now())
+	if token := r.URL.Query().Get("token"); token != "" {
+		filtered := make([]*Usage, 0, 1)
+		for _, u := range usage {
+			if u.TokenID == token {
+				filtered = append(filtered, u)
+			}
+		}
+		usage = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"defaults": q.defaults,
+		"usage":    usage,
+	})
+}
@@ -0,0 +1,168 @@
+package quotas_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/quotas"
+	"github.com/depscloud/depscloud/internal/rbac"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ci      = &rbac.Credential{Subject: "serviceaccount:ci", Role: rbac.RoleViewer, TokenID: "ci"}
+	release = &rbac.Credential{Subject: "serviceaccount:release", Role: rbac.RoleViewer, TokenID: "release"}
+)
+
+func TestConfig(t *testing.T) {
+	q, err := quotas.New(&quotas.Config{})
+	require.Nil(t, err)
+	require.False(t, q.Enabled())
+	require.Nil(t, q.ServerOptions())
+
+	_, err = quotas.New(&quotas.Config{Overrides: cli.NewStringSlice("ci=10")})
+	require.NotNil(t, err)
+
+	_, err = quotas.New(&quotas.Config{Overrides: cli.NewStringSlice("ci=ten/100")})
+	require.NotNil(t, err)
+
+	q, err = quotas.New(&quotas.Config{Overrides: cli.NewStringSlice("ci=10/100")})
+	require.Nil(t, err)
+	require.True(t, q.Enabled())
+}
+
+func TestTake(t *testing.T) {
+	q, err := quotas.New(&quotas.Config{
+		Daily:     2,
+		Monthly:   3,
+		Overrides: cli.NewStringSlice("release=0/0"),
+	})
+	require.Nil(t, err)
+
+	now := time.Date(2020, time.October, 31, 12, 0, 0, 0, time.UTC)
+
+	// requests without a token aren't counted
+	usage, err := q.Take("", nil, now)
+	require.Nil(t, err)
+	require.Nil(t, usage)
+
+	for i := int64(1); i <= 2; i++ {
+		usage, err = q.Take("", ci, now)
+		require.Nil(t, err)
+		require.Equal(t, i, usage.Daily)
+	}
+
+	limit, remaining, reset := usage.Remaining(now)
+	require.Equal(t, int64(2), limit)
+	require.Equal(t, int64(0), remaining)
+	require.Equal(t, now.Add(12*time.Hour), reset)
+
+	usage, err = q.Take("", ci, now)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, int64(2), usage.Daily)
+
+	exceeded, ok := err.(*quotas.ExceededError)
+	require.True(t, ok)
+	require.Equal(t, "daily", exceeded.Period)
+
+	// the next day is also the next month
+	usage, err = q.Take("", ci, now.Add(12*time.Hour))
+	require.Nil(t, err)
+	require.Equal(t, int64(1), usage.Daily)
+	require.Equal(t, int64(1), usage.Monthly)
+
+	_, err = q.Take("", ci, now.Add(36*time.Hour))
+	require.Nil(t, err)
+	_, err = q.Take("", ci, now.Add(60*time.Hour))
+	require.Nil(t, err)
+
+	_, err = q.Take("", ci, now.Add(84*time.Hour))
+	require.NotNil(t, err)
+	require.Equal(t, "monthly", err.(*quotas.ExceededError).Period)
+
+	// overridden tokens are unlimited
+	for i := 0; i < 5; i++ {
+		_, err = q.Take("", release, now)
+		require.Nil(t, err)
+	}
+
+	// usage is kept per tenant
+	usage, err = q.Take("payments", ci, now.Add(84*time.Hour))
+	require.Nil(t, err)
+	require.Equal(t, int64(1), usage.Daily)
+
+	listed := q.List("", now.Add(84*time.Hour))
+	require.Len(t, listed, 2)
+	require.Equal(t, "ci", listed[0].TokenID)
+	require.Equal(t, int64(3), listed[0].Monthly)
+	require.Equal(t, "release", listed[1].TokenID)
+	require.Equal(t, int64(0), listed[1].Monthly)
+}
+
+func TestMiddleware(t *testing.T) {
+	q, err := quotas.New(&quotas.Config{Daily: 1})
+	require.Nil(t, err)
+
+	authorizer, err := rbac.NewAuthorizer(&rbac.Config{Mode: rbac.ModeCertificate, DefaultRole: string(rbac.RoleAdmin)}, nil)
+	require.Nil(t, err)
+
+	authorizer.WithVerifier(func(ctx context.Context, tenant, token string) (*rbac.Credential, error) {
+		if token == "ci" {
+			return ci, nil
+		}
+		return nil, nil
+	})
+
+	handler := authorizer.Middleware(q.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == quotas.Route {
+			q.ServeHTTP(w, r)
+		}
+	})))
+
+	serve := func(path, authorization string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		if authorization != "" {
+			request.Header.Set(rbac.AuthorizationHeaderKey, authorization)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve("/v1alpha/labels/modules", "Bearer ci")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+
+	recorder = serve("/v1alpha/labels/modules", "Bearer ci")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+
+	// health checks and callers without a token aren't limited
+	require.Equal(t, http.StatusOK, serve("/health", "Bearer ci").Code)
+	require.Equal(t, http.StatusOK, serve("/v1alpha/labels/modules", "").Code)
+
+	recorder = serve(quotas.Route+"?token=ci", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := struct {
+		Defaults quotas.Limits   `json:"defaults"`
+		Usage    []*quotas.Usage `json:"usage"`
+	}{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(&body))
+	require.Equal(t, int64(1), body.Defaults.Daily)
+	require.Len(t, body.Usage, 1)
+	require.Equal(t, "serviceaccount:ci", body.Usage[0].Subject)
+	require.Equal(t, int64(1), body.Usage[0].Daily)
+}
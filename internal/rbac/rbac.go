@@ -0,0 +1,757 @@
+// Package rbac grants roles to callers and enforces them on each request.
+package rbac
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/requestmeta"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Like the tenant, the subject is passed between services through request
+// metadata, or the header over HTTP.
+const (
+	MetadataKey = "x-depscloud-subject"
+	HeaderKey   = "X-Depscloud-Subject"
+
+	// gatewayHeaderKey is forwarded as metadata by the grpc-gateway.
+	gatewayHeaderKey = "Grpc-Metadata-X-Depscloud-Subject"
+)
+
+// Bearer tokens are passed using the standard authorization metadata and
+// header. The grpc-gateway forwards the header as metadata.
+const (
+	AuthorizationMetadataKey = "authorization"
+	AuthorizationHeaderKey   = "Authorization"
+
+	bearerPrefix = "Bearer "
+)
+
+// RoutePrefix prefixes the HTTP routes used to manage role bindings.
+const RoutePrefix = "/v1alpha/rbac/"
+
+// Modes determine how the subject of a caller is identified.
+const (
+	// ModeNone disables role based access control.
+	ModeNone = "none"
+	// ModeCertificate uses the common name of the verified client
+	// certificate. Trusted proxies may act on behalf of another subject
+	// using metadata.
+	ModeCertificate = "certificate"
+	// ModeMetadata trusts the subject provided in metadata. It's meant for
+	// deployments behind a proxy that authenticates callers.
+	ModeMetadata = "metadata"
+)
+
+// Role grants a set of permissions. Each role includes the permissions of the
+// roles before it.
+type Role string
+
+// Roles, from least to most privileged.
+const (
+	// RoleNone grants nothing.
+	RoleNone Role = "none"
+	// RoleViewer may read the graph.
+	RoleViewer Role = "viewer"
+	// RoleEditor may also track sources and write to the graph. It's meant
+	// for indexers.
+	RoleEditor Role = "editor"
+	// RoleAdmin may also manage role bindings, tombstones, snapshots, and
+	// other administrative operations.
+	RoleAdmin Role = "admin"
+)
+
+var ranks = map[Role]int{
+	RoleNone:   0,
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// ParseRole returns the role with the name.
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if _, ok := ranks[role]; !ok {
+		return "", fmt.Errorf("unsupported role %s, specify one of none/viewer/editor/admin", name)
+	}
+	return role, nil
+}
+
+// Includes returns true when the role grants the permissions of the other.
+func (r Role) Includes(other Role) bool {
+	return ranks[r] >= ranks[other]
+}
+
+// Binding grants a role to a subject. Bindings can be narrowed to a tenant
+// and to the modules of an organization. Empty fields match everything.
+type Binding struct {
+	Subject      string `json:"subject"`
+	Role         Role   `json:"role"`
+	Tenant       string `json:"tenant,omitempty"`
+	Organization string `json:"organization,omitempty"`
+}
+
+// Validate ensures the binding names a subject and a supported role.
+func (b *Binding) Validate() error {
+	if b.Subject == "" {
+		return fmt.Errorf("subject is required")
+	}
+
+	_, err := ParseRole(string(b.Role))
+	return err
+}
+
+func (b *Binding) matches(subject, tenant, organization string) bool {
+	return b.Subject == subject &&
+		(b.Tenant == "" || b.Tenant == tenant) &&
+		(b.Organization == "" || b.Organization == organization)
+}
+
+// Credential is the subject and scope of a verified bearer token. A token
+// grants its role in place of the bindings of its subject, only for the
+// modules of its organization when one is set. The token id identifies the
+// token for quotas, when the verifier knows it.
+type Credential struct {
+	Subject      string `json:"subject"`
+	Role         Role   `json:"role"`
+	Organization string `json:"organization,omitempty"`
+	TokenID      string `json:"tokenId,omitempty"`
+}
+
+func (c *Credential) role(organization string) Role {
+	if c.Organization != "" && c.Organization != organization {
+		return RoleNone
+	}
+	return c.Role
+}
+
+// Verifier verifies a bearer token presented within a tenant. Tokens the
+// verifier doesn't recognize, such as those checked by a proxy in front of the
+// service, return no credential and no error.
+type Verifier func(ctx context.Context, tenant, token string) (*Credential, error)
+
+// Config controls how callers are identified and which roles they're granted.
+type Config struct {
+	Mode            string
+	DefaultRole     string
+	TrustedProxies  *cli.StringSlice
+	BindingsFile    string
+	RefreshInterval time.Duration
+}
+
+// WithFlags returns the flags used to configure role based access control.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	if cfg.TrustedProxies == nil {
+		cfg.TrustedProxies = cli.NewStringSlice()
+	}
+
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "rbac",
+			Usage:       "how the subject of callers is identified; none, certificate, or metadata",
+			Value:       cfg.Mode,
+			Destination: &(cfg.Mode),
+			EnvVars:     []string{"RBAC"},
+		},
+		&cli.StringFlag{
+			Name:        "rbac-default-role",
+			Usage:       "the role of every caller, including anonymous ones; none, viewer, editor, or admin",
+			Value:       cfg.DefaultRole,
+			Destination: &(cfg.DefaultRole),
+			EnvVars:     []string{"RBAC_DEFAULT_ROLE"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "rbac-trusted-proxy",
+			Usage:       "the common name of a client certificate allowed to act on behalf of other subjects",
+			Destination: cfg.TrustedProxies,
+			EnvVars:     []string{"RBAC_TRUSTED_PROXIES"},
+		},
+		&cli.StringFlag{
+			Name:        "rbac-bindings-file",
+			Usage:       "path to a yaml file of role bindings that can't be changed using the api",
+			Value:       cfg.BindingsFile,
+			Destination: &(cfg.BindingsFile),
+			EnvVars:     []string{"RBAC_BINDINGS_FILE"},
+		},
+		&cli.DurationFlag{
+			Name:        "rbac-refresh-interval",
+			Usage:       "how long the role bindings of a tenant are cached",
+			Value:       cfg.RefreshInterval,
+			Destination: &(cfg.RefreshInterval),
+			EnvVars:     []string{"RBAC_REFRESH_INTERVAL"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// Enabled returns true when the roles of callers are enforced.
+func (c *Config) Enabled() bool {
+	return c != nil && c.Mode != "" && c.Mode != ModeNone
+}
+
+// Validate ensures the mode and default role are supported.
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case "", ModeNone, ModeCertificate, ModeMetadata:
+	default:
+		return fmt.Errorf("unsupported rbac mode %s, specify one of none/certificate/metadata", c.Mode)
+	}
+
+	if c.DefaultRole == "" {
+		return nil
+	}
+
+	_, err := ParseRole(c.DefaultRole)
+	return err
+}
+
+// resolve determines the subject of a caller from its verified certificates
+// and the subject it asserted. Callers without a subject are anonymous.
+func (c *Config) resolve(certificates []*x509.Certificate, asserted string) string {
+	if c.Mode == ModeMetadata {
+		return asserted
+	}
+
+	if len(certificates) == 0 {
+		return ""
+	}
+
+	commonName := certificates[0].Subject.CommonName
+	if asserted != "" && c.TrustedProxies != nil {
+		for _, proxy := range c.TrustedProxies.Value() {
+			if commonName == proxy {
+				return asserted
+			}
+		}
+	}
+
+	return commonName
+}
+
+// LoadBindingsFile loads the role bindings listed under the bindings key of a
+// yaml file.
+func LoadBindingsFile(yamlFile string) ([]*Binding, error) {
+	contents, err := ioutil.ReadFile(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+
+	file := struct {
+		Bindings []*Binding `json:"bindings"`
+	}{}
+
+	if err := yaml.Unmarshal(contents, &file); err != nil {
+		return nil, err
+	}
+
+	for _, binding := range file.Bindings {
+		if err := binding.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return file.Bindings, nil
+}
+
+// Source lists the role bindings managed through the api for a tenant.
+type Source func(ctx context.Context, tenant string) ([]*Binding, error)
+
+// BindingsResponse contains the role bindings of a tenant.
+type BindingsResponse struct {
+	Bindings []*Binding `json:"bindings"`
+}
+
+// HTTPSource lists the role bindings of a tenant using the http api of the
+// tracker.
+func HTTPSource(address string, client *http.Client) Source {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(ctx context.Context, tenant string) ([]*Binding, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(address, "/")+RoutePrefix+"bindings", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if tenant != "" {
+			req.Header.Set(tenants.HeaderKey, tenant)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("tracker responded with %s", resp.Status)
+		}
+
+		response := &BindingsResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return nil, err
+		}
+
+		return response.Bindings, nil
+	}
+}
+
+type cachedBindings struct {
+	bindings []*Binding
+	loadedAt time.Time
+}
+
+// Authorizer decides whether callers have the role required by a request.
+// The bindings managed through the api are cached per tenant.
+type Authorizer struct {
+	cfg         *Config
+	defaultRole Role
+	static      []*Binding
+	source      Source
+	verifier    Verifier
+
+	mu     sync.Mutex
+	cached map[string]*cachedBindings
+}
+
+// NewAuthorizer returns an authorizer granting the roles bound by the bindings
+// file and the source. The source may be nil.
+func NewAuthorizer(cfg *Config, source Source) (*Authorizer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	defaultRole := RoleViewer
+	if cfg.DefaultRole != "" {
+		defaultRole = Role(cfg.DefaultRole)
+	}
+
+	var static []*Binding
+	if cfg.BindingsFile != "" {
+		var err error
+		if static, err = LoadBindingsFile(cfg.BindingsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Authorizer{
+		cfg:         cfg,
+		defaultRole: defaultRole,
+		static:      static,
+		source:      source,
+		cached:      make(map[string]*cachedBindings),
+	}, nil
+}
+
+// WithVerifier identifies callers presenting a bearer token using the
+// verifier. The subject and scope of a verified token take precedence over
+// certificates and asserted subjects.
+func (a *Authorizer) WithVerifier(verifier Verifier) *Authorizer {
+	if a != nil {
+		a.verifier = verifier
+	}
+	return a
+}
+
+// Invalidate drops the cached bindings of the tenant so changes made through
+// the api apply to the next request.
+func (a *Authorizer) Invalidate(tenant string) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.cached, tenant)
+}
+
+// bindings returns the bindings of the tenant. When the source fails, the
+// previously loaded bindings continue to be used.
+func (a *Authorizer) bindings(ctx context.Context, tenant string) []*Binding {
+	if a.source == nil {
+		return a.static
+	}
+
+	a.mu.Lock()
+	cached := a.cached[tenant]
+	a.mu.Unlock()
+
+	if cached != nil && time.Since(cached.loadedAt) < a.cfg.RefreshInterval {
+		return a.withStatic(cached.bindings)
+	}
+
+	loaded, err := a.source(ctx, tenant)
+	if err != nil {
+		logrus.Errorf("[rbac] failed to load bindings for tenant %q: %s", tenant, err.Error())
+		if cached == nil {
+			return a.static
+		}
+		return a.withStatic(cached.bindings)
+	}
+
+	a.mu.Lock()
+	a.cached[tenant] = &cachedBindings{bindings: loaded, loadedAt: time.Now()}
+	a.mu.Unlock()
+
+	return a.withStatic(loaded)
+}
+
+func (a *Authorizer) withStatic(bindings []*Binding) []*Binding {
+	all := make([]*Binding, 0, len(a.static)+len(bindings))
+	return append(append(all, a.static...), bindings...)
+}
+
+// Role returns the most privileged role granted to the subject within the
+// tenant for the modules of the organization.
+func (a *Authorizer) Role(ctx context.Context, subject, tenant, organization string) Role {
+	role := a.defaultRole
+	if subject == "" {
+		return role
+	}
+
+	for _, binding := range a.bindings(ctx, tenant) {
+		if binding.matches(subject, tenant, organization) && !role.Includes(binding.Role) {
+			role = binding.Role
+		}
+	}
+	return role
+}
+
+// Authorize returns a PermissionDenied error when the subject of the request
+// isn't granted the required role for the modules of the organization. It
+// must run after the tenant and subject of the request are resolved.
+// Disabled configs allow everything.
+func (a *Authorizer) Authorize(ctx context.Context, required Role, organization string) error {
+	if a == nil || !a.cfg.Enabled() {
+		return nil
+	}
+
+	subject := FromContext(ctx)
+
+	var role Role
+	if credential := CredentialFromContext(ctx); credential != nil {
+		role = credential.role(organization)
+		if !role.Includes(a.defaultRole) {
+			role = a.defaultRole
+		}
+	} else {
+		role = a.Role(ctx, subject, tenants.FromContext(ctx), organization)
+	}
+
+	if role.Includes(required) {
+		return nil
+	}
+
+	if subject == "" {
+		return status.Errorf(codes.PermissionDenied, "anonymous callers aren't granted the %s role", required)
+	}
+	return status.Errorf(codes.PermissionDenied, "%s isn't granted the %s role", subject, required)
+}
+
+// health checks, metrics, and token verification are never subject to roles
+var exempt = map[string]bool{
+	"/grpc.health.v1.Health/Check":           true,
+	"/grpc.health.v1.Health/Watch":           true,
+	"/health":                                true,
+	"/healthz":                               true,
+	"/readyz":                                true,
+	"/metrics":                               true,
+	"/version":                               true,
+	"/v1alpha/serviceaccounts/tokens/verify": true,
+}
+
+// editorMethods write to the graph. Every other method only reads it.
+var editorMethods = map[string]bool{
+	"/cloud.deps.api.v1alpha.tracker.SourceService/Track": true,
+	"/cloud.deps.api.v1alpha.store.GraphStore/Put":        true,
+	"/cloud.deps.api.v1alpha.store.GraphStore/Delete":     true,
+	"/depscloud.api.v1beta.ManifestStorageService/Store":  true,
+	"/graphstore.api.v1beta.GraphStore/Put":               true,
+	"/graphstore.api.v1beta.GraphStore/Delete":            true,
+}
+
+// adminRoutes are the http routes whose writes are administrative.
+var adminRoutes = []string{
+	RoutePrefix,
+	"/v1alpha/admin/",
+	"/v1alpha/graph/",
+	"/v1alpha/tombstones/",
+	"/v1alpha/webhooks/",
+}
+
+// restrictedRoutes are the http routes that only admins can read.
+var restrictedRoutes = []string{
+	"/v1alpha/admin/quotas",
+	"/v1alpha/audit/",
+	"/v1alpha/serviceaccounts/",
+}
+
+// RequiredForMethod returns the role required to call the grpc method.
+func RequiredForMethod(method string) Role {
+	switch {
+	case exempt[method]:
+		return RoleNone
+	case editorMethods[method]:
+		return RoleEditor
+	}
+	return RoleViewer
+}
+
+// RequiredForRequest returns the role required to make the http request.
+// Queries are read using POST, but other writes require an editor, or an
+// admin for administrative routes. Restricted routes, like the audit log and
+// service accounts, require an admin to read them as well.
+func RequiredForRequest(r *http.Request) Role {
+	if exempt[r.URL.Path] {
+		return RoleNone
+	}
+
+	for _, prefix := range restrictedRoutes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return RoleAdmin
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RoleViewer
+	case http.MethodPost:
+		if strings.HasPrefix(r.URL.Path, "/v1alpha/queries/") {
+			return RoleViewer
+		}
+	}
+
+	for _, prefix := range adminRoutes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return RoleAdmin
+		}
+	}
+	return RoleEditor
+}
+
+// organizationOf returns the organization of a grpc request, if it has one.
+func organizationOf(req interface{}) string {
+	if req, ok := req.(interface{ GetOrganization() string }); ok {
+		return req.GetOrganization()
+	}
+	return ""
+}
+
+// verify returns the credential of the bearer token in the authorization
+// value. No credential is returned without a verifier or a bearer token.
+func (a *Authorizer) verify(ctx context.Context, authorization string) (*Credential, error) {
+	if a.verifier == nil || !strings.HasPrefix(authorization, bearerPrefix) {
+		return nil, nil
+	}
+
+	credential, err := a.verifier(ctx, tenants.FromContext(ctx), strings.TrimPrefix(authorization, bearerPrefix))
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %s", err.Error())
+	}
+	return credential, nil
+}
+
+func (a *Authorizer) resolveContext(ctx context.Context) (context.Context, error) {
+	credential, err := a.verify(ctx, requestmeta.First(ctx, AuthorizationMetadataKey))
+	if err != nil {
+		return nil, err
+	} else if credential != nil {
+		return newCredentialContext(NewContext(ctx, credential.Subject), credential), nil
+	}
+
+	var certificates []*x509.Certificate
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			certificates = info.State.PeerCertificates
+		}
+	}
+
+	return NewContext(ctx, a.cfg.resolve(certificates, FromIncomingContext(ctx))), nil
+}
+
+// UnaryServerInterceptor resolves the subject of each call and rejects those
+// without the role required by the method. It must run after the tenant of
+// the call is resolved.
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.resolveContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := a.Authorize(ctx, RequiredForMethod(info.FullMethod), organizationOf(req)); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+type subjectStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *subjectStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor resolves the subject of each stream and rejects
+// those without the role required by the method. Streams aren't narrowed to
+// an organization.
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.resolveContext(ss.Context())
+		if err != nil {
+			return err
+		}
+
+		if err := a.Authorize(ctx, RequiredForMethod(info.FullMethod), ""); err != nil {
+			return err
+		}
+		return handler(srv, &subjectStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// ServerOptions returns the interceptors that enforce roles. No options are
+// returned when role based access control is disabled. When subjects are
+// identified by certificate, the credentials for the provided TLS
+// configuration are included.
+func (a *Authorizer) ServerOptions(tlsConfig *tls.Config) ([]grpc.ServerOption, error) {
+	if a == nil || !a.cfg.Enabled() {
+		return nil, nil
+	}
+
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(a.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(a.StreamServerInterceptor()),
+	}
+
+	if a.cfg.Mode != ModeCertificate {
+		return options, nil
+	} else if tlsConfig == nil {
+		return nil, fmt.Errorf("certificate rbac requires TLS")
+	}
+
+	return append(options, grpc.Creds(credentials.NewTLS(tlsConfig))), nil
+}
+
+// Middleware resolves the subject of each http request and rejects those
+// without the role required by the route. The organization is read from the
+// organization parameter. The resolved subject replaces any the caller
+// provided in the request headers so it's passed along when the request is
+// proxied. Bearer tokens are passed along as well so the backend can verify
+// their scope. It must run after the tenant of the request is resolved.
+func (a *Authorizer) Middleware(next http.Handler) http.Handler {
+	if a == nil || !a.cfg.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		credential, err := a.verify(ctx, r.Header.Get(AuthorizationHeaderKey))
+		if err != nil {
+			http.Error(w, status.Convert(err).Message(), http.StatusUnauthorized)
+			return
+		}
+
+		var subject string
+		if credential != nil {
+			subject = credential.Subject
+			ctx = newCredentialContext(ctx, credential)
+		} else {
+			var certificates []*x509.Certificate
+			if r.TLS != nil {
+				certificates = r.TLS.PeerCertificates
+			}
+
+			subject = a.cfg.resolve(certificates, r.Header.Get(HeaderKey))
+		}
+
+		r.Header.Del(HeaderKey)
+		r.Header.Del(gatewayHeaderKey)
+		if subject != "" {
+			r.Header.Set(HeaderKey, subject)
+			r.Header.Set(gatewayHeaderKey, subject)
+		}
+
+		ctx = NewContext(ctx, subject)
+		if err := a.Authorize(ctx, RequiredForRequest(r), r.URL.Query().Get("organization")); err != nil {
+			http.Error(w, status.Convert(err).Message(), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type contextKey struct{}
+
+type credentialContextKey struct{}
+
+func newCredentialContext(ctx context.Context, credential *Credential) context.Context {
+	return context.WithValue(ctx, credentialContextKey{}, credential)
+}
+
+// CredentialFromContext returns the credential of the bearer token the request
+// was made with. Requests without a verified token have no credential.
+func CredentialFromContext(ctx context.Context) *Credential {
+	credential, _ := ctx.Value(credentialContextKey{}).(*Credential)
+	return credential
+}
+
+// NewContext returns a context for the resolved subject.
+func NewContext(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, contextKey{}, subject)
+}
+
+// FromContext returns the subject of the request. The subject resolved by
+// this service is preferred, otherwise the subject passed by the calling
+// service is used. Anonymous callers have no subject.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if subject, ok := ctx.Value(contextKey{}).(string); ok {
+		return subject
+	}
+
+	return FromIncomingContext(ctx)
+}
+
+// FromIncomingContext returns the subject passed in the request metadata.
+func FromIncomingContext(ctx context.Context) string {
+	return requestmeta.First(ctx, MetadataKey)
+}
+
+// ForwardContext passes the subject and bearer token of the request along to
+// the backend.
+func ForwardContext(ctx context.Context) context.Context {
+	ctx = requestmeta.Forward(ctx, AuthorizationMetadataKey)
+	return requestmeta.Append(ctx, MetadataKey, FromContext(ctx))
+}
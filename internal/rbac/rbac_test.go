@@ -0,0 +1,306 @@
+package rbac_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const bindingsFile = `
+bindings:
+  - subject: indexer
+    role: editor
+  - subject: alice
+    role: admin
+    tenant: payments
+`
+
+func authorizer(t *testing.T, cfg *rbac.Config, source rbac.Source) *rbac.Authorizer {
+	dir, err := ioutil.TempDir("", "rbac")
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	cfg.BindingsFile = filepath.Join(dir, "bindings.yaml")
+	require.Nil(t, ioutil.WriteFile(cfg.BindingsFile, []byte(bindingsFile), 0644))
+
+	a, err := rbac.NewAuthorizer(cfg, source)
+	require.Nil(t, err)
+	return a
+}
+
+func TestRoles(t *testing.T) {
+	require.True(t, rbac.RoleAdmin.Includes(rbac.RoleEditor))
+	require.True(t, rbac.RoleEditor.Includes(rbac.RoleEditor))
+	require.False(t, rbac.RoleViewer.Includes(rbac.RoleEditor))
+
+	_, err := rbac.ParseRole("owner")
+	require.NotNil(t, err)
+
+	require.NotNil(t, (&rbac.Binding{Role: rbac.RoleViewer}).Validate())
+	require.NotNil(t, (&rbac.Binding{Subject: "bob", Role: "owner"}).Validate())
+	require.NotNil(t, (&rbac.Config{Mode: "oauth"}).Validate())
+	require.NotNil(t, (&rbac.Config{DefaultRole: "owner"}).Validate())
+}
+
+func TestAuthorize(t *testing.T) {
+	calls := 0
+	source := func(ctx context.Context, tenant string) ([]*rbac.Binding, error) {
+		calls++
+		return []*rbac.Binding{
+			{Subject: "bob", Role: rbac.RoleEditor, Tenant: tenant, Organization: "depscloud"},
+		}, nil
+	}
+
+	a := authorizer(t, &rbac.Config{Mode: rbac.ModeMetadata, RefreshInterval: time.Minute}, source)
+
+	authorize := func(tenant, subject string, required rbac.Role, organization string) codes.Code {
+		ctx := rbac.NewContext(tenants.NewContext(context.Background(), tenant), subject)
+		return status.Code(a.Authorize(ctx, required, organization))
+	}
+
+	// everyone is a viewer by default
+	require.Equal(t, codes.OK, authorize("", "", rbac.RoleViewer, ""))
+	require.Equal(t, codes.PermissionDenied, authorize("", "", rbac.RoleEditor, ""))
+
+	require.Equal(t, codes.OK, authorize("", "indexer", rbac.RoleEditor, "depscloud"))
+	require.Equal(t, codes.PermissionDenied, authorize("", "indexer", rbac.RoleAdmin, ""))
+
+	// bindings narrowed to a tenant or organization only apply within it
+	require.Equal(t, codes.OK, authorize("payments", "alice", rbac.RoleAdmin, ""))
+	require.Equal(t, codes.PermissionDenied, authorize("search", "alice", rbac.RoleAdmin, ""))
+	require.Equal(t, codes.OK, authorize("payments", "bob", rbac.RoleEditor, "depscloud"))
+	require.Equal(t, codes.PermissionDenied, authorize("payments", "bob", rbac.RoleEditor, "mjpitz"))
+	require.Equal(t, codes.PermissionDenied, authorize("payments", "bob", rbac.RoleEditor, ""))
+
+	// bindings are cached per tenant until they're invalidated
+	require.Equal(t, 3, calls)
+	a.Invalidate("payments")
+	require.Equal(t, codes.OK, authorize("payments", "bob", rbac.RoleEditor, "depscloud"))
+	require.Equal(t, 4, calls)
+
+	// disabled configs allow everything
+	disabled := authorizer(t, &rbac.Config{Mode: rbac.ModeNone, DefaultRole: string(rbac.RoleNone)}, nil)
+	require.Nil(t, disabled.Authorize(context.Background(), rbac.RoleAdmin, ""))
+}
+
+func TestRequired(t *testing.T) {
+	require.Equal(t, rbac.RoleEditor, rbac.RequiredForMethod("/cloud.deps.api.v1alpha.tracker.SourceService/Track"))
+	require.Equal(t, rbac.RoleViewer, rbac.RequiredForMethod("/cloud.deps.api.v1alpha.tracker.SourceService/List"))
+	require.Equal(t, rbac.RoleNone, rbac.RequiredForMethod("/grpc.health.v1.Health/Check"))
+
+	required := func(method, path string) rbac.Role {
+		return rbac.RequiredForRequest(httptest.NewRequest(method, path, nil))
+	}
+
+	require.Equal(t, rbac.RoleViewer, required(http.MethodGet, "/v1alpha/graph/export"))
+	require.Equal(t, rbac.RoleViewer, required(http.MethodPost, "/v1alpha/queries/evaluate"))
+	require.Equal(t, rbac.RoleEditor, required(http.MethodPut, "/v1alpha/labels/modules"))
+	require.Equal(t, rbac.RoleAdmin, required(http.MethodPost, "/v1alpha/graph/import"))
+	require.Equal(t, rbac.RoleAdmin, required(http.MethodPut, "/v1alpha/rbac/bindings"))
+	require.Equal(t, rbac.RoleAdmin, required(http.MethodGet, "/v1alpha/audit/records"))
+	require.Equal(t, rbac.RoleAdmin, required(http.MethodGet, "/v1alpha/serviceaccounts/tokens"))
+	require.Equal(t, rbac.RoleAdmin, required(http.MethodGet, "/v1alpha/admin/quotas"))
+	require.Equal(t, rbac.RoleNone, required(http.MethodPost, "/v1alpha/serviceaccounts/tokens/verify"))
+	require.Equal(t, rbac.RoleNone, required(http.MethodGet, "/healthz"))
+}
+
+func TestMiddleware(t *testing.T) {
+	a := authorizer(t, &rbac.Config{
+		Mode:           rbac.ModeCertificate,
+		DefaultRole:    string(rbac.RoleViewer),
+		TrustedProxies: cli.NewStringSlice("gateway"),
+	}, nil)
+
+	serve := func(method, path, commonName, asserted string) (int, string) {
+		subject := ""
+		handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject = rbac.FromContext(r.Context())
+			require.Equal(t, subject, r.Header.Get(rbac.HeaderKey))
+		}))
+
+		request := httptest.NewRequest(method, path, nil)
+		if commonName != "" {
+			request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+				{Subject: pkix.Name{CommonName: commonName}},
+			}}
+		}
+		if asserted != "" {
+			request.Header.Set(rbac.HeaderKey, asserted)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code, subject
+	}
+
+	code, subject := serve(http.MethodGet, "/v1alpha/labels/modules", "", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "", subject)
+
+	code, _ = serve(http.MethodPut, "/v1alpha/labels/modules", "", "indexer")
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, subject = serve(http.MethodPut, "/v1alpha/labels/modules", "indexer", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "indexer", subject)
+
+	// untrusted callers can't act on behalf of another subject
+	code, _ = serve(http.MethodPut, "/v1alpha/labels/modules", "bob", "indexer")
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, subject = serve(http.MethodPut, "/v1alpha/labels/modules", "gateway", "indexer")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "indexer", subject)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	a := authorizer(t, &rbac.Config{Mode: rbac.ModeCertificate}, nil)
+	interceptor := a.UnaryServerInterceptor()
+
+	call := func(commonName, method string, req interface{}) (string, error) {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+				{Subject: pkix.Name{CommonName: commonName}},
+			}}},
+		})
+
+		subject := ""
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			subject = rbac.FromContext(ctx)
+			return nil, nil
+		})
+		return subject, err
+	}
+
+	subject, err := call("indexer", "/cloud.deps.api.v1alpha.tracker.SourceService/Track", &tracker.SourceRequest{})
+	require.Nil(t, err)
+	require.Equal(t, "indexer", subject)
+
+	_, err = call("bob", "/cloud.deps.api.v1alpha.tracker.SourceService/Track", &tracker.SourceRequest{})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = call("bob", "/cloud.deps.api.v1alpha.tracker.DependencyService/ListDependents", &tracker.DependencyRequest{Organization: "depscloud"})
+	require.Nil(t, err)
+}
+
+func TestVerifier(t *testing.T) {
+	a := authorizer(t, &rbac.Config{Mode: rbac.ModeCertificate}, nil).
+		WithVerifier(func(ctx context.Context, tenant, token string) (*rbac.Credential, error) {
+			switch token {
+			case "ci":
+				return &rbac.Credential{Subject: "serviceaccount:ci", Role: rbac.RoleEditor, Organization: "depscloud"}, nil
+			case "revoked":
+				return nil, fmt.Errorf("token was revoked")
+			}
+			return nil, nil
+		})
+
+	serve := func(method, path, commonName, authorization string) (int, string) {
+		subject := ""
+		handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject = rbac.FromContext(r.Context())
+		}))
+
+		request := httptest.NewRequest(method, path, nil)
+		if commonName != "" {
+			request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+				{Subject: pkix.Name{CommonName: commonName}},
+			}}
+		}
+		request.Header.Set(rbac.AuthorizationHeaderKey, authorization)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code, subject
+	}
+
+	// tokens are only granted their role within their organization
+	code, subject := serve(http.MethodPut, "/v1alpha/labels/modules?organization=depscloud", "", "Bearer ci")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "serviceaccount:ci", subject)
+
+	code, _ = serve(http.MethodPut, "/v1alpha/labels/modules?organization=github.com", "", "Bearer ci")
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, _ = serve(http.MethodGet, "/v1alpha/labels/modules", "", "Bearer ci")
+	require.Equal(t, http.StatusOK, code)
+
+	// the token takes precedence over the certificate of the caller
+	code, _ = serve(http.MethodPost, "/v1alpha/graph/import?organization=depscloud", "indexer", "Bearer ci")
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, _ = serve(http.MethodGet, "/v1alpha/labels/modules", "", "Bearer revoked")
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	// unrecognized tokens fall back to the certificate
+	code, subject = serve(http.MethodPut, "/v1alpha/labels/modules", "indexer", "Bearer oidc")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "indexer", subject)
+
+	interceptor := a.UnaryServerInterceptor()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(rbac.AuthorizationMetadataKey, "Bearer revoked"))
+	_, err := interceptor(ctx, &tracker.SourceRequest{}, &grpc.UnaryServerInfo{FullMethod: "/cloud.deps.api.v1alpha.tracker.SourceService/List"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestForwardContext(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(rbac.AuthorizationMetadataKey, "Bearer ci"))
+	ctx = rbac.ForwardContext(rbac.NewContext(ctx, "indexer"))
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, []string{"indexer"}, md.Get(rbac.MetadataKey))
+	require.Equal(t, []string{"Bearer ci"}, md.Get(rbac.AuthorizationMetadataKey))
+
+	incoming := metadata.NewIncomingContext(context.Background(), md)
+	require.Equal(t, "indexer", rbac.FromContext(incoming))
+}
+
+func TestHTTPSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1alpha/rbac/bindings" || r.Header.Get(tenants.HeaderKey) != "payments" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(&rbac.BindingsResponse{Bindings: []*rbac.Binding{
+			{Subject: "bob", Role: rbac.RoleAdmin, Tenant: "payments"},
+		}})
+	}))
+	defer server.Close()
+
+	bindings, err := rbac.HTTPSource(server.URL+"/", nil)(context.Background(), "payments")
+	require.Nil(t, err)
+	require.Len(t, bindings, 1)
+	require.Equal(t, "bob", bindings[0].Subject)
+
+	_, err = rbac.HTTPSource(server.URL, nil)(context.Background(), "search")
+	require.NotNil(t, err)
+}
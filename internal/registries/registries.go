@@ -0,0 +1,408 @@
+package registries
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/internal/constraints"
+)
+
+// The labels the registry enrichment records on modules. Deprecated modules
+// are labeled so they can be found using the selector registry.deprecated=true.
+const (
+	LabelPrefix     = "registry."
+	LabelLatest     = LabelPrefix + "latest"
+	LabelPublished  = LabelPrefix + "published"
+	LabelDeprecated = LabelPrefix + "deprecated"
+)
+
+// Metadata describes the latest release of a module published to a registry.
+// Registries that don't publish deprecations never mark a module as
+// deprecated.
+type Metadata struct {
+	Latest     string     `json:"latest"`
+	Published  *time.Time `json:"published,omitempty"`
+	Deprecated bool       `json:"deprecated,omitempty"`
+}
+
+// Labels encodes the metadata as the labels of a module.
+func (m *Metadata) Labels() map[string]string {
+	labels := map[string]string{LabelLatest: m.Latest}
+	if m.Published != nil {
+		labels[LabelPublished] = m.Published.UTC().Format(time.RFC3339)
+	}
+	if m.Deprecated {
+		labels[LabelDeprecated] = "true"
+	}
+	return labels
+}
+
+// FromLabels reads the metadata recorded in the labels of a module, or nil
+// when the module hasn't been enriched.
+func FromLabels(labels map[string]string) *Metadata {
+	latest, ok := labels[LabelLatest]
+	if !ok {
+		return nil
+	}
+
+	metadata := &Metadata{
+		Latest:     latest,
+		Deprecated: labels[LabelDeprecated] == "true",
+	}
+
+	if published, err := time.Parse(time.RFC3339, labels[LabelPublished]); err == nil {
+		metadata.Published = &published
+	}
+	return metadata
+}
+
+// registry looks up the metadata of the modules published to it.
+type registry struct {
+	baseURL string
+	path    func(module *schema.Module) string
+	parse   func(data []byte) (*Metadata, error)
+}
+
+// packageName returns the name a module is published under.
+func packageName(module *schema.Module) string {
+	switch {
+	case module.GetName() != "":
+		return module.GetName()
+	case module.GetOrganization() == "" || module.GetOrganization() == "_":
+		return module.GetModule()
+	}
+	return module.GetOrganization() + "/" + module.GetModule()
+}
+
+// parseTime parses a timestamp, returning nil when it can't be read.
+func parseTime(layout, value string) *time.Time {
+	parsed, err := time.Parse(layout, value)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+// registries are keyed by the language of the modules they publish.
+var registries = map[string]*registry{
+	"go": {
+		baseURL: "https://proxy.golang.org",
+		path: func(module *schema.Module) string {
+			return "/" + escapeGoPath(packageName(module)) + "/@latest"
+		},
+		parse: func(data []byte) (*Metadata, error) {
+			response := struct {
+				Version string    `json:"Version"`
+				Time    time.Time `json:"Time"`
+			}{}
+			if err := json.Unmarshal(data, &response); err != nil {
+				return nil, err
+			}
+			metadata := &Metadata{Latest: response.Version}
+			if !response.Time.IsZero() {
+				metadata.Published = &response.Time
+			}
+			return metadata, nil
+		},
+	},
+	"node": {
+		baseURL: "https://registry.npmjs.org",
+		path: func(module *schema.Module) string {
+			return "/" + strings.Replace(packageName(module), "/", "%2f", 1)
+		},
+		parse: func(data []byte) (*Metadata, error) {
+			response := struct {
+				DistTags struct {
+					Latest string `json:"latest"`
+				} `json:"dist-tags"`
+				Time     map[string]string `json:"time"`
+				Versions map[string]struct {
+					Deprecated interface{} `json:"deprecated"`
+				} `json:"versions"`
+			}{}
+			if err := json.Unmarshal(data, &response); err != nil {
+				return nil, err
+			}
+
+			latest := response.DistTags.Latest
+			deprecated := response.Versions[latest].Deprecated
+			return &Metadata{
+				Latest:     latest,
+				Published:  parseTime(time.RFC3339, response.Time[latest]),
+				Deprecated: deprecated != nil && deprecated != false && deprecated != "",
+			}, nil
+		},
+	},
+	"python": {
+		baseURL: "https://pypi.org/pypi",
+		path: func(module *schema.Module) string {
+			return "/" + url.PathEscape(packageName(module)) + "/json"
+		},
+		parse: func(data []byte) (*Metadata, error) {
+			response := struct {
+				Info struct {
+					Version     string   `json:"version"`
+					Classifiers []string `json:"classifiers"`
+				} `json:"info"`
+				URLs []struct {
+					UploadTime string `json:"upload_time_iso_8601"`
+				} `json:"urls"`
+			}{}
+			if err := json.Unmarshal(data, &response); err != nil {
+				return nil, err
+			}
+
+			metadata := &Metadata{Latest: response.Info.Version}
+			if len(response.URLs) > 0 {
+				metadata.Published = parseTime(time.RFC3339, response.URLs[0].UploadTime)
+			}
+
+			// python packages are retired by marking them inactive
+			for _, classifier := range response.Info.Classifiers {
+				if classifier == "Development Status :: 7 - Inactive" {
+					metadata.Deprecated = true
+				}
+			}
+			return metadata, nil
+		},
+	},
+	"java": {
+		baseURL: "https://repo1.maven.org/maven2",
+		path: func(module *schema.Module) string {
+			return "/" + strings.Replace(module.GetOrganization(), ".", "/", -1) + "/" + module.GetModule() + "/maven-metadata.xml"
+		},
+		parse: func(data []byte) (*Metadata, error) {
+			response := struct {
+				Release     string `xml:"versioning>release"`
+				Latest      string `xml:"versioning>latest"`
+				LastUpdated string `xml:"versioning>lastUpdated"`
+			}{}
+			if err := xml.Unmarshal(data, &response); err != nil {
+				return nil, err
+			}
+
+			metadata := &Metadata{
+				Latest:    response.Release,
+				Published: parseTime("20060102150405", response.LastUpdated),
+			}
+			if metadata.Latest == "" {
+				metadata.Latest = response.Latest
+			}
+			return metadata, nil
+		},
+	},
+	"rust": {
+		baseURL: "https://crates.io/api/v1/crates",
+		path: func(module *schema.Module) string {
+			return "/" + url.PathEscape(packageName(module))
+		},
+		parse: func(data []byte) (*Metadata, error) {
+			response := struct {
+				Crate struct {
+					MaxStableVersion string `json:"max_stable_version"`
+					MaxVersion       string `json:"max_version"`
+				} `json:"crate"`
+				Versions []struct {
+					Num       string `json:"num"`
+					CreatedAt string `json:"created_at"`
+				} `json:"versions"`
+			}{}
+			if err := json.Unmarshal(data, &response); err != nil {
+				return nil, err
+			}
+
+			metadata := &Metadata{Latest: response.Crate.MaxStableVersion}
+			if metadata.Latest == "" {
+				metadata.Latest = response.Crate.MaxVersion
+			}
+
+			for _, version := range response.Versions {
+				if version.Num == metadata.Latest {
+					metadata.Published = parseTime(time.RFC3339, version.CreatedAt)
+				}
+			}
+			return metadata, nil
+		},
+	},
+	"php": {
+		baseURL: "https://repo.packagist.org/p2",
+		path: func(module *schema.Module) string {
+			return "/" + packageName(module) + ".json"
+		},
+		parse: func(data []byte) (*Metadata, error) {
+			response := struct {
+				Packages map[string][]struct {
+					Version   string      `json:"version"`
+					Time      string      `json:"time"`
+					Abandoned interface{} `json:"abandoned"`
+				} `json:"packages"`
+			}{}
+			if err := json.Unmarshal(data, &response); err != nil {
+				return nil, err
+			}
+
+			// versions are listed newest first, including pre-releases
+			for _, versions := range response.Packages {
+				for _, version := range versions {
+					if constraints.IsVersion(version.Version) && !strings.Contains(version.Version, "-") {
+						return &Metadata{
+							Latest:     version.Version,
+							Published:  parseTime(time.RFC3339, version.Time),
+							Deprecated: version.Abandoned != nil && version.Abandoned != false,
+						}, nil
+					}
+				}
+			}
+			return &Metadata{}, nil
+		},
+	},
+	"r": {
+		baseURL: "https://crandb.r-pkg.org",
+		path: func(module *schema.Module) string {
+			return "/" + url.PathEscape(packageName(module))
+		},
+		parse: func(data []byte) (*Metadata, error) {
+			response := struct {
+				Version     string `json:"Version"`
+				Publication string `json:"Date/Publication"`
+			}{}
+			if err := json.Unmarshal(data, &response); err != nil {
+				return nil, err
+			}
+			return &Metadata{
+				Latest:    response.Version,
+				Published: parseTime("2006-01-02 15:04:05 MST", response.Publication),
+			}, nil
+		},
+	},
+}
+
+// escapeGoPath escapes a module path for the module proxy protocol, where
+// upper case letters are replaced with an exclamation mark followed by the
+// letter in lower case.
+func escapeGoPath(path string) string {
+	escaped := strings.Builder{}
+	for _, r := range path {
+		if r >= 'A' && r <= 'Z' {
+			escaped.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// Supported returns true when a registry is known for the language.
+func Supported(language string) bool {
+	_, ok := registries[language]
+	return ok
+}
+
+// Languages returns the languages with a known registry, sorted by name.
+func Languages() []string {
+	languages := make([]string, 0, len(registries))
+	for language := range registries {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Client looks up the metadata of modules from the registries they're
+// published to.
+type Client struct {
+	Client *http.Client
+	// BaseURLs replace the public registry of a language, such as with an
+	// internal mirror.
+	BaseURLs map[string]string
+	// UserAgent identifies the client to the registries.
+	UserAgent string
+}
+
+// NewClient returns a client for the public registries, replacing those with
+// a base url.
+func NewClient(userAgent string, baseURLs map[string]string) *Client {
+	return &Client{
+		Client:    &http.Client{Timeout: 30 * time.Second},
+		BaseURLs:  baseURLs,
+		UserAgent: userAgent,
+	}
+}
+
+// ParseURLs reads the base urls of registries written as language=url.
+func ParseURLs(values []string) (map[string]string, error) {
+	baseURLs := make(map[string]string, len(values))
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("registry urls are written as language=url, got %q", value)
+		} else if !Supported(parts[0]) {
+			return nil, fmt.Errorf("no registry is known for %s", parts[0])
+		}
+		baseURLs[parts[0]] = strings.TrimSuffix(parts[1], "/")
+	}
+	return baseURLs, nil
+}
+
+// Lookup returns the metadata of the module's latest release. Nil is
+// returned when no registry is known for the module's language or the module
+// isn't published, as is the case for internal modules.
+func (c *Client) Lookup(ctx context.Context, module *schema.Module) (*Metadata, error) {
+	registry, ok := registries[module.GetLanguage()]
+	if !ok {
+		return nil, nil
+	}
+
+	baseURL := registry.baseURL
+	if override, ok := c.BaseURLs[module.GetLanguage()]; ok {
+		baseURL = override
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+registry.path(module), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// crates.io rejects requests without a user agent
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s responded with %s", req.URL.Host, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := registry.parse(data)
+	if err != nil {
+		return nil, err
+	} else if metadata.Latest == "" {
+		return nil, nil
+	}
+	return metadata, nil
+}
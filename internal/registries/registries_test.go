@@ -0,0 +1,130 @@
+package registries_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/internal/registries"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	published := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		module   *schema.Module
+		path     string
+		data     string
+		expected *registries.Metadata
+	}{
+		{
+			module:   &schema.Module{Language: "go", Organization: "github.com", Module: "BurntSushi/toml"},
+			path:     "/github.com/!burnt!sushi/toml/@latest",
+			data:     `{"Version":"v1.2.0","Time":"2020-10-01T12:00:00Z"}`,
+			expected: &registries.Metadata{Latest: "v1.2.0", Published: &published},
+		},
+		{
+			module:   &schema.Module{Language: "node", Organization: "@types", Module: "node"},
+			path:     "/@types%2fnode",
+			data:     `{"dist-tags":{"latest":"2.0.0"},"time":{"2.0.0":"2020-10-01T12:00:00Z"},"versions":{"1.0.0":{},"2.0.0":{"deprecated":"use something else"}}}`,
+			expected: &registries.Metadata{Latest: "2.0.0", Published: &published, Deprecated: true},
+		},
+		{
+			module:   &schema.Module{Language: "python", Module: "requests"},
+			path:     "/requests/json",
+			data:     `{"info":{"version":"3.1","classifiers":["Development Status :: 5 - Production/Stable"]},"urls":[{"upload_time_iso_8601":"2020-10-01T12:00:00Z"}]}`,
+			expected: &registries.Metadata{Latest: "3.1", Published: &published},
+		},
+		{
+			module:   &schema.Module{Language: "java", Organization: "org.slf4j", Module: "slf4j-api"},
+			path:     "/org/slf4j/slf4j-api/maven-metadata.xml",
+			data:     `<metadata><versioning><latest>2.0-SNAPSHOT</latest><release>1.9</release><lastUpdated>20201001120000</lastUpdated></versioning></metadata>`,
+			expected: &registries.Metadata{Latest: "1.9", Published: &published},
+		},
+		{
+			module:   &schema.Module{Language: "rust", Module: "serde"},
+			path:     "/serde",
+			data:     `{"crate":{"max_stable_version":"0.4.0","max_version":"0.5.0-beta"},"versions":[{"num":"0.5.0-beta","created_at":"2020-11-01T12:00:00Z"},{"num":"0.4.0","created_at":"2020-10-01T12:00:00Z"}]}`,
+			expected: &registries.Metadata{Latest: "0.4.0", Published: &published},
+		},
+		{
+			module:   &schema.Module{Language: "php", Organization: "a", Module: "b"},
+			path:     "/a/b.json",
+			data:     `{"packages":{"a/b":[{"version":"2.0.0-RC1"},{"version":"1.5.0","time":"2020-10-01T12:00:00+00:00","abandoned":"c/d"}]}}`,
+			expected: &registries.Metadata{Latest: "1.5.0", Published: &published, Deprecated: true},
+		},
+		{
+			module:   &schema.Module{Language: "r", Module: "ggplot2"},
+			path:     "/ggplot2",
+			data:     `{"Version":"3.3.2","Date/Publication":"2020-10-01 12:00:00 UTC"}`,
+			expected: &registries.Metadata{Latest: "3.3.2", Published: &published},
+		},
+	}
+
+	responses := make(map[string]string, len(testCases))
+	for _, testCase := range testCases {
+		responses[testCase.module.GetLanguage()+testCase.path] = testCase.data
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "depscloud-test", r.Header.Get("User-Agent"))
+
+		data, ok := responses[r.URL.EscapedPath()[1:]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	baseURLs := make(map[string]string)
+	for _, language := range registries.Languages() {
+		baseURLs[language] = server.URL + "/" + language
+	}
+
+	client := registries.NewClient("depscloud-test", baseURLs)
+	ctx := context.Background()
+
+	for _, testCase := range testCases {
+		language := testCase.module.GetLanguage()
+
+		metadata, err := client.Lookup(ctx, testCase.module)
+		require.Nil(t, err, language)
+		require.NotNil(t, metadata, language)
+		require.Equal(t, testCase.expected.Latest, metadata.Latest, language)
+		require.Equal(t, testCase.expected.Deprecated, metadata.Deprecated, language)
+		require.True(t, testCase.expected.Published.Equal(*metadata.Published), language)
+
+		// labels round trip the metadata
+		require.Equal(t, metadata.Labels(), registries.FromLabels(metadata.Labels()).Labels(), language)
+	}
+
+	// unpublished modules and unknown languages have no metadata
+	metadata, err := client.Lookup(ctx, &schema.Module{Language: "go", Organization: "internal", Module: "private"})
+	require.Nil(t, err)
+	require.Nil(t, metadata)
+
+	metadata, err = client.Lookup(ctx, &schema.Module{Language: "cobol", Module: "payroll"})
+	require.Nil(t, err)
+	require.Nil(t, metadata)
+
+	require.Nil(t, registries.FromLabels(map[string]string{"team": "platform"}))
+}
+
+func TestParseURLs(t *testing.T) {
+	baseURLs, err := registries.ParseURLs([]string{"go=https://goproxy.internal/"})
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"go": "https://goproxy.internal"}, baseURLs)
+
+	_, err = registries.ParseURLs([]string{"cobol=https://example.com"})
+	require.NotNil(t, err)
+
+	_, err = registries.ParseURLs([]string{"go"})
+	require.NotNil(t, err)
+}
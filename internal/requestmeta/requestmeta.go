@@ -0,0 +1,70 @@
+// Package requestmeta reads and forwards the metadata passed alongside tracker
+// requests.
+//
+// The tracker APIs can't be changed, so anything a request needs beyond its
+// message, such as paging, filters, the time to read the graph as of,
+// idempotency keys, partial updates, the tenant, and the subject, is passed
+// through request metadata under an x-depscloud-* key. Over HTTP, the
+// grpc-gateway passes these as Grpc-Metadata-* headers. Each package owning a
+// key parses it from the incoming context using First or Values, and proxies
+// pass it along to the backend using Forward. Values are forwarded as is, so
+// malformed values are still rejected by the backend.
+package requestmeta
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Get returns the first value of the key, or the empty string when there is
+// none. It's used to read response headers as well as request metadata.
+func Get(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Values returns every value of the key in the metadata of an incoming
+// request.
+func Values(ctx context.Context, key string) []string {
+	if ctx == nil {
+		return nil
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	return md.Get(key)
+}
+
+// First returns the first value of the key in the metadata of an incoming
+// request, or the empty string when there is none.
+func First(ctx context.Context, key string) string {
+	if values := Values(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Append attaches the value to requests made with the returned context. An
+// empty value leaves the context untouched.
+func Append(ctx context.Context, key, value string) context.Context {
+	if value == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, key, value)
+}
+
+// Forward copies the values of the keys in the metadata of an incoming request
+// onto the outgoing context, so they're passed along to the backend.
+func Forward(ctx context.Context, keys ...string) context.Context {
+	for _, key := range keys {
+		for _, value := range Values(ctx, key) {
+			ctx = Append(ctx, key, value)
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,56 @@
+package requestmeta_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/requestmeta"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFirst(t *testing.T) {
+	require.Equal(t, "", requestmeta.First(nil, "x-depscloud-tenant"))
+	require.Equal(t, "", requestmeta.First(context.Background(), "x-depscloud-tenant"))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-depscloud-removed-module", "language=go&module=a",
+		"x-depscloud-removed-module", "language=go&module=b",
+	))
+	require.Equal(t, "language=go&module=a", requestmeta.First(ctx, "x-depscloud-removed-module"))
+	require.Equal(t, []string{"language=go&module=a", "language=go&module=b"}, requestmeta.Values(ctx, "x-depscloud-removed-module"))
+	require.Empty(t, requestmeta.Values(ctx, "x-depscloud-tenant"))
+}
+
+func TestForward(t *testing.T) {
+	ctx := requestmeta.Forward(context.Background(), "x-depscloud-tenant")
+	_, ok := metadata.FromOutgoingContext(ctx)
+	require.False(t, ok)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-depscloud-tenant", "acme",
+		"x-depscloud-removed-module", "language=go&module=a",
+		"x-depscloud-removed-module", "language=go&module=b",
+		"x-depscloud-page-token", "",
+		"authorization", "secret",
+	))
+
+	md, ok := metadata.FromOutgoingContext(requestmeta.Forward(ctx, "x-depscloud-tenant", "x-depscloud-removed-module", "x-depscloud-page-token"))
+	require.True(t, ok)
+	require.Equal(t, metadata.Pairs(
+		"x-depscloud-tenant", "acme",
+		"x-depscloud-removed-module", "language=go&module=a",
+		"x-depscloud-removed-module", "language=go&module=b",
+	), md)
+}
+
+func TestAppend(t *testing.T) {
+	ctx := requestmeta.Append(context.Background(), "x-depscloud-tenant", "")
+	_, ok := metadata.FromOutgoingContext(ctx)
+	require.False(t, ok)
+
+	md, _ := metadata.FromOutgoingContext(requestmeta.Append(context.Background(), "x-depscloud-tenant", "acme"))
+	require.Equal(t, []string{"acme"}, md.Get("x-depscloud-tenant"))
+}
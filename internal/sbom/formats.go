@@ -0,0 +1,205 @@
+package sbom
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// https://cyclonedx.org/docs/1.4/json/
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp"`
+	Tools     []cdxTool     `json:"tools"`
+	Component *cdxComponent `json:"component"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+func cycloneDX(b *BOM, doc Document) *cdxBOM {
+	result := &cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: "urn:uuid:" + doc.ID,
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: doc.Created.Format(time.RFC3339),
+			Tools:     []cdxTool{{Name: doc.Tool, Version: doc.Version}},
+			Component: &cdxComponent{
+				Type: "application",
+				Name: b.Source,
+			},
+		},
+		Components:   make([]cdxComponent, 0, len(b.Components)),
+		Dependencies: make([]cdxDependency, 0, len(b.Dependencies)),
+	}
+
+	for _, c := range b.Components {
+		component := cdxComponent{
+			Type:    "library",
+			BOMRef:  c.Ref,
+			Name:    name(c.Module),
+			Version: c.Version,
+			PURL:    Purl(c.Module, c.Version),
+		}
+
+		if c.Constraint != "" {
+			component.Properties = []cdxProperty{{Name: "deps:version_constraint", Value: c.Constraint}}
+		}
+
+		result.Components = append(result.Components, component)
+
+		if dependsOn, ok := b.Dependencies[c.Ref]; ok {
+			result.Dependencies = append(result.Dependencies, cdxDependency{Ref: c.Ref, DependsOn: dependsOn})
+		}
+	}
+
+	return result
+}
+
+// https://spdx.github.io/spdx-spec/v2.3/
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Comment          string            `json:"comment,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+// noAssertion is used for the fields of a package the graph knows nothing
+// about.
+const noAssertion = "NOASSERTION"
+
+func spdx(b *BOM, doc Document) *spdxDocument {
+	tool := doc.Tool
+	if doc.Version != "" {
+		tool += "-" + doc.Version
+	}
+
+	result := &spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              b.Source,
+		DocumentNamespace: fmt.Sprintf("https://deps.cloud/spdx/%s-%s", url.PathEscape(b.Source), doc.ID),
+		CreationInfo: spdxCreationInfo{
+			Created:  doc.Created.Format(time.RFC3339),
+			Creators: []string{"Tool: " + tool},
+		},
+		Packages:      make([]spdxPackage, 0, len(b.Components)),
+		Relationships: make([]spdxRelationship, 0),
+	}
+
+	// spdx ids are limited to letters, numbers, dots, and dashes
+	ids := make(map[string]string, len(b.Components))
+	for i, c := range b.Components {
+		ids[c.Ref] = fmt.Sprintf("SPDXRef-Package-%d", i+1)
+	}
+
+	for _, c := range b.Components {
+		pkg := spdxPackage{
+			SPDXID:           ids[c.Ref],
+			Name:             name(c.Module),
+			VersionInfo:      c.Version,
+			DownloadLocation: noAssertion,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  noAssertion,
+			CopyrightText:    noAssertion,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  Purl(c.Module, c.Version),
+			}},
+		}
+
+		if c.Constraint != "" {
+			pkg.Comment = "version constraint: " + c.Constraint
+		}
+
+		result.Packages = append(result.Packages, pkg)
+
+		if dependsOn, ok := b.Dependencies[c.Ref]; ok {
+			result.Relationships = append(result.Relationships, spdxRelationship{
+				SPDXElementID:      "SPDXRef-DOCUMENT",
+				RelationshipType:   "DESCRIBES",
+				RelatedSPDXElement: ids[c.Ref],
+			})
+
+			for _, ref := range dependsOn {
+				result.Relationships = append(result.Relationships, spdxRelationship{
+					SPDXElementID:      ids[c.Ref],
+					RelationshipType:   "DEPENDS_ON",
+					RelatedSPDXElement: ids[ref],
+				})
+			}
+		}
+	}
+
+	return result
+}
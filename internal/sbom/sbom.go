@@ -0,0 +1,216 @@
+package sbom
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/internal/constraints"
+)
+
+// The formats an SBOM can be written in.
+const (
+	FormatCycloneDX = "cyclonedx"
+	FormatSPDX      = "spdx"
+)
+
+// ContentTypes are the media types of each format.
+var ContentTypes = map[string]string{
+	FormatCycloneDX: "application/vnd.cyclonedx+json",
+	FormatSPDX:      "application/spdx+json",
+}
+
+// purlTypes maps the languages known to the graph to their package url types.
+var purlTypes = map[string]string{
+	"go":     "golang",
+	"node":   "npm",
+	"js":     "npm",
+	"java":   "maven",
+	"python": "pypi",
+	"rust":   "cargo",
+	"php":    "composer",
+	"ruby":   "gem",
+	"dotnet": "nuget",
+}
+
+// name returns the name the module is published under.
+func name(module *schema.Module) string {
+	if module.Name != "" {
+		return module.Name
+	}
+	if module.Organization == "" || module.Organization == "_" {
+		return module.Module
+	}
+	return module.Organization + "/" + module.Module
+}
+
+// Purl returns the package url of the module, including the version when it's
+// known exactly.
+func Purl(module *schema.Module, version string) string {
+	name := name(module)
+
+	purlType, ok := purlTypes[module.Language]
+	if !ok {
+		purlType = "generic"
+	}
+
+	if purlType == "maven" {
+		name = strings.Replace(name, ":", "/", 1)
+	}
+
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		// @ separates the version, so it must be escaped within the name
+		segments[i] = strings.Replace(url.PathEscape(segment), "@", "%40", -1)
+	}
+
+	result := "pkg:" + purlType + "/" + strings.Join(segments, "/")
+	if version != "" {
+		result += "@" + url.PathEscape(version)
+	}
+	return result
+}
+
+// Component is a module within the SBOM. Versions are only known exactly when
+// a dependency pins one, otherwise the constraint is kept alongside it.
+type Component struct {
+	Ref        string
+	Module     *schema.Module
+	Version    string
+	Constraint string
+}
+
+// NewComponent returns the component for a dependency on the module with the
+// constraint. Modules that are managed by the source have no constraint.
+func NewComponent(module *schema.Module, constraint string) *Component {
+	c := &Component{Module: module}
+
+	if constraints.IsVersion(constraint) {
+		c.Version = constraint
+	} else {
+		c.Constraint = constraint
+	}
+
+	c.Ref = Purl(module, c.Version)
+	if c.Constraint != "" {
+		// the same module can be depended on with different constraints
+		c.Ref += "?constraint=" + url.QueryEscape(c.Constraint)
+	}
+	return c
+}
+
+// BOM is what the graph knows about a source: the modules it manages, and the
+// modules those depend on.
+type BOM struct {
+	Source       string
+	Components   []*Component
+	Dependencies map[string][]string
+
+	refs map[string]bool
+}
+
+// New returns an empty BOM for the source.
+func New(source string) *BOM {
+	return &BOM{
+		Source:       source,
+		Dependencies: make(map[string][]string),
+		refs:         make(map[string]bool),
+	}
+}
+
+// Add adds the component unless a component with the same ref was added.
+func (b *BOM) Add(c *Component) {
+	if !b.refs[c.Ref] {
+		b.refs[c.Ref] = true
+		b.Components = append(b.Components, c)
+	}
+}
+
+// DependsOn records the components the managed component depends on.
+func (b *BOM) DependsOn(root *Component, dependencies []*Component) {
+	b.Add(root)
+
+	refs := make([]string, 0, len(dependencies))
+	for _, c := range dependencies {
+		b.Add(c)
+		refs = append(refs, c.Ref)
+	}
+
+	sort.Strings(refs)
+	b.Dependencies[root.Ref] = refs
+}
+
+// Sort orders the components by their ref, so the output is stable.
+func (b *BOM) Sort() {
+	sort.SliceStable(b.Components, func(i, j int) bool {
+		return b.Components[i].Ref < b.Components[j].Ref
+	})
+}
+
+// uuid returns a random (version 4) uuid.
+func uuid() (string, error) {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	data[6] = (data[6] & 0x0f) | 0x40
+	data[8] = (data[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:]), nil
+}
+
+// Document identifies a generated SBOM.
+type Document struct {
+	ID      string
+	Created time.Time
+	Tool    string
+	Version string
+}
+
+// NewDocument identifies an SBOM generated now by the tool.
+func NewDocument(tool, version string) (Document, error) {
+	id, err := uuid()
+	if err != nil {
+		return Document{}, err
+	}
+
+	return Document{
+		ID:      id,
+		Created: time.Now().UTC(),
+		Tool:    tool,
+		Version: version,
+	}, nil
+}
+
+// Validate ensures the format is supported.
+func Validate(format string) error {
+	if format != FormatCycloneDX && format != FormatSPDX {
+		return fmt.Errorf("unsupported format %q, must be one of %s, %s", format, FormatCycloneDX, FormatSPDX)
+	}
+	return nil
+}
+
+// Write writes the BOM as json in the format.
+func Write(out io.Writer, format string, b *BOM, doc Document) error {
+	var value interface{}
+
+	switch format {
+	case FormatCycloneDX:
+		value = cycloneDX(b, doc)
+	case FormatSPDX:
+		value = spdx(b, doc)
+	default:
+		return Validate(format)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,98 @@
+package sbom_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/internal/sbom"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurl(t *testing.T) {
+	require.Equal(t, "pkg:golang/github.com/depscloud/api@v0.1.0",
+		sbom.Purl(&schema.Module{Language: "go", Name: "github.com/depscloud/api"}, "v0.1.0"))
+	require.Equal(t, "pkg:maven/com.google.guava/guava@28.0",
+		sbom.Purl(&schema.Module{Language: "java", Organization: "com.google.guava", Module: "guava"}, "28.0"))
+	require.Equal(t, "pkg:npm/%40types/node",
+		sbom.Purl(&schema.Module{Language: "node", Name: "@types/node"}, ""))
+	require.Equal(t, "pkg:generic/thing",
+		sbom.Purl(&schema.Module{Language: "unknown", Name: "thing"}, ""))
+}
+
+func TestWrite(t *testing.T) {
+	b := sbom.New("https://github.com/depscloud/depscloud.git")
+	b.DependsOn(sbom.NewComponent(&schema.Module{Language: "go", Name: "github.com/depscloud/depscloud"}, ""), []*sbom.Component{
+		sbom.NewComponent(&schema.Module{Language: "go", Name: "github.com/depscloud/api"}, "v0.1.0"),
+		sbom.NewComponent(&schema.Module{Language: "go", Name: "github.com/spf13/cobra"}, "^1.0.0"),
+	})
+	b.Sort()
+
+	require.Len(t, b.Components, 3)
+	require.Equal(t, []string{
+		"pkg:golang/github.com/depscloud/api@v0.1.0",
+		"pkg:golang/github.com/spf13/cobra?constraint=%5E1.0.0",
+	}, b.Dependencies["pkg:golang/github.com/depscloud/depscloud"])
+
+	doc := sbom.Document{
+		ID:      "00000000-0000-4000-8000-000000000000",
+		Created: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Tool:    "deps",
+		Version: "0.1.0",
+	}
+
+	{
+		out := bytes.NewBuffer(nil)
+		require.NoError(t, sbom.Write(out, sbom.FormatCycloneDX, b, doc))
+
+		result := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.Equal(t, "urn:uuid:"+doc.ID, result["serialNumber"])
+
+		metadata := result["metadata"].(map[string]interface{})
+		require.Equal(t, "2020-01-01T00:00:00Z", metadata["timestamp"])
+
+		components := result["components"].([]interface{})
+		require.Len(t, components, 3)
+		require.Equal(t, "v0.1.0", components[0].(map[string]interface{})["version"])
+
+		properties := components[2].(map[string]interface{})["properties"].([]interface{})
+		require.Equal(t, "^1.0.0", properties[0].(map[string]interface{})["value"])
+
+		dependencies := result["dependencies"].([]interface{})
+		require.Len(t, dependencies, 1)
+		require.Len(t, dependencies[0].(map[string]interface{})["dependsOn"], 2)
+	}
+
+	{
+		out := bytes.NewBuffer(nil)
+		require.NoError(t, sbom.Write(out, sbom.FormatSPDX, b, doc))
+
+		result := &struct {
+			CreationInfo struct {
+				Creators []string `json:"creators"`
+			} `json:"creationInfo"`
+			Packages []struct {
+				ExternalRefs []struct {
+					ReferenceLocator string `json:"referenceLocator"`
+				} `json:"externalRefs"`
+			} `json:"packages"`
+			Relationships []map[string]string `json:"relationships"`
+		}{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), result))
+		require.Equal(t, []string{"Tool: deps-0.1.0"}, result.CreationInfo.Creators)
+		require.Len(t, result.Packages, 3)
+		require.Equal(t, "pkg:golang/github.com/depscloud/api@v0.1.0", result.Packages[0].ExternalRefs[0].ReferenceLocator)
+		require.Equal(t, []map[string]string{
+			{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-Package-2"},
+			{"spdxElementId": "SPDXRef-Package-2", "relationshipType": "DEPENDS_ON", "relatedSpdxElement": "SPDXRef-Package-1"},
+			{"spdxElementId": "SPDXRef-Package-2", "relationshipType": "DEPENDS_ON", "relatedSpdxElement": "SPDXRef-Package-3"},
+		}, result.Relationships)
+	}
+
+	require.Error(t, sbom.Write(bytes.NewBuffer(nil), "xml", b, doc))
+	require.Error(t, sbom.Validate("xml"))
+}
@@ -0,0 +1,59 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/schedule"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	start := time.Date(2020, time.January, 31, 10, 17, 30, 0, time.UTC)
+
+	testCases := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"* * * * *", time.Date(2020, time.January, 31, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"5 * * * *", time.Date(2020, time.January, 31, 11, 5, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2020, time.February, 1, 2, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2020, time.February, 3, 9, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 5", time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2020, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2020, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2020, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2020, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", start.Add(90 * time.Minute)},
+	}
+
+	for _, testCase := range testCases {
+		sched, err := schedule.Parse(testCase.expression)
+		require.NoError(t, err, testCase.expression)
+		require.Equal(t, testCase.expected, sched.Next(start), testCase.expression)
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@every 1s",
+		"@every soon",
+	} {
+		_, err := schedule.Parse(expression)
+		require.Error(t, err, expression)
+	}
+}
@@ -0,0 +1,153 @@
+package scopes
+
+import (
+	"strings"
+)
+
+// Scope is the canonical classification of a dependency. Extractors report
+// ecosystem specific scopes (compile, devDependencies, build_requires, ...)
+// which are mapped onto one of these so edges can be compared across
+// languages.
+type Scope = string
+
+const (
+	Runtime  Scope = "runtime"
+	Dev      Scope = "dev"
+	Test     Scope = "test"
+	Build    Scope = "build"
+	Optional Scope = "optional"
+)
+
+// ResolvedPrefix qualifies the version a lockfile resolved a dependency to
+// (resolved:1.2.3). The versionConstraint of the dependency remains the range
+// declared by the manifest.
+const ResolvedPrefix = "resolved:"
+
+// All contains every canonical scope.
+var All = []Scope{Runtime, Dev, Test, Build, Optional}
+
+// qualifiers describe how a dependency was reached rather than what it is
+// needed for, so they don't influence the classification.
+var qualifiers = map[string]bool{
+	"direct":          true,
+	"indirect":        true,
+	"internal":        true,
+	"transitive":      true,
+	"transitive=true": true,
+}
+
+var known = map[string]Scope{
+	"action":              Build,
+	"annotationprocessor": Build,
+	"compileonly":         Build,
+	"enhances":            Optional,
+	"host":                Build,
+	"kapt":                Build,
+	"linking":             Build,
+	"parent":              Build,
+	"suggests":            Optional,
+	"tool_requires":       Build,
+	"weak":                Optional,
+	"workflow":            Build,
+	"workspace":           Build,
+}
+
+// when no runtime scope is present, the least restrictive classification wins.
+var precedence = []Scope{Optional, Build, Dev, Test}
+
+// IsCanonical returns true when the provided scope is one of the canonical
+// scopes.
+func IsCanonical(scope string) bool {
+	for _, s := range All {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyScope maps a single ecosystem specific scope onto a canonical scope.
+// The second return value is false for qualifiers such as direct or indirect.
+func ClassifyScope(scope string) (Scope, bool) {
+	normalized := strings.ToLower(scope)
+
+	// ivy configurations are written as conf->dependencyConf
+	if idx := strings.Index(normalized, "->"); idx > -1 {
+		normalized = normalized[:idx]
+	}
+
+	if qualifiers[normalized] || strings.HasPrefix(normalized, "feature:") || strings.HasPrefix(normalized, ResolvedPrefix) {
+		return "", false
+	} else if s, ok := known[normalized]; ok {
+		return s, true
+	} else if strings.Contains(normalized, "test") {
+		return Test, true
+	} else if strings.HasPrefix(normalized, "dev") {
+		return Dev, true
+	} else if strings.Contains(normalized, "build") {
+		return Build, true
+	} else if strings.Contains(normalized, "optional") {
+		return Optional, true
+	}
+
+	return Runtime, true
+}
+
+// Classify maps the ecosystem specific scopes of a dependency onto one of the
+// canonical scopes. Dependencies without a scope are runtime.
+func Classify(scopes []string) Scope {
+	classified := make(map[Scope]bool)
+	for _, scope := range scopes {
+		if s, ok := ClassifyScope(scope); ok {
+			classified[s] = true
+		}
+	}
+
+	if len(classified) == 0 || classified[Runtime] {
+		return Runtime
+	}
+
+	for _, s := range precedence {
+		if classified[s] {
+			return s
+		}
+	}
+
+	return Runtime
+}
+
+// Resolved returns the version the dependency was resolved to by a lockfile.
+func Resolved(scopes []string) (string, bool) {
+	for _, scope := range scopes {
+		if strings.HasPrefix(scope, ResolvedPrefix) {
+			return strings.TrimPrefix(scope, ResolvedPrefix), true
+		}
+	}
+	return "", false
+}
+
+// Normalize ensures the canonical scope is present within the provided scopes.
+func Normalize(scopes []string) []string {
+	scope := Classify(scopes)
+	for _, s := range scopes {
+		if s == scope {
+			return scopes
+		}
+	}
+
+	normalized := make([]string, 0, len(scopes)+1)
+	normalized = append(normalized, scopes...)
+	return append(normalized, scope)
+}
+
+// Matches returns true when the canonical scope of the provided scopes is
+// not contained within the excluded set.
+func Matches(scopes []string, excluded []Scope) bool {
+	scope := Classify(scopes)
+	for _, s := range excluded {
+		if s == scope {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package scopes_test
+
+import (
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/scopes"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		scopes   []string
+		expected scopes.Scope
+	}{
+		{nil, scopes.Runtime},
+		{[]string{""}, scopes.Runtime},
+		{[]string{"compile"}, scopes.Runtime},
+		{[]string{"indirect"}, scopes.Runtime},
+		{[]string{"compile", "annotationProcessor"}, scopes.Runtime},
+		{[]string{"dev"}, scopes.Dev},
+		{[]string{"transitive", "dev"}, scopes.Dev},
+		{[]string{"test", "dev"}, scopes.Dev},
+		{[]string{"test"}, scopes.Test},
+		{[]string{"testCompile"}, scopes.Test},
+		{[]string{"test->default"}, scopes.Test},
+		{[]string{"build"}, scopes.Build},
+		{[]string{"host", "feature:ssl"}, scopes.Build},
+		{[]string{"dev", "resolved:1.0.0-test"}, scopes.Dev},
+		{[]string{"parent"}, scopes.Build},
+		{[]string{"optional"}, scopes.Optional},
+		{[]string{"suggests"}, scopes.Optional},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, scopes.Classify(test.scopes), "%v", test.scopes)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	require.Equal(t, []string{"compile", "runtime"}, scopes.Normalize([]string{"compile"}))
+	require.Equal(t, []string{"test"}, scopes.Normalize([]string{"test"}))
+	require.Equal(t, []string{"runtime"}, scopes.Normalize(nil))
+}
+
+func TestMatches(t *testing.T) {
+	excluded := []scopes.Scope{scopes.Test, scopes.Dev}
+
+	require.True(t, scopes.Matches([]string{"compile"}, excluded))
+	require.False(t, scopes.Matches([]string{"testCompile"}, excluded))
+	require.False(t, scopes.Matches([]string{"dev"}, excluded))
+	require.True(t, scopes.Matches([]string{"dev"}, nil))
+}
+
+func TestResolved(t *testing.T) {
+	version, ok := scopes.Resolved([]string{"", "resolved:4.17.20"})
+	require.True(t, ok)
+	require.Equal(t, "4.17.20", version)
+
+	_, ok = scopes.Resolved([]string{"compile"})
+	require.False(t, ok)
+}
@@ -0,0 +1,198 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/sigv4"
+)
+
+// vault reads secrets from HashiCorp Vault using the address and token in the
+// standard VAULT_ADDR and VAULT_TOKEN environment variables. Both the v1 and
+// v2 key value engines are supported, the path is the full api path of the
+// secret (secret/data/depscloud for v2).
+type vault struct{}
+
+type vaultResponse struct {
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+func (v *vault) Read(ctx context.Context, ref *Reference) ([]byte, error) {
+	address := getenv("VAULT_ADDR", "https://127.0.0.1:8200")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(address, "/")+"/v1/"+ref.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	data, err := get(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &vaultResponse{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, err
+	}
+
+	// the v2 engine nests the secret alongside its metadata
+	if inner, ok := resp.Data["data"]; ok && resp.Data["metadata"] != nil {
+		return inner, nil
+	}
+
+	return json.Marshal(resp.Data)
+}
+
+// awsSecretsManager reads secrets from AWS Secrets Manager using the
+// credentials in the standard environment variables. The path is the name or
+// ARN of the secret, and the region is read from the region parameter or the
+// AWS_REGION environment variable.
+type awsSecretsManager struct{}
+
+type getSecretValueRequest struct {
+	SecretID     string `json:"SecretId"`
+	VersionStage string `json:"VersionStage,omitempty"`
+}
+
+type getSecretValueResponse struct {
+	SecretString string `json:"SecretString"`
+	SecretBinary []byte `json:"SecretBinary"`
+}
+
+func (a *awsSecretsManager) Read(ctx context.Context, ref *Reference) ([]byte, error) {
+	credentials, err := sigv4.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	region := ref.Query.Get("region")
+	if region == "" {
+		region = getenv("AWS_REGION", os.Getenv("AWS_DEFAULT_REGION"))
+	}
+	if region == "" {
+		return nil, fmt.Errorf("no aws region provided")
+	}
+
+	endpoint := getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER", fmt.Sprintf("https://secretsmanager.%s.amazonaws.com/", region))
+
+	body, err := json.Marshal(&getSecretValueRequest{
+		SecretID:     ref.Path,
+		VersionStage: ref.Query.Get("stage"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sigv4.Sign(req, body, credentials, region, "secretsmanager", time.Now())
+
+	data, err := get(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &getSecretValueResponse{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, err
+	}
+
+	if resp.SecretString != "" {
+		return []byte(resp.SecretString), nil
+	}
+	return resp.SecretBinary, nil
+}
+
+// gcpSecretManager reads secrets from GCP Secret Manager. The path is the
+// project and secret, and the latest version is read unless the version
+// parameter is set. The access token is read from GOOGLE_OAUTH_ACCESS_TOKEN,
+// falling back to the service account of the metadata server.
+type gcpSecretManager struct{}
+
+type gcpTokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+type gcpAccessResponse struct {
+	Payload struct {
+		Data string `json:"data"`
+	} `json:"payload"`
+}
+
+func (g *gcpSecretManager) token(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	metadataURL := "http://" + getenv("GCE_METADATA_HOST", "metadata.google.internal") +
+		"/computeMetadata/v1/instance/service-accounts/default/token"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	data, err := get(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch access token: %v", err)
+	}
+
+	resp := &gcpTokenResponse{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return "", err
+	}
+	return resp.AccessToken, nil
+}
+
+func (g *gcpSecretManager) Read(ctx context.Context, ref *Reference) ([]byte, error) {
+	parts := strings.Split(ref.Path, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("expected a reference of the form gcpsm://project/secret")
+	}
+
+	version := ref.Query.Get("version")
+	if version == "" {
+		version = "latest"
+	}
+
+	token, err := g.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("https://secretmanager.googleapis.com/v1/projects/%s/secrets/%s/versions/%s:access", parts[0], parts[1], version)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	data, err := get(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &gcpAccessResponse{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(resp.Payload.Data)
+}
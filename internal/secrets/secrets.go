@@ -0,0 +1,170 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Reference names a secret held by a secret manager. References take the form
+// scheme://path?query#field, where the field selects a key from a secret
+// holding a json object. For example:
+//
+//	vault://secret/data/depscloud/tracker#dsn
+//	awssm://depscloud/github?region=us-west-2#token
+//	gcpsm://my-project/tracker-tls-key?version=3&as=file
+//
+// Setting as=file writes the secret to a private temporary file and resolves
+// to its path, for options that expect a path such as TLS keys.
+type Reference struct {
+	Scheme string
+	Path   string
+	Query  url.Values
+	Field  string
+}
+
+// Provider reads secrets from a secret manager.
+type Provider interface {
+	Read(ctx context.Context, ref *Reference) ([]byte, error)
+}
+
+var providers = map[string]Provider{
+	"vault": &vault{},
+	"awssm": &awsSecretsManager{},
+	"gcpsm": &gcpSecretManager{},
+}
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Parse returns the reference in the value, or false when the value isn't a
+// reference to a known secret manager.
+func Parse(value string) (*Reference, bool) {
+	parts := strings.SplitN(value, "://", 2)
+	if len(parts) != 2 {
+		return nil, false
+	}
+
+	if _, ok := providers[parts[0]]; !ok {
+		return nil, false
+	}
+
+	ref := &Reference{Scheme: parts[0], Query: url.Values{}}
+
+	rest := parts[1]
+	if i := strings.LastIndex(rest, "#"); i >= 0 {
+		rest, ref.Field = rest[:i], rest[i+1:]
+	}
+
+	if i := strings.Index(rest, "?"); i >= 0 {
+		query, err := url.ParseQuery(rest[i+1:])
+		if err != nil {
+			return nil, false
+		}
+		rest, ref.Query = rest[:i], query
+	}
+
+	ref.Path = strings.Trim(rest, "/")
+	return ref, ref.Path != ""
+}
+
+// IsReference returns true when the value refers to a secret.
+func IsReference(value string) bool {
+	_, ok := Parse(value)
+	return ok
+}
+
+// field selects the key from a secret holding a json object.
+func field(data []byte, name string) ([]byte, error) {
+	object := make(map[string]interface{})
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("secret is not a json object")
+	}
+
+	value, ok := object[name]
+	if !ok {
+		return nil, fmt.Errorf("secret has no field %s", name)
+	}
+
+	if s, ok := value.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(value)
+}
+
+// writeFile writes the secret to a file only readable by the current user.
+func writeFile(data []byte) (string, error) {
+	file, err := ioutil.TempFile("", "secret")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := file.Chmod(0600); err != nil {
+		return "", err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// Resolve returns the secret the value refers to. Values that aren't
+// references are returned as is, so it can be applied to any option.
+func Resolve(ctx context.Context, value string) (string, error) {
+	ref, ok := Parse(value)
+	if !ok {
+		return value, nil
+	}
+
+	data, err := providers[ref.Scheme].Read(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s://%s: %v", ref.Scheme, ref.Path, err)
+	}
+
+	if ref.Field != "" {
+		if data, err = field(data, ref.Field); err != nil {
+			return "", fmt.Errorf("failed to read secret %s://%s: %v", ref.Scheme, ref.Path, err)
+		}
+	}
+
+	if ref.Query.Get("as") == "file" {
+		return writeFile(data)
+	}
+
+	return string(data), nil
+}
+
+// get performs the request and returns the body of a successful response.
+func get(req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return data, nil
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
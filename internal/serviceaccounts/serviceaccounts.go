@@ -0,0 +1,274 @@
+package serviceaccounts
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/tenants"
+)
+
+// RoutePrefix prefixes the HTTP routes used to manage service accounts and
+// their tokens.
+const RoutePrefix = "/v1alpha/serviceaccounts/"
+
+// SubjectPrefix prefixes the subject of every service account so they can't
+// be confused with the common names of certificates.
+const SubjectPrefix = "serviceaccount:"
+
+const (
+	// DefaultTTL is how long tokens are valid for when no ttl is requested.
+	DefaultTTL = 90 * 24 * time.Hour
+	// MaxTTL is the longest a token can be valid for.
+	MaxTTL = 365 * 24 * time.Hour
+)
+
+// tokens look like dcsa_<id>_<secret> so they're easy to spot when leaked
+const tokenPrefix = "dcsa_"
+
+var namePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]{0,62}[a-z0-9])?$`)
+
+// ValidateName ensures the name of a service account is lowercase and safe to
+// use in urls and subjects.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("service account names must be lowercase alphanumeric, '.', '_', or '-' and at most 64 characters")
+	}
+	return nil
+}
+
+// Subject returns the rbac subject of the service account.
+func Subject(account string) string {
+	return SubjectPrefix + account
+}
+
+// Account identifies a non-human caller, like the indexer or a CI job.
+type Account struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Token describes a token issued to a service account. The secret is only
+// returned when the token is issued. Only its hash is stored.
+type Token struct {
+	ID           string     `json:"id"`
+	Account      string     `json:"account"`
+	Role         rbac.Role  `json:"role"`
+	Organization string     `json:"organization,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+	Hash         string     `json:"-"`
+}
+
+// Active returns true when the token is neither expired nor revoked.
+func (t *Token) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// Credential returns the subject and scope granted by the token.
+func (t *Token) Credential() *rbac.Credential {
+	return &rbac.Credential{
+		Subject:      Subject(t.Account),
+		Role:         t.Role,
+		Organization: t.Organization,
+		TokenID:      t.ID,
+	}
+}
+
+// Issue creates a token for the account, returning the secret token along with
+// the details that are stored.
+func Issue(account string, role rbac.Role, organization string, ttl time.Duration) (string, *Token, error) {
+	if role == rbac.RoleNone {
+		return "", nil, fmt.Errorf("tokens must grant a role")
+	} else if _, err := rbac.ParseRole(string(role)); err != nil {
+		return "", nil, err
+	}
+
+	if ttl <= 0 || ttl > MaxTTL {
+		return "", nil, fmt.Errorf("ttl must be positive and at most %s", MaxTTL)
+	}
+
+	id, err := random(8)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret, err := random(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now().UTC()
+	return tokenPrefix + id + "_" + secret, &Token{
+		ID:           id,
+		Account:      account,
+		Role:         role,
+		Organization: organization,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+		Hash:         Hash(secret),
+	}, nil
+}
+
+// Parse splits a token into its id and secret. Tokens that weren't issued to
+// a service account return an error.
+func Parse(token string) (id, secret string, err error) {
+	parts := strings.Split(strings.TrimPrefix(token, tokenPrefix), "_")
+	if !strings.HasPrefix(token, tokenPrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("not a service account token")
+	}
+	return parts[0], parts[1], nil
+}
+
+// Recognized returns true when the token was issued to a service account.
+func Recognized(token string) bool {
+	return strings.HasPrefix(token, tokenPrefix)
+}
+
+// Hash returns the hash stored in place of the secret.
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify ensures the secret matches the stored token and that the token is
+// still active.
+func Verify(stored *Token, secret string, now time.Time) error {
+	if stored == nil || subtle.ConstantTimeCompare([]byte(stored.Hash), []byte(Hash(secret))) != 1 {
+		return fmt.Errorf("unknown token")
+	} else if stored.RevokedAt != nil {
+		return fmt.Errorf("token was revoked")
+	} else if !now.Before(stored.ExpiresAt) {
+		return fmt.Errorf("token expired")
+	}
+	return nil
+}
+
+func random(size int) (string, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// AccountRequest describes a service account being created or updated.
+type AccountRequest struct {
+	Description string `json:"description,omitempty"`
+}
+
+// AccountsResponse contains the service accounts of a tenant.
+type AccountsResponse struct {
+	Accounts []*Account `json:"accounts"`
+}
+
+// TokenRequest describes the scope of a token being issued. The ttl is a
+// duration, like 720h.
+type TokenRequest struct {
+	Role         rbac.Role `json:"role"`
+	Organization string    `json:"organization,omitempty"`
+	TTL          string    `json:"ttl,omitempty"`
+}
+
+// TokensResponse contains the tokens of a service account.
+type TokensResponse struct {
+	Tokens []*Token `json:"tokens"`
+}
+
+// IssuedResponse contains a newly issued token. The secret token can't be
+// retrieved again.
+type IssuedResponse struct {
+	Token   string `json:"token"`
+	Details *Token `json:"details"`
+}
+
+// VerifyRequest contains a token to verify.
+type VerifyRequest struct {
+	Token string `json:"token"`
+}
+
+type cachedCredential struct {
+	credential *rbac.Credential
+	verifiedAt time.Time
+}
+
+// HTTPVerifier verifies service account tokens using the http api of the
+// tracker. Verified tokens are cached for the ttl, so revocations can take up
+// to the ttl to apply. Other bearer tokens aren't recognized.
+func HTTPVerifier(address string, client *http.Client, ttl time.Duration) rbac.Verifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	mu := sync.Mutex{}
+	cache := make(map[string]*cachedCredential)
+
+	return func(ctx context.Context, tenant, token string) (*rbac.Credential, error) {
+		if !Recognized(token) {
+			return nil, nil
+		}
+
+		key := tenant + "/" + Hash(token)
+
+		mu.Lock()
+		cached := cache[key]
+		if cached != nil && time.Since(cached.verifiedAt) >= ttl {
+			delete(cache, key)
+			cached = nil
+		}
+		mu.Unlock()
+
+		if cached != nil {
+			return cached.credential, nil
+		}
+
+		body, err := json.Marshal(&VerifyRequest{Token: token})
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(address, "/")+RoutePrefix+"tokens/verify", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		if tenant != "" {
+			req.Header.Set(tenants.HeaderKey, tenant)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("tracker responded with %s", resp.Status)
+		}
+
+		credential := &rbac.Credential{}
+		if err := json.NewDecoder(resp.Body).Decode(credential); err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		cache[key] = &cachedCredential{credential: credential, verifiedAt: time.Now()}
+		mu.Unlock()
+
+		return credential, nil
+	}
+}
@@ -0,0 +1,106 @@
+package serviceaccounts_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/serviceaccounts"
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateName(t *testing.T) {
+	require.Nil(t, serviceaccounts.ValidateName("indexer"))
+	require.Nil(t, serviceaccounts.ValidateName("ci.payments-deploy"))
+	require.NotNil(t, serviceaccounts.ValidateName(""))
+	require.NotNil(t, serviceaccounts.ValidateName("Indexer"))
+	require.NotNil(t, serviceaccounts.ValidateName("-indexer"))
+	require.NotNil(t, serviceaccounts.ValidateName("ci/deploy"))
+}
+
+func TestIssue(t *testing.T) {
+	token, details, err := serviceaccounts.Issue("indexer", rbac.RoleEditor, "github.com", time.Hour)
+	require.Nil(t, err)
+	require.True(t, serviceaccounts.Recognized(token))
+	require.Equal(t, "indexer", details.Account)
+	require.Equal(t, time.Hour, details.ExpiresAt.Sub(details.CreatedAt))
+	require.Equal(t, &rbac.Credential{
+		Subject:      "serviceaccount:indexer",
+		Role:         rbac.RoleEditor,
+		Organization: "github.com",
+		TokenID:      details.ID,
+	}, details.Credential())
+
+	id, secret, err := serviceaccounts.Parse(token)
+	require.Nil(t, err)
+	require.Equal(t, details.ID, id)
+	require.NotContains(t, details.Hash, secret)
+
+	now := details.CreatedAt
+	require.Nil(t, serviceaccounts.Verify(details, secret, now))
+	require.NotNil(t, serviceaccounts.Verify(details, "guess", now))
+	require.NotNil(t, serviceaccounts.Verify(nil, secret, now))
+	require.NotNil(t, serviceaccounts.Verify(details, secret, now.Add(time.Hour)))
+
+	details.RevokedAt = &now
+	require.NotNil(t, serviceaccounts.Verify(details, secret, now))
+	require.False(t, details.Active(now))
+
+	_, _, err = serviceaccounts.Issue("indexer", rbac.RoleNone, "", time.Hour)
+	require.NotNil(t, err)
+
+	_, _, err = serviceaccounts.Issue("indexer", rbac.RoleViewer, "", 2*serviceaccounts.MaxTTL)
+	require.NotNil(t, err)
+
+	_, _, err = serviceaccounts.Parse("eyJhbGciOiJSUzI1NiJ9")
+	require.NotNil(t, err)
+}
+
+func TestHTTPVerifier(t *testing.T) {
+	token, details, err := serviceaccounts.Issue("indexer", rbac.RoleEditor, "", time.Hour)
+	require.Nil(t, err)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		req := &serviceaccounts.VerifyRequest{}
+		_ = json.NewDecoder(r.Body).Decode(req)
+
+		if r.URL.Path != "/v1alpha/serviceaccounts/tokens/verify" ||
+			r.Header.Get(tenants.HeaderKey) != "payments" || req.Token != token {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(details.Credential())
+	}))
+	defer server.Close()
+
+	verifier := serviceaccounts.HTTPVerifier(server.URL, nil, time.Minute)
+	ctx := context.Background()
+
+	credential, err := verifier(ctx, "payments", token)
+	require.Nil(t, err)
+	require.Equal(t, details.Credential(), credential)
+
+	// verified tokens are cached
+	_, err = verifier(ctx, "payments", token)
+	require.Nil(t, err)
+	require.Equal(t, 1, calls)
+
+	_, err = verifier(ctx, "search", token)
+	require.NotNil(t, err)
+
+	// tokens from other issuers are left to the caller
+	credential, err = verifier(ctx, "payments", "eyJhbGciOiJSUzI1NiJ9")
+	require.Nil(t, err)
+	require.Nil(t, credential)
+	require.Equal(t, 2, calls)
+}
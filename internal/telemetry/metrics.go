@@ -0,0 +1,210 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The aggregation temporality of sums and histograms, numbered as it is by
+// OTLP. Prometheus metrics are always cumulative.
+const temporalityCumulative = 2
+
+// MetricExporter periodically exports the metrics a service serves to
+// prometheus to an OTLP/HTTP collector, so the same counters, gauges, and
+// histograms are available to collectors that don't scrape.
+type MetricExporter struct {
+	service  string
+	version  string
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+	gatherer prometheus.Gatherer
+	interval time.Duration
+	start    time.Time
+}
+
+// NewMetricExporter returns an exporter of the metrics gathered for the
+// service. No exporter is returned when exporting metrics is disabled.
+func NewMetricExporter(service, version string, cfg *Config, client *http.Client, gatherer prometheus.Gatherer) (*MetricExporter, error) {
+	if !cfg.MetricsEnabled() {
+		return nil, nil
+	}
+
+	headers, err := cfg.headers()
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if gatherer == nil {
+		gatherer = prometheus.DefaultGatherer
+	}
+
+	return &MetricExporter{
+		service:  service,
+		version:  version,
+		endpoint: strings.TrimSuffix(cfg.Endpoint, "/") + metricsPath,
+		headers:  headers,
+		client:   client,
+		gatherer: gatherer,
+		interval: cfg.MetricsInterval,
+		start:    time.Now(),
+	}, nil
+}
+
+// Run exports metrics on each interval until the context is done, exporting
+// them once more before returning.
+func (m *MetricExporter) Run(ctx context.Context) {
+	if m == nil {
+		return
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := m.export(context.Background()); err != nil {
+				logrus.Errorf("[telemetry] failed to export metrics: %s", err.Error())
+			}
+			return
+		case <-ticker.C:
+			if err := m.export(ctx); err != nil {
+				logrus.Errorf("[telemetry] failed to export metrics: %s", err.Error())
+			}
+		}
+	}
+}
+
+// export gathers the metrics and posts them to the collector using the json
+// encoding of OTLP.
+func (m *MetricExporter) export(ctx context.Context) error {
+	families, err := m.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+
+	start := strconv.FormatInt(m.start.UnixNano(), 10)
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	metrics := make([]map[string]interface{}, 0, len(families))
+	for _, family := range families {
+		if metric := otlpMetric(family, start, now); metric != nil {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	return post(ctx, m.client, m.endpoint, m.headers, map[string]interface{}{
+		"resourceMetrics": []map[string]interface{}{{
+			"resource": otlpResource(m.service, m.version),
+			"scopeMetrics": []map[string]interface{}{{
+				"scope":   map[string]string{"name": instrumentationScope},
+				"metrics": metrics,
+			}},
+		}},
+	})
+}
+
+// otlpMetric encodes a family of prometheus metrics as an OTLP metric. Nil is
+// returned for families without a counterpart in OTLP.
+func otlpMetric(family *dto.MetricFamily, start, now string) map[string]interface{} {
+	points := make([]map[string]interface{}, 0, len(family.GetMetric()))
+	for _, metric := range family.GetMetric() {
+		labels := make(map[string]string, len(metric.GetLabel()))
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+
+		point := map[string]interface{}{
+			"attributes":        otlpAttributes(labels),
+			"startTimeUnixNano": start,
+			"timeUnixNano":      now,
+		}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			point["asDouble"] = metric.GetCounter().GetValue()
+		case dto.MetricType_GAUGE:
+			point["asDouble"] = metric.GetGauge().GetValue()
+		case dto.MetricType_UNTYPED:
+			point["asDouble"] = metric.GetUntyped().GetValue()
+		case dto.MetricType_HISTOGRAM:
+			histogram := metric.GetHistogram()
+			bounds, counts := otlpBuckets(histogram)
+			point["count"] = strconv.FormatUint(histogram.GetSampleCount(), 10)
+			point["sum"] = histogram.GetSampleSum()
+			point["explicitBounds"] = bounds
+			point["bucketCounts"] = counts
+		case dto.MetricType_SUMMARY:
+			summary := metric.GetSummary()
+			quantiles := make([]map[string]float64, 0, len(summary.GetQuantile()))
+			for _, quantile := range summary.GetQuantile() {
+				quantiles = append(quantiles, map[string]float64{
+					"quantile": quantile.GetQuantile(),
+					"value":    quantile.GetValue(),
+				})
+			}
+			point["count"] = strconv.FormatUint(summary.GetSampleCount(), 10)
+			point["sum"] = summary.GetSampleSum()
+			point["quantileValues"] = quantiles
+		default:
+			return nil
+		}
+
+		points = append(points, point)
+	}
+
+	metric := map[string]interface{}{
+		"name":        family.GetName(),
+		"description": family.GetHelp(),
+	}
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		metric["sum"] = map[string]interface{}{
+			"dataPoints":             points,
+			"aggregationTemporality": temporalityCumulative,
+			"isMonotonic":            true,
+		}
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		metric["gauge"] = map[string]interface{}{"dataPoints": points}
+	case dto.MetricType_HISTOGRAM:
+		metric["histogram"] = map[string]interface{}{
+			"dataPoints":             points,
+			"aggregationTemporality": temporalityCumulative,
+		}
+	case dto.MetricType_SUMMARY:
+		metric["summary"] = map[string]interface{}{"dataPoints": points}
+	}
+	return metric
+}
+
+// otlpBuckets converts the cumulative buckets of a prometheus histogram to the
+// bounds and per bucket counts of OTLP, which end with the bucket of samples
+// above the last bound.
+func otlpBuckets(histogram *dto.Histogram) ([]float64, []string) {
+	bounds := make([]float64, 0, len(histogram.GetBucket()))
+	counts := make([]string, 0, len(histogram.GetBucket())+1)
+
+	previous := uint64(0)
+	for _, bucket := range histogram.GetBucket() {
+		bounds = append(bounds, bucket.GetUpperBound())
+		counts = append(counts, strconv.FormatUint(bucket.GetCumulativeCount()-previous, 10))
+		previous = bucket.GetCumulativeCount()
+	}
+
+	counts = append(counts, strconv.FormatUint(histogram.GetSampleCount()-previous, 10))
+	return bounds, counts
+}
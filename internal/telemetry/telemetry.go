@@ -0,0 +1,697 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TraceparentKey is the W3C trace context header, and grpc metadata key, used
+// to pass the span of a caller to the services it calls.
+const TraceparentKey = "traceparent"
+
+// tracesPath and metricsPath are where OTLP/HTTP collectors receive spans
+// and metrics.
+const (
+	tracesPath  = "/v1/traces"
+	metricsPath = "/v1/metrics"
+)
+
+// instrumentationScope names the instrumentation spans are reported by.
+const instrumentationScope = "github.com/depscloud/depscloud/internal/telemetry"
+
+// The kinds of spans, numbered as they are by OTLP.
+const (
+	kindInternal = 1
+	kindServer   = 2
+	kindClient   = 3
+)
+
+// The status codes of spans, numbered as they are by OTLP.
+const (
+	statusUnset = 0
+	statusError = 2
+)
+
+var spanDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "telemetry_span_duration_seconds",
+	Help:    "How long each traced operation, such as an extraction or a storage transaction, took by result.",
+	Buckets: prometheus.ExponentialBuckets(0.005, 2, 16),
+}, []string{"span", "result"})
+
+// Config controls where the spans and metrics of a service are exported.
+type Config struct {
+	Endpoint        string
+	Headers         *cli.StringSlice
+	SampleRatio     float64
+	BufferSize      int
+	FlushInterval   time.Duration
+	MetricsInterval time.Duration
+}
+
+// DefaultConfig returns the defaults used to export spans and metrics.
+func DefaultConfig() *Config {
+	return &Config{
+		Headers:         cli.NewStringSlice(),
+		SampleRatio:     1,
+		BufferSize:      2048,
+		FlushInterval:   5 * time.Second,
+		MetricsInterval: 30 * time.Second,
+	}
+}
+
+// WithFlags returns the flags used to configure tracing and metrics. The
+// environment variables follow the conventions of OpenTelemetry.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	if cfg.Headers == nil {
+		cfg.Headers = cli.NewStringSlice()
+	}
+
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "otlp-endpoint",
+			Usage:       "the base url of the OTLP/HTTP collector spans and metrics are exported to, such as http://otel-collector:4318, nothing is exported without one",
+			Value:       cfg.Endpoint,
+			Destination: &(cfg.Endpoint),
+			EnvVars:     []string{"OTEL_EXPORTER_OTLP_ENDPOINT"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "otlp-header",
+			Usage:       "a header sent to the collector with exported spans and metrics, as key=value",
+			Destination: cfg.Headers,
+			EnvVars:     []string{"OTEL_EXPORTER_OTLP_HEADERS"},
+		},
+		&cli.Float64Flag{
+			Name:        "trace-sample-ratio",
+			Usage:       "the fraction of traces started by this service that are exported, traces started by a caller follow its decision",
+			Value:       cfg.SampleRatio,
+			Destination: &(cfg.SampleRatio),
+			EnvVars:     []string{"OTEL_TRACES_SAMPLER_ARG"},
+		},
+		&cli.IntFlag{
+			Name:        "otlp-buffer-size",
+			Usage:       "the number of spans held before they're exported, spans are dropped when it's full",
+			Value:       cfg.BufferSize,
+			Destination: &(cfg.BufferSize),
+			EnvVars:     []string{"OTLP_BUFFER_SIZE"},
+		},
+		&cli.DurationFlag{
+			Name:        "otlp-flush-interval",
+			Usage:       "how often buffered spans are exported",
+			Value:       cfg.FlushInterval,
+			Destination: &(cfg.FlushInterval),
+			EnvVars:     []string{"OTLP_FLUSH_INTERVAL"},
+		},
+		&cli.DurationFlag{
+			Name:        "otlp-metrics-interval",
+			Usage:       "how often metrics are exported, 0 only serves them to prometheus",
+			Value:       cfg.MetricsInterval,
+			Destination: &(cfg.MetricsInterval),
+			EnvVars:     []string{"OTLP_METRICS_INTERVAL"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// Enabled returns true when spans are exported.
+func (c *Config) Enabled() bool {
+	return c != nil && c.Endpoint != ""
+}
+
+// MetricsEnabled returns true when metrics are exported.
+func (c *Config) MetricsEnabled() bool {
+	return c.Enabled() && c.MetricsInterval > 0
+}
+
+// headers parses the key=value headers sent to the collector.
+func (c *Config) headers() (map[string]string, error) {
+	headers := make(map[string]string)
+	if c.Headers == nil {
+		return headers, nil
+	}
+
+	for _, header := range c.Headers.Value() {
+		parts := strings.SplitN(header, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("otlp headers must be provided as key=value, got %q", header)
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return headers, nil
+}
+
+// SpanContext identifies a span and the trace it belongs to.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// Valid returns true when both the trace and span are identified.
+func (s SpanContext) Valid() bool {
+	return s.TraceID != [16]byte{} && s.SpanID != [8]byte{}
+}
+
+// Traceparent formats the span context as a W3C traceparent.
+func (s SpanContext) Traceparent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(s.TraceID[:]), hex.EncodeToString(s.SpanID[:]), flags)
+}
+
+// ParseTraceparent parses a W3C traceparent, returning false when it's
+// malformed.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	sc := SpanContext{}
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	} else if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags&1 == 1
+
+	return sc, sc.Valid()
+}
+
+type contextKey struct{}
+
+// NewContext returns a context for the span, making it the parent of the
+// spans started using the context.
+func NewContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, contextKey{}, sc)
+}
+
+// FromContext returns the current span, either started by this service or
+// passed by the caller. The span context isn't valid when there isn't one.
+func FromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	sc, _ := ctx.Value(contextKey{}).(SpanContext)
+	return sc
+}
+
+// Span is an operation being traced. A nil span does nothing, so callers
+// don't need to check whether tracing is enabled.
+type Span struct {
+	tracer  *Tracer
+	name    string
+	kind    int
+	parent  SpanContext
+	context SpanContext
+	start   time.Time
+	end     time.Time
+
+	mu         sync.Mutex
+	attributes map[string]string
+	err        string
+}
+
+// SetAttribute describes the span, such as the url of the source it acts on.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// End finishes the span, marking it failed when there's an error.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.end = time.Now()
+	if err != nil {
+		s.err = status.Convert(err).Message()
+	}
+	s.mu.Unlock()
+
+	if s.kind == kindInternal {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		spanDuration.WithLabelValues(s.name, result).Observe(s.end.Sub(s.start).Seconds())
+	}
+
+	s.tracer.record(s)
+}
+
+// Tracer buffers the spans of a service and exports them to an OTLP/HTTP
+// collector in batches. A nil tracer doesn't record spans, but the spans
+// passed by callers are still passed along to the services called.
+type Tracer struct {
+	service       string
+	version       string
+	endpoint      string
+	headers       map[string]string
+	client        *http.Client
+	sampleRatio   float64
+	spans         chan *Span
+	flushInterval time.Duration
+}
+
+// NewTracer returns a tracer exporting the spans of the service. No tracer is
+// returned when tracing is disabled.
+func NewTracer(service, version string, cfg *Config, client *http.Client) (*Tracer, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	headers, err := cfg.headers()
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+
+	flushInterval := cfg.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	return &Tracer{
+		service:       service,
+		version:       version,
+		endpoint:      strings.TrimSuffix(cfg.Endpoint, "/") + tracesPath,
+		headers:       headers,
+		client:        client,
+		sampleRatio:   cfg.SampleRatio,
+		spans:         make(chan *Span, bufferSize),
+		flushInterval: flushInterval,
+	}, nil
+}
+
+var global atomic.Value
+
+// SetTracer makes the tracer record the spans started by this process.
+func SetTracer(t *Tracer) {
+	global.Store(t)
+}
+
+func current() *Tracer {
+	t, _ := global.Load().(*Tracer)
+	return t
+}
+
+func randomID(id []byte) {
+	if _, err := rand.Read(id); err != nil {
+		// fall back to the clock rather than producing an invalid id
+		binary.BigEndian.PutUint64(id[len(id)-8:], uint64(time.Now().UnixNano()))
+	}
+}
+
+// sampled decides whether a trace started by this service is exported.
+func (t *Tracer) sampled() bool {
+	if t.sampleRatio >= 1 {
+		return true
+	} else if t.sampleRatio <= 0 {
+		return false
+	}
+
+	value := make([]byte, 8)
+	randomID(value)
+	return float64(binary.BigEndian.Uint64(value)>>11)/(1<<53) < t.sampleRatio
+}
+
+// start begins a span that's a child of the span in the context, or the root
+// of a new trace when there isn't one.
+func (t *Tracer) start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := FromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled}
+	if !parent.Valid() {
+		randomID(sc.TraceID[:])
+		sc.Sampled = t.sampled()
+	}
+	randomID(sc.SpanID[:])
+
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		parent:     parent,
+		context:    sc,
+		start:      time.Now(),
+		attributes: make(map[string]string),
+	}
+
+	return NewContext(ctx, sc), span
+}
+
+// Start begins an operation traced by this process, such as an extraction or
+// a storage transaction. The span must be ended by the caller.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return current().start(ctx, name, kindInternal)
+}
+
+// record buffers the span when it's sampled. Spans are dropped rather than
+// holding up the caller when the buffer is full.
+func (t *Tracer) record(span *Span) {
+	if t == nil || !span.context.Sampled {
+		return
+	}
+
+	select {
+	case t.spans <- span:
+	default:
+		logrus.Errorf("[telemetry] buffer is full, dropped span %s", span.name)
+	}
+}
+
+// Run exports buffered spans until the context is done, flushing whatever
+// remains before returning.
+func (t *Tracer) Run(ctx context.Context) {
+	if t == nil {
+		return
+	}
+
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, cap(t.spans))
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := t.export(ctx, batch); err != nil {
+			logrus.Errorf("[telemetry] failed to export %d spans: %s", len(batch), err.Error())
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case span := <-t.spans:
+					batch = append(batch, span)
+				default:
+					flush(context.Background())
+					return
+				}
+			}
+		case span := <-t.spans:
+			batch = append(batch, span)
+			if len(batch) == cap(batch) {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// otlpAttributes encodes attributes as OTLP key values.
+func otlpAttributes(attributes map[string]string) []map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(attributes))
+	for key, value := range attributes {
+		encoded = append(encoded, map[string]interface{}{
+			"key":   key,
+			"value": map[string]string{"stringValue": value},
+		})
+	}
+	return encoded
+}
+
+// export posts the spans to the collector using the json encoding of OTLP.
+func (t *Tracer) export(ctx context.Context, spans []*Span) error {
+	encoded := make([]map[string]interface{}, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		value := map[string]interface{}{
+			"traceId":           hex.EncodeToString(span.context.TraceID[:]),
+			"spanId":            hex.EncodeToString(span.context.SpanID[:]),
+			"name":              span.name,
+			"kind":              span.kind,
+			"startTimeUnixNano": strconv.FormatInt(span.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.end.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.attributes),
+			"status":            map[string]interface{}{"code": statusUnset},
+		}
+		if span.parent.Valid() {
+			value["parentSpanId"] = hex.EncodeToString(span.parent.SpanID[:])
+		}
+		if span.err != "" {
+			value["status"] = map[string]interface{}{"code": statusError, "message": span.err}
+		}
+		span.mu.Unlock()
+
+		encoded = append(encoded, value)
+	}
+
+	return post(ctx, t.client, t.endpoint, t.headers, map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource": otlpResource(t.service, t.version),
+			"scopeSpans": []map[string]interface{}{{
+				"scope": map[string]string{"name": instrumentationScope},
+				"spans": encoded,
+			}},
+		}},
+	})
+}
+
+// otlpResource describes the service exporting spans and metrics.
+func otlpResource(service, version string) map[string]interface{} {
+	return map[string]interface{}{
+		"attributes": otlpAttributes(map[string]string{
+			"service.name":    service,
+			"service.version": version,
+		}),
+	}
+}
+
+// post sends the json encoding of an OTLP request to the collector. Requests
+// are encoded by hand rather than using the OpenTelemetry SDK, whose metrics
+// exporter needs a newer version of go than depscloud is built with.
+func post(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, request interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// incomingContext adopts the span passed by the caller of a grpc call.
+func incomingContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(TraceparentKey)) == 0 {
+		return ctx
+	}
+
+	if sc, ok := ParseTraceparent(md.Get(TraceparentKey)[0]); ok {
+		return NewContext(ctx, sc)
+	}
+	return ctx
+}
+
+// outgoingContext passes the current span along to the service being called.
+func outgoingContext(ctx context.Context) context.Context {
+	sc := FromContext(ctx)
+	if !sc.Valid() {
+		return ctx
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(TraceparentKey, sc.Traceparent())
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// UnaryServerInterceptor traces each call, continuing the trace of the caller.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := current().start(incomingContext(ctx), info.FullMethod, kindServer)
+		resp, err := handler(ctx, req)
+		span.End(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor traces each stream, continuing the trace of the
+// caller.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := current().start(incomingContext(ss.Context()), info.FullMethod, kindServer)
+
+		wrapped := grpc_middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+
+		err := handler(srv, wrapped)
+		span.End(err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor traces each call, passing the span to the service
+// being called.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := current().start(ctx, method, kindClient)
+		err := invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+		span.End(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor passes the current span to the service being
+// called. Streams outlive the call that opens them, so they aren't traced
+// themselves.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Middleware traces each http request, continuing the trace of the caller.
+// Requests failing with a server error are marked as failed.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if sc, ok := ParseTraceparent(r.Header.Get(TraceparentKey)); ok {
+			ctx = NewContext(ctx, sc)
+		}
+
+		ctx, span := current().start(ctx, r.Method+" "+r.URL.Path, kindServer)
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		var err error
+		if recorder.status >= http.StatusInternalServerError {
+			err = fmt.Errorf("%s", http.StatusText(recorder.status))
+		}
+		span.End(err)
+	})
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := current().start(r.Context(), r.Method+" "+r.URL.Host, kindClient)
+	if sc := FromContext(ctx); sc.Valid() {
+		r = r.Clone(ctx)
+		r.Header.Set(TraceparentKey, sc.Traceparent())
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+		span.End(fmt.Errorf("%s", resp.Status))
+	} else {
+		span.End(err)
+	}
+	return resp, err
+}
+
+// Transport traces the requests made by the round tripper, passing the span
+// to the service being called. The default transport is used when base is
+// nil.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
@@ -0,0 +1,256 @@
+package telemetry_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/telemetry"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := telemetry.ParseTraceparent(traceparent)
+	require.True(t, ok)
+	require.True(t, sc.Sampled)
+	require.Equal(t, traceparent, sc.Traceparent())
+
+	sc, ok = telemetry.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.True(t, ok)
+	require.False(t, sc.Sampled)
+
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, ok := telemetry.ParseTraceparent(value)
+		require.False(t, ok, value)
+	}
+}
+
+type exported struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Status       struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+func TestTracer(t *testing.T) {
+	received := make(chan []*exported, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/traces", r.URL.Path)
+		require.Equal(t, "secret", r.Header.Get("x-api-key"))
+
+		body := struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []*exported `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}{}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		received <- body.ResourceSpans[0].ScopeSpans[0].Spans
+	}))
+	defer collector.Close()
+
+	cfg := telemetry.DefaultConfig()
+	cfg.Endpoint = collector.URL
+	cfg.Headers = cli.NewStringSlice("x-api-key=secret")
+	cfg.FlushInterval = time.Hour
+
+	tracer, err := telemetry.NewTracer("indexer", "v0.3.0", cfg, collector.Client())
+	require.Nil(t, err)
+
+	telemetry.SetTracer(tracer)
+	defer telemetry.SetTracer(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tracer.Run(ctx)
+		close(done)
+	}()
+
+	// the server span continues the trace of the caller, and the client span
+	// passes its own span along
+	forwarded := ""
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		forwarded = md.Get(telemetry.TraceparentKey)[0]
+		return fmt.Errorf("unavailable")
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		ctx, span := telemetry.Start(ctx, "extract")
+		defer span.End(nil)
+
+		err := telemetry.UnaryClientInterceptor()(ctx, "/tracker/Track", nil, nil, nil, invoker)
+		return nil, err
+	}
+
+	incoming := metadata.NewIncomingContext(context.Background(), metadata.Pairs(telemetry.TraceparentKey, traceparent))
+	_, err = telemetry.UnaryServerInterceptor()(incoming, nil, &grpc.UnaryServerInfo{FullMethod: "/extractor/Extract"}, handler)
+	require.NotNil(t, err)
+
+	cancel()
+	<-done
+
+	spans := <-received
+	require.Len(t, spans, 3)
+
+	byName := make(map[string]*exported)
+	for _, span := range spans {
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID)
+		byName[span.Name] = span
+	}
+
+	require.Equal(t, "00f067aa0ba902b7", byName["/extractor/Extract"].ParentSpanID)
+	require.Equal(t, 2, byName["/extractor/Extract"].Kind)
+	require.Equal(t, byName["/extractor/Extract"].SpanID, byName["extract"].ParentSpanID)
+	require.Equal(t, byName["extract"].SpanID, byName["/tracker/Track"].ParentSpanID)
+	require.Equal(t, "unavailable", byName["/tracker/Track"].Status.Message)
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+byName["/tracker/Track"].SpanID+"-01", forwarded)
+}
+
+func TestTracer_disabled(t *testing.T) {
+	tracer, err := telemetry.NewTracer("gateway", "", telemetry.DefaultConfig(), nil)
+	require.Nil(t, err)
+	require.Nil(t, tracer)
+
+	// without a tracer, the trace of the caller is still passed along
+	sc, ok := telemetry.ParseTraceparent(traceparent)
+	require.True(t, ok)
+
+	ctx, span := telemetry.Start(telemetry.NewContext(context.Background(), sc), "clone")
+	require.Nil(t, span)
+	span.SetAttribute("url", "https://github.com/depscloud/depscloud.git")
+	span.End(nil)
+
+	forwarded := ""
+	_ = telemetry.UnaryClientInterceptor()(ctx, "/tracker/Track", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			forwarded = md.Get(telemetry.TraceparentKey)[0]
+			return nil
+		})
+	require.Equal(t, traceparent, forwarded)
+
+	cfg := telemetry.DefaultConfig()
+	cfg.Endpoint = "http://otel-collector:4318"
+	cfg.Headers = cli.NewStringSlice("invalid")
+	_, err = telemetry.NewTracer("gateway", "", cfg, nil)
+	require.NotNil(t, err)
+}
+
+func TestMetricExporter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total", Help: "requests"}, []string{"method"})
+	requests.WithLabelValues("Track").Add(3)
+
+	duration := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "duration_seconds", Buckets: []float64{1, 5}})
+	duration.Observe(0.5)
+	duration.Observe(2)
+	duration.Observe(10)
+
+	registry.MustRegister(requests, duration)
+
+	type point struct {
+		Attributes []struct {
+			Key string `json:"key"`
+		} `json:"attributes"`
+		AsDouble       float64   `json:"asDouble"`
+		Count          string    `json:"count"`
+		ExplicitBounds []float64 `json:"explicitBounds"`
+		BucketCounts   []string  `json:"bucketCounts"`
+	}
+
+	type metric struct {
+		Name string `json:"name"`
+		Sum  *struct {
+			DataPoints  []*point `json:"dataPoints"`
+			IsMonotonic bool     `json:"isMonotonic"`
+		} `json:"sum"`
+		Histogram *struct {
+			DataPoints []*point `json:"dataPoints"`
+		} `json:"histogram"`
+	}
+
+	received := make(chan []*metric, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/metrics", r.URL.Path)
+		require.Equal(t, "secret", r.Header.Get("x-api-key"))
+
+		body := struct {
+			ResourceMetrics []struct {
+				ScopeMetrics []struct {
+					Metrics []*metric `json:"metrics"`
+				} `json:"scopeMetrics"`
+			} `json:"resourceMetrics"`
+		}{}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		received <- body.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	}))
+	defer collector.Close()
+
+	cfg := telemetry.DefaultConfig()
+	cfg.Endpoint = collector.URL
+	cfg.Headers = cli.NewStringSlice("x-api-key=secret")
+	cfg.MetricsInterval = time.Hour
+
+	exporter, err := telemetry.NewMetricExporter("tracker", "v0.3.0", cfg, collector.Client(), registry)
+	require.Nil(t, err)
+
+	// metrics are exported once more when the service stops
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	exporter.Run(ctx)
+
+	metrics := <-received
+	require.Len(t, metrics, 2)
+
+	byName := make(map[string]*metric)
+	for _, m := range metrics {
+		byName[m.Name] = m
+	}
+
+	total := byName["requests_total"]
+	require.True(t, total.Sum.IsMonotonic)
+	require.Equal(t, float64(3), total.Sum.DataPoints[0].AsDouble)
+	require.Equal(t, "method", total.Sum.DataPoints[0].Attributes[0].Key)
+
+	histogram := byName["duration_seconds"].Histogram.DataPoints[0]
+	require.Equal(t, "3", histogram.Count)
+	require.Equal(t, []float64{1, 5}, histogram.ExplicitBounds)
+	require.Equal(t, []string{"1", "1", "1"}, histogram.BucketCounts)
+
+	// metrics aren't exported without an interval
+	cfg.MetricsInterval = 0
+	exporter, err = telemetry.NewMetricExporter("tracker", "v0.3.0", cfg, nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, exporter)
+}
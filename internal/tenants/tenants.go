@@ -0,0 +1,265 @@
+// Package tenants separates the graphs of callers into tenants.
+package tenants
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+
+	"github.com/depscloud/depscloud/internal/requestmeta"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The tenant is passed between services through request metadata, or the
+// header over HTTP.
+const (
+	MetadataKey = "x-depscloud-tenant"
+	HeaderKey   = "X-Depscloud-Tenant"
+
+	// gatewayHeaderKey is forwarded as metadata by the grpc-gateway.
+	gatewayHeaderKey = "Grpc-Metadata-X-Depscloud-Tenant"
+)
+
+// Modes determine how the tenant of a caller is identified.
+const (
+	// ModeNone serves every caller from the default tenant.
+	ModeNone = "none"
+	// ModeCertificate uses the organization of the verified client
+	// certificate. Trusted proxies, identified by the common name of their
+	// certificate, may act on behalf of another tenant using metadata.
+	ModeCertificate = "certificate"
+	// ModeMetadata trusts the tenant provided in metadata. It's meant for
+	// deployments behind a proxy that authenticates callers.
+	ModeMetadata = "metadata"
+)
+
+// Config controls how callers are mapped onto tenants.
+type Config struct {
+	Mode           string
+	TrustedProxies *cli.StringSlice
+}
+
+// WithFlags returns the flags used to configure tenancy.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	if cfg.TrustedProxies == nil {
+		cfg.TrustedProxies = cli.NewStringSlice()
+	}
+
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "tenancy",
+			Usage:       "how callers are mapped onto tenants; none, certificate, or metadata",
+			Value:       cfg.Mode,
+			Destination: &(cfg.Mode),
+			EnvVars:     []string{"TENANCY"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "tenancy-trusted-proxy",
+			Usage:       "the common name of a client certificate allowed to act on behalf of other tenants",
+			Destination: cfg.TrustedProxies,
+			EnvVars:     []string{"TENANCY_TRUSTED_PROXIES"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// Enabled returns true when callers are separated into tenants.
+func (c *Config) Enabled() bool {
+	return c != nil && c.Mode != "" && c.Mode != ModeNone
+}
+
+// Validate ensures the mode is supported.
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case "", ModeNone, ModeCertificate, ModeMetadata:
+		return nil
+	}
+	return fmt.Errorf("unsupported tenancy mode %s, specify one of none/certificate/metadata", c.Mode)
+}
+
+// resolve determines the tenant of a caller from its verified certificates and
+// the tenant it asserted.
+func (c *Config) resolve(certificates []*x509.Certificate, asserted string) (string, error) {
+	if !c.Enabled() {
+		return "", nil
+	}
+
+	if c.Mode == ModeMetadata {
+		if asserted == "" {
+			return "", fmt.Errorf("a tenant is required")
+		}
+		return asserted, nil
+	}
+
+	if len(certificates) == 0 {
+		return "", fmt.Errorf("a client certificate is required")
+	}
+
+	subject := certificates[0].Subject
+	if asserted != "" && c.TrustedProxies != nil {
+		for _, proxy := range c.TrustedProxies.Value() {
+			if subject.CommonName == proxy {
+				return asserted, nil
+			}
+		}
+	}
+
+	if len(subject.Organization) == 0 || subject.Organization[0] == "" {
+		return "", fmt.Errorf("the client certificate doesn't name an organization")
+	}
+
+	return subject.Organization[0], nil
+}
+
+func (c *Config) resolveContext(ctx context.Context) (context.Context, error) {
+	var certificates []*x509.Certificate
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			certificates = info.State.PeerCertificates
+		}
+	}
+
+	tenant, err := c.resolve(certificates, FromIncomingContext(ctx))
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return NewContext(ctx, tenant), nil
+}
+
+// UnaryServerInterceptor resolves the tenant of each call, rejecting callers
+// without one.
+func (c *Config) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := c.resolveContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ServerOptions returns the interceptors that resolve the tenant of each call.
+// When tenants are identified by certificate, the grpc server must handle TLS
+// itself so client certificates are available, and the credentials for the
+// provided TLS configuration are included.
+func (c *Config) ServerOptions(tlsConfig *tls.Config) ([]grpc.ServerOption, error) {
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(c.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(c.StreamServerInterceptor()),
+	}
+
+	if c.Mode != ModeCertificate {
+		return options, nil
+	} else if tlsConfig == nil {
+		return nil, fmt.Errorf("certificate tenancy requires TLS")
+	}
+
+	return append(options, grpc.Creds(credentials.NewTLS(tlsConfig))), nil
+}
+
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor resolves the tenant of each stream, rejecting
+// callers without one.
+func (c *Config) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := c.resolveContext(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &tenantStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// Middleware resolves the tenant of each http request, rejecting callers
+// without one. The resolved tenant replaces any the caller provided in the
+// request headers so it's passed along when the request is proxied.
+func (c *Config) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var certificates []*x509.Certificate
+		if r.TLS != nil {
+			certificates = r.TLS.PeerCertificates
+		}
+
+		tenant, err := c.resolve(certificates, r.Header.Get(HeaderKey))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		r.Header.Del(HeaderKey)
+		r.Header.Del(gatewayHeaderKey)
+		if tenant != "" {
+			r.Header.Set(HeaderKey, tenant)
+			r.Header.Set(gatewayHeaderKey, tenant)
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), tenant)))
+	})
+}
+
+type contextKey struct{}
+
+// NewContext returns a context for the resolved tenant.
+func NewContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant of the request. The tenant resolved by this
+// service is preferred, otherwise the tenant passed by the calling service is
+// used. The default tenant is the empty string.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if tenant, ok := ctx.Value(contextKey{}).(string); ok {
+		return tenant
+	}
+
+	return FromIncomingContext(ctx)
+}
+
+// FromIncomingContext returns the tenant passed in the request metadata.
+func FromIncomingContext(ctx context.Context) string {
+	return requestmeta.First(ctx, MetadataKey)
+}
+
+// ForwardContext passes the tenant of the request along to the backend.
+func ForwardContext(ctx context.Context) context.Context {
+	return requestmeta.Append(ctx, MetadataKey, FromContext(ctx))
+}
+
+// UnaryClientInterceptor passes the tenant of the request along with every
+// call made to the backend.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(ForwardContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor passes the tenant of the request along with every
+// stream opened to the backend.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(ForwardContext(ctx), desc, cc, method, opts...)
+	}
+}
@@ -0,0 +1,144 @@
+package tenants_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/tenants"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/urfave/cli/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func certificate(commonName string, organizations ...string) []*x509.Certificate {
+	return []*x509.Certificate{
+		{Subject: pkix.Name{CommonName: commonName, Organization: organizations}},
+	}
+}
+
+func serve(config *tenants.Config, certificates []*x509.Certificate, asserted string) (int, string, string) {
+	tenant, header := "", ""
+	handler := config.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = tenants.FromContext(r.Context())
+		header = r.Header.Get(tenants.HeaderKey)
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/v1alpha/queries/search", nil)
+	if certificates != nil {
+		request.TLS = &tls.ConnectionState{PeerCertificates: certificates}
+	}
+	if asserted != "" {
+		request.Header.Set(tenants.HeaderKey, asserted)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder.Code, tenant, header
+}
+
+func TestMiddleware(t *testing.T) {
+	{
+		// callers can't choose a tenant unless tenancy is enabled
+		config := &tenants.Config{Mode: tenants.ModeNone}
+
+		code, tenant, header := serve(config, nil, "payments")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "", tenant)
+		require.Equal(t, "", header)
+	}
+
+	{
+		config := &tenants.Config{Mode: tenants.ModeCertificate, TrustedProxies: cli.NewStringSlice("gateway")}
+
+		code, tenant, header := serve(config, certificate("indexer", "payments"), "")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "payments", tenant)
+		require.Equal(t, "payments", header)
+
+		// untrusted callers can't act on behalf of another tenant
+		code, tenant, _ = serve(config, certificate("indexer", "payments"), "search")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "payments", tenant)
+
+		code, tenant, _ = serve(config, certificate("gateway", "platform"), "search")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "search", tenant)
+
+		code, _, _ = serve(config, certificate("indexer"), "")
+		require.Equal(t, http.StatusForbidden, code)
+
+		code, _, _ = serve(config, nil, "search")
+		require.Equal(t, http.StatusForbidden, code)
+	}
+
+	{
+		config := &tenants.Config{Mode: tenants.ModeMetadata}
+
+		code, tenant, _ := serve(config, nil, "search")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "search", tenant)
+
+		code, _, _ = serve(config, nil, "")
+		require.Equal(t, http.StatusForbidden, code)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	config := &tenants.Config{Mode: tenants.ModeCertificate}
+	interceptor := config.UnaryServerInterceptor()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return tenants.FromContext(ctx), nil
+	}
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: certificate("indexer", "payments")}},
+	})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(tenants.MetadataKey, "search"))
+
+	tenant, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.Nil(t, err)
+	require.Equal(t, "payments", tenant)
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestForwardContext(t *testing.T) {
+	ctx := tenants.ForwardContext(context.Background())
+	_, ok := metadata.FromOutgoingContext(ctx)
+	require.False(t, ok)
+
+	// the tenant resolved by the service replaces the one passed to it
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(tenants.MetadataKey, "search"))
+	require.Equal(t, "search", tenants.FromContext(ctx))
+
+	ctx = tenants.NewContext(ctx, "payments")
+	require.Equal(t, "payments", tenants.FromContext(ctx))
+
+	md, ok := metadata.FromOutgoingContext(tenants.ForwardContext(ctx))
+	require.True(t, ok)
+	require.Equal(t, metadata.Pairs(tenants.MetadataKey, "payments"), md)
+}
+
+func TestValidate(t *testing.T) {
+	require.Nil(t, (&tenants.Config{}).Validate())
+	require.Nil(t, (&tenants.Config{Mode: tenants.ModeMetadata}).Validate())
+	require.NotNil(t, (&tenants.Config{Mode: "header"}).Validate())
+
+	_, err := (&tenants.Config{Mode: tenants.ModeCertificate}).ServerOptions(nil)
+	require.NotNil(t, err)
+}
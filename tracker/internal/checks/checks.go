@@ -12,12 +12,13 @@ import (
 	"github.com/mjpitz/go-gracefully/state"
 )
 
-// Checks returns an array of all health checks for the system.
+// Checks returns an array of all health checks for the system. The v1alpha
+// graph store may be nil when the storage driver doesn't support it.
 func Checks(
 	v1betaGraphStore apiv1beta.GraphStoreClient,
 	graphStore apiv1alpha.GraphStoreClient,
 ) []check.Check {
-	return []check.Check{
+	checks := []check.Check{
 		&check.Periodic{
 			Metadata: check.Metadata{
 				Name:   "graphstore-v1beta-read",
@@ -37,6 +38,13 @@ func Checks(
 				return state.OK, nil
 			},
 		},
+	}
+
+	if graphStore == nil {
+		return checks
+	}
+
+	return append(checks,
 		&check.Periodic{
 			Metadata: check.Metadata{
 				Name:   "graphstore-v1alpha-read",
@@ -56,5 +64,21 @@ func Checks(
 				return state.OK, nil
 			},
 		},
+	)
+}
+
+// Services returns the checks each grpc service of the tracker depends on.
+// The v1alpha services read through the v1alpha graph store, and the v1beta
+// services through the v1beta graph store.
+func Services() map[string][]string {
+	v1alpha := []string{"graphstore-v1alpha-read"}
+	v1beta := []string{"graphstore-v1beta-read"}
+
+	return map[string][]string{
+		"cloud.deps.api.v1alpha.tracker.SourceService":     v1alpha,
+		"cloud.deps.api.v1alpha.tracker.ModuleService":     v1alpha,
+		"cloud.deps.api.v1alpha.tracker.DependencyService": v1alpha,
+		"cloud.deps.api.v1alpha.tracker.SearchService":     v1alpha,
+		"depscloud.api.v1beta.ManifestStorageService":      v1beta,
 	}
 }
@@ -0,0 +1,231 @@
+// Package eventbus publishes the mutations made to the graph to an external
+// event bus so downstream systems can follow changes without reading the
+// database.
+//
+// Delivery is at-most-once when the bus falls behind: mutations are dropped
+// when the queue is full or the event bus keeps failing, rather than slowing
+// down writes to the graph. Every drop is logged at the error level and
+// counted by tracker_mutation_events_dropped_total, so consumers that cache
+// the graph should rebuild their caches after a drop.
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	eventsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tracker_mutation_events_published_total",
+		Help: "The number of graph mutations published to the event bus.",
+	})
+
+	eventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tracker_mutation_events_dropped_total",
+		Help: "The number of graph mutations that couldn't be published to the event bus.",
+	}, []string{"reason"})
+)
+
+// DriverKafka publishes to Kafka through the Kafka REST proxy, which is the
+// only supported event bus.
+const DriverKafka = "kafka"
+
+// Message is an event bound for the bus. Buses without keys ignore them.
+type Message struct {
+	Key   []byte
+	Value []byte
+}
+
+// Publisher writes messages to an event bus.
+type Publisher interface {
+	Publish(ctx context.Context, messages []*Message) error
+	Close() error
+}
+
+// Config selects the event bus mutations are published to. Publishing is
+// disabled when no driver is configured.
+type Config struct {
+	Driver    string
+	Address   string
+	Topic     string
+	QueueSize int
+}
+
+// WithFlags returns the flags used to configure the event bus.
+func WithFlags(cfg *Config) (*Config, []cli.Flag) {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "event-bus",
+			Usage:       "the event bus graph mutations are published to; only kafka, through the kafka rest proxy, is supported, disabled when empty",
+			Value:       cfg.Driver,
+			Destination: &(cfg.Driver),
+			EnvVars:     []string{"EVENT_BUS"},
+		},
+		&cli.StringFlag{
+			Name:        "event-bus-address",
+			Usage:       "the url of the kafka rest proxy",
+			Value:       cfg.Address,
+			Destination: &(cfg.Address),
+			EnvVars:     []string{"EVENT_BUS_ADDRESS"},
+		},
+		&cli.StringFlag{
+			Name:        "event-bus-topic",
+			Usage:       "the kafka topic graph mutations are published to",
+			Value:       cfg.Topic,
+			Destination: &(cfg.Topic),
+			EnvVars:     []string{"EVENT_BUS_TOPIC"},
+		},
+		&cli.IntFlag{
+			Name:        "event-bus-queue-size",
+			Usage:       "the number of mutations held while waiting on the event bus, mutations are dropped when the queue is full",
+			Value:       cfg.QueueSize,
+			Destination: &(cfg.QueueSize),
+			EnvVars:     []string{"EVENT_BUS_QUEUE_SIZE"},
+		},
+	}
+
+	return cfg, flags
+}
+
+// Publisher returns the publisher for the configured driver.
+func (c *Config) Publisher() (Publisher, error) {
+	if c.Address == "" || c.Topic == "" {
+		return nil, fmt.Errorf("the event bus requires an address and topic")
+	}
+
+	if c.Driver != DriverKafka {
+		return nil, fmt.Errorf("unsupported event bus %s, only kafka is supported", c.Driver)
+	}
+	return NewKafkaPublisher(c.Address, c.Topic), nil
+}
+
+// maxBatch limits the number of messages published at once.
+const maxBatch = 100
+
+// Bus queues messages so writes to the graph don't wait on the event bus.
+// Messages are published in the order they're sent. Failed batches are
+// retried as a whole, so consumers may see a message more than once, and are
+// dropped once the retries run out. A nil Bus discards everything it's sent.
+type Bus struct {
+	publisher Publisher
+	queue     chan *Message
+	backoff   time.Duration
+}
+
+// NewBus returns a bus publishing to the configured event bus, or nil when
+// publishing is disabled.
+func NewBus(cfg *Config) (*Bus, error) {
+	if cfg == nil || cfg.Driver == "" {
+		return nil, nil
+	}
+
+	publisher, err := cfg.Publisher()
+	if err != nil {
+		return nil, err
+	}
+
+	return newBus(publisher, cfg.QueueSize), nil
+}
+
+func newBus(publisher Publisher, queueSize int) *Bus {
+	if queueSize <= 0 {
+		queueSize = 10000
+	}
+
+	return &Bus{
+		publisher: publisher,
+		queue:     make(chan *Message, queueSize),
+		backoff:   time.Second,
+	}
+}
+
+// Send queues the messages. Messages that don't fit in the queue are dropped
+// rather than holding up the write that produced them.
+func (b *Bus) Send(ctx context.Context, messages ...*Message) {
+	if b == nil {
+		return
+	}
+
+	dropped := 0
+	for _, message := range messages {
+		select {
+		case b.queue <- message:
+		default:
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		logging.FromContext(ctx).Errorf("[eventbus] dropping %d mutations: the queue is full", dropped)
+		eventsDropped.WithLabelValues("queue_full").Add(float64(dropped))
+	}
+}
+
+// Run publishes queued messages in batches until the context is done. Each
+// batch is attempted a few times before it's dropped.
+func (b *Bus) Run(ctx context.Context) {
+	if b == nil {
+		return
+	}
+	defer b.publisher.Close()
+
+	for {
+		var batch []*Message
+
+		select {
+		case <-ctx.Done():
+			return
+		case message := <-b.queue:
+			batch = append(batch, message)
+		}
+
+	drain:
+		for len(batch) < maxBatch {
+			select {
+			case message := <-b.queue:
+				batch = append(batch, message)
+			default:
+				break drain
+			}
+		}
+
+		b.publish(ctx, batch)
+	}
+}
+
+func (b *Bus) publish(ctx context.Context, batch []*Message) {
+	backoff := b.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := b.publisher.Publish(ctx, batch)
+		if err == nil {
+			eventsPublished.Add(float64(len(batch)))
+			return
+		}
+
+		if attempt == 3 {
+			logging.FromContext(ctx).Errorf("[eventbus] dropping %d mutations: %s", len(batch), err.Error())
+			eventsDropped.WithLabelValues("publish_failed").Add(float64(len(batch)))
+			return
+		}
+
+		logging.FromContext(ctx).Warnf("[eventbus] failed to publish %d mutations: %s", len(batch), err.Error())
+
+		select {
+		case <-ctx.Done():
+			logging.FromContext(ctx).Errorf("[eventbus] dropping %d mutations: shutting down", len(batch))
+			eventsDropped.WithLabelValues("shutdown").Add(float64(len(batch)))
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
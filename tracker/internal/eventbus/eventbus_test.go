@@ -0,0 +1,137 @@
+package eventbus_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/tracker/internal/eventbus"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMutation_roundTrip(t *testing.T) {
+	mutation := &eventbus.Mutation{
+		Operation: eventbus.OperationDelete,
+		Tenant:    "acme",
+		Timestamp: time.Unix(0, 1600000000123456789),
+		Item: &store.GraphItem{
+			GraphItemType: "depends",
+			K1:            []byte("a"),
+			K2:            []byte("b"),
+			K3:            []byte("c"),
+			Encoding:      store.GraphItemEncoding_JSON,
+			GraphItemData: []byte(`{}`),
+		},
+	}
+
+	data, err := mutation.Marshal()
+	require.NoError(t, err)
+
+	decoded, err := eventbus.UnmarshalMutation(data)
+	require.NoError(t, err)
+	require.Equal(t, mutation.Operation, decoded.Operation)
+	require.Equal(t, mutation.Tenant, decoded.Tenant)
+	require.True(t, mutation.Timestamp.Equal(decoded.Timestamp))
+	require.Equal(t, mutation.Item.GetGraphItemType(), decoded.Item.GetGraphItemType())
+	require.Equal(t, mutation.Item.GetK1(), decoded.Item.GetK1())
+	require.Equal(t, mutation.Item.GetK3(), decoded.Item.GetK3())
+	require.Equal(t, mutation.Item.GetGraphItemData(), decoded.Item.GetGraphItemData())
+
+	empty, err := (&eventbus.Mutation{}).Marshal()
+	require.NoError(t, err)
+	require.Empty(t, empty)
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	var records []map[string][]byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/topics/mutations", r.URL.Path)
+		require.Equal(t, "application/vnd.kafka.binary.v2+json", r.Header.Get("Content-Type"))
+
+		body := struct {
+			Records []map[string][]byte `json:"records"`
+		}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		records = append(records, body.Records...)
+
+		if string(body.Records[0]["value"]) == "fail" {
+			_, _ = io.WriteString(w, `{"offsets":[{"partition":null,"offset":null,"error_code":50002,"error":"boom"}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"offsets":[{"partition":0,"offset":1,"error_code":null,"error":null}]}`)
+	}))
+	defer server.Close()
+
+	publisher := eventbus.NewKafkaPublisher(server.URL+"/", "mutations")
+	defer publisher.Close()
+
+	ctx := context.Background()
+	err := publisher.Publish(ctx, []*eventbus.Message{{Key: []byte("k"), Value: []byte("v")}})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "k", string(records[0]["key"]))
+	require.Equal(t, "v", string(records[0]["value"]))
+
+	err = publisher.Publish(ctx, []*eventbus.Message{{Value: []byte("fail")}})
+	require.Error(t, err)
+}
+
+func TestBus(t *testing.T) {
+	{
+		bus, err := eventbus.NewBus(&eventbus.Config{})
+		require.NoError(t, err)
+		require.Nil(t, bus)
+
+		// disabled buses discard messages
+		bus.Send(context.Background(), &eventbus.Message{Value: []byte("a")})
+		bus.Run(context.Background())
+	}
+
+	{
+		_, err := eventbus.NewBus(&eventbus.Config{Driver: "rabbitmq", Address: "localhost", Topic: "mutations"})
+		require.Error(t, err)
+	}
+
+	received := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Records []map[string][]byte `json:"records"`
+		}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		for _, record := range body.Records {
+			received <- string(record["value"])
+		}
+		_, _ = io.WriteString(w, `{"offsets":[]}`)
+	}))
+	defer server.Close()
+
+	bus, err := eventbus.NewBus(&eventbus.Config{
+		Driver:  eventbus.DriverKafka,
+		Address: server.URL,
+		Topic:   "mutations",
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bus.Run(ctx)
+
+	bus.Send(context.Background(), &eventbus.Message{Value: []byte("a")}, &eventbus.Message{Value: []byte("b")})
+
+	for _, expected := range []string{"a", "b"} {
+		select {
+		case value := <-received:
+			require.Equal(t, expected, value)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting on %s", expected)
+		}
+	}
+}
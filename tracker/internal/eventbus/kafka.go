@@ -0,0 +1,88 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// kafkaPublisher produces messages to a topic through the Kafka REST proxy,
+// which avoids depending on a native Kafka client.
+type kafkaPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewKafkaPublisher returns a publisher for the topic using the REST proxy at
+// the url.
+func NewKafkaPublisher(proxyURL, topic string) Publisher {
+	return &kafkaPublisher{
+		url:    strings.TrimSuffix(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type kafkaRecord struct {
+	// binary keys and values are base64 encoded by encoding/json
+	Key   []byte `json:"key,omitempty"`
+	Value []byte `json:"value"`
+}
+
+type kafkaResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (k *kafkaPublisher) Publish(ctx context.Context, messages []*Message) error {
+	records := make([]*kafkaRecord, len(messages))
+	for i, message := range messages {
+		records[i] = &kafkaRecord{Key: message.Key, Value: message.Value}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.binary.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kafka rest proxy responded with %s", resp.Status)
+	}
+
+	response := &kafkaResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return err
+	}
+
+	// records are produced individually, so the batch can partially fail
+	for _, offset := range response.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka failed to produce a record: %s", offset.Error)
+		}
+	}
+
+	return nil
+}
+
+func (k *kafkaPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package eventbus
+
+import (
+	"time"
+
+	"github.com/depscloud/api/v1alpha/store"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+//go:generate protoc -I=. -I=$GOPATH/src --gogo_out=Mdepscloud_api/v1alpha/store/store.proto=github.com/depscloud/api/v1alpha/store:. mutation.proto
+
+// Operation is the kind of write a mutation describes.
+type Operation = GraphMutation_Operation
+
+// The operations of a GraphMutation.
+const (
+	OperationUnknown = GraphMutation_UNKNOWN
+	OperationPut     = GraphMutation_PUT
+	OperationDelete  = GraphMutation_DELETE
+)
+
+// Mutation is a write made to a node or edge of the graph. It's published
+// using the GraphMutation message described by mutation.proto.
+type Mutation struct {
+	Operation Operation
+	Tenant    string
+	Timestamp time.Time
+	Item      *store.GraphItem
+}
+
+// Marshal encodes the mutation as a GraphMutation.
+func (m *Mutation) Marshal() ([]byte, error) {
+	message := &GraphMutation{
+		Operation: m.Operation,
+		Tenant:    m.Tenant,
+		Item:      m.Item,
+	}
+
+	if !m.Timestamp.IsZero() {
+		message.Timestamp = m.Timestamp.UnixNano()
+	}
+
+	return proto.Marshal(message)
+}
+
+// UnmarshalMutation decodes a GraphMutation. Unknown fields are skipped so
+// consumers keep working as fields are added.
+func UnmarshalMutation(data []byte) (*Mutation, error) {
+	message := &GraphMutation{}
+	if err := proto.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+
+	m := &Mutation{
+		Operation: message.GetOperation(),
+		Tenant:    message.GetTenant(),
+		Item:      message.GetItem(),
+	}
+
+	if message.GetTimestamp() != 0 {
+		m.Timestamp = time.Unix(0, message.GetTimestamp())
+	}
+
+	return m, nil
+}
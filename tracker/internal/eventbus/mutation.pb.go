@@ -0,0 +1,144 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: mutation.proto
+
+package eventbus
+
+import (
+	fmt "fmt"
+	store "github.com/depscloud/api/v1alpha/store"
+	proto "github.com/gogo/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+type GraphMutation_Operation int32
+
+const (
+	GraphMutation_UNKNOWN GraphMutation_Operation = 0
+	GraphMutation_PUT     GraphMutation_Operation = 1
+	GraphMutation_DELETE  GraphMutation_Operation = 2
+)
+
+var GraphMutation_Operation_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "PUT",
+	2: "DELETE",
+}
+
+var GraphMutation_Operation_value = map[string]int32{
+	"UNKNOWN": 0,
+	"PUT":     1,
+	"DELETE":  2,
+}
+
+func (x GraphMutation_Operation) String() string {
+	return proto.EnumName(GraphMutation_Operation_name, int32(x))
+}
+
+func (GraphMutation_Operation) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_abb5a9726058e37d, []int{0, 0}
+}
+
+// GraphMutation is published to the event bus for each node and edge the
+// tracker writes. Messages are keyed by the base64 encoded k1 of the item so
+// the mutations of a node are kept in order.
+type GraphMutation struct {
+	Operation GraphMutation_Operation `protobuf:"varint,1,opt,name=operation,proto3,enum=cloud.deps.tracker.v1alpha.events.GraphMutation_Operation" json:"operation,omitempty"`
+	Tenant    string                  `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	// timestamp is the time of the write in nanoseconds since the epoch.
+	Timestamp            int64            `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Item                 *store.GraphItem `protobuf:"bytes,4,opt,name=item,proto3" json:"item,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *GraphMutation) Reset()         { *m = GraphMutation{} }
+func (m *GraphMutation) String() string { return proto.CompactTextString(m) }
+func (*GraphMutation) ProtoMessage()    {}
+func (*GraphMutation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_abb5a9726058e37d, []int{0}
+}
+func (m *GraphMutation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GraphMutation.Unmarshal(m, b)
+}
+func (m *GraphMutation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GraphMutation.Marshal(b, m, deterministic)
+}
+func (m *GraphMutation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GraphMutation.Merge(m, src)
+}
+func (m *GraphMutation) XXX_Size() int {
+	return xxx_messageInfo_GraphMutation.Size(m)
+}
+func (m *GraphMutation) XXX_DiscardUnknown() {
+	xxx_messageInfo_GraphMutation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GraphMutation proto.InternalMessageInfo
+
+func (m *GraphMutation) GetOperation() GraphMutation_Operation {
+	if m != nil {
+		return m.Operation
+	}
+	return GraphMutation_UNKNOWN
+}
+
+func (m *GraphMutation) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+func (m *GraphMutation) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *GraphMutation) GetItem() *store.GraphItem {
+	if m != nil {
+		return m.Item
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("cloud.deps.tracker.v1alpha.events.GraphMutation_Operation", GraphMutation_Operation_name, GraphMutation_Operation_value)
+	proto.RegisterType((*GraphMutation)(nil), "cloud.deps.tracker.v1alpha.events.GraphMutation")
+}
+
+func init() { proto.RegisterFile("mutation.proto", fileDescriptor_abb5a9726058e37d) }
+
+var fileDescriptor_abb5a9726058e37d = []byte{
+	// 267 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x90, 0x41, 0x4b, 0xc3, 0x30,
+	0x14, 0xc7, 0xcd, 0x3a, 0x3a, 0xfb, 0x86, 0xa3, 0xe4, 0x20, 0x45, 0x3c, 0xd4, 0x5d, 0xd6, 0x8b,
+	0x19, 0xce, 0x9b, 0xde, 0xc4, 0x22, 0xa2, 0x76, 0x52, 0x36, 0x14, 0x2f, 0x92, 0x6d, 0x0f, 0x56,
+	0x5c, 0x9b, 0x90, 0xbc, 0xee, 0x4b, 0xf8, 0xa5, 0xc5, 0xb4, 0x6e, 0x7a, 0xf2, 0x12, 0x78, 0x79,
+	0xc9, 0xef, 0xfd, 0xfe, 0x0f, 0x06, 0x65, 0x4d, 0x92, 0x0a, 0x55, 0x09, 0x6d, 0x14, 0x29, 0x7e,
+	0xb6, 0xdc, 0xa8, 0x7a, 0x25, 0x56, 0xa8, 0xad, 0x20, 0x23, 0x97, 0x1f, 0x68, 0xc4, 0xf6, 0x42,
+	0x6e, 0xf4, 0x5a, 0x0a, 0xdc, 0x62, 0x45, 0xf6, 0x64, 0xf4, 0xdd, 0x74, 0xcf, 0xde, 0xa5, 0x2e,
+	0xc6, 0x6d, 0x77, 0x6c, 0x49, 0x19, 0x6c, 0xce, 0x86, 0x35, 0xfc, 0xec, 0xc0, 0xd1, 0x9d, 0x91,
+	0x7a, 0xfd, 0xd4, 0xce, 0xe0, 0xaf, 0x10, 0x28, 0x8d, 0xc6, 0x15, 0x11, 0x8b, 0x59, 0x32, 0x98,
+	0x5c, 0x89, 0x7f, 0x27, 0x8a, 0x3f, 0x10, 0x31, 0xfd, 0x21, 0xe4, 0x7b, 0x18, 0x3f, 0x06, 0x9f,
+	0xb0, 0x92, 0x15, 0x45, 0x9d, 0x98, 0x25, 0x41, 0xde, 0x56, 0xfc, 0x14, 0x02, 0x2a, 0x4a, 0xb4,
+	0x24, 0x4b, 0x1d, 0x79, 0x31, 0x4b, 0xbc, 0x7c, 0x7f, 0xc1, 0xaf, 0xa1, 0x5b, 0x10, 0x96, 0x51,
+	0x37, 0x66, 0x49, 0x7f, 0x32, 0xfa, 0xad, 0x22, 0x75, 0xb1, 0xd3, 0x68, 0x42, 0x39, 0x8b, 0x7b,
+	0xc2, 0x32, 0x77, 0x9f, 0x86, 0xe7, 0x10, 0xec, 0x54, 0x78, 0x1f, 0x7a, 0xf3, 0xec, 0x21, 0x9b,
+	0xbe, 0x64, 0xe1, 0x01, 0xef, 0x81, 0xf7, 0x3c, 0x9f, 0x85, 0x8c, 0x03, 0xf8, 0xb7, 0xe9, 0x63,
+	0x3a, 0x4b, 0xc3, 0xce, 0x0d, 0xbc, 0x1d, 0xba, 0x38, 0x8b, 0xda, 0x2e, 0x7c, 0xb7, 0xa0, 0xcb,
+	0xaf, 0x01, 0x00, 0x5e, 0x3c, 0xd3, 0x3a, 0x7e, 0x01, 0x00, 0x00,
+}
@@ -0,0 +1,129 @@
+package v1alpha
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/depscloud/internal/audit"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditQuery narrows the audit records that are listed. Empty fields match
+// every record.
+type AuditQuery struct {
+	Service string
+	Subject string
+	Action  string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
+
+// AuditLog stores the audit records written by the services, so they can be
+// queried by the admins of each tenant.
+type AuditLog interface {
+	// WriteAuditRecords stores the records under the tenant of each record.
+	WriteAuditRecords(ctx context.Context, records []*audit.Record) error
+
+	// ListAuditRecords returns the records of the tenant matching the query,
+	// newest first.
+	ListAuditRecords(ctx context.Context, query *AuditQuery) ([]*audit.Record, error)
+}
+
+type auditRow struct {
+	Tenant     string `db:"tenant"`
+	RecordedAt int64  `db:"recorded_at"`
+	Service    string `db:"service"`
+	Subject    string `db:"subject"`
+	Action     string `db:"action"`
+	Resource   string `db:"resource"`
+	Status     int    `db:"status"`
+	Error      string `db:"error"`
+}
+
+func (gs *graphStore) WriteAuditRecords(ctx context.Context, records []*audit.Record) error {
+	if gs.rwdb == nil || gs.statements.InsertAuditRecord == "" {
+		return api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	return gs.inTx(ctx, func(tx *sqlx.Tx) error {
+		for _, record := range records {
+			_, err := tx.NamedExecContext(ctx, gs.statements.InsertAuditRecord, &auditRow{
+				Tenant:     record.Tenant,
+				RecordedAt: record.Time.UnixNano(),
+				Service:    record.Service,
+				Subject:    record.Subject,
+				Action:     record.Action,
+				Resource:   record.Resource,
+				Status:     record.Status,
+				Error:      record.Error,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (gs *graphStore) ListAuditRecords(ctx context.Context, query *AuditQuery) ([]*audit.Record, error) {
+	if gs.statements.SelectAuditRecords == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	until := int64(math.MaxInt64)
+	if !query.Until.IsZero() {
+		until = query.Until.UnixNano()
+	}
+
+	since := int64(0)
+	if !query.Since.IsZero() {
+		since = query.Since.UnixNano()
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := gs.rodb().NamedQueryContext(ctx, gs.statements.SelectAuditRecords, map[string]interface{}{
+		"tenant":  scopeFor(ctx).name,
+		"since":   since,
+		"until":   until,
+		"service": query.Service,
+		"subject": query.Subject,
+		"action":  query.Action,
+		"limit":   query.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*audit.Record, 0)
+	for rows.Next() {
+		row := &auditRow{}
+		if err := rows.StructScan(row); err != nil {
+			return nil, err
+		}
+
+		results = append(results, &audit.Record{
+			Time:     time.Unix(0, row.RecordedAt).UTC(),
+			Service:  row.Service,
+			Tenant:   row.Tenant,
+			Subject:  row.Subject,
+			Action:   row.Action,
+			Resource: row.Resource,
+			Status:   row.Status,
+			Error:    row.Error,
+		})
+	}
+
+	return results, rows.Err()
+}
+
+var _ AuditLog = &graphStore{}
@@ -0,0 +1,273 @@
+package v1alpha
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/asof"
+	"github.com/depscloud/depscloud/internal/features"
+	"github.com/depscloud/depscloud/internal/filters"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	findCacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tracker_find_cache_hits_total",
+		Help: "The number of upstream and downstream lookups answered from the cache.",
+	}, []string{"direction"})
+
+	findCacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tracker_find_cache_misses_total",
+		Help: "The number of upstream and downstream lookups read from the database.",
+	}, []string{"direction"})
+)
+
+// CacheConfig controls the cache of upstream and downstream lookups. A handful
+// of popular modules account for most lookups, so keeping their results in
+// memory takes load off of the database.
+//
+// Writes through the graph store evict the results they touch. Writes made by
+// other tracker replicas can't be seen, so entries also expire after the TTL.
+type CacheConfig struct {
+	Size int
+	TTL  time.Duration
+}
+
+// Apply enables the cache on a graph store created by this package. It's safe
+// to call on a nil config, and a size of zero leaves the cache disabled.
+func (c *CacheConfig) Apply(server store.GraphStoreServer) {
+	if c == nil || c.Size <= 0 {
+		return
+	}
+
+	gs, ok := server.(*graphStore)
+	if !ok {
+		logrus.Warnf("[graphstore] caching is not supported by %T", server)
+		return
+	}
+
+	gs.cache = newFindCache(c.Size, c.TTL)
+}
+
+type findCacheEntry struct {
+	key     string
+	nodes   []string
+	expires time.Time
+	pairs   []*store.GraphItemPair
+}
+
+// findCache is a least recently used cache of find responses. Entries are
+// indexed by the nodes they involve so writes can evict them.
+type findCache struct {
+	mu         sync.Mutex
+	size       int
+	ttl        time.Duration
+	generation uint64
+	order      *list.List
+	entries    map[string]*list.Element
+	nodes      map[string]map[string]bool
+}
+
+func newFindCache(size int, ttl time.Duration) *findCache {
+	return &findCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		nodes:   make(map[string]map[string]bool),
+	}
+}
+
+// nodeKey identifies a node within a tenant.
+func nodeKey(tenant string, key []byte) string {
+	return tenant + "\x00" + string(key)
+}
+
+// lookup describes a find request that can be served from the cache.
+type lookup struct {
+	key        string
+	tenant     string
+	direction  string
+	generation uint64
+}
+
+// lookupFor returns the lookup for the request. Reads from the history aren't
+// cached, nor are reads while the find cache feature is off, so false is
+// returned for them.
+func (c *findCache) lookupFor(ctx context.Context, direction string, req *store.FindRequest) (*lookup, bool) {
+	if c == nil || !features.Enabled(ctx, features.FindCache) {
+		return nil, false
+	}
+
+	if asOf, err := asof.FromIncomingContext(ctx); err != nil || !asOf.IsZero() {
+		return nil, false
+	}
+
+	tenant := scopeFor(ctx).name
+	filter := filters.FromIncomingContext(ctx)
+
+	parts := []string{
+		direction, tenant,
+		filter.Language, filter.Organization, filter.NamePrefix, filter.Labels,
+		strings.Join(req.GetEdgeTypes(), ","),
+		strings.Join(req.GetNodeTypes(), ","),
+	}
+	for _, key := range req.GetKeys() {
+		parts = append(parts, Base64encode(key))
+	}
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	return &lookup{
+		key:        strings.Join(parts, "\x00"),
+		tenant:     tenant,
+		direction:  direction,
+		generation: generation,
+	}, true
+}
+
+func (c *findCache) get(l *lookup) ([]*store.GraphItemPair, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[l.key]
+	if ok && c.ttl > 0 && time.Now().After(element.Value.(*findCacheEntry).expires) {
+		c.remove(element)
+		ok = false
+	}
+
+	if !ok {
+		findCacheMisses.WithLabelValues(l.direction).Inc()
+		return nil, false
+	}
+
+	findCacheHits.WithLabelValues(l.direction).Inc()
+	c.order.MoveToFront(element)
+	return element.Value.(*findCacheEntry).pairs, true
+}
+
+// put caches the pairs read for the lookup. Results are dropped when a write
+// was made while they were read since they may predate it.
+func (c *findCache) put(l *lookup, req *store.FindRequest, pairs []*store.GraphItemPair) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != l.generation {
+		return
+	}
+
+	if element, ok := c.entries[l.key]; ok {
+		c.remove(element)
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range req.GetKeys() {
+		seen[nodeKey(l.tenant, key)] = true
+	}
+	for _, pair := range pairs {
+		seen[nodeKey(l.tenant, pair.GetNode().GetK1())] = true
+		seen[nodeKey(l.tenant, pair.GetEdge().GetK1())] = true
+		seen[nodeKey(l.tenant, pair.GetEdge().GetK2())] = true
+	}
+
+	entry := &findCacheEntry{
+		key:     l.key,
+		nodes:   make([]string, 0, len(seen)),
+		expires: time.Now().Add(c.ttl),
+		pairs:   pairs,
+	}
+
+	for node := range seen {
+		entry.nodes = append(entry.nodes, node)
+
+		if c.nodes[node] == nil {
+			c.nodes[node] = make(map[string]bool)
+		}
+		c.nodes[node][l.key] = true
+	}
+
+	c.entries[l.key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// invalidate evicts the entries involving any of the tenant's keys.
+func (c *findCache) invalidate(ctx context.Context, keys ...[]byte) {
+	if c == nil {
+		return
+	}
+
+	tenant := scopeFor(ctx).name
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	for _, key := range keys {
+		if len(key) == 0 {
+			continue
+		}
+
+		for entryKey := range c.nodes[nodeKey(tenant, key)] {
+			if element, ok := c.entries[entryKey]; ok {
+				c.remove(element)
+			}
+		}
+	}
+}
+
+// clear evicts every entry.
+func (c *findCache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	c.nodes = make(map[string]map[string]bool)
+}
+
+// invalidateItems evicts the entries involving the items.
+func (c *findCache) invalidateItems(ctx context.Context, items []*store.GraphItem) {
+	if c == nil {
+		return
+	}
+
+	keys := make([][]byte, 0, 2*len(items))
+	for _, item := range items {
+		keys = append(keys, item.GetK1(), item.GetK2())
+	}
+
+	c.invalidate(ctx, keys...)
+}
+
+func (c *findCache) remove(element *list.Element) {
+	entry := element.Value.(*findCacheEntry)
+
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+
+	for _, node := range entry.nodes {
+		delete(c.nodes[node], entry.key)
+		if len(c.nodes[node]) == 0 {
+			delete(c.nodes, node)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package v1alpha
+
+import (
+	"context"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/store"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	graphItemsWritten = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tracker_graph_items_written_total",
+		Help: "The number of items put into or deleted from the graph, by type.",
+	}, []string{"operation", "type"})
+
+	graphItemsRead = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tracker_graph_items_read_total",
+		Help: "The number of items and pairs read from the graph, by operation.",
+	}, []string{"operation"})
+)
+
+// recordWrites counts the items written by an operation.
+func recordWrites(operation string, items []*store.GraphItem) {
+	for _, item := range items {
+		graphItemsWritten.WithLabelValues(operation, item.GetGraphItemType()).Inc()
+	}
+}
+
+// Cardinality counts the items in the graph, which helps with capacity
+// planning and confirming that indexing stored something.
+type Cardinality interface {
+	// CountItems returns the number of items of each type and language across
+	// all tenants. Deleted items aren't counted, and items without a language
+	// are counted under the empty language.
+	CountItems(ctx context.Context) ([]*ItemCount, error)
+}
+
+// ItemCount is the number of items sharing a type and language.
+type ItemCount struct {
+	GraphItemType string
+	Language      string
+	Count         int64
+}
+
+func (gs *graphStore) CountItems(ctx context.Context) ([]*ItemCount, error) {
+	if gs.statements.CountGraphDataByLanguage == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := gs.rodb().QueryxContext(ctx, gs.statements.CountGraphDataByLanguage)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]*ItemCount, 0)
+	for rows.Next() {
+		count := &ItemCount{}
+		if err := rows.Scan(&count.GraphItemType, &count.Language, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+var _ Cardinality = &graphStore{}
@@ -0,0 +1,226 @@
+package v1alpha
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Counts answers how many nodes a find would return without reading them.
+type Counts interface {
+	// CountUpstream returns the number of distinct nodes FindUpstream would
+	// return for the request.
+	CountUpstream(ctx context.Context, req *store.FindRequest) (int64, error)
+
+	// CountDownstream returns the number of distinct nodes FindDownstream
+	// would return for the request.
+	CountDownstream(ctx context.Context, req *store.FindRequest) (int64, error)
+}
+
+// CountRebuilder recomputes the materialized counts of modules.
+type CountRebuilder interface {
+	// RebuildCounts recomputes the number of dependents and dependencies of
+	// every module from the edges in the graph. Counts are maintained as edges
+	// are written, so this only corrects drift, such as from writes made using
+	// statements that predate the counts.
+	RebuildCounts(ctx context.Context) error
+}
+
+// moduleCounts are the materialized counts of a module.
+type moduleCounts struct {
+	Dependents   int64 `db:"dependents"`
+	Dependencies int64 `db:"dependencies"`
+}
+
+func (gs *graphStore) CountUpstream(ctx context.Context, req *store.FindRequest) (int64, error) {
+	return gs.countFind(ctx, gs.statements.CountGraphDataUpstream, func(counts *moduleCounts) int64 {
+		return counts.Dependencies
+	}, req)
+}
+
+func (gs *graphStore) CountDownstream(ctx context.Context, req *store.FindRequest) (int64, error) {
+	return gs.countFind(ctx, gs.statements.CountGraphDataDownstream, func(counts *moduleCounts) int64 {
+		return counts.Dependents
+	}, req)
+}
+
+// materialized returns whether the request can be answered by the counts of
+// its module. Counts are kept per pair of modules connected by a depends edge,
+// so they can't answer filtered requests or requests spanning several keys.
+func (gs *graphStore) materialized(ctx context.Context, req *store.FindRequest) bool {
+	edgeTypes, nodeTypes := req.GetEdgeTypes(), req.GetNodeTypes()
+
+	return gs.statements.SelectModuleCounts != "" &&
+		len(req.GetKeys()) == 1 &&
+		len(edgeTypes) == 1 && edgeTypes[0] == types.DependsType &&
+		len(nodeTypes) == 1 && nodeTypes[0] == types.ModuleType &&
+		filters.FromIncomingContext(ctx).Empty()
+}
+
+func (gs *graphStore) countFind(ctx context.Context, statement string, materialized func(*moduleCounts) int64, req *store.FindRequest) (int64, error) {
+	if statement == "" {
+		return 0, api.ErrUnsupported
+	} else if len(req.GetKeys()) == 0 {
+		return 0, nil
+	}
+
+	scope := scopeFor(ctx)
+
+	keys := make([]string, len(req.GetKeys()))
+	for i, key := range scope.keys(req.GetKeys()) {
+		keys[i] = Base64encode(key)
+	}
+
+	if gs.materialized(ctx, req) {
+		counts, err := gs.selectModuleCounts(ctx, keys[0])
+		if err != nil {
+			return 0, err
+		}
+		return materialized(counts), nil
+	}
+
+	params := filterPatterns(filters.FromIncomingContext(ctx), req.GetNodeTypes())
+	params["keys"] = keys
+	params["edge_types"] = req.GetEdgeTypes()
+	params["node_types"] = req.GetNodeTypes()
+
+	query, args, err := sqlx.Named(statement, params)
+	if err != nil {
+		return 0, err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rodb := gs.rodb()
+
+	count := int64(0)
+	if err := rodb.QueryRowxContext(ctx, rodb.Rebind(query), args...).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (gs *graphStore) selectModuleCounts(ctx context.Context, key string) (*moduleCounts, error) {
+	query, args, err := sqlx.Named(gs.statements.SelectModuleCounts, map[string]interface{}{
+		"k1": key,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rodb := gs.rodb()
+
+	counts := &moduleCounts{}
+	err = rodb.QueryRowxContext(ctx, rodb.Rebind(query), args...).StructScan(counts)
+	if err == sql.ErrNoRows {
+		// modules without edges are never counted
+		return counts, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// countEdges returns the number of live edges between the nodes of the
+// params, whatever their k3.
+func (gs *graphStore) countEdges(ctx context.Context, tx *sqlx.Tx, params map[string]interface{}) (int64, error) {
+	query, args, err := sqlx.Named(gs.statements.CountActiveEdges, params)
+	if err != nil {
+		return 0, err
+	}
+
+	count := int64(0)
+	if err := tx.QueryRowxContext(ctx, tx.Rebind(query), args...).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// updateModuleCounts runs a write to the item described by the params. When
+// the item is a depends edge and the write connects or disconnects its modules,
+// the counts of both modules are updated in the same transaction. The same
+// edge reported by several sources only connects the modules once.
+func (gs *graphStore) updateModuleCounts(ctx context.Context, tx *sqlx.Tx, params map[string]interface{}, write func() error) error {
+	if gs.statements.CountActiveEdges == "" || gs.statements.UpsertModuleCounts == "" ||
+		params["graph_item_type"] != types.DependsType || params["k1"] == params["k2"] {
+		return write()
+	}
+
+	before, err := gs.countEdges(ctx, tx, params)
+	if err != nil {
+		return err
+	}
+
+	if err := write(); err != nil {
+		return err
+	}
+
+	after, err := gs.countEdges(ctx, tx, params)
+	if err != nil {
+		return err
+	}
+
+	var delta int64
+	switch {
+	case before == 0 && after > 0:
+		delta = 1
+	case before > 0 && after == 0:
+		delta = -1
+	default:
+		return nil
+	}
+
+	if _, err := tx.NamedExecContext(ctx, gs.statements.UpsertModuleCounts, map[string]interface{}{
+		"k1":           params["k2"],
+		"dependents":   delta,
+		"dependencies": 0,
+	}); err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExecContext(ctx, gs.statements.UpsertModuleCounts, map[string]interface{}{
+		"k1":           params["k1"],
+		"dependents":   0,
+		"dependencies": delta,
+	})
+	return err
+}
+
+func (gs *graphStore) RebuildCounts(ctx context.Context) error {
+	if gs.rwdb == nil || gs.statements.DeleteModuleCounts == "" || gs.statements.RebuildModuleCounts == "" {
+		return api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	return gs.inTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, gs.statements.DeleteModuleCounts); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, gs.statements.RebuildModuleCounts)
+		return err
+	})
+}
+
+var _ Counts = &graphStore{}
+var _ CountRebuilder = &graphStore{}
@@ -0,0 +1,105 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// statements use ! to escape LIKE patterns since every database treats it the
+// same way, unlike a backslash.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// jsonPattern returns a LIKE pattern that matches graph data containing the
+// field. Graph data is encoded using encoding/json, so the field and its value
+// are always written the same way. When prefix is set, the value only needs to
+// start with the provided value.
+func jsonPattern(field, value string, prefix bool) string {
+	if value == "" {
+		return "%"
+	}
+
+	encoded, _ := json.Marshal(value)
+	if prefix {
+		// drop the closing quote
+		encoded = encoded[:len(encoded)-1]
+	}
+
+	return "%" + likeEscaper.Replace(`"`+field+`":`+string(encoded)) + "%"
+}
+
+// labelsPattern returns a LIKE pattern that matches encoded labels satisfying
+// every selector. Since labels are encoded in key order, the selectors only
+// need to be sorted the same way to be matched by a single pattern.
+func labelsPattern(selector string) string {
+	selectors := make([][]string, 0)
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		selectors = append(selectors, strings.SplitN(part, "=", 2))
+	}
+
+	if len(selectors) == 0 {
+		return "%"
+	}
+
+	sort.SliceStable(selectors, func(i, j int) bool {
+		return selectors[i][0] < selectors[j][0]
+	})
+
+	pattern := "%"
+	for _, s := range selectors {
+		key := strings.TrimSpace(s[0])
+
+		var encoded string
+		if len(s) == 2 {
+			encoded = encodeLabel(key, strings.TrimSpace(s[1]))
+		} else {
+			// match any value by dropping the empty one
+			encoded = strings.TrimSuffix(encodeLabel(key, ""), `""]`)
+		}
+
+		pattern += likeEscaper.Replace(encoded) + "%"
+	}
+
+	return pattern
+}
+
+// filterPatterns returns the named parameters used to filter graph data of
+// the provided type. Filters that don't apply to the type match everything.
+func filterPatterns(filter *filters.Filter, graphItemTypes []string) map[string]interface{} {
+	patterns := map[string]interface{}{
+		"language_pattern":     "%",
+		"organization_pattern": "%",
+		"name_pattern":         "%",
+		"labels_pattern":       "%",
+	}
+
+	if filter.Empty() {
+		return patterns
+	}
+
+	// labels are matched against the node, whatever its type
+	patterns["labels_pattern"] = labelsPattern(filter.Labels)
+
+	if len(graphItemTypes) != 1 {
+		return patterns
+	}
+
+	switch graphItemTypes[0] {
+	case types.ModuleType:
+		patterns["language_pattern"] = jsonPattern("language", filter.Language, false)
+		patterns["organization_pattern"] = jsonPattern("organization", filter.Organization, false)
+		patterns["name_pattern"] = jsonPattern("name", filter.NamePrefix, true)
+	case types.SourceType:
+		patterns["name_pattern"] = jsonPattern("url", filter.NamePrefix, true)
+	}
+
+	return patterns
+}
@@ -0,0 +1,164 @@
+package v1alpha
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/store"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Change is a put or delete of an edge recorded in the history. From and To
+// are the current nodes at either end of the edge and are nil when the node
+// no longer exists.
+type Change struct {
+	Item      *store.GraphItem
+	From      *store.GraphItem
+	To        *store.GraphItem
+	Deleted   bool
+	Timestamp time.Time
+}
+
+// History provides the changes made to the edges of the graph over time.
+type History interface {
+	// Changes returns the changes made up until the provided time to edges
+	// that reference any of the keys, oldest first.
+	Changes(ctx context.Context, keys [][]byte, until time.Time) ([]*Change, error)
+}
+
+// recordHistory runs a statement that records a change to an item. Puts are
+// only recorded when the item is new or its data changed, so re-indexing an
+// unchanged source doesn't grow the history. Statement files that predate the
+// history leave the statement empty and are skipped.
+func (gs *graphStore) recordHistory(ctx context.Context, tx *sqlx.Tx, statement string, params map[string]interface{}) error {
+	if statement == "" {
+		return nil
+	}
+
+	_, err := tx.NamedExecContext(ctx, statement, params)
+	return err
+}
+
+func (gs *graphStore) Changes(ctx context.Context, keys [][]byte, until time.Time) ([]*Change, error) {
+	if gs.statements.SelectGraphDataHistory == "" {
+		return nil, api.ErrUnsupported
+	} else if len(keys) == 0 {
+		return make([]*Change, 0), nil
+	}
+
+	scope := scopeFor(ctx)
+
+	encoded := make([]string, len(keys))
+	for i, key := range scope.keys(keys) {
+		encoded[i] = Base64encode(key)
+	}
+
+	query, args, err := sqlx.Named(gs.statements.SelectGraphDataHistory, map[string]interface{}{
+		"keys":  encoded,
+		"until": until.UnixNano(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rodb := gs.rodb()
+
+	// transform the query to the DB specific bindvar type
+	query = rodb.Rebind(query)
+
+	rows, err := rodb.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := readChanges(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		change.Item = scope.unscopedItem(change.Item)
+		change.From = scope.unscopedItem(change.From)
+		change.To = scope.unscopedItem(change.To)
+	}
+
+	return changes, nil
+}
+
+func readChanges(rows *sqlx.Rows) ([]*Change, error) {
+	defer rows.Close()
+
+	results := make([]*Change, 0)
+
+	for rows.Next() {
+		var (
+			t          string
+			k1         string
+			k2         string
+			k3         string
+			enc        store.GraphItemEncoding
+			data       string
+			changeType string
+			changedAt  int64
+			fromType   sql.NullString
+			fromEnc    sql.NullInt32
+			fromData   sql.NullString
+			toType     sql.NullString
+			toEnc      sql.NullInt32
+			toData     sql.NullString
+		)
+
+		if err := rows.Scan(&t, &k1, &k2, &k3, &enc, &data, &changeType, &changedAt,
+			&fromType, &fromEnc, &fromData, &toType, &toEnc, &toData); err != nil {
+			return nil, err
+		}
+
+		k1Bytes, _ := Base64decode(k1)
+		k2Bytes, _ := Base64decode(k2)
+		k3Bytes, _ := Base64decode(k3)
+
+		results = append(results, &Change{
+			Item: &store.GraphItem{
+				GraphItemType: t,
+				K1:            k1Bytes,
+				K2:            k2Bytes,
+				K3:            k3Bytes,
+				Encoding:      enc,
+				GraphItemData: []byte(data),
+			},
+			From:      nodeFor(k1Bytes, fromType, fromEnc, fromData),
+			To:        nodeFor(k2Bytes, toType, toEnc, toData),
+			Deleted:   changeType == "delete",
+			Timestamp: time.Unix(0, changedAt),
+		})
+	}
+
+	return results, nil
+}
+
+func nodeFor(key []byte, t sql.NullString, enc sql.NullInt32, data sql.NullString) *store.GraphItem {
+	if !t.Valid {
+		return nil
+	}
+
+	return &store.GraphItem{
+		GraphItemType: t.String,
+		K1:            key,
+		K2:            key,
+		Encoding:      store.GraphItemEncoding(enc.Int32),
+		GraphItemData: []byte(data.String),
+	}
+}
+
+var _ History = &graphStore{}
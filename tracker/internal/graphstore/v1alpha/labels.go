@@ -0,0 +1,184 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Labels attaches key/value labels, like the owning team or a deprecation
+// status, to the nodes of the graph. Lists and searches can be narrowed to
+// the nodes matching a label selector using filters.
+type Labels interface {
+	// SetLabels replaces the labels of the node. Providing no labels removes
+	// them.
+	SetLabels(ctx context.Context, graphItemType string, key []byte, labels map[string]string) error
+
+	// GetLabels returns the labels of the nodes, indexed by key. Nodes
+	// without labels are left out.
+	GetLabels(ctx context.Context, graphItemType string, keys [][]byte) (map[string]map[string]string, error)
+}
+
+// ValidateLabels ensures the labels can be matched by selectors. Keys can't be
+// empty or contain a comma or equals sign, and values can't contain a comma.
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if k == "" || strings.ContainsAny(k, ",=") {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+
+		if strings.Contains(v, ",") {
+			return fmt.Errorf("invalid value for label %q", k)
+		}
+	}
+	return nil
+}
+
+// encodeLabel encodes a label as a json array. Quotes are always escaped within
+// json strings, so an encoded label can't be matched inside of another.
+func encodeLabel(key, value string) string {
+	encoded, _ := json.Marshal([]string{key, value})
+	return string(encoded)
+}
+
+// encodeLabels encodes the labels in key order so they can be matched by
+// labelsPattern.
+func encodeLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	encoded := strings.Builder{}
+	for _, key := range keys {
+		encoded.WriteString(encodeLabel(key, labels[key]))
+	}
+	return encoded.String()
+}
+
+func decodeLabels(encoded string) (map[string]string, error) {
+	labels := make(map[string]string)
+
+	decoder := json.NewDecoder(strings.NewReader(encoded))
+	for {
+		label := make([]string, 0, 2)
+		if err := decoder.Decode(&label); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		} else if len(label) != 2 {
+			return nil, fmt.Errorf("malformed label")
+		}
+
+		labels[label[0]] = label[1]
+	}
+
+	return labels, nil
+}
+
+func (gs *graphStore) SetLabels(ctx context.Context, graphItemType string, key []byte, labels map[string]string) error {
+	if gs.rwdb == nil || gs.statements.UpsertLabels == "" {
+		return api.ErrUnsupported
+	}
+
+	if err := ValidateLabels(labels); err != nil {
+		return err
+	}
+
+	// label selectors narrow lookups, so results involving the node go stale
+	defer gs.cache.invalidate(ctx, key)
+
+	key = scopeFor(ctx).key(key)
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	params := map[string]interface{}{
+		"graph_item_type": graphItemType,
+		"k1":              Base64encode(key),
+	}
+
+	statement := gs.statements.DeleteLabels
+	if len(labels) > 0 {
+		statement = gs.statements.UpsertLabels
+		params["labels"] = encodeLabels(labels)
+		params["last_modified"] = time.Now()
+	}
+
+	_, err := gs.rwdb.NamedExecContext(ctx, statement, params)
+	return err
+}
+
+func (gs *graphStore) GetLabels(ctx context.Context, graphItemType string, keys [][]byte) (map[string]map[string]string, error) {
+	if gs.statements.SelectLabels == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	results := make(map[string]map[string]string)
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	scope := scopeFor(ctx)
+
+	encodedKeys := make([]string, len(keys))
+	for i, key := range scope.keys(keys) {
+		encodedKeys[i] = Base64encode(key)
+	}
+
+	query, args, err := sqlx.Named(gs.statements.SelectLabels, map[string]interface{}{
+		"graph_item_type": graphItemType,
+		"keys":            encodedKeys,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rodb := gs.rodb()
+
+	rows, err := rodb.QueryxContext(ctx, rodb.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k1, encoded string
+		if err := rows.Scan(&k1, &encoded); err != nil {
+			return nil, err
+		}
+
+		key, err := Base64decode(k1)
+		if err != nil {
+			return nil, err
+		}
+
+		labels, err := decodeLabels(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		results[string(scope.unscopedKey(key))] = labels
+	}
+
+	return results, rows.Err()
+}
+
+var _ Labels = &graphStore{}
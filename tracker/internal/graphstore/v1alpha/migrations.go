@@ -0,0 +1,123 @@
+package v1alpha
+
+import (
+	"github.com/depscloud/depscloud/tracker/internal/migrations"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SchemaName identifies the v1alpha schema in the schema_migrations table.
+const SchemaName = "v1alpha"
+
+// Migrations returns the versioned changes to the v1alpha schema. Each
+// migration must be able to run against databases that were created before
+// migrations were introduced.
+func Migrations(statements *Statements) []*migrations.Migration {
+	return []*migrations.Migration{
+		{
+			Version:     1,
+			Description: "create dts_graphdata",
+			Up:          []string{statements.CreateGraphDataTable},
+			Down:        []string{"DROP TABLE IF EXISTS dts_graphdata"},
+		},
+		{
+			Version:     2,
+			Description: "create dts_graphdata_history",
+			Up:          []string{statements.CreateGraphDataHistoryTable},
+			Down:        []string{"DROP TABLE IF EXISTS dts_graphdata_history"},
+		},
+		{
+			// items written before history was recorded are treated as
+			// having always existed
+			Version:     3,
+			Description: "backfill dts_graphdata_history",
+			Up:          []string{statements.BackfillGraphDataHistory},
+			Down:        []string{"DELETE FROM dts_graphdata_history WHERE changed_at = 0"},
+		},
+		{
+			Version:     4,
+			Description: "create dts_labels",
+			Up:          []string{statements.CreateLabelsTable},
+			Down:        []string{"DROP TABLE IF EXISTS dts_labels"},
+		},
+		{
+			// existing data belongs to the default tenant
+			Version:     5,
+			Description: "add tenant to dts_graphdata and dts_graphdata_history",
+			Up: []string{
+				"ALTER TABLE dts_graphdata ADD COLUMN tenant VARCHAR(64) NOT NULL DEFAULT ''",
+				"ALTER TABLE dts_graphdata_history ADD COLUMN tenant VARCHAR(64) NOT NULL DEFAULT ''",
+			},
+			Down: []string{
+				"ALTER TABLE dts_graphdata_history DROP COLUMN tenant",
+				"ALTER TABLE dts_graphdata DROP COLUMN tenant",
+			},
+		},
+		{
+			Version:     6,
+			Description: "create dts_idempotency_keys",
+			Up:          []string{statements.CreateIdempotencyKeysTable},
+			Down:        []string{"DROP TABLE IF EXISTS dts_idempotency_keys"},
+		},
+		{
+			Version:     7,
+			Description: "create dts_advisories",
+			Up:          []string{statements.CreateAdvisoriesTable},
+			Down:        []string{"DROP TABLE IF EXISTS dts_advisories"},
+		},
+		{
+			// existing advisories were recorded by the osv scan
+			Version:     8,
+			Description: "add source to dts_advisories",
+			Up:          []string{"ALTER TABLE dts_advisories ADD COLUMN source VARCHAR(32) NOT NULL DEFAULT 'osv'"},
+			Down:        []string{"ALTER TABLE dts_advisories DROP COLUMN source"},
+		},
+		{
+			Version:     9,
+			Description: "create dts_role_bindings",
+			Up:          []string{statements.CreateRoleBindingsTable},
+			Down:        []string{"DROP TABLE IF EXISTS dts_role_bindings"},
+		},
+		{
+			Version:     10,
+			Description: "create dts_audit_log",
+			Up:          []string{statements.CreateAuditLogTable},
+			Down:        []string{"DROP TABLE IF EXISTS dts_audit_log"},
+		},
+		{
+			Version:     11,
+			Description: "create dts_service_accounts and dts_tokens",
+			Up:          []string{statements.CreateServiceAccountsTable, statements.CreateTokensTable},
+			Down:        []string{"DROP TABLE IF EXISTS dts_tokens", "DROP TABLE IF EXISTS dts_service_accounts"},
+		},
+		{
+			Version:     12,
+			Description: "create and backfill dts_module_counts",
+			Up:          []string{statements.CreateModuleCountsTable, statements.RebuildModuleCounts},
+			Down:        []string{"DROP TABLE IF EXISTS dts_module_counts"},
+		},
+		{
+			// states are recorded as sources are tracked, until then the
+			// state is derived from the items of the source
+			Version:     13,
+			Description: "create dts_source_states",
+			Up:          []string{statements.CreateSourceStatesTable},
+			Down:        []string{"DROP TABLE IF EXISTS dts_source_states"},
+		},
+	}
+}
+
+// NewMigrator returns a migrator for the v1alpha schema of the sql driver.
+func NewMigrator(db *sqlx.DB, storageDriver string) (*migrations.Migrator, error) {
+	storageDriver, err := ResolveDriverName(storageDriver)
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := DefaultStatementsFor(storageDriver)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrations.NewMigrator(db, SchemaName, Migrations(statements)), nil
+}
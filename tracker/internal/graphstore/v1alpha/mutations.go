@@ -0,0 +1,59 @@
+package v1alpha
+
+import (
+	"context"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/tracker/internal/eventbus"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PublishMutations publishes each node and edge written through a graph
+// store created by this package to the bus. It's safe to call with a nil bus,
+// which leaves publishing disabled.
+func PublishMutations(server store.GraphStoreServer, bus *eventbus.Bus) {
+	if bus == nil {
+		return
+	}
+
+	gs, ok := server.(*graphStore)
+	if !ok {
+		logrus.Warnf("[graphstore] publishing mutations is not supported by %T", server)
+		return
+	}
+
+	gs.bus = bus
+}
+
+// publish sends the committed writes to the bus. Items are published as the
+// caller provided them, outside of their tenant's scope.
+func (gs *graphStore) publish(ctx context.Context, scope *tenantScope, operation eventbus.Operation, items []*store.GraphItem, timestamp time.Time) {
+	if gs.bus == nil {
+		return
+	}
+
+	messages := make([]*eventbus.Message, 0, len(items))
+	for _, item := range items {
+		value, err := (&eventbus.Mutation{
+			Operation: operation,
+			Tenant:    scope.name,
+			Timestamp: timestamp,
+			Item:      item,
+		}).Marshal()
+
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[graphstore] failed to encode mutation: %s", err.Error())
+			continue
+		}
+
+		messages = append(messages, &eventbus.Message{
+			Key:   []byte(scope.name + Base64encode(item.GetK1())),
+			Value: value,
+		})
+	}
+
+	gs.bus.Send(ctx, messages...)
+}
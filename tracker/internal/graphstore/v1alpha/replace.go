@@ -0,0 +1,237 @@
+package v1alpha
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/tracker/internal/eventbus"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// IdempotencyKeyTTL is how long an applied idempotency key is remembered.
+// Retries arriving later are applied again, which converges on the same items.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// ErrSourceChanged is returned when a source isn't in the state a replace
+// requires.
+var ErrSourceChanged = errors.New("source changed since the required state")
+
+// SourceState guards a replace on the state of the source it writes. The
+// state is recorded alongside the items of the source so it's compared and
+// swapped within the same transaction.
+type SourceState struct {
+	// Key identifies the source.
+	Key []byte
+
+	// IfState, when set, must match the state of the source for the replace
+	// to apply.
+	IfState string
+
+	// Observed is the state of the source as read before the replace. It
+	// stands in for sources whose state hasn't been recorded yet.
+	Observed string
+
+	// State is recorded once the replace applies. When the replace was
+	// already applied, it's set to the recorded state of the source.
+	State string
+}
+
+// Replacements swap one set of items in the graph for another atomically.
+type Replacements interface {
+	// Replace deletes and puts the items within a single transaction so
+	// readers see either the old items or the new items, never a mix of them.
+	// When a replace with the same idempotency key was already applied for
+	// the tenant, nothing is written and false is returned. When a source
+	// state is provided, ErrSourceChanged is returned if the source isn't in
+	// the required state.
+	Replace(ctx context.Context, idempotencyKey string, state *SourceState, toPut, toDelete []*store.GraphItem) (bool, error)
+}
+
+func (gs *graphStore) Replace(ctx context.Context, idempotencyKey string, state *SourceState, toPut, toDelete []*store.GraphItem) (_ bool, err error) {
+	if gs.rwdb == nil {
+		return false, api.ErrUnsupported
+	} else if idempotencyKey != "" && gs.statements.SelectIdempotencyKey == "" {
+		return false, api.ErrUnsupported
+	} else if state != nil && gs.statements.SelectSourceState == "" {
+		return false, api.ErrUnsupported
+	}
+
+	ctx, span := startTx(ctx, "graphstore.replace", len(toPut)+len(toDelete))
+	defer func() { span.End(err) }()
+
+	timestamp := time.Now()
+	scope := scopeFor(ctx)
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	// evict cached lookups once the write is visible
+	defer gs.cache.invalidateItems(ctx, toPut)
+	defer gs.cache.invalidateItems(ctx, toDelete)
+
+	var next string
+	if state != nil {
+		next = state.State
+	}
+
+	applied := false
+	err = gs.inTx(ctx, func(tx *sqlx.Tx) error {
+		applied = false
+
+		var recorded string
+		var found bool
+		if state != nil {
+			var err error
+			if recorded, found, err = gs.selectSourceState(ctx, tx, scope.key(state.Key)); err != nil {
+				return err
+			}
+		}
+
+		if idempotencyKey != "" {
+			params := map[string]interface{}{
+				"tenant":          scope.name,
+				"idempotency_key": idempotencyKey,
+				"applied_at":      timestamp.UnixNano(),
+				"before":          timestamp.Add(-IdempotencyKeyTTL).UnixNano(),
+			}
+
+			if _, err := tx.NamedExecContext(ctx, gs.statements.PurgeIdempotencyKeys, params); err != nil {
+				return err
+			}
+
+			rows, err := namedQueryTx(ctx, tx, gs.statements.SelectIdempotencyKey, params)
+			if err != nil {
+				return err
+			}
+
+			seen := int64(0)
+			if rows.Next() {
+				err = rows.Scan(&seen)
+			}
+			rows.Close()
+
+			if err != nil {
+				return err
+			} else if seen > 0 {
+				if found {
+					next = recorded
+				}
+				return nil
+			}
+
+			// concurrent retries conflict here, leaving one of them to apply
+			if _, err := tx.NamedExecContext(ctx, gs.statements.InsertIdempotencyKey, params); err != nil {
+				return err
+			}
+		}
+
+		if state != nil {
+			if err := gs.swapSourceState(ctx, tx, scope.key(state.Key), state, recorded, found); err != nil {
+				return err
+			}
+		}
+
+		for _, item := range scope.scopedItems(toDelete) {
+			if err := gs.deleteItem(ctx, tx, item, timestamp); err != nil {
+				return err
+			}
+		}
+
+		for _, item := range scope.scopedItems(toPut) {
+			if err := gs.putItem(ctx, tx, scope, item, timestamp); err != nil {
+				return err
+			}
+		}
+
+		applied = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if state != nil {
+		state.State = next
+	}
+
+	if !applied {
+		return false, nil
+	}
+
+	recordWrites("delete", toDelete)
+	recordWrites("put", toPut)
+	gs.publish(ctx, scope, eventbus.OperationDelete, toDelete, timestamp)
+	gs.publish(ctx, scope, eventbus.OperationPut, toPut, timestamp)
+	return true, nil
+}
+
+func (gs *graphStore) selectSourceState(ctx context.Context, tx *sqlx.Tx, key []byte) (string, bool, error) {
+	rows, err := namedQueryTx(ctx, tx, gs.statements.SelectSourceState, map[string]interface{}{
+		"k1": Base64encode(key),
+	})
+	if err != nil {
+		return "", false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return "", false, rows.Err()
+	}
+
+	var recorded string
+	if err := rows.Scan(&recorded); err != nil {
+		return "", false, err
+	}
+	return recorded, true, nil
+}
+
+// swapSourceState checks the state of the source and records its next state.
+// The update only applies while the recorded state is unchanged, so a
+// concurrent replace of the same source fails the check rather than both
+// being written.
+func (gs *graphStore) swapSourceState(ctx context.Context, tx *sqlx.Tx, key []byte, state *SourceState, recorded string, found bool) error {
+	current := state.Observed
+	if found {
+		current = recorded
+	}
+
+	if state.IfState != "" && state.IfState != current {
+		return ErrSourceChanged
+	}
+
+	params := map[string]interface{}{
+		"k1":       Base64encode(key),
+		"state":    state.State,
+		"previous": recorded,
+	}
+
+	if !found {
+		// concurrent first writes conflict on the key, leaving one to apply
+		_, err := tx.NamedExecContext(ctx, gs.statements.InsertSourceState, params)
+		return err
+	}
+
+	// mysql doesn't count rows left unchanged as updated
+	if recorded == state.State {
+		return nil
+	}
+
+	result, err := tx.NamedExecContext(ctx, gs.statements.UpdateSourceState, params)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if updated == 0 {
+		return ErrSourceChanged
+	}
+	return nil
+}
+
+var _ Replacements = &graphStore{}
@@ -0,0 +1,197 @@
+package v1alpha
+
+import (
+	"context"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/tenants"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RetentionPolicy describes how long data is kept in the graph. Each rule is
+// disabled when its age is zero.
+type RetentionPolicy struct {
+	// EdgeMaxAge tombstones edges that haven't been observed by an indexing
+	// run within the age.
+	EdgeMaxAge time.Duration
+	// SourceMaxAge tombstones sources that haven't been indexed within the
+	// age.
+	SourceMaxAge time.Duration
+	// TombstoneMaxAge purges tombstones older than the age.
+	TombstoneMaxAge time.Duration
+	// HistoryMaxAge purges versions of items that were replaced longer ago
+	// than the age. The version that was current at the cutoff is kept so as
+	// of reads after the cutoff are unaffected.
+	HistoryMaxAge time.Duration
+}
+
+// RetentionReport summarizes what a retention run changed, or would have
+// changed during a dry run.
+type RetentionReport struct {
+	DryRun           bool
+	StaleEdges       int64
+	StaleSources     int64
+	PurgedTombstones int64
+	PurgedHistory    int64
+}
+
+// Retention applies retention policies to the graph. Policies apply to the
+// data of every tenant.
+type Retention interface {
+	// ApplyRetention enforces the policy as of now. When dryRun is set,
+	// nothing is changed and the report describes what would be.
+	ApplyRetention(ctx context.Context, policy *RetentionPolicy, now time.Time, dryRun bool) (*RetentionReport, error)
+}
+
+func (gs *graphStore) ApplyRetention(ctx context.Context, policy *RetentionPolicy, now time.Time, dryRun bool) (*RetentionReport, error) {
+	if gs.rwdb == nil || gs.statements.SelectStaleGraphData == "" || gs.statements.PurgeAllTombstones == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	// stale items are read without a tenant and must be deleted without one
+	ctx = tenants.NewContext(ctx, "")
+
+	report := &RetentionReport{DryRun: dryRun}
+	var err error
+
+	// purge before tombstoning so this run's tombstones and history are kept
+	if policy.TombstoneMaxAge > 0 {
+		before := now.Add(-policy.TombstoneMaxAge)
+
+		if dryRun {
+			report.PurgedTombstones, err = gs.count(ctx, gs.statements.CountTombstones, map[string]interface{}{
+				"before": before.Local(),
+			})
+		} else {
+			report.PurgedTombstones, err = gs.exec(ctx, gs.statements.PurgeAllTombstones, map[string]interface{}{
+				"before": before.Local(),
+			})
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if policy.HistoryMaxAge > 0 {
+		params := map[string]interface{}{
+			"before": now.Add(-policy.HistoryMaxAge).UnixNano(),
+		}
+
+		if dryRun {
+			report.PurgedHistory, err = gs.count(ctx, gs.statements.CountSupersededHistory, params)
+		} else {
+			report.PurgedHistory, err = gs.exec(ctx, gs.statements.PurgeSupersededHistory, params)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if policy.EdgeMaxAge > 0 {
+		report.StaleEdges, err = gs.tombstoneStale(ctx, []string{types.ManagesType, types.DependsType}, now.Add(-policy.EdgeMaxAge), dryRun)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if policy.SourceMaxAge > 0 {
+		report.StaleSources, err = gs.tombstoneStale(ctx, []string{types.SourceType}, now.Add(-policy.SourceMaxAge), dryRun)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// tombstoneStale deletes the items of the given types that were last put
+// before the cutoff. Deletes go through Delete so they're recorded in the
+// history and can be restored.
+func (gs *graphStore) tombstoneStale(ctx context.Context, graphItemTypes []string, before time.Time, dryRun bool) (int64, error) {
+	query, args, err := sqlx.Named(gs.statements.SelectStaleGraphData, map[string]interface{}{
+		"graph_item_types": graphItemTypes,
+		"before":           before.Local(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	readCtx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := gs.rwdb.QueryxContext(readCtx, gs.rwdb.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	items := make([]*store.GraphItem, 0)
+	for rows.Next() {
+		var t, k1, k2, k3 string
+		if err := rows.Scan(&t, &k1, &k2, &k3); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		k1Bytes, _ := Base64decode(k1)
+		k2Bytes, _ := Base64decode(k2)
+		k3Bytes, _ := Base64decode(k3)
+
+		items = append(items, &store.GraphItem{GraphItemType: t, K1: k1Bytes, K2: k2Bytes, K3: k3Bytes})
+	}
+	rows.Close()
+
+	if dryRun || len(items) == 0 {
+		return int64(len(items)), nil
+	}
+
+	if _, err := gs.Delete(ctx, &store.DeleteRequest{Items: items}); err != nil {
+		return 0, err
+	}
+
+	return int64(len(items)), nil
+}
+
+func (gs *graphStore) count(ctx context.Context, statement string, params map[string]interface{}) (int64, error) {
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := gs.rwdb.NamedQueryContext(ctx, statement, params)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := int64(0)
+	if rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, rows.Err()
+}
+
+func (gs *graphStore) exec(ctx context.Context, statement string, params map[string]interface{}) (int64, error) {
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := gs.rwdb.NamedExecContext(ctx, statement, params)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+var _ Retention = &graphStore{}
@@ -0,0 +1,130 @@
+package v1alpha
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync/atomic"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/tracker/internal/sqlretry"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/require"
+)
+
+type serializationFailure struct{}
+
+func (serializationFailure) Error() string    { return "restart transaction: serialization failure" }
+func (serializationFailure) SQLState() string { return sqlretry.SerializationFailure }
+
+// flakyFailures is the number of commits left to abort.
+var flakyFailures int32
+
+// flakyDriver aborts commits with a serialization failure while flakyFailures
+// is positive, like cockroachdb does under contention.
+type flakyDriver struct {
+	driver.Driver
+}
+
+func (d flakyDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyConn{Conn: conn}, nil
+}
+
+type flakyConn struct {
+	driver.Conn
+}
+
+func (c *flakyConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *flakyConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyTx{Tx: tx}, nil
+}
+
+type flakyTx struct {
+	driver.Tx
+}
+
+func (t *flakyTx) Commit() error {
+	if atomic.AddInt32(&flakyFailures, -1) >= 0 {
+		_ = t.Tx.Rollback()
+		return serializationFailure{}
+	}
+	return t.Tx.Commit()
+}
+
+func init() {
+	sql.Register("sqlite3_flaky", flakyDriver{Driver: &sqlite3.SQLiteDriver{}})
+}
+
+func TestRetry_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3_flaky", "file:retry?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer db.Close()
+
+	rwdb := sqlx.NewDb(db, "sqlite3")
+
+	migrator, err := NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	server, err := newSQLGraphStore(rwdb, []*sqlx.DB{rwdb}, statements, nil)
+	require.Nil(t, err)
+
+	gs := server.(*graphStore)
+	gs.maxRetries = sqlretry.CockroachDBMaxRetries
+
+	module := func(key string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key), GraphItemData: []byte(key)}
+	}
+
+	count := func() int {
+		resp, err := gs.List(ctx, &store.ListRequest{Page: 1, Count: 10, Type: "module"})
+		require.Nil(t, err)
+		return len(resp.GetItems())
+	}
+
+	// aborted transactions are run again
+	atomic.StoreInt32(&flakyFailures, 2)
+	applied, err := gs.Replace(ctx, "run-1", nil, []*store.GraphItem{module("a"), module("b")}, nil)
+	require.Nil(t, err)
+	require.True(t, applied)
+	require.Equal(t, 2, count())
+
+	atomic.StoreInt32(&flakyFailures, 1)
+	_, err = gs.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{module("c")}})
+	require.Nil(t, err)
+	require.Equal(t, 3, count())
+
+	atomic.StoreInt32(&flakyFailures, 1)
+	_, err = gs.Delete(ctx, &store.DeleteRequest{Items: []*store.GraphItem{module("c")}})
+	require.Nil(t, err)
+	require.Equal(t, 2, count())
+
+	// until the retries run out
+	atomic.StoreInt32(&flakyFailures, int32(sqlretry.CockroachDBMaxRetries+1))
+	_, err = gs.Replace(ctx, "run-2", nil, []*store.GraphItem{module("d")}, nil)
+	require.True(t, sqlretry.IsRetryable(err))
+	require.Equal(t, 2, count())
+
+	atomic.StoreInt32(&flakyFailures, 0)
+}
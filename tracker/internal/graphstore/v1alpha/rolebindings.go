@@ -0,0 +1,93 @@
+package v1alpha
+
+import (
+	"context"
+
+	"github.com/depscloud/api"
+)
+
+// RoleBinding grants a role to a subject within the tenant, optionally
+// narrowed to the modules of an organization.
+type RoleBinding struct {
+	Subject      string `db:"subject"`
+	Organization string `db:"organization"`
+	Role         string `db:"role"`
+}
+
+// RoleBindings stores the role bindings managed through the api. Bindings are
+// kept per tenant.
+type RoleBindings interface {
+	// ListRoleBindings returns the bindings of the tenant.
+	ListRoleBindings(ctx context.Context) ([]*RoleBinding, error)
+
+	// PutRoleBinding sets the role of the subject within the organization,
+	// replacing any previous one.
+	PutRoleBinding(ctx context.Context, binding *RoleBinding) error
+
+	// DeleteRoleBinding removes the binding of the subject within the
+	// organization.
+	DeleteRoleBinding(ctx context.Context, subject, organization string) error
+}
+
+func (gs *graphStore) ListRoleBindings(ctx context.Context) ([]*RoleBinding, error) {
+	if gs.statements.SelectRoleBindings == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := gs.rodb().NamedQueryContext(ctx, gs.statements.SelectRoleBindings, map[string]interface{}{
+		"tenant": scopeFor(ctx).name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*RoleBinding, 0)
+	for rows.Next() {
+		binding := &RoleBinding{}
+		if err := rows.StructScan(binding); err != nil {
+			return nil, err
+		}
+		results = append(results, binding)
+	}
+
+	return results, rows.Err()
+}
+
+func (gs *graphStore) PutRoleBinding(ctx context.Context, binding *RoleBinding) error {
+	if gs.rwdb == nil || gs.statements.UpsertRoleBinding == "" {
+		return api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := gs.rwdb.NamedExecContext(ctx, gs.statements.UpsertRoleBinding, map[string]interface{}{
+		"tenant":       scopeFor(ctx).name,
+		"subject":      binding.Subject,
+		"organization": binding.Organization,
+		"role":         binding.Role,
+	})
+	return err
+}
+
+func (gs *graphStore) DeleteRoleBinding(ctx context.Context, subject, organization string) error {
+	if gs.rwdb == nil || gs.statements.DeleteRoleBinding == "" {
+		return api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := gs.rwdb.NamedExecContext(ctx, gs.statements.DeleteRoleBinding, map[string]interface{}{
+		"tenant":       scopeFor(ctx).name,
+		"subject":      subject,
+		"organization": organization,
+	})
+	return err
+}
+
+var _ RoleBindings = &graphStore{}
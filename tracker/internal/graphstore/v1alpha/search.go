@@ -0,0 +1,153 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/store"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Popularity describes how widely used a node is. Dependents is the number of
+// modules that depend on it and LastObserved is when it was last indexed.
+type Popularity struct {
+	Dependents   int64
+	LastObserved time.Time
+}
+
+// TextSearch finds nodes by the text of their data or labels. Results are
+// candidates, callers are expected to check which fields matched and rank
+// them.
+type TextSearch interface {
+	// SearchText returns up to limit nodes of the type whose data or labels
+	// contain the text. When fuzzy is set, the characters of the text only
+	// need to appear in order. Matching ignores case where the database
+	// allows it. The most popular nodes are returned first so they aren't
+	// cut off by the limit.
+	SearchText(ctx context.Context, graphItemType, text string, fuzzy bool, limit int) ([]*store.GraphItem, error)
+
+	// GetPopularity returns the popularity of the nodes, indexed by key.
+	GetPopularity(ctx context.Context, graphItemType string, keys [][]byte) (map[string]*Popularity, error)
+}
+
+// jsonText encodes text the same way it's written within a json string.
+func jsonText(text string) string {
+	encoded, _ := json.Marshal(text)
+	return string(encoded[1 : len(encoded)-1])
+}
+
+// textPattern returns a LIKE pattern that matches data containing the text,
+// or its characters in order when fuzzy is set.
+func textPattern(text string, fuzzy bool) string {
+	if !fuzzy {
+		return "%" + likeEscaper.Replace(jsonText(text)) + "%"
+	}
+
+	pattern := strings.Builder{}
+	pattern.WriteString("%")
+	for _, r := range text {
+		pattern.WriteString(likeEscaper.Replace(jsonText(string(r))))
+		pattern.WriteString("%")
+	}
+	return pattern.String()
+}
+
+func (gs *graphStore) SearchText(ctx context.Context, graphItemType, text string, fuzzy bool, limit int) ([]*store.GraphItem, error) {
+	if gs.statements.SearchGraphData == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	scope := scopeFor(ctx)
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := gs.rodb().NamedQueryContext(ctx, gs.statements.SearchGraphData, map[string]interface{}{
+		"graph_item_type": graphItemType,
+		"tenant":          scope.name,
+		"pattern":         textPattern(text, fuzzy),
+		"limit":           limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := readGraphItems(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return scope.unscopedItems(items), nil
+}
+
+func (gs *graphStore) GetPopularity(ctx context.Context, graphItemType string, keys [][]byte) (map[string]*Popularity, error) {
+	if gs.statements.SelectPopularity == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	results := make(map[string]*Popularity, len(keys))
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	scope := scopeFor(ctx)
+
+	encodedKeys := make([]string, len(keys))
+	for i, key := range scope.keys(keys) {
+		encodedKeys[i] = Base64encode(key)
+	}
+
+	query, args, err := sqlx.Named(gs.statements.SelectPopularity, map[string]interface{}{
+		"graph_item_type": graphItemType,
+		"keys":            encodedKeys,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rodb := gs.rodb()
+
+	rows, err := rodb.QueryxContext(ctx, rodb.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			k1           string
+			lastObserved scannedTime
+			dependents   int64
+		)
+
+		if err := rows.Scan(&k1, &lastObserved, &dependents); err != nil {
+			return nil, err
+		}
+
+		key, err := Base64decode(k1)
+		if err != nil {
+			return nil, err
+		}
+
+		results[string(scope.unscopedKey(key))] = &Popularity{
+			Dependents:   dependents,
+			LastObserved: lastObserved.Time,
+		}
+	}
+
+	return results, rows.Err()
+}
+
+var _ TextSearch = &graphStore{}
@@ -3,14 +3,21 @@ package v1alpha
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/depscloud/api"
 	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/asof"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/telemetry"
+	"github.com/depscloud/depscloud/tracker/internal/eventbus"
+	"github.com/depscloud/depscloud/tracker/internal/sqlpool"
+	"github.com/depscloud/depscloud/tracker/internal/sqlretry"
 
 	"github.com/jmoiron/sqlx"
-
-	"github.com/sirupsen/logrus"
 )
 
 // Constants representing the DBMS's supported with a mapping to the underlying driver names used
@@ -26,17 +33,35 @@ func ResolveDriverName(dbmsName string) (string, error) {
 	case "mysql":
 		return mysql, nil
 
-	case "sqlite":
+	case "sqlite", "sqlite3":
 		return sqlite, nil
 
-	case "postgres":
+	case "postgres", "postgresql", "pgx", "cockroach", "cockroachdb", "crdb":
+		// cockroachdb is compatible with the postgres statements
 		return postgres, nil
 	}
 
-	return "", fmt.Errorf("%s not supported, specify one of the supported systems; mysql/postgres/sqlite", dbmsName)
+	return "", fmt.Errorf("%s not supported, specify one of the supported systems; mysql/postgres/cockroachdb/sqlite", dbmsName)
+}
+
+// NewGraphStoreFor opens the storage tier for the driver. When multiple read
+// only addresses are provided, reads are balanced across them.
+func NewGraphStoreFor(storageDriver, storageAddress string, storageReadOnlyAddresses ...string) (server store.GraphStoreServer, err error) {
+	return NewGraphStoreWithPool(nil, storageDriver, storageAddress, storageReadOnlyAddresses...)
 }
 
-func NewGraphStoreFor(storageDriver, storageAddress, storageReadOnlyAddress string) (server store.GraphStoreServer, err error) {
+// NewGraphStoreWithPool is like NewGraphStoreFor, but configures the
+// connection pools. Pools for sqlite are left alone since closing the
+// connections of an in-memory database discards it.
+func NewGraphStoreWithPool(pool *sqlpool.Config, storageDriver, storageAddress string, storageReadOnlyAddresses ...string) (server store.GraphStoreServer, err error) {
+	// cockroachdb shares the postgres statements, but aborts conflicting
+	// transactions and expects the client to retry them
+	maxRetries := 0
+	switch storageDriver {
+	case "cockroach", "cockroachdb", "crdb":
+		maxRetries = sqlretry.CockroachDBMaxRetries
+	}
+
 	storageDriver, err = ResolveDriverName(storageDriver)
 	if err != nil {
 		return nil, err
@@ -49,51 +74,120 @@ func NewGraphStoreFor(storageDriver, storageAddress, storageReadOnlyAddress stri
 		if err != nil {
 			return nil, err
 		}
+
+		if storageDriver == sqlite {
+			// sqlite allows a single writer, serialize writes rather than
+			// failing with "database is locked"
+			rwdb.SetMaxOpenConns(1)
+		} else {
+			pool.Apply(rwdb.DB)
+		}
 	}
 
-	rodb := rwdb
-	if len(storageReadOnlyAddress) > 0 {
-		rodb, err = sqlx.Open(storageDriver, storageReadOnlyAddress)
+	rodbs := make([]*sqlx.DB, 0, len(storageReadOnlyAddresses))
+	for _, storageReadOnlyAddress := range storageReadOnlyAddresses {
+		if len(storageReadOnlyAddress) == 0 {
+			continue
+		}
+
+		rodb, err := sqlx.Open(storageDriver, storageReadOnlyAddress)
 		if err != nil {
 			return nil, err
 		}
+
+		if storageDriver != sqlite {
+			pool.Apply(rodb.DB)
+		}
+		rodbs = append(rodbs, rodb)
 	}
 
-	if rodb == nil && rwdb == nil {
+	if len(rodbs) == 0 && rwdb == nil {
 		return nil, fmt.Errorf("either a storage-address or storage-readonly-address must be provided")
 	}
 
+	// without replicas, reads go to the primary
+	if len(rodbs) == 0 {
+		rodbs = append(rodbs, rwdb)
+	}
+
 	statements, err := DefaultStatementsFor(storageDriver)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewSQLGraphStore(rwdb, rodb, statements)
+	gs, err := newSQLGraphStore(rwdb, rodbs, statements, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	gs.(*graphStore).maxRetries = maxRetries
+	return gs, nil
 }
 
 // NewSQLGraphStore constructs a new GraphStore with a sql driven backend. Current
-// queries support sqlite3 but should be able to work on mysql as well.
+// queries support sqlite3 but should be able to work on mysql as well. The
+// schema must already be migrated, see NewMigrator.
 func NewSQLGraphStore(rwdb, rodb *sqlx.DB, statements *Statements) (store.GraphStoreServer, error) {
-	if rwdb != nil {
-		if _, err := rwdb.Exec(statements.CreateGraphDataTable); err != nil {
-			return nil, err
-		}
-	}
+	return newSQLGraphStore(rwdb, []*sqlx.DB{rodb}, statements, nil)
+}
 
+func newSQLGraphStore(rwdb *sqlx.DB, rodbs []*sqlx.DB, statements *Statements, pool *sqlpool.Config) (store.GraphStoreServer, error) {
 	return &graphStore{
 		rwdb:       rwdb,
-		rodb:       rodb,
+		rodbs:      rodbs,
 		statements: statements,
+		pool:       pool,
 	}, nil
 }
 
 type graphStore struct {
 	rwdb       *sqlx.DB
-	rodb       *sqlx.DB
+	rodbs      []*sqlx.DB
+	next       uint32
 	statements *Statements
+	pool       *sqlpool.Config
+	maxRetries int
+	cache      *findCache
+	bus        *eventbus.Bus
 }
 
-func (gs *graphStore) Put(ctx context.Context, req *store.PutRequest) (*store.PutResponse, error) {
+// rodb returns the next read only connection, balancing reads across the
+// configured replicas.
+func (gs *graphStore) rodb() *sqlx.DB {
+	if len(gs.rodbs) == 1 {
+		return gs.rodbs[0]
+	}
+
+	i := atomic.AddUint32(&gs.next, 1)
+	return gs.rodbs[int(i%uint32(len(gs.rodbs)))]
+}
+
+// inTx runs fn within a write transaction, committing once fn succeeds.
+// Transactions aborted by a serialization failure, as cockroachdb does under
+// contention, are run again from the start up to maxRetries times.
+func (gs *graphStore) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	return sqlretry.Do(ctx, gs.maxRetries, func() error {
+		tx, err := gs.rwdb.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// startTx traces a write transaction, recording how many items it touches.
+func startTx(ctx context.Context, name string, items int) (context.Context, *telemetry.Span) {
+	ctx, span := telemetry.Start(ctx, name)
+	span.SetAttribute("graphstore.items", strconv.Itoa(items))
+	return ctx, span
+}
+
+func (gs *graphStore) Put(ctx context.Context, req *store.PutRequest) (_ *store.PutResponse, err error) {
 	if gs.rwdb == nil {
 		return nil, api.ErrUnsupported
 	}
@@ -102,46 +196,49 @@ func (gs *graphStore) Put(ctx context.Context, req *store.PutRequest) (*store.Pu
 		return &store.PutResponse{}, nil
 	}
 
-	timestamp := time.Now()
-	errors := make([]error, 0)
-
-	tx, err := gs.rwdb.Beginx()
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
+	ctx, span := startTx(ctx, "graphstore.put", len(req.GetItems()))
+	defer func() { span.End(err) }()
 
-	for _, item := range req.GetItems() {
-		_, err := tx.NamedExec(gs.statements.InsertGraphData, map[string]interface{}{
-			"graph_item_type": item.GetGraphItemType(),
-			"k1":              Base64encode(item.GetK1()),
-			"k2":              Base64encode(item.GetK2()),
-			"k3":              Base64encode(item.GetK3()),
-			"encoding":        item.GetEncoding(),
-			"graph_item_data": string(item.GetGraphItemData()),
-			"last_modified":   timestamp,
-		})
-
-		if err != nil {
-			errors = append(errors, err)
+	timestamp := time.Now()
+	var errors []error
+	scope := scopeFor(ctx)
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	// evict cached lookups once the write is visible
+	defer gs.cache.invalidateItems(ctx, req.GetItems())
+
+	err = gs.inTx(ctx, func(tx *sqlx.Tx) error {
+		errors = make([]error, 0)
+		for _, item := range scope.scopedItems(req.GetItems()) {
+			if err := gs.putItem(ctx, tx, scope, item, timestamp); err != nil {
+				// aborted transactions are retried as a whole
+				if sqlretry.IsRetryable(err) {
+					return err
+				}
+				errors = append(errors, err)
+			}
 		}
-	}
-
-	if err := tx.Commit(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	if len(errors) > 0 {
 		for _, err := range errors {
-			logrus.Errorf(err.Error())
+			logging.FromContext(ctx).Errorf("[graphstore] %s", err.Error())
 		}
 		return nil, api.ErrPartialInsertion
 	}
 
+	recordWrites("put", req.GetItems())
+	gs.publish(ctx, scope, eventbus.OperationPut, req.GetItems(), timestamp)
 	return &store.PutResponse{}, nil
 }
 
-func (gs *graphStore) Delete(ctx context.Context, req *store.DeleteRequest) (*store.DeleteResponse, error) {
+func (gs *graphStore) Delete(ctx context.Context, req *store.DeleteRequest) (_ *store.DeleteResponse, err error) {
 	if gs.rwdb == nil {
 		return nil, api.ErrUnsupported
 	}
@@ -150,51 +247,96 @@ func (gs *graphStore) Delete(ctx context.Context, req *store.DeleteRequest) (*st
 		return &store.DeleteResponse{}, nil
 	}
 
-	timestamp := time.Now()
-	errors := make([]error, 0)
-
-	tx, err := gs.rwdb.Beginx()
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
+	ctx, span := startTx(ctx, "graphstore.delete", len(req.GetItems()))
+	defer func() { span.End(err) }()
 
-	for _, key := range req.GetItems() {
-		_, err := tx.NamedExec(gs.statements.DeleteGraphData, map[string]interface{}{
-			"date_deleted":    timestamp,
-			"graph_item_type": key.GetGraphItemType(),
-			"k1":              Base64encode(key.GetK1()),
-			"k2":              Base64encode(key.GetK2()),
-			"k3":              Base64encode(key.GetK3()),
-		})
-		if err != nil {
-			errors = append(errors, err)
+	timestamp := time.Now()
+	var errors []error
+	scope := scopeFor(ctx)
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	// evict cached lookups once the write is visible
+	defer gs.cache.invalidateItems(ctx, req.GetItems())
+
+	err = gs.inTx(ctx, func(tx *sqlx.Tx) error {
+		errors = make([]error, 0)
+		for _, key := range scope.scopedItems(req.GetItems()) {
+			if err := gs.deleteItem(ctx, tx, key, timestamp); err != nil {
+				// aborted transactions are retried as a whole
+				if sqlretry.IsRetryable(err) {
+					return err
+				}
+				errors = append(errors, err)
+			}
 		}
-	}
-
-	if err := tx.Commit(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	if len(errors) > 0 {
 		for _, err := range errors {
-			logrus.Errorf(err.Error())
+			logging.FromContext(ctx).Errorf("[graphstore] %s", err.Error())
 		}
 		return nil, api.ErrPartialDeletion
 	}
 
+	recordWrites("delete", req.GetItems())
+	gs.publish(ctx, scope, eventbus.OperationDelete, req.GetItems(), timestamp)
 	return &store.DeleteResponse{}, nil
 }
 
-func max(a, b int32) int32 {
-	if a > b {
-		return a
+// putItem writes an item that's already been scoped to the tenant.
+func (gs *graphStore) putItem(ctx context.Context, tx *sqlx.Tx, scope *tenantScope, item *store.GraphItem, timestamp time.Time) error {
+	params := map[string]interface{}{
+		"graph_item_type": item.GetGraphItemType(),
+		"k1":              Base64encode(item.GetK1()),
+		"k2":              Base64encode(item.GetK2()),
+		"k3":              Base64encode(item.GetK3()),
+		"encoding":        item.GetEncoding(),
+		"graph_item_data": string(item.GetGraphItemData()),
+		"last_modified":   timestamp,
+		"changed_at":      timestamp.UnixNano(),
+		"tenant":          scope.name,
 	}
-	return b
+
+	// history must be recorded before the item is replaced
+	if err := gs.recordHistory(ctx, tx, gs.statements.InsertGraphDataPutHistory, params); err != nil {
+		return err
+	}
+
+	return gs.updateModuleCounts(ctx, tx, params, func() error {
+		_, err := tx.NamedExecContext(ctx, gs.statements.InsertGraphData, params)
+		return err
+	})
 }
 
-func min(a, b int32) int32 {
-	if a < b {
+// deleteItem tombstones an item that's already been scoped to the tenant.
+func (gs *graphStore) deleteItem(ctx context.Context, tx *sqlx.Tx, key *store.GraphItem, timestamp time.Time) error {
+	params := map[string]interface{}{
+		"date_deleted":    timestamp,
+		"graph_item_type": key.GetGraphItemType(),
+		"k1":              Base64encode(key.GetK1()),
+		"k2":              Base64encode(key.GetK2()),
+		"k3":              Base64encode(key.GetK3()),
+		"changed_at":      timestamp.UnixNano(),
+	}
+
+	if err := gs.recordHistory(ctx, tx, gs.statements.InsertGraphDataDeleteHistory, params); err != nil {
+		return err
+	}
+
+	return gs.updateModuleCounts(ctx, tx, params, func() error {
+		_, err := tx.NamedExecContext(ctx, gs.statements.DeleteGraphData, params)
+		return err
+	})
+}
+
+func max(a, b int32) int32 {
+	if a > b {
 		return a
 	}
 	return b
@@ -204,14 +346,29 @@ func (gs *graphStore) List(ctx context.Context, req *store.ListRequest) (*store.
 	graphItemType := req.GetType()
 	page := max(req.GetPage(), 1)
 
-	limit := max(min(req.GetCount(), 100), 10)
+	// page sizes are bounded by the services
+	limit := req.GetCount()
+	if limit <= 0 {
+		limit = 10
+	}
 	offset := (page - 1) * limit
+	scope := scopeFor(ctx)
 
-	rows, err := gs.rodb.NamedQuery(gs.statements.ListGraphData, map[string]interface{}{
-		"graph_item_type": graphItemType,
-		"limit":           limit,
-		"offset":          offset,
-	})
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	params := filterPatterns(filters.FromIncomingContext(ctx), []string{graphItemType})
+	params["graph_item_type"] = graphItemType
+	params["tenant"] = scope.name
+	params["limit"] = limit
+	params["offset"] = offset
+
+	statement, err := readStatement(ctx, gs.statements.ListGraphData, gs.statements.ListGraphDataAsOf, params)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := gs.rodb().NamedQueryContext(ctx, statement, params)
 	if err != nil {
 		return nil, err
 	}
@@ -221,22 +378,39 @@ func (gs *graphStore) List(ctx context.Context, req *store.ListRequest) (*store.
 		return nil, err
 	}
 
+	graphItemsRead.WithLabelValues("list").Add(float64(len(items)))
 	return &store.ListResponse{
-		Items: items,
+		Items: scope.unscopedItems(items),
 	}, nil
 }
 
 func (gs *graphStore) FindUpstream(ctx context.Context, req *store.FindRequest) (*store.FindResponse, error) {
+	lookup, cacheable := gs.cache.lookupFor(ctx, "upstream", req)
+	if cacheable {
+		if pairs, ok := gs.cache.get(lookup); ok {
+			graphItemsRead.WithLabelValues("find_upstream").Add(float64(len(pairs)))
+			return &store.FindResponse{Pairs: pairs}, nil
+		}
+	}
+
+	scope := scopeFor(ctx)
+
 	keys := make([]string, len(req.GetKeys()))
-	for i, key := range req.GetKeys() {
+	for i, key := range scope.keys(req.GetKeys()) {
 		keys[i] = Base64encode(key)
 	}
 
-	query, args, err := sqlx.Named(gs.statements.SelectGraphDataUpstreamDependencies, map[string]interface{}{
-		"keys":       keys,
-		"edge_types": req.GetEdgeTypes(),
-		"node_types": req.GetNodeTypes(),
-	})
+	params := filterPatterns(filters.FromIncomingContext(ctx), req.GetNodeTypes())
+	params["keys"] = keys
+	params["edge_types"] = req.GetEdgeTypes()
+	params["node_types"] = req.GetNodeTypes()
+
+	statement, err := readStatement(ctx, gs.statements.SelectGraphDataUpstreamDependencies, gs.statements.SelectGraphDataUpstreamAsOf, params)
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err := sqlx.Named(statement, params)
 	if err != nil {
 		return nil, err
 	}
@@ -246,10 +420,15 @@ func (gs *graphStore) FindUpstream(ctx context.Context, req *store.FindRequest)
 		return nil, err
 	}
 
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rodb := gs.rodb()
+
 	// transform the query to the DB specific bindvar type
-	query = gs.rodb.Rebind(query)
+	query = rodb.Rebind(query)
 
-	rows, err := gs.rodb.Queryx(query, args...)
+	rows, err := rodb.QueryxContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -259,22 +438,44 @@ func (gs *graphStore) FindUpstream(ctx context.Context, req *store.FindRequest)
 		return nil, err
 	}
 
+	pairs = scope.unscopedPairs(pairs)
+	if cacheable {
+		gs.cache.put(lookup, req, pairs)
+	}
+
+	graphItemsRead.WithLabelValues("find_upstream").Add(float64(len(pairs)))
 	return &store.FindResponse{
 		Pairs: pairs,
 	}, nil
 }
 
 func (gs *graphStore) FindDownstream(ctx context.Context, req *store.FindRequest) (*store.FindResponse, error) {
+	lookup, cacheable := gs.cache.lookupFor(ctx, "downstream", req)
+	if cacheable {
+		if pairs, ok := gs.cache.get(lookup); ok {
+			graphItemsRead.WithLabelValues("find_downstream").Add(float64(len(pairs)))
+			return &store.FindResponse{Pairs: pairs}, nil
+		}
+	}
+
+	scope := scopeFor(ctx)
+
 	keys := make([]string, len(req.GetKeys()))
-	for i, key := range req.GetKeys() {
+	for i, key := range scope.keys(req.GetKeys()) {
 		keys[i] = Base64encode(key)
 	}
 
-	query, args, err := sqlx.Named(gs.statements.SelectGraphDataDownstreamDependencies, map[string]interface{}{
-		"keys":       keys,
-		"edge_types": req.GetEdgeTypes(),
-		"node_types": req.GetNodeTypes(),
-	})
+	params := filterPatterns(filters.FromIncomingContext(ctx), req.GetNodeTypes())
+	params["keys"] = keys
+	params["edge_types"] = req.GetEdgeTypes()
+	params["node_types"] = req.GetNodeTypes()
+
+	statement, err := readStatement(ctx, gs.statements.SelectGraphDataDownstreamDependencies, gs.statements.SelectGraphDataDownstreamAsOf, params)
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err := sqlx.Named(statement, params)
 	if err != nil {
 		return nil, err
 	}
@@ -284,10 +485,15 @@ func (gs *graphStore) FindDownstream(ctx context.Context, req *store.FindRequest
 		return nil, err
 	}
 
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rodb := gs.rodb()
+
 	// transform the query to the DB specific bindvar type
-	query = gs.rodb.Rebind(query)
+	query = rodb.Rebind(query)
 
-	rows, err := gs.rodb.Queryx(query, args...)
+	rows, err := rodb.QueryxContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -297,14 +503,43 @@ func (gs *graphStore) FindDownstream(ctx context.Context, req *store.FindRequest
 		return nil, err
 	}
 
+	pairs = scope.unscopedPairs(pairs)
+	if cacheable {
+		gs.cache.put(lookup, req, pairs)
+	}
+
+	graphItemsRead.WithLabelValues("find_downstream").Add(float64(len(pairs)))
 	return &store.FindResponse{
 		Pairs: pairs,
 	}, nil
 }
 
+// readStatement returns the statement used to answer a read. Reads as of a
+// point in time are answered from the history using asOfStatement.
+func readStatement(ctx context.Context, statement, asOfStatement string, params map[string]interface{}) (string, error) {
+	asOf, err := asof.FromIncomingContext(ctx)
+	if err != nil {
+		return "", err
+	} else if asOf.IsZero() {
+		return statement, nil
+	} else if asOfStatement == "" {
+		return "", api.ErrUnsupported
+	}
+
+	params["as_of"] = asOf.UnixNano()
+	return asOfStatement, nil
+}
+
 func readGraphItems(rows *sqlx.Rows) ([]*store.GraphItem, error) {
 	defer rows.Close()
 
+	// statement files written before k3 was listed omit the column
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	withK3 := len(columns) > 5
+
 	results := make([]*store.GraphItem, 0)
 
 	for rows.Next() {
@@ -312,21 +547,29 @@ func readGraphItems(rows *sqlx.Rows) ([]*store.GraphItem, error) {
 			t    string
 			k1   string
 			k2   string
+			k3   string
 			enc  store.GraphItemEncoding
 			data string
 		)
 
-		if err := rows.Scan(&t, &k1, &k2, &enc, &data); err != nil {
+		dest := []interface{}{&t, &k1, &k2, &k3, &enc, &data}
+		if !withK3 {
+			dest = []interface{}{&t, &k1, &k2, &enc, &data}
+		}
+
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 
 		k1Bytes, _ := Base64decode(k1)
 		k2Bytes, _ := Base64decode(k2)
+		k3Bytes, _ := Base64decode(k3)
 
 		item := &store.GraphItem{
 			GraphItemType: t,
 			K1:            k1Bytes,
 			K2:            k2Bytes,
+			K3:            k3Bytes,
 			Encoding:      enc,
 			GraphItemData: []byte(data),
 		}
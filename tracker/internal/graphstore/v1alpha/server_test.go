@@ -1,10 +1,24 @@
 package v1alpha_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/depscloud/api"
 	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/asof"
+	"github.com/depscloud/depscloud/internal/audit"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/serviceaccounts"
+	"github.com/depscloud/depscloud/internal/tenants"
+	"github.com/depscloud/depscloud/tracker/internal/eventbus"
 	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
 
 	"github.com/jmoiron/sqlx"
@@ -12,6 +26,8 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/metadata"
 )
 
 var (
@@ -50,6 +66,10 @@ func TestNewSQLGraphStore_sqlite(t *testing.T) {
 	rodb, err := sqlx.Open("sqlite3", "file::memory:?cache=shared&mode=ro")
 	require.Nil(t, err)
 
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(context.Background()))
+
 	statements, err := graphstore.DefaultStatementsFor("sqlite3")
 	require.Nil(t, err)
 
@@ -144,39 +164,1412 @@ func TestNewSQLGraphStore_sqlite(t *testing.T) {
 	require.Nil(t, err)
 }
 
-func TestReadOnly_sqlite(t *testing.T) {
-	rodb, err := sqlx.Open("sqlite3", "file::memory:?cache=shared&mode=ro")
+func TestFilters_sqlite(t *testing.T) {
+	module := func(key, data string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key), GraphItemData: []byte(data)}
+	}
+
+	data := []*store.GraphItem{
+		module("go-a", `{"language":"go","organization":"github.com","module":"a","name":"github.com/depscloud/a"}`),
+		module("go-b", `{"language":"go","organization":"github.com","module":"b","name":"github.com/other/b"}`),
+		module("node-c", `{"language":"node","organization":"depscloud","module":"c","name":"@depscloud/c"}`),
+		module("node-d", `{"language":"node","organization":"","module":"d_100%","name":"d_100%"}`),
+		{GraphItemType: "source", K1: []byte("src"), K2: []byte("src"), GraphItemData: []byte(`{"url":"https://github.com/depscloud/a.git"}`)},
+
+		{GraphItemType: "depends", K1: []byte("go-a"), K2: []byte("go-b")},
+		{GraphItemType: "depends", K1: []byte("node-c"), K2: []byte("go-b")},
+		{GraphItemType: "depends", K1: []byte("node-d"), K2: []byte("go-b")},
+	}
+
+	rwdb, err := sqlx.Open("sqlite3", "file:filters?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
 	require.Nil(t, err)
+	require.Nil(t, migrator.Up(context.Background()))
 
 	statements, err := graphstore.DefaultStatementsFor("sqlite3")
 	require.Nil(t, err)
 
-	graphStore, err := graphstore.NewSQLGraphStore(nil, rodb, statements)
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
 	require.Nil(t, err)
 
-	{
-		resp, err := graphStore.Put(nil, &store.PutRequest{})
-		require.Nil(t, resp)
-		require.Equal(t, api.ErrUnsupported, err)
+	_, err = graphStore.Put(context.Background(), &store.PutRequest{Items: data})
+	require.Nil(t, err)
+
+	filtered := func(kv ...string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
 	}
 
-	{
-		resp, err := graphStore.Delete(nil, &store.DeleteRequest{})
-		require.Nil(t, resp)
-		require.Equal(t, api.ErrUnsupported, err)
+	list := func(ctx context.Context, graphItemType string) []string {
+		resp, err := graphStore.List(ctx, &store.ListRequest{Page: 1, Count: 10, Type: graphItemType})
+		require.Nil(t, err)
+
+		keys := make([]string, len(resp.GetItems()))
+		for i, item := range resp.GetItems() {
+			keys[i] = string(item.GetK1())
+		}
+		return keys
+	}
+
+	require.Equal(t, []string{"go-a", "go-b", "node-c", "node-d"}, list(context.Background(), "module"))
+	require.Equal(t, []string{"go-a", "go-b"}, list(filtered(filters.LanguageMetadataKey, "go"), "module"))
+	require.Equal(t, []string{"node-c"}, list(filtered(filters.OrganizationMetadataKey, "depscloud"), "module"))
+	require.Equal(t, []string{"go-a"}, list(filtered(
+		filters.LanguageMetadataKey, "go",
+		filters.NamePrefixMetadataKey, "github.com/depscloud/",
+	), "module"))
+
+	// wildcards are matched literally
+	require.Equal(t, []string{"node-d"}, list(filtered(filters.NamePrefixMetadataKey, "d_100%"), "module"))
+	require.Len(t, list(filtered(filters.NamePrefixMetadataKey, "d%"), "module"), 0)
+
+	// sources are filtered by url and ignore the language
+	require.Equal(t, []string{"src"}, list(filtered(
+		filters.LanguageMetadataKey, "go",
+		filters.NamePrefixMetadataKey, "https://github.com/depscloud/",
+	), "source"))
+
+	downstream, err := graphStore.FindDownstream(filtered(filters.LanguageMetadataKey, "node"), &store.FindRequest{
+		Keys:      [][]byte{[]byte("go-b")},
+		EdgeTypes: []string{"depends"},
+		NodeTypes: []string{"module"},
+	})
+	require.Nil(t, err)
+	require.Len(t, downstream.GetPairs(), 2)
+
+	for _, pair := range downstream.GetPairs() {
+		require.Contains(t, []string{"node-c", "node-d"}, string(pair.GetNode().GetK1()))
 	}
 }
 
-func TestResolveDriverName(t *testing.T) {
-	_, err := graphstore.ResolveDriverName("sqlite")
+func TestLabels_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	module := func(key string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key), GraphItemData: []byte(`{}`)}
+	}
+
+	rwdb, err := sqlx.Open("sqlite3", "file:labels?mode=memory&cache=shared")
 	require.Nil(t, err)
+	defer rwdb.Close()
 
-	_, err = graphstore.ResolveDriverName("mysql")
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
 	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
 
-	_, err = graphstore.ResolveDriverName("postgres")
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
 	require.Nil(t, err)
 
-	_, err = graphstore.ResolveDriverName("noDB")
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{
+		module("a"), module("b"), module("c"),
+		{GraphItemType: "depends", K1: []byte("a"), K2: []byte("b")},
+		{GraphItemType: "depends", K1: []byte("a"), K2: []byte("c")},
+	}})
+	require.Nil(t, err)
+
+	labels := graphStore.(graphstore.Labels)
+	require.NotNil(t, labels.SetLabels(ctx, "module", []byte("a"), map[string]string{"a=b": "c"}))
+	require.Nil(t, labels.SetLabels(ctx, "module", []byte("a"), map[string]string{"team": "core", "tier": "1"}))
+	require.Nil(t, labels.SetLabels(ctx, "module", []byte("b"), map[string]string{"team": "core", "deprecated": `"true"`}))
+	require.Nil(t, labels.SetLabels(ctx, "module", []byte("c"), map[string]string{"team": "infra"}))
+	require.Nil(t, labels.SetLabels(ctx, "module", []byte("c"), nil))
+
+	current, err := labels.GetLabels(ctx, "module", [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	require.Nil(t, err)
+	require.Equal(t, map[string]map[string]string{
+		"a": {"team": "core", "tier": "1"},
+		"b": {"team": "core", "deprecated": `"true"`},
+	}, current)
+
+	list := func(selector string) []string {
+		ctx := metadata.NewIncomingContext(ctx, metadata.Pairs(filters.LabelsMetadataKey, selector))
+
+		resp, err := graphStore.List(ctx, &store.ListRequest{Page: 1, Count: 10, Type: "module"})
+		require.Nil(t, err)
+
+		keys := make([]string, len(resp.GetItems()))
+		for i, item := range resp.GetItems() {
+			keys[i] = string(item.GetK1())
+		}
+		return keys
+	}
+
+	require.Equal(t, []string{"a", "b"}, list("team=core"))
+	require.Equal(t, []string{"a"}, list("tier=1, team=core"))
+	require.Equal(t, []string{"b"}, list("deprecated"))
+	require.Equal(t, []string{"b"}, list(`deprecated="true"`))
+	require.Len(t, list("team=cor"), 0)
+	require.Len(t, list("team=infra"), 0)
+
+	upstream, err := graphStore.FindUpstream(
+		metadata.NewIncomingContext(ctx, metadata.Pairs(filters.LabelsMetadataKey, "team=core")),
+		&store.FindRequest{
+			Keys:      [][]byte{[]byte("a")},
+			EdgeTypes: []string{"depends"},
+			NodeTypes: []string{"module"},
+		})
+	require.Nil(t, err)
+	require.Len(t, upstream.GetPairs(), 1)
+	require.Equal(t, "b", string(upstream.GetPairs()[0].GetNode().GetK1()))
+}
+
+func TestSearchText_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	module := func(key, name string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key), GraphItemData: []byte(`{"name":"` + name + `"}`)}
+	}
+
+	rwdb, err := sqlx.Open("sqlite3", "file:search?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{
+		module("a", "github.com/depscloud/api"),
+		module("b", "github.com/depscloud/depscloud"),
+		module("c", "gopkg.in/yaml.v2"),
+		module("d", "100%_done"),
+	}})
+	require.Nil(t, err)
+
+	require.Nil(t, graphStore.(graphstore.Labels).SetLabels(ctx, "module", []byte("c"), map[string]string{"team": "core"}))
+
+	search := func(text string, fuzzy bool) []string {
+		items, err := graphStore.(graphstore.TextSearch).SearchText(ctx, "module", text, fuzzy, 10)
+		require.Nil(t, err)
+
+		keys := make([]string, len(items))
+		for i, item := range items {
+			keys[i] = string(item.GetK1())
+		}
+		return keys
+	}
+
+	require.Equal(t, []string{"a", "b"}, search("DEPSCLOUD", false))
+	require.Equal(t, []string{"c"}, search("yaml", false))
+	require.Equal(t, []string{"c"}, search("core", false))
+	require.Equal(t, []string{"d"}, search("0%_", false))
+	require.Len(t, search("gdc", false), 0)
+	require.Equal(t, []string{"a", "b"}, search("gdc", true))
+
+	// modules with more dependents come first
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{
+		{GraphItemType: "depends", K1: []byte("a"), K2: []byte("b")},
+		{GraphItemType: "depends", K1: []byte("c"), K2: []byte("b")},
+		{GraphItemType: "depends", K1: []byte("d"), K2: []byte("a")},
+	}})
+	require.Nil(t, err)
+
+	require.Equal(t, []string{"b", "a"}, search("depscloud", false))
+
+	popularity, err := graphStore.(graphstore.TextSearch).GetPopularity(ctx, "module", [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	require.Nil(t, err)
+	require.Len(t, popularity, 3)
+	require.Equal(t, int64(1), popularity["a"].Dependents)
+	require.Equal(t, int64(2), popularity["b"].Dependents)
+	require.Equal(t, int64(0), popularity["c"].Dependents)
+	require.False(t, popularity["c"].LastObserved.IsZero())
+}
+
+func TestHistory_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	rwdb, err := sqlx.Open("sqlite3", "file:history?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	history := graphStore.(graphstore.History)
+
+	edge := func(data string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "depends", K1: k1, K2: k2, K3: k3, GraphItemData: []byte(data)}
+	}
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{
+		{GraphItemType: "module", K1: k1, K2: k1, GraphItemData: []byte(`{"module":"a"}`)},
+		{GraphItemType: "module", K1: k2, K2: k2, GraphItemData: []byte(`{"module":"b"}`)},
+		edge(`{"versionConstraint":"v1"}`),
+	}})
+	require.Nil(t, err)
+
+	// unchanged items aren't recorded again
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{edge(`{"versionConstraint":"v1"}`)}})
+	require.Nil(t, err)
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{edge(`{"versionConstraint":"v2"}`)}})
+	require.Nil(t, err)
+
+	_, err = graphStore.Delete(ctx, &store.DeleteRequest{Items: []*store.GraphItem{edge("")}})
+	require.Nil(t, err)
+
+	// deleting a deleted item isn't recorded again
+	_, err = graphStore.Delete(ctx, &store.DeleteRequest{Items: []*store.GraphItem{edge("")}})
+	require.Nil(t, err)
+
+	for _, key := range [][]byte{k1, k2, k3} {
+		changes, err := history.Changes(ctx, [][]byte{key}, time.Now())
+		require.Nil(t, err)
+		require.Len(t, changes, 3)
+
+		require.False(t, changes[0].Deleted)
+		require.Equal(t, `{"versionConstraint":"v1"}`, string(changes[0].Item.GetGraphItemData()))
+		require.False(t, changes[1].Deleted)
+		require.Equal(t, `{"versionConstraint":"v2"}`, string(changes[1].Item.GetGraphItemData()))
+		require.True(t, changes[2].Deleted)
+		require.Equal(t, `{"versionConstraint":"v2"}`, string(changes[2].Item.GetGraphItemData()))
+
+		require.Equal(t, `{"module":"a"}`, string(changes[0].From.GetGraphItemData()))
+		require.Equal(t, `{"module":"b"}`, string(changes[0].To.GetGraphItemData()))
+		require.False(t, changes[1].Timestamp.Before(changes[0].Timestamp))
+	}
+
+	changes, err := history.Changes(ctx, [][]byte{k1}, time.Unix(0, 0))
+	require.Nil(t, err)
+	require.Len(t, changes, 0)
+}
+
+func TestAsOf_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	rwdb, err := sqlx.Open("sqlite3", "file:asof?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	module := func(key []byte) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: key, K2: key, GraphItemData: []byte(`{}`)}
+	}
+
+	edge := func(to []byte, data string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "depends", K1: k1, K2: to, GraphItemData: []byte(data)}
+	}
+
+	start := time.Now()
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{
+		module(k1), module(k2), module(k3), edge(k2, "v1"),
+	}})
+	require.Nil(t, err)
+
+	first := time.Now()
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{edge(k2, "v2"), edge(k3, "v1")}})
+	require.Nil(t, err)
+
+	second := time.Now()
+
+	_, err = graphStore.Delete(ctx, &store.DeleteRequest{Items: []*store.GraphItem{edge(k2, "")}})
+	require.Nil(t, err)
+
+	asOf := func(at time.Time) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(asof.MetadataKey, at.Format(time.RFC3339Nano)))
+	}
+
+	upstream := func(ctx context.Context) map[string]string {
+		response, err := graphStore.FindUpstream(ctx, &store.FindRequest{
+			Keys:      [][]byte{k1},
+			EdgeTypes: []string{"depends"},
+			NodeTypes: []string{"module"},
+		})
+		require.Nil(t, err)
+
+		edges := make(map[string]string)
+		for _, pair := range response.GetPairs() {
+			edges[string(pair.GetNode().GetK1())] = string(pair.GetEdge().GetGraphItemData())
+		}
+		return edges
+	}
+
+	require.Equal(t, map[string]string{}, upstream(asOf(start)))
+	require.Equal(t, map[string]string{string(k2): "v1"}, upstream(asOf(first)))
+	require.Equal(t, map[string]string{string(k2): "v2", string(k3): "v1"}, upstream(asOf(second)))
+	require.Equal(t, map[string]string{string(k3): "v1"}, upstream(ctx))
+
+	downstream, err := graphStore.FindDownstream(asOf(first), &store.FindRequest{
+		Keys:      [][]byte{k2},
+		EdgeTypes: []string{"depends"},
+		NodeTypes: []string{"module"},
+	})
+	require.Nil(t, err)
+	require.Len(t, downstream.GetPairs(), 1)
+
+	list := func(ctx context.Context) int {
+		response, err := graphStore.List(ctx, &store.ListRequest{Page: 1, Count: 10, Type: "depends"})
+		require.Nil(t, err)
+		return len(response.GetItems())
+	}
+
+	require.Equal(t, 0, list(asOf(start)))
+	require.Equal(t, 1, list(asOf(first)))
+	require.Equal(t, 2, list(asOf(second)))
+
+	_, err = graphStore.List(metadata.NewIncomingContext(ctx, metadata.Pairs(asof.MetadataKey, "yesterday")),
+		&store.ListRequest{Page: 1, Count: 10, Type: "depends"})
 	require.NotNil(t, err)
 }
+
+func TestTombstones_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	rwdb, err := sqlx.Open("sqlite3", "file:tombstones?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	tombstones := graphStore.(graphstore.Tombstones)
+
+	source := func(key []byte) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "source", K1: key, K2: key, GraphItemData: []byte(`{}`)}
+	}
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{source(k1), source(k2), source(k3)}})
+	require.Nil(t, err)
+
+	_, err = graphStore.Delete(ctx, &store.DeleteRequest{Items: []*store.GraphItem{source(k1), source(k2)}})
+	require.Nil(t, err)
+
+	list := func() int {
+		response, err := graphStore.List(ctx, &store.ListRequest{Page: 1, Count: 10, Type: "source"})
+		require.Nil(t, err)
+		return len(response.GetItems())
+	}
+
+	// tombstones are hidden from reads
+	require.Equal(t, 1, list())
+
+	listed, err := tombstones.ListTombstones(ctx, "source", 1, 10)
+	require.Nil(t, err)
+	require.Len(t, listed, 2)
+	require.False(t, listed[0].DateDeleted.IsZero())
+
+	// putting a tombstoned item restores it
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{source(k2)}})
+	require.Nil(t, err)
+	require.Equal(t, 2, list())
+
+	purged, err := tombstones.PurgeTombstones(ctx, time.Now().Add(-time.Hour))
+	require.Nil(t, err)
+	require.Equal(t, int64(0), purged)
+
+	purged, err = tombstones.PurgeTombstones(ctx, time.Now().Add(time.Second))
+	require.Nil(t, err)
+	require.Equal(t, int64(1), purged)
+
+	listed, err = tombstones.ListTombstones(ctx, "source", 1, 10)
+	require.Nil(t, err)
+	require.Len(t, listed, 0)
+}
+
+func TestRetention_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	rwdb, err := sqlx.Open("sqlite3", "file:retention?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	retention := graphStore.(graphstore.Retention)
+
+	edge := func(data string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "depends", K1: k1, K2: k2, GraphItemData: []byte(data)}
+	}
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{
+		{GraphItemType: "source", K1: k3, K2: k3, GraphItemData: []byte(`{}`)},
+		{GraphItemType: "module", K1: k1, K2: k1, GraphItemData: []byte(`{}`)},
+		{GraphItemType: "module", K1: k2, K2: k2, GraphItemData: []byte(`{}`)},
+		edge("v1"),
+	}})
+	require.Nil(t, err)
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{edge("v2")}})
+	require.Nil(t, err)
+
+	policy := &graphstore.RetentionPolicy{
+		EdgeMaxAge:      time.Hour,
+		SourceMaxAge:    time.Hour,
+		TombstoneMaxAge: time.Hour,
+		HistoryMaxAge:   time.Hour,
+	}
+
+	// nothing is old enough yet
+	report, err := retention.ApplyRetention(ctx, policy, time.Now(), false)
+	require.Nil(t, err)
+	require.Equal(t, &graphstore.RetentionReport{}, report)
+
+	later := time.Now().Add(2 * time.Hour)
+
+	report, err = retention.ApplyRetention(ctx, policy, later, true)
+	require.Nil(t, err)
+	require.Equal(t, &graphstore.RetentionReport{DryRun: true, StaleEdges: 1, StaleSources: 1, PurgedHistory: 1}, report)
+
+	// dry runs don't change anything
+	report, err = retention.ApplyRetention(ctx, policy, later, false)
+	require.Nil(t, err)
+	require.Equal(t, &graphstore.RetentionReport{StaleEdges: 1, StaleSources: 1, PurgedHistory: 1}, report)
+
+	// the tombstones created by the previous run are purged by a later one
+	report, err = retention.ApplyRetention(ctx, policy, later.Add(2*time.Hour), false)
+	require.Nil(t, err)
+	require.Equal(t, int64(0), report.StaleEdges)
+	require.Equal(t, int64(2), report.PurgedTombstones)
+}
+
+func TestTenants_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	module := func(key, data string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key), GraphItemData: []byte(data)}
+	}
+
+	rwdb, err := sqlx.Open("sqlite3", "file:tenants?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	payments := tenants.NewContext(ctx, "payments")
+	search := metadata.NewIncomingContext(ctx, metadata.Pairs(tenants.MetadataKey, "search"))
+
+	// both tenants track the same modules
+	for _, tenantCtx := range []context.Context{ctx, payments, search} {
+		data := `{"tenant":"` + tenants.FromContext(tenantCtx) + `"}`
+
+		_, err = graphStore.Put(tenantCtx, &store.PutRequest{Items: []*store.GraphItem{
+			module("a", data), module("b", data),
+			{GraphItemType: "depends", K1: []byte("a"), K2: []byte("b")},
+		}})
+		require.Nil(t, err)
+	}
+
+	_, err = graphStore.Put(payments, &store.PutRequest{Items: []*store.GraphItem{module("c", `{"tenant":"payments"}`)}})
+	require.Nil(t, err)
+
+	list := func(ctx context.Context) []string {
+		resp, err := graphStore.List(ctx, &store.ListRequest{Page: 1, Count: 10, Type: "module"})
+		require.Nil(t, err)
+
+		keys := make([]string, len(resp.GetItems()))
+		for i, item := range resp.GetItems() {
+			require.Equal(t, `{"tenant":"`+tenants.FromContext(ctx)+`"}`, string(item.GetGraphItemData()))
+			keys[i] = string(item.GetK1())
+		}
+		return keys
+	}
+
+	require.ElementsMatch(t, []string{"a", "b"}, list(ctx))
+	require.ElementsMatch(t, []string{"a", "b", "c"}, list(payments))
+	require.ElementsMatch(t, []string{"a", "b"}, list(search))
+
+	upstream, err := graphStore.FindUpstream(search, &store.FindRequest{
+		Keys:      [][]byte{[]byte("a")},
+		EdgeTypes: []string{"depends"},
+		NodeTypes: []string{"module"},
+	})
+	require.Nil(t, err)
+	require.Len(t, upstream.GetPairs(), 1)
+	require.Equal(t, "b", string(upstream.GetPairs()[0].GetNode().GetK1()))
+	require.Equal(t, `{"tenant":"search"}`, string(upstream.GetPairs()[0].GetNode().GetGraphItemData()))
+
+	// deletes only affect the caller's tenant
+	_, err = graphStore.Delete(payments, &store.DeleteRequest{Items: []*store.GraphItem{module("a", "")}})
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"b", "c"}, list(payments))
+	require.ElementsMatch(t, []string{"a", "b"}, list(search))
+
+	tombstones := graphStore.(graphstore.Tombstones)
+
+	listed, err := tombstones.ListTombstones(payments, "module", 1, 10)
+	require.Nil(t, err)
+	require.Len(t, listed, 1)
+	require.Equal(t, "a", string(listed[0].Item.GetK1()))
+
+	listed, err = tombstones.ListTombstones(search, "module", 1, 10)
+	require.Nil(t, err)
+	require.Len(t, listed, 0)
+
+	changes, err := graphStore.(graphstore.History).Changes(payments, [][]byte{[]byte("a")}, time.Now())
+	require.Nil(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, "b", string(changes[0].Item.GetK2()))
+
+	labels := graphStore.(graphstore.Labels)
+	require.Nil(t, labels.SetLabels(search, "module", []byte("b"), map[string]string{"team": "search"}))
+
+	current, err := labels.GetLabels(payments, "module", [][]byte{[]byte("b")})
+	require.Nil(t, err)
+	require.Len(t, current, 0)
+
+	current, err = labels.GetLabels(search, "module", [][]byte{[]byte("b")})
+	require.Nil(t, err)
+	require.Equal(t, map[string]map[string]string{"b": {"team": "search"}}, current)
+
+	results, err := graphStore.(graphstore.TextSearch).SearchText(search, "module", "tenant", false, 10)
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		require.Equal(t, `{"tenant":"search"}`, string(result.GetGraphItemData()))
+	}
+}
+
+func TestCounts_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	module := func(key string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key), GraphItemData: []byte(key)}
+	}
+
+	depends := func(from, to, source string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "depends", K1: []byte(from), K2: []byte(to), K3: []byte(source)}
+	}
+
+	rwdb, err := sqlx.Open("sqlite3", "file:counts?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	// the same edge reported by two sources is counted once
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{
+		module("a"), module("b"), module("c"),
+		depends("a", "c", "repo-a"), depends("a", "c", "repo-a-fork"),
+		depends("b", "c", "repo-b"), depends("a", "b", "repo-a"),
+	}})
+	require.Nil(t, err)
+
+	counts := graphStore.(graphstore.Counts)
+
+	find := func(key string) *store.FindRequest {
+		return &store.FindRequest{
+			Keys:      [][]byte{[]byte(key)},
+			EdgeTypes: []string{"depends"},
+			NodeTypes: []string{"module"},
+		}
+	}
+
+	count, err := counts.CountDownstream(ctx, find("c"))
+	require.Nil(t, err)
+	require.Equal(t, int64(2), count)
+
+	count, err = counts.CountUpstream(ctx, find("a"))
+	require.Nil(t, err)
+	require.Equal(t, int64(2), count)
+
+	count, err = counts.CountUpstream(ctx, find("c"))
+	require.Nil(t, err)
+	require.Equal(t, int64(0), count)
+
+	// other tenants don't see the edges
+	count, err = counts.CountDownstream(tenants.NewContext(ctx, "payments"), find("c"))
+	require.Nil(t, err)
+	require.Equal(t, int64(0), count)
+
+	popularity, err := graphStore.(graphstore.TextSearch).GetPopularity(ctx, "module", [][]byte{[]byte("b"), []byte("c")})
+	require.Nil(t, err)
+	require.Equal(t, int64(1), popularity["b"].Dependents)
+	require.Equal(t, int64(2), popularity["c"].Dependents)
+
+	// the modules stay connected until every source drops the edge
+	_, err = graphStore.Delete(ctx, &store.DeleteRequest{Items: []*store.GraphItem{depends("a", "c", "repo-a")}})
+	require.Nil(t, err)
+
+	count, err = counts.CountDownstream(ctx, find("c"))
+	require.Nil(t, err)
+	require.Equal(t, int64(2), count)
+
+	// deleting an edge twice only disconnects the modules once
+	for i := 0; i < 2; i++ {
+		_, err = graphStore.Delete(ctx, &store.DeleteRequest{Items: []*store.GraphItem{depends("a", "c", "repo-a-fork")}})
+		require.Nil(t, err)
+	}
+
+	count, err = counts.CountDownstream(ctx, find("c"))
+	require.Nil(t, err)
+	require.Equal(t, int64(1), count)
+
+	count, err = counts.CountUpstream(ctx, find("a"))
+	require.Nil(t, err)
+	require.Equal(t, int64(1), count)
+
+	// rebuilding from the edges arrives at the same counts
+	_, err = rwdb.Exec("UPDATE dts_module_counts SET dependents = 10")
+	require.Nil(t, err)
+	require.Nil(t, graphStore.(graphstore.CountRebuilder).RebuildCounts(ctx))
+
+	count, err = counts.CountDownstream(ctx, find("c"))
+	require.Nil(t, err)
+	require.Equal(t, int64(1), count)
+
+	count, err = counts.CountDownstream(ctx, find("a"))
+	require.Nil(t, err)
+	require.Equal(t, int64(0), count)
+}
+
+func TestCache_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	module := func(key string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key), GraphItemData: []byte(key)}
+	}
+
+	depends := func(from, to string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "depends", K1: []byte(from), K2: []byte(to), K3: []byte(from)}
+	}
+
+	rwdb, err := sqlx.Open("sqlite3", "file:cache?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+	(&graphstore.CacheConfig{Size: 2, TTL: time.Hour}).Apply(graphStore)
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{
+		module("a"), module("b"), module("c"), module("d"),
+		depends("a", "c"), depends("b", "c"),
+	}})
+	require.Nil(t, err)
+
+	dependents := func(ctx context.Context, key string) []string {
+		resp, err := graphStore.FindDownstream(ctx, &store.FindRequest{
+			Keys:      [][]byte{[]byte(key)},
+			EdgeTypes: []string{"depends"},
+			NodeTypes: []string{"module"},
+		})
+		require.Nil(t, err)
+
+		keys := make([]string, len(resp.GetPairs()))
+		for i, pair := range resp.GetPairs() {
+			keys[i] = string(pair.GetNode().GetK1())
+		}
+		return keys
+	}
+
+	require.ElementsMatch(t, []string{"a", "b"}, dependents(ctx, "c"))
+
+	// changes made around the graph store are only seen once the entry is evicted
+	_, err = rwdb.Exec("DELETE FROM dts_graphdata WHERE graph_item_type = 'depends' AND k1 = ?", graphstore.Base64encode([]byte("b")))
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"a", "b"}, dependents(ctx, "c"))
+
+	// other tenants are cached separately
+	require.Len(t, dependents(tenants.NewContext(ctx, "payments"), "c"), 0)
+
+	// writes evict the lookups involving their nodes
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{depends("d", "c")}})
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"a", "d"}, dependents(ctx, "c"))
+
+	_, err = graphStore.Delete(ctx, &store.DeleteRequest{Items: []*store.GraphItem{module("d")}})
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"a"}, dependents(ctx, "c"))
+}
+
+func TestSnapshots_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	module := func(key string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key), GraphItemData: []byte(key)}
+	}
+
+	list := func(graphStore store.GraphStoreServer, ctx context.Context) []string {
+		resp, err := graphStore.List(ctx, &store.ListRequest{Page: 1, Count: 10, Type: "module"})
+		require.Nil(t, err)
+
+		keys := make([]string, len(resp.GetItems()))
+		for i, item := range resp.GetItems() {
+			keys[i] = string(item.GetK1())
+		}
+		return keys
+	}
+
+	rwdb, err := sqlx.Open("sqlite3", "file:snapshots?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	payments := tenants.NewContext(ctx, "payments")
+	_, err = graphStore.Put(payments, &store.PutRequest{Items: []*store.GraphItem{module("a"), module("b")}})
+	require.Nil(t, err)
+	require.Nil(t, graphStore.(graphstore.Labels).SetLabels(payments, "module", []byte("a"), map[string]string{"team": "payments"}))
+
+	_, err = graphStore.Delete(payments, &store.DeleteRequest{Items: []*store.GraphItem{module("b")}})
+	require.Nil(t, err)
+
+	snapshots := graphStore.(graphstore.Snapshots)
+
+	snapshot := &bytes.Buffer{}
+	summary, err := snapshots.WriteSnapshot(payments, snapshot)
+	require.Nil(t, err)
+	require.Equal(t, 1, summary.Items)
+	require.Equal(t, 1, summary.Labels)
+
+	// changes made after the snapshot are undone by the restore
+	_, err = graphStore.Put(payments, &store.PutRequest{Items: []*store.GraphItem{module("c")}})
+	require.Nil(t, err)
+	require.Nil(t, graphStore.(graphstore.Labels).SetLabels(payments, "module", []byte("a"), map[string]string{"team": "search"}))
+
+	restored, err := snapshots.RestoreSnapshot(payments, bytes.NewReader(snapshot.Bytes()))
+	require.Nil(t, err)
+	require.Equal(t, 1, restored.Items)
+	require.Equal(t, 1, restored.Deleted)
+	require.Equal(t, summary.CreatedAt.Unix(), restored.CreatedAt.Unix())
+	require.ElementsMatch(t, []string{"a"}, list(graphStore, payments))
+
+	labels, err := graphStore.(graphstore.Labels).GetLabels(payments, "module", [][]byte{[]byte("a")})
+	require.Nil(t, err)
+	require.Equal(t, map[string]map[string]string{"a": {"team": "payments"}}, labels)
+
+	// snapshots can't be restored into another tenant
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{module("d")}})
+	require.Nil(t, err)
+
+	_, err = snapshots.RestoreSnapshot(ctx, bytes.NewReader(snapshot.Bytes()))
+	require.Equal(t, graphstore.ErrSnapshotTenant, err)
+	_, err = snapshots.RestoreSnapshot(tenants.NewContext(ctx, "search"), bytes.NewReader(snapshot.Bytes()))
+	require.Equal(t, graphstore.ErrSnapshotTenant, err)
+	require.ElementsMatch(t, []string{"d"}, list(graphStore, ctx))
+	require.ElementsMatch(t, []string{"a"}, list(graphStore, payments))
+
+	_, err = snapshots.RestoreSnapshot(ctx, strings.NewReader("not a snapshot"))
+	require.NotNil(t, err)
+}
+
+func TestCardinality_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	module := func(key, language string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key),
+			GraphItemData: []byte(`{"language":"` + language + `","organization":"depscloud","module":"` + key + `"}`)}
+	}
+
+	rwdb, err := sqlx.Open("sqlite3", "file:cardinality?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	_, err = graphStore.Put(ctx, &store.PutRequest{Items: []*store.GraphItem{
+		module("a", "go"), module("b", "go"), module("c", "node"),
+		{GraphItemType: "depends", K1: []byte("a"), K2: []byte("b"), GraphItemData: []byte(`{"language":"go"}`)},
+		{GraphItemType: "source", K1: []byte("src"), K2: []byte("src"), GraphItemData: []byte(`{"url":"https://github.com/depscloud/a.git"}`)},
+	}})
+	require.Nil(t, err)
+
+	// other tenants and deleted items
+	_, err = graphStore.Put(tenants.NewContext(ctx, "payments"), &store.PutRequest{Items: []*store.GraphItem{module("a", "go")}})
+	require.Nil(t, err)
+
+	_, err = graphStore.Delete(ctx, &store.DeleteRequest{Items: []*store.GraphItem{module("c", "node")}})
+	require.Nil(t, err)
+
+	counts, err := graphStore.(graphstore.Cardinality).CountItems(ctx)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []*graphstore.ItemCount{
+		{GraphItemType: "module", Language: "go", Count: 3},
+		{GraphItemType: "depends", Language: "go", Count: 1},
+		{GraphItemType: "source", Language: "", Count: 1},
+	}, counts)
+}
+
+func TestReadOnly_sqlite(t *testing.T) {
+	rodb, err := sqlx.Open("sqlite3", "file::memory:?cache=shared&mode=ro")
+	require.Nil(t, err)
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(nil, rodb, statements)
+	require.Nil(t, err)
+
+	{
+		resp, err := graphStore.Put(nil, &store.PutRequest{})
+		require.Nil(t, resp)
+		require.Equal(t, api.ErrUnsupported, err)
+	}
+
+	{
+		resp, err := graphStore.Delete(nil, &store.DeleteRequest{})
+		require.Nil(t, resp)
+		require.Equal(t, api.ErrUnsupported, err)
+	}
+}
+
+func TestResolveDriverName(t *testing.T) {
+	_, err := graphstore.ResolveDriverName("sqlite")
+	require.Nil(t, err)
+
+	_, err = graphstore.ResolveDriverName("sqlite3")
+	require.Nil(t, err)
+
+	_, err = graphstore.ResolveDriverName("mysql")
+	require.Nil(t, err)
+
+	_, err = graphstore.ResolveDriverName("postgres")
+	require.Nil(t, err)
+
+	_, err = graphstore.ResolveDriverName("cockroachdb")
+	require.Nil(t, err)
+
+	_, err = graphstore.ResolveDriverName("noDB")
+	require.NotNil(t, err)
+}
+
+func TestReplace_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	module := func(key string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key), GraphItemData: []byte(key)}
+	}
+
+	list := func(graphStore store.GraphStoreServer, ctx context.Context) []string {
+		resp, err := graphStore.List(ctx, &store.ListRequest{Page: 1, Count: 10, Type: "module"})
+		require.Nil(t, err)
+
+		keys := make([]string, len(resp.GetItems()))
+		for i, item := range resp.GetItems() {
+			keys[i] = string(item.GetK1())
+		}
+		return keys
+	}
+
+	rwdb, err := sqlx.Open("sqlite3", "file:replace?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	replacements := graphStore.(graphstore.Replacements)
+
+	applied, err := replacements.Replace(ctx, "run-1", nil, []*store.GraphItem{module("a"), module("b")}, nil)
+	require.Nil(t, err)
+	require.True(t, applied)
+	require.ElementsMatch(t, []string{"a", "b"}, list(graphStore, ctx))
+
+	applied, err = replacements.Replace(ctx, "run-2", nil, []*store.GraphItem{module("c")}, []*store.GraphItem{module("b")})
+	require.Nil(t, err)
+	require.True(t, applied)
+	require.ElementsMatch(t, []string{"a", "c"}, list(graphStore, ctx))
+
+	// retrying an applied replace leaves the graph alone
+	applied, err = replacements.Replace(ctx, "run-1", nil, []*store.GraphItem{module("a"), module("b")}, nil)
+	require.Nil(t, err)
+	require.False(t, applied)
+	require.ElementsMatch(t, []string{"a", "c"}, list(graphStore, ctx))
+
+	// keys are remembered per tenant
+	payments := tenants.NewContext(ctx, "payments")
+	applied, err = replacements.Replace(payments, "run-1", nil, []*store.GraphItem{module("d")}, nil)
+	require.Nil(t, err)
+	require.True(t, applied)
+	require.ElementsMatch(t, []string{"d"}, list(graphStore, payments))
+
+	// a failed replace writes nothing
+	_, err = rwdb.Exec("CREATE TRIGGER reject_e BEFORE INSERT ON dts_graphdata WHEN NEW.k1 = 'ZQ' BEGIN SELECT RAISE(ABORT, 'rejected'); END")
+	require.Nil(t, err)
+
+	_, err = replacements.Replace(ctx, "run-3", nil, []*store.GraphItem{module("e")}, []*store.GraphItem{module("a")})
+	require.NotNil(t, err)
+	require.ElementsMatch(t, []string{"a", "c"}, list(graphStore, ctx))
+
+	// nor does it remember the key
+	_, err = rwdb.Exec("DROP TRIGGER reject_e")
+	require.Nil(t, err)
+
+	applied, err = replacements.Replace(ctx, "run-3", nil, []*store.GraphItem{module("e")}, []*store.GraphItem{module("a")})
+	require.Nil(t, err)
+	require.True(t, applied)
+	require.ElementsMatch(t, []string{"c", "e"}, list(graphStore, ctx))
+}
+
+func TestReplace_sourceState_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	module := func(key string) *store.GraphItem {
+		return &store.GraphItem{GraphItemType: "module", K1: []byte(key), K2: []byte(key), GraphItemData: []byte(key)}
+	}
+
+	rwdb, err := sqlx.Open("sqlite3", "file:replace_state?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	replacements := graphStore.(graphstore.Replacements)
+	source := []byte("source")
+
+	// sources without a recorded state are compared to their observed state
+	_, err = replacements.Replace(ctx, "", &graphstore.SourceState{Key: source, IfState: "s0", Observed: "s1", State: "s2"}, []*store.GraphItem{module("a")}, nil)
+	require.Equal(t, graphstore.ErrSourceChanged, err)
+
+	applied, err := replacements.Replace(ctx, "run-1", &graphstore.SourceState{Key: source, IfState: "s1", Observed: "s1", State: "s2"}, []*store.GraphItem{module("a")}, nil)
+	require.Nil(t, err)
+	require.True(t, applied)
+
+	// two writers holding the same state can't both apply
+	applied, err = replacements.Replace(ctx, "run-2", &graphstore.SourceState{Key: source, IfState: "s2", Observed: "s2", State: "s3"}, []*store.GraphItem{module("b")}, nil)
+	require.Nil(t, err)
+	require.True(t, applied)
+
+	_, err = replacements.Replace(ctx, "run-3", &graphstore.SourceState{Key: source, IfState: "s2", Observed: "s2", State: "s4"}, []*store.GraphItem{module("c")}, nil)
+	require.Equal(t, graphstore.ErrSourceChanged, err)
+
+	resp, err := graphStore.List(ctx, &store.ListRequest{Page: 1, Count: 10, Type: "module"})
+	require.Nil(t, err)
+	require.Len(t, resp.GetItems(), 2)
+
+	// the recorded state wins over the observed one
+	_, err = replacements.Replace(ctx, "run-4", &graphstore.SourceState{Key: source, IfState: "s1", Observed: "s1", State: "s5"}, nil, nil)
+	require.Equal(t, graphstore.ErrSourceChanged, err)
+
+	// retries of an applied replace return the recorded state
+	state := &graphstore.SourceState{Key: source, Observed: "s1", State: "s2"}
+	applied, err = replacements.Replace(ctx, "run-1", state, []*store.GraphItem{module("a")}, nil)
+	require.Nil(t, err)
+	require.False(t, applied)
+	require.Equal(t, "s3", state.State)
+
+	// states are kept per tenant
+	payments := tenants.NewContext(ctx, "payments")
+	applied, err = replacements.Replace(payments, "", &graphstore.SourceState{Key: source, IfState: "p0", Observed: "p0", State: "p1"}, nil, nil)
+	require.Nil(t, err)
+	require.True(t, applied)
+}
+
+func TestVulnerabilities_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	rwdb, err := sqlx.Open("sqlite3", "file:vulnerabilities?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	modified := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	advisory := &graphstore.Advisory{
+		ID:       "GHSA-1234",
+		Aliases:  []string{"CVE-2021-1234", "CVE-2021-12345"},
+		Summary:  "remote code execution",
+		Severity: "HIGH",
+		Modified: modified,
+	}
+
+	osv := graphstore.AdvisorySourceOSV
+	vulnerabilities := graphStore.(graphstore.Vulnerabilities)
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, osv, k1, "1.0.0", []*graphstore.Advisory{advisory}))
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, osv, k1, "1.1.0", []*graphstore.Advisory{advisory}))
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, osv, k2, "2.0.0", []*graphstore.Advisory{{ID: "GHSA-5678", Modified: modified}}))
+
+	// versions are replaced rather than added to
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, osv, k1, "1.1.0", nil))
+
+	// sources are synced independently and an advisory is only recorded once
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, "ghsa", k1, "1.0.0", []*graphstore.Advisory{advisory}))
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, "ghsa", k1, "1.0.0", nil))
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, "ghsa", k3, "3.0.0", []*graphstore.Advisory{{ID: "GHSA-9999", Modified: modified}}))
+
+	affected, err := vulnerabilities.FindAffected(ctx, "CVE-2021-1234")
+	require.Nil(t, err)
+	require.Len(t, affected, 1)
+	require.Equal(t, k1, affected[0].Key)
+	require.Equal(t, "1.0.0", affected[0].Version)
+	require.Equal(t, advisory, affected[0].Advisory)
+
+	affected, err = vulnerabilities.FindAffected(ctx, "GHSA-1234")
+	require.Nil(t, err)
+	require.Len(t, affected, 1)
+
+	// aliases only match in full
+	affected, err = vulnerabilities.FindAffected(ctx, "CVE-2021-123")
+	require.Nil(t, err)
+	require.Len(t, affected, 0)
+
+	affected, err = vulnerabilities.GetAdvisories(ctx, [][]byte{k1, k2, k3})
+	require.Nil(t, err)
+	require.Len(t, affected, 3)
+	require.Equal(t, "GHSA-5678", affected[1].Advisory.ID)
+
+	// advisories are recorded per tenant
+	affected, err = vulnerabilities.FindAffected(tenants.NewContext(ctx, "payments"), "GHSA-1234")
+	require.Nil(t, err)
+	require.Len(t, affected, 0)
+}
+
+func TestMutations_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	received := make(chan *eventbus.Mutation, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Records []map[string][]byte `json:"records"`
+		}{}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+
+		for _, record := range body.Records {
+			mutation, err := eventbus.UnmarshalMutation(record["value"])
+			require.Nil(t, err)
+			received <- mutation
+		}
+		_, _ = w.Write([]byte(`{"offsets":[]}`))
+	}))
+	defer server.Close()
+
+	bus, err := eventbus.NewBus(&eventbus.Config{Driver: eventbus.DriverKafka, Address: server.URL, Topic: "mutations"})
+	require.Nil(t, err)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go bus.Run(runCtx)
+
+	rwdb, err := sqlx.Open("sqlite3", "file:mutations?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+	graphstore.PublishMutations(graphStore, bus)
+
+	next := func() *eventbus.Mutation {
+		select {
+		case mutation := <-received:
+			return mutation
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting on a mutation")
+			return nil
+		}
+	}
+
+	item := &store.GraphItem{GraphItemType: "module", K1: k1, K2: k1, GraphItemData: []byte("{}")}
+
+	payments := tenants.NewContext(ctx, "payments")
+	_, err = graphStore.Put(payments, &store.PutRequest{Items: []*store.GraphItem{item}})
+	require.Nil(t, err)
+
+	mutation := next()
+	require.Equal(t, eventbus.OperationPut, mutation.Operation)
+	require.Equal(t, "payments", mutation.Tenant)
+	require.Equal(t, k1, mutation.Item.GetK1())
+
+	_, err = graphStore.Delete(payments, &store.DeleteRequest{Items: []*store.GraphItem{item}})
+	require.Nil(t, err)
+
+	mutation = next()
+	require.Equal(t, eventbus.OperationDelete, mutation.Operation)
+	require.Equal(t, "payments", mutation.Tenant)
+	require.Equal(t, k1, mutation.Item.GetK1())
+}
+
+func TestRoleBindings_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	rwdb, err := sqlx.Open("sqlite3", "file:rolebindings?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	bindings := graphStore.(graphstore.RoleBindings)
+	require.Nil(t, bindings.PutRoleBinding(ctx, &graphstore.RoleBinding{Subject: "indexer", Role: "editor"}))
+	require.Nil(t, bindings.PutRoleBinding(ctx, &graphstore.RoleBinding{Subject: "alice", Organization: "depscloud", Role: "editor"}))
+	require.Nil(t, bindings.PutRoleBinding(ctx, &graphstore.RoleBinding{Subject: "bob", Role: "viewer"}))
+
+	// bindings are replaced rather than added to
+	require.Nil(t, bindings.PutRoleBinding(ctx, &graphstore.RoleBinding{Subject: "alice", Organization: "depscloud", Role: "admin"}))
+	require.Nil(t, bindings.DeleteRoleBinding(ctx, "bob", ""))
+
+	listed, err := bindings.ListRoleBindings(ctx)
+	require.Nil(t, err)
+	require.Equal(t, []*graphstore.RoleBinding{
+		{Subject: "alice", Organization: "depscloud", Role: "admin"},
+		{Subject: "indexer", Role: "editor"},
+	}, listed)
+
+	// bindings are kept per tenant
+	listed, err = bindings.ListRoleBindings(tenants.NewContext(ctx, "payments"))
+	require.Nil(t, err)
+	require.Len(t, listed, 0)
+}
+
+func TestAuditLog_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	rwdb, err := sqlx.Open("sqlite3", "file:auditlog?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	start := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	records := []*audit.Record{
+		{Time: start, Service: "tracker", Subject: "indexer", Action: "/cloud.deps.api.v1alpha.tracker.SourceService/Track", Resource: "https://github.com/depscloud/depscloud.git", Status: 200},
+		{Time: start.Add(time.Minute), Service: "gateway", Subject: "indexer", Action: "/cloud.deps.api.v1alpha.extractor.DependencyExtractor/Extract", Status: 200},
+		{Time: start.Add(2 * time.Minute), Service: "tracker", Subject: "alice", Action: "PUT /v1alpha/rbac/bindings", Resource: "subject=bob", Status: 403, Error: "denied"},
+		{Time: start.Add(3 * time.Minute), Service: "tracker", Tenant: "payments", Subject: "carol", Action: "POST /v1alpha/tombstones/purge", Status: 200},
+	}
+
+	auditLog := graphStore.(graphstore.AuditLog)
+	require.Nil(t, auditLog.WriteAuditRecords(ctx, records))
+
+	listed, err := auditLog.ListAuditRecords(ctx, &graphstore.AuditQuery{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, []*audit.Record{records[2], records[1], records[0]}, listed)
+
+	listed, err = auditLog.ListAuditRecords(ctx, &graphstore.AuditQuery{Subject: "indexer", Service: "tracker", Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, []*audit.Record{records[0]}, listed)
+
+	listed, err = auditLog.ListAuditRecords(ctx, &graphstore.AuditQuery{Since: start.Add(time.Minute), Until: start.Add(2 * time.Minute), Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, []*audit.Record{records[1]}, listed)
+
+	listed, err = auditLog.ListAuditRecords(ctx, &graphstore.AuditQuery{Limit: 1})
+	require.Nil(t, err)
+	require.Len(t, listed, 1)
+
+	// records are kept per tenant
+	listed, err = auditLog.ListAuditRecords(tenants.NewContext(ctx, "payments"), &graphstore.AuditQuery{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, []*audit.Record{records[3]}, listed)
+}
+
+func TestServiceAccounts_sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	rwdb, err := sqlx.Open("sqlite3", "file:serviceaccounts?mode=memory&cache=shared")
+	require.Nil(t, err)
+	defer rwdb.Close()
+
+	migrator, err := graphstore.NewMigrator(rwdb, "sqlite")
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(ctx))
+
+	statements, err := graphstore.DefaultStatementsFor("sqlite3")
+	require.Nil(t, err)
+
+	graphStore, err := graphstore.NewSQLGraphStore(rwdb, rwdb, statements)
+	require.Nil(t, err)
+
+	accounts := graphStore.(graphstore.ServiceAccounts)
+	createdAt := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	require.Nil(t, accounts.PutServiceAccount(ctx, &serviceaccounts.Account{Name: "indexer", CreatedAt: createdAt}))
+	require.Nil(t, accounts.PutServiceAccount(ctx, &serviceaccounts.Account{Name: "ci", Description: "deploys", CreatedAt: createdAt}))
+
+	listed, err := accounts.ListServiceAccounts(ctx)
+	require.Nil(t, err)
+	require.Equal(t, []*serviceaccounts.Account{
+		{Name: "ci", Description: "deploys", CreatedAt: createdAt},
+		{Name: "indexer", CreatedAt: createdAt},
+	}, listed)
+
+	token := &serviceaccounts.Token{
+		ID:        "0123456789abcdef",
+		Account:   "indexer",
+		Role:      rbac.RoleEditor,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(time.Hour),
+		Hash:      serviceaccounts.Hash("secret"),
+	}
+	require.Nil(t, accounts.PutToken(ctx, token))
+
+	found, err := accounts.GetToken(ctx, token.ID)
+	require.Nil(t, err)
+	require.Equal(t, token, found)
+
+	// tokens are kept per tenant
+	found, err = accounts.GetToken(tenants.NewContext(ctx, "payments"), token.ID)
+	require.Nil(t, err)
+	require.Nil(t, found)
+
+	revokedAt := createdAt.Add(time.Minute)
+	require.Nil(t, accounts.RevokeToken(ctx, token.ID, revokedAt))
+	require.Nil(t, accounts.RevokeToken(ctx, token.ID, revokedAt.Add(time.Minute)))
+
+	tokens, err := accounts.ListTokens(ctx, "indexer")
+	require.Nil(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, &revokedAt, tokens[0].RevokedAt)
+
+	// revoked tokens aren't replaced when rotated
+	replacement := &serviceaccounts.Token{
+		ID:        "replacement",
+		Account:   "indexer",
+		Role:      rbac.RoleViewer,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(time.Hour),
+		Hash:      serviceaccounts.Hash("rotated"),
+	}
+	require.Equal(t, graphstore.ErrTokenRevoked, accounts.RotateToken(ctx, token.ID, revokedAt, replacement))
+
+	found, err = accounts.GetToken(ctx, replacement.ID)
+	require.Nil(t, err)
+	require.Nil(t, found)
+
+	// rotating an active token revokes it along with storing the replacement
+	active := &serviceaccounts.Token{
+		ID:        "active",
+		Account:   "indexer",
+		Role:      rbac.RoleViewer,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(time.Hour),
+		Hash:      serviceaccounts.Hash("active"),
+	}
+	require.Nil(t, accounts.PutToken(ctx, active))
+	require.Nil(t, accounts.RotateToken(ctx, active.ID, revokedAt, replacement))
+
+	found, err = accounts.GetToken(ctx, active.ID)
+	require.Nil(t, err)
+	require.Equal(t, &revokedAt, found.RevokedAt)
+
+	found, err = accounts.GetToken(ctx, replacement.ID)
+	require.Nil(t, err)
+	require.Equal(t, replacement, found)
+
+	require.Nil(t, accounts.DeleteServiceAccount(ctx, "indexer"))
+
+	tokens, err = accounts.ListTokens(ctx, "indexer")
+	require.Nil(t, err)
+	require.Len(t, tokens, 0)
+
+	listed, err = accounts.ListServiceAccounts(ctx)
+	require.Nil(t, err)
+	require.Len(t, listed, 1)
+}
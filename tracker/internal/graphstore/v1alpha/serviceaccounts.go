@@ -0,0 +1,297 @@
+package v1alpha
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/serviceaccounts"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrTokenRevoked is returned when rotating a token that was already revoked.
+var ErrTokenRevoked = errors.New("token was already revoked")
+
+// ServiceAccounts stores the service accounts of each tenant along with the
+// hashes of the tokens issued to them.
+type ServiceAccounts interface {
+	// ListServiceAccounts returns the service accounts of the tenant.
+	ListServiceAccounts(ctx context.Context) ([]*serviceaccounts.Account, error)
+
+	// PutServiceAccount creates or replaces the service account.
+	PutServiceAccount(ctx context.Context, account *serviceaccounts.Account) error
+
+	// DeleteServiceAccount removes the service account and its tokens.
+	DeleteServiceAccount(ctx context.Context, name string) error
+
+	// ListTokens returns the tokens issued to the service account, oldest
+	// first.
+	ListTokens(ctx context.Context, account string) ([]*serviceaccounts.Token, error)
+
+	// GetToken returns the token with the id, or nil when there isn't one.
+	GetToken(ctx context.Context, id string) (*serviceaccounts.Token, error)
+
+	// PutToken stores a newly issued token.
+	PutToken(ctx context.Context, token *serviceaccounts.Token) error
+
+	// RevokeToken marks the token as revoked. Tokens that were already
+	// revoked keep their original revocation time.
+	RevokeToken(ctx context.Context, id string, revokedAt time.Time) error
+
+	// RotateToken revokes the token and stores its replacement in a single
+	// transaction. ErrTokenRevoked is returned, and the replacement isn't
+	// stored, when the token was already revoked.
+	RotateToken(ctx context.Context, id string, revokedAt time.Time, replacement *serviceaccounts.Token) error
+}
+
+type serviceAccountRow struct {
+	Name        string `db:"name"`
+	Description string `db:"description"`
+	CreatedAt   int64  `db:"created_at"`
+}
+
+type tokenRow struct {
+	ID           string `db:"id"`
+	Account      string `db:"account"`
+	Hash         string `db:"token_hash"`
+	Role         string `db:"role"`
+	Organization string `db:"organization"`
+	CreatedAt    int64  `db:"created_at"`
+	ExpiresAt    int64  `db:"expires_at"`
+	RevokedAt    int64  `db:"revoked_at"`
+}
+
+func (row *tokenRow) token() *serviceaccounts.Token {
+	token := &serviceaccounts.Token{
+		ID:           row.ID,
+		Account:      row.Account,
+		Role:         rbac.Role(row.Role),
+		Organization: row.Organization,
+		CreatedAt:    time.Unix(0, row.CreatedAt).UTC(),
+		ExpiresAt:    time.Unix(0, row.ExpiresAt).UTC(),
+		Hash:         row.Hash,
+	}
+
+	if row.RevokedAt > 0 {
+		revokedAt := time.Unix(0, row.RevokedAt).UTC()
+		token.RevokedAt = &revokedAt
+	}
+
+	return token
+}
+
+func (gs *graphStore) ListServiceAccounts(ctx context.Context) ([]*serviceaccounts.Account, error) {
+	if gs.statements.SelectServiceAccounts == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := gs.rodb().NamedQueryContext(ctx, gs.statements.SelectServiceAccounts, map[string]interface{}{
+		"tenant": scopeFor(ctx).name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*serviceaccounts.Account, 0)
+	for rows.Next() {
+		row := &serviceAccountRow{}
+		if err := rows.StructScan(row); err != nil {
+			return nil, err
+		}
+
+		results = append(results, &serviceaccounts.Account{
+			Name:        row.Name,
+			Description: row.Description,
+			CreatedAt:   time.Unix(0, row.CreatedAt).UTC(),
+		})
+	}
+
+	return results, rows.Err()
+}
+
+func (gs *graphStore) PutServiceAccount(ctx context.Context, account *serviceaccounts.Account) error {
+	if gs.rwdb == nil || gs.statements.UpsertServiceAccount == "" {
+		return api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := gs.rwdb.NamedExecContext(ctx, gs.statements.UpsertServiceAccount, map[string]interface{}{
+		"tenant":      scopeFor(ctx).name,
+		"name":        account.Name,
+		"description": account.Description,
+		"created_at":  account.CreatedAt.UnixNano(),
+	})
+	return err
+}
+
+func (gs *graphStore) DeleteServiceAccount(ctx context.Context, name string) error {
+	if gs.rwdb == nil || gs.statements.DeleteServiceAccount == "" || gs.statements.DeleteTokens == "" {
+		return api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	args := map[string]interface{}{
+		"tenant":  scopeFor(ctx).name,
+		"name":    name,
+		"account": name,
+	}
+
+	return gs.inTx(ctx, func(tx *sqlx.Tx) error {
+		for _, statement := range []string{gs.statements.DeleteTokens, gs.statements.DeleteServiceAccount} {
+			if _, err := tx.NamedExecContext(ctx, statement, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (gs *graphStore) ListTokens(ctx context.Context, account string) ([]*serviceaccounts.Token, error) {
+	if gs.statements.SelectTokens == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := gs.rodb().NamedQueryContext(ctx, gs.statements.SelectTokens, map[string]interface{}{
+		"tenant":  scopeFor(ctx).name,
+		"account": account,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*serviceaccounts.Token, 0)
+	for rows.Next() {
+		row := &tokenRow{}
+		if err := rows.StructScan(row); err != nil {
+			return nil, err
+		}
+		results = append(results, row.token())
+	}
+
+	return results, rows.Err()
+}
+
+func (gs *graphStore) GetToken(ctx context.Context, id string) (*serviceaccounts.Token, error) {
+	if gs.statements.SelectToken == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	// tokens are read from the primary, when there is one, so revocations
+	// apply immediately
+	db := gs.rwdb
+	if db == nil {
+		db = gs.rodb()
+	}
+
+	rows, err := db.NamedQueryContext(ctx, gs.statements.SelectToken, map[string]interface{}{
+		"tenant": scopeFor(ctx).name,
+		"id":     id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	row := &tokenRow{}
+	if err := rows.StructScan(row); err != nil {
+		return nil, err
+	}
+
+	return row.token(), nil
+}
+
+func tokenParams(ctx context.Context, token *serviceaccounts.Token) map[string]interface{} {
+	return map[string]interface{}{
+		"tenant":       scopeFor(ctx).name,
+		"id":           token.ID,
+		"account":      token.Account,
+		"token_hash":   token.Hash,
+		"role":         string(token.Role),
+		"organization": token.Organization,
+		"created_at":   token.CreatedAt.UnixNano(),
+		"expires_at":   token.ExpiresAt.UnixNano(),
+	}
+}
+
+func (gs *graphStore) PutToken(ctx context.Context, token *serviceaccounts.Token) error {
+	if gs.rwdb == nil || gs.statements.InsertToken == "" {
+		return api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := gs.rwdb.NamedExecContext(ctx, gs.statements.InsertToken, tokenParams(ctx, token))
+	return err
+}
+
+func (gs *graphStore) RevokeToken(ctx context.Context, id string, revokedAt time.Time) error {
+	if gs.rwdb == nil || gs.statements.RevokeToken == "" {
+		return api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := gs.rwdb.NamedExecContext(ctx, gs.statements.RevokeToken, map[string]interface{}{
+		"tenant":     scopeFor(ctx).name,
+		"id":         id,
+		"revoked_at": revokedAt.UnixNano(),
+	})
+	return err
+}
+
+func (gs *graphStore) RotateToken(ctx context.Context, id string, revokedAt time.Time, replacement *serviceaccounts.Token) error {
+	if gs.rwdb == nil || gs.statements.RevokeToken == "" || gs.statements.InsertToken == "" {
+		return api.ErrUnsupported
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	return gs.inTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.NamedExecContext(ctx, gs.statements.RevokeToken, map[string]interface{}{
+			"tenant":     scopeFor(ctx).name,
+			"id":         id,
+			"revoked_at": revokedAt.UnixNano(),
+		})
+		if err != nil {
+			return err
+		}
+
+		// the revoke only applies to active tokens, so a concurrent rotation
+		// or revocation leaves nothing to update
+		if revoked, err := result.RowsAffected(); err != nil {
+			return err
+		} else if revoked == 0 {
+			return ErrTokenRevoked
+		}
+
+		_, err = tx.NamedExecContext(ctx, gs.statements.InsertToken, tokenParams(ctx, replacement))
+		return err
+	})
+}
+
+var _ ServiceAccounts = &graphStore{}
@@ -0,0 +1,307 @@
+package v1alpha
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/tracker/internal/eventbus"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SnapshotVersion is the version of the snapshot format written by the store.
+const SnapshotVersion = 1
+
+// ErrSnapshotTenant is returned when restoring a snapshot taken from another
+// tenant, which would expose that tenant's graph.
+var ErrSnapshotTenant = errors.New("snapshot was taken from another tenant")
+
+// Snapshots copy the graph of a tenant in and out of the store. Snapshots are
+// gzipped JSON lines that don't depend on the driver, so one taken from one
+// database can be restored into another.
+type Snapshots interface {
+	// WriteSnapshot writes the tenant's graph and labels to w. Everything is
+	// read within a single transaction, so writes made while the snapshot is
+	// taken are either entirely in it or left out.
+	WriteSnapshot(ctx context.Context, w io.Writer) (*SnapshotSummary, error)
+
+	// RestoreSnapshot replaces the tenant's graph with the one in the snapshot
+	// and reapplies its labels. The restore is applied in a single transaction
+	// and recorded in the history like any other write. Snapshots can only be
+	// restored into the tenant they were taken from.
+	RestoreSnapshot(ctx context.Context, r io.Reader) (*SnapshotSummary, error)
+}
+
+// SnapshotSummary describes a snapshot that was written or restored. Deleted
+// is the number of items removed by a restore because the snapshot didn't
+// contain them.
+type SnapshotSummary struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Items     int       `json:"items"`
+	Labels    int       `json:"labels"`
+	Deleted   int       `json:"deleted,omitempty"`
+}
+
+// snapshotRecord is a line of a snapshot. The first line holds the version,
+// creation time, and tenant, every other line holds either an item or labels.
+type snapshotRecord struct {
+	Version   int              `json:"version,omitempty"`
+	CreatedAt *time.Time       `json:"createdAt,omitempty"`
+	Tenant    string           `json:"tenant,omitempty"`
+	Item      *store.GraphItem `json:"item,omitempty"`
+	Labels    *snapshotLabels  `json:"labels,omitempty"`
+}
+
+type snapshotLabels struct {
+	GraphItemType string            `json:"graphItemType"`
+	K1            []byte            `json:"k1"`
+	Labels        map[string]string `json:"labels"`
+}
+
+func (gs *graphStore) WriteSnapshot(ctx context.Context, w io.Writer) (*SnapshotSummary, error) {
+	if gs.statements.SelectSnapshotGraphData == "" || gs.statements.SelectSnapshotLabels == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	scope := scopeFor(ctx)
+	params := map[string]interface{}{
+		"tenant": scope.name,
+	}
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := gs.rodb().BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := namedQueryTx(ctx, tx, gs.statements.SelectSnapshotGraphData, params)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := readGraphItems(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = namedQueryTx(ctx, tx, gs.statements.SelectSnapshotLabels, params)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := readSnapshotLabels(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	summary := &SnapshotSummary{
+		CreatedAt: time.Now().UTC(),
+		Items:     len(items),
+		Labels:    len(labels),
+	}
+
+	gz := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gz)
+
+	if err := encoder.Encode(&snapshotRecord{Version: SnapshotVersion, CreatedAt: &summary.CreatedAt, Tenant: scope.name}); err != nil {
+		return nil, err
+	}
+
+	for _, item := range scope.unscopedItems(items) {
+		if err := encoder.Encode(&snapshotRecord{Item: item}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, label := range labels {
+		label.K1 = scope.unscopedKey(label.K1)
+		if err := encoder.Encode(&snapshotRecord{Labels: label}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func (gs *graphStore) RestoreSnapshot(ctx context.Context, r io.Reader) (*SnapshotSummary, error) {
+	if gs.rwdb == nil {
+		return nil, api.ErrUnsupported
+	} else if gs.statements.SelectSnapshotGraphData == "" || gs.statements.UpsertLabels == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("malformed snapshot: %v", err)
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(gz))
+
+	header := &snapshotRecord{}
+	if err := decoder.Decode(header); err != nil {
+		return nil, fmt.Errorf("malformed snapshot: %v", err)
+	} else if header.Version != SnapshotVersion || header.CreatedAt == nil {
+		return nil, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+
+	scope := scopeFor(ctx)
+	if header.Tenant != scope.name {
+		return nil, ErrSnapshotTenant
+	}
+
+	timestamp := time.Now()
+	summary := &SnapshotSummary{CreatedAt: *header.CreatedAt}
+
+	// evict cached lookups once the restore is visible
+	defer gs.cache.clear()
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := gs.rwdb.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	restored := make(map[string]bool)
+	put := make([]*store.GraphItem, 0)
+	for {
+		record := &snapshotRecord{}
+		if err := decoder.Decode(record); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("malformed snapshot: %v", err)
+		}
+
+		if record.Item != nil {
+			item := scope.item(record.Item, scope.key)
+			if err := gs.putItem(ctx, tx, scope, item, timestamp); err != nil {
+				return nil, err
+			}
+
+			restored[itemKey(item)] = true
+			put = append(put, record.Item)
+			summary.Items++
+
+		} else if record.Labels != nil {
+			if err := ValidateLabels(record.Labels.Labels); err != nil {
+				return nil, err
+			}
+
+			params := map[string]interface{}{
+				"graph_item_type": record.Labels.GraphItemType,
+				"k1":              Base64encode(scope.key(record.Labels.K1)),
+				"labels":          encodeLabels(record.Labels.Labels),
+				"last_modified":   timestamp,
+			}
+
+			if _, err := tx.NamedExecContext(ctx, gs.statements.UpsertLabels, params); err != nil {
+				return nil, err
+			}
+
+			summary.Labels++
+		}
+	}
+
+	// remove the items that were added after the snapshot was taken
+	rows, err := namedQueryTx(ctx, tx, gs.statements.SelectSnapshotGraphData, map[string]interface{}{
+		"tenant": scope.name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := readGraphItems(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]*store.GraphItem, 0)
+	for _, item := range current {
+		if restored[itemKey(item)] {
+			continue
+		}
+
+		if err := gs.deleteItem(ctx, tx, item, timestamp); err != nil {
+			return nil, err
+		}
+
+		deleted = append(deleted, scope.unscopedItem(item))
+		summary.Deleted++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	gs.publish(ctx, scope, eventbus.OperationDelete, deleted, timestamp)
+	gs.publish(ctx, scope, eventbus.OperationPut, put, timestamp)
+
+	return summary, nil
+}
+
+// namedQueryTx runs a named query within the transaction.
+func namedQueryTx(ctx context.Context, tx *sqlx.Tx, statement string, params map[string]interface{}) (*sqlx.Rows, error) {
+	query, args, err := tx.BindNamed(statement, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.QueryxContext(ctx, query, args...)
+}
+
+func readSnapshotLabels(rows *sqlx.Rows) ([]*snapshotLabels, error) {
+	defer rows.Close()
+
+	results := make([]*snapshotLabels, 0)
+	for rows.Next() {
+		var graphItemType, k1, encoded string
+		if err := rows.Scan(&graphItemType, &k1, &encoded); err != nil {
+			return nil, err
+		}
+
+		labels, err := decodeLabels(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		key, _ := Base64decode(k1)
+		results = append(results, &snapshotLabels{
+			GraphItemType: graphItemType,
+			K1:            key,
+			Labels:        labels,
+		})
+	}
+
+	return results, rows.Err()
+}
+
+// itemKey identifies a stored item by its type and keys.
+func itemKey(item *store.GraphItem) string {
+	return item.GetGraphItemType() + "\x00" + Base64encode(item.GetK1()) + "\x00" +
+		Base64encode(item.GetK2()) + "\x00" + Base64encode(item.GetK3())
+}
+
+var _ Snapshots = &graphStore{}
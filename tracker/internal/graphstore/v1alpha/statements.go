@@ -15,6 +15,68 @@ type Statements struct {
 	ListGraphData                         string `json:"listGraphData"`
 	SelectGraphDataUpstreamDependencies   string `json:"selectGraphDataUpstreamDependencies"`
 	SelectGraphDataDownstreamDependencies string `json:"selectGraphDataDownstreamDependencies"`
+	CreateGraphDataHistoryTable           string `json:"createGraphDataHistoryTable"`
+	InsertGraphDataPutHistory             string `json:"insertGraphDataPutHistory"`
+	InsertGraphDataDeleteHistory          string `json:"insertGraphDataDeleteHistory"`
+	SelectGraphDataHistory                string `json:"selectGraphDataHistory"`
+	BackfillGraphDataHistory              string `json:"backfillGraphDataHistory"`
+	ListGraphDataAsOf                     string `json:"listGraphDataAsOf"`
+	SelectGraphDataUpstreamAsOf           string `json:"selectGraphDataUpstreamAsOf"`
+	SelectGraphDataDownstreamAsOf         string `json:"selectGraphDataDownstreamAsOf"`
+	ListTombstones                        string `json:"listTombstones"`
+	PurgeTombstones                       string `json:"purgeTombstones"`
+	SelectStaleGraphData                  string `json:"selectStaleGraphData"`
+	CountTombstones                       string `json:"countTombstones"`
+	CountSupersededHistory                string `json:"countSupersededHistory"`
+	PurgeSupersededHistory                string `json:"purgeSupersededHistory"`
+	CreateLabelsTable                     string `json:"createLabelsTable"`
+	UpsertLabels                          string `json:"upsertLabels"`
+	DeleteLabels                          string `json:"deleteLabels"`
+	SelectLabels                          string `json:"selectLabels"`
+	SearchGraphData                       string `json:"searchGraphData"`
+	SelectPopularity                      string `json:"selectPopularity"`
+	PurgeAllTombstones                    string `json:"purgeAllTombstones"`
+	CountGraphDataUpstream                string `json:"countGraphDataUpstream"`
+	CountGraphDataDownstream              string `json:"countGraphDataDownstream"`
+	SelectSnapshotGraphData               string `json:"selectSnapshotGraphData"`
+	SelectSnapshotLabels                  string `json:"selectSnapshotLabels"`
+	CountGraphDataByLanguage              string `json:"countGraphDataByLanguage"`
+	CreateIdempotencyKeysTable            string `json:"createIdempotencyKeysTable"`
+	SelectIdempotencyKey                  string `json:"selectIdempotencyKey"`
+	InsertIdempotencyKey                  string `json:"insertIdempotencyKey"`
+	PurgeIdempotencyKeys                  string `json:"purgeIdempotencyKeys"`
+	CreateAdvisoriesTable                 string `json:"createAdvisoriesTable"`
+	DeleteAdvisories                      string `json:"deleteAdvisories"`
+	InsertAdvisory                        string `json:"insertAdvisory"`
+	SelectAdvisories                      string `json:"selectAdvisories"`
+	SelectAffected                        string `json:"selectAffected"`
+	CreateRoleBindingsTable               string `json:"createRoleBindingsTable"`
+	UpsertRoleBinding                     string `json:"upsertRoleBinding"`
+	DeleteRoleBinding                     string `json:"deleteRoleBinding"`
+	SelectRoleBindings                    string `json:"selectRoleBindings"`
+	CreateAuditLogTable                   string `json:"createAuditLogTable"`
+	InsertAuditRecord                     string `json:"insertAuditRecord"`
+	SelectAuditRecords                    string `json:"selectAuditRecords"`
+	CreateServiceAccountsTable            string `json:"createServiceAccountsTable"`
+	UpsertServiceAccount                  string `json:"upsertServiceAccount"`
+	DeleteServiceAccount                  string `json:"deleteServiceAccount"`
+	SelectServiceAccounts                 string `json:"selectServiceAccounts"`
+	CreateTokensTable                     string `json:"createTokensTable"`
+	InsertToken                           string `json:"insertToken"`
+	SelectTokens                          string `json:"selectTokens"`
+	SelectToken                           string `json:"selectToken"`
+	RevokeToken                           string `json:"revokeToken"`
+	DeleteTokens                          string `json:"deleteTokens"`
+	CreateModuleCountsTable               string `json:"createModuleCountsTable"`
+	CountActiveEdges                      string `json:"countActiveEdges"`
+	UpsertModuleCounts                    string `json:"upsertModuleCounts"`
+	SelectModuleCounts                    string `json:"selectModuleCounts"`
+	DeleteModuleCounts                    string `json:"deleteModuleCounts"`
+	RebuildModuleCounts                   string `json:"rebuildModuleCounts"`
+	CreateSourceStatesTable               string `json:"createSourceStatesTable"`
+	SelectSourceState                     string `json:"selectSourceState"`
+	InsertSourceState                     string `json:"insertSourceState"`
+	UpdateSourceState                     string `json:"updateSourceState"`
 }
 
 // statements for sqlite
@@ -36,8 +98,8 @@ createGraphDataTable: |
 
 insertGraphData: |
   REPLACE INTO dts_graphdata 
-  (graph_item_type, k1, k2, k3, encoding, graph_item_data, last_modified, date_deleted)
-  VALUES (:graph_item_type, :k1, :k2, :k3, :encoding, :graph_item_data, :last_modified, NULL);
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, last_modified, date_deleted, tenant)
+  VALUES (:graph_item_type, :k1, :k2, :k3, :encoding, :graph_item_data, :last_modified, NULL, :tenant);
 
 deleteGraphData: |
   UPDATE dts_graphdata
@@ -45,9 +107,20 @@ deleteGraphData: |
   WHERE (graph_item_type = :graph_item_type and k1 = :k1 and k2 = :k2 and k3 = :k3);
 
 listGraphData: |
-  SELECT graph_item_type, k1, k2, encoding, graph_item_data
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data
   FROM dts_graphdata
-  WHERE graph_item_type = :graph_item_type 
+  WHERE graph_item_type = :graph_item_type
+  AND tenant = :tenant
+  AND date_deleted IS NULL
+  AND graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = dts_graphdata.graph_item_type AND l.k1 = dts_graphdata.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ))
+  ORDER BY k1, k2, k3
   LIMIT :limit OFFSET :offset;
 
 selectGraphDataUpstreamDependencies: |
@@ -61,7 +134,15 @@ selectGraphDataUpstreamDependencies: |
   AND g2.date_deleted IS NULL
   AND g1.graph_item_type IN (:node_types)
   AND g1.k1 = g1.k2 
-  AND g1.date_deleted IS NULL;
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
 
 selectGraphDataDownstreamDependencies: |
   SELECT g1.graph_item_type, g1.k1, g1.k2, g1.encoding, g1.graph_item_data,
@@ -74,7 +155,580 @@ selectGraphDataDownstreamDependencies: |
   AND g2.date_deleted IS NULL
   AND g1.graph_item_type IN (:node_types)
   AND g1.k1 = g1.k2 
-  AND g1.date_deleted IS NULL;
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+createGraphDataHistoryTable: |
+  CREATE TABLE IF NOT EXISTS dts_graphdata_history(
+      graph_item_type VARCHAR(55),
+      k1 CHAR(64),
+      k2 CHAR(64),
+      k3 VARCHAR(64),
+      encoding TINYINT,
+      graph_item_data TEXT,
+      change_type VARCHAR(8),
+      changed_at BIGINT
+  );
+  CREATE INDEX IF NOT EXISTS history_k1 ON dts_graphdata_history(k1, changed_at);
+  CREATE INDEX IF NOT EXISTS history_k2 ON dts_graphdata_history(k2, changed_at);
+  CREATE INDEX IF NOT EXISTS history_k3 ON dts_graphdata_history(k3, changed_at);
+
+insertGraphDataPutHistory: |
+  INSERT INTO dts_graphdata_history
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, change_type, changed_at, tenant)
+  SELECT :graph_item_type, :k1, :k2, :k3, :encoding, :graph_item_data, 'put', :changed_at, :tenant
+  WHERE NOT EXISTS (
+      SELECT 1 FROM dts_graphdata
+      WHERE graph_item_type = :graph_item_type AND k1 = :k1 AND k2 = :k2 AND k3 = :k3
+      AND graph_item_data = :graph_item_data AND date_deleted IS NULL
+  );
+
+insertGraphDataDeleteHistory: |
+  INSERT INTO dts_graphdata_history
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, change_type, changed_at, tenant)
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data, 'delete', :changed_at, tenant
+  FROM dts_graphdata
+  WHERE graph_item_type = :graph_item_type AND k1 = :k1 AND k2 = :k2 AND k3 = :k3
+  AND date_deleted IS NULL;
+
+selectGraphDataHistory: |
+  SELECT h.graph_item_type, h.k1, h.k2, h.k3, h.encoding, h.graph_item_data, h.change_type, h.changed_at,
+          n1.graph_item_type, n1.encoding, n1.graph_item_data,
+          n2.graph_item_type, n2.encoding, n2.graph_item_data
+  FROM dts_graphdata_history AS h
+  LEFT JOIN dts_graphdata AS n1 ON n1.k1 = h.k1 AND n1.k2 = h.k1
+  LEFT JOIN dts_graphdata AS n2 ON n2.k1 = h.k2 AND n2.k2 = h.k2
+  WHERE (h.k1 IN (:keys) OR h.k2 IN (:keys) OR h.k3 IN (:keys))
+  AND h.k1 != h.k2
+  AND h.changed_at <= :until
+  ORDER BY h.changed_at;
+
+backfillGraphDataHistory: |
+  INSERT INTO dts_graphdata_history
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, change_type, changed_at)
+  SELECT g.graph_item_type, g.k1, g.k2, g.k3, g.encoding, g.graph_item_data, 'put', 0
+  FROM dts_graphdata AS g
+  WHERE g.date_deleted IS NULL
+  AND NOT EXISTS (
+      SELECT 1 FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g.graph_item_type AND h.k1 = g.k1 AND h.k2 = g.k2 AND h.k3 = g.k3
+  );
+
+listGraphDataAsOf: |
+  SELECT g.graph_item_type, g.k1, g.k2, g.k3, g.encoding, g.graph_item_data
+  FROM dts_graphdata_history AS g
+  WHERE g.graph_item_type = :graph_item_type
+  AND g.tenant = :tenant
+  AND g.change_type = 'put'
+  AND g.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g.graph_item_type
+      AND h.k1 = g.k1 AND h.k2 = g.k2 AND h.k3 = g.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g.graph_item_type AND l.k1 = g.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ))
+  ORDER BY g.k1, g.k2, g.k3
+  LIMIT :limit OFFSET :offset;
+
+selectGraphDataUpstreamAsOf: |
+  SELECT g1.graph_item_type, g1.k1, g1.k2, g1.encoding, g1.graph_item_data,
+          g2.graph_item_type, g2.k1, g2.k2, g2.k3, g2.encoding, g2.graph_item_data
+  FROM dts_graphdata_history AS g1
+  INNER JOIN dts_graphdata_history AS g2 ON g1.k1 = g2.k2
+  WHERE g2.k1 IN (:keys)
+  AND g2.graph_item_type IN (:edge_types)
+  AND g2.k1 != g2.k2
+  AND g2.change_type = 'put'
+  AND g2.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g2.graph_item_type
+      AND h.k1 = g2.k1 AND h.k2 = g2.k2 AND h.k3 = g2.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2
+  AND g1.change_type = 'put'
+  AND g1.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g1.graph_item_type
+      AND h.k1 = g1.k1 AND h.k2 = g1.k2 AND h.k3 = g1.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+selectGraphDataDownstreamAsOf: |
+  SELECT g1.graph_item_type, g1.k1, g1.k2, g1.encoding, g1.graph_item_data,
+          g2.graph_item_type, g2.k1, g2.k2, g2.k3, g2.encoding, g2.graph_item_data
+  FROM dts_graphdata_history AS g1
+  INNER JOIN dts_graphdata_history AS g2 ON g1.k2 = g2.k1
+  WHERE g2.k2 IN (:keys)
+  AND g2.graph_item_type IN (:edge_types)
+  AND g2.k1 != g2.k2
+  AND g2.change_type = 'put'
+  AND g2.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g2.graph_item_type
+      AND h.k1 = g2.k1 AND h.k2 = g2.k2 AND h.k3 = g2.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2
+  AND g1.change_type = 'put'
+  AND g1.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g1.graph_item_type
+      AND h.k1 = g1.k1 AND h.k2 = g1.k2 AND h.k3 = g1.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+listTombstones: |
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data, date_deleted
+  FROM dts_graphdata
+  WHERE graph_item_type = :graph_item_type
+  AND tenant = :tenant
+  AND date_deleted IS NOT NULL
+  ORDER BY date_deleted DESC, k1, k2, k3
+  LIMIT :limit OFFSET :offset;
+
+purgeTombstones: |
+  DELETE FROM dts_graphdata
+  WHERE tenant = :tenant
+  AND date_deleted IS NOT NULL
+  AND date_deleted <= :before;
+
+purgeAllTombstones: |
+  DELETE FROM dts_graphdata
+  WHERE date_deleted IS NOT NULL
+  AND date_deleted <= :before;
+
+selectStaleGraphData: |
+  SELECT graph_item_type, k1, k2, k3
+  FROM dts_graphdata
+  WHERE graph_item_type IN (:graph_item_types)
+  AND date_deleted IS NULL
+  AND last_modified < :before;
+
+countTombstones: |
+  SELECT COUNT(*)
+  FROM dts_graphdata
+  WHERE date_deleted IS NOT NULL
+  AND date_deleted <= :before;
+
+countSupersededHistory: |
+  SELECT COUNT(*)
+  FROM dts_graphdata_history AS h
+  WHERE h.changed_at < :before
+  AND EXISTS (
+      SELECT 1 FROM dts_graphdata_history AS newer
+      WHERE newer.graph_item_type = h.graph_item_type
+      AND newer.k1 = h.k1 AND newer.k2 = h.k2 AND newer.k3 = h.k3
+      AND newer.changed_at > h.changed_at
+      AND newer.changed_at <= :before
+  );
+
+purgeSupersededHistory: |
+  DELETE FROM dts_graphdata_history
+  WHERE changed_at < :before
+  AND EXISTS (
+      SELECT 1 FROM dts_graphdata_history AS newer
+      WHERE newer.graph_item_type = dts_graphdata_history.graph_item_type
+      AND newer.k1 = dts_graphdata_history.k1 AND newer.k2 = dts_graphdata_history.k2 AND newer.k3 = dts_graphdata_history.k3
+      AND newer.changed_at > dts_graphdata_history.changed_at
+      AND newer.changed_at <= :before
+  );
+
+createLabelsTable: |
+  CREATE TABLE IF NOT EXISTS dts_labels(
+      graph_item_type VARCHAR(55),
+      k1 CHAR(64),
+      labels TEXT,
+      last_modified DATETIME,
+      PRIMARY KEY (graph_item_type, k1)
+  );
+
+upsertLabels: |
+  REPLACE INTO dts_labels (graph_item_type, k1, labels, last_modified)
+  VALUES (:graph_item_type, :k1, :labels, :last_modified);
+
+deleteLabels: |
+  DELETE FROM dts_labels
+  WHERE graph_item_type = :graph_item_type AND k1 = :k1;
+
+selectLabels: |
+  SELECT k1, labels
+  FROM dts_labels
+  WHERE graph_item_type = :graph_item_type
+  AND k1 IN (:keys);
+
+searchGraphData: |
+  SELECT g.graph_item_type, g.k1, g.k2, g.k3, g.encoding, g.graph_item_data
+  FROM dts_graphdata AS g
+  WHERE g.graph_item_type = :graph_item_type
+  AND g.tenant = :tenant
+  AND g.k1 = g.k2
+  AND g.date_deleted IS NULL
+  AND (g.graph_item_data LIKE :pattern ESCAPE '!' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g.graph_item_type AND l.k1 = g.k1
+      AND l.labels LIKE :pattern ESCAPE '!'
+  ))
+  ORDER BY COALESCE((
+      SELECT c.dependents FROM dts_module_counts AS c
+      WHERE c.k1 = g.k1
+  ), 0) DESC, g.last_modified DESC, g.k1
+  LIMIT :limit;
+
+selectPopularity: |
+  SELECT g.k1, g.last_modified, COALESCE((
+      SELECT c.dependents FROM dts_module_counts AS c
+      WHERE c.k1 = g.k1
+  ), 0) AS dependents
+  FROM dts_graphdata AS g
+  WHERE g.graph_item_type = :graph_item_type
+  AND g.k1 IN (:keys)
+  AND g.k1 = g.k2
+  AND g.date_deleted IS NULL;
+
+countGraphDataUpstream: |
+  SELECT COUNT(DISTINCT g1.k1)
+  FROM dts_graphdata AS g1
+  INNER JOIN dts_graphdata AS g2 ON g1.k1 = g2.k2
+  WHERE g2.k1 IN (:keys) 
+  AND g2.graph_item_type IN (:edge_types) 
+  AND g2.k1 != g2.k2 
+  AND g2.date_deleted IS NULL
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2 
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+countGraphDataDownstream: |
+  SELECT COUNT(DISTINCT g1.k1)
+  FROM dts_graphdata AS g1
+  INNER JOIN dts_graphdata AS g2 ON g1.k2 = g2.k1
+  WHERE g2.k2 IN (:keys) 
+  AND g2.graph_item_type IN (:edge_types) 
+  AND g2.k1 != g2.k2 
+  AND g2.date_deleted IS NULL
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2 
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+selectSnapshotGraphData: |
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data
+  FROM dts_graphdata
+  WHERE tenant = :tenant
+  AND date_deleted IS NULL
+  ORDER BY graph_item_type, k1, k2, k3;
+
+selectSnapshotLabels: |
+  SELECT l.graph_item_type, l.k1, l.labels
+  FROM dts_labels AS l
+  WHERE EXISTS (
+      SELECT 1 FROM dts_graphdata AS g
+      WHERE g.graph_item_type = l.graph_item_type AND g.k1 = l.k1
+      AND g.tenant = :tenant
+      AND g.date_deleted IS NULL
+  )
+  ORDER BY l.graph_item_type, l.k1;
+
+countGraphDataByLanguage: |
+  SELECT graph_item_type, language, COUNT(*)
+  FROM (
+      SELECT graph_item_type,
+      CASE WHEN instr(graph_item_data, '"language":"') > 0
+      THEN substr(
+          substr(graph_item_data, instr(graph_item_data, '"language":"') + 12), 1,
+          instr(substr(graph_item_data, instr(graph_item_data, '"language":"') + 12), '"') - 1
+      )
+      ELSE '' END AS language
+      FROM dts_graphdata
+      WHERE date_deleted IS NULL
+  ) AS items
+  GROUP BY graph_item_type, language;
+
+createIdempotencyKeysTable: |
+  CREATE TABLE IF NOT EXISTS dts_idempotency_keys(
+      tenant VARCHAR(64),
+      idempotency_key VARCHAR(128),
+      applied_at BIGINT,
+      PRIMARY KEY (tenant, idempotency_key)
+  );
+
+selectIdempotencyKey: |
+  SELECT COUNT(*)
+  FROM dts_idempotency_keys
+  WHERE tenant = :tenant
+  AND idempotency_key = :idempotency_key;
+
+insertIdempotencyKey: |
+  INSERT INTO dts_idempotency_keys (tenant, idempotency_key, applied_at)
+  VALUES (:tenant, :idempotency_key, :applied_at);
+
+purgeIdempotencyKeys: |
+  DELETE FROM dts_idempotency_keys
+  WHERE applied_at < :before;
+
+createAdvisoriesTable: |
+  CREATE TABLE IF NOT EXISTS dts_advisories(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      k1 CHAR(64),
+      version VARCHAR(255),
+      advisory_id VARCHAR(128),
+      aliases TEXT,
+      summary TEXT,
+      severity VARCHAR(64),
+      modified BIGINT,
+      PRIMARY KEY (k1, version, advisory_id)
+  );
+  CREATE INDEX IF NOT EXISTS advisory_id ON dts_advisories(tenant, advisory_id);
+
+deleteAdvisories: |
+  DELETE FROM dts_advisories
+  WHERE k1 = :k1 AND version = :version AND source = :source;
+
+insertAdvisory: |
+  INSERT OR IGNORE INTO dts_advisories (tenant, source, k1, version, advisory_id, aliases, summary, severity, modified)
+  VALUES (:tenant, :source, :k1, :version, :advisory_id, :aliases, :summary, :severity, :modified);
+
+selectAdvisories: |
+  SELECT k1, version, advisory_id, aliases, summary, severity, modified
+  FROM dts_advisories
+  WHERE k1 IN (:keys)
+  ORDER BY k1, version, advisory_id;
+
+selectAffected: |
+  SELECT k1, version, advisory_id, aliases, summary, severity, modified
+  FROM dts_advisories
+  WHERE tenant = :tenant
+  AND (advisory_id = :advisory_id OR aliases LIKE :pattern ESCAPE '!')
+  ORDER BY k1, version;
+
+createRoleBindingsTable: |
+  CREATE TABLE IF NOT EXISTS dts_role_bindings(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      subject VARCHAR(255),
+      organization VARCHAR(255) NOT NULL DEFAULT '',
+      role VARCHAR(16),
+      PRIMARY KEY (tenant, subject, organization)
+  );
+
+upsertRoleBinding: |
+  REPLACE INTO dts_role_bindings (tenant, subject, organization, role)
+  VALUES (:tenant, :subject, :organization, :role);
+
+deleteRoleBinding: |
+  DELETE FROM dts_role_bindings
+  WHERE tenant = :tenant AND subject = :subject AND organization = :organization;
+
+selectRoleBindings: |
+  SELECT subject, organization, role
+  FROM dts_role_bindings
+  WHERE tenant = :tenant
+  ORDER BY subject, organization;
+
+createAuditLogTable: |
+  CREATE TABLE IF NOT EXISTS dts_audit_log(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      recorded_at BIGINT,
+      service VARCHAR(64),
+      subject VARCHAR(255),
+      action VARCHAR(255),
+      resource TEXT,
+      status INTEGER,
+      error TEXT
+  );
+  CREATE INDEX IF NOT EXISTS audit_recorded_at ON dts_audit_log(tenant, recorded_at);
+
+insertAuditRecord: |
+  INSERT INTO dts_audit_log (tenant, recorded_at, service, subject, action, resource, status, error)
+  VALUES (:tenant, :recorded_at, :service, :subject, :action, :resource, :status, :error);
+
+selectAuditRecords: |
+  SELECT tenant, recorded_at, service, subject, action, resource, status, error
+  FROM dts_audit_log
+  WHERE tenant = :tenant
+  AND recorded_at >= :since AND recorded_at < :until
+  AND (:service = '' OR service = :service)
+  AND (:subject = '' OR subject = :subject)
+  AND (:action = '' OR action = :action)
+  ORDER BY recorded_at DESC
+  LIMIT :limit;
+
+createServiceAccountsTable: |
+  CREATE TABLE IF NOT EXISTS dts_service_accounts(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      name VARCHAR(64),
+      description TEXT,
+      created_at BIGINT,
+      PRIMARY KEY (tenant, name)
+  );
+
+upsertServiceAccount: |
+  REPLACE INTO dts_service_accounts (tenant, name, description, created_at)
+  VALUES (:tenant, :name, :description, :created_at);
+
+deleteServiceAccount: |
+  DELETE FROM dts_service_accounts
+  WHERE tenant = :tenant AND name = :name;
+
+selectServiceAccounts: |
+  SELECT name, description, created_at
+  FROM dts_service_accounts
+  WHERE tenant = :tenant
+  ORDER BY name;
+
+createTokensTable: |
+  CREATE TABLE IF NOT EXISTS dts_tokens(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      id VARCHAR(32),
+      account VARCHAR(64),
+      token_hash CHAR(64),
+      role VARCHAR(16),
+      organization VARCHAR(255) NOT NULL DEFAULT '',
+      created_at BIGINT,
+      expires_at BIGINT,
+      revoked_at BIGINT NOT NULL DEFAULT 0,
+      PRIMARY KEY (tenant, id)
+  );
+  CREATE INDEX IF NOT EXISTS tokens_account ON dts_tokens(tenant, account);
+
+insertToken: |
+  INSERT INTO dts_tokens (tenant, id, account, token_hash, role, organization, created_at, expires_at)
+  VALUES (:tenant, :id, :account, :token_hash, :role, :organization, :created_at, :expires_at);
+
+selectTokens: |
+  SELECT id, account, token_hash, role, organization, created_at, expires_at, revoked_at
+  FROM dts_tokens
+  WHERE tenant = :tenant AND account = :account
+  ORDER BY created_at;
+
+selectToken: |
+  SELECT id, account, token_hash, role, organization, created_at, expires_at, revoked_at
+  FROM dts_tokens
+  WHERE tenant = :tenant AND id = :id;
+
+revokeToken: |
+  UPDATE dts_tokens
+  SET revoked_at = :revoked_at
+  WHERE tenant = :tenant AND id = :id AND revoked_at = 0;
+
+deleteTokens: |
+  DELETE FROM dts_tokens
+  WHERE tenant = :tenant AND account = :account;
+
+createModuleCountsTable: |
+  CREATE TABLE IF NOT EXISTS dts_module_counts(
+      k1 CHAR(64),
+      dependents BIGINT NOT NULL DEFAULT 0,
+      dependencies BIGINT NOT NULL DEFAULT 0,
+      PRIMARY KEY (k1)
+  );
+
+countActiveEdges: |
+  SELECT COUNT(*)
+  FROM dts_graphdata
+  WHERE graph_item_type = :graph_item_type
+  AND k1 = :k1
+  AND k2 = :k2
+  AND date_deleted IS NULL;
+
+upsertModuleCounts: |
+  INSERT INTO dts_module_counts (k1, dependents, dependencies)
+  VALUES (:k1, :dependents, :dependencies)
+  ON CONFLICT (k1) DO UPDATE SET
+  dependents = dependents + excluded.dependents,
+  dependencies = dependencies + excluded.dependencies;
+
+selectModuleCounts: |
+  SELECT dependents, dependencies
+  FROM dts_module_counts
+  WHERE k1 = :k1;
+
+deleteModuleCounts: |
+  DELETE FROM dts_module_counts;
+
+rebuildModuleCounts: |
+  INSERT INTO dts_module_counts (k1, dependents, dependencies)
+  SELECT c.k1, SUM(c.dependents), SUM(c.dependencies)
+  FROM (
+      SELECT k2 AS k1, COUNT(DISTINCT k1) AS dependents, 0 AS dependencies
+      FROM dts_graphdata
+      WHERE graph_item_type = 'depends' AND k1 != k2 AND date_deleted IS NULL
+      GROUP BY k2
+      UNION ALL
+      SELECT k1, 0 AS dependents, COUNT(DISTINCT k2) AS dependencies
+      FROM dts_graphdata
+      WHERE graph_item_type = 'depends' AND k1 != k2 AND date_deleted IS NULL
+      GROUP BY k1
+  ) AS c
+  GROUP BY c.k1;
+
+createSourceStatesTable: |
+  CREATE TABLE IF NOT EXISTS dts_source_states(
+      k1 CHAR(64),
+      state CHAR(64) NOT NULL,
+      PRIMARY KEY (k1)
+  );
+
+selectSourceState: |
+  SELECT state
+  FROM dts_source_states
+  WHERE k1 = :k1;
+
+insertSourceState: |
+  INSERT INTO dts_source_states (k1, state)
+  VALUES (:k1, :state);
+
+updateSourceState: |
+  UPDATE dts_source_states
+  SET state = :state
+  WHERE k1 = :k1
+  AND state = :previous;
 `
 
 // statements for mysql
@@ -96,8 +750,8 @@ createGraphDataTable: |
 
 insertGraphData: |
   INSERT INTO dts_graphdata 
-  (graph_item_type, k1, k2, k3, encoding, graph_item_data, last_modified, date_deleted)
-  VALUES (:graph_item_type, :k1, :k2, :k3, :encoding, :graph_item_data, :last_modified, NULL)
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, last_modified, date_deleted, tenant)
+  VALUES (:graph_item_type, :k1, :k2, :k3, :encoding, :graph_item_data, :last_modified, NULL, :tenant)
   ON DUPLICATE KEY UPDATE
   encoding = :encoding,
   graph_item_data = :graph_item_data, 
@@ -110,9 +764,20 @@ deleteGraphData: |
   WHERE (graph_item_type = :graph_item_type and k1 = :k1 and k2 = :k2 and k3 = :k3);
 
 listGraphData: |
-  SELECT graph_item_type, k1, k2, encoding, graph_item_data
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data
   FROM dts_graphdata
-  WHERE graph_item_type = :graph_item_type 
+  WHERE graph_item_type = :graph_item_type
+  AND tenant = :tenant
+  AND date_deleted IS NULL
+  AND graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = dts_graphdata.graph_item_type AND l.k1 = dts_graphdata.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ))
+  ORDER BY k1, k2, k3
   LIMIT :limit OFFSET :offset;
 
 selectGraphDataUpstreamDependencies: |
@@ -126,7 +791,15 @@ selectGraphDataUpstreamDependencies: |
   AND g2.date_deleted IS NULL
   AND g1.graph_item_type IN (:node_types)
   AND g1.k1 = g1.k2 
-  AND g1.date_deleted IS NULL;
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
 
 selectGraphDataDownstreamDependencies: |
   SELECT g1.graph_item_type, g1.k1, g1.k2, g1.encoding, g1.graph_item_data,
@@ -139,7 +812,583 @@ selectGraphDataDownstreamDependencies: |
   AND g2.date_deleted IS NULL
   AND g1.graph_item_type IN (:node_types)
   AND g1.k1 = g1.k2 
-  AND g1.date_deleted IS NULL;
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+createGraphDataHistoryTable: |
+  CREATE TABLE IF NOT EXISTS dts_graphdata_history(
+      graph_item_type VARCHAR(55),
+      k1 CHAR(64),
+      k2 CHAR(64),
+      k3 VARCHAR(64),
+      encoding TINYINT,
+      graph_item_data TEXT,
+      change_type VARCHAR(8),
+      changed_at BIGINT,
+      KEY history_k1 (k1, changed_at),
+      KEY history_k2 (k2, changed_at),
+      KEY history_k3 (k3, changed_at)
+  );
+
+insertGraphDataPutHistory: |
+  INSERT INTO dts_graphdata_history
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, change_type, changed_at, tenant)
+  SELECT :graph_item_type, :k1, :k2, :k3, :encoding, :graph_item_data, 'put', :changed_at, :tenant
+  FROM DUAL
+  WHERE NOT EXISTS (
+      SELECT 1 FROM dts_graphdata
+      WHERE graph_item_type = :graph_item_type AND k1 = :k1 AND k2 = :k2 AND k3 = :k3
+      AND graph_item_data = :graph_item_data AND date_deleted IS NULL
+  );
+
+insertGraphDataDeleteHistory: |
+  INSERT INTO dts_graphdata_history
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, change_type, changed_at, tenant)
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data, 'delete', :changed_at, tenant
+  FROM dts_graphdata
+  WHERE graph_item_type = :graph_item_type AND k1 = :k1 AND k2 = :k2 AND k3 = :k3
+  AND date_deleted IS NULL;
+
+selectGraphDataHistory: |
+  SELECT h.graph_item_type, h.k1, h.k2, h.k3, h.encoding, h.graph_item_data, h.change_type, h.changed_at,
+          n1.graph_item_type, n1.encoding, n1.graph_item_data,
+          n2.graph_item_type, n2.encoding, n2.graph_item_data
+  FROM dts_graphdata_history AS h
+  LEFT JOIN dts_graphdata AS n1 ON n1.k1 = h.k1 AND n1.k2 = h.k1
+  LEFT JOIN dts_graphdata AS n2 ON n2.k1 = h.k2 AND n2.k2 = h.k2
+  WHERE (h.k1 IN (:keys) OR h.k2 IN (:keys) OR h.k3 IN (:keys))
+  AND h.k1 != h.k2
+  AND h.changed_at <= :until
+  ORDER BY h.changed_at;
+
+backfillGraphDataHistory: |
+  INSERT INTO dts_graphdata_history
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, change_type, changed_at)
+  SELECT g.graph_item_type, g.k1, g.k2, g.k3, g.encoding, g.graph_item_data, 'put', 0
+  FROM dts_graphdata AS g
+  WHERE g.date_deleted IS NULL
+  AND NOT EXISTS (
+      SELECT 1 FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g.graph_item_type AND h.k1 = g.k1 AND h.k2 = g.k2 AND h.k3 = g.k3
+  );
+
+listGraphDataAsOf: |
+  SELECT g.graph_item_type, g.k1, g.k2, g.k3, g.encoding, g.graph_item_data
+  FROM dts_graphdata_history AS g
+  WHERE g.graph_item_type = :graph_item_type
+  AND g.tenant = :tenant
+  AND g.change_type = 'put'
+  AND g.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g.graph_item_type
+      AND h.k1 = g.k1 AND h.k2 = g.k2 AND h.k3 = g.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g.graph_item_type AND l.k1 = g.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ))
+  ORDER BY g.k1, g.k2, g.k3
+  LIMIT :limit OFFSET :offset;
+
+selectGraphDataUpstreamAsOf: |
+  SELECT g1.graph_item_type, g1.k1, g1.k2, g1.encoding, g1.graph_item_data,
+          g2.graph_item_type, g2.k1, g2.k2, g2.k3, g2.encoding, g2.graph_item_data
+  FROM dts_graphdata_history AS g1
+  INNER JOIN dts_graphdata_history AS g2 ON g1.k1 = g2.k2
+  WHERE g2.k1 IN (:keys)
+  AND g2.graph_item_type IN (:edge_types)
+  AND g2.k1 != g2.k2
+  AND g2.change_type = 'put'
+  AND g2.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g2.graph_item_type
+      AND h.k1 = g2.k1 AND h.k2 = g2.k2 AND h.k3 = g2.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2
+  AND g1.change_type = 'put'
+  AND g1.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g1.graph_item_type
+      AND h.k1 = g1.k1 AND h.k2 = g1.k2 AND h.k3 = g1.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+selectGraphDataDownstreamAsOf: |
+  SELECT g1.graph_item_type, g1.k1, g1.k2, g1.encoding, g1.graph_item_data,
+          g2.graph_item_type, g2.k1, g2.k2, g2.k3, g2.encoding, g2.graph_item_data
+  FROM dts_graphdata_history AS g1
+  INNER JOIN dts_graphdata_history AS g2 ON g1.k2 = g2.k1
+  WHERE g2.k2 IN (:keys)
+  AND g2.graph_item_type IN (:edge_types)
+  AND g2.k1 != g2.k2
+  AND g2.change_type = 'put'
+  AND g2.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g2.graph_item_type
+      AND h.k1 = g2.k1 AND h.k2 = g2.k2 AND h.k3 = g2.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2
+  AND g1.change_type = 'put'
+  AND g1.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g1.graph_item_type
+      AND h.k1 = g1.k1 AND h.k2 = g1.k2 AND h.k3 = g1.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+listTombstones: |
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data, date_deleted
+  FROM dts_graphdata
+  WHERE graph_item_type = :graph_item_type
+  AND tenant = :tenant
+  AND date_deleted IS NOT NULL
+  ORDER BY date_deleted DESC, k1, k2, k3
+  LIMIT :limit OFFSET :offset;
+
+purgeTombstones: |
+  DELETE FROM dts_graphdata
+  WHERE tenant = :tenant
+  AND date_deleted IS NOT NULL
+  AND date_deleted <= :before;
+
+purgeAllTombstones: |
+  DELETE FROM dts_graphdata
+  WHERE date_deleted IS NOT NULL
+  AND date_deleted <= :before;
+
+selectStaleGraphData: |
+  SELECT graph_item_type, k1, k2, k3
+  FROM dts_graphdata
+  WHERE graph_item_type IN (:graph_item_types)
+  AND date_deleted IS NULL
+  AND last_modified < :before;
+
+countTombstones: |
+  SELECT COUNT(*)
+  FROM dts_graphdata
+  WHERE date_deleted IS NOT NULL
+  AND date_deleted <= :before;
+
+countSupersededHistory: |
+  SELECT COUNT(*)
+  FROM dts_graphdata_history AS h
+  WHERE h.changed_at < :before
+  AND EXISTS (
+      SELECT 1 FROM dts_graphdata_history AS newer
+      WHERE newer.graph_item_type = h.graph_item_type
+      AND newer.k1 = h.k1 AND newer.k2 = h.k2 AND newer.k3 = h.k3
+      AND newer.changed_at > h.changed_at
+      AND newer.changed_at <= :before
+  );
+
+purgeSupersededHistory: |
+  DELETE h FROM dts_graphdata_history AS h
+  INNER JOIN dts_graphdata_history AS newer
+  ON newer.graph_item_type = h.graph_item_type
+  AND newer.k1 = h.k1 AND newer.k2 = h.k2 AND newer.k3 = h.k3
+  WHERE h.changed_at < :before
+  AND newer.changed_at > h.changed_at
+  AND newer.changed_at <= :before;
+
+createLabelsTable: |
+  CREATE TABLE IF NOT EXISTS dts_labels(
+      graph_item_type VARCHAR(55),
+      k1 CHAR(64),
+      labels TEXT,
+      last_modified DATETIME,
+      PRIMARY KEY (graph_item_type, k1)
+  );
+
+upsertLabels: |
+  INSERT INTO dts_labels (graph_item_type, k1, labels, last_modified)
+  VALUES (:graph_item_type, :k1, :labels, :last_modified)
+  ON DUPLICATE KEY UPDATE
+  labels = :labels,
+  last_modified = :last_modified;
+
+deleteLabels: |
+  DELETE FROM dts_labels
+  WHERE graph_item_type = :graph_item_type AND k1 = :k1;
+
+selectLabels: |
+  SELECT k1, labels
+  FROM dts_labels
+  WHERE graph_item_type = :graph_item_type
+  AND k1 IN (:keys);
+
+searchGraphData: |
+  SELECT g.graph_item_type, g.k1, g.k2, g.k3, g.encoding, g.graph_item_data
+  FROM dts_graphdata AS g
+  WHERE g.graph_item_type = :graph_item_type
+  AND g.tenant = :tenant
+  AND g.k1 = g.k2
+  AND g.date_deleted IS NULL
+  AND (g.graph_item_data LIKE :pattern ESCAPE '!' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g.graph_item_type AND l.k1 = g.k1
+      AND l.labels LIKE :pattern ESCAPE '!'
+  ))
+  ORDER BY COALESCE((
+      SELECT c.dependents FROM dts_module_counts AS c
+      WHERE c.k1 = g.k1
+  ), 0) DESC, g.last_modified DESC, g.k1
+  LIMIT :limit;
+
+selectPopularity: |
+  SELECT g.k1, g.last_modified, COALESCE((
+      SELECT c.dependents FROM dts_module_counts AS c
+      WHERE c.k1 = g.k1
+  ), 0) AS dependents
+  FROM dts_graphdata AS g
+  WHERE g.graph_item_type = :graph_item_type
+  AND g.k1 IN (:keys)
+  AND g.k1 = g.k2
+  AND g.date_deleted IS NULL;
+
+countGraphDataUpstream: |
+  SELECT COUNT(DISTINCT g1.k1)
+  FROM dts_graphdata AS g1
+  INNER JOIN dts_graphdata AS g2 ON g1.k1 = g2.k2
+  WHERE g2.k1 IN (:keys) 
+  AND g2.graph_item_type IN (:edge_types) 
+  AND g2.k1 != g2.k2 
+  AND g2.date_deleted IS NULL
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2 
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+countGraphDataDownstream: |
+  SELECT COUNT(DISTINCT g1.k1)
+  FROM dts_graphdata AS g1
+  INNER JOIN dts_graphdata AS g2 ON g1.k2 = g2.k1
+  WHERE g2.k2 IN (:keys) 
+  AND g2.graph_item_type IN (:edge_types) 
+  AND g2.k1 != g2.k2 
+  AND g2.date_deleted IS NULL
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2 
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (:labels_pattern = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+selectSnapshotGraphData: |
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data
+  FROM dts_graphdata
+  WHERE tenant = :tenant
+  AND date_deleted IS NULL
+  ORDER BY graph_item_type, k1, k2, k3;
+
+selectSnapshotLabels: |
+  SELECT l.graph_item_type, l.k1, l.labels
+  FROM dts_labels AS l
+  WHERE EXISTS (
+      SELECT 1 FROM dts_graphdata AS g
+      WHERE g.graph_item_type = l.graph_item_type AND g.k1 = l.k1
+      AND g.tenant = :tenant
+      AND g.date_deleted IS NULL
+  )
+  ORDER BY l.graph_item_type, l.k1;
+
+countGraphDataByLanguage: |
+  SELECT graph_item_type, language, COUNT(*)
+  FROM (
+      SELECT graph_item_type,
+      CASE WHEN LOCATE('"language":"', graph_item_data) > 0
+      THEN SUBSTRING_INDEX(SUBSTRING_INDEX(graph_item_data, '"language":"', -1), '"', 1)
+      ELSE '' END AS language
+      FROM dts_graphdata
+      WHERE date_deleted IS NULL
+  ) AS items
+  GROUP BY graph_item_type, language;
+
+createIdempotencyKeysTable: |
+  CREATE TABLE IF NOT EXISTS dts_idempotency_keys(
+      tenant VARCHAR(64),
+      idempotency_key VARCHAR(128),
+      applied_at BIGINT,
+      PRIMARY KEY (tenant, idempotency_key)
+  );
+
+selectIdempotencyKey: |
+  SELECT COUNT(*)
+  FROM dts_idempotency_keys
+  WHERE tenant = :tenant
+  AND idempotency_key = :idempotency_key;
+
+insertIdempotencyKey: |
+  INSERT INTO dts_idempotency_keys (tenant, idempotency_key, applied_at)
+  VALUES (:tenant, :idempotency_key, :applied_at);
+
+purgeIdempotencyKeys: |
+  DELETE FROM dts_idempotency_keys
+  WHERE applied_at < :before;
+
+createAdvisoriesTable: |
+  CREATE TABLE IF NOT EXISTS dts_advisories(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      k1 CHAR(64),
+      version VARCHAR(255),
+      advisory_id VARCHAR(128),
+      aliases TEXT,
+      summary TEXT,
+      severity VARCHAR(64),
+      modified BIGINT,
+      PRIMARY KEY (k1, version, advisory_id),
+      KEY advisory_id (tenant, advisory_id)
+  );
+
+deleteAdvisories: |
+  DELETE FROM dts_advisories
+  WHERE k1 = :k1 AND version = :version AND source = :source;
+
+insertAdvisory: |
+  INSERT IGNORE INTO dts_advisories (tenant, source, k1, version, advisory_id, aliases, summary, severity, modified)
+  VALUES (:tenant, :source, :k1, :version, :advisory_id, :aliases, :summary, :severity, :modified);
+
+selectAdvisories: |
+  SELECT k1, version, advisory_id, aliases, summary, severity, modified
+  FROM dts_advisories
+  WHERE k1 IN (:keys)
+  ORDER BY k1, version, advisory_id;
+
+selectAffected: |
+  SELECT k1, version, advisory_id, aliases, summary, severity, modified
+  FROM dts_advisories
+  WHERE tenant = :tenant
+  AND (advisory_id = :advisory_id OR aliases LIKE :pattern ESCAPE '!')
+  ORDER BY k1, version;
+
+createRoleBindingsTable: |
+  CREATE TABLE IF NOT EXISTS dts_role_bindings(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      subject VARCHAR(255),
+      organization VARCHAR(255) NOT NULL DEFAULT '',
+      role VARCHAR(16),
+      PRIMARY KEY (tenant, subject, organization)
+  );
+
+upsertRoleBinding: |
+  INSERT INTO dts_role_bindings (tenant, subject, organization, role)
+  VALUES (:tenant, :subject, :organization, :role)
+  ON DUPLICATE KEY UPDATE
+  role = :role;
+
+deleteRoleBinding: |
+  DELETE FROM dts_role_bindings
+  WHERE tenant = :tenant AND subject = :subject AND organization = :organization;
+
+selectRoleBindings: |
+  SELECT subject, organization, role
+  FROM dts_role_bindings
+  WHERE tenant = :tenant
+  ORDER BY subject, organization;
+
+createAuditLogTable: |
+  CREATE TABLE IF NOT EXISTS dts_audit_log(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      recorded_at BIGINT,
+      service VARCHAR(64),
+      subject VARCHAR(255),
+      action VARCHAR(255),
+      resource TEXT,
+      status INTEGER,
+      error TEXT,
+      KEY audit_recorded_at (tenant, recorded_at)
+  );
+
+insertAuditRecord: |
+  INSERT INTO dts_audit_log (tenant, recorded_at, service, subject, action, resource, status, error)
+  VALUES (:tenant, :recorded_at, :service, :subject, :action, :resource, :status, :error);
+
+selectAuditRecords: |
+  SELECT tenant, recorded_at, service, subject, action, resource, status, error
+  FROM dts_audit_log
+  WHERE tenant = :tenant
+  AND recorded_at >= :since AND recorded_at < :until
+  AND (:service = '' OR service = :service)
+  AND (:subject = '' OR subject = :subject)
+  AND (:action = '' OR action = :action)
+  ORDER BY recorded_at DESC
+  LIMIT :limit;
+
+createServiceAccountsTable: |
+  CREATE TABLE IF NOT EXISTS dts_service_accounts(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      name VARCHAR(64),
+      description TEXT,
+      created_at BIGINT,
+      PRIMARY KEY (tenant, name)
+  );
+
+upsertServiceAccount: |
+  INSERT INTO dts_service_accounts (tenant, name, description, created_at)
+  VALUES (:tenant, :name, :description, :created_at)
+  ON DUPLICATE KEY UPDATE
+  description = :description;
+
+deleteServiceAccount: |
+  DELETE FROM dts_service_accounts
+  WHERE tenant = :tenant AND name = :name;
+
+selectServiceAccounts: |
+  SELECT name, description, created_at
+  FROM dts_service_accounts
+  WHERE tenant = :tenant
+  ORDER BY name;
+
+createTokensTable: |
+  CREATE TABLE IF NOT EXISTS dts_tokens(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      id VARCHAR(32),
+      account VARCHAR(64),
+      token_hash CHAR(64),
+      role VARCHAR(16),
+      organization VARCHAR(255) NOT NULL DEFAULT '',
+      created_at BIGINT,
+      expires_at BIGINT,
+      revoked_at BIGINT NOT NULL DEFAULT 0,
+      PRIMARY KEY (tenant, id),
+      KEY tokens_account (tenant, account)
+  );
+
+insertToken: |
+  INSERT INTO dts_tokens (tenant, id, account, token_hash, role, organization, created_at, expires_at)
+  VALUES (:tenant, :id, :account, :token_hash, :role, :organization, :created_at, :expires_at);
+
+selectTokens: |
+  SELECT id, account, token_hash, role, organization, created_at, expires_at, revoked_at
+  FROM dts_tokens
+  WHERE tenant = :tenant AND account = :account
+  ORDER BY created_at;
+
+selectToken: |
+  SELECT id, account, token_hash, role, organization, created_at, expires_at, revoked_at
+  FROM dts_tokens
+  WHERE tenant = :tenant AND id = :id;
+
+revokeToken: |
+  UPDATE dts_tokens
+  SET revoked_at = :revoked_at
+  WHERE tenant = :tenant AND id = :id AND revoked_at = 0;
+
+deleteTokens: |
+  DELETE FROM dts_tokens
+  WHERE tenant = :tenant AND account = :account;
+
+createModuleCountsTable: |
+  CREATE TABLE IF NOT EXISTS dts_module_counts(
+      k1 CHAR(64),
+      dependents BIGINT NOT NULL DEFAULT 0,
+      dependencies BIGINT NOT NULL DEFAULT 0,
+      PRIMARY KEY (k1)
+  );
+
+countActiveEdges: |
+  SELECT COUNT(*)
+  FROM dts_graphdata
+  WHERE graph_item_type = :graph_item_type
+  AND k1 = :k1
+  AND k2 = :k2
+  AND date_deleted IS NULL;
+
+upsertModuleCounts: |
+  INSERT INTO dts_module_counts (k1, dependents, dependencies)
+  VALUES (:k1, :dependents, :dependencies)
+  ON DUPLICATE KEY UPDATE
+  dependents = dependents + VALUES(dependents),
+  dependencies = dependencies + VALUES(dependencies);
+
+selectModuleCounts: |
+  SELECT dependents, dependencies
+  FROM dts_module_counts
+  WHERE k1 = :k1;
+
+deleteModuleCounts: |
+  DELETE FROM dts_module_counts;
+
+rebuildModuleCounts: |
+  INSERT INTO dts_module_counts (k1, dependents, dependencies)
+  SELECT c.k1, SUM(c.dependents), SUM(c.dependencies)
+  FROM (
+      SELECT k2 AS k1, COUNT(DISTINCT k1) AS dependents, 0 AS dependencies
+      FROM dts_graphdata
+      WHERE graph_item_type = 'depends' AND k1 != k2 AND date_deleted IS NULL
+      GROUP BY k2
+      UNION ALL
+      SELECT k1, 0 AS dependents, COUNT(DISTINCT k2) AS dependencies
+      FROM dts_graphdata
+      WHERE graph_item_type = 'depends' AND k1 != k2 AND date_deleted IS NULL
+      GROUP BY k1
+  ) AS c
+  GROUP BY c.k1;
+
+createSourceStatesTable: |
+  CREATE TABLE IF NOT EXISTS dts_source_states(
+      k1 CHAR(64),
+      state CHAR(64) NOT NULL,
+      PRIMARY KEY (k1)
+  );
+
+selectSourceState: |
+  SELECT state
+  FROM dts_source_states
+  WHERE k1 = :k1;
+
+insertSourceState: |
+  INSERT INTO dts_source_states (k1, state)
+  VALUES (:k1, :state);
+
+updateSourceState: |
+  UPDATE dts_source_states
+  SET state = :state
+  WHERE k1 = :k1
+  AND state = :previous;
 `
 
 // sqlStatements for PostgreSQL
@@ -161,12 +1410,13 @@ createGraphDataTable: |
 
 insertGraphData: |
   INSERT INTO dts_graphdata 
-  (graph_item_type, k1, k2, k3, encoding, graph_item_data, last_modified)
-  VALUES (:graph_item_type, :k1, :k2, :k3, :encoding, :graph_item_data, :last_modified)
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, last_modified, tenant)
+  VALUES (:graph_item_type, :k1, :k2, :k3, :encoding, :graph_item_data, :last_modified, :tenant)
   ON CONFLICT (graph_item_type, k1, k2, k3) 
   DO UPDATE SET graph_item_data = EXCLUDED.graph_item_data, 
                 encoding = EXCLUDED.encoding, 
-                last_modified = EXCLUDED.last_modified
+                last_modified = EXCLUDED.last_modified,
+                date_deleted = NULL
 
 deleteGraphData: |
   UPDATE dts_graphdata
@@ -174,9 +1424,20 @@ deleteGraphData: |
   WHERE (graph_item_type = :graph_item_type and k1 = :k1 and k2 = :k2 and k3 = :k3);
 
 listGraphData: |
-  SELECT graph_item_type, k1, k2, encoding, graph_item_data
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data
   FROM dts_graphdata
-  WHERE graph_item_type = :graph_item_type 
+  WHERE graph_item_type = :graph_item_type
+  AND tenant = :tenant
+  AND date_deleted IS NULL
+  AND graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (CAST(:labels_pattern AS TEXT) = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = dts_graphdata.graph_item_type AND l.k1 = dts_graphdata.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ))
+  ORDER BY k1, k2, k3
   LIMIT :limit OFFSET :offset;
 
 selectGraphDataUpstreamDependencies: |
@@ -190,7 +1451,15 @@ selectGraphDataUpstreamDependencies: |
   AND g2.date_deleted IS NULL
   AND g1.graph_item_type IN (:node_types)
   AND g1.k1 = g1.k2 
-  AND g1.date_deleted IS NULL;
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (CAST(:labels_pattern AS TEXT) = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
 
 selectGraphDataDownstreamDependencies: |
   SELECT g1.graph_item_type, g1.k1, g1.k2, g1.encoding, g1.graph_item_data,
@@ -203,7 +1472,583 @@ selectGraphDataDownstreamDependencies: |
   AND g2.date_deleted IS NULL
   AND g1.graph_item_type IN (:node_types)
   AND g1.k1 = g1.k2 
-  AND g1.date_deleted IS NULL;
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (CAST(:labels_pattern AS TEXT) = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+createGraphDataHistoryTable: |
+  CREATE TABLE IF NOT EXISTS dts_graphdata_history(
+      graph_item_type VARCHAR(55),
+      k1 CHAR(64),
+      k2 CHAR(64),
+      k3 VARCHAR(64),
+      encoding SMALLINT,
+      graph_item_data TEXT,
+      change_type VARCHAR(8),
+      changed_at BIGINT
+  );
+  CREATE INDEX IF NOT EXISTS history_k1 ON dts_graphdata_history(k1, changed_at);
+  CREATE INDEX IF NOT EXISTS history_k2 ON dts_graphdata_history(k2, changed_at);
+  CREATE INDEX IF NOT EXISTS history_k3 ON dts_graphdata_history(k3, changed_at);
+
+insertGraphDataPutHistory: |
+  INSERT INTO dts_graphdata_history
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, change_type, changed_at, tenant)
+  SELECT :graph_item_type, :k1, :k2, :k3, CAST(:encoding AS SMALLINT), :graph_item_data, 'put', CAST(:changed_at AS BIGINT), :tenant
+  WHERE NOT EXISTS (
+      SELECT 1 FROM dts_graphdata
+      WHERE graph_item_type = :graph_item_type AND k1 = :k1 AND k2 = :k2 AND k3 = :k3
+      AND graph_item_data = :graph_item_data AND date_deleted IS NULL
+  );
+
+insertGraphDataDeleteHistory: |
+  INSERT INTO dts_graphdata_history
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, change_type, changed_at, tenant)
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data, 'delete', CAST(:changed_at AS BIGINT), tenant
+  FROM dts_graphdata
+  WHERE graph_item_type = :graph_item_type AND k1 = :k1 AND k2 = :k2 AND k3 = :k3
+  AND date_deleted IS NULL;
+
+selectGraphDataHistory: |
+  SELECT h.graph_item_type, h.k1, h.k2, h.k3, h.encoding, h.graph_item_data, h.change_type, h.changed_at,
+          n1.graph_item_type, n1.encoding, n1.graph_item_data,
+          n2.graph_item_type, n2.encoding, n2.graph_item_data
+  FROM dts_graphdata_history AS h
+  LEFT JOIN dts_graphdata AS n1 ON n1.k1 = h.k1 AND n1.k2 = h.k1
+  LEFT JOIN dts_graphdata AS n2 ON n2.k1 = h.k2 AND n2.k2 = h.k2
+  WHERE (h.k1 IN (:keys) OR h.k2 IN (:keys) OR h.k3 IN (:keys))
+  AND h.k1 != h.k2
+  AND h.changed_at <= :until
+  ORDER BY h.changed_at;
+
+backfillGraphDataHistory: |
+  INSERT INTO dts_graphdata_history
+  (graph_item_type, k1, k2, k3, encoding, graph_item_data, change_type, changed_at)
+  SELECT g.graph_item_type, g.k1, g.k2, g.k3, g.encoding, g.graph_item_data, 'put', 0
+  FROM dts_graphdata AS g
+  WHERE g.date_deleted IS NULL
+  AND NOT EXISTS (
+      SELECT 1 FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g.graph_item_type AND h.k1 = g.k1 AND h.k2 = g.k2 AND h.k3 = g.k3
+  );
+
+listGraphDataAsOf: |
+  SELECT g.graph_item_type, g.k1, g.k2, g.k3, g.encoding, g.graph_item_data
+  FROM dts_graphdata_history AS g
+  WHERE g.graph_item_type = :graph_item_type
+  AND g.tenant = :tenant
+  AND g.change_type = 'put'
+  AND g.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g.graph_item_type
+      AND h.k1 = g.k1 AND h.k2 = g.k2 AND h.k3 = g.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (CAST(:labels_pattern AS TEXT) = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g.graph_item_type AND l.k1 = g.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ))
+  ORDER BY g.k1, g.k2, g.k3
+  LIMIT :limit OFFSET :offset;
+
+selectGraphDataUpstreamAsOf: |
+  SELECT g1.graph_item_type, g1.k1, g1.k2, g1.encoding, g1.graph_item_data,
+          g2.graph_item_type, g2.k1, g2.k2, g2.k3, g2.encoding, g2.graph_item_data
+  FROM dts_graphdata_history AS g1
+  INNER JOIN dts_graphdata_history AS g2 ON g1.k1 = g2.k2
+  WHERE g2.k1 IN (:keys)
+  AND g2.graph_item_type IN (:edge_types)
+  AND g2.k1 != g2.k2
+  AND g2.change_type = 'put'
+  AND g2.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g2.graph_item_type
+      AND h.k1 = g2.k1 AND h.k2 = g2.k2 AND h.k3 = g2.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2
+  AND g1.change_type = 'put'
+  AND g1.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g1.graph_item_type
+      AND h.k1 = g1.k1 AND h.k2 = g1.k2 AND h.k3 = g1.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (CAST(:labels_pattern AS TEXT) = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+selectGraphDataDownstreamAsOf: |
+  SELECT g1.graph_item_type, g1.k1, g1.k2, g1.encoding, g1.graph_item_data,
+          g2.graph_item_type, g2.k1, g2.k2, g2.k3, g2.encoding, g2.graph_item_data
+  FROM dts_graphdata_history AS g1
+  INNER JOIN dts_graphdata_history AS g2 ON g1.k2 = g2.k1
+  WHERE g2.k2 IN (:keys)
+  AND g2.graph_item_type IN (:edge_types)
+  AND g2.k1 != g2.k2
+  AND g2.change_type = 'put'
+  AND g2.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g2.graph_item_type
+      AND h.k1 = g2.k1 AND h.k2 = g2.k2 AND h.k3 = g2.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2
+  AND g1.change_type = 'put'
+  AND g1.changed_at = (
+      SELECT MAX(h.changed_at) FROM dts_graphdata_history AS h
+      WHERE h.graph_item_type = g1.graph_item_type
+      AND h.k1 = g1.k1 AND h.k2 = g1.k2 AND h.k3 = g1.k3
+      AND h.changed_at <= :as_of
+  )
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (CAST(:labels_pattern AS TEXT) = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+listTombstones: |
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data, date_deleted
+  FROM dts_graphdata
+  WHERE graph_item_type = :graph_item_type
+  AND tenant = :tenant
+  AND date_deleted IS NOT NULL
+  ORDER BY date_deleted DESC, k1, k2, k3
+  LIMIT :limit OFFSET :offset;
+
+purgeTombstones: |
+  DELETE FROM dts_graphdata
+  WHERE tenant = :tenant
+  AND date_deleted IS NOT NULL
+  AND date_deleted <= :before;
+
+purgeAllTombstones: |
+  DELETE FROM dts_graphdata
+  WHERE date_deleted IS NOT NULL
+  AND date_deleted <= :before;
+
+selectStaleGraphData: |
+  SELECT graph_item_type, k1, k2, k3
+  FROM dts_graphdata
+  WHERE graph_item_type IN (:graph_item_types)
+  AND date_deleted IS NULL
+  AND last_modified < :before;
+
+countTombstones: |
+  SELECT COUNT(*)
+  FROM dts_graphdata
+  WHERE date_deleted IS NOT NULL
+  AND date_deleted <= :before;
+
+countSupersededHistory: |
+  SELECT COUNT(*)
+  FROM dts_graphdata_history AS h
+  WHERE h.changed_at < :before
+  AND EXISTS (
+      SELECT 1 FROM dts_graphdata_history AS newer
+      WHERE newer.graph_item_type = h.graph_item_type
+      AND newer.k1 = h.k1 AND newer.k2 = h.k2 AND newer.k3 = h.k3
+      AND newer.changed_at > h.changed_at
+      AND newer.changed_at <= :before
+  );
+
+purgeSupersededHistory: |
+  DELETE FROM dts_graphdata_history
+  WHERE changed_at < :before
+  AND EXISTS (
+      SELECT 1 FROM dts_graphdata_history AS newer
+      WHERE newer.graph_item_type = dts_graphdata_history.graph_item_type
+      AND newer.k1 = dts_graphdata_history.k1 AND newer.k2 = dts_graphdata_history.k2 AND newer.k3 = dts_graphdata_history.k3
+      AND newer.changed_at > dts_graphdata_history.changed_at
+      AND newer.changed_at <= :before
+  );
+
+createLabelsTable: |
+  CREATE TABLE IF NOT EXISTS dts_labels(
+      graph_item_type VARCHAR(55),
+      k1 CHAR(64),
+      labels TEXT,
+      last_modified TIMESTAMP,
+      PRIMARY KEY (graph_item_type, k1)
+  );
+
+upsertLabels: |
+  INSERT INTO dts_labels (graph_item_type, k1, labels, last_modified)
+  VALUES (:graph_item_type, :k1, :labels, :last_modified)
+  ON CONFLICT (graph_item_type, k1)
+  DO UPDATE SET labels = EXCLUDED.labels,
+                last_modified = EXCLUDED.last_modified;
+
+deleteLabels: |
+  DELETE FROM dts_labels
+  WHERE graph_item_type = :graph_item_type AND k1 = :k1;
+
+selectLabels: |
+  SELECT k1, labels
+  FROM dts_labels
+  WHERE graph_item_type = :graph_item_type
+  AND k1 IN (:keys);
+
+searchGraphData: |
+  SELECT g.graph_item_type, g.k1, g.k2, g.k3, g.encoding, g.graph_item_data
+  FROM dts_graphdata AS g
+  WHERE g.graph_item_type = :graph_item_type
+  AND g.tenant = :tenant
+  AND g.k1 = g.k2
+  AND g.date_deleted IS NULL
+  AND (g.graph_item_data ILIKE :pattern ESCAPE '!' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g.graph_item_type AND l.k1 = g.k1
+      AND l.labels ILIKE :pattern ESCAPE '!'
+  ))
+  ORDER BY COALESCE((
+      SELECT c.dependents FROM dts_module_counts AS c
+      WHERE c.k1 = g.k1
+  ), 0) DESC, g.last_modified DESC, g.k1
+  LIMIT :limit;
+
+selectPopularity: |
+  SELECT g.k1, g.last_modified, COALESCE((
+      SELECT c.dependents FROM dts_module_counts AS c
+      WHERE c.k1 = g.k1
+  ), 0) AS dependents
+  FROM dts_graphdata AS g
+  WHERE g.graph_item_type = :graph_item_type
+  AND g.k1 IN (:keys)
+  AND g.k1 = g.k2
+  AND g.date_deleted IS NULL;
+
+countGraphDataUpstream: |
+  SELECT COUNT(DISTINCT g1.k1)
+  FROM dts_graphdata AS g1
+  INNER JOIN dts_graphdata AS g2 ON g1.k1 = g2.k2
+  WHERE g2.k1 IN (:keys) 
+  AND g2.graph_item_type IN (:edge_types) 
+  AND g2.k1 != g2.k2 
+  AND g2.date_deleted IS NULL
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2 
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (CAST(:labels_pattern AS TEXT) = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+countGraphDataDownstream: |
+  SELECT COUNT(DISTINCT g1.k1)
+  FROM dts_graphdata AS g1
+  INNER JOIN dts_graphdata AS g2 ON g1.k2 = g2.k1
+  WHERE g2.k2 IN (:keys) 
+  AND g2.graph_item_type IN (:edge_types) 
+  AND g2.k1 != g2.k2 
+  AND g2.date_deleted IS NULL
+  AND g1.graph_item_type IN (:node_types)
+  AND g1.k1 = g1.k2 
+  AND g1.date_deleted IS NULL
+  AND g1.graph_item_data LIKE :language_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :organization_pattern ESCAPE '!'
+  AND g1.graph_item_data LIKE :name_pattern ESCAPE '!'
+  AND (CAST(:labels_pattern AS TEXT) = '%' OR EXISTS (
+      SELECT 1 FROM dts_labels AS l
+      WHERE l.graph_item_type = g1.graph_item_type AND l.k1 = g1.k1
+      AND l.labels LIKE :labels_pattern ESCAPE '!'
+  ));
+
+selectSnapshotGraphData: |
+  SELECT graph_item_type, k1, k2, k3, encoding, graph_item_data
+  FROM dts_graphdata
+  WHERE tenant = :tenant
+  AND date_deleted IS NULL
+  ORDER BY graph_item_type, k1, k2, k3;
+
+selectSnapshotLabels: |
+  SELECT l.graph_item_type, l.k1, l.labels
+  FROM dts_labels AS l
+  WHERE EXISTS (
+      SELECT 1 FROM dts_graphdata AS g
+      WHERE g.graph_item_type = l.graph_item_type AND g.k1 = l.k1
+      AND g.tenant = :tenant
+      AND g.date_deleted IS NULL
+  )
+  ORDER BY l.graph_item_type, l.k1;
+
+countGraphDataByLanguage: |
+  SELECT graph_item_type, language, COUNT(*)
+  FROM (
+      SELECT graph_item_type,
+      COALESCE(SUBSTRING(graph_item_data FROM '"language":"([^"]*)"'), '') AS language
+      FROM dts_graphdata
+      WHERE date_deleted IS NULL
+  ) AS items
+  GROUP BY graph_item_type, language;
+
+createIdempotencyKeysTable: |
+  CREATE TABLE IF NOT EXISTS dts_idempotency_keys(
+      tenant VARCHAR(64),
+      idempotency_key VARCHAR(128),
+      applied_at BIGINT,
+      PRIMARY KEY (tenant, idempotency_key)
+  );
+
+selectIdempotencyKey: |
+  SELECT COUNT(*)
+  FROM dts_idempotency_keys
+  WHERE tenant = :tenant
+  AND idempotency_key = :idempotency_key;
+
+insertIdempotencyKey: |
+  INSERT INTO dts_idempotency_keys (tenant, idempotency_key, applied_at)
+  VALUES (:tenant, :idempotency_key, :applied_at);
+
+purgeIdempotencyKeys: |
+  DELETE FROM dts_idempotency_keys
+  WHERE applied_at < :before;
+
+createAdvisoriesTable: |
+  CREATE TABLE IF NOT EXISTS dts_advisories(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      k1 CHAR(64),
+      version VARCHAR(255),
+      advisory_id VARCHAR(128),
+      aliases TEXT,
+      summary TEXT,
+      severity VARCHAR(64),
+      modified BIGINT,
+      PRIMARY KEY (k1, version, advisory_id)
+  );
+  CREATE INDEX IF NOT EXISTS advisory_id ON dts_advisories(tenant, advisory_id);
+
+deleteAdvisories: |
+  DELETE FROM dts_advisories
+  WHERE k1 = :k1 AND version = :version AND source = :source;
+
+insertAdvisory: |
+  INSERT INTO dts_advisories (tenant, source, k1, version, advisory_id, aliases, summary, severity, modified)
+  VALUES (:tenant, :source, :k1, :version, :advisory_id, :aliases, :summary, :severity, :modified)
+  ON CONFLICT DO NOTHING;
+
+selectAdvisories: |
+  SELECT k1, version, advisory_id, aliases, summary, severity, modified
+  FROM dts_advisories
+  WHERE k1 IN (:keys)
+  ORDER BY k1, version, advisory_id;
+
+selectAffected: |
+  SELECT k1, version, advisory_id, aliases, summary, severity, modified
+  FROM dts_advisories
+  WHERE tenant = :tenant
+  AND (advisory_id = :advisory_id OR aliases LIKE :pattern ESCAPE '!')
+  ORDER BY k1, version;
+
+createRoleBindingsTable: |
+  CREATE TABLE IF NOT EXISTS dts_role_bindings(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      subject VARCHAR(255),
+      organization VARCHAR(255) NOT NULL DEFAULT '',
+      role VARCHAR(16),
+      PRIMARY KEY (tenant, subject, organization)
+  );
+
+upsertRoleBinding: |
+  INSERT INTO dts_role_bindings (tenant, subject, organization, role)
+  VALUES (:tenant, :subject, :organization, :role)
+  ON CONFLICT (tenant, subject, organization)
+  DO UPDATE SET role = EXCLUDED.role;
+
+deleteRoleBinding: |
+  DELETE FROM dts_role_bindings
+  WHERE tenant = :tenant AND subject = :subject AND organization = :organization;
+
+selectRoleBindings: |
+  SELECT subject, organization, role
+  FROM dts_role_bindings
+  WHERE tenant = :tenant
+  ORDER BY subject, organization;
+
+createAuditLogTable: |
+  CREATE TABLE IF NOT EXISTS dts_audit_log(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      recorded_at BIGINT,
+      service VARCHAR(64),
+      subject VARCHAR(255),
+      action VARCHAR(255),
+      resource TEXT,
+      status INTEGER,
+      error TEXT
+  );
+  CREATE INDEX IF NOT EXISTS audit_recorded_at ON dts_audit_log(tenant, recorded_at);
+
+insertAuditRecord: |
+  INSERT INTO dts_audit_log (tenant, recorded_at, service, subject, action, resource, status, error)
+  VALUES (:tenant, :recorded_at, :service, :subject, :action, :resource, :status, :error);
+
+selectAuditRecords: |
+  SELECT tenant, recorded_at, service, subject, action, resource, status, error
+  FROM dts_audit_log
+  WHERE tenant = :tenant
+  AND recorded_at >= :since AND recorded_at < :until
+  AND (:service = '' OR service = :service)
+  AND (:subject = '' OR subject = :subject)
+  AND (:action = '' OR action = :action)
+  ORDER BY recorded_at DESC
+  LIMIT :limit;
+
+createServiceAccountsTable: |
+  CREATE TABLE IF NOT EXISTS dts_service_accounts(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      name VARCHAR(64),
+      description TEXT,
+      created_at BIGINT,
+      PRIMARY KEY (tenant, name)
+  );
+
+upsertServiceAccount: |
+  INSERT INTO dts_service_accounts (tenant, name, description, created_at)
+  VALUES (:tenant, :name, :description, :created_at)
+  ON CONFLICT (tenant, name)
+  DO UPDATE SET description = EXCLUDED.description;
+
+deleteServiceAccount: |
+  DELETE FROM dts_service_accounts
+  WHERE tenant = :tenant AND name = :name;
+
+selectServiceAccounts: |
+  SELECT name, description, created_at
+  FROM dts_service_accounts
+  WHERE tenant = :tenant
+  ORDER BY name;
+
+createTokensTable: |
+  CREATE TABLE IF NOT EXISTS dts_tokens(
+      tenant VARCHAR(64) NOT NULL DEFAULT '',
+      id VARCHAR(32),
+      account VARCHAR(64),
+      token_hash CHAR(64),
+      role VARCHAR(16),
+      organization VARCHAR(255) NOT NULL DEFAULT '',
+      created_at BIGINT,
+      expires_at BIGINT,
+      revoked_at BIGINT NOT NULL DEFAULT 0,
+      PRIMARY KEY (tenant, id)
+  );
+  CREATE INDEX IF NOT EXISTS tokens_account ON dts_tokens(tenant, account);
+
+insertToken: |
+  INSERT INTO dts_tokens (tenant, id, account, token_hash, role, organization, created_at, expires_at)
+  VALUES (:tenant, :id, :account, :token_hash, :role, :organization, :created_at, :expires_at);
+
+selectTokens: |
+  SELECT id, account, token_hash, role, organization, created_at, expires_at, revoked_at
+  FROM dts_tokens
+  WHERE tenant = :tenant AND account = :account
+  ORDER BY created_at;
+
+selectToken: |
+  SELECT id, account, token_hash, role, organization, created_at, expires_at, revoked_at
+  FROM dts_tokens
+  WHERE tenant = :tenant AND id = :id;
+
+revokeToken: |
+  UPDATE dts_tokens
+  SET revoked_at = :revoked_at
+  WHERE tenant = :tenant AND id = :id AND revoked_at = 0;
+
+deleteTokens: |
+  DELETE FROM dts_tokens
+  WHERE tenant = :tenant AND account = :account;
+
+createModuleCountsTable: |
+  CREATE TABLE IF NOT EXISTS dts_module_counts(
+      k1 CHAR(64),
+      dependents BIGINT NOT NULL DEFAULT 0,
+      dependencies BIGINT NOT NULL DEFAULT 0,
+      PRIMARY KEY (k1)
+  );
+
+countActiveEdges: |
+  SELECT COUNT(*)
+  FROM dts_graphdata
+  WHERE graph_item_type = :graph_item_type
+  AND k1 = :k1
+  AND k2 = :k2
+  AND date_deleted IS NULL;
+
+upsertModuleCounts: |
+  INSERT INTO dts_module_counts (k1, dependents, dependencies)
+  VALUES (:k1, :dependents, :dependencies)
+  ON CONFLICT (k1) DO UPDATE SET
+  dependents = dts_module_counts.dependents + EXCLUDED.dependents,
+  dependencies = dts_module_counts.dependencies + EXCLUDED.dependencies;
+
+selectModuleCounts: |
+  SELECT dependents, dependencies
+  FROM dts_module_counts
+  WHERE k1 = :k1;
+
+deleteModuleCounts: |
+  DELETE FROM dts_module_counts;
+
+rebuildModuleCounts: |
+  INSERT INTO dts_module_counts (k1, dependents, dependencies)
+  SELECT c.k1, SUM(c.dependents), SUM(c.dependencies)
+  FROM (
+      SELECT k2 AS k1, COUNT(DISTINCT k1) AS dependents, 0 AS dependencies
+      FROM dts_graphdata
+      WHERE graph_item_type = 'depends' AND k1 != k2 AND date_deleted IS NULL
+      GROUP BY k2
+      UNION ALL
+      SELECT k1, 0 AS dependents, COUNT(DISTINCT k2) AS dependencies
+      FROM dts_graphdata
+      WHERE graph_item_type = 'depends' AND k1 != k2 AND date_deleted IS NULL
+      GROUP BY k1
+  ) AS c
+  GROUP BY c.k1;
+
+createSourceStatesTable: |
+  CREATE TABLE IF NOT EXISTS dts_source_states(
+      k1 CHAR(64),
+      state CHAR(64) NOT NULL,
+      PRIMARY KEY (k1)
+  );
+
+selectSourceState: |
+  SELECT state
+  FROM dts_source_states
+  WHERE k1 = :k1;
+
+insertSourceState: |
+  INSERT INTO dts_source_states (k1, state)
+  VALUES (:k1, :state);
+
+updateSourceState: |
+  UPDATE dts_source_states
+  SET state = :state
+  WHERE k1 = :k1
+  AND state = :previous;
 `
 
 // LoadStatementsFile loads an external yaml file containing SQL statements
@@ -0,0 +1,119 @@
+package v1alpha
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/tenants"
+)
+
+// Tenants share the tables, but not their keys. Each key is scoped to its
+// tenant by prefixing it with a tag derived from the tenant's name, so the
+// same module tracked by two tenants is stored as two items and lookups by key
+// never cross tenants. Statements that scan a type rather than look up keys
+// filter on the tenant column instead. The default tenant has no tag so data
+// written before tenants were introduced is unaffected.
+
+// tenantTagLength keeps scoped keys within the key columns.
+const tenantTagLength = 8
+
+type tenantScope struct {
+	name string
+	tag  []byte
+}
+
+// scopeFor returns the scope of the tenant making the request.
+func scopeFor(ctx context.Context) *tenantScope {
+	name := tenants.FromContext(ctx)
+	if name == "" {
+		return &tenantScope{}
+	}
+
+	tag := sha256.Sum256([]byte("tenant:" + name))
+	return &tenantScope{name: name, tag: tag[:tenantTagLength]}
+}
+
+// key returns the stored key for a key used by the tenant.
+func (s *tenantScope) key(key []byte) []byte {
+	if len(s.tag) == 0 || len(key) == 0 {
+		return key
+	}
+
+	return append(append(make([]byte, 0, len(s.tag)+len(key)), s.tag...), key...)
+}
+
+// unscopedKey returns the key used by the tenant for a stored key.
+func (s *tenantScope) unscopedKey(key []byte) []byte {
+	if len(s.tag) == 0 || !bytes.HasPrefix(key, s.tag) {
+		return key
+	}
+
+	return key[len(s.tag):]
+}
+
+func (s *tenantScope) keys(keys [][]byte) [][]byte {
+	scoped := make([][]byte, len(keys))
+	for i, key := range keys {
+		scoped[i] = s.key(key)
+	}
+	return scoped
+}
+
+func (s *tenantScope) item(item *store.GraphItem, key func([]byte) []byte) *store.GraphItem {
+	if item == nil || len(s.tag) == 0 {
+		return item
+	}
+
+	return &store.GraphItem{
+		GraphItemType: item.GetGraphItemType(),
+		K1:            key(item.GetK1()),
+		K2:            key(item.GetK2()),
+		K3:            key(item.GetK3()),
+		Encoding:      item.GetEncoding(),
+		GraphItemData: item.GetGraphItemData(),
+	}
+}
+
+// scopedItems returns the items the tenant provided with their stored keys.
+func (s *tenantScope) scopedItems(items []*store.GraphItem) []*store.GraphItem {
+	if len(s.tag) == 0 {
+		return items
+	}
+
+	scoped := make([]*store.GraphItem, len(items))
+	for i, item := range items {
+		scoped[i] = s.item(item, s.key)
+	}
+	return scoped
+}
+
+// unscopedItem returns a stored item with the keys used by the tenant.
+func (s *tenantScope) unscopedItem(item *store.GraphItem) *store.GraphItem {
+	return s.item(item, s.unscopedKey)
+}
+
+func (s *tenantScope) unscopedItems(items []*store.GraphItem) []*store.GraphItem {
+	if len(s.tag) == 0 {
+		return items
+	}
+
+	unscoped := make([]*store.GraphItem, len(items))
+	for i, item := range items {
+		unscoped[i] = s.unscopedItem(item)
+	}
+	return unscoped
+}
+
+func (s *tenantScope) unscopedPairs(pairs []*store.GraphItemPair) []*store.GraphItemPair {
+	if len(s.tag) == 0 {
+		return pairs
+	}
+
+	for _, pair := range pairs {
+		pair.Node = s.unscopedItem(pair.GetNode())
+		pair.Edge = s.unscopedItem(pair.GetEdge())
+	}
+	return pairs
+}
@@ -0,0 +1,174 @@
+package v1alpha
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/store"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Tombstone is an item that was deleted from the graph but is kept until it's
+// purged.
+type Tombstone struct {
+	Item        *store.GraphItem
+	DateDeleted time.Time
+}
+
+// Tombstones provides access to the items deleted from the graph. Deletes
+// only mark items as deleted so they can be inspected, and restored by
+// putting them again, until they're purged.
+type Tombstones interface {
+	// ListTombstones returns a page of deleted items of the given type, most
+	// recently deleted first.
+	ListTombstones(ctx context.Context, graphItemType string, page, count int32) ([]*Tombstone, error)
+
+	// PurgeTombstones removes the items deleted at or before the provided
+	// time and returns the number of items removed.
+	PurgeTombstones(ctx context.Context, before time.Time) (int64, error)
+}
+
+func (gs *graphStore) ListTombstones(ctx context.Context, graphItemType string, page, count int32) ([]*Tombstone, error) {
+	if gs.statements.ListTombstones == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	page = max(page, 1)
+	if count <= 0 {
+		count = 10
+	}
+
+	scope := scopeFor(ctx)
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := gs.rodb().NamedQueryContext(ctx, gs.statements.ListTombstones, map[string]interface{}{
+		"graph_item_type": graphItemType,
+		"tenant":          scope.name,
+		"limit":           count,
+		"offset":          (page - 1) * count,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tombstones, err := readTombstones(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tombstone := range tombstones {
+		tombstone.Item = scope.unscopedItem(tombstone.Item)
+	}
+
+	return tombstones, nil
+}
+
+func (gs *graphStore) PurgeTombstones(ctx context.Context, before time.Time) (int64, error) {
+	if gs.rwdb == nil || gs.statements.PurgeTombstones == "" {
+		return 0, api.ErrUnsupported
+	}
+
+	tenant := scopeFor(ctx).name
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	// deletes are recorded in local time, compare in the same zone
+	result, err := gs.rwdb.NamedExecContext(ctx, gs.statements.PurgeTombstones, map[string]interface{}{
+		"before": before.Local(),
+		"tenant": tenant,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func readTombstones(rows *sqlx.Rows) ([]*Tombstone, error) {
+	defer rows.Close()
+
+	results := make([]*Tombstone, 0)
+
+	for rows.Next() {
+		var (
+			t           string
+			k1          string
+			k2          string
+			k3          string
+			enc         store.GraphItemEncoding
+			data        string
+			dateDeleted scannedTime
+		)
+
+		if err := rows.Scan(&t, &k1, &k2, &k3, &enc, &data, &dateDeleted); err != nil {
+			return nil, err
+		}
+
+		k1Bytes, _ := Base64decode(k1)
+		k2Bytes, _ := Base64decode(k2)
+		k3Bytes, _ := Base64decode(k3)
+
+		results = append(results, &Tombstone{
+			Item: &store.GraphItem{
+				GraphItemType: t,
+				K1:            k1Bytes,
+				K2:            k2Bytes,
+				K3:            k3Bytes,
+				Encoding:      enc,
+				GraphItemData: []byte(data),
+			},
+			DateDeleted: dateDeleted.Time,
+		})
+	}
+
+	return results, nil
+}
+
+// timeLayouts are the formats drivers return timestamps in when they aren't
+// parsed into a time.Time, such as mysql without parseTime.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+}
+
+// scannedTime reads a timestamp regardless of how the driver returns it.
+type scannedTime struct {
+	time.Time
+}
+
+func (s *scannedTime) Scan(value interface{}) error {
+	var raw string
+
+	switch v := value.(type) {
+	case nil:
+		s.Time = time.Time{}
+		return nil
+	case time.Time:
+		s.Time = v
+		return nil
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	default:
+		return fmt.Errorf("unsupported timestamp type %T", value)
+	}
+
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, raw); err == nil {
+			s.Time = parsed
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unrecognized timestamp %q", raw)
+}
+
+var _ Tombstones = &graphStore{}
@@ -0,0 +1,210 @@
+package v1alpha
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Advisory is a published vulnerability, such as an OSV entry.
+type Advisory struct {
+	ID       string    `json:"id"`
+	Aliases  []string  `json:"aliases,omitempty"`
+	Summary  string    `json:"summary,omitempty"`
+	Severity string    `json:"severity,omitempty"`
+	Modified time.Time `json:"modified"`
+}
+
+// AdvisorySourceOSV is the source of the advisories recorded by the OSV scan.
+const AdvisorySourceOSV = "osv"
+
+// Affected is a version of a module an advisory applies to.
+type Affected struct {
+	Key      []byte
+	Version  string
+	Advisory *Advisory
+}
+
+// Vulnerabilities records the advisories affecting the versions of modules
+// that are depended on, so the graph can answer which modules are exposed to
+// a given advisory. Advisories are recorded by source (such as OSV or GHSA) so
+// each source can be synced without disturbing the others. An advisory that's
+// published by more than one source is recorded once.
+type Vulnerabilities interface {
+	// SetAdvisories replaces the advisories the source reports for the
+	// version of the module. Providing no advisories marks the version as
+	// unaffected by the source.
+	SetAdvisories(ctx context.Context, source string, key []byte, version string, advisories []*Advisory) error
+
+	// GetAdvisories returns the advisories affecting any version of the
+	// modules.
+	GetAdvisories(ctx context.Context, keys [][]byte) ([]*Affected, error)
+
+	// FindAffected returns the module versions affected by the advisory,
+	// matching either its id or one of its aliases.
+	FindAffected(ctx context.Context, id string) ([]*Affected, error)
+}
+
+// encodeAliases wraps each alias in separators so a single alias can be
+// matched using LIKE without matching part of another.
+func encodeAliases(aliases []string) string {
+	if len(aliases) == 0 {
+		return ""
+	}
+	return "|" + strings.Join(aliases, "|") + "|"
+}
+
+func decodeAliases(encoded string) []string {
+	encoded = strings.Trim(encoded, "|")
+	if encoded == "" {
+		return nil
+	}
+	return strings.Split(encoded, "|")
+}
+
+type advisoryRow struct {
+	K1         string `db:"k1"`
+	Version    string `db:"version"`
+	AdvisoryID string `db:"advisory_id"`
+	Aliases    string `db:"aliases"`
+	Summary    string `db:"summary"`
+	Severity   string `db:"severity"`
+	Modified   int64  `db:"modified"`
+}
+
+func (gs *graphStore) SetAdvisories(ctx context.Context, source string, key []byte, version string, advisories []*Advisory) error {
+	if gs.rwdb == nil || gs.statements.InsertAdvisory == "" {
+		return api.ErrUnsupported
+	}
+
+	scope := scopeFor(ctx)
+	k1 := Base64encode(scope.key(key))
+
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	return gs.inTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExecContext(ctx, gs.statements.DeleteAdvisories, map[string]interface{}{
+			"source":  source,
+			"k1":      k1,
+			"version": version,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, advisory := range advisories {
+			_, err = tx.NamedExecContext(ctx, gs.statements.InsertAdvisory, map[string]interface{}{
+				"tenant":      scope.name,
+				"source":      source,
+				"k1":          k1,
+				"version":     version,
+				"advisory_id": advisory.ID,
+				"aliases":     encodeAliases(advisory.Aliases),
+				"summary":     advisory.Summary,
+				"severity":    advisory.Severity,
+				"modified":    advisory.Modified.Unix(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (gs *graphStore) GetAdvisories(ctx context.Context, keys [][]byte) ([]*Affected, error) {
+	if gs.statements.SelectAdvisories == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	if len(keys) == 0 {
+		return []*Affected{}, nil
+	}
+
+	scope := scopeFor(ctx)
+
+	encodedKeys := make([]string, len(keys))
+	for i, key := range scope.keys(keys) {
+		encodedKeys[i] = Base64encode(key)
+	}
+
+	query, args, err := sqlx.Named(gs.statements.SelectAdvisories, map[string]interface{}{
+		"keys": encodedKeys,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return gs.selectAffected(ctx, scope, query, args)
+}
+
+func (gs *graphStore) FindAffected(ctx context.Context, id string) ([]*Affected, error) {
+	if gs.statements.SelectAffected == "" {
+		return nil, api.ErrUnsupported
+	}
+
+	scope := scopeFor(ctx)
+
+	query, args, err := sqlx.Named(gs.statements.SelectAffected, map[string]interface{}{
+		"tenant":      scope.name,
+		"advisory_id": id,
+		"pattern":     "%" + encodeAliases([]string{likeEscaper.Replace(id)}) + "%",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return gs.selectAffected(ctx, scope, query, args)
+}
+
+func (gs *graphStore) selectAffected(ctx context.Context, scope *tenantScope, query string, args []interface{}) ([]*Affected, error) {
+	ctx, cancel := gs.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rodb := gs.rodb()
+
+	rows, err := rodb.QueryxContext(ctx, rodb.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*Affected, 0)
+	for rows.Next() {
+		row := &advisoryRow{}
+		if err := rows.StructScan(row); err != nil {
+			return nil, err
+		}
+
+		key, err := Base64decode(row.K1)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, &Affected{
+			Key:     scope.unscopedKey(key),
+			Version: row.Version,
+			Advisory: &Advisory{
+				ID:       row.AdvisoryID,
+				Aliases:  decodeAliases(row.Aliases),
+				Summary:  row.Summary,
+				Severity: row.Severity,
+				Modified: time.Unix(row.Modified, 0).UTC(),
+			},
+		})
+	}
+
+	return results, rows.Err()
+}
+
+var _ Vulnerabilities = &graphStore{}
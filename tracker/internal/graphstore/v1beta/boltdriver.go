@@ -0,0 +1,346 @@
+package v1beta
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/depscloud/api"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const boltFileName = "graphstore.db"
+
+var (
+	// items are keyed by (k1, k2, k3) so a node and the edges leaving it are
+	// stored next to one another
+	boltItemsBucket = []byte("items")
+	// inbound indexes edges by (k2, k1, k3)
+	boltInboundBucket = []byte("inbound")
+	// kinds indexes items by (kind, k1, k2, k3)
+	boltKindsBucket = []byte("kinds")
+)
+
+// boltKey length prefixes each part so that keys can be scanned by prefix
+// without one key running into the next.
+func boltKey(parts ...string) []byte {
+	buf := make([]byte, 0, 64)
+	length := make([]byte, binary.MaxVarintLen64)
+	for _, part := range parts {
+		n := binary.PutUvarint(length, uint64(len(part)))
+		buf = append(buf, length[:n]...)
+		buf = append(buf, part...)
+	}
+	return buf
+}
+
+func boltKeyParts(key []byte) ([]string, error) {
+	parts := make([]string, 0, 4)
+	for len(key) > 0 {
+		length, n := binary.Uvarint(key)
+		if n <= 0 || uint64(len(key)-n) < length {
+			return nil, fmt.Errorf("malformed key")
+		}
+
+		key = key[n:]
+		parts = append(parts, string(key[:length]))
+		key = key[length:]
+	}
+	return parts, nil
+}
+
+// NewBoltDriver returns a driver that stores the graph in an embedded bbolt
+// database within the provided data directory. This allows the tracker to run
+// without an external database. Only one process may write to the directory at
+// a time.
+func NewBoltDriver(storageAddress, storageReadOnlyAddress string) (Driver, error) {
+	options := &bolt.Options{Timeout: 5 * time.Second}
+
+	driver := &boltDriver{}
+
+	if len(storageAddress) > 0 {
+		if err := os.MkdirAll(storageAddress, 0755); err != nil {
+			return nil, err
+		}
+
+		db, err := bolt.Open(filepath.Join(storageAddress, boltFileName), 0644, options)
+		if err != nil {
+			return nil, err
+		}
+
+		// only set up the buckets on RW connections
+		err = db.Update(func(tx *bolt.Tx) error {
+			for _, bucket := range [][]byte{boltItemsBucket, boltInboundBucket, boltKindsBucket} {
+				if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		driver.rw = db
+		driver.ro = db
+	} else if len(storageReadOnlyAddress) > 0 {
+		// the file is locked by the writer so a separate read only handle is
+		// only opened when this process isn't the writer
+		ro := *options
+		ro.ReadOnly = true
+
+		db, err := bolt.Open(filepath.Join(storageReadOnlyAddress, boltFileName), 0644, &ro)
+		if err != nil {
+			return nil, err
+		}
+
+		driver.ro = db
+	}
+
+	if driver.rw == nil && driver.ro == nil {
+		return nil, fmt.Errorf("must provide one storage address")
+	}
+
+	return driver, nil
+}
+
+type boltDriver struct {
+	rw *bolt.DB
+	ro *bolt.DB
+}
+
+func isNode(item *GraphData) bool {
+	return item.K1 == item.K2
+}
+
+func (d *boltDriver) Put(ctx context.Context, items []*GraphData) error {
+	if d.rw == nil {
+		return api.ErrUnsupported
+	}
+
+	return d.rw.Update(func(tx *bolt.Tx) error {
+		itemsBucket := tx.Bucket(boltItemsBucket)
+		inboundBucket := tx.Bucket(boltInboundBucket)
+		kindsBucket := tx.Bucket(boltKindsBucket)
+
+		for _, item := range items {
+			key := boltKey(item.K1, item.K2, item.K3)
+
+			// drop the index entry for the previous kind
+			if value := itemsBucket.Get(key); value != nil {
+				existing := &GraphData{}
+				if err := json.Unmarshal(value, existing); err != nil {
+					return err
+				}
+
+				if existing.Kind != item.Kind {
+					if err := kindsBucket.Delete(boltKey(existing.Kind, item.K1, item.K2, item.K3)); err != nil {
+						return err
+					}
+				}
+			}
+
+			stored := *item
+			stored.DateDeleted = nil
+
+			value, err := json.Marshal(&stored)
+			if err != nil {
+				return err
+			}
+
+			if err := itemsBucket.Put(key, value); err != nil {
+				return err
+			}
+
+			if err := kindsBucket.Put(boltKey(item.Kind, item.K1, item.K2, item.K3), []byte{}); err != nil {
+				return err
+			}
+
+			if !isNode(item) {
+				if err := inboundBucket.Put(boltKey(item.K2, item.K1, item.K3), []byte{}); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete marks items as deleted, like the sql drivers do.
+func (d *boltDriver) Delete(ctx context.Context, items []*GraphData) error {
+	if d.rw == nil {
+		return api.ErrUnsupported
+	}
+
+	return d.rw.Update(func(tx *bolt.Tx) error {
+		itemsBucket := tx.Bucket(boltItemsBucket)
+
+		for _, item := range items {
+			key := boltKey(item.K1, item.K2, item.K3)
+
+			value := itemsBucket.Get(key)
+			if value == nil {
+				continue
+			}
+
+			existing := &GraphData{}
+			if err := json.Unmarshal(value, existing); err != nil {
+				return err
+			}
+
+			existing.DateDeleted = item.DateDeleted
+
+			value, err := json.Marshal(existing)
+			if err != nil {
+				return err
+			}
+
+			if err := itemsBucket.Put(key, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// boltGet returns the item stored under the key or nil when it's missing or
+// deleted.
+func boltGet(bucket *bolt.Bucket, key []byte) (*GraphData, error) {
+	value := bucket.Get(key)
+	if value == nil {
+		return nil, nil
+	}
+
+	item := &GraphData{}
+	if err := json.Unmarshal(value, item); err != nil {
+		return nil, err
+	}
+
+	if item.DateDeleted != nil && item.DateDeleted.Valid {
+		return nil, nil
+	}
+	return item, nil
+}
+
+func (d *boltDriver) List(ctx context.Context, kind string, offset, limit int) ([]*GraphData, bool, error) {
+	results := make([]*GraphData, 0, limit)
+	hasNextPage := false
+
+	err := d.ro.View(func(tx *bolt.Tx) error {
+		itemsBucket := tx.Bucket(boltItemsBucket)
+		if itemsBucket == nil {
+			return nil
+		}
+
+		prefix := boltKey(kind)
+		cursor := tx.Bucket(boltKindsBucket).Cursor()
+
+		for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+			item, err := boltGet(itemsBucket, k[len(prefix):])
+			if err != nil {
+				return err
+			} else if item == nil {
+				continue
+			}
+
+			if offset > 0 {
+				offset--
+				continue
+			}
+
+			if len(results) == limit {
+				hasNextPage = true
+				break
+			}
+
+			results = append(results, item)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return results, hasNextPage, nil
+}
+
+// neighbors scans the edges indexed under each key in the bucket. The edge
+// func maps an index entry to the keys of the edge and the neighboring node.
+func (d *boltDriver) neighbors(bucket []byte, keys []string, edge func(parts []string) (edgeKey, nodeKey []byte)) ([]*GraphData, error) {
+	results := make([]*GraphData, 0)
+
+	err := d.ro.View(func(tx *bolt.Tx) error {
+		itemsBucket := tx.Bucket(boltItemsBucket)
+		if itemsBucket == nil {
+			return nil
+		}
+
+		cursor := tx.Bucket(bucket).Cursor()
+
+		for _, key := range keys {
+			prefix := boltKey(key)
+
+			for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+				parts, err := boltKeyParts(k)
+				if err != nil {
+					return err
+				}
+
+				// nodes share the prefix with the edges leaving them
+				if len(parts) != 3 || parts[0] == parts[1] {
+					continue
+				}
+
+				edgeKey, nodeKey := edge(parts)
+
+				edgeItem, err := boltGet(itemsBucket, edgeKey)
+				if err != nil {
+					return err
+				} else if edgeItem == nil {
+					continue
+				}
+
+				nodeItem, err := boltGet(itemsBucket, nodeKey)
+				if err != nil {
+					return err
+				} else if nodeItem == nil {
+					continue
+				}
+
+				results = append(results, nodeItem, edgeItem)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (d *boltDriver) NeighborsTo(ctx context.Context, toKeys []string) ([]*GraphData, error) {
+	// inbound keys are (k2, k1, k3)
+	return d.neighbors(boltInboundBucket, toKeys, func(parts []string) ([]byte, []byte) {
+		return boltKey(parts[1], parts[0], parts[2]), boltKey(parts[1], parts[1], "")
+	})
+}
+
+func (d *boltDriver) NeighborsFrom(ctx context.Context, fromKeys []string) ([]*GraphData, error) {
+	return d.neighbors(boltItemsBucket, fromKeys, func(parts []string) ([]byte, []byte) {
+		return boltKey(parts[0], parts[1], parts[2]), boltKey(parts[1], parts[1], "")
+	})
+}
+
+var _ Driver = &boltDriver{}
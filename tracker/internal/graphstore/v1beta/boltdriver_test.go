@@ -0,0 +1,73 @@
+package v1beta_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/tracker/internal/graphstore/v1beta"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltDriver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltdriver")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	driver, err := v1beta.Resolve("bolt", dir, "")
+	require.Nil(t, err)
+
+	testServer(t, driver)
+}
+
+func TestBoltDriver_delete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltdriver")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	driver, err := v1beta.Resolve("bbolt", dir, "")
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	items := []*v1beta.GraphData{
+		{K1: "a", K2: "a", Kind: "module", LastModified: now},
+		{K1: "b", K2: "b", Kind: "module", LastModified: now},
+		{K1: "a", K2: "b", K3: "src", Kind: "depends", LastModified: now},
+	}
+	require.Nil(t, driver.Put(ctx, items))
+
+	neighbors, err := driver.NeighborsFrom(ctx, []string{"a"})
+	require.Nil(t, err)
+	require.Len(t, neighbors, 2)
+	require.Equal(t, "b", neighbors[0].K1)
+	require.Equal(t, "src", neighbors[1].K3)
+
+	deleted := &sql.NullTime{Time: now, Valid: true}
+	require.Nil(t, driver.Delete(ctx, []*v1beta.GraphData{
+		{K1: "b", K2: "b", DateDeleted: deleted},
+	}))
+
+	neighbors, err = driver.NeighborsFrom(ctx, []string{"a"})
+	require.Nil(t, err)
+	require.Len(t, neighbors, 0)
+
+	listed, hasNext, err := driver.List(ctx, "module", 0, 10)
+	require.Nil(t, err)
+	require.False(t, hasNext)
+	require.Len(t, listed, 1)
+	require.Equal(t, "a", listed[0].K1)
+
+	// writing the item again restores it
+	require.Nil(t, driver.Put(ctx, items[1:2]))
+
+	neighbors, err = driver.NeighborsTo(ctx, []string{"b"})
+	require.Nil(t, err)
+	require.Len(t, neighbors, 2)
+	require.Equal(t, "a", neighbors[0].K1)
+}
@@ -0,0 +1,281 @@
+package v1beta
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/depscloud/api"
+)
+
+// The graph is stored as adjacency lists. Edges are written to both
+// graph_edges_out and graph_edges_in, partitioned by the node they leave and
+// the node they enter, so a neighbor lookup only ever reads a single
+// partition. graph_kinds supports listing items of a kind.
+var cassandraCreateTables = []string{
+	`CREATE TABLE IF NOT EXISTS graph_nodes (
+	key blob PRIMARY KEY, kind text, encoding int, data text, date_deleted timestamp, last_modified timestamp
+)`,
+	`CREATE TABLE IF NOT EXISTS graph_edges_out (
+	k1 blob, k2 blob, k3 blob, kind text, encoding int, data text, last_modified timestamp,
+	PRIMARY KEY (k1, k2, k3)
+)`,
+	`CREATE TABLE IF NOT EXISTS graph_edges_in (
+	k1 blob, k2 blob, k3 blob, kind text, encoding int, data text, last_modified timestamp,
+	PRIMARY KEY (k2, k1, k3)
+)`,
+	`CREATE TABLE IF NOT EXISTS graph_kinds (
+	kind text, k1 blob, k2 blob, k3 blob, encoding int, data text, date_deleted timestamp,
+	PRIMARY KEY (kind, k1, k2, k3)
+)`,
+}
+
+const (
+	cassandraPutNode = `INSERT INTO graph_nodes (key, kind, encoding, data, date_deleted, last_modified)
+VALUES (?, ?, ?, ?, null, ?)`
+
+	cassandraPutEdgeOut = `INSERT INTO graph_edges_out (k1, k2, k3, kind, encoding, data, last_modified)
+VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	cassandraPutEdgeIn = `INSERT INTO graph_edges_in (k1, k2, k3, kind, encoding, data, last_modified)
+VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	cassandraPutKind = `INSERT INTO graph_kinds (kind, k1, k2, k3, encoding, data, date_deleted)
+VALUES (?, ?, ?, ?, ?, ?, null)`
+
+	// like the sql drivers, nodes are only marked as deleted
+	cassandraDeleteNode = `UPDATE graph_nodes SET date_deleted = ? WHERE key = ?`
+
+	cassandraDeleteNodeKind = `UPDATE graph_kinds SET date_deleted = ? WHERE kind = ? AND k1 = ? AND k2 = ? AND k3 = ?`
+
+	cassandraDeleteEdgeOut = `DELETE FROM graph_edges_out WHERE k1 = ? AND k2 = ? AND k3 = ?`
+
+	cassandraDeleteEdgeIn = `DELETE FROM graph_edges_in WHERE k2 = ? AND k1 = ? AND k3 = ?`
+
+	cassandraDeleteEdgeKind = `DELETE FROM graph_kinds WHERE kind = ? AND k1 = ? AND k2 = ? AND k3 = ?`
+
+	cassandraList = `SELECT k1, k2, k3, encoding, data, date_deleted FROM graph_kinds WHERE kind = ?`
+
+	cassandraSelectNode = `SELECT kind, encoding, data, date_deleted FROM graph_nodes WHERE key = ?`
+
+	cassandraSelectEdgesOut = `SELECT k1, k2, k3, kind, encoding, data FROM graph_edges_out WHERE k1 = ?`
+
+	cassandraSelectEdgesIn = `SELECT k1, k2, k3, kind, encoding, data FROM graph_edges_in WHERE k2 = ?`
+)
+
+// NewCassandraDriver returns a driver that stores the graph in cassandra or
+// scylladb. Addresses take the form user:pass@host1:9042,host2:9042/keyspace.
+// The keyspace must already exist so its replication can be configured by the
+// operator.
+func NewCassandraDriver(storageAddress, storageReadOnlyAddress string) (Driver, error) {
+	driver := &cassandraDriver{}
+
+	if len(storageAddress) > 0 {
+		rw, err := newCQLClient(storageAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		// only set up the schema on RW connections
+		for _, statement := range cassandraCreateTables {
+			if _, err := rw.query(context.Background(), statement); err != nil {
+				return nil, err
+			}
+		}
+
+		driver.rw = rw
+		driver.ro = rw
+	}
+
+	if len(storageReadOnlyAddress) > 0 {
+		ro, err := newCQLClient(storageReadOnlyAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		driver.ro = ro
+	}
+
+	if driver.rw == nil && driver.ro == nil {
+		return nil, fmt.Errorf("must provide one storage address")
+	}
+
+	return driver, nil
+}
+
+type cassandraDriver struct {
+	rw cqlSession
+	ro cqlSession
+}
+
+func (d *cassandraDriver) Put(ctx context.Context, items []*GraphData) error {
+	if d.rw == nil {
+		return api.ErrUnsupported
+	}
+
+	for _, item := range items {
+		k1, k2, k3 := []byte(item.K1), []byte(item.K2), []byte(item.K3)
+		encoding := int(item.Encoding)
+
+		if item.K1 == item.K2 {
+			if _, err := d.rw.query(ctx, cassandraPutNode, k1, item.Kind, encoding, item.Data, item.LastModified); err != nil {
+				return err
+			}
+		} else {
+			for _, statement := range []string{cassandraPutEdgeOut, cassandraPutEdgeIn} {
+				if _, err := d.rw.query(ctx, statement, k1, k2, k3, item.Kind, encoding, item.Data, item.LastModified); err != nil {
+					return err
+				}
+			}
+		}
+
+		if _, err := d.rw.query(ctx, cassandraPutKind, item.Kind, k1, k2, k3, encoding, item.Data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete marks nodes as deleted and removes edges.
+func (d *cassandraDriver) Delete(ctx context.Context, items []*GraphData) error {
+	if d.rw == nil {
+		return api.ErrUnsupported
+	}
+
+	for _, item := range items {
+		k1, k2, k3 := []byte(item.K1), []byte(item.K2), []byte(item.K3)
+
+		if item.K1 == item.K2 {
+			var dateDeleted interface{}
+			if item.DateDeleted != nil && item.DateDeleted.Valid {
+				dateDeleted = item.DateDeleted.Time
+			}
+
+			if _, err := d.rw.query(ctx, cassandraDeleteNode, dateDeleted, k1); err != nil {
+				return err
+			}
+
+			if _, err := d.rw.query(ctx, cassandraDeleteNodeKind, dateDeleted, item.Kind, k1, k2, k3); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := d.rw.query(ctx, cassandraDeleteEdgeOut, k1, k2, k3); err != nil {
+			return err
+		}
+
+		if _, err := d.rw.query(ctx, cassandraDeleteEdgeIn, k2, k1, k3); err != nil {
+			return err
+		}
+
+		if _, err := d.rw.query(ctx, cassandraDeleteEdgeKind, item.Kind, k1, k2, k3); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *cassandraDriver) List(ctx context.Context, kind string, offset, limit int) ([]*GraphData, bool, error) {
+	rows, err := d.ro.query(ctx, cassandraList, kind)
+	if err != nil {
+		return nil, false, err
+	}
+
+	results := make([]*GraphData, 0, limit)
+	for _, row := range rows {
+		// deleted nodes
+		if row[5] != nil {
+			continue
+		}
+
+		if offset > 0 {
+			offset--
+			continue
+		}
+
+		if len(results) == limit {
+			return results, true, nil
+		}
+
+		results = append(results, &GraphData{
+			K1:       string(row[0]),
+			K2:       string(row[1]),
+			K3:       string(row[2]),
+			Kind:     kind,
+			Encoding: Encoding(cqlInt(row[3])),
+			Data:     string(row[4]),
+		})
+	}
+
+	return results, false, nil
+}
+
+// neighbors reads the edges stored in the partition of each key. The node
+// func picks the neighboring node from the edge.
+func (d *cassandraDriver) neighbors(ctx context.Context, statement string, keys []string, node func(edge *GraphData) string) ([]*GraphData, error) {
+	nodes := make(map[string]*GraphData)
+	results := make([]*GraphData, 0)
+
+	for _, key := range keys {
+		rows, err := d.ro.query(ctx, statement, []byte(key))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			edge := &GraphData{
+				K1:       string(row[0]),
+				K2:       string(row[1]),
+				K3:       string(row[2]),
+				Kind:     string(row[3]),
+				Encoding: Encoding(cqlInt(row[4])),
+				Data:     string(row[5]),
+			}
+
+			neighborKey := node(edge)
+
+			neighbor, ok := nodes[neighborKey]
+			if !ok {
+				nodeRows, err := d.ro.query(ctx, cassandraSelectNode, []byte(neighborKey))
+				if err != nil {
+					return nil, err
+				}
+
+				// nodes that were never written or have been deleted
+				if len(nodeRows) > 0 && nodeRows[0][0] != nil && nodeRows[0][3] == nil {
+					neighbor = &GraphData{
+						K1:       neighborKey,
+						K2:       neighborKey,
+						Kind:     string(nodeRows[0][0]),
+						Encoding: Encoding(cqlInt(nodeRows[0][1])),
+						Data:     string(nodeRows[0][2]),
+					}
+				}
+
+				nodes[neighborKey] = neighbor
+			}
+
+			if neighbor != nil {
+				results = append(results, neighbor, edge)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func (d *cassandraDriver) NeighborsTo(ctx context.Context, toKeys []string) ([]*GraphData, error) {
+	return d.neighbors(ctx, cassandraSelectEdgesIn, toKeys, func(edge *GraphData) string {
+		return edge.K1
+	})
+}
+
+func (d *cassandraDriver) NeighborsFrom(ctx context.Context, fromKeys []string) ([]*GraphData, error) {
+	return d.neighbors(ctx, cassandraSelectEdgesOut, fromKeys, func(edge *GraphData) string {
+		return edge.K2
+	})
+}
+
+var _ Driver = &cassandraDriver{}
@@ -0,0 +1,102 @@
+package v1beta
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCQLSession struct {
+	statements []string
+	results    map[string][][][]byte
+}
+
+func (s *fakeCQLSession) query(ctx context.Context, statement string, values ...interface{}) ([][][]byte, error) {
+	// record the operation and table, e.g. INSERT graph_nodes
+	fields := strings.Fields(statement)
+	s.statements = append(s.statements, fields[0]+" "+fields[2])
+
+	key := statement
+	if len(values) > 0 {
+		if value, ok := values[0].([]byte); ok {
+			key += " " + string(value)
+		}
+	}
+	return s.results[key], nil
+}
+
+func TestCassandraDriver_Put(t *testing.T) {
+	session := &fakeCQLSession{}
+	driver := &cassandraDriver{rw: session, ro: session}
+
+	err := driver.Put(context.Background(), []*GraphData{
+		{K1: "a", K2: "a", Kind: "module", LastModified: time.Now()},
+		{K1: "a", K2: "b", K3: "src", Kind: "depends", LastModified: time.Now()},
+	})
+	require.Nil(t, err)
+
+	require.Equal(t, []string{
+		"INSERT graph_nodes",
+		"INSERT graph_kinds",
+		"INSERT graph_edges_out",
+		"INSERT graph_edges_in",
+		"INSERT graph_kinds",
+	}, session.statements)
+}
+
+func TestCassandraDriver_NeighborsFrom(t *testing.T) {
+	encoding := []byte{0, 0, 0, 1}
+
+	session := &fakeCQLSession{
+		results: map[string][][][]byte{
+			cassandraSelectEdgesOut + " a": {
+				{[]byte("a"), []byte("b"), []byte("src"), []byte("depends"), encoding, []byte("{}")},
+				{[]byte("a"), []byte("c"), []byte("src"), []byte("depends"), encoding, []byte("{}")},
+			},
+			cassandraSelectNode + " b": {
+				{[]byte("module"), encoding, []byte("{}"), nil},
+			},
+			// deleted
+			cassandraSelectNode + " c": {
+				{[]byte("module"), encoding, []byte("{}"), []byte{0, 0, 0, 0, 0, 0, 0, 1}},
+			},
+		},
+	}
+	driver := &cassandraDriver{ro: session}
+
+	results, err := driver.NeighborsFrom(context.Background(), []string{"a"})
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+
+	require.Equal(t, "b", results[0].K1)
+	require.Equal(t, "module", results[0].Kind)
+	require.Equal(t, EncodingJSON, results[0].Encoding)
+
+	require.Equal(t, "a", results[1].K1)
+	require.Equal(t, "b", results[1].K2)
+	require.Equal(t, "src", results[1].K3)
+	require.Equal(t, "depends", results[1].Kind)
+}
+
+func TestCassandraDriver_List(t *testing.T) {
+	session := &fakeCQLSession{
+		results: map[string][][][]byte{
+			cassandraList: {
+				{[]byte("a"), []byte("a"), []byte{}, nil, nil, nil},
+				{[]byte("b"), []byte("b"), []byte{}, nil, nil, []byte{0, 0, 0, 0, 0, 0, 0, 1}},
+				{[]byte("c"), []byte("c"), []byte{}, nil, nil, nil},
+				{[]byte("d"), []byte("d"), []byte{}, nil, nil, nil},
+			},
+		},
+	}
+	driver := &cassandraDriver{ro: session}
+
+	results, hasNext, err := driver.List(context.Background(), "module", 1, 1)
+	require.Nil(t, err)
+	require.True(t, hasNext)
+	require.Len(t, results, 1)
+	require.Equal(t, "c", results[0].K1)
+}
@@ -0,0 +1,504 @@
+package v1beta
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file implements the small subset of the cassandra native protocol (v4)
+// needed by the cassandra driver. It's compatible with cassandra and scylladb.
+
+const (
+	cqlRequestVersion  = 0x04
+	cqlResponseVersion = 0x84
+
+	cqlOpError         = 0x00
+	cqlOpStartup       = 0x01
+	cqlOpReady         = 0x02
+	cqlOpAuthenticate  = 0x03
+	cqlOpQuery         = 0x07
+	cqlOpResult        = 0x08
+	cqlOpAuthResponse  = 0x0F
+	cqlOpAuthSuccess   = 0x10
+	cqlFlagTracing     = 0x02
+	cqlFlagPayload     = 0x04
+	cqlFlagWarning     = 0x08
+	cqlResultRows      = 0x0002
+	cqlQueryValues     = 0x01
+	cqlQueryPageSize   = 0x04
+	cqlQueryPageState  = 0x08
+	cqlRowsGlobalSpec  = 0x0001
+	cqlRowsMorePages   = 0x0002
+	cqlRowsNoMetadata  = 0x0004
+	cqlLocalQuorum     = 0x0006
+	cqlDefaultPort     = "9042"
+	cqlPageSize        = 1000
+	cqlDefaultDeadline = 30 * time.Second
+)
+
+type cqlWriter struct {
+	buf []byte
+}
+
+func (w *cqlWriter) byte(b byte) {
+	w.buf = append(w.buf, b)
+}
+
+func (w *cqlWriter) short(n uint16) {
+	w.buf = append(w.buf, byte(n>>8), byte(n))
+}
+
+func (w *cqlWriter) int(n int32) {
+	w.buf = append(w.buf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func (w *cqlWriter) string(s string) {
+	w.short(uint16(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *cqlWriter) longString(s string) {
+	w.int(int32(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *cqlWriter) bytes(b []byte) {
+	if b == nil {
+		w.int(-1)
+		return
+	}
+	w.int(int32(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+type cqlReader struct {
+	buf []byte
+	err error
+}
+
+func (r *cqlReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf) < n {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+
+	data := r.buf[:n]
+	r.buf = r.buf[n:]
+	return data
+}
+
+func (r *cqlReader) short() uint16 {
+	if data := r.next(2); data != nil {
+		return binary.BigEndian.Uint16(data)
+	}
+	return 0
+}
+
+func (r *cqlReader) int() int32 {
+	if data := r.next(4); data != nil {
+		return int32(binary.BigEndian.Uint32(data))
+	}
+	return 0
+}
+
+func (r *cqlReader) string() string {
+	return string(r.next(int(r.short())))
+}
+
+func (r *cqlReader) bytes() []byte {
+	n := r.int()
+	if n < 0 {
+		return nil
+	}
+
+	// distinguish empty values from null values
+	return append([]byte{}, r.next(int(n))...)
+}
+
+// option skips over a column type. The driver knows the types of the columns
+// it selects so they don't need to be kept.
+func (r *cqlReader) option() {
+	switch r.short() {
+	case 0x0000: // custom
+		r.string()
+	case 0x0020, 0x0022: // list, set
+		r.option()
+	case 0x0021: // map
+		r.option()
+		r.option()
+	case 0x0030: // udt
+		r.string()
+		r.string()
+		for i := r.short(); i > 0 && r.err == nil; i-- {
+			r.string()
+			r.option()
+		}
+	case 0x0031: // tuple
+		for i := r.short(); i > 0 && r.err == nil; i-- {
+			r.option()
+		}
+	}
+}
+
+// cqlRows reads a rows result returning the rows and the paging state, which
+// is nil on the last page.
+func cqlRows(r *cqlReader) ([][][]byte, []byte, error) {
+	flags := r.int()
+	columns := int(r.int())
+
+	var pagingState []byte
+	if flags&cqlRowsMorePages != 0 {
+		pagingState = r.bytes()
+	}
+
+	if flags&cqlRowsNoMetadata == 0 {
+		if flags&cqlRowsGlobalSpec != 0 {
+			r.string()
+			r.string()
+		}
+
+		for i := 0; i < columns && r.err == nil; i++ {
+			if flags&cqlRowsGlobalSpec == 0 {
+				r.string()
+				r.string()
+			}
+			r.string()
+			r.option()
+		}
+	}
+
+	count := int(r.int())
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+
+	rows := make([][][]byte, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		row := make([][]byte, columns)
+		for j := range row {
+			row[j] = r.bytes()
+		}
+		rows = append(rows, row)
+	}
+
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	return rows, pagingState, nil
+}
+
+// cqlValue encodes the values the driver binds to its statements.
+func cqlValue(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case int:
+		data := make([]byte, 4)
+		binary.BigEndian.PutUint32(data, uint32(int32(v)))
+		return data, nil
+	case time.Time:
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, uint64(v.UnixNano()/int64(time.Millisecond)))
+		return data, nil
+	}
+	return nil, fmt.Errorf("unsupported value type: %T", value)
+}
+
+func cqlInt(data []byte) int {
+	if len(data) != 4 {
+		return 0
+	}
+	return int(int32(binary.BigEndian.Uint32(data)))
+}
+
+// cqlSession executes a statement and returns the raw column values of each
+// row. All pages of the result are read.
+type cqlSession interface {
+	query(ctx context.Context, statement string, values ...interface{}) ([][][]byte, error)
+}
+
+// cqlClient executes statements one at a time over a single connection to
+// the first reachable host. The connection is re-established after errors.
+type cqlClient struct {
+	hosts    []string
+	username string
+	password string
+	keyspace string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// newCQLClient parses addresses of the form
+// [cassandra://][user:pass@]host1[:port][,host2[:port]...]/keyspace.
+func newCQLClient(address string) (*cqlClient, error) {
+	address = strings.TrimPrefix(address, "cassandra://")
+	address = strings.TrimPrefix(address, "scylla://")
+
+	c := &cqlClient{}
+
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		credentials := address[:i]
+		address = address[i+1:]
+
+		c.username = credentials
+		if j := strings.Index(credentials, ":"); j >= 0 {
+			c.username = credentials[:j]
+			c.password = credentials[j+1:]
+		}
+	}
+
+	if i := strings.Index(address, "/"); i >= 0 {
+		c.keyspace = address[i+1:]
+		address = address[:i]
+	}
+
+	if c.keyspace == "" {
+		return nil, fmt.Errorf("cassandra address must include a keyspace")
+	}
+
+	for _, host := range strings.Split(address, ",") {
+		if host == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, cqlDefaultPort)
+		}
+		c.hosts = append(c.hosts, host)
+	}
+
+	if len(c.hosts) == 0 {
+		return nil, fmt.Errorf("cassandra address must include a host")
+	}
+
+	return c, nil
+}
+
+func (c *cqlClient) send(opcode byte, body []byte) error {
+	header := &cqlWriter{}
+	header.byte(cqlRequestVersion)
+	header.byte(0)
+	header.short(0)
+	header.byte(opcode)
+	header.int(int32(len(body)))
+
+	_, err := c.conn.Write(append(header.buf, body...))
+	return err
+}
+
+func (c *cqlClient) receive() (byte, *cqlReader, error) {
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return 0, nil, err
+	}
+
+	if header[0] != cqlResponseVersion {
+		return 0, nil, fmt.Errorf("unsupported protocol version: %#x", header[0])
+	}
+
+	body := make([]byte, binary.BigEndian.Uint32(header[5:]))
+	if _, err := io.ReadFull(c.conn, body); err != nil {
+		return 0, nil, err
+	}
+
+	r := &cqlReader{buf: body}
+
+	flags := header[1]
+	if flags&cqlFlagTracing != 0 {
+		r.next(16)
+	}
+	if flags&cqlFlagWarning != 0 {
+		for i := r.short(); i > 0 && r.err == nil; i-- {
+			r.string()
+		}
+	}
+	if flags&cqlFlagPayload != 0 {
+		for i := r.short(); i > 0 && r.err == nil; i-- {
+			r.string()
+			r.bytes()
+		}
+	}
+
+	opcode := header[4]
+	if opcode == cqlOpError {
+		code := r.int()
+		message := r.string()
+		return 0, nil, fmt.Errorf("cassandra error %#x: %s", code, message)
+	}
+
+	return opcode, r, r.err
+}
+
+func (c *cqlClient) connect(ctx context.Context, deadline time.Time) error {
+	dialer := &net.Dialer{Deadline: deadline}
+
+	var err error
+	for _, host := range c.hosts {
+		if c.conn, err = dialer.DialContext(ctx, "tcp", host); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	startup := &cqlWriter{}
+	startup.short(1)
+	startup.string("CQL_VERSION")
+	startup.string("3.0.0")
+
+	if err := c.send(cqlOpStartup, startup.buf); err != nil {
+		return err
+	}
+
+	opcode, _, err := c.receive()
+	if err != nil {
+		return err
+	}
+
+	if opcode == cqlOpAuthenticate {
+		token := &cqlWriter{}
+		token.bytes([]byte("\x00" + c.username + "\x00" + c.password))
+
+		if err := c.send(cqlOpAuthResponse, token.buf); err != nil {
+			return err
+		}
+
+		if opcode, _, err = c.receive(); err != nil {
+			return err
+		} else if opcode != cqlOpAuthSuccess {
+			return fmt.Errorf("unexpected authentication response: %#x", opcode)
+		}
+	} else if opcode != cqlOpReady {
+		return fmt.Errorf("unexpected startup response: %#x", opcode)
+	}
+
+	_, err = c.execute(fmt.Sprintf(`USE "%s"`, c.keyspace), nil, nil)
+	return err
+}
+
+func (c *cqlClient) execute(statement string, values [][]byte, pagingState []byte) (*cqlReader, error) {
+	body := &cqlWriter{}
+	body.longString(statement)
+	body.short(cqlLocalQuorum)
+
+	flags := byte(cqlQueryPageSize)
+	if len(values) > 0 {
+		flags |= cqlQueryValues
+	}
+	if pagingState != nil {
+		flags |= cqlQueryPageState
+	}
+	body.byte(flags)
+
+	if len(values) > 0 {
+		body.short(uint16(len(values)))
+		for _, value := range values {
+			body.bytes(value)
+		}
+	}
+
+	body.int(cqlPageSize)
+
+	if pagingState != nil {
+		body.bytes(pagingState)
+	}
+
+	if err := c.send(cqlOpQuery, body.buf); err != nil {
+		return nil, err
+	}
+
+	opcode, r, err := c.receive()
+	if err != nil {
+		return nil, err
+	} else if opcode != cqlOpResult {
+		return nil, fmt.Errorf("unexpected query response: %#x", opcode)
+	}
+
+	return r, nil
+}
+
+func (c *cqlClient) query(ctx context.Context, statement string, values ...interface{}) ([][][]byte, error) {
+	encoded := make([][]byte, len(values))
+	for i, value := range values {
+		data, err := cqlValue(value)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = data
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(cqlDefaultDeadline)
+	}
+
+	if c.conn == nil {
+		if err := c.connect(ctx, deadline); err != nil {
+			c.close()
+			return nil, err
+		}
+	} else if err := c.conn.SetDeadline(deadline); err != nil {
+		c.close()
+		return nil, err
+	}
+
+	results := make([][][]byte, 0)
+
+	var pagingState []byte
+	for {
+		r, err := c.execute(statement, encoded, pagingState)
+		if err != nil {
+			// server errors leave the connection usable
+			if _, ok := err.(net.Error); ok || err == io.EOF || err == io.ErrUnexpectedEOF {
+				c.close()
+			}
+			return nil, err
+		}
+
+		if r.int() != cqlResultRows {
+			return results, r.err
+		}
+
+		rows, next, err := cqlRows(r)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, rows...)
+
+		if next == nil {
+			return results, nil
+		}
+		pagingState = next
+	}
+}
+
+func (c *cqlClient) close() {
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+}
+
+var _ cqlSession = &cqlClient{}
@@ -0,0 +1,490 @@
+package v1beta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api"
+)
+
+// Nodes are stored as GraphNode and edges as GraphEdge. Since several sources
+// can report the same edge between two nodes, edges are nodes of their own
+// that link to the nodes they connect. Keys are base64 encoded since they are
+// arbitrary bytes.
+const dgraphSchema = `
+graph.key: string @index(exact) @upsert .
+graph.edgeKey: string .
+graph.kind: string @index(exact) .
+graph.encoding: int .
+graph.data: string .
+graph.deleted: datetime .
+graph.lastModified: datetime .
+graph.from: uid @reverse .
+graph.to: uid @reverse .
+
+type GraphNode {
+	graph.key
+	graph.kind
+	graph.encoding
+	graph.data
+	graph.deleted
+	graph.lastModified
+}
+
+type GraphEdge {
+	graph.key
+	graph.edgeKey
+	graph.kind
+	graph.encoding
+	graph.data
+	graph.lastModified
+	graph.from
+	graph.to
+}
+`
+
+const dgraphItemFields = `
+	graph.key
+	graph.edgeKey
+	graph.kind
+	graph.encoding
+	graph.data
+`
+
+type dgraphItem struct {
+	Key      string        `json:"graph.key"`
+	EdgeKey  string        `json:"graph.edgeKey"`
+	Kind     string        `json:"graph.kind"`
+	Encoding Encoding      `json:"graph.encoding"`
+	Data     string        `json:"graph.data"`
+	Types    []string      `json:"dgraph.type"`
+	From     *dgraphItem   `json:"graph.from"`
+	To       *dgraphItem   `json:"graph.to"`
+	Outgoing []*dgraphItem `json:"~graph.from"`
+	Incoming []*dgraphItem `json:"~graph.to"`
+}
+
+type dgraphResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// dgraphClient issues queries and mutations using the dgraph http api.
+type dgraphClient struct {
+	client   *http.Client
+	endpoint string
+}
+
+// newDgraphClient parses addresses of the form http://host:8080.
+func newDgraphClient(address string) (*dgraphClient, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("dgraph address must use http or https: %s", address)
+	}
+
+	return &dgraphClient{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		endpoint: fmt.Sprintf("%s://%s", u.Scheme, u.Host),
+	}, nil
+}
+
+func (c *dgraphClient) post(ctx context.Context, path, contentType string, body []byte) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &dgraphResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("dgraph responded with status %d: %v", resp.StatusCode, err)
+	}
+
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("dgraph: %s", result.Errors[0].Message)
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dgraph responded with status %d", resp.StatusCode)
+	}
+
+	return result.Data, nil
+}
+
+func (c *dgraphClient) alter(ctx context.Context, schema string) error {
+	_, err := c.post(ctx, "/alter", "application/dql", []byte(schema))
+	return err
+}
+
+func (c *dgraphClient) mutate(ctx context.Context, mutation map[string]interface{}) error {
+	body, err := json.Marshal(mutation)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(ctx, "/mutate?commitNow=true", "application/json", body)
+	return err
+}
+
+func (c *dgraphClient) query(ctx context.Context, query string, variables map[string]string, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := c.post(ctx, "/query", "application/json", body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, result)
+}
+
+// NewDgraphDriver returns a driver that stores the graph in dgraph.
+func NewDgraphDriver(storageAddress, storageReadOnlyAddress string) (Driver, error) {
+	driver := &dgraphDriver{}
+
+	if len(storageAddress) > 0 {
+		rw, err := newDgraphClient(storageAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		// only set up the schema on RW connections
+		if err := rw.alter(context.Background(), dgraphSchema); err != nil {
+			return nil, err
+		}
+
+		driver.rw = rw
+		driver.ro = rw
+	}
+
+	if len(storageReadOnlyAddress) > 0 {
+		ro, err := newDgraphClient(storageReadOnlyAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		driver.ro = ro
+	}
+
+	if driver.rw == nil && driver.ro == nil {
+		return nil, fmt.Errorf("must provide one storage address")
+	}
+
+	return driver, nil
+}
+
+type dgraphDriver struct {
+	rw *dgraphClient
+	ro *dgraphClient
+}
+
+// dgraphEdgeKey identifies an edge by the nodes it connects and its key. The
+// separator isn't part of the base64 alphabet, so edge keys never collide with
+// node keys.
+func dgraphEdgeKey(item *GraphData) string {
+	return strings.Join([]string{encodeKey(item.K1), encodeKey(item.K2), encodeKey(item.K3)}, ":")
+}
+
+// dgraphUpsert builds an upsert block that resolves the uid of every key
+// referenced by the items. Keys are base64 encoded, so they are safe to inline
+// in the query.
+type dgraphUpsert struct {
+	vars  map[string]string
+	query []string
+}
+
+func (u *dgraphUpsert) uid(key string) string {
+	if name, ok := u.vars[key]; ok {
+		return fmt.Sprintf("uid(%s)", name)
+	}
+
+	name := fmt.Sprintf("v%d", len(u.vars))
+	u.vars[key] = name
+	u.query = append(u.query, fmt.Sprintf("%s as var(func: eq(graph.key, %q))", name, key))
+	return fmt.Sprintf("uid(%s)", name)
+}
+
+func (u *dgraphUpsert) String() string {
+	return "{\n" + strings.Join(u.query, "\n") + "\n}"
+}
+
+func (d *dgraphDriver) Put(ctx context.Context, items []*GraphData) error {
+	if d.rw == nil {
+		return api.ErrUnsupported
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	upsert := &dgraphUpsert{vars: make(map[string]string)}
+	set := make([]map[string]interface{}, 0, len(items))
+	del := make([]map[string]interface{}, 0)
+
+	for _, item := range items {
+		lastModified := item.LastModified.UTC().Format(time.RFC3339Nano)
+
+		if item.K1 == item.K2 {
+			key := encodeKey(item.K1)
+			uid := upsert.uid(key)
+
+			set = append(set, map[string]interface{}{
+				"uid":                uid,
+				"dgraph.type":        "GraphNode",
+				"graph.key":          key,
+				"graph.kind":         item.Kind,
+				"graph.encoding":     item.Encoding,
+				"graph.data":         item.Data,
+				"graph.lastModified": lastModified,
+			})
+
+			// nodes that are written again are no longer deleted
+			del = append(del, map[string]interface{}{
+				"uid":           uid,
+				"graph.deleted": nil,
+			})
+			continue
+		}
+
+		from := encodeKey(item.K1)
+		to := encodeKey(item.K2)
+		key := dgraphEdgeKey(item)
+
+		set = append(set, map[string]interface{}{
+			"uid":                upsert.uid(key),
+			"dgraph.type":        "GraphEdge",
+			"graph.key":          key,
+			"graph.edgeKey":      encodeKey(item.K3),
+			"graph.kind":         item.Kind,
+			"graph.encoding":     item.Encoding,
+			"graph.data":         item.Data,
+			"graph.lastModified": lastModified,
+			// nodes may not have been written yet, so ensure they have a key
+			"graph.from": map[string]interface{}{"uid": upsert.uid(from), "graph.key": from},
+			"graph.to":   map[string]interface{}{"uid": upsert.uid(to), "graph.key": to},
+		})
+	}
+
+	return d.rw.mutate(ctx, map[string]interface{}{
+		"query":  upsert.String(),
+		"set":    set,
+		"delete": del,
+	})
+}
+
+func (d *dgraphDriver) Delete(ctx context.Context, items []*GraphData) error {
+	if d.rw == nil {
+		return api.ErrUnsupported
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	upsert := &dgraphUpsert{vars: make(map[string]string)}
+	set := make([]map[string]interface{}, 0)
+	del := make([]map[string]interface{}, 0)
+
+	for _, item := range items {
+		if item.K1 == item.K2 {
+			// like the sql drivers, nodes are only marked as deleted
+			dateDeleted := time.Now()
+			if item.DateDeleted != nil && item.DateDeleted.Valid {
+				dateDeleted = item.DateDeleted.Time
+			}
+
+			set = append(set, map[string]interface{}{
+				"uid":           upsert.uid(encodeKey(item.K1)),
+				"graph.deleted": dateDeleted.UTC().Format(time.RFC3339Nano),
+			})
+			continue
+		}
+
+		del = append(del, map[string]interface{}{
+			"uid": upsert.uid(dgraphEdgeKey(item)),
+		})
+	}
+
+	return d.rw.mutate(ctx, map[string]interface{}{
+		"query":  upsert.String(),
+		"set":    set,
+		"delete": del,
+	})
+}
+
+func (i *dgraphItem) graphData(k1, k2 string) (*GraphData, error) {
+	var err error
+	item := &GraphData{
+		Kind:     i.Kind,
+		Encoding: i.Encoding,
+		Data:     i.Data,
+	}
+
+	if item.K1, err = decodeKey(k1); err != nil {
+		return nil, err
+	}
+	if item.K2, err = decodeKey(k2); err != nil {
+		return nil, err
+	}
+	if item.K3, err = decodeKey(i.EdgeKey); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+const dgraphList = `
+query list($kind: string) {
+	items(func: eq(graph.kind, $kind), orderasc: graph.key, first: %d, offset: %d) @filter(NOT has(graph.deleted)) {
+		dgraph.type
+		%s
+		graph.from { graph.key }
+		graph.to { graph.key }
+	}
+}
+`
+
+func (d *dgraphDriver) List(ctx context.Context, kind string, offset, limit int) ([]*GraphData, bool, error) {
+	result := struct {
+		Items []*dgraphItem `json:"items"`
+	}{}
+
+	query := fmt.Sprintf(dgraphList, limit+1, offset, dgraphItemFields)
+	if err := d.ro.query(ctx, query, map[string]string{"$kind": kind}, &result); err != nil {
+		return nil, false, err
+	}
+
+	results := make([]*GraphData, 0, len(result.Items))
+	for _, i := range result.Items {
+		k1, k2 := i.Key, i.Key
+		if i.From != nil && i.To != nil {
+			k1, k2 = i.From.Key, i.To.Key
+		}
+
+		item, err := i.graphData(k1, k2)
+		if err != nil {
+			return nil, false, err
+		}
+		results = append(results, item)
+	}
+
+	if len(results) > limit {
+		return results[:limit], true, nil
+	}
+	return results, false, nil
+}
+
+// dgraphNeighbors selects the edges leaving (or entering) the nodes along with
+// the node at the other end of each edge.
+const dgraphNeighbors = `
+{
+	nodes(func: eq(graph.key, [%s])) {
+		graph.key
+		%s @filter(type(GraphEdge)) {
+			%s
+			%s @filter(has(graph.kind) AND NOT has(graph.deleted)) {
+				%s
+			}
+		}
+	}
+}
+`
+
+func (d *dgraphDriver) neighbors(ctx context.Context, keys []string, outgoing bool) ([]*GraphData, error) {
+	if len(keys) == 0 {
+		return []*GraphData{}, nil
+	}
+
+	quoted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		quoted = append(quoted, fmt.Sprintf("%q", encodeKey(key)))
+	}
+
+	edgePredicate, nodePredicate := "~graph.from", "graph.to"
+	if !outgoing {
+		edgePredicate, nodePredicate = "~graph.to", "graph.from"
+	}
+
+	query := fmt.Sprintf(dgraphNeighbors, strings.Join(quoted, ", "),
+		edgePredicate, dgraphItemFields, nodePredicate, dgraphItemFields)
+
+	result := struct {
+		Nodes []*dgraphItem `json:"nodes"`
+	}{}
+
+	if err := d.ro.query(ctx, query, nil, &result); err != nil {
+		return nil, err
+	}
+
+	results := make([]*GraphData, 0)
+	for _, node := range result.Nodes {
+		edges := node.Outgoing
+		if !outgoing {
+			edges = node.Incoming
+		}
+
+		for _, edge := range edges {
+			neighbor := edge.To
+			k1, k2 := node.Key, ""
+			if !outgoing {
+				neighbor = edge.From
+			}
+
+			// the neighbor was filtered out
+			if neighbor == nil {
+				continue
+			}
+
+			if outgoing {
+				k2 = neighbor.Key
+			} else {
+				k1, k2 = neighbor.Key, node.Key
+			}
+
+			n, err := neighbor.graphData(neighbor.Key, neighbor.Key)
+			if err != nil {
+				return nil, err
+			}
+
+			e, err := edge.graphData(k1, k2)
+			if err != nil {
+				return nil, err
+			}
+
+			results = append(results, n, e)
+		}
+	}
+
+	return results, nil
+}
+
+func (d *dgraphDriver) NeighborsTo(ctx context.Context, toKeys []string) ([]*GraphData, error) {
+	return d.neighbors(ctx, toKeys, false)
+}
+
+func (d *dgraphDriver) NeighborsFrom(ctx context.Context, fromKeys []string) ([]*GraphData, error) {
+	return d.neighbors(ctx, fromKeys, true)
+}
+
+var _ Driver = &dgraphDriver{}
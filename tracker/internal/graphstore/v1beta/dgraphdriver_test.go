@@ -0,0 +1,133 @@
+package v1beta_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/tracker/internal/graphstore/v1beta"
+
+	"github.com/stretchr/testify/require"
+)
+
+type dgraphRequest struct {
+	Path string
+	Body string
+}
+
+func newDgraphServer(t *testing.T, data string) (*httptest.Server, *[]dgraphRequest) {
+	requests := make([]dgraphRequest, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.Nil(t, err)
+
+		requests = append(requests, dgraphRequest{Path: r.URL.Path, Body: string(body)})
+
+		w.Write([]byte(`{"data": ` + data + `}`))
+	}))
+
+	return server, &requests
+}
+
+func TestDgraphDriver_Put(t *testing.T) {
+	server, requests := newDgraphServer(t, `{}`)
+	defer server.Close()
+
+	driver, err := v1beta.Resolve("dgraph", server.URL, "")
+	require.Nil(t, err)
+
+	require.Len(t, *requests, 1)
+	require.Equal(t, "/alter", (*requests)[0].Path)
+
+	err = driver.Put(context.Background(), []*v1beta.GraphData{
+		{K1: "a", K2: "a", Kind: "module", Encoding: v1beta.EncodingJSON, Data: "{}", LastModified: time.Now()},
+		{K1: "a", K2: "b", K3: "src", Kind: "depends", Encoding: v1beta.EncodingJSON, Data: "{}", LastModified: time.Now()},
+	})
+	require.Nil(t, err)
+
+	require.Len(t, *requests, 2)
+	require.Equal(t, "/mutate", (*requests)[1].Path)
+
+	mutation := struct {
+		Query string                   `json:"query"`
+		Set   []map[string]interface{} `json:"set"`
+	}{}
+	require.Nil(t, json.Unmarshal([]byte((*requests)[1].Body), &mutation))
+
+	// one variable for each distinct key: a, the edge, and b
+	require.Equal(t, 3, strings.Count(mutation.Query, " as var("))
+	require.Len(t, mutation.Set, 2)
+
+	require.Equal(t, "uid(v0)", mutation.Set[0]["uid"])
+	require.Equal(t, b64("a"), mutation.Set[0]["graph.key"])
+
+	edge := mutation.Set[1]
+	require.Equal(t, "uid(v1)", edge["uid"])
+	require.Equal(t, b64("src"), edge["graph.edgeKey"])
+	require.Equal(t, "uid(v0)", edge["graph.from"].(map[string]interface{})["uid"])
+	require.Equal(t, "uid(v2)", edge["graph.to"].(map[string]interface{})["uid"])
+}
+
+func TestDgraphDriver_NeighborsFrom(t *testing.T) {
+	server, requests := newDgraphServer(t, `{"nodes": [{
+		"graph.key": "`+b64("a")+`",
+		"~graph.from": [
+			{
+				"graph.key": "edge",
+				"graph.edgeKey": "`+b64("src")+`",
+				"graph.kind": "depends",
+				"graph.encoding": 1,
+				"graph.data": "{}",
+				"graph.to": {
+					"graph.key": "`+b64("b")+`",
+					"graph.kind": "module",
+					"graph.encoding": 1,
+					"graph.data": "{}"
+				}
+			},
+			{
+				"graph.key": "deleted",
+				"graph.edgeKey": "`+b64("src")+`",
+				"graph.kind": "depends"
+			}
+		]
+	}]}`)
+	defer server.Close()
+
+	driver, err := v1beta.Resolve("dgraph", "", server.URL)
+	require.Nil(t, err)
+
+	results, err := driver.NeighborsFrom(context.Background(), []string{"a"})
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+
+	require.Equal(t, "b", results[0].K1)
+	require.Equal(t, "b", results[0].K2)
+	require.Equal(t, "", results[0].K3)
+	require.Equal(t, "module", results[0].Kind)
+
+	require.Equal(t, "a", results[1].K1)
+	require.Equal(t, "b", results[1].K2)
+	require.Equal(t, "src", results[1].K3)
+	require.Equal(t, v1beta.EncodingJSON, results[1].Encoding)
+
+	require.Contains(t, (*requests)[0].Body, b64("a"))
+	require.Contains(t, (*requests)[0].Body, "~graph.from")
+}
+
+func TestDgraphDriver_errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors": [{"message": "schema is invalid"}]}`))
+	}))
+	defer server.Close()
+
+	_, err := v1beta.Resolve("dgraph", server.URL, "")
+	require.Error(t, err)
+	require.Equal(t, "dgraph: schema is invalid", err.Error())
+}
@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/depscloud/depscloud/tracker/internal/sqlpool"
+	"github.com/depscloud/depscloud/tracker/internal/sqlretry"
+
 	"github.com/jmoiron/sqlx"
 
 	"gorm.io/driver/mysql"
@@ -29,44 +32,107 @@ type Driver interface {
 	NeighborsFrom(ctx context.Context, fromKeys []string) ([]*GraphData, error)
 }
 
-// Resolve takes in connection criteria and returns the appropriate driver
-func Resolve(driver, storageAddress, storageReadOnlyAddress string) (Driver, error) {
-	var statements *Statements
-	var dialectorRW gorm.Dialector
-	var dialectorRO gorm.Dialector
+// nonSQLDrivers construct drivers for graph and key/value stores.
+var nonSQLDrivers = map[string]func(storageAddress, storageReadOnlyAddress string) (Driver, error){
+	"neo4j":     NewNeo4jDriver,
+	"dgraph":    NewDgraphDriver,
+	"cassandra": NewCassandraDriver,
+	"scylla":    NewCassandraDriver,
+	"scylladb":  NewCassandraDriver,
+	"bolt":      NewBoltDriver,
+	"bbolt":     NewBoltDriver,
+}
+
+// readOnlyAddress returns the single read only address supported by drivers
+// that don't balance reads across replicas.
+func readOnlyAddress(driver string, storageReadOnlyAddresses []string) (string, error) {
+	switch len(storageReadOnlyAddresses) {
+	case 0:
+		return "", nil
+	case 1:
+		return storageReadOnlyAddresses[0], nil
+	}
+	return "", fmt.Errorf("the %s driver supports a single read only address", driver)
+}
+
+type sqlDialect struct {
+	driverName string
+	statements *Statements
+	dialector  func(dsn string) gorm.Dialector
+	maxRetries int
+}
 
+func resolveSQLDialect(driver string) (*sqlDialect, error) {
 	switch driver {
 	case "mysql":
-		driver = mysqlDriverName
-		statements = MySQLStatements
-		dialectorRW = mysql.Open(storageAddress)
-		dialectorRO = mysql.Open(storageReadOnlyAddress)
-		break
+		return &sqlDialect{
+			driverName: mysqlDriverName,
+			statements: MySQLStatements,
+			dialector:  mysql.Open,
+		}, nil
 	case "sqlite", "sqlite3":
-		driver = sqliteDriverName
-		statements = SQLiteStatements
-		dialectorRW = sqlite.Open(storageAddress)
-		dialectorRO = sqlite.Open(storageReadOnlyAddress)
-		break
+		return &sqlDialect{
+			driverName: sqliteDriverName,
+			statements: SQLiteStatements,
+			dialector:  sqlite.Open,
+		}, nil
 	case "postgres", "postgresql", "pgx":
-		driver = postgresqlDriverName
-		statements = PostgreSQLStatements
-		dialectorRW = postgres.Open(storageAddress)
-		dialectorRO = postgres.Open(storageReadOnlyAddress)
-		break
-	default:
-		return nil, fmt.Errorf("failed to resolve driver: %s", driver)
+		return &sqlDialect{
+			driverName: postgresqlDriverName,
+			statements: PostgreSQLStatements,
+			dialector:  postgres.Open,
+		}, nil
+	case "cockroach", "cockroachdb", "crdb":
+		// cockroachdb speaks the postgres wire protocol, but aborts conflicting
+		// transactions and expects the client to retry them
+		return &sqlDialect{
+			driverName: postgresqlDriverName,
+			statements: CockroachDBStatements,
+			dialector:  postgres.Open,
+			maxRetries: sqlretry.CockroachDBMaxRetries,
+		}, nil
 	}
 
-	var dbrw *sql.DB
-	if len(storageAddress) > 0 {
-		gormRW, err := gorm.Open(dialectorRW, &gorm.Config{})
+	return nil, fmt.Errorf("failed to resolve driver: %s", driver)
+}
+
+// Resolve takes in connection criteria and returns the appropriate driver.
+// When multiple read only addresses are provided, the sql drivers balance
+// reads across them.
+func Resolve(driver, storageAddress string, storageReadOnlyAddresses ...string) (Driver, error) {
+	return ResolveWithPool(nil, driver, storageAddress, storageReadOnlyAddresses...)
+}
+
+// ResolveWithPool is like Resolve, but configures the connection pools of the
+// sql drivers. Pools for sqlite are left alone since closing the connections
+// of an in-memory database discards it.
+func ResolveWithPool(pool *sqlpool.Config, driver, storageAddress string, storageReadOnlyAddresses ...string) (Driver, error) {
+	readOnlyAddresses := make([]string, 0, len(storageReadOnlyAddresses))
+	for _, address := range storageReadOnlyAddresses {
+		if len(address) > 0 {
+			readOnlyAddresses = append(readOnlyAddresses, address)
+		}
+	}
+
+	if newDriver, ok := nonSQLDrivers[driver]; ok {
+		ro, err := readOnlyAddress(driver, readOnlyAddresses)
 		if err != nil {
 			return nil, err
 		}
+		return newDriver(storageAddress, ro)
+	}
+
+	dialect, err := resolveSQLDialect(driver)
+	if err != nil {
+		return nil, err
+	}
 
-		// only migrate on RW connections
-		err = gormRW.AutoMigrate(&GraphData{})
+	driver = dialect.driverName
+	dialector := dialect.dialector
+
+	var dbrw *sql.DB
+	if len(storageAddress) > 0 {
+		gormRW, err := gorm.Open(dialector(storageAddress), &gorm.Config{})
 		if err != nil {
 			return nil, err
 		}
@@ -75,28 +141,49 @@ func Resolve(driver, storageAddress, storageReadOnlyAddress string) (Driver, err
 		if err != nil {
 			return nil, err
 		}
+
+		if driver == sqliteDriverName {
+			// sqlite allows a single writer, serialize writes rather than
+			// failing with "database is locked"
+			dbrw.SetMaxOpenConns(1)
+		} else {
+			pool.Apply(dbrw)
+		}
 	}
 
-	dbro := dbrw
-	if len(storageReadOnlyAddress) > 0 {
-		gormRO, err := gorm.Open(dialectorRO, &gorm.Config{})
+	rodbs := make([]*sqlx.DB, 0, len(readOnlyAddresses))
+	for _, address := range readOnlyAddresses {
+		gormRO, err := gorm.Open(dialector(address), &gorm.Config{})
 		if err != nil {
 			return nil, err
 		}
 
-		dbro, err = gormRO.DB()
+		dbro, err := gormRO.DB()
 		if err != nil {
 			return nil, err
 		}
+
+		if driver != sqliteDriverName {
+			pool.Apply(dbro)
+		}
+
+		rodbs = append(rodbs, sqlx.NewDb(dbro, driver))
 	}
 
-	if dbrw == nil && dbro == nil {
+	if dbrw == nil && len(rodbs) == 0 {
 		return nil, fmt.Errorf("must provide one storage address")
 	}
 
+	// without replicas, reads go to the primary
+	if len(rodbs) == 0 {
+		rodbs = append(rodbs, sqlx.NewDb(dbrw, driver))
+	}
+
 	return &sqlDriver{
 		rwdb:       sqlx.NewDb(dbrw, driver),
-		rodb:       sqlx.NewDb(dbro, driver),
-		statements: statements,
+		rodbs:      rodbs,
+		statements: dialect.statements,
+		maxRetries: dialect.maxRetries,
+		pool:       pool,
 	}, nil
 }
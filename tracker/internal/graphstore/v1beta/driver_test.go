@@ -49,3 +49,24 @@ func Test_Resolve_postgres_readonly(t *testing.T) {
 	_, err := v1beta.Resolve("postgres", "", "user:pass@localhost:5432/db")
 	require.Error(t, err)
 }
+
+func Test_Resolve_cockroachdb_readwrite(t *testing.T) {
+	_, err := v1beta.Resolve("cockroachdb", "postgresql://root@localhost:26257/db", "")
+	require.Error(t, err)
+}
+
+func Test_Resolve_cockroachdb_readonly(t *testing.T) {
+	_, err := v1beta.Resolve("cockroachdb", "", "postgresql://root@localhost:26257/db")
+	require.Error(t, err)
+}
+
+func Test_Resolve_sqlite_replicas(t *testing.T) {
+	_, err := v1beta.Resolve("sqlite", "file::memory:", "file::memory:?mode=ro", "file::memory:?mode=ro")
+	require.Nil(t, err)
+}
+
+func Test_Resolve_neo4j_replicas(t *testing.T) {
+	_, err := v1beta.Resolve("neo4j", "", "http://replica1:7474", "http://replica2:7474")
+	require.Error(t, err)
+	require.Equal(t, "the neo4j driver supports a single read only address", err.Error())
+}
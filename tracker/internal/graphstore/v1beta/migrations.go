@@ -0,0 +1,34 @@
+package v1beta
+
+import (
+	"github.com/depscloud/depscloud/tracker/internal/migrations"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SchemaName identifies the v1beta schema in the schema_migrations table.
+const SchemaName = "v1beta"
+
+// Migrations returns the versioned changes to the v1beta schema. Each
+// migration must be able to run against databases that were created before
+// migrations were introduced.
+func Migrations(statements *Statements) []*migrations.Migration {
+	return []*migrations.Migration{
+		{
+			Version:     1,
+			Description: "create graph_data",
+			Up:          []string{statements.CreateGraphDataTable},
+			Down:        []string{"DROP TABLE IF EXISTS graph_data"},
+		},
+	}
+}
+
+// NewMigrator returns a migrator for the v1beta schema of the sql driver.
+func NewMigrator(db *sqlx.DB, driver string) (*migrations.Migrator, error) {
+	dialect, err := resolveSQLDialect(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrations.NewMigrator(db, SchemaName, Migrations(dialect.statements)), nil
+}
@@ -0,0 +1,136 @@
+package v1beta_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/depscloud/tracker/internal/graphstore/v1beta"
+
+	"github.com/stretchr/testify/require"
+)
+
+type neo4jRequest struct {
+	Statements []struct {
+		Statement  string                 `json:"statement"`
+		Parameters map[string]interface{} `json:"parameters"`
+	} `json:"statements"`
+}
+
+func b64(key string) string {
+	return base64.StdEncoding.EncodeToString([]byte(key))
+}
+
+func newNeo4jServer(t *testing.T, rows [][]interface{}) (*httptest.Server, *[]neo4jRequest) {
+	requests := make([]neo4jRequest, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/db/graph/tx/commit", r.URL.Path)
+
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "neo4j", username)
+		require.Equal(t, "secret", password)
+
+		req := neo4jRequest{}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+
+		data := make([]map[string]interface{}, 0, len(rows))
+		for _, row := range rows {
+			data = append(data, map[string]interface{}{"row": row})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []interface{}{map[string]interface{}{"data": data}},
+			"errors":  []interface{}{},
+		})
+	}))
+
+	return server, &requests
+}
+
+func neo4jAddress(server *httptest.Server) string {
+	return strings.Replace(server.URL, "http://", "http://neo4j:secret@", 1) + "/graph"
+}
+
+func TestNeo4jDriver_Put(t *testing.T) {
+	server, requests := newNeo4jServer(t, nil)
+	defer server.Close()
+
+	driver, err := v1beta.Resolve("neo4j", neo4jAddress(server), "")
+	require.Nil(t, err)
+
+	// constraint
+	require.Len(t, *requests, 1)
+
+	err = driver.Put(context.Background(), []*v1beta.GraphData{
+		{K1: "a", K2: "a", Kind: "module", Encoding: v1beta.EncodingJSON, Data: "{}", LastModified: time.Now()},
+		{K1: "a", K2: "b", K3: "src", Kind: "depends", Encoding: v1beta.EncodingJSON, Data: "{}", LastModified: time.Now()},
+	})
+	require.Nil(t, err)
+
+	require.Len(t, *requests, 2)
+	put := (*requests)[1]
+	require.Len(t, put.Statements, 2)
+
+	nodes := put.Statements[0].Parameters["items"].([]interface{})
+	require.Len(t, nodes, 1)
+	require.Equal(t, b64("a"), nodes[0].(map[string]interface{})["k1"])
+
+	edges := put.Statements[1].Parameters["items"].([]interface{})
+	require.Len(t, edges, 1)
+	require.Equal(t, b64("b"), edges[0].(map[string]interface{})["k2"])
+	require.Equal(t, b64("src"), edges[0].(map[string]interface{})["k3"])
+}
+
+func TestNeo4jDriver_NeighborsFrom(t *testing.T) {
+	server, requests := newNeo4jServer(t, [][]interface{}{
+		{b64("b"), b64("b"), "", "module", 1, "{}", b64("a"), b64("b"), b64("src"), "depends", 1, "{}"},
+	})
+	defer server.Close()
+
+	driver, err := v1beta.Resolve("neo4j", "", neo4jAddress(server))
+	require.Nil(t, err)
+
+	require.Equal(t, api.ErrUnsupported, driver.Put(context.Background(), nil))
+
+	results, err := driver.NeighborsFrom(context.Background(), []string{"a"})
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+
+	require.Equal(t, "b", results[0].K1)
+	require.Equal(t, "b", results[0].K2)
+	require.Equal(t, "module", results[0].Kind)
+	require.Equal(t, v1beta.EncodingJSON, results[0].Encoding)
+
+	require.Equal(t, "a", results[1].K1)
+	require.Equal(t, "b", results[1].K2)
+	require.Equal(t, "src", results[1].K3)
+
+	query := (*requests)[0].Statements[0]
+	require.Equal(t, []interface{}{b64("a")}, query.Parameters["keys"])
+}
+
+func TestNeo4jDriver_errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []interface{}{},
+			"errors": []interface{}{map[string]interface{}{
+				"code":    "Neo.ClientError.Security.Unauthorized",
+				"message": "invalid credentials",
+			}},
+		})
+	}))
+	defer server.Close()
+
+	_, err := v1beta.Resolve("neo4j", server.URL, "")
+	require.Error(t, err)
+	require.Equal(t, "Neo.ClientError.Security.Unauthorized: invalid credentials", err.Error())
+}
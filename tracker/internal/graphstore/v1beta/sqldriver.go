@@ -2,31 +2,56 @@ package v1beta
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/depscloud/api"
+	"github.com/depscloud/depscloud/tracker/internal/sqlpool"
+	"github.com/depscloud/depscloud/tracker/internal/sqlretry"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type sqlDriver struct {
 	rwdb       *sqlx.DB
-	rodb       *sqlx.DB
+	rodbs      []*sqlx.DB
+	next       uint32
 	statements *Statements
+	maxRetries int
+	pool       *sqlpool.Config
 }
 
-func (s *sqlDriver) Put(ctx context.Context, items []*GraphData) error {
-	if s.rwdb == nil {
-		return api.ErrUnsupported
+// rodb returns the next read only connection, balancing reads across the
+// configured replicas.
+func (s *sqlDriver) rodb() *sqlx.DB {
+	if len(s.rodbs) == 1 {
+		return s.rodbs[0]
 	}
 
-	tx, err := s.rwdb.Beginx()
+	i := atomic.AddUint32(&s.next, 1)
+	return s.rodbs[int(i%uint32(len(s.rodbs)))]
+}
+
+// execAll executes the statement for every item within a single transaction.
+// Transactions aborted by a serialization failure are retried up to
+// maxRetries times with an exponential backoff.
+func (s *sqlDriver) execAll(ctx context.Context, statement string, items []*GraphData) error {
+	return sqlretry.Do(ctx, s.maxRetries, func() error {
+		return s.execAllOnce(ctx, statement, items)
+	})
+}
+
+func (s *sqlDriver) execAllOnce(ctx context.Context, statement string, items []*GraphData) error {
+	ctx, cancel := s.pool.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.rwdb.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
 	for _, item := range items {
-		_, err := tx.NamedExecContext(ctx, s.statements.InsertGraphData, item)
+		_, err := tx.NamedExecContext(ctx, statement, item)
 		if err != nil {
 			return err
 		}
@@ -35,29 +60,27 @@ func (s *sqlDriver) Put(ctx context.Context, items []*GraphData) error {
 	return tx.Commit()
 }
 
-func (s *sqlDriver) Delete(ctx context.Context, items []*GraphData) error {
+func (s *sqlDriver) Put(ctx context.Context, items []*GraphData) error {
 	if s.rwdb == nil {
 		return api.ErrUnsupported
 	}
 
-	tx, err := s.rwdb.Beginx()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
+	return s.execAll(ctx, s.statements.InsertGraphData, items)
+}
 
-	for _, item := range items {
-		_, err := tx.NamedExecContext(ctx, s.statements.DeleteGraphData, item)
-		if err != nil {
-			return err
-		}
+func (s *sqlDriver) Delete(ctx context.Context, items []*GraphData) error {
+	if s.rwdb == nil {
+		return api.ErrUnsupported
 	}
 
-	return tx.Commit()
+	return s.execAll(ctx, s.statements.DeleteGraphData, items)
 }
 
 func (s *sqlDriver) List(ctx context.Context, kind string, offset, limit int) ([]*GraphData, bool, error) {
-	rows, err := s.rodb.NamedQueryContext(ctx, s.statements.ListGraphData, map[string]interface{}{
+	ctx, cancel := s.pool.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.rodb().NamedQueryContext(ctx, s.statements.ListGraphData, map[string]interface{}{
 		"kind":   kind,
 		"offset": offset,
 		"limit":  limit + 1,
@@ -81,6 +104,9 @@ func (s *sqlDriver) List(ctx context.Context, kind string, offset, limit int) ([
 }
 
 func (s *sqlDriver) neighbors(ctx context.Context, statement string, keys []string) ([]*GraphData, error) {
+	ctx, cancel := s.pool.WithTimeout(ctx)
+	defer cancel()
+
 	query, args, err := sqlx.Named(statement, map[string]interface{}{
 		"keys": keys,
 	})
@@ -93,10 +119,12 @@ func (s *sqlDriver) neighbors(ctx context.Context, statement string, keys []stri
 		return nil, err
 	}
 
+	rodb := s.rodb()
+
 	// use DB specific bindvar type
-	query = s.rodb.Rebind(query)
+	query = rodb.Rebind(query)
 
-	rows, err := s.rodb.QueryxContext(ctx, query, args...)
+	rows, err := rodb.QueryxContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1,22 +1,91 @@
 package v1beta_test
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/depscloud/depscloud/tracker/internal/graphstore/v1beta"
 
+	"github.com/jmoiron/sqlx"
+
 	"github.com/stretchr/testify/require"
 )
 
+// migrate brings the schema of the database up to date.
+func migrate(t *testing.T, driver, address string) {
+	db, err := sqlx.Open("sqlite3", address)
+	require.Nil(t, err)
+	defer db.Close()
+
+	migrator, err := v1beta.NewMigrator(db, driver)
+	require.Nil(t, err)
+	require.Nil(t, migrator.Up(context.Background()))
+}
+
 func TestSQLDriver(t *testing.T) {
 	storageAddress := "sqldriver_test.db?cache=shared"
 	storageReadOnlyAddress := "sqldriver_test.db?cache=shared&mode=ro"
 
 	defer os.Remove("sqldriver_test.db")
 
+	migrate(t, "sqlite", storageAddress)
+
 	driver, err := v1beta.Resolve("sqlite", storageAddress, storageReadOnlyAddress)
 	require.Nil(t, err)
 
 	testServer(t, driver)
 }
+
+func TestSQLDriver_replicas(t *testing.T) {
+	storageAddress := "sqldriver_replicas_test.db?cache=shared"
+	storageReadOnlyAddress := "sqldriver_replicas_test.db?cache=shared&mode=ro"
+
+	defer os.Remove("sqldriver_replicas_test.db")
+
+	migrate(t, "sqlite", storageAddress)
+
+	driver, err := v1beta.Resolve("sqlite", storageAddress, storageReadOnlyAddress, storageReadOnlyAddress)
+	require.Nil(t, err)
+
+	testServer(t, driver)
+}
+
+func TestSQLDriver_concurrentWrites(t *testing.T) {
+	defer os.Remove("sqldriver_concurrent_test.db")
+
+	migrate(t, "sqlite", "sqldriver_concurrent_test.db")
+
+	driver, err := v1beta.Resolve("sqlite", "sqldriver_concurrent_test.db", "")
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			key := fmt.Sprintf("key%d", i)
+			errs <- driver.Put(ctx, []*v1beta.GraphData{
+				{K1: key, K2: key, K3: "", Kind: "module", LastModified: time.Now()},
+			})
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.Nil(t, err)
+	}
+
+	items, _, err := driver.List(ctx, "module", 0, 20)
+	require.Nil(t, err)
+	require.Len(t, items, 10)
+}
@@ -0,0 +1,19 @@
+package v1beta
+
+const cockroachDBInsertGraphData = `
+UPSERT INTO graph_data
+(k1, k2, k3, kind, encoding, data, date_deleted, last_modified)
+VALUES (:k1, :k2, :k3, :kind, :encoding, :data, NULL, :last_modified);
+`
+
+// CockroachDBStatements expose statements that are specific to the CockroachDB backend
+var CockroachDBStatements = &Statements{
+	CreateGraphDataTable: postgresqlCreateGraphDataTable,
+	InsertGraphData:      cockroachDBInsertGraphData,
+
+	// everything else is fine, no modifications required
+	DeleteGraphData:    sqliteDeleteGraphData,
+	ListGraphData:      sqliteListGraphData,
+	SelectToNeighbor:   sqliteSelectToNeighbor,
+	SelectFromNeighbor: sqliteSelectFromNeighbor,
+}
@@ -1,5 +1,23 @@
 package v1beta
 
+const mysqlCreateGraphDataTable = `
+CREATE TABLE IF NOT EXISTS graph_data (
+	k1 VARCHAR(64),
+	k2 VARCHAR(64),
+	k3 VARCHAR(64),
+	kind VARCHAR(55),
+	encoding TINYINT UNSIGNED,
+	data TEXT,
+	date_deleted DATETIME(3) NULL,
+	last_modified DATETIME(3) NULL,
+	PRIMARY KEY (k1, k2, k3),
+	KEY secondary (k2, k1, k3),
+	KEY kind (kind),
+	KEY date_deleted (date_deleted),
+	KEY last_modified (last_modified)
+);
+`
+
 const mysqlInsertGraphData = `
 INSERT INTO dts_graphdata 
 (graph_item_type, k1, k2, k3, encoding, graph_item_data, date_deleted, last_modified)
@@ -13,7 +31,8 @@ ON DUPLICATE KEY UPDATE
 
 // MySQLStatements expose statements that are specific to the mysql backend
 var MySQLStatements = &Statements{
-	InsertGraphData: mysqlInsertGraphData,
+	CreateGraphDataTable: mysqlCreateGraphDataTable,
+	InsertGraphData:      mysqlInsertGraphData,
 
 	// everything else is fine, no modifications required
 	DeleteGraphData:    sqliteDeleteGraphData,
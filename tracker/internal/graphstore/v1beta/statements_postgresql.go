@@ -1,5 +1,23 @@
 package v1beta
 
+const postgresqlCreateGraphDataTable = `
+CREATE TABLE IF NOT EXISTS graph_data (
+	k1 VARCHAR(64),
+	k2 VARCHAR(64),
+	k3 VARCHAR(64),
+	kind VARCHAR(55),
+	encoding SMALLINT,
+	data TEXT,
+	date_deleted TIMESTAMPTZ,
+	last_modified TIMESTAMPTZ,
+	PRIMARY KEY (k1, k2, k3)
+);
+CREATE INDEX IF NOT EXISTS secondary ON graph_data(k2, k1, k3);
+CREATE INDEX IF NOT EXISTS kind ON graph_data(kind);
+CREATE INDEX IF NOT EXISTS date_deleted ON graph_data(date_deleted);
+CREATE INDEX IF NOT EXISTS last_modified ON graph_data(last_modified);
+`
+
 const postgresqlInsertGraphData = `
 INSERT INTO graph_data 
 (k1, k2, k3, kind, encoding, data, date_deleted, last_modified)
@@ -15,7 +33,8 @@ DO UPDATE SET
 
 // PostgreSQLStatements expose statements that are specific to the PostgreSQL backend
 var PostgreSQLStatements = &Statements{
-	InsertGraphData: postgresqlInsertGraphData,
+	CreateGraphDataTable: postgresqlCreateGraphDataTable,
+	InsertGraphData:      postgresqlInsertGraphData,
 
 	// everything else is fine, no modifications required
 	DeleteGraphData:    sqliteDeleteGraphData,
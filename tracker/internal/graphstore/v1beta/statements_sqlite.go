@@ -1,5 +1,23 @@
 package v1beta
 
+const sqliteCreateGraphDataTable = `
+CREATE TABLE IF NOT EXISTS graph_data (
+	k1 VARCHAR(64),
+	k2 VARCHAR(64),
+	k3 VARCHAR(64),
+	kind VARCHAR(55),
+	encoding INTEGER,
+	data TEXT,
+	date_deleted DATETIME,
+	last_modified DATETIME,
+	PRIMARY KEY (k1, k2, k3)
+);
+CREATE INDEX IF NOT EXISTS secondary ON graph_data(k2, k1, k3);
+CREATE INDEX IF NOT EXISTS kind ON graph_data(kind);
+CREATE INDEX IF NOT EXISTS date_deleted ON graph_data(date_deleted);
+CREATE INDEX IF NOT EXISTS last_modified ON graph_data(last_modified);
+`
+
 const sqliteInsertGraphData = `
 REPLACE INTO graph_data
 (k1, k2, k3, kind, encoding, data, date_deleted, last_modified)
@@ -48,9 +66,10 @@ AND g1.date_deleted IS NULL;
 
 // SQLiteStatements expose statements that are specific to the SQLite backend
 var SQLiteStatements = &Statements{
-	InsertGraphData:    sqliteInsertGraphData,
-	DeleteGraphData:    sqliteDeleteGraphData,
-	ListGraphData:      sqliteListGraphData,
-	SelectToNeighbor:   sqliteSelectToNeighbor,
-	SelectFromNeighbor: sqliteSelectFromNeighbor,
+	CreateGraphDataTable: sqliteCreateGraphDataTable,
+	InsertGraphData:      sqliteInsertGraphData,
+	DeleteGraphData:      sqliteDeleteGraphData,
+	ListGraphData:        sqliteListGraphData,
+	SelectToNeighbor:     sqliteSelectToNeighbor,
+	SelectFromNeighbor:   sqliteSelectFromNeighbor,
 }
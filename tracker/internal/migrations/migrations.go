@@ -0,0 +1,237 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/depscloud/depscloud/internal/logging"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	name VARCHAR(64) NOT NULL,
+	version INTEGER NOT NULL,
+	dirty BOOLEAN NOT NULL,
+	PRIMARY KEY (name)
+)`
+
+const selectVersion = `SELECT version, dirty FROM schema_migrations WHERE name = ?`
+
+const deleteVersion = `DELETE FROM schema_migrations WHERE name = ?`
+
+const insertVersion = `INSERT INTO schema_migrations (name, version, dirty) VALUES (?, ?, ?)`
+
+// ErrDirty is returned when a previous migration failed part way through. The
+// schema needs to be repaired by hand before forcing the version.
+var ErrDirty = errors.New("schema is dirty, a previous migration failed")
+
+// Migration describes a change to the schema and how to revert it.
+type Migration struct {
+	Version     int
+	Description string
+	Up          []string
+	Down        []string
+}
+
+// Migrator applies versioned migrations to a database. The version of each
+// named schema is tracked in the schema_migrations table. Before a migration
+// runs, its version is recorded as dirty and the flag is only cleared once the
+// migration succeeds. Since some databases can't roll back schema changes, a
+// failure leaves the version dirty so it can be inspected.
+type Migrator struct {
+	db         *sqlx.DB
+	name       string
+	migrations []*Migration
+}
+
+// NewMigrator returns a migrator for the named schema. Migrations must be
+// sorted by version.
+func NewMigrator(db *sqlx.DB, name string, migrations []*Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		name:       name,
+		migrations: migrations,
+	}
+}
+
+// Latest returns the version of the last migration.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the current version of the schema and whether it's dirty.
+// Schemas that have never been migrated are at version 0.
+func (m *Migrator) Version(ctx context.Context) (int, bool, error) {
+	if _, err := m.db.ExecContext(ctx, createMigrationsTable); err != nil {
+		return 0, false, err
+	}
+
+	version, dirty := 0, false
+	err := m.db.QueryRowxContext(ctx, m.db.Rebind(selectVersion), m.name).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	return version, dirty, nil
+}
+
+func (m *Migrator) setVersion(ctx context.Context, version int, dirty bool) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.db.Rebind(deleteVersion), m.name); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, m.db.Rebind(insertVersion), m.name, version, dirty); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) run(ctx context.Context, statements []string) error {
+	for _, statement := range statements {
+		if _, err := m.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check returns an error when the schema is dirty or hasn't been migrated to
+// the latest version.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	} else if dirty {
+		return fmt.Errorf("%s: %w", m.name, ErrDirty)
+	} else if version < m.Latest() {
+		return fmt.Errorf("%s schema is at version %d, expected %d, run tracker migrate up", m.name, version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies all migrations after the current version.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the migration at the current version.
+func (m *Migrator) Down(ctx context.Context) error {
+	version, _, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	target := 0
+	for _, migration := range m.migrations {
+		if migration.Version < version {
+			target = migration.Version
+		}
+	}
+
+	return m.To(ctx, target)
+}
+
+// To applies or reverts migrations until the schema is at the target version.
+func (m *Migrator) To(ctx context.Context, target int) error {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	} else if dirty {
+		return fmt.Errorf("%s: %w", m.name, ErrDirty)
+	}
+
+	if target != 0 && m.find(target) == nil {
+		return fmt.Errorf("%s has no migration with version %d", m.name, target)
+	}
+
+	if target >= version {
+		for _, migration := range m.migrations {
+			if migration.Version <= version || migration.Version > target {
+				continue
+			}
+
+			logging.FromContext(ctx).Infof("[migrations] %s: applying %d %s", m.name, migration.Version, migration.Description)
+
+			if err := m.setVersion(ctx, migration.Version, true); err != nil {
+				return err
+			}
+
+			if err := m.run(ctx, migration.Up); err != nil {
+				return fmt.Errorf("%s: failed to apply %d: %w", m.name, migration.Version, err)
+			}
+
+			if err := m.setVersion(ctx, migration.Version, false); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version > version || migration.Version <= target {
+			continue
+		}
+
+		logging.FromContext(ctx).Infof("[migrations] %s: reverting %d %s", m.name, migration.Version, migration.Description)
+
+		if err := m.setVersion(ctx, migration.Version, true); err != nil {
+			return err
+		}
+
+		if err := m.run(ctx, migration.Down); err != nil {
+			return fmt.Errorf("%s: failed to revert %d: %w", m.name, migration.Version, err)
+		}
+
+		previous := 0
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+
+		if err := m.setVersion(ctx, previous, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Force records the schema as being at the version without running any
+// migrations. It's used to clear the dirty flag after repairing the schema.
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("%s has no migration with version %d", m.name, version)
+	}
+
+	if _, err := m.db.ExecContext(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+
+	return m.setVersion(ctx, version, false)
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package migrations_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/depscloud/depscloud/tracker/internal/migrations"
+
+	"github.com/jmoiron/sqlx"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = []*migrations.Migration{
+	{
+		Version:     1,
+		Description: "create a",
+		Up:          []string{"CREATE TABLE a (id INTEGER)"},
+		Down:        []string{"DROP TABLE a"},
+	},
+	{
+		Version:     2,
+		Description: "create b",
+		Up:          []string{"CREATE TABLE b (id INTEGER)"},
+		Down:        []string{"DROP TABLE b"},
+	},
+}
+
+func tableExists(t *testing.T, db *sqlx.DB, table string) bool {
+	count := 0
+	err := db.Get(&count, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table)
+	require.Nil(t, err)
+	return count > 0
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sqlx.Open("sqlite3", "file::memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	migrator := migrations.NewMigrator(db, "test", testMigrations)
+	require.Equal(t, 2, migrator.Latest())
+
+	version, dirty, err := migrator.Version(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 0, version)
+	require.False(t, dirty)
+	require.Error(t, migrator.Check(ctx))
+
+	// up
+	require.Nil(t, migrator.Up(ctx))
+	require.True(t, tableExists(t, db, "a"))
+	require.True(t, tableExists(t, db, "b"))
+	require.Nil(t, migrator.Check(ctx))
+
+	// running again is a noop
+	require.Nil(t, migrator.Up(ctx))
+
+	// down
+	require.Nil(t, migrator.Down(ctx))
+	require.True(t, tableExists(t, db, "a"))
+	require.False(t, tableExists(t, db, "b"))
+
+	version, _, err = migrator.Version(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, version)
+
+	require.Nil(t, migrator.To(ctx, 0))
+	require.False(t, tableExists(t, db, "a"))
+
+	require.Error(t, migrator.To(ctx, 3))
+}
+
+func TestMigrator_dirty(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sqlx.Open("sqlite3", "file::memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	// b already exists so the second migration fails
+	_, err = db.Exec("CREATE TABLE b (id INTEGER)")
+	require.Nil(t, err)
+
+	migrator := migrations.NewMigrator(db, "test", testMigrations)
+	require.Error(t, migrator.Up(ctx))
+
+	version, dirty, err := migrator.Version(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 2, version)
+	require.True(t, dirty)
+
+	require.True(t, errors.Is(migrator.Check(ctx), migrations.ErrDirty))
+	require.True(t, errors.Is(migrator.Up(ctx), migrations.ErrDirty))
+
+	// after the schema has been repaired by hand
+	require.Nil(t, migrator.Force(ctx, 2))
+	require.Nil(t, migrator.Check(ctx))
+}
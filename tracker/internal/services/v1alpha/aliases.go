@@ -0,0 +1,135 @@
+package v1alpha
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+
+	"github.com/ghodss/yaml"
+)
+
+// CanonicalModule identifies the module an alias resolves to. The language of
+// the alias is kept.
+type CanonicalModule struct {
+	Organization string `json:"organization"`
+	Module       string `json:"module"`
+	// Name replaces the name of the aliased module when set.
+	Name string `json:"name,omitempty"`
+}
+
+// Alias declares another name a module is known by, such as a vanity import
+// path, a renamed package, or a mirrored registry.
+type Alias struct {
+	Language     string           `json:"language"`
+	Organization string           `json:"organization"`
+	Module       string           `json:"module"`
+	Canonical    *CanonicalModule `json:"canonical"`
+}
+
+// Aliases resolves aliased modules to their canonical module so the graph
+// contains a single node for each of them. A nil Aliases resolves nothing.
+type Aliases struct {
+	index map[string]*CanonicalModule
+}
+
+func aliasKey(language, organization, module string) string {
+	return string(key(language, organization, module))
+}
+
+// NewAliases indexes the aliases. An alias can't resolve to another alias
+// since chains are easy to get wrong and hard to debug.
+func NewAliases(aliases []*Alias) (*Aliases, error) {
+	index := make(map[string]*CanonicalModule, len(aliases))
+
+	for _, alias := range aliases {
+		if alias.Language == "" || alias.Module == "" || alias.Canonical == nil || alias.Canonical.Module == "" {
+			return nil, fmt.Errorf("aliases require a language, module, and canonical module")
+		}
+
+		k := aliasKey(alias.Language, alias.Organization, alias.Module)
+		if _, ok := index[k]; ok {
+			return nil, fmt.Errorf("duplicate alias for %s/%s/%s", alias.Language, alias.Organization, alias.Module)
+		}
+
+		index[k] = alias.Canonical
+	}
+
+	for _, alias := range aliases {
+		canonical := alias.Canonical
+		if _, ok := index[aliasKey(alias.Language, canonical.Organization, canonical.Module)]; ok {
+			return nil, fmt.Errorf("alias for %s/%s/%s resolves to another alias", alias.Language, alias.Organization, alias.Module)
+		}
+	}
+
+	return &Aliases{index: index}, nil
+}
+
+// LoadAliasesFile loads an external yaml file containing a list of aliases
+// under the aliases key.
+func LoadAliasesFile(yamlFile string) (*Aliases, error) {
+	contents, err := ioutil.ReadFile(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+
+	file := struct {
+		Aliases []*Alias `json:"aliases"`
+	}{}
+
+	if err := yaml.Unmarshal(contents, &file); err != nil {
+		return nil, err
+	}
+
+	return NewAliases(file.Aliases)
+}
+
+func (a *Aliases) lookup(language, organization, module string) *CanonicalModule {
+	if a == nil {
+		return nil
+	}
+	return a.index[aliasKey(language, organization, module)]
+}
+
+// module returns the canonical module for the provided one. The provided
+// module is returned when it isn't an alias.
+func (a *Aliases) module(module *schema.Module) *schema.Module {
+	canonical := a.lookup(module.GetLanguage(), module.GetOrganization(), module.GetModule())
+	if canonical == nil {
+		return module
+	}
+
+	name := canonical.Name
+	if name == "" {
+		name = module.GetName()
+	}
+
+	return &schema.Module{
+		Language:     module.GetLanguage(),
+		Organization: canonical.Organization,
+		Module:       canonical.Module,
+		Name:         name,
+	}
+}
+
+// request returns a request for the canonical module of the provided one.
+// The provided request is returned when it isn't for an alias.
+func (a *Aliases) request(req *tracker.DependencyRequest) *tracker.DependencyRequest {
+	canonical := a.lookup(req.GetLanguage(), req.GetOrganization(), req.GetModule())
+	if canonical == nil {
+		return req
+	}
+
+	name := canonical.Name
+	if name == "" {
+		name = req.GetName()
+	}
+
+	return &tracker.DependencyRequest{
+		Language:     req.GetLanguage(),
+		Organization: canonical.Organization,
+		Module:       canonical.Module,
+		Name:         name,
+	}
+}
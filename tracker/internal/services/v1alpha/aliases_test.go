@@ -0,0 +1,106 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/deps"
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAliases(t *testing.T) {
+	canonical := &CanonicalModule{Organization: "depscloud", Module: "b"}
+
+	invalid := [][]*Alias{
+		{{Language: "go", Organization: "depscloud", Module: "old-b"}},
+		{{Language: "go", Module: "old-b", Canonical: &CanonicalModule{}}},
+		{
+			{Language: "go", Organization: "depscloud", Module: "old-b", Canonical: canonical},
+			{Language: "go", Organization: "depscloud", Module: "old-b", Canonical: canonical},
+		},
+		{
+			{Language: "go", Organization: "depscloud", Module: "old-b", Canonical: canonical},
+			{Language: "go", Organization: "depscloud", Module: "older-b", Canonical: &CanonicalModule{Organization: "depscloud", Module: "old-b"}},
+		},
+	}
+
+	for _, aliases := range invalid {
+		_, err := NewAliases(aliases)
+		require.NotNil(t, err)
+	}
+
+	aliases, err := NewAliases([]*Alias{
+		{Language: "go", Organization: "depscloud", Module: "old-b", Canonical: canonical},
+	})
+	require.Nil(t, err)
+
+	module := &schema.Module{Language: "go", Organization: "depscloud", Module: "a", Name: "a"}
+	require.Equal(t, module, aliases.module(module))
+	require.Equal(t, &schema.Module{Language: "go", Organization: "depscloud", Module: "b", Name: "old-b"},
+		aliases.module(&schema.Module{Language: "go", Organization: "depscloud", Module: "old-b", Name: "old-b"}))
+
+	// aliases are scoped to a language
+	module = &schema.Module{Language: "java", Organization: "depscloud", Module: "old-b"}
+	require.Equal(t, module, aliases.module(module))
+
+	var none *Aliases
+	require.Equal(t, module, none.module(module))
+}
+
+func TestTrackAliases(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"b": {"c"},
+	})
+
+	aliases, err := NewAliases([]*Alias{
+		{Language: "go", Organization: "depscloud", Module: "old-b", Canonical: &CanonicalModule{Organization: "depscloud", Module: "b"}},
+	})
+	require.Nil(t, err)
+
+	sources := &sourceService{gs: gs, aliases: aliases}
+	_, err = sources.Track(context.Background(), &tracker.SourceRequest{
+		Source: &schema.Source{Url: "https://github.com/depscloud/a.git"},
+		ManagementFiles: []*deps.DependencyManagementFile{
+			{
+				Language:     proto.String("go"),
+				System:       proto.String("vgo"),
+				Organization: proto.String("depscloud"),
+				Module:       proto.String("a"),
+				Dependencies: []*deps.Dependency{
+					{
+						Organization:      proto.String("depscloud"),
+						Module:            proto.String("old-b"),
+						VersionConstraint: proto.String("v1.0.0"),
+					},
+				},
+			},
+		},
+	})
+	require.Nil(t, err)
+
+	// the dependency was recorded against the canonical module
+	_, ok := gs.nodes[string(moduleKey("old-b"))]
+	require.False(t, ok)
+
+	server := http.NewServeMux()
+	RegisterQueryService(server, gs, 5, aliases)
+
+	for _, module := range []string{"b", "old-b"} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/v1alpha/queries/transitive-dependents?language=go&organization=depscloud&module="+module, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		response := &TransitiveDependentsResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		require.Equal(t, map[string]int{"a": 1}, reachedDepths(response.Dependents), module)
+	}
+}
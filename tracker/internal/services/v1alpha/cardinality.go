@@ -0,0 +1,49 @@
+package v1alpha
+
+import (
+	"context"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var graphItems = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "tracker_graph_items",
+	Help: "The number of items in the graph by type and language, as of the last count.",
+}, []string{"type", "language"})
+
+// RunCardinalityMetrics periodically counts the items in the graph and exports
+// them as metrics. Counting scans the graph, so it's done on an interval rather
+// than on every scrape. It runs until the context is canceled.
+func RunCardinalityMetrics(ctx context.Context, cardinality graphstore.Cardinality, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		recordCardinality(ctx, cardinality)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func recordCardinality(ctx context.Context, cardinality graphstore.Cardinality) {
+	counts, err := cardinality.CountItems(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[service.cardinality] failed to count items: %s", err.Error())
+		return
+	}
+
+	// combinations that no longer exist shouldn't keep reporting old counts
+	graphItems.Reset()
+	for _, count := range counts {
+		graphItems.WithLabelValues(count.GraphItemType, count.Language).Set(float64(count.Count))
+	}
+}
@@ -0,0 +1,178 @@
+package v1alpha
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of a webhook body when
+// the channel is configured with a secret.
+const SignatureHeader = "X-Depscloud-Signature"
+
+// Channel delivers notifications.
+type Channel interface {
+	Send(ctx context.Context, event *Event) error
+}
+
+var notificationClient = &http.Client{Timeout: 30 * time.Second}
+
+// sign returns the value of the SignatureHeader for the body.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func post(ctx context.Context, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := notificationClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded with %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+// SlackChannel posts the message of an event to a Slack incoming webhook.
+type SlackChannel struct {
+	WebhookURL string `json:"webhookURL"`
+}
+
+func (s *SlackChannel) Send(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(map[string]string{"text": event.Message()})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.WebhookURL, body, nil)
+}
+
+// WebhookChannel posts events as json to a url. When a secret is configured,
+// the body is signed so receivers can verify its origin.
+type WebhookChannel struct {
+	URL     string            `json:"url"`
+	Secret  string            `json:"secret,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// WebhookPayload is the body of webhook notifications.
+type WebhookPayload struct {
+	*Event
+	Message string `json:"message"`
+}
+
+func (w *WebhookChannel) Send(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(&WebhookPayload{Event: event, Message: event.Message()})
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(w.Headers)+1)
+	for key, value := range w.Headers {
+		headers[key] = value
+	}
+
+	if w.Secret != "" {
+		headers[SignatureHeader] = sign(w.Secret, body)
+	}
+
+	return post(ctx, w.URL, body, headers)
+}
+
+// EmailChannel mails events through an SMTP server. Credentials are only
+// used when a username is provided.
+type EmailChannel struct {
+	Address  string   `json:"address"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+func (e *EmailChannel) message(event *Event) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "From: %s\r\n", e.From)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(buf, "Subject: [depscloud] %s\r\n", event.Subject())
+	fmt.Fprintf(buf, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(buf, "%s\r\n", event.Message())
+	return buf.Bytes()
+}
+
+func (e *EmailChannel) Send(ctx context.Context, event *Event) error {
+	return e.send(e.message(event))
+}
+
+// send mails the message to each of the recipients.
+func (e *EmailChannel) send(message []byte) error {
+	var auth smtp.Auth
+	if e.Username != "" {
+		host, _, err := net.SplitHostPort(e.Address)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	return smtp.SendMail(e.Address, auth, e.From, e.To, message)
+}
+
+// ChannelConfig configures a single kind of channel.
+type ChannelConfig struct {
+	Slack   *SlackChannel   `json:"slack,omitempty"`
+	Email   *EmailChannel   `json:"email,omitempty"`
+	Webhook *WebhookChannel `json:"webhook,omitempty"`
+}
+
+// channel returns the configured channel, ensuring exactly one is set.
+func (c *ChannelConfig) channel() (Channel, error) {
+	channels := make([]Channel, 0, 1)
+
+	if c.Slack != nil {
+		if c.Slack.WebhookURL == "" {
+			return nil, fmt.Errorf("slack channels require a webhookURL")
+		}
+		channels = append(channels, c.Slack)
+	}
+
+	if c.Email != nil {
+		if c.Email.Address == "" || c.Email.From == "" || len(c.Email.To) == 0 {
+			return nil, fmt.Errorf("email channels require an address, from, and to")
+		}
+		channels = append(channels, c.Email)
+	}
+
+	if c.Webhook != nil {
+		if c.Webhook.URL == "" {
+			return nil, fmt.Errorf("webhook channels require a url")
+		}
+		channels = append(channels, c.Webhook)
+	}
+
+	if len(channels) != 1 {
+		return nil, fmt.Errorf("exactly one of slack, email, or webhook must be configured")
+	}
+	return channels[0], nil
+}
@@ -0,0 +1,312 @@
+package v1alpha
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/ghodss/yaml"
+)
+
+// The statuses of a license, from best to worst. Unknown licenses belong to
+// dependencies without a license label.
+const (
+	LicenseAllowed = "allowed"
+	LicenseUnknown = "unknown"
+	LicenseFlagged = "flagged"
+	LicenseDenied  = "denied"
+)
+
+var licenseSeverity = map[string]int{
+	LicenseAllowed: 0,
+	LicenseUnknown: 1,
+	LicenseFlagged: 2,
+	LicenseDenied:  3,
+}
+
+// worseStatus returns the worse of the two statuses.
+func worseStatus(a, b string) string {
+	if licenseSeverity[b] > licenseSeverity[a] {
+		return b
+	}
+	return a
+}
+
+// LicenseRules lists the licenses that are allowed, denied, and flagged for
+// review. When licenses are allowed, any other license is denied. Licenses
+// are matched ignoring case.
+type LicenseRules struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+	Flag  []string `json:"flag,omitempty"`
+}
+
+func containsLicense(licenses []string, license string) bool {
+	for _, l := range licenses {
+		if strings.EqualFold(l, license) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateID evaluates a single license identifier.
+func (r *LicenseRules) evaluateID(license string) (string, string) {
+	switch {
+	case containsLicense(r.Deny, license):
+		return LicenseDenied, license + " is denied"
+	case containsLicense(r.Flag, license):
+		return LicenseFlagged, license + " is flagged for review"
+	case containsLicense(r.Allow, license):
+		return LicenseAllowed, ""
+	case len(r.Allow) > 0:
+		return LicenseDenied, license + " is not allowed"
+	}
+	return LicenseAllowed, ""
+}
+
+var (
+	orOperator  = regexp.MustCompile(`(?i)\s+OR\s+`)
+	andOperator = regexp.MustCompile(`(?i)\s+AND\s+`)
+)
+
+// evaluate evaluates an SPDX license expression. A choice between licenses
+// (OR) is as good as its best option, while a combination (AND) is as bad as
+// its worst license. Parentheses are ignored, so AND binds tighter than OR.
+func (r *LicenseRules) evaluate(expression string) (string, string) {
+	expression = strings.TrimSpace(strings.NewReplacer("(", " ", ")", " ").Replace(expression))
+	if expression == "" {
+		return LicenseUnknown, "no license was found"
+	}
+
+	best, bestReason := "", ""
+	for _, alternative := range orOperator.Split(expression, -1) {
+		worst, worstReason := LicenseAllowed, ""
+		for _, license := range andOperator.Split(alternative, -1) {
+			status, reason := r.evaluateID(strings.TrimSpace(license))
+			if worseStatus(worst, status) != worst {
+				worst, worstReason = status, reason
+			}
+		}
+
+		if best == "" || licenseSeverity[worst] < licenseSeverity[best] {
+			best, bestReason = worst, worstReason
+		}
+	}
+
+	return best, bestReason
+}
+
+// LicensePolicy decides which licenses the dependencies of a module may use.
+// The top level rules apply to every organization without rules of its own.
+type LicensePolicy struct {
+	// Label is the module label holding the license, defaulting to license.
+	Label string `json:"label,omitempty"`
+
+	LicenseRules
+
+	Organizations map[string]*LicenseRules `json:"organizations,omitempty"`
+}
+
+// LoadLicensePolicyFile loads an external yaml file containing a license
+// policy. For example:
+//
+//	deny: [AGPL-3.0]
+//	flag: [LGPL-2.1]
+//	organizations:
+//	  depscloud:
+//	    allow: [MIT, Apache-2.0]
+func LoadLicensePolicyFile(yamlFile string) (*LicensePolicy, error) {
+	contents, err := ioutil.ReadFile(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &LicensePolicy{}
+	if err := yaml.Unmarshal(contents, policy); err != nil {
+		return nil, err
+	}
+
+	if policy.Label == "" {
+		policy.Label = "license"
+	}
+
+	for organization, rules := range policy.Organizations {
+		if rules == nil {
+			return nil, fmt.Errorf("organization %s has no license rules", organization)
+		}
+	}
+
+	return policy, nil
+}
+
+// rules returns the rules of the organization.
+func (p *LicensePolicy) rules(organization string) *LicenseRules {
+	if rules, ok := p.Organizations[organization]; ok {
+		return rules
+	}
+	return &p.LicenseRules
+}
+
+// LicenseFinding is the license of a dependency and how the policy treats it.
+type LicenseFinding struct {
+	Dependency *schema.Module `json:"dependency"`
+	License    string         `json:"license"`
+	Status     string         `json:"status"`
+	Reason     string         `json:"reason,omitempty"`
+}
+
+// ComplianceEvaluation evaluates the licenses of a module's dependencies
+// using the rules of the module's organization. Its status is the worst
+// status of its findings.
+type ComplianceEvaluation struct {
+	Module   *schema.Module    `json:"module"`
+	Status   string            `json:"status"`
+	Findings []*LicenseFinding `json:"findings"`
+}
+
+// evaluateModules evaluates each of the modules, which are indexed by key.
+// Evaluations are returned in the order of their module's name.
+func evaluateModules(
+	ctx context.Context,
+	gs store.GraphStoreClient,
+	labels graphstore.Labels,
+	policy *LicensePolicy,
+	modules map[string]*schema.Module,
+) ([]*ComplianceEvaluation, error) {
+	keys := make([][]byte, 0, len(modules))
+	for key := range modules {
+		keys = append(keys, []byte(key))
+	}
+
+	pairs, err := findPairs(ctx, gs.FindUpstream, keys, types.DependsType, types.ModuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencyKeys := make([][]byte, 0)
+	seen := make(map[string]bool)
+	for _, pair := range pairs {
+		if key := pair.GetNode().GetK1(); !seen[string(key)] {
+			seen[string(key)] = true
+			dependencyKeys = append(dependencyKeys, key)
+		}
+	}
+
+	licenses := make(map[string]string, len(dependencyKeys))
+	for start := 0; start < len(dependencyKeys); start += traversalBatchSize {
+		end := start + traversalBatchSize
+		if end > len(dependencyKeys) {
+			end = len(dependencyKeys)
+		}
+
+		batch, err := labels.GetLabels(ctx, types.ModuleType, dependencyKeys[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for key, moduleLabels := range batch {
+			licenses[key] = moduleLabels[policy.Label]
+		}
+	}
+
+	evaluations := make(map[string]*ComplianceEvaluation, len(modules))
+	for key, module := range modules {
+		evaluations[key] = &ComplianceEvaluation{
+			Module:   module,
+			Status:   LicenseAllowed,
+			Findings: make([]*LicenseFinding, 0),
+		}
+	}
+
+	for _, pair := range pairs {
+		evaluation, ok := evaluations[string(pair.GetEdge().GetK1())]
+		if !ok {
+			continue
+		}
+
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			return nil, err
+		}
+
+		license := licenses[string(pair.GetNode().GetK1())]
+		status, reason := policy.rules(evaluation.Module.GetOrganization()).evaluate(license)
+
+		evaluation.Status = worseStatus(evaluation.Status, status)
+		evaluation.Findings = append(evaluation.Findings, &LicenseFinding{
+			Dependency: node.(*schema.Module),
+			License:    license,
+			Status:     status,
+			Reason:     reason,
+		})
+	}
+
+	results := make([]*ComplianceEvaluation, 0, len(evaluations))
+	for _, evaluation := range evaluations {
+		results = append(results, evaluation)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return moduleName(results[i].Module) < moduleName(results[j].Module)
+	})
+
+	return results, nil
+}
+
+// ComplianceReport summarizes the compliance of the modules in the graph.
+type ComplianceReport struct {
+	Modules int `json:"modules"`
+	// Statuses counts the modules by their status.
+	Statuses map[string]int `json:"statuses"`
+	// Licenses counts the denied and flagged findings by license.
+	Licenses map[string]int `json:"licenses"`
+	// Organizations counts the modules with denied or flagged findings by
+	// organization.
+	Organizations map[string]int `json:"organizations"`
+	// Violations are the modules with denied or flagged findings, keeping
+	// only those findings.
+	Violations []*ComplianceEvaluation `json:"violations"`
+}
+
+// summarize reports on the evaluations.
+func summarize(evaluations []*ComplianceEvaluation) *ComplianceReport {
+	report := &ComplianceReport{
+		Modules:       len(evaluations),
+		Statuses:      make(map[string]int),
+		Licenses:      make(map[string]int),
+		Organizations: make(map[string]int),
+		Violations:    make([]*ComplianceEvaluation, 0),
+	}
+
+	for _, evaluation := range evaluations {
+		report.Statuses[evaluation.Status]++
+
+		violations := make([]*LicenseFinding, 0)
+		for _, finding := range evaluation.Findings {
+			if finding.Status == LicenseDenied || finding.Status == LicenseFlagged {
+				violations = append(violations, finding)
+				report.Licenses[finding.License]++
+			}
+		}
+
+		if len(violations) > 0 {
+			report.Organizations[evaluation.Module.GetOrganization()]++
+			report.Violations = append(report.Violations, &ComplianceEvaluation{
+				Module:   evaluation.Module,
+				Status:   evaluation.Status,
+				Findings: violations,
+			})
+		}
+	}
+
+	return report
+}
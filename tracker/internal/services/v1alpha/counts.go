@@ -0,0 +1,34 @@
+package v1alpha
+
+import (
+	"context"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+)
+
+// RunCountRebuild periodically recomputes the dependent and dependency counts
+// of modules. Counts are updated as edges are written, so rebuilding only
+// corrects drift and can run infrequently. It runs until the context is
+// canceled.
+func RunCountRebuild(ctx context.Context, rebuilder graphstore.CountRebuilder, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		if err := rebuilder.RebuildCounts(ctx); err != nil {
+			logging.FromContext(ctx).Errorf("[service.counts] failed to rebuild counts: %s", err.Error())
+			continue
+		}
+
+		logging.FromContext(ctx).Infof("[service.counts] rebuilt counts in %s", time.Since(start))
+	}
+}
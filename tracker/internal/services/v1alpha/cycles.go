@@ -0,0 +1,62 @@
+package v1alpha
+
+import (
+	"context"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/logging"
+)
+
+// Cycle is a group of modules that depend on one another, directly or
+// indirectly.
+type Cycle struct {
+	Modules []*schema.Module `json:"modules"`
+}
+
+// detectCycles finds the cycles between the modules matching the filter.
+func detectCycles(ctx context.Context, gs store.GraphStoreClient, filter *filters.Filter) ([]*Cycle, error) {
+	graph, err := loadModuleGraph(ctx, gs, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	cycles := make([]*Cycle, 0)
+	for _, keys := range graph.cycles() {
+		cycles = append(cycles, &Cycle{Modules: graph.modulesFor(keys)})
+	}
+
+	return cycles, nil
+}
+
+// RunCycleDetection periodically checks the modules matching the filter for
+// cycles and logs the ones it finds. It runs until the context is canceled.
+func RunCycleDetection(ctx context.Context, gs store.GraphStoreClient, filter *filters.Filter, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cycles, err := detectCycles(ctx, gs, filter)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[service.query] failed to detect cycles: %s", err.Error())
+			continue
+		}
+
+		logging.FromContext(ctx).Infof("[service.query] found %d dependency cycles", len(cycles))
+		for _, cycle := range cycles {
+			names := make([]string, len(cycle.Modules))
+			for i, module := range cycle.Modules {
+				names[i] = moduleName(module)
+			}
+			logging.FromContext(ctx).Warnf("[service.query] dependency cycle between %v", names)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package v1alpha
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/registries"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// EnrichmentReport summarizes a pass of the registry enrichment.
+type EnrichmentReport struct {
+	Modules    int
+	Enriched   int
+	Deprecated int
+	Failed     int
+}
+
+// mergeLabels replaces the labels of a node starting with the prefix, leaving
+// the labels set by others alone.
+func mergeLabels(current map[string]string, prefix string, labels map[string]string) map[string]string {
+	merged := make(map[string]string, len(current)+len(labels))
+	for key, value := range current {
+		if !strings.HasPrefix(key, prefix) {
+			merged[key] = value
+		}
+	}
+
+	for key, value := range labels {
+		merged[key] = value
+	}
+	return merged
+}
+
+// labelsChanged returns true when the merged labels differ from the current.
+func labelsChanged(current, merged map[string]string) bool {
+	if len(current) == 0 && len(merged) == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(current, merged)
+}
+
+// enrichModules looks up each module in the registry it's published to and
+// records the metadata of its latest release in the module's labels. Modules
+// that can't be looked up keep the metadata from the last pass.
+func enrichModules(ctx context.Context, gs store.GraphStoreClient, labels graphstore.Labels, client *registries.Client) (*EnrichmentReport, error) {
+	modules, err := listModules(ctx, gs, &filters.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([][]byte, 0, len(modules))
+	for key := range modules {
+		keys = append(keys, []byte(key))
+	}
+
+	current, err := labels.GetLabels(ctx, types.ModuleType, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &EnrichmentReport{}
+	for key, module := range modules {
+		if !registries.Supported(module.GetLanguage()) {
+			continue
+		}
+		report.Modules++
+
+		metadata, err := client.Lookup(ctx, module)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("[service.registry] failed to look up %s: %s", moduleName(module), err.Error())
+			report.Failed++
+			continue
+		}
+
+		if metadata != nil {
+			report.Enriched++
+			if metadata.Deprecated {
+				report.Deprecated++
+			}
+		}
+
+		var registryLabels map[string]string
+		if metadata != nil {
+			registryLabels = metadata.Labels()
+		}
+
+		merged := mergeLabels(current[key], registries.LabelPrefix, registryLabels)
+		if !labelsChanged(current[key], merged) {
+			continue
+		}
+
+		if err := labels.SetLabels(ctx, types.ModuleType, []byte(key), merged); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// RunRegistryEnrichment periodically records the latest published version,
+// publish date, and deprecation status of each module in its labels. It runs
+// until the context is canceled.
+func RunRegistryEnrichment(ctx context.Context, gs store.GraphStoreClient, labels graphstore.Labels, client *registries.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := enrichModules(ctx, gs, labels, client)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[service.registry] failed to enrich modules: %s", err.Error())
+			continue
+		}
+
+		logging.FromContext(ctx).Infof("[service.registry] modules=%d enriched=%d deprecated=%d failed=%d",
+			report.Modules, report.Enriched, report.Deprecated, report.Failed)
+	}
+}
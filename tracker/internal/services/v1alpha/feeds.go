@@ -0,0 +1,474 @@
+package v1alpha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/constraints"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+)
+
+// DefaultGHSAURL is the GitHub GraphQL api serving security advisories.
+const DefaultGHSAURL = "https://api.github.com/graphql"
+
+// FeedAdvisory is an advisory along with the packages it affects.
+type FeedAdvisory struct {
+	Advisory *graphstore.Advisory
+	Affected []*AffectedPackage
+}
+
+// AffectedPackage is a package and the versions of it an advisory affects.
+// A version is affected when it's listed or satisfies the ranges.
+type AffectedPackage struct {
+	Package  *OSVPackage
+	Ranges   constraints.Constraint
+	Versions []string
+}
+
+// affects reports whether the version of the package is affected.
+func (a *AffectedPackage) affects(version string) bool {
+	for _, v := range a.Versions {
+		if constraints.Compare(v, version) == 0 {
+			return true
+		}
+	}
+	return len(a.Ranges) > 0 && a.Ranges.Satisfies(version)
+}
+
+// AdvisoryFeed is a source of advisories that's synced in full, such as the
+// GitHub advisory database or a local mirror of one.
+type AdvisoryFeed interface {
+	// Name identifies the source of the advisories in the vulnerability
+	// store.
+	Name() string
+
+	// Advisories returns every advisory published by the feed.
+	Advisories(ctx context.Context) ([]*FeedAdvisory, error)
+}
+
+var pypiSeparators = regexp.MustCompile(`[-_.]+`)
+
+// packageID identifies a package within its ecosystem. Ecosystems that treat
+// names as case insensitive are normalized so a name matches however it was
+// written.
+func packageID(pkg *OSVPackage) string {
+	name := pkg.Name
+
+	switch pkg.Ecosystem {
+	case "PyPI":
+		name = pypiSeparators.ReplaceAllString(strings.ToLower(name), "-")
+	case "NuGet", "Packagist":
+		name = strings.ToLower(name)
+	}
+
+	return pkg.Ecosystem + sep + name
+}
+
+// osvDocument is an advisory in the OSV schema, as published by the GitHub
+// advisory database and the OSV exports.
+type osvDocument struct {
+	osvVulnerability
+	Withdrawn *time.Time `json:"withdrawn"`
+	Affected  []struct {
+		Package OSVPackage `json:"package"`
+		Ranges  []struct {
+			Type   string              `json:"type"`
+			Events []map[string]string `json:"events"`
+		} `json:"ranges"`
+		Versions []string `json:"versions"`
+	} `json:"affected"`
+}
+
+// rangesFromEvents converts OSV events into ranges. Each introduced event
+// opens a range that's closed by the next fixed or last_affected event. Git
+// ranges are skipped since commits can't be compared with versions.
+func rangesFromEvents(rangeType string, events []map[string]string) constraints.Constraint {
+	if rangeType == "GIT" {
+		return nil
+	}
+
+	result := make(constraints.Constraint, 0)
+	var open constraints.Range
+
+	for _, event := range events {
+		if introduced, ok := event["introduced"]; ok {
+			open = constraints.Range{}
+			if introduced != "0" {
+				open = append(open, constraints.Comparator{Operator: constraints.GreaterThanOrEqual, Version: introduced})
+			}
+		} else if open == nil {
+			continue
+		} else if fixed, ok := event["fixed"]; ok {
+			result = append(result, append(open, constraints.Comparator{Operator: constraints.LessThan, Version: fixed}))
+			open = nil
+		} else if last, ok := event["last_affected"]; ok {
+			result = append(result, append(open, constraints.Comparator{Operator: constraints.LessThanOrEqual, Version: last}))
+			open = nil
+		}
+	}
+
+	if open != nil {
+		// a range without any bounds affects every version
+		if len(open) == 0 {
+			open = constraints.Range{{Operator: constraints.GreaterThanOrEqual, Version: "0"}}
+		}
+		result = append(result, open)
+	}
+
+	return result
+}
+
+// feedAdvisory converts the document, returning nil when it was withdrawn.
+func (d *osvDocument) feedAdvisory() *FeedAdvisory {
+	if d.Withdrawn != nil {
+		return nil
+	}
+
+	advisory := &FeedAdvisory{Advisory: d.advisory()}
+	for _, affected := range d.Affected {
+		pkg := &AffectedPackage{
+			Package:  &OSVPackage{Ecosystem: affected.Package.Ecosystem, Name: affected.Package.Name},
+			Versions: affected.Versions,
+		}
+
+		for _, r := range affected.Ranges {
+			pkg.Ranges = append(pkg.Ranges, rangesFromEvents(r.Type, r.Events)...)
+		}
+
+		advisory.Affected = append(advisory.Affected, pkg)
+	}
+
+	return advisory
+}
+
+// MirrorFeed reads advisories in the OSV schema from a directory, such as a
+// clone of the GitHub advisory database or an unpacked OSV export. It allows
+// advisories to be synced without access to the internet.
+type MirrorFeed struct {
+	Path string
+}
+
+func (m *MirrorFeed) Name() string {
+	return "mirror"
+}
+
+func (m *MirrorFeed) Advisories(ctx context.Context) ([]*FeedAdvisory, error) {
+	advisories := make([]*FeedAdvisory, 0)
+
+	err := filepath.Walk(m.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		} else if info.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		document := &osvDocument{}
+		if err := json.Unmarshal(data, document); err != nil {
+			return fmt.Errorf("failed to read advisory %s: %v", path, err)
+		}
+
+		if advisory := document.feedAdvisory(); advisory != nil && document.ID != "" {
+			advisories = append(advisories, advisory)
+		}
+		return nil
+	})
+
+	return advisories, err
+}
+
+// ghsaEcosystems maps the ecosystems of the GitHub advisory database to the
+// ones used by OSV.
+var ghsaEcosystems = map[string]string{
+	"GO":       "Go",
+	"NPM":      "npm",
+	"MAVEN":    "Maven",
+	"RUST":     "crates.io",
+	"COMPOSER": "Packagist",
+	"PIP":      "PyPI",
+	"RUBYGEMS": "RubyGems",
+	"NUGET":    "NuGet",
+	"ACTIONS":  "GitHub Actions",
+	"ERLANG":   "Hex",
+	"PUB":      "Pub",
+	"SWIFT":    "SwiftURL",
+}
+
+const ghsaQuery = `query($after: String) {
+  securityVulnerabilities(first: 100, after: $after) {
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      package { ecosystem name }
+      vulnerableVersionRange
+      advisory {
+        ghsaId
+        summary
+        severity
+        updatedAt
+        withdrawnAt
+        identifiers { type value }
+      }
+    }
+  }
+}`
+
+type ghsaResponse struct {
+	Data struct {
+		SecurityVulnerabilities struct {
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+			Nodes []struct {
+				Package struct {
+					Ecosystem string `json:"ecosystem"`
+					Name      string `json:"name"`
+				} `json:"package"`
+				VulnerableVersionRange string `json:"vulnerableVersionRange"`
+				Advisory               struct {
+					GHSAID      string     `json:"ghsaId"`
+					Summary     string     `json:"summary"`
+					Severity    string     `json:"severity"`
+					UpdatedAt   time.Time  `json:"updatedAt"`
+					WithdrawnAt *time.Time `json:"withdrawnAt"`
+					Identifiers []struct {
+						Type  string `json:"type"`
+						Value string `json:"value"`
+					} `json:"identifiers"`
+				} `json:"advisory"`
+			} `json:"nodes"`
+		} `json:"securityVulnerabilities"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// GHSAFeed reads the GitHub advisory database using the GraphQL api, which
+// requires a token.
+type GHSAFeed struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// NewGHSAFeed returns a feed reading from the GraphQL api at the url.
+func NewGHSAFeed(url, token string) *GHSAFeed {
+	return &GHSAFeed{
+		URL:    url,
+		Token:  token,
+		Client: &http.Client{Timeout: time.Minute},
+	}
+}
+
+func (g *GHSAFeed) Name() string {
+	return "ghsa"
+}
+
+func (g *GHSAFeed) page(ctx context.Context, after string) (*ghsaResponse, error) {
+	variables := map[string]interface{}{"after": nil}
+	if after != "" {
+		variables["after"] = after
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     ghsaQuery,
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+g.Token)
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github responded with %s", resp.Status)
+	}
+
+	response := &ghsaResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return nil, err
+	}
+
+	if len(response.Errors) > 0 {
+		return nil, fmt.Errorf("github responded with an error: %s", response.Errors[0].Message)
+	}
+
+	return response, nil
+}
+
+func (g *GHSAFeed) Advisories(ctx context.Context) ([]*FeedAdvisory, error) {
+	advisories := make([]*FeedAdvisory, 0)
+	byID := make(map[string]*FeedAdvisory)
+
+	for after := ""; ; {
+		response, err := g.page(ctx, after)
+		if err != nil {
+			return nil, err
+		}
+
+		vulnerabilities := response.Data.SecurityVulnerabilities
+		for _, node := range vulnerabilities.Nodes {
+			ecosystem, ok := ghsaEcosystems[node.Package.Ecosystem]
+			if !ok || node.Advisory.WithdrawnAt != nil {
+				continue
+			}
+
+			ranges, err := constraints.Parse("", node.VulnerableVersionRange)
+			if err != nil {
+				logging.FromContext(ctx).Warnf("[service.vulnerability] skipping %s, unable to read range %q",
+					node.Advisory.GHSAID, node.VulnerableVersionRange)
+				continue
+			}
+
+			advisory, ok := byID[node.Advisory.GHSAID]
+			if !ok {
+				aliases := make([]string, 0)
+				for _, identifier := range node.Advisory.Identifiers {
+					if identifier.Value != node.Advisory.GHSAID {
+						aliases = append(aliases, identifier.Value)
+					}
+				}
+
+				advisory = &FeedAdvisory{
+					Advisory: &graphstore.Advisory{
+						ID:       node.Advisory.GHSAID,
+						Aliases:  aliases,
+						Summary:  node.Advisory.Summary,
+						Severity: node.Advisory.Severity,
+						Modified: node.Advisory.UpdatedAt,
+					},
+				}
+				byID[advisory.Advisory.ID] = advisory
+				advisories = append(advisories, advisory)
+			}
+
+			advisory.Affected = append(advisory.Affected, &AffectedPackage{
+				Package: &OSVPackage{Ecosystem: ecosystem, Name: node.Package.Name},
+				Ranges:  ranges,
+			})
+		}
+
+		if !vulnerabilities.PageInfo.HasNextPage {
+			break
+		}
+		after = vulnerabilities.PageInfo.EndCursor
+	}
+
+	return advisories, nil
+}
+
+// matchFeed records the advisories of the feed affecting each version that's
+// depended on.
+func matchFeed(ctx context.Context, vulnerabilities graphstore.Vulnerabilities, targets []*scanTarget, feed AdvisoryFeed, nvd *NVDClient) (*ScanReport, error) {
+	advisories, err := feed.Advisories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type affecting struct {
+		advisory *graphstore.Advisory
+		pkg      *AffectedPackage
+	}
+
+	index := make(map[string][]*affecting)
+	for _, advisory := range advisories {
+		for _, pkg := range advisory.Affected {
+			id := packageID(pkg.Package)
+			index[id] = append(index[id], &affecting{advisory: advisory.Advisory, pkg: pkg})
+		}
+	}
+
+	report := &ScanReport{Versions: len(targets)}
+	matched := make(map[string]bool)
+
+	for _, target := range targets {
+		found := make([]*graphstore.Advisory, 0)
+		seen := make(map[string]bool)
+
+		for _, candidate := range index[packageID(target.pkg)] {
+			if seen[candidate.advisory.ID] || !candidate.pkg.affects(osvVersion(target.pkg, target.version)) {
+				continue
+			}
+			seen[candidate.advisory.ID] = true
+
+			if !matched[candidate.advisory.ID] {
+				matched[candidate.advisory.ID] = true
+				nvd.enrich(ctx, candidate.advisory)
+			}
+			found = append(found, candidate.advisory)
+		}
+
+		if err := vulnerabilities.SetAdvisories(ctx, feed.Name(), target.key, target.version, found); err != nil {
+			return nil, err
+		}
+
+		if len(found) > 0 {
+			report.Affected++
+		}
+	}
+
+	report.Advisories = len(matched)
+	return report, nil
+}
+
+// RunFeedSync periodically syncs the advisories of each feed against the
+// versions of modules that are depended on. It runs until the context is
+// canceled.
+func RunFeedSync(ctx context.Context, gs store.GraphStoreClient, vulnerabilities graphstore.Vulnerabilities, feeds []AdvisoryFeed, nvd *NVDClient, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		targets, err := dependedVersions(ctx, gs)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[service.vulnerability] failed to list versions: %s", err.Error())
+			continue
+		}
+
+		for _, feed := range feeds {
+			report, err := matchFeed(ctx, vulnerabilities, targets, feed, nvd)
+			if err != nil {
+				logging.FromContext(ctx).Errorf("[service.vulnerability] failed to sync %s: %s", feed.Name(), err.Error())
+				continue
+			}
+
+			logging.FromContext(ctx).Infof("[service.vulnerability] feed=%s versions=%d affected=%d advisories=%d",
+				feed.Name(), report.Versions, report.Affected, report.Advisories)
+		}
+	}
+}
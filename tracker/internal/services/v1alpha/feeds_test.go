@@ -0,0 +1,129 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangesFromEvents(t *testing.T) {
+	ranges := rangesFromEvents("SEMVER", []map[string]string{
+		{"introduced": "0"},
+		{"fixed": "1.0.2"},
+		{"introduced": "1.1.0"},
+		{"last_affected": "1.1.3"},
+		{"introduced": "2.0.0"},
+	})
+	require.Equal(t, "<1.0.2 || >=1.1.0 <=1.1.3 || >=2.0.0", ranges.String())
+
+	require.True(t, ranges.Satisfies("1.0.1"))
+	require.False(t, ranges.Satisfies("1.0.2"))
+	require.True(t, ranges.Satisfies("1.1.3"))
+	require.False(t, ranges.Satisfies("1.2.0"))
+	require.True(t, ranges.Satisfies("2.3.0"))
+
+	require.Nil(t, rangesFromEvents("GIT", []map[string]string{{"introduced": "0"}}))
+}
+
+func TestFeeds(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "advisories")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "go"), 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "go", "GHSA-0002.json"), []byte(`{
+		"id": "GHSA-0002",
+		"aliases": ["CVE-2021-0002"],
+		"modified": "2021-03-01T00:00:00Z",
+		"affected": [{
+			"package": {"ecosystem": "Go", "name": "depscloud/b"},
+			"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.0.1"}]}]
+		}]
+	}`), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "go", "GHSA-0003.json"), []byte(`{
+		"id": "GHSA-0003",
+		"withdrawn": "2021-04-01T00:00:00Z",
+		"affected": [{"package": {"ecosystem": "Go", "name": "depscloud/d"}, "versions": ["1.0.0"]}]
+	}`), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# advisories"), 0644))
+
+	ghsa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "bearer token", r.Header.Get("Authorization"))
+
+		request := struct {
+			Variables map[string]interface{} `json:"variables"`
+		}{}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+
+		if request.Variables["after"] == nil {
+			_, _ = w.Write([]byte(`{"data": {"securityVulnerabilities": {
+				"pageInfo": {"hasNextPage": true, "endCursor": "page-2"},
+				"nodes": [{
+					"package": {"ecosystem": "GO", "name": "depscloud/d"},
+					"vulnerableVersionRange": ">= 0.9.0, < 1.2.0",
+					"advisory": {"ghsaId": "GHSA-0004", "summary": "path traversal", "updatedAt": "2021-03-01T00:00:00Z",
+						"identifiers": [{"type": "GHSA", "value": "GHSA-0004"}, {"type": "CVE", "value": "CVE-2021-0004"}]}
+				}]
+			}}}`))
+			return
+		}
+
+		require.Equal(t, "page-2", request.Variables["after"])
+		_, _ = w.Write([]byte(`{"data": {"securityVulnerabilities": {
+			"pageInfo": {"hasNextPage": false},
+			"nodes": [{
+				"package": {"ecosystem": "GO", "name": "depscloud/b"},
+				"vulnerableVersionRange": "< 0.5.0",
+				"advisory": {"ghsaId": "GHSA-0005", "severity": "LOW", "updatedAt": "2021-03-01T00:00:00Z"}
+			}]
+		}}}`))
+	}))
+	defer ghsa.Close()
+
+	nvd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "CVE-2021-0004", r.URL.Query().Get("cveId"))
+		_, _ = w.Write([]byte(`{"vulnerabilities": [{"cve": {"id": "CVE-2021-0004", "metrics": {
+			"cvssMetricV31": [{"cvssData": {"baseSeverity": "CRITICAL"}}]
+		}}}]}`))
+	}))
+	defer nvd.Close()
+
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b"},
+		"c": {"b", "d"},
+	})
+
+	targets, err := dependedVersions(ctx, gs)
+	require.Nil(t, err)
+	require.Len(t, targets, 2)
+
+	vulnerabilities := fakeVulnerabilities{}
+
+	report, err := matchFeed(ctx, vulnerabilities, targets, &MirrorFeed{Path: dir}, nil)
+	require.Nil(t, err)
+	require.Equal(t, &ScanReport{Versions: 2, Affected: 1, Advisories: 1}, report)
+	require.Equal(t, "GHSA-0002", vulnerabilities["mirror"][string(moduleKey("b"))]["v1.0.0"][0].ID)
+	require.Len(t, vulnerabilities["mirror"][string(moduleKey("d"))]["v1.0.0"], 0)
+
+	report, err = matchFeed(ctx, vulnerabilities, targets, NewGHSAFeed(ghsa.URL, "token"), NewNVDClient(nvd.URL, ""))
+	require.Nil(t, err)
+	require.Equal(t, &ScanReport{Versions: 2, Affected: 1, Advisories: 1}, report)
+
+	advisories := vulnerabilities["ghsa"][string(moduleKey("d"))]["v1.0.0"]
+	require.Len(t, advisories, 1)
+	require.Equal(t, "GHSA-0004", advisories[0].ID)
+	require.Equal(t, []string{"CVE-2021-0004"}, advisories[0].Aliases)
+	require.Equal(t, "CRITICAL", advisories[0].Severity)
+
+	// each source is synced separately
+	require.Len(t, vulnerabilities["mirror"][string(moduleKey("b"))]["v1.0.0"], 1)
+}
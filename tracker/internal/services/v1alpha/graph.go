@@ -0,0 +1,315 @@
+package v1alpha
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// moduleListCount is the number of modules requested per page while loading a
+// module graph.
+const moduleListCount = 500
+
+// moduleGraph holds a set of modules and the depends edges between them.
+// Edges to modules outside of the set are dropped.
+type moduleGraph struct {
+	keys    []string
+	modules map[string]*schema.Module
+	edges   map[string][]string
+}
+
+// loadModuleGraph loads the modules matching the filter along with the edges
+// between them.
+func loadModuleGraph(ctx context.Context, gs store.GraphStoreClient, filter *filters.Filter) (*moduleGraph, error) {
+	modules, err := listModules(ctx, gs, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &moduleGraph{
+		keys:    make([]string, 0, len(modules)),
+		modules: modules,
+		edges:   make(map[string][]string),
+	}
+
+	for key := range modules {
+		graph.keys = append(graph.keys, key)
+	}
+
+	keys := make([][]byte, len(graph.keys))
+	for i, key := range graph.keys {
+		keys[i] = []byte(key)
+	}
+
+	pairs, err := findPairs(ctx, gs.FindUpstream, keys, types.DependsType, types.ModuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pair := range pairs {
+		from, to := string(pair.GetEdge().GetK1()), string(pair.GetNode().GetK1())
+		if _, ok := graph.modules[to]; !ok {
+			continue
+		}
+
+		graph.edges[from] = append(graph.edges[from], to)
+	}
+
+	sort.Strings(graph.keys)
+	return graph, nil
+}
+
+// listModules returns the modules matching the filter by key.
+func listModules(ctx context.Context, gs store.GraphStoreClient, filter *filters.Filter) (map[string]*schema.Module, error) {
+	modules := make(map[string]*schema.Module)
+
+	listCtx := filter.AppendToOutgoingContext(ctx)
+	for page := int32(1); ; page++ {
+		resp, err := gs.List(listCtx, &store.ListRequest{
+			Page:  page,
+			Count: moduleListCount,
+			Type:  types.ModuleType,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range resp.GetItems() {
+			module, err := Decode(item)
+			if err != nil {
+				return nil, err
+			}
+
+			key := string(item.GetK1())
+			if _, ok := modules[key]; !ok {
+				modules[key] = module.(*schema.Module)
+			}
+		}
+
+		if len(resp.GetItems()) < moduleListCount {
+			break
+		}
+	}
+
+	return modules, nil
+}
+
+// stronglyConnected returns the strongly connected components of the graph
+// using Tarjan's algorithm. Components are returned in reverse topological
+// order, so a component only depends on the ones before it.
+func (g *moduleGraph) stronglyConnected() [][]string {
+	index := 0
+	indices := make(map[string]int)
+	lowlinks := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	components := make([][]string, 0)
+
+	var connect func(key string)
+	connect = func(key string) {
+		indices[key] = index
+		lowlinks[key] = index
+		index++
+
+		stack = append(stack, key)
+		onStack[key] = true
+
+		for _, next := range g.edges[key] {
+			if _, visited := indices[next]; !visited {
+				connect(next)
+				if lowlinks[next] < lowlinks[key] {
+					lowlinks[key] = lowlinks[next]
+				}
+			} else if onStack[next] && indices[next] < lowlinks[key] {
+				lowlinks[key] = indices[next]
+			}
+		}
+
+		if lowlinks[key] != indices[key] {
+			return
+		}
+
+		component := make([]string, 0)
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+
+			component = append(component, last)
+			if last == key {
+				break
+			}
+		}
+
+		sort.Strings(component)
+		components = append(components, component)
+	}
+
+	for _, key := range g.keys {
+		if _, visited := indices[key]; !visited {
+			connect(key)
+		}
+	}
+
+	return components
+}
+
+// cycles returns the groups of modules that depend on one another.
+func (g *moduleGraph) cycles() [][]string {
+	cycles := make([][]string, 0)
+
+	for _, component := range g.stronglyConnected() {
+		if len(component) > 1 {
+			cycles = append(cycles, component)
+			continue
+		}
+
+		// a module that depends on itself
+		for _, next := range g.edges[component[0]] {
+			if next == component[0] {
+				cycles = append(cycles, component)
+				break
+			}
+		}
+	}
+
+	return cycles
+}
+
+func (g *moduleGraph) modulesFor(keys []string) []*schema.Module {
+	modules := make([]*schema.Module, len(keys))
+	for i, key := range keys {
+		modules[i] = g.modules[key]
+	}
+	return modules
+}
+
+// topological orders the modules so every module comes after the modules it
+// depends on. Modules are assigned a stage one greater than the largest stage
+// of their dependencies, so modules within a stage don't depend on each other.
+// Ties are broken by module name to keep the order stable. The second return value is
+// false when the graph contains a cycle and no order exists.
+func (g *moduleGraph) topological() ([]string, map[string]int, bool) {
+	dependents := make(map[string][]string)
+	remaining := make(map[string]int, len(g.keys))
+
+	for _, key := range g.keys {
+		seen := make(map[string]bool)
+		for _, next := range g.edges[key] {
+			if seen[next] {
+				continue
+			}
+			seen[next] = true
+
+			dependents[next] = append(dependents[next], key)
+			remaining[key]++
+		}
+	}
+
+	ready := make([]string, 0)
+	for _, key := range g.keys {
+		if remaining[key] == 0 {
+			ready = append(ready, key)
+		}
+	}
+
+	order := make([]string, 0, len(g.keys))
+	stages := make(map[string]int, len(g.keys))
+
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool {
+			return g.name(ready[i]) < g.name(ready[j])
+		})
+		key := ready[0]
+		ready = ready[1:]
+
+		order = append(order, key)
+		for _, dependent := range dependents[key] {
+			if stages[key]+1 > stages[dependent] {
+				stages[dependent] = stages[key] + 1
+			}
+
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	return order, stages, len(order) == len(g.keys)
+}
+
+// dependentCounts returns the number of modules that depend on each module,
+// directly and transitively. Modules in a cycle are transitive dependents of
+// one another. Reachability is computed over the strongly connected components
+// so each component's dependents are only collected once.
+func (g *moduleGraph) dependentCounts() (map[string]int, map[string]int) {
+	dependents := make(map[string]map[string]bool, len(g.keys))
+	for _, key := range g.keys {
+		for _, next := range g.edges[key] {
+			if next == key {
+				continue
+			}
+
+			if dependents[next] == nil {
+				dependents[next] = make(map[string]bool)
+			}
+			dependents[next][key] = true
+		}
+	}
+
+	components := g.stronglyConnected()
+	componentOf := make(map[string]int, len(g.keys))
+	for i, component := range components {
+		for _, key := range component {
+			componentOf[key] = i
+		}
+	}
+
+	direct := make(map[string]int, len(g.keys))
+	transitive := make(map[string]int, len(g.keys))
+
+	// components only depend on the ones before them, so walking backwards
+	// visits every dependent component before the components it depends on
+	reached := make([]*big.Int, len(components))
+	for i := len(components) - 1; i >= 0; i-- {
+		reached[i] = new(big.Int)
+
+		for _, key := range components[i] {
+			for dependent := range dependents[key] {
+				if j := componentOf[dependent]; j != i {
+					reached[i].SetBit(reached[i], j, 1)
+					reached[i].Or(reached[i], reached[j])
+				}
+			}
+		}
+
+		count := len(components[i]) - 1
+		for j := range components {
+			if reached[i].Bit(j) == 1 {
+				count += len(components[j])
+			}
+		}
+
+		for _, key := range components[i] {
+			direct[key] = len(dependents[key])
+			transitive[key] = count
+		}
+	}
+
+	return direct, transitive
+}
+
+// name returns a readable name for the module with the given key.
+func (g *moduleGraph) name(key string) string {
+	return moduleName(g.modules[key])
+}
+
+func moduleName(module *schema.Module) string {
+	return module.GetLanguage() + "/" + module.GetOrganization() + "/" + module.GetModule()
+}
@@ -0,0 +1,343 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/constraints"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/ghodss/yaml"
+)
+
+// The statuses of a module nearing the end of its life.
+const (
+	LifecycleDeprecated = "deprecated"
+	LifecycleEndOfLife  = "eol"
+)
+
+// The labels recording the lifecycle of a module. Modules with a lifecycle can
+// be found using the selector lifecycle.status.
+const (
+	LifecycleLabelPrefix      = "lifecycle."
+	LifecycleLabelStatus      = LifecycleLabelPrefix + "status"
+	LifecycleLabelEndOfLife   = LifecycleLabelPrefix + "eol"
+	LifecycleLabelReplacement = LifecycleLabelPrefix + "replacement"
+	LifecycleLabelVersions    = LifecycleLabelPrefix + "versions"
+)
+
+// lifecycleDate is the layout of end of life dates.
+const lifecycleDate = "2006-01-02"
+
+// DefaultEndOfLifeURL is the public endoflife.date api.
+const DefaultEndOfLifeURL = "https://endoflife.date"
+
+// Lifecycle marks a module as deprecated or reaching its end of life, along
+// with the module that should be used instead.
+type Lifecycle struct {
+	Status string `json:"status"`
+	// EndOfLife is the date support ends, written as YYYY-MM-DD. Modules
+	// reaching their end of life without a date have already reached it.
+	EndOfLife   string `json:"eol,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+	// Versions is a constraint limiting the lifecycle to some versions of the
+	// module, such as <2.0.0. Every version is affected when it's empty.
+	Versions string `json:"versions,omitempty"`
+}
+
+// Validate ensures the lifecycle can be recorded.
+func (l *Lifecycle) Validate() error {
+	if l.Status != LifecycleDeprecated && l.Status != LifecycleEndOfLife {
+		return fmt.Errorf("status must be one of %s or %s", LifecycleDeprecated, LifecycleEndOfLife)
+	}
+
+	if l.EndOfLife != "" {
+		if _, err := time.Parse(lifecycleDate, l.EndOfLife); err != nil {
+			return fmt.Errorf("eol must be a date written as YYYY-MM-DD")
+		}
+	}
+
+	if l.Versions != "" {
+		if _, err := constraints.Parse("", l.Versions); err != nil {
+			return fmt.Errorf("invalid versions: %v", err)
+		}
+	}
+
+	return graphstore.ValidateLabels(l.labels())
+}
+
+// labels encodes the lifecycle as the labels of a module.
+func (l *Lifecycle) labels() map[string]string {
+	labels := map[string]string{LifecycleLabelStatus: l.Status}
+	if l.EndOfLife != "" {
+		labels[LifecycleLabelEndOfLife] = l.EndOfLife
+	}
+	if l.Replacement != "" {
+		labels[LifecycleLabelReplacement] = l.Replacement
+	}
+	if l.Versions != "" {
+		labels[LifecycleLabelVersions] = l.Versions
+	}
+	return labels
+}
+
+// lifecycle reads the lifecycle recorded in the labels of a module, or nil
+// when the module doesn't have one.
+func lifecycle(labels map[string]string) *Lifecycle {
+	status, ok := labels[LifecycleLabelStatus]
+	if !ok {
+		return nil
+	}
+
+	return &Lifecycle{
+		Status:      status,
+		EndOfLife:   labels[LifecycleLabelEndOfLife],
+		Replacement: labels[LifecycleLabelReplacement],
+		Versions:    labels[LifecycleLabelVersions],
+	}
+}
+
+// ended returns true when the module is deprecated or its end of life is on
+// or before the date.
+func (l *Lifecycle) ended(date time.Time) bool {
+	if l.Status != LifecycleEndOfLife || l.EndOfLife == "" {
+		return true
+	}
+	return l.EndOfLife <= date.Format(lifecycleDate)
+}
+
+// affects returns true when the version falls under the lifecycle. Versions
+// that can't be determined are assumed to be affected.
+func (l *Lifecycle) affects(version string) bool {
+	if l.Versions == "" || version == "" {
+		return true
+	}
+
+	parsed, err := constraints.Parse("", l.Versions)
+	if err != nil {
+		return true
+	}
+	return parsed.Satisfies(version)
+}
+
+// EndOfLifeCycle is a release cycle of a product tracked by endoflife.date.
+// The eol field is either a date or a boolean.
+type EndOfLifeCycle struct {
+	Cycle string      `json:"cycle"`
+	EOL   interface{} `json:"eol"`
+}
+
+// ended returns whether the cycle's support ended by the date along with the
+// date it ended, when known.
+func (c *EndOfLifeCycle) ended(date time.Time) (bool, string) {
+	switch eol := c.EOL.(type) {
+	case bool:
+		return eol, ""
+	case string:
+		return eol <= date.Format(lifecycleDate), eol
+	}
+	return false, ""
+}
+
+// EndOfLifeModule identifies a module that's part of a product.
+type EndOfLifeModule struct {
+	Language     string `json:"language"`
+	Organization string `json:"organization"`
+	Module       string `json:"module"`
+}
+
+// EndOfLifeProduct maps a product tracked by endoflife.date to the modules
+// it's published as.
+type EndOfLifeProduct struct {
+	Product     string             `json:"product"`
+	Replacement string             `json:"replacement,omitempty"`
+	Modules     []*EndOfLifeModule `json:"modules"`
+}
+
+// EndOfLifeConfig lists the products whose release cycles are imported.
+type EndOfLifeConfig struct {
+	Products []*EndOfLifeProduct `json:"products"`
+}
+
+// LoadEndOfLifeFile loads an external yaml file listing the products to
+// import from endoflife.date. For example:
+//
+//	products:
+//	- product: django
+//	  modules:
+//	  - language: python
+//	    module: django
+func LoadEndOfLifeFile(yamlFile string) (*EndOfLifeConfig, error) {
+	contents, err := ioutil.ReadFile(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &EndOfLifeConfig{}
+	if err := yaml.Unmarshal(contents, config); err != nil {
+		return nil, err
+	}
+
+	for _, product := range config.Products {
+		if product.Product == "" || len(product.Modules) == 0 {
+			return nil, fmt.Errorf("products require a name and at least one module")
+		}
+	}
+
+	return config, nil
+}
+
+// EndOfLifeClient reads the release cycles of products from endoflife.date.
+type EndOfLifeClient struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewEndOfLifeClient returns a client for the endoflife.date api at the url.
+func NewEndOfLifeClient(baseURL string) *EndOfLifeClient {
+	return &EndOfLifeClient{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Cycles returns the release cycles of the product.
+func (c *EndOfLifeClient) Cycles(ctx context.Context, product string) ([]*EndOfLifeCycle, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/api/"+url.PathEscape(product)+".json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("endoflife.date responded with %s for %s", resp.Status, product)
+	}
+
+	cycles := make([]*EndOfLifeCycle, 0)
+	if err := json.NewDecoder(resp.Body).Decode(&cycles); err != nil {
+		return nil, err
+	}
+	return cycles, nil
+}
+
+// cycleLifecycle derives the lifecycle of a product from its release cycles.
+// When some cycles are still supported, the lifecycle is limited to the
+// versions before the oldest of them. Nil is returned while every cycle is
+// supported.
+func cycleLifecycle(cycles []*EndOfLifeCycle, date time.Time) *Lifecycle {
+	oldestSupported := ""
+	for _, cycle := range cycles {
+		if ended, _ := cycle.ended(date); !ended {
+			if oldestSupported == "" || constraints.Compare(cycle.Cycle, oldestSupported) < 0 {
+				oldestSupported = cycle.Cycle
+			}
+		}
+	}
+
+	result := &Lifecycle{Status: LifecycleEndOfLife}
+	matched := false
+
+	for _, cycle := range cycles {
+		ended, eol := cycle.ended(date)
+		if !ended || (oldestSupported != "" && constraints.Compare(cycle.Cycle, oldestSupported) > 0) {
+			continue
+		}
+
+		matched = true
+		if eol > result.EndOfLife {
+			result.EndOfLife = eol
+		}
+	}
+
+	if !matched {
+		return nil
+	}
+
+	if oldestSupported != "" {
+		result.Versions = "<" + oldestSupported
+	}
+	return result
+}
+
+// syncEndOfLife imports the release cycles of each product and records the
+// lifecycle of its modules. It returns the number of modules reaching their
+// end of life.
+func syncEndOfLife(ctx context.Context, labels graphstore.Labels, client *EndOfLifeClient, config *EndOfLifeConfig, aliases *Aliases) (int, error) {
+	ended := 0
+	date := time.Now()
+
+	for _, product := range config.Products {
+		cycles, err := client.Cycles(ctx, product.Product)
+		if err != nil {
+			return ended, err
+		}
+
+		var lifecycleLabels map[string]string
+		if result := cycleLifecycle(cycles, date); result != nil {
+			result.Replacement = product.Replacement
+			lifecycleLabels = result.labels()
+			ended += len(product.Modules)
+		}
+
+		for _, module := range product.Modules {
+			key := keyForDependencyRequest(aliases.request(&tracker.DependencyRequest{
+				Language:     module.Language,
+				Organization: module.Organization,
+				Module:       module.Module,
+			}))
+
+			current, err := labels.GetLabels(ctx, types.ModuleType, [][]byte{key})
+			if err != nil {
+				return ended, err
+			}
+
+			merged := mergeLabels(current[string(key)], LifecycleLabelPrefix, lifecycleLabels)
+			if !labelsChanged(current[string(key)], merged) {
+				continue
+			}
+
+			if err := labels.SetLabels(ctx, types.ModuleType, key, merged); err != nil {
+				return ended, err
+			}
+		}
+	}
+
+	return ended, nil
+}
+
+// RunEndOfLifeSync periodically imports the release cycles of the configured
+// products from endoflife.date. It runs until the context is canceled.
+func RunEndOfLifeSync(ctx context.Context, labels graphstore.Labels, client *EndOfLifeClient, config *EndOfLifeConfig, aliases *Aliases, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ended, err := syncEndOfLife(ctx, labels, client, config, aliases)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[service.lifecycle] failed to sync endoflife.date: %s", err.Error())
+			continue
+		}
+
+		logging.FromContext(ctx).Infof("[service.lifecycle] products=%d ended=%d", len(config.Products), ended)
+	}
+}
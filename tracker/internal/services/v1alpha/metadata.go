@@ -0,0 +1,14 @@
+package v1alpha
+
+import (
+	"context"
+
+	"github.com/depscloud/depscloud/internal/asof"
+	"github.com/depscloud/depscloud/internal/filters"
+)
+
+// forwardContext passes the filter and as of metadata of a request along to
+// the graph store.
+func forwardContext(ctx context.Context) context.Context {
+	return asof.ForwardContext(filters.ForwardContext(ctx))
+}
@@ -0,0 +1,526 @@
+package v1alpha
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/tenants"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/ghodss/yaml"
+)
+
+// The triggers a subscription can fire on.
+const (
+	// TriggerDependentAdded fires when a module starts depending on a watched
+	// module.
+	TriggerDependentAdded = "dependent_added"
+	// TriggerDependencyRemoved fires when a module stops depending on
+	// another. It's delivered to subscriptions watching either module or
+	// owning the source of the module.
+	TriggerDependencyRemoved = "dependency_removed"
+	// TriggerVulnerability fires when a new advisory affects a version of a
+	// module that's depended on. It's delivered to subscriptions watching
+	// the affected module or owning the source of a dependent.
+	TriggerVulnerability = "vulnerability"
+)
+
+var triggers = map[string]bool{
+	TriggerDependentAdded:    true,
+	TriggerDependencyRemoved: true,
+	TriggerVulnerability:     true,
+}
+
+// Event is a change to the graph that subscriptions may be notified about.
+type Event struct {
+	Trigger string `json:"trigger"`
+	Tenant  string `json:"tenant,omitempty"`
+	// Source manages the dependent.
+	Source *schema.Source `json:"source,omitempty"`
+	// Module is the module that's depended on.
+	Module            *schema.Module       `json:"module"`
+	Dependent         *schema.Module       `json:"dependent"`
+	VersionConstraint string               `json:"versionConstraint,omitempty"`
+	Version           string               `json:"version,omitempty"`
+	Advisory          *graphstore.Advisory `json:"advisory,omitempty"`
+}
+
+// Subject briefly describes the event.
+func (e *Event) Subject() string {
+	switch e.Trigger {
+	case TriggerDependentAdded:
+		return "new dependent of " + moduleName(e.Module)
+	case TriggerDependencyRemoved:
+		return moduleName(e.Dependent) + " removed " + moduleName(e.Module)
+	case TriggerVulnerability:
+		return e.Advisory.ID + " affects " + moduleName(e.Module)
+	}
+	return e.Trigger
+}
+
+// Message describes the event.
+func (e *Event) Message() string {
+	message := ""
+	switch e.Trigger {
+	case TriggerDependentAdded:
+		message = fmt.Sprintf("%s now depends on %s", moduleName(e.Dependent), moduleName(e.Module))
+		if e.VersionConstraint != "" {
+			message += " " + e.VersionConstraint
+		}
+	case TriggerDependencyRemoved:
+		message = fmt.Sprintf("%s no longer depends on %s", moduleName(e.Dependent), moduleName(e.Module))
+	case TriggerVulnerability:
+		message = e.Advisory.ID
+		if e.Advisory.Severity != "" {
+			message += " (" + e.Advisory.Severity + ")"
+		}
+		message += fmt.Sprintf(" affects %s %s, which %s depends on", moduleName(e.Module), e.Version, moduleName(e.Dependent))
+		if e.Advisory.Summary != "" {
+			message += ": " + e.Advisory.Summary
+		}
+	default:
+		message = e.Trigger
+	}
+
+	if e.Source != nil {
+		message += " (" + e.Source.GetUrl() + ")"
+	}
+	return message
+}
+
+// Subscription decides which events a team is notified about and the
+// channels they're delivered to. Modules are watched by their
+// language/organization/module name. Sources are owned when listed or, when
+// labels are available, when labeled with the team.
+type Subscription struct {
+	Team     string   `json:"team"`
+	Tenant   string   `json:"tenant,omitempty"`
+	Triggers []string `json:"triggers"`
+	Modules  []string `json:"modules,omitempty"`
+	Sources  []string `json:"sources,omitempty"`
+	Channels []string `json:"channels"`
+}
+
+func (s *Subscription) fires(trigger string) bool {
+	for _, t := range s.Triggers {
+		if t == trigger {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscription) watches(module *schema.Module) bool {
+	if module == nil {
+		return false
+	}
+
+	name := moduleName(module)
+	for _, watched := range s.Modules {
+		if strings.EqualFold(watched, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationConfig declares the channels notifications are delivered to
+// and the subscriptions of each team.
+type NotificationConfig struct {
+	// OwnerLabel is the source label naming the team that owns it,
+	// defaulting to team.
+	OwnerLabel    string                    `json:"ownerLabel,omitempty"`
+	Channels      map[string]*ChannelConfig `json:"channels"`
+	Subscriptions []*Subscription           `json:"subscriptions"`
+}
+
+// Notifier delivers events to the channels of matching subscriptions. A nil
+// Notifier delivers nothing.
+type Notifier struct {
+	config   *NotificationConfig
+	channels map[string]Channel
+	labels   graphstore.Labels
+}
+
+// NewNotifier validates the configuration. Labels are optional and used to
+// determine the team that owns a source.
+func NewNotifier(config *NotificationConfig, labels graphstore.Labels) (*Notifier, error) {
+	if config.OwnerLabel == "" {
+		config.OwnerLabel = "team"
+	}
+
+	channels := make(map[string]Channel, len(config.Channels))
+	for name, channelConfig := range config.Channels {
+		if channelConfig == nil {
+			return nil, fmt.Errorf("channel %s is not configured", name)
+		}
+
+		channel, err := channelConfig.channel()
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %s", name, err.Error())
+		}
+		channels[name] = channel
+	}
+
+	for i, subscription := range config.Subscriptions {
+		if subscription == nil || subscription.Team == "" {
+			return nil, fmt.Errorf("subscription %d requires a team", i)
+		}
+
+		for _, trigger := range subscription.Triggers {
+			if !triggers[trigger] {
+				return nil, fmt.Errorf("subscription %d has an unsupported trigger %s", i, trigger)
+			}
+		}
+
+		for _, name := range subscription.Channels {
+			if _, ok := channels[name]; !ok {
+				return nil, fmt.Errorf("subscription %d uses an undefined channel %s", i, name)
+			}
+		}
+	}
+
+	return &Notifier{config: config, channels: channels, labels: labels}, nil
+}
+
+// LoadNotificationsFile loads an external yaml file configuring notifications.
+// For example:
+//
+//	channels:
+//	  core-slack:
+//	    slack:
+//	      webhookURL: https://hooks.slack.com/services/...
+//	subscriptions:
+//	  - team: core
+//	    triggers: [dependent_added, vulnerability]
+//	    modules: [go/github.com/depscloud/api]
+//	    channels: [core-slack]
+func LoadNotificationsFile(yamlFile string, labels graphstore.Labels) (*Notifier, error) {
+	contents, err := ioutil.ReadFile(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &NotificationConfig{}
+	if err := yaml.Unmarshal(contents, config); err != nil {
+		return nil, err
+	}
+
+	return NewNotifier(config, labels)
+}
+
+// owners returns the team owning each of the sources of the events, indexed
+// by url.
+func (n *Notifier) owners(ctx context.Context, events []*Event) (map[string]string, error) {
+	owners := make(map[string]string)
+	if n.labels == nil {
+		return owners, nil
+	}
+
+	urls := make(map[string]string)
+	keys := make([][]byte, 0)
+	for _, event := range events {
+		if event.Source == nil {
+			continue
+		}
+
+		key := string(keyForSource(event.Source))
+		if _, ok := urls[key]; !ok {
+			urls[key] = event.Source.GetUrl()
+			keys = append(keys, []byte(key))
+		}
+	}
+
+	for start := 0; start < len(keys); start += traversalBatchSize {
+		end := start + traversalBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		batch, err := n.labels.GetLabels(ctx, types.SourceType, keys[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for key, sourceLabels := range batch {
+			if team := sourceLabels[n.config.OwnerLabel]; team != "" {
+				owners[urls[key]] = team
+			}
+		}
+	}
+
+	return owners, nil
+}
+
+func (n *Notifier) matches(subscription *Subscription, event *Event, owners map[string]string) bool {
+	if !subscription.fires(event.Trigger) ||
+		(subscription.Tenant != "" && subscription.Tenant != event.Tenant) {
+		return false
+	}
+
+	owned := false
+	if event.Source != nil {
+		url := event.Source.GetUrl()
+		owned = owners[url] == subscription.Team
+		for _, source := range subscription.Sources {
+			owned = owned || source == url
+		}
+	}
+
+	switch event.Trigger {
+	case TriggerDependentAdded:
+		return subscription.watches(event.Module)
+	case TriggerDependencyRemoved:
+		return owned || subscription.watches(event.Module) || subscription.watches(event.Dependent)
+	case TriggerVulnerability:
+		return owned || subscription.watches(event.Module)
+	}
+	return false
+}
+
+// Notify delivers each event to the channels of the subscriptions it
+// matches. An event is delivered to a channel at most once. Failures are
+// logged rather than returned since the change has already been made.
+func (n *Notifier) Notify(ctx context.Context, events []*Event) {
+	if n == nil || len(events) == 0 {
+		return
+	}
+
+	owners, err := n.owners(ctx, events)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[service.notification] failed to look up source owners: %s", err.Error())
+		owners = make(map[string]string)
+	}
+
+	for _, event := range events {
+		delivered := make(map[string]bool)
+
+		for _, subscription := range n.config.Subscriptions {
+			if !n.matches(subscription, event, owners) {
+				continue
+			}
+
+			for _, name := range subscription.Channels {
+				if delivered[name] {
+					continue
+				}
+				delivered[name] = true
+
+				if err := n.channels[name].Send(ctx, event); err != nil {
+					logging.FromContext(ctx).Errorf("[service.notification] failed to notify %s through %s: %s", subscription.Team, name, err.Error())
+				}
+			}
+		}
+	}
+}
+
+// trackEvents returns the events caused by tracking a source: a
+// dependent_added event for each new depends edge and a dependency_removed
+// event for each deleted one.
+func trackEvents(tenant string, source *schema.Source, currentSet, proposedSet map[string]*store.GraphItem, toDelete []*store.GraphItem) ([]*Event, error) {
+	modules, err := decodeModules(currentSet, proposedSet)
+	if err != nil {
+		return nil, err
+	}
+
+	event := func(trigger string, item *store.GraphItem) (*Event, error) {
+		depends, err := Decode(item)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Event{
+			Trigger:           trigger,
+			Tenant:            tenant,
+			Source:            source,
+			Module:            modules[string(item.GetK2())],
+			Dependent:         modules[string(item.GetK1())],
+			VersionConstraint: depends.(*schema.Depends).GetVersionConstraint(),
+		}, nil
+	}
+
+	events := make([]*Event, 0)
+	for key, item := range proposedSet {
+		if _, ok := currentSet[key]; ok || item.GetGraphItemType() != types.DependsType {
+			continue
+		}
+
+		e, err := event(TriggerDependentAdded, item)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	for _, item := range toDelete {
+		if item.GetGraphItemType() != types.DependsType {
+			continue
+		}
+
+		e, err := event(TriggerDependencyRemoved, item)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// notifyingVulnerabilities notifies subscriptions of advisories that weren't
+// previously recorded for a version of a module.
+type notifyingVulnerabilities struct {
+	graphstore.Vulnerabilities
+
+	gs       store.GraphStoreClient
+	notifier *Notifier
+}
+
+// NotifyVulnerabilities wraps the vulnerabilities so that recording a new
+// advisory notifies the subscriptions of the affected modules and of the
+// sources of their dependents.
+func NotifyVulnerabilities(vulnerabilities graphstore.Vulnerabilities, gs store.GraphStoreClient, notifier *Notifier) graphstore.Vulnerabilities {
+	if notifier == nil {
+		return vulnerabilities
+	}
+	return &notifyingVulnerabilities{Vulnerabilities: vulnerabilities, gs: gs, notifier: notifier}
+}
+
+func (v *notifyingVulnerabilities) SetAdvisories(ctx context.Context, source string, key []byte, version string, advisories []*graphstore.Advisory) error {
+	previous, err := v.GetAdvisories(ctx, [][]byte{key})
+	if err != nil {
+		return err
+	}
+
+	if err := v.Vulnerabilities.SetAdvisories(ctx, source, key, version, advisories); err != nil {
+		return err
+	}
+
+	// advisories are often published by several sources under aliases
+	known := make(map[string]bool)
+	for _, affected := range previous {
+		if affected.Version != version {
+			continue
+		}
+		for _, id := range append([]string{affected.Advisory.ID}, affected.Advisory.Aliases...) {
+			known[id] = true
+		}
+	}
+
+	added := make([]*graphstore.Advisory, 0)
+	for _, advisory := range advisories {
+		if !known[advisory.ID] {
+			added = append(added, advisory)
+		}
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	events, err := vulnerabilityEvents(ctx, v.gs, key, version, added)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[service.notification] failed to find vulnerable dependents: %s", err.Error())
+		return nil
+	}
+
+	v.notifier.Notify(ctx, events)
+	return nil
+}
+
+// vulnerabilityEvents returns an event for each source managing a module
+// that depends on the version of the module affected by the advisories.
+// Dependents without a source are still reported to those watching the
+// affected module.
+func vulnerabilityEvents(ctx context.Context, gs store.GraphStoreClient, key []byte, version string, advisories []*graphstore.Advisory) ([]*Event, error) {
+	pairs, err := findPairs(ctx, gs.FindDownstream, [][]byte{key}, types.DependsType, types.ModuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := make(map[string]*schema.Module)
+	dependentKeys := make([][]byte, 0)
+	for _, pair := range pairs {
+		dependent, depends, err := decodePair(pair)
+		if err != nil {
+			return nil, err
+		}
+
+		if resolveVersion(dependent.GetLanguage(), depends.GetVersionConstraint()) != version {
+			continue
+		}
+
+		if _, ok := dependents[string(pair.GetNode().GetK1())]; !ok {
+			dependents[string(pair.GetNode().GetK1())] = dependent
+			dependentKeys = append(dependentKeys, pair.GetNode().GetK1())
+		}
+	}
+
+	if len(dependentKeys) == 0 {
+		return make([]*Event, 0), nil
+	}
+
+	// the affected module is only known by key, so it's read from the edges
+	// of one of its dependents
+	upstream, err := findPairs(ctx, gs.FindUpstream, dependentKeys[:1], types.DependsType, types.ModuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	var module *schema.Module
+	for _, pair := range upstream {
+		if string(pair.GetNode().GetK1()) == string(key) {
+			module, _, err = decodePair(pair)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	managers, err := findPairs(ctx, gs.FindDownstream, dependentKeys, types.ManagesType, types.SourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := tenants.FromContext(ctx)
+	events := make([]*Event, 0)
+	event := func(source *schema.Source, dependentKey []byte) {
+		for _, advisory := range advisories {
+			events = append(events, &Event{
+				Trigger:   TriggerVulnerability,
+				Tenant:    tenant,
+				Source:    source,
+				Module:    module,
+				Dependent: dependents[string(dependentKey)],
+				Version:   version,
+				Advisory:  advisory,
+			})
+		}
+	}
+
+	managed := make(map[string]bool)
+	for _, pair := range managers {
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			return nil, err
+		}
+
+		managed[string(pair.GetEdge().GetK2())] = true
+		event(node.(*schema.Source), pair.GetEdge().GetK2())
+	}
+
+	for _, dependentKey := range dependentKeys {
+		if !managed[string(dependentKey)] {
+			event(nil, dependentKey)
+		}
+	}
+
+	return events, nil
+}
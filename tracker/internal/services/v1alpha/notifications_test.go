@@ -0,0 +1,190 @@
+package v1alpha
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/deps"
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+type delivery struct {
+	channel string
+	payload *WebhookPayload
+	raw     map[string]interface{}
+}
+
+// notificationServer records the notifications delivered to each path.
+func notificationServer(t *testing.T) (*httptest.Server, chan *delivery) {
+	deliveries := make(chan *delivery, 100)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.Nil(t, err)
+
+		d := &delivery{channel: r.URL.Path, payload: &WebhookPayload{}}
+		require.Nil(t, json.Unmarshal(body, &d.raw))
+		require.Nil(t, json.Unmarshal(body, d.payload))
+
+		if signature := r.Header.Get(SignatureHeader); signature != "" {
+			mac := hmac.New(sha256.New, []byte("secret"))
+			mac.Write(body)
+			require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+		}
+
+		deliveries <- d
+	}))
+
+	return server, deliveries
+}
+
+func receive(t *testing.T, deliveries chan *delivery) *delivery {
+	select {
+	case d := <-deliveries:
+		return d
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no notification was delivered")
+		return nil
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	for _, config := range []*NotificationConfig{
+		{Channels: map[string]*ChannelConfig{"a": {}}},
+		{Channels: map[string]*ChannelConfig{"a": {Slack: &SlackChannel{WebhookURL: "x"}, Webhook: &WebhookChannel{URL: "y"}}}},
+		{Channels: map[string]*ChannelConfig{"a": {Email: &EmailChannel{Address: "smtp:25"}}}},
+		{Subscriptions: []*Subscription{{Team: "core", Channels: []string{"missing"}}}},
+		{Subscriptions: []*Subscription{{Team: "core", Triggers: []string{"module_renamed"}}}},
+		{Subscriptions: []*Subscription{{Triggers: []string{TriggerVulnerability}}}},
+	} {
+		_, err := NewNotifier(config, nil)
+		require.NotNil(t, err)
+	}
+
+	notifier, err := NewNotifier(&NotificationConfig{}, nil)
+	require.Nil(t, err)
+	require.Equal(t, "team", notifier.config.OwnerLabel)
+
+	email := &EmailChannel{From: "depscloud@example.com", To: []string{"core@example.com", "web@example.com"}}
+	message := string(email.message(&Event{
+		Trigger:   TriggerDependencyRemoved,
+		Module:    &schema.Module{Language: "go", Organization: "depscloud", Module: "b"},
+		Dependent: &schema.Module{Language: "go", Organization: "depscloud", Module: "a"},
+	}))
+	require.Contains(t, message, "To: core@example.com, web@example.com\r\n")
+	require.Contains(t, message, "Subject: [depscloud] go/depscloud/a removed go/depscloud/b\r\n")
+	require.Contains(t, message, "\r\n\r\ngo/depscloud/a no longer depends on go/depscloud/b\r\n")
+}
+
+func TestNotifications_track(t *testing.T) {
+	server, deliveries := notificationServer(t)
+	defer server.Close()
+
+	source := &schema.Source{Url: "https://github.com/depscloud/depscloud.git", Kind: "repository"}
+
+	labels := fakeLabels{}
+	labels[types.SourceType+string(keyForSource(source))] = map[string]string{"team": "web"}
+
+	notifier, err := NewNotifier(&NotificationConfig{
+		Channels: map[string]*ChannelConfig{
+			"core": {Slack: &SlackChannel{WebhookURL: server.URL + "/core"}},
+			"web":  {Webhook: &WebhookChannel{URL: server.URL + "/web", Secret: "secret"}},
+		},
+		Subscriptions: []*Subscription{
+			{Team: "core", Triggers: []string{TriggerDependentAdded}, Modules: []string{"go/depscloud/c"}, Channels: []string{"core"}},
+			{Team: "web", Triggers: []string{TriggerDependencyRemoved}, Channels: []string{"web"}},
+		},
+	}, labels)
+	require.Nil(t, err)
+
+	gs := newFakeGraphStore(t, nil)
+	svc := &sourceService{gs: gs, notifier: notifier}
+
+	_, err = svc.Track(context.Background(), &tracker.SourceRequest{
+		Source:          source,
+		ManagementFiles: []*deps.DependencyManagementFile{managementFile("a", "c", "d")},
+	})
+	require.Nil(t, err)
+
+	// only the watched module notifies its subscribers
+	d := receive(t, deliveries)
+	require.Equal(t, "/core", d.channel)
+	require.Equal(t, "go/depscloud/a now depends on go/depscloud/c v1.0.0 (https://github.com/depscloud/depscloud.git)", d.raw["text"])
+
+	_, err = svc.Track(context.Background(), &tracker.SourceRequest{
+		Source:          source,
+		ManagementFiles: []*deps.DependencyManagementFile{managementFile("a", "d")},
+	})
+	require.Nil(t, err)
+
+	// the source is owned by web through its label
+	d = receive(t, deliveries)
+	require.Equal(t, "/web", d.channel)
+	require.Equal(t, TriggerDependencyRemoved, d.payload.Trigger)
+	require.Equal(t, "c", d.payload.Module.GetModule())
+	require.Equal(t, "a", d.payload.Dependent.GetModule())
+	require.Equal(t, source.GetUrl(), d.payload.Source.GetUrl())
+	require.Equal(t, "go/depscloud/a no longer depends on go/depscloud/c (https://github.com/depscloud/depscloud.git)", d.payload.Message)
+
+	require.Len(t, deliveries, 0)
+}
+
+func TestNotifications_vulnerabilities(t *testing.T) {
+	server, deliveries := notificationServer(t)
+	defer server.Close()
+
+	notifier, err := NewNotifier(&NotificationConfig{
+		Channels: map[string]*ChannelConfig{
+			"core": {Webhook: &WebhookChannel{URL: server.URL + "/core"}},
+		},
+		Subscriptions: []*Subscription{
+			{Team: "core", Triggers: []string{TriggerVulnerability}, Sources: []string{"https://github.com/depscloud/a.git"}, Channels: []string{"core"}},
+			{Team: "core", Triggers: []string{TriggerVulnerability}, Modules: []string{"go/depscloud/b"}, Channels: []string{"core"}},
+		},
+	}, nil)
+	require.Nil(t, err)
+
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b"},
+		"c": {"d"},
+	})
+	gs.manage(t, "https://github.com/depscloud/a.git", "a")
+
+	vulnerabilities := NotifyVulnerabilities(fakeVulnerabilities{}, gs, notifier)
+
+	ctx := context.Background()
+	advisory := &graphstore.Advisory{ID: "GHSA-0001", Aliases: []string{"CVE-2021-0001"}, Severity: "HIGH", Summary: "remote code execution"}
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, "osv", moduleKey("b"), "v1.0.0", []*graphstore.Advisory{advisory}))
+
+	// matching both subscriptions delivers the event to the channel once
+	d := receive(t, deliveries)
+	require.Equal(t, TriggerVulnerability, d.payload.Trigger)
+	require.Equal(t, "b", d.payload.Module.GetModule())
+	require.Equal(t, "a", d.payload.Dependent.GetModule())
+	require.Equal(t, "v1.0.0", d.payload.Version)
+	require.Equal(t, "GHSA-0001 (HIGH) affects go/depscloud/b v1.0.0, which go/depscloud/a depends on: remote code execution (https://github.com/depscloud/a.git)", d.payload.Message)
+	require.Len(t, deliveries, 0)
+
+	// advisories already recorded under an alias aren't new
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, "ghsa", moduleKey("b"), "v1.0.0", []*graphstore.Advisory{{ID: "CVE-2021-0001"}}))
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, "osv", moduleKey("b"), "v1.0.0", []*graphstore.Advisory{advisory}))
+
+	// modules that aren't watched or depended on by owned sources are quiet
+	require.Nil(t, vulnerabilities.SetAdvisories(ctx, "osv", moduleKey("d"), "v1.0.0", []*graphstore.Advisory{{ID: "GHSA-0002"}}))
+	require.Len(t, deliveries, 0)
+
+	require.Equal(t, vulnerabilities, NotifyVulnerabilities(vulnerabilities, gs, nil))
+}
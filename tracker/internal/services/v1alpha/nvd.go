@@ -0,0 +1,134 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+)
+
+// DefaultNVDURL is the CVE api of the National Vulnerability Database.
+const DefaultNVDURL = "https://services.nvd.nist.gov/rest/json/cves/2.0"
+
+// NVDClient looks up CVEs in the National Vulnerability Database. NVD
+// describes affected products rather than packages, so it's used to rate the
+// severity of advisories that are published without one.
+type NVDClient struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+
+	severities map[string]string
+}
+
+// NewNVDClient returns a client for the CVE api at the url.
+func NewNVDClient(url, apiKey string) *NVDClient {
+	return &NVDClient{
+		URL:    url,
+		APIKey: apiKey,
+		Client: &http.Client{Timeout: time.Minute},
+	}
+}
+
+type nvdMetric struct {
+	CVSSData struct {
+		BaseSeverity string `json:"baseSeverity"`
+	} `json:"cvssData"`
+	BaseSeverity string `json:"baseSeverity"`
+}
+
+type nvdResponse struct {
+	Vulnerabilities []struct {
+		CVE struct {
+			ID      string `json:"id"`
+			Metrics struct {
+				V31 []nvdMetric `json:"cvssMetricV31"`
+				V30 []nvdMetric `json:"cvssMetricV30"`
+				V2  []nvdMetric `json:"cvssMetricV2"`
+			} `json:"metrics"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+// Severity returns the severity NVD rates the CVE with, preferring the most
+// recent version of CVSS. An empty string is returned for unrated CVEs.
+func (n *NVDClient) Severity(ctx context.Context, cve string) (string, error) {
+	if severity, ok := n.severities[cve]; ok {
+		return severity, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.URL+"?cveId="+url.QueryEscape(cve), nil)
+	if err != nil {
+		return "", err
+	}
+	if n.APIKey != "" {
+		req.Header.Set("apiKey", n.APIKey)
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("nvd responded with %s for %s", resp.Status, cve)
+	}
+
+	response := &nvdResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return "", err
+	}
+
+	severity := ""
+	for _, vuln := range response.Vulnerabilities {
+		metrics := vuln.CVE.Metrics
+		for _, metric := range append(append(metrics.V31, metrics.V30...), metrics.V2...) {
+			if severity = metric.CVSSData.BaseSeverity; severity == "" {
+				// cvss v2 reports the severity alongside the data
+				severity = metric.BaseSeverity
+			}
+			if severity != "" {
+				break
+			}
+		}
+	}
+
+	if n.severities == nil {
+		n.severities = make(map[string]string)
+	}
+	n.severities[cve] = severity
+	return severity, nil
+}
+
+// enrich rates the advisory using the first of its CVEs known to NVD when it
+// was published without a severity. Failures are logged and leave the
+// advisory unrated. A nil client leaves advisories as they are.
+func (n *NVDClient) enrich(ctx context.Context, advisory *graphstore.Advisory) {
+	if n == nil || advisory.Severity != "" {
+		return
+	}
+
+	for _, id := range append([]string{advisory.ID}, advisory.Aliases...) {
+		if !strings.HasPrefix(id, "CVE-") {
+			continue
+		}
+
+		severity, err := n.Severity(ctx, id)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("[service.vulnerability] failed to look up %s: %s", id, err.Error())
+			return
+		}
+
+		if severity != "" {
+			advisory.Severity = severity
+			return
+		}
+	}
+}
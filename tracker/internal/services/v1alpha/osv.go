@@ -0,0 +1,207 @@
+package v1alpha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+)
+
+// DefaultOSVURL is the public OSV.dev api.
+const DefaultOSVURL = "https://api.osv.dev"
+
+// osvBatchSize is the most queries OSV accepts in a single batch.
+const osvBatchSize = 1000
+
+// osvEcosystems maps the languages of modules to the OSV ecosystem their
+// packages are published to.
+var osvEcosystems = map[string]string{
+	"go":      "Go",
+	"node":    "npm",
+	"java":    "Maven",
+	"rust":    "crates.io",
+	"php":     "Packagist",
+	"python":  "PyPI",
+	"ruby":    "RubyGems",
+	"dotnet":  "NuGet",
+	"r":       "CRAN",
+	"actions": "GitHub Actions",
+}
+
+// OSVPackage is a package published to an OSV ecosystem.
+type OSVPackage struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+}
+
+// OSVQuery asks for the vulnerabilities affecting a version of a package.
+type OSVQuery struct {
+	Package *OSVPackage `json:"package"`
+	Version string      `json:"version"`
+}
+
+// osvPackage returns the OSV package of the module, or nil when its language
+// isn't covered by OSV.
+func osvPackage(module *schema.Module) *OSVPackage {
+	ecosystem, ok := osvEcosystems[module.GetLanguage()]
+	if !ok {
+		return nil
+	}
+
+	name := module.GetName()
+	switch {
+	case module.GetLanguage() == "java":
+		name = module.GetOrganization() + ":" + module.GetModule()
+	case name != "":
+	case module.GetOrganization() == "" || module.GetOrganization() == "_":
+		name = module.GetModule()
+	default:
+		name = module.GetOrganization() + "/" + module.GetModule()
+	}
+
+	return &OSVPackage{Ecosystem: ecosystem, Name: name}
+}
+
+// osvVersion converts a version to the form used by the ecosystem. Go
+// versions are recorded without their leading v.
+func osvVersion(pkg *OSVPackage, version string) string {
+	if pkg.Ecosystem == "Go" {
+		return strings.TrimPrefix(version, "v")
+	}
+	return version
+}
+
+// OSVClient queries the OSV api for the vulnerabilities affecting packages.
+type OSVClient struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewOSVClient returns a client for the OSV api at the url.
+func NewOSVClient(baseURL string) *OSVClient {
+	return &OSVClient{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  &http.Client{Timeout: time.Minute},
+	}
+}
+
+type osvBatchResponse struct {
+	Results []struct {
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+	} `json:"results"`
+}
+
+type osvVulnerability struct {
+	ID       string    `json:"id"`
+	Aliases  []string  `json:"aliases"`
+	Summary  string    `json:"summary"`
+	Modified time.Time `json:"modified"`
+	Severity []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// advisory converts the vulnerability, preferring the severity rating of the
+// database over a CVSS vector.
+func (v *osvVulnerability) advisory() *graphstore.Advisory {
+	severity := v.DatabaseSpecific.Severity
+	if severity == "" && len(v.Severity) > 0 {
+		severity = v.Severity[0].Score
+	}
+
+	return &graphstore.Advisory{
+		ID:       v.ID,
+		Aliases:  v.Aliases,
+		Summary:  v.Summary,
+		Severity: severity,
+		Modified: v.Modified,
+	}
+}
+
+func (c *OSVClient) do(ctx context.Context, method, path string, body, response interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("osv responded with %s for %s", resp.Status, path)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+// QueryBatch returns the ids of the vulnerabilities affecting each query, in
+// the order the queries were provided.
+func (c *OSVClient) QueryBatch(ctx context.Context, queries []*OSVQuery) ([][]string, error) {
+	results := make([][]string, 0, len(queries))
+
+	for start := 0; start < len(queries); start += osvBatchSize {
+		end := start + osvBatchSize
+		if end > len(queries) {
+			end = len(queries)
+		}
+
+		response := &osvBatchResponse{}
+		err := c.do(ctx, http.MethodPost, "/v1/querybatch", map[string]interface{}{
+			"queries": queries[start:end],
+		}, response)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(response.Results) != end-start {
+			return nil, fmt.Errorf("osv returned %d results for %d queries", len(response.Results), end-start)
+		}
+
+		for _, result := range response.Results {
+			ids := make([]string, 0, len(result.Vulns))
+			for _, vuln := range result.Vulns {
+				ids = append(ids, vuln.ID)
+			}
+			results = append(results, ids)
+		}
+	}
+
+	return results, nil
+}
+
+// Advisory returns the details of the vulnerability.
+func (c *OSVClient) Advisory(ctx context.Context, id string) (*graphstore.Advisory, error) {
+	vuln := &osvVulnerability{}
+	if err := c.do(ctx, http.MethodGet, "/v1/vulns/"+url.PathEscape(id), nil, vuln); err != nil {
+		return nil, err
+	}
+
+	return vuln.advisory(), nil
+}
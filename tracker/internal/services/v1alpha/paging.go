@@ -0,0 +1,148 @@
+package v1alpha
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/paging"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultListCount is the number of items returned by List when the client
+// doesn't ask for a count.
+const defaultListCount = 10
+
+// Paging configures the page sizes used by the list endpoints.
+type Paging struct {
+	// DefaultPageSize is used when a client doesn't ask for a page size. When
+	// 0, endpoints that weren't paginated before return all of their results.
+	DefaultPageSize int
+	// MaxPageSize caps the page size a client can ask for.
+	MaxPageSize int
+}
+
+// DefaultPaging returns the paging configuration used by the tracker.
+func DefaultPaging() *Paging {
+	return &Paging{
+		DefaultPageSize: 0,
+		MaxPageSize:     1000,
+	}
+}
+
+func (p *Paging) clamp(size int) int {
+	if p != nil && p.MaxPageSize > 0 && size > p.MaxPageSize {
+		return p.MaxPageSize
+	}
+	return size
+}
+
+// listPage determines the page and count to request from the graph store.
+// Clients can either page through results using the page and count fields or
+// using page tokens.
+func (p *Paging) listPage(ctx context.Context, req *tracker.ListRequest) (int32, int32, error) {
+	size, token, err := paging.FromIncomingContext(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	count := int(req.GetCount())
+	if count <= 0 {
+		count = size
+	}
+	if count <= 0 && p != nil {
+		count = p.DefaultPageSize
+	}
+	if count <= 0 {
+		count = defaultListCount
+	}
+	count = p.clamp(count)
+
+	page := int(req.GetPage())
+	if page <= 0 {
+		page = 1
+	}
+
+	if token != "" {
+		cursor, err := paging.DecodeToken(token)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		offset, err := strconv.Atoi(cursor)
+		if err != nil || offset < 0 {
+			return 0, 0, status.Error(codes.InvalidArgument, "invalid page token")
+		} else if offset%count != 0 {
+			return 0, 0, status.Error(codes.InvalidArgument, "page size must not change between pages")
+		}
+
+		page = offset/count + 1
+	}
+
+	return int32(page), int32(count), nil
+}
+
+// setNextListPage returns a token for the page following a full page of
+// results.
+func setNextListPage(ctx context.Context, page, count int32, results int) error {
+	if results < int(count) {
+		return nil
+	}
+
+	offset := int(page) * int(count)
+	return paging.SetNextToken(ctx, paging.EncodeToken(strconv.Itoa(offset)))
+}
+
+// pairs returns a page of pairs from the graph store. Pairs are ordered by
+// their edge, which is used as the cursor for the next page. This keeps pages
+// stable as other edges are added or removed between requests.
+func (p *Paging) pairs(ctx context.Context, pairs []*store.GraphItemPair) ([]*store.GraphItemPair, error) {
+	size, token, err := paging.FromIncomingContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if size <= 0 && p != nil {
+		size = p.DefaultPageSize
+	}
+	size = p.clamp(size)
+
+	if size <= 0 && token == "" {
+		return pairs, nil
+	}
+
+	sorted := make([]*store.GraphItemPair, len(pairs))
+	copy(sorted, pairs)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return readableKey(sorted[i].GetEdge()) < readableKey(sorted[j].GetEdge())
+	})
+
+	start := 0
+	if token != "" {
+		cursor, err := paging.DecodeToken(token)
+		if err != nil {
+			return nil, err
+		}
+
+		start = sort.Search(len(sorted), func(i int) bool {
+			return readableKey(sorted[i].GetEdge()) > cursor
+		})
+	}
+
+	end := len(sorted)
+	if size > 0 && start+size < end {
+		end = start + size
+
+		next := paging.EncodeToken(readableKey(sorted[end-1].GetEdge()))
+		if err := paging.SetNextToken(ctx, next); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted[start:end], nil
+}
@@ -0,0 +1,124 @@
+package v1alpha
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/paging"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func pagingContext(size, token string) (context.Context, *headerStream) {
+	stream := &headerStream{}
+
+	md := metadata.Pairs(paging.SizeMetadataKey, size)
+	if token != "" {
+		md.Set(paging.TokenMetadataKey, token)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	return grpc.NewContextWithServerTransportStream(ctx, stream), stream
+}
+
+func TestPairs(t *testing.T) {
+	pairs := make([]*store.GraphItemPair, 0, 5)
+	for _, k := range []string{"d", "b", "e", "a", "c"} {
+		pairs = append(pairs, &store.GraphItemPair{
+			Node: &store.GraphItem{GraphItemType: "module", K1: []byte(k), K2: []byte(k)},
+			Edge: &store.GraphItem{GraphItemType: "depends", K1: []byte("x"), K2: []byte(k)},
+		})
+	}
+
+	p := DefaultPaging()
+
+	// not paginated unless asked for
+	all, err := p.pairs(context.Background(), pairs)
+	require.Nil(t, err)
+	require.Len(t, all, 5)
+
+	seen := make([]string, 0, 5)
+	token := ""
+	for i := 0; i < 3; i++ {
+		ctx, stream := pagingContext("2", token)
+
+		page, err := p.pairs(ctx, pairs)
+		require.Nil(t, err)
+
+		for _, pair := range page {
+			seen = append(seen, string(pair.GetNode().GetK1()))
+		}
+
+		token = ""
+		if values := stream.header.Get(paging.NextTokenMetadataKey); len(values) > 0 {
+			token = values[0]
+		}
+	}
+
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, seen)
+	require.Equal(t, "", token)
+
+	// page sizes are capped
+	p.MaxPageSize = 3
+	ctx, _ := pagingContext("100", "")
+	page, err := p.pairs(ctx, pairs)
+	require.Nil(t, err)
+	require.Len(t, page, 3)
+
+	ctx, _ = pagingContext("2", "not a token!")
+	_, err = p.pairs(ctx, pairs)
+	require.NotNil(t, err)
+}
+
+func TestListPage(t *testing.T) {
+	p := DefaultPaging()
+
+	page, count, err := p.listPage(context.Background(), &tracker.ListRequest{})
+	require.Nil(t, err)
+	require.Equal(t, int32(1), page)
+	require.Equal(t, int32(defaultListCount), count)
+
+	page, count, err = p.listPage(context.Background(), &tracker.ListRequest{Page: 3, Count: 5000})
+	require.Nil(t, err)
+	require.Equal(t, int32(3), page)
+	require.Equal(t, int32(1000), count)
+
+	ctx, stream := pagingContext("50", "")
+	page, count, err = p.listPage(ctx, &tracker.ListRequest{})
+	require.Nil(t, err)
+	require.Equal(t, int32(1), page)
+	require.Equal(t, int32(50), count)
+
+	require.Nil(t, setNextListPage(ctx, page, count, 50))
+	token := stream.header.Get(paging.NextTokenMetadataKey)
+	require.Len(t, token, 1)
+
+	ctx, stream = pagingContext("50", token[0])
+	page, count, err = p.listPage(ctx, &tracker.ListRequest{})
+	require.Nil(t, err)
+	require.Equal(t, int32(2), page)
+	require.Equal(t, int32(50), count)
+
+	require.Nil(t, setNextListPage(ctx, page, count, 10))
+	require.Len(t, stream.header.Get(paging.NextTokenMetadataKey), 0)
+
+	ctx, _ = pagingContext("20", paging.EncodeToken(fmt.Sprint(50)))
+	_, _, err = p.listPage(ctx, &tracker.ListRequest{})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,474 @@
+package v1alpha
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+)
+
+// maxQueryLength bounds the size of a query.
+const maxQueryLength = 4096
+
+// maxQuerySteps bounds the number of steps chained onto a query.
+const maxQuerySteps = 16
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenInt
+	tokenPunct
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// punctuation is ordered so longer operators match before their prefixes.
+var punctuation = []string{"==", "!=", "<=", ">=", "&&", "<", ">", "(", ")", ".", ","}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func lexQuery(input string) ([]*queryToken, error) {
+	tokens := make([]*queryToken, 0)
+
+	for i := 0; i < len(input); {
+		c := input[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '"':
+			end := i + 1
+			for end < len(input) && input[end] != '"' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			if end >= len(input) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+
+			value, err := strconv.Unquote(input[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d", i)
+			}
+
+			tokens = append(tokens, &queryToken{kind: tokenString, text: value, pos: i})
+			i = end + 1
+
+		case isLetter(c):
+			end := i
+			for end < len(input) && (isLetter(input[end]) || isDigit(input[end])) {
+				end++
+			}
+
+			tokens = append(tokens, &queryToken{kind: tokenIdent, text: input[i:end], pos: i})
+			i = end
+
+		case isDigit(c):
+			end := i
+			for end < len(input) && isDigit(input[end]) {
+				end++
+			}
+
+			tokens = append(tokens, &queryToken{kind: tokenInt, text: input[i:end], pos: i})
+			i = end
+
+		default:
+			matched := ""
+			for _, punct := range punctuation {
+				if strings.HasPrefix(input[i:], punct) {
+					matched = punct
+					break
+				}
+			}
+
+			if matched == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+
+			tokens = append(tokens, &queryToken{kind: tokenPunct, text: matched, pos: i})
+			i += len(matched)
+		}
+	}
+
+	return append(tokens, &queryToken{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// moduleQuery starts from a module and applies each step in order.
+type moduleQuery struct {
+	start *tracker.DependencyRequest
+	steps []*queryStep
+}
+
+type queryStep struct {
+	name       string
+	depth      int
+	limit      int
+	conditions []*queryCondition
+}
+
+type queryCondition struct {
+	field  string
+	op     string
+	value  string
+	number int
+}
+
+var stringFields = map[string]func(module *schema.Module) string{
+	"language":     (*schema.Module).GetLanguage,
+	"organization": (*schema.Module).GetOrganization,
+	"module":       (*schema.Module).GetModule,
+	"name":         (*schema.Module).GetName,
+}
+
+func (c *queryCondition) matches(reached *Reached) bool {
+	if c.field == "depth" {
+		switch c.op {
+		case "==":
+			return reached.Depth == c.number
+		case "!=":
+			return reached.Depth != c.number
+		case "<":
+			return reached.Depth < c.number
+		case "<=":
+			return reached.Depth <= c.number
+		case ">":
+			return reached.Depth > c.number
+		case ">=":
+			return reached.Depth >= c.number
+		}
+		return false
+	}
+
+	value := stringFields[c.field](reached.Module)
+	if c.op == "==" {
+		return value == c.value
+	}
+	return value != c.value
+}
+
+type queryParser struct {
+	tokens []*queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() *queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() *queryToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *queryParser) is(kind tokenKind, text string) bool {
+	token := p.peek()
+	return token.kind == kind && (text == "" || token.text == text)
+}
+
+func (p *queryParser) expect(kind tokenKind, text, description string) (*queryToken, error) {
+	if !p.is(kind, text) {
+		return nil, fmt.Errorf("expected %s at %d", description, p.peek().pos)
+	}
+	return p.next(), nil
+}
+
+// parseQuery parses a query, which chains steps onto a starting module:
+//
+//	module("go", "k8s.io", "client-go").dependents(depth<=3).filter(language=="go")
+//
+// module takes the language, organization, and module to start from. The
+// organization can be left out for languages that don't have one. Each step
+// works on the modules produced by the step before it:
+//
+//	dependents(depth<=N)    the modules depending on them, up to N edges away
+//	dependencies(depth<=N)  the modules they depend on, up to N edges away
+//	filter(condition)       the modules matching the condition
+//	limit(N)                the first N modules
+//
+// Traversals follow a single edge when no depth is given. Conditions compare
+// the language, organization, module, or name to a string using == or !=, or
+// the depth to an integer using ==, !=, <, <=, >, or >=, and are combined
+// using &&. Depths are counted from the modules the last traversal started at.
+func parseQuery(input string) (*moduleQuery, error) {
+	if len(input) > maxQueryLength {
+		return nil, fmt.Errorf("query must be at most %d characters", maxQueryLength)
+	}
+
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+
+	if _, err := p.expect(tokenIdent, "module", "module"); err != nil {
+		return nil, err
+	}
+
+	args, err := p.parseStrings()
+	if err != nil {
+		return nil, err
+	}
+
+	query := &moduleQuery{}
+	switch len(args) {
+	case 2:
+		query.start = &tracker.DependencyRequest{Language: args[0], Module: args[1]}
+	case 3:
+		query.start = &tracker.DependencyRequest{Language: args[0], Organization: args[1], Module: args[2]}
+	default:
+		return nil, fmt.Errorf("module takes a language, an optional organization, and a module")
+	}
+
+	for p.is(tokenPunct, ".") {
+		p.next()
+
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+
+		query.steps = append(query.steps, step)
+		if len(query.steps) > maxQuerySteps {
+			return nil, fmt.Errorf("query must have at most %d steps", maxQuerySteps)
+		}
+	}
+
+	if _, err := p.expect(tokenEOF, "", "end of query"); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+func (p *queryParser) parseStrings() ([]string, error) {
+	if _, err := p.expect(tokenPunct, "(", "("); err != nil {
+		return nil, err
+	}
+
+	values := make([]string, 0)
+	for !p.is(tokenPunct, ")") {
+		if len(values) > 0 {
+			if _, err := p.expect(tokenPunct, ",", ","); err != nil {
+				return nil, err
+			}
+		}
+
+		value, err := p.expect(tokenString, "", "string")
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value.text)
+	}
+	p.next()
+
+	return values, nil
+}
+
+func (p *queryParser) parseStep() (*queryStep, error) {
+	name, err := p.expect(tokenIdent, "", "step")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(tokenPunct, "(", "("); err != nil {
+		return nil, err
+	}
+
+	step := &queryStep{name: name.text}
+
+	switch step.name {
+	case "dependents", "dependencies":
+		step.depth = 1
+
+		if !p.is(tokenPunct, ")") {
+			condition, err := p.parseCondition()
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case condition.field == "depth" && condition.op == "<=":
+				step.depth = condition.number
+			case condition.field == "depth" && condition.op == "<":
+				step.depth = condition.number - 1
+			default:
+				return nil, fmt.Errorf("%s only accepts depth<=N at %d", step.name, name.pos)
+			}
+
+			if step.depth <= 0 {
+				return nil, fmt.Errorf("%s must follow at least one edge at %d", step.name, name.pos)
+			}
+		}
+
+	case "filter":
+		for {
+			condition, err := p.parseCondition()
+			if err != nil {
+				return nil, err
+			}
+			step.conditions = append(step.conditions, condition)
+
+			if !p.is(tokenPunct, "&&") {
+				break
+			}
+			p.next()
+		}
+
+	case "limit":
+		value, err := p.expect(tokenInt, "", "integer")
+		if err != nil {
+			return nil, err
+		}
+
+		step.limit, err = strconv.Atoi(value.text)
+		if err != nil || step.limit <= 0 {
+			return nil, fmt.Errorf("limit must be a positive integer at %d", value.pos)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown step %s at %d", step.name, name.pos)
+	}
+
+	if _, err := p.expect(tokenPunct, ")", ")"); err != nil {
+		return nil, err
+	}
+
+	return step, nil
+}
+
+func (p *queryParser) parseCondition() (*queryCondition, error) {
+	field, err := p.expect(tokenIdent, "", "field")
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := p.expect(tokenPunct, "", "comparison")
+	if err != nil {
+		return nil, err
+	}
+
+	condition := &queryCondition{field: field.text, op: op.text}
+
+	if field.text == "depth" {
+		switch op.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+		default:
+			return nil, fmt.Errorf("expected comparison at %d", op.pos)
+		}
+
+		value, err := p.expect(tokenInt, "", "integer")
+		if err != nil {
+			return nil, err
+		}
+
+		condition.number, err = strconv.Atoi(value.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer at %d", value.pos)
+		}
+
+		return condition, nil
+	}
+
+	if _, ok := stringFields[field.text]; !ok {
+		return nil, fmt.Errorf("unknown field %s at %d", field.text, field.pos)
+	}
+
+	if op.text != "==" && op.text != "!=" {
+		return nil, fmt.Errorf("%s can only be compared using == or != at %d", field.text, op.pos)
+	}
+
+	value, err := p.expect(tokenString, "", "string")
+	if err != nil {
+		return nil, err
+	}
+	condition.value = value.text
+
+	return condition, nil
+}
+
+// evaluate runs the query against the graph. Traversals are limited to the
+// max depth of the service.
+func (q *queryService) evaluate(ctx context.Context, query *moduleQuery) ([]*Reached, error) {
+	start := q.aliases.request(query.start)
+
+	modules := []*Reached{{
+		Module: &schema.Module{
+			Language:     start.GetLanguage(),
+			Organization: start.GetOrganization(),
+			Module:       start.GetModule(),
+			Name:         start.GetName(),
+		},
+		key: keyForDependencyRequest(start),
+	}}
+
+	for _, step := range query.steps {
+		switch step.name {
+		case "dependents", "dependencies":
+			find := q.gs.FindDownstream
+			if step.name == "dependencies" {
+				find = q.gs.FindUpstream
+			}
+
+			depth := step.depth
+			if depth > q.maxDepth {
+				depth = q.maxDepth
+			}
+
+			roots := make([][]byte, 0, len(modules))
+			for _, module := range modules {
+				roots = append(roots, module.key)
+			}
+
+			reached, err := traverseFrom(ctx, find, roots, depth)
+			if err != nil {
+				return nil, err
+			}
+			modules = reached
+
+		case "filter":
+			filtered := make([]*Reached, 0, len(modules))
+			for _, module := range modules {
+				matches := true
+				for _, condition := range step.conditions {
+					matches = matches && condition.matches(module)
+				}
+
+				if matches {
+					filtered = append(filtered, module)
+				}
+			}
+			modules = filtered
+
+		case "limit":
+			if len(modules) > step.limit {
+				modules = modules[:step.limit]
+			}
+		}
+	}
+
+	return modules, nil
+}
@@ -0,0 +1,102 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	query, err := parseQuery(`module("go", "depscloud", "a").dependents(depth<=3).filter(language=="go" && depth>1).limit(5)`)
+	require.Nil(t, err)
+	require.Equal(t, "go", query.start.GetLanguage())
+	require.Equal(t, "depscloud", query.start.GetOrganization())
+	require.Equal(t, "a", query.start.GetModule())
+	require.Len(t, query.steps, 3)
+	require.Equal(t, 3, query.steps[0].depth)
+	require.Len(t, query.steps[1].conditions, 2)
+	require.Equal(t, 5, query.steps[2].limit)
+
+	query, err = parseQuery(`module("node", "@depscloud/b").dependencies()`)
+	require.Nil(t, err)
+	require.Equal(t, "", query.start.GetOrganization())
+	require.Equal(t, 1, query.steps[0].depth)
+
+	for _, invalid := range []string{
+		``,
+		`module("go")`,
+		`module("go", "a"`,
+		`module("go", "a").dependents(depth>=2)`,
+		`module("go", "a").dependents(depth<1)`,
+		`module("go", "a").filter(language<"go")`,
+		`module("go", "a").filter(version=="1")`,
+		`module("go", "a").limit(0)`,
+		`module("go", "a").sources()`,
+		`module("go", "a") module("go", "b")`,
+		`module("go", "a).dependents()`,
+		`module("go", "a").dependents() | limit(1)`,
+		`module("go", "a")` + strings.Repeat(".limit(1)", maxQuerySteps+1),
+	} {
+		_, err := parseQuery(invalid)
+		require.NotNil(t, err, invalid)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"b": {"d"},
+		"c": {"d"},
+		"d": {"e"},
+		"x": {"b"},
+	})
+
+	server := http.NewServeMux()
+	RegisterQueryService(server, gs, 5, nil)
+
+	evaluate := func(req *http.Request) map[string]int {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		response := &EvaluateResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		return reachedDepths(response.Modules)
+	}
+
+	get := func(query string) map[string]int {
+		return evaluate(httptest.NewRequest(http.MethodGet, "/v1alpha/queries/evaluate?q="+url.QueryEscape(query), nil))
+	}
+
+	require.Equal(t, map[string]int{"a": 0}, get(`module("go", "depscloud", "a")`))
+	require.Equal(t, map[string]int{"b": 1, "c": 1, "d": 2}, get(`module("go", "depscloud", "a").dependencies(depth<=2)`))
+	require.Equal(t, map[string]int{"d": 2}, get(`module("go", "depscloud", "a").dependencies(depth<=3).filter(depth==2)`))
+
+	// traversals start from every module of the previous step
+	require.Equal(t, map[string]int{"a": 1, "x": 1}, get(`module("go", "depscloud", "d").dependents().filter(module!="c").dependents()`))
+
+	require.Len(t, get(`module("go", "depscloud", "e").dependents(depth<=5).limit(2)`), 2)
+	require.Empty(t, get(`module("go", "depscloud", "a").dependencies().filter(language=="node")`))
+
+	post := httptest.NewRequest(http.MethodPost, "/v1alpha/queries/evaluate",
+		strings.NewReader(`module("go", "depscloud", "a").dependencies(depth<=10)`))
+	require.Equal(t, map[string]int{"b": 1, "c": 1, "d": 2, "e": 3}, evaluate(post))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/v1alpha/queries/evaluate?q="+url.QueryEscape(`module("go")`), nil),
+		httptest.NewRequest(http.MethodGet, "/v1alpha/queries/evaluate?as_of=x&q="+url.QueryEscape(`module("go", "a")`), nil),
+	} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/v1alpha/queries/evaluate", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
@@ -0,0 +1,917 @@
+package v1alpha
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/constraints"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/registries"
+	"github.com/depscloud/depscloud/internal/schedule"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/ghodss/yaml"
+)
+
+// The formats reports can be rendered in.
+const (
+	ReportFormatJSON = "json"
+	ReportFormatHTML = "html"
+	ReportFormatPDF  = "pdf"
+)
+
+var reportContentTypes = map[string]string{
+	ReportFormatJSON: "application/json",
+	ReportFormatHTML: "text/html; charset=UTF-8",
+	ReportFormatPDF:  "application/pdf",
+}
+
+// defaultReportTop is the number of risky modules a report lists by default.
+const defaultReportTop = 10
+
+// riskSeverities weighs the advisories affecting a module by their severity.
+// Advisories without a known severity are treated as severe.
+var riskSeverities = map[string]int{
+	"LOW":      1,
+	"MODERATE": 3,
+	"MEDIUM":   3,
+	"HIGH":     7,
+	"CRITICAL": 10,
+}
+
+const (
+	riskUnknownSeverity = 7
+	riskLifecycle       = 5
+	riskDeprecated      = 5
+	riskMajorBehind     = 2
+)
+
+// ReportStorage stores reports under a location. Local paths and file urls
+// are written to disk, while http and https urls are uploaded with a PUT
+// carrying the headers, such as the authorization of a bucket. Reports are
+// stored as <location>/<organization>/<report>-<date>.<format>.
+type ReportStorage struct {
+	Location string            `json:"location"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+// ReportDefinition configures a report generated for each of the
+// organizations on a cron schedule. Each run covers the changes made since
+// the previous run. Reports are rendered in each of the formats, defaulting
+// to json, and delivered to the storage, the email recipients, or both.
+type ReportDefinition struct {
+	Name          string   `json:"name"`
+	Schedule      string   `json:"schedule"`
+	Organizations []string `json:"organizations"`
+	Formats       []string `json:"formats,omitempty"`
+	// Top is the number of risky modules listed, defaulting to 10.
+	Top     int            `json:"top,omitempty"`
+	Storage *ReportStorage `json:"storage,omitempty"`
+	Email   *EmailChannel  `json:"email,omitempty"`
+
+	schedule schedule.Schedule
+}
+
+// ReportConfig declares the reports generated by the tracker.
+type ReportConfig struct {
+	// LicenseLabel is the module label holding the license, defaulting to
+	// license.
+	LicenseLabel string              `json:"licenseLabel,omitempty"`
+	Reports      []*ReportDefinition `json:"reports"`
+}
+
+// validate ensures each report can be scheduled, rendered, and delivered,
+// filling in the defaults.
+func (c *ReportConfig) validate() error {
+	if c.LicenseLabel == "" {
+		c.LicenseLabel = "license"
+	}
+
+	names := make(map[string]bool, len(c.Reports))
+	for i, definition := range c.Reports {
+		if definition == nil || definition.Name == "" {
+			return fmt.Errorf("report %d requires a name", i)
+		} else if names[definition.Name] {
+			return fmt.Errorf("report %s is defined more than once", definition.Name)
+		}
+		names[definition.Name] = true
+
+		var err error
+		if definition.schedule, err = schedule.Parse(definition.Schedule); err != nil {
+			return fmt.Errorf("report %s has an invalid schedule: %v", definition.Name, err)
+		}
+
+		if len(definition.Organizations) == 0 {
+			return fmt.Errorf("report %s requires organizations", definition.Name)
+		}
+
+		if len(definition.Formats) == 0 {
+			definition.Formats = []string{ReportFormatJSON}
+		}
+		for _, format := range definition.Formats {
+			if _, ok := reportContentTypes[format]; !ok {
+				return fmt.Errorf("report %s has an unsupported format %s", definition.Name, format)
+			}
+		}
+
+		if definition.Top <= 0 {
+			definition.Top = defaultReportTop
+		}
+
+		if definition.Storage == nil && definition.Email == nil {
+			return fmt.Errorf("report %s requires a storage or email", definition.Name)
+		} else if definition.Storage != nil && definition.Storage.Location == "" {
+			return fmt.Errorf("report %s requires a storage location", definition.Name)
+		} else if email := definition.Email; email != nil && (email.Address == "" || email.From == "" || len(email.To) == 0) {
+			return fmt.Errorf("report %s requires an email address, from, and to", definition.Name)
+		}
+	}
+
+	return nil
+}
+
+// LoadReportsFile loads an external yaml file configuring reports. For
+// example:
+//
+//	reports:
+//	  - name: weekly
+//	    schedule: "0 8 * * 1"
+//	    organizations: [depscloud]
+//	    formats: [html, pdf]
+//	    storage:
+//	      location: https://storage.googleapis.com/reports
+//	      headers:
+//	        Authorization: Bearer ...
+//	    email:
+//	      address: smtp.example.com:587
+//	      from: depscloud@example.com
+//	      to: [platform@example.com]
+func LoadReportsFile(yamlFile string) (*ReportConfig, error) {
+	contents, err := ioutil.ReadFile(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ReportConfig{}
+	if err := yaml.Unmarshal(contents, config); err != nil {
+		return nil, err
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ReportDependency is a dependency of a module in the organization.
+type ReportDependency struct {
+	Dependent         *schema.Module `json:"dependent"`
+	Module            *schema.Module `json:"module"`
+	VersionConstraint string         `json:"version_constraint"`
+}
+
+// RiskyModule is a module the organization depends on, scored by the
+// advisories affecting the versions in use, its lifecycle, its deprecation by
+// its registry, and how far behind the latest major version it's used.
+type RiskyModule struct {
+	Module     *schema.Module `json:"module"`
+	Score      int            `json:"score"`
+	Dependents int            `json:"dependents"`
+	Advisories []string       `json:"advisories,omitempty"`
+	Reasons    []string       `json:"reasons"`
+}
+
+// OrganizationReport summarizes the dependencies of the modules in an
+// organization. Added and Removed are the dependencies that changed between
+// Since and Until, and are only known when the store records its history.
+type OrganizationReport struct {
+	Organization string              `json:"organization"`
+	Since        time.Time           `json:"since"`
+	Until        time.Time           `json:"until"`
+	Modules      int                 `json:"modules"`
+	Dependencies int                 `json:"dependencies"`
+	Added        []*ReportDependency `json:"added"`
+	Removed      []*ReportDependency `json:"removed"`
+	Risky        []*RiskyModule      `json:"risky"`
+	// Licenses counts the modules depended on by their license.
+	Licenses map[string]int `json:"licenses"`
+}
+
+// ReportGenerator generates the reports of organizations. Labels, history,
+// and vulnerabilities are optional and leave out the parts of the report
+// that depend on them.
+type ReportGenerator struct {
+	gs              store.GraphStoreClient
+	labels          graphstore.Labels
+	history         graphstore.History
+	vulnerabilities graphstore.Vulnerabilities
+	licenseLabel    string
+}
+
+// NewReportGenerator returns a ReportGenerator reading the license of
+// modules from the license label.
+func NewReportGenerator(
+	gs store.GraphStoreClient,
+	labels graphstore.Labels,
+	history graphstore.History,
+	vulnerabilities graphstore.Vulnerabilities,
+	licenseLabel string,
+) *ReportGenerator {
+	if licenseLabel == "" {
+		licenseLabel = "license"
+	}
+
+	return &ReportGenerator{
+		gs:              gs,
+		labels:          labels,
+		history:         history,
+		vulnerabilities: vulnerabilities,
+		licenseLabel:    licenseLabel,
+	}
+}
+
+// reportedDependency collects how the organization uses a module.
+type reportedDependency struct {
+	module     *schema.Module
+	dependents map[string]bool
+	versions   map[string]bool
+}
+
+// Generate reports on the organization, listing up to top risky modules.
+func (g *ReportGenerator) Generate(ctx context.Context, organization string, since, until time.Time, top int) (*OrganizationReport, error) {
+	modules, err := listModules(ctx, g.gs, &filters.Filter{Organization: organization})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([][]byte, 0, len(modules))
+	for key, module := range modules {
+		// stores without filters list every module
+		if module.GetOrganization() == organization {
+			keys = append(keys, []byte(key))
+		}
+	}
+
+	pairs, err := findPairs(ctx, g.gs.FindUpstream, keys, types.DependsType, types.ModuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies := make(map[string]*reportedDependency)
+	dependencyKeys := make([][]byte, 0)
+	for _, pair := range pairs {
+		module, depends, err := decodePair(pair)
+		if err != nil {
+			return nil, err
+		}
+
+		key := string(pair.GetNode().GetK1())
+		dependency, ok := dependencies[key]
+		if !ok {
+			dependency = &reportedDependency{
+				module:     module,
+				dependents: make(map[string]bool),
+				versions:   make(map[string]bool),
+			}
+			dependencies[key] = dependency
+			dependencyKeys = append(dependencyKeys, pair.GetNode().GetK1())
+		}
+
+		dependency.dependents[string(pair.GetEdge().GetK1())] = true
+		if version := resolveVersion(module.GetLanguage(), depends.GetVersionConstraint()); version != "" {
+			dependency.versions[version] = true
+		}
+	}
+
+	report := &OrganizationReport{
+		Organization: organization,
+		Since:        since,
+		Until:        until,
+		Modules:      len(keys),
+		Dependencies: len(dependencies),
+		Added:        make([]*ReportDependency, 0),
+		Removed:      make([]*ReportDependency, 0),
+		Licenses:     make(map[string]int),
+	}
+
+	if err := g.changes(ctx, report, keys); err != nil {
+		return nil, err
+	}
+
+	labels, err := g.getLabels(ctx, dependencyKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	advisories, err := g.getAdvisories(ctx, dependencyKeys, dependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	risky := make([]*RiskyModule, 0)
+	for key, dependency := range dependencies {
+		moduleLabels := labels[key]
+
+		license := moduleLabels[g.licenseLabel]
+		if license == "" {
+			license = LicenseUnknown
+		}
+		report.Licenses[license]++
+
+		if result := score(dependency, moduleLabels, advisories[key]); result.Score > 0 {
+			risky = append(risky, result)
+		}
+	}
+
+	sort.Slice(risky, func(i, j int) bool {
+		if risky[i].Score != risky[j].Score {
+			return risky[i].Score > risky[j].Score
+		} else if risky[i].Dependents != risky[j].Dependents {
+			return risky[i].Dependents > risky[j].Dependents
+		}
+		return moduleName(risky[i].Module) < moduleName(risky[j].Module)
+	})
+
+	if len(risky) > top {
+		risky = risky[:top]
+	}
+	report.Risky = risky
+
+	return report, nil
+}
+
+// changes fills in the dependencies the modules of the organization added
+// and removed during the period of the report.
+func (g *ReportGenerator) changes(ctx context.Context, report *OrganizationReport, keys [][]byte) error {
+	if g.history == nil {
+		return nil
+	}
+
+	changes, err := g.history.Changes(ctx, keys, report.Until)
+	if err == api.ErrUnsupported {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	response, err := diff(changes, report.Since)
+	if err != nil {
+		return err
+	}
+
+	dependency := func(change *EdgeChange, edge interface{}) *ReportDependency {
+		dependent, ok := change.From.(*schema.Module)
+		if !ok || change.Type != types.DependsType || dependent.GetOrganization() != report.Organization {
+			return nil
+		}
+
+		module, _ := change.To.(*schema.Module)
+		depends, _ := edge.(*schema.Depends)
+		return &ReportDependency{
+			Dependent:         dependent,
+			Module:            module,
+			VersionConstraint: depends.GetVersionConstraint(),
+		}
+	}
+
+	for _, change := range response.Added {
+		if added := dependency(change, change.After); added != nil {
+			report.Added = append(report.Added, added)
+		}
+	}
+
+	for _, change := range response.Removed {
+		if removed := dependency(change, change.Before); removed != nil {
+			report.Removed = append(report.Removed, removed)
+		}
+	}
+
+	return nil
+}
+
+// getLabels returns the labels of the modules, indexed by key.
+func (g *ReportGenerator) getLabels(ctx context.Context, keys [][]byte) (map[string]map[string]string, error) {
+	labels := make(map[string]map[string]string, len(keys))
+	if g.labels == nil {
+		return labels, nil
+	}
+
+	for start := 0; start < len(keys); start += traversalBatchSize {
+		end := start + traversalBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		batch, err := g.labels.GetLabels(ctx, types.ModuleType, keys[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for key, moduleLabels := range batch {
+			labels[key] = moduleLabels
+		}
+	}
+
+	return labels, nil
+}
+
+// getAdvisories returns the advisories affecting the versions of the modules
+// the organization uses, indexed by key.
+func (g *ReportGenerator) getAdvisories(ctx context.Context, keys [][]byte, dependencies map[string]*reportedDependency) (map[string][]*graphstore.Advisory, error) {
+	advisories := make(map[string][]*graphstore.Advisory)
+	if g.vulnerabilities == nil {
+		return advisories, nil
+	}
+
+	seen := make(map[string]bool)
+	for start := 0; start < len(keys); start += traversalBatchSize {
+		end := start + traversalBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		affected, err := g.vulnerabilities.GetAdvisories(ctx, keys[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range affected {
+			key := string(a.Key)
+			if dependency, ok := dependencies[key]; !ok || !dependency.versions[a.Version] || seen[key+a.Advisory.ID] {
+				continue
+			}
+
+			seen[key+a.Advisory.ID] = true
+			advisories[key] = append(advisories[key], a.Advisory)
+		}
+	}
+
+	return advisories, nil
+}
+
+// score rates how risky it is for the organization to depend on the module.
+func score(dependency *reportedDependency, labels map[string]string, advisories []*graphstore.Advisory) *RiskyModule {
+	result := &RiskyModule{
+		Module:     dependency.module,
+		Dependents: len(dependency.dependents),
+		Reasons:    make([]string, 0),
+	}
+
+	for _, advisory := range advisories {
+		weight, ok := riskSeverities[strings.ToUpper(advisory.Severity)]
+		if !ok {
+			weight = riskUnknownSeverity
+		}
+
+		result.Score += weight
+		result.Advisories = append(result.Advisories, advisory.ID)
+	}
+	sort.Strings(result.Advisories)
+
+	if len(advisories) > 0 {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%d advisories affect the versions in use", len(advisories)))
+	}
+
+	if current := lifecycle(labels); current != nil && current.ended(time.Now()) {
+		for version := range dependency.versions {
+			if current.affects(version) {
+				result.Score += riskLifecycle
+				result.Reasons = append(result.Reasons, fmt.Sprintf("the module is %s", current.Status))
+				break
+			}
+		}
+	}
+
+	metadata := registries.FromLabels(labels)
+	if metadata != nil && metadata.Deprecated {
+		result.Score += riskDeprecated
+		result.Reasons = append(result.Reasons, "the registry deprecated the module")
+	}
+
+	if metadata != nil {
+		for version := range dependency.versions {
+			if behind, _ := constraints.Behind(version, metadata.Latest); behind == "major" {
+				result.Score += riskMajorBehind
+				result.Reasons = append(result.Reasons, "a version behind the latest major version is in use")
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// lines renders the report as plain text, which is used for the body of
+// emails and the pages of PDFs.
+func (r *OrganizationReport) lines() []string {
+	dependency := func(d *ReportDependency) string {
+		line := fmt.Sprintf("  %s -> %s", moduleName(d.Dependent), moduleName(d.Module))
+		if d.VersionConstraint != "" {
+			line += " " + d.VersionConstraint
+		}
+		return line
+	}
+
+	lines := []string{
+		fmt.Sprintf("Dependency report for %s", r.Organization),
+		fmt.Sprintf("%s to %s", r.Since.Format(time.RFC3339), r.Until.Format(time.RFC3339)),
+		"",
+		fmt.Sprintf("Modules: %d", r.Modules),
+		fmt.Sprintf("Dependencies: %d", r.Dependencies),
+		"",
+		fmt.Sprintf("New dependencies (%d)", len(r.Added)),
+	}
+
+	for _, added := range r.Added {
+		lines = append(lines, dependency(added))
+	}
+
+	lines = append(lines, "", fmt.Sprintf("Removed dependencies (%d)", len(r.Removed)))
+	for _, removed := range r.Removed {
+		lines = append(lines, dependency(removed))
+	}
+
+	lines = append(lines, "", "Top risky modules")
+	for _, risky := range r.Risky {
+		lines = append(lines, fmt.Sprintf("  %s (score %d, %d dependents): %s",
+			moduleName(risky.Module), risky.Score, risky.Dependents, strings.Join(risky.Reasons, "; ")))
+	}
+
+	lines = append(lines, "", "Licenses")
+	for _, license := range sortedLicenses(r.Licenses) {
+		lines = append(lines, fmt.Sprintf("  %s: %d", license, r.Licenses[license]))
+	}
+
+	return lines
+}
+
+// sortedLicenses orders the licenses by how many modules use them.
+func sortedLicenses(licenses map[string]int) []string {
+	sorted := make([]string, 0, len(licenses))
+	for license := range licenses {
+		sorted = append(sorted, license)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if licenses[sorted[i]] != licenses[sorted[j]] {
+			return licenses[sorted[i]] > licenses[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	return sorted
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"name":     moduleName,
+	"licenses": sortedLicenses,
+	"date":     func(t time.Time) string { return t.Format(time.RFC3339) },
+	"join":     strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Dependency report for {{ .Organization }}</title>
+</head>
+<body>
+<h1>Dependency report for {{ .Organization }}</h1>
+<p>{{ date .Since }} to {{ date .Until }}</p>
+<p>Modules: {{ .Modules }}<br>Dependencies: {{ .Dependencies }}</p>
+<h2>New dependencies ({{ len .Added }})</h2>
+<ul>{{ range .Added }}
+<li>{{ name .Dependent }} &rarr; {{ name .Module }} {{ .VersionConstraint }}</li>{{ end }}
+</ul>
+<h2>Removed dependencies ({{ len .Removed }})</h2>
+<ul>{{ range .Removed }}
+<li>{{ name .Dependent }} &rarr; {{ name .Module }} {{ .VersionConstraint }}</li>{{ end }}
+</ul>
+<h2>Top risky modules</h2>
+<table>
+<tr><th>Module</th><th>Score</th><th>Dependents</th><th>Reasons</th></tr>{{ range .Risky }}
+<tr><td>{{ name .Module }}</td><td>{{ .Score }}</td><td>{{ .Dependents }}</td><td>{{ join .Reasons "; " }}</td></tr>{{ end }}
+</table>
+<h2>Licenses</h2>
+<table>
+<tr><th>License</th><th>Modules</th></tr>{{ $licenses := .Licenses }}{{ range licenses .Licenses }}
+<tr><td>{{ . }}</td><td>{{ index $licenses . }}</td></tr>{{ end }}
+</table>
+</body>
+</html>
+`))
+
+// render renders the report in the format.
+func (r *OrganizationReport) render(format string) ([]byte, error) {
+	switch format {
+	case ReportFormatJSON:
+		return json.MarshalIndent(r, "", "  ")
+	case ReportFormatHTML:
+		buf := &bytes.Buffer{}
+		if err := reportTemplate.Execute(buf, r); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case ReportFormatPDF:
+		return writePDF(r.lines()), nil
+	}
+	return nil, fmt.Errorf("unsupported format %s", format)
+}
+
+const (
+	pdfLinesPerPage = 48
+	pdfLineLength   = 100
+)
+
+// pdfEscaper escapes the characters that end or escape a PDF string.
+var pdfEscaper = strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`)
+
+// writePDF lays the lines out on letter sized pages using Helvetica. Long
+// lines are wrapped and characters outside of ASCII are replaced, since the
+// standard fonts don't cover them.
+func writePDF(lines []string) []byte {
+	wrapped := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.Map(func(r rune) rune {
+			if r < 32 || r > 126 {
+				return '?'
+			}
+			return r
+		}, line)
+
+		for len(line) > pdfLineLength {
+			wrapped = append(wrapped, line[:pdfLineLength])
+			line = "    " + line[pdfLineLength:]
+		}
+		wrapped = append(wrapped, line)
+	}
+
+	pages := make([][]string, 0)
+	for start := 0; start < len(wrapped); start += pdfLinesPerPage {
+		end := start + pdfLinesPerPage
+		if end > len(wrapped) {
+			end = len(wrapped)
+		}
+		pages = append(pages, wrapped[start:end])
+	}
+
+	// objects 1 through 3 are the catalog, page tree, and font, followed by
+	// each page and its contents
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	for i, page := range pages {
+		content := &strings.Builder{}
+		content.WriteString("BT /F1 10 Tf 14 TL 50 742 Td")
+		for _, line := range page {
+			fmt.Fprintf(content, " (%s) Tj T*", pdfEscaper.Replace(line))
+		}
+		content.WriteString(" ET")
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// renderedReport is a report rendered in one of its formats.
+type renderedReport struct {
+	name        string
+	contentType string
+	body        []byte
+}
+
+var reportClient = &http.Client{Timeout: 5 * time.Minute}
+
+// store writes the files under the directory of the organization.
+func (s *ReportStorage) store(ctx context.Context, organization string, files []*renderedReport) error {
+	location := strings.TrimPrefix(s.Location, "file://")
+
+	for _, file := range files {
+		if !isURL(location) {
+			dir := filepath.Join(location, organization)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+
+			if err := ioutil.WriteFile(filepath.Join(dir, file.name), file.body, 0644); err != nil {
+				return err
+			}
+			continue
+		}
+
+		target := strings.TrimSuffix(location, "/") + "/" + organization + "/" + file.name
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(file.body))
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Content-Type", file.contentType)
+		for key, value := range s.Headers {
+			req.Header.Set(key, value)
+		}
+
+		resp, err := reportClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("upload of %s failed with status %d", file.name, resp.StatusCode)
+		}
+	}
+
+	return nil
+}
+
+// reportMessage builds an email with the text of the report as its body and
+// each of the files attached.
+func (e *EmailChannel) reportMessage(subject string, lines []string, files []*renderedReport) ([]byte, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=UTF-8"}})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write([]byte(strings.Join(lines, "\r\n") + "\r\n")); err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {file.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", file.name)},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoded := base64.StdEncoding.EncodeToString(file.body)
+		for len(encoded) > 76 {
+			fmt.Fprintf(part, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(part, "%s\r\n", encoded)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "From: %s\r\n", e.From)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(buf, "Subject: [depscloud] %s\r\n", subject)
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// deliver generates the report for each organization, rendering and
+// delivering it to the storage and email of the definition. Organizations
+// that fail don't prevent the others from being delivered.
+func (g *ReportGenerator) deliver(ctx context.Context, definition *ReportDefinition, since, until time.Time) error {
+	failed := 0
+	for _, organization := range definition.Organizations {
+		if err := g.deliverOrganization(ctx, definition, organization, since, until); err != nil {
+			logging.FromContext(ctx).Errorf("[service.report] failed to deliver report %s for %s: %s", definition.Name, organization, err.Error())
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d organizations failed", failed, len(definition.Organizations))
+	}
+	return nil
+}
+
+func (g *ReportGenerator) deliverOrganization(ctx context.Context, definition *ReportDefinition, organization string, since, until time.Time) error {
+	report, err := g.Generate(ctx, organization, since, until, definition.Top)
+	if err != nil {
+		return err
+	}
+
+	files := make([]*renderedReport, 0, len(definition.Formats))
+	for _, format := range definition.Formats {
+		body, err := report.render(format)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, &renderedReport{
+			name:        fmt.Sprintf("%s-%s.%s", definition.Name, until.Format(lifecycleDate), format),
+			contentType: reportContentTypes[format],
+			body:        body,
+		})
+	}
+
+	if definition.Storage != nil {
+		if err := definition.Storage.store(ctx, organization, files); err != nil {
+			return err
+		}
+	}
+
+	if definition.Email != nil {
+		subject := fmt.Sprintf("%s report for %s", definition.Name, organization)
+		message, err := definition.Email.reportMessage(subject, report.lines(), files)
+		if err != nil {
+			return err
+		}
+
+		if err := definition.Email.send(message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunReports delivers each of the configured reports on its schedule until
+// the context is done. The first run of a report covers the time between two
+// of its runs, and each later run covers the time since the previous one.
+func RunReports(ctx context.Context, generator *ReportGenerator, config *ReportConfig) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(config.Reports))
+
+	for _, definition := range config.Reports {
+		go func(definition *ReportDefinition) {
+			defer wg.Done()
+
+			var since time.Time
+			for {
+				next := definition.schedule.Next(time.Now())
+				if next.IsZero() {
+					logging.FromContext(ctx).Errorf("[service.report] report %s will never run again", definition.Name)
+					return
+				}
+
+				if since.IsZero() {
+					since = next.Add(-definition.schedule.Next(next).Sub(next))
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Until(next)):
+				}
+
+				if err := generator.deliver(ctx, definition, since, next); err != nil {
+					logging.FromContext(ctx).Errorf("[service.report] report %s: %s", definition.Name, err.Error())
+				} else {
+					logging.FromContext(ctx).Infof("[service.report] delivered report %s for %d organizations", definition.Name, len(definition.Organizations))
+				}
+				since = next
+			}
+		}(definition)
+	}
+
+	wg.Wait()
+}
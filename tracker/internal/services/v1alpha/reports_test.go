@@ -0,0 +1,233 @@
+package v1alpha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/registries"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReports(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"d": {"b"},
+	})
+
+	labels := fakeLabels{}
+	labels[types.ModuleType+string(moduleKey("b"))] = map[string]string{
+		"license":                  "MIT",
+		registries.LabelLatest:     "v2.0.0",
+		registries.LabelDeprecated: "true",
+	}
+
+	vulnerabilities := fakeVulnerabilities{}
+	require.Nil(t, vulnerabilities.SetAdvisories(context.Background(), "osv", moduleKey("c"), "v1.0.0",
+		[]*graphstore.Advisory{{ID: "GHSA-1", Severity: "CRITICAL"}}))
+	require.Nil(t, vulnerabilities.SetAdvisories(context.Background(), "osv", moduleKey("c"), "v0.9.0",
+		[]*graphstore.Advisory{{ID: "GHSA-2", Severity: "LOW"}}))
+
+	at := func(day int) time.Time {
+		return time.Date(2020, time.January, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	encode := func(msg interface{}) *store.GraphItem {
+		item, err := Encode(msg)
+		require.Nil(t, err)
+		return item
+	}
+
+	change := func(day int, from, to string, deleted bool) *graphstore.Change {
+		item := encode(&schema.Depends{Language: "go", VersionConstraint: "v1.0.0"})
+		item.K1 = moduleKey(from)
+		item.K2 = moduleKey(to)
+
+		return &graphstore.Change{
+			Item:      item,
+			From:      encode(&schema.Module{Language: "go", Organization: "depscloud", Module: from}),
+			To:        encode(&schema.Module{Language: "go", Organization: "depscloud", Module: to}),
+			Deleted:   deleted,
+			Timestamp: at(day),
+		}
+	}
+
+	history := fakeHistory{
+		change(1, "a", "e", false),
+		change(1, "a", "b", false),
+		change(5, "a", "c", false),
+		change(6, "a", "e", true),
+	}
+
+	generator := NewReportGenerator(gs, labels, history, vulnerabilities, "")
+
+	report, err := generator.Generate(context.Background(), "depscloud", at(3), at(10), 10)
+	require.Nil(t, err)
+	require.Equal(t, 4, report.Modules)
+	require.Equal(t, 2, report.Dependencies)
+
+	require.Len(t, report.Added, 1)
+	require.Equal(t, "c", report.Added[0].Module.GetModule())
+	require.Len(t, report.Removed, 1)
+	require.Equal(t, "e", report.Removed[0].Module.GetModule())
+
+	require.Len(t, report.Risky, 2)
+	require.Equal(t, "c", report.Risky[0].Module.GetModule())
+	require.Equal(t, 10, report.Risky[0].Score)
+	require.Equal(t, []string{"GHSA-1"}, report.Risky[0].Advisories)
+	require.Equal(t, "b", report.Risky[1].Module.GetModule())
+	require.Equal(t, 7, report.Risky[1].Score)
+	require.Equal(t, 2, report.Risky[1].Dependents)
+
+	require.Equal(t, map[string]int{"MIT": 1, LicenseUnknown: 1}, report.Licenses)
+
+	report, err = generator.Generate(context.Background(), "depscloud", at(3), at(10), 1)
+	require.Nil(t, err)
+	require.Len(t, report.Risky, 1)
+
+	// formats
+	body, err := report.render(ReportFormatJSON)
+	require.Nil(t, err)
+	decoded := &OrganizationReport{}
+	require.Nil(t, json.Unmarshal(body, decoded))
+	require.Equal(t, "depscloud", decoded.Organization)
+
+	body, err = report.render(ReportFormatHTML)
+	require.Nil(t, err)
+	require.Contains(t, string(body), "<h1>Dependency report for depscloud</h1>")
+
+	body, err = report.render(ReportFormatPDF)
+	require.Nil(t, err)
+	require.True(t, bytes.HasPrefix(body, []byte("%PDF-1.4")))
+	require.True(t, bytes.HasSuffix(body, []byte("%%EOF\n")))
+
+	// delivery
+	uploads := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		data, err := ioutil.ReadAll(r.Body)
+		require.Nil(t, err)
+		uploads[r.URL.Path] = r.Header.Get("Content-Type") + " " + string(data[:4])
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "reports")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	definition := &ReportDefinition{
+		Name:          "weekly",
+		Organizations: []string{"depscloud"},
+		Formats:       []string{ReportFormatJSON, ReportFormatPDF},
+		Top:           10,
+		Storage:       &ReportStorage{Location: server.URL + "/reports/", Headers: map[string]string{"Authorization": "Bearer token"}},
+	}
+
+	require.Nil(t, generator.deliver(context.Background(), definition, at(3), at(10)))
+	require.Equal(t, map[string]string{
+		"/reports/depscloud/weekly-2020-01-10.json": "application/json {\n  ",
+		"/reports/depscloud/weekly-2020-01-10.pdf":  "application/pdf %PDF",
+	}, uploads)
+
+	definition.Storage = &ReportStorage{Location: "file://" + dir}
+	require.Nil(t, generator.deliver(context.Background(), definition, at(3), at(10)))
+
+	stored, err := ioutil.ReadFile(filepath.Join(dir, "depscloud", "weekly-2020-01-10.json"))
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(stored, decoded))
+
+	email := &EmailChannel{From: "depscloud@example.com", To: []string{"platform@example.com"}}
+	message, err := email.reportMessage("weekly report for depscloud", report.lines(), []*renderedReport{
+		{name: "weekly-2020-01-10.pdf", contentType: "application/pdf", body: []byte("%PDF")},
+	})
+	require.Nil(t, err)
+	require.Contains(t, string(message), "Subject: [depscloud] weekly report for depscloud\r\n")
+	require.Contains(t, string(message), "Content-Type: multipart/mixed; boundary=")
+	require.Contains(t, string(message), `attachment; filename="weekly-2020-01-10.pdf"`)
+	require.Contains(t, string(message), "JVBERg==")
+}
+
+func TestReportConfig(t *testing.T) {
+	config := &ReportConfig{
+		Reports: []*ReportDefinition{
+			{
+				Name:          "weekly",
+				Schedule:      "0 8 * * 1",
+				Organizations: []string{"depscloud"},
+				Storage:       &ReportStorage{Location: "/var/reports"},
+			},
+		},
+	}
+	require.Nil(t, config.validate())
+	require.Equal(t, "license", config.LicenseLabel)
+	require.Equal(t, []string{ReportFormatJSON}, config.Reports[0].Formats)
+	require.Equal(t, defaultReportTop, config.Reports[0].Top)
+
+	invalid := func(mutate func(definition *ReportDefinition), message string) {
+		definition := &ReportDefinition{
+			Name:          "weekly",
+			Schedule:      "@weekly",
+			Organizations: []string{"depscloud"},
+			Storage:       &ReportStorage{Location: "/var/reports"},
+		}
+		mutate(definition)
+
+		err := (&ReportConfig{Reports: []*ReportDefinition{definition}}).validate()
+		require.NotNil(t, err)
+		require.True(t, strings.Contains(err.Error(), message), err.Error())
+	}
+
+	invalid(func(d *ReportDefinition) { d.Schedule = "weekly" }, "invalid schedule")
+	invalid(func(d *ReportDefinition) { d.Organizations = nil }, "requires organizations")
+	invalid(func(d *ReportDefinition) { d.Formats = []string{"docx"} }, "unsupported format docx")
+	invalid(func(d *ReportDefinition) { d.Storage = nil }, "requires a storage or email")
+	invalid(func(d *ReportDefinition) { d.Storage = nil; d.Email = &EmailChannel{Address: "smtp:25"} }, "requires an email address")
+}
+
+func TestReportService(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b"},
+	})
+
+	server := http.NewServeMux()
+	RegisterReportService(server, NewReportGenerator(gs, nil, nil, nil, ""))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/reports/organizations?"+query, nil))
+		return recorder
+	}
+
+	recorder := get("organization=depscloud")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	report := &OrganizationReport{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(report))
+	require.Equal(t, 1, report.Dependencies)
+	require.Equal(t, map[string]int{LicenseUnknown: 1}, report.Licenses)
+
+	recorder = get("organization=depscloud&format=pdf")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/pdf", recorder.Header().Get("Content-Type"))
+
+	require.Equal(t, http.StatusBadRequest, get("").Code)
+	require.Equal(t, http.StatusBadRequest, get("organization=depscloud&format=docx").Code)
+	require.Equal(t, http.StatusBadRequest, get("organization=depscloud&since=2030-01-01T00:00:00Z").Code)
+	require.Equal(t, http.StatusBadRequest, get("organization=depscloud&top=0").Code)
+}
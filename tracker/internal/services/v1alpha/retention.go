@@ -0,0 +1,39 @@
+package v1alpha
+
+import (
+	"context"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+)
+
+// RunRetention periodically applies the retention policy to the graph and
+// logs what was removed. During a dry run, nothing is removed and the report
+// describes what would have been. It runs until the context is canceled.
+func RunRetention(ctx context.Context, retention graphstore.Retention, policy *graphstore.RetentionPolicy, interval time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := retention.ApplyRetention(ctx, policy, time.Now(), dryRun)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[service.retention] failed to apply retention: %s", err.Error())
+			continue
+		}
+
+		prefix := "[service.retention]"
+		if report.DryRun {
+			prefix = "[service.retention] dry run,"
+		}
+
+		logging.FromContext(ctx).Infof("%s staleEdges=%d staleSources=%d purgedTombstones=%d purgedHistory=%d",
+			prefix, report.StaleEdges, report.StaleSources, report.PurgedTombstones, report.PurgedHistory)
+	}
+}
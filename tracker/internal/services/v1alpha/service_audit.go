@@ -0,0 +1,128 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/audit"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/tenants"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// RegisterAuditService registers the auditService routes with the http server.
+func RegisterAuditService(server *http.ServeMux, auditLog graphstore.AuditLog) {
+	svc := &auditService{auditLog: auditLog}
+
+	server.HandleFunc(audit.RoutePrefix+"records", svc.Records)
+}
+
+// DatabaseSink writes the audit records of the tracker to the audit log of
+// the graph store.
+type DatabaseSink struct {
+	AuditLog graphstore.AuditLog
+}
+
+func (d *DatabaseSink) Write(ctx context.Context, records []*audit.Record) error {
+	return d.AuditLog.WriteAuditRecords(ctx, records)
+}
+
+var _ audit.Sink = &DatabaseSink{}
+
+type auditService struct {
+	auditLog graphstore.AuditLog
+}
+
+// Records handles GET and POST /v1alpha/audit/records. GET lists the records
+// of the tenant, newest first, optionally narrowed by the service, subject,
+// action, since, and until (RFC 3339) parameters. The limit parameter caps the
+// number of records returned. POST stores records written by other services
+// using the http sink. Callers acting within a tenant can only write records
+// for it.
+func (s *auditService) Records(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		req := &audit.RecordsRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("failed to parse records"))
+			return
+		}
+
+		tenant := tenants.FromContext(ctx)
+		for _, record := range req.Records {
+			if record.Action == "" || record.Service == "" || record.Time.IsZero() {
+				writeError(w, r, http.StatusBadRequest, fmt.Errorf("records require a time, service, and action"))
+				return
+			}
+
+			if tenant != "" {
+				record.Tenant = tenant
+			}
+		}
+
+		if err := s.auditLog.WriteAuditRecords(ctx, req.Records); err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.audit] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to write records"))
+			return
+		}
+
+		writeJSON(w, r, http.StatusOK, &audit.RecordsResponse{Records: req.Records})
+		return
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+	auditQuery := &graphstore.AuditQuery{
+		Service: query.Get("service"),
+		Subject: query.Get("subject"),
+		Action:  query.Get("action"),
+		Limit:   defaultAuditLimit,
+	}
+
+	var err error
+	if value := query.Get("since"); value != "" {
+		if auditQuery.Since, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("since must be an RFC 3339 timestamp"))
+			return
+		}
+	}
+
+	if value := query.Get("until"); value != "" {
+		if auditQuery.Until, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("until must be an RFC 3339 timestamp"))
+			return
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		if auditQuery.Limit, err = strconv.Atoi(value); err != nil || auditQuery.Limit <= 0 {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+			return
+		} else if auditQuery.Limit > maxAuditLimit {
+			auditQuery.Limit = maxAuditLimit
+		}
+	}
+
+	records, err := s.auditLog.ListAuditRecords(ctx, auditQuery)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.audit] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to list records"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &audit.RecordsResponse{Records: records})
+}
@@ -0,0 +1,100 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/audit"
+	"github.com/depscloud/depscloud/internal/tenants"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuditLog holds records in the order they were written.
+type fakeAuditLog struct {
+	records []*audit.Record
+	query   *graphstore.AuditQuery
+}
+
+func (f *fakeAuditLog) WriteAuditRecords(ctx context.Context, records []*audit.Record) error {
+	f.records = append(f.records, records...)
+	return nil
+}
+
+func (f *fakeAuditLog) ListAuditRecords(ctx context.Context, query *graphstore.AuditQuery) ([]*audit.Record, error) {
+	f.query = query
+
+	results := make([]*audit.Record, 0)
+	for i := len(f.records) - 1; i >= 0 && len(results) < query.Limit; i-- {
+		if f.records[i].Tenant == tenants.FromContext(ctx) {
+			results = append(results, f.records[i])
+		}
+	}
+	return results, nil
+}
+
+func TestAudit(t *testing.T) {
+	auditLog := &fakeAuditLog{}
+
+	server := http.NewServeMux()
+	RegisterAuditService(server, auditLog)
+
+	call := func(tenant, method, path, body string) (int, []*audit.Record) {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request = request.WithContext(tenants.NewContext(request.Context(), tenant))
+
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			return recorder.Code, nil
+		}
+
+		response := &audit.RecordsResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		return recorder.Code, response.Records
+	}
+
+	code, _ := call("", http.MethodPost, "/v1alpha/audit/records", `{"records":[
+		{"time":"2021-03-01T00:00:00Z","service":"gateway","subject":"indexer","action":"/cloud.deps.api.v1alpha.tracker.SourceService/Track","status":200},
+		{"time":"2021-03-01T00:01:00Z","service":"gateway","tenant":"payments","action":"PUT /v1alpha/labels/modules","status":200}
+	]}`)
+	require.Equal(t, http.StatusOK, code)
+
+	// callers within a tenant only write records for it
+	code, _ = call("search", http.MethodPost, "/v1alpha/audit/records", `{"records":[
+		{"time":"2021-03-01T00:02:00Z","service":"gateway","tenant":"payments","action":"POST /v1alpha/graph/import","status":200}
+	]}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "search", auditLog.records[2].Tenant)
+
+	code, _ = call("", http.MethodPost, "/v1alpha/audit/records", `{"records":[{"service":"gateway"}]}`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, records := call("", http.MethodGet, "/v1alpha/audit/records?subject=indexer&since=2021-03-01T00:00:00Z&limit=5000", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, records, 1)
+	require.Equal(t, "indexer", records[0].Subject)
+	require.Equal(t, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), records[0].Time)
+	require.Equal(t, &graphstore.AuditQuery{
+		Subject: "indexer",
+		Since:   time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+		Limit:   maxAuditLimit,
+	}, auditLog.query)
+
+	code, records = call("payments", http.MethodGet, "/v1alpha/audit/records", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, records, 1)
+	require.Equal(t, "PUT /v1alpha/labels/modules", records[0].Action)
+
+	code, _ = call("", http.MethodGet, "/v1alpha/audit/records?until=yesterday", "")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = call("", http.MethodDelete, "/v1alpha/audit/records", "")
+	require.Equal(t, http.StatusMethodNotAllowed, code)
+}
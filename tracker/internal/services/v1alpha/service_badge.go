@@ -0,0 +1,229 @@
+package v1alpha
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// BadgeRoutePrefix prefixes the HTTP routes rendering badges that can be
+// embedded in a README.
+const BadgeRoutePrefix = "/badge/"
+
+// colors used by shields.io
+const (
+	badgeLabelColor = "#555"
+	badgeBlue       = "#007ec6"
+	badgeGreen      = "#4c1"
+	badgeYellow     = "#dfb317"
+	badgeOrange     = "#fe7d37"
+	badgeRed        = "#e05d44"
+	badgeGrey       = "#9f9f9f"
+)
+
+// severityColors ranks the severities of advisories from least to most severe.
+var severityColors = []struct {
+	severity string
+	color    string
+}{
+	{"LOW", badgeYellow},
+	{"MODERATE", badgeOrange},
+	{"MEDIUM", badgeOrange},
+	{"HIGH", badgeRed},
+	{"CRITICAL", badgeRed},
+}
+
+// RegisterBadgeService registers the badgeService routes with the http
+// server. The vulnerability badge is only available when vulnerabilities are
+// recorded.
+func RegisterBadgeService(server *http.ServeMux, gs store.GraphStoreClient, counts graphstore.Counts, vulnerabilities graphstore.Vulnerabilities, aliases *Aliases) {
+	svc := &badgeService{
+		counts:          &countService{gs: gs, counts: counts, aliases: aliases},
+		vulnerabilities: vulnerabilities,
+		aliases:         aliases,
+	}
+
+	server.HandleFunc(BadgeRoutePrefix+"dependents", svc.Dependents)
+	if vulnerabilities != nil {
+		server.HandleFunc(BadgeRoutePrefix+"vulnerabilities", svc.Vulnerabilities)
+	}
+}
+
+type badgeService struct {
+	counts          *countService
+	vulnerabilities graphstore.Vulnerabilities
+	aliases         *Aliases
+}
+
+// Badge is a shields.io style badge.
+type Badge struct {
+	Label   string
+	Message string
+	Color   string
+}
+
+// textWidth approximates the width of text rendered in 11px Verdana.
+func textWidth(text string) int {
+	return utf8.RuneCountInString(text)*7 + 10
+}
+
+// SVG renders the badge.
+func (b *Badge) SVG() string {
+	label, message := html.EscapeString(b.Label), html.EscapeString(b.Message)
+	labelWidth, messageWidth := textWidth(b.Label), textWidth(b.Message)
+	width := labelWidth + messageWidth
+
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, label, message)
+	fmt.Fprintf(buf, `<title>%s: %s</title>`, label, message)
+	buf.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(buf, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, width)
+	fmt.Fprintf(buf, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="%s"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		labelWidth, badgeLabelColor, labelWidth, messageWidth, html.EscapeString(b.Color), width)
+	buf.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	for _, text := range []struct {
+		x     int
+		value string
+	}{
+		{labelWidth / 2, label},
+		{labelWidth + messageWidth/2, message},
+	} {
+		fmt.Fprintf(buf, `<text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`, text.x, text.value, text.x, text.value)
+	}
+	buf.WriteString(`</g></svg>`)
+
+	return buf.String()
+}
+
+// writeBadge renders the badge, letting the label parameter of the request
+// replace its label. Badges are cached briefly since they're often embedded
+// in pages that are viewed frequently.
+func writeBadge(w http.ResponseWriter, r *http.Request, status int, badge *Badge) {
+	if label := r.URL.Query().Get("label"); label != "" {
+		badge.Label = label
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml;charset=utf-8")
+	w.Header().Set("Cache-Control", "max-age=300")
+	w.WriteHeader(status)
+
+	if _, err := w.Write([]byte(badge.SVG())); err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.badge] failed to write response: %s", err.Error())
+	}
+}
+
+// formatCount abbreviates large counts the way shields.io does.
+func formatCount(count int64) string {
+	switch {
+	case count >= 1000000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(count)/1000000), ".0") + "M"
+	case count >= 1000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(count)/1000), ".0") + "k"
+	}
+	return fmt.Sprintf("%d", count)
+}
+
+// Dependents handles GET /badge/dependents. It renders the number of modules
+// that directly depend on the module identified by the language,
+// organization, and module parameters.
+func (b *badgeService) Dependents(w http.ResponseWriter, r *http.Request) {
+	badge := &Badge{Label: "dependents", Message: "invalid", Color: badgeGrey}
+
+	if r.Method != http.MethodGet {
+		writeBadge(w, r, http.StatusMethodNotAllowed, badge)
+		return
+	}
+
+	req, err := parseModule(r)
+	if err != nil {
+		writeBadge(w, r, http.StatusBadRequest, badge)
+		return
+	}
+
+	find := &store.FindRequest{
+		Keys:      [][]byte{keyForDependencyRequest(b.aliases.request(req))},
+		EdgeTypes: []string{types.DependsType},
+		NodeTypes: []string{types.ModuleType},
+	}
+
+	count, err := b.counts.count(r.Context(), find, false, false)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.badge] %s", err.Error())
+		badge.Message = "unavailable"
+		writeBadge(w, r, http.StatusInternalServerError, badge)
+		return
+	}
+
+	badge.Message = formatCount(count)
+	badge.Color = badgeBlue
+	writeBadge(w, r, http.StatusOK, badge)
+}
+
+// Vulnerabilities handles GET /badge/vulnerabilities. It renders the number
+// of advisories affecting the module identified by the language,
+// organization, and module parameters, colored by the most severe of them.
+// The optional version parameter limits the advisories to a single version.
+func (b *badgeService) Vulnerabilities(w http.ResponseWriter, r *http.Request) {
+	badge := &Badge{Label: "vulnerabilities", Message: "invalid", Color: badgeGrey}
+
+	if r.Method != http.MethodGet {
+		writeBadge(w, r, http.StatusMethodNotAllowed, badge)
+		return
+	}
+
+	req, err := parseModule(r)
+	if err != nil {
+		writeBadge(w, r, http.StatusBadRequest, badge)
+		return
+	}
+
+	key := keyForDependencyRequest(b.aliases.request(req))
+
+	affected, err := b.vulnerabilities.GetAdvisories(r.Context(), [][]byte{key})
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.badge] %s", err.Error())
+		badge.Message = "unavailable"
+		writeBadge(w, r, http.StatusInternalServerError, badge)
+		return
+	}
+
+	version := r.URL.Query().Get("version")
+	advisories := make(map[string]bool)
+	worst := -1
+
+	for _, a := range affected {
+		if version != "" && a.Version != version {
+			continue
+		}
+		advisories[a.Advisory.ID] = true
+
+		// advisories without a known severity are treated as severe
+		rank := len(severityColors) - 1
+		for i, s := range severityColors {
+			if strings.EqualFold(s.severity, a.Advisory.Severity) {
+				rank = i
+			}
+		}
+
+		if rank > worst {
+			worst = rank
+		}
+	}
+
+	if len(advisories) == 0 {
+		badge.Message = "none"
+		badge.Color = badgeGreen
+	} else {
+		badge.Message = formatCount(int64(len(advisories)))
+		badge.Color = severityColors[worst].color
+	}
+
+	writeBadge(w, r, http.StatusOK, badge)
+}
@@ -0,0 +1,78 @@
+package v1alpha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBadge(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c"},
+		"d": {"c"},
+	})
+
+	vulnerabilities := fakeVulnerabilities{}
+	require.Nil(t, vulnerabilities.SetAdvisories(context.Background(), "osv", moduleKey("b"), "v1.0.0", []*graphstore.Advisory{
+		{ID: "GHSA-0001", Severity: "moderate"},
+	}))
+	require.Nil(t, vulnerabilities.SetAdvisories(context.Background(), "osv", moduleKey("b"), "v2.0.0", []*graphstore.Advisory{
+		{ID: "GHSA-0002", Severity: "CRITICAL"},
+		{ID: "GHSA-0001", Severity: "moderate"},
+	}))
+
+	server := http.NewServeMux()
+	RegisterBadgeService(server, gs, nil, vulnerabilities, nil)
+
+	badge := func(route, query string) (int, string) {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/badge/"+route+"?"+query, nil))
+		require.Equal(t, "image/svg+xml;charset=utf-8", recorder.Header().Get("Content-Type"))
+		return recorder.Code, recorder.Body.String()
+	}
+
+	code, body := badge("dependents", "language=go&organization=depscloud&module=c")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `aria-label="dependents: 3"`)
+	require.Contains(t, body, badgeBlue)
+
+	code, body = badge("dependents", "language=go&organization=depscloud&module=a&label=used%20by")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `aria-label="used by: 0"`)
+
+	code, body = badge("dependents", "module=c")
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Contains(t, body, `aria-label="dependents: invalid"`)
+
+	code, body = badge("vulnerabilities", "language=go&organization=depscloud&module=b")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `aria-label="vulnerabilities: 2"`)
+	require.Contains(t, body, badgeRed)
+
+	code, body = badge("vulnerabilities", "language=go&organization=depscloud&module=b&version=v1.0.0")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `aria-label="vulnerabilities: 1"`)
+	require.Contains(t, body, badgeOrange)
+
+	code, body = badge("vulnerabilities", "language=go&organization=depscloud&module=c")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `aria-label="vulnerabilities: none"`)
+	require.Contains(t, body, badgeGreen)
+
+	// labels are escaped
+	code, body = badge("dependents", "language=go&organization=depscloud&module=c&label=%3Cb%3E")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "&lt;b&gt;: 3")
+	require.NotContains(t, body, "<b>")
+
+	require.Equal(t, "999", formatCount(999))
+	require.Equal(t, "1k", formatCount(1000))
+	require.Equal(t, "1.5k", formatCount(1530))
+	require.Equal(t, "2.3M", formatCount(2250001))
+}
@@ -0,0 +1,154 @@
+package v1alpha
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// ComplianceRoutePrefix prefixes the HTTP routes used to evaluate the
+// licenses of dependencies against the license policy.
+const ComplianceRoutePrefix = "/v1alpha/compliance/"
+
+// RegisterComplianceService registers the complianceService routes with the
+// http server
+func RegisterComplianceService(server *http.ServeMux, gs store.GraphStoreClient, labels graphstore.Labels, policy *LicensePolicy, aliases *Aliases) {
+	svc := &complianceService{gs: gs, labels: labels, policy: policy, aliases: aliases}
+
+	server.HandleFunc(ComplianceRoutePrefix+"modules", svc.Modules)
+	server.HandleFunc(ComplianceRoutePrefix+"sources", svc.Sources)
+	server.HandleFunc(ComplianceRoutePrefix+"report", svc.Report)
+}
+
+type complianceService struct {
+	gs      store.GraphStoreClient
+	labels  graphstore.Labels
+	policy  *LicensePolicy
+	aliases *Aliases
+}
+
+// ComplianceResponse contains the evaluations of the modules that were
+// requested. Its status is the worst status of the evaluations.
+type ComplianceResponse struct {
+	Status      string                  `json:"status"`
+	Evaluations []*ComplianceEvaluation `json:"evaluations"`
+}
+
+func (c *complianceService) respond(w http.ResponseWriter, r *http.Request, modules map[string]*schema.Module) {
+	evaluations, err := evaluateModules(r.Context(), c.gs, c.labels, c.policy, modules)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.compliance] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to evaluate licenses"))
+		return
+	}
+
+	status := LicenseAllowed
+	for _, evaluation := range evaluations {
+		status = worseStatus(status, evaluation.Status)
+	}
+
+	writeJSON(w, r, http.StatusOK, &ComplianceResponse{Status: status, Evaluations: evaluations})
+}
+
+// Modules handles GET /v1alpha/compliance/modules. The module is identified
+// by the language, organization, and module parameters.
+func (c *complianceService) Modules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	req, err := parseModule(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	req = c.aliases.request(req)
+	module := &schema.Module{
+		Language:     req.GetLanguage(),
+		Organization: req.GetOrganization(),
+		Module:       req.GetModule(),
+		Name:         req.GetName(),
+	}
+
+	c.respond(w, r, map[string]*schema.Module{
+		string(keyForDependencyRequest(req)): module,
+	})
+}
+
+// Sources handles GET /v1alpha/compliance/sources. Each of the modules the
+// source identified by the url parameter manages is evaluated.
+func (c *complianceService) Sources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("url is required"))
+		return
+	}
+
+	key := keyForSource(&schema.Source{Url: url})
+
+	pairs, err := findPairs(r.Context(), c.gs.FindUpstream, [][]byte{key}, types.ManagesType, types.ModuleType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.compliance] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find managed modules"))
+		return
+	}
+
+	modules := make(map[string]*schema.Module, len(pairs))
+	for _, pair := range pairs {
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		modules[string(pair.GetNode().GetK1())] = node.(*schema.Module)
+	}
+
+	c.respond(w, r, modules)
+}
+
+// Report handles GET /v1alpha/compliance/report, summarizing the violations
+// of every module in the graph. The language and organization parameters
+// narrow the modules that are evaluated.
+func (c *complianceService) Report(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+	filter := &filters.Filter{
+		Language:     query.Get("language"),
+		Organization: query.Get("organization"),
+	}
+
+	ctx := r.Context()
+
+	modules, err := listModules(ctx, c.gs, filter)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.compliance] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to list modules"))
+		return
+	}
+
+	evaluations, err := evaluateModules(ctx, c.gs, c.labels, c.policy, modules)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.compliance] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to evaluate licenses"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, summarize(evaluations))
+}
@@ -0,0 +1,115 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicenseRules(t *testing.T) {
+	rules := &LicenseRules{
+		Allow: []string{"MIT", "Apache-2.0", "LGPL-2.1"},
+		Deny:  []string{"GPL-3.0"},
+		Flag:  []string{"LGPL-2.1"},
+	}
+
+	for _, test := range []struct {
+		license, status string
+	}{
+		{"MIT", LicenseAllowed},
+		{"mit", LicenseAllowed},
+		{"", LicenseUnknown},
+		{"GPL-3.0", LicenseDenied},
+		{"BSD-3-Clause", LicenseDenied},
+		{"LGPL-2.1", LicenseFlagged},
+		{"GPL-3.0 OR MIT", LicenseAllowed},
+		{"(MIT AND GPL-3.0)", LicenseDenied},
+		{"MIT AND LGPL-2.1 OR GPL-3.0", LicenseFlagged},
+	} {
+		status, _ := rules.evaluate(test.license)
+		require.Equal(t, test.status, status, test.license)
+	}
+
+	_, reason := rules.evaluate("BSD-3-Clause")
+	require.Equal(t, "BSD-3-Clause is not allowed", reason)
+
+	// without an allow list, anything not denied or flagged is allowed
+	status, _ := (&LicenseRules{Deny: []string{"GPL-3.0"}}).evaluate("BSD-3-Clause")
+	require.Equal(t, LicenseAllowed, status)
+}
+
+func TestCompliance(t *testing.T) {
+	file, err := ioutil.TempFile("", "policy.yaml")
+	require.Nil(t, err)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString("deny: [GPL-3.0]\nflag: [LGPL-2.1]\norganizations:\n  other:\n    allow: [MIT]\n")
+	require.Nil(t, err)
+	require.Nil(t, file.Close())
+
+	policy, err := LoadLicensePolicyFile(file.Name())
+	require.Nil(t, err)
+	require.Equal(t, "license", policy.Label)
+	require.Equal(t, []string{"MIT"}, policy.rules("other").Allow)
+	require.Equal(t, []string{"GPL-3.0"}, policy.rules("depscloud").Deny)
+
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"d": {"c", "e"},
+	})
+	gs.manage(t, "https://github.com/depscloud/a.git", "a")
+
+	labels := fakeLabels{}
+	labels[types.ModuleType+string(moduleKey("b"))] = map[string]string{"license": "MIT"}
+	labels[types.ModuleType+string(moduleKey("c"))] = map[string]string{"license": "GPL-3.0"}
+	labels[types.ModuleType+string(moduleKey("e"))] = map[string]string{"license": "LGPL-2.1"}
+
+	server := http.NewServeMux()
+	RegisterComplianceService(server, gs, labels, policy, nil)
+
+	get := func(path string, body interface{}) int {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code == http.StatusOK {
+			require.Nil(t, json.NewDecoder(recorder.Body).Decode(body))
+		}
+		return recorder.Code
+	}
+
+	response := &ComplianceResponse{}
+	require.Equal(t, http.StatusOK, get("/v1alpha/compliance/modules?language=go&organization=depscloud&module=a", response))
+	require.Equal(t, LicenseDenied, response.Status)
+	require.Len(t, response.Evaluations, 1)
+	require.Len(t, response.Evaluations[0].Findings, 2)
+
+	response = &ComplianceResponse{}
+	require.Equal(t, http.StatusOK, get("/v1alpha/compliance/modules?language=go&organization=depscloud&module=b", response))
+	require.Equal(t, LicenseAllowed, response.Status)
+	require.Len(t, response.Evaluations[0].Findings, 0)
+
+	response = &ComplianceResponse{}
+	require.Equal(t, http.StatusOK, get("/v1alpha/compliance/sources?url=https://github.com/depscloud/a.git", response))
+	require.Equal(t, LicenseDenied, response.Status)
+	require.Len(t, response.Evaluations, 1)
+	require.Equal(t, "a", response.Evaluations[0].Module.GetModule())
+
+	report := &ComplianceReport{}
+	require.Equal(t, http.StatusOK, get("/v1alpha/compliance/report", report))
+	require.Equal(t, 5, report.Modules)
+	require.Equal(t, map[string]int{LicenseAllowed: 3, LicenseDenied: 2}, report.Statuses)
+	require.Equal(t, map[string]int{"GPL-3.0": 2, "LGPL-2.1": 1}, report.Licenses)
+	require.Equal(t, map[string]int{"depscloud": 2}, report.Organizations)
+	require.Len(t, report.Violations, 2)
+	require.Equal(t, "a", report.Violations[0].Module.GetModule())
+	require.Len(t, report.Violations[0].Findings, 1)
+	require.Len(t, report.Violations[1].Findings, 2)
+
+	require.Equal(t, http.StatusBadRequest, get("/v1alpha/compliance/sources", nil))
+}
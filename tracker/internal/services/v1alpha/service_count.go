@@ -0,0 +1,116 @@
+package v1alpha
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// RegisterCountService registers the countService routes with the http
+// server. Counts are optional, without them the edges are read and counted.
+func RegisterCountService(server *http.ServeMux, gs store.GraphStoreClient, counts graphstore.Counts, aliases *Aliases) {
+	svc := &countService{gs: gs, counts: counts, aliases: aliases}
+
+	server.HandleFunc(QueryRoutePrefix+"count-dependents", svc.CountDependents)
+	server.HandleFunc(QueryRoutePrefix+"count-dependencies", svc.CountDependencies)
+}
+
+type countService struct {
+	gs      store.GraphStoreClient
+	counts  graphstore.Counts
+	aliases *Aliases
+}
+
+// CountResponse contains the number of distinct modules matching a query.
+type CountResponse struct {
+	Count int64 `json:"count"`
+}
+
+// CountDependents handles GET /v1alpha/queries/count-dependents. It returns
+// the number of modules that directly depend on the module identified by the
+// language, organization, and module parameters, without listing them. The
+// optional as_of parameter counts the dependents at a point in time.
+func (c *countService) CountDependents(w http.ResponseWriter, r *http.Request) {
+	c.handle(w, r, false)
+}
+
+// CountDependencies handles GET /v1alpha/queries/count-dependencies. It takes
+// the same parameters as CountDependents and returns the number of modules the
+// module directly depends on.
+func (c *countService) CountDependencies(w http.ResponseWriter, r *http.Request) {
+	c.handle(w, r, true)
+}
+
+func (c *countService) handle(w http.ResponseWriter, r *http.Request, upstream bool) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	req, err := parseModule(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, err := queryContext(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	find := &store.FindRequest{
+		Keys:      [][]byte{keyForDependencyRequest(c.aliases.request(req))},
+		EdgeTypes: []string{types.DependsType},
+		NodeTypes: []string{types.ModuleType},
+	}
+
+	count, err := c.count(ctx, find, upstream, r.URL.Query().Get("as_of") != "")
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.count] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to count modules"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &CountResponse{Count: count})
+}
+
+// count returns the number of distinct modules matching the request, using
+// the counts of the store when they're available.
+func (c *countService) count(ctx context.Context, find *store.FindRequest, upstream, historical bool) (int64, error) {
+	// counting in the store doesn't support reading from the history
+	if c.counts == nil || historical {
+		return c.countPairs(ctx, find, upstream)
+	}
+
+	if upstream {
+		return c.counts.CountUpstream(ctx, find)
+	}
+	return c.counts.CountDownstream(ctx, find)
+}
+
+// countPairs reads the pairs matching the request and counts the distinct
+// nodes among them.
+func (c *countService) countPairs(ctx context.Context, find *store.FindRequest, upstream bool) (int64, error) {
+	findFn := c.gs.FindDownstream
+	if upstream {
+		findFn = c.gs.FindUpstream
+	}
+
+	resp, err := findFn(ctx, find)
+	if err != nil {
+		return 0, err
+	}
+
+	nodes := make(map[string]bool)
+	for _, pair := range resp.GetPairs() {
+		nodes[string(pair.GetNode().GetK1())] = true
+	}
+
+	return int64(len(nodes)), nil
+}
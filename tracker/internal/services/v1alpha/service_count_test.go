@@ -0,0 +1,41 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCount(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c"},
+		"d": {"c"},
+	})
+
+	server := http.NewServeMux()
+	RegisterCountService(server, gs, nil, nil)
+
+	count := func(route, module string) int64 {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/v1alpha/queries/"+route+"?language=go&organization=depscloud&module="+module, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		response := &CountResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		return response.Count
+	}
+
+	require.Equal(t, int64(3), count("count-dependents", "c"))
+	require.Equal(t, int64(0), count("count-dependents", "a"))
+	require.Equal(t, int64(2), count("count-dependencies", "a"))
+	require.Equal(t, int64(0), count("count-dependencies", "c"))
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/queries/count-dependents", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
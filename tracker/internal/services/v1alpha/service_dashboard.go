@@ -0,0 +1,460 @@
+package v1alpha
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/depscloud/api"
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// DashboardRoutePrefix prefixes the HTTP routes that return everything a page
+// of a web UI needs in a single call, saving browsers from a waterfall of
+// requests.
+const DashboardRoutePrefix = "/v1alpha/dashboard/"
+
+const (
+	// defaultDashboardTop is the number of dependents a dashboard lists by
+	// default.
+	defaultDashboardTop = 10
+
+	// defaultDashboardWindow is how far back a dashboard looks for changes by
+	// default.
+	defaultDashboardWindow = 7 * 24 * time.Hour
+
+	// dashboardConcurrency bounds the number of dependents counted at once.
+	dashboardConcurrency = 8
+)
+
+// RegisterDashboardService registers the dashboardService routes with the
+// http server. History and vulnerabilities are optional and leave out the
+// parts of the dashboard that depend on them.
+func RegisterDashboardService(
+	server *http.ServeMux,
+	gs store.GraphStoreClient,
+	counts graphstore.Counts,
+	history graphstore.History,
+	vulnerabilities graphstore.Vulnerabilities,
+	aliases *Aliases,
+) {
+	svc := &dashboardService{
+		gs:              gs,
+		counts:          &countService{gs: gs, counts: counts, aliases: aliases},
+		history:         history,
+		vulnerabilities: vulnerabilities,
+		aliases:         aliases,
+	}
+
+	server.HandleFunc(DashboardRoutePrefix+"sources", svc.Source)
+	server.HandleFunc(DashboardRoutePrefix+"modules", svc.Module)
+}
+
+type dashboardService struct {
+	gs              store.GraphStoreClient
+	counts          *countService
+	history         graphstore.History
+	vulnerabilities graphstore.Vulnerabilities
+	aliases         *Aliases
+}
+
+// DashboardModule is a module shown on a dashboard along with the number of
+// modules that directly depend on it and that it directly depends on.
+type DashboardModule struct {
+	Module       *schema.Module  `json:"module"`
+	Manages      *schema.Manages `json:"manages,omitempty"`
+	Dependents   int64           `json:"dependents"`
+	Dependencies int64           `json:"dependencies"`
+}
+
+// DashboardVulnerability is an advisory affecting a version of a module that
+// the modules on a dashboard depend on.
+type DashboardVulnerability struct {
+	Module   *schema.Module       `json:"module"`
+	Version  string               `json:"version"`
+	Advisory *graphstore.Advisory `json:"advisory"`
+}
+
+// Dashboard summarizes a source or a module. TopDependents are the modules
+// depending on it, ranked by how many modules depend on them in turn. Changes
+// are the edges that changed since Since and are only known when the store
+// records its history. Vulnerabilities are only known when advisories are
+// recorded.
+type Dashboard struct {
+	Source          *schema.Source            `json:"source,omitempty"`
+	Sources         []*schema.Source          `json:"sources,omitempty"`
+	Modules         []*DashboardModule        `json:"modules"`
+	TopDependents   []*DashboardModule        `json:"top_dependents"`
+	Since           time.Time                 `json:"since"`
+	Changes         *DiffResponse             `json:"changes,omitempty"`
+	Vulnerabilities []*DashboardVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+// dashboardOptions holds the parameters shared by the dashboard routes.
+type dashboardOptions struct {
+	top   int
+	since time.Time
+}
+
+// parseDashboardOptions reads the optional top and since parameters.
+func parseDashboardOptions(r *http.Request) (*dashboardOptions, error) {
+	query := r.URL.Query()
+	options := &dashboardOptions{
+		top:   defaultDashboardTop,
+		since: time.Now().Add(-defaultDashboardWindow),
+	}
+
+	if value := query.Get("top"); value != "" {
+		top, err := strconv.Atoi(value)
+		if err != nil || top < 1 {
+			return nil, fmt.Errorf("top must be a positive number")
+		}
+		options.top = top
+	}
+
+	if value := query.Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("since must be an RFC 3339 timestamp")
+		}
+		options.since = since
+	}
+
+	return options, nil
+}
+
+// Source handles GET /v1alpha/dashboard/sources. The source is identified by
+// the url parameter. The dashboard lists the modules the source manages, the
+// top dependents of those modules, the changes made to the source, and the
+// advisories affecting the versions its modules depend on. The optional top
+// parameter limits the number of dependents and the optional since parameter,
+// an RFC 3339 timestamp, defaults to a week ago.
+func (d *dashboardService) Source(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("url is required"))
+		return
+	}
+
+	options, err := parseDashboardOptions(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	source := &schema.Source{Url: url}
+	sourceKey := keyForSource(source)
+
+	pairs, err := findPairs(ctx, d.gs.FindUpstream, [][]byte{sourceKey}, types.ManagesType, types.ModuleType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.dashboard] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to list modules"))
+		return
+	}
+
+	modules := make([]*DashboardModule, len(pairs))
+	keys := make([][]byte, len(pairs))
+	for i, pair := range pairs {
+		node, _ := Decode(pair.GetNode())
+		edge, _ := Decode(pair.GetEdge())
+
+		modules[i] = &DashboardModule{Module: node.(*schema.Module), Manages: edge.(*schema.Manages)}
+		keys[i] = pair.GetNode().GetK1()
+	}
+
+	dashboard := &Dashboard{Source: source, Modules: modules, Since: options.since}
+	if err := d.assemble(ctx, dashboard, sourceKey, keys, options); err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.dashboard] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to assemble dashboard"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, dashboard)
+}
+
+// Module handles GET /v1alpha/dashboard/modules. The module is identified by
+// the language, organization, and module parameters. The dashboard lists the
+// sources managing the module, its top dependents, the changes made to its
+// edges, and the advisories affecting the versions it depends on. It takes
+// the same optional parameters as Source.
+func (d *dashboardService) Module(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	req, err := parseModule(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	options, err := parseDashboardOptions(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	module := d.aliases.module(&schema.Module{
+		Language:     req.GetLanguage(),
+		Organization: req.GetOrganization(),
+		Module:       req.GetModule(),
+	})
+	key := keyForModule(module)
+
+	pairs, err := findPairs(ctx, d.gs.FindDownstream, [][]byte{key}, types.ManagesType, types.SourceType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.dashboard] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to list sources"))
+		return
+	}
+
+	sources := make([]*schema.Source, len(pairs))
+	for i, pair := range pairs {
+		node, _ := Decode(pair.GetNode())
+		sources[i] = node.(*schema.Source)
+	}
+
+	dashboard := &Dashboard{
+		Sources: sources,
+		Modules: []*DashboardModule{{Module: module}},
+		Since:   options.since,
+	}
+	if err := d.assemble(ctx, dashboard, key, [][]byte{key}, options); err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.dashboard] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to assemble dashboard"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, dashboard)
+}
+
+// parallel runs each of the tasks concurrently, returning the first error.
+func parallel(tasks ...func() error) error {
+	errs := make([]error, len(tasks))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(tasks))
+	for i, task := range tasks {
+		go func(i int, task func() error) {
+			defer wg.Done()
+			errs[i] = task()
+		}(i, task)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assemble fills in the parts of the dashboard that only depend on the keys
+// of its modules, fetching each of them concurrently. The history is read for
+// the changes made to the edges of the root key.
+func (d *dashboardService) assemble(ctx context.Context, dashboard *Dashboard, root []byte, keys [][]byte, options *dashboardOptions) error {
+	return parallel(
+		func() error {
+			return d.countModules(ctx, dashboard.Modules, keys)
+		},
+		func() error {
+			dependents, err := d.topDependents(ctx, keys, options.top)
+			dashboard.TopDependents = dependents
+			return err
+		},
+		func() error {
+			changes, err := d.changes(ctx, root, options.since)
+			dashboard.Changes = changes
+			return err
+		},
+		func() error {
+			vulnerabilities, err := d.dependencyVulnerabilities(ctx, keys)
+			dashboard.Vulnerabilities = vulnerabilities
+			return err
+		},
+	)
+}
+
+// count returns the number of modules that directly depend on the key, or that
+// the key directly depends on when upstream is set.
+func (d *dashboardService) count(ctx context.Context, key []byte, upstream bool) (int64, error) {
+	return d.counts.count(ctx, &store.FindRequest{
+		Keys:      [][]byte{key},
+		EdgeTypes: []string{types.DependsType},
+		NodeTypes: []string{types.ModuleType},
+	}, upstream, false)
+}
+
+// countModules fills in the number of dependents and dependencies of each of
+// the modules, counting up to dashboardConcurrency modules at once.
+func (d *dashboardService) countModules(ctx context.Context, modules []*DashboardModule, keys [][]byte) error {
+	window := make(chan struct{}, dashboardConcurrency)
+	tasks := make([]func() error, len(modules))
+
+	for i := range modules {
+		module, key := modules[i], keys[i]
+		tasks[i] = func() error {
+			window <- struct{}{}
+			defer func() { <-window }()
+
+			var err error
+			if module.Dependents, err = d.count(ctx, key, false); err != nil {
+				return err
+			}
+			module.Dependencies, err = d.count(ctx, key, true)
+			return err
+		}
+	}
+
+	return parallel(tasks...)
+}
+
+// topDependents returns up to top of the modules that directly depend on the
+// keys, ranked by how many modules depend on them.
+func (d *dashboardService) topDependents(ctx context.Context, keys [][]byte, top int) ([]*DashboardModule, error) {
+	pairs, err := findPairs(ctx, d.gs.FindDownstream, keys, types.DependsType, types.ModuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := make([]*DashboardModule, 0)
+	dependentKeys := make([][]byte, 0)
+	seen := make(map[string]bool)
+	for _, pair := range pairs {
+		key := pair.GetNode().GetK1()
+		if seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+
+		module, _, err := decodePair(pair)
+		if err != nil {
+			return nil, err
+		}
+
+		dependents = append(dependents, &DashboardModule{Module: module})
+		dependentKeys = append(dependentKeys, key)
+	}
+
+	if err := d.countModules(ctx, dependents, dependentKeys); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(dependents, func(i, j int) bool {
+		if dependents[i].Dependents != dependents[j].Dependents {
+			return dependents[i].Dependents > dependents[j].Dependents
+		}
+		return moduleName(dependents[i].Module) < moduleName(dependents[j].Module)
+	})
+
+	if len(dependents) > top {
+		dependents = dependents[:top]
+	}
+	return dependents, nil
+}
+
+// changes returns the edges of the key that changed since the time, or nil
+// when the store doesn't record its history.
+func (d *dashboardService) changes(ctx context.Context, key []byte, since time.Time) (*DiffResponse, error) {
+	if d.history == nil {
+		return nil, nil
+	}
+
+	changes, err := d.history.Changes(ctx, [][]byte{key}, time.Now())
+	if err == api.ErrUnsupported {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return diff(changes, since)
+}
+
+// dependencyVulnerabilities returns the advisories affecting the versions of
+// the modules the keys depend on, or nil when advisories aren't recorded.
+func (d *dashboardService) dependencyVulnerabilities(ctx context.Context, keys [][]byte) ([]*DashboardVulnerability, error) {
+	if d.vulnerabilities == nil {
+		return nil, nil
+	}
+
+	pairs, err := findPairs(ctx, d.gs.FindUpstream, keys, types.DependsType, types.ModuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make(map[string]*schema.Module)
+	versions := make(map[string]bool)
+	dependencyKeys := make([][]byte, 0)
+	for _, pair := range pairs {
+		module, depends, err := decodePair(pair)
+		if err != nil {
+			return nil, err
+		}
+
+		key := string(pair.GetNode().GetK1())
+		if _, ok := modules[key]; !ok {
+			modules[key] = module
+			dependencyKeys = append(dependencyKeys, pair.GetNode().GetK1())
+		}
+
+		if version := resolveVersion(module.GetLanguage(), depends.GetVersionConstraint()); version != "" {
+			versions[key+"@"+version] = true
+		}
+	}
+
+	results := make([]*DashboardVulnerability, 0)
+	seen := make(map[string]bool)
+	for start := 0; start < len(dependencyKeys); start += traversalBatchSize {
+		end := start + traversalBatchSize
+		if end > len(dependencyKeys) {
+			end = len(dependencyKeys)
+		}
+
+		affected, err := d.vulnerabilities.GetAdvisories(ctx, dependencyKeys[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range affected {
+			id := string(a.Key) + "@" + a.Version
+			if !versions[id] || seen[id+"#"+a.Advisory.ID] {
+				continue
+			}
+
+			seen[id+"#"+a.Advisory.ID] = true
+			results = append(results, &DashboardVulnerability{
+				Module:   modules[string(a.Key)],
+				Version:  a.Version,
+				Advisory: a.Advisory,
+			})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if a, b := moduleName(results[i].Module), moduleName(results[j].Module); a != b {
+			return a < b
+		} else if results[i].Version != results[j].Version {
+			return results[i].Version < results[j].Version
+		}
+		return results[i].Advisory.ID < results[j].Advisory.ID
+	})
+
+	return results, nil
+}
@@ -0,0 +1,99 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboard(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"api":     {"logging"},
+		"gateway": {"api"},
+		"tracker": {"api"},
+		"indexer": {"api", "tracker"},
+		"deps":    {"gateway"},
+		"cli":     {"gateway"},
+	})
+	gs.manage(t, "https://github.com/depscloud/api.git", "api")
+
+	vulnerabilities := fakeVulnerabilities{}
+	require.Nil(t, vulnerabilities.SetAdvisories(context.Background(), "osv", moduleKey("logging"), "v1.0.0", []*graphstore.Advisory{
+		{ID: "GHSA-0001", Severity: "HIGH"},
+	}))
+	require.Nil(t, vulnerabilities.SetAdvisories(context.Background(), "osv", moduleKey("logging"), "v2.0.0", []*graphstore.Advisory{
+		{ID: "GHSA-0002", Severity: "LOW"},
+	}))
+
+	depends, err := Encode(&schema.Depends{Language: "go", VersionConstraint: "v1.0.0"})
+	require.Nil(t, err)
+	depends.K1 = moduleKey("api")
+	depends.K2 = moduleKey("logging")
+
+	history := fakeHistory{
+		{Item: depends, From: gs.nodes[string(moduleKey("api"))], To: gs.nodes[string(moduleKey("logging"))], Timestamp: time.Now().Add(-time.Hour)},
+	}
+
+	server := http.NewServeMux()
+	RegisterDashboardService(server, gs, nil, history, vulnerabilities, nil)
+
+	dashboard := func(route, query string) (int, *Dashboard) {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/dashboard/"+route+"?"+query, nil))
+
+		response := &Dashboard{}
+		if recorder.Code == http.StatusOK {
+			require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		}
+		return recorder.Code, response
+	}
+
+	names := func(modules []*DashboardModule) []string {
+		results := make([]string, 0, len(modules))
+		for _, module := range modules {
+			results = append(results, module.Module.GetModule())
+		}
+		return results
+	}
+
+	code, response := dashboard("sources", "url=https://github.com/depscloud/api.git&top=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "https://github.com/depscloud/api.git", response.Source.GetUrl())
+	require.Equal(t, []string{"api"}, names(response.Modules))
+	require.Equal(t, int64(3), response.Modules[0].Dependents)
+	require.Equal(t, int64(1), response.Modules[0].Dependencies)
+
+	// dependents are ranked by their own dependents
+	require.Equal(t, []string{"gateway", "tracker"}, names(response.TopDependents))
+	require.Equal(t, int64(2), response.TopDependents[0].Dependents)
+
+	require.Len(t, response.Changes.Added, 1)
+
+	// only the advisories affecting the versions in use are listed
+	require.Len(t, response.Vulnerabilities, 1)
+	require.Equal(t, "logging", response.Vulnerabilities[0].Module.GetModule())
+	require.Equal(t, "GHSA-0001", response.Vulnerabilities[0].Advisory.ID)
+
+	code, response = dashboard("modules", "language=go&organization=depscloud&module=gateway")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, response.Sources, 0)
+	require.Equal(t, []string{"cli", "deps"}, names(response.TopDependents))
+	require.Len(t, response.Vulnerabilities, 0)
+
+	code, response = dashboard("modules", "language=go&organization=depscloud&module=api")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "https://github.com/depscloud/api.git", response.Sources[0].GetUrl())
+
+	for _, query := range []string{"", "url=x&top=0", "url=x&since=yesterday"} {
+		code, _ = dashboard("sources", query)
+		require.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
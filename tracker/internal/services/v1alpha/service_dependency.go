@@ -13,12 +13,14 @@ import (
 )
 
 // RegisterDependencyService registers the dependencyService implementation with the server
-func RegisterDependencyService(server *grpc.Server, gs store.GraphStoreClient) {
-	tracker.RegisterDependencyServiceServer(server, &dependencyService{gs: gs})
+func RegisterDependencyService(server *grpc.Server, gs store.GraphStoreClient, paging *Paging, aliases *Aliases) {
+	tracker.RegisterDependencyServiceServer(server, &dependencyService{gs: gs, paging: paging, aliases: aliases})
 }
 
 type dependencyService struct {
-	gs store.GraphStoreClient
+	gs      store.GraphStoreClient
+	paging  *Paging
+	aliases *Aliases
 }
 
 var _ tracker.DependencyServiceServer = &dependencyService{}
@@ -32,9 +34,9 @@ func keyForDependencyRequest(req *tracker.DependencyRequest) []byte {
 }
 
 func (d *dependencyService) ListDependents(ctx context.Context, req *tracker.DependencyRequest) (*tracker.ListDependentsResponse, error) {
-	key := keyForDependencyRequest(req)
+	key := keyForDependencyRequest(d.aliases.request(req))
 
-	response, err := d.gs.FindDownstream(ctx, &store.FindRequest{
+	response, err := d.gs.FindDownstream(forwardContext(ctx), &store.FindRequest{
 		Keys:      [][]byte{key},
 		EdgeTypes: []string{types.DependsType},
 		NodeTypes: []string{types.ModuleType},
@@ -44,8 +46,13 @@ func (d *dependencyService) ListDependents(ctx context.Context, req *tracker.Dep
 		return nil, api.ErrModuleNotFound
 	}
 
-	dependents := make([]*tracker.Dependency, len(response.GetPairs()))
-	for i, pair := range response.GetPairs() {
+	pairs, err := d.paging.pairs(ctx, response.GetPairs())
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := make([]*tracker.Dependency, len(pairs))
+	for i, pair := range pairs {
 		a, _ := Decode(pair.Node)
 		b, _ := Decode(pair.Edge)
 
@@ -61,9 +68,9 @@ func (d *dependencyService) ListDependents(ctx context.Context, req *tracker.Dep
 }
 
 func (d *dependencyService) ListDependencies(ctx context.Context, req *tracker.DependencyRequest) (*tracker.ListDependenciesResponse, error) {
-	key := keyForDependencyRequest(req)
+	key := keyForDependencyRequest(d.aliases.request(req))
 
-	response, err := d.gs.FindUpstream(ctx, &store.FindRequest{
+	response, err := d.gs.FindUpstream(forwardContext(ctx), &store.FindRequest{
 		Keys:      [][]byte{key},
 		EdgeTypes: []string{types.DependsType},
 		NodeTypes: []string{types.ModuleType},
@@ -73,8 +80,13 @@ func (d *dependencyService) ListDependencies(ctx context.Context, req *tracker.D
 		return nil, api.ErrModuleNotFound
 	}
 
-	dependencies := make([]*tracker.Dependency, len(response.GetPairs()))
-	for i, pair := range response.GetPairs() {
+	pairs, err := d.paging.pairs(ctx, response.GetPairs())
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies := make([]*tracker.Dependency, len(pairs))
+	for i, pair := range pairs {
 		a, _ := Decode(pair.Node)
 		b, _ := Decode(pair.Edge)
 
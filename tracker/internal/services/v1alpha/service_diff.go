@@ -0,0 +1,197 @@
+package v1alpha
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+)
+
+// RegisterDiffService registers the diffService routes with the http server
+func RegisterDiffService(server *http.ServeMux, history graphstore.History) {
+	svc := &diffService{history: history}
+
+	server.HandleFunc(QueryRoutePrefix+"diff", svc.Diff)
+}
+
+type diffService struct {
+	history graphstore.History
+}
+
+// EdgeChange is an edge that was added, removed, or changed. From and To are
+// the nodes at either end of the edge. Before and After hold the edge as it
+// was at the start and end of the diff and are omitted when it didn't exist.
+type EdgeChange struct {
+	Type   string      `json:"type"`
+	From   interface{} `json:"from"`
+	To     interface{} `json:"to"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// DiffResponse contains the edges of a source or module that changed between
+// two points in time.
+type DiffResponse struct {
+	Added   []*EdgeChange `json:"added"`
+	Removed []*EdgeChange `json:"removed"`
+	Changed []*EdgeChange `json:"changed"`
+}
+
+// Diff handles GET /v1alpha/queries/diff. Either the source parameter, holding
+// the url of a source, or the language, organization, and module parameters
+// identify what to diff. For a source, the edges it manages and the
+// dependencies it declares are compared. For a module, the edges to and from
+// the module are compared. The since and until parameters are RFC 3339
+// timestamps, until defaults to now.
+func (d *diffService) Diff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+
+	var key []byte
+	if url := query.Get("source"); url != "" {
+		key = keyForSource(&schema.Source{Url: url})
+	} else {
+		req, err := parseModule(r)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("source or language and module are required"))
+			return
+		}
+		key = keyForDependencyRequest(req)
+	}
+
+	since, err := time.Parse(time.RFC3339, query.Get("since"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("since must be an RFC 3339 timestamp"))
+		return
+	}
+
+	until := time.Now()
+	if value := query.Get("until"); value != "" {
+		until, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("until must be an RFC 3339 timestamp"))
+			return
+		}
+	}
+
+	if !since.Before(until) {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("since must be before until"))
+		return
+	}
+
+	changes, err := d.history.Changes(r.Context(), [][]byte{key}, until)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.diff] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query history"))
+		return
+	}
+
+	response, err := diff(changes, since)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.diff] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query history"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// diff compares the state of each edge at since with its latest state. The
+// changes must be sorted from oldest to newest.
+func diff(changes []*graphstore.Change, since time.Time) (*DiffResponse, error) {
+	before := make(map[string]*graphstore.Change)
+	after := make(map[string]*graphstore.Change)
+	keys := make([]string, 0)
+
+	for _, change := range changes {
+		key := readableKey(change.Item)
+
+		if !change.Timestamp.After(since) {
+			before[key] = change
+		}
+
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+		after[key] = change
+	}
+
+	sort.Strings(keys)
+
+	response := &DiffResponse{
+		Added:   make([]*EdgeChange, 0),
+		Removed: make([]*EdgeChange, 0),
+		Changed: make([]*EdgeChange, 0),
+	}
+
+	for _, key := range keys {
+		first, last := before[key], after[key]
+		existed := first != nil && !first.Deleted
+		exists := !last.Deleted
+
+		if !existed && !exists {
+			continue
+		} else if existed && exists &&
+			bytes.Equal(first.Item.GetGraphItemData(), last.Item.GetGraphItemData()) {
+			continue
+		}
+
+		edge, err := edgeChangeFor(last)
+		if err != nil {
+			return nil, err
+		}
+
+		if existed {
+			if edge.Before, err = Decode(first.Item); err != nil {
+				return nil, err
+			}
+		}
+
+		if exists {
+			if edge.After, err = Decode(last.Item); err != nil {
+				return nil, err
+			}
+		}
+
+		switch {
+		case !existed:
+			response.Added = append(response.Added, edge)
+		case !exists:
+			response.Removed = append(response.Removed, edge)
+		default:
+			response.Changed = append(response.Changed, edge)
+		}
+	}
+
+	return response, nil
+}
+
+func edgeChangeFor(change *graphstore.Change) (*EdgeChange, error) {
+	edge := &EdgeChange{
+		Type: change.Item.GetGraphItemType(),
+	}
+
+	var err error
+	if change.From != nil {
+		if edge.From, err = Decode(change.From); err != nil {
+			return nil, err
+		}
+	}
+
+	if change.To != nil {
+		if edge.To, err = Decode(change.To); err != nil {
+			return nil, err
+		}
+	}
+
+	return edge, nil
+}
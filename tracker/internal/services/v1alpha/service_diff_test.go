@@ -0,0 +1,107 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHistory []*graphstore.Change
+
+func (h fakeHistory) Changes(ctx context.Context, keys [][]byte, until time.Time) ([]*graphstore.Change, error) {
+	changes := make([]*graphstore.Change, 0)
+	for _, change := range h {
+		if !change.Timestamp.After(until) {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func TestDiff(t *testing.T) {
+	encode := func(msg interface{}) *store.GraphItem {
+		item, err := Encode(msg)
+		require.Nil(t, err)
+		return item
+	}
+
+	depends := func(to, version string) *store.GraphItem {
+		item := encode(&schema.Depends{Language: "go", VersionConstraint: version})
+		item.K1 = moduleKey("service")
+		item.K2 = moduleKey(to)
+		return item
+	}
+
+	at := func(day int) time.Time {
+		return time.Date(2020, time.January, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	service := encode(&schema.Module{Language: "go", Organization: "depscloud", Module: "service"})
+	change := func(day int, to, version string, deleted bool) *graphstore.Change {
+		return &graphstore.Change{
+			Item:      depends(to, version),
+			From:      service,
+			To:        encode(&schema.Module{Language: "go", Organization: "depscloud", Module: to}),
+			Deleted:   deleted,
+			Timestamp: at(day),
+		}
+	}
+
+	server := http.NewServeMux()
+	RegisterDiffService(server, fakeHistory{
+		change(1, "logging", "v1.0.0", false),
+		change(1, "http", "v1.0.0", false),
+		change(1, "metrics", "v1.0.0", false),
+		change(3, "http", "v1.1.0", false),
+		change(3, "metrics", "v1.0.0", true),
+		change(3, "tracing", "v1.0.0", false),
+		change(5, "tracing", "v1.0.0", true),
+	})
+
+	diff := func(query string) *DiffResponse {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/v1alpha/queries/diff?language=go&organization=depscloud&module=service&"+query, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		response := &DiffResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		return response
+	}
+
+	names := func(edges []*EdgeChange) []string {
+		results := make([]string, 0, len(edges))
+		for _, edge := range edges {
+			results = append(results, edge.To.(map[string]interface{})["module"].(string))
+		}
+		return results
+	}
+
+	response := diff("since=2020-01-02T00:00:00Z&until=2020-01-04T00:00:00Z")
+	require.Equal(t, []string{"tracing"}, names(response.Added))
+	require.Equal(t, []string{"metrics"}, names(response.Removed))
+	require.Equal(t, []string{"http"}, names(response.Changed))
+	require.Equal(t, "v1.0.0", response.Changed[0].Before.(map[string]interface{})["version_constraint"])
+	require.Equal(t, "v1.1.0", response.Changed[0].After.(map[string]interface{})["version_constraint"])
+
+	// tracing was added and removed within the window
+	response = diff("since=2020-01-02T00:00:00Z")
+	require.Len(t, response.Added, 0)
+	require.Equal(t, []string{"metrics"}, names(response.Removed))
+	require.Equal(t, []string{"http"}, names(response.Changed))
+
+	for _, query := range []string{"since=yesterday", "since=2020-01-04T00:00:00Z&until=2020-01-02T00:00:00Z"} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/queries/diff?source=x&"+query, nil))
+		require.Equal(t, http.StatusBadRequest, recorder.Code, query)
+	}
+}
@@ -0,0 +1,258 @@
+package v1alpha
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// GraphRoutePrefix prefixes the HTTP routes used to move the graph between
+// tracker instances.
+const GraphRoutePrefix = "/v1alpha/graph/"
+
+// Formats of an exported graph. JSONL writes one JSON encoded GraphItem per
+// line. Protobuf writes GraphItem messages, each prefixed by its varint
+// encoded length.
+const (
+	FormatJSONL    = "jsonl"
+	FormatProtobuf = "protobuf"
+)
+
+// exportTypes are exported in order so nodes are imported before the edges
+// between them.
+var exportTypes = []string{types.SourceType, types.ModuleType, types.ManagesType, types.DependsType}
+
+// transferBatchSize is the number of items read or written at once.
+const transferBatchSize = 500
+
+// RegisterGraphService registers the graphService routes with the http server
+func RegisterGraphService(server *http.ServeMux, gs store.GraphStoreClient) {
+	svc := &graphService{gs: gs}
+
+	server.HandleFunc(GraphRoutePrefix+"export", svc.Export)
+	server.HandleFunc(GraphRoutePrefix+"import", svc.Import)
+}
+
+type graphService struct {
+	gs store.GraphStoreClient
+}
+
+// Export handles GET /v1alpha/graph/export. The entire graph is streamed in
+// the format selected by the format parameter, jsonl by default.
+func (g *graphService) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	format, err := parseFormat(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if format == FormatProtobuf {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	writer := bufio.NewWriter(w)
+	exported := 0
+
+	for _, graphItemType := range exportTypes {
+		for page := int32(1); ; page++ {
+			resp, err := g.gs.List(r.Context(), &store.ListRequest{
+				Page:  page,
+				Count: transferBatchSize,
+				Type:  graphItemType,
+			})
+			if err != nil {
+				// the status was already sent, truncate the stream
+				logging.FromContext(r.Context()).Errorf("[service.graph] export failed after %d items: %s", exported, err.Error())
+				return
+			}
+
+			for _, item := range resp.GetItems() {
+				if err := writeItem(writer, format, item); err != nil {
+					logging.FromContext(r.Context()).Errorf("[service.graph] export failed after %d items: %s", exported, err.Error())
+					return
+				}
+				exported++
+			}
+
+			if err := writer.Flush(); err != nil {
+				logging.FromContext(r.Context()).Errorf("[service.graph] export failed after %d items: %s", exported, err.Error())
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+
+			if len(resp.GetItems()) < transferBatchSize {
+				break
+			}
+		}
+	}
+
+	logging.FromContext(r.Context()).Infof("[service.graph] exported %d items", exported)
+}
+
+// ImportResponse contains the number of items that were imported.
+type ImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+// Import handles POST /v1alpha/graph/import. The body holds a graph written by
+// Export, in the format selected by the format parameter. Items are put as
+// they're read, so a failed import can safely be retried.
+func (g *graphService) Import(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	format, err := parseFormat(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	reader := bufio.NewReader(r.Body)
+	batch := make([]*store.GraphItem, 0, transferBatchSize)
+	imported := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if _, err := g.gs.Put(r.Context(), &store.PutRequest{Items: batch}); err != nil {
+			return err
+		}
+
+		imported += len(batch)
+		batch = make([]*store.GraphItem, 0, transferBatchSize)
+		return nil
+	}
+
+	for {
+		item, err := readItem(reader, format)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("malformed item after %d items: %s", imported+len(batch), err.Error()))
+			return
+		}
+
+		batch = append(batch, item)
+		if len(batch) < transferBatchSize {
+			continue
+		}
+
+		if err := flush(); err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.graph] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("import failed after %d items", imported))
+			return
+		}
+	}
+
+	if err := flush(); err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.graph] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("import failed after %d items", imported))
+		return
+	}
+
+	logging.FromContext(r.Context()).Infof("[service.graph] imported %d items", imported)
+	writeJSON(w, r, http.StatusOK, &ImportResponse{
+		Imported: imported,
+	})
+}
+
+func parseFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", FormatJSONL:
+		return FormatJSONL, nil
+	case FormatProtobuf:
+		return FormatProtobuf, nil
+	default:
+		return "", fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+func writeItem(w *bufio.Writer, format string, item *store.GraphItem) error {
+	if format == FormatJSONL {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		return w.WriteByte('\n')
+	}
+
+	data, err := proto.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	length := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(length, uint64(len(data)))
+
+	if _, err := w.Write(length[:n]); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func readItem(r *bufio.Reader, format string) (*store.GraphItem, error) {
+	item := &store.GraphItem{}
+
+	if format == FormatJSONL {
+		for {
+			line, err := r.ReadBytes('\n')
+			if err == io.EOF && len(line) == 0 {
+				return nil, io.EOF
+			} else if err != nil && err != io.EOF {
+				return nil, err
+			}
+
+			// tolerate blank lines, such as a trailing newline
+			if len(bytes.TrimSpace(line)) == 0 {
+				if err == io.EOF {
+					return nil, io.EOF
+				}
+				continue
+			}
+
+			return item, json.Unmarshal(line, item)
+		}
+	}
+
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return item, proto.Unmarshal(data, item)
+}
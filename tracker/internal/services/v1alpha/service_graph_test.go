@@ -0,0 +1,55 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func edgeData(gs *fakeGraphStore) []string {
+	data := make([]string, 0, len(gs.edges))
+	for _, edge := range gs.edges {
+		data = append(data, readableKey(edge)+string(edge.GetGraphItemData()))
+	}
+	return data
+}
+
+func TestExportImport(t *testing.T) {
+	source := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c"},
+	})
+	source.manage(t, "https://github.com/depscloud/a.git", "a")
+
+	sourceServer := http.NewServeMux()
+	RegisterGraphService(sourceServer, source)
+
+	for _, format := range []string{FormatJSONL, FormatProtobuf} {
+		exported := httptest.NewRecorder()
+		sourceServer.ServeHTTP(exported, httptest.NewRequest(http.MethodGet, "/v1alpha/graph/export?format="+format, nil))
+		require.Equal(t, http.StatusOK, exported.Code)
+
+		target := newFakeGraphStore(t, map[string][]string{})
+		targetServer := http.NewServeMux()
+		RegisterGraphService(targetServer, target)
+
+		imported := httptest.NewRecorder()
+		targetServer.ServeHTTP(imported, httptest.NewRequest(http.MethodPost, "/v1alpha/graph/import?format="+format, exported.Body))
+		require.Equal(t, http.StatusOK, imported.Code)
+
+		response := &ImportResponse{}
+		require.Nil(t, json.NewDecoder(imported.Body).Decode(response))
+
+		// 3 modules, 1 source, 3 depends, and 1 manages
+		require.Equal(t, 8, response.Imported, format)
+		require.Len(t, target.nodes, len(source.nodes))
+		require.ElementsMatch(t, edgeData(source), edgeData(target))
+	}
+
+	recorder := httptest.NewRecorder()
+	sourceServer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/graph/export?format=xml", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
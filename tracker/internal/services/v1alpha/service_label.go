@@ -0,0 +1,111 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// LabelRoutePrefix prefixes the HTTP routes used to manage the labels of
+// sources and modules.
+const LabelRoutePrefix = "/v1alpha/labels/"
+
+// RegisterLabelService registers the labelService routes with the http server
+func RegisterLabelService(server *http.ServeMux, labels graphstore.Labels, aliases *Aliases) {
+	svc := &labelService{labels: labels, aliases: aliases}
+
+	server.HandleFunc(LabelRoutePrefix+"sources", svc.Sources)
+	server.HandleFunc(LabelRoutePrefix+"modules", svc.Modules)
+}
+
+type labelService struct {
+	labels  graphstore.Labels
+	aliases *Aliases
+}
+
+// LabelsRequest replaces the labels of a source or module.
+type LabelsRequest struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// LabelsResponse contains the labels of a source or module.
+type LabelsResponse struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// Sources handles GET and PUT /v1alpha/labels/sources. The source is
+// identified by the url parameter.
+func (l *labelService) Sources(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("url is required"))
+		return
+	}
+
+	l.handle(w, r, types.SourceType, keyForSource(&schema.Source{Url: url}))
+}
+
+// Modules handles GET and PUT /v1alpha/labels/modules. The module is
+// identified by the language, organization, and module parameters. Labels
+// set on an alias are set on its canonical module.
+func (l *labelService) Modules(w http.ResponseWriter, r *http.Request) {
+	req, err := parseModule(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	l.handle(w, r, types.ModuleType, keyForDependencyRequest(l.aliases.request(req)))
+}
+
+func (l *labelService) handle(w http.ResponseWriter, r *http.Request, graphItemType string, key []byte) {
+	switch r.Method {
+	case http.MethodGet:
+		current, err := l.labels.GetLabels(r.Context(), graphItemType, [][]byte{key})
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.label] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to get labels"))
+			return
+		}
+
+		labels := current[string(key)]
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+
+		writeJSON(w, r, http.StatusOK, &LabelsResponse{Labels: labels})
+
+	case http.MethodPut:
+		req := &LabelsRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("failed to parse labels"))
+			return
+		}
+
+		if err := graphstore.ValidateLabels(req.Labels); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := l.labels.SetLabels(r.Context(), graphItemType, key, req.Labels); err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.label] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to set labels"))
+			return
+		}
+
+		labels := req.Labels
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+
+		writeJSON(w, r, http.StatusOK, &LabelsResponse{Labels: labels})
+
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+	}
+}
@@ -0,0 +1,80 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLabels holds labels by type and key.
+type fakeLabels map[string]map[string]string
+
+func (f fakeLabels) SetLabels(ctx context.Context, graphItemType string, key []byte, labels map[string]string) error {
+	f[graphItemType+string(key)] = labels
+	return nil
+}
+
+func (f fakeLabels) GetLabels(ctx context.Context, graphItemType string, keys [][]byte) (map[string]map[string]string, error) {
+	results := make(map[string]map[string]string)
+	for _, key := range keys {
+		if labels, ok := f[graphItemType+string(key)]; ok {
+			results[string(key)] = labels
+		}
+	}
+	return results, nil
+}
+
+func TestLabels(t *testing.T) {
+	aliases, err := NewAliases([]*Alias{
+		{Language: "go", Organization: "depscloud", Module: "old-a", Canonical: &CanonicalModule{Organization: "depscloud", Module: "a"}},
+	})
+	require.Nil(t, err)
+
+	server := http.NewServeMux()
+	RegisterLabelService(server, fakeLabels{}, aliases)
+
+	call := func(method, path, body string) (int, map[string]string) {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if recorder.Code != http.StatusOK {
+			return recorder.Code, nil
+		}
+
+		response := &LabelsResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		return recorder.Code, response.Labels
+	}
+
+	code, labels := call(http.MethodGet, "/v1alpha/labels/modules?language=go&organization=depscloud&module=a", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, labels, 0)
+
+	// labels set on an alias are set on the canonical module
+	code, _ = call(http.MethodPut, "/v1alpha/labels/modules?language=go&organization=depscloud&module=old-a", `{"labels":{"team":"core"}}`)
+	require.Equal(t, http.StatusOK, code)
+
+	code, labels = call(http.MethodGet, "/v1alpha/labels/modules?language=go&organization=depscloud&module=a", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]string{"team": "core"}, labels)
+
+	code, _ = call(http.MethodPut, "/v1alpha/labels/sources?url=https://github.com/depscloud/a.git", `{"labels":{"tier":"1"}}`)
+	require.Equal(t, http.StatusOK, code)
+
+	code, labels = call(http.MethodGet, "/v1alpha/labels/sources?url=https://github.com/depscloud/a.git", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]string{"tier": "1"}, labels)
+
+	code, _ = call(http.MethodPut, "/v1alpha/labels/sources?url=https://github.com/depscloud/a.git", `{"labels":{"a,b":"c"}}`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = call(http.MethodGet, "/v1alpha/labels/sources", "")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = call(http.MethodPost, "/v1alpha/labels/sources?url=https://github.com/depscloud/a.git", "")
+	require.Equal(t, http.StatusMethodNotAllowed, code)
+}
@@ -0,0 +1,255 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// LifecycleRoutePrefix prefixes the HTTP routes used to mark modules as
+// deprecated or reaching their end of life.
+const LifecycleRoutePrefix = "/v1alpha/lifecycle/"
+
+// RegisterLifecycleService registers the lifecycleService routes with the
+// http server.
+func RegisterLifecycleService(server *http.ServeMux, gs store.GraphStoreClient, labels graphstore.Labels, aliases *Aliases) {
+	svc := &lifecycleService{gs: gs, labels: labels, aliases: aliases}
+
+	server.HandleFunc(LifecycleRoutePrefix+"modules", svc.Modules)
+	server.HandleFunc(LifecycleRoutePrefix+"sources", svc.Sources)
+}
+
+type lifecycleService struct {
+	gs      store.GraphStoreClient
+	labels  graphstore.Labels
+	aliases *Aliases
+}
+
+// LifecycleResponse contains the lifecycle of a module, which is omitted
+// when the module doesn't have one.
+type LifecycleResponse struct {
+	Lifecycle *Lifecycle `json:"lifecycle,omitempty"`
+}
+
+// EndOfLifeDependency is a dependency on a module that's deprecated or has
+// reached its end of life.
+type EndOfLifeDependency struct {
+	Dependent         *schema.Module `json:"dependent"`
+	Module            *schema.Module `json:"module"`
+	VersionConstraint string         `json:"version_constraint"`
+	Version           string         `json:"version,omitempty"`
+	Lifecycle         *Lifecycle     `json:"lifecycle"`
+}
+
+// EndOfLifeSource is a source managing modules with dependencies on modules
+// that are deprecated or have reached their end of life.
+type EndOfLifeSource struct {
+	Source       *schema.Source         `json:"source"`
+	Dependencies []*EndOfLifeDependency `json:"dependencies"`
+}
+
+// EndOfLifeSourcesResponse contains the sources still depending on modules
+// that are deprecated or have reached their end of life.
+type EndOfLifeSourcesResponse struct {
+	Sources []*EndOfLifeSource `json:"sources"`
+}
+
+// Modules handles GET, PUT, and DELETE /v1alpha/lifecycle/modules. The module
+// is identified by the language, organization, and module parameters. PUT
+// replaces the lifecycle of the module and DELETE removes it.
+func (l *lifecycleService) Modules(w http.ResponseWriter, r *http.Request) {
+	req, err := parseModule(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	key := keyForDependencyRequest(l.aliases.request(req))
+
+	var replacement *Lifecycle
+	switch r.Method {
+	case http.MethodGet, http.MethodDelete:
+	case http.MethodPut:
+		replacement = &Lifecycle{}
+		if err := json.NewDecoder(r.Body).Decode(replacement); err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("failed to parse lifecycle"))
+			return
+		}
+
+		if err := replacement.Validate(); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	current, err := l.labels.GetLabels(ctx, types.ModuleType, [][]byte{key})
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.lifecycle] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to get lifecycle"))
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		writeJSON(w, r, http.StatusOK, &LifecycleResponse{Lifecycle: lifecycle(current[string(key)])})
+		return
+	}
+
+	var lifecycleLabels map[string]string
+	if replacement != nil {
+		lifecycleLabels = replacement.labels()
+	}
+
+	merged := mergeLabels(current[string(key)], LifecycleLabelPrefix, lifecycleLabels)
+	if err := l.labels.SetLabels(ctx, types.ModuleType, key, merged); err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.lifecycle] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to set lifecycle"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &LifecycleResponse{Lifecycle: replacement})
+}
+
+// Sources handles GET /v1alpha/lifecycle/sources. It lists the sources whose
+// modules depend on a deprecated module or a version of a module that has
+// reached its end of life. The optional date parameter (YYYY-MM-DD) looks
+// ahead to the modules reaching their end of life by then, and the optional
+// status parameter limits the results to deprecated or eol modules.
+func (l *lifecycleService) Sources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+	status := query.Get("status")
+
+	date := time.Now()
+	if value := query.Get("date"); value != "" {
+		parsed, err := time.Parse(lifecycleDate, value)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("date must be written as YYYY-MM-DD"))
+			return
+		}
+		date = parsed
+	}
+
+	ctx := r.Context()
+
+	// stores without label selectors list every module, so the labels are
+	// checked as well
+	modules, err := listModules(ctx, l.gs, &filters.Filter{Labels: LifecycleLabelStatus})
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.lifecycle] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to list modules"))
+		return
+	}
+
+	keys := make([][]byte, 0, len(modules))
+	for key := range modules {
+		keys = append(keys, []byte(key))
+	}
+
+	labels, err := l.labels.GetLabels(ctx, types.ModuleType, keys)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.lifecycle] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to get lifecycles"))
+		return
+	}
+
+	lifecycles := make(map[string]*Lifecycle)
+	endedKeys := make([][]byte, 0)
+	for key, moduleLabels := range labels {
+		result := lifecycle(moduleLabels)
+		if result == nil || !result.ended(date) || (status != "" && result.Status != status) {
+			continue
+		}
+
+		lifecycles[key] = result
+		endedKeys = append(endedKeys, []byte(key))
+	}
+
+	pairs, err := findPairs(ctx, l.gs.FindDownstream, endedKeys, types.DependsType, types.ModuleType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.lifecycle] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find dependents"))
+		return
+	}
+
+	dependencies := make(map[string][]*EndOfLifeDependency)
+	dependentKeys := make([][]byte, 0)
+	for _, pair := range pairs {
+		dependent, depends, err := decodePair(pair)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		moduleKey := string(pair.GetEdge().GetK2())
+		result := lifecycles[moduleKey]
+		version := resolveVersion(dependent.GetLanguage(), depends.GetVersionConstraint())
+		if !result.affects(version) {
+			continue
+		}
+
+		dependentKey := string(pair.GetNode().GetK1())
+		if _, ok := dependencies[dependentKey]; !ok {
+			dependentKeys = append(dependentKeys, pair.GetNode().GetK1())
+		}
+
+		dependencies[dependentKey] = append(dependencies[dependentKey], &EndOfLifeDependency{
+			Dependent:         dependent,
+			Module:            modules[moduleKey],
+			VersionConstraint: depends.GetVersionConstraint(),
+			Version:           version,
+			Lifecycle:         result,
+		})
+	}
+
+	managers, err := findPairs(ctx, l.gs.FindDownstream, dependentKeys, types.ManagesType, types.SourceType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.lifecycle] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find sources"))
+		return
+	}
+
+	sources := make(map[string]*EndOfLifeSource)
+	for _, pair := range managers {
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		source := node.(*schema.Source)
+
+		if _, ok := sources[source.GetUrl()]; !ok {
+			sources[source.GetUrl()] = &EndOfLifeSource{Source: source, Dependencies: make([]*EndOfLifeDependency, 0)}
+		}
+
+		entry := sources[source.GetUrl()]
+		entry.Dependencies = append(entry.Dependencies, dependencies[string(pair.GetEdge().GetK2())]...)
+	}
+
+	results := make([]*EndOfLifeSource, 0, len(sources))
+	for _, source := range sources {
+		results = append(results, source)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Source.GetUrl() < results[j].Source.GetUrl()
+	})
+
+	writeJSON(w, r, http.StatusOK, &EndOfLifeSourcesResponse{Sources: results})
+}
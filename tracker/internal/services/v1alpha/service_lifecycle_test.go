@@ -0,0 +1,130 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycle(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"d": {"b"},
+		"e": {"c"},
+	})
+	gs.manage(t, "https://github.com/depscloud/a.git", "a")
+	gs.manage(t, "https://github.com/depscloud/d.git", "d")
+	gs.manage(t, "https://github.com/depscloud/e.git", "e")
+
+	labels := fakeLabels{}
+	labels[types.ModuleType+string(moduleKey("b"))] = map[string]string{"team": "platform"}
+
+	server := http.NewServeMux()
+	RegisterLifecycleService(server, gs, labels, nil)
+
+	call := func(method, path, body string, response interface{}) int {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if response != nil && recorder.Code == http.StatusOK {
+			require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		}
+		return recorder.Code
+	}
+
+	module := func(name string) string {
+		return "/v1alpha/lifecycle/modules?language=go&organization=depscloud&module=" + name
+	}
+
+	require.Equal(t, http.StatusBadRequest, call(http.MethodPut, module("b"), `{"status":"retired"}`, nil))
+	require.Equal(t, http.StatusBadRequest, call(http.MethodPut, module("b"), `{"status":"eol","eol":"soon"}`, nil))
+
+	code := call(http.MethodPut, module("b"), `{"status":"deprecated","replacement":"go/depscloud/f"}`, nil)
+	require.Equal(t, http.StatusOK, code)
+	code = call(http.MethodPut, module("c"), `{"status":"eol","eol":"2030-01-01","versions":"<2.0.0"}`, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	// labels set by others are kept
+	require.Equal(t, "platform", labels[types.ModuleType+string(moduleKey("b"))]["team"])
+
+	response := &LifecycleResponse{}
+	require.Equal(t, http.StatusOK, call(http.MethodGet, module("b"), "", response))
+	require.Equal(t, &Lifecycle{Status: LifecycleDeprecated, Replacement: "go/depscloud/f"}, response.Lifecycle)
+
+	sources := func(query string) []string {
+		response := &EndOfLifeSourcesResponse{}
+		require.Equal(t, http.StatusOK, call(http.MethodGet, "/v1alpha/lifecycle/sources?"+query, "", response))
+
+		urls := make([]string, 0, len(response.Sources))
+		for _, source := range response.Sources {
+			urls = append(urls, source.Source.GetUrl())
+		}
+		return urls
+	}
+
+	require.Equal(t, []string{"https://github.com/depscloud/a.git", "https://github.com/depscloud/d.git"}, sources(""))
+
+	// c reaches its end of life later on
+	require.Equal(t, []string{
+		"https://github.com/depscloud/a.git",
+		"https://github.com/depscloud/d.git",
+		"https://github.com/depscloud/e.git",
+	}, sources("date=2030-06-01"))
+	require.Equal(t, []string{"https://github.com/depscloud/a.git", "https://github.com/depscloud/e.git"}, sources("date=2030-06-01&status=eol"))
+
+	require.Equal(t, http.StatusOK, call(http.MethodDelete, module("b"), "", nil))
+	require.Equal(t, map[string]string{"team": "platform"}, labels[types.ModuleType+string(moduleKey("b"))])
+	require.Equal(t, []string{}, sources(""))
+}
+
+func TestEndOfLife(t *testing.T) {
+	date := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cycles := []*EndOfLifeCycle{
+		{Cycle: "3.0", EOL: false},
+		{Cycle: "2.2", EOL: "2022-04-01"},
+		{Cycle: "2.1", EOL: "2020-12-01"},
+		{Cycle: "1.11", EOL: true},
+	}
+	require.Equal(t, &Lifecycle{Status: LifecycleEndOfLife, EndOfLife: "2020-12-01", Versions: "<2.2"}, cycleLifecycle(cycles, date))
+	require.Equal(t, &Lifecycle{Status: LifecycleEndOfLife, EndOfLife: "2022-04-01"}, cycleLifecycle(cycles[1:], date.AddDate(2, 0, 0)))
+	require.Nil(t, cycleLifecycle(cycles[:1], date))
+
+	result := cycleLifecycle(cycles, date)
+	require.True(t, result.affects("2.1.9"))
+	require.False(t, result.affects("2.2.0"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/django.json", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"cycle":"3.0","eol":false},{"cycle":"2.1","eol":"2020-12-01"}]`))
+	}))
+	defer server.Close()
+
+	labels := fakeLabels{}
+	config := &EndOfLifeConfig{
+		Products: []*EndOfLifeProduct{
+			{
+				Product:     "django",
+				Replacement: "python/django@3",
+				Modules:     []*EndOfLifeModule{{Language: "go", Organization: "depscloud", Module: "django"}},
+			},
+		},
+	}
+
+	ended, err := syncEndOfLife(context.Background(), labels, NewEndOfLifeClient(server.URL), config, nil)
+	require.Nil(t, err)
+	require.Equal(t, 1, ended)
+	require.Equal(t, map[string]string{
+		LifecycleLabelStatus:      LifecycleEndOfLife,
+		LifecycleLabelEndOfLife:   "2020-12-01",
+		LifecycleLabelReplacement: "python/django@3",
+		LifecycleLabelVersions:    "<3.0",
+	}, labels[types.ModuleType+string(moduleKey("django"))])
+}
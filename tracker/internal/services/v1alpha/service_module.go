@@ -7,33 +7,39 @@ import (
 	"github.com/depscloud/api/v1alpha/schema"
 	"github.com/depscloud/api/v1alpha/store"
 	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/logging"
 	"github.com/depscloud/depscloud/tracker/internal/types"
 
-	"github.com/sirupsen/logrus"
-
 	"google.golang.org/grpc"
 )
 
 // RegisterModuleService registers the moduleService implementation with the server
-func RegisterModuleService(server *grpc.Server, gs store.GraphStoreClient) {
-	tracker.RegisterModuleServiceServer(server, &moduleService{gs: gs})
+func RegisterModuleService(server *grpc.Server, gs store.GraphStoreClient, paging *Paging, aliases *Aliases) {
+	tracker.RegisterModuleServiceServer(server, &moduleService{gs: gs, paging: paging, aliases: aliases})
 }
 
 type moduleService struct {
-	gs store.GraphStoreClient
+	gs      store.GraphStoreClient
+	paging  *Paging
+	aliases *Aliases
 }
 
 var _ tracker.ModuleServiceServer = &moduleService{}
 
 func (s *moduleService) List(ctx context.Context, req *tracker.ListRequest) (*tracker.ListModuleResponse, error) {
-	resp, err := s.gs.List(ctx, &store.ListRequest{
-		Page:  req.GetPage(),
-		Count: req.GetCount(),
+	page, count, err := s.paging.listPage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.gs.List(forwardContext(ctx), &store.ListRequest{
+		Page:  page,
+		Count: count,
 		Type:  types.ModuleType,
 	})
 
 	if err != nil {
-		logrus.Errorf("[service.module] %s", err.Error())
+		logging.FromContext(ctx).Errorf("[service.module] %s", err.Error())
 		return nil, err
 	}
 
@@ -43,17 +49,21 @@ func (s *moduleService) List(ctx context.Context, req *tracker.ListRequest) (*tr
 		modules = append(modules, module.(*schema.Module))
 	}
 
+	if err := setNextListPage(ctx, page, count, len(resp.GetItems())); err != nil {
+		return nil, err
+	}
+
 	return &tracker.ListModuleResponse{
-		Page:    req.GetPage(),
-		Count:   req.GetCount(),
+		Page:    page,
+		Count:   count,
 		Modules: modules,
 	}, nil
 }
 
 func (s *moduleService) ListSources(ctx context.Context, req *schema.Module) (*tracker.ListSourcesResponse, error) {
-	key := keyForModule(req)
+	key := keyForModule(s.aliases.module(req))
 
-	response, err := s.gs.FindDownstream(ctx, &store.FindRequest{
+	response, err := s.gs.FindDownstream(forwardContext(ctx), &store.FindRequest{
 		Keys:      [][]byte{key},
 		EdgeTypes: []string{types.ManagesType},
 		NodeTypes: []string{types.SourceType},
@@ -63,8 +73,13 @@ func (s *moduleService) ListSources(ctx context.Context, req *schema.Module) (*t
 		return nil, api.ErrModuleNotFound
 	}
 
-	sources := make([]*tracker.ManagedSource, len(response.GetPairs()))
-	for i, pair := range response.GetPairs() {
+	pairs, err := s.paging.pairs(ctx, response.GetPairs())
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]*tracker.ManagedSource, len(pairs))
+	for i, pair := range pairs {
 		a, _ := Decode(pair.Node)
 		b, _ := Decode(pair.Edge)
 
@@ -82,7 +97,7 @@ func (s *moduleService) ListSources(ctx context.Context, req *schema.Module) (*t
 func (s *moduleService) ListManaged(ctx context.Context, req *schema.Source) (*tracker.ListManagedResponse, error) {
 	key := keyForSource(req)
 
-	response, err := s.gs.FindUpstream(ctx, &store.FindRequest{
+	response, err := s.gs.FindUpstream(forwardContext(ctx), &store.FindRequest{
 		Keys:      [][]byte{key},
 		EdgeTypes: []string{types.ManagesType},
 		NodeTypes: []string{types.ModuleType},
@@ -92,8 +107,13 @@ func (s *moduleService) ListManaged(ctx context.Context, req *schema.Source) (*t
 		return nil, api.ErrModuleNotFound
 	}
 
-	modules := make([]*tracker.ManagedModule, len(response.GetPairs()))
-	for i, pair := range response.GetPairs() {
+	pairs, err := s.paging.pairs(ctx, response.GetPairs())
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]*tracker.ManagedModule, len(pairs))
+	for i, pair := range pairs {
 		a, _ := Decode(pair.Node)
 		b, _ := Decode(pair.Edge)
 
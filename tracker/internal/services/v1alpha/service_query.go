@@ -0,0 +1,584 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/asof"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/logging"
+)
+
+// QueryRoutePrefix prefixes the HTTP routes of graph queries that aren't part
+// of the tracker api.
+const QueryRoutePrefix = "/v1alpha/queries/"
+
+// maxPaths bounds the number of paths returned by the shortest paths query.
+const maxPaths = 100
+
+// maxLeaderboardSize bounds the number of modules returned by a leaderboard.
+const maxLeaderboardSize = 100
+
+// DefaultMaxDepth bounds how far queries walk the graph when no limit is
+// configured.
+const DefaultMaxDepth = 25
+
+// RegisterQueryService registers the queryService routes with the http server
+func RegisterQueryService(server *http.ServeMux, gs store.GraphStoreClient, maxDepth int, aliases *Aliases) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	svc := &queryService{gs: gs, maxDepth: maxDepth, aliases: aliases}
+
+	server.HandleFunc(QueryRoutePrefix+"transitive-dependencies", svc.TransitiveDependencies)
+	server.HandleFunc(QueryRoutePrefix+"transitive-dependents", svc.TransitiveDependents)
+	server.HandleFunc(QueryRoutePrefix+"shortest-paths", svc.ShortestPaths)
+	server.HandleFunc(QueryRoutePrefix+"cycles", svc.Cycles)
+	server.HandleFunc(QueryRoutePrefix+"build-order", svc.BuildOrder)
+	server.HandleFunc(QueryRoutePrefix+"leaderboard", svc.Leaderboard)
+	server.HandleFunc(QueryRoutePrefix+"evaluate", svc.Evaluate)
+}
+
+type queryService struct {
+	gs       store.GraphStoreClient
+	maxDepth int
+	aliases  *Aliases
+}
+
+// TransitiveDependenciesResponse contains every module the requested module
+// depends on, directly or indirectly.
+type TransitiveDependenciesResponse struct {
+	Dependencies []*Reached `json:"dependencies"`
+}
+
+// TransitiveDependencies handles GET /v1alpha/queries/transitive-dependencies.
+// The module is identified by the language, organization, and module query
+// parameters. The optional depth parameter limits how many edges are
+// followed.
+func (q *queryService) TransitiveDependencies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	req, depth, err := q.parseTraversal(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, err := queryContext(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	dependencies, err := traverse(ctx, q.gs.FindUpstream, keyForDependencyRequest(req), depth)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.query] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query dependencies"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &TransitiveDependenciesResponse{
+		Dependencies: dependencies,
+	})
+}
+
+// TransitiveDependentsResponse contains every module that depends on the
+// requested module, directly or indirectly, and the sources managing them.
+type TransitiveDependentsResponse struct {
+	Dependents []*Reached       `json:"dependents"`
+	Sources    []*ReachedSource `json:"sources"`
+}
+
+// TransitiveDependents handles GET /v1alpha/queries/transitive-dependents.
+// It takes the same parameters as TransitiveDependencies and is used to assess
+// the impact of a change to a module.
+func (q *queryService) TransitiveDependents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	req, depth, err := q.parseTraversal(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, err := queryContext(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	dependents, err := traverse(ctx, q.gs.FindDownstream, keyForDependencyRequest(req), depth)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.query] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query dependents"))
+		return
+	}
+
+	sources, err := sourcesFor(ctx, q.gs.FindDownstream, dependents)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.query] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query sources"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &TransitiveDependentsResponse{
+		Dependents: dependents,
+		Sources:    sources,
+	})
+}
+
+// DependencyPath is a chain of modules where each module depends on the next.
+// The first module is the one the path starts from and has no depends edge.
+type DependencyPath struct {
+	Modules []*Reached `json:"modules"`
+}
+
+// ShortestPathsResponse contains the shortest paths between two modules. It's
+// empty when the target isn't a dependency of the module.
+type ShortestPathsResponse struct {
+	Paths []*DependencyPath `json:"paths"`
+}
+
+// ShortestPaths handles GET /v1alpha/queries/shortest-paths. The module the
+// paths start from is identified by the language, organization, and module
+// query parameters. The target is identified by the target_organization and
+// target_module parameters, along with target_language when it differs. The
+// optional depth and limit parameters bound the length and number of paths.
+func (q *queryService) ShortestPaths(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	req, depth, err := q.parseTraversal(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, err := queryContext(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	query := r.URL.Query()
+	target := &tracker.DependencyRequest{
+		Language:     query.Get("target_language"),
+		Organization: query.Get("target_organization"),
+		Module:       query.Get("target_module"),
+	}
+	if target.Language == "" {
+		target.Language = req.Language
+	}
+
+	if target.Module == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("target_module is required"))
+		return
+	}
+
+	limit := 10
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+			return
+		} else if limit > maxPaths {
+			limit = maxPaths
+		}
+	}
+
+	from, to := keyForDependencyRequest(req), keyForDependencyRequest(q.aliases.request(target))
+	if string(from) == string(to) {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("module and target must differ"))
+		return
+	}
+
+	paths, err := shortestPaths(ctx, q.gs.FindUpstream, from, to, depth, limit)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.query] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query paths"))
+		return
+	}
+
+	response := &ShortestPathsResponse{
+		Paths: make([]*DependencyPath, 0, len(paths)),
+	}
+
+	for _, path := range paths {
+		modules := make([]*Reached, 0, len(path)+1)
+		modules = append(modules, &Reached{
+			Module: &schema.Module{
+				Language:     req.Language,
+				Organization: req.Organization,
+				Module:       req.Module,
+			},
+		})
+
+		for i, pair := range path {
+			module, depends, err := decodePair(pair)
+			if err != nil {
+				logging.FromContext(r.Context()).Errorf("[service.query] %s", err.Error())
+				writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query paths"))
+				return
+			}
+
+			modules = append(modules, &Reached{
+				Module:  module,
+				Depends: depends,
+				Depth:   i + 1,
+			})
+		}
+
+		response.Paths = append(response.Paths, &DependencyPath{Modules: modules})
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// CyclesResponse contains the dependency cycles between a set of modules.
+type CyclesResponse struct {
+	Cycles []*Cycle `json:"cycles"`
+}
+
+// Cycles handles GET /v1alpha/queries/cycles. The language, organization,
+// name_prefix, and labels query parameters limit the modules that are checked.
+// Without them, the entire graph is checked.
+func (q *queryService) Cycles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	ctx, err := queryContext(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	cycles, err := detectCycles(ctx, q.gs, parseFilter(r))
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.query] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to detect cycles"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &CyclesResponse{
+		Cycles: cycles,
+	})
+}
+
+// BuildStep is a module in a build order. Modules in the same stage don't
+// depend on one another and can be published together.
+type BuildStep struct {
+	Module *schema.Module `json:"module"`
+	Stage  int            `json:"stage"`
+}
+
+// BuildOrderResponse contains the order in which a set of modules must be
+// published. When the modules depend on one another, no order exists and the
+// cycles preventing one are returned instead.
+type BuildOrderResponse struct {
+	Steps  []*BuildStep `json:"steps"`
+	Cycles []*Cycle     `json:"cycles,omitempty"`
+}
+
+// BuildOrder handles GET /v1alpha/queries/build-order. It takes the same
+// parameters as Cycles and orders the modules so each comes after the modules
+// it depends on. Dependencies on modules outside of the set are ignored.
+func (q *queryService) BuildOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	ctx, err := queryContext(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	graph, err := loadModuleGraph(ctx, q.gs, parseFilter(r))
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.query] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query modules"))
+		return
+	}
+
+	order, stages, ok := graph.topological()
+	if !ok {
+		cycles := make([]*Cycle, 0)
+		for _, keys := range graph.cycles() {
+			cycles = append(cycles, &Cycle{Modules: graph.modulesFor(keys)})
+		}
+
+		writeJSON(w, r, http.StatusConflict, &BuildOrderResponse{
+			Steps:  make([]*BuildStep, 0),
+			Cycles: cycles,
+		})
+		return
+	}
+
+	steps := make([]*BuildStep, 0, len(order))
+	for _, key := range order {
+		steps = append(steps, &BuildStep{
+			Module: graph.modules[key],
+			Stage:  stages[key],
+		})
+	}
+
+	writeJSON(w, r, http.StatusOK, &BuildOrderResponse{
+		Steps: steps,
+	})
+}
+
+// LeaderboardEntry is a module and the number of modules that depend on it.
+type LeaderboardEntry struct {
+	Module               *schema.Module `json:"module"`
+	DirectDependents     int            `json:"direct_dependents"`
+	TransitiveDependents int            `json:"transitive_dependents"`
+}
+
+// LeaderboardResponse contains the most depended upon modules, most
+// depended upon first.
+type LeaderboardResponse struct {
+	Modules []*LeaderboardEntry `json:"modules"`
+}
+
+// Leaderboard handles GET /v1alpha/queries/leaderboard. The language,
+// organization, name_prefix, and labels query parameters limit the modules
+// that are ranked, but dependents are counted across the entire graph. The
+// sort parameter ranks by transitive, the default, or direct dependents and
+// the limit parameter bounds the number of modules returned.
+func (q *queryService) Leaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+
+	byDirect := false
+	switch query.Get("sort") {
+	case "", "transitive":
+	case "direct":
+		byDirect = true
+	default:
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("sort must be direct or transitive"))
+		return
+	}
+
+	limit, err := positiveInt(query.Get("limit"), 10)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+		return
+	} else if limit > maxLeaderboardSize {
+		limit = maxLeaderboardSize
+	}
+
+	ctx, err := queryContext(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	graph, err := loadModuleGraph(ctx, q.gs, &filters.Filter{})
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.query] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query modules"))
+		return
+	}
+
+	candidates := graph.modules
+	if filter := parseFilter(r); !filter.Empty() {
+		if candidates, err = listModules(ctx, q.gs, filter); err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.query] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query modules"))
+			return
+		}
+	}
+
+	direct, transitive := graph.dependentCounts()
+
+	entries := make([]*LeaderboardEntry, 0, len(candidates))
+	for key, module := range candidates {
+		entries = append(entries, &LeaderboardEntry{
+			Module:               module,
+			DirectDependents:     direct[key],
+			TransitiveDependents: transitive[key],
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+
+		first, second := a.TransitiveDependents-b.TransitiveDependents, a.DirectDependents-b.DirectDependents
+		if byDirect {
+			first, second = second, first
+		}
+
+		if first != 0 {
+			return first > 0
+		} else if second != 0 {
+			return second > 0
+		}
+		return moduleName(a.Module) < moduleName(b.Module)
+	})
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	writeJSON(w, r, http.StatusOK, &LeaderboardResponse{
+		Modules: entries,
+	})
+}
+
+// EvaluateResponse contains the modules produced by a query.
+type EvaluateResponse struct {
+	Modules []*Reached `json:"modules"`
+}
+
+// Evaluate handles GET and POST /v1alpha/queries/evaluate. The query is read
+// from the q parameter, or from the body of a POST, and is written in the
+// language described by parseQuery. This lets traversals be expressed without
+// a route of their own.
+func (q *queryService) Evaluate(w http.ResponseWriter, r *http.Request) {
+	var input string
+
+	switch r.Method {
+	case http.MethodGet:
+		input = r.URL.Query().Get("q")
+	case http.MethodPost:
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxQueryLength+1))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("failed to read query"))
+			return
+		}
+		input = string(body)
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	query, err := parseQuery(input)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, err := queryContext(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	modules, err := q.evaluate(ctx, query)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.query] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to evaluate query"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &EvaluateResponse{
+		Modules: modules,
+	})
+}
+
+// queryContext returns the context used to query the graph store. The
+// optional as_of query parameter, an RFC 3339 timestamp, answers the query
+// using the graph as it was at that time.
+func queryContext(r *http.Request) (context.Context, error) {
+	value := r.URL.Query().Get("as_of")
+	if value == "" {
+		return r.Context(), nil
+	}
+
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("as_of must be an RFC 3339 timestamp")
+	}
+
+	return asof.AppendToOutgoingContext(r.Context(), asOf), nil
+}
+
+// parseFilter reads the filter for queries that operate on a set of modules.
+func parseFilter(r *http.Request) *filters.Filter {
+	query := r.URL.Query()
+
+	return &filters.Filter{
+		Language:     query.Get("language"),
+		Organization: query.Get("organization"),
+		NamePrefix:   query.Get("name_prefix"),
+		Labels:       query.Get("labels"),
+	}
+}
+
+// parseModule reads the module identified by the query parameters.
+func parseModule(r *http.Request) (*tracker.DependencyRequest, error) {
+	query := r.URL.Query()
+
+	req := &tracker.DependencyRequest{
+		Language:     query.Get("language"),
+		Organization: query.Get("organization"),
+		Module:       query.Get("module"),
+	}
+
+	if req.Language == "" || req.Module == "" {
+		return nil, fmt.Errorf("language and module are required")
+	}
+
+	return req, nil
+}
+
+// parseTraversal reads the module and depth of a query that walks the graph.
+func (q *queryService) parseTraversal(r *http.Request) (*tracker.DependencyRequest, int, error) {
+	req, err := parseModule(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	depth := q.maxDepth
+	if value := r.URL.Query().Get("depth"); value != "" {
+		depth, err = strconv.Atoi(value)
+		if err != nil || depth <= 0 {
+			return nil, 0, fmt.Errorf("depth must be a positive integer")
+		} else if depth > q.maxDepth {
+			depth = q.maxDepth
+		}
+	}
+
+	return q.aliases.request(req), depth, nil
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.query] failed to write response: %s", err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	writeJSON(w, r, status, map[string]string{
+		"error": err.Error(),
+	})
+}
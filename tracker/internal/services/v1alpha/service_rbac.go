@@ -0,0 +1,125 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/tenants"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+)
+
+// RegisterRBACService registers the rbacService routes with the http server.
+// Changes to bindings invalidate the bindings cached by the authorizer.
+func RegisterRBACService(server *http.ServeMux, bindings graphstore.RoleBindings, authorizer *rbac.Authorizer) {
+	svc := &rbacService{bindings: bindings, authorizer: authorizer}
+
+	server.HandleFunc(rbac.RoutePrefix+"bindings", svc.Bindings)
+}
+
+// RoleBindingSource lists the role bindings of a tenant from the graph store.
+func RoleBindingSource(bindings graphstore.RoleBindings) rbac.Source {
+	return func(ctx context.Context, tenant string) ([]*rbac.Binding, error) {
+		stored, err := bindings.ListRoleBindings(tenants.NewContext(ctx, tenant))
+		if err != nil {
+			return nil, err
+		}
+
+		results := make([]*rbac.Binding, 0, len(stored))
+		for _, binding := range stored {
+			results = append(results, &rbac.Binding{
+				Subject:      binding.Subject,
+				Role:         rbac.Role(binding.Role),
+				Tenant:       tenant,
+				Organization: binding.Organization,
+			})
+		}
+		return results, nil
+	}
+}
+
+type rbacService struct {
+	bindings   graphstore.RoleBindings
+	authorizer *rbac.Authorizer
+}
+
+// RoleRequest sets the role of a binding.
+type RoleRequest struct {
+	Role rbac.Role `json:"role"`
+}
+
+// Bindings handles GET, PUT, and DELETE /v1alpha/rbac/bindings. PUT and
+// DELETE set and remove the binding identified by the subject and
+// organization parameters, so only admins of the organization can change
+// them. Each responds with the bindings of the tenant, filtered by the
+// subject parameter when it's provided.
+func (s *rbacService) Bindings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := tenants.FromContext(ctx)
+	subject := r.URL.Query().Get("subject")
+	organization := r.URL.Query().Get("organization")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		req := &RoleRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("failed to parse role"))
+			return
+		}
+
+		binding := &rbac.Binding{Subject: subject, Role: req.Role, Organization: organization}
+		if err := binding.Validate(); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err := s.bindings.PutRoleBinding(ctx, &graphstore.RoleBinding{
+			Subject:      binding.Subject,
+			Organization: binding.Organization,
+			Role:         string(binding.Role),
+		})
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.rbac] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to set role binding"))
+			return
+		}
+
+		s.authorizer.Invalidate(tenant)
+	case http.MethodDelete:
+		if subject == "" {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("subject is required"))
+			return
+		}
+
+		if err := s.bindings.DeleteRoleBinding(ctx, subject, organization); err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.rbac] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to delete role binding"))
+			return
+		}
+
+		s.authorizer.Invalidate(tenant)
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	listed, err := RoleBindingSource(s.bindings)(ctx, tenant)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.rbac] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to list role bindings"))
+		return
+	}
+
+	results := make([]*rbac.Binding, 0, len(listed))
+	for _, binding := range listed {
+		if subject == "" || binding.Subject == subject {
+			results = append(results, binding)
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, &rbac.BindingsResponse{Bindings: results})
+}
@@ -0,0 +1,113 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/tenants"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRoleBindings holds role bindings by tenant.
+type fakeRoleBindings map[string][]*graphstore.RoleBinding
+
+func (f fakeRoleBindings) ListRoleBindings(ctx context.Context) ([]*graphstore.RoleBinding, error) {
+	return f[tenants.FromContext(ctx)], nil
+}
+
+func (f fakeRoleBindings) PutRoleBinding(ctx context.Context, binding *graphstore.RoleBinding) error {
+	_ = f.DeleteRoleBinding(ctx, binding.Subject, binding.Organization)
+
+	tenant := tenants.FromContext(ctx)
+	f[tenant] = append(f[tenant], binding)
+	return nil
+}
+
+func (f fakeRoleBindings) DeleteRoleBinding(ctx context.Context, subject, organization string) error {
+	tenant := tenants.FromContext(ctx)
+
+	remaining := make([]*graphstore.RoleBinding, 0)
+	for _, binding := range f[tenant] {
+		if binding.Subject != subject || binding.Organization != organization {
+			remaining = append(remaining, binding)
+		}
+	}
+	f[tenant] = remaining
+	return nil
+}
+
+func TestRBAC(t *testing.T) {
+	bindings := fakeRoleBindings{
+		"": {{Subject: "root", Role: "admin"}},
+	}
+
+	authorizer, err := rbac.NewAuthorizer(&rbac.Config{
+		Mode:            rbac.ModeMetadata,
+		DefaultRole:     string(rbac.RoleViewer),
+		RefreshInterval: time.Hour,
+	}, RoleBindingSource(bindings))
+	require.Nil(t, err)
+
+	server := http.NewServeMux()
+	RegisterRBACService(server, bindings, authorizer)
+	handler := authorizer.Middleware(server)
+
+	call := func(subject, method, path, body string) (int, []*rbac.Binding) {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set(rbac.HeaderKey, subject)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			return recorder.Code, nil
+		}
+
+		response := &rbac.BindingsResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		return recorder.Code, response.Bindings
+	}
+
+	code, listed := call("", http.MethodGet, "/v1alpha/rbac/bindings", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []*rbac.Binding{{Subject: "root", Role: rbac.RoleAdmin}}, listed)
+
+	// only admins can bind roles
+	code, _ = call("alice", http.MethodPut, "/v1alpha/rbac/bindings?subject=alice&organization=depscloud", `{"role":"admin"}`)
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, listed = call("root", http.MethodPut, "/v1alpha/rbac/bindings?subject=alice&organization=depscloud", `{"role":"admin"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []*rbac.Binding{{Subject: "alice", Role: rbac.RoleAdmin, Organization: "depscloud"}}, listed)
+
+	// admins of an organization manage the bindings within it, which take
+	// effect immediately
+	code, _ = call("alice", http.MethodPut, "/v1alpha/rbac/bindings?subject=bob&organization=depscloud", `{"role":"editor"}`)
+	require.Equal(t, http.StatusOK, code)
+
+	code, _ = call("alice", http.MethodPut, "/v1alpha/rbac/bindings?subject=bob", `{"role":"editor"}`)
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, _ = call("root", http.MethodPut, "/v1alpha/rbac/bindings?subject=bob", `{"role":"owner"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = call("root", http.MethodDelete, "/v1alpha/rbac/bindings?subject=alice&organization=depscloud", "")
+	require.Equal(t, http.StatusOK, code)
+
+	code, _ = call("alice", http.MethodDelete, "/v1alpha/rbac/bindings?subject=bob&organization=depscloud", "")
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, listed = call("", http.MethodGet, "/v1alpha/rbac/bindings?subject=bob", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []*rbac.Binding{{Subject: "bob", Role: rbac.RoleEditor, Organization: "depscloud"}}, listed)
+
+	code, _ = call("root", http.MethodPost, "/v1alpha/rbac/bindings", "")
+	require.Equal(t, http.StatusMethodNotAllowed, code)
+}
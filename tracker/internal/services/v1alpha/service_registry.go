@@ -0,0 +1,169 @@
+package v1alpha
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/constraints"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/registries"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// RegistryRoutePrefix prefixes the HTTP routes listing the edges of a module
+// along with the registry metadata recorded by the enrichment.
+const RegistryRoutePrefix = "/v1alpha/registry/"
+
+// RegisterRegistryService registers the registryService routes with the http
+// server.
+func RegisterRegistryService(server *http.ServeMux, gs store.GraphStoreClient, labels graphstore.Labels, aliases *Aliases) {
+	svc := &registryService{gs: gs, labels: labels, aliases: aliases}
+
+	server.HandleFunc(RegistryRoutePrefix+"dependencies", svc.Dependencies)
+	server.HandleFunc(RegistryRoutePrefix+"dependents", svc.Dependents)
+}
+
+type registryService struct {
+	gs      store.GraphStoreClient
+	labels  graphstore.Labels
+	aliases *Aliases
+}
+
+// RegistryEdge is a dependency on a module, described by the version that's
+// used and how far it trails the latest published version.
+type RegistryEdge struct {
+	Module            *schema.Module       `json:"module"`
+	VersionConstraint string               `json:"version_constraint"`
+	Version           string               `json:"version,omitempty"`
+	Registry          *registries.Metadata `json:"registry,omitempty"`
+	Lifecycle         *Lifecycle           `json:"lifecycle,omitempty"`
+	Behind            string               `json:"behind,omitempty"`
+	Distance          int                  `json:"distance,omitempty"`
+}
+
+// RegistryDependenciesResponse contains the dependencies of a module.
+type RegistryDependenciesResponse struct {
+	Dependencies []*RegistryEdge `json:"dependencies"`
+}
+
+// RegistryDependentsResponse contains the dependents of a module along with
+// the module's registry metadata.
+type RegistryDependentsResponse struct {
+	Registry   *registries.Metadata `json:"registry,omitempty"`
+	Dependents []*RegistryEdge      `json:"dependents"`
+}
+
+// Dependencies handles GET /v1alpha/registry/dependencies. It lists the
+// modules the module identified by the language, organization, and module
+// parameters depends on, along with their registry metadata. Setting the
+// outdated parameter to true only lists dependencies on a version older than
+// the latest, and setting deprecated to true only lists modules deprecated by
+// their registry or marked with a lifecycle.
+func (s *registryService) Dependencies(w http.ResponseWriter, r *http.Request) {
+	edges, _, ok := s.handle(w, r, true)
+	if ok {
+		writeJSON(w, r, http.StatusOK, &RegistryDependenciesResponse{Dependencies: edges})
+	}
+}
+
+// Dependents handles GET /v1alpha/registry/dependents. It takes the same
+// parameters as Dependencies and lists the modules that depend on the module,
+// which can be used to find the dependents using an outdated version of it.
+func (s *registryService) Dependents(w http.ResponseWriter, r *http.Request) {
+	edges, metadata, ok := s.handle(w, r, false)
+	if ok {
+		writeJSON(w, r, http.StatusOK, &RegistryDependentsResponse{Registry: metadata, Dependents: edges})
+	}
+}
+
+func (s *registryService) handle(w http.ResponseWriter, r *http.Request, upstream bool) ([]*RegistryEdge, *registries.Metadata, bool) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return nil, nil, false
+	}
+
+	req, err := parseModule(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return nil, nil, false
+	}
+
+	query := r.URL.Query()
+	outdatedOnly := query.Get("outdated") == "true"
+	deprecatedOnly := query.Get("deprecated") == "true"
+
+	ctx := r.Context()
+	key := keyForDependencyRequest(s.aliases.request(req))
+
+	findFn := s.gs.FindDownstream
+	if upstream {
+		findFn = s.gs.FindUpstream
+	}
+
+	pairs, err := findPairs(ctx, findFn, [][]byte{key}, types.DependsType, types.ModuleType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.registry] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find modules"))
+		return nil, nil, false
+	}
+
+	// the metadata belongs to the module that's depended on
+	keys := [][]byte{key}
+	if upstream {
+		keys = make([][]byte, len(pairs))
+		for i, pair := range pairs {
+			keys[i] = pair.GetNode().GetK1()
+		}
+	}
+
+	labels, err := s.labels.GetLabels(ctx, types.ModuleType, keys)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.registry] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to get registry metadata"))
+		return nil, nil, false
+	}
+
+	edges := make([]*RegistryEdge, 0, len(pairs))
+	for _, pair := range pairs {
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return nil, nil, false
+		}
+		module := node.(*schema.Module)
+
+		edge, err := Decode(pair.GetEdge())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return nil, nil, false
+		}
+		constraint := edge.(*schema.Depends).GetVersionConstraint()
+
+		dependencyLabels := labels[string(pair.GetEdge().GetK2())]
+		metadata := registries.FromLabels(dependencyLabels)
+		result := &RegistryEdge{
+			Module:            module,
+			VersionConstraint: constraint,
+			Version:           resolveVersion(module.GetLanguage(), constraint),
+			Registry:          metadata,
+			Lifecycle:         lifecycle(dependencyLabels),
+		}
+
+		if metadata != nil && result.Version != "" {
+			result.Behind, result.Distance = constraints.Behind(result.Version, metadata.Latest)
+		}
+
+		if outdatedOnly && result.Behind == "" {
+			continue
+		} else if deprecatedOnly && (metadata == nil || !metadata.Deprecated) && result.Lifecycle == nil {
+			continue
+		}
+
+		edges = append(edges, result)
+	}
+
+	return edges, registries.FromLabels(labels[string(key)]), true
+}
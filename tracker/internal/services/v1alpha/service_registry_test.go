@@ -0,0 +1,91 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/registries"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryEnrichment(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"d": {"b"},
+	})
+
+	proxy := http.NewServeMux()
+	proxy.HandleFunc("/depscloud/b/@latest", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Version":"v1.2.0","Time":"2020-10-01T12:00:00Z"}`))
+	})
+	proxy.HandleFunc("/depscloud/c/@latest", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Version":"v1.0.0","Time":"2020-10-01T12:00:00Z"}`))
+	})
+	proxy.HandleFunc("/depscloud/d/@latest", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	labels := fakeLabels{}
+	labels[types.ModuleType+string(moduleKey("b"))] = map[string]string{"team": "platform", registries.LabelDeprecated: "true"}
+
+	client := registries.NewClient("depscloud-tracker", map[string]string{"go": server.URL})
+
+	report, err := enrichModules(context.Background(), gs, labels, client)
+	require.Nil(t, err)
+	require.Equal(t, &EnrichmentReport{Modules: 4, Enriched: 2, Failed: 1}, report)
+
+	// labels set by others are kept while stale registry labels are replaced
+	require.Equal(t, map[string]string{
+		"team":                    "platform",
+		registries.LabelLatest:    "v1.2.0",
+		registries.LabelPublished: "2020-10-01T12:00:00Z",
+	}, labels[types.ModuleType+string(moduleKey("b"))])
+
+	// unpublished modules aren't labeled
+	_, ok := labels[types.ModuleType+string(moduleKey("a"))]
+	require.False(t, ok)
+
+	mux := http.NewServeMux()
+	RegisterRegistryService(mux, gs, labels, nil)
+
+	call := func(route, query string, response interface{}) {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/v1alpha/registry/"+route+"?language=go&organization=depscloud&"+query, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+	}
+
+	dependencies := &RegistryDependenciesResponse{}
+	call("dependencies", "module=a", dependencies)
+	require.Len(t, dependencies.Dependencies, 2)
+
+	dependencies = &RegistryDependenciesResponse{}
+	call("dependencies", "module=a&outdated=true", dependencies)
+	require.Len(t, dependencies.Dependencies, 1)
+	require.Equal(t, "b", dependencies.Dependencies[0].Module.GetModule())
+	require.Equal(t, "v1.0.0", dependencies.Dependencies[0].Version)
+	require.Equal(t, "v1.2.0", dependencies.Dependencies[0].Registry.Latest)
+	require.Equal(t, "minor", dependencies.Dependencies[0].Behind)
+	require.Equal(t, 2, dependencies.Dependencies[0].Distance)
+
+	dependencies = &RegistryDependenciesResponse{}
+	call("dependencies", "module=a&deprecated=true", dependencies)
+	require.Len(t, dependencies.Dependencies, 0)
+
+	dependents := &RegistryDependentsResponse{}
+	call("dependents", "module=b&outdated=true", dependents)
+	require.Equal(t, "v1.2.0", dependents.Registry.Latest)
+	require.Len(t, dependents.Dependents, 2)
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/registry/dependencies", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
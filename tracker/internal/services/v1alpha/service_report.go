@@ -0,0 +1,106 @@
+package v1alpha
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+)
+
+// ReportRoutePrefix prefixes the HTTP routes generating reports on demand.
+const ReportRoutePrefix = "/v1alpha/reports/"
+
+// defaultReportPeriod is how far back reports requested without a since
+// parameter look for changes.
+const defaultReportPeriod = 7 * 24 * time.Hour
+
+// RegisterReportService registers the reportService routes with the http
+// server.
+func RegisterReportService(server *http.ServeMux, generator *ReportGenerator) {
+	svc := &reportService{generator: generator}
+
+	server.HandleFunc(ReportRoutePrefix+"organizations", svc.Organizations)
+}
+
+type reportService struct {
+	generator *ReportGenerator
+}
+
+// Organizations handles GET /v1alpha/reports/organizations. It generates the
+// report of the organization parameter in the format parameter, defaulting
+// to json. The since and until parameters are RFC 3339 timestamps bounding
+// the changes that are reported, defaulting to the last week, and top limits
+// the number of risky modules.
+func (s *reportService) Organizations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+
+	organization := query.Get("organization")
+	if organization == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("organization is required"))
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = ReportFormatJSON
+	} else if _, ok := reportContentTypes[format]; !ok {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("unsupported format %s", format))
+		return
+	}
+
+	var err error
+
+	until := time.Now()
+	if value := query.Get("until"); value != "" {
+		if until, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("until must be an RFC 3339 timestamp"))
+			return
+		}
+	}
+
+	since := until.Add(-defaultReportPeriod)
+	if value := query.Get("since"); value != "" {
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("since must be an RFC 3339 timestamp"))
+			return
+		}
+	}
+
+	if !since.Before(until) {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("since must be before until"))
+		return
+	}
+
+	top := defaultReportTop
+	if value := query.Get("top"); value != "" {
+		if top, err = strconv.Atoi(value); err != nil || top <= 0 {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("top must be a positive number"))
+			return
+		}
+	}
+
+	report, err := s.generator.Generate(r.Context(), organization, since, until, top)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.report] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to generate report"))
+		return
+	}
+
+	body, err := report.render(format)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.report] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to render report"))
+		return
+	}
+
+	w.Header().Set("Content-Type", reportContentTypes[format])
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
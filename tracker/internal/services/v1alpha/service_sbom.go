@@ -0,0 +1,123 @@
+package v1alpha
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/sbom"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// SBOMRoutePrefix prefixes the HTTP routes generating software bills of
+// materials from the graph.
+const SBOMRoutePrefix = "/v1alpha/sbom/"
+
+// RegisterSBOMService registers the sbomService routes with the http server.
+// The version is recorded as the version of the tool creating the SBOMs.
+func RegisterSBOMService(server *http.ServeMux, gs store.GraphStoreClient, version string) {
+	svc := &sbomService{gs: gs, version: version}
+
+	server.HandleFunc(SBOMRoutePrefix+"sources", svc.Sources)
+}
+
+type sbomService struct {
+	gs      store.GraphStoreClient
+	version string
+}
+
+// Sources handles GET /v1alpha/sbom/sources. It writes an SBOM for the source
+// identified by the url parameter, listing the modules it manages and the
+// modules they depend on. The format parameter is either cyclonedx, the
+// default, or spdx.
+func (s *sbomService) Sources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+
+	url := query.Get("url")
+	if url == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("url is required"))
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = sbom.FormatCycloneDX
+	} else if err := sbom.Validate(format); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	managed, err := findPairs(ctx, s.gs.FindUpstream, [][]byte{keyForSource(&schema.Source{Url: url})}, types.ManagesType, types.ModuleType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.sbom] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find managed modules"))
+		return
+	} else if len(managed) == 0 {
+		writeError(w, r, http.StatusNotFound, fmt.Errorf("no modules are known for source %s", url))
+		return
+	}
+
+	managedKeys := make([][]byte, len(managed))
+	for i, pair := range managed {
+		managedKeys[i] = pair.GetNode().GetK1()
+	}
+
+	pairs, err := findPairs(ctx, s.gs.FindUpstream, managedKeys, types.DependsType, types.ModuleType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.sbom] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find dependencies"))
+		return
+	}
+
+	dependencies := make(map[string][]*sbom.Component, len(managed))
+	for _, pair := range pairs {
+		module, depends, err := decodePair(pair)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		dependent := string(pair.GetEdge().GetK1())
+		dependencies[dependent] = append(dependencies[dependent], sbom.NewComponent(module, depends.GetVersionConstraint()))
+	}
+
+	b := sbom.New(url)
+	for _, pair := range managed {
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		b.DependsOn(sbom.NewComponent(node.(*schema.Module), ""), dependencies[string(pair.GetNode().GetK1())])
+	}
+	b.Sort()
+
+	doc, err := sbom.NewDocument("depscloud-tracker", s.version)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.sbom] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to generate sbom"))
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err := sbom.Write(buf, format, b, doc); err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.sbom] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to generate sbom"))
+		return
+	}
+
+	w.Header().Set("Content-Type", sbom.ContentTypes[format])
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,62 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSBOM(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"d": {"b"},
+	})
+	gs.manage(t, "https://github.com/depscloud/a.git", "a")
+
+	server := http.NewServeMux()
+	RegisterSBOMService(server, gs, "0.1.0")
+
+	get := func(query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/sbom/sources?"+query, nil))
+		return recorder
+	}
+
+	recorder := get("url=https://github.com/depscloud/a.git")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/vnd.cyclonedx+json", recorder.Header().Get("Content-Type"))
+
+	bom := &struct {
+		BOMFormat  string `json:"bomFormat"`
+		Components []struct {
+			PURL    string `json:"purl"`
+			Version string `json:"version"`
+		} `json:"components"`
+		Dependencies []struct {
+			Ref       string   `json:"ref"`
+			DependsOn []string `json:"dependsOn"`
+		} `json:"dependencies"`
+	}{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(bom))
+	require.Equal(t, "CycloneDX", bom.BOMFormat)
+	require.Len(t, bom.Components, 3)
+	require.Equal(t, "pkg:golang/depscloud/a", bom.Components[0].PURL)
+	require.Equal(t, "v1.0.0", bom.Components[1].Version)
+	require.Len(t, bom.Dependencies, 1)
+	require.Equal(t, []string{"pkg:golang/depscloud/b@v1.0.0", "pkg:golang/depscloud/c@v1.0.0"}, bom.Dependencies[0].DependsOn)
+
+	recorder = get("url=https://github.com/depscloud/a.git&format=spdx")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/spdx+json", recorder.Header().Get("Content-Type"))
+
+	spdx := make(map[string]interface{})
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(&spdx))
+	require.Equal(t, "SPDX-2.3", spdx["spdxVersion"])
+
+	require.Equal(t, http.StatusNotFound, get("url=https://github.com/depscloud/unknown.git").Code)
+	require.Equal(t, http.StatusBadRequest, get("url=https://github.com/depscloud/a.git&format=xml").Code)
+	require.Equal(t, http.StatusBadRequest, get("").Code)
+}
@@ -2,6 +2,8 @@ package v1alpha
 
 import (
 	"context"
+	"io"
+	"sync"
 
 	"github.com/depscloud/api"
 	"github.com/depscloud/api/v1alpha/store"
@@ -13,18 +15,28 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// DefaultSearchWindow is the number of requests on a search stream that are
+// processed at once when no window is configured.
+const DefaultSearchWindow = 16
+
 // RegisterSearchService registers the searchService implementation with the server
-func RegisterSearchService(server *grpc.Server, gs store.GraphStoreClient) {
+func RegisterSearchService(server *grpc.Server, gs store.GraphStoreClient, window int, aliases *Aliases) {
+	if window <= 0 {
+		window = DefaultSearchWindow
+	}
+
 	tracker.RegisterSearchServiceServer(server, &searchService{
-		gs: gs,
-		ss: &sourceService{gs: gs},
-		ms: &moduleService{gs: gs},
-		ds: &dependencyService{gs: gs},
+		gs:     gs,
+		window: window,
+		ss:     &sourceService{gs: gs, aliases: aliases},
+		ms:     &moduleService{gs: gs, aliases: aliases},
+		ds:     &dependencyService{gs: gs, aliases: aliases},
 	})
 }
 
 type searchService struct {
-	gs store.GraphStoreClient
+	gs     store.GraphStoreClient
+	window int
 
 	ss tracker.SourceServiceServer
 	ms tracker.ModuleServiceServer
@@ -92,34 +104,100 @@ func (s *searchService) processRequest(ctx context.Context, request *tracker.Sea
 	return response, interr
 }
 
+// Search processes the requests sent on the stream and sends a response for
+// each, carrying the request so clients can match them up. Up to window
+// requests are processed at a time. Once the window is full, no further
+// requests are read until a response has been sent, so a client that sends
+// faster than it receives is slowed down by the stream's flow control. The
+// stream ends once the client stops sending or sends a cancel request.
 func (s *searchService) Search(server tracker.SearchService_SearchServer) error {
 	ctx, cancel := context.WithCancel(server.Context())
 	defer cancel()
 
 	done := ctx.Done()
-	stream := consumeStream(ctx, server)
+	window := make(chan struct{}, s.window)
+	requests := make(chan *tracker.SearchRequest)
+	recvErr := make(chan error, 1)
 
-	select {
-	case <-done:
-		return nil
-	case request := <-stream:
-		if request.GetCancel() {
-			return nil
-		}
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case window <- struct{}{}:
+			}
 
-		// TODO: eventually send this to a task queue
+			request, err := server.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
 
-		response, err := s.processRequest(ctx, request)
-		if err != nil {
-			return err
+			select {
+			case <-done:
+				return
+			case requests <- request:
+			}
 		}
+	}()
 
-		if err := server.Send(response); err != nil {
-			return err
+	wg := &sync.WaitGroup{}
+	sendLock := &sync.Mutex{}
+	errs := make(chan error, 1)
+
+	// wait lets the requests in flight finish and returns the first error
+	wait := func(err error) error {
+		wg.Wait()
+
+		if err == nil {
+			select {
+			case err = <-errs:
+			default:
+			}
 		}
+		return err
 	}
 
-	return nil
+	for {
+		select {
+		case <-done:
+			return wait(nil)
+
+		case err := <-recvErr:
+			if err != io.EOF {
+				cancel()
+				return wait(err)
+			}
+			return wait(nil)
+
+		case request := <-requests:
+			if request.GetCancel() {
+				cancel()
+				return wait(nil)
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-window }()
+
+				response, err := s.processRequest(ctx, request)
+				if err == nil {
+					sendLock.Lock()
+					err = server.Send(response)
+					sendLock.Unlock()
+				}
+
+				if err != nil {
+					select {
+					case errs <- err:
+						cancel()
+					default:
+					}
+				}
+			}()
+		}
+	}
 }
 
 func transformResponse(response *tracker.SearchResponse) (requests []*tracker.SearchRequest, err error) {
@@ -0,0 +1,110 @@
+package v1alpha
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/tracker"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+)
+
+// fakeSearchServer replays requests and records the responses along with the
+// most requests that were ever read ahead of their responses.
+type fakeSearchServer struct {
+	grpc.ServerStream
+
+	mu        sync.Mutex
+	requests  []*tracker.SearchRequest
+	responses []*tracker.SearchResponse
+	inFlight  int
+	maxFlight int
+}
+
+func (f *fakeSearchServer) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fakeSearchServer) Recv() (*tracker.SearchRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.requests) == 0 {
+		return nil, io.EOF
+	}
+
+	request := f.requests[0]
+	f.requests = f.requests[1:]
+
+	f.inFlight++
+	if f.inFlight > f.maxFlight {
+		f.maxFlight = f.inFlight
+	}
+
+	return request, nil
+}
+
+func (f *fakeSearchServer) Send(response *tracker.SearchResponse) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.inFlight--
+	f.responses = append(f.responses, response)
+	return nil
+}
+
+func TestSearch(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"c"},
+		"b": {"c"},
+		"c": {"d"},
+	})
+
+	svc := &searchService{
+		gs:     gs,
+		window: 2,
+		ds:     &dependencyService{gs: gs},
+	}
+
+	dependency := func(module string) *tracker.DependencyRequest {
+		return &tracker.DependencyRequest{Language: "go", Organization: "depscloud", Module: module}
+	}
+
+	server := &fakeSearchServer{}
+	for i := 0; i < 25; i++ {
+		server.requests = append(server.requests,
+			&tracker.SearchRequest{DependentsOf: dependency("c")},
+			&tracker.SearchRequest{DependenciesOf: dependency("c")},
+		)
+	}
+
+	require.Nil(t, svc.Search(server))
+	require.Len(t, server.responses, 50)
+	require.LessOrEqual(t, server.maxFlight, 2)
+
+	for _, response := range server.responses {
+		if response.GetRequest().GetDependentsOf() != nil {
+			require.Len(t, response.GetDependents(), 2)
+		} else {
+			require.Len(t, response.GetDependencies(), 1)
+			require.Equal(t, "d", response.GetDependencies()[0].GetModule().GetModule())
+		}
+	}
+
+	// nothing after a cancel is processed
+	server = &fakeSearchServer{requests: []*tracker.SearchRequest{
+		{Cancel: true},
+		{DependentsOf: dependency("c")},
+	}}
+
+	require.Nil(t, svc.Search(server))
+	require.Len(t, server.responses, 0)
+
+	// the first failed request ends the stream
+	server = &fakeSearchServer{requests: []*tracker.SearchRequest{{}}}
+	require.NotNil(t, svc.Search(server))
+}
@@ -0,0 +1,315 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/serviceaccounts"
+	"github.com/depscloud/depscloud/internal/tenants"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+)
+
+// RegisterServiceAccountService registers the serviceAccountService routes
+// with the http server.
+func RegisterServiceAccountService(server *http.ServeMux, accounts graphstore.ServiceAccounts) {
+	svc := &serviceAccountService{accounts: accounts}
+
+	server.HandleFunc(serviceaccounts.RoutePrefix+"accounts", svc.Accounts)
+	server.HandleFunc(serviceaccounts.RoutePrefix+"tokens", svc.Tokens)
+	server.HandleFunc(serviceaccounts.RoutePrefix+"tokens/rotate", svc.Rotate)
+	server.HandleFunc(serviceaccounts.RoutePrefix+"tokens/verify", svc.Verify)
+}
+
+// TokenVerifier verifies service account tokens against the hashes stored in
+// the graph store. Other bearer tokens aren't recognized.
+func TokenVerifier(accounts graphstore.ServiceAccounts) rbac.Verifier {
+	return func(ctx context.Context, tenant, token string) (*rbac.Credential, error) {
+		if !serviceaccounts.Recognized(token) {
+			return nil, nil
+		}
+
+		id, secret, err := serviceaccounts.Parse(token)
+		if err != nil {
+			return nil, err
+		}
+
+		stored, err := accounts.GetToken(tenants.NewContext(ctx, tenant), id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := serviceaccounts.Verify(stored, secret, time.Now()); err != nil {
+			return nil, err
+		}
+
+		return stored.Credential(), nil
+	}
+}
+
+type serviceAccountService struct {
+	accounts graphstore.ServiceAccounts
+}
+
+// account returns the service account with the name, or nil when there isn't
+// one.
+func (s *serviceAccountService) account(ctx context.Context, name string) (*serviceaccounts.Account, error) {
+	accounts, err := s.accounts.ListServiceAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, account := range accounts {
+		if account.Name == name {
+			return account, nil
+		}
+	}
+	return nil, nil
+}
+
+// Accounts handles GET, PUT, and DELETE /v1alpha/serviceaccounts/accounts.
+// PUT creates or updates the service account identified by the name
+// parameter. DELETE removes it along with its tokens. Each responds with the
+// service accounts of the tenant.
+func (s *serviceAccountService) Accounts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := r.URL.Query().Get("name")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		req := &serviceaccounts.AccountRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("failed to parse service account"))
+			return
+		}
+
+		if err := serviceaccounts.ValidateName(name); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		existing, err := s.account(ctx, name)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.serviceaccounts] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to put service account"))
+			return
+		}
+
+		account := &serviceaccounts.Account{Name: name, Description: req.Description, CreatedAt: time.Now().UTC()}
+		if existing != nil {
+			account.CreatedAt = existing.CreatedAt
+		}
+
+		if err := s.accounts.PutServiceAccount(ctx, account); err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.serviceaccounts] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to put service account"))
+			return
+		}
+	case http.MethodDelete:
+		if name == "" {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("name is required"))
+			return
+		}
+
+		if err := s.accounts.DeleteServiceAccount(ctx, name); err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.serviceaccounts] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to delete service account"))
+			return
+		}
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	accounts, err := s.accounts.ListServiceAccounts(ctx)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.serviceaccounts] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to list service accounts"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &serviceaccounts.AccountsResponse{Accounts: accounts})
+}
+
+// issue creates a new token for the account, stores it using put, and writes
+// it to the response.
+func (s *serviceAccountService) issue(
+	w http.ResponseWriter, r *http.Request,
+	account string, role rbac.Role, organization string, ttl time.Duration,
+	put func(ctx context.Context, token *serviceaccounts.Token) error,
+) {
+	ctx := r.Context()
+
+	token, details, err := serviceaccounts.Issue(account, role, organization, ttl)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := put(ctx, details); errors.Is(err, graphstore.ErrTokenRevoked) {
+		writeError(w, r, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		logging.FromContext(ctx).Errorf("[service.serviceaccounts] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to issue token"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &serviceaccounts.IssuedResponse{Token: token, Details: details})
+}
+
+// Tokens handles GET, POST, and DELETE /v1alpha/serviceaccounts/tokens. GET
+// lists the tokens of the service account named by the account parameter.
+// POST issues it a token scoped to a role and, optionally, an organization.
+// The secret token is only returned in this response. DELETE revokes the
+// token identified by the id parameter.
+func (s *serviceAccountService) Tokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := r.URL.Query().Get("account")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		req := &serviceaccounts.TokenRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("failed to parse token request"))
+			return
+		}
+
+		ttl := serviceaccounts.DefaultTTL
+		if req.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(req.TTL); err != nil {
+				writeError(w, r, http.StatusBadRequest, fmt.Errorf("ttl must be a duration, like 720h"))
+				return
+			}
+		}
+
+		account, err := s.account(ctx, name)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.serviceaccounts] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to issue token"))
+			return
+		} else if account == nil {
+			writeError(w, r, http.StatusNotFound, fmt.Errorf("service account %q not found", name))
+			return
+		}
+
+		s.issue(w, r, account.Name, req.Role, req.Organization, ttl, s.accounts.PutToken)
+		return
+	case http.MethodDelete:
+		token, ok := s.revoke(w, r)
+		if !ok {
+			return
+		}
+		name = token.Account
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	if name == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("account is required"))
+		return
+	}
+
+	tokens, err := s.accounts.ListTokens(ctx, name)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.serviceaccounts] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to list tokens"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &serviceaccounts.TokensResponse{Tokens: tokens})
+}
+
+// revoke revokes the token identified by the id parameter, writing an error
+// to the response when it can't.
+func (s *serviceAccountService) revoke(w http.ResponseWriter, r *http.Request) (*serviceaccounts.Token, bool) {
+	ctx := r.Context()
+	id := r.URL.Query().Get("id")
+
+	token, err := s.accounts.GetToken(ctx, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.serviceaccounts] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to revoke token"))
+		return nil, false
+	} else if token == nil {
+		writeError(w, r, http.StatusNotFound, fmt.Errorf("token %q not found", id))
+		return nil, false
+	}
+
+	if err := s.accounts.RevokeToken(ctx, id, time.Now().UTC()); err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.serviceaccounts] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to revoke token"))
+		return nil, false
+	}
+
+	return token, true
+}
+
+// Rotate handles POST /v1alpha/serviceaccounts/tokens/rotate. The active token
+// identified by the id parameter is revoked and replaced by a token with the
+// same scope and lifetime. Revoked and expired tokens can't be rotated, since
+// that would bring their access back.
+func (s *serviceAccountService) Rotate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	ctx := r.Context()
+	id := r.URL.Query().Get("id")
+
+	token, err := s.accounts.GetToken(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[service.serviceaccounts] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to rotate token"))
+		return
+	} else if token == nil {
+		writeError(w, r, http.StatusNotFound, fmt.Errorf("token %q not found", id))
+		return
+	}
+
+	now := time.Now().UTC()
+	if !token.Active(now) {
+		writeError(w, r, http.StatusConflict, fmt.Errorf("token %q is revoked or expired", id))
+		return
+	}
+
+	s.issue(w, r, token.Account, token.Role, token.Organization, token.ExpiresAt.Sub(token.CreatedAt),
+		func(ctx context.Context, replacement *serviceaccounts.Token) error {
+			return s.accounts.RotateToken(ctx, id, now, replacement)
+		})
+}
+
+// Verify handles POST /v1alpha/serviceaccounts/tokens/verify, responding with
+// the credential of an active token. It's used by the gateway to identify
+// callers, so it's exempt from roles.
+func (s *serviceAccountService) Verify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	req := &serviceaccounts.VerifyRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("failed to parse token"))
+		return
+	}
+
+	credential, err := TokenVerifier(s.accounts)(r.Context(), tenants.FromContext(r.Context()), req.Token)
+	if err != nil || credential == nil {
+		writeError(w, r, http.StatusUnauthorized, fmt.Errorf("invalid token"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, credential)
+}
@@ -0,0 +1,187 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/serviceaccounts"
+	"github.com/depscloud/depscloud/internal/tenants"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServiceAccounts holds service accounts and tokens by tenant.
+type fakeServiceAccounts struct {
+	accounts map[string][]*serviceaccounts.Account
+	tokens   map[string][]*serviceaccounts.Token
+}
+
+func (f *fakeServiceAccounts) ListServiceAccounts(ctx context.Context) ([]*serviceaccounts.Account, error) {
+	return f.accounts[tenants.FromContext(ctx)], nil
+}
+
+func (f *fakeServiceAccounts) PutServiceAccount(ctx context.Context, account *serviceaccounts.Account) error {
+	tenant := tenants.FromContext(ctx)
+
+	remaining := make([]*serviceaccounts.Account, 0)
+	for _, existing := range f.accounts[tenant] {
+		if existing.Name != account.Name {
+			remaining = append(remaining, existing)
+		}
+	}
+	f.accounts[tenant] = append(remaining, account)
+	return nil
+}
+
+func (f *fakeServiceAccounts) DeleteServiceAccount(ctx context.Context, name string) error {
+	tenant := tenants.FromContext(ctx)
+
+	accounts := make([]*serviceaccounts.Account, 0)
+	for _, account := range f.accounts[tenant] {
+		if account.Name != name {
+			accounts = append(accounts, account)
+		}
+	}
+	f.accounts[tenant] = accounts
+
+	tokens := make([]*serviceaccounts.Token, 0)
+	for _, token := range f.tokens[tenant] {
+		if token.Account != name {
+			tokens = append(tokens, token)
+		}
+	}
+	f.tokens[tenant] = tokens
+	return nil
+}
+
+func (f *fakeServiceAccounts) ListTokens(ctx context.Context, account string) ([]*serviceaccounts.Token, error) {
+	results := make([]*serviceaccounts.Token, 0)
+	for _, token := range f.tokens[tenants.FromContext(ctx)] {
+		if token.Account == account {
+			results = append(results, token)
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeServiceAccounts) GetToken(ctx context.Context, id string) (*serviceaccounts.Token, error) {
+	for _, token := range f.tokens[tenants.FromContext(ctx)] {
+		if token.ID == id {
+			return token, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeServiceAccounts) PutToken(ctx context.Context, token *serviceaccounts.Token) error {
+	tenant := tenants.FromContext(ctx)
+	f.tokens[tenant] = append(f.tokens[tenant], token)
+	return nil
+}
+
+func (f *fakeServiceAccounts) RevokeToken(ctx context.Context, id string, revokedAt time.Time) error {
+	token, _ := f.GetToken(ctx, id)
+	if token != nil && token.RevokedAt == nil {
+		token.RevokedAt = &revokedAt
+	}
+	return nil
+}
+
+func (f *fakeServiceAccounts) RotateToken(ctx context.Context, id string, revokedAt time.Time, replacement *serviceaccounts.Token) error {
+	token, _ := f.GetToken(ctx, id)
+	if token == nil || token.RevokedAt != nil {
+		return graphstore.ErrTokenRevoked
+	}
+
+	token.RevokedAt = &revokedAt
+	return f.PutToken(ctx, replacement)
+}
+
+func TestServiceAccounts(t *testing.T) {
+	accounts := &fakeServiceAccounts{
+		accounts: make(map[string][]*serviceaccounts.Account),
+		tokens:   make(map[string][]*serviceaccounts.Token),
+	}
+
+	server := http.NewServeMux()
+	RegisterServiceAccountService(server, accounts)
+
+	call := func(tenant, method, path, body string, response interface{}) int {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request = request.WithContext(tenants.NewContext(request.Context(), tenant))
+
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code == http.StatusOK && response != nil {
+			require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		}
+		return recorder.Code
+	}
+
+	listed := &serviceaccounts.AccountsResponse{}
+	require.Equal(t, http.StatusOK, call("payments", http.MethodPut, "/v1alpha/serviceaccounts/accounts?name=indexer", `{"description":"indexes payments"}`, listed))
+	require.Len(t, listed.Accounts, 1)
+	require.Equal(t, "indexes payments", listed.Accounts[0].Description)
+
+	require.Equal(t, http.StatusBadRequest, call("payments", http.MethodPut, "/v1alpha/serviceaccounts/accounts?name=Indexer", "", nil))
+	require.Equal(t, http.StatusNotFound, call("payments", http.MethodPost, "/v1alpha/serviceaccounts/tokens?account=ci", `{"role":"viewer"}`, nil))
+	require.Equal(t, http.StatusBadRequest, call("payments", http.MethodPost, "/v1alpha/serviceaccounts/tokens?account=indexer", `{"role":"owner"}`, nil))
+	require.Equal(t, http.StatusBadRequest, call("payments", http.MethodPost, "/v1alpha/serviceaccounts/tokens?account=indexer", `{"role":"editor","ttl":"forever"}`, nil))
+
+	issued := &serviceaccounts.IssuedResponse{}
+	require.Equal(t, http.StatusOK, call("payments", http.MethodPost, "/v1alpha/serviceaccounts/tokens?account=indexer", `{"role":"editor","ttl":"720h"}`, issued))
+	require.Equal(t, 720*time.Hour, issued.Details.ExpiresAt.Sub(issued.Details.CreatedAt))
+
+	verify := func(tenant, token string) (int, *rbac.Credential) {
+		credential := &rbac.Credential{}
+		body, _ := json.Marshal(&serviceaccounts.VerifyRequest{Token: token})
+		return call(tenant, http.MethodPost, "/v1alpha/serviceaccounts/tokens/verify", string(body), credential), credential
+	}
+
+	code, credential := verify("payments", issued.Token)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, &rbac.Credential{Subject: "serviceaccount:indexer", Role: rbac.RoleEditor, TokenID: issued.Details.ID}, credential)
+
+	// tokens are only valid within the tenant they were issued in
+	code, _ = verify("search", issued.Token)
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	// rotating replaces the token with one of the same scope and lifetime
+	rotated := &serviceaccounts.IssuedResponse{}
+	require.Equal(t, http.StatusOK, call("payments", http.MethodPost, "/v1alpha/serviceaccounts/tokens/rotate?id="+issued.Details.ID, "", rotated))
+	require.NotEqual(t, issued.Token, rotated.Token)
+	require.Equal(t, 720*time.Hour, rotated.Details.ExpiresAt.Sub(rotated.Details.CreatedAt))
+
+	code, _ = verify("payments", issued.Token)
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = verify("payments", rotated.Token)
+	require.Equal(t, http.StatusOK, code)
+
+	// revoked tokens can't be rotated back into use
+	require.Equal(t, http.StatusConflict, call("payments", http.MethodPost, "/v1alpha/serviceaccounts/tokens/rotate?id="+issued.Details.ID, "", nil))
+	require.Equal(t, http.StatusNotFound, call("payments", http.MethodPost, "/v1alpha/serviceaccounts/tokens/rotate?id=missing", "", nil))
+
+	tokens := &serviceaccounts.TokensResponse{}
+	require.Equal(t, http.StatusOK, call("payments", http.MethodDelete, "/v1alpha/serviceaccounts/tokens?id="+rotated.Details.ID, "", tokens))
+	require.Len(t, tokens.Tokens, 2)
+	require.NotNil(t, tokens.Tokens[1].RevokedAt)
+
+	code, _ = verify("payments", rotated.Token)
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	require.Equal(t, http.StatusNotFound, call("payments", http.MethodDelete, "/v1alpha/serviceaccounts/tokens?id=missing", "", nil))
+
+	// deleting an account removes its tokens
+	require.Equal(t, http.StatusOK, call("payments", http.MethodDelete, "/v1alpha/serviceaccounts/accounts?name=indexer", "", listed))
+	require.Len(t, listed.Accounts, 0)
+	require.Equal(t, http.StatusOK, call("payments", http.MethodGet, "/v1alpha/serviceaccounts/tokens?account=indexer", "", tokens))
+	require.Len(t, tokens.Tokens, 0)
+}
@@ -0,0 +1,275 @@
+package v1alpha
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/tenants"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+)
+
+// RegisterSnapshotService registers the snapshotService routes with the http
+// server. Snapshots can only be written to and restored from the allowed
+// locations, so the routes aren't registered when there are none.
+func RegisterSnapshotService(server *http.ServeMux, snapshots graphstore.Snapshots, allowed []string) {
+	if len(allowed) == 0 {
+		return
+	}
+
+	svc := &snapshotService{
+		snapshots: snapshots,
+		storage:   &snapshotStorage{allowed: allowed, client: http.DefaultClient},
+	}
+
+	server.HandleFunc(GraphRoutePrefix+"snapshot", svc.Snapshot)
+	server.HandleFunc(GraphRoutePrefix+"restore", svc.Restore)
+}
+
+type snapshotService struct {
+	snapshots graphstore.Snapshots
+	storage   *snapshotStorage
+}
+
+// SnapshotResponse describes the snapshot that was written or restored.
+type SnapshotResponse struct {
+	Location string `json:"location"`
+	*graphstore.SnapshotSummary
+}
+
+// Snapshot handles POST /v1alpha/graph/snapshot. A consistent snapshot of the
+// graph is written to the location parameter, which must fall under one of
+// the allowed locations, within the directory of the tenant.
+func (s *snapshotService) Snapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	location, err := s.storage.location(r.URL.Query().Get("location"), tenants.FromContext(r.Context()))
+	if err != nil {
+		writeError(w, r, http.StatusForbidden, err)
+		return
+	}
+
+	var summary *graphstore.SnapshotSummary
+	err = s.storage.write(r.Context(), location, func(writer io.Writer) error {
+		summary, err = s.snapshots.WriteSnapshot(r.Context(), writer)
+		return err
+	})
+
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.snapshot] failed to write snapshot to %s: %s", location, err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to write snapshot"))
+		return
+	}
+
+	logging.FromContext(r.Context()).Infof("[service.snapshot] wrote %d items and %d labels to %s", summary.Items, summary.Labels, location)
+	writeJSON(w, r, http.StatusOK, &SnapshotResponse{Location: location, SnapshotSummary: summary})
+}
+
+// Restore handles POST /v1alpha/graph/restore. The graph is replaced with the
+// snapshot read from the location parameter, which must fall under one of the
+// allowed locations, within the directory of the tenant. Only snapshots taken
+// from the same tenant can be restored.
+func (s *snapshotService) Restore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	location, err := s.storage.location(r.URL.Query().Get("location"), tenants.FromContext(r.Context()))
+	if err != nil {
+		writeError(w, r, http.StatusForbidden, err)
+		return
+	}
+
+	reader, err := s.storage.open(r.Context(), location)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.snapshot] failed to read snapshot from %s: %s", location, err.Error())
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("failed to read snapshot"))
+		return
+	}
+	defer reader.Close()
+
+	summary, err := s.snapshots.RestoreSnapshot(r.Context(), reader)
+	if err == graphstore.ErrSnapshotTenant {
+		writeError(w, r, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.snapshot] failed to restore snapshot from %s: %s", location, err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to restore snapshot"))
+		return
+	}
+
+	logging.FromContext(r.Context()).Infof("[service.snapshot] restored %d items and %d labels from %s, deleting %d items",
+		summary.Items, summary.Labels, location, summary.Deleted)
+	writeJSON(w, r, http.StatusOK, &SnapshotResponse{Location: location, SnapshotSummary: summary})
+}
+
+// snapshotStorage reads and writes snapshots. Local paths and file urls are
+// read from and written to disk. Http and https urls are read with a GET and
+// written with a PUT, which covers the presigned urls of S3 and the signed
+// urls of GCS without depending on their SDKs.
+type snapshotStorage struct {
+	allowed []string
+	client  *http.Client
+}
+
+// location normalizes the requested location and ensures it falls under one
+// of the allowed locations. Tenants other than the default are limited to the
+// directory named after them within each allowed location, so they can't read
+// or replace the snapshots of another tenant.
+func (s *snapshotStorage) location(requested, tenant string) (string, error) {
+	if requested == "" {
+		return "", fmt.Errorf("location is required")
+	} else if tenant == "." || tenant == ".." {
+		return "", fmt.Errorf("tenant %s can't take snapshots", tenant)
+	}
+
+	if strings.HasPrefix(requested, "file://") {
+		requested = strings.TrimPrefix(requested, "file://")
+	}
+
+	var requestedURL *url.URL
+	if isURL(requested) {
+		u, err := url.Parse(requested)
+		if err != nil || u.Host == "" || strings.Contains(u.Path, "..") {
+			return "", fmt.Errorf("location must be an absolute path or url")
+		}
+		requestedURL = u
+	} else {
+		requested = filepath.Clean(requested)
+		if !filepath.IsAbs(requested) {
+			return "", fmt.Errorf("location must be an absolute path or url")
+		}
+	}
+
+	for _, allowed := range s.allowed {
+		allowed = strings.TrimPrefix(allowed, "file://")
+
+		switch {
+		case requestedURL != nil && isURL(allowed):
+			allowedURL, err := url.Parse(allowed)
+			if err != nil {
+				continue
+			}
+
+			dir := strings.TrimSuffix(allowedURL.Path, "/")
+			if tenant != "" {
+				dir += "/" + url.PathEscape(tenant)
+			}
+
+			// urls only allow the paths within them on the same host
+			if requestedURL.Scheme == allowedURL.Scheme && requestedURL.Host == allowedURL.Host &&
+				strings.HasPrefix(requestedURL.Path, dir+"/") {
+				return requested, nil
+			}
+
+		case requestedURL == nil && !isURL(allowed):
+			dir := filepath.Clean(allowed)
+			if tenant != "" {
+				dir = filepath.Join(dir, url.PathEscape(tenant))
+			}
+
+			// directories only allow the paths within them
+			if strings.HasPrefix(requested, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+				return requested, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("location %s is not allowed", requested)
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// write stages the snapshot in a temporary file before moving or uploading
+// it, so a failed snapshot never replaces a good one.
+func (s *snapshotStorage) write(ctx context.Context, location string, fn func(io.Writer) error) error {
+	dir := ""
+	if !isURL(location) {
+		dir = filepath.Dir(location)
+	}
+
+	staged, err := ioutil.TempFile(dir, ".snapshot-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(staged.Name())
+	defer staged.Close()
+
+	if err := fn(staged); err != nil {
+		return err
+	}
+
+	if err := staged.Sync(); err != nil {
+		return err
+	}
+
+	if !isURL(location) {
+		if err := staged.Close(); err != nil {
+			return err
+		}
+		return os.Rename(staged.Name(), location)
+	}
+
+	info, err := staged.Stat()
+	if err != nil {
+		return err
+	}
+
+	if _, err := staged.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, location, staged)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/gzip")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("upload failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *snapshotStorage) open(ctx context.Context, location string) (io.ReadCloser, error) {
+	if !isURL(location) {
+		return os.Open(location)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,118 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/tenants"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSnapshots writes a fixed snapshot and records the last one restored.
+type fakeSnapshots struct {
+	restored string
+}
+
+func (f *fakeSnapshots) WriteSnapshot(ctx context.Context, w io.Writer) (*graphstore.SnapshotSummary, error) {
+	_, err := io.WriteString(w, "snapshot")
+	return &graphstore.SnapshotSummary{Items: 1}, err
+}
+
+func (f *fakeSnapshots) RestoreSnapshot(ctx context.Context, r io.Reader) (*graphstore.SnapshotSummary, error) {
+	data, err := ioutil.ReadAll(r)
+	f.restored = string(data)
+	return &graphstore.SnapshotSummary{Items: 1}, err
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshots")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	uploaded := ""
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			require.Equal(t, int64(len("snapshot")), r.ContentLength)
+			data, _ := ioutil.ReadAll(r.Body)
+			uploaded = string(data)
+			return
+		}
+		_, _ = io.WriteString(w, uploaded)
+	}))
+	defer bucket.Close()
+
+	snapshots := &fakeSnapshots{}
+	server := http.NewServeMux()
+	RegisterSnapshotService(server, snapshots, []string{dir, bucket.URL + "/backups/"})
+
+	postAs := func(tenant, route, location string) int {
+		request := httptest.NewRequest(http.MethodPost, "/v1alpha/graph/"+route+"?location="+url.QueryEscape(location), nil)
+		request = request.WithContext(tenants.NewContext(request.Context(), tenant))
+
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code == http.StatusOK {
+			response := &SnapshotResponse{}
+			require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+			require.Equal(t, 1, response.Items)
+		}
+		return recorder.Code
+	}
+
+	post := func(route, location string) int {
+		return postAs("", route, location)
+	}
+
+	path := filepath.Join(dir, "graph.jsonl.gz")
+	require.Equal(t, http.StatusOK, post("snapshot", "file://"+path))
+
+	data, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	require.Equal(t, "snapshot", string(data))
+
+	require.Equal(t, http.StatusOK, post("restore", path))
+	require.Equal(t, "snapshot", snapshots.restored)
+
+	require.Equal(t, http.StatusOK, post("snapshot", bucket.URL+"/backups/graph.jsonl.gz"))
+	require.Equal(t, "snapshot", uploaded)
+
+	snapshots.restored = ""
+	require.Equal(t, http.StatusOK, post("restore", bucket.URL+"/backups/graph.jsonl.gz"))
+	require.Equal(t, "snapshot", snapshots.restored)
+
+	// only the allowed locations can be used
+	require.Equal(t, http.StatusForbidden, post("snapshot", filepath.Join(dir, "..", "graph.jsonl.gz")))
+	require.Equal(t, http.StatusForbidden, post("snapshot", dir+"-other/graph.jsonl.gz"))
+	require.Equal(t, http.StatusForbidden, post("snapshot", bucket.URL+"/other/graph.jsonl.gz"))
+	require.Equal(t, http.StatusForbidden, post("restore", "graph.jsonl.gz"))
+
+	// urls must share the scheme and host, and fall within the path
+	require.Equal(t, http.StatusForbidden, post("snapshot", bucket.URL+".attacker.net/backups/graph.jsonl.gz"))
+	require.Equal(t, http.StatusForbidden, post("snapshot", bucket.URL+"@attacker.net/backups/graph.jsonl.gz"))
+	require.Equal(t, http.StatusForbidden, post("snapshot", bucket.URL+"/backups-other/graph.jsonl.gz"))
+	require.Equal(t, http.StatusForbidden, post("snapshot", strings.Replace(bucket.URL, "http://", "https://", 1)+"/backups/graph.jsonl.gz"))
+
+	// tenants are limited to their own directory
+	require.Equal(t, http.StatusForbidden, postAs("payments", "restore", path))
+	require.Equal(t, http.StatusForbidden, postAs("payments", "snapshot", filepath.Join(dir, "search", "graph.jsonl.gz")))
+	require.Equal(t, http.StatusForbidden, postAs("payments", "snapshot", bucket.URL+"/backups/graph.jsonl.gz"))
+	require.Nil(t, os.Mkdir(filepath.Join(dir, "payments"), 0755))
+	require.Equal(t, http.StatusOK, postAs("payments", "snapshot", filepath.Join(dir, "payments", "graph.jsonl.gz")))
+	require.Equal(t, http.StatusOK, postAs("payments", "snapshot", bucket.URL+"/backups/payments/graph.jsonl.gz"))
+
+	// without allowed locations, the routes aren't registered
+	server = http.NewServeMux()
+	RegisterSnapshotService(server, snapshots, nil)
+	require.Equal(t, http.StatusNotFound, post("snapshot", path))
+}
@@ -3,38 +3,74 @@ package v1alpha
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
 
 	"github.com/depscloud/api"
 	"github.com/depscloud/api/v1alpha/schema"
 	"github.com/depscloud/api/v1alpha/store"
 	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/delta"
+	"github.com/depscloud/depscloud/internal/features"
+	"github.com/depscloud/depscloud/internal/idempotency"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/policies"
+	"github.com/depscloud/depscloud/internal/scopes"
+	"github.com/depscloud/depscloud/internal/tenants"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
 	"github.com/depscloud/depscloud/tracker/internal/types"
 
-	"github.com/sirupsen/logrus"
-
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-// RegisterSourceService registers the sourceService implementation with the server
-func RegisterSourceService(server *grpc.Server, gs store.GraphStoreClient) {
-	tracker.RegisterSourceServiceServer(server, &sourceService{gs: gs})
+// RegisterSourceService registers the sourceService implementation with the
+// server. When replacements are available, the edges of a source are replaced
+// atomically rather than deleted and put in separate requests. When policies
+// are enabled, the proposed edges of a source are checked against the track
+// policy before they're stored. Subscriptions are notified of the depends
+// edges that are added and removed, and webhooks are sent for each source
+// that's tracked.
+func RegisterSourceService(server *grpc.Server, gs store.GraphStoreClient, replacements graphstore.Replacements, paging *Paging, aliases *Aliases, policies *policies.Config, notifier *Notifier, webhooks *Webhooks) {
+	tracker.RegisterSourceServiceServer(server, &sourceService{
+		gs:           gs,
+		replacements: replacements,
+		paging:       paging,
+		aliases:      aliases,
+		policies:     policies,
+		notifier:     notifier,
+		webhooks:     webhooks,
+	})
 }
 
 type sourceService struct {
-	gs store.GraphStoreClient
+	gs           store.GraphStoreClient
+	replacements graphstore.Replacements
+	paging       *Paging
+	aliases      *Aliases
+	policies     *policies.Config
+	notifier     *Notifier
+	webhooks     *Webhooks
 }
 
 var _ tracker.SourceServiceServer = &sourceService{}
 
 func (s *sourceService) List(ctx context.Context, req *tracker.ListRequest) (*tracker.ListSourceResponse, error) {
-	resp, err := s.gs.List(ctx, &store.ListRequest{
-		Page:  req.GetPage(),
-		Count: req.GetCount(),
+	page, count, err := s.paging.listPage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.gs.List(forwardContext(ctx), &store.ListRequest{
+		Page:  page,
+		Count: count,
 		Type:  types.SourceType,
 	})
 
 	if err != nil {
-		logrus.Errorf("[service.source] %s", err.Error())
+		logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 		return nil, err
 	}
 
@@ -44,56 +80,281 @@ func (s *sourceService) List(ctx context.Context, req *tracker.ListRequest) (*tr
 		sources = append(sources, source.(*schema.Source))
 	}
 
+	if err := setNextListPage(ctx, page, count, len(resp.GetItems())); err != nil {
+		return nil, err
+	}
+
 	return &tracker.ListSourceResponse{
-		Page:    req.GetPage(),
-		Count:   req.GetCount(),
+		Page:    page,
+		Count:   count,
 		Sources: sources,
 	}, nil
 }
 
 func (s *sourceService) Track(ctx context.Context, req *tracker.SourceRequest) (*tracker.TrackResponse, error) {
+	idempotencyKey, err := idempotency.FromIncomingContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	update, err := delta.FromIncomingContext(ctx)
+	if err != nil {
+		return nil, err
+	} else if update.Delta && !features.Enabled(ctx, features.DeltaUpdates) {
+		return nil, status.Errorf(codes.FailedPrecondition, "delta updates are disabled")
+	}
+
 	currentSet, err := s.getCurrent(ctx, req.GetSource())
 	if err != nil {
-		logrus.Errorf("[service.source] %s", err.Error())
+		logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 		return nil, api.ErrModuleNotFound
 	}
 
 	proposedSet, err := s.getProposed(ctx, req)
 	if err != nil {
-		logrus.Errorf("[service.source] %s", err.Error())
+		logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 		return nil, api.ErrModuleNotFound
 	}
 
+	if s.policies.Enabled() {
+		input, err := trackInput(ctx, req.GetSource(), proposedSet)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
+			return nil, err
+		}
+
+		if err := s.policies.Check(ctx, s.policies.TrackPolicy, input); err != nil {
+			logging.FromContext(ctx).Warnf("[service.source] %s", err.Error())
+			return nil, err
+		}
+	}
+
+	// delta updates only replace the modules they name
+	var replaced map[string]bool
+	if update.Delta {
+		replaced = make(map[string]bool)
+		for _, item := range proposedSet {
+			replaced[string(managedKey(item))] = true
+		}
+		for _, module := range update.Removed {
+			replaced[string(keyForModule(s.aliases.module(module)))] = true
+		}
+	}
+
+	nextSet := make(map[string]*store.GraphItem, len(currentSet)+len(proposedSet))
 	toDelete := make([]*store.GraphItem, 0)
 	for key, item := range currentSet {
-		if _, ok := proposedSet[key]; !ok {
-			// don't delete modules from the graph when an edge to it is removed.
-			// we'll put a cleanup in later
-			if item.GetGraphItemType() != types.ModuleType {
-				toDelete = append(toDelete, item)
-			}
+		_, proposed := proposedSet[key]
+
+		// don't delete modules from the graph when an edge to it is removed.
+		// we'll put a cleanup in later
+		if proposed || item.GetGraphItemType() == types.ModuleType ||
+			(replaced != nil && !replaced[string(managedKey(item))]) {
+			nextSet[key] = item
+			continue
 		}
+
+		toDelete = append(toDelete, item)
 	}
 
 	toPut := make([]*store.GraphItem, 0, len(proposedSet))
-	for _, item := range proposedSet {
+	for key, item := range proposedSet {
+		nextSet[key] = item
 		toPut = append(toPut, item)
 	}
 
-	logrus.Infof("[service.source] currentSet=%d proposedSet=%d toDelete=%d toPut=%d",
-		len(currentSet), len(proposedSet), len(toDelete), len(toPut))
+	logging.FromContext(ctx).Infof("[service.source] delta=%t currentSet=%d proposedSet=%d toDelete=%d toPut=%d",
+		update.Delta, len(currentSet), len(proposedSet), len(toDelete), len(toPut))
+
+	sourceKey := keyForSource(req.GetSource())
+	state := &graphstore.SourceState{
+		Key:      sourceKey,
+		IfState:  update.IfState,
+		Observed: sourceState(sourceKey, currentSet),
+		State:    sourceState(sourceKey, nextSet),
+	}
+
+	if err := s.write(ctx, idempotencyKey, state, toPut, toDelete); err != nil {
+		return nil, err
+	}
+
+	if err := delta.SetState(ctx, state.State); err != nil {
+		logging.FromContext(ctx).Warnf("[service.source] failed to return source state: %s", err.Error())
+	}
+
+	tenant := tenants.FromContext(ctx)
+	if err := s.webhooks.emitTracked(ctx, tenant, req.GetSource(), currentSet, proposedSet, toDelete); err != nil {
+		logging.FromContext(ctx).Errorf("[service.source] failed to emit webhooks: %s", err.Error())
+	}
+
+	if s.notifier != nil {
+		events, err := trackEvents(tenant, req.GetSource(), currentSet, proposedSet, toDelete)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[service.source] failed to determine events: %s", err.Error())
+		} else {
+			// notifications are delivered in the background so slow channels
+			// don't hold up indexing
+			go s.notifier.Notify(tenants.NewContext(context.Background(), tenant), events)
+		}
+	}
+
+	return &tracker.TrackResponse{Tracking: true}, nil
+}
+
+// TrackDependency is an edge between a module of the source and one of its
+// dependencies.
+type TrackDependency struct {
+	Module     *schema.Module  `json:"module"`
+	Dependency *schema.Module  `json:"dependency"`
+	Depends    *schema.Depends `json:"depends"`
+}
+
+// TrackInput is the input of the track policy. It describes the modules a
+// source proposes to manage and the edges to their dependencies.
+type TrackInput struct {
+	Tenant       string             `json:"tenant"`
+	Source       *schema.Source     `json:"source"`
+	Modules      []*schema.Module   `json:"modules"`
+	Dependencies []*TrackDependency `json:"dependencies"`
+}
+
+func trackInput(ctx context.Context, source *schema.Source, proposedSet map[string]*store.GraphItem) (*TrackInput, error) {
+	sourceKey := keyForSource(source)
+
+	modules := make(map[string]*schema.Module)
+	for _, item := range proposedSet {
+		if item.GetGraphItemType() != types.ModuleType {
+			continue
+		}
+
+		module, err := Decode(item)
+		if err != nil {
+			return nil, err
+		}
+		modules[string(item.GetK1())] = module.(*schema.Module)
+	}
+
+	input := &TrackInput{
+		Tenant:       tenants.FromContext(ctx),
+		Source:       source,
+		Modules:      make([]*schema.Module, 0),
+		Dependencies: make([]*TrackDependency, 0),
+	}
+
+	for _, item := range proposedSet {
+		switch item.GetGraphItemType() {
+		case types.ManagesType:
+			// manages edges of discovered sources belong to those sources
+			if bytes.Equal(item.GetK1(), sourceKey) {
+				input.Modules = append(input.Modules, modules[string(item.GetK2())])
+			}
+		case types.DependsType:
+			depends, err := Decode(item)
+			if err != nil {
+				return nil, err
+			}
+
+			input.Dependencies = append(input.Dependencies, &TrackDependency{
+				Module:     modules[string(item.GetK1())],
+				Dependency: modules[string(item.GetK2())],
+				Depends:    depends.(*schema.Depends),
+			})
+		}
+	}
+
+	// keep the input stable for policies and their decision logs
+	sort.Slice(input.Modules, func(i, j int) bool {
+		return moduleName(input.Modules[i]) < moduleName(input.Modules[j])
+	})
+	sort.Slice(input.Dependencies, func(i, j int) bool {
+		a, b := input.Dependencies[i], input.Dependencies[j]
+		if moduleName(a.Module) != moduleName(b.Module) {
+			return moduleName(a.Module) < moduleName(b.Module)
+		}
+		return moduleName(a.Dependency) < moduleName(b.Dependency)
+	})
+
+	return input, nil
+}
+
+// write replaces the items of a source once it's in the required state. When
+// replacements are unavailable, the state is checked against the items read
+// before the write, and the items are deleted and put in separate requests.
+func (s *sourceService) write(ctx context.Context, idempotencyKey string, state *graphstore.SourceState, toPut, toDelete []*store.GraphItem) error {
+	if s.replacements != nil {
+		applied, err := s.replacements.Replace(ctx, idempotencyKey, state, toPut, toDelete)
+		if err == graphstore.ErrSourceChanged {
+			return status.Errorf(codes.FailedPrecondition, "source changed since state %s", state.IfState)
+		} else if err != nil {
+			logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
+			return api.ErrPartialInsertion
+		}
+
+		if !applied {
+			logging.FromContext(ctx).Infof("[service.source] skipping %s, already applied", idempotencyKey)
+		}
+
+		return nil
+	}
+
+	if state.IfState != "" && state.IfState != state.Observed {
+		return status.Errorf(codes.FailedPrecondition, "source changed since state %s", state.IfState)
+	}
 
 	if _, err := s.gs.Delete(ctx, &store.DeleteRequest{Items: toDelete}); err != nil {
-		logrus.Errorf("[service.source] %s", err.Error())
-		return nil, api.ErrPartialDeletion
+		logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
+		return api.ErrPartialDeletion
 	}
 
 	if _, err := s.gs.Put(ctx, &store.PutRequest{Items: toPut}); err != nil {
-		logrus.Errorf("[service.source] %s", err.Error())
-		return nil, api.ErrPartialInsertion
+		logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
+		return api.ErrPartialInsertion
 	}
 
-	return &tracker.TrackResponse{Tracking: true}, nil
+	return nil
+}
+
+// managedKey returns the key of the module an edge of the source belongs to.
+// Nil is returned for nodes.
+func managedKey(item *store.GraphItem) []byte {
+	switch item.GetGraphItemType() {
+	case types.ManagesType:
+		return item.GetK2()
+	case types.DependsType:
+		return item.GetK1()
+	}
+	return nil
+}
+
+// sourceState summarizes the edges a source manages and produces. Any change
+// to them, including to the data of an edge, changes the state.
+func sourceState(sourceKey []byte, items map[string]*store.GraphItem) string {
+	keys := make([]string, 0, len(items))
+	for key, item := range items {
+		switch item.GetGraphItemType() {
+		case types.ManagesType:
+			// manages edges of discovered sources belong to those sources
+			if !bytes.Equal(item.GetK1(), sourceKey) {
+				continue
+			}
+		case types.DependsType:
+		default:
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write(sepData)
+		hash.Write(items[key].GetGraphItemData())
+		hash.Write(sepData)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
 }
 
 func (s *sourceService) getCurrent(ctx context.Context, source *schema.Source) (map[string]*store.GraphItem, error) {
@@ -101,7 +362,7 @@ func (s *sourceService) getCurrent(ctx context.Context, source *schema.Source) (
 
 	item, err := Encode(source)
 	if err != nil {
-		logrus.Errorf("[service.source] %s", err.Error())
+		logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 		return nil, err
 	}
 
@@ -114,33 +375,41 @@ func (s *sourceService) getCurrent(ctx context.Context, source *schema.Source) (
 	})
 
 	if err != nil {
-		logrus.Errorf("[service.source] %s", err.Error())
+		logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 		return nil, err
 	}
 
 	sourceKey := item.GetK1()
+	managedKeys := make([][]byte, 0, len(manages.GetPairs()))
 	for _, managed := range manages.GetPairs() {
 		idx[readableKey(managed.GetNode())] = managed.GetNode()
 		idx[readableKey(managed.GetEdge())] = managed.GetEdge()
+		managedKeys = append(managedKeys, managed.GetNode().GetK1())
+	}
 
-		depends, err := s.gs.FindUpstream(ctx, &store.FindRequest{
-			Keys:      [][]byte{managed.GetNode().GetK1()},
-			EdgeTypes: []string{types.DependsType},
-			NodeTypes: []string{types.ModuleType},
-		})
+	if len(managedKeys) == 0 {
+		return idx, nil
+	}
 
-		if err != nil {
-			logrus.Errorf("[service.source] %s", err.Error())
-			return nil, err
-		}
+	// look up the dependencies of every managed module at once rather than
+	// making a round trip per management file
+	depends, err := s.gs.FindUpstream(ctx, &store.FindRequest{
+		Keys:      managedKeys,
+		EdgeTypes: []string{types.DependsType},
+		NodeTypes: []string{types.ModuleType},
+	})
 
-		for _, depended := range depends.GetPairs() {
-			// Return only the depends edges that are produced by modules of this source URL
-			dependsEdgeK3 := depended.GetEdge().GetK3()
-			if len(dependsEdgeK3) == 0 || bytes.Equal(dependsEdgeK3, sourceKey) {
-				idx[readableKey(depended.GetNode())] = depended.GetNode()
-				idx[readableKey(depended.GetEdge())] = depended.GetEdge()
-			}
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
+		return nil, err
+	}
+
+	for _, depended := range depends.GetPairs() {
+		// Return only the depends edges that are produced by modules of this source URL
+		dependsEdgeK3 := depended.GetEdge().GetK3()
+		if len(dependsEdgeK3) == 0 || bytes.Equal(dependsEdgeK3, sourceKey) {
+			idx[readableKey(depended.GetNode())] = depended.GetNode()
+			idx[readableKey(depended.GetEdge())] = depended.GetEdge()
 		}
 	}
 
@@ -152,22 +421,22 @@ func (s *sourceService) getProposed(ctx context.Context, request *tracker.Source
 
 	source, err := Encode(request.GetSource())
 	if err != nil {
-		logrus.Errorf("[service.source] %s", err.Error())
+		logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 		return nil, err
 	}
 
 	idx[readableKey(source)] = source
 
 	for _, managementFile := range request.GetManagementFiles() {
-		managedModule, err := Encode(&schema.Module{
+		managedModule, err := Encode(s.aliases.module(&schema.Module{
 			Language:     managementFile.GetLanguage(),
 			Organization: managementFile.GetOrganization(),
 			Module:       managementFile.GetModule(),
 			Name:         managementFile.GetName(),
-		})
+		}))
 
 		if err != nil {
-			logrus.Errorf("[service.source] %s", err.Error())
+			logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 			return nil, err
 		}
 
@@ -177,7 +446,7 @@ func (s *sourceService) getProposed(ctx context.Context, request *tracker.Source
 			Version:  managementFile.GetVersion(),
 		})
 		if err != nil {
-			logrus.Errorf("[service.source] %s", err.Error())
+			logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 			return nil, err
 		}
 
@@ -193,7 +462,7 @@ func (s *sourceService) getProposed(ctx context.Context, request *tracker.Source
 				Kind: "repository",
 			})
 			if err != nil {
-				logrus.Errorf("[service.source] %s", err.Error())
+				logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 				return nil, err
 			}
 
@@ -203,7 +472,7 @@ func (s *sourceService) getProposed(ctx context.Context, request *tracker.Source
 				Version:  managementFile.GetVersion(),
 			})
 			if err != nil {
-				logrus.Errorf("[service.source] %s", err.Error())
+				logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 				return nil, err
 			}
 
@@ -215,25 +484,25 @@ func (s *sourceService) getProposed(ctx context.Context, request *tracker.Source
 		}
 
 		for _, dependency := range managementFile.GetDependencies() {
-			dependedModule, err := Encode(&schema.Module{
+			dependedModule, err := Encode(s.aliases.module(&schema.Module{
 				Language:     managementFile.GetLanguage(),
 				Organization: dependency.GetOrganization(),
 				Module:       dependency.GetModule(),
 				Name:         dependency.GetName(),
-			})
+			}))
 			if err != nil {
-				logrus.Errorf("[service.source] %s", err.Error())
+				logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 				return nil, err
 			}
 
 			depends, err := Encode(&schema.Depends{
 				Language:          managementFile.GetLanguage(),
 				VersionConstraint: dependency.GetVersionConstraint(),
-				Scopes:            dependency.GetScopes(),
+				Scopes:            scopes.Normalize(dependency.GetScopes()),
 				Ref:               request.GetSource().Ref,
 			})
 			if err != nil {
-				logrus.Errorf("[service.source] %s", err.Error())
+				logging.FromContext(ctx).Errorf("[service.source] %s", err.Error())
 				return nil, err
 			}
 
@@ -0,0 +1,291 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/deps"
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/api/v1alpha/tracker"
+	"github.com/depscloud/depscloud/internal/delta"
+	"github.com/depscloud/depscloud/internal/features"
+	"github.com/depscloud/depscloud/internal/idempotency"
+	"github.com/depscloud/depscloud/internal/policies"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// countingGraphStore counts the upstream lookups made against the graph store.
+type countingGraphStore struct {
+	*fakeGraphStore
+
+	finds int
+}
+
+func (gs *countingGraphStore) FindUpstream(ctx context.Context, req *store.FindRequest, opts ...grpc.CallOption) (*store.FindResponse, error) {
+	gs.finds++
+	return gs.fakeGraphStore.FindUpstream(ctx, req, opts...)
+}
+
+func managementFile(module string, dependencies ...string) *deps.DependencyManagementFile {
+	file := &deps.DependencyManagementFile{
+		Language:     proto.String("go"),
+		System:       proto.String("vgo"),
+		Organization: proto.String("depscloud"),
+		Module:       proto.String(module),
+	}
+
+	for _, dependency := range dependencies {
+		file.Dependencies = append(file.Dependencies, &deps.Dependency{
+			Organization:      proto.String("depscloud"),
+			Module:            proto.String(dependency),
+			VersionConstraint: proto.String("v1.0.0"),
+		})
+	}
+
+	return file
+}
+
+func upstreamModules(t *testing.T, gs *fakeGraphStore, edgeType string, key []byte) []string {
+	resp, err := gs.FindUpstream(context.Background(), &store.FindRequest{
+		Keys:      [][]byte{key},
+		EdgeTypes: []string{edgeType},
+	})
+	require.Nil(t, err)
+
+	modules := make([]string, 0, len(resp.GetPairs()))
+	for _, pair := range resp.GetPairs() {
+		decoded, err := Decode(pair.GetNode())
+		require.Nil(t, err)
+		modules = append(modules, decoded.(*schema.Module).GetModule())
+	}
+	return modules
+}
+
+func TestTrack(t *testing.T) {
+	ctx := context.Background()
+
+	gs := &countingGraphStore{fakeGraphStore: newFakeGraphStore(t, nil)}
+	svc := &sourceService{gs: gs}
+
+	source := &schema.Source{Url: "https://github.com/depscloud/depscloud.git", Kind: "repository"}
+
+	_, err := svc.Track(ctx, &tracker.SourceRequest{
+		Source: source,
+		ManagementFiles: []*deps.DependencyManagementFile{
+			managementFile("a", "c"),
+			managementFile("b", "d"),
+		},
+	})
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"c"}, upstreamModules(t, gs.fakeGraphStore, types.DependsType, moduleKey("a")))
+	require.ElementsMatch(t, []string{"d"}, upstreamModules(t, gs.fakeGraphStore, types.DependsType, moduleKey("b")))
+
+	gs.finds = 0
+	_, err = svc.Track(ctx, &tracker.SourceRequest{
+		Source: source,
+		ManagementFiles: []*deps.DependencyManagementFile{
+			managementFile("a", "e"),
+			managementFile("b"),
+		},
+	})
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"e"}, upstreamModules(t, gs.fakeGraphStore, types.DependsType, moduleKey("a")))
+	require.Empty(t, upstreamModules(t, gs.fakeGraphStore, types.DependsType, moduleKey("b")))
+
+	// the managed files are read together rather than one at a time
+	require.Equal(t, 2, gs.finds)
+}
+
+func TestTrack_delta(t *testing.T) {
+	gs := newFakeGraphStore(t, nil)
+	svc := &sourceService{gs: gs}
+
+	source := &schema.Source{Url: "https://github.com/depscloud/depscloud.git", Kind: "repository"}
+	sourceKey := keyForSource(source)
+
+	track := func(update *delta.Update, files ...*deps.DependencyManagementFile) (string, error) {
+		stream := &headerStream{}
+
+		md, _ := metadata.FromOutgoingContext(delta.AppendToOutgoingContext(context.Background(), update))
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+		_, err := svc.Track(ctx, &tracker.SourceRequest{Source: source, ManagementFiles: files})
+
+		state := stream.header.Get(delta.StateMetadataKey)
+		if len(state) == 0 {
+			return "", err
+		}
+		return state[0], err
+	}
+
+	state, err := track(nil, managementFile("a", "c"), managementFile("b", "d"))
+	require.Nil(t, err)
+	require.NotEmpty(t, state)
+
+	// only the modules of the request are replaced
+	next, err := track(&delta.Update{Delta: true, IfState: state}, managementFile("a", "e"))
+	require.Nil(t, err)
+	require.NotEqual(t, state, next)
+	require.ElementsMatch(t, []string{"e"}, upstreamModules(t, gs, types.DependsType, moduleKey("a")))
+	require.ElementsMatch(t, []string{"d"}, upstreamModules(t, gs, types.DependsType, moduleKey("b")))
+
+	// updates made against an old state are rejected
+	_, err = track(&delta.Update{Delta: true, IfState: state}, managementFile("a", "c"))
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.ElementsMatch(t, []string{"e"}, upstreamModules(t, gs, types.DependsType, moduleKey("a")))
+
+	state, err = track(&delta.Update{
+		Delta:   true,
+		Removed: []*schema.Module{{Language: "go", Organization: "depscloud", Module: "b"}},
+		IfState: next,
+	})
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"a"}, upstreamModules(t, gs, types.ManagesType, sourceKey))
+	require.Empty(t, upstreamModules(t, gs, types.DependsType, moduleKey("b")))
+
+	// the returned state matches the stored state
+	_, err = track(&delta.Update{Delta: true, IfState: state})
+	require.Nil(t, err)
+
+	// delta updates are rejected when the feature is turned off
+	featureSet, err := features.New(nil)
+	require.Nil(t, err)
+	require.Nil(t, featureSet.Set(features.DeltaUpdates, "off"))
+
+	defer features.SetDefault(features.Default())
+	features.SetDefault(featureSet)
+
+	_, err = track(&delta.Update{Delta: true, IfState: state})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+// stateReplacements records the state of a single source, like the graph
+// store does within its write transaction.
+type stateReplacements struct {
+	state   string
+	applied map[string]bool
+}
+
+func (r *stateReplacements) Replace(ctx context.Context, idempotencyKey string, state *graphstore.SourceState, toPut, toDelete []*store.GraphItem) (bool, error) {
+	if r.applied[idempotencyKey] {
+		state.State = r.state
+		return false, nil
+	}
+
+	if state.IfState != "" && state.IfState != r.state {
+		return false, graphstore.ErrSourceChanged
+	}
+
+	r.applied[idempotencyKey] = true
+	r.state = state.State
+	return true, nil
+}
+
+func TestTrack_deltaReplacements(t *testing.T) {
+	replacements := &stateReplacements{state: "recorded", applied: make(map[string]bool)}
+	svc := &sourceService{gs: newFakeGraphStore(t, nil), replacements: replacements}
+
+	source := &schema.Source{Url: "https://github.com/depscloud/depscloud.git", Kind: "repository"}
+
+	track := func(key string, update *delta.Update) (string, error) {
+		stream := &headerStream{}
+
+		md, _ := metadata.FromOutgoingContext(delta.AppendToOutgoingContext(context.Background(), update))
+		md.Set(idempotency.MetadataKey, key)
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+		_, err := svc.Track(ctx, &tracker.SourceRequest{Source: source, ManagementFiles: []*deps.DependencyManagementFile{managementFile("a", "c")}})
+
+		state := stream.header.Get(delta.StateMetadataKey)
+		if len(state) == 0 {
+			return "", err
+		}
+		return state[0], err
+	}
+
+	// the state is checked by the write rather than against an earlier read
+	_, err := track("run-1", &delta.Update{Delta: true, IfState: "observed"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	state, err := track("run-2", &delta.Update{Delta: true, IfState: "recorded"})
+	require.Nil(t, err)
+	require.Equal(t, replacements.state, state)
+
+	_, err = track("run-3", &delta.Update{Delta: true, IfState: "recorded"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// retries of an applied write return the recorded state
+	replacements.state = "changed"
+	state, err = track("run-2", &delta.Update{Delta: true, IfState: "recorded"})
+	require.Nil(t, err)
+	require.Equal(t, "changed", state)
+}
+
+func TestTrack_policies(t *testing.T) {
+	ctx := context.Background()
+
+	inputs := make([]*TrackInput, 0)
+	opa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/data/depscloud/track/deny", r.URL.Path)
+
+		request := struct {
+			Input *TrackInput `json:"input"`
+		}{}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		inputs = append(inputs, request.Input)
+
+		for _, dependency := range request.Input.Dependencies {
+			if dependency.Dependency.GetModule() == "banned" {
+				_, _ = w.Write([]byte(`{"result": ["banned is not allowed"]}`))
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{"result": []}`))
+	}))
+	defer opa.Close()
+
+	gs := newFakeGraphStore(t, nil)
+	svc := &sourceService{gs: gs, policies: &policies.Config{URL: opa.URL, TrackPolicy: policies.DefaultTrackPolicy}}
+
+	source := &schema.Source{Url: "https://github.com/depscloud/depscloud.git", Kind: "repository"}
+
+	_, err := svc.Track(ctx, &tracker.SourceRequest{
+		Source:          source,
+		ManagementFiles: []*deps.DependencyManagementFile{managementFile("a", "c", "b")},
+	})
+	require.Nil(t, err)
+
+	require.Len(t, inputs, 1)
+	require.Equal(t, source.GetUrl(), inputs[0].Source.GetUrl())
+	require.Len(t, inputs[0].Modules, 1)
+	require.Equal(t, "a", inputs[0].Modules[0].GetModule())
+	require.Len(t, inputs[0].Dependencies, 2)
+	require.Equal(t, "a", inputs[0].Dependencies[0].Module.GetModule())
+	require.Equal(t, "b", inputs[0].Dependencies[0].Dependency.GetModule())
+	require.Equal(t, "v1.0.0", inputs[0].Dependencies[0].Depends.GetVersionConstraint())
+
+	// denied sources are left as they were
+	_, err = svc.Track(ctx, &tracker.SourceRequest{
+		Source:          source,
+		ManagementFiles: []*deps.DependencyManagementFile{managementFile("a", "banned")},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), "banned is not allowed")
+	require.ElementsMatch(t, []string{"b", "c"}, upstreamModules(t, gs, types.DependsType, moduleKey("a")))
+}
@@ -0,0 +1,239 @@
+package v1alpha
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/constraints"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/registries"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// SuggestionRoutePrefix prefixes the HTTP routes suggesting changes to the
+// dependencies of a source.
+const SuggestionRoutePrefix = "/v1alpha/suggestions/"
+
+// constraintOperators are the operators a suggested constraint keeps, ordered
+// so longer operators are matched first.
+var constraintOperators = []string{"~>", ">=", "==", "^", "~", "="}
+
+// RegisterSuggestionService registers the suggestionService routes with the
+// http server. Suggestions are made using the registry metadata recorded by
+// the enrichment.
+func RegisterSuggestionService(server *http.ServeMux, gs store.GraphStoreClient, labels graphstore.Labels) {
+	svc := &suggestionService{gs: gs, labels: labels}
+
+	server.HandleFunc(SuggestionRoutePrefix+"upgrades", svc.Upgrades)
+}
+
+type suggestionService struct {
+	gs     store.GraphStoreClient
+	labels graphstore.Labels
+}
+
+// UpgradeSuggestion proposes moving a dependency of a module to the latest
+// published version of the module it depends on.
+type UpgradeSuggestion struct {
+	Dependent *schema.Module `json:"dependent"`
+	// System is the dependency management system of the dependent, such as
+	// npm or vgo.
+	System            string         `json:"system"`
+	Module            *schema.Module `json:"module"`
+	VersionConstraint string         `json:"version_constraint"`
+	Current           string         `json:"current"`
+	Latest            string         `json:"latest"`
+	Behind            string         `json:"behind"`
+	Distance          int            `json:"distance"`
+	// Satisfied is true when the constraint already allows the latest
+	// version, so only a lock file needs to be refreshed.
+	Satisfied bool `json:"satisfied"`
+	// Breaking is true when the latest version is expected to be
+	// incompatible with the current one.
+	Breaking bool `json:"breaking"`
+	// SuggestedConstraint rewrites the constraint for the latest version. It's
+	// empty when the constraint can't be rewritten automatically.
+	SuggestedConstraint string     `json:"suggested_constraint,omitempty"`
+	Lifecycle           *Lifecycle `json:"lifecycle,omitempty"`
+}
+
+// UpgradeSuggestionsResponse contains the upgrades suggested for a source.
+type UpgradeSuggestionsResponse struct {
+	Source      *schema.Source       `json:"source"`
+	Suggestions []*UpgradeSuggestion `json:"suggestions"`
+}
+
+// breaking returns true when moving from the current version to the latest
+// changes the major version, or the minor version of a version before 1.0.0.
+func breaking(current, behind string) bool {
+	return behind == "major" || (behind == "minor" && constraints.Compare(current, "1") < 0)
+}
+
+// suggestConstraint rewrites a constraint on a single version to allow the
+// latest version, keeping its operator. The go convention of prefixing
+// versions with a v is preserved.
+func suggestConstraint(constraint, latest string) string {
+	constraint = strings.TrimSpace(constraint)
+
+	operator := ""
+	for _, candidate := range constraintOperators {
+		if strings.HasPrefix(constraint, candidate) {
+			operator = candidate
+			break
+		}
+	}
+
+	current := strings.TrimSpace(strings.TrimPrefix(constraint, operator))
+	if strings.ContainsAny(current, " ,|<>") || !constraints.IsVersion(current) {
+		return ""
+	}
+
+	latest = strings.TrimPrefix(latest, "v")
+	if strings.HasPrefix(current, "v") {
+		latest = "v" + latest
+	}
+
+	// keep the spacing used between the operator and version
+	return strings.TrimSuffix(constraint, current) + latest
+}
+
+// Upgrades handles GET /v1alpha/suggestions/upgrades. It suggests upgrading
+// each dependency of the modules managed by the source identified by the url
+// parameter that trails the latest published version. Setting the compatible
+// parameter to true leaves out breaking upgrades.
+func (s *suggestionService) Upgrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("url is required"))
+		return
+	}
+	compatibleOnly := r.URL.Query().Get("compatible") == "true"
+
+	ctx := r.Context()
+	source := &schema.Source{Url: url}
+
+	managed, err := findPairs(ctx, s.gs.FindUpstream, [][]byte{keyForSource(source)}, types.ManagesType, types.ModuleType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.suggestion] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find managed modules"))
+		return
+	} else if len(managed) == 0 {
+		writeError(w, r, http.StatusNotFound, fmt.Errorf("no modules are known for source %s", url))
+		return
+	}
+
+	systems := make(map[string]string, len(managed))
+	managedKeys := make([][]byte, len(managed))
+	for i, pair := range managed {
+		edge, err := Decode(pair.GetEdge())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		systems[string(pair.GetNode().GetK1())] = edge.(*schema.Manages).GetSystem()
+		managedKeys[i] = pair.GetNode().GetK1()
+	}
+
+	dependencies, err := findPairs(ctx, s.gs.FindUpstream, managedKeys, types.DependsType, types.ModuleType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.suggestion] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find dependencies"))
+		return
+	}
+
+	// the dependents are read from the edges of the managed modules
+	dependents := make(map[string]*schema.Module, len(managed))
+	for _, pair := range managed {
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		dependents[string(pair.GetNode().GetK1())] = node.(*schema.Module)
+	}
+
+	keys := make([][]byte, len(dependencies))
+	for i, pair := range dependencies {
+		keys[i] = pair.GetNode().GetK1()
+	}
+
+	labels, err := s.labels.GetLabels(ctx, types.ModuleType, keys)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.suggestion] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to get registry metadata"))
+		return
+	}
+
+	suggestions := make([]*UpgradeSuggestion, 0)
+	for _, pair := range dependencies {
+		module, depends, err := decodePair(pair)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		moduleLabels := labels[string(pair.GetNode().GetK1())]
+		metadata := registries.FromLabels(moduleLabels)
+		if metadata == nil {
+			continue
+		}
+
+		constraint := depends.GetVersionConstraint()
+		current := resolveVersion(module.GetLanguage(), constraint)
+		behind, distance := constraints.Behind(current, metadata.Latest)
+		if current == "" || behind == "" {
+			continue
+		}
+
+		dependentKey := string(pair.GetEdge().GetK1())
+		system := systems[dependentKey]
+		if system == "" {
+			system = constraintSystems[module.GetLanguage()]
+		}
+
+		suggestion := &UpgradeSuggestion{
+			Dependent:           dependents[dependentKey],
+			System:              system,
+			Module:              module,
+			VersionConstraint:   constraint,
+			Current:             current,
+			Latest:              metadata.Latest,
+			Behind:              behind,
+			Distance:            distance,
+			Breaking:            breaking(current, behind),
+			SuggestedConstraint: suggestConstraint(constraint, metadata.Latest),
+			Lifecycle:           lifecycle(moduleLabels),
+		}
+
+		if parsed, err := constraints.Parse(system, constraint); err == nil {
+			suggestion.Satisfied = parsed.Satisfies(metadata.Latest)
+		}
+
+		if compatibleOnly && suggestion.Breaking {
+			continue
+		}
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := moduleName(suggestions[i].Dependent), moduleName(suggestions[j].Dependent)
+		if a != b {
+			return a < b
+		}
+		return moduleName(suggestions[i].Module) < moduleName(suggestions[j].Module)
+	})
+
+	writeJSON(w, r, http.StatusOK, &UpgradeSuggestionsResponse{Source: source, Suggestions: suggestions})
+}
@@ -0,0 +1,80 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/depscloud/internal/registries"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestions(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c", "d"},
+	})
+	gs.manage(t, "https://github.com/depscloud/a.git", "a")
+
+	labels := fakeLabels{}
+	labels[types.ModuleType+string(moduleKey("b"))] = map[string]string{registries.LabelLatest: "v1.2.0"}
+	labels[types.ModuleType+string(moduleKey("c"))] = map[string]string{registries.LabelLatest: "v2.0.0", LifecycleLabelStatus: LifecycleDeprecated}
+	labels[types.ModuleType+string(moduleKey("d"))] = map[string]string{registries.LabelLatest: "v1.0.0"}
+
+	server := http.NewServeMux()
+	RegisterSuggestionService(server, gs, labels)
+
+	upgrades := func(query string) (int, *UpgradeSuggestionsResponse) {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/suggestions/upgrades?"+query, nil))
+
+		response := &UpgradeSuggestionsResponse{}
+		if recorder.Code == http.StatusOK {
+			require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		}
+		return recorder.Code, response
+	}
+
+	code, response := upgrades("url=https://github.com/depscloud/a.git")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, response.Suggestions, 2)
+
+	b := response.Suggestions[0]
+	require.Equal(t, "a", b.Dependent.GetModule())
+	require.Equal(t, "b", b.Module.GetModule())
+	require.Equal(t, "vgo", b.System)
+	require.Equal(t, "v1.0.0", b.Current)
+	require.Equal(t, "v1.2.0", b.Latest)
+	require.Equal(t, "minor", b.Behind)
+	require.True(t, b.Satisfied)
+	require.False(t, b.Breaking)
+	require.Equal(t, "v1.2.0", b.SuggestedConstraint)
+
+	c := response.Suggestions[1]
+	require.Equal(t, "c", c.Module.GetModule())
+	require.True(t, c.Breaking)
+	require.Equal(t, LifecycleDeprecated, c.Lifecycle.Status)
+
+	code, response = upgrades("url=https://github.com/depscloud/a.git&compatible=true")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, response.Suggestions, 1)
+	require.Equal(t, "b", response.Suggestions[0].Module.GetModule())
+
+	code, _ = upgrades("url=https://github.com/depscloud/unknown.git")
+	require.Equal(t, http.StatusNotFound, code)
+
+	code, _ = upgrades("")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	require.Equal(t, "^1.4.0", suggestConstraint("^1.2.3", "1.4.0"))
+	require.Equal(t, ">= 2.0", suggestConstraint(">= 1.0", "2.0"))
+	require.Equal(t, "~> 0.5.1", suggestConstraint("~> 0.4.0", "0.5.1"))
+	require.Equal(t, "v1.3.0", suggestConstraint("v1.2.0", "1.3.0"))
+	require.Equal(t, "", suggestConstraint(">=1.0 <2.0", "2.1.0"))
+	require.Equal(t, "", suggestConstraint("latest", "2.1.0"))
+
+	require.True(t, breaking("0.3.0", "minor"))
+	require.False(t, breaking("1.3.0", "minor"))
+}
@@ -0,0 +1,323 @@
+package v1alpha
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// maxTextResults bounds the number of results returned by a text search.
+const maxTextResults = 100
+
+// textCandidateFactor is how many candidates are read from the store for each
+// result requested since the store matches against all of an item's data.
+const textCandidateFactor = 10
+
+// text search modes, from strictest to loosest.
+const (
+	MatchPrefix    = "prefix"
+	MatchSubstring = "substring"
+	MatchFuzzy     = "fuzzy"
+)
+
+// RegisterTextSearchService registers the textSearchService routes with the
+// http server. Labels are optional and are searched when provided.
+func RegisterTextSearchService(server *http.ServeMux, search graphstore.TextSearch, labels graphstore.Labels) {
+	svc := &textSearchService{search: search, labels: labels}
+
+	server.HandleFunc(QueryRoutePrefix+"search", svc.Search)
+}
+
+type textSearchService struct {
+	search graphstore.TextSearch
+	labels graphstore.Labels
+}
+
+// TextSearchResult is a source or module that matched a text search. Field
+// names what matched and Score ranks the match, lower is better. Dependents
+// and LastObserved describe how popular the result is.
+type TextSearchResult struct {
+	Type         string            `json:"type"`
+	Data         interface{}       `json:"data"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Field        string            `json:"field"`
+	Score        int               `json:"score"`
+	Dependents   int64             `json:"dependents"`
+	LastObserved *time.Time        `json:"last_observed,omitempty"`
+
+	matched string
+}
+
+// TextSearchResponse contains the results of a text search, best match first.
+type TextSearchResponse struct {
+	Results []*TextSearchResult `json:"results"`
+}
+
+// Search handles GET /v1alpha/queries/search. The q parameter is matched
+// against the names of modules, the urls of sources, and the labels of either,
+// ignoring case. The type parameter chooses between modules, the default, and
+// sources. The mode parameter is one of prefix, substring, the default, or
+// fuzzy, where the characters of q only need to appear in order. The limit
+// parameter bounds the number of results.
+//
+// Results are grouped by how they matched: exact matches, then prefixes, then
+// substrings, then fuzzy matches. Within a group, results with the most
+// dependents come first, followed by the most recently observed.
+func (s *textSearchService) Search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+
+	text := query.Get("q")
+	if text == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("q is required"))
+		return
+	}
+
+	graphItemType := query.Get("type")
+	if graphItemType == "" {
+		graphItemType = types.ModuleType
+	} else if graphItemType != types.ModuleType && graphItemType != types.SourceType {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("type must be module or source"))
+		return
+	}
+
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = MatchSubstring
+	} else if mode != MatchPrefix && mode != MatchSubstring && mode != MatchFuzzy {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("mode must be prefix, substring, or fuzzy"))
+		return
+	}
+
+	limit, err := positiveInt(query.Get("limit"), 20)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+		return
+	} else if limit > maxTextResults {
+		limit = maxTextResults
+	}
+
+	ctx := r.Context()
+
+	candidates, err := s.search.SearchText(ctx, graphItemType, text, mode == MatchFuzzy, limit*textCandidateFactor)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.text] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to search"))
+		return
+	}
+
+	labels := make(map[string]map[string]string)
+	if s.labels != nil && len(candidates) > 0 {
+		keys := make([][]byte, len(candidates))
+		for i, candidate := range candidates {
+			keys[i] = candidate.GetK1()
+		}
+
+		if labels, err = s.labels.GetLabels(ctx, graphItemType, keys); err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.text] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to search"))
+			return
+		}
+	}
+
+	results := make([]*TextSearchResult, 0, len(candidates))
+	keys := make([][]byte, 0, len(candidates))
+	for _, candidate := range candidates {
+		result, err := rankCandidate(candidate, labels[string(candidate.GetK1())], text, mode)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.text] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to search"))
+			return
+		} else if result != nil {
+			results = append(results, result)
+			keys = append(keys, candidate.GetK1())
+		}
+	}
+
+	popularity, err := s.search.GetPopularity(ctx, graphItemType, keys)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.text] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to search"))
+		return
+	}
+
+	for i, result := range results {
+		if p, ok := popularity[string(keys[i])]; ok {
+			result.Dependents = p.Dependents
+			if !p.LastObserved.IsZero() {
+				lastObserved := p.LastObserved
+				result.LastObserved = &lastObserved
+			}
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+
+		if matchTier(a.Score) != matchTier(b.Score) {
+			return matchTier(a.Score) < matchTier(b.Score)
+		} else if a.Dependents != b.Dependents {
+			return a.Dependents > b.Dependents
+		} else if observed := compareObserved(a.LastObserved, b.LastObserved); observed != 0 {
+			return observed > 0
+		} else if a.Score != b.Score {
+			return a.Score < b.Score
+		} else if len(a.matched) != len(b.matched) {
+			return len(a.matched) < len(b.matched)
+		}
+		return a.matched < b.matched
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	writeJSON(w, r, http.StatusOK, &TextSearchResponse{Results: results})
+}
+
+// rankCandidate returns the best match between the text and the fields of the
+// candidate, or nil when none of them match.
+func rankCandidate(item *store.GraphItem, labels map[string]string, text, mode string) (*TextSearchResult, error) {
+	data, err := Decode(item)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([][2]string, 0, len(labels)+2)
+	switch v := data.(type) {
+	case *schema.Module:
+		fields = append(fields, [2]string{"name", v.GetName()}, [2]string{"module", v.GetModule()})
+	case *schema.Source:
+		fields = append(fields, [2]string{"url", v.GetUrl()})
+	}
+
+	for key, value := range labels {
+		fields = append(fields, [2]string{"labels." + key, key + "=" + value})
+	}
+
+	var best *TextSearchResult
+	for _, field := range fields {
+		score, ok := matchScore(field[1], text, mode)
+		if !ok {
+			continue
+		}
+
+		if best == nil || score < best.Score || (score == best.Score && field[1] < best.matched) {
+			best = &TextSearchResult{
+				Type:    item.GetGraphItemType(),
+				Data:    data,
+				Labels:  labels,
+				Field:   field[0],
+				Score:   score,
+				matched: field[1],
+			}
+		}
+	}
+
+	return best, nil
+}
+
+// matchTier groups scores by how they matched, placing every fuzzy match in
+// the same group.
+func matchTier(score int) int {
+	if score > 3 {
+		return 3
+	}
+	return score
+}
+
+// compareObserved returns a positive number when a was observed more recently
+// than b, a negative number when b was, and 0 otherwise. Results that were
+// never observed come last.
+func compareObserved(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case b == nil:
+		return 1
+	case a == nil:
+		return -1
+	case a.After(*b):
+		return 1
+	case b.After(*a):
+		return -1
+	}
+	return 0
+}
+
+// matchScore scores how well the value matches the text, ignoring case. Exact
+// matches score 0, prefixes 1, and substrings 2. Fuzzy matches score 3 plus
+// the number of characters between the matched ones, so tighter matches rank
+// higher.
+func matchScore(value, text, mode string) (int, bool) {
+	value, text = strings.ToLower(value), strings.ToLower(text)
+
+	switch {
+	case value == "":
+		return 0, false
+	case value == text:
+		return 0, true
+	case strings.HasPrefix(value, text):
+		return 1, true
+	case mode == MatchPrefix:
+		return 0, false
+	case strings.Contains(value, text):
+		return 2, true
+	case mode == MatchSubstring:
+		return 0, false
+	}
+
+	// find the shortest window containing the characters of text in order
+	first, _ := utf8.DecodeRuneInString(text)
+
+	best := -1
+	for start, r := range value {
+		if r != first {
+			continue
+		}
+
+		end, ok := subsequenceEnd(value[start:], text)
+		if !ok {
+			// later starts can't match either
+			break
+		}
+
+		if span := utf8.RuneCountInString(value[start : start+end]); best < 0 || span < best {
+			best = span
+		}
+	}
+
+	if best < 0 {
+		return 0, false
+	}
+
+	return 3 + best - utf8.RuneCountInString(text), true
+}
+
+// subsequenceEnd returns the byte offset just past the last character of text
+// when the characters of text appear in order within value.
+func subsequenceEnd(value, text string) (int, bool) {
+	i := 0
+	for _, r := range text {
+		next := strings.IndexRune(value[i:], r)
+		if next < 0 {
+			return 0, false
+		}
+		i += next + utf8.RuneLen(r)
+	}
+
+	return i, true
+}
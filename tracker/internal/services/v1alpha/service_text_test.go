@@ -0,0 +1,122 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTextSearch returns every item as a candidate.
+type fakeTextSearch struct {
+	items      []*store.GraphItem
+	popularity map[string]*graphstore.Popularity
+}
+
+func (f *fakeTextSearch) SearchText(ctx context.Context, graphItemType, text string, fuzzy bool, limit int) ([]*store.GraphItem, error) {
+	return f.items, nil
+}
+
+func (f *fakeTextSearch) GetPopularity(ctx context.Context, graphItemType string, keys [][]byte) (map[string]*graphstore.Popularity, error) {
+	return f.popularity, nil
+}
+
+func TestMatchScore(t *testing.T) {
+	for _, test := range []struct {
+		value, text, mode string
+		score             int
+		ok                bool
+	}{
+		{"yaml", "YAML", MatchPrefix, 0, true},
+		{"yaml.v2", "yaml", MatchPrefix, 1, true},
+		{"gopkg.in/yaml.v2", "yaml", MatchPrefix, 0, false},
+		{"gopkg.in/yaml.v2", "yaml", MatchSubstring, 2, true},
+		{"gopkg.in/yaml.v2", "gyv", MatchSubstring, 0, false},
+		{"gopkg.in/yaml.v2", "yv2", MatchFuzzy, 7, true},
+		{"gopkg.in/yaml.v2", "v2y", MatchFuzzy, 0, false},
+		{"", "a", MatchFuzzy, 0, false},
+	} {
+		score, ok := matchScore(test.value, test.text, test.mode)
+		require.Equal(t, test.ok, ok, test.value+" "+test.text)
+		require.Equal(t, test.score, score, test.value+" "+test.text)
+	}
+}
+
+func TestTextSearch(t *testing.T) {
+	module := func(name string) *store.GraphItem {
+		item, err := Encode(&schema.Module{Language: "go", Module: name, Name: name})
+		require.Nil(t, err)
+		return item
+	}
+
+	candidates := &fakeTextSearch{
+		items: []*store.GraphItem{
+			module("github.com/go-yaml/yaml"),
+			module("gopkg.in/yaml.v2"),
+			module("yaml"),
+			module("github.com/depscloud/api"),
+		},
+		popularity: make(map[string]*graphstore.Popularity),
+	}
+
+	labels := fakeLabels{}
+	require.Nil(t, labels.SetLabels(context.Background(), "module", candidates.items[3].GetK1(), map[string]string{"format": "yaml"}))
+
+	server := http.NewServeMux()
+	RegisterTextSearchService(server, candidates, labels)
+
+	search := func(query string) (int, []*TextSearchResult) {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/queries/search?"+query, nil))
+		if recorder.Code != http.StatusOK {
+			return recorder.Code, nil
+		}
+
+		response := &TextSearchResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		return recorder.Code, response.Results
+	}
+
+	code, results := search("q=yaml")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, results, 4)
+	require.Equal(t, "name", results[0].Field)
+	require.Equal(t, 0, results[0].Score)
+	require.Equal(t, "labels.format", results[1].Field)
+
+	// popular modules come first among the substring matches
+	observed := time.Now()
+	candidates.popularity[string(candidates.items[0].GetK1())] = &graphstore.Popularity{Dependents: 40, LastObserved: observed}
+	candidates.popularity[string(candidates.items[1].GetK1())] = &graphstore.Popularity{Dependents: 40, LastObserved: observed.Add(-time.Hour)}
+
+	code, results = search("q=yaml")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, results, 4)
+	require.Equal(t, "yaml", results[0].Data.(map[string]interface{})["name"])
+	require.Equal(t, "github.com/go-yaml/yaml", results[1].Data.(map[string]interface{})["name"])
+	require.Equal(t, int64(40), results[1].Dependents)
+	require.Equal(t, "gopkg.in/yaml.v2", results[2].Data.(map[string]interface{})["name"])
+	require.Equal(t, "labels.format", results[3].Field)
+
+	code, results = search("q=yaml&mode=prefix&limit=1")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, results, 1)
+
+	code, results = search("q=gyy&mode=fuzzy")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, results, 1)
+	require.Equal(t, "module", results[0].Type)
+
+	for _, query := range []string{"", "q=a&type=depends", "q=a&mode=regex", "q=a&limit=0"} {
+		code, _ = search(query)
+		require.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
@@ -0,0 +1,220 @@
+package v1alpha
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// TombstoneRoutePrefix prefixes the HTTP routes used to manage the items
+// deleted from the graph.
+const TombstoneRoutePrefix = "/v1alpha/tombstones/"
+
+// maxTombstonePageSize bounds the number of tombstones returned at once.
+const maxTombstonePageSize = 1000
+
+// RegisterTombstoneService registers the tombstoneService routes with the http server
+func RegisterTombstoneService(server *http.ServeMux, gs store.GraphStoreClient, tombstones graphstore.Tombstones) {
+	svc := &tombstoneService{
+		sources:    &sourceService{gs: gs},
+		tombstones: tombstones,
+	}
+
+	server.HandleFunc(TombstoneRoutePrefix, svc.List)
+	server.HandleFunc(TombstoneRoutePrefix+"sources", svc.TombstoneSource)
+	server.HandleFunc(TombstoneRoutePrefix+"purge", svc.Purge)
+}
+
+type tombstoneService struct {
+	sources    *sourceService
+	tombstones graphstore.Tombstones
+}
+
+// TombstoneEntry is an item deleted from the graph.
+type TombstoneEntry struct {
+	Type        string      `json:"type"`
+	Data        interface{} `json:"data"`
+	DateDeleted time.Time   `json:"date_deleted"`
+}
+
+// ListTombstonesResponse contains a page of items deleted from the graph.
+type ListTombstonesResponse struct {
+	Page       int32             `json:"page"`
+	Count      int32             `json:"count"`
+	Tombstones []*TombstoneEntry `json:"tombstones"`
+}
+
+// List handles GET /v1alpha/tombstones/. The type parameter selects the kind of
+// item to list and defaults to sources. The page and count parameters select
+// the page of results.
+func (t *tombstoneService) List(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != TombstoneRoutePrefix {
+		writeError(w, r, http.StatusNotFound, fmt.Errorf("not found"))
+		return
+	} else if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+
+	graphItemType := query.Get("type")
+	if graphItemType == "" {
+		graphItemType = types.SourceType
+	}
+
+	switch graphItemType {
+	case types.SourceType, types.ManagesType, types.ModuleType, types.DependsType:
+	default:
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("unrecognized type %s", graphItemType))
+		return
+	}
+
+	page, err := positiveInt(query.Get("page"), 1)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("page must be a positive integer"))
+		return
+	}
+
+	count, err := positiveInt(query.Get("count"), 100)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("count must be a positive integer"))
+		return
+	} else if count > maxTombstonePageSize {
+		count = maxTombstonePageSize
+	}
+
+	tombstones, err := t.tombstones.ListTombstones(r.Context(), graphItemType, int32(page), int32(count))
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.tombstone] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to list tombstones"))
+		return
+	}
+
+	response := &ListTombstonesResponse{
+		Page:       int32(page),
+		Count:      int32(count),
+		Tombstones: make([]*TombstoneEntry, 0, len(tombstones)),
+	}
+
+	for _, tombstone := range tombstones {
+		data, err := Decode(tombstone.Item)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("[service.tombstone] %s", err.Error())
+			writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to list tombstones"))
+			return
+		}
+
+		response.Tombstones = append(response.Tombstones, &TombstoneEntry{
+			Type:        tombstone.Item.GetGraphItemType(),
+			Data:        data,
+			DateDeleted: tombstone.DateDeleted,
+		})
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// TombstoneSourceResponse contains the number of items that were tombstoned.
+type TombstoneSourceResponse struct {
+	Tombstoned int `json:"tombstoned"`
+}
+
+// TombstoneSource handles POST /v1alpha/tombstones/sources. It's called when
+// the source identified by the url parameter no longer exists, such as when a
+// repository is archived or deleted. The source along with the edges it
+// manages and the dependencies it declares are tombstoned rather than
+// removed. Tracking the source again restores them.
+func (t *tombstoneService) TombstoneSource(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("url is required"))
+		return
+	}
+
+	current, err := t.sources.getCurrent(r.Context(), &schema.Source{Url: url})
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.tombstone] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to query source"))
+		return
+	}
+
+	// modules may be managed by other sources and are left alone, matching
+	// the behavior of Track
+	toDelete := make([]*store.GraphItem, 0, len(current))
+	for _, item := range current {
+		if item.GetGraphItemType() != types.ModuleType {
+			toDelete = append(toDelete, item)
+		}
+	}
+
+	if _, err := t.sources.gs.Delete(r.Context(), &store.DeleteRequest{Items: toDelete}); err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.tombstone] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to tombstone source"))
+		return
+	}
+
+	logging.FromContext(r.Context()).Infof("[service.tombstone] tombstoned %d items for %s", len(toDelete), url)
+	writeJSON(w, r, http.StatusOK, &TombstoneSourceResponse{
+		Tombstoned: len(toDelete),
+	})
+}
+
+// PurgeResponse contains the number of tombstones that were removed.
+type PurgeResponse struct {
+	Purged int64 `json:"purged"`
+}
+
+// Purge handles POST /v1alpha/tombstones/purge. Every item tombstoned at or
+// before the time in the RFC 3339 before parameter is permanently removed.
+func (t *tombstoneService) Purge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	before, err := time.Parse(time.RFC3339, r.URL.Query().Get("before"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("before must be an RFC 3339 timestamp"))
+		return
+	}
+
+	purged, err := t.tombstones.PurgeTombstones(r.Context(), before)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.tombstone] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to purge tombstones"))
+		return
+	}
+
+	logging.FromContext(r.Context()).Infof("[service.tombstone] purged %d tombstones deleted before %s", purged, before.Format(time.RFC3339))
+	writeJSON(w, r, http.StatusOK, &PurgeResponse{
+		Purged: purged,
+	})
+}
+
+// positiveInt parses an optional positive integer, returning def when the
+// value is empty.
+func positiveInt(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("not a positive integer")
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,54 @@
+package v1alpha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTombstoneSource(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+	})
+	gs.manage(t, "https://github.com/depscloud/a.git", "a")
+	gs.manage(t, "https://github.com/depscloud/b.git", "b")
+
+	// the edges of a are declared by its source
+	for _, edge := range gs.edges {
+		if string(edge.GetK1()) == string(moduleKey("a")) {
+			edge.K3 = keyForSource(&schema.Source{Url: "https://github.com/depscloud/a.git"})
+		}
+	}
+
+	server := http.NewServeMux()
+	RegisterTombstoneService(server, gs, nil)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/v1alpha/tombstones/sources?url=https://github.com/depscloud/a.git", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost,
+		"/v1alpha/tombstones/sources?url=https://github.com/depscloud/a.git", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	response := &TombstoneSourceResponse{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+
+	// the source, the manages edge, and the depends edge
+	require.Equal(t, 3, response.Tombstoned)
+	require.Len(t, gs.edges, 2)
+	require.Contains(t, gs.nodes, string(moduleKey("a")))
+	require.NotContains(t, gs.nodes, string(keyForSource(&schema.Source{Url: "https://github.com/depscloud/a.git"})))
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1alpha/tombstones/purge?before=yesterday", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
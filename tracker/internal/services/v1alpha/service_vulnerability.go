@@ -0,0 +1,196 @@
+package v1alpha
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// VulnerabilityRoutePrefix prefixes the HTTP routes used to look up the
+// advisories recorded by the vulnerability scan.
+const VulnerabilityRoutePrefix = "/v1alpha/vulnerabilities/"
+
+// RegisterVulnerabilityService registers the vulnerabilityService routes with
+// the http server
+func RegisterVulnerabilityService(server *http.ServeMux, gs store.GraphStoreClient, vulnerabilities graphstore.Vulnerabilities, aliases *Aliases) {
+	svc := &vulnerabilityService{gs: gs, vulnerabilities: vulnerabilities, aliases: aliases}
+
+	server.HandleFunc(VulnerabilityRoutePrefix+"dependents", svc.ListVulnerableDependents)
+	server.HandleFunc(VulnerabilityRoutePrefix+"modules", svc.Modules)
+}
+
+type vulnerabilityService struct {
+	gs              store.GraphStoreClient
+	vulnerabilities graphstore.Vulnerabilities
+	aliases         *Aliases
+}
+
+// VulnerableDependent is a module that depends on a version of another
+// module affected by an advisory.
+type VulnerableDependent struct {
+	Advisory  *graphstore.Advisory `json:"advisory"`
+	Module    *schema.Module       `json:"module"`
+	Version   string               `json:"version"`
+	Dependent *schema.Module       `json:"dependent"`
+	Depends   *schema.Depends      `json:"depends"`
+}
+
+// ListVulnerableDependentsResponse contains the modules exposed to an
+// advisory.
+type ListVulnerableDependentsResponse struct {
+	Dependents []*VulnerableDependent `json:"dependents"`
+}
+
+// ModuleVulnerability is an advisory affecting a version of a module.
+type ModuleVulnerability struct {
+	Version  string               `json:"version"`
+	Advisory *graphstore.Advisory `json:"advisory"`
+}
+
+// ModuleVulnerabilitiesResponse contains the advisories affecting a module.
+type ModuleVulnerabilitiesResponse struct {
+	Vulnerabilities []*ModuleVulnerability `json:"vulnerabilities"`
+}
+
+// ListVulnerableDependents handles GET /v1alpha/vulnerabilities/dependents.
+// The advisory is identified by the id parameter, which can be any of its
+// aliases (such as a CVE). Each module depending on an affected version is
+// returned along with the module and version it depends on.
+func (v *vulnerabilityService) ListVulnerableDependents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("id is required"))
+		return
+	}
+
+	ctx := r.Context()
+
+	affected, err := v.vulnerabilities.FindAffected(ctx, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.vulnerability] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find affected modules"))
+		return
+	}
+
+	versions := make(map[string]map[string]*graphstore.Advisory)
+	keys := make([][]byte, 0)
+	for _, a := range affected {
+		if _, ok := versions[string(a.Key)]; !ok {
+			versions[string(a.Key)] = make(map[string]*graphstore.Advisory)
+			keys = append(keys, a.Key)
+		}
+		versions[string(a.Key)][a.Version] = a.Advisory
+	}
+
+	pairs, err := findPairs(ctx, v.gs.FindDownstream, keys, types.DependsType, types.ModuleType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.vulnerability] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find dependents"))
+		return
+	}
+
+	dependents := make([]*VulnerableDependent, 0)
+	dependentKeys := make([][]byte, 0)
+	vulnerableKeys := make([]string, 0)
+
+	for _, pair := range pairs {
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		dependent := node.(*schema.Module)
+
+		edge, err := Decode(pair.GetEdge())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		depends := edge.(*schema.Depends)
+
+		version := resolveVersion(dependent.GetLanguage(), depends.GetVersionConstraint())
+		advisory, ok := versions[string(pair.GetEdge().GetK2())][version]
+		if version == "" || !ok {
+			continue
+		}
+
+		dependents = append(dependents, &VulnerableDependent{
+			Advisory:  advisory,
+			Version:   version,
+			Dependent: dependent,
+			Depends:   depends,
+		})
+		dependentKeys = append(dependentKeys, pair.GetEdge().GetK1())
+		vulnerableKeys = append(vulnerableKeys, string(pair.GetEdge().GetK2()))
+	}
+
+	// the affected modules are only known by key, so they're read from the
+	// edges of their dependents
+	upstream, err := findPairs(ctx, v.gs.FindUpstream, dependentKeys, types.DependsType, types.ModuleType)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.vulnerability] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to find affected modules"))
+		return
+	}
+
+	modules := make(map[string]*schema.Module)
+	for _, pair := range upstream {
+		if _, ok := versions[string(pair.GetNode().GetK1())]; !ok {
+			continue
+		}
+
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		modules[string(pair.GetNode().GetK1())] = node.(*schema.Module)
+	}
+
+	for i, dependent := range dependents {
+		dependent.Module = modules[vulnerableKeys[i]]
+	}
+
+	writeJSON(w, r, http.StatusOK, &ListVulnerableDependentsResponse{Dependents: dependents})
+}
+
+// Modules handles GET /v1alpha/vulnerabilities/modules. The module is
+// identified by the language, organization, and module parameters.
+func (v *vulnerabilityService) Modules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	req, err := parseModule(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	key := keyForDependencyRequest(v.aliases.request(req))
+
+	affected, err := v.vulnerabilities.GetAdvisories(r.Context(), [][]byte{key})
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.vulnerability] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to get advisories"))
+		return
+	}
+
+	results := make([]*ModuleVulnerability, len(affected))
+	for i, a := range affected {
+		results[i] = &ModuleVulnerability{Version: a.Version, Advisory: a.Advisory}
+	}
+
+	writeJSON(w, r, http.StatusOK, &ModuleVulnerabilitiesResponse{Vulnerabilities: results})
+}
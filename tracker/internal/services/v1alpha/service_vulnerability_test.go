@@ -0,0 +1,142 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVulnerabilities holds the advisories of each module version by source.
+type fakeVulnerabilities map[string]map[string]map[string][]*graphstore.Advisory
+
+func (f fakeVulnerabilities) SetAdvisories(ctx context.Context, source string, key []byte, version string, advisories []*graphstore.Advisory) error {
+	if f[source] == nil {
+		f[source] = make(map[string]map[string][]*graphstore.Advisory)
+	}
+	if f[source][string(key)] == nil {
+		f[source][string(key)] = make(map[string][]*graphstore.Advisory)
+	}
+	f[source][string(key)][version] = advisories
+	return nil
+}
+
+func (f fakeVulnerabilities) GetAdvisories(ctx context.Context, keys [][]byte) ([]*graphstore.Affected, error) {
+	results := make([]*graphstore.Affected, 0)
+	for _, modules := range f {
+		for _, key := range keys {
+			for version, advisories := range modules[string(key)] {
+				for _, advisory := range advisories {
+					results = append(results, &graphstore.Affected{Key: key, Version: version, Advisory: advisory})
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+func (f fakeVulnerabilities) FindAffected(ctx context.Context, id string) ([]*graphstore.Affected, error) {
+	results := make([]*graphstore.Affected, 0)
+	for _, modules := range f {
+		for key, versions := range modules {
+			for version, advisories := range versions {
+				for _, advisory := range advisories {
+					if advisory.ID == id || strings.Contains(strings.Join(advisory.Aliases, ","), id) {
+						results = append(results, &graphstore.Affected{Key: []byte(key), Version: version, Advisory: advisory})
+					}
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+func osvServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/querybatch", func(w http.ResponseWriter, r *http.Request) {
+		request := struct {
+			Queries []*OSVQuery `json:"queries"`
+		}{}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+
+		results := make([]map[string]interface{}, len(request.Queries))
+		for i, query := range request.Queries {
+			require.Equal(t, "Go", query.Package.Ecosystem)
+			require.Equal(t, "1.0.0", query.Version)
+
+			results[i] = map[string]interface{}{}
+			if query.Package.Name == "depscloud/b" {
+				results[i]["vulns"] = []map[string]string{{"id": "GHSA-0001"}}
+			}
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	})
+	mux.HandleFunc("/v1/vulns/GHSA-0001", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"id": "GHSA-0001",
+			"aliases": ["CVE-2021-0001"],
+			"summary": "denial of service",
+			"modified": "2021-03-01T00:00:00Z",
+			"database_specific": {"severity": "HIGH"}
+		}`))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestVulnerabilities(t *testing.T) {
+	osv := osvServer(t)
+	defer osv.Close()
+
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b"},
+		"c": {"b", "d"},
+	})
+	vulnerabilities := fakeVulnerabilities{}
+
+	report, err := scanVulnerabilities(context.Background(), gs, vulnerabilities, NewOSVClient(osv.URL))
+	require.Nil(t, err)
+	require.Equal(t, &ScanReport{Versions: 2, Affected: 1, Advisories: 1}, report)
+
+	server := http.NewServeMux()
+	RegisterVulnerabilityService(server, gs, vulnerabilities, nil)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/vulnerabilities/dependents?id=CVE-2021-0001", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	dependents := &ListVulnerableDependentsResponse{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(dependents))
+	require.Len(t, dependents.Dependents, 2)
+
+	names := make([]string, 0, 2)
+	for _, dependent := range dependents.Dependents {
+		require.Equal(t, "b", dependent.Module.GetModule())
+		require.Equal(t, "v1.0.0", dependent.Version)
+		require.Equal(t, "GHSA-0001", dependent.Advisory.ID)
+		require.Equal(t, "HIGH", dependent.Advisory.Severity)
+		names = append(names, dependent.Dependent.GetModule())
+	}
+	require.ElementsMatch(t, []string{"a", "c"}, names)
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/vulnerabilities/modules?language=go&organization=depscloud&module=b", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	modules := &ModuleVulnerabilitiesResponse{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(modules))
+	require.Len(t, modules.Vulnerabilities, 1)
+	require.Equal(t, "v1.0.0", modules.Vulnerabilities[0].Version)
+	require.Equal(t, []string{"CVE-2021-0001"}, modules.Vulnerabilities[0].Advisory.Aliases)
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/vulnerabilities/dependents", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
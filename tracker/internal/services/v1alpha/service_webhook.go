@@ -0,0 +1,66 @@
+package v1alpha
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/depscloud/depscloud/internal/logging"
+)
+
+// WebhookRoutePrefix prefixes the HTTP routes used to manage webhook
+// deliveries that failed.
+const WebhookRoutePrefix = "/v1alpha/webhooks/"
+
+// RegisterWebhookService registers the webhookService routes with the http
+// server
+func RegisterWebhookService(server *http.ServeMux, webhooks *Webhooks) {
+	svc := &webhookService{webhooks: webhooks}
+
+	server.HandleFunc(WebhookRoutePrefix+"dead-letters", svc.DeadLetters)
+	server.HandleFunc(WebhookRoutePrefix+"dead-letters/replay", svc.Replay)
+}
+
+type webhookService struct {
+	webhooks *Webhooks
+}
+
+// DeadLettersResponse contains the deliveries that exhausted their attempts.
+type DeadLettersResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+}
+
+// DeadLetters handles GET /v1alpha/webhooks/dead-letters, listing the failed
+// deliveries oldest first.
+func (s *webhookService) DeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	deliveries, err := s.webhooks.DeadLetters()
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.webhook] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to read dead letters"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &DeadLettersResponse{Deliveries: deliveries})
+}
+
+// Replay handles POST /v1alpha/webhooks/dead-letters/replay, attempting each
+// failed delivery once more.
+func (s *webhookService) Replay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method))
+		return
+	}
+
+	report, err := s.webhooks.Replay(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("[service.webhook] %s", err.Error())
+		writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to replay dead letters"))
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, report)
+}
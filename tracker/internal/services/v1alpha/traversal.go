@@ -0,0 +1,271 @@
+package v1alpha
+
+import (
+	"context"
+	"sort"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"google.golang.org/grpc"
+)
+
+// traversalBatchSize bounds the number of keys looked up by a single query
+// since databases limit the number of parameters in a statement.
+const traversalBatchSize = 500
+
+// Reached is a module found while walking the graph. Depends is the edge the
+// module was first reached by and Depth is the number of edges between the
+// module and the one the walk started from.
+type Reached struct {
+	Module  *schema.Module  `json:"module"`
+	Depends *schema.Depends `json:"depends"`
+	Depth   int             `json:"depth"`
+
+	key []byte
+}
+
+// findFunc is either FindUpstream, to walk dependencies, or FindDownstream, to
+// walk dependents.
+type findFunc func(ctx context.Context, req *store.FindRequest, opts ...grpc.CallOption) (*store.FindResponse, error)
+
+// findPairs returns the nodes adjacent to the keys along with the edges that
+// connect them.
+func findPairs(ctx context.Context, find findFunc, keys [][]byte, edgeType, nodeType string) ([]*store.GraphItemPair, error) {
+	pairs := make([]*store.GraphItemPair, 0)
+
+	for start := 0; start < len(keys); start += traversalBatchSize {
+		end := start + traversalBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		response, err := find(ctx, &store.FindRequest{
+			Keys:      keys[start:end],
+			EdgeTypes: []string{edgeType},
+			NodeTypes: []string{nodeType},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, response.GetPairs()...)
+	}
+
+	// keep results stable between calls
+	sort.Slice(pairs, func(i, j int) bool {
+		return readableKey(pairs[i].GetEdge()) < readableKey(pairs[j].GetEdge())
+	})
+
+	return pairs, nil
+}
+
+// decodePair decodes the module and depends edge of a pair.
+func decodePair(pair *store.GraphItemPair) (*schema.Module, *schema.Depends, error) {
+	node, err := Decode(pair.GetNode())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	edge, err := Decode(pair.GetEdge())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return node.(*schema.Module), edge.(*schema.Depends), nil
+}
+
+// ReachedSource is a source that manages a module found while walking the
+// graph. Depth is the depth of the module.
+type ReachedSource struct {
+	Source  *schema.Source  `json:"source"`
+	Manages *schema.Manages `json:"manages"`
+	Module  *schema.Module  `json:"module"`
+	Depth   int             `json:"depth"`
+}
+
+// sourcesFor returns the sources that manage the reached modules. Each source
+// is returned once, for the module reached at the smallest depth.
+func sourcesFor(ctx context.Context, find findFunc, reached []*Reached) ([]*ReachedSource, error) {
+	keys := make([][]byte, 0, len(reached))
+	modules := make(map[string]*Reached, len(reached))
+
+	for _, r := range reached {
+		keys = append(keys, r.key)
+		modules[string(r.key)] = r
+	}
+
+	pairs, err := findPairs(ctx, find, keys, types.ManagesType, types.SourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	bySource := make(map[string]*ReachedSource)
+	results := make([]*ReachedSource, 0)
+
+	for _, pair := range pairs {
+		module := modules[string(pair.GetEdge().GetK2())]
+		if module == nil {
+			continue
+		}
+
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			return nil, err
+		}
+
+		edge, err := Decode(pair.GetEdge())
+		if err != nil {
+			return nil, err
+		}
+
+		source := node.(*schema.Source)
+		if existing, ok := bySource[source.GetUrl()]; ok {
+			if existing.Depth > module.Depth {
+				existing.Manages = edge.(*schema.Manages)
+				existing.Module = module.Module
+				existing.Depth = module.Depth
+			}
+			continue
+		}
+
+		reachedSource := &ReachedSource{
+			Source:  source,
+			Manages: edge.(*schema.Manages),
+			Module:  module.Module,
+			Depth:   module.Depth,
+		}
+
+		bySource[source.GetUrl()] = reachedSource
+		results = append(results, reachedSource)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Depth < results[j].Depth
+	})
+
+	return results, nil
+}
+
+// traverse walks the graph breadth first from the root until maxDepth is
+// reached. Each module is returned once, at the smallest depth it was reached
+// at. The root is never included in the results, even when part of a cycle.
+func traverse(ctx context.Context, find findFunc, root []byte, maxDepth int) ([]*Reached, error) {
+	return traverseFrom(ctx, find, [][]byte{root}, maxDepth)
+}
+
+// traverseFrom is like traverse, but walks from several roots at once. Depths
+// are measured from the closest root.
+func traverseFrom(ctx context.Context, find findFunc, roots [][]byte, maxDepth int) ([]*Reached, error) {
+	seen := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		seen[string(root)] = true
+	}
+	frontier := roots
+	results := make([]*Reached, 0)
+
+	for depth := 1; depth <= maxDepth && len(frontier) > 0; depth++ {
+		pairs, err := findPairs(ctx, find, frontier, types.DependsType, types.ModuleType)
+		if err != nil {
+			return nil, err
+		}
+
+		next := make([][]byte, 0)
+		for _, pair := range pairs {
+			key := pair.GetNode().GetK1()
+			if seen[string(key)] {
+				continue
+			}
+			seen[string(key)] = true
+
+			module, depends, err := decodePair(pair)
+			if err != nil {
+				return nil, err
+			}
+
+			results = append(results, &Reached{
+				Module:  module,
+				Depends: depends,
+				Depth:   depth,
+				key:     key,
+			})
+			next = append(next, key)
+		}
+
+		frontier = next
+	}
+
+	return results, nil
+}
+
+// shortestPaths walks the dependencies of from, breadth first, until to is
+// reached. Every edge that reaches a module at its smallest depth is kept so
+// all of the shortest paths can be rebuilt. At most limit paths are returned,
+// each as the pairs leading from from to to.
+func shortestPaths(ctx context.Context, find findFunc, from, to []byte, maxDepth, limit int) ([][]*store.GraphItemPair, error) {
+	depths := map[string]int{
+		string(from): 0,
+	}
+	parents := make(map[string][]*store.GraphItemPair)
+	frontier := [][]byte{from}
+
+	found := false
+	for depth := 1; depth <= maxDepth && len(frontier) > 0 && !found; depth++ {
+		pairs, err := findPairs(ctx, find, frontier, types.DependsType, types.ModuleType)
+		if err != nil {
+			return nil, err
+		}
+
+		next := make([][]byte, 0)
+		for _, pair := range pairs {
+			key := pair.GetNode().GetK1()
+
+			if seenAt, ok := depths[string(key)]; ok {
+				if seenAt == depth {
+					parents[string(key)] = append(parents[string(key)], pair)
+				}
+				continue
+			}
+
+			depths[string(key)] = depth
+			parents[string(key)] = []*store.GraphItemPair{pair}
+			next = append(next, key)
+
+			found = found || string(key) == string(to)
+		}
+
+		frontier = next
+	}
+
+	paths := make([][]*store.GraphItemPair, 0)
+	if !found {
+		return paths, nil
+	}
+
+	// walk back from to, building the paths in reverse
+	var walk func(key string, reversed []*store.GraphItemPair)
+	walk = func(key string, reversed []*store.GraphItemPair) {
+		if len(paths) >= limit {
+			return
+		}
+
+		if key == string(from) {
+			path := make([]*store.GraphItemPair, len(reversed))
+			for i, pair := range reversed {
+				path[len(reversed)-1-i] = pair
+			}
+			paths = append(paths, path)
+			return
+		}
+
+		for _, pair := range parents[key] {
+			next := make([]*store.GraphItemPair, len(reversed), len(reversed)+1)
+			copy(next, reversed)
+			walk(string(pair.GetEdge().GetK1()), append(next, pair))
+		}
+	}
+	walk(string(to), nil)
+
+	return paths, nil
+}
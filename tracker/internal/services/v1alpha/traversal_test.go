@@ -0,0 +1,483 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/filters"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeGraphStore holds modules and the depends edges between them.
+type fakeGraphStore struct {
+	store.GraphStoreClient
+
+	nodes map[string]*store.GraphItem
+	edges []*store.GraphItem
+}
+
+func newFakeGraphStore(t *testing.T, edges map[string][]string) *fakeGraphStore {
+	gs := &fakeGraphStore{
+		nodes: make(map[string]*store.GraphItem),
+	}
+
+	module := func(name string) *store.GraphItem {
+		item, err := Encode(&schema.Module{Language: "go", Organization: "depscloud", Module: name})
+		require.Nil(t, err)
+
+		gs.nodes[string(item.GetK1())] = item
+		return item
+	}
+
+	for from, tos := range edges {
+		fromItem := module(from)
+
+		for _, to := range tos {
+			toItem := module(to)
+
+			edge, err := Encode(&schema.Depends{Language: "go", VersionConstraint: "v1.0.0"})
+			require.Nil(t, err)
+
+			edge.K1 = fromItem.GetK1()
+			edge.K2 = toItem.GetK1()
+			gs.edges = append(gs.edges, edge)
+		}
+	}
+
+	return gs
+}
+
+// manage adds a source that manages the module.
+func (gs *fakeGraphStore) manage(t *testing.T, url, module string) {
+	source, err := Encode(&schema.Source{Url: url})
+	require.Nil(t, err)
+	gs.nodes[string(source.GetK1())] = source
+
+	manages, err := Encode(&schema.Manages{Language: "go", System: "vgo"})
+	require.Nil(t, err)
+
+	manages.K1 = source.GetK1()
+	manages.K2 = moduleKey(module)
+	gs.edges = append(gs.edges, manages)
+}
+
+func (gs *fakeGraphStore) find(req *store.FindRequest, upstream bool) *store.FindResponse {
+	pairs := make([]*store.GraphItemPair, 0)
+
+	for _, key := range req.GetKeys() {
+		for _, edge := range gs.edges {
+			if edge.GetGraphItemType() != req.GetEdgeTypes()[0] {
+				continue
+			}
+
+			if upstream && string(edge.GetK1()) == string(key) {
+				pairs = append(pairs, &store.GraphItemPair{Node: gs.nodes[string(edge.GetK2())], Edge: edge})
+			} else if !upstream && string(edge.GetK2()) == string(key) {
+				pairs = append(pairs, &store.GraphItemPair{Node: gs.nodes[string(edge.GetK1())], Edge: edge})
+			}
+		}
+	}
+
+	return &store.FindResponse{Pairs: pairs}
+}
+
+func (gs *fakeGraphStore) List(ctx context.Context, req *store.ListRequest, opts ...grpc.CallOption) (*store.ListResponse, error) {
+	language := ""
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(filters.LanguageMetadataKey)) > 0 {
+		language = md.Get(filters.LanguageMetadataKey)[0]
+	}
+
+	all := make(map[string]*store.GraphItem)
+	for _, node := range gs.nodes {
+		all[readableKey(node)] = node
+	}
+	for _, edge := range gs.edges {
+		all[readableKey(edge)] = edge
+	}
+
+	keys := make([]string, 0, len(all))
+	for key, item := range all {
+		if item.GetGraphItemType() != req.GetType() {
+			continue
+		}
+
+		if language != "" && !strings.Contains(string(item.GetGraphItemData()), `"language":"`+language+`"`) {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	items := make([]*store.GraphItem, 0)
+	for i := int((req.GetPage() - 1) * req.GetCount()); i < len(keys) && len(items) < int(req.GetCount()); i++ {
+		items = append(items, all[keys[i]])
+	}
+
+	return &store.ListResponse{Items: items}, nil
+}
+
+func (gs *fakeGraphStore) FindUpstream(ctx context.Context, req *store.FindRequest, opts ...grpc.CallOption) (*store.FindResponse, error) {
+	return gs.find(req, true), nil
+}
+
+func (gs *fakeGraphStore) FindDownstream(ctx context.Context, req *store.FindRequest, opts ...grpc.CallOption) (*store.FindResponse, error) {
+	return gs.find(req, false), nil
+}
+
+func (gs *fakeGraphStore) Put(ctx context.Context, req *store.PutRequest, opts ...grpc.CallOption) (*store.PutResponse, error) {
+	for _, item := range req.GetItems() {
+		if string(item.GetK1()) == string(item.GetK2()) {
+			gs.nodes[string(item.GetK1())] = item
+			continue
+		}
+
+		replaced := false
+		for i, edge := range gs.edges {
+			if readableKey(edge) == readableKey(item) {
+				gs.edges[i] = item
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			gs.edges = append(gs.edges, item)
+		}
+	}
+
+	return &store.PutResponse{}, nil
+}
+
+func (gs *fakeGraphStore) Delete(ctx context.Context, req *store.DeleteRequest, opts ...grpc.CallOption) (*store.DeleteResponse, error) {
+	deleted := make(map[string]bool)
+	for _, item := range req.GetItems() {
+		deleted[readableKey(item)] = true
+		if string(item.GetK1()) == string(item.GetK2()) {
+			delete(gs.nodes, string(item.GetK1()))
+		}
+	}
+
+	edges := make([]*store.GraphItem, 0, len(gs.edges))
+	for _, edge := range gs.edges {
+		if !deleted[readableKey(edge)] {
+			edges = append(edges, edge)
+		}
+	}
+	gs.edges = edges
+
+	return &store.DeleteResponse{}, nil
+}
+
+func moduleKey(name string) []byte {
+	return keyForModule(&schema.Module{Language: "go", Organization: "depscloud", Module: name})
+}
+
+func reachedDepths(reached []*Reached) map[string]int {
+	depths := make(map[string]int)
+	for _, r := range reached {
+		depths[r.Module.GetModule()] = r.Depth
+	}
+	return depths
+}
+
+func TestTraverse(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"b": {"d"},
+		"c": {"d", "a"},
+		"d": {"e"},
+	})
+
+	reached, err := traverse(context.Background(), gs.FindUpstream, moduleKey("a"), 10)
+	require.Nil(t, err)
+	require.Len(t, reached, 4)
+	require.Equal(t, map[string]int{"b": 1, "c": 1, "d": 2, "e": 3}, reachedDepths(reached))
+
+	reached, err = traverse(context.Background(), gs.FindUpstream, moduleKey("a"), 2)
+	require.Nil(t, err)
+	require.Equal(t, map[string]int{"b": 1, "c": 1, "d": 2}, reachedDepths(reached))
+
+	reached, err = traverse(context.Background(), gs.FindDownstream, moduleKey("d"), 10)
+	require.Nil(t, err)
+	require.Equal(t, map[string]int{"b": 1, "c": 1, "a": 2}, reachedDepths(reached))
+}
+
+func TestTransitiveDependencies(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+	})
+
+	server := http.NewServeMux()
+	RegisterQueryService(server, gs, 5, nil)
+
+	{
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/v1alpha/queries/transitive-dependencies?language=go&organization=depscloud&module=a&depth=1", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		response := &TransitiveDependenciesResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		require.Equal(t, map[string]int{"b": 1}, reachedDepths(response.Dependencies))
+		require.Equal(t, "v1.0.0", response.Dependencies[0].Depends.GetVersionConstraint())
+	}
+
+	{
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/v1alpha/queries/transitive-dependencies?language=go&organization=depscloud&module=a", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		response := &TransitiveDependenciesResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		require.Equal(t, map[string]int{"b": 1, "c": 2}, reachedDepths(response.Dependencies))
+	}
+
+	for _, query := range []string{"language=go", "language=go&module=a&depth=0", "language=go&module=a&depth=x", "language=go&module=a&as_of=x"} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/v1alpha/queries/transitive-dependencies?"+query, nil))
+		require.Equal(t, http.StatusBadRequest, recorder.Code, query)
+	}
+}
+
+func TestTransitiveDependents(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c"},
+		"c": {"d"},
+		"d": {"a"},
+	})
+	gs.manage(t, "https://github.com/depscloud/a.git", "a")
+	gs.manage(t, "https://github.com/depscloud/b.git", "b")
+	gs.manage(t, "https://github.com/depscloud/d.git", "d")
+	gs.manage(t, "https://github.com/depscloud/monorepo.git", "a")
+	gs.manage(t, "https://github.com/depscloud/monorepo.git", "b")
+
+	server := http.NewServeMux()
+	RegisterQueryService(server, gs, 5, nil)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/v1alpha/queries/transitive-dependents?language=go&organization=depscloud&module=c", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	response := &TransitiveDependentsResponse{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+
+	// the cycle back to c is ignored
+	require.Equal(t, map[string]int{"a": 1, "b": 1, "d": 2}, reachedDepths(response.Dependents))
+
+	sources := make(map[string]int)
+	for _, source := range response.Sources {
+		sources[source.Source.GetUrl()] = source.Depth
+	}
+
+	require.Equal(t, map[string]int{
+		"https://github.com/depscloud/a.git":        1,
+		"https://github.com/depscloud/b.git":        1,
+		"https://github.com/depscloud/d.git":        2,
+		"https://github.com/depscloud/monorepo.git": 1,
+	}, sources)
+	require.Len(t, response.Sources, 4)
+}
+
+func TestShortestPaths(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"service": {"web", "client", "logging"},
+		"web":     {"http", "flagged"},
+		"client":  {"http"},
+		"http":    {"flagged"},
+		"logging": {"service"},
+	})
+
+	server := http.NewServeMux()
+	RegisterQueryService(server, gs, 5, nil)
+
+	paths := func(query string) [][]string {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/v1alpha/queries/shortest-paths?language=go&organization=depscloud&module=service&target_organization=depscloud&"+query, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		response := &ShortestPathsResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+
+		results := make([][]string, 0, len(response.Paths))
+		for _, path := range response.Paths {
+			modules := make([]string, 0, len(path.Modules))
+			for i, module := range path.Modules {
+				require.Equal(t, i, module.Depth)
+				modules = append(modules, module.Module.GetModule())
+			}
+			results = append(results, modules)
+		}
+		return results
+	}
+
+	require.Equal(t, [][]string{{"service", "web", "flagged"}}, paths("target_module=flagged"))
+	require.Equal(t, [][]string{{"service", "web", "flagged"}}, paths("target_module=flagged&depth=2"))
+	require.Len(t, paths("target_module=flagged&depth=1"), 0)
+	require.Len(t, paths("target_module=missing"), 0)
+
+	viaHTTP := paths("target_module=http")
+	require.Len(t, viaHTTP, 2)
+	require.ElementsMatch(t, [][]string{{"service", "web", "http"}, {"service", "client", "http"}}, viaHTTP)
+	require.Len(t, paths("target_module=http&limit=1"), 1)
+}
+
+func TestCycles(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "e"},
+		"b": {"c"},
+		"c": {"a"},
+		"d": {"d", "e"},
+		"e": {"f"},
+	})
+
+	server := http.NewServeMux()
+	RegisterQueryService(server, gs, 5, nil)
+
+	cycles := func(query string) [][]string {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/queries/cycles?"+query, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		response := &CyclesResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+
+		results := make([][]string, 0, len(response.Cycles))
+		for _, cycle := range response.Cycles {
+			modules := make([]string, 0, len(cycle.Modules))
+			for _, module := range cycle.Modules {
+				modules = append(modules, module.GetModule())
+			}
+			sort.Strings(modules)
+			results = append(results, modules)
+		}
+		return results
+	}
+
+	require.ElementsMatch(t, [][]string{{"a", "b", "c"}, {"d"}}, cycles(""))
+	require.ElementsMatch(t, [][]string{{"a", "b", "c"}, {"d"}}, cycles("language=go"))
+	require.Len(t, cycles("language=node"), 0)
+}
+
+func TestBuildOrder(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"service": {"client", "logging"},
+		"client":  {"http", "logging"},
+		"http":    {"logging"},
+		"cli":     {"client"},
+		"logging": {},
+	})
+
+	server := http.NewServeMux()
+	RegisterQueryService(server, gs, 5, nil)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/queries/build-order?language=go", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	response := &BuildOrderResponse{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+	require.Len(t, response.Cycles, 0)
+
+	order := make([]string, 0, len(response.Steps))
+	stages := make(map[string]int)
+	for _, step := range response.Steps {
+		order = append(order, step.Module.GetModule())
+		stages[step.Module.GetModule()] = step.Stage
+	}
+
+	require.Equal(t, []string{"logging", "http", "client", "cli", "service"}, order)
+	require.Equal(t, map[string]int{"logging": 0, "http": 1, "client": 2, "cli": 3, "service": 3}, stages)
+
+	gs = newFakeGraphStore(t, map[string][]string{
+		"a": {"b"},
+		"b": {"a"},
+		"c": {"a"},
+	})
+
+	server = http.NewServeMux()
+	RegisterQueryService(server, gs, 5, nil)
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/queries/build-order", nil))
+	require.Equal(t, http.StatusConflict, recorder.Code)
+
+	response = &BuildOrderResponse{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+	require.Len(t, response.Steps, 0)
+	require.Len(t, response.Cycles, 1)
+	require.Len(t, response.Cycles[0].Modules, 2)
+}
+
+func TestLeaderboard(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"service": {"client", "logging"},
+		"client":  {"http", "logging"},
+		"http":    {"logging"},
+		"cli":     {"client"},
+		"a":       {"b"},
+		"b":       {"a", "logging"},
+	})
+
+	server := http.NewServeMux()
+	RegisterQueryService(server, gs, 5, nil)
+
+	leaderboard := func(query string) []*LeaderboardEntry {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/queries/leaderboard?"+query, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		response := &LeaderboardResponse{}
+		require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+		return response.Modules
+	}
+
+	entries := leaderboard("limit=4")
+	require.Len(t, entries, 4)
+
+	require.Equal(t, "logging", entries[0].Module.GetModule())
+	require.Equal(t, 4, entries[0].DirectDependents)
+	require.Equal(t, 6, entries[0].TransitiveDependents)
+
+	require.Equal(t, "http", entries[1].Module.GetModule())
+	require.Equal(t, 1, entries[1].DirectDependents)
+	require.Equal(t, 3, entries[1].TransitiveDependents)
+
+	require.Equal(t, "client", entries[2].Module.GetModule())
+	require.Equal(t, 2, entries[2].DirectDependents)
+	require.Equal(t, 2, entries[2].TransitiveDependents)
+
+	// modules in a cycle depend on each other
+	require.Equal(t, "a", entries[3].Module.GetModule())
+	require.Equal(t, 1, entries[3].DirectDependents)
+	require.Equal(t, 1, entries[3].TransitiveDependents)
+
+	entries = leaderboard("sort=direct&limit=2")
+	require.Equal(t, "logging", entries[0].Module.GetModule())
+	require.Equal(t, "client", entries[1].Module.GetModule())
+
+	require.Len(t, leaderboard("language=node"), 0)
+	require.Len(t, leaderboard("language=go"), 7)
+
+	for _, query := range []string{"sort=name", "limit=0", "limit=x"} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/queries/leaderboard?"+query, nil))
+		require.Equal(t, http.StatusBadRequest, recorder.Code, query)
+	}
+}
@@ -0,0 +1,182 @@
+package v1alpha
+
+import (
+	"context"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/constraints"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+)
+
+// constraintSystems maps the languages of modules to the system used to read
+// the version constraints placed on them.
+var constraintSystems = map[string]string{
+	"go":   "vgo",
+	"node": "npm",
+	"rust": "cargo",
+	"php":  "composer",
+}
+
+// resolveVersion returns the version a dependent is assumed to use given its
+// version constraint. Ranges resolve to the lowest version they allow, which
+// is the most likely to be affected. An empty string is returned when no
+// version can be determined.
+func resolveVersion(language, constraint string) string {
+	if constraints.IsVersion(constraint) {
+		return constraint
+	}
+
+	parsed, err := constraints.Parse(constraintSystems[language], constraint)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Floor()
+}
+
+// ScanReport summarizes a vulnerability scan.
+type ScanReport struct {
+	Versions   int
+	Affected   int
+	Advisories int
+}
+
+// scanTarget is a version of a module that's depended on.
+type scanTarget struct {
+	key     []byte
+	version string
+	pkg     *OSVPackage
+}
+
+// dependedVersions returns every version of a module covered by OSV that's
+// depended on.
+func dependedVersions(ctx context.Context, gs store.GraphStoreClient) ([]*scanTarget, error) {
+	modules, err := listModules(ctx, gs, &filters.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([][]byte, 0, len(modules))
+	for key := range modules {
+		keys = append(keys, []byte(key))
+	}
+
+	pairs, err := findPairs(ctx, gs.FindUpstream, keys, types.DependsType, types.ModuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*scanTarget, 0)
+	seen := make(map[string]bool)
+
+	for _, pair := range pairs {
+		node, err := Decode(pair.GetNode())
+		if err != nil {
+			return nil, err
+		}
+		module := node.(*schema.Module)
+
+		pkg := osvPackage(module)
+		if pkg == nil {
+			continue
+		}
+
+		edge, err := Decode(pair.GetEdge())
+		if err != nil {
+			return nil, err
+		}
+
+		version := resolveVersion(module.GetLanguage(), edge.(*schema.Depends).GetVersionConstraint())
+		id := string(pair.GetNode().GetK1()) + sep + version
+		if version == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		targets = append(targets, &scanTarget{
+			key:     pair.GetNode().GetK1(),
+			version: version,
+			pkg:     pkg,
+		})
+	}
+
+	return targets, nil
+}
+
+// scanVulnerabilities checks every version of a module that's depended on
+// against OSV and records the advisories affecting it.
+func scanVulnerabilities(ctx context.Context, gs store.GraphStoreClient, vulnerabilities graphstore.Vulnerabilities, osv *OSVClient) (*ScanReport, error) {
+	targets, err := dependedVersions(ctx, gs)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]*OSVQuery, len(targets))
+	for i, target := range targets {
+		queries[i] = &OSVQuery{Package: target.pkg, Version: osvVersion(target.pkg, target.version)}
+	}
+
+	results, err := osv.QueryBatch(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ScanReport{Versions: len(targets)}
+	details := make(map[string]*graphstore.Advisory)
+
+	for i, target := range targets {
+		advisories := make([]*graphstore.Advisory, 0, len(results[i]))
+		for _, id := range results[i] {
+			if _, ok := details[id]; !ok {
+				advisory, err := osv.Advisory(ctx, id)
+				if err != nil {
+					return nil, err
+				}
+				details[id] = advisory
+			}
+			advisories = append(advisories, details[id])
+		}
+
+		err := vulnerabilities.SetAdvisories(ctx, graphstore.AdvisorySourceOSV, target.key, target.version, advisories)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(advisories) > 0 {
+			report.Affected++
+		}
+	}
+
+	report.Advisories = len(details)
+	return report, nil
+}
+
+// RunVulnerabilityScan periodically checks the versions of modules that are
+// depended on against OSV and records the advisories affecting them. It runs
+// until the context is canceled.
+func RunVulnerabilityScan(ctx context.Context, gs store.GraphStoreClient, vulnerabilities graphstore.Vulnerabilities, osv *OSVClient, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := scanVulnerabilities(ctx, gs, vulnerabilities, osv)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("[service.vulnerability] failed to scan for vulnerabilities: %s", err.Error())
+			continue
+		}
+
+		logging.FromContext(ctx).Infof("[service.vulnerability] versions=%d affected=%d advisories=%d",
+			report.Versions, report.Affected, report.Advisories)
+	}
+}
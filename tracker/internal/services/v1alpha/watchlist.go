@@ -0,0 +1,98 @@
+package v1alpha
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	graphstore "github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	moduleDependents = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "depscloud_module_dependents",
+		Help: "The number of modules that directly depend on each watched module, as of the last count.",
+	}, []string{"language", "organization", "module"})
+
+	moduleDependencies = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "depscloud_module_dependencies",
+		Help: "The number of modules each watched module directly depends on, as of the last count.",
+	}, []string{"language", "organization", "module"})
+)
+
+// ParseWatchlist parses the modules to export statistics for. Each module is
+// named by its language/organization/module, like go/depscloud/api. The
+// organization may be left empty for languages without one.
+func ParseWatchlist(names []string) ([]*schema.Module, error) {
+	watchlist := make([]*schema.Module, 0, len(names))
+
+	for _, name := range names {
+		parts := strings.SplitN(name, "/", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("watched modules must be named language/organization/module, got %q", name)
+		}
+
+		watchlist = append(watchlist, &schema.Module{
+			Language:     parts[0],
+			Organization: parts[1],
+			Module:       parts[2],
+		})
+	}
+
+	return watchlist, nil
+}
+
+// RunWatchlistMetrics periodically counts the dependents and dependencies of
+// the watched modules and exports them as metrics, so alerts can fire when a
+// module, such as a deprecated library, gains dependents. Counts are
+// optional, without them the edges are read and counted. It runs until the
+// context is canceled.
+func RunWatchlistMetrics(ctx context.Context, gs store.GraphStoreClient, counts graphstore.Counts, aliases *Aliases, watchlist []*schema.Module, interval time.Duration) {
+	counter := &countService{gs: gs, counts: counts, aliases: aliases}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		recordWatchlist(ctx, counter, watchlist)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func recordWatchlist(ctx context.Context, counter *countService, watchlist []*schema.Module) {
+	for _, module := range watchlist {
+		find := &store.FindRequest{
+			Keys:      [][]byte{keyForModule(counter.aliases.module(module))},
+			EdgeTypes: []string{types.DependsType},
+			NodeTypes: []string{types.ModuleType},
+		}
+
+		labels := []string{module.GetLanguage(), module.GetOrganization(), module.GetModule()}
+
+		// a failed count keeps reporting the previous one
+		if dependents, err := counter.count(ctx, find, false, false); err != nil {
+			logging.FromContext(ctx).Errorf("[service.watchlist] failed to count dependents of %s: %s", moduleName(module), err.Error())
+		} else {
+			moduleDependents.WithLabelValues(labels...).Set(float64(dependents))
+		}
+
+		if dependencies, err := counter.count(ctx, find, true, false); err != nil {
+			logging.FromContext(ctx).Errorf("[service.watchlist] failed to count dependencies of %s: %s", moduleName(module), err.Error())
+		} else {
+			moduleDependencies.WithLabelValues(labels...).Set(float64(dependencies))
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package v1alpha
+
+import (
+	"context"
+	"testing"
+
+	"github.com/depscloud/api/v1alpha/schema"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWatchlist(t *testing.T) {
+	watchlist, err := ParseWatchlist([]string{"go/depscloud/api", "node//left-pad", "go/github.com/depscloud/api"})
+	require.Nil(t, err)
+	require.Equal(t, []*schema.Module{
+		{Language: "go", Organization: "depscloud", Module: "api"},
+		{Language: "node", Organization: "", Module: "left-pad"},
+		{Language: "go", Organization: "github.com", Module: "depscloud/api"},
+	}, watchlist)
+
+	for _, name := range []string{"api", "go/api", "/depscloud/api", "go/depscloud/"} {
+		_, err := ParseWatchlist([]string{name})
+		require.NotNil(t, err, name)
+	}
+}
+
+func TestWatchlist(t *testing.T) {
+	gs := newFakeGraphStore(t, map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c"},
+		"d": {"c"},
+	})
+
+	watchlist, err := ParseWatchlist([]string{"go/depscloud/c", "go/depscloud/a"})
+	require.Nil(t, err)
+
+	recordWatchlist(context.Background(), &countService{gs: gs}, watchlist)
+
+	require.Equal(t, float64(3), testutil.ToFloat64(moduleDependents.WithLabelValues("go", "depscloud", "c")))
+	require.Equal(t, float64(0), testutil.ToFloat64(moduleDependencies.WithLabelValues("go", "depscloud", "c")))
+	require.Equal(t, float64(0), testutil.ToFloat64(moduleDependents.WithLabelValues("go", "depscloud", "a")))
+	require.Equal(t, float64(2), testutil.ToFloat64(moduleDependencies.WithLabelValues("go", "depscloud", "a")))
+
+	// new dependents are picked up by the next count
+	added := newFakeGraphStore(t, map[string][]string{"e": {"c"}})
+	gs.nodes[string(moduleKey("e"))] = added.nodes[string(moduleKey("e"))]
+	gs.edges = append(gs.edges, added.edges...)
+
+	recordWatchlist(context.Background(), &countService{gs: gs}, watchlist)
+	require.Equal(t, float64(4), testutil.ToFloat64(moduleDependents.WithLabelValues("go", "depscloud", "c")))
+}
@@ -0,0 +1,530 @@
+package v1alpha
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/store"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/tracker/internal/types"
+
+	"github.com/ghodss/yaml"
+)
+
+// The types of events sent to webhooks.
+const (
+	// WebhookSourceIndexed is sent each time a source is tracked.
+	WebhookSourceIndexed = "source.indexed"
+	// WebhookModuleEdgesChanged is sent when tracking a source adds, changes,
+	// or removes the dependencies of one of its modules.
+	WebhookModuleEdgesChanged = "module.edges_changed"
+)
+
+// Headers sent along with each webhook. Requests to endpoints with a secret
+// are signed using the SignatureHeader.
+const (
+	WebhookEventHeader    = "X-Depscloud-Event"
+	WebhookDeliveryHeader = "X-Depscloud-Delivery"
+)
+
+var webhookEvents = map[string]bool{
+	WebhookSourceIndexed:      true,
+	WebhookModuleEdgesChanged: true,
+}
+
+// WebhookEndpoint receives events. Endpoints without events receive all of
+// them.
+type WebhookEndpoint struct {
+	URL     string            `json:"url"`
+	Secret  string            `json:"secret,omitempty"`
+	Events  []string          `json:"events,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (e *WebhookEndpoint) receives(eventType string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+
+	for _, event := range e.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookConfig declares the endpoints events are sent to. Deliveries are
+// attempted up to MaxAttempts times, backing off exponentially between
+// attempts, before they're written to the DeadLetterDir. Without a
+// DeadLetterDir, failed deliveries are dropped.
+type WebhookConfig struct {
+	Endpoints     []*WebhookEndpoint `json:"endpoints"`
+	MaxAttempts   int                `json:"maxAttempts,omitempty"`
+	DeadLetterDir string             `json:"deadLetterDir,omitempty"`
+	QueueSize     int                `json:"queueSize,omitempty"`
+}
+
+// DependencyEdge describes a depends edge of a module.
+type DependencyEdge struct {
+	Dependency        *schema.Module `json:"dependency"`
+	VersionConstraint string         `json:"versionConstraint,omitempty"`
+	Scopes            []string       `json:"scopes,omitempty"`
+}
+
+// SourceIndexed is the data of a source.indexed event.
+type SourceIndexed struct {
+	Source  *schema.Source   `json:"source"`
+	Modules []*schema.Module `json:"modules"`
+}
+
+// ModuleEdgesChanged is the data of a module.edges_changed event.
+type ModuleEdgesChanged struct {
+	Source  *schema.Source    `json:"source"`
+	Module  *schema.Module    `json:"module"`
+	Added   []*DependencyEdge `json:"added"`
+	Changed []*DependencyEdge `json:"changed"`
+	Removed []*DependencyEdge `json:"removed"`
+}
+
+// WebhookEvent is the body of a webhook.
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Tenant    string      `json:"tenant,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDelivery is an event bound for an endpoint. Deliveries that exhaust
+// their attempts are kept as dead letters until they're replayed.
+type WebhookDelivery struct {
+	ID        string        `json:"id"`
+	Endpoint  string        `json:"endpoint"`
+	Event     *WebhookEvent `json:"event"`
+	Attempts  int           `json:"attempts"`
+	LastError string        `json:"lastError,omitempty"`
+}
+
+// Webhooks sends events to external systems so they can react to changes
+// without polling. A nil Webhooks sends nothing.
+type Webhooks struct {
+	config  *WebhookConfig
+	queues  []chan *WebhookDelivery
+	backoff time.Duration
+
+	mu sync.Mutex
+}
+
+// NewWebhooks validates the configuration.
+func NewWebhooks(config *WebhookConfig) (*Webhooks, error) {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1000
+	}
+
+	queues := make([]chan *WebhookDelivery, len(config.Endpoints))
+	for i, endpoint := range config.Endpoints {
+		if endpoint == nil || endpoint.URL == "" {
+			return nil, fmt.Errorf("endpoint %d requires a url", i)
+		}
+
+		for _, event := range endpoint.Events {
+			if !webhookEvents[event] {
+				return nil, fmt.Errorf("endpoint %d has an unsupported event %s", i, event)
+			}
+		}
+
+		queues[i] = make(chan *WebhookDelivery, config.QueueSize)
+	}
+
+	if config.DeadLetterDir != "" {
+		if err := os.MkdirAll(config.DeadLetterDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Webhooks{config: config, queues: queues, backoff: time.Second}, nil
+}
+
+// LoadWebhooksFile loads an external yaml file configuring webhooks. For
+// example:
+//
+//	deadLetterDir: /var/lib/depscloud/webhooks
+//	endpoints:
+//	  - url: https://cmdb.example.com/hooks/depscloud
+//	    secret: change-me
+//	    events: [module.edges_changed]
+func LoadWebhooksFile(yamlFile string) (*Webhooks, error) {
+	contents, err := ioutil.ReadFile(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &WebhookConfig{}
+	if err := yaml.Unmarshal(contents, config); err != nil {
+		return nil, err
+	}
+
+	return NewWebhooks(config)
+}
+
+func randomID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Emit queues the event for each of the endpoints receiving it. Deliveries
+// that don't fit in the queue of an endpoint are dead lettered right away.
+func (w *Webhooks) Emit(ctx context.Context, tenant, eventType string, data interface{}) {
+	if w == nil {
+		return
+	}
+
+	event := &WebhookEvent{
+		ID:        randomID(),
+		Type:      eventType,
+		Tenant:    tenant,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+
+	for i, endpoint := range w.config.Endpoints {
+		if !endpoint.receives(eventType) {
+			continue
+		}
+
+		delivery := &WebhookDelivery{ID: fmt.Sprintf("%s-%d", event.ID, i), Endpoint: endpoint.URL, Event: event}
+
+		select {
+		case w.queues[i] <- delivery:
+		default:
+			delivery.LastError = "queue is full"
+			w.deadLetter(ctx, delivery)
+		}
+	}
+}
+
+// Run delivers queued events until the context is done. Each endpoint is
+// delivered to separately so a failing endpoint doesn't hold up the others.
+func (w *Webhooks) Run(ctx context.Context) {
+	if w == nil {
+		return
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := range w.queues {
+		wg.Add(1)
+		go func(endpoint *WebhookEndpoint, queue chan *WebhookDelivery) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case delivery := <-queue:
+					w.deliver(ctx, endpoint, delivery)
+				}
+			}
+		}(w.config.Endpoints[i], w.queues[i])
+	}
+	wg.Wait()
+}
+
+// deliver attempts the delivery until it succeeds or runs out of attempts.
+func (w *Webhooks) deliver(ctx context.Context, endpoint *WebhookEndpoint, delivery *WebhookDelivery) {
+	backoff := w.backoff
+	for delivery.Attempts < w.config.MaxAttempts {
+		if delivery.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				w.deadLetter(ctx, delivery)
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		delivery.Attempts++
+		err := w.send(ctx, endpoint, delivery)
+		if err == nil {
+			return
+		}
+
+		delivery.LastError = err.Error()
+		logging.FromContext(ctx).Warnf("[service.webhook] attempt %d of %s to %s failed: %s",
+			delivery.Attempts, delivery.ID, endpoint.URL, err.Error())
+	}
+
+	w.deadLetter(ctx, delivery)
+}
+
+func (w *Webhooks) send(ctx context.Context, endpoint *WebhookEndpoint, delivery *WebhookDelivery) error {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(endpoint.Headers)+3)
+	for key, value := range endpoint.Headers {
+		headers[key] = value
+	}
+
+	headers[WebhookEventHeader] = delivery.Event.Type
+	headers[WebhookDeliveryHeader] = delivery.ID
+	if endpoint.Secret != "" {
+		headers[SignatureHeader] = sign(endpoint.Secret, body)
+	}
+
+	return post(ctx, endpoint.URL, body, headers)
+}
+
+func (w *Webhooks) deadLetterPath(delivery *WebhookDelivery) string {
+	return filepath.Join(w.config.DeadLetterDir, delivery.ID+".json")
+}
+
+// deadLetter keeps a failed delivery so it can be replayed.
+func (w *Webhooks) deadLetter(ctx context.Context, delivery *WebhookDelivery) {
+	if w.config.DeadLetterDir == "" {
+		logging.FromContext(ctx).Errorf("[service.webhook] dropping %s to %s: %s", delivery.ID, delivery.Endpoint, delivery.LastError)
+		return
+	}
+
+	contents, err := json.Marshal(delivery)
+	if err == nil {
+		w.mu.Lock()
+		err = ioutil.WriteFile(w.deadLetterPath(delivery), contents, 0644)
+		w.mu.Unlock()
+	}
+
+	if err != nil {
+		logging.FromContext(ctx).Errorf("[service.webhook] failed to dead letter %s: %s", delivery.ID, err.Error())
+		return
+	}
+
+	logging.FromContext(ctx).Warnf("[service.webhook] dead lettered %s to %s: %s", delivery.ID, delivery.Endpoint, delivery.LastError)
+}
+
+// DeadLetters returns the failed deliveries, oldest first.
+func (w *Webhooks) DeadLetters() ([]*WebhookDelivery, error) {
+	deliveries := make([]*WebhookDelivery, 0)
+	if w.config.DeadLetterDir == "" {
+		return deliveries, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	files, err := ioutil.ReadDir(w.config.DeadLetterDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(w.config.DeadLetterDir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		delivery := &WebhookDelivery{}
+		if err := json.Unmarshal(contents, delivery); err != nil {
+			return nil, fmt.Errorf("failed to read dead letter %s: %s", file.Name(), err.Error())
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].Event.Timestamp.Before(deliveries[j].Event.Timestamp)
+	})
+
+	return deliveries, nil
+}
+
+// ReplayReport summarizes a replay of the dead letters.
+type ReplayReport struct {
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+}
+
+// Replay attempts each dead letter once more. Delivered letters are removed
+// and failed ones are kept with their latest error.
+func (w *Webhooks) Replay(ctx context.Context) (*ReplayReport, error) {
+	deliveries, err := w.DeadLetters()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make(map[string]*WebhookEndpoint, len(w.config.Endpoints))
+	for _, endpoint := range w.config.Endpoints {
+		endpoints[endpoint.URL] = endpoint
+	}
+
+	report := &ReplayReport{}
+	for _, delivery := range deliveries {
+		endpoint, ok := endpoints[delivery.Endpoint]
+		if !ok {
+			err = fmt.Errorf("endpoint is no longer configured")
+		} else {
+			delivery.Attempts++
+			err = w.send(ctx, endpoint, delivery)
+		}
+
+		if err != nil {
+			report.Failed++
+			delivery.LastError = err.Error()
+			w.deadLetter(ctx, delivery)
+			continue
+		}
+
+		report.Delivered++
+
+		w.mu.Lock()
+		err = os.Remove(w.deadLetterPath(delivery))
+		w.mu.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func decodeModules(sets ...map[string]*store.GraphItem) (map[string]*schema.Module, error) {
+	modules := make(map[string]*schema.Module)
+	for _, set := range sets {
+		for _, item := range set {
+			if item.GetGraphItemType() != types.ModuleType {
+				continue
+			}
+
+			module, err := Decode(item)
+			if err != nil {
+				return nil, err
+			}
+			modules[string(item.GetK1())] = module.(*schema.Module)
+		}
+	}
+	return modules, nil
+}
+
+// emitTracked emits the events of tracking a source: source.indexed along
+// with module.edges_changed for each module whose depends edges changed.
+func (w *Webhooks) emitTracked(ctx context.Context, tenant string, source *schema.Source, currentSet, proposedSet map[string]*store.GraphItem, toDelete []*store.GraphItem) error {
+	if w == nil {
+		return nil
+	}
+
+	modules, err := decodeModules(currentSet, proposedSet)
+	if err != nil {
+		return err
+	}
+
+	sourceKey := keyForSource(source)
+	indexed := &SourceIndexed{Source: source, Modules: make([]*schema.Module, 0)}
+	changes := make(map[string]*ModuleEdgesChanged)
+
+	edgeChange := func(item *store.GraphItem) (*ModuleEdgesChanged, *DependencyEdge, error) {
+		decoded, err := Decode(item)
+		if err != nil {
+			return nil, nil, err
+		}
+		depends := decoded.(*schema.Depends)
+
+		module := string(item.GetK1())
+		if changes[module] == nil {
+			changes[module] = &ModuleEdgesChanged{
+				Source:  source,
+				Module:  modules[module],
+				Added:   make([]*DependencyEdge, 0),
+				Changed: make([]*DependencyEdge, 0),
+				Removed: make([]*DependencyEdge, 0),
+			}
+		}
+
+		return changes[module], &DependencyEdge{
+			Dependency:        modules[string(item.GetK2())],
+			VersionConstraint: depends.GetVersionConstraint(),
+			Scopes:            depends.GetScopes(),
+		}, nil
+	}
+
+	for key, item := range proposedSet {
+		switch item.GetGraphItemType() {
+		case types.ManagesType:
+			if string(item.GetK1()) == string(sourceKey) {
+				indexed.Modules = append(indexed.Modules, modules[string(item.GetK2())])
+			}
+		case types.DependsType:
+			current, ok := currentSet[key]
+			if ok && string(current.GetGraphItemData()) == string(item.GetGraphItemData()) {
+				continue
+			}
+
+			change, edge, err := edgeChange(item)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				change.Changed = append(change.Changed, edge)
+			} else {
+				change.Added = append(change.Added, edge)
+			}
+		}
+	}
+
+	for _, item := range toDelete {
+		if item.GetGraphItemType() != types.DependsType {
+			continue
+		}
+
+		change, edge, err := edgeChange(item)
+		if err != nil {
+			return err
+		}
+		change.Removed = append(change.Removed, edge)
+	}
+
+	sort.Slice(indexed.Modules, func(i, j int) bool {
+		return moduleName(indexed.Modules[i]) < moduleName(indexed.Modules[j])
+	})
+
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return moduleName(changes[keys[i]].Module) < moduleName(changes[keys[j]].Module)
+	})
+
+	w.Emit(ctx, tenant, WebhookSourceIndexed, indexed)
+	for _, key := range keys {
+		change := changes[key]
+		for _, edges := range [][]*DependencyEdge{change.Added, change.Changed, change.Removed} {
+			sort.Slice(edges, func(i, j int) bool {
+				return moduleName(edges[i].Dependency) < moduleName(edges[j].Dependency)
+			})
+		}
+		w.Emit(ctx, tenant, WebhookModuleEdgesChanged, change)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package v1alpha
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/depscloud/api/v1alpha/deps"
+	"github.com/depscloud/api/v1alpha/schema"
+	"github.com/depscloud/api/v1alpha/tracker"
+
+	"github.com/stretchr/testify/require"
+)
+
+type received struct {
+	headers http.Header
+	event   map[string]interface{}
+}
+
+func TestWebhooks_track(t *testing.T) {
+	events := make(chan *received, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.Nil(t, err)
+		require.Equal(t, sign("secret", body), r.Header.Get(SignatureHeader))
+
+		event := make(map[string]interface{})
+		require.Nil(t, json.Unmarshal(body, &event))
+		events <- &received{headers: r.Header, event: event}
+	}))
+	defer server.Close()
+
+	webhooks, err := NewWebhooks(&WebhookConfig{
+		Endpoints: []*WebhookEndpoint{{URL: server.URL, Secret: "secret"}},
+	})
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go webhooks.Run(ctx)
+
+	svc := &sourceService{gs: newFakeGraphStore(t, nil), webhooks: webhooks}
+	source := &schema.Source{Url: "https://github.com/depscloud/depscloud.git", Kind: "repository"}
+
+	track := func(files ...*deps.DependencyManagementFile) {
+		_, err := svc.Track(ctx, &tracker.SourceRequest{Source: source, ManagementFiles: files})
+		require.Nil(t, err)
+	}
+
+	next := func() *received {
+		select {
+		case r := <-events:
+			return r
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no webhook was received")
+			return nil
+		}
+	}
+
+	track(managementFile("a", "c"), managementFile("b"))
+
+	r := next()
+	require.Equal(t, WebhookSourceIndexed, r.headers.Get(WebhookEventHeader))
+	require.Equal(t, r.event["id"].(string)+"-0", r.headers.Get(WebhookDeliveryHeader))
+	data := r.event["data"].(map[string]interface{})
+	require.Equal(t, source.GetUrl(), data["source"].(map[string]interface{})["url"])
+	require.Len(t, data["modules"], 2)
+
+	r = next()
+	require.Equal(t, WebhookModuleEdgesChanged, r.event["type"])
+	data = r.event["data"].(map[string]interface{})
+	require.Equal(t, "a", data["module"].(map[string]interface{})["module"])
+	require.Len(t, data["added"], 1)
+	require.Len(t, data["removed"], 0)
+
+	// unchanged modules don't send module.edges_changed
+	track(managementFile("a", "d"), managementFile("b"))
+
+	require.Equal(t, WebhookSourceIndexed, next().event["type"])
+	r = next()
+	require.Equal(t, WebhookModuleEdgesChanged, r.event["type"])
+	data = r.event["data"].(map[string]interface{})
+	require.Equal(t, "d", data["added"].([]interface{})[0].(map[string]interface{})["dependency"].(map[string]interface{})["module"])
+	require.Equal(t, "c", data["removed"].([]interface{})[0].(map[string]interface{})["dependency"].(map[string]interface{})["module"])
+
+	select {
+	case r := <-events:
+		require.FailNow(t, "unexpected webhook", r.event["type"])
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWebhooks_deadLetters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead-letters")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var requests, failures int32
+	failures = 100
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	webhooks, err := NewWebhooks(&WebhookConfig{
+		Endpoints: []*WebhookEndpoint{
+			{URL: server.URL, Events: []string{WebhookSourceIndexed}},
+		},
+		MaxAttempts:   3,
+		DeadLetterDir: dir,
+	})
+	require.Nil(t, err)
+	webhooks.backoff = time.Millisecond
+
+	ctx := context.Background()
+	endpoint := webhooks.config.Endpoints[0]
+
+	// deliveries are retried until they succeed
+	atomic.StoreInt32(&failures, 2)
+	delivery := &WebhookDelivery{ID: "1-0", Endpoint: server.URL, Event: &WebhookEvent{ID: "1", Type: WebhookSourceIndexed}}
+	webhooks.deliver(ctx, endpoint, delivery)
+	require.Equal(t, 3, delivery.Attempts)
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	letters, err := webhooks.DeadLetters()
+	require.Nil(t, err)
+	require.Len(t, letters, 0)
+
+	// and dead lettered once they run out of attempts
+	atomic.StoreInt32(&failures, 3)
+	webhooks.deliver(ctx, endpoint, &WebhookDelivery{ID: "2-0", Endpoint: server.URL, Event: &WebhookEvent{ID: "2", Type: WebhookSourceIndexed}})
+
+	// events the endpoint doesn't receive aren't queued
+	webhooks.Emit(context.Background(), "", WebhookModuleEdgesChanged, nil)
+	require.Len(t, webhooks.queues[0], 0)
+
+	mux := http.NewServeMux()
+	RegisterWebhookService(mux, webhooks)
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1alpha/webhooks/dead-letters", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	response := &DeadLettersResponse{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(response))
+	require.Len(t, response.Deliveries, 1)
+	require.Equal(t, "2-0", response.Deliveries[0].ID)
+	require.Equal(t, 3, response.Deliveries[0].Attempts)
+	require.Contains(t, response.Deliveries[0].LastError, "502")
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1alpha/webhooks/dead-letters/replay", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	report := &ReplayReport{}
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(report))
+	require.Equal(t, &ReplayReport{Delivered: 1}, report)
+
+	letters, err = webhooks.DeadLetters()
+	require.Nil(t, err)
+	require.Len(t, letters, 0)
+}
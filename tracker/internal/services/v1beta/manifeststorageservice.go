@@ -7,6 +7,8 @@ import (
 
 	"github.com/depscloud/api/v1beta"
 	"github.com/depscloud/api/v1beta/graphstore"
+	"github.com/depscloud/depscloud/internal/constraints"
+	"github.com/depscloud/depscloud/internal/scopes"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -22,6 +24,19 @@ const (
 	ArtifactDefaultKind   DefaultKind = "artifact"
 )
 
+const (
+	// ScopeLabel is the label used to persist the canonical scope of a dependency.
+	ScopeLabel = "scope"
+
+	// ConstraintLabel is the label used to persist the canonical version
+	// constraint of a dependency.
+	ConstraintLabel = "constraint"
+
+	// ResolvedLabel is the label used to persist the version a lockfile
+	// resolved a dependency to.
+	ResolvedLabel = "resolved"
+)
+
 func RegisterManifestStorageServiceServer(server *grpc.Server, graphStore graphstore.GraphStoreClient) {
 	v1beta.RegisterManifestStorageServiceServer(server, &manifestStorageService{
 		graphStore: graphStore,
@@ -77,10 +92,25 @@ func (m *manifestStorageService) GetProposed(request *v1beta.StoreRequest) ([]*g
 				Name:     manifestDependency.GetName(),
 			})
 
+			dependencyScopes := scopes.Normalize(manifestDependency.GetScopes())
+
+			labels := map[string]string{
+				ScopeLabel: scopes.Classify(dependencyScopes),
+			}
+
+			if constraint := constraints.Normalize(system, manifestDependency.GetVersionConstraint()); constraint != "" {
+				labels[ConstraintLabel] = constraint
+			}
+
+			if resolved, ok := scopes.Resolved(dependencyScopes); ok {
+				labels[ResolvedLabel] = resolved
+			}
+
 			moduleDependency, _ := newEdge(&v1beta.ModuleDependency{
 				Ref:               ref,
 				VersionConstraint: manifestDependency.GetVersionConstraint(),
-				Scopes:            manifestDependency.GetScopes(),
+				Scopes:            dependencyScopes,
+				Labels:            labels,
 			})
 
 			moduleDependency.FromKey = module.Key
@@ -0,0 +1,61 @@
+package sqlpool
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Config controls the connection pool of the sql backed graph stores. Zero
+// values leave the database/sql defaults in place.
+type Config struct {
+	MaxOpenConns     int
+	MaxIdleConns     int
+	ConnMaxLifetime  time.Duration
+	StatementTimeout time.Duration
+}
+
+// DefaultConfig returns limits that keep a tracker replica from exhausting
+// the connections of a typical database during large indexing runs.
+func DefaultConfig() *Config {
+	return &Config{
+		MaxOpenConns:     20,
+		MaxIdleConns:     10,
+		ConnMaxLifetime:  30 * time.Minute,
+		StatementTimeout: time.Minute,
+	}
+}
+
+// Apply configures the connection pool of the db. It's safe to call on a nil
+// config.
+func (c *Config) Apply(db *sql.DB) {
+	if c == nil || db == nil {
+		return
+	}
+
+	if c.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(c.MaxOpenConns)
+	}
+
+	if c.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(c.MaxIdleConns)
+	}
+
+	if c.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+}
+
+// WithTimeout bounds the context by the statement timeout. It's safe to call
+// on a nil config. Nil contexts are treated as the background context since
+// some callers predate the context aware queries.
+func (c *Config) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if c == nil || c.StatementTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.StatementTimeout)
+}
@@ -0,0 +1,49 @@
+package sqlpool_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/depscloud/depscloud/tracker/internal/sqlpool"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Apply(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.Nil(t, err)
+	defer db.Close()
+
+	config := &sqlpool.Config{MaxOpenConns: 3}
+	config.Apply(db)
+
+	require.Equal(t, 3, db.Stats().MaxOpenConnections)
+
+	// nil configs leave the defaults in place
+	var none *sqlpool.Config
+	none.Apply(db)
+
+	require.Equal(t, 3, db.Stats().MaxOpenConnections)
+}
+
+func TestConfig_WithTimeout(t *testing.T) {
+	config := &sqlpool.Config{StatementTimeout: time.Second}
+
+	ctx, cancel := config.WithTimeout(context.Background())
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+
+	var none *sqlpool.Config
+	ctx, cancel = none.WithTimeout(context.Background())
+	defer cancel()
+
+	_, ok = ctx.Deadline()
+	require.False(t, ok)
+}
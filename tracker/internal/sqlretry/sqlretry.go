@@ -0,0 +1,47 @@
+package sqlretry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SerializationFailure is the SQLSTATE returned when a transaction is aborted
+// due to contention with another transaction and can be retried.
+const SerializationFailure = "40001"
+
+// CockroachDBMaxRetries is the number of times a transaction is retried after
+// cockroachdb aborts it due to contention.
+const CockroachDBMaxRetries = 5
+
+// initialBackoff is how long the first retry waits, doubling with each retry.
+var initialBackoff = 10 * time.Millisecond
+
+// IsRetryable returns true when the error is a serialization failure. The
+// check relies on the SQLState method exposed by the postgres driver errors.
+func IsRetryable(err error) bool {
+	var state interface{ SQLState() string }
+	return errors.As(err, &state) && state.SQLState() == SerializationFailure
+}
+
+// Do calls fn until it succeeds or fails with an error that can't be retried.
+// Serialization failures are retried up to maxRetries times with an
+// exponential backoff. fn must run the whole transaction, since an aborted
+// transaction can't be resumed.
+func Do(ctx context.Context, maxRetries int, fn func() error) error {
+	backoff := initialBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,54 @@
+package sqlretry_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/depscloud/depscloud/tracker/internal/sqlretry"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stateError string
+
+func (e stateError) Error() string    { return "sql error " + string(e) }
+func (e stateError) SQLState() string { return string(e) }
+
+func TestIsRetryable(t *testing.T) {
+	require.True(t, sqlretry.IsRetryable(stateError("40001")))
+	require.True(t, sqlretry.IsRetryable(fmt.Errorf("commit: %w", stateError("40001"))))
+	require.False(t, sqlretry.IsRetryable(stateError("23505")))
+	require.False(t, sqlretry.IsRetryable(fmt.Errorf("connection refused")))
+}
+
+func TestDo(t *testing.T) {
+	calls := 0
+	err := sqlretry.Do(context.Background(), 2, func() error {
+		calls++
+		if calls < 3 {
+			return stateError(sqlretry.SerializationFailure)
+		}
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 3, calls)
+
+	// retries are bounded
+	calls = 0
+	err = sqlretry.Do(context.Background(), 2, func() error {
+		calls++
+		return stateError(sqlretry.SerializationFailure)
+	})
+	require.True(t, sqlretry.IsRetryable(err))
+	require.Equal(t, 3, calls)
+
+	// other errors aren't retried
+	calls = 0
+	err = sqlretry.Do(context.Background(), 2, func() error {
+		calls++
+		return stateError("23505")
+	})
+	require.NotNil(t, err)
+	require.Equal(t, 1, calls)
+}
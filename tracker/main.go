@@ -5,21 +5,34 @@ import (
 	"net"
 	"os"
 	"strings"
+	"time"
 
 	apiv1alpha "github.com/depscloud/api/v1alpha/store"
 	apiv1beta "github.com/depscloud/api/v1beta/graphstore"
+	"github.com/depscloud/depscloud/internal/audit"
+	"github.com/depscloud/depscloud/internal/config"
+	"github.com/depscloud/depscloud/internal/diagnostics"
+	"github.com/depscloud/depscloud/internal/features"
+	"github.com/depscloud/depscloud/internal/filters"
+	"github.com/depscloud/depscloud/internal/logging"
+	"github.com/depscloud/depscloud/internal/middleware"
 	"github.com/depscloud/depscloud/internal/mux"
+	"github.com/depscloud/depscloud/internal/policies"
+	"github.com/depscloud/depscloud/internal/rbac"
+	"github.com/depscloud/depscloud/internal/registries"
+	"github.com/depscloud/depscloud/internal/telemetry"
+	"github.com/depscloud/depscloud/internal/tenants"
 	"github.com/depscloud/depscloud/tracker/internal/checks"
+	"github.com/depscloud/depscloud/tracker/internal/eventbus"
 	"github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
 	"github.com/depscloud/depscloud/tracker/internal/graphstore/v1beta"
 	svcsv1alpha "github.com/depscloud/depscloud/tracker/internal/services/v1alpha"
 	svcsv1beta "github.com/depscloud/depscloud/tracker/internal/services/v1beta"
+	"github.com/depscloud/depscloud/tracker/internal/sqlpool"
 
 	_ "github.com/go-sql-driver/mysql"
 
-	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
-	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 
 	_ "github.com/jackc/pgx/v4"
 	_ "github.com/jackc/pgx/v4/stdlib"
@@ -40,39 +53,50 @@ var date string
 
 const sockAddr = "localhost:47274"
 
-func startGraphStore(driver, address, readOnlyAddress string) error {
-	grpcServer := grpc.NewServer()
+// startGraphStore starts the graph stores for the driver. The returned v1alpha
+// graph store is nil when it isn't supported by the driver, as is the case for
+// graph databases. It's returned so the features that aren't part of the
+// store api, like the history, can be used directly.
+func startGraphStore(driver, address string, readOnlyAddresses []string, pool *sqlpool.Config, cache *v1alpha.CacheConfig, bus *eventbus.Bus) (apiv1alpha.GraphStoreServer, error) {
+	grpcServer := middleware.NewServer()
 
 	// v1beta
-	v1betaDriver, err := v1beta.Resolve(driver, address, readOnlyAddress)
+	v1betaDriver, err := v1beta.ResolveWithPool(pool, driver, address, readOnlyAddresses...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	apiv1beta.RegisterGraphStoreServer(grpcServer, &v1beta.GraphStoreServer{Driver: v1betaDriver})
 
 	// v1alpha
-	v1alphaGraphStore, err := v1alpha.NewGraphStoreFor(driver, address, readOnlyAddress)
-	if err != nil {
-		return err
+	var v1alphaGraphStore apiv1alpha.GraphStoreServer
+	if _, err := v1alpha.ResolveDriverName(driver); err != nil {
+		logrus.Warnf("[graphstore] v1alpha is not supported by the %s driver, only v1beta apis are available", driver)
+	} else {
+		v1alphaGraphStore, err = v1alpha.NewGraphStoreWithPool(pool, driver, address, readOnlyAddresses...)
+		if err != nil {
+			return nil, err
+		}
+		cache.Apply(v1alphaGraphStore)
+		v1alpha.PublishMutations(v1alphaGraphStore, bus)
+		apiv1alpha.RegisterGraphStoreServer(grpcServer, v1alphaGraphStore)
 	}
-	apiv1alpha.RegisterGraphStoreServer(grpcServer, v1alphaGraphStore)
 
 	// listen and serve
 	logrus.Infof("[graphstore] starting grpc on %s", sockAddr)
 	listener, err := net.Listen("tcp", sockAddr)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	go grpcServer.Serve(listener)
-	return nil
+	return v1alphaGraphStore, nil
 }
 
-func registerV1Alpha(v1alphaClient apiv1alpha.GraphStoreClient, server *grpc.Server) {
-	svcsv1alpha.RegisterDependencyService(server, v1alphaClient)
-	svcsv1alpha.RegisterModuleService(server, v1alphaClient)
-	svcsv1alpha.RegisterSourceService(server, v1alphaClient)
-	svcsv1alpha.RegisterSearchService(server, v1alphaClient)
+func registerV1Alpha(v1alphaClient apiv1alpha.GraphStoreClient, replacements v1alpha.Replacements, server *grpc.Server, paging *svcsv1alpha.Paging, searchWindow int, aliases *svcsv1alpha.Aliases, policies *policies.Config, notifier *svcsv1alpha.Notifier, webhooks *svcsv1alpha.Webhooks) {
+	svcsv1alpha.RegisterDependencyService(server, v1alphaClient, paging, aliases)
+	svcsv1alpha.RegisterModuleService(server, v1alphaClient, paging, aliases)
+	svcsv1alpha.RegisterSourceService(server, v1alphaClient, replacements, paging, aliases, policies, notifier, webhooks)
+	svcsv1alpha.RegisterSearchService(server, v1alphaClient, searchWindow, aliases)
 }
 
 func registerV1Beta(v1betaClient apiv1beta.GraphStoreClient, server *grpc.Server) {
@@ -81,11 +105,55 @@ func registerV1Beta(v1betaClient apiv1beta.GraphStoreClient, server *grpc.Server
 }
 
 type trackerConfig struct {
-	httpPort               int
-	grpcPort               int
+	server                 *config.Server
 	storageDriver          string
 	storageAddress         string
 	storageReadOnlyAddress string
+	storageReplicaAddress  *cli.StringSlice
+	pool                   *sqlpool.Config
+	cache                  *v1alpha.CacheConfig
+	autoMigrate            bool
+	paging                 *svcsv1alpha.Paging
+	maxTraversalDepth      int
+	searchWindow           int
+	cycleDetection         time.Duration
+	cycleDetectionFilter   *filters.Filter
+	retention              time.Duration
+	retentionPolicy        *v1alpha.RetentionPolicy
+	retentionDryRun        bool
+	cardinalityMetrics     time.Duration
+	countRebuild           time.Duration
+	watchlist              *cli.StringSlice
+	watchlistMetrics       time.Duration
+	vulnerabilityScan      time.Duration
+	osvURL                 string
+	advisoryFeeds          time.Duration
+	advisoryMirror         string
+	ghsaURL                string
+	ghsaToken              string
+	nvdURL                 string
+	nvdAPIKey              string
+	registryEnrichment     time.Duration
+	registryURLs           *cli.StringSlice
+	endOfLifeFile          string
+	endOfLifeURL           string
+	endOfLifeSync          time.Duration
+	aliasesFile            string
+	licensePolicyFile      string
+	notificationsFile      string
+	webhooksFile           string
+	reportsFile            string
+	snapshotLocations      *cli.StringSlice
+	tenancy                *tenants.Config
+	policies               *policies.Config
+	rbac                   *rbac.Config
+	audit                  *audit.Config
+	eventBus               *eventbus.Config
+	telemetry              *telemetry.Config
+	logging                *logging.Config
+	middleware             *middleware.Config
+	diagnostics            *diagnostics.Config
+	features               *features.Config
 }
 
 var description = strings.TrimSpace(`
@@ -96,14 +164,431 @@ var description = strings.TrimSpace(`
 func main() {
 	version := mux.Version{Version: version, Commit: commit, Date: date}
 	cfg := &trackerConfig{
-		httpPort:               8080,
-		grpcPort:               8090,
+		server:                 &config.Server{HTTPPort: 8080, GRPCPort: 8090, PortAlias: config.PortGRPC},
 		storageDriver:          "sqlite",
 		storageAddress:         "file::memory:?cache=shared",
 		storageReadOnlyAddress: "",
+		storageReplicaAddress:  cli.NewStringSlice(),
+		pool:                   sqlpool.DefaultConfig(),
+		cache:                  &v1alpha.CacheConfig{TTL: time.Minute},
+		autoMigrate:            true,
+		paging:                 svcsv1alpha.DefaultPaging(),
+		maxTraversalDepth:      svcsv1alpha.DefaultMaxDepth,
+		searchWindow:           svcsv1alpha.DefaultSearchWindow,
+		cycleDetection:         0,
+		cycleDetectionFilter:   &filters.Filter{},
+		retention:              0,
+		retentionPolicy:        &v1alpha.RetentionPolicy{},
+		retentionDryRun:        false,
+		cardinalityMetrics:     0,
+		countRebuild:           0,
+		watchlist:              cli.NewStringSlice(),
+		watchlistMetrics:       time.Minute,
+		vulnerabilityScan:      0,
+		osvURL:                 svcsv1alpha.DefaultOSVURL,
+		advisoryFeeds:          0,
+		advisoryMirror:         "",
+		ghsaURL:                svcsv1alpha.DefaultGHSAURL,
+		ghsaToken:              "",
+		nvdURL:                 svcsv1alpha.DefaultNVDURL,
+		nvdAPIKey:              "",
+		registryEnrichment:     0,
+		registryURLs:           cli.NewStringSlice(),
+		endOfLifeFile:          "",
+		endOfLifeURL:           svcsv1alpha.DefaultEndOfLifeURL,
+		endOfLifeSync:          24 * time.Hour,
+		aliasesFile:            "",
+		licensePolicyFile:      "",
+		notificationsFile:      "",
+		webhooksFile:           "",
+		reportsFile:            "",
+		snapshotLocations:      cli.NewStringSlice(),
 	}
 
-	tlsConfig := &mux.TLSConfig{}
+	var serverFlags []cli.Flag
+	cfg.server, serverFlags = config.WithServerFlags(cfg.server)
+
+	var tenancyFlags []cli.Flag
+	cfg.tenancy, tenancyFlags = tenants.WithFlags(&tenants.Config{Mode: tenants.ModeNone})
+
+	var policyFlags []cli.Flag
+	cfg.policies, policyFlags = policies.WithFlags(&policies.Config{
+		TrackPolicy: policies.DefaultTrackPolicy,
+		QueryPolicy: policies.DefaultQueryPolicy,
+		Timeout:     5 * time.Second,
+	})
+
+	var rbacFlags []cli.Flag
+	cfg.rbac, rbacFlags = rbac.WithFlags(&rbac.Config{
+		Mode:            rbac.ModeNone,
+		DefaultRole:     string(rbac.RoleViewer),
+		RefreshInterval: 30 * time.Second,
+	})
+
+	var auditFlags []cli.Flag
+	cfg.audit, auditFlags = audit.WithFlags(&audit.Config{
+		Sink:          audit.SinkNone,
+		BufferSize:    1000,
+		FlushInterval: time.Second,
+	})
+
+	var eventBusFlags []cli.Flag
+	cfg.eventBus, eventBusFlags = eventbus.WithFlags(&eventbus.Config{
+		Topic: "depscloud.graph.mutations",
+	})
+
+	var telemetryFlags []cli.Flag
+	cfg.telemetry, telemetryFlags = telemetry.WithFlags(telemetry.DefaultConfig())
+
+	var loggingFlags []cli.Flag
+	cfg.logging, loggingFlags = logging.WithFlags(logging.DefaultConfig())
+
+	var middlewareFlags []cli.Flag
+	cfg.middleware, middlewareFlags = middleware.WithFlags(middleware.DefaultConfig())
+
+	var diagnosticsFlags []cli.Flag
+	cfg.diagnostics, diagnosticsFlags = diagnostics.WithFlags(&diagnostics.Config{})
+
+	var featureFlags []cli.Flag
+	cfg.features, featureFlags = features.WithFlags(&features.Config{})
+
+	flags := append(serverFlags, []cli.Flag{
+		&cli.StringFlag{
+			Name:        "storage-driver",
+			Usage:       "the driver used to configure the storage tier",
+			Value:       cfg.storageDriver,
+			Destination: &cfg.storageDriver,
+			EnvVars:     []string{"STORAGE_DRIVER"},
+		},
+		&cli.StringFlag{
+			Name:        "storage-address",
+			Usage:       "the address of the storage tier",
+			Value:       cfg.storageAddress,
+			Destination: &cfg.storageAddress,
+			EnvVars:     []string{"STORAGE_ADDRESS"},
+		},
+		&cli.StringFlag{
+			Name:        "storage-readonly-address",
+			Usage:       "the readonly address of the storage tier",
+			Value:       cfg.storageReadOnlyAddress,
+			Destination: &cfg.storageReadOnlyAddress,
+			EnvVars:     []string{"STORAGE_READ_ONLY_ADDRESS"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "storage-replica-address",
+			Usage:       "the address of an additional read replica, reads are balanced across the readonly address and replicas",
+			Destination: cfg.storageReplicaAddress,
+			EnvVars:     []string{"STORAGE_REPLICA_ADDRESSES"},
+		},
+		&cli.BoolFlag{
+			Name:        "auto-migrate",
+			Usage:       "apply pending schema migrations on startup, when disabled the tracker refuses to start until tracker migrate up is run",
+			Value:       cfg.autoMigrate,
+			Destination: &cfg.autoMigrate,
+			EnvVars:     []string{"AUTO_MIGRATE"},
+		},
+		&cli.IntFlag{
+			Name:        "storage-max-open-conns",
+			Usage:       "the maximum number of open connections to each sql database",
+			Value:       cfg.pool.MaxOpenConns,
+			Destination: &cfg.pool.MaxOpenConns,
+			EnvVars:     []string{"STORAGE_MAX_OPEN_CONNS"},
+		},
+		&cli.IntFlag{
+			Name:        "storage-max-idle-conns",
+			Usage:       "the maximum number of idle connections kept for each sql database",
+			Value:       cfg.pool.MaxIdleConns,
+			Destination: &cfg.pool.MaxIdleConns,
+			EnvVars:     []string{"STORAGE_MAX_IDLE_CONNS"},
+		},
+		&cli.DurationFlag{
+			Name:        "storage-conn-max-lifetime",
+			Usage:       "the maximum amount of time a sql connection may be reused for",
+			Value:       cfg.pool.ConnMaxLifetime,
+			Destination: &cfg.pool.ConnMaxLifetime,
+			EnvVars:     []string{"STORAGE_CONN_MAX_LIFETIME"},
+		},
+		&cli.DurationFlag{
+			Name:        "storage-statement-timeout",
+			Usage:       "the maximum amount of time a sql query or write may take, 0 disables the timeout",
+			Value:       cfg.pool.StatementTimeout,
+			Destination: &cfg.pool.StatementTimeout,
+			EnvVars:     []string{"STORAGE_STATEMENT_TIMEOUT"},
+		},
+		&cli.IntFlag{
+			Name:        "find-cache-size",
+			Usage:       "the number of dependents and dependencies lookups to cache in memory, 0 disables the cache",
+			Value:       cfg.cache.Size,
+			Destination: &cfg.cache.Size,
+			EnvVars:     []string{"FIND_CACHE_SIZE"},
+		},
+		&cli.DurationFlag{
+			Name:        "find-cache-ttl",
+			Usage:       "how long a cached lookup is served, bounds how stale results are when other replicas write to the graph, 0 keeps them until evicted",
+			Value:       cfg.cache.TTL,
+			Destination: &cfg.cache.TTL,
+			EnvVars:     []string{"FIND_CACHE_TTL"},
+		},
+		&cli.IntFlag{
+			Name:        "default-page-size",
+			Usage:       "the page size used when a client doesn't ask for one, 0 returns all dependents, dependencies, and managed items",
+			Value:       cfg.paging.DefaultPageSize,
+			Destination: &cfg.paging.DefaultPageSize,
+			EnvVars:     []string{"DEFAULT_PAGE_SIZE"},
+		},
+		&cli.IntFlag{
+			Name:        "max-page-size",
+			Usage:       "the largest page size a client can ask for",
+			Value:       cfg.paging.MaxPageSize,
+			Destination: &cfg.paging.MaxPageSize,
+			EnvVars:     []string{"MAX_PAGE_SIZE"},
+		},
+		&cli.IntFlag{
+			Name:        "max-traversal-depth",
+			Usage:       "the maximum number of edges followed by transitive queries",
+			Value:       cfg.maxTraversalDepth,
+			Destination: &cfg.maxTraversalDepth,
+			EnvVars:     []string{"MAX_TRAVERSAL_DEPTH"},
+		},
+		&cli.IntFlag{
+			Name:        "search-window",
+			Usage:       "the number of requests on a search stream processed at once, further requests are read as responses are sent",
+			Value:       cfg.searchWindow,
+			Destination: &cfg.searchWindow,
+			EnvVars:     []string{"SEARCH_WINDOW"},
+		},
+		&cli.DurationFlag{
+			Name:        "cardinality-metrics-interval",
+			Usage:       "how often to count the items in the graph by type and language for the metrics, 0 disables the count",
+			Value:       cfg.cardinalityMetrics,
+			Destination: &cfg.cardinalityMetrics,
+			EnvVars:     []string{"CARDINALITY_METRICS_INTERVAL"},
+		},
+		&cli.DurationFlag{
+			Name:        "count-rebuild-interval",
+			Usage:       "how often to recompute the dependent and dependency counts of modules from the graph, 0 disables the rebuild",
+			Value:       cfg.countRebuild,
+			Destination: &cfg.countRebuild,
+			EnvVars:     []string{"COUNT_REBUILD_INTERVAL"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "watchlist-module",
+			Usage:       "a module, named language/organization/module, whose dependents and dependencies are exported as metrics",
+			Destination: cfg.watchlist,
+			EnvVars:     []string{"WATCHLIST_MODULES"},
+		},
+		&cli.DurationFlag{
+			Name:        "watchlist-metrics-interval",
+			Usage:       "how often to count the dependents and dependencies of the watched modules for the metrics",
+			Value:       cfg.watchlistMetrics,
+			Destination: &cfg.watchlistMetrics,
+			EnvVars:     []string{"WATCHLIST_METRICS_INTERVAL"},
+		},
+		&cli.DurationFlag{
+			Name:        "vulnerability-scan-interval",
+			Usage:       "how often to check the versions depended on against OSV for advisories, 0 disables the scan",
+			Value:       cfg.vulnerabilityScan,
+			Destination: &cfg.vulnerabilityScan,
+			EnvVars:     []string{"VULNERABILITY_SCAN_INTERVAL"},
+		},
+		&cli.StringFlag{
+			Name:        "osv-url",
+			Usage:       "the url of the OSV api used by the vulnerability scan",
+			Value:       cfg.osvURL,
+			Destination: &cfg.osvURL,
+			EnvVars:     []string{"OSV_URL"},
+		},
+		&cli.DurationFlag{
+			Name:        "advisory-feed-interval",
+			Usage:       "how often to sync the advisory feeds against the versions depended on, 0 disables the sync",
+			Value:       cfg.advisoryFeeds,
+			Destination: &cfg.advisoryFeeds,
+			EnvVars:     []string{"ADVISORY_FEED_INTERVAL"},
+		},
+		&cli.StringFlag{
+			Name:        "advisory-mirror",
+			Usage:       "path to a directory of advisories in the OSV format to sync, such as a clone of the github advisory database",
+			Value:       cfg.advisoryMirror,
+			Destination: &cfg.advisoryMirror,
+			EnvVars:     []string{"ADVISORY_MIRROR"},
+		},
+		&cli.StringFlag{
+			Name:        "ghsa-url",
+			Usage:       "the url of the github graphql api the security advisories are read from",
+			Value:       cfg.ghsaURL,
+			Destination: &cfg.ghsaURL,
+			EnvVars:     []string{"GHSA_URL"},
+		},
+		&cli.StringFlag{
+			Name:        "ghsa-token",
+			Usage:       "a github token used to sync the github security advisories, the feed is disabled without one",
+			Value:       cfg.ghsaToken,
+			Destination: &cfg.ghsaToken,
+			EnvVars:     []string{"GHSA_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:        "nvd-url",
+			Usage:       "the url of the nvd cve api used to rate advisories without a severity, empty disables the lookup",
+			Value:       cfg.nvdURL,
+			Destination: &cfg.nvdURL,
+			EnvVars:     []string{"NVD_URL"},
+		},
+		&cli.StringFlag{
+			Name:        "nvd-api-key",
+			Usage:       "an api key raising the rate limit of the nvd cve api",
+			Value:       cfg.nvdAPIKey,
+			Destination: &cfg.nvdAPIKey,
+			EnvVars:     []string{"NVD_API_KEY"},
+		},
+		&cli.DurationFlag{
+			Name:        "registry-enrichment-interval",
+			Usage:       "how often to label modules with the latest version published to their registry, 0 disables the enrichment",
+			Value:       cfg.registryEnrichment,
+			Destination: &cfg.registryEnrichment,
+			EnvVars:     []string{"REGISTRY_ENRICHMENT_INTERVAL"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "registry-url",
+			Usage:       "replaces the public registry of a language, written as language=url",
+			Destination: cfg.registryURLs,
+			EnvVars:     []string{"REGISTRY_URLS"},
+		},
+		&cli.StringFlag{
+			Name:        "endoflife-file",
+			Usage:       "path to a yaml file mapping endoflife.date products to the modules they're published as, the import is disabled without one",
+			Value:       cfg.endOfLifeFile,
+			Destination: &cfg.endOfLifeFile,
+			EnvVars:     []string{"ENDOFLIFE_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "endoflife-url",
+			Usage:       "the url of the endoflife.date api release cycles are imported from",
+			Value:       cfg.endOfLifeURL,
+			Destination: &cfg.endOfLifeURL,
+			EnvVars:     []string{"ENDOFLIFE_URL"},
+		},
+		&cli.DurationFlag{
+			Name:        "endoflife-interval",
+			Usage:       "how often to import the release cycles of the products in the endoflife file",
+			Value:       cfg.endOfLifeSync,
+			Destination: &cfg.endOfLifeSync,
+			EnvVars:     []string{"ENDOFLIFE_INTERVAL"},
+		},
+		&cli.DurationFlag{
+			Name:        "cycle-detection-interval",
+			Usage:       "how often to check the graph for dependency cycles and log them, 0 disables the check",
+			Value:       cfg.cycleDetection,
+			Destination: &cfg.cycleDetection,
+			EnvVars:     []string{"CYCLE_DETECTION_INTERVAL"},
+		},
+		&cli.StringFlag{
+			Name:        "cycle-detection-language",
+			Usage:       "only check modules of this language for dependency cycles",
+			Value:       cfg.cycleDetectionFilter.Language,
+			Destination: &cfg.cycleDetectionFilter.Language,
+			EnvVars:     []string{"CYCLE_DETECTION_LANGUAGE"},
+		},
+		&cli.StringFlag{
+			Name:        "cycle-detection-organization",
+			Usage:       "only check modules of this organization for dependency cycles",
+			Value:       cfg.cycleDetectionFilter.Organization,
+			Destination: &cfg.cycleDetectionFilter.Organization,
+			EnvVars:     []string{"CYCLE_DETECTION_ORGANIZATION"},
+		},
+		&cli.DurationFlag{
+			Name:        "retention-interval",
+			Usage:       "how often to apply the retention policy, 0 disables retention",
+			Value:       cfg.retention,
+			Destination: &cfg.retention,
+			EnvVars:     []string{"RETENTION_INTERVAL"},
+		},
+		&cli.DurationFlag{
+			Name:        "retention-edge-max-age",
+			Usage:       "tombstone edges that haven't been indexed within this age, 0 keeps them",
+			Value:       cfg.retentionPolicy.EdgeMaxAge,
+			Destination: &cfg.retentionPolicy.EdgeMaxAge,
+			EnvVars:     []string{"RETENTION_EDGE_MAX_AGE"},
+		},
+		&cli.DurationFlag{
+			Name:        "retention-source-max-age",
+			Usage:       "tombstone sources that haven't been indexed within this age, 0 keeps them",
+			Value:       cfg.retentionPolicy.SourceMaxAge,
+			Destination: &cfg.retentionPolicy.SourceMaxAge,
+			EnvVars:     []string{"RETENTION_SOURCE_MAX_AGE"},
+		},
+		&cli.DurationFlag{
+			Name:        "retention-tombstone-max-age",
+			Usage:       "purge tombstones older than this age, 0 keeps them",
+			Value:       cfg.retentionPolicy.TombstoneMaxAge,
+			Destination: &cfg.retentionPolicy.TombstoneMaxAge,
+			EnvVars:     []string{"RETENTION_TOMBSTONE_MAX_AGE"},
+		},
+		&cli.DurationFlag{
+			Name:        "retention-history-max-age",
+			Usage:       "purge history replaced longer ago than this age, 0 keeps it",
+			Value:       cfg.retentionPolicy.HistoryMaxAge,
+			Destination: &cfg.retentionPolicy.HistoryMaxAge,
+			EnvVars:     []string{"RETENTION_HISTORY_MAX_AGE"},
+		},
+		&cli.BoolFlag{
+			Name:        "retention-dry-run",
+			Usage:       "log what the retention policy would remove without removing it",
+			Value:       cfg.retentionDryRun,
+			Destination: &cfg.retentionDryRun,
+			EnvVars:     []string{"RETENTION_DRY_RUN"},
+		},
+		&cli.StringFlag{
+			Name:        "aliases-file",
+			Usage:       "path to a yaml file declaring the aliases modules are known by",
+			Value:       cfg.aliasesFile,
+			Destination: &cfg.aliasesFile,
+			EnvVars:     []string{"ALIASES_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "license-policy-file",
+			Usage:       "path to a yaml file declaring the licenses dependencies may use, compliance is disabled without one",
+			Value:       cfg.licensePolicyFile,
+			Destination: &cfg.licensePolicyFile,
+			EnvVars:     []string{"LICENSE_POLICY_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "notifications-file",
+			Usage:       "path to a yaml file declaring notification channels and team subscriptions, notifications are disabled without one",
+			Value:       cfg.notificationsFile,
+			Destination: &cfg.notificationsFile,
+			EnvVars:     []string{"NOTIFICATIONS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "webhooks-file",
+			Usage:       "path to a yaml file declaring the endpoints source and module events are sent to, webhooks are disabled without one",
+			Value:       cfg.webhooksFile,
+			Destination: &cfg.webhooksFile,
+			EnvVars:     []string{"WEBHOOKS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "reports-file",
+			Usage:       "path to a yaml file declaring the reports delivered for organizations on a schedule, scheduled reports are disabled without one",
+			Value:       cfg.reportsFile,
+			Destination: &cfg.reportsFile,
+			EnvVars:     []string{"REPORTS_FILE"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "snapshot-location",
+			Usage:       "a directory or url prefix snapshots can be written to and restored from, tenants are limited to the directory named after them within it, snapshots are disabled when none are given",
+			Destination: cfg.snapshotLocations,
+			EnvVars:     []string{"SNAPSHOT_LOCATIONS"},
+		},
+	}...)
+	flags = append(flags, tenancyFlags...)
+	flags = append(flags, policyFlags...)
+	flags = append(flags, rbacFlags...)
+	flags = append(flags, auditFlags...)
+	flags = append(flags, eventBusFlags...)
+	flags = append(flags, telemetryFlags...)
+	flags = append(flags, loggingFlags...)
+	flags = append(flags, middlewareFlags...)
+	flags = append(flags, diagnosticsFlags...)
+	flags = append(flags, featureFlags...)
 
 	app := &cli.App{
 		Name:        "tracker",
@@ -120,105 +605,340 @@ func main() {
 
 				},
 			},
+			migrateCommand(cfg),
 		},
-		Flags: []cli.Flag{
-			&cli.IntFlag{
-				Name:        "http-port",
-				Usage:       "the port to run http on",
-				Value:       cfg.httpPort,
-				Destination: &cfg.httpPort,
-				EnvVars:     []string{"HTTP_PORT"},
-			},
-			&cli.IntFlag{
-				Name:        "grpc-port",
-				Aliases:     []string{"port"},
-				Usage:       "the port to run grpc on",
-				Value:       cfg.grpcPort,
-				Destination: &cfg.grpcPort,
-				EnvVars:     []string{"GRPC_PORT"},
-			},
-			&cli.StringFlag{
-				Name:        "storage-driver",
-				Usage:       "the driver used to configure the storage tier",
-				Value:       cfg.storageDriver,
-				Destination: &cfg.storageDriver,
-				EnvVars:     []string{"STORAGE_DRIVER"},
-			},
-			&cli.StringFlag{
-				Name:        "storage-address",
-				Usage:       "the address of the storage tier",
-				Value:       cfg.storageAddress,
-				Destination: &cfg.storageAddress,
-				EnvVars:     []string{"STORAGE_ADDRESS"},
-			},
-			&cli.StringFlag{
-				Name:        "storage-readonly-address",
-				Usage:       "the readonly address of the storage tier",
-				Value:       cfg.storageReadOnlyAddress,
-				Destination: &cfg.storageReadOnlyAddress,
-				EnvVars:     []string{"STORAGE_READ_ONLY_ADDRESS"},
-			},
-			&cli.StringFlag{
-				Name:        "tls-key",
-				Usage:       "path to the file containing the TLS private key",
-				Value:       tlsConfig.KeyPath,
-				Destination: &tlsConfig.KeyPath,
-				EnvVars:     []string{"TLS_KEY_PATH"},
-			},
-			&cli.StringFlag{
-				Name:        "tls-cert",
-				Usage:       "path to the file containing the TLS certificate",
-				Value:       tlsConfig.CertPath,
-				Destination: &tlsConfig.CertPath,
-				EnvVars:     []string{"TLS_CERT_PATH"},
-			},
-			&cli.StringFlag{
-				Name:        "tls-ca",
-				Usage:       "path to the file containing the TLS certificate authority",
-				Value:       tlsConfig.CAPath,
-				Destination: &tlsConfig.CAPath,
-				EnvVars:     []string{"TLS_CA_PATH"},
-			},
-		},
+		Flags: flags,
 		Action: func(c *cli.Context) error {
-			err := startGraphStore(cfg.storageDriver, cfg.storageAddress, cfg.storageReadOnlyAddress)
+			if err := logging.Setup("tracker", cfg.logging); err != nil {
+				return err
+			}
+			middleware.Setup(cfg.middleware)
+
+			if err := diagnostics.Serve(c.Context, cfg.diagnostics); err != nil {
+				return err
+			}
+
+			if err := cfg.tenancy.Validate(); err != nil {
+				return err
+			}
+
+			if err := cfg.rbac.Validate(); err != nil {
+				return err
+			}
+
+			featureSet, err := features.New(cfg.features)
+			if err != nil {
+				return err
+			}
+			features.SetDefault(featureSet)
+
+			tracer, err := telemetry.NewTracer("tracker", version.Version, cfg.telemetry, nil)
+			if err != nil {
+				return err
+			} else if tracer != nil {
+				telemetry.SetTracer(tracer)
+				go tracer.Run(c.Context)
+			}
+
+			metricExporter, err := telemetry.NewMetricExporter("tracker", version.Version, cfg.telemetry, nil, nil)
+			if err != nil {
+				return err
+			}
+			go metricExporter.Run(c.Context)
+
+			db, err := prepareSchemas(c.Context, cfg)
+			if err != nil {
+				return err
+			} else if db != nil {
+				defer db.Close()
+			}
+
+			var aliases *svcsv1alpha.Aliases
+			if cfg.aliasesFile != "" {
+				if aliases, err = svcsv1alpha.LoadAliasesFile(cfg.aliasesFile); err != nil {
+					return err
+				}
+			}
+
+			var licensePolicy *svcsv1alpha.LicensePolicy
+			if cfg.licensePolicyFile != "" {
+				if licensePolicy, err = svcsv1alpha.LoadLicensePolicyFile(cfg.licensePolicyFile); err != nil {
+					return err
+				}
+			}
+
+			var reports *svcsv1alpha.ReportConfig
+			if cfg.reportsFile != "" {
+				if reports, err = svcsv1alpha.LoadReportsFile(cfg.reportsFile); err != nil {
+					return err
+				}
+			}
+
+			readOnlyAddresses := append([]string{cfg.storageReadOnlyAddress}, cfg.storageReplicaAddress.Value()...)
+
+			bus, err := eventbus.NewBus(cfg.eventBus)
+			if err != nil {
+				return err
+			} else if bus != nil {
+				go bus.Run(c.Context)
+			}
+
+			v1alphaGraphStore, err := startGraphStore(cfg.storageDriver, cfg.storageAddress, readOnlyAddresses, cfg.pool, cfg.cache, bus)
 			if err != nil {
 				return err
 			}
 
-			cc, err := grpc.Dial(sockAddr,
+			// the tenant, id, and trace of each request is passed along to
+			// the graph store
+			cc, err := grpc.Dial(sockAddr, middleware.DialOptions(
 				grpc.WithInsecure(),
-				grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(
-					grpc_retry.UnaryClientInterceptor(grpc_retry.WithMax(5)),
-					grpc_prometheus.UnaryClientInterceptor,
-				)),
-				grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(
-					grpc_prometheus.StreamClientInterceptor,
-				)),
-			)
+				grpc.WithChainUnaryInterceptor(grpc_retry.UnaryClientInterceptor(grpc_retry.WithMax(5))),
+			)...)
 			if err != nil {
 				return err
 			}
 
-			grpcServer, httpServer := mux.DefaultServers()
+			serverTLSConfig, err := mux.LoadTLSConfig(cfg.server.TLS)
+			if err != nil {
+				return err
+			}
+
+			serverOptions, err := cfg.tenancy.ServerOptions(serverTLSConfig)
+			if err != nil {
+				return err
+			}
+
+			// roles are bound by the api when the graph store supports it
+			var bindingSource rbac.Source
+			roleBindings, _ := v1alphaGraphStore.(v1alpha.RoleBindings)
+			if roleBindings != nil {
+				bindingSource = svcsv1alpha.RoleBindingSource(roleBindings)
+			}
+
+			authorizer, err := rbac.NewAuthorizer(cfg.rbac, bindingSource)
+			if err != nil {
+				return err
+			}
+
+			// service accounts authenticate using tokens verified against
+			// the graph store
+			accounts, _ := v1alphaGraphStore.(v1alpha.ServiceAccounts)
+			if accounts != nil {
+				authorizer.WithVerifier(svcsv1alpha.TokenVerifier(accounts))
+			}
+
+			// roles and policies are checked once the tenant of a call is
+			// resolved
+			rbacOptions, err := authorizer.ServerOptions(serverTLSConfig)
+			if err != nil {
+				return err
+			}
+
+			// actions are audited once the caller is authorized, so those
+			// denied by a policy are recorded as well
+			auditLog, _ := v1alphaGraphStore.(v1alpha.AuditLog)
+
+			var auditSink audit.Sink
+			if cfg.audit.Sink == audit.SinkDatabase {
+				if auditLog == nil {
+					return fmt.Errorf("the database audit sink requires a sql graph store")
+				}
+				auditSink = &svcsv1alpha.DatabaseSink{AuditLog: auditLog}
+			} else if auditSink, err = cfg.audit.NewSink(nil); err != nil {
+				return err
+			}
+
+			auditLogger := audit.NewLogger("tracker", auditSink, cfg.audit)
+			go auditLogger.Run(c.Context)
+
+			serverOptions = append(serverOptions, rbacOptions...)
+			serverOptions = append(serverOptions, auditLogger.ServerOptions()...)
+			serverOptions = append(serverOptions, cfg.policies.ServerOptions()...)
+
+			grpcServer, httpServer := mux.DefaultServers(serverOptions...)
+			httpServer.Handle(features.Route, featureSet)
 
 			v1betaClient := apiv1beta.NewGraphStoreClient(cc)
 			registerV1Beta(v1betaClient, grpcServer)
 
-			v1alphaClient := apiv1alpha.NewGraphStoreClient(cc)
-			registerV1Alpha(v1alphaClient, grpcServer)
+			var v1alphaClient apiv1alpha.GraphStoreClient
+			if v1alphaGraphStore != nil {
+				v1alphaClient = apiv1alpha.NewGraphStoreClient(cc)
+				replacements, _ := v1alphaGraphStore.(v1alpha.Replacements)
+				labels, _ := v1alphaGraphStore.(v1alpha.Labels)
+
+				var notifier *svcsv1alpha.Notifier
+				if cfg.notificationsFile != "" {
+					if notifier, err = svcsv1alpha.LoadNotificationsFile(cfg.notificationsFile, labels); err != nil {
+						return err
+					}
+				}
+
+				var webhooks *svcsv1alpha.Webhooks
+				if cfg.webhooksFile != "" {
+					if webhooks, err = svcsv1alpha.LoadWebhooksFile(cfg.webhooksFile); err != nil {
+						return err
+					}
+
+					svcsv1alpha.RegisterWebhookService(httpServer, webhooks)
+					go webhooks.Run(c.Context)
+				}
+
+				registerV1Alpha(v1alphaClient, replacements, grpcServer, cfg.paging, cfg.searchWindow, aliases, cfg.policies, notifier, webhooks)
+				svcsv1alpha.RegisterQueryService(httpServer, v1alphaClient, cfg.maxTraversalDepth, aliases)
+				svcsv1alpha.RegisterGraphService(httpServer, v1alphaClient)
+				svcsv1alpha.RegisterSBOMService(httpServer, v1alphaClient, version.Version)
+
+				counts, _ := v1alphaGraphStore.(v1alpha.Counts)
+				svcsv1alpha.RegisterCountService(httpServer, v1alphaClient, counts, aliases)
+
+				vulnerabilities, _ := v1alphaGraphStore.(v1alpha.Vulnerabilities)
+				svcsv1alpha.RegisterBadgeService(httpServer, v1alphaClient, counts, vulnerabilities, aliases)
+
+				history, _ := v1alphaGraphStore.(v1alpha.History)
+				if history != nil {
+					svcsv1alpha.RegisterDiffService(httpServer, history)
+				}
+
+				svcsv1alpha.RegisterDashboardService(httpServer, v1alphaClient, counts, history, vulnerabilities, aliases)
+
+				licenseLabel := ""
+				if reports != nil {
+					licenseLabel = reports.LicenseLabel
+				}
+
+				reportGenerator := svcsv1alpha.NewReportGenerator(v1alphaClient, labels, history, vulnerabilities, licenseLabel)
+				svcsv1alpha.RegisterReportService(httpServer, reportGenerator)
+				if reports != nil {
+					go svcsv1alpha.RunReports(c.Context, reportGenerator, reports)
+				}
+
+				if roleBindings != nil {
+					svcsv1alpha.RegisterRBACService(httpServer, roleBindings, authorizer)
+				}
+
+				if auditLog != nil {
+					svcsv1alpha.RegisterAuditService(httpServer, auditLog)
+				}
+
+				if accounts != nil {
+					svcsv1alpha.RegisterServiceAccountService(httpServer, accounts)
+				}
+
+				if tombstones, ok := v1alphaGraphStore.(v1alpha.Tombstones); ok {
+					svcsv1alpha.RegisterTombstoneService(httpServer, v1alphaClient, tombstones)
+				}
+
+				if labels != nil {
+					svcsv1alpha.RegisterLabelService(httpServer, labels, aliases)
+					svcsv1alpha.RegisterRegistryService(httpServer, v1alphaClient, labels, aliases)
+					svcsv1alpha.RegisterLifecycleService(httpServer, v1alphaClient, labels, aliases)
+					svcsv1alpha.RegisterSuggestionService(httpServer, v1alphaClient, labels)
+
+					if cfg.endOfLifeFile != "" && cfg.endOfLifeSync > 0 {
+						endOfLife, err := svcsv1alpha.LoadEndOfLifeFile(cfg.endOfLifeFile)
+						if err != nil {
+							return err
+						}
 
-			return mux.Serve(grpcServer, httpServer, &mux.Config{
+						client := svcsv1alpha.NewEndOfLifeClient(cfg.endOfLifeURL)
+						go svcsv1alpha.RunEndOfLifeSync(c.Context, labels, client, endOfLife, aliases, cfg.endOfLifeSync)
+					}
+
+					if cfg.registryEnrichment > 0 {
+						registryURLs, err := registries.ParseURLs(cfg.registryURLs.Value())
+						if err != nil {
+							return err
+						}
+
+						client := registries.NewClient("depscloud-tracker", registryURLs)
+						go svcsv1alpha.RunRegistryEnrichment(c.Context, v1alphaClient, labels, client, cfg.registryEnrichment)
+					}
+
+					if licensePolicy != nil {
+						svcsv1alpha.RegisterComplianceService(httpServer, v1alphaClient, labels, licensePolicy, aliases)
+					}
+				}
+
+				if snapshots, ok := v1alphaGraphStore.(v1alpha.Snapshots); ok {
+					svcsv1alpha.RegisterSnapshotService(httpServer, snapshots, cfg.snapshotLocations.Value())
+				}
+
+				if search, ok := v1alphaGraphStore.(v1alpha.TextSearch); ok {
+					svcsv1alpha.RegisterTextSearchService(httpServer, search, labels)
+				}
+
+				if vulnerabilities, ok := v1alphaGraphStore.(v1alpha.Vulnerabilities); ok {
+					svcsv1alpha.RegisterVulnerabilityService(httpServer, v1alphaClient, vulnerabilities, aliases)
+
+					vulnerabilities = svcsv1alpha.NotifyVulnerabilities(vulnerabilities, v1alphaClient, notifier)
+
+					if cfg.vulnerabilityScan > 0 {
+						osv := svcsv1alpha.NewOSVClient(cfg.osvURL)
+						go svcsv1alpha.RunVulnerabilityScan(c.Context, v1alphaClient, vulnerabilities, osv, cfg.vulnerabilityScan)
+					}
+
+					feeds := make([]svcsv1alpha.AdvisoryFeed, 0)
+					if cfg.advisoryMirror != "" {
+						feeds = append(feeds, &svcsv1alpha.MirrorFeed{Path: cfg.advisoryMirror})
+					}
+					if cfg.ghsaToken != "" {
+						feeds = append(feeds, svcsv1alpha.NewGHSAFeed(cfg.ghsaURL, cfg.ghsaToken))
+					}
+
+					if len(feeds) > 0 && cfg.advisoryFeeds > 0 {
+						var nvd *svcsv1alpha.NVDClient
+						if cfg.nvdURL != "" {
+							nvd = svcsv1alpha.NewNVDClient(cfg.nvdURL, cfg.nvdAPIKey)
+						}
+
+						go svcsv1alpha.RunFeedSync(c.Context, v1alphaClient, vulnerabilities, feeds, nvd, cfg.advisoryFeeds)
+					}
+				}
+
+				if retention, ok := v1alphaGraphStore.(v1alpha.Retention); ok && cfg.retention > 0 {
+					go svcsv1alpha.RunRetention(c.Context, retention, cfg.retentionPolicy, cfg.retention, cfg.retentionDryRun)
+				}
+
+				if cardinality, ok := v1alphaGraphStore.(v1alpha.Cardinality); ok && cfg.cardinalityMetrics > 0 {
+					go svcsv1alpha.RunCardinalityMetrics(c.Context, cardinality, cfg.cardinalityMetrics)
+				}
+
+				if rebuilder, ok := v1alphaGraphStore.(v1alpha.CountRebuilder); ok && cfg.countRebuild > 0 {
+					go svcsv1alpha.RunCountRebuild(c.Context, rebuilder, cfg.countRebuild)
+				}
+
+				if len(cfg.watchlist.Value()) > 0 && cfg.watchlistMetrics > 0 {
+					watchlist, err := svcsv1alpha.ParseWatchlist(cfg.watchlist.Value())
+					if err != nil {
+						return err
+					}
+
+					go svcsv1alpha.RunWatchlistMetrics(c.Context, v1alphaClient, counts, aliases, watchlist, cfg.watchlistMetrics)
+				}
+
+				if cfg.cycleDetection > 0 {
+					go svcsv1alpha.RunCycleDetection(c.Context, v1alphaClient, cfg.cycleDetectionFilter, cfg.cycleDetection)
+				}
+			}
+
+			httpHandler := cfg.tenancy.Middleware(authorizer.Middleware(auditLogger.Middleware(cfg.policies.Middleware(httpServer))))
+
+			return mux.Serve(grpcServer, httpHandler, &mux.Config{
 				Context:         c.Context,
-				BindAddressHTTP: fmt.Sprintf("0.0.0.0:%d", cfg.httpPort),
-				BindAddressGRPC: fmt.Sprintf("0.0.0.0:%d", cfg.grpcPort),
+				BindAddressHTTP: fmt.Sprintf("0.0.0.0:%d", cfg.server.HTTPPort),
+				BindAddressGRPC: fmt.Sprintf("0.0.0.0:%d", cfg.server.GRPCPort),
 				Checks:          checks.Checks(v1betaClient, v1alphaClient),
+				ServiceChecks:   checks.Services(),
 				Version:         &version,
-				TLSConfig:       tlsConfig,
+				TLSConfig:       cfg.server.TLS,
+				GRPCCredentials: cfg.tenancy.Mode == tenants.ModeCertificate || cfg.rbac.Mode == rbac.ModeCertificate,
 			})
 		},
 	}
 
+	config.Apply(app)
+
 	if err := app.Run(os.Args); err != nil {
 		logrus.Fatal(err)
 	}
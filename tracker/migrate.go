@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/depscloud/depscloud/tracker/internal/graphstore/v1alpha"
+	"github.com/depscloud/depscloud/tracker/internal/graphstore/v1beta"
+	"github.com/depscloud/depscloud/tracker/internal/migrations"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/urfave/cli/v2"
+)
+
+// openMigrators connects to the storage tier and returns the migrators for
+// each schema. Only sql drivers are versioned, a nil db is returned for the
+// others.
+func openMigrators(driver, address string) (*sqlx.DB, []*migrations.Migrator, error) {
+	driverName, err := v1alpha.ResolveDriverName(driver)
+	if err != nil {
+		return nil, nil, nil
+	}
+
+	db, err := sqlx.Open(driverName, address)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v1betaMigrator, err := v1beta.NewMigrator(db, driver)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	v1alphaMigrator, err := v1alpha.NewMigrator(db, driver)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return db, []*migrations.Migrator{v1betaMigrator, v1alphaMigrator}, nil
+}
+
+// prepareSchemas migrates the schemas to the latest version or, when
+// automatic migrations are disabled, verifies they already are. The returned
+// db must be kept open while the stores are in use since closing the last
+// connection to an in-memory sqlite database discards it.
+func prepareSchemas(ctx context.Context, cfg *trackerConfig) (*sqlx.DB, error) {
+	if len(cfg.storageAddress) == 0 {
+		return nil, nil
+	}
+
+	db, migrators, err := openMigrators(cfg.storageDriver, cfg.storageAddress)
+	if err != nil || db == nil {
+		return nil, err
+	}
+
+	for _, migrator := range migrators {
+		if cfg.autoMigrate {
+			err = migrator.Up(ctx)
+		} else {
+			err = migrator.Check(ctx)
+		}
+
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+func migrateCommand(cfg *trackerConfig) *cli.Command {
+	schema := ""
+
+	withMigrators := func(action func(c *cli.Context, migrators []*migrations.Migrator) error) cli.ActionFunc {
+		return func(c *cli.Context) error {
+			db, migrators, err := openMigrators(cfg.storageDriver, cfg.storageAddress)
+			if err != nil {
+				return err
+			} else if db == nil {
+				return fmt.Errorf("migrations are only supported by the sql drivers")
+			}
+			defer db.Close()
+
+			return action(c, migrators)
+		}
+	}
+
+	// selectMigrator returns the migrator for the --schema flag
+	selectMigrator := func(migrators []*migrations.Migrator) (*migrations.Migrator, error) {
+		for i, name := range []string{v1beta.SchemaName, v1alpha.SchemaName} {
+			if name == schema {
+				return migrators[i], nil
+			}
+		}
+		return nil, fmt.Errorf("--schema must be one of %s or %s", v1beta.SchemaName, v1alpha.SchemaName)
+	}
+
+	schemaFlag := &cli.StringFlag{
+		Name:        "schema",
+		Usage:       fmt.Sprintf("the schema to migrate, one of %s or %s", v1beta.SchemaName, v1alpha.SchemaName),
+		Destination: &schema,
+		Required:    true,
+	}
+
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "Manage the versions of the storage schemas",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "up",
+				Usage: "Apply all pending migrations",
+				Action: withMigrators(func(c *cli.Context, migrators []*migrations.Migrator) error {
+					for _, migrator := range migrators {
+						if err := migrator.Up(c.Context); err != nil {
+							return err
+						}
+					}
+					return nil
+				}),
+			},
+			{
+				Name:      "down",
+				Usage:     "Revert the latest migration, or all migrations after a version",
+				ArgsUsage: "[version]",
+				Flags:     []cli.Flag{schemaFlag},
+				Action: withMigrators(func(c *cli.Context, migrators []*migrations.Migrator) error {
+					migrator, err := selectMigrator(migrators)
+					if err != nil {
+						return err
+					}
+
+					if c.NArg() == 0 {
+						return migrator.Down(c.Context)
+					}
+
+					version, err := strconv.Atoi(c.Args().First())
+					if err != nil {
+						return err
+					}
+					return migrator.To(c.Context, version)
+				}),
+			},
+			{
+				Name:      "force",
+				Usage:     "Record the version of a schema and clear its dirty state after repairing it by hand",
+				ArgsUsage: "version",
+				Flags:     []cli.Flag{schemaFlag},
+				Action: withMigrators(func(c *cli.Context, migrators []*migrations.Migrator) error {
+					migrator, err := selectMigrator(migrators)
+					if err != nil {
+						return err
+					}
+
+					version, err := strconv.Atoi(c.Args().First())
+					if err != nil {
+						return fmt.Errorf("a version is required: %w", err)
+					}
+					return migrator.Force(c.Context, version)
+				}),
+			},
+			{
+				Name:  "status",
+				Usage: "Output the version of each schema",
+				Action: withMigrators(func(c *cli.Context, migrators []*migrations.Migrator) error {
+					for i, name := range []string{v1beta.SchemaName, v1alpha.SchemaName} {
+						version, dirty, err := migrators[i].Version(c.Context)
+						if err != nil {
+							return err
+						}
+
+						status := "ok"
+						if dirty {
+							status = "dirty"
+						} else if version < migrators[i].Latest() {
+							status = "pending"
+						}
+
+						fmt.Printf("%s\tversion %d of %d\t%s\n", name, version, migrators[i].Latest(), status)
+					}
+					return nil
+				}),
+			},
+		},
+	}
+}